@@ -0,0 +1,78 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// buildWorkspaceMarkdownTarScript returns a pod-side shell script that streams
+// the top-level *.md files of a workspace as a gzipped tar archive on stdout.
+// Like find -maxdepth 1 -type f -name '*.md', it includes dotfiles and skips
+// directories and symlinks. An empty or missing workspace yields an empty
+// (zero-byte) stream.
+func buildWorkspaceMarkdownTarScript(workspace string) string {
+	return fmt.Sprintf(`cd %s 2>/dev/null || exit 0
+set --
+for f in *.md .*.md .md; do
+  [ -f "$f" ] && [ ! -L "$f" ] && set -- "$@" "$f"
+done
+[ "$#" -gt 0 ] || exit 0
+tar -czf - -- "$@"`, shellQuote(workspace))
+}
+
+// extractMarkdownTarGz unpacks a gzipped tar stream produced by
+// buildWorkspaceMarkdownTarScript into destDir. Only regular top-level *.md
+// entries are written; anything else (directories, links, nested or unsafe
+// paths) is skipped. It returns the number of files written.
+func extractMarkdownTarGz(r io.Reader, destDir string) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("invalid gzip stream: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	tr := tar.NewReader(gz)
+	written := 0
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return written, fmt.Errorf("invalid tar stream: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		if name != path.Base(name) || name == "." || name == ".." || !strings.HasSuffix(strings.ToLower(name), ".md") {
+			continue
+		}
+
+		target := filepath.Join(destDir, name)
+		f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+		if err != nil {
+			return written, fmt.Errorf("failed to create %s: %w", target, err)
+		}
+		if _, err := io.Copy(f, tr); err != nil {
+			_ = f.Close()
+			return written, fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		if err := f.Close(); err != nil {
+			return written, fmt.Errorf("failed to close %s: %w", target, err)
+		}
+		written++
+	}
+	return written, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func buildTestTarGz(t *testing.T, entries map[string]string, extra ...*tar.Header) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	for _, hdr := range extra {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("WriteHeader: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("tar Close: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractMarkdownTarGz(t *testing.T) {
+	archive := buildTestTarGz(t, map[string]string{
+		"AGENTS.md":      "# agents\n",
+		"./SOUL.md":      "soul\n",
+		"../escape.md":   "nope",
+		"nested/deep.md": "nope",
+		"notes.txt":      "nope",
+	}, &tar.Header{Name: "link.md", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"})
+
+	dir := t.TempDir()
+	n, err := extractMarkdownTarGz(bytes.NewReader(archive), dir)
+	if err != nil {
+		t.Fatalf("extractMarkdownTarGz() error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("extractMarkdownTarGz() wrote %d files, want 2", n)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "SOUL.md"))
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(got) != "soul\n" {
+		t.Errorf("SOUL.md = %q, want %q", got, "soul\n")
+	}
+	for _, name := range []string{"escape.md", "deep.md", "notes.txt", "link.md"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("unexpected file %s extracted", name)
+		}
+	}
+}
+
+func TestExtractMarkdownTarGz_EmptyStream(t *testing.T) {
+	n, err := extractMarkdownTarGz(bytes.NewReader(nil), t.TempDir())
+	if err != nil {
+		t.Fatalf("extractMarkdownTarGz() error on empty stream: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("extractMarkdownTarGz() wrote %d files, want 0", n)
+	}
+}
+
+func TestExtractMarkdownTarGz_InvalidStream(t *testing.T) {
+	if _, err := extractMarkdownTarGz(strings.NewReader("not gzip"), t.TempDir()); err == nil {
+		t.Fatal("extractMarkdownTarGz() expected error for invalid stream")
+	}
+}
+
+func TestBuildWorkspaceMarkdownTarScript(t *testing.T) {
+	got := buildWorkspaceMarkdownTarScript("/home/node/.openclaw/workspace-coding")
+	if !strings.HasPrefix(got, "cd '/home/node/.openclaw/workspace-coding' 2>/dev/null || exit 0") {
+		t.Fatalf("unexpected script prefix: %q", got)
+	}
+	if !strings.Contains(got, `tar -czf - -- "$@"`) {
+		t.Fatalf("script does not stream tar archive: %q", got)
+	}
+}
+
+func TestWorkspaceMarkdownTarScriptMatchesLikeFind(t *testing.T) {
+	for _, tool := range []string{"sh", "tar"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not found", tool)
+		}
+	}
+	workspace := t.TempDir()
+	for name, content := range map[string]string{
+		"AGENTS.md":     "# agents\n",
+		".hidden.md":    "hidden\n",
+		"notes.txt":     "not markdown\n",
+		"sub/NESTED.md": "nested\n",
+	} {
+		path := filepath.Join(workspace, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(workspace, "dir.md"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("AGENTS.md", filepath.Join(workspace, "link.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := exec.Command("sh", "-c", buildWorkspaceMarkdownTarScript(workspace)).Output()
+	if err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+	dest := t.TempDir()
+	if n, err := extractMarkdownTarGz(bytes.NewReader(archive), dest); err != nil || n != 2 {
+		t.Fatalf("extractMarkdownTarGz() = %d, %v; want AGENTS.md and .hidden.md", n, err)
+	}
+	for _, name := range []string{"AGENTS.md", ".hidden.md"} {
+		if _, err := os.Stat(filepath.Join(dest, name)); err != nil {
+			t.Errorf("missing %s: %v", name, err)
+		}
+	}
+
+	empty, err := exec.Command("sh", "-c", buildWorkspaceMarkdownTarScript(filepath.Join(workspace, "sub", "missing"))).Output()
+	if err != nil || len(empty) != 0 {
+		t.Errorf("missing workspace: %d bytes, %v; want an empty stream", len(empty), err)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// approvalEntry is a runtime allowlist entry that is not part of the project
+// approvals baseline, typically added by an "allow always" decision in chat.
+type approvalEntry struct {
+	Agent   string
+	Pattern string
+	// LastUsedCommand is informational; OpenClaw records it on use
+	LastUsedCommand string
+}
+
+type approvalDecision string
+
+const (
+	approvalPending  approvalDecision = ""
+	approvalApproved approvalDecision = "approve"
+	approvalDenied   approvalDecision = "deny"
+)
+
+// decodeApprovalsDocument parses a normalized approvals payload into a generic
+// document so unknown fields survive a round trip.
+func decodeApprovalsDocument(payload []byte) (map[string]any, error) {
+	doc := map[string]any{}
+	if len(strings.TrimSpace(string(payload))) == 0 {
+		return doc, nil
+	}
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, fmt.Errorf("invalid approvals JSON: %w", err)
+	}
+	return doc, nil
+}
+
+// approvalsAllowlist returns the allowlist array of an agent, or nil
+func approvalsAllowlist(doc map[string]any, agent string) []any {
+	agents, _ := doc["agents"].(map[string]any)
+	entry, _ := agents[agent].(map[string]any)
+	list, _ := entry["allowlist"].([]any)
+	return list
+}
+
+func allowlistPattern(item any) string {
+	entry, _ := item.(map[string]any)
+	pattern, _ := entry["pattern"].(string)
+	return pattern
+}
+
+// pendingApprovalEntries lists runtime allowlist entries whose pattern is not
+// in the baseline for the same agent, ordered by agent and then runtime order.
+func pendingApprovalEntries(runtime, baseline map[string]any) []approvalEntry {
+	agents, _ := runtime["agents"].(map[string]any)
+	names := make([]string, 0, len(agents))
+	for name := range agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pending []approvalEntry
+	for _, agent := range names {
+		known := map[string]bool{}
+		for _, item := range approvalsAllowlist(baseline, agent) {
+			known[allowlistPattern(item)] = true
+		}
+		for _, item := range approvalsAllowlist(runtime, agent) {
+			pattern := allowlistPattern(item)
+			if pattern == "" || known[pattern] {
+				continue
+			}
+			known[pattern] = true
+			entry, _ := item.(map[string]any)
+			lastUsed, _ := entry["lastUsedCommand"].(string)
+			pending = append(pending, approvalEntry{Agent: agent, Pattern: pattern, LastUsedCommand: lastUsed})
+		}
+	}
+	return pending
+}
+
+// applyApprovalDecisions removes denied entries from the runtime document and
+// appends approved patterns to the baseline document. It reports whether each
+// document changed.
+func applyApprovalDecisions(runtime, baseline map[string]any, entries []approvalEntry, decisions []approvalDecision) (runtimeChanged, baselineChanged bool) {
+	for i, entry := range entries {
+		switch decisions[i] {
+		case approvalDenied:
+			list := approvalsAllowlist(runtime, entry.Agent)
+			kept := list[:0]
+			for _, item := range list {
+				if allowlistPattern(item) != entry.Pattern {
+					kept = append(kept, item)
+				}
+			}
+			setApprovalsAllowlist(runtime, entry.Agent, kept)
+			runtimeChanged = true
+		case approvalApproved:
+			list := approvalsAllowlist(baseline, entry.Agent)
+			list = append(list, map[string]any{"pattern": entry.Pattern})
+			setApprovalsAllowlist(baseline, entry.Agent, list)
+			baselineChanged = true
+		}
+	}
+	return runtimeChanged, baselineChanged
+}
+
+func setApprovalsAllowlist(doc map[string]any, agent string, list []any) {
+	if _, ok := doc["version"]; !ok {
+		doc["version"] = 1
+	}
+	agents, ok := doc["agents"].(map[string]any)
+	if !ok {
+		agents = map[string]any{}
+		doc["agents"] = agents
+	}
+	entry, ok := agents[agent].(map[string]any)
+	if !ok {
+		entry = map[string]any{}
+		agents[agent] = entry
+	}
+	entry["allowlist"] = list
+}
+
+var errApprovalsReviewAborted = errors.New("approvals review aborted")
+
+// reviewApprovalEntries walks the operator through each pending entry and
+// records a decision per entry. Entries left undecided (skip, quit, EOF) stay
+// pending. Quitting keeps the decisions made so far; "x" aborts the review.
+func reviewApprovalEntries(in *bufio.Reader, out io.Writer, entries []approvalEntry) ([]approvalDecision, error) {
+	decisions := make([]approvalDecision, len(entries))
+
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		fmt.Fprintf(out, "\n[%d/%d] agent %s\n", i+1, len(entries), entry.Agent)
+		fmt.Fprintf(out, "  pattern:   %s\n", entry.Pattern)
+		if entry.LastUsedCommand != "" {
+			fmt.Fprintf(out, "  last used: %s\n", entry.LastUsedCommand)
+		}
+		fmt.Fprint(out, "  [a]pprove  [d]eny  [s]kip  [b]ack  [q]uit  e[x]it without changes > ")
+
+		line, err := in.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "" && errors.Is(err, io.EOF) {
+			fmt.Fprintln(out)
+			return decisions, nil
+		}
+		switch answer {
+		case "a", "approve":
+			decisions[i] = approvalApproved
+		case "d", "deny":
+			decisions[i] = approvalDenied
+		case "s", "skip", "":
+			decisions[i] = approvalPending
+		case "b", "back":
+			if i > 0 {
+				i -= 2
+			} else {
+				i--
+			}
+		case "q", "quit":
+			fmt.Fprintln(out)
+			return decisions, nil
+		case "x", "exit":
+			fmt.Fprintln(out)
+			return nil, errApprovalsReviewAborted
+		default:
+			fmt.Fprintf(out, "  unknown choice %q\n", answer)
+			i--
+		}
+		if errors.Is(err, io.EOF) {
+			return decisions, nil
+		}
+	}
+	return decisions, nil
+}
+
+func countApprovalDecisions(decisions []approvalDecision, want approvalDecision) int {
+	n := 0
+	for _, d := range decisions {
+		if d == want {
+			n++
+		}
+	}
+	return n
+}
+
+// printApprovalDecisions summarizes the decisions before anything is written
+func printApprovalDecisions(out io.Writer, entries []approvalEntry, decisions []approvalDecision) {
+	for i, entry := range entries {
+		decision := string(decisions[i])
+		if decision == "" {
+			decision = "skip"
+		}
+		fmt.Fprintf(out, "  %-8s %-16s %s\n", decision, entry.Agent, entry.Pattern)
+	}
+}
+
+// confirmApprovalChanges asks a y/N question on the review input
+func confirmApprovalChanges(in *bufio.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	line, _ := in.ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+const testRuntimeApprovals = `{"version":1,"defaults":{},"agents":{
+  "main":{"allowlist":[{"pattern":"/usr/bin/curl"},{"pattern":"/usr/bin/rm","lastUsedCommand":"rm -rf /tmp/x"},{"pattern":"/usr/bin/jq"}]},
+  "ops":{"allowlist":[{"pattern":"/usr/bin/kubectl","id":"k1"}]}}}`
+
+const testBaselineApprovals = `{"version":1,"defaults":{},"agents":{"main":{"allowlist":[{"pattern":"/usr/bin/curl"}]}}}`
+
+func testApprovalDocs(t *testing.T) (map[string]any, map[string]any) {
+	t.Helper()
+	runtime, err := decodeApprovalsDocument([]byte(testRuntimeApprovals))
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseline, err := decodeApprovalsDocument([]byte(testBaselineApprovals))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime, baseline
+}
+
+func approvalPatterns(doc map[string]any, agent string) string {
+	var patterns []string
+	for _, item := range approvalsAllowlist(doc, agent) {
+		patterns = append(patterns, allowlistPattern(item))
+	}
+	return strings.Join(patterns, ",")
+}
+
+func TestPendingApprovalEntries(t *testing.T) {
+	runtime, baseline := testApprovalDocs(t)
+	entries := pendingApprovalEntries(runtime, baseline)
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Agent+" "+e.Pattern)
+	}
+	want := "main /usr/bin/rm,main /usr/bin/jq,ops /usr/bin/kubectl"
+	if strings.Join(got, ",") != want {
+		t.Errorf("pendingApprovalEntries() = %v, want %s", got, want)
+	}
+	if entries[0].LastUsedCommand != "rm -rf /tmp/x" {
+		t.Errorf("LastUsedCommand = %q", entries[0].LastUsedCommand)
+	}
+
+	empty, _ := decodeApprovalsDocument(nil)
+	if n := len(pendingApprovalEntries(runtime, empty)); n != 4 {
+		t.Errorf("without a baseline got %d pending entries, want 4", n)
+	}
+}
+
+func TestApplyApprovalDecisions(t *testing.T) {
+	runtime, baseline := testApprovalDocs(t)
+	entries := pendingApprovalEntries(runtime, baseline)
+	decisions := []approvalDecision{approvalDenied, approvalPending, approvalApproved}
+
+	runtimeChanged, baselineChanged := applyApprovalDecisions(runtime, baseline, entries, decisions)
+	if !runtimeChanged || !baselineChanged {
+		t.Fatalf("changed = %v/%v, want true/true", runtimeChanged, baselineChanged)
+	}
+	if got := approvalPatterns(runtime, "main"); got != "/usr/bin/curl,/usr/bin/jq" {
+		t.Errorf("runtime main = %s", got)
+	}
+	if got := approvalPatterns(runtime, "ops"); got != "/usr/bin/kubectl" {
+		t.Errorf("runtime ops = %s", got)
+	}
+	if got := approvalPatterns(baseline, "ops"); got != "/usr/bin/kubectl" {
+		t.Errorf("baseline ops = %s", got)
+	}
+	if got := approvalPatterns(baseline, "main"); got != "/usr/bin/curl" {
+		t.Errorf("baseline main = %s, skipped entries must not be added", got)
+	}
+}
+
+func TestReviewApprovalEntries(t *testing.T) {
+	entries := []approvalEntry{{Agent: "main", Pattern: "a"}, {Agent: "main", Pattern: "b"}, {Agent: "ops", Pattern: "c"}}
+	tests := []struct {
+		name    string
+		input   string
+		want    []approvalDecision
+		wantErr error
+	}{
+		{"all answered", "a\nd\ns\n", []approvalDecision{approvalApproved, approvalDenied, approvalPending}, nil},
+		{"back and unknown", "d\nb\nwhat\na\nd\nd\n", []approvalDecision{approvalApproved, approvalDenied, approvalDenied}, nil},
+		{"quit keeps decisions", "d\nq\n", []approvalDecision{approvalDenied, approvalPending, approvalPending}, nil},
+		{"eof", "a", []approvalDecision{approvalApproved, approvalPending, approvalPending}, nil},
+		{"exit aborts", "a\nx\n", nil, errApprovalsReviewAborted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := reviewApprovalEntries(bufio.NewReader(strings.NewReader(tt.input)), io.Discard, entries)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("reviewApprovalEntries() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("decisions = %q, want %q", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
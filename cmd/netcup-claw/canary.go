@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/mfittko/netcup-kube/internal/waitfor"
+)
+
+const (
+	// canarySuffix names the canary Deployment and its selector label values
+	canarySuffix = "-canary"
+	// canaryLabel marks canary Deployments and pods
+	canaryLabel = "netcup-claw/canary"
+
+	defaultCanaryTimeout = 5 * time.Minute
+	defaultCanarySoak    = 30 * time.Second
+)
+
+var (
+	upgradeCanary        bool
+	upgradeCanaryTimeout time.Duration
+	upgradeCanarySoak    time.Duration
+)
+
+// runCanary deploys the Deployment named like the release, rendered from the
+// target chart version with the release's current values (and the extra helm
+// args of the upgrade, e.g. a digest pin), as a one-replica canary outside
+// the Service selector. It fails unless the canary becomes ready and stays
+// ready without restarts for the soak period. The canary is removed
+// afterwards either way.
+func runCanary(ctx context.Context, namespace, release, chartRef, version string, extraArgs []string) error {
+	values, err := helmOutput(ctx, "get", "values", release, "-n", namespace, "-o", "yaml")
+	if err != nil {
+		return fmt.Errorf("failed to read values of release %s: %w", release, err)
+	}
+	deploy, err := renderReleaseDeployment(ctx, namespace, release, chartRef, version, values, extraArgs...)
+	if err != nil {
+		return err
+	}
+	selector, err := makeCanaryDeployment(deploy)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(deploy)
+	if err != nil {
+		return err
+	}
+
+	name := release + canarySuffix
+	fmt.Printf("\ndeploying canary deployment/%s (chart %s)...\n", name, version)
+	defer func() {
+		// Also after Ctrl-C, so an interrupted check leaves no canary behind
+		ctx, cancel := interrupt.CleanupContext(ctx, cleanupTimeout)
+		defer cancel()
+		fmt.Printf("removing canary deployment/%s\n", name)
+		if _, err := kubectlRunner.Output(ctx, "-n", namespace, "delete", "deployment", name, "--ignore-not-found", "--wait=false"); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to remove canary deployment/%s: %v\n", name, err)
+		}
+	}()
+	if err := applyKubectlStdin(ctx, payload, "-n", namespace, "apply", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to deploy canary: %w", err)
+	}
+
+	progress := func(detail string) { fmt.Printf("waiting for deployment/%s: %s\n", name, detail) }
+	if err := waitfor.Poll(ctx, waitfor.DeploymentReady(kubectlRunner, namespace, name),
+		upgradeCanaryTimeout, waitfor.DefaultBackoff, progress); err != nil {
+		return fmt.Errorf("canary did not become ready: %w", err)
+	}
+	if upgradeCanarySoak > 0 {
+		fmt.Printf("canary ready; checking it stays healthy for %s...\n", upgradeCanarySoak)
+		select {
+		case <-time.After(upgradeCanarySoak):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	ready, detail, err := waitfor.PodsReady(kubectlRunner, namespace, selector)(ctx)
+	if err != nil {
+		return err
+	}
+	if !ready {
+		return fmt.Errorf("canary unhealthy after %s: %s", upgradeCanarySoak, detail)
+	}
+	out, err := runKubectlOutput(ctx, "-n", namespace, "get", "pods", "-l", selector, "-o", "json")
+	if err != nil {
+		return fmt.Errorf("failed to read canary pods: %w", err)
+	}
+	restarts, err := podRestarts(out)
+	if err != nil {
+		return err
+	}
+	if restarts > 0 {
+		return fmt.Errorf("canary containers restarted %d time(s)", restarts)
+	}
+	fmt.Println("canary healthy")
+	return nil
+}
+
+// renderReleaseDeployment renders the chart version with values (YAML or
+// JSON) and extra helm args and returns the Deployment named like the release
+func renderReleaseDeployment(ctx context.Context, namespace, release, chartRef, version string, values []byte, extraArgs ...string) (map[string]any, error) {
+	var rendered bytes.Buffer
+	args := append([]string{"template", release, chartRef, "--version", version, "-n", namespace, "-f", "-"}, extraArgs...)
+	tmpl := interrupt.Command(ctx, "helm", args...)
+	tmpl.Stdin = bytes.NewReader(values)
+	tmpl.Stdout = &rendered
+	tmpl.Stderr = os.Stderr
+	if err := helmRun(ctx, tmpl); err != nil {
+		return nil, fmt.Errorf("helm template failed: %w", err)
+	}
+	docs := manifestDocsOfKind(rendered.String(), "Deployment")
+	if docs == "" {
+		return nil, fmt.Errorf("chart %s %s renders no Deployment", chartRef, version)
+	}
+
+	// Let kubectl turn the YAML into JSON rather than parsing it here
+	var converted bytes.Buffer
+	if err := kubectlRunner.Run(ctx, kubectl.Streams{
+		Stdin:  strings.NewReader(docs),
+		Stdout: &converted,
+		Stderr: os.Stderr,
+	}, "-n", namespace, "create", "--dry-run=client", "-o", "json", "-f", "-"); err != nil {
+		return nil, fmt.Errorf("failed to read rendered deployments: %w", err)
+	}
+	return findRenderedDeployment(converted.Bytes(), release)
+}
+
+// manifestDocsOfKind returns the documents of a multi-document YAML stream
+// whose top-level kind is kind, joined as a new stream
+func manifestDocsOfKind(stream, kind string) string {
+	var docs []string
+	for _, doc := range strings.Split("\n"+stream, "\n---") {
+		for _, line := range strings.Split(doc, "\n") {
+			if strings.TrimRight(line, " ") == "kind: "+kind {
+				docs = append(docs, strings.TrimLeft(doc, "\n"))
+				break
+			}
+		}
+	}
+	if len(docs) == 0 {
+		return ""
+	}
+	return strings.Join(docs, "\n---\n")
+}
+
+// findRenderedDeployment returns the Deployment named name from kubectl JSON
+// output, either a single object or a List
+func findRenderedDeployment(data []byte, name string) (map[string]any, error) {
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("failed to parse rendered deployments: %w", err)
+	}
+	items := []any{obj}
+	if obj["kind"] == "List" {
+		items, _ = obj["items"].([]any)
+	}
+	for _, item := range items {
+		d, _ := item.(map[string]any)
+		meta, _ := d["metadata"].(map[string]any)
+		if d["kind"] == "Deployment" && meta["name"] == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("chart renders no Deployment named %s", name)
+}
+
+// makeCanaryDeployment turns a rendered Deployment into its canary: renamed,
+// one replica, selector label values suffixed so neither the Service nor the
+// release's Deployment selects its pods, and PersistentVolumeClaims replaced
+// by emptyDir volumes so the release's state is not shared. It returns the
+// label selector of the canary pods.
+func makeCanaryDeployment(d map[string]any) (string, error) {
+	meta, _ := d["metadata"].(map[string]any)
+	spec, _ := d["spec"].(map[string]any)
+	selector, _ := spec["selector"].(map[string]any)
+	matchLabels, _ := selector["matchLabels"].(map[string]any)
+	template, _ := spec["template"].(map[string]any)
+	if meta == nil || template == nil || len(matchLabels) == 0 {
+		return "", fmt.Errorf("deployment has no metadata, pod template, or selector labels")
+	}
+	tmplMeta, _ := template["metadata"].(map[string]any)
+	if tmplMeta == nil {
+		tmplMeta = map[string]any{}
+		template["metadata"] = tmplMeta
+	}
+	podLabels, _ := tmplMeta["labels"].(map[string]any)
+	if podLabels == nil {
+		podLabels = map[string]any{}
+		tmplMeta["labels"] = podLabels
+	}
+
+	meta["name"] = fmt.Sprint(meta["name"]) + canarySuffix
+	labels, _ := meta["labels"].(map[string]any)
+	if labels == nil {
+		labels = map[string]any{}
+		meta["labels"] = labels
+	}
+	labels[canaryLabel] = "true"
+	spec["replicas"] = 1
+
+	var terms []string
+	for key, value := range matchLabels {
+		canary := fmt.Sprint(value) + canarySuffix
+		matchLabels[key] = canary
+		podLabels[key] = canary
+		terms = append(terms, key+"="+canary)
+	}
+	podLabels[canaryLabel] = "true"
+	sort.Strings(terms)
+
+	podSpec, _ := template["spec"].(map[string]any)
+	volumes, _ := podSpec["volumes"].([]any)
+	for i, v := range volumes {
+		vol, _ := v.(map[string]any)
+		if _, ok := vol["persistentVolumeClaim"]; ok {
+			volumes[i] = map[string]any{"name": vol["name"], "emptyDir": map[string]any{}}
+		}
+	}
+	return strings.Join(terms, ","), nil
+}
+
+// podRestarts sums the container restart counts of a kubectl pod list
+func podRestarts(data []byte) (int, error) {
+	var pods struct {
+		Items []struct {
+			Status struct {
+				ContainerStatuses []struct {
+					RestartCount int `json:"restartCount"`
+				} `json:"containerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &pods); err != nil {
+		return 0, fmt.Errorf("failed to parse canary pods: %w", err)
+	}
+	total := 0
+	for _, p := range pods.Items {
+		for _, c := range p.Status.ContainerStatuses {
+			total += c.RestartCount
+		}
+	}
+	return total, nil
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradeCanary, "canary", false, "Check the new version in a one-replica canary Deployment before upgrading")
+	upgradeCmd.Flags().DurationVar(&upgradeCanaryTimeout, "canary-timeout", defaultCanaryTimeout, "How long the canary may take to become ready")
+	upgradeCmd.Flags().DurationVar(&upgradeCanarySoak, "canary-soak", defaultCanarySoak, "How long the ready canary must stay healthy without restarts")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestManifestDocsOfKind(t *testing.T) {
+	stream := `---
+# Source: openclaw/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: openclaw
+---
+# Source: openclaw/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: openclaw
+spec:
+  template:
+    spec:
+      containers:
+        - name: main
+          env:
+            - name: KIND
+              value: "kind: Deployment"
+`
+	got := manifestDocsOfKind(stream, "Deployment")
+	if strings.Contains(got, "kind: Service") || !strings.Contains(got, "name: openclaw") {
+		t.Errorf("manifestDocsOfKind() = %q", got)
+	}
+	if manifestDocsOfKind(stream, "StatefulSet") != "" {
+		t.Error("manifestDocsOfKind(StatefulSet) expected no documents")
+	}
+}
+
+func TestMakeCanaryDeployment(t *testing.T) {
+	list := `{"kind":"List","items":[
+	  {"kind":"Deployment","metadata":{"name":"openclaw-sidecar"}},
+	  {"kind":"Deployment","metadata":{"name":"openclaw","labels":{"app":"openclaw"}},
+	   "spec":{"replicas":2,
+	     "selector":{"matchLabels":{"app.kubernetes.io/name":"openclaw","app.kubernetes.io/instance":"openclaw"}},
+	     "template":{"metadata":{"labels":{"app.kubernetes.io/name":"openclaw","app.kubernetes.io/instance":"openclaw","tier":"app"}},
+	       "spec":{"volumes":[{"name":"data","persistentVolumeClaim":{"claimName":"openclaw"}},{"name":"config","configMap":{"name":"openclaw"}}]}}}}]}`
+	d, err := findRenderedDeployment([]byte(list), "openclaw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector, err := makeCanaryDeployment(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selector != "app.kubernetes.io/instance=openclaw-canary,app.kubernetes.io/name=openclaw-canary" {
+		t.Errorf("selector = %q", selector)
+	}
+
+	data, _ := json.Marshal(d)
+	for _, want := range []string{
+		`"name":"openclaw-canary"`,
+		`"replicas":1`,
+		`"netcup-claw/canary":"true"`,
+		`"tier":"app"`,
+		`{"emptyDir":{},"name":"data"}`,
+		`{"configMap":{"name":"openclaw"},"name":"config"}`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("canary %s lacks %s", data, want)
+		}
+	}
+	if strings.Contains(string(data), "claimName") {
+		t.Errorf("canary still mounts the release's PVC: %s", data)
+	}
+
+	if _, err := findRenderedDeployment([]byte(`{"kind":"Deployment","metadata":{"name":"other"}}`), "openclaw"); err == nil {
+		t.Error("findRenderedDeployment() expected error for missing deployment")
+	}
+}
+
+func TestPodRestarts(t *testing.T) {
+	out := `{"items":[{"status":{"containerStatuses":[{"restartCount":0},{"restartCount":2}]}},{"status":{"containerStatuses":[{"restartCount":1}]}}]}`
+	if got, err := podRestarts([]byte(out)); err != nil || got != 3 {
+		t.Errorf("podRestarts() = %d, %v", got, err)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
+)
+
+// configDeployAuditFile is the config deploy log in the state directory of
+// netcup-claw
+const configDeployAuditFile = "config-deploys.jsonl"
+
+// configDeployEntry is one recorded `config deploy`. The config is recorded
+// by path and SHA-256 only, since it may hold secrets.
+type configDeployEntry struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	File      string    `json:"file"`
+	SHA256    string    `json:"sha256"`
+	Backup    string    `json:"backup,omitempty"`
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// configDeployAuditPath returns the config deploy log
+// ($XDG_STATE_HOME/netcup-claw/config-deploys.jsonl)
+func configDeployAuditPath() string {
+	return filepath.Join(paths.StateDir("netcup-claw"), configDeployAuditFile)
+}
+
+// newConfigDeployEntry describes a deploy of payload from file
+func newConfigDeployEntry(namespace, file string, payload []byte) configDeployEntry {
+	sum := sha256.Sum256(payload)
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	return configDeployEntry{
+		Time:      time.Now().UTC(),
+		Namespace: namespace,
+		File:      file,
+		SHA256:    hex.EncodeToString(sum[:]),
+	}
+}
+
+// appendConfigDeployEntry appends e to the log at path
+func appendConfigDeployEntry(path string, e configDeployEntry) error {
+	if err := paths.EnsurePrivateDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// loadConfigDeployEntries reads the log, oldest first, skipping lines that
+// cannot be parsed; a missing log has no entries
+func loadConfigDeployEntries(path string) ([]configDeployEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []configDeployEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e configDeployEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Time.IsZero() {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/keyring"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/portforward"
+	"github.com/spf13/cobra"
+)
+
+// litellmHealthPath is the LiteLLM liveness endpoint, probed when the
+// configured URL has no path
+const litellmHealthPath = "/health/liveliness"
+
+var (
+	connectivityWebhooks []string
+	connectivityLiteLLM  string
+	connectivityTimeout  time.Duration
+	connectivityJSON     bool
+)
+
+// connectivityEndpoint is an integration OpenClaw depends on
+type connectivityEndpoint struct {
+	Name string
+	// Target is shown in the matrix; webhook URLs are cut to scheme and host
+	Target string
+	// URL is set for HTTP endpoints; TCP endpoints use Host and Port
+	URL  string
+	Host string
+	Port string
+	// Fire posts a test alert instead of a GET
+	Fire bool
+	// Namespace and Service name the cluster Service the laptop reaches
+	// through a port-forward; both are empty outside the cluster
+	Namespace string
+	Service   string
+	// Skip is why the endpoint is not probed
+	Skip string
+}
+
+// connectivityProbe is the result of one endpoint from one side
+type connectivityProbe struct {
+	State     output.HealthState `json:"state"`
+	Detail    string             `json:"detail,omitempty"`
+	LatencyMS int64              `json:"latency_ms"`
+}
+
+// connectivityRow is one line of the matrix
+type connectivityRow struct {
+	Endpoint string            `json:"endpoint"`
+	Target   string            `json:"target"`
+	Pod      connectivityProbe `json:"pod"`
+	Laptop   connectivityProbe `json:"laptop"`
+}
+
+var connectivityCmd = &cobra.Command{
+	Use:   "connectivity",
+	Short: "Check the integrations OpenClaw depends on",
+	Long: `Check the integrations OpenClaw depends on.
+
+Sub-commands:
+  test  - Probe every configured endpoint from the pod and from the laptop`,
+}
+
+var connectivityTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Probe webhook sinks, LiteLLM, Postgres and Redis from the pod and the laptop",
+	Long: `Probe every configured integration endpoint twice and print a pass/fail
+matrix:
+
+  pod     from inside the OpenClaw pod (kubectl exec, node)
+  laptop  from this machine, through a temporary port-forward for cluster
+          services and directly for everything else
+
+Endpoints:
+  postgres, redis  the platform releases (skipped when not installed)
+  litellm          --litellm-url or OPENCLAW_LITELLM_URL; a URL without a path
+                   is probed at ` + litellmHealthPath + `
+  webhook          every --webhook, else OPENCLAW_ALERT_WEBHOOK (keyring:<name>
+                   references are resolved); a test alert is POSTed
+                   ({"source":"netcup-claw","test":true,...}) from each side
+
+Postgres and Redis are TCP checks; HTTP endpoints pass with a 2xx status.
+The command fails when any probe fails.
+
+Examples:
+  netcup-claw connectivity test
+  netcup-claw connectivity test --litellm-url http://litellm.platform.svc:4000
+  netcup-claw connectivity test --webhook https://hooks.example.com/claw --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if connectivityTimeout <= 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+		cfg, pod, err := resolveOpenClawPod(ctx)
+		if err != nil {
+			return err
+		}
+		endpoints, err := connectivityEndpoints(ctx)
+		if err != nil {
+			return err
+		}
+
+		podExec := func(ctx context.Context, script string) ([]byte, error) {
+			return kubectlRunner.Output(ctx, "-n", cfg.Namespace, "exec", "-c", openclawMainContainer, pod, "--", "node", "-e", script)
+		}
+		rows := make([]connectivityRow, 0, len(endpoints))
+		for _, e := range endpoints {
+			row := connectivityRow{Endpoint: e.Name, Target: e.Target}
+			if e.Skip != "" {
+				skipped := connectivityProbe{State: output.HealthSkip, Detail: e.Skip}
+				row.Pod, row.Laptop = skipped, skipped
+			} else {
+				row.Pod = runConnectivityProbe(func() error { return probeFromPod(ctx, podExec, e) })
+				row.Laptop = runConnectivityProbe(func() error { return probeFromLaptop(ctx, e) })
+			}
+			rows = append(rows, row)
+		}
+
+		if connectivityJSON {
+			if err := output.WriteJSON(os.Stdout, rows); err != nil {
+				return err
+			}
+		} else {
+			renderConnectivityMatrix(os.Stdout, rows)
+		}
+		if failed := countConnectivityFailures(rows); failed > 0 {
+			return fmt.Errorf("%d connectivity probe(s) failed", failed)
+		}
+		return nil
+	},
+}
+
+// connectivityEndpoints returns the endpoints to probe
+func connectivityEndpoints(ctx context.Context) ([]connectivityEndpoint, error) {
+	var endpoints []connectivityEndpoint
+	namespace := dbNamespaceOrDefault()
+	for _, target := range []dbTarget{postgresTarget(false), redisTarget()} {
+		svc := strings.TrimPrefix(target.FallbackSvc, "svc/")
+		e := connectivityEndpoint{
+			Name:      target.Name,
+			Host:      svc + "." + namespace + ".svc",
+			Port:      target.RemotePort,
+			Namespace: namespace,
+			Service:   svc,
+		}
+		e.Target = e.Host + ":" + e.Port
+		if _, err := kubectlRunner.Output(ctx, "-n", namespace, "get", "service", svc, "-o", "name"); err != nil {
+			if !strings.Contains(err.Error(), "NotFound") {
+				return nil, err
+			}
+			e.Skip = fmt.Sprintf("service %s/%s not installed", namespace, svc)
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	litellm := strings.TrimSpace(connectivityLiteLLM)
+	if litellm == "" {
+		litellm = strings.TrimSpace(os.Getenv("OPENCLAW_LITELLM_URL"))
+	}
+	if litellm == "" {
+		endpoints = append(endpoints, connectivityEndpoint{Name: "litellm", Target: "-", Skip: "not configured (--litellm-url or OPENCLAW_LITELLM_URL)"})
+	} else {
+		e, err := httpEndpoint("litellm", litellm, litellm)
+		if err != nil {
+			return nil, err
+		}
+		if u, _ := url.Parse(e.URL); u.Path == "" || u.Path == "/" {
+			u.Path = litellmHealthPath
+			e.URL = u.String()
+			e.Target = e.URL
+		}
+		endpoints = append(endpoints, e)
+	}
+
+	webhooks := connectivityWebhooks
+	if len(webhooks) == 0 {
+		if env := strings.TrimSpace(os.Getenv("OPENCLAW_ALERT_WEBHOOK")); env != "" {
+			webhooks = []string{env}
+		}
+	}
+	if len(webhooks) == 0 {
+		endpoints = append(endpoints, connectivityEndpoint{Name: "webhook", Target: "-", Skip: "not configured (--webhook or OPENCLAW_ALERT_WEBHOOK)"})
+	}
+	for _, raw := range webhooks {
+		resolved, err := keyring.Resolve(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("webhook: %w", err)
+		}
+		e, err := httpEndpoint("webhook", resolved, webhookDisplay(resolved))
+		if err != nil {
+			return nil, err
+		}
+		e.Fire = true
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, nil
+}
+
+// httpEndpoint returns the endpoint of an HTTP URL; a host of the form
+// <service>.<namespace>.svc[.cluster.local] is reached through a port-forward
+// from the laptop
+func httpEndpoint(name, raw, display string) (connectivityEndpoint, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return connectivityEndpoint{}, fmt.Errorf("%s: invalid URL %q (expected http(s)://host[:port]/path)", name, display)
+	}
+	e := connectivityEndpoint{Name: name, Target: display, URL: raw, Host: u.Hostname(), Port: u.Port()}
+	if e.Port == "" {
+		e.Port = "80"
+		if u.Scheme == "https" {
+			e.Port = "443"
+		}
+	}
+	e.Service, e.Namespace = clusterService(e.Host)
+	return e, nil
+}
+
+// clusterService splits a cluster-internal service host name into service
+// and namespace; both are empty for other hosts
+func clusterService(host string) (string, string) {
+	host = strings.TrimSuffix(strings.TrimSuffix(host, "."), ".cluster.local")
+	parts := strings.Split(host, ".")
+	if len(parts) != 3 || parts[2] != "svc" || parts[0] == "" || parts[1] == "" {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// webhookDisplay cuts a webhook URL to scheme and host; the path and query
+// of webhook URLs often carry the token
+func webhookDisplay(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "(invalid URL)"
+	}
+	return u.Scheme + "://" + u.Host + "/..."
+}
+
+// connectivityPayload is the test alert posted to webhook sinks
+func connectivityPayload(side string) []byte {
+	body, _ := json.Marshal(map[string]any{
+		"source":  "netcup-claw",
+		"test":    true,
+		"from":    side,
+		"message": "netcup-claw connectivity test",
+	})
+	return body
+}
+
+func runConnectivityProbe(probe func() error) connectivityProbe {
+	start := time.Now()
+	err := probe()
+	p := connectivityProbe{State: output.HealthOK, LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		p.State, p.Detail = output.HealthFail, err.Error()
+	}
+	return p
+}
+
+// probeFromPod runs a node script in the OpenClaw pod
+func probeFromPod(ctx context.Context, run func(context.Context, string) ([]byte, error), e connectivityEndpoint) error {
+	script := podTCPScript(e.Host, e.Port)
+	if e.URL != "" {
+		script = podHTTPScript(e.URL, e.Fire, connectivityTimeout)
+	}
+	_, err := run(ctx, script)
+	return err
+}
+
+// podHTTPScript returns a node script that requests target (POSTing the test
+// alert when fire is set) and fails unless the status is 2xx
+func podHTTPScript(target string, fire bool, timeout time.Duration) string {
+	opts := map[string]any{"method": "GET"}
+	if fire {
+		opts = map[string]any{"method": "POST", "headers": map[string]string{"Content-Type": "application/json"}, "body": string(connectivityPayload("pod"))}
+	}
+	u, _ := json.Marshal(target)
+	o, _ := json.Marshal(opts)
+	return fmt.Sprintf(`fetch(%s,Object.assign(%s,{signal:AbortSignal.timeout(%d)})).then(r=>{if(r.ok){process.exit(0)}console.error("HTTP "+r.status);process.exit(1)}).catch(e=>{console.error(e.cause?e.cause.message||e.message:e.message);process.exit(1)})`, u, o, timeout.Milliseconds())
+}
+
+// podTCPScript returns a node script that opens a TCP connection to host:port
+func podTCPScript(host, port string) string {
+	return fmt.Sprintf(`const s=require("net").connect(%s,%q,()=>{s.end();process.exit(0)});s.setTimeout(3000,()=>{console.error("timeout");process.exit(1)});s.on("error",e=>{console.error(e.message);process.exit(1)})`, port, host)
+}
+
+// probeFromLaptop connects from this machine, through a temporary
+// port-forward for cluster services
+func probeFromLaptop(ctx context.Context, e connectivityEndpoint) error {
+	addr := net.JoinHostPort(e.Host, e.Port)
+	if e.Service != "" {
+		local, stop, err := connectivityForward(ctx, e)
+		if err != nil {
+			return err
+		}
+		defer stop()
+		addr = net.JoinHostPort("127.0.0.1", local)
+	}
+	if e.URL == "" {
+		conn, err := net.DialTimeout("tcp", addr, connectivityTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	return laptopHTTPProbe(ctx, e, addr)
+}
+
+// laptopHTTPProbe requests the endpoint at addr, keeping the original host
+// name for the Host header and TLS
+func laptopHTTPProbe(ctx context.Context, e connectivityEndpoint, addr string) error {
+	method, body := http.MethodGet, io.Reader(nil)
+	if e.Fire {
+		method, body = http.MethodPost, bytes.NewReader(connectivityPayload("laptop"))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, e.URL, body)
+	if err != nil {
+		return err
+	}
+	if e.Fire {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	dialer := &net.Dialer{Timeout: connectivityTimeout}
+	transport := &http.Transport{DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}}
+	defer transport.CloseIdleConnections()
+	resp, err := (&http.Client{Timeout: connectivityTimeout, Transport: transport}).Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// connectivityForward starts a port-forward to the endpoint's service on a
+// free local port and returns the port and a function that stops it
+func connectivityForward(ctx context.Context, e connectivityEndpoint) (string, func(), error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, fmt.Errorf("no free local port: %w", err)
+	}
+	local := strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+	_ = ln.Close()
+
+	mgr := portforward.New(e.Namespace, "svc/"+e.Service, local, e.Port)
+	if err := mgr.Start(); err != nil {
+		return "", nil, fmt.Errorf("port-forward to svc/%s: %w", e.Service, err)
+	}
+	stop := func() {
+		if err := mgr.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to stop the port-forward to svc/%s: %v\n", e.Service, err)
+		}
+	}
+	if err := portforward.WaitReady(ctx, local, 5*time.Second); err != nil {
+		stop()
+		return "", nil, fmt.Errorf("port-forward to svc/%s: %w", e.Service, err)
+	}
+	return local, stop, nil
+}
+
+func renderConnectivityMatrix(w io.Writer, rows []connectivityRow) {
+	cell := func(p connectivityProbe) string {
+		if p.State == output.HealthSkip {
+			return string(p.State)
+		}
+		return fmt.Sprintf("%s %dms", p.State, p.LatencyMS)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ENDPOINT\tTARGET\tPOD\tLAPTOP")
+	for _, r := range rows {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Endpoint, r.Target, cell(r.Pod), cell(r.Laptop))
+	}
+	_ = tw.Flush()
+
+	var notes []string
+	for _, r := range rows {
+		if r.Pod.State == output.HealthSkip {
+			notes = append(notes, fmt.Sprintf("  %s: skipped, %s", r.Endpoint, r.Pod.Detail))
+			continue
+		}
+		for _, side := range []struct {
+			name  string
+			probe connectivityProbe
+		}{{"pod", r.Pod}, {"laptop", r.Laptop}} {
+			if side.probe.State == output.HealthFail {
+				notes = append(notes, fmt.Sprintf("  %s (%s): %s", r.Endpoint, side.name, side.probe.Detail))
+			}
+		}
+	}
+	if len(notes) > 0 {
+		_, _ = fmt.Fprintf(w, "\n%s\n", strings.Join(notes, "\n"))
+	}
+}
+
+func countConnectivityFailures(rows []connectivityRow) int {
+	n := 0
+	for _, r := range rows {
+		for _, p := range []connectivityProbe{r.Pod, r.Laptop} {
+			if p.State == output.HealthFail {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func init() {
+	connectivityTestCmd.Flags().StringArrayVar(&connectivityWebhooks, "webhook", nil, "Webhook sink to test-fire (repeatable; default: OPENCLAW_ALERT_WEBHOOK)")
+	connectivityTestCmd.Flags().StringVar(&connectivityLiteLLM, "litellm-url", "", "LiteLLM base URL as OpenClaw uses it (default: OPENCLAW_LITELLM_URL)")
+	connectivityTestCmd.Flags().DurationVar(&connectivityTimeout, "timeout", 5*time.Second, "Timeout of each probe")
+	connectivityTestCmd.Flags().BoolVar(&connectivityJSON, "json", false, "Print the matrix as JSON")
+	connectivityCmd.AddCommand(connectivityTestCmd)
+	rootCmd.AddCommand(connectivityCmd)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+)
+
+func TestClusterService(t *testing.T) {
+	for host, want := range map[string][2]string{
+		"litellm.platform.svc":                {"litellm", "platform"},
+		"litellm.platform.svc.cluster.local":  {"litellm", "platform"},
+		"litellm.platform.svc.cluster.local.": {"litellm", "platform"},
+		"hooks.example.com":                   {"", ""},
+		"litellm":                             {"", ""},
+	} {
+		svc, ns := clusterService(host)
+		if svc != want[0] || ns != want[1] {
+			t.Errorf("clusterService(%q) = %q, %q; want %q, %q", host, svc, ns, want[0], want[1])
+		}
+	}
+}
+
+func TestHTTPEndpoint(t *testing.T) {
+	e, err := httpEndpoint("webhook", "https://hooks.example.com/T0/B1/secret", webhookDisplay("https://hooks.example.com/T0/B1/secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Port != "443" || e.Service != "" || e.Target != "https://hooks.example.com/..." {
+		t.Errorf("httpEndpoint() = %+v", e)
+	}
+	e, err = httpEndpoint("litellm", "http://litellm.platform.svc:4000", "")
+	if err != nil || e.Port != "4000" || e.Service != "litellm" || e.Namespace != "platform" {
+		t.Errorf("httpEndpoint() = %+v, %v", e, err)
+	}
+	if _, err := httpEndpoint("litellm", "litellm:4000", "litellm:4000"); err == nil {
+		t.Error("httpEndpoint() accepted a URL without scheme")
+	}
+}
+
+func TestPodHTTPScript(t *testing.T) {
+	script := podHTTPScript("https://hooks.example.com/a\"b", true, 5*time.Second)
+	for _, want := range []string{`fetch("https://hooks.example.com/a\"b"`, `"method":"POST"`, `\"test\":true`, `AbortSignal.timeout(5000)`} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script lacks %q:\n%s", want, script)
+		}
+	}
+	if script := podHTTPScript("http://litellm.platform.svc:4000/health/liveliness", false, time.Second); !strings.Contains(script, `"method":"GET"`) {
+		t.Errorf("GET script = %s", script)
+	}
+}
+
+func TestLaptopHTTPProbe(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	saved := connectivityTimeout
+	connectivityTimeout = 2 * time.Second
+	t.Cleanup(func() { connectivityTimeout = saved })
+
+	e, err := httpEndpoint("webhook", srv.URL+"/hook", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Fire = true
+	u, _ := url.Parse(srv.URL)
+	if err := laptopHTTPProbe(context.Background(), e, u.Host); err != nil {
+		t.Fatal(err)
+	}
+	if got["test"] != true || got["from"] != "laptop" {
+		t.Errorf("payload = %v", got)
+	}
+
+	e.Fire = false
+	if err := laptopHTTPProbe(context.Background(), e, u.Host); err == nil || err.Error() != "HTTP 405" {
+		t.Errorf("GET error = %v", err)
+	}
+}
+
+func TestRenderConnectivityMatrix(t *testing.T) {
+	rows := []connectivityRow{
+		{Endpoint: "postgres", Target: "postgres-postgresql.platform.svc:5432", Pod: connectivityProbe{State: output.HealthOK, LatencyMS: 12}, Laptop: connectivityProbe{State: output.HealthFail, Detail: "connection refused", LatencyMS: 3}},
+		{Endpoint: "litellm", Target: "-", Pod: connectivityProbe{State: output.HealthSkip, Detail: "not configured"}, Laptop: connectivityProbe{State: output.HealthSkip, Detail: "not configured"}},
+	}
+	var buf bytes.Buffer
+	renderConnectivityMatrix(&buf, rows)
+	for _, want := range []string{
+		"ENDPOINT  TARGET",
+		"postgres  postgres-postgresql.platform.svc:5432  ok 12ms  fail 3ms",
+		"  postgres (laptop): connection refused",
+		"  litellm: skipped, not configured",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("matrix lacks %q:\n%s", want, buf.String())
+		}
+	}
+	if n := countConnectivityFailures(rows); n != 1 {
+		t.Errorf("countConnectivityFailures() = %d", n)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/clawcontext"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	contextFlag       string
+	ctxUseNamespace   string
+	ctxUseLocalPort   string
+	ctxUseRemotePort  string
+	ctxUseKubeconfig  string
+	ctxListJSON       bool
+	activeContextName string
+)
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Switch between named OpenClaw installs",
+	Long: `Contexts persist the tunnel host, namespace, ports and kubeconfig path of
+an OpenClaw install under a name, like kubectl contexts. Every command then
+runs against the current context as if its settings were exported as
+TUNNEL_*, OPENCLAW_NAMESPACE, OPENCLAW_LOCAL_PORT, OPENCLAW_REMOTE_PORT and
+KUBECONFIG; they replace those environment variables, flags still win.
+
+Contexts are stored in ~/.config/netcup-claw/contexts.json (override with
+NETCUP_CLAW_CONTEXTS). The global --context flag or NETCUP_CLAW_CONTEXT
+selects a context for one invocation without switching.
+
+Sub-commands:
+  use      - Switch to a context, creating or updating it from flags
+  list     - Show all contexts
+  current  - Print the current context
+  delete   - Remove a context
+
+Examples:
+  netcup-claw context use prod --tunnel-host ops.example.com --kubeconfig ~/.kube/prod.yaml
+  netcup-claw context use staging --tunnel-host staging.example.com --namespace openclaw-staging --local-port 28789
+  netcup-claw context use prod
+  netcup-claw --context staging status`,
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch to a context, creating or updating it from flags",
+	Long: `Make <name> the current context. Settings given as flags (--tunnel-*,
+--namespace, --local-port, --remote-port, --kubeconfig) are saved into the
+context first, creating it if needed; settings not given are kept.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if err := clawcontext.ValidateName(name); err != nil {
+			return err
+		}
+		update, err := contextUpdateFromFlags()
+		if err != nil {
+			return err
+		}
+		path := clawcontext.DefaultPath()
+		file, err := clawcontext.Load(path)
+		if err != nil {
+			return err
+		}
+
+		existing, exists := file.Contexts[name]
+		if !exists && update == (clawcontext.Context{}) {
+			return fmt.Errorf("context %q not found; pass settings such as --tunnel-host to create it", name)
+		}
+		file.Contexts[name] = existing.Merge(update)
+		file.Current = name
+		if err := file.Save(path); err != nil {
+			return err
+		}
+
+		switch {
+		case !exists:
+			fmt.Printf("created context %q and switched to it\n", name)
+		case update != (clawcontext.Context{}):
+			fmt.Printf("updated context %q and switched to it\n", name)
+		default:
+			fmt.Printf("switched to context %q\n", name)
+		}
+		return nil
+	},
+}
+
+var contextListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "Show all contexts",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, err := clawcontext.Load(clawcontext.DefaultPath())
+		if err != nil {
+			return err
+		}
+		if ctxListJSON {
+			return output.WriteJSON(os.Stdout, file)
+		}
+		if len(file.Contexts) == 0 {
+			fmt.Println("no contexts defined; create one with: netcup-claw context use <name> --tunnel-host <host>")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "CURRENT\tNAME\tTUNNEL\tNAMESPACE\tLOCAL PORT\tKUBECONFIG")
+		for _, name := range file.Names() {
+			c := file.Contexts[name]
+			current := ""
+			if name == file.Current {
+				current = "*"
+			}
+			tunnel := "-"
+			if c.TunnelHost != "" {
+				tunnel = c.TunnelHost
+				if c.TunnelUser != "" {
+					tunnel = c.TunnelUser + "@" + tunnel
+				}
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", current, name, tunnel,
+				orDash(c.Namespace), orDash(c.LocalPort), orDash(c.Kubeconfig))
+		}
+		return w.Flush()
+	},
+}
+
+var contextCurrentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Print the current context",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, err := selectedContextName()
+		if err != nil {
+			return err
+		}
+		if name == "" {
+			return fmt.Errorf("no current context (set one with: netcup-claw context use <name>)")
+		}
+		fmt.Println(name)
+		return nil
+	},
+}
+
+var contextDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Remove a context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		path := clawcontext.DefaultPath()
+		file, err := clawcontext.Load(path)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Lookup(name); err != nil {
+			return err
+		}
+		delete(file.Contexts, name)
+		if file.Current == name {
+			file.Current = ""
+		}
+		if err := file.Save(path); err != nil {
+			return err
+		}
+		fmt.Printf("deleted context %q\n", name)
+		return nil
+	},
+}
+
+// contextUpdateFromFlags collects the settings passed to "context use"
+func contextUpdateFromFlags() (clawcontext.Context, error) {
+	update := clawcontext.Context{
+		TunnelHost:        tunHost,
+		TunnelUser:        tunUser,
+		TunnelLocalPort:   tunLocalPort,
+		TunnelRemoteHost:  tunRemoteHost,
+		TunnelRemotePort:  tunRemotePort,
+		TunnelBindAddress: tunBindAddr,
+		Namespace:         ctxUseNamespace,
+		LocalPort:         ctxUseLocalPort,
+		RemotePort:        ctxUseRemotePort,
+	}
+	if ctxUseKubeconfig != "" {
+		abs, err := filepath.Abs(ctxUseKubeconfig)
+		if err != nil {
+			return update, fmt.Errorf("failed to resolve kubeconfig path: %w", err)
+		}
+		if _, err := os.Stat(abs); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: kubeconfig %s: %v\n", abs, err)
+		}
+		update.Kubeconfig = abs
+	}
+	return update, nil
+}
+
+// selectedContextName returns the context of this invocation: --context,
+// else NETCUP_CLAW_CONTEXT, else the current context of the contexts file
+func selectedContextName() (string, error) {
+	if contextFlag != "" {
+		return contextFlag, nil
+	}
+	if name := os.Getenv("NETCUP_CLAW_CONTEXT"); name != "" {
+		return name, nil
+	}
+	file, err := clawcontext.Load(clawcontext.DefaultPath())
+	if err != nil {
+		return "", err
+	}
+	return file.Current, nil
+}
+
+// applyContext exports the settings of the selected context as environment
+// variables, which the flag/env lookups of every command then pick up
+func applyContext() error {
+	name, err := selectedContextName()
+	if err != nil || name == "" {
+		return err
+	}
+	file, err := clawcontext.Load(clawcontext.DefaultPath())
+	if err != nil {
+		return err
+	}
+	c, err := file.Lookup(name)
+	if err != nil {
+		return err
+	}
+	for key, value := range c.Env() {
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to apply context %q: %w", name, err)
+		}
+	}
+	activeContextName = name
+	return nil
+}
+
+// isContextCommand reports whether cmd manages contexts, which must work
+// even when the current context is broken
+func isContextCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == contextCmd {
+			return true
+		}
+	}
+	return false
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&contextFlag, "context", "", "Context to use for this invocation (default: $NETCUP_CLAW_CONTEXT or the current context)")
+	contextUseCmd.Flags().StringVarP(&ctxUseNamespace, "namespace", "n", "", "OpenClaw namespace of the context")
+	contextUseCmd.Flags().StringVar(&ctxUseLocalPort, "local-port", "", "Local port-forward port of the context")
+	contextUseCmd.Flags().StringVar(&ctxUseRemotePort, "remote-port", "", "Remote OpenClaw port of the context")
+	contextUseCmd.Flags().StringVar(&ctxUseKubeconfig, "kubeconfig", "", "Kubeconfig path of the context")
+	contextListCmd.Flags().BoolVar(&ctxListJSON, "json", false, "Print the contexts file as JSON")
+	contextCmd.AddCommand(contextUseCmd)
+	contextCmd.AddCommand(contextListCmd)
+	contextCmd.AddCommand(contextCurrentCmd)
+	contextCmd.AddCommand(contextDeleteCmd)
+	rootCmd.AddCommand(contextCmd)
+}
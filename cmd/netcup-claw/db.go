@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/openclaw"
+	"github.com/mfittko/netcup-kube/internal/portforward"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbNamespace   string
+	dbLocalPort   string
+	dbAdmin       bool
+	dbDatabase    string
+	dbKeepForward bool
+)
+
+// dbTarget describes a platform database service installed by the postgres/redis recipes.
+type dbTarget struct {
+	Name          string
+	LabelSelector string
+	FallbackSvc   string
+	RemotePort    string
+	LocalPort     string
+	SecretName    string
+	SecretKey     string
+	Client        string
+}
+
+// postgresTarget returns the lookup parameters for the Bitnami PostgreSQL release
+// installed by `netcup-kube install postgres`.
+func postgresTarget(admin bool) dbTarget {
+	key := "password"
+	if admin {
+		key = "postgres-password"
+	}
+	return dbTarget{
+		Name:          "postgres",
+		LabelSelector: "app.kubernetes.io/instance=postgres,app.kubernetes.io/component=primary",
+		FallbackSvc:   "svc/postgres-postgresql",
+		RemotePort:    "5432",
+		LocalPort:     "15432",
+		SecretName:    "postgres-postgresql",
+		SecretKey:     key,
+		Client:        "psql",
+	}
+}
+
+// redisTarget returns the lookup parameters for the Bitnami Redis release
+// installed by `netcup-kube install redis`.
+func redisTarget() dbTarget {
+	return dbTarget{
+		Name:          "redis",
+		LabelSelector: "app.kubernetes.io/instance=redis,app.kubernetes.io/component=master",
+		FallbackSvc:   "svc/redis-master",
+		RemotePort:    "6379",
+		LocalPort:     "16379",
+		SecretName:    "redis",
+		SecretKey:     "redis-password",
+		Client:        "redis-cli",
+	}
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Connect to platform Postgres/Redis through a managed port-forward",
+	Long: `Open a database client against the platform Postgres/Redis releases.
+
+Each sub-command resolves the service, reads credentials from the release
+secret, starts a port-forward on a dedicated local port, and launches the
+local client with the password passed via environment (never argv).
+
+Sub-commands:
+  psql       - Launch psql against postgres-postgresql
+  redis-cli  - Launch redis-cli against redis-master`,
+}
+
+var dbPsqlCmd = &cobra.Command{
+	Use:   "psql [-- psql args...]",
+	Short: "Launch psql against the platform PostgreSQL release",
+	Long: `Launch psql against the platform PostgreSQL release.
+
+Examples:
+  netcup-claw db psql
+  netcup-claw db psql --admin
+  netcup-claw db psql -- -c 'select version()'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		target := postgresTarget(dbAdmin)
+		user := "app"
+		if dbAdmin {
+			user = "postgres"
+		}
+		return runDBClient(ctx, target, func(localPort, password string) *exec.Cmd {
+			c := interrupt.Command(ctx, target.Client, buildPsqlArgs(localPort, user, dbDatabase, args)...)
+			c.Env = append(os.Environ(), "PGPASSWORD="+password)
+			return c
+		})
+	},
+}
+
+var dbRedisCLICmd = &cobra.Command{
+	Use:   "redis-cli [-- redis-cli args...]",
+	Short: "Launch redis-cli against the platform Redis release",
+	Long: `Launch redis-cli against the platform Redis release.
+
+Examples:
+  netcup-claw db redis-cli
+  netcup-claw db redis-cli -- info server`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		target := redisTarget()
+		return runDBClient(ctx, target, func(localPort, password string) *exec.Cmd {
+			c := interrupt.Command(ctx, target.Client, buildRedisCLIArgs(localPort, args)...)
+			c.Env = append(os.Environ(), "REDISCLI_AUTH="+password)
+			return c
+		})
+	},
+}
+
+// buildPsqlArgs builds psql arguments for a localhost port-forward connection.
+func buildPsqlArgs(localPort, user, database string, extra []string) []string {
+	args := []string{"-h", "127.0.0.1", "-p", localPort, "-U", user}
+	if strings.TrimSpace(database) != "" {
+		args = append(args, "-d", database)
+	}
+	return append(args, extra...)
+}
+
+// buildRedisCLIArgs builds redis-cli arguments for a localhost port-forward connection.
+func buildRedisCLIArgs(localPort string, extra []string) []string {
+	args := []string{"-h", "127.0.0.1", "-p", localPort}
+	return append(args, extra...)
+}
+
+// decodeSecretValue decodes a base64 value returned by a jsonpath secret lookup.
+func decodeSecretValue(raw []byte) (string, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" {
+		return "", fmt.Errorf("secret value is empty")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(trimmed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret value: %w", err)
+	}
+	return string(decoded), nil
+}
+
+func fetchSecretValue(ctx context.Context, namespace, name, key string) (string, error) {
+	out, err := runKubectlOutput(
+		ctx,
+		"-n", namespace,
+		"get", "secret", name,
+		"-o", "jsonpath={.data."+strings.ReplaceAll(key, ".", "\\.")+"}",
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s/%s: %w", namespace, name, err)
+	}
+	value, err := decodeSecretValue(out)
+	if err != nil {
+		return "", fmt.Errorf("secret %s/%s key %s: %w", namespace, name, key, err)
+	}
+	return value, nil
+}
+
+func dbNamespaceOrDefault() string {
+	if ns := strings.TrimSpace(dbNamespace); ns != "" {
+		return ns
+	}
+	if ns := strings.TrimSpace(os.Getenv("NAMESPACE_PLATFORM")); ns != "" {
+		return ns
+	}
+	return "platform"
+}
+
+// runDBClient resolves the target service, reads its credentials, ensures a
+// port-forward, and runs the client built by newClient with stdio attached.
+func runDBClient(ctx context.Context, target dbTarget, newClient func(localPort, password string) *exec.Cmd) error {
+	if _, err := exec.LookPath(target.Client); err != nil {
+		return fmt.Errorf("%s not found in PATH; install it locally to use this command", target.Client)
+	}
+
+	if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+		return err
+	}
+
+	namespace := dbNamespaceOrDefault()
+	localPort := target.LocalPort
+	if strings.TrimSpace(dbLocalPort) != "" {
+		localPort = strings.TrimSpace(dbLocalPort)
+	}
+
+	resolver := openclaw.New(openclaw.Config{
+		Namespace:     namespace,
+		LabelSelector: target.LabelSelector,
+		FallbackSvc:   target.FallbackSvc,
+		LocalPort:     localPort,
+		RemotePort:    target.RemotePort,
+	}, kubectlExec)
+	svc, err := resolver.ResolveService(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s service: %w", target.Name, err)
+	}
+
+	password, err := fetchSecretValue(ctx, namespace, target.SecretName, target.SecretKey)
+	if err != nil {
+		return err
+	}
+
+	mgr := portforward.New(namespace, svc, localPort, target.RemotePort)
+	alreadyRunning := mgr.Status().State == portforward.StateRunning
+	if err := mgr.Start(); err != nil {
+		return fmt.Errorf("failed to start %s port-forward: %w", target.Name, err)
+	}
+	if !alreadyRunning && !dbKeepForward {
+		defer func() {
+			if stopErr := mgr.Stop(); stopErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to stop %s port-forward: %v\n", target.Name, stopErr)
+			}
+		}()
+	}
+	if err := portforward.WaitReady(ctx, localPort, 5*time.Second); err != nil {
+		return fmt.Errorf("%s port-forward not ready: %w", target.Name, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "connected: localhost:%s -> %s in namespace %s\n", localPort, svc, namespace)
+
+	client := newClient(localPort, password)
+	client.Stdin = os.Stdin
+	client.Stdout = os.Stdout
+	client.Stderr = os.Stderr
+	if err := client.Run(); err != nil {
+		return fmt.Errorf("%s exited with error: %w", target.Client, err)
+	}
+	return nil
+}
+
+func init() {
+	dbCmd.PersistentFlags().StringVarP(&dbNamespace, "namespace", "n", "", "Namespace of the platform release (default: $NAMESPACE_PLATFORM or platform)")
+	dbCmd.PersistentFlags().StringVar(&dbLocalPort, "local-port", "", "Local port for the port-forward (default: 15432 for psql, 16379 for redis-cli)")
+	dbCmd.PersistentFlags().BoolVar(&dbKeepForward, "keep-forward", false, "Leave the port-forward running after the client exits")
+	dbPsqlCmd.Flags().BoolVar(&dbAdmin, "admin", false, "Connect as the postgres superuser instead of the app user")
+	dbPsqlCmd.Flags().StringVar(&dbDatabase, "database", "app", "Database name to connect to")
+	dbCmd.AddCommand(dbPsqlCmd)
+	dbCmd.AddCommand(dbRedisCLICmd)
+	rootCmd.AddCommand(dbCmd)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+)
+
+func TestBuildPsqlArgs(t *testing.T) {
+	got := buildPsqlArgs("15432", "app", "app", []string{"-c", "select 1"})
+	want := []string{"-h", "127.0.0.1", "-p", "15432", "-U", "app", "-d", "app", "-c", "select 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildPsqlArgs() = %v, want %v", got, want)
+	}
+
+	got = buildPsqlArgs("15432", "postgres", " ", nil)
+	want = []string{"-h", "127.0.0.1", "-p", "15432", "-U", "postgres"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildPsqlArgs() without database = %v, want %v", got, want)
+	}
+}
+
+func TestBuildRedisCLIArgs(t *testing.T) {
+	got := buildRedisCLIArgs("16379", []string{"info", "server"})
+	want := []string{"-h", "127.0.0.1", "-p", "16379", "info", "server"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("buildRedisCLIArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeSecretValue(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("s3cret"))
+	got, err := decodeSecretValue([]byte(encoded + "\n"))
+	if err != nil {
+		t.Fatalf("decodeSecretValue() error: %v", err)
+	}
+	if got != "s3cret" {
+		t.Fatalf("decodeSecretValue() = %q, want %q", got, "s3cret")
+	}
+
+	if _, err := decodeSecretValue([]byte("  ")); err == nil {
+		t.Fatal("decodeSecretValue() expected error for empty value")
+	}
+	if _, err := decodeSecretValue([]byte("not base64!")); err == nil {
+		t.Fatal("decodeSecretValue() expected error for invalid base64")
+	}
+}
+
+func TestPostgresTargetAdminKey(t *testing.T) {
+	if got := postgresTarget(false).SecretKey; got != "password" {
+		t.Fatalf("postgresTarget(false).SecretKey = %q, want password", got)
+	}
+	if got := postgresTarget(true).SecretKey; got != "postgres-password" {
+		t.Fatalf("postgresTarget(true).SecretKey = %q, want postgres-password", got)
+	}
+}
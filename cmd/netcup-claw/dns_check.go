@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dnsCheckPort      string
+	dnsCheckTimeout   time.Duration
+	dnsCheckPod       string
+	dnsCheckContainer string
+	dnsCheckJSON      bool
+)
+
+// dnsCheckScript runs in the pod with the arguments PORT TIMEOUT NAME...: it
+// prints the resolver configuration, resolves every NAME (getent, else node)
+// and connects to the first NAME when PORT is set (nc, else node)
+const dnsCheckScript = `port=$1; t=$2; shift 2
+grep -E '^(search|nameserver) ' /etc/resolv.conf 2>/dev/null | sed 's/^/resolv /'
+for name in "$@"; do
+  if command -v getent >/dev/null 2>&1; then
+    echo "resolve $name getent $(getent hosts "$name" | awk '{print $1}' | tr '\n' ' ')"
+  elif command -v node >/dev/null 2>&1; then
+    echo "resolve $name node $(node -e 'require("dns").lookup(process.argv[1],{all:true},(e,a)=>{if(!e)console.log(a.map(x=>x.address).join(" "))})' "$name")"
+  else
+    echo "resolve $name none"
+  fi
+done
+[ -n "$port" ] || exit 0
+if command -v nc >/dev/null 2>&1; then
+  tool=nc; out=$(nc -z -w "$t" "$1" "$port" 2>&1)
+elif command -v node >/dev/null 2>&1; then
+  tool=node; out=$(node -e 'const s=require("net").connect(+process.argv[2],process.argv[1],()=>{s.end();process.exit(0)});s.setTimeout(process.argv[3]*1000,()=>{console.error("timeout");process.exit(1)});s.on("error",e=>{console.error(e.message);process.exit(1)})' "$1" "$port" "$t" 2>&1)
+else
+  echo "tcp none"; exit 0
+fi
+if [ $? -eq 0 ]; then echo "tcp $tool ok"; else echo "tcp $tool fail $(echo "$out" | tr '\n' ' ')"; fi
+`
+
+// dnsCheckTarget is the service the check is about
+type dnsCheckTarget struct {
+	// Name is the host name as given, the way an application would use it
+	Name string `json:"name"`
+	// FQDN, Service and Namespace are set for cluster service names
+	FQDN      string `json:"fqdn,omitempty"`
+	Service   string `json:"service,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Port      string `json:"port,omitempty"`
+}
+
+// dnsServiceInfo is the Service as the API server knows it
+type dnsServiceInfo struct {
+	Exists         bool     `json:"exists"`
+	ClusterIP      string   `json:"cluster_ip,omitempty"`
+	Ports          []string `json:"ports,omitempty"`
+	ReadyEndpoints int      `json:"ready_endpoints"`
+}
+
+// dnsLookup is the resolution of one name inside the pod
+type dnsLookup struct {
+	Name      string   `json:"name"`
+	Tool      string   `json:"tool"`
+	Addresses []string `json:"addresses"`
+}
+
+// dnsTCPResult is the connection test inside the pod
+type dnsTCPResult struct {
+	Address string `json:"address"`
+	Tool    string `json:"tool"`
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+}
+
+// dnsCheckResult is the report of dns-check
+type dnsCheckResult struct {
+	Target      dnsCheckTarget  `json:"target"`
+	Pod         string          `json:"pod"`
+	Service     *dnsServiceInfo `json:"service,omitempty"`
+	Search      []string        `json:"search"`
+	Nameservers []string        `json:"nameservers"`
+	Lookups     []dnsLookup     `json:"lookups"`
+	TCP         *dnsTCPResult   `json:"tcp,omitempty"`
+	Hints       []string        `json:"hints,omitempty"`
+}
+
+// ok reports whether every name resolved and the connection succeeded
+func (r dnsCheckResult) ok() bool {
+	for _, l := range r.Lookups {
+		if len(l.Addresses) == 0 {
+			return false
+		}
+	}
+	return r.TCP == nil || r.TCP.OK
+}
+
+var dnsCheckCmd = &cobra.Command{
+	Use:   "dns-check <service>[:port]",
+	Short: "Debug service discovery: resolve and connect to a service from inside the OpenClaw pod",
+	Long: `Resolve a service name and open a TCP connection to it from inside the
+OpenClaw main container, the way the application would, e.g. when the pod
+"cannot reach postgres-postgresql.platform".
+
+<service> is a host name as the application uses it: <name> (in the OpenClaw
+namespace), <name>.<namespace>, the full <name>.<namespace>.svc.cluster.local,
+or any other host name. The port comes from --port, a :port suffix or the
+first port of the Service; without one only resolution is checked.
+
+Inside the pod the check uses getent for resolution and nc for the
+connection, falling back to node when a tool is missing. It prints the pod's
+resolver search domains and nameservers, the addresses of the name as given
+and of the FQDN, and compares them with the Service's cluster IP and ready
+endpoints, with hints for the usual causes.
+
+Examples:
+  netcup-claw dns-check postgres-postgresql.platform
+  netcup-claw dns-check redis-master.platform:6379
+  netcup-claw dns-check litellm.platform --port 4000 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if dnsCheckTimeout <= 0 {
+			return fmt.Errorf("--timeout must be positive")
+		}
+		cfg, pod, err := resolveTargetPod(ctx, dnsCheckPod)
+		if err != nil {
+			return err
+		}
+		target, err := parseDNSCheckTarget(args[0], cfg.Namespace)
+		if err != nil {
+			return err
+		}
+		if dnsCheckPort != "" {
+			target.Port = dnsCheckPort
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, 4*dnsCheckTimeout+30*time.Second)
+		defer cancel()
+		res := dnsCheckResult{Target: target, Pod: pod}
+		if target.Service != "" {
+			info, err := lookupDNSCheckService(ctx, target)
+			if err != nil {
+				return err
+			}
+			res.Service = info
+			if res.Target.Port == "" && len(info.Ports) > 0 {
+				res.Target.Port = info.Ports[0]
+			}
+		}
+
+		names := []string{target.Name}
+		if target.FQDN != "" && target.FQDN != target.Name {
+			names = append(names, target.FQDN)
+		}
+		scriptArgs := append([]string{"--", "sh", "-c", dnsCheckScript, "sh", res.Target.Port, strconv.Itoa(int(dnsCheckTimeout.Round(time.Second).Seconds()))}, names...)
+		out, err := kubectlRunner.Output(ctx, append([]string{"-n", cfg.Namespace, "exec", "-c", dnsCheckContainer, pod}, scriptArgs...)...)
+		if err != nil {
+			return fmt.Errorf("failed to run the check in pod %s: %w", pod, err)
+		}
+		parseDNSCheckOutput(&res, string(out))
+		res.Hints = dnsCheckHints(res)
+
+		if dnsCheckJSON {
+			if err := output.WriteJSON(os.Stdout, res); err != nil {
+				return err
+			}
+		} else {
+			renderDNSCheck(os.Stdout, res)
+		}
+		if !res.ok() {
+			return fmt.Errorf("%s is not reachable from pod %s", target.Name, pod)
+		}
+		return nil
+	},
+}
+
+// parseDNSCheckTarget splits a <service>[:port] argument; names of the forms
+// name, name.namespace and name.namespace.svc[.cluster.local] are cluster
+// services, longer names are checked without a Service lookup
+func parseDNSCheckTarget(arg, namespace string) (dnsCheckTarget, error) {
+	arg = strings.TrimPrefix(strings.TrimSpace(arg), "svc/")
+	var t dnsCheckTarget
+	t.Name = arg
+	if host, port, err := net.SplitHostPort(arg); err == nil {
+		t.Name, t.Port = host, port
+	}
+	if t.Name == "" {
+		return t, fmt.Errorf("invalid service %q", arg)
+	}
+	if t.Port != "" {
+		if n, err := strconv.Atoi(t.Port); err != nil || n < 1 || n > 65535 {
+			return t, fmt.Errorf("invalid port %q", t.Port)
+		}
+	}
+
+	short := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(t.Name, "."), ".cluster.local"), ".svc")
+	parts := strings.Split(short, ".")
+	switch {
+	case len(parts) == 1:
+		t.Service, t.Namespace = parts[0], namespace
+	case len(parts) == 2:
+		t.Service, t.Namespace = parts[0], parts[1]
+	}
+	if t.Service != "" {
+		t.FQDN = t.Service + "." + t.Namespace + ".svc.cluster.local"
+	}
+	return t, nil
+}
+
+// lookupDNSCheckService reads the Service and its ready endpoints
+func lookupDNSCheckService(ctx context.Context, t dnsCheckTarget) (*dnsServiceInfo, error) {
+	info := &dnsServiceInfo{}
+	out, err := kubectlRunner.Output(ctx, "-n", t.Namespace, "get", "service", t.Service, "-o", "json")
+	if err != nil {
+		if strings.Contains(err.Error(), "NotFound") {
+			return info, nil
+		}
+		return nil, fmt.Errorf("failed to read service %s/%s: %w", t.Namespace, t.Service, err)
+	}
+	var svc struct {
+		Spec struct {
+			ClusterIP string `json:"clusterIP"`
+			Ports     []struct {
+				Port int `json:"port"`
+			} `json:"ports"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(out, &svc); err != nil {
+		return nil, fmt.Errorf("failed to parse service %s/%s: %w", t.Namespace, t.Service, err)
+	}
+	info.Exists = true
+	info.ClusterIP = svc.Spec.ClusterIP
+	for _, p := range svc.Spec.Ports {
+		info.Ports = append(info.Ports, strconv.Itoa(p.Port))
+	}
+
+	out, err = kubectlRunner.Output(ctx, "-n", t.Namespace, "get", "endpointslices", "-l", "kubernetes.io/service-name="+t.Service, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endpoints of %s/%s: %w", t.Namespace, t.Service, err)
+	}
+	var eps struct {
+		Items []struct {
+			Endpoints []struct {
+				Conditions struct {
+					Ready *bool `json:"ready"`
+				} `json:"conditions"`
+			} `json:"endpoints"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &eps); err != nil {
+		return nil, fmt.Errorf("failed to parse endpoints of %s/%s: %w", t.Namespace, t.Service, err)
+	}
+	for _, s := range eps.Items {
+		for _, e := range s.Endpoints {
+			// A missing condition means ready
+			if e.Conditions.Ready == nil || *e.Conditions.Ready {
+				info.ReadyEndpoints++
+			}
+		}
+	}
+	return info, nil
+}
+
+// parseDNSCheckOutput reads the lines printed by dnsCheckScript
+func parseDNSCheckOutput(res *dnsCheckResult, out string) {
+	res.Search, res.Nameservers, res.Lookups = []string{}, []string{}, []dnsLookup{}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "resolv":
+			if fields[1] == "search" {
+				res.Search = append(res.Search, fields[2:]...)
+			} else if fields[1] == "nameserver" {
+				res.Nameservers = append(res.Nameservers, fields[2:]...)
+			}
+		case "resolve":
+			if len(fields) < 3 {
+				continue
+			}
+			res.Lookups = append(res.Lookups, dnsLookup{Name: fields[1], Tool: fields[2], Addresses: append([]string{}, fields[3:]...)})
+		case "tcp":
+			tcp := &dnsTCPResult{Address: net.JoinHostPort(res.Target.Name, res.Target.Port), Tool: fields[1]}
+			if fields[1] == "none" {
+				tcp.Error = "neither nc nor node is available in the container"
+			} else if len(fields) > 2 && fields[2] == "ok" {
+				tcp.OK = true
+			} else {
+				tcp.Error = strings.Join(fields[3:], " ")
+				if tcp.Error == "" {
+					tcp.Error = "connection failed"
+				}
+			}
+			res.TCP = tcp
+		}
+	}
+}
+
+// dnsCheckHints explains the usual causes of a failed check
+func dnsCheckHints(res dnsCheckResult) []string {
+	var hints []string
+	t := res.Target
+	if res.Service != nil && !res.Service.Exists {
+		hints = append(hints, fmt.Sprintf("service %s does not exist in namespace %s (kubectl get svc -A | grep %s)", t.Service, t.Namespace, t.Service))
+	}
+
+	var given, fqdn *dnsLookup
+	for i := range res.Lookups {
+		switch res.Lookups[i].Name {
+		case t.Name:
+			given = &res.Lookups[i]
+		case t.FQDN:
+			fqdn = &res.Lookups[i]
+		}
+	}
+	switch {
+	case given != nil && given.Tool == "none":
+		hints = append(hints, "neither getent nor node is available in the container to resolve names")
+	case given != nil && len(given.Addresses) == 0 && fqdn != nil && len(fqdn.Addresses) > 0:
+		hints = append(hints, fmt.Sprintf("%s does not resolve with the pod's search domains (%s); use %s", t.Name, strings.Join(res.Search, " "), t.FQDN))
+	case given != nil && len(given.Addresses) == 0 && res.Service != nil && res.Service.Exists:
+		hints = append(hints, "cluster DNS does not resolve an existing service; check CoreDNS (kubectl -n kube-system get pods -l k8s-app=kube-dns) and the pod's nameservers")
+	case given != nil && res.Service != nil && res.Service.ClusterIP != "" && res.Service.ClusterIP != "None" && len(given.Addresses) > 0 && !slices.Contains(given.Addresses, res.Service.ClusterIP):
+		hints = append(hints, fmt.Sprintf("%s resolves to %s, not to the service's cluster IP %s; check the search domains and ndots", t.Name, strings.Join(given.Addresses, " "), res.Service.ClusterIP))
+	}
+
+	if res.TCP != nil && !res.TCP.OK && res.Service != nil && res.Service.Exists {
+		switch {
+		case res.Service.ReadyEndpoints == 0:
+			hints = append(hints, fmt.Sprintf("service %s/%s has no ready endpoints; check its pods and selector", t.Namespace, t.Service))
+		case !slices.Contains(res.Service.Ports, t.Port):
+			hints = append(hints, fmt.Sprintf("port %s is not a port of the service (ports: %s)", t.Port, strings.Join(res.Service.Ports, ", ")))
+		default:
+			hints = append(hints, "the service has ready endpoints; a NetworkPolicy may block traffic from the OpenClaw namespace")
+		}
+	}
+	return hints
+}
+
+func renderDNSCheck(w io.Writer, res dnsCheckResult) {
+	t := res.Target
+	_, _ = fmt.Fprintf(w, "pod          %s\n", res.Pod)
+	if res.Service != nil {
+		if res.Service.Exists {
+			_, _ = fmt.Fprintf(w, "service      %s/%s: cluster IP %s, ports %s, %d ready endpoint(s)\n", t.Namespace, t.Service, res.Service.ClusterIP, orDash(strings.Join(res.Service.Ports, ",")), res.Service.ReadyEndpoints)
+		} else {
+			_, _ = fmt.Fprintf(w, "service      %s/%s: not found\n", t.Namespace, t.Service)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "search       %s\n", orDash(strings.Join(res.Search, " ")))
+	_, _ = fmt.Fprintf(w, "nameservers  %s\n", orDash(strings.Join(res.Nameservers, " ")))
+	for _, l := range res.Lookups {
+		result := "does not resolve"
+		if len(l.Addresses) > 0 {
+			result = strings.Join(l.Addresses, " ")
+		}
+		_, _ = fmt.Fprintf(w, "resolve      %s -> %s (%s)\n", l.Name, result, l.Tool)
+	}
+	switch {
+	case res.TCP == nil:
+		_, _ = fmt.Fprintln(w, "tcp          - (no port; use --port)")
+	case res.TCP.OK:
+		_, _ = fmt.Fprintf(w, "tcp          %s ok (%s)\n", res.TCP.Address, res.TCP.Tool)
+	default:
+		_, _ = fmt.Fprintf(w, "tcp          %s failed (%s): %s\n", res.TCP.Address, res.TCP.Tool, res.TCP.Error)
+	}
+	for _, h := range res.Hints {
+		_, _ = fmt.Fprintf(w, "hint: %s\n", h)
+	}
+}
+
+func init() {
+	dnsCheckCmd.Flags().StringVarP(&dnsCheckPort, "port", "p", "", "Port to connect to (default: the :port suffix or the first port of the Service)")
+	dnsCheckCmd.Flags().DurationVar(&dnsCheckTimeout, "timeout", 3*time.Second, "Connection timeout inside the pod")
+	dnsCheckCmd.Flags().StringVar(&dnsCheckPod, "pod", "", "Pod to run the check in (default: the resolved OpenClaw pod)")
+	dnsCheckCmd.Flags().StringVarP(&dnsCheckContainer, "container", "c", openclawMainContainer, "Container to run the check in")
+	dnsCheckCmd.Flags().BoolVar(&dnsCheckJSON, "json", false, "Print the report as JSON")
+	rootCmd.AddCommand(dnsCheckCmd)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseDNSCheckTarget(t *testing.T) {
+	for arg, want := range map[string]dnsCheckTarget{
+		"litellm":                      {Name: "litellm", Service: "litellm", Namespace: "openclaw", FQDN: "litellm.openclaw.svc.cluster.local"},
+		"postgres-postgresql.platform": {Name: "postgres-postgresql.platform", Service: "postgres-postgresql", Namespace: "platform", FQDN: "postgres-postgresql.platform.svc.cluster.local"},
+		"redis-master.platform.svc.cluster.local": {Name: "redis-master.platform.svc.cluster.local", Service: "redis-master", Namespace: "platform", FQDN: "redis-master.platform.svc.cluster.local"},
+		"svc/redis-master.platform:6379":          {Name: "redis-master.platform", Service: "redis-master", Namespace: "platform", FQDN: "redis-master.platform.svc.cluster.local", Port: "6379"},
+		"api.example.co.uk:443":                   {Name: "api.example.co.uk", Port: "443"},
+	} {
+		got, err := parseDNSCheckTarget(arg, "openclaw")
+		if err != nil || got != want {
+			t.Errorf("parseDNSCheckTarget(%q) = %+v, %v; want %+v", arg, got, err, want)
+		}
+	}
+	for _, arg := range []string{"", ":5432", "postgres:99999", "postgres:pg"} {
+		if _, err := parseDNSCheckTarget(arg, "openclaw"); err == nil {
+			t.Errorf("parseDNSCheckTarget(%q) succeeded", arg)
+		}
+	}
+}
+
+func TestDNSCheckReport(t *testing.T) {
+	target, _ := parseDNSCheckTarget("postgres-postgresql:5432", "platform")
+	res := dnsCheckResult{
+		Target:  target,
+		Pod:     "openclaw-0",
+		Service: &dnsServiceInfo{Exists: true, ClusterIP: "10.43.0.20", Ports: []string{"5432"}, ReadyEndpoints: 0},
+	}
+	parseDNSCheckOutput(&res, `resolv search openclaw.svc.cluster.local svc.cluster.local cluster.local
+resolv nameserver 10.43.0.10
+resolve postgres-postgresql getent 10.43.0.20 
+resolve postgres-postgresql.platform.svc.cluster.local getent 10.43.0.20
+tcp nc fail nc: connect to postgres-postgresql port 5432 (tcp) timed out
+`)
+	if len(res.Search) != 3 || res.Nameservers[0] != "10.43.0.10" || len(res.Lookups) != 2 || res.Lookups[0].Addresses[0] != "10.43.0.20" {
+		t.Fatalf("parsed = %+v", res)
+	}
+	if res.TCP == nil || res.TCP.OK || res.TCP.Address != "postgres-postgresql:5432" || !strings.HasPrefix(res.TCP.Error, "nc: connect") {
+		t.Fatalf("tcp = %+v", res.TCP)
+	}
+	if res.ok() {
+		t.Error("ok() = true for a failed connection")
+	}
+	res.Hints = dnsCheckHints(res)
+	if len(res.Hints) != 1 || !strings.Contains(res.Hints[0], "no ready endpoints") {
+		t.Errorf("hints = %q", res.Hints)
+	}
+
+	var buf bytes.Buffer
+	renderDNSCheck(&buf, res)
+	for _, want := range []string{
+		"service      platform/postgres-postgresql: cluster IP 10.43.0.20, ports 5432, 0 ready endpoint(s)",
+		"resolve      postgres-postgresql -> 10.43.0.20 (getent)",
+		"tcp          postgres-postgresql:5432 failed (nc): nc: connect",
+		"hint: service platform/postgres-postgresql has no ready endpoints",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestDNSCheckHints(t *testing.T) {
+	target, _ := parseDNSCheckTarget("litellm", "openclaw")
+	res := dnsCheckResult{Target: target, Service: &dnsServiceInfo{}, Search: []string{"default.svc.cluster.local"}}
+	parseDNSCheckOutput(&res, "resolv search default.svc.cluster.local\nresolve litellm getent \nresolve litellm.openclaw.svc.cluster.local getent\n")
+	hints := strings.Join(dnsCheckHints(res), "\n")
+	if !strings.Contains(hints, "does not exist in namespace openclaw") {
+		t.Errorf("hints = %q", hints)
+	}
+
+	res.Service = &dnsServiceInfo{Exists: true, ClusterIP: "10.43.0.30", Ports: []string{"4000"}, ReadyEndpoints: 1}
+	res.Lookups[1].Addresses = []string{"10.43.0.30"}
+	if hints := strings.Join(dnsCheckHints(res), "\n"); !strings.Contains(hints, "use litellm.openclaw.svc.cluster.local") {
+		t.Errorf("hints = %q", hints)
+	}
+}
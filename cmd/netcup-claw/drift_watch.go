@@ -0,0 +1,322 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/openclawapi"
+	"github.com/mfittko/netcup-kube/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+var (
+	driftWatchInterval      time.Duration
+	driftWatchOnce          bool
+	driftWatchAlertWebhook  string
+	driftWatchStateFile     string
+	driftWatchConfigFile    string
+	driftWatchApprovalsFile string
+)
+
+const (
+	driftInSync = "in-sync"
+	driftFound  = "drift"
+)
+
+// driftTarget is one workspace file compared against its deployed state
+type driftTarget struct {
+	Name string
+	Path string
+	// fetch returns the deployed payload, normalized like the local file
+	fetch func() ([]byte, error)
+	// normalize is applied to the local file before hashing; may be nil
+	normalize func([]byte) ([]byte, error)
+}
+
+// driftStatus is the result of one comparison, persisted between checks
+type driftStatus struct {
+	Target       string `json:"target"`
+	Path         string `json:"path"`
+	State        string `json:"state"`
+	LocalHash    string `json:"localHash"`
+	DeployedHash string `json:"deployedHash"`
+	Revision     string `json:"revision,omitempty"`
+	CheckedAt    string `json:"checkedAt"`
+}
+
+// driftWatchState is the last-known status per target
+type driftWatchState struct {
+	Targets map[string]driftStatus `json:"targets"`
+}
+
+var driftWatchCmd = &cobra.Command{
+	Use:   "drift-watch",
+	Short: "Periodically report drift between deployed and workspace config/approvals",
+	Long: `Run as a long-lived service that compares the deployed OpenClaw config
+(ConfigMap) and exec approvals (runtime) against the workspace checkout every
+--interval and reports drift.
+
+Payloads are compared by the SHA-256 of their canonical JSON, so formatting and
+key order do not count as drift. A line is printed, and with --alert-webhook
+(or OPENCLAW_ALERT_WEBHOOK) a JSON alert is POSTed, only when a target starts
+drifting, drifts to a different state, or returns in sync. Last-known hashes
+are kept in --state-file so restarts do not re-alert on known drift.
+
+Failed checks (tunnel down, pod restarting) are reported as warnings and
+retried on the next interval.
+
+Examples:
+  netcup-claw drift-watch
+  netcup-claw drift-watch --interval 15m --alert-webhook https://hooks.example.com/claw
+  netcup-claw drift-watch --once`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if driftWatchInterval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+		cfg := openclawConfig()
+		if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+			return err
+		}
+
+		webhook, err := alertWebhookFromFlag(driftWatchAlertWebhook)
+		if err != nil {
+			return err
+		}
+
+		statePath := driftWatchStateFile
+		if statePath == "" {
+			statePath = defaultDriftStateFile()
+		}
+		state, err := loadDriftWatchState(statePath)
+		if err != nil {
+			return err
+		}
+
+		configPath := strings.TrimSpace(driftWatchConfigFile)
+		if configPath == "" {
+			configPath = "scripts/recipes/openclaw/openclaw.json"
+		}
+		approvalsPath := strings.TrimSpace(driftWatchApprovalsFile)
+		if approvalsPath == "" {
+			approvalsPath = filepath.Join(localApprovalsWorkspaceDir(), "approvals.json")
+		}
+		targets := []driftTarget{
+			{
+				Name: "config",
+				Path: configPath,
+				fetch: func() ([]byte, error) {
+					return fetchDeployedConfig(ctx, cfg)
+				},
+			},
+			{
+				Name: "approvals",
+				Path: approvalsPath,
+				fetch: func() ([]byte, error) {
+					cfg, pod, err := resolveOpenClawPod(ctx)
+					if err != nil {
+						return nil, err
+					}
+					snapshot, err := openclawAPI(cfg.Namespace, pod).ApprovalsGet(ctx)
+					if err != nil {
+						return nil, err
+					}
+					return snapshot.File, nil
+				},
+				normalize: openclawapi.NormalizeApprovals,
+			},
+		}
+
+		check := func() error {
+			for _, target := range targets {
+				status, err := checkDrift(target, time.Now())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "warning: drift check %s: %v\n", target.Name, err)
+					continue
+				}
+				prev, seen := state.Targets[target.Name]
+				state.Targets[target.Name] = status
+				if !driftChanged(prev, seen, status) {
+					continue
+				}
+				fmt.Println(formatDriftStatus(status))
+				if webhook != nil {
+					if err := webhook.post(ctx, map[string]any{"source": "netcup-claw", "drift": status}); err != nil {
+						fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+					}
+				}
+			}
+			return saveDriftWatchState(statePath, state)
+		}
+
+		if err := check(); err != nil || driftWatchOnce {
+			return err
+		}
+		ticker := time.NewTicker(driftWatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := check(); err != nil {
+					return err
+				}
+			}
+		}
+	},
+}
+
+// checkDrift hashes the local file and the deployed payload of a target
+func checkDrift(target driftTarget, now time.Time) (driftStatus, error) {
+	local, err := os.ReadFile(target.Path)
+	if err != nil {
+		return driftStatus{}, fmt.Errorf("failed to read %s: %w", target.Path, err)
+	}
+	if target.normalize != nil {
+		if local, err = target.normalize(local); err != nil {
+			return driftStatus{}, err
+		}
+	}
+	localHash, err := canonicalJSONHash(local)
+	if err != nil {
+		return driftStatus{}, fmt.Errorf("%s: %w", target.Path, err)
+	}
+	deployed, err := target.fetch()
+	if err != nil {
+		return driftStatus{}, err
+	}
+	deployedHash, err := canonicalJSONHash(deployed)
+	if err != nil {
+		return driftStatus{}, fmt.Errorf("deployed %s: %w", target.Name, err)
+	}
+
+	status := driftStatus{
+		Target:       target.Name,
+		Path:         target.Path,
+		State:        driftInSync,
+		LocalHash:    localHash,
+		DeployedHash: deployedHash,
+		Revision:     workspaceRevision(target.Path),
+		CheckedAt:    now.UTC().Format(time.RFC3339),
+	}
+	if localHash != deployedHash {
+		status.State = driftFound
+	}
+	return status, nil
+}
+
+// canonicalJSONHash returns the SHA-256 of payload re-encoded with sorted
+// keys and no insignificant whitespace
+func canonicalJSONHash(payload []byte) (string, error) {
+	var decoded any
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	canonical, err := json.Marshal(decoded)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// driftChanged reports whether cur should be reported given the last-known
+// status: new or changed drift, or drift that was resolved. A first check
+// that is in sync is not reported.
+func driftChanged(prev driftStatus, seen bool, cur driftStatus) bool {
+	if cur.State == driftInSync {
+		return seen && prev.State == driftFound
+	}
+	return !seen || prev.State != driftFound ||
+		prev.LocalHash != cur.LocalHash || prev.DeployedHash != cur.DeployedHash
+}
+
+func formatDriftStatus(s driftStatus) string {
+	line := fmt.Sprintf("%s %s: %s (local %s, deployed %s, %s",
+		s.CheckedAt, s.Target, s.State, shortHash(s.LocalHash), shortHash(s.DeployedHash), s.Path)
+	if s.Revision != "" {
+		line += " @ " + s.Revision
+	}
+	return line + ")"
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// workspaceRevision returns the short git commit of the checkout holding
+// path, or "" when it is not in a git repository
+func workspaceRevision(path string) string {
+	out, err := exec.Command("git", "-C", filepath.Dir(path), "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// defaultDriftStateFile is drift-watch.json in the state directory of
+// netcup-claw ($XDG_STATE_HOME/netcup-claw, ~/.local/state/netcup-claw)
+func defaultDriftStateFile() string {
+	return filepath.Join(paths.StateDir("netcup-claw"), "drift-watch.json")
+}
+
+func loadDriftWatchState(path string) (*driftWatchState, error) {
+	state := &driftWatchState{Targets: map[string]driftStatus{}}
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read drift state %s: %w", path, err)
+	}
+	if err := json.Unmarshal(payload, state); err != nil {
+		return nil, fmt.Errorf("invalid drift state %s: %w", path, err)
+	}
+	if state.Targets == nil {
+		state.Targets = map[string]driftStatus{}
+	}
+	return state, nil
+}
+
+// saveDriftWatchState writes the state atomically so an interrupted write
+// never leaves a truncated file behind
+func saveDriftWatchState(path string, state *driftWatchState) error {
+	payload, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode drift state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create drift state directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(payload, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write drift state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write drift state: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	driftWatchCmd.Flags().DurationVar(&driftWatchInterval, "interval", 5*time.Minute, "Time between checks")
+	driftWatchCmd.Flags().BoolVar(&driftWatchOnce, "once", false, "Run a single check and exit")
+	driftWatchCmd.Flags().StringVar(&driftWatchAlertWebhook, "alert-webhook", "", "POST drift alerts as JSON to this URL (default: OPENCLAW_ALERT_WEBHOOK)")
+	driftWatchCmd.Flags().StringVar(&driftWatchStateFile, "state-file", "", "File holding last-known hashes (default: $XDG_STATE_HOME/netcup-claw/drift-watch.json)")
+	driftWatchCmd.Flags().StringVar(&driftWatchConfigFile, "config-file", "", "Workspace config file (default: scripts/recipes/openclaw/openclaw.json)")
+	driftWatchCmd.Flags().StringVar(&driftWatchApprovalsFile, "approvals-file", "", "Workspace approvals file (default: <approvals workspace-dir>/approvals.json)")
+	rootCmd.AddCommand(driftWatchCmd)
+}
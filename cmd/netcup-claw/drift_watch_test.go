@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/openclawapi"
+)
+
+func TestCanonicalJSONHashIgnoresFormatting(t *testing.T) {
+	a, err := canonicalJSONHash([]byte(`{"b": 1, "a": {"y": [1, 2], "x": true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := canonicalJSONHash([]byte("{\n  \"a\": {\"x\": true, \"y\": [1,2]},\n  \"b\": 1\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("hashes differ for equivalent JSON: %s != %s", a, b)
+	}
+	c, _ := canonicalJSONHash([]byte(`{"a": {"x": true, "y": [2, 1]}, "b": 1}`))
+	if a == c {
+		t.Error("expected different hash for reordered array")
+	}
+	if _, err := canonicalJSONHash([]byte(`{`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestDriftChanged(t *testing.T) {
+	inSync := driftStatus{State: driftInSync, LocalHash: "a", DeployedHash: "a"}
+	drift := driftStatus{State: driftFound, LocalHash: "a", DeployedHash: "b"}
+	driftAgain := driftStatus{State: driftFound, LocalHash: "a", DeployedHash: "c"}
+
+	tests := []struct {
+		name string
+		prev driftStatus
+		seen bool
+		cur  driftStatus
+		want bool
+	}{
+		{"first check in sync", driftStatus{}, false, inSync, false},
+		{"first check drift", driftStatus{}, false, drift, true},
+		{"still in sync", inSync, true, inSync, false},
+		{"new drift", inSync, true, drift, true},
+		{"same drift", drift, true, drift, false},
+		{"changed drift", drift, true, driftAgain, true},
+		{"resolved", drift, true, inSync, true},
+	}
+	for _, tt := range tests {
+		if got := driftChanged(tt.prev, tt.seen, tt.cur); got != tt.want {
+			t.Errorf("%s: driftChanged() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckDrift(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "approvals.json")
+	if err := os.WriteFile(path, []byte(`{"file": {"agents": {}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := driftTarget{
+		Name:      "approvals",
+		Path:      path,
+		fetch:     func() ([]byte, error) { return []byte(`{"agents":{}}`), nil },
+		normalize: openclawapi.NormalizeApprovals,
+	}
+	now := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	status, err := checkDrift(target, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != driftInSync || status.CheckedAt != "2026-10-16T10:00:00Z" {
+		t.Errorf("unexpected status: %+v", status)
+	}
+
+	target.fetch = func() ([]byte, error) { return []byte(`{"agents":{"main":{}}}`), nil }
+	if status, err = checkDrift(target, now); err != nil {
+		t.Fatal(err)
+	}
+	if status.State != driftFound || status.LocalHash == status.DeployedHash {
+		t.Errorf("expected drift, got %+v", status)
+	}
+}
+
+func TestDriftWatchStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "drift-watch.json")
+	state, err := loadDriftWatchState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Targets) != 0 {
+		t.Fatalf("expected empty state, got %+v", state)
+	}
+	state.Targets["config"] = driftStatus{Target: "config", State: driftFound, LocalHash: "a", DeployedHash: "b"}
+	if err := saveDriftWatchState(path, state); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadDriftWatchState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Targets["config"] != state.Targets["config"] {
+		t.Errorf("round trip mismatch: %+v", loaded.Targets["config"])
+	}
+}
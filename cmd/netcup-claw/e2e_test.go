@@ -0,0 +1,427 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/openclaw"
+	"github.com/mfittko/netcup-kube/internal/openclawapi"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
+	"github.com/mfittko/netcup-kube/internal/testkit"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The tests in this file run whole commands against the fake kubectl and
+// helm of internal/testkit, answering from the fixtures in testdata/e2e.
+
+func TestMain(m *testing.M) { testkit.Main(m) }
+
+// fixture returns the absolute path of a testdata/e2e fixture, which stays
+// valid after runClaw changes the working directory
+func fixture(t *testing.T, name string) string {
+	t.Helper()
+	path, err := filepath.Abs(filepath.Join("testdata", "e2e", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// runClaw runs netcup-claw with args in workDir, isolated from the contexts,
+// hooks and caches of the user running the tests
+func runClaw(t *testing.T, workDir string, args ...string) error {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(home, ".cache"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, ".local", "state"))
+	t.Setenv("NETCUP_CLAW_CONTEXTS", filepath.Join(home, "contexts.json"))
+	t.Setenv("NETCUP_CLAW_CONTEXT", "")
+	t.Setenv("HOOKS_DIR", filepath.Join(home, "hooks"))
+	t.Setenv("OPENCLAW_RESOLVE_CACHE_TTL", "0")
+	t.Setenv("OPENCLAW_NAMESPACE", "")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	rootCmd.SetArgs(args)
+	cmd, err := rootCmd.ExecuteC()
+	resetFlags(cmd)
+	return err
+}
+
+// resetFlags restores the flags of cmd and its parents, which cobra keeps
+// across Execute calls
+func resetFlags(cmd *cobra.Command) {
+	for c := cmd; c != nil; c = c.Parent() {
+		reset := func(f *pflag.Flag) {
+			if !f.Changed {
+				return
+			}
+			if s, ok := f.Value.(pflag.SliceValue); ok {
+				_ = s.Replace(nil)
+			} else {
+				_ = f.Value.Set(f.DefValue)
+			}
+			f.Changed = false
+		}
+		c.Flags().VisitAll(reset)
+		c.PersistentFlags().VisitAll(reset)
+	}
+}
+
+// callIndex returns the position of the first call matching args, -1 if none
+func callIndex(calls []testkit.Call, tool string, args ...string) int {
+	pattern := testkit.Rule{Tool: tool, Args: args}
+	for i, c := range calls {
+		if pattern.Matches(c.Tool, c.Args) {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestConfigDeployEndToEnd(t *testing.T) {
+	kit := testkit.New(t)
+	kit.Load(fixture(t, "config-deploy.json"))
+	work := t.TempDir()
+	input := filepath.Join(work, "openclaw.json")
+	if err := os.WriteFile(input, []byte(`{"gateway": {"port": 18789}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	backups := filepath.Join(work, "backup")
+
+	if err := runClaw(t, work, "config", "deploy", "--yes", "--file", input, "--backup-path", backups); err != nil {
+		t.Fatalf("config deploy: %v", err)
+	}
+
+	kit.AssertAllMatched()
+	calls := kit.Calls()
+	apply := callIndex(calls, "kubectl", "-n", "openclaw", "apply", "-f", testkit.Any)
+	restart := callIndex(calls, "kubectl", "-n", "openclaw", "rollout", "restart", "deployment/openclaw")
+	if apply < 0 || restart < apply {
+		t.Fatalf("want the ConfigMap applied before the restart, calls:\n%v", calls)
+	}
+	kit.AssertCalled("kubectl", "-n", "openclaw", "create", "configmap", "openclaw", "--from-file=openclaw.json="+input, testkit.AnyRest)
+
+	entries, err := os.ReadDir(backups)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("want one config backup in %s, got %v (%v)", backups, entries, err)
+	}
+	backup, err := os.ReadFile(filepath.Join(backups, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(backup), "18788") {
+		t.Errorf("backup does not hold the deployed config: %s", backup)
+	}
+
+	deploys, err := loadConfigDeployEntries(configDeployAuditPath())
+	if err != nil || len(deploys) != 1 || !deploys[0].Success || deploys[0].Backup != filepath.Join(backups, entries[0].Name()) {
+		t.Errorf("config deploy log = %+v, %v", deploys, err)
+	}
+}
+
+func TestConfigDeployStopsWhenApplyFails(t *testing.T) {
+	kit := testkit.New(t)
+	kit.Add(testkit.Rule{
+		Tool:   "kubectl",
+		Args:   []string{"-n", "openclaw", "apply", "-f", testkit.Any},
+		Stderr: "error: configmaps \"openclaw\" is forbidden\n",
+		Exit:   1,
+	})
+	kit.Load(fixture(t, "config-deploy.json"))
+	work := t.TempDir()
+	input := filepath.Join(work, "openclaw.json")
+	if err := os.WriteFile(input, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runClaw(t, work, "config", "deploy", "--yes", "--file", input, "--backup-path", "off")
+	if err == nil || !strings.Contains(err.Error(), "failed to apply configmap") {
+		t.Fatalf("want an apply error, got %v", err)
+	}
+	kit.AssertNotCalled("kubectl", "-n", "openclaw", "rollout", testkit.AnyRest)
+	kit.AssertNotCalled("kubectl", "-n", "openclaw", "get", "configmap", testkit.AnyRest)
+
+	deploys, err := loadConfigDeployEntries(configDeployAuditPath())
+	if err != nil || len(deploys) != 1 || deploys[0].Success || !strings.Contains(deploys[0].Error, "failed to apply configmap") {
+		t.Errorf("config deploy log = %+v, %v", deploys, err)
+	}
+}
+
+// markdownArchive returns a gzipped tar of files, as the workspace archive
+// script in the pod produces it
+func markdownArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAgentsBackupEndToEnd(t *testing.T) {
+	kit := testkit.New(t)
+	kit.Load(fixture(t, "agents-backup.json"))
+	archives := t.TempDir()
+	workspaces := map[string]map[string]string{
+		"main": {"AGENTS.md": "# main\n", "SOUL.md": "calm\n"},
+		"ops":  {"AGENTS.md": "# ops\n", "notes.txt": "not markdown\n"},
+	}
+	for id, files := range workspaces {
+		workspace := "/home/node/.openclaw/workspace"
+		if id != "main" {
+			workspace += "-" + id
+		}
+		archive := filepath.Join(archives, id+".tar.gz")
+		if err := os.WriteFile(archive, markdownArchive(t, files), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		kit.Add(testkit.Rule{
+			Tool:       "kubectl",
+			Args:       []string{"-n", "openclaw", "exec", "-c", "main", "openclaw-7d9f8b6c5-x2k4q", "--", "sh", "-lc", buildWorkspaceMarkdownTarScript(workspace)},
+			StdoutFile: archive,
+		})
+	}
+	work := t.TempDir()
+
+	if err := runClaw(t, work, "agents", "backup", "--workspace-dir", work); err != nil {
+		t.Fatalf("agents backup: %v", err)
+	}
+
+	kit.AssertAllMatched()
+	backup := filepath.Join(work, "backup")
+	for _, name := range []string{"agents.list.json", "main/AGENTS.md", "main/SOUL.md", "ops/AGENTS.md"} {
+		if _, err := os.Stat(filepath.Join(backup, name)); err != nil {
+			t.Errorf("missing backup file %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"ops/notes.txt", "scratch"} {
+		if _, err := os.Stat(filepath.Join(backup, name)); err == nil {
+			t.Errorf("unexpected backup file %s", name)
+		}
+	}
+}
+
+// upgradeWorkDir returns a working directory with a recipes.conf pinning the
+// OpenClaw chart at 1.3.0
+func upgradeWorkDir(t *testing.T) string {
+	t.Helper()
+	work := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(work, filepath.Dir(recipesConfRel)), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(work, recipesConfRel), []byte("CHART_VERSION_OPENCLAW=1.3.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return work
+}
+
+func TestUpgradeEndToEnd(t *testing.T) {
+	kit := testkit.New(t)
+	kit.Load(fixture(t, "upgrade.json"))
+	work := upgradeWorkDir(t)
+
+	if err := runClaw(t, work, "upgrade"); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+
+	kit.AssertAllMatched()
+	calls := kit.Calls()
+	manifest := callIndex(calls, "helm", "get", "manifest", "openclaw", "-n", "openclaw")
+	upgrade := callIndex(calls, "helm", "upgrade", "openclaw", "openclaw/openclaw", testkit.AnyRest)
+	if manifest < 0 || upgrade < manifest {
+		t.Fatalf("want the release backed up before the upgrade, calls:\n%v", calls)
+	}
+
+	conf, err := os.ReadFile(filepath.Join(work, recipesConfRel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(conf), "CHART_VERSION_OPENCLAW=1.4.0") {
+		t.Errorf("pin not updated: %s", conf)
+	}
+	backups, _ := filepath.Glob(filepath.Join(work, defaultHelmBackupRel, "openclaw", "openclaw", "pre-upgrade-*.json"))
+	if len(backups) != 1 {
+		t.Fatalf("want one release backup, got %v", backups)
+	}
+	backup, err := os.ReadFile(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(backup), "claw.example.com") || !strings.Contains(string(backup), "kind: Deployment") {
+		t.Errorf("backup lacks the values or manifest: %s", backup)
+	}
+}
+
+func TestUpgradeExportsTrace(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+	kit := testkit.New(t)
+	kit.Load(fixture(t, "upgrade.json"))
+	work := upgradeWorkDir(t)
+
+	args, timings := setupTelemetry([]string{"upgrade", "--timings"})
+	if !timings || len(args) != 1 {
+		t.Fatalf("setupTelemetry() = %v, %v", args, timings)
+	}
+	if err := runClaw(t, work, args...); err != nil {
+		t.Fatalf("upgrade: %v", err)
+	}
+	if err := telemetry.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+	for _, want := range []string{`"netcup-claw"`, `"helm upgrade"`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("exported trace lacks %s: %s", want, body)
+		}
+	}
+}
+
+func TestUpgradeKeepsPinWhenHelmUpgradeFails(t *testing.T) {
+	kit := testkit.New(t)
+	kit.Add(testkit.Rule{
+		Tool:   "helm",
+		Args:   []string{"upgrade", "openclaw", testkit.AnyRest},
+		Stderr: "Error: UPGRADE FAILED: context deadline exceeded\n",
+		Exit:   1,
+	})
+	kit.Load(fixture(t, "upgrade.json"))
+	work := upgradeWorkDir(t)
+
+	err := runClaw(t, work, "upgrade")
+	if err == nil || !strings.Contains(err.Error(), "helm upgrade failed") {
+		t.Fatalf("want a helm upgrade error, got %v", err)
+	}
+	kit.AssertNotCalled("kubectl", "-n", "openclaw", "get", "deployment", "openclaw", "-o", "json")
+	conf, err := os.ReadFile(filepath.Join(work, recipesConfRel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(conf), "CHART_VERSION_OPENCLAW=1.3.0") {
+		t.Errorf("pin changed after a failed upgrade: %s", conf)
+	}
+}
+
+func TestRollbackEndToEnd(t *testing.T) {
+	kit := testkit.New(t)
+	kit.Load(fixture(t, "rollback.json"))
+	work := upgradeWorkDir(t)
+	if err := os.WriteFile(filepath.Join(work, recipesConfRel), []byte("CHART_VERSION_OPENCLAW=1.4.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runClaw(t, work, "rollback", "--yes"); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+
+	kit.AssertAllMatched()
+	calls := kit.Calls()
+	manifest := callIndex(calls, "helm", "get", "manifest", "openclaw", "-n", "openclaw")
+	rollback := callIndex(calls, "helm", "rollback", "openclaw", "4", testkit.AnyRest)
+	if manifest < 0 || rollback < manifest {
+		t.Fatalf("want the release backed up before the rollback, calls:\n%v", calls)
+	}
+	conf, err := os.ReadFile(filepath.Join(work, recipesConfRel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(conf), "CHART_VERSION_OPENCLAW=1.3.0") {
+		t.Errorf("pin not set to the rolled back chart: %s", conf)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(work, defaultHelmBackupRel, "openclaw", "openclaw", "pre-rollback-*.json")); len(backups) != 1 {
+		t.Errorf("want one release backup, got %v", backups)
+	}
+}
+
+func TestNamespaceRestoreNeedsConfirmation(t *testing.T) {
+	kit := testkit.New(t)
+	t.Setenv("CONFIRM", "")
+	work := t.TempDir()
+	manifest, err := json.Marshal(snapshotManifest{Version: snapshotFormatVersion, Namespace: "openclaw", ConfigMaps: []string{"openclaw"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	members := map[string][]byte{
+		snapshotManifestFile:   manifest,
+		snapshotConfigMapsFile: []byte(`{"kind":"List","items":[]}`),
+	}
+	if err := writeSnapshotArchive(&archive, members, time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(work, "snapshot.tar.gz")
+	if err := os.WriteFile(path, archive.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	err = runClaw(t, work, "namespace", "restore", path)
+	var notConfirmed *confirm.NotConfirmedError
+	if !errors.As(err, &notConfirmed) {
+		t.Fatalf("namespace restore without --yes: err = %v, want NotConfirmedError", err)
+	}
+	if calls := kit.CallsTo("kubectl"); len(calls) != 0 {
+		t.Errorf("kubectl called without confirmation: %v", calls)
+	}
+
+	if err := runClaw(t, work, "namespace", "restore", path, "--dry-run"); err != nil {
+		t.Errorf("namespace restore --dry-run: %v", err)
+	}
+}
+
+func TestAgentsBackupReportsFailedArchive(t *testing.T) {
+	kit := testkit.New(t)
+	workspace := "/home/node/.openclaw/workspace"
+	kit.Add(testkit.Rule{
+		Tool:   "kubectl",
+		Args:   []string{"-n", "openclaw", "exec", "-c", "main", "openclaw-7d9f8b6c5-x2k4q", "--", "sh", "-lc", buildWorkspaceMarkdownTarScript(workspace)},
+		Stdout: "\x1f\x8b",
+		Stderr: "tar: write error",
+		Exit:   2,
+	})
+
+	agent := openclawapi.Agent{ID: "main", Workspace: workspace}
+	_, err := backupAgentWorkspace(context.Background(), openclaw.Config{Namespace: "openclaw"}, "openclaw-7d9f8b6c5-x2k4q", agent, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "failed to archive workspace markdown files") {
+		t.Fatalf("backupAgentWorkspace() error = %v, want the kubectl failure", err)
+	}
+}
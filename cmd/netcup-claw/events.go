@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	eventsFollow       bool
+	eventsTypes        []string
+	eventsReasons      []string
+	eventsJSON         bool
+	eventsNoColor      bool
+	eventsAlertWebhook string
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Show or stream Kubernetes events of the OpenClaw namespace",
+	Long: `Show Kubernetes events of the OpenClaw namespace, oldest first, or stream
+new ones with --follow until interrupted.
+
+Severity is colorized on terminals (Normal green, Warning yellow, failures such
+as BackOff or FailedMount red); set NO_COLOR or --no-color to disable it.
+--json prints one JSON object per line.
+
+With --alert-webhook (or OPENCLAW_ALERT_WEBHOOK), every Warning event that
+passes the filters is also POSTed as JSON to that URL.
+
+Examples:
+  netcup-claw events
+  netcup-claw events --follow --type Warning
+  netcup-claw events -f --reason BackOff,Unhealthy --json
+  netcup-claw events -f --type Warning --alert-webhook https://hooks.example.com/claw`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := openclawConfig()
+		if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+			return err
+		}
+
+		filter := eventFilter{Types: eventsTypes, Reasons: eventsReasons}
+		printer := eventPrinter{out: os.Stdout, json: eventsJSON, color: eventsUseColor()}
+		webhook, err := alertWebhookFromFlag(eventsAlertWebhook)
+		if err != nil {
+			return err
+		}
+		emit := func(r eventRecord) error {
+			if !filter.match(r) {
+				return nil
+			}
+			if err := printer.print(r); err != nil {
+				return err
+			}
+			if webhook != nil {
+				if err := webhook.send(ctx, r); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+				}
+			}
+			return nil
+		}
+
+		if !eventsFollow {
+			payload, err := runKubectlOutput(ctx, "-n", cfg.Namespace, "get", "events", "-o", "json")
+			if err != nil {
+				return fmt.Errorf("failed to list events: %w", err)
+			}
+			records, err := parseEventList(payload)
+			if err != nil {
+				return err
+			}
+			for _, r := range records {
+				if err := emit(r); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		pr, pw := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			err := kubectlRunner.Run(ctx, kubectl.Streams{Stdout: pw, Stderr: os.Stderr},
+				"-n", cfg.Namespace, "get", "events", "--watch", "-o", "json")
+			_ = pw.CloseWithError(err)
+			done <- err
+		}()
+		streamErr := streamEvents(pr, emit)
+		_ = pr.Close()
+		runErr := <-done
+		if ctx.Err() != nil {
+			return nil
+		}
+		if streamErr != nil {
+			return streamErr
+		}
+		if runErr != nil {
+			return fmt.Errorf("event watch ended: %w", runErr)
+		}
+		return nil
+	},
+}
+
+// eventsUseColor reports whether text output should be colorized
+func eventsUseColor() bool {
+	if eventsNoColor || eventsJSON || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	eventsCmd.Flags().BoolVarP(&eventsFollow, "follow", "f", false, "Stream new events until interrupted")
+	eventsCmd.Flags().StringSliceVar(&eventsTypes, "type", nil, "Only show events of these types (Normal, Warning)")
+	eventsCmd.Flags().StringSliceVar(&eventsReasons, "reason", nil, "Only show events with these reasons (e.g. BackOff,Unhealthy)")
+	eventsCmd.Flags().BoolVar(&eventsJSON, "json", false, "Print one JSON object per event")
+	eventsCmd.Flags().BoolVar(&eventsNoColor, "no-color", false, "Disable colorized severity")
+	eventsCmd.Flags().StringVar(&eventsAlertWebhook, "alert-webhook", "", "POST Warning events as JSON to this URL (default: OPENCLAW_ALERT_WEBHOOK)")
+	rootCmd.AddCommand(eventsCmd)
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/keyring"
+	"github.com/mfittko/netcup-kube/internal/output"
+)
+
+// ANSI colors for event severity
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+)
+
+// failureReasons are Warning reasons shown in red rather than yellow
+var failureReasons = map[string]bool{
+	"BackOff":          true,
+	"Evicted":          true,
+	"Failed":           true,
+	"FailedCreate":     true,
+	"FailedMount":      true,
+	"FailedScheduling": true,
+	"OOMKilling":       true,
+	"Unhealthy":        true,
+}
+
+// clusterEvent is a core/v1 Event as printed by kubectl -o json
+type clusterEvent struct {
+	Metadata struct {
+		Name            string `json:"name"`
+		Namespace       string `json:"namespace"`
+		UID             string `json:"uid"`
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Type           string `json:"type"`
+	Reason         string `json:"reason"`
+	Message        string `json:"message"`
+	Count          int    `json:"count"`
+	FirstTimestamp string `json:"firstTimestamp"`
+	LastTimestamp  string `json:"lastTimestamp"`
+	EventTime      string `json:"eventTime"`
+	InvolvedObject struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"involvedObject"`
+	Series struct {
+		Count            int    `json:"count"`
+		LastObservedTime string `json:"lastObservedTime"`
+	} `json:"series"`
+}
+
+// eventRecord is the flattened event printed by `netcup-claw events --json`
+// and posted to the alert webhook
+type eventRecord struct {
+	Time      string `json:"time"`
+	Namespace string `json:"namespace"`
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	Object    string `json:"object"`
+	Message   string `json:"message"`
+	Count     int    `json:"count"`
+}
+
+func (e clusterEvent) record() eventRecord {
+	ts := e.LastTimestamp
+	if e.Series.LastObservedTime != "" {
+		ts = e.Series.LastObservedTime
+	}
+	if ts == "" {
+		ts = e.EventTime
+	}
+	if ts == "" {
+		ts = e.FirstTimestamp
+	}
+	count := e.Count
+	if e.Series.Count > count {
+		count = e.Series.Count
+	}
+	if count == 0 {
+		count = 1
+	}
+	return eventRecord{
+		Time:      ts,
+		Namespace: e.Metadata.Namespace,
+		Type:      e.Type,
+		Reason:    e.Reason,
+		Object:    e.InvolvedObject.Kind + "/" + e.InvolvedObject.Name,
+		Message:   strings.TrimSpace(e.Message),
+		Count:     count,
+	}
+}
+
+// eventFilter selects events by type and reason; empty lists match everything
+type eventFilter struct {
+	Types   []string
+	Reasons []string
+}
+
+func (f eventFilter) match(r eventRecord) bool {
+	return matchesFold(f.Types, r.Type) && matchesFold(f.Reasons, r.Reason)
+}
+
+func matchesFold(allowed []string, value string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSpace(a), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEventList returns the events of a kubectl list sorted oldest first
+func parseEventList(payload []byte) ([]eventRecord, error) {
+	var list struct {
+		Items []clusterEvent `json:"items"`
+	}
+	if err := json.Unmarshal(payload, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse events: %w", err)
+	}
+	records := make([]eventRecord, 0, len(list.Items))
+	for _, e := range list.Items {
+		records = append(records, e.record())
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339, records[i].Time)
+		tj, _ := time.Parse(time.RFC3339, records[j].Time)
+		return ti.Before(tj)
+	})
+	return records, nil
+}
+
+// streamEvents decodes the concatenated JSON objects of `kubectl get events
+// --watch -o json` and calls emit for each new event. Events repeated after a
+// watch restart (same UID and resourceVersion) are skipped.
+func streamEvents(r io.Reader, emit func(eventRecord) error) error {
+	seen := map[string]bool{}
+	dec := json.NewDecoder(r)
+	for {
+		var e clusterEvent
+		if err := dec.Decode(&e); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode event stream: %w", err)
+		}
+		key := e.Metadata.UID + "/" + e.Metadata.ResourceVersion
+		if e.Metadata.UID != "" && seen[key] {
+			continue
+		}
+		seen[key] = true
+		if err := emit(e.record()); err != nil {
+			return err
+		}
+	}
+}
+
+// eventPrinter writes events as colorized text lines or JSON lines
+type eventPrinter struct {
+	out   io.Writer
+	json  bool
+	color bool
+}
+
+func (p eventPrinter) print(r eventRecord) error {
+	if p.json {
+		return output.WriteJSONLine(p.out, r)
+	}
+	kind := fmt.Sprintf("%-7s", r.Type)
+	if p.color {
+		kind = eventColor(r) + kind + colorReset
+	}
+	msg := r.Message
+	if r.Count > 1 {
+		msg += fmt.Sprintf(" (x%d)", r.Count)
+	}
+	_, err := fmt.Fprintf(p.out, "%-20s  %s  %-18s  %s  %s\n", eventTimeLabel(r.Time), kind, r.Reason, r.Object, msg)
+	return err
+}
+
+// eventColor maps severity to a color: Normal green, Warning yellow, and
+// failure reasons red
+func eventColor(r eventRecord) string {
+	switch {
+	case r.Type != "Warning":
+		return colorGreen
+	case failureReasons[r.Reason]:
+		return colorRed
+	default:
+		return colorYellow
+	}
+}
+
+func eventTimeLabel(ts string) string {
+	if ts == "" {
+		return "-"
+	}
+	return ts
+}
+
+// alertWebhook posts alerts (Warning events, drift reports) to an HTTP
+// endpoint as JSON
+type alertWebhook struct {
+	url    string
+	client *http.Client
+}
+
+func newAlertWebhook(url string) *alertWebhook {
+	return &alertWebhook{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// alertWebhookFromFlag returns the webhook for an --alert-webhook value,
+// falling back to OPENCLAW_ALERT_WEBHOOK, or nil when neither is set. The URL
+// may be a keyring:<name> reference.
+func alertWebhookFromFlag(flag string) (*alertWebhook, error) {
+	url := strings.TrimSpace(flag)
+	if url == "" {
+		url = strings.TrimSpace(os.Getenv("OPENCLAW_ALERT_WEBHOOK"))
+	}
+	url, err := keyring.Resolve(url)
+	if err != nil {
+		return nil, fmt.Errorf("alert webhook: %w", err)
+	}
+	if url == "" {
+		return nil, nil
+	}
+	return newAlertWebhook(url), nil
+}
+
+// send posts the event unless it is not a Warning
+func (w *alertWebhook) send(ctx context.Context, r eventRecord) error {
+	if r.Type != "Warning" {
+		return nil
+	}
+	return w.post(ctx, map[string]any{
+		"source": "netcup-claw",
+		"event":  r,
+	})
+}
+
+// post sends payload as a JSON body
+func (w *alertWebhook) post(ctx context.Context, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("alert webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("alert webhook: %s returned %s", w.url, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testEventStream = `{
+  "metadata": {"name": "openclaw-0.1", "namespace": "openclaw", "uid": "a", "resourceVersion": "10"},
+  "type": "Normal", "reason": "Pulled", "message": "Container image pulled",
+  "count": 1, "lastTimestamp": "2026-10-16T10:00:00Z",
+  "involvedObject": {"kind": "Pod", "name": "openclaw-0"}
+}
+{
+  "metadata": {"name": "openclaw-0.2", "namespace": "openclaw", "uid": "b", "resourceVersion": "11"},
+  "type": "Warning", "reason": "BackOff", "message": "Back-off restarting failed container\n",
+  "count": 4, "lastTimestamp": "2026-10-16T10:01:00Z",
+  "involvedObject": {"kind": "Pod", "name": "openclaw-0"}
+}
+{
+  "metadata": {"name": "openclaw-0.1", "namespace": "openclaw", "uid": "a", "resourceVersion": "10"},
+  "type": "Normal", "reason": "Pulled", "message": "Container image pulled",
+  "count": 1, "lastTimestamp": "2026-10-16T10:00:00Z",
+  "involvedObject": {"kind": "Pod", "name": "openclaw-0"}
+}
+{
+  "metadata": {"name": "litellm.3", "namespace": "openclaw", "uid": "c", "resourceVersion": "12"},
+  "type": "Warning", "reason": "Unhealthy", "message": "Readiness probe failed",
+  "eventTime": "2026-10-16T10:02:00.000000Z", "series": {"count": 7, "lastObservedTime": "2026-10-16T10:03:00Z"},
+  "involvedObject": {"kind": "Pod", "name": "litellm"}
+}
+`
+
+func TestStreamEvents(t *testing.T) {
+	var got []eventRecord
+	err := streamEvents(strings.NewReader(testEventStream), func(r eventRecord) error {
+		got = append(got, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("streamEvents() error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d events, want 3 (duplicate skipped): %+v", len(got), got)
+	}
+	if got[1].Message != "Back-off restarting failed container" || got[1].Object != "Pod/openclaw-0" || got[1].Count != 4 {
+		t.Errorf("BackOff record = %+v", got[1])
+	}
+	if got[2].Time != "2026-10-16T10:03:00Z" || got[2].Count != 7 {
+		t.Errorf("series record = %+v", got[2])
+	}
+
+	if err := streamEvents(strings.NewReader(`{"metadata":`), func(eventRecord) error { return nil }); err == nil {
+		t.Error("streamEvents() expected error for truncated stream")
+	}
+}
+
+func TestParseEventListSortsOldestFirst(t *testing.T) {
+	payload := `{"items":[
+  {"type":"Warning","reason":"BackOff","lastTimestamp":"2026-10-16T10:05:00Z","involvedObject":{"kind":"Pod","name":"a"}},
+  {"type":"Normal","reason":"Started","lastTimestamp":"2026-10-16T10:00:00Z","involvedObject":{"kind":"Pod","name":"a"}}]}`
+	records, err := parseEventList([]byte(payload))
+	if err != nil {
+		t.Fatalf("parseEventList() error: %v", err)
+	}
+	if len(records) != 2 || records[0].Reason != "Started" || records[1].Count != 1 {
+		t.Errorf("records = %+v", records)
+	}
+}
+
+func TestEventFilter(t *testing.T) {
+	warning := eventRecord{Type: "Warning", Reason: "BackOff"}
+	normal := eventRecord{Type: "Normal", Reason: "Pulled"}
+	tests := []struct {
+		filter eventFilter
+		want   [2]bool
+	}{
+		{eventFilter{}, [2]bool{true, true}},
+		{eventFilter{Types: []string{"warning"}}, [2]bool{true, false}},
+		{eventFilter{Reasons: []string{"Pulled", "Created"}}, [2]bool{false, true}},
+		{eventFilter{Types: []string{"Warning"}, Reasons: []string{"Pulled"}}, [2]bool{false, false}},
+	}
+	for _, tt := range tests {
+		if got := [2]bool{tt.filter.match(warning), tt.filter.match(normal)}; got != tt.want {
+			t.Errorf("%+v: match = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestEventPrinter(t *testing.T) {
+	r := eventRecord{Time: "2026-10-16T10:01:00Z", Type: "Warning", Reason: "BackOff", Object: "Pod/openclaw-0", Message: "Back-off", Count: 4}
+
+	var buf bytes.Buffer
+	if err := (eventPrinter{out: &buf, color: true}).print(r); err != nil {
+		t.Fatal(err)
+	}
+	line := buf.String()
+	if !strings.Contains(line, colorRed+"Warning") || !strings.HasSuffix(line, "Pod/openclaw-0  Back-off (x4)\n") {
+		t.Errorf("text line = %q", line)
+	}
+
+	buf.Reset()
+	if err := (eventPrinter{out: &buf, json: true}).print(r); err != nil {
+		t.Fatal(err)
+	}
+	var decoded eventRecord
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded != r {
+		t.Errorf("json line = %q (%v)", buf.String(), err)
+	}
+
+	if eventColor(eventRecord{Type: "Warning", Reason: "Rebooted"}) != colorYellow || eventColor(eventRecord{Type: "Normal"}) != colorGreen {
+		t.Error("unexpected severity colors")
+	}
+}
+
+func TestAlertWebhookSendsWarningsOnly(t *testing.T) {
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if strings.Contains(string(b), "Unhealthy") {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	hook := newAlertWebhook(srv.URL)
+	ctx := context.Background()
+	if err := hook.send(ctx, eventRecord{Type: "Normal", Reason: "Pulled"}); err != nil {
+		t.Errorf("send(Normal) error: %v", err)
+	}
+	if err := hook.send(ctx, eventRecord{Type: "Warning", Reason: "BackOff"}); err != nil {
+		t.Errorf("send(Warning) error: %v", err)
+	}
+	if err := hook.send(ctx, eventRecord{Type: "Warning", Reason: "Unhealthy"}); err == nil {
+		t.Error("send() expected error for non-2xx response")
+	}
+	if len(bodies) != 2 || !strings.Contains(bodies[0], `"source":"netcup-claw"`) || !strings.Contains(bodies[0], `"reason":"BackOff"`) {
+		t.Errorf("bodies = %v", bodies)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultHelmBackupRel is where release backups are kept, one directory per
+// namespace and release
+const defaultHelmBackupRel = "scripts/recipes/openclaw/helm-backups"
+
+var helmBackupPath string
+
+// helmReleaseBackup is the state of a release before a helm operation
+type helmReleaseBackup struct {
+	Release    string          `json:"release"`
+	Namespace  string          `json:"namespace"`
+	Operation  string          `json:"operation"`
+	Chart      string          `json:"chart,omitempty"`
+	AppVersion string          `json:"app_version,omitempty"`
+	Revision   string          `json:"revision,omitempty"`
+	Status     string          `json:"status,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+	Values     json.RawMessage `json:"values"`
+	Manifest   string          `json:"manifest"`
+}
+
+// helmBackupDir returns the backup directory of a release, or "" when
+// backups are off
+func helmBackupDir(namespace, release string) string {
+	root := strings.TrimSpace(helmBackupPath)
+	if root == "off" {
+		return ""
+	}
+	if root == "" {
+		root = defaultHelmBackupRel
+	}
+	return filepath.Join(root, namespace, release)
+}
+
+// backupHelmRelease saves the user-supplied values and the rendered manifest
+// of a release before operation (e.g. "upgrade") changes it, so the state
+// before an incident can be compared later. It returns the backup file, or
+// "" when backups are off. The directory is private since manifests include
+// Secrets.
+func backupHelmRelease(ctx context.Context, namespace, release, operation string) (string, error) {
+	dir := helmBackupDir(namespace, release)
+	if dir == "" {
+		return "", nil
+	}
+	backup := helmReleaseBackup{
+		Release:   release,
+		Namespace: namespace,
+		Operation: operation,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+	if deployed, err := helmListReleases(ctx, namespace); err == nil {
+		if rel := findHelmRelease(deployed, release); rel != nil {
+			backup.Chart, backup.AppVersion, backup.Revision, backup.Status = rel.Chart, rel.AppVersion, rel.Revision, rel.Status
+		}
+	}
+	values, err := helmOutput(ctx, "get", "values", release, "-n", namespace, "-o", "json")
+	if err != nil {
+		return "", fmt.Errorf("failed to read values of release %s: %w", release, err)
+	}
+	backup.Values = json.RawMessage("null")
+	if v := strings.TrimSpace(string(values)); v != "" {
+		backup.Values = json.RawMessage(v)
+	}
+	manifest, err := helmOutput(ctx, "get", "manifest", release, "-n", namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to read manifest of release %s: %w", release, err)
+	}
+	backup.Manifest = string(manifest)
+
+	payload, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return writeSnapshotBackup(dir, "pre-"+operation, append(payload, '\n'))
+}
+
+// backupBeforeHelm runs backupHelmRelease and reports the file; a failed
+// backup stops the operation
+func backupBeforeHelm(ctx context.Context, namespace, release, operation string) error {
+	file, err := backupHelmRelease(ctx, namespace, release, operation)
+	if err != nil {
+		return fmt.Errorf("%w (skip the backup with --helm-backup-path off)", err)
+	}
+	if file != "" {
+		fmt.Printf("helm release backup saved: %s\n", file)
+	}
+	return nil
+}
+
+// helmBackupPreview describes the backup a dry run skips, or "" when backups
+// are off
+func helmBackupPreview(namespace, release, operation string) string {
+	dir := helmBackupDir(namespace, release)
+	if dir == "" {
+		return ""
+	}
+	return fmt.Sprintf("would back up values and manifest of %s to %s before %s", release, dir, operation)
+}
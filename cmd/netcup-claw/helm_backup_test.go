@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHelmBackupDir(t *testing.T) {
+	t.Cleanup(func() { helmBackupPath = "" })
+	if got := helmBackupDir("openclaw", "openclaw"); got != filepath.Join(defaultHelmBackupRel, "openclaw", "openclaw") {
+		t.Errorf("helmBackupDir() = %q", got)
+	}
+	helmBackupPath = "off"
+	if got := helmBackupDir("openclaw", "openclaw"); got != "" {
+		t.Errorf("helmBackupDir(off) = %q", got)
+	}
+	if helmBackupPreview("openclaw", "openclaw", "upgrade") != "" {
+		t.Error("helmBackupPreview() described a disabled backup")
+	}
+}
+
+func TestBackupHelmRelease(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell script as helm")
+	}
+	bin := t.TempDir()
+	script := `#!/bin/sh
+case "$1 $2" in
+  "list -n") echo '[{"name":"openclaw","chart":"openclaw-1.4.4","app_version":"2026.2.17","status":"deployed","revision":"7"}]' ;;
+  "get values") echo '{"replicaCount":1}' ;;
+  "get manifest") printf -- '---\nkind: Deployment\n' ;;
+  *) exit 1 ;;
+esac
+`
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	helmBackupPath = t.TempDir()
+	t.Cleanup(func() { helmBackupPath = "" })
+
+	file, err := backupHelmRelease(context.Background(), "openclaw", "openclaw", "upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir := filepath.Dir(file); dir != filepath.Join(helmBackupPath, "openclaw", "openclaw") || !strings.HasPrefix(filepath.Base(file), "pre-upgrade-") {
+		t.Errorf("backup file = %s", file)
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got helmReleaseBackup
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Chart != "openclaw-1.4.4" || got.Revision != "7" || got.Operation != "upgrade" ||
+		strings.Join(strings.Fields(string(got.Values)), "") != `{"replicaCount":1}` || got.Manifest != "---\nkind: Deployment\n" {
+		t.Errorf("backup = %+v", got)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/openclaw"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/releases"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyRelease string
+	historyMax     int
+	historyJSON    bool
+
+	rollbackRelease       string
+	rollbackYes           bool
+	rollbackDryRun        bool
+	rollbackSkipPinUpdate bool
+)
+
+// helmRevision is one entry of `helm history -o json`
+type helmRevision struct {
+	Revision    int    `json:"revision"`
+	Updated     string `json:"updated"`
+	Status      string `json:"status"`
+	Chart       string `json:"chart"`
+	AppVersion  string `json:"app_version"`
+	Description string `json:"description"`
+}
+
+// label describes the revision in prompts and progress output
+func (r helmRevision) label() string {
+	return fmt.Sprintf("revision %d (%s, app %s)", r.Revision, r.Chart, r.AppVersion)
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the Helm revisions of the OpenClaw release",
+	Long: `Show the revisions of a Helm release of the OpenClaw namespace, oldest
+first, as 'helm history' does: chart and app version, status and what
+created each revision (install, upgrade, rollback).
+
+Roll back to one of them with 'netcup-claw rollback <revision>'.
+
+Examples:
+  netcup-claw history
+  netcup-claw history --max 5
+  netcup-claw history --release openclaw-browser --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := openclawConfig()
+		if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+			return err
+		}
+		revisions, err := helmHistory(ctx, cfg.Namespace, historyRelease, historyMax)
+		if err != nil {
+			return err
+		}
+		if historyJSON {
+			return output.WriteJSON(os.Stdout, revisions)
+		}
+		renderHelmHistory(os.Stdout, revisions)
+		return nil
+	},
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [revision]",
+	Short: "Roll the OpenClaw release back to an earlier Helm revision",
+	Long: `Roll a Helm release of the OpenClaw namespace back to an earlier revision,
+by default the one before the deployed revision. List them with
+'netcup-claw history'.
+
+Steps:
+  1. Look up the deployed and the target revision in the release history
+  2. Ask for confirmation (--yes or CONFIRM=true skips it)
+  3. Back up the release's values and manifest, then run
+     helm rollback <release> <revision> --wait --timeout 5m
+  4. Wait for the OpenClaw rollout to complete
+  5. Set the release's pin (e.g. CHART_VERSION_OPENCLAW) in recipes.conf to
+     the chart version of the target revision, so the next install or
+     upgrade starts from what runs
+  6. Verify the in-cluster health of OpenClaw (kube API, deployment, pod,
+     service, databases) as 'netcup-claw status' shows it
+
+The backup goes to --helm-backup-path (default:
+scripts/recipes/openclaw/helm-backups/<namespace>/<release>/pre-rollback-<time>.json,
+'off' disables it); a failed backup stops the rollback. The pin key comes
+from the release manifest (scripts/recipes/openclaw/releases.yaml).
+
+Examples:
+  netcup-claw rollback
+  netcup-claw rollback 4 --dry-run
+  netcup-claw rollback 4 --yes
+  netcup-claw rollback --release openclaw-browser --skip-pin-update`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := openclawConfig()
+		target := 0
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid revision %q (use a revision number from 'netcup-claw history')", args[0])
+			}
+			target = n
+		}
+		if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+			return err
+		}
+		revisions, err := helmHistory(ctx, cfg.Namespace, rollbackRelease, 0)
+		if err != nil {
+			return err
+		}
+		current, to, err := rollbackRevisions(revisions, target)
+		if err != nil {
+			return fmt.Errorf("release %s: %w", rollbackRelease, err)
+		}
+		pin := releasePin(rollbackRelease)
+		version := chartVersionFromChart(to.Chart)
+
+		fmt.Printf("current: %s\n", current.label())
+		fmt.Printf("target:  %s\n", to.label())
+		if rollbackDryRun {
+			fmt.Printf("\ndry-run: would run 'helm rollback %s %d -n %s --wait --timeout 5m'\n", rollbackRelease, to.Revision, cfg.Namespace)
+			if preview := helmBackupPreview(cfg.Namespace, rollbackRelease, "rollback"); preview != "" {
+				fmt.Printf("dry-run: %s\n", preview)
+			}
+			if !rollbackSkipPinUpdate && pin != "" {
+				fmt.Printf("dry-run: would update %s=%s in %s\n", pin, version, recipesConfRel)
+			}
+			return nil
+		}
+
+		prompt := fmt.Sprintf("roll back release %s in namespace %s from %s to %s", rollbackRelease, cfg.Namespace, current.label(), to.label())
+		if err := confirm.New(rollbackYes).Confirm(prompt); err != nil {
+			return err
+		}
+		if err := backupBeforeHelm(ctx, cfg.Namespace, rollbackRelease, "rollback"); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nrolling back to revision %d ...\n", to.Revision)
+		rollback := interrupt.Command(ctx, "helm", "rollback", rollbackRelease, strconv.Itoa(to.Revision), "-n", cfg.Namespace, "--wait", "--timeout", "5m")
+		rollback.Stdout = os.Stdout
+		rollback.Stderr = os.Stderr
+		if err := helmRun(ctx, rollback); err != nil {
+			return fmt.Errorf("helm rollback failed: %w", err)
+		}
+		fmt.Println("rollback complete")
+
+		if rollbackRelease == helmReleaseName {
+			fmt.Println("waiting for rollout...")
+			if err := waitForOpenClawRollout(ctx, cfg); err != nil {
+				return fmt.Errorf("rollout did not complete: %w", err)
+			}
+		}
+
+		if !rollbackSkipPinUpdate && pin != "" {
+			if err := updateRecipesConfKeyAt(recipesConfRel, pin, version); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to update %s: %v\n", recipesConfRel, err)
+			} else {
+				fmt.Printf("updated %s=%s in %s\n", pin, version, recipesConfRel)
+			}
+		}
+
+		return verifyClusterHealth(ctx, cfg)
+	},
+}
+
+// helmHistory returns the revisions of release, oldest first; max > 0 keeps
+// only the most recent ones
+func helmHistory(ctx context.Context, namespace, release string, max int) ([]helmRevision, error) {
+	args := []string{"history", release, "-n", namespace, "-o", "json"}
+	if max > 0 {
+		args = append(args, "--max", strconv.Itoa(max))
+	}
+	out, err := helmOutput(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("helm history %s failed: %w", release, err)
+	}
+	return parseHelmRevisions(release, out)
+}
+
+// parseHelmRevisions parses `helm history -o json` of release
+func parseHelmRevisions(release string, payload []byte) ([]helmRevision, error) {
+	var revisions []helmRevision
+	if err := json.Unmarshal(payload, &revisions); err != nil {
+		return nil, fmt.Errorf("failed to parse helm history of %s: %w", release, err)
+	}
+	return revisions, nil
+}
+
+// rollbackRevisions returns the deployed revision and the one to roll back
+// to: target, or the newest revision before the deployed one when target is 0
+func rollbackRevisions(revisions []helmRevision, target int) (helmRevision, helmRevision, error) {
+	var current *helmRevision
+	for i := range revisions {
+		if revisions[i].Status == "deployed" {
+			current = &revisions[i]
+		}
+	}
+	if current == nil {
+		return helmRevision{}, helmRevision{}, fmt.Errorf("no deployed revision in the history")
+	}
+	var to *helmRevision
+	for i := range revisions {
+		r := &revisions[i]
+		switch {
+		case target > 0 && r.Revision == target:
+			to = r
+		case target == 0 && r.Revision < current.Revision && (to == nil || r.Revision > to.Revision):
+			to = r
+		}
+	}
+	switch {
+	case to == nil && target > 0:
+		return *current, helmRevision{}, fmt.Errorf("revision %d not found in the history", target)
+	case to == nil:
+		return *current, helmRevision{}, fmt.Errorf("no revision before the deployed revision %d", current.Revision)
+	case to.Revision == current.Revision:
+		return *current, *to, fmt.Errorf("revision %d is already deployed", target)
+	}
+	return *current, *to, nil
+}
+
+// releasePin returns the recipes.conf pin key of release from the release
+// manifest, or "" when the release is not pinned
+func releasePin(release string) string {
+	manifest, err := releases.Load(releasesManifestRel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		return ""
+	}
+	for _, r := range manifest {
+		if r.Name == release {
+			return r.Pin
+		}
+	}
+	return ""
+}
+
+// inClusterHealthChecks returns the checks of openclawHealthChecks that do
+// not depend on this machine's tunnel and port-forward
+func inClusterHealthChecks(cfg openclaw.Config) []healthCheck {
+	var checks []healthCheck
+	for _, c := range openclawHealthChecks(cfg) {
+		switch c.Name {
+		case "tunnel", "port-forward", "http":
+			continue
+		case "kube-api":
+			c.Parent = ""
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// verifyClusterHealth prints the in-cluster health tree and fails when a
+// check failed
+func verifyClusterHealth(ctx context.Context, cfg openclaw.Config) error {
+	fmt.Println("\nverifying health...")
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	results := runHealthChecks(ctx, inClusterHealthChecks(cfg))
+	renderHealthTree(os.Stdout, results)
+	if !healthy(results) {
+		return fmt.Errorf("OpenClaw is not healthy after the rollback; inspect it with 'netcup-claw status' and 'netcup-claw timeline'")
+	}
+	return nil
+}
+
+func renderHelmHistory(w io.Writer, revisions []helmRevision) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "REVISION\tUPDATED\tSTATUS\tCHART\tAPP VERSION\tDESCRIPTION")
+	for _, r := range revisions {
+		updated := r.Updated
+		if t, err := time.Parse(time.RFC3339Nano, r.Updated); err == nil {
+			updated = t.Local().Format("2006-01-02 15:04:05")
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", r.Revision, updated, r.Status, r.Chart, r.AppVersion, r.Description)
+	}
+	_ = tw.Flush()
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historyRelease, "release", helmReleaseName, "Helm release in the OpenClaw namespace")
+	historyCmd.Flags().IntVar(&historyMax, "max", 0, "Only show the most recent revisions (0 for all)")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Print the revisions as a JSON array")
+	rootCmd.AddCommand(historyCmd)
+
+	rollbackCmd.Flags().StringVar(&rollbackRelease, "release", helmReleaseName, "Helm release in the OpenClaw namespace")
+	rollbackCmd.Flags().BoolVarP(&rollbackYes, "yes", "y", false, "Skip the confirmation prompt (same as CONFIRM=true)")
+	rollbackCmd.Flags().BoolVar(&rollbackDryRun, "dry-run", false, "Preview the rollback without applying it")
+	rollbackCmd.Flags().BoolVar(&rollbackSkipPinUpdate, "skip-pin-update", false, "Skip updating the chart version pin in recipes.conf")
+	rollbackCmd.Flags().StringVar(&helmBackupPath, "helm-backup-path", "", "Directory for release backups before the rollback (default: "+defaultHelmBackupRel+", 'off' disables)")
+	rootCmd.AddCommand(rollbackCmd)
+}
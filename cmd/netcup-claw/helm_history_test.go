@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/openclaw"
+)
+
+func TestRollbackRevisions(t *testing.T) {
+	history := []helmRevision{
+		{Revision: 3, Status: "superseded", Chart: "openclaw-1.2.0"},
+		{Revision: 4, Status: "failed", Chart: "openclaw-1.3.0"},
+		{Revision: 5, Status: "deployed", Chart: "openclaw-1.4.0"},
+		{Revision: 6, Status: "pending-upgrade", Chart: "openclaw-1.5.0"},
+	}
+	tests := []struct {
+		target  int
+		want    int
+		wantErr string
+	}{
+		{0, 4, ""},
+		{3, 3, ""},
+		{6, 6, ""},
+		{5, 0, "already deployed"},
+		{9, 0, "revision 9 not found"},
+	}
+	for _, tt := range tests {
+		current, to, err := rollbackRevisions(history, tt.target)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("rollbackRevisions(%d) error = %v, want %q", tt.target, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || current.Revision != 5 || to.Revision != tt.want {
+			t.Errorf("rollbackRevisions(%d) = %d -> %d, %v; want 5 -> %d", tt.target, current.Revision, to.Revision, err, tt.want)
+		}
+	}
+
+	if _, _, err := rollbackRevisions(history[:1], 0); err == nil || !strings.Contains(err.Error(), "no deployed revision") {
+		t.Errorf("without a deployed revision: %v", err)
+	}
+	if _, _, err := rollbackRevisions([]helmRevision{{Revision: 1, Status: "deployed"}}, 0); err == nil || !strings.Contains(err.Error(), "no revision before") {
+		t.Errorf("first revision: %v", err)
+	}
+}
+
+func TestInClusterHealthChecks(t *testing.T) {
+	var names []string
+	for _, c := range inClusterHealthChecks(openclaw.DefaultConfig()) {
+		names = append(names, c.Name)
+		if c.Name == "kube-api" && c.Parent != "" {
+			t.Errorf("kube-api depends on %q, want the root", c.Parent)
+		}
+	}
+	if got := strings.Join(names, " "); got != "kube-api namespace deployment pod service postgres redis" {
+		t.Errorf("checks = %s", got)
+	}
+}
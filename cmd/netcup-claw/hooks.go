@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/hooks"
+	"github.com/spf13/cobra"
+)
+
+// commandHooks tracks the hooks of the running command so its post hooks can
+// run once the command has finished
+type commandHooks struct {
+	runner  *hooks.Runner
+	inv     hooks.Invocation
+	started time.Time
+}
+
+// activeHooks is set once the pre hooks of the running command succeeded
+var activeHooks *commandHooks
+
+// startCommandHooks runs the pre hooks of cmd. Hooks live in
+// $HOOKS_DIR/netcup-claw (default config/hooks.d/netcup-claw, else
+// ~/.config/netcup-claw/hooks.d) or HOOK_* env vars.
+func startCommandHooks(cmd *cobra.Command, args []string) error {
+	if !cmd.HasParent() {
+		return nil
+	}
+	h := &commandHooks{
+		runner: &hooks.Runner{
+			CLI:    "netcup-claw",
+			Dir:    hooks.Dir(os.Getenv("HOOKS_DIR"), "netcup-claw"),
+			Lookup: os.Getenv,
+			Env:    os.Environ(),
+		},
+		inv: hooks.Invocation{
+			Command: strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "),
+			Args:    args,
+		},
+		started: time.Now(),
+	}
+	if err := h.runner.Run(cmd.Context(), hooks.Pre, h.inv); err != nil {
+		return err
+	}
+	activeHooks = h
+	return nil
+}
+
+// finishCommandHooks runs the post hooks of the command that returned err.
+// A failing post hook with policy abort fails an otherwise successful command.
+func finishCommandHooks(ctx context.Context, err error) error {
+	if activeHooks == nil {
+		return err
+	}
+	h := activeHooks
+	activeHooks = nil
+	h.inv.Err = err
+	h.inv.Duration = time.Since(h.started)
+	hookErr := h.runner.Run(ctx, hooks.Post, h.inv)
+	if hookErr == nil {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", hookErr)
+		return err
+	}
+	return hookErr
+}
@@ -0,0 +1,414 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/registry"
+	"github.com/spf13/cobra"
+)
+
+// Image check status
+const (
+	imageOK           = "ok"
+	imageStale        = "stale"
+	imageMissing      = "missing"
+	imageUnauthorized = "unauthorized"
+	imagePullError    = "pull-error"
+	imageUnverified   = "unverified"
+
+	pullSecretUnused = "unused"
+	pullSecretBad    = "invalid"
+)
+
+var (
+	imageCheckPod     string
+	imageCheckTimeout time.Duration
+	imageCheckJSON    bool
+)
+
+// imageContainerReport is the check of one container image
+type imageContainerReport struct {
+	Container      string `json:"container"`
+	Image          string `json:"image"`
+	PullPolicy     string `json:"pull_policy,omitempty"`
+	RunningDigest  string `json:"running_digest,omitempty"`
+	RegistryDigest string `json:"registry_digest,omitempty"`
+	Status         string `json:"status"`
+	Detail         string `json:"detail,omitempty"`
+}
+
+// imagePullSecretReport is the check of one imagePullSecret of the pod
+type imagePullSecretReport struct {
+	Name       string   `json:"name"`
+	Registries []string `json:"registries,omitempty"`
+	Status     string   `json:"status"`
+	Detail     string   `json:"detail,omitempty"`
+}
+
+// imageCheckReport is the report of `image check`
+type imageCheckReport struct {
+	Pod         string                  `json:"pod"`
+	Containers  []imageContainerReport  `json:"containers"`
+	PullSecrets []imagePullSecretReport `json:"pull_secrets"`
+	Hints       []string                `json:"hints,omitempty"`
+}
+
+// problems counts the containers and pull secrets that failed
+func (r imageCheckReport) problems() int {
+	n := 0
+	for _, c := range r.Containers {
+		if c.Status != imageOK {
+			n++
+		}
+	}
+	for _, s := range r.PullSecrets {
+		if s.Status != imageOK && s.Status != pullSecretUnused {
+			n++
+		}
+	}
+	return n
+}
+
+// imagePodSpec holds the fields of a pod the check needs
+type imagePodSpec struct {
+	Spec struct {
+		InitContainers   []imagePodContainer `json:"initContainers"`
+		Containers       []imagePodContainer `json:"containers"`
+		ImagePullSecrets []struct {
+			Name string `json:"name"`
+		} `json:"imagePullSecrets"`
+	} `json:"spec"`
+	Status struct {
+		InitContainerStatuses []imagePodStatus `json:"initContainerStatuses"`
+		ContainerStatuses     []imagePodStatus `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+type imagePodContainer struct {
+	Name            string `json:"name"`
+	Image           string `json:"image"`
+	ImagePullPolicy string `json:"imagePullPolicy"`
+}
+
+type imagePodStatus struct {
+	Name    string `json:"name"`
+	ImageID string `json:"imageID"`
+	State   struct {
+		Waiting *struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"waiting"`
+	} `json:"state"`
+}
+
+var imageCmd = &cobra.Command{
+	Use:   "image",
+	Short: "Check the container images of the OpenClaw pod",
+	Long: `Check the container images of the OpenClaw pod against their registries.
+
+Sub-commands:
+  check  - Verify images, digests and imagePullSecrets`,
+}
+
+var imageCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Verify the pod's images at their registries and its imagePullSecrets",
+	Long: `Verify the images of the OpenClaw pod (init containers included):
+
+  - the image tag (or digest) exists at its registry, resolved over the
+    registry API with the credentials of the pod's imagePullSecrets, else
+    anonymously
+  - the digest the node pulled matches what the registry serves for the tag
+    now; a tag that moved since the pull is reported as stale
+  - pull errors the kubelet reports (ErrImagePull, ImagePullBackOff)
+  - each imagePullSecret exists, is a kubernetes.io/dockerconfigjson secret
+    and its credentials authenticate at the registries of the images
+  - the OpenClaw image tag matches the appVersion of the deployed chart,
+    the stale-tag situation 'netcup-claw upgrade' reports as "image tag
+    differs from chart metadata"
+
+The registries are queried from this machine, so it needs access to them.
+The command fails when a check fails.
+
+Examples:
+  netcup-claw image check
+  netcup-claw image check --pod openclaw-5d9c7b7f4-x2x9k --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg, pod, err := resolveTargetPod(ctx, imageCheckPod)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(ctx, imageCheckTimeout)
+		defer cancel()
+
+		out, err := kubectlRunner.Output(ctx, "-n", cfg.Namespace, "get", "pod", pod, "-o", "json")
+		if err != nil {
+			return fmt.Errorf("failed to read pod %s: %w", pod, err)
+		}
+		var spec imagePodSpec
+		if err := json.Unmarshal(out, &spec); err != nil {
+			return fmt.Errorf("failed to parse pod %s: %w", pod, err)
+		}
+
+		secrets := map[string]registry.DockerConfig{}
+		report := imageCheckReport{Pod: pod, PullSecrets: []imagePullSecretReport{}}
+		for _, ps := range spec.Spec.ImagePullSecrets {
+			dockerConfig, res := readPullSecret(ctx, cfg.Namespace, ps.Name)
+			if res.Status == "" {
+				secrets[ps.Name] = dockerConfig
+			}
+			report.PullSecrets = append(report.PullSecrets, res)
+		}
+
+		client := &registry.Client{HTTP: &http.Client{Timeout: 15 * time.Second}}
+		report.Containers = checkPodImages(ctx, client, spec, secrets)
+		report.PullSecrets = checkPullSecrets(ctx, client, report.PullSecrets, secrets, spec)
+
+		appVersion := ""
+		if rel, err := helmCurrentRelease(ctx, cfg.Namespace); err == nil {
+			appVersion = rel.AppVersion
+		}
+		report.Hints = imageCheckHints(report, appVersion)
+
+		if imageCheckJSON {
+			if err := output.WriteJSON(os.Stdout, report); err != nil {
+				return err
+			}
+		} else {
+			renderImageCheck(os.Stdout, report)
+		}
+		if n := report.problems(); n > 0 {
+			return fmt.Errorf("image check of pod %s found %d problem(s)", pod, n)
+		}
+		return nil
+	},
+}
+
+// readPullSecret reads a pull secret; a report with a status means the
+// secret is unusable
+func readPullSecret(ctx context.Context, namespace, name string) (registry.DockerConfig, imagePullSecretReport) {
+	res := imagePullSecretReport{Name: name}
+	out, err := kubectlRunner.Output(ctx, "-n", namespace, "get", "secret", name, "-o", "json")
+	if err != nil {
+		res.Status, res.Detail = imageMissing, "secret not found"
+		if !strings.Contains(err.Error(), "NotFound") {
+			res.Status, res.Detail = imageUnverified, err.Error()
+		}
+		return registry.DockerConfig{}, res
+	}
+	dockerConfig, err := parsePullSecret(out)
+	if err != nil {
+		res.Status, res.Detail = pullSecretBad, err.Error()
+	}
+	return dockerConfig, res
+}
+
+// parsePullSecret returns the registry credentials of a secret
+func parsePullSecret(payload []byte) (registry.DockerConfig, error) {
+	var secret struct {
+		Type string            `json:"type"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &secret); err != nil {
+		return registry.DockerConfig{}, fmt.Errorf("failed to parse secret: %w", err)
+	}
+	if secret.Type != "kubernetes.io/dockerconfigjson" {
+		return registry.DockerConfig{}, fmt.Errorf("type %s, want kubernetes.io/dockerconfigjson", orDash(secret.Type))
+	}
+	data, err := base64.StdEncoding.DecodeString(secret.Data[".dockerconfigjson"])
+	if err != nil {
+		return registry.DockerConfig{}, fmt.Errorf("invalid .dockerconfigjson: %w", err)
+	}
+	return registry.ParseDockerConfig(data)
+}
+
+// checkPodImages checks every container image of the pod at its registry
+func checkPodImages(ctx context.Context, client *registry.Client, spec imagePodSpec, secrets map[string]registry.DockerConfig) []imageContainerReport {
+	statuses := map[string]imagePodStatus{}
+	for _, s := range append(spec.Status.InitContainerStatuses, spec.Status.ContainerStatuses...) {
+		statuses[s.Name] = s
+	}
+	containers := append(append([]imagePodContainer{}, spec.Spec.InitContainers...), spec.Spec.Containers...)
+	reports := make([]imageContainerReport, 0, len(containers))
+	for _, c := range containers {
+		ref, err := registry.ParseReference(c.Image)
+		if err != nil {
+			reports = append(reports, imageContainerReport{Container: c.Name, Image: c.Image, Status: imageMissing, Detail: err.Error()})
+			continue
+		}
+		var creds *registry.Credentials
+		for _, dockerConfig := range secrets {
+			if found, ok := dockerConfig.Lookup(ref.Registry); ok {
+				creds = &found
+				break
+			}
+		}
+		digest, err := client.Resolve(ctx, ref, creds)
+		reports = append(reports, evaluateImage(c, statuses[c.Name], digest, err))
+	}
+	return reports
+}
+
+// evaluateImage compares a container's image with the registry's answer
+func evaluateImage(c imagePodContainer, status imagePodStatus, registryDigest string, resolveErr error) imageContainerReport {
+	r := imageContainerReport{Container: c.Name, Image: c.Image, PullPolicy: c.ImagePullPolicy, RegistryDigest: registryDigest}
+	if _, digest, ok := strings.Cut(status.ImageID, "@"); ok {
+		r.RunningDigest = digest
+	}
+	switch {
+	case errors.Is(resolveErr, registry.ErrNotFound):
+		r.Status, r.Detail = imageMissing, "the registry has no manifest for this tag"
+	case errors.Is(resolveErr, registry.ErrUnauthorized):
+		r.Status, r.Detail = imageUnauthorized, "the registry refused access with the pod's pull secrets"
+	case resolveErr != nil:
+		r.Status, r.Detail = imageUnverified, resolveErr.Error()
+	case status.State.Waiting != nil && (status.State.Waiting.Reason == "ErrImagePull" || status.State.Waiting.Reason == "ImagePullBackOff" || status.State.Waiting.Reason == "InvalidImageName"):
+		r.Status, r.Detail = imagePullError, status.State.Waiting.Reason+": "+status.State.Waiting.Message
+	case r.RunningDigest != "" && r.RunningDigest != registryDigest:
+		r.Status, r.Detail = imageStale, "the tag moved at the registry since the node pulled it"
+	default:
+		r.Status = imageOK
+		if r.RunningDigest == "" {
+			r.Detail = "not pulled yet"
+		}
+	}
+	return r
+}
+
+// checkPullSecrets checks the credentials of the readable pull secrets
+// against the registries of the pod's images
+func checkPullSecrets(ctx context.Context, client *registry.Client, reports []imagePullSecretReport, secrets map[string]registry.DockerConfig, spec imagePodSpec) []imagePullSecretReport {
+	var refs []registry.Reference
+	for _, c := range append(append([]imagePodContainer{}, spec.Spec.InitContainers...), spec.Spec.Containers...) {
+		if ref, err := registry.ParseReference(c.Image); err == nil {
+			refs = append(refs, ref)
+		}
+	}
+	for i := range reports {
+		dockerConfig, ok := secrets[reports[i].Name]
+		if !ok {
+			continue
+		}
+		reports[i].Status = pullSecretUnused
+		reports[i].Detail = "no credentials for the registries of the pod's images"
+		seen := map[string]bool{}
+		for _, ref := range refs {
+			creds, found := dockerConfig.Lookup(ref.Registry)
+			if !found || seen[ref.Registry] {
+				continue
+			}
+			seen[ref.Registry] = true
+			reports[i].Registries = append(reports[i].Registries, ref.Registry)
+			switch err := client.CheckCredentials(ctx, ref, creds); {
+			case errors.Is(err, registry.ErrUnauthorized):
+				reports[i].Status, reports[i].Detail = imageUnauthorized, "credentials rejected by "+ref.Registry
+			case err != nil:
+				reports[i].Status, reports[i].Detail = imageUnverified, err.Error()
+			case reports[i].Status == pullSecretUnused:
+				reports[i].Status, reports[i].Detail = imageOK, ""
+			}
+		}
+	}
+	return reports
+}
+
+// imageCheckHints explains failed checks and detects a stale OpenClaw tag
+func imageCheckHints(r imageCheckReport, appVersion string) []string {
+	var hints []string
+	for _, c := range r.Containers {
+		switch c.Status {
+		case imageMissing:
+			hints = append(hints, fmt.Sprintf("%s: %s does not exist; check the tag in the Helm values (netcup-claw history shows what changed)", c.Container, c.Image))
+		case imageUnauthorized:
+			hints = append(hints, fmt.Sprintf("%s: add an imagePullSecret with credentials for the registry of %s, or renew the expired token", c.Container, c.Image))
+		case imageStale:
+			hint := fmt.Sprintf("%s: the node runs %s but %s now points to %s", c.Container, c.RunningDigest, c.Image, c.RegistryDigest)
+			if c.PullPolicy != "Always" {
+				hint += fmt.Sprintf("; with imagePullPolicy %s nodes keep the old image, pin a digest or restart after setting Always", orDash(c.PullPolicy))
+			} else {
+				hint += "; restart the deployment to pull it"
+			}
+			hints = append(hints, hint)
+		}
+		if c.Container == openclawMainContainer && appVersion != "" {
+			if ref, err := registry.ParseReference(c.Image); err == nil && ref.Tag != "" && ref.Tag != appVersion {
+				hints = append(hints, fmt.Sprintf("%s: image tag %s differs from the chart's appVersion %s, likely kept by an upgrade with --reuse-values; 'netcup-claw upgrade' re-applies the chart's tag", c.Container, ref.Tag, appVersion))
+			}
+		}
+		if ref, err := registry.ParseReference(c.Image); err == nil && ref.Tag == "latest" && ref.Digest == "" {
+			hints = append(hints, fmt.Sprintf("%s: %s uses the mutable tag latest; pin a version or digest", c.Container, c.Image))
+		}
+	}
+	for _, s := range r.PullSecrets {
+		switch s.Status {
+		case imageMissing:
+			hints = append(hints, fmt.Sprintf("pull secret %s does not exist in the namespace; pulls from private registries fail", s.Name))
+		case pullSecretBad:
+			hints = append(hints, fmt.Sprintf("pull secret %s is unusable (%s); recreate it with kubectl create secret docker-registry", s.Name, s.Detail))
+		case imageUnauthorized:
+			hints = append(hints, fmt.Sprintf("pull secret %s: %s; the token may have expired", s.Name, s.Detail))
+		}
+	}
+	return hints
+}
+
+func renderImageCheck(w io.Writer, r imageCheckReport) {
+	_, _ = fmt.Fprintf(w, "pod  %s\n", r.Pod)
+	for _, c := range r.Containers {
+		line := fmt.Sprintf("image  %-10s %-12s %s", c.Container, c.Status, c.Image)
+		if c.RunningDigest != "" {
+			line += " running " + shortDigest(c.RunningDigest)
+		}
+		if c.RegistryDigest != "" && c.RegistryDigest != c.RunningDigest {
+			line += " registry " + shortDigest(c.RegistryDigest)
+		}
+		if c.Detail != "" {
+			line += " (" + c.Detail + ")"
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	if len(r.PullSecrets) == 0 {
+		_, _ = fmt.Fprintln(w, "pull-secret  - (none)")
+	}
+	for _, s := range r.PullSecrets {
+		line := fmt.Sprintf("pull-secret  %s %s", s.Name, s.Status)
+		if len(s.Registries) > 0 {
+			line += " " + strings.Join(s.Registries, ",")
+		}
+		if s.Detail != "" {
+			line += " (" + s.Detail + ")"
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	for _, h := range r.Hints {
+		_, _ = fmt.Fprintf(w, "hint: %s\n", h)
+	}
+}
+
+// shortDigest abbreviates sha256:<hex> for display
+func shortDigest(digest string) string {
+	if len(digest) > len("sha256:")+12 {
+		return digest[:len("sha256:")+12]
+	}
+	return digest
+}
+
+func init() {
+	imageCheckCmd.Flags().StringVar(&imageCheckPod, "pod", "", "Pod to check (default: the resolved OpenClaw pod)")
+	imageCheckCmd.Flags().DurationVar(&imageCheckTimeout, "timeout", time.Minute, "Timeout for the whole check")
+	imageCheckCmd.Flags().BoolVar(&imageCheckJSON, "json", false, "Print the report as JSON")
+	imageCmd.AddCommand(imageCheckCmd)
+	rootCmd.AddCommand(imageCmd)
+}
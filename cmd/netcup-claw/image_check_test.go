@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/registry"
+)
+
+func TestParsePullSecret(t *testing.T) {
+	config := base64.StdEncoding.EncodeToString([]byte(`{"auths":{"ghcr.io":{"username":"me","password":"pat"}}}`))
+	cfg, err := parsePullSecret([]byte(`{"type":"kubernetes.io/dockerconfigjson","data":{".dockerconfigjson":"` + config + `"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds, ok := cfg.Lookup("ghcr.io"); !ok || creds.Password != "pat" {
+		t.Errorf("Lookup(ghcr.io) = %+v, %v", creds, ok)
+	}
+	if _, err := parsePullSecret([]byte(`{"type":"Opaque","data":{}}`)); err == nil || !strings.Contains(err.Error(), "type Opaque") {
+		t.Errorf("parsePullSecret(Opaque) error = %v", err)
+	}
+}
+
+func TestEvaluateImage(t *testing.T) {
+	c := imagePodContainer{Name: "main", Image: "ghcr.io/openclaw/openclaw:2026.3.1", ImagePullPolicy: "IfNotPresent"}
+	running := imagePodStatus{Name: "main", ImageID: "ghcr.io/openclaw/openclaw@sha256:aaa"}
+	pullErr := imagePodStatus{Name: "main"}
+	pullErr.State.Waiting = &struct {
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	}{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}
+
+	cases := []struct {
+		name   string
+		status imagePodStatus
+		digest string
+		err    error
+		want   string
+	}{
+		{"ok", running, "sha256:aaa", nil, imageOK},
+		{"stale", running, "sha256:bbb", nil, imageStale},
+		{"missing", pullErr, "", fmt.Errorf("x: %w", registry.ErrNotFound), imageMissing},
+		{"unauthorized", pullErr, "", fmt.Errorf("x: %w", registry.ErrUnauthorized), imageUnauthorized},
+		{"pull-error", pullErr, "sha256:aaa", nil, imagePullError},
+		{"unverified", running, "", fmt.Errorf("dial tcp: timeout"), imageUnverified},
+	}
+	for _, tc := range cases {
+		if got := evaluateImage(c, tc.status, tc.digest, tc.err); got.Status != tc.want {
+			t.Errorf("%s: evaluateImage() = %+v, want status %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestImageCheckHints(t *testing.T) {
+	report := imageCheckReport{
+		Pod: "openclaw-0",
+		Containers: []imageContainerReport{
+			{Container: "main", Image: "ghcr.io/openclaw/openclaw:2026.2.1", PullPolicy: "IfNotPresent", RunningDigest: "sha256:aaa", RegistryDigest: "sha256:bbb", Status: imageStale},
+			{Container: "sidecar", Image: "busybox", Status: imageOK},
+		},
+		PullSecrets: []imagePullSecretReport{{Name: "ghcr", Status: imageMissing}},
+	}
+	hints := imageCheckHints(report, "2026.3.1")
+	joined := strings.Join(hints, "\n")
+	for _, want := range []string{"imagePullPolicy IfNotPresent", "differs from the chart's appVersion 2026.3.1", "mutable tag latest", "pull secret ghcr does not exist"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("hints missing %q:\n%s", want, joined)
+		}
+	}
+	if report.problems() != 2 {
+		t.Errorf("problems() = %d, want 2", report.problems())
+	}
+
+	var buf bytes.Buffer
+	report.Hints = hints
+	renderImageCheck(&buf, report)
+	if !strings.Contains(buf.String(), "image  main       stale        ghcr.io/openclaw/openclaw:2026.2.1 running sha256:aaa registry sha256:bbb") {
+		t.Errorf("render =\n%s", buf.String())
+	}
+}
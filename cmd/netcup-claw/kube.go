@@ -1,40 +1,188 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
+	"net"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/mfittko/netcup-kube/internal/tunnel"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/mfittko/netcup-kube/internal/kubediag"
+	"github.com/mfittko/netcup-kube/internal/openclaw"
+	"github.com/mfittko/netcup-kube/internal/probe"
+	"github.com/mfittko/netcup-kube/internal/wireguard"
+	"github.com/spf13/cobra"
 )
 
-// probeKubeAPI checks if the local Kubernetes API is reachable by running
-// kubectl with a short request timeout. This is kubeconfig-aware and handles
-// TLS/auth automatically, avoiding false negatives from raw HTTP probes.
+// cachedResolvers holds one resolver per namespace/selector so repeated
+// lookups within a process share the in-memory cache.
+var cachedResolvers = map[string]*openclaw.Resolver{}
+
+// resolverCacheTTL returns the resolver cache TTL from OPENCLAW_RESOLVE_CACHE_TTL
+// (a Go duration such as "30s"; "0" disables caching). Local clusters default
+// to no caching since they are recreated often.
+func resolverCacheTTL() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("OPENCLAW_RESOLVE_CACHE_TTL"))
+	if raw == "" {
+		if localCluster.Kind != "" {
+			return 0
+		}
+		return openclaw.DefaultCacheTTL
+	}
+	ttl, err := time.ParseDuration(raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: invalid OPENCLAW_RESOLVE_CACHE_TTL %q; using %s\n", raw, openclaw.DefaultCacheTTL)
+		return openclaw.DefaultCacheTTL
+	}
+	return ttl
+}
+
+// newOpenClawResolver returns a caching resolver for cfg. Results are shared
+// across invocations via a state file until the TTL expires.
+func newOpenClawResolver(cfg openclaw.Config) *openclaw.Resolver {
+	key := cfg.Namespace + "|" + cfg.LabelSelector
+	if r, ok := cachedResolvers[key]; ok {
+		return r
+	}
+	r := openclaw.New(cfg, kubectlExec,
+		openclaw.WithCacheTTL(resolverCacheTTL()),
+		openclaw.WithCacheFile(openclaw.DefaultCacheFile(resolverCacheScope(cfg.Namespace))),
+	)
+	cachedResolvers[key] = r
+	return r
+}
+
+// resolverCacheScope keys the resolver state file by context as well, so
+// installs in equally named namespaces of different clusters do not share it
+func resolverCacheScope(namespace string) string {
+	if localCluster.Kind != "" {
+		return localCluster.Context + "-" + namespace
+	}
+	if activeContextName == "" {
+		return namespace
+	}
+	return activeContextName + "-" + namespace
+}
+
+// invalidateResolverCache drops cached service/pod lookups after a failed
+// kubectl call, since the cached target may no longer exist.
+func invalidateResolverCache() {
+	for _, r := range cachedResolvers {
+		r.Invalidate()
+	}
+}
+
+// probeKubeAPI checks if the local Kubernetes API is reachable with the probe
+// strategy of KUBE_PROBE; the default runs kubectl with a short request
+// timeout, which is kubeconfig-aware and handles TLS/auth automatically.
 func probeKubeAPI() bool {
-	cmd := exec.Command("kubectl", "--request-timeout=3s", "get", "--raw=/livez")
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run() == nil
+	return kubeAPIProbe() == nil
+}
+
+// kubeAPIProbe is probeKubeAPI returning why the probe failed
+func kubeAPIProbe() error {
+	_, err := kubeProbe().Probe(context.Background())
+	return err
 }
 
-func ensureKubeAPIReachableWithTunnel() error {
+// kubeProbe returns the API probe selected by KUBE_PROBE, KUBE_PROBE_TIMEOUT
+// and KUBE_PROBE_TOKEN
+func kubeProbe() probe.Strategy {
+	cfg, err := probe.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; using the kubectl probe\n", err)
+		cfg = probe.Config{}
+	}
+	strategy, err := probe.New(cfg)
+	if err != nil {
+		return probe.Kubectl{}
+	}
+	return strategy
+}
+
+// kubeAPIUnreachable returns message with a diagnosis of why the API does not
+// answer: kubeconfig and credentials, the tunnel, TCP and TLS to the server
+func kubeAPIUnreachable(ctx context.Context, message string, tun tunnelParams) error {
+	opts := kubediag.Options{Probe: kubeAPIProbe(), TunnelStart: "netcup-kube ssh tunnel start"}
+	if strings.TrimSpace(tun.Host) != "" {
+		opts.TunnelSocket = tun.manager().GetControlSocket()
+	}
+	return kubediag.Unreachable(ctx, message, opts)
+}
+
+// wgAPIPort and wgProbeTimeout are where and how long the API is probed at
+// WG_SERVER_IP
+var (
+	wgAPIPort      = wireguard.APIPort
+	wgProbeTimeout = 2 * time.Second
+)
+
+// useWireguard points KUBECONFIG at a copy of the kubeconfig that reaches the
+// API at WG_SERVER_IP, as netcup-kube install does, when the API answers
+// there. It returns false when the caller should start the SSH tunnel.
+func useWireguard() bool {
+	serverIP := strings.TrimSpace(os.Getenv("WG_SERVER_IP"))
+	if serverIP == "" || localCluster.Kind != "" {
+		return false
+	}
+	previous, set := os.LookupEnv("KUBECONFIG")
+	kubeconfig := previous
+	if kubeconfig == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return false
+		}
+		kubeconfig = filepath.Join(home, ".kube", "config")
+	}
+	if strings.Contains(kubeconfig, string(os.PathListSeparator)) {
+		fmt.Fprintln(os.Stderr, "KUBECONFIG lists several files; not using WireGuard")
+		return false
+	}
+
+	addr := net.JoinHostPort(serverIP, wgAPIPort)
+	path, err := wireguard.Kubeconfig(kubeconfig, addr, wgProbeTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v; falling back to SSH tunnel\n", err)
+		return false
+	}
+	if err := os.Setenv("KUBECONFIG", path); err != nil {
+		return false
+	}
 	if probeKubeAPI() {
+		fmt.Fprintf(os.Stderr, "kube API unreachable; using WireGuard via %s\n", addr)
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "kube API does not answer over WireGuard (%s); falling back to SSH tunnel\n", addr)
+	if set {
+		_ = os.Setenv("KUBECONFIG", previous)
+	} else {
+		_ = os.Unsetenv("KUBECONFIG")
+	}
+	return false
+}
+
+func ensureKubeAPIReachableWithTunnel(ctx context.Context) error {
+	if probeKubeAPI() || useWireguard() {
 		return nil
 	}
+	if localCluster.Kind != "" {
+		return localClusterUnreachable()
+	}
 
 	tun := tunnelConfig()
 	if strings.TrimSpace(tun.Host) == "" {
 		return fmt.Errorf("kube API is unreachable and no tunnel host configured (set TUNNEL_HOST or --tunnel-host)")
 	}
 
-	mgr := tunnel.New(tun.User, tun.Host, tun.LocalPort, tun.RemoteHost, tun.RemotePort)
-	if !mgr.IsRunning() {
+	mgr := tun.manager()
+	if !mgr.IsRunning(ctx) {
 		fmt.Fprintf(os.Stderr, "kube API unreachable; starting SSH tunnel via %s@%s...\n", tun.User, tun.Host)
-		if err := mgr.Start(); err != nil {
+		if err := mgr.Start(ctx); err != nil {
 			return fmt.Errorf("failed to start SSH tunnel: %w", err)
 		}
 	}
@@ -47,48 +195,122 @@ func ensureKubeAPIReachableWithTunnel() error {
 		time.Sleep(300 * time.Millisecond)
 	}
 
-	return fmt.Errorf("kube API still unreachable after tunnel recovery")
+	return kubeAPIUnreachable(ctx, "kube API still unreachable after tunnel recovery", tun)
 }
 
-// runKubectl runs kubectl with the given arguments, connecting stdio
-func runKubectl(args ...string) error {
-	cmd := exec.Command("kubectl", args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		if recoverErr := ensureKubeAPIReachableWithTunnel(); recoverErr == nil {
-			retry := exec.Command("kubectl", args...)
-			retry.Stdin = os.Stdin
-			retry.Stdout = os.Stdout
-			retry.Stderr = os.Stderr
-			if retryErr := retry.Run(); retryErr == nil {
-				return nil
-			}
+// kubectlRunner is the shared kubectl runner for all netcup-claw commands.
+// Transient failures (dropped tunnel, TLS handshake timeouts, EOF) are retried
+// after re-establishing the SSH tunnel.
+var kubectlRunner = kubectl.New(
+	kubectl.WithTimeout(kubectlTimeout()),
+	kubectl.WithRetries(kubectlRetries()),
+	kubectl.WithRecover(ensureKubeAPIReachableWithTunnel),
+	kubectl.WithOnFailure(func(*kubectl.Error) { invalidateResolverCache() }),
+)
+
+// kubectlTimeout returns the per-call timeout for captured kubectl output from
+// OPENCLAW_KUBECTL_TIMEOUT (a Go duration; "0" disables the timeout).
+func kubectlTimeout() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("OPENCLAW_KUBECTL_TIMEOUT"))
+	if raw == "" {
+		return defaultKubectlTimeout
+	}
+	timeout, err := time.ParseDuration(raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: invalid OPENCLAW_KUBECTL_TIMEOUT %q; using %s\n", raw, defaultKubectlTimeout)
+		return defaultKubectlTimeout
+	}
+	return timeout
+}
+
+// kubectlRetries returns the number of retries for transient kubectl failures
+// from OPENCLAW_KUBECTL_RETRIES.
+func kubectlRetries() int {
+	raw := strings.TrimSpace(os.Getenv("OPENCLAW_KUBECTL_RETRIES"))
+	if raw == "" {
+		return kubectl.DefaultRetries
+	}
+	retries, err := strconv.Atoi(raw)
+	if err != nil || retries < 0 {
+		fmt.Fprintf(os.Stderr, "warning: invalid OPENCLAW_KUBECTL_RETRIES %q; using %d\n", raw, kubectl.DefaultRetries)
+		return kubectl.DefaultRetries
+	}
+	return retries
+}
+
+// maxParallelFlag is --max-parallel; when unset, OPENCLAW_MAX_PARALLEL or
+// defaultMaxParallel applies
+var maxParallelFlag int
+
+// maxParallel is the bound in effect, set by applyMaxParallel
+var maxParallel = defaultMaxParallel
+
+// defaultMaxParallel bounds concurrent kubectl exec and cp calls, so bulk
+// operations do not flood the API server over the SSH tunnel
+const defaultMaxParallel = 8
+
+// kubectlMaxParallel returns the bound on concurrent kubectl exec and cp
+// calls from OPENCLAW_MAX_PARALLEL ("0" removes the bound).
+func kubectlMaxParallel() int {
+	raw := strings.TrimSpace(os.Getenv("OPENCLAW_MAX_PARALLEL"))
+	if raw == "" {
+		return defaultMaxParallel
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		fmt.Fprintf(os.Stderr, "warning: invalid OPENCLAW_MAX_PARALLEL %q; using %d\n", raw, defaultMaxParallel)
+		return defaultMaxParallel
+	}
+	return n
+}
+
+// applyMaxParallel bounds the shared kubectl runner before a command runs:
+// --max-parallel when given, else kubectlMaxParallel
+func applyMaxParallel(cmd *cobra.Command) error {
+	n := kubectlMaxParallel()
+	if f := cmd.Flags().Lookup("max-parallel"); f != nil && f.Changed {
+		if maxParallelFlag < 0 {
+			return fmt.Errorf("--max-parallel must be 0 (no limit) or greater")
 		}
-		return fmt.Errorf("kubectl error: %w", err)
+		n = maxParallelFlag
 	}
+	setMaxParallel(n)
 	return nil
 }
 
-// runKubectlOutput runs kubectl and returns combined output bytes.
-func runKubectlOutput(args ...string) ([]byte, error) {
-	cmd := exec.Command("kubectl", args...)
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	out, err := cmd.Output()
-	if err != nil {
-		if recoverErr := ensureKubeAPIReachableWithTunnel(); recoverErr == nil {
-			retry := exec.Command("kubectl", args...)
-			var retryStderr bytes.Buffer
-			retry.Stderr = &retryStderr
-			retryOut, retryErr := retry.Output()
-			if retryErr == nil {
-				return retryOut, nil
-			}
-			return nil, fmt.Errorf("kubectl error: %w (stderr: %s)", retryErr, strings.TrimSpace(retryStderr.String()))
-		}
-		return nil, fmt.Errorf("kubectl error: %w (stderr: %s)", err, strings.TrimSpace(stderr.String()))
+// setMaxParallel sets the bound in effect on the shared kubectl runner
+func setMaxParallel(n int) {
+	maxParallel = n
+	kubectlRunner.SetMaxParallel(n)
+}
+
+// defaultKubectlTimeout bounds each captured kubectl call. Streaming calls
+// (logs -f, interactive exec) are not subject to a timeout.
+const defaultKubectlTimeout = 5 * time.Minute
+
+// cleanupTimeout bounds the cleanups that still run after an interrupt
+const cleanupTimeout = time.Minute
+
+// runKubectl runs kubectl with the given arguments, connecting stdio
+func runKubectl(ctx context.Context, args ...string) error {
+	return kubectlRunner.Run(ctx, kubectl.Streams{
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}, args...)
+}
+
+// runKubectlOutput runs kubectl and returns stdout bytes. Stderr is captured
+// into the returned error.
+func runKubectlOutput(ctx context.Context, args ...string) ([]byte, error) {
+	return kubectlRunner.Output(ctx, args...)
+}
+
+// kubectlExec adapts the shared runner to openclaw.ExecFunc so resolver
+// lookups get the same timeout and retry policy.
+func kubectlExec(ctx context.Context, name string, args ...string) ([]byte, error) {
+	if name != kubectl.DefaultBinary {
+		return interrupt.Command(ctx, name, args...).Output()
 	}
-	return out, nil
+	return runKubectlOutput(ctx, args...)
 }
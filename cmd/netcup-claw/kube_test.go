@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/testkit"
+)
+
+func TestUseWireguard(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = ln.Close() }()
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	oldPort := wgAPIPort
+	t.Cleanup(func() { wgAPIPort = oldPort })
+	wgAPIPort = port
+
+	kubeconfig := filepath.Join(t.TempDir(), "k3s.yaml")
+	if err := os.WriteFile(kubeconfig, []byte("clusters:\n- cluster:\n    server: https://127.0.0.1:6443\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", kubeconfig)
+	t.Setenv("KUBE_PROBE", "")
+
+	t.Setenv("WG_SERVER_IP", "")
+	if useWireguard() {
+		t.Fatal("useWireguard() = true without WG_SERVER_IP")
+	}
+
+	kit := testkit.New(t)
+	kit.Add(testkit.Rule{Tool: "kubectl", Args: []string{testkit.AnyRest}, Stderr: "connection refused", Exit: 1, Times: 1})
+	kit.On("kubectl", []string{testkit.AnyRest}, "ok")
+	t.Setenv("WG_SERVER_IP", host)
+
+	// The API does not answer over WireGuard either: keep the kubeconfig
+	if useWireguard() {
+		t.Fatal("useWireguard() = true although the probe failed")
+	}
+	if got := os.Getenv("KUBECONFIG"); got != kubeconfig {
+		t.Errorf("KUBECONFIG = %s after a failed probe, want %s", got, kubeconfig)
+	}
+
+	if !useWireguard() {
+		t.Fatal("useWireguard() = false with a reachable WireGuard API")
+	}
+	wgKubeconfig := os.Getenv("KUBECONFIG")
+	if wgKubeconfig != filepath.Join(filepath.Dir(kubeconfig), "k3s-wireguard.yaml") {
+		t.Fatalf("KUBECONFIG = %s, want the WireGuard copy", wgKubeconfig)
+	}
+	if data, err := os.ReadFile(wgKubeconfig); err != nil || !strings.Contains(string(data), "server: https://"+ln.Addr().String()) {
+		t.Errorf("WireGuard kubeconfig = %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/kubediag"
+)
+
+// localClusterEnv enables local cluster mode like --local-cluster
+const localClusterEnv = "NETCUP_CLAW_LOCAL_CLUSTER"
+
+var localClusterFlag bool
+
+// localCluster is the kind or k3d cluster of this invocation in local cluster
+// mode; Kind is empty otherwise
+var localCluster struct {
+	Kind    string
+	Context string
+}
+
+// currentKubeContext returns the current kubeconfig context; a variable so
+// tests can replace it
+var currentKubeContext = func() (string, error) {
+	out, err := exec.Command("kubectl", "config", "current-context").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the current kube context: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// localClusterKind returns "kind" or "k3d" for the contexts those tools
+// create (kind-<name>, k3d-<name>), and "" for any other context
+func localClusterKind(context string) string {
+	for _, kind := range []string{"kind", "k3d"} {
+		if name, ok := strings.CutPrefix(context, kind+"-"); ok && name != "" {
+			return kind
+		}
+	}
+	return ""
+}
+
+// localClusterEnabled reports whether --local-cluster or
+// NETCUP_CLAW_LOCAL_CLUSTER asks for local cluster mode
+func localClusterEnabled() bool {
+	if localClusterFlag {
+		return true
+	}
+	raw := strings.TrimSpace(os.Getenv(localClusterEnv))
+	if raw == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: invalid %s %q; local cluster mode stays off\n", localClusterEnv, raw)
+		return false
+	}
+	return enabled
+}
+
+// applyLocalCluster switches to local cluster mode when enabled: the current
+// kube context must be a kind or k3d cluster. The SSH tunnel is then never
+// started, and resolver lookups are neither cached across invocations nor
+// shared with the remote installs, since local clusters are recreated often.
+func applyLocalCluster() error {
+	if !localClusterEnabled() {
+		return nil
+	}
+	context, err := currentKubeContext()
+	if err != nil {
+		return err
+	}
+	kind := localClusterKind(context)
+	if kind == "" {
+		return fmt.Errorf("--local-cluster: current kube context %q is not a kind or k3d cluster (switch with: kubectl config use-context kind-<name>)", context)
+	}
+	localCluster.Kind, localCluster.Context = kind, context
+	return nil
+}
+
+// localClusterUnreachable is the error when the API of the local cluster does
+// not answer; there is no tunnel to start
+func localClusterUnreachable() error {
+	hint := "kind get clusters"
+	if localCluster.Kind == "k3d" {
+		hint = "k3d cluster list"
+	}
+	message := fmt.Sprintf("kube API of local %s cluster %s is unreachable; is it running? (%s)", localCluster.Kind, localCluster.Context, hint)
+	return kubediag.Unreachable(context.Background(), message, kubediag.Options{Context: localCluster.Context, Probe: kubeAPIProbe()})
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&localClusterFlag, "local-cluster", false, "Run against the current kind/k3d context without the SSH tunnel (default: $"+localClusterEnv+")")
+}
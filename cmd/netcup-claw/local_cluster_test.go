@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalClusterKind(t *testing.T) {
+	tests := map[string]string{
+		"kind-dev":       "kind",
+		"k3d-openclaw":   "k3d",
+		"kind-":          "",
+		"default":        "",
+		"netcup-prod":    "",
+		"kindergarten-1": "",
+	}
+	for context, want := range tests {
+		if got := localClusterKind(context); got != want {
+			t.Errorf("localClusterKind(%q) = %q, want %q", context, got, want)
+		}
+	}
+}
+
+func TestApplyLocalCluster(t *testing.T) {
+	defer func(orig func() (string, error)) { currentKubeContext = orig }(currentKubeContext)
+	reset := func() {
+		localClusterFlag = false
+		localCluster.Kind, localCluster.Context = "", ""
+	}
+	defer reset()
+
+	reset()
+	t.Setenv(localClusterEnv, "")
+	currentKubeContext = func() (string, error) { return "kind-dev", nil }
+	if err := applyLocalCluster(); err != nil || localCluster.Kind != "" {
+		t.Fatalf("disabled: kind %q, err %v", localCluster.Kind, err)
+	}
+
+	t.Setenv(localClusterEnv, "true")
+	if err := applyLocalCluster(); err != nil || localCluster.Kind != "kind" || localCluster.Context != "kind-dev" {
+		t.Fatalf("enabled: %+v, err %v", localCluster, err)
+	}
+	if ttl := resolverCacheTTL(); ttl != 0 {
+		t.Errorf("resolver cache TTL = %s, want 0", ttl)
+	}
+	if scope := resolverCacheScope("openclaw"); scope != "kind-dev-openclaw" {
+		t.Errorf("resolver cache scope = %q", scope)
+	}
+	t.Setenv("OPENCLAW_RESOLVE_CACHE_TTL", "30s")
+	if ttl := resolverCacheTTL(); ttl != 30*time.Second {
+		t.Errorf("explicit resolver cache TTL = %s", ttl)
+	}
+	if err := localClusterUnreachable(); !strings.Contains(err.Error(), "kind get clusters") {
+		t.Errorf("unreachable error = %v", err)
+	}
+
+	reset()
+	t.Setenv(localClusterEnv, "")
+	localClusterFlag = true
+	currentKubeContext = func() (string, error) { return "netcup-prod", nil }
+	if err := applyLocalCluster(); err == nil || !strings.Contains(err.Error(), "not a kind or k3d cluster") {
+		t.Errorf("production context: err %v", err)
+	}
+}
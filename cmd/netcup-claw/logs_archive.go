@@ -0,0 +1,370 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/keyring"
+	"github.com/mfittko/netcup-kube/internal/objectstore"
+	"github.com/mfittko/netcup-kube/internal/paths"
+	"github.com/mfittko/netcup-kube/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsArchiveEndpoint  string
+	logsArchiveBucket    string
+	logsArchiveRegion    string
+	logsArchivePrefix    string
+	logsArchiveContainer string
+	logsArchiveStateDir  string
+	logsArchiveRateLimit string
+	logsArchiveInterval  time.Duration
+	logsArchiveOnce      bool
+)
+
+// logChunkLayout names spool files and objects by the hour they cover
+const logChunkLayout = "2006010215"
+
+// logStreamState tracks how far one pod container's log has been spooled
+type logStreamState struct {
+	// LastTimestamp is the kubelet timestamp of the last spooled line
+	LastTimestamp string `json:"lastTimestamp"`
+	// Offset is the number of log bytes spooled from this stream so far
+	Offset int64 `json:"offset"`
+}
+
+// logArchiveState is persisted in <state-dir>/state.json
+type logArchiveState struct {
+	Streams map[string]*logStreamState `json:"streams"`
+}
+
+// logStream identifies one container log; its parts are DNS labels, so they
+// never contain '_' and can be joined into spool file names
+type logStream struct {
+	Namespace string
+	Pod       string
+	Container string
+}
+
+func (s logStream) key() string {
+	return s.Namespace + "/" + s.Pod + "/" + s.Container
+}
+
+var logsArchiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Ship OpenClaw logs to S3-compatible storage in hourly gzip chunks",
+	Long: `Archive OpenClaw container logs to S3-compatible object storage without a
+logging stack.
+
+Each run fetches only log lines newer than the last archived line
+(kubectl logs --timestamps --since-time) and appends them to hourly spool files
+in --state-dir. Hours that are over are gzipped and uploaded as
+<prefix>/<namespace>/<pod>/<container>/YYYY/MM/DD/HH.log.gz, then removed from
+the spool. Progress (last timestamp and byte offset per container) is kept in
+<state-dir>/state.json, so an interrupted run resumes where it stopped and a
+failed upload is retried on the next run.
+
+The object store is configured with --endpoint/--bucket/--region or
+S3_ENDPOINT, S3_BUCKET and AWS_REGION; credentials are read from
+AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY only. Uploads use path-style URLs
+and can be throttled with --rate-limit (bytes per second, e.g. 512Ki).
+
+Without --once the archiver keeps running and repeats every --interval.
+
+Examples:
+  netcup-claw logs archive --once
+  netcup-claw logs archive --endpoint https://s3.example.com --bucket ops-logs --rate-limit 1Mi
+  netcup-claw logs archive --interval 5m --container litellm`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		client, err := logArchiveClient()
+		if err != nil {
+			return err
+		}
+		stateDir := logsArchiveStateDir
+		if stateDir == "" {
+			stateDir = filepath.Join(paths.StateDir("netcup-claw"), "logs-archive")
+		}
+		spoolDir := filepath.Join(stateDir, "spool")
+		if err := os.MkdirAll(spoolDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create spool directory: %w", err)
+		}
+		statePath := filepath.Join(stateDir, "state.json")
+		state, err := loadLogArchiveState(statePath)
+		if err != nil {
+			return err
+		}
+
+		run := func() error {
+			cfg, pod, err := resolveOpenClawPod(ctx)
+			if err != nil {
+				return err
+			}
+			stream := logStream{Namespace: cfg.Namespace, Pod: pod, Container: logsArchiveContainer}
+			st := state.Streams[stream.key()]
+			if st == nil {
+				st = &logStreamState{}
+				state.Streams[stream.key()] = st
+			}
+			logArgs := []string{"-n", stream.Namespace, "logs", stream.Pod, "-c", stream.Container, "--timestamps"}
+			if st.LastTimestamp != "" {
+				logArgs = append(logArgs, "--since-time="+st.LastTimestamp)
+			}
+			out, err := runKubectlOutput(ctx, logArgs...)
+			if err != nil {
+				return fmt.Errorf("failed to fetch logs of %s: %w", stream.key(), err)
+			}
+			n, err := spoolLogLines(spoolDir, stream, st, out)
+			if err != nil {
+				return err
+			}
+			if err := saveLogArchiveState(statePath, state); err != nil {
+				return err
+			}
+			if n > 0 {
+				fmt.Printf("spooled %d bytes from %s\n", n, stream.key())
+			}
+			return uploadClosedLogChunks(ctx, client, spoolDir, time.Now())
+		}
+
+		if err := run(); err != nil {
+			if logsArchiveOnce {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+		if logsArchiveOnce {
+			return nil
+		}
+		ticker := time.NewTicker(logsArchiveInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := run(); err != nil {
+					fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+				}
+			}
+		}
+	},
+}
+
+func logArchiveClient() (*objectstore.Client, error) {
+	client := &objectstore.Client{
+		Endpoint: firstNonEmpty(logsArchiveEndpoint, os.Getenv("S3_ENDPOINT")),
+		Bucket:   firstNonEmpty(logsArchiveBucket, os.Getenv("S3_BUCKET")),
+		Region:   firstNonEmpty(logsArchiveRegion, os.Getenv("AWS_REGION")),
+	}
+	var err error
+	if client.AccessKey, err = keyring.Resolve(os.Getenv("AWS_ACCESS_KEY_ID")); err != nil {
+		return nil, err
+	}
+	if client.SecretKey, err = keyring.Resolve(os.Getenv("AWS_SECRET_ACCESS_KEY")); err != nil {
+		return nil, err
+	}
+	if client.Endpoint == "" || client.Bucket == "" {
+		return nil, fmt.Errorf("object storage is not configured (set --endpoint/--bucket or S3_ENDPOINT/S3_BUCKET)")
+	}
+	if client.AccessKey == "" || client.SecretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	if limit := strings.TrimSpace(logsArchiveRateLimit); limit != "" {
+		rate, err := storage.ParseQuantity(limit)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid --rate-limit %q (expected bytes per second such as 512Ki or 2M)", limit)
+		}
+		client.BytesPerSecond = rate
+	}
+	return client, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// spoolLogLines appends lines of `kubectl logs --timestamps` output that are
+// newer than st.LastTimestamp to the hourly spool files of stream, advancing
+// st. It returns the number of bytes spooled.
+func spoolLogLines(spoolDir string, stream logStream, st *logStreamState, output []byte) (int64, error) {
+	var last time.Time
+	if st.LastTimestamp != "" {
+		t, err := time.Parse(time.RFC3339Nano, st.LastTimestamp)
+		if err != nil {
+			return 0, fmt.Errorf("invalid archived timestamp %q for %s: %w", st.LastTimestamp, stream.key(), err)
+		}
+		last = t
+	}
+
+	chunks := map[string]*bytes.Buffer{}
+	var hours []string
+	var spooled int64
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		stamp, _, _ := strings.Cut(line, " ")
+		ts, err := time.Parse(time.RFC3339Nano, stamp)
+		if err != nil || !ts.After(last) {
+			// --since-time has second precision, so already archived
+			// lines of that second come back and are dropped here
+			continue
+		}
+		hour := ts.UTC().Format(logChunkLayout)
+		buf, ok := chunks[hour]
+		if !ok {
+			buf = &bytes.Buffer{}
+			chunks[hour] = buf
+			hours = append(hours, hour)
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+		spooled += int64(len(line)) + 1
+		last = ts
+		st.LastTimestamp = stamp
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read logs of %s: %w", stream.key(), err)
+	}
+
+	for _, hour := range hours {
+		path := filepath.Join(spoolDir, strings.Join([]string{stream.Namespace, stream.Pod, stream.Container, hour}, "_")+".log")
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return 0, fmt.Errorf("failed to open spool file: %w", err)
+		}
+		_, err = f.Write(chunks[hour].Bytes())
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to write spool file %s: %w", path, err)
+		}
+	}
+	st.Offset += spooled
+	return spooled, nil
+}
+
+// logChunkKey returns the object key of a spool file name, and whether the
+// hour it covers ended before now
+func logChunkKey(prefix, name string, now time.Time) (key string, closed bool, ok bool) {
+	parts := strings.Split(strings.TrimSuffix(name, ".log"), "_")
+	if len(parts) != 4 || !strings.HasSuffix(name, ".log") {
+		return "", false, false
+	}
+	hour, err := time.Parse(logChunkLayout, parts[3])
+	if err != nil {
+		return "", false, false
+	}
+	key = strings.Join([]string{parts[0], parts[1], parts[2], hour.Format("2006/01/02/15")}, "/") + ".log.gz"
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		key = prefix + "/" + key
+	}
+	// Allow a minute for lines of the last second to be fetched
+	return key, now.After(hour.Add(time.Hour + time.Minute)), true
+}
+
+// uploadClosedLogChunks gzips and uploads spool files of finished hours,
+// removing each one once it is stored
+func uploadClosedLogChunks(ctx context.Context, client *objectstore.Client, spoolDir string, now time.Time) error {
+	entries, err := os.ReadDir(spoolDir)
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key, closed, ok := logChunkKey(logsArchivePrefix, name, now)
+		if !ok || !closed {
+			continue
+		}
+		path := filepath.Join(spoolDir, name)
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read spool file: %w", err)
+		}
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		if _, err := zw.Write(raw); err != nil {
+			return fmt.Errorf("failed to compress %s: %w", name, err)
+		}
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf("failed to compress %s: %w", name, err)
+		}
+		if err := client.Put(ctx, key, gz.Bytes(), "application/gzip"); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove spool file: %w", err)
+		}
+		fmt.Printf("archived %s (%d bytes, %d compressed)\n", key, len(raw), gz.Len())
+	}
+	return nil
+}
+
+func loadLogArchiveState(path string) (*logArchiveState, error) {
+	state := &logArchiveState{Streams: map[string]*logStreamState{}}
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive state %s: %w", path, err)
+	}
+	if err := json.Unmarshal(payload, state); err != nil {
+		return nil, fmt.Errorf("invalid archive state %s: %w", path, err)
+	}
+	if state.Streams == nil {
+		state.Streams = map[string]*logStreamState{}
+	}
+	return state, nil
+}
+
+func saveLogArchiveState(path string, state *logArchiveState) error {
+	payload, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode archive state: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(payload, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write archive state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write archive state: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	logsArchiveCmd.Flags().StringVar(&logsArchiveEndpoint, "endpoint", "", "S3-compatible endpoint URL (default: S3_ENDPOINT)")
+	logsArchiveCmd.Flags().StringVar(&logsArchiveBucket, "bucket", "", "Target bucket (default: S3_BUCKET)")
+	logsArchiveCmd.Flags().StringVar(&logsArchiveRegion, "region", "", "Signing region (default: AWS_REGION or us-east-1)")
+	logsArchiveCmd.Flags().StringVar(&logsArchivePrefix, "prefix", "openclaw-logs", "Object key prefix")
+	logsArchiveCmd.Flags().StringVarP(&logsArchiveContainer, "container", "c", openclawMainContainer, "Container whose logs are archived")
+	logsArchiveCmd.Flags().StringVar(&logsArchiveStateDir, "state-dir", "", "Spool and progress directory (default: $XDG_STATE_HOME/netcup-claw/logs-archive)")
+	logsArchiveCmd.Flags().StringVar(&logsArchiveRateLimit, "rate-limit", "", "Upload bandwidth limit in bytes per second (e.g. 512Ki, 2M)")
+	logsArchiveCmd.Flags().DurationVar(&logsArchiveInterval, "interval", time.Minute, "Time between runs")
+	logsArchiveCmd.Flags().BoolVar(&logsArchiveOnce, "once", false, "Run once and exit")
+	logsCmd.AddCommand(logsArchiveCmd)
+}
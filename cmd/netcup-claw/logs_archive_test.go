@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSpoolLogLines(t *testing.T) {
+	dir := t.TempDir()
+	stream := logStream{Namespace: "openclaw", Pod: "openclaw-abc", Container: "main"}
+	st := &logStreamState{}
+	out := []byte("2026-10-16T09:59:58.100000000Z first\n" +
+		"2026-10-16T10:00:01.200000000Z second\n" +
+		"not a log line\n")
+	n, err := spoolLogLines(dir, stream, st, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 75 || st.Offset != 75 || st.LastTimestamp != "2026-10-16T10:00:01.200000000Z" {
+		t.Fatalf("n=%d state=%+v", n, st)
+	}
+
+	// A later fetch with --since-time repeats lines of the same second
+	out = []byte("2026-10-16T10:00:01.200000000Z second\n" +
+		"2026-10-16T10:00:01.300000000Z third\n")
+	if n, err = spoolLogLines(dir, stream, st, out); err != nil {
+		t.Fatal(err)
+	}
+	if n != 37 {
+		t.Errorf("expected only the new line to be spooled, got %d bytes", n)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "openclaw_openclaw-abc_main_2026101610.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2026-10-16T10:00:01.200000000Z second\n2026-10-16T10:00:01.300000000Z third\n"
+	if string(got) != want {
+		t.Errorf("spool = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "openclaw_openclaw-abc_main_2026101609.log")); err != nil {
+		t.Errorf("expected 09h spool file: %v", err)
+	}
+}
+
+func TestLogChunkKey(t *testing.T) {
+	now := time.Date(2026, 10, 16, 11, 0, 30, 0, time.UTC)
+	key, closed, ok := logChunkKey("/openclaw-logs/", "openclaw_openclaw-abc_main_2026101609.log", now)
+	if !ok || !closed || key != "openclaw-logs/openclaw/openclaw-abc/main/2026/10/16/09.log.gz" {
+		t.Errorf("got %q closed=%v ok=%v", key, closed, ok)
+	}
+	if _, closed, _ := logChunkKey("", "openclaw_openclaw-abc_main_2026101610.log", now); closed {
+		t.Error("expected the previous hour to stay open within its grace minute")
+	}
+	if _, _, ok := logChunkKey("", "state.json.tmp", now); ok {
+		t.Error("expected non-spool file to be ignored")
+	}
+}
@@ -2,8 +2,11 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,11 +14,22 @@ import (
 	"regexp"
 	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/helmmirror"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/keyring"
+	"github.com/mfittko/netcup-kube/internal/kubectl"
 	"github.com/mfittko/netcup-kube/internal/openclaw"
+	"github.com/mfittko/netcup-kube/internal/openclawapi"
+	"github.com/mfittko/netcup-kube/internal/pins"
 	"github.com/mfittko/netcup-kube/internal/portforward"
+	"github.com/mfittko/netcup-kube/internal/registry"
+	"github.com/mfittko/netcup-kube/internal/releases"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
 	"github.com/mfittko/netcup-kube/internal/tunnel"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +41,7 @@ var (
 	pfNamespace  string
 	pfLocalPort  string
 	pfRemotePort string
+	pfStatusJSON bool
 
 	// Tunnel flags
 	tunHost       string
@@ -34,11 +49,16 @@ var (
 	tunLocalPort  string
 	tunRemoteHost string
 	tunRemotePort string
+	tunBindAddr   string
 
 	agentsWorkspaceDir    string
+	agentsConcurrency     int
 	approvalsWorkspaceDir string
 	approvalsDeployFile   string
 	approvalsBackupPath   string
+	approvalsDeployYes    bool
+	approvalsReviewList   bool
+	approvalsReviewDryRun bool
 	cronWorkspaceDir      string
 	cronDeployFile        string
 	cronBackupPath        string
@@ -50,6 +70,10 @@ var (
 	skillName             string
 	skillsPullAll         bool
 	skillsExclude         []string
+	skillsListLocal       bool
+	skillsDeployAll       bool
+	skillsDeployExclude   []string
+	skillsReload          bool
 	secretsEnvFile        string
 	secretsName           string
 	secretsCreateMissing  bool
@@ -57,17 +81,20 @@ var (
 	configWorkspaceDir    string
 	configDeployFile      string
 	configBackupPath      string
+	configDeployYes       bool
 
 	// Upgrade flags
 	upgradeVersion       string
 	upgradeDryRun        bool
 	upgradeSkipPinUpdate bool
 	upgradeForce         bool
+	upgradeManifest      string
+	upgradeReleases      []string
+	upgradeChannel       string
 )
 
 const (
 	openclawMainContainer = "main"
-	openclawCLIPath       = "/app/openclaw.mjs"
 )
 
 var rootCmd = &cobra.Command{
@@ -78,9 +105,31 @@ var rootCmd = &cobra.Command{
 port-forwarding, pod command execution, logs, and health/status checks.
 
 It automatically bootstraps the SSH tunnel when the Kubernetes API is
-unreachable, providing a first-class operator experience.`,
+unreachable, providing a first-class operator experience.
+
+With --local-cluster (or NETCUP_CLAW_LOCAL_CLUSTER=true) it runs against the
+current kind or k3d context instead: the tunnel is never started and service
+and pod lookups are not cached across invocations, for development, tests
+and demos.`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyJSONQuery(cmd); err != nil {
+			return err
+		}
+		if err := applyMaxParallel(cmd); err != nil {
+			return err
+		}
+		if !isContextCommand(cmd) {
+			if err := applyContext(); err != nil {
+				return err
+			}
+			if err := applyLocalCluster(); err != nil {
+				return err
+			}
+		}
+		return startCommandHooks(cmd, args)
+	},
 }
 
 // portForwardCmd is the top-level "port-forward" command
@@ -102,38 +151,42 @@ var portForwardStartCmd = &cobra.Command{
 
 Steps:
   1. Probe local Kubernetes API reachability
-  2. If unreachable, ensure SSH tunnel is running
+  2. If unreachable, use WireGuard (WG_SERVER_IP) or ensure SSH tunnel is running
   3. Resolve OpenClaw service target (label lookup with fallback)
   4. Start background kubectl port-forward
   5. Validate local port readiness`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		cfg := openclawConfig()
 
-		// Step 1: Probe kube API
-		if !probeKubeAPI() {
+		// Step 1: Probe kube API, over WireGuard when WG_SERVER_IP is set
+		if !probeKubeAPI() && !useWireguard() {
+			if localCluster.Kind != "" {
+				return localClusterUnreachable()
+			}
 			// Step 2: API unreachable – ensure SSH tunnel is running
 			tun := tunnelConfig()
 			if tun.Host == "" {
 				return fmt.Errorf("kube API is unreachable and no tunnel host configured (set TUNNEL_HOST or --tunnel-host)")
 			}
 
-			mgr := tunnel.New(tun.User, tun.Host, tun.LocalPort, tun.RemoteHost, tun.RemotePort)
-			if !mgr.IsRunning() {
+			mgr := tun.manager()
+			if !mgr.IsRunning(ctx) {
 				fmt.Fprintf(os.Stderr, "kube API unreachable; starting SSH tunnel via %s@%s...\n", tun.User, tun.Host)
-				if err := mgr.Start(); err != nil {
+				if err := mgr.Start(ctx); err != nil {
 					return fmt.Errorf("failed to start SSH tunnel: %w", err)
 				}
 			}
 
 			// Re-probe after tunnel start
 			if !probeKubeAPI() {
-				return fmt.Errorf("kube API still unreachable after starting SSH tunnel; check tunnel config and kubeconfig")
+				return kubeAPIUnreachable(ctx, "kube API still unreachable after starting SSH tunnel", tun)
 			}
 		}
 
 		// Step 3: Resolve service target
-		resolver := openclaw.New(cfg, nil)
-		svcTarget, err := resolver.ResolveService()
+		resolver := newOpenClawResolver(cfg)
+		svcTarget, err := resolver.ResolveService(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to resolve OpenClaw service: %w", err)
 		}
@@ -186,12 +239,29 @@ var portForwardStatusCmd = &cobra.Command{
 		mgr := pfManager(cfg, "")
 		st := mgr.Status()
 
+		if pfStatusJSON {
+			return printPortForwardHealth(cfg, st)
+		}
+
 		fmt.Printf("state:      %s\n", st.State)
 		fmt.Printf("namespace:  %s\n", cfg.Namespace)
 		fmt.Printf("port:       %s\n", cfg.LocalPort)
+		if st.Target != "" {
+			target := st.Target
+			if st.RemotePort != "" {
+				target += ":" + st.RemotePort
+			}
+			fmt.Printf("target:     %s\n", target)
+		}
 		if st.PID > 0 {
 			fmt.Printf("pid:        %d\n", st.PID)
 		}
+		if !st.StartedAt.IsZero() {
+			fmt.Printf("started:    %s (%s ago)\n", st.StartedAt.Local().Format(time.RFC3339), time.Since(st.StartedAt).Round(time.Second))
+		}
+		if st.Restarts > 0 {
+			fmt.Printf("restarts:   %d\n", st.Restarts)
+		}
 		if st.LogFile != "" {
 			fmt.Printf("log:        %s\n", st.LogFile)
 		}
@@ -205,29 +275,38 @@ var portForwardStatusCmd = &cobra.Command{
 
 // runCmd executes a shell command on the main pod
 var runCmd = &cobra.Command{
-	Use:   "run <shell command...>",
+	Use:   "run [--pod <name> | --all-pods [--selector <labels>] [--concurrency <n>]] [--container <name>] <shell command...>",
 	Short: "Run a shell command on the main OpenClaw pod",
 	Long: `Execute a shell command on the main OpenClaw pod container.
 
 The command is executed as:
   sh -lc "<your command>"
 
+--pod runs the command in the named pod instead of the first resolved one,
+e.g. during a rollout while two pods exist; --container picks the container
+(default: main). --selector picks the pod by label instead of the OpenClaw
+selector; kubectl then chooses the container unless --container is given. With --all-pods the
+command runs in every running pod matching the selector at once, or in at
+most --concurrency pods at a time (capped by --max-parallel): each output
+line is prefixed with "[<pod>] ", a table of per-pod exit codes follows, and
+the run fails if any pod failed. These flags must come before the command;
+use -- if the command itself starts with one of them.
+
 Examples:
   netcup-claw run ls -la /app
   netcup-claw run env | grep OPENCLAW
   netcup-claw run "cat /home/node/.openclaw/openclaw.json"
+  netcup-claw run --pod openclaw-7d9f8-abcde ls /tmp
+  netcup-claw run --all-pods df -h /home/node
+  netcup-claw run --selector app=openclaw --all-pods "cat /etc/hostname"
+  netcup-claw run --all-pods --concurrency 2 du -sh /home/node
+  netcup-claw run --all-pods --max-parallel 2 du -sh /home/node
   netcup-claw run --help`,
 	Args:               cobra.MinimumNArgs(1),
 	DisableFlagParsing: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
-		if err != nil {
-			return err
-		}
-
-		execArgs := buildShellRunKubectlArgs(cfg.Namespace, pod, args)
-
-		return runKubectl(execArgs...)
+		ctx := cmd.Context()
+		return runShellCommand(ctx, args)
 	},
 }
 
@@ -237,19 +316,50 @@ var openclawCmd = &cobra.Command{
 	Short: "Run OpenClaw CLI commands on the main pod",
 	Long: `Execute OpenClaw CLI commands in the main OpenClaw pod container.
 
+With --json the output of the command (run with --json) is captured instead
+of streamed, checked to be valid JSON and printed compactly, so scripts get a
+clean document. --pretty indents it; --query selects values with a subset
+of jq (paths such as .key, .list[0] or .list[].key, |, select) and prints one
+JSON value per line. Both imply --json. Arguments after -- are passed on unchanged.
+
+--pod and --container, given before the subcommand, pick the pod and
+container instead of the main container of the first resolved pod.
+
 Examples:
   netcup-claw openclaw status
   netcup-claw openclaw logs --follow
-  netcup-claw openclaw security audit --deep`,
+  netcup-claw openclaw security audit --deep
+  netcup-claw openclaw cron list --json --pretty
+  netcup-claw openclaw status --query .gateway
+  netcup-claw openclaw --pod openclaw-7d9f8-abcde status`,
 	Args:               cobra.MinimumNArgs(1),
 	DisableFlagParsing: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		args, target, err := parsePodFlags(args)
+		if err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("missing OpenClaw subcommand")
+		}
+		args, jsonOpts, err := parseOpenClawJSONFlags(args)
+		if err != nil {
+			return err
+		}
+		cfg, pod, err := resolveTargetPod(ctx, target.Pod)
 		if err != nil {
 			return err
 		}
+		api := openclawAPI(cfg.Namespace, pod)
+		if target.Container != "" {
+			api.Container = target.Container
+		}
+		if jsonOpts.JSON {
+			return runOpenClawJSON(ctx, api, args, jsonOpts)
+		}
 
-		execArgs := buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, args)
+		execArgs := api.Args(args...)
 		useTTY := hasTerminalStdio()
 		if useTTY {
 			execArgs = withKubectlExecTTY(execArgs)
@@ -257,7 +367,7 @@ Examples:
 			return fmt.Errorf("command requires an interactive TTY")
 		}
 
-		return runKubectl(execArgs...)
+		return runKubectl(ctx, execArgs...)
 	},
 }
 
@@ -279,19 +389,14 @@ func buildShellRunKubectlArgs(namespace, pod string, args []string) []string {
 }
 
 func buildOpenClawCLIKubectlArgs(namespace, pod string, args []string) []string {
+	return openclawAPI(namespace, pod).Args(args...)
+}
 
-	execArgs := []string{
-		"-n", namespace,
-		"exec",
-		"-c", openclawMainContainer,
-		pod,
-		"--",
-		"node",
-		"--no-warnings",
-		openclawCLIPath,
-	}
-
-	return append(execArgs, args...)
+// openclawAPI returns a typed client for the OpenClaw CLI in pod
+func openclawAPI(namespace, pod string) *openclawapi.Client {
+	api := openclawapi.New(kubectlRunner, namespace, pod)
+	api.Container = openclawMainContainer
+	return api
 }
 
 func openclawArgsRequireTTY(args []string) bool {
@@ -335,11 +440,6 @@ func withKubectlExecTTY(args []string) []string {
 	return updated
 }
 
-type agentListEntry struct {
-	ID        string `json:"id"`
-	Workspace string `json:"workspace"`
-}
-
 func shellQuote(value string) string {
 	return "'" + strings.ReplaceAll(value, "'", "'\"'\"'") + "'"
 }
@@ -351,79 +451,151 @@ func localAgentWorkspaceDir() string {
 	return "scripts/recipes/openclaw/agent-workspace"
 }
 
-func resolveOpenClawPod() (openclaw.Config, string, error) {
+func resolveOpenClawPod(ctx context.Context) (openclaw.Config, string, error) {
 	cfg := openclawConfig()
-	if err := ensureKubeAPIReachableWithTunnel(); err != nil {
+	if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
 		return cfg, "", err
 	}
-	resolver := openclaw.New(cfg, nil)
-	pod, err := resolver.ResolvePod()
+	resolver := newOpenClawResolver(cfg)
+	pod, err := resolver.ResolvePod(ctx)
 	if err != nil {
 		return cfg, "", fmt.Errorf("failed to resolve OpenClaw pod: %w", err)
 	}
 	return cfg, pod, nil
 }
 
-func fetchAgentList(cfg openclaw.Config, pod string) ([]agentListEntry, []byte, error) {
-	out, err := runKubectlOutput(
-		"-n", cfg.Namespace,
-		"exec",
-		"-c", openclawMainContainer,
-		pod,
-		"--",
-		"node",
-		openclawCLIPath,
-		"agents",
-		"list",
-		"--json",
-	)
-	if err != nil {
-		return nil, nil, err
+// filterWorkspaceAgents drops agents without an id or workspace path.
+func filterWorkspaceAgents(agents []openclawapi.Agent) []openclawapi.Agent {
+	filtered := make([]openclawapi.Agent, 0, len(agents))
+	for _, agent := range agents {
+		if strings.TrimSpace(agent.ID) == "" || strings.TrimSpace(agent.Workspace) == "" {
+			continue
+		}
+		filtered = append(filtered, agent)
 	}
+	return filtered
+}
 
-	var agents []agentListEntry
-	if err := json.Unmarshal(out, &agents); err != nil {
-		return nil, nil, fmt.Errorf("failed to parse agents list json: %w", err)
+func agentIDs(agents []openclawapi.Agent) []string {
+	ids := make([]string, len(agents))
+	for i, agent := range agents {
+		ids[i] = agent.ID
 	}
-
-	return agents, out, nil
+	return ids
 }
 
-func fetchApprovalsSnapshot(cfg openclaw.Config, pod string) ([]byte, error) {
-	out, err := runKubectlOutput(buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, []string{"approvals", "get", "--json"})...)
+// backupAgentWorkspace pulls the top-level markdown files of one agent workspace
+// into <backupRoot>/<agent id> and returns the number of files written.
+func backupAgentWorkspace(ctx context.Context, cfg openclaw.Config, pod string, agent openclawapi.Agent, backupRoot string) (int, error) {
+	agentBackupDir := filepath.Join(backupRoot, agent.ID)
+	if err := os.MkdirAll(agentBackupDir, 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create backup directory %s: %w", agentBackupDir, err)
+	}
+
+	// The archive is unpacked while kubectl streams it instead of being
+	// buffered in memory
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := kubectlRunner.Run(ctx, kubectl.Streams{Stdout: pw},
+			"-n", cfg.Namespace,
+			"exec",
+			"-c", openclawMainContainer,
+			pod,
+			"--",
+			"sh",
+			"-lc",
+			buildWorkspaceMarkdownTarScript(agent.Workspace),
+		)
+		_ = pw.CloseWithError(err)
+		done <- err
+	}()
+	count, extractErr := extractMarkdownTarGz(pr, agentBackupDir)
+	if extractErr == nil {
+		// Read the tar padding and gzip trailer so kubectl can finish
+		_, _ = io.Copy(io.Discard, pr)
+	}
+	_ = pr.Close()
+	runErr := <-done
+	switch {
+	case runErr != nil && (extractErr == nil || errors.Is(extractErr, runErr)):
+		// kubectl failed first; the unpack error only reports the cut stream
+		return count, fmt.Errorf("failed to archive workspace markdown files: %w", runErr)
+	case extractErr != nil:
+		return count, fmt.Errorf("failed to unpack workspace markdown files: %w", extractErr)
+	}
+	return count, nil
+}
+
+// deployAgentOverrides copies the markdown overrides in <overridesRoot>/<agent id>
+// into the agent workspace and returns the number of files applied. Agents
+// without an override directory are skipped.
+func deployAgentOverrides(ctx context.Context, cfg openclaw.Config, pod string, agent openclawapi.Agent, overridesRoot string) (int, error) {
+	agentOverrideDir := filepath.Join(overridesRoot, agent.ID)
+	entries, err := os.ReadDir(agentOverrideDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch approvals snapshot: %w", err)
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read overrides: %w", err)
 	}
-	return out, nil
-}
 
-func normalizeApprovalsPayload(payload []byte) ([]byte, error) {
-	var asMap map[string]json.RawMessage
-	if err := json.Unmarshal(payload, &asMap); err != nil {
-		return nil, fmt.Errorf("invalid approvals JSON: %w", err)
+	if err := runKubectl(
+		ctx,
+		"-n", cfg.Namespace,
+		"exec",
+		"-c", openclawMainContainer,
+		pod,
+		"--",
+		"sh",
+		"-lc",
+		fmt.Sprintf("mkdir -p %s", shellQuote(agent.Workspace)),
+	); err != nil {
+		return 0, fmt.Errorf("failed to ensure workspace directory: %w", err)
 	}
 
-	if rawFile, ok := asMap["file"]; ok && len(rawFile) > 0 {
-		var inner any
-		if err := json.Unmarshal(rawFile, &inner); err != nil {
-			return nil, fmt.Errorf("invalid approvals snapshot envelope (field 'file'): %w", err)
+	applied := 0
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
 		}
-		normalized, err := json.Marshal(inner)
-		if err != nil {
-			return nil, fmt.Errorf("failed to normalize approvals snapshot envelope: %w", err)
+		name := entry.Name()
+		if !strings.HasSuffix(strings.ToLower(name), ".md") {
+			continue
 		}
-		return normalized, nil
-	}
 
-	var direct any
-	if err := json.Unmarshal(payload, &direct); err != nil {
-		return nil, fmt.Errorf("invalid approvals JSON: %w", err)
-	}
-	normalized, err := json.Marshal(direct)
-	if err != nil {
-		return nil, fmt.Errorf("failed to normalize approvals JSON: %w", err)
+		sourcePath := filepath.Join(agentOverrideDir, name)
+		tmpPath := agent.Workspace + "/." + name + ".netcup-claw"
+		targetPath := agent.Workspace + "/" + name
+
+		if err := runKubectl(
+			ctx,
+			"-n", cfg.Namespace,
+			"cp",
+			sourcePath,
+			pod+":"+tmpPath,
+			"-c", openclawMainContainer,
+		); err != nil {
+			return applied, fmt.Errorf("failed to copy override %s: %w", name, err)
+		}
+
+		if err := runKubectl(
+			ctx,
+			"-n", cfg.Namespace,
+			"exec",
+			"-c", openclawMainContainer,
+			pod,
+			"--",
+			"sh",
+			"-lc",
+			fmt.Sprintf("mv %s %s && chmod 0644 %s", shellQuote(tmpPath), shellQuote(targetPath), shellQuote(targetPath)),
+		); err != nil {
+			return applied, fmt.Errorf("failed to place override %s: %w", name, err)
+		}
+
+		applied++
 	}
-	return normalized, nil
+	return applied, nil
 }
 
 func prettyJSON(payload []byte) ([]byte, error) {
@@ -470,6 +642,17 @@ func writeSnapshotBackup(backupPath, prefix string, payload []byte) (string, err
 	return backupFile, nil
 }
 
+// applyApprovalsPayload applies an approvals JSON payload with
+// "openclaw approvals set" and prints the CLI output.
+func applyApprovalsPayload(ctx context.Context, cfg openclaw.Config, pod string, payload []byte) error {
+	out, err := openclawAPI(cfg.Namespace, pod).ApprovalsSet(ctx, payload)
+	if err != nil {
+		return err
+	}
+	_, _ = os.Stdout.Write(out)
+	return nil
+}
+
 func localApprovalsWorkspaceDir() string {
 	if strings.TrimSpace(approvalsWorkspaceDir) != "" {
 		return approvalsWorkspaceDir
@@ -506,9 +689,10 @@ func remoteSkillDir(skill string) string {
 	return remoteSkillsRootDir() + "/" + skill
 }
 
-func listRemoteSkillNames(cfg openclaw.Config, pod string) ([]string, error) {
+func listRemoteSkillNames(ctx context.Context, cfg openclaw.Config, pod string) ([]string, error) {
 	root := remoteSkillsRootDir()
 	out, err := runKubectlOutput(
+		ctx,
 		"-n", cfg.Namespace,
 		"exec",
 		"-c", openclawMainContainer,
@@ -571,7 +755,7 @@ func resolveSelectedSkill(args []string) (string, error) {
 	return selected, nil
 }
 
-func copyRemoteSkillToLocal(cfg openclaw.Config, pod, skill, targetDir string) error {
+func copyRemoteSkillToLocal(ctx context.Context, cfg openclaw.Config, pod, skill, targetDir string) error {
 	if strings.TrimSpace(skill) == "" {
 		return fmt.Errorf("skill name cannot be empty")
 	}
@@ -588,6 +772,7 @@ func copyRemoteSkillToLocal(cfg openclaw.Config, pod, skill, targetDir string) e
 	}
 
 	if err := runKubectl(
+		ctx,
 		"-n", cfg.Namespace,
 		"cp",
 		pod+":"+remoteSkillDir(skill)+"/.",
@@ -599,7 +784,7 @@ func copyRemoteSkillToLocal(cfg openclaw.Config, pod, skill, targetDir string) e
 	return nil
 }
 
-func backupRemoteSkillSnapshot(cfg openclaw.Config, pod, skill, backupPath string) (string, error) {
+func backupRemoteSkillSnapshot(ctx context.Context, cfg openclaw.Config, pod, skill, backupPath string) (string, error) {
 	resolvedPath := strings.TrimSpace(backupPath)
 	if resolvedPath == "" {
 		return "", nil
@@ -610,14 +795,14 @@ func backupRemoteSkillSnapshot(cfg openclaw.Config, pod, skill, backupPath strin
 		return "", fmt.Errorf("failed to create skill backup dir %s: %w", snapshotRoot, err)
 	}
 
-	if err := copyRemoteSkillToLocal(cfg, pod, skill, snapshotRoot); err != nil {
+	if err := copyRemoteSkillToLocal(ctx, cfg, pod, skill, snapshotRoot); err != nil {
 		return "", err
 	}
 
 	return filepath.Join(snapshotRoot, skill), nil
 }
 
-func deployLocalSkillToRemote(cfg openclaw.Config, pod, skill, sourceDir string) error {
+func deployLocalSkillToRemote(ctx context.Context, cfg openclaw.Config, pod, skill, sourceDir string) error {
 	if strings.TrimSpace(skill) == "" {
 		return fmt.Errorf("skill name cannot be empty")
 	}
@@ -633,8 +818,12 @@ func deployLocalSkillToRemote(cfg openclaw.Config, pod, skill, sourceDir string)
 	if filepath.Base(sourceDir) != skill {
 		return fmt.Errorf("skill source dir basename (%s) must match --skill (%s)", filepath.Base(sourceDir), skill)
 	}
+	if _, err := validateSkillDir(sourceDir); err != nil {
+		return err
+	}
 
 	if err := runKubectl(
+		ctx,
 		"-n", cfg.Namespace,
 		"exec",
 		"-c", openclawMainContainer,
@@ -648,6 +837,7 @@ func deployLocalSkillToRemote(cfg openclaw.Config, pod, skill, sourceDir string)
 	}
 
 	if err := runKubectl(
+		ctx,
 		"-n", cfg.Namespace,
 		"cp",
 		sourceDir,
@@ -660,8 +850,43 @@ func deployLocalSkillToRemote(cfg openclaw.Config, pod, skill, sourceDir string)
 	return nil
 }
 
-func fetchCronJobsSnapshot(cfg openclaw.Config, pod string) ([]byte, error) {
+func removeRemoteSkill(ctx context.Context, cfg openclaw.Config, pod, skill string) error {
+	if err := validateSkillName(skill); err != nil {
+		return err
+	}
+	if err := runKubectl(
+		ctx,
+		"-n", cfg.Namespace,
+		"exec",
+		"-c", openclawMainContainer,
+		pod,
+		"--",
+		"sh",
+		"-lc",
+		fmt.Sprintf("rm -rf %s", shellQuote(remoteSkillDir(skill))),
+	); err != nil {
+		return fmt.Errorf("failed to remove remote skill %s: %w", skill, err)
+	}
+	return nil
+}
+
+// reloadOpenClawSkills restarts the OpenClaw deployment so the gateway
+// rebuilds its skills snapshot from the workspace.
+func reloadOpenClawSkills(ctx context.Context, cfg openclaw.Config) error {
+	fmt.Printf("reloading skills: restarting deployment/%s in namespace %s...\n", deployedConfigDeploymentName(), cfg.Namespace)
+	if err := runKubectl(ctx, "-n", cfg.Namespace, "rollout", "restart", "deployment/"+deployedConfigDeploymentName()); err != nil {
+		return fmt.Errorf("failed to restart deployment: %w", err)
+	}
+	if err := waitForOpenClawRollout(ctx, cfg); err != nil {
+		return fmt.Errorf("deployment restart triggered but rollout did not complete: %w", err)
+	}
+	fmt.Println("skills reload complete")
+	return nil
+}
+
+func fetchCronJobsSnapshot(ctx context.Context, cfg openclaw.Config, pod string) ([]byte, error) {
 	out, err := runKubectlOutput(
+		ctx,
 		"-n", cfg.Namespace,
 		"exec",
 		"-c", openclawMainContainer,
@@ -899,7 +1124,7 @@ func cronJobSpecEqualForSync(desired cronJobSpec, current cronJobSpec) bool {
 	return reflect.DeepEqual(desired, current)
 }
 
-func runOpenClawCronDelete(cfg openclaw.Config, pod, jobID string) error {
+func runOpenClawCronDelete(ctx context.Context, cfg openclaw.Config, pod, jobID string) error {
 	trimmedID := strings.TrimSpace(jobID)
 	if trimmedID == "" {
 		return fmt.Errorf("job id is required")
@@ -913,7 +1138,7 @@ func runOpenClawCronDelete(cfg openclaw.Config, pod, jobID string) error {
 
 	errs := make([]string, 0, len(candidates))
 	for _, args := range candidates {
-		err := runKubectl(buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, args)...)
+		err := runKubectl(ctx, buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, args)...)
 		if err == nil {
 			return nil
 		}
@@ -1005,9 +1230,10 @@ func deployedConfigDeploymentName() string {
 	return "openclaw"
 }
 
-func fetchDeployedConfig(cfg openclaw.Config) ([]byte, error) {
+func fetchDeployedConfig(ctx context.Context, cfg openclaw.Config) ([]byte, error) {
 	pathExpr := "{.data.openclaw\\.json}"
 	out, err := runKubectlOutput(
+		ctx,
 		"-n", cfg.Namespace,
 		"get",
 		"configmap",
@@ -1036,8 +1262,9 @@ var configBackupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "Pull current deployed OpenClaw config into local backup path",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		cfg := openclawConfig()
-		payload, err := fetchDeployedConfig(cfg)
+		payload, err := fetchDeployedConfig(ctx, cfg)
 		if err != nil {
 			return err
 		}
@@ -1061,8 +1288,9 @@ var configPullCmd = &cobra.Command{
 	Use:   "pull",
 	Short: "Pull current deployed OpenClaw config into local workspace file",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		cfg := openclawConfig()
-		payload, err := fetchDeployedConfig(cfg)
+		payload, err := fetchDeployedConfig(ctx, cfg)
 		if err != nil {
 			return err
 		}
@@ -1093,7 +1321,8 @@ var configDeployCmd = &cobra.Command{
 	Use:     "deploy",
 	Aliases: []string{"push"},
 	Short:   "Deploy local OpenClaw config to ConfigMap and restart",
-	RunE: func(cmd *cobra.Command, args []string) error {
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		ctx := cmd.Context()
 		cfg := openclawConfig()
 
 		inputPath := strings.TrimSpace(configDeployFile)
@@ -1111,13 +1340,29 @@ var configDeployCmd = &cobra.Command{
 			return fmt.Errorf("invalid JSON in %s: %w", inputPath, err)
 		}
 
+		if err := confirm.New(configDeployYes).Confirm(fmt.Sprintf("replace the OpenClaw config in namespace %s with %s and restart", cfg.Namespace, inputPath)); err != nil {
+			return err
+		}
+
+		// Every deploy past the confirmation is logged for `netcup-claw timeline`
+		audit := newConfigDeployEntry(cfg.Namespace, inputPath, payload)
+		defer func() {
+			audit.Success = err == nil
+			if err != nil {
+				audit.Error = err.Error()
+			}
+			if auditErr := appendConfigDeployEntry(configDeployAuditPath(), audit); auditErr != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to record the config deploy: %v\n", auditErr)
+			}
+		}()
+
 		backupPath := strings.TrimSpace(configBackupPath)
 		if backupPath == "" {
 			backupPath = filepath.Join(localConfigWorkspaceDir(), "backup")
 		}
 
 		if backupPath != "off" {
-			existing, err := fetchDeployedConfig(cfg)
+			existing, err := fetchDeployedConfig(ctx, cfg)
 			if err != nil {
 				return err
 			}
@@ -1126,11 +1371,13 @@ var configDeployCmd = &cobra.Command{
 				return err
 			}
 			if backupFile != "" {
+				audit.Backup = backupFile
 				fmt.Printf("config backup saved: %s\n", backupFile)
 			}
 		}
 
 		generated, err := runKubectlOutput(
+			ctx,
 			"-n", cfg.Namespace,
 			"create",
 			"configmap",
@@ -1162,15 +1409,15 @@ var configDeployCmd = &cobra.Command{
 			_ = os.Remove(tmpPath)
 		}()
 
-		if err := runKubectl("-n", cfg.Namespace, "apply", "-f", tmpPath); err != nil {
+		if err := runKubectl(ctx, "-n", cfg.Namespace, "apply", "-f", tmpPath); err != nil {
 			return fmt.Errorf("failed to apply configmap: %w", err)
 		}
 
-		if err := runKubectl("-n", cfg.Namespace, "rollout", "restart", "deployment/"+deployedConfigDeploymentName()); err != nil {
+		if err := runKubectl(ctx, "-n", cfg.Namespace, "rollout", "restart", "deployment/"+deployedConfigDeploymentName()); err != nil {
 			return fmt.Errorf("failed to restart deployment: %w", err)
 		}
 
-		if err := runKubectl("-n", cfg.Namespace, "rollout", "status", "deployment/"+deployedConfigDeploymentName(), "--timeout=180s"); err != nil {
+		if err := waitForOpenClawRollout(ctx, cfg); err != nil {
 			return fmt.Errorf("deployment rollout did not complete: %w", err)
 		}
 
@@ -1193,14 +1440,15 @@ var agentsBackupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "Pull existing workspace markdown files for all agents into backup/",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
-		agents, raw, err := fetchAgentList(cfg, pod)
+		agents, raw, err := openclawAPI(cfg.Namespace, pod).AgentsList(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to list agents: %w", err)
+			return err
 		}
 
 		workspaceRoot := localAgentWorkspaceDir()
@@ -1212,65 +1460,24 @@ var agentsBackupCmd = &cobra.Command{
 			return fmt.Errorf("failed to write agents.list.json: %w", err)
 		}
 
-		filesBackedUp := 0
-		for _, agent := range agents {
-			if strings.TrimSpace(agent.ID) == "" || strings.TrimSpace(agent.Workspace) == "" {
-				continue
-			}
-
-			agentBackupDir := filepath.Join(backupRoot, agent.ID)
-			if err := os.MkdirAll(agentBackupDir, 0o755); err != nil {
-				return fmt.Errorf("failed to create backup directory %s: %w", agentBackupDir, err)
-			}
-
-			listOut, err := runKubectlOutput(
-				"-n", cfg.Namespace,
-				"exec",
-				"-c", openclawMainContainer,
-				pod,
-				"--",
-				"sh",
-				"-lc",
-				fmt.Sprintf("find %s -maxdepth 1 -type f -name '*.md' -printf '%%f\\n' 2>/dev/null || true", shellQuote(agent.Workspace)),
-			)
+		targets := filterWorkspaceAgents(agents)
+		counts := make([]int, len(targets))
+		errs := runBounded(len(targets), boundedConcurrency(agentsConcurrency), func(i int) error {
+			count, err := backupAgentWorkspace(ctx, cfg, pod, targets[i], backupRoot)
+			counts[i] = count
 			if err != nil {
-				return fmt.Errorf("failed to list workspace markdown files for agent %s: %w", agent.ID, err)
+				fmt.Fprintf(os.Stderr, "agent %s: backup failed: %v\n", targets[i].ID, err)
 			}
+			return err
+		})
 
-			var names []string
-			for _, line := range strings.Split(strings.TrimSpace(string(listOut)), "\n") {
-				name := strings.TrimSpace(line)
-				if name == "" {
-					continue
-				}
-				names = append(names, name)
-			}
-			sort.Strings(names)
-
-			for _, name := range names {
-				content, err := runKubectlOutput(
-					"-n", cfg.Namespace,
-					"exec",
-					"-c", openclawMainContainer,
-					pod,
-					"--",
-					"sh",
-					"-lc",
-					fmt.Sprintf("cat %s", shellQuote(agent.Workspace+"/"+name)),
-				)
-				if err != nil {
-					return fmt.Errorf("failed to read %s for agent %s: %w", name, agent.ID, err)
-				}
-
-				if err := os.WriteFile(filepath.Join(agentBackupDir, name), content, 0o644); err != nil {
-					return fmt.Errorf("failed to write backup file for agent %s (%s): %w", agent.ID, name, err)
-				}
-				filesBackedUp++
-			}
+		filesBackedUp := 0
+		for _, count := range counts {
+			filesBackedUp += count
 		}
 
 		fmt.Printf("backup complete: %d files -> %s\n", filesBackedUp, backupRoot)
-		return nil
+		return aggregateItemErrors("agents", agentIDs(targets), errs)
 	},
 }
 
@@ -1278,14 +1485,15 @@ var agentsDeployCmd = &cobra.Command{
 	Use:   "deploy",
 	Short: "Deploy local per-agent override markdown files to running agent workspaces",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
-		agents, _, err := fetchAgentList(cfg, pod)
+		agents, _, err := openclawAPI(cfg.Namespace, pod).AgentsList(ctx)
 		if err != nil {
-			return fmt.Errorf("failed to list agents: %w", err)
+			return err
 		}
 
 		workspaceRoot := localAgentWorkspaceDir()
@@ -1294,76 +1502,24 @@ var agentsDeployCmd = &cobra.Command{
 			return fmt.Errorf("agent overrides directory not found: %s", overridesRoot)
 		}
 
-		applied := 0
-		for _, agent := range agents {
-			if strings.TrimSpace(agent.ID) == "" || strings.TrimSpace(agent.Workspace) == "" {
-				continue
-			}
-
-			agentOverrideDir := filepath.Join(overridesRoot, agent.ID)
-			entries, err := os.ReadDir(agentOverrideDir)
+		targets := filterWorkspaceAgents(agents)
+		counts := make([]int, len(targets))
+		errs := runBounded(len(targets), boundedConcurrency(agentsConcurrency), func(i int) error {
+			count, err := deployAgentOverrides(ctx, cfg, pod, targets[i], overridesRoot)
+			counts[i] = count
 			if err != nil {
-				if os.IsNotExist(err) {
-					continue
-				}
-				return fmt.Errorf("failed to read overrides for agent %s: %w", agent.ID, err)
+				fmt.Fprintf(os.Stderr, "agent %s: deploy failed: %v\n", targets[i].ID, err)
 			}
+			return err
+		})
 
-			if err := runKubectl(
-				"-n", cfg.Namespace,
-				"exec",
-				"-c", openclawMainContainer,
-				pod,
-				"--",
-				"sh",
-				"-lc",
-				fmt.Sprintf("mkdir -p %s", shellQuote(agent.Workspace)),
-			); err != nil {
-				return fmt.Errorf("failed to ensure workspace directory for agent %s: %w", agent.ID, err)
-			}
-
-			for _, entry := range entries {
-				if entry.IsDir() {
-					continue
-				}
-				name := entry.Name()
-				if !strings.HasSuffix(strings.ToLower(name), ".md") {
-					continue
-				}
-
-				sourcePath := filepath.Join(agentOverrideDir, name)
-				tmpPath := agent.Workspace + "/." + name + ".netcup-claw"
-				targetPath := agent.Workspace + "/" + name
-
-				if err := runKubectl(
-					"-n", cfg.Namespace,
-					"cp",
-					sourcePath,
-					pod+":"+tmpPath,
-					"-c", openclawMainContainer,
-				); err != nil {
-					return fmt.Errorf("failed to copy override %s for agent %s: %w", name, agent.ID, err)
-				}
-
-				if err := runKubectl(
-					"-n", cfg.Namespace,
-					"exec",
-					"-c", openclawMainContainer,
-					pod,
-					"--",
-					"sh",
-					"-lc",
-					fmt.Sprintf("mv %s %s && chmod 0644 %s", shellQuote(tmpPath), shellQuote(targetPath), shellQuote(targetPath)),
-				); err != nil {
-					return fmt.Errorf("failed to place override %s for agent %s: %w", name, agent.ID, err)
-				}
-
-				applied++
-			}
+		applied := 0
+		for _, count := range counts {
+			applied += count
 		}
 
 		fmt.Printf("deploy complete: %d files applied from %s\n", applied, overridesRoot)
-		return nil
+		return aggregateItemErrors("agents", agentIDs(targets), errs)
 	},
 }
 
@@ -1375,19 +1531,21 @@ var approvalsCmd = &cobra.Command{
 Sub-commands:
   backup  - Pull current approvals snapshot into local backup path
   pull    - Pull current approvals snapshot into local workspace file
-  deploy  - Push local approvals JSON to runtime with optional pre-change backup`,
+  deploy  - Push local approvals JSON to runtime with optional pre-change backup
+  review  - Interactively approve or deny runtime entries missing from the baseline`,
 }
 
 var approvalsBackupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "Pull current approvals snapshot into local backup path",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
-		snapshot, err := fetchApprovalsSnapshot(cfg, pod)
+		snapshot, err := openclawAPI(cfg.Namespace, pod).ApprovalsGet(ctx)
 		if err != nil {
 			return err
 		}
@@ -1397,7 +1555,7 @@ var approvalsBackupCmd = &cobra.Command{
 			backupPath = filepath.Join(localApprovalsWorkspaceDir(), "backup")
 		}
 
-		backupFile, err := writeApprovalsBackup(backupPath, snapshot)
+		backupFile, err := writeApprovalsBackup(backupPath, snapshot.Raw)
 		if err != nil {
 			return err
 		}
@@ -1411,22 +1569,18 @@ var approvalsPullCmd = &cobra.Command{
 	Use:   "pull",
 	Short: "Pull current approvals snapshot into local workspace file",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
-		if err != nil {
-			return err
-		}
-
-		snapshot, err := fetchApprovalsSnapshot(cfg, pod)
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
-		normalizedPayload, err := normalizeApprovalsPayload(snapshot)
+		snapshot, err := openclawAPI(cfg.Namespace, pod).ApprovalsGet(ctx)
 		if err != nil {
 			return err
 		}
 
-		prettyPayload, err := prettyJSON(normalizedPayload)
+		prettyPayload, err := prettyJSON(snapshot.File)
 		if err != nil {
 			return err
 		}
@@ -1453,6 +1607,7 @@ var approvalsDeployCmd = &cobra.Command{
 	Aliases: []string{"push"},
 	Short:   "Deploy local approvals JSON to runtime",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		inputPath := strings.TrimSpace(approvalsDeployFile)
 		if inputPath == "" {
 			inputPath = filepath.Join(localApprovalsWorkspaceDir(), "approvals.json")
@@ -1470,27 +1625,31 @@ var approvalsDeployCmd = &cobra.Command{
 			return fmt.Errorf("failed to read approvals file %s: %w", inputPath, err)
 		}
 
-		normalizedPayload, err := normalizeApprovalsPayload(payload)
+		normalizedPayload, err := openclawapi.NormalizeApprovals(payload)
 		if err != nil {
 			return err
 		}
 
-		cfg, pod, err := resolveOpenClawPod()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
+		if err := confirm.New(approvalsDeployYes).Confirm(fmt.Sprintf("replace the runtime approvals of %s/%s with %s", cfg.Namespace, pod, inputPath)); err != nil {
+			return err
+		}
+
 		backupPath := strings.TrimSpace(approvalsBackupPath)
 		if backupPath == "" {
 			backupPath = filepath.Join(localApprovalsWorkspaceDir(), "backup")
 		}
 
 		if backupPath != "off" {
-			snapshot, err := fetchApprovalsSnapshot(cfg, pod)
+			snapshot, err := openclawAPI(cfg.Namespace, pod).ApprovalsGet(ctx)
 			if err != nil {
 				return err
 			}
-			backupFile, err := writeApprovalsBackup(backupPath, snapshot)
+			backupFile, err := writeApprovalsBackup(backupPath, snapshot.Raw)
 			if err != nil {
 				return err
 			}
@@ -1499,90 +1658,196 @@ var approvalsDeployCmd = &cobra.Command{
 			}
 		}
 
-		tmpLocalFile, err := os.CreateTemp("", "netcup-claw-approvals-*.json")
-		if err != nil {
-			return fmt.Errorf("failed to create temporary approvals file: %w", err)
-		}
-		tmpLocalPath := tmpLocalFile.Name()
-		if _, err := tmpLocalFile.Write(normalizedPayload); err != nil {
-			_ = tmpLocalFile.Close()
-			_ = os.Remove(tmpLocalPath)
-			return fmt.Errorf("failed to write temporary approvals file: %w", err)
-		}
-		if err := tmpLocalFile.Close(); err != nil {
-			_ = os.Remove(tmpLocalPath)
-			return fmt.Errorf("failed to close temporary approvals file: %w", err)
-		}
-		defer func() {
-			_ = os.Remove(tmpLocalPath)
-		}()
-
-		remoteTempPath := "/tmp/netcup-claw-approvals.json"
-		if err := runKubectl(
-			"-n", cfg.Namespace,
-			"cp",
-			tmpLocalPath,
-			pod+":"+remoteTempPath,
-			"-c", openclawMainContainer,
-		); err != nil {
-			return fmt.Errorf("failed to upload approvals file: %w", err)
-		}
-
-		if err := runKubectl(buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, []string{"approvals", "set", "--file", remoteTempPath, "--json"})...); err != nil {
-			return fmt.Errorf("failed to apply approvals file: %w", err)
+		if err := applyApprovalsPayload(ctx, cfg, pod, normalizedPayload); err != nil {
+			return err
 		}
 
-		_ = runKubectl(
-			"-n", cfg.Namespace,
-			"exec",
-			"-c", openclawMainContainer,
-			pod,
-			"--",
-			"sh",
-			"-lc",
-			fmt.Sprintf("rm -f %s", shellQuote(remoteTempPath)),
-		)
-
 		fmt.Printf("deploy complete: %s\n", inputPath)
 		return nil
 	},
 }
 
-var cronCmd = &cobra.Command{
-	Use:   "cron",
-	Short: "Backup, pull, or sync OpenClaw cron jobs",
-	Long: `Manage OpenClaw cron jobs state against the running pod.
-
-Sub-commands:
-  backup  - Pull current cron jobs snapshot into local backup path
-  pull    - Pull current cron jobs snapshot into local workspace file
-	deploy  - Sync local jobs to scheduler (default, with optional pre-change backup)
-	sync    - Upsert local jobs via openclaw cron edit/add to refresh scheduler state
-	delete  - Delete a specific runtime cron job by id (or by name lookup)`,
-}
+var approvalsReviewCmd = &cobra.Command{
+	Use:   "review",
+	Short: "Interactively approve or deny runtime approvals missing from the baseline",
+	Long: `Review runtime allowlist entries that are not in the project approvals
+baseline (for example patterns added by "allow always" decisions in chat).
 
-func syncCronJobsFromFile(inputPath string) error {
-	payload, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to read cron jobs file %s: %w", inputPath, err)
-	}
+Each pending entry is shown one at a time:
+  approve - keep it in the runtime and add it to the local baseline file
+  deny    - remove it from the runtime via 'openclaw approvals set'
+  skip    - leave it pending for a later review
 
-	normalizedPayload, err := normalizeCronJobsPayload(payload)
-	if err != nil {
-		return err
-	}
+Nothing is written until the summary is confirmed. A runtime backup is taken
+before denied entries are removed (disable with --backup-path off).
 
-	file, err := parseCronJobsFile(normalizedPayload)
-	if err != nil {
-		return err
-	}
+Examples:
+  netcup-claw approvals review
+  netcup-claw approvals review --list
+  netcup-claw approvals review --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		baselinePath := strings.TrimSpace(approvalsDeployFile)
+		if baselinePath == "" {
+			baselinePath = filepath.Join(localApprovalsWorkspaceDir(), "approvals.json")
+		}
+		baselinePayload, err := os.ReadFile(baselinePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read approvals file %s: %w", baselinePath, err)
+		}
+		if len(baselinePayload) > 0 {
+			if baselinePayload, err = openclawapi.NormalizeApprovals(baselinePayload); err != nil {
+				return err
+			}
+		}
+		baseline, err := decodeApprovalsDocument(baselinePayload)
+		if err != nil {
+			return err
+		}
 
-	cfg, pod, err := resolveOpenClawPod()
-	if err != nil {
-		return err
+		cfg, pod, err := resolveOpenClawPod(ctx)
+		if err != nil {
+			return err
+		}
+		api := openclawAPI(cfg.Namespace, pod)
+		snapshot, err := api.ApprovalsGet(ctx)
+		if err != nil {
+			return err
+		}
+		runtime, err := decodeApprovalsDocument(snapshot.File)
+		if err != nil {
+			return err
+		}
+
+		entries := pendingApprovalEntries(runtime, baseline)
+		if len(entries) == 0 {
+			fmt.Printf("no pending approvals: runtime matches %s\n", baselinePath)
+			return nil
+		}
+		if approvalsReviewList {
+			for _, entry := range entries {
+				fmt.Printf("%s\t%s\n", entry.Agent, entry.Pattern)
+			}
+			return nil
+		}
+		if !hasTerminalStdio() {
+			return fmt.Errorf("approvals review needs an interactive terminal (use --list to print pending entries)")
+		}
+
+		fmt.Printf("%d pending approval(s) not in %s\n", len(entries), baselinePath)
+		in := bufio.NewReader(os.Stdin)
+		decisions, err := reviewApprovalEntries(in, os.Stdout, entries)
+		if err != nil {
+			return err
+		}
+		approved := countApprovalDecisions(decisions, approvalApproved)
+		denied := countApprovalDecisions(decisions, approvalDenied)
+		if approved+denied == 0 {
+			fmt.Println("no decisions made; nothing to write")
+			return nil
+		}
+
+		fmt.Println("Summary:")
+		printApprovalDecisions(os.Stdout, entries, decisions)
+		if approvalsReviewDryRun {
+			fmt.Printf("[dry-run] would deny %d and approve %d pending approval(s) (baseline: %s)\n", denied, approved, baselinePath)
+			return nil
+		}
+		if !confirmApprovalChanges(in, os.Stdout, fmt.Sprintf("Apply %d approval(s) and %d denial(s)?", approved, denied)) {
+			fmt.Println("aborted; nothing written")
+			return nil
+		}
+
+		if denied > 0 {
+			// Re-read the runtime so entries added during the review are kept
+			if snapshot, err = api.ApprovalsGet(ctx); err != nil {
+				return err
+			}
+			if runtime, err = decodeApprovalsDocument(snapshot.File); err != nil {
+				return err
+			}
+		}
+		runtimeChanged, baselineChanged := applyApprovalDecisions(runtime, baseline, entries, decisions)
+		if runtimeChanged {
+			backupPath := strings.TrimSpace(approvalsBackupPath)
+			if backupPath == "" {
+				backupPath = filepath.Join(localApprovalsWorkspaceDir(), "backup")
+			}
+			if backupPath != "off" {
+				backupFile, err := writeApprovalsBackup(backupPath, snapshot.Raw)
+				if err != nil {
+					return err
+				}
+				if backupFile != "" {
+					fmt.Printf("approvals backup saved: %s\n", backupFile)
+				}
+			}
+			payload, err := json.Marshal(runtime)
+			if err != nil {
+				return fmt.Errorf("failed to encode approvals: %w", err)
+			}
+			if err := applyApprovalsPayload(ctx, cfg, pod, payload); err != nil {
+				return err
+			}
+			fmt.Printf("runtime updated: %d denied approval(s) removed\n", denied)
+		}
+		if baselineChanged {
+			payload, err := json.Marshal(baseline)
+			if err != nil {
+				return fmt.Errorf("failed to encode approvals: %w", err)
+			}
+			prettyPayload, err := prettyJSON(payload)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(baselinePath), 0o755); err != nil {
+				return fmt.Errorf("failed to create target directory for %s: %w", baselinePath, err)
+			}
+			if err := os.WriteFile(baselinePath, prettyPayload, 0o644); err != nil {
+				return fmt.Errorf("failed to write approvals to %s: %w", baselinePath, err)
+			}
+			fmt.Printf("baseline updated: %d approved approval(s) added to %s\n", approved, baselinePath)
+		}
+		return nil
+	},
+}
+
+var cronCmd = &cobra.Command{
+	Use:   "cron",
+	Short: "Backup, pull, or sync OpenClaw cron jobs",
+	Long: `Manage OpenClaw cron jobs state against the running pod.
+
+Sub-commands:
+  backup  - Pull current cron jobs snapshot into local backup path
+  pull    - Pull current cron jobs snapshot into local workspace file
+	deploy  - Sync local jobs to scheduler (default, with optional pre-change backup)
+	sync    - Upsert local jobs via openclaw cron edit/add to refresh scheduler state
+	delete  - Delete a specific runtime cron job by id (or by name lookup)`,
+}
+
+func syncCronJobsFromFile(ctx context.Context, inputPath string) error {
+	payload, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read cron jobs file %s: %w", inputPath, err)
+	}
+
+	normalizedPayload, err := normalizeCronJobsPayload(payload)
+	if err != nil {
+		return err
 	}
 
-	currentSnapshot, err := fetchCronJobsSnapshot(cfg, pod)
+	file, err := parseCronJobsFile(normalizedPayload)
+	if err != nil {
+		return err
+	}
+
+	cfg, pod, err := resolveOpenClawPod(ctx)
+	if err != nil {
+		return err
+	}
+
+	currentSnapshot, err := fetchCronJobsSnapshot(ctx, cfg, pod)
 	if err != nil {
 		return err
 	}
@@ -1647,7 +1912,7 @@ func syncCronJobsFromFile(inputPath string) error {
 				return fmt.Errorf("job %q (%s): %w", resolved.Name, resolved.ID, buildErr)
 			}
 
-			if err := runKubectl(buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, editArgs)...); err != nil {
+			if err := runKubectl(ctx, buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, editArgs)...); err != nil {
 				return fmt.Errorf("failed to sync job %q (%s): %w", resolved.Name, resolved.ID, err)
 			}
 			updated++
@@ -1659,7 +1924,7 @@ func syncCronJobsFromFile(inputPath string) error {
 			return fmt.Errorf("job %q (%s): %w", resolved.Name, resolved.ID, buildErr)
 		}
 
-		if err := runKubectl(buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, addArgs)...); err != nil {
+		if err := runKubectl(ctx, buildOpenClawCLIKubectlArgs(cfg.Namespace, pod, addArgs)...); err != nil {
 			return fmt.Errorf("failed to create job %q (%s): %w", resolved.Name, resolved.ID, err)
 		}
 		created++
@@ -1677,7 +1942,7 @@ func syncCronJobsFromFile(inputPath string) error {
 				continue
 			}
 
-			if err := runOpenClawCronDelete(cfg, pod, id); err != nil {
+			if err := runOpenClawCronDelete(ctx, cfg, pod, id); err != nil {
 				return fmt.Errorf("failed to prune job %q (%s): %w", strings.TrimSpace(current.Name), id, err)
 			}
 			pruned++
@@ -1698,12 +1963,13 @@ var cronBackupCmd = &cobra.Command{
 	Use:   "backup",
 	Short: "Pull current cron jobs snapshot into local backup path",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
-		snapshot, err := fetchCronJobsSnapshot(cfg, pod)
+		snapshot, err := fetchCronJobsSnapshot(ctx, cfg, pod)
 		if err != nil {
 			return err
 		}
@@ -1727,12 +1993,13 @@ var cronPullCmd = &cobra.Command{
 	Use:   "pull",
 	Short: "Pull current cron jobs snapshot into local workspace file",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
-		snapshot, err := fetchCronJobsSnapshot(cfg, pod)
+		snapshot, err := fetchCronJobsSnapshot(ctx, cfg, pod)
 		if err != nil {
 			return err
 		}
@@ -1775,6 +2042,7 @@ This command now uses the same semantics as:
 
 Optional backup still runs first unless --backup-path=off.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		inputPath := strings.TrimSpace(cronDeployFile)
 		if inputPath == "" {
 			inputPath = filepath.Join(localCronWorkspaceDir(), "jobs.json")
@@ -1784,7 +2052,7 @@ Optional backup still runs first unless --backup-path=off.`,
 			return fmt.Errorf("failed to read cron jobs file %s: %w", inputPath, err)
 		}
 
-		cfg, pod, err := resolveOpenClawPod()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
@@ -1795,7 +2063,7 @@ Optional backup still runs first unless --backup-path=off.`,
 		}
 
 		if backupPath != "off" {
-			snapshot, err := fetchCronJobsSnapshot(cfg, pod)
+			snapshot, err := fetchCronJobsSnapshot(ctx, cfg, pod)
 			if err != nil {
 				return err
 			}
@@ -1809,7 +2077,7 @@ Optional backup still runs first unless --backup-path=off.`,
 		}
 
 		fmt.Println("deploy uses sync semantics (scheduler-safe) by default")
-		return syncCronJobsFromFile(inputPath)
+		return syncCronJobsFromFile(ctx, inputPath)
 	},
 }
 
@@ -1825,11 +2093,12 @@ and creates missing jobs via:
 
 It is useful when file-based deploy does not fully refresh scheduler in-memory state.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		inputPath := strings.TrimSpace(cronDeployFile)
 		if inputPath == "" {
 			inputPath = filepath.Join(localCronWorkspaceDir(), "jobs.json")
 		}
-		return syncCronJobsFromFile(inputPath)
+		return syncCronJobsFromFile(ctx, inputPath)
 	},
 }
 
@@ -1845,7 +2114,8 @@ With --name it resolves the current runtime job id by exact name first:
   netcup-claw cron delete --name "Daily GitHub Morning Brief (sofatutor)"`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
@@ -1857,7 +2127,7 @@ With --name it resolves the current runtime job id by exact name first:
 
 		jobID := jobRef
 		if cronDeleteByName {
-			snapshot, err := fetchCronJobsSnapshot(cfg, pod)
+			snapshot, err := fetchCronJobsSnapshot(ctx, cfg, pod)
 			if err != nil {
 				return err
 			}
@@ -1883,7 +2153,7 @@ With --name it resolves the current runtime job id by exact name first:
 			}
 		}
 
-		if err := runOpenClawCronDelete(cfg, pod, jobID); err != nil {
+		if err := runOpenClawCronDelete(ctx, cfg, pod, jobID); err != nil {
 			return err
 		}
 
@@ -1897,23 +2167,33 @@ var skillsCmd = &cobra.Command{
 	Short: "Backup, pull, or deploy OpenClaw skill directories",
 	Long: `Manage OpenClaw skill code under /home/node/.openclaw/workspace/skills.
 
+Each skill directory must contain a SKILL.md whose frontmatter declares a
+name matching the directory and a description. Deploys validate this locally
+before anything is copied, then restart OpenClaw to reload skills (--reload).
+
 Sub-commands:
-  list   - List runtime skills present in OpenClaw workspace
+  list   - List runtime skills (or local workspace skills with --local)
   backup - Pull runtime skill into timestamped local backup path
   pull   - Pull runtime skill(s) into repository workspace path
-  deploy - Push local repository skill to runtime (with optional backup)`,
+  deploy - Push local repository skill(s) to runtime (with optional backup)
+  remove - Delete a runtime skill (with optional backup)`,
 }
 
 var skillsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List runtime skill directories",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		if skillsListLocal {
+			return printLocalSkills(localSkillsWorkspaceDir())
+		}
+
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
-		names, err := listRemoteSkillNames(cfg, pod)
+		names, err := listRemoteSkillNames(ctx, cfg, pod)
 		if err != nil {
 			return err
 		}
@@ -1929,7 +2209,8 @@ var skillsBackupCmd = &cobra.Command{
 	Short: "Backup runtime skill directory into local timestamped snapshot",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
@@ -1944,7 +2225,7 @@ var skillsBackupCmd = &cobra.Command{
 			backupPath = filepath.Join(localSkillsWorkspaceDir(), "backup")
 		}
 
-		backupDir, err := backupRemoteSkillSnapshot(cfg, pod, selectedSkill, backupPath)
+		backupDir, err := backupRemoteSkillSnapshot(ctx, cfg, pod, selectedSkill, backupPath)
 		if err != nil {
 			return err
 		}
@@ -1959,7 +2240,8 @@ var skillsPullCmd = &cobra.Command{
 	Short: "Pull runtime skill directory/directories into repository workspace",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
@@ -1974,7 +2256,7 @@ var skillsPullCmd = &cobra.Command{
 				return fmt.Errorf("do not pass a positional skill when using --all")
 			}
 
-			remoteSkills, listErr := listRemoteSkillNames(cfg, pod)
+			remoteSkills, listErr := listRemoteSkillNames(ctx, cfg, pod)
 			if listErr != nil {
 				return listErr
 			}
@@ -1986,7 +2268,7 @@ var skillsPullCmd = &cobra.Command{
 			}
 
 			for _, name := range targets {
-				if err := copyRemoteSkillToLocal(cfg, pod, name, workspaceRoot); err != nil {
+				if err := copyRemoteSkillToLocal(ctx, cfg, pod, name, workspaceRoot); err != nil {
 					return err
 				}
 				fmt.Printf("pulled: %s\n", filepath.Join(workspaceRoot, name))
@@ -2002,7 +2284,7 @@ var skillsPullCmd = &cobra.Command{
 		}
 
 		targetPath := filepath.Join(workspaceRoot, selectedSkill)
-		if err := copyRemoteSkillToLocal(cfg, pod, selectedSkill, workspaceRoot); err != nil {
+		if err := copyRemoteSkillToLocal(ctx, cfg, pod, selectedSkill, workspaceRoot); err != nil {
 			return err
 		}
 
@@ -2015,47 +2297,197 @@ var skillsDeployCmd = &cobra.Command{
 	Use:     "deploy [skill]",
 	Aliases: []string{"push"},
 	Short:   "Deploy local skill directory to runtime",
-	Args:    cobra.MaximumNArgs(1),
+	Long: `Validate and push local skill directories into the OpenClaw workspace.
+
+Each skill is validated (SKILL.md frontmatter, no symlinks, size limit)
+before the runtime copy is backed up and replaced. With --all every skill in
+the workspace is validated first and nothing is deployed if any is invalid.
+OpenClaw is restarted afterwards to reload skills unless --reload=false.
+
+Examples:
+  netcup-claw skills deploy hormuz-ais-watch
+  netcup-claw skills deploy --all --exclude scratch
+  netcup-claw skills deploy my-skill --source-dir ~/skills/my-skill --reload=false`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		sources, err := resolveSkillDeploySources(args)
 		if err != nil {
 			return err
 		}
+		for _, skill := range sortedKeys(sources) {
+			if _, err := validateSkillDir(sources[skill]); err != nil {
+				return err
+			}
+		}
 
-		selectedSkill, err := resolveSelectedSkill(args)
+		cfg, pod, err := resolveOpenClawPod(ctx)
 		if err != nil {
 			return err
 		}
 
-		sourceDir := strings.TrimSpace(skillsSourceDir)
-		if sourceDir == "" {
-			sourceDir = filepath.Join(localSkillsWorkspaceDir(), selectedSkill)
-		}
-
 		backupPath := strings.TrimSpace(skillsBackupPath)
 		if backupPath == "" {
 			backupPath = filepath.Join(localSkillsWorkspaceDir(), "backup")
 		}
 
+		var remoteSkills []string
 		if backupPath != "off" {
-			backupDir, backupErr := backupRemoteSkillSnapshot(cfg, pod, selectedSkill, backupPath)
-			if backupErr != nil {
-				return backupErr
+			if remoteSkills, err = listRemoteSkillNames(ctx, cfg, pod); err != nil {
+				return err
 			}
-			if backupDir != "" {
-				fmt.Printf("skill backup saved: %s\n", backupDir)
+		}
+
+		for _, skill := range sortedKeys(sources) {
+			if backupPath != "off" && containsString(remoteSkills, skill) {
+				backupDir, backupErr := backupRemoteSkillSnapshot(ctx, cfg, pod, skill, backupPath)
+				if backupErr != nil {
+					return backupErr
+				}
+				if backupDir != "" {
+					fmt.Printf("skill backup saved: %s\n", backupDir)
+				}
+			}
+
+			if err := deployLocalSkillToRemote(ctx, cfg, pod, skill, sources[skill]); err != nil {
+				return err
+			}
+			fmt.Printf("deploy complete: %s -> %s\n", sources[skill], remoteSkillDir(skill))
+		}
+
+		if !skillsReload {
+			fmt.Println("note: restart OpenClaw deployment to reload skills")
+			return nil
+		}
+		return reloadOpenClawSkills(ctx, cfg)
+	},
+}
+
+// resolveSkillDeploySources maps skill names to the local directories to
+// deploy: every workspace skill with --all, otherwise the selected skill.
+func resolveSkillDeploySources(args []string) (map[string]string, error) {
+	workspaceRoot := localSkillsWorkspaceDir()
+	if skillsDeployAll {
+		if len(args) > 0 || strings.TrimSpace(skillsSourceDir) != "" {
+			return nil, fmt.Errorf("do not pass a positional skill or --source-dir when using --all")
+		}
+		names, err := listLocalSkillDirs(workspaceRoot)
+		if err != nil {
+			return nil, err
+		}
+		sources := map[string]string{}
+		for _, name := range filterSkillNames(names, skillsDeployExclude) {
+			sources[name] = filepath.Join(workspaceRoot, name)
+		}
+		if len(sources) == 0 {
+			return nil, fmt.Errorf("no skills to deploy in %s", workspaceRoot)
+		}
+		return sources, nil
+	}
+
+	selectedSkill, err := resolveSelectedSkill(args)
+	if err != nil {
+		return nil, err
+	}
+	sourceDir := strings.TrimSpace(skillsSourceDir)
+	if sourceDir == "" {
+		sourceDir = filepath.Join(workspaceRoot, selectedSkill)
+	}
+	return map[string]string{selectedSkill: sourceDir}, nil
+}
+
+var skillsRemoveCmd = &cobra.Command{
+	Use:     "remove <skill>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a runtime skill directory (with optional backup)",
+	Long: `Delete a skill from the OpenClaw workspace. The runtime copy is backed up
+first (unless --backup-path off) and OpenClaw is restarted to reload skills
+unless --reload=false. The skill must be named explicitly.
+
+Examples:
+  netcup-claw skills remove old-skill
+  netcup-claw skills remove old-skill --backup-path off --reload=false`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		skill := strings.TrimSpace(args[0])
+		if err := validateSkillName(skill); err != nil {
+			return err
+		}
+
+		cfg, pod, err := resolveOpenClawPod(ctx)
+		if err != nil {
+			return err
+		}
+		remoteSkills, err := listRemoteSkillNames(ctx, cfg, pod)
+		if err != nil {
+			return err
+		}
+		if !containsString(remoteSkills, skill) {
+			return fmt.Errorf("skill %s not found in runtime workspace (present: %s)", skill, strings.Join(remoteSkills, ", "))
+		}
+
+		backupPath := strings.TrimSpace(skillsBackupPath)
+		if backupPath == "" {
+			backupPath = filepath.Join(localSkillsWorkspaceDir(), "backup")
+		}
+		if backupPath != "off" {
+			backupDir, err := backupRemoteSkillSnapshot(ctx, cfg, pod, skill, backupPath)
+			if err != nil {
+				return err
 			}
+			fmt.Printf("skill backup saved: %s\n", backupDir)
 		}
 
-		if err := deployLocalSkillToRemote(cfg, pod, selectedSkill, sourceDir); err != nil {
+		if err := removeRemoteSkill(ctx, cfg, pod, skill); err != nil {
 			return err
 		}
+		fmt.Printf("remove complete: %s\n", remoteSkillDir(skill))
 
-		fmt.Printf("deploy complete: %s -> %s\n", sourceDir, remoteSkillDir(selectedSkill))
-		return nil
+		if !skillsReload {
+			fmt.Println("note: restart OpenClaw deployment to reload skills")
+			return nil
+		}
+		return reloadOpenClawSkills(ctx, cfg)
 	},
 }
 
+// printLocalSkills lists workspace skills with their manifest validation status
+func printLocalSkills(root string) error {
+	names, err := listLocalSkillDirs(root)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSTATUS\tDESCRIPTION")
+	invalid := 0
+	for _, name := range names {
+		m, err := validateSkillDir(filepath.Join(root, name))
+		status := "ok"
+		if err != nil {
+			status = "invalid: " + strings.TrimPrefix(err.Error(), "skill "+name+": ")
+			invalid++
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", name, status, m.Description)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d local skills are invalid", invalid, len(names))
+	}
+	return nil
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
 var secretsCmd = &cobra.Command{
 	Use:   "secrets",
 	Short: "Manage OpenClaw Kubernetes secret values",
@@ -2070,9 +2502,13 @@ Precedence:
   1) Values from --env-file (default: .env)
   2) Process environment variables
 
+Values of the form keyring:<name> are replaced by the secret stored with
+"netcup-kube keyring set <name>".
+
 Only known OpenClaw-related keys are synced. Existing secret keys not in this
 set are preserved when patching.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		cfg := openclawConfig()
 
 		processValues := processEnvMap()
@@ -2092,6 +2528,13 @@ set are preserved when patching.`,
 		if len(resolved) == 0 {
 			return fmt.Errorf("no secret values resolved; set env vars or provide --env-file")
 		}
+		for key, value := range resolved {
+			secret, err := keyring.Resolve(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			resolved[key] = secret
+		}
 
 		patchPayload := map[string]any{"stringData": resolved}
 		patchBytes, err := json.Marshal(patchPayload)
@@ -2100,6 +2543,7 @@ set are preserved when patching.`,
 		}
 
 		if err := runKubectl(
+			ctx,
 			"-n", cfg.Namespace,
 			"patch",
 			"secret",
@@ -2117,7 +2561,7 @@ set are preserved when patching.`,
 			for _, key := range sortedKeys(resolved) {
 				createArgs = append(createArgs, "--from-literal="+key+"="+resolved[key])
 			}
-			if createErr := runKubectl(createArgs...); createErr != nil {
+			if createErr := runKubectl(ctx, createArgs...); createErr != nil {
 				return fmt.Errorf("failed to patch or create secret %s: %w", secretsName, createErr)
 			}
 			fmt.Printf("created secret: %s (namespace: %s, keys synced: %d)\n", secretsName, cfg.Namespace, len(resolved))
@@ -2132,10 +2576,10 @@ set are preserved when patching.`,
 
 		if secretsRestart {
 			fmt.Printf("restarting deployment/%s in namespace %s...\n", deployedConfigDeploymentName(), cfg.Namespace)
-			if err := runKubectl("-n", cfg.Namespace, "rollout", "restart", "deployment/"+deployedConfigDeploymentName()); err != nil {
+			if err := runKubectl(ctx, "-n", cfg.Namespace, "rollout", "restart", "deployment/"+deployedConfigDeploymentName()); err != nil {
 				return fmt.Errorf("secret synced but failed to restart deployment: %w", err)
 			}
-			if err := runKubectl("-n", cfg.Namespace, "rollout", "status", "deployment/"+deployedConfigDeploymentName(), "--timeout=180s"); err != nil {
+			if err := waitForOpenClawRollout(ctx, cfg); err != nil {
 				return fmt.Errorf("deployment restart triggered but rollout did not complete: %w", err)
 			}
 			fmt.Println("deployment restart complete")
@@ -2153,10 +2597,12 @@ set are preserved when patching.`,
 const (
 	helmRepoName    = "openclaw"
 	helmRepoURL     = "https://serhanekicii.github.io/openclaw-helm"
-	helmChartRef    = "openclaw/openclaw"
+	helmChartName   = "openclaw"
 	helmReleaseName = "openclaw"
 	recipesConfRel  = "scripts/recipes/recipes.conf"
 	recipesConfKey  = "CHART_VERSION_OPENCLAW"
+
+	releasesManifestRel = "scripts/recipes/openclaw/releases.yaml"
 )
 
 // helmRelease holds the fields we care about from `helm list -o json`.
@@ -2165,6 +2611,7 @@ type helmRelease struct {
 	Chart      string `json:"chart"`
 	AppVersion string `json:"app_version"`
 	Status     string `json:"status"`
+	Revision   string `json:"revision"`
 }
 
 // helmSearchEntry holds a single row from `helm search repo -o json`.
@@ -2174,8 +2621,14 @@ type helmSearchEntry struct {
 	AppVersion string `json:"app_version"`
 }
 
-// chartVersionFromChart extracts the version suffix from a chart string like "openclaw-1.3.18".
+// chartVersionFromChart extracts the version suffix from a chart string like
+// "openclaw-1.3.18" or "openclaw-1.5.0-beta.1".
 func chartVersionFromChart(chart string) string {
+	for i := 0; i+1 < len(chart); i++ {
+		if chart[i] == '-' && chartVersionCore.MatchString(chart[i+1:]) {
+			return chart[i+1:]
+		}
+	}
 	idx := strings.LastIndex(chart, "-")
 	if idx < 0 {
 		return chart
@@ -2183,26 +2636,160 @@ func chartVersionFromChart(chart string) string {
 	return chart[idx+1:]
 }
 
+// chartVersionCore matches a string starting with a major.minor version
+var chartVersionCore = regexp.MustCompile(`^v?[0-9]+\.[0-9]+([.+-]|$)`)
+
+// helmChartMirror returns the chart mirror configured by the HELM_MIRROR_*
+// environment variables, with a keyring: password resolved.
+func helmChartMirror() (helmmirror.Mirror, error) {
+	m := helmmirror.FromEnv(processEnvMap())
+	password, err := keyring.Resolve(m.Password)
+	if err != nil {
+		return m, fmt.Errorf("failed to resolve %s: %w", helmmirror.PasswordKey, err)
+	}
+	m.Password = password
+	return m, nil
+}
+
+// openclawChartRef returns the chart reference to upgrade from: openclaw/openclaw,
+// or the chart in the OCI mirror when one is configured.
+func openclawChartRef() string {
+	return helmmirror.FromEnv(processEnvMap()).ChartRef(helmRepoName, helmChartName)
+}
+
 // helmRepoEnsure ensures the openclaw Helm repo is added and updated.
-func helmRepoEnsure() error {
-	// Idempotent add
-	cmd := exec.Command("helm", "repo", "add", helmRepoName, helmRepoURL)
+func helmRepoEnsure(ctx context.Context) error {
+	return helmRepoEnsureFor(ctx, helmRepoName, helmRepoURL)
+}
+
+// helmRepoEnsureFor ensures a Helm repo is added and updated. With a chart
+// mirror the repo points at the mirror; an OCI mirror needs no repo, only a
+// registry login.
+func helmRepoEnsureFor(ctx context.Context, name, url string) error {
+	m, err := helmChartMirror()
+	if err != nil {
+		return err
+	}
+	repoURL := url
+	if u := m.RepoURL(name); u != "" {
+		fmt.Printf("using chart mirror %s\n", u)
+		if helmmirror.IsOCI(u) {
+			return helmRegistryLogin(ctx, m, u)
+		}
+		repoURL = u
+	}
+
+	// Idempotent add: an existing upstream repo is not an error, while
+	// --force-update re-points an existing repo at the mirror
+	args := []string{"repo", "add", name, repoURL}
+	if repoURL != url {
+		args = append(args, "--force-update")
+	}
+	cmd := interrupt.Command(ctx, "helm", helmCredentialArgs(m, args)...)
+	cmd.Stdin = strings.NewReader(m.Password)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	_ = cmd.Run() // may already exist, ignore error
+	if err := helmRun(ctx, cmd); err != nil && repoURL != url {
+		return fmt.Errorf("helm repo add failed: %w", err)
+	}
 
-	cmd = exec.Command("helm", "repo", "update", helmRepoName)
+	cmd = interrupt.Command(ctx, "helm", "repo", "update", name)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := helmRun(ctx, cmd); err != nil {
 		return fmt.Errorf("helm repo update failed: %w", err)
 	}
 	return nil
 }
 
-// helmLatestStableVersion queries the Helm repo for the latest chart version.
-func helmLatestStableVersion() (string, string, error) {
-	out, err := exec.Command("helm", "search", "repo", helmChartRef, "-o", "json").Output()
+// helmRegistryLogin logs helm into the OCI registry of ref when mirror
+// credentials are configured.
+func helmRegistryLogin(ctx context.Context, m helmmirror.Mirror, ref string) error {
+	if m.Username == "" {
+		return nil
+	}
+	cmd := interrupt.Command(ctx, "helm", helmCredentialArgs(m, []string{"registry", "login", helmmirror.Registry(ref)})...)
+	cmd.Stdin = strings.NewReader(m.Password)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := helmRun(ctx, cmd); err != nil {
+		return fmt.Errorf("helm registry login failed: %w", err)
+	}
+	return nil
+}
+
+// helmCredentialArgs appends the mirror username to args; the password is
+// passed on stdin so it does not show up in the process list.
+func helmCredentialArgs(m helmmirror.Mirror, args []string) []string {
+	if m.Username == "" {
+		return args
+	}
+	return append(args, "--username", m.Username, "--password-stdin")
+}
+
+// helmRun runs a prepared helm command as a "helm <subcommand>" phase of
+// --timings and traces.
+func helmRun(ctx context.Context, cmd *exec.Cmd) error {
+	_, span := telemetry.Start(ctx, helmPhase(cmd.Args[1:]))
+	return span.End(cmd.Run())
+}
+
+// helmOutput runs helm and returns stdout, recorded like helmRun.
+func helmOutput(ctx context.Context, args ...string) ([]byte, error) {
+	_, span := telemetry.Start(ctx, helmPhase(args))
+	out, err := interrupt.Command(ctx, "helm", args...).Output()
+	return out, span.End(err)
+}
+
+// rollbackInterruptedUpgrade rolls release back to revision after Ctrl-C
+// stopped its upgrade, which otherwise stays pending and blocks the next one
+func rollbackInterruptedUpgrade(ctx context.Context, namespace, release, revision string) {
+	if revision == "" {
+		return
+	}
+	ctx, cancel := interrupt.CleanupContext(ctx, 5*time.Minute+cleanupTimeout)
+	defer cancel()
+	fmt.Fprintf(os.Stderr, "upgrade interrupted; rolling %s back to revision %s (Ctrl-C again to abort)\n", release, revision)
+	rollback := interrupt.Command(ctx, "helm", "rollback", release, revision, "-n", namespace, "--wait", "--timeout", "5m")
+	rollback.Stdout = os.Stdout
+	rollback.Stderr = os.Stderr
+	if err := helmRun(ctx, rollback); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: rollback of %s failed: %v (check with: helm history %s -n %s)\n", release, err, release, namespace)
+	}
+}
+
+// helmPhase names a helm invocation by its (sub)command, e.g. "helm repo update"
+func helmPhase(args []string) string {
+	if len(args) == 0 {
+		return "helm"
+	}
+	if (args[0] == "repo" || args[0] == "get") && len(args) > 1 {
+		return "helm " + args[0] + " " + args[1]
+	}
+	return "helm " + args[0]
+}
+
+// helmLatestVersion queries the Helm repo for the latest chart version; devel
+// includes pre-release versions. An OCI chart reference is asked for its chart
+// metadata instead, which describes the latest version.
+func helmLatestVersion(ctx context.Context, chartRef string, devel bool) (string, string, error) {
+	var develArgs []string
+	if devel {
+		develArgs = []string{"--devel"}
+	}
+	if helmmirror.IsOCI(chartRef) {
+		out, err := helmOutput(ctx, append([]string{"show", "chart", chartRef}, develArgs...)...)
+		if err != nil {
+			return "", "", fmt.Errorf("helm show chart failed: %w", err)
+		}
+		version, appVersion := parseChartMetadata(out)
+		if version == "" {
+			return "", "", fmt.Errorf("chart %s has no version", chartRef)
+		}
+		return version, appVersion, nil
+	}
+
+	out, err := helmOutput(ctx, append([]string{"search", "repo", chartRef, "-o", "json"}, develArgs...)...)
 	if err != nil {
 		return "", "", fmt.Errorf("helm search repo failed: %w", err)
 	}
@@ -2213,78 +2800,93 @@ func helmLatestStableVersion() (string, string, error) {
 	}
 
 	for _, e := range entries {
-		if e.Name == helmChartRef {
+		if e.Name == chartRef {
 			return e.Version, e.AppVersion, nil
 		}
 	}
-	return "", "", fmt.Errorf("chart %s not found in search results", helmChartRef)
+	return "", "", fmt.Errorf("chart %s not found in search results", chartRef)
+}
+
+// parseChartMetadata extracts version and appVersion from Chart.yaml content.
+func parseChartMetadata(data []byte) (string, string) {
+	var version, appVersion string
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch key {
+		case "version":
+			version = value
+		case "appVersion":
+			appVersion = value
+		}
+	}
+	return version, appVersion
 }
 
 // helmCurrentRelease queries the deployed Helm release for openclaw.
-func helmCurrentRelease(namespace string) (*helmRelease, error) {
-	out, err := exec.Command("helm", "list", "-n", namespace, "-o", "json").Output()
+func helmCurrentRelease(ctx context.Context, namespace string) (*helmRelease, error) {
+	deployed, err := helmListReleases(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if rel := findHelmRelease(deployed, helmReleaseName); rel != nil {
+		return rel, nil
+	}
+	return nil, fmt.Errorf("no Helm release named %q found in namespace %s", helmReleaseName, namespace)
+}
+
+// helmListReleases returns the Helm releases deployed in namespace.
+func helmListReleases(ctx context.Context, namespace string) ([]helmRelease, error) {
+	out, err := helmOutput(ctx, "list", "-n", namespace, "-o", "json")
 	if err != nil {
 		return nil, fmt.Errorf("helm list failed: %w", err)
 	}
 
-	var releases []helmRelease
-	if err := json.Unmarshal(out, &releases); err != nil {
+	var deployed []helmRelease
+	if err := json.Unmarshal(out, &deployed); err != nil {
 		return nil, fmt.Errorf("failed to parse helm list output: %w", err)
 	}
+	return deployed, nil
+}
 
-	for i := range releases {
-		if releases[i].Name == helmReleaseName {
-			return &releases[i], nil
+// findHelmRelease returns the release named name, or nil.
+func findHelmRelease(deployed []helmRelease, name string) *helmRelease {
+	for i := range deployed {
+		if deployed[i].Name == name {
+			return &deployed[i]
 		}
 	}
-	return nil, fmt.Errorf("no Helm release named %q found in namespace %s", helmReleaseName, namespace)
-}
-
-// updateRecipesConfPin updates CHART_VERSION_OPENCLAW in recipes.conf.
-func updateRecipesConfPin(newVersion string) error {
-	return updateRecipesConfPinAt(recipesConfRel, newVersion)
+	return nil
 }
 
 // updateRecipesConfPinAt updates CHART_VERSION_OPENCLAW in the given file path.
 func updateRecipesConfPinAt(path, newVersion string) error {
-	f, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("failed to open %s: %w", path, err)
-	}
-	defer func() { _ = f.Close() }()
-
-	re := regexp.MustCompile(`^(` + regexp.QuoteMeta(recipesConfKey) + `)=(.*)$`)
-	var lines []string
-	updated := false
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if m := re.FindStringSubmatch(line); m != nil {
-			lines = append(lines, m[1]+"="+newVersion)
-			updated = true
-		} else {
-			lines = append(lines, line)
-		}
-	}
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("failed to read %s: %w", path, err)
-	}
-
-	if !updated {
-		return fmt.Errorf("key %s not found in %s", recipesConfKey, path)
-	}
+	return updateRecipesConfKeyAt(path, recipesConfKey, newVersion)
+}
 
-	content := strings.Join(lines, "\n") + "\n"
-	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
-		return fmt.Errorf("failed to write %s: %w", path, err)
-	}
-	return nil
+// updateRecipesConfKeyAt updates the chart version pinned under key in the
+// given file path, holding its lock so concurrent upgrades do not lose pins.
+func updateRecipesConfKeyAt(path, key, newVersion string) error {
+	return pins.Update(path, func(f *pins.File) error {
+		if err := f.Replace(key, newVersion); err != nil {
+			if errors.Is(err, pins.ErrNotFound) {
+				return fmt.Errorf("key %s not found in %s", key, path)
+			}
+			return err
+		}
+		return nil
+	})
 }
 
-// detectRunningImageTag queries the actual running image tag of the main container.
+// detectRunningImage queries the image of the main container, e.g.
+// ghcr.io/openclaw/openclaw:2026.2.17 (with @<digest> when pinned).
 // Returns empty string if detection fails (non-fatal).
-func detectRunningImageTag(namespace string) string {
+func detectRunningImage(ctx context.Context, namespace string) string {
 	out, err := runKubectlOutput(
+		ctx,
 		"-n", namespace,
 		"get", "deploy", deployedConfigDeploymentName(),
 		"-o", "jsonpath={.spec.template.spec.containers[?(@.name==\"main\")].image}",
@@ -2292,135 +2894,353 @@ func detectRunningImageTag(namespace string) string {
 	if err != nil {
 		return ""
 	}
-	image := strings.TrimSpace(string(out))
-	// Image format: ghcr.io/openclaw/openclaw:2026.2.17
-	if idx := strings.LastIndex(image, ":"); idx >= 0 {
-		return image[idx+1:]
+	return strings.TrimSpace(string(out))
+}
+
+// imageTag returns the tag of an image reference, or empty string.
+func imageTag(image string) string {
+	if image == "" {
+		return ""
+	}
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return ""
 	}
-	return ""
+	return ref.Tag
 }
 
 var upgradeCmd = &cobra.Command{
 	Use:   "upgrade",
-	Short: "Upgrade OpenClaw Helm release to the latest stable chart version",
-	Long: `Upgrade the deployed OpenClaw Helm release to the latest stable version.
-
-Steps:
-  1. Ensure openclaw Helm repo is added and up-to-date
-  2. Query the latest stable chart version
+	Short: "Upgrade OpenClaw Helm releases to the latest stable chart versions",
+	Long: `Upgrade the Helm releases of the OpenClaw namespace to the latest stable version.
+
+The releases come from a manifest (default: scripts/recipes/openclaw/releases.yaml,
+or OpenClaw alone when it does not exist) listing each release with its chart
+repo, chart, recipes.conf pin key, and the releases it needs. Releases are
+upgraded in dependency order; a failed upgrade stops the releases after it.
+When upgrading several releases, those that are not installed are skipped.
+
+Steps, per release:
+  1. Ensure the Helm repo is added and up-to-date
+  2. Query the latest chart version of the channel
   3. Compare with the currently deployed release
-  4. Perform helm upgrade --reset-then-reuse-values --version <target>
+  4. Check the rollout keeps a ready pod, back up the release's values and
+     manifest, then perform helm upgrade --reset-then-reuse-values --version <target>
   5. Wait for rollout to complete
-  6. Update the CHART_VERSION_OPENCLAW pin in recipes.conf
+  6. Update the release's pin (e.g. CHART_VERSION_OPENCLAW) in recipes.conf
 
-Use --version to target a specific chart version instead of latest.
+Use --release to upgrade only some releases (repeatable).
+Use --version to target a specific chart version instead of latest (needs a
+single release).
 Use --dry-run to preview the upgrade without applying it.
 Use --skip-pin-update to skip updating recipes.conf.
+Use --channel beta to include pre-release chart versions (helm --devel). The
+chosen channel is tracked next to the pin as CHART_CHANNEL_<NAME> in
+recipes.conf and used by later runs; switching from beta back to stable warns,
+as the latest stable chart may be older than a deployed pre-release.
+Before upgrading, a preflight checks the release's Deployments and
+StatefulSets: a Recreate strategy, maxUnavailable covering every replica, a
+single-replica StatefulSet, or required hostname anti-affinity on a
+single-node cluster would leave no ready pod or stall the rollout. The
+upgrade stops on such a risk unless --allow-downtime. PodDisruptionBudgets
+that allow no disruption are only warned about, as they block node drains.
+Use --canary to first run the new version as a one-replica canary Deployment
+(<release>-canary) outside the Service selector: the upgrade only proceeds
+once the canary is ready and has stayed ready without restarts for
+--canary-soak. The canary is rendered with the release's current values, its
+PersistentVolumeClaims replaced by emptyDir volumes; it shares the release's
+Secrets, so integrations it connects to see a second instance meanwhile.
+Use --pin-digest to pin the OpenClaw image to the digest its chart tag
+resolves to at the registry (set as <tag>@<digest> in the release values), so
+the running workload stays immutable even if the tag is re-pushed upstream.
+An upgrade without --pin-digest drops an earlier pin and follows the chart's
+tag again.
+
+Before the upgrade, 'helm get values' and 'helm get manifest' of the release
+are saved to --helm-backup-path (default:
+scripts/recipes/openclaw/helm-backups/<namespace>/<release>/pre-upgrade-<time>.json,
+'off' disables it); a failed backup stops the upgrade.
+
+With HELM_MIRROR_URL (or HELM_MIRROR_REPO_<REPO>) set, charts are fetched
+from that mirror, authenticated with HELM_MIRROR_USERNAME/HELM_MIRROR_PASSWORD
+(the password may be a keyring:<name> reference).
 
 Examples:
   netcup-claw upgrade
   netcup-claw upgrade --dry-run
   netcup-claw upgrade --version 1.3.20
-  netcup-claw upgrade --skip-pin-update`,
+  netcup-claw upgrade --release openclaw-browser
+  netcup-claw upgrade --canary --canary-soak 1m
+  netcup-claw upgrade --pin-digest
+  netcup-claw upgrade --allow-downtime
+  netcup-claw upgrade --channel beta --dry-run
+  netcup-claw upgrade --manifest ./releases.yaml --skip-pin-update`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		cfg := openclawConfig()
 
-		// Step 1: Ensure Helm repo
-		fmt.Println("Updating Helm repo...")
-		if err := helmRepoEnsure(); err != nil {
+		manifest, err := releases.Load(upgradeManifest)
+		if err != nil {
 			return err
 		}
-
-		// Step 2: Determine target version
-		targetVersion := strings.TrimSpace(upgradeVersion)
-		var latestAppVersion string
-		if targetVersion == "" {
-			v, av, err := helmLatestStableVersion()
-			if err != nil {
-				return fmt.Errorf("failed to determine latest stable version: %w", err)
+		ordered, err := releases.Order(manifest)
+		if err != nil {
+			return err
+		}
+		selected, err := releases.Select(ordered, upgradeReleases)
+		if err != nil {
+			return err
+		}
+		if upgradeChannel != "" {
+			if err := releases.ValidateChannel(upgradeChannel); err != nil {
+				return err
 			}
-			targetVersion = v
-			latestAppVersion = av
+		}
+		if strings.TrimSpace(upgradeVersion) != "" && len(selected) != 1 {
+			return fmt.Errorf("--version needs a single release; select it with --release")
 		}
 
-		// Step 3: Get currently deployed version
-		rel, err := helmCurrentRelease(cfg.Namespace)
+		deployed, err := helmListReleases(ctx, cfg.Namespace)
 		if err != nil {
 			return fmt.Errorf("failed to query current release: %w", err)
 		}
-		currentVersion := chartVersionFromChart(rel.Chart)
+		for _, rel := range selected {
+			if len(selected) > 1 {
+				fmt.Printf("\n== %s ==\n", rel.Name)
+				if findHelmRelease(deployed, rel.Name) == nil {
+					fmt.Printf("not installed in namespace %s — skipping\n", cfg.Namespace)
+					continue
+				}
+			}
+			if err := upgradeRelease(ctx, cfg, rel, deployed); err != nil {
+				if len(selected) > 1 {
+					return fmt.Errorf("release %s: %w", rel.Name, err)
+				}
+				return err
+			}
+		}
+		return nil
+	},
+}
 
-		fmt.Printf("\ncurrent: chart=%s  app=%s  status=%s\n", currentVersion, rel.AppVersion, rel.Status)
-		if latestAppVersion != "" {
-			fmt.Printf("target:  chart=%s  app=%s\n", targetVersion, latestAppVersion)
-		} else {
-			fmt.Printf("target:  chart=%s\n", targetVersion)
+// upgradeRelease upgrades one release of the manifest to the target version.
+func upgradeRelease(ctx context.Context, cfg openclaw.Config, spec releases.Release, deployed []helmRelease) error {
+	// Step 1: Ensure Helm repo
+	fmt.Println("Updating Helm repo...")
+	if err := helmRepoEnsureFor(ctx, spec.Repo, spec.URL); err != nil {
+		return err
+	}
+	chartRef := helmmirror.FromEnv(processEnvMap()).ChartRef(spec.Repo, spec.Chart)
+
+	// Release channel: --channel, else the one tracked in recipes.conf
+	channelKey := releases.ChannelKey(spec.Pin)
+	trackedChannel := recipesConfValue(channelKey)
+	channel := upgradeChannel
+	if channel == "" {
+		channel = trackedChannel
+	}
+	if channel == "" {
+		channel = releases.ChannelStable
+	}
+	if err := releases.ValidateChannel(channel); err != nil {
+		return fmt.Errorf("%s in %s: %w", channelKey, recipesConfRel, err)
+	}
+	if trackedChannel == releases.ChannelBeta && channel == releases.ChannelStable {
+		fmt.Fprintf(os.Stderr, "warning: switching %s from the beta to the stable channel; a deployed pre-release may be newer than the latest stable chart\n", spec.Name)
+	}
+	// Record the channel unless it is the implicit default
+	trackChannel := channelKey != "" && !upgradeSkipPinUpdate && channel != trackedChannel &&
+		(trackedChannel != "" || channel != releases.ChannelStable)
+
+	// Step 2: Determine target version
+	targetVersion := strings.TrimSpace(upgradeVersion)
+	var latestAppVersion string
+	if targetVersion == "" {
+		v, av, err := helmLatestVersion(ctx, chartRef, channel == releases.ChannelBeta)
+		if err != nil {
+			return fmt.Errorf("failed to determine latest %s version: %w", channel, err)
 		}
+		targetVersion = v
+		latestAppVersion = av
+	}
+
+	// Step 3: Get currently deployed version
+	rel := findHelmRelease(deployed, spec.Name)
+	if rel == nil {
+		return fmt.Errorf("failed to query current release: no Helm release named %q found in namespace %s", spec.Name, cfg.Namespace)
+	}
+	currentVersion := chartVersionFromChart(rel.Chart)
+	if releases.CompareVersions(targetVersion, currentVersion) < 0 {
+		fmt.Fprintf(os.Stderr, "warning: target chart %s is older than the deployed %s; upgrading downgrades %s\n", targetVersion, currentVersion, spec.Name)
+	}
 
-		// Check the actual running image tag to detect stale images from
-		// prior --reuse-values upgrades.
-		runningAppVersion := detectRunningImageTag(cfg.Namespace)
-		if runningAppVersion != "" && runningAppVersion != rel.AppVersion {
-			fmt.Printf("running: app=%s (image tag differs from chart metadata)\n", runningAppVersion)
+	fmt.Printf("\ncurrent: chart=%s  app=%s  status=%s\n", currentVersion, rel.AppVersion, rel.Status)
+	if latestAppVersion != "" {
+		fmt.Printf("target:  chart=%s  app=%s\n", targetVersion, latestAppVersion)
+	} else {
+		fmt.Printf("target:  chart=%s\n", targetVersion)
+	}
+	if channel != releases.ChannelStable {
+		fmt.Printf("channel: %s (pre-releases included)\n", channel)
+	}
+
+	// Check the actual running image tag of OpenClaw to detect stale images
+	// from prior --reuse-values upgrades.
+	runningImage, runningAppVersion := "", ""
+	if spec.Name == helmReleaseName {
+		runningImage = detectRunningImage(ctx, cfg.Namespace)
+		runningAppVersion = imageTag(runningImage)
+	}
+	if runningAppVersion != "" && runningAppVersion != rel.AppVersion {
+		fmt.Printf("running: app=%s (image tag differs from chart metadata)\n", runningAppVersion)
+	}
+
+	// Digest pinning (or dropping an earlier pin) of the OpenClaw image
+	var pin imagePin
+	if spec.Name == helmReleaseName {
+		var err error
+		if pin, err = planImagePin(ctx, cfg.Namespace, spec.Name, chartRef, targetVersion, runningImage); err != nil {
+			return err
+		}
+		if pin.Image != "" {
+			fmt.Printf("pinned:  %s\n", pin.Image)
+		}
+		if pin.Dropped != "" {
+			fmt.Printf("dropping digest pin %s (use --pin-digest to keep pinning)\n", pin.Dropped)
 		}
+	} else if upgradePinDigest {
+		fmt.Fprintf(os.Stderr, "warning: --pin-digest only pins the %s image; %s keeps its chart's tags\n", helmReleaseName, spec.Name)
+	}
 
-		chartMatch := currentVersion == targetVersion
-		imageMatch := runningAppVersion == "" || runningAppVersion == latestAppVersion
-		if chartMatch && imageMatch && rel.Status == "deployed" && !upgradeForce {
-			fmt.Println("\nalready at target version — nothing to do")
-			return nil
+	chartMatch := currentVersion == targetVersion
+	imageMatch := runningAppVersion == "" || runningAppVersion == latestAppVersion
+	if pin.Image != "" || pin.Dropped != "" {
+		imageMatch = imageMatch && runningImage == pin.Image
+	}
+	if chartMatch && imageMatch && rel.Status == "deployed" && !upgradeForce {
+		fmt.Println("\nalready at target version — nothing to do")
+		if trackChannel && !upgradeDryRun {
+			setRecipesConfChannel(channelKey, channel)
 		}
-		if chartMatch && !imageMatch && !upgradeForce {
+		return nil
+	}
+	if chartMatch && !imageMatch && !upgradeForce {
+		if pin.Image != "" && runningImage != pin.Image {
+			fmt.Printf("\nchart version matches but running image is not pinned (%s != %s)\n", runningImage, pin.Image)
+			fmt.Println("re-upgrading to pin the image digest...")
+		} else if pin.Dropped != "" {
+			fmt.Println("\nchart version matches; re-upgrading to drop the digest pin...")
+		} else {
 			fmt.Printf("\nchart version matches but running image is stale (%s != %s)\n", runningAppVersion, latestAppVersion)
 			fmt.Println("re-upgrading to apply chart-default image tag...")
 		}
+	}
 
-		// Step 4: Perform upgrade
-		if upgradeDryRun {
-			fmt.Printf("\ndry-run: would run 'helm upgrade %s %s --reset-then-reuse-values --version %s -n %s --wait --timeout 5m'\n",
-				helmReleaseName, helmChartRef, targetVersion, cfg.Namespace)
-			if !upgradeSkipPinUpdate {
-				fmt.Printf("dry-run: would update %s=%s in %s\n", recipesConfKey, targetVersion, recipesConfRel)
-			}
-			return nil
+	// Step 4: Perform upgrade, after checking the rollout keeps a ready pod
+	// and the new version in a canary
+	if err := checkUpgradePreflight(ctx, cfg.Namespace, spec.Name); err != nil {
+		return err
+	}
+	if upgradeDryRun {
+		if upgradeCanary {
+			fmt.Printf("\ndry-run: would check chart %s in canary deployment/%s%s first\n", targetVersion, spec.Name, canarySuffix)
+		}
+		fmt.Printf("\ndry-run: would run 'helm upgrade %s %s --reset-then-reuse-values --version %s -n %s --wait --timeout 5m%s'\n",
+			spec.Name, chartRef, targetVersion, cfg.Namespace, strings.TrimRight(" "+strings.Join(pin.HelmArgs, " "), " "))
+		if preview := helmBackupPreview(cfg.Namespace, spec.Name, "upgrade"); preview != "" {
+			fmt.Printf("dry-run: %s\n", preview)
 		}
+		if !upgradeSkipPinUpdate && spec.Pin != "" {
+			fmt.Printf("dry-run: would update %s=%s in %s\n", spec.Pin, targetVersion, recipesConfRel)
+		}
+		if trackChannel {
+			fmt.Printf("dry-run: would update %s=%s in %s\n", channelKey, channel, recipesConfRel)
+		}
+		return nil
+	}
 
-		fmt.Printf("\nupgrading %s -> %s ...\n", currentVersion, targetVersion)
-		upgradeArgs := []string{
-			"upgrade", helmReleaseName, helmChartRef,
-			"--reset-then-reuse-values",
-			"--version", targetVersion,
-			"-n", cfg.Namespace,
-			"--wait",
-			"--timeout", "5m",
+	if upgradeCanary {
+		if err := runCanary(ctx, cfg.Namespace, spec.Name, chartRef, targetVersion, pin.HelmArgs); err != nil {
+			return fmt.Errorf("canary check failed, release left at %s: %w", currentVersion, err)
 		}
-		upgradeCmd := exec.Command("helm", upgradeArgs...)
-		upgradeCmd.Stdout = os.Stdout
-		upgradeCmd.Stderr = os.Stderr
-		if err := upgradeCmd.Run(); err != nil {
-			return fmt.Errorf("helm upgrade failed: %w", err)
+	}
+
+	if err := backupBeforeHelm(ctx, cfg.Namespace, spec.Name, "upgrade"); err != nil {
+		return err
+	}
+	fmt.Printf("\nupgrading %s -> %s ...\n", currentVersion, targetVersion)
+	upgradeArgs := []string{
+		"upgrade", spec.Name, chartRef,
+		"--reset-then-reuse-values",
+		"--version", targetVersion,
+		"-n", cfg.Namespace,
+		"--wait",
+		"--timeout", "5m",
+	}
+	upgradeArgs = append(upgradeArgs, pin.HelmArgs...)
+	upgradeCmd := interrupt.Command(ctx, "helm", upgradeArgs...)
+	upgradeCmd.Stdout = os.Stdout
+	upgradeCmd.Stderr = os.Stderr
+	if err := helmRun(ctx, upgradeCmd); err != nil {
+		if ctx.Err() != nil {
+			rollbackInterruptedUpgrade(ctx, cfg.Namespace, spec.Name, rel.Revision)
 		}
+		return fmt.Errorf("helm upgrade failed: %w", err)
+	}
 
-		fmt.Println("upgrade complete")
+	fmt.Println("upgrade complete")
 
-		// Step 5: Wait for rollout
+	// Step 5: Wait for rollout (helm --wait covers the other releases)
+	if spec.Name == helmReleaseName {
 		fmt.Println("waiting for rollout...")
-		if err := runKubectl("-n", cfg.Namespace, "rollout", "status",
-			"deployment/"+deployedConfigDeploymentName(), "--timeout=180s"); err != nil {
+		if err := waitForOpenClawRollout(ctx, cfg); err != nil {
 			return fmt.Errorf("rollout did not complete: %w", err)
 		}
+	}
 
-		// Step 6: Update recipes.conf pin
-		if !upgradeSkipPinUpdate {
-			if err := updateRecipesConfPin(targetVersion); err != nil {
-				fmt.Fprintf(os.Stderr, "warning: failed to update %s: %v\n", recipesConfRel, err)
-			} else {
-				fmt.Printf("updated %s=%s in %s\n", recipesConfKey, targetVersion, recipesConfRel)
-			}
+	// Step 6: Update recipes.conf pin
+	if !upgradeSkipPinUpdate && spec.Pin != "" {
+		if err := updateRecipesConfKeyAt(recipesConfRel, spec.Pin, targetVersion); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to update %s: %v\n", recipesConfRel, err)
+		} else {
+			fmt.Printf("updated %s=%s in %s\n", spec.Pin, targetVersion, recipesConfRel)
 		}
+	}
+	if trackChannel {
+		setRecipesConfChannel(channelKey, channel)
+	}
 
-		return nil
-	},
+	return nil
+}
+
+// recipesConfValue returns the value of key in recipes.conf, or "".
+func recipesConfValue(key string) string {
+	if key == "" {
+		return ""
+	}
+	values, err := config.LoadEnvFileToMap(recipesConfRel)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(values[key])
+}
+
+// setRecipesConfChannel records the release channel under key in
+// recipes.conf, warning on failure like the pin update.
+func setRecipesConfChannel(key, channel string) {
+	if err := setRecipesConfValue(key, channel); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to update %s: %v\n", recipesConfRel, err)
+		return
+	}
+	fmt.Printf("updated %s=%s in %s\n", key, channel, recipesConfRel)
+}
+
+// setRecipesConfValue sets key in recipes.conf under its lock
+func setRecipesConfValue(key, value string) error {
+	return pins.Update(recipesConfRel, func(f *pins.File) error {
+		_, err := f.Set(key, value)
+		return err
+	})
 }
 
 // logsCmd streams or fetches logs from the OpenClaw pod
@@ -2429,85 +3249,33 @@ var logsCmd = &cobra.Command{
 	Short: "Fetch or stream logs from the OpenClaw pod",
 	Long: `Fetch or stream logs from the OpenClaw workload pod.
 
-Flags are passed through to kubectl logs.
+--pod picks the pod instead of the first resolved one, e.g. during a
+rollout. All other flags are passed through to kubectl logs, such as
+--container and --previous for the logs of a crashed previous container.
+
+Sub-commands:
+  archive  - Ship logs to S3-compatible storage in hourly gzip chunks
 
 Examples:
   netcup-claw logs
   netcup-claw logs --follow
-  netcup-claw logs --tail 100`,
+  netcup-claw logs --tail 100
+  netcup-claw logs --pod openclaw-7d9f8-abcde --previous
+  netcup-claw logs archive --once`,
 	DisableFlagParsing: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, pod, err := resolveOpenClawPod()
+		ctx := cmd.Context()
+		args, pod, err := stripPodFlag(args)
 		if err != nil {
 			return err
 		}
-
-		logArgs := append([]string{"-n", cfg.Namespace, "logs", pod}, args...)
-		return runKubectl(logArgs...)
-	},
-}
-
-// statusCmd shows a unified status view
-var statusCmd = &cobra.Command{
-	Use:   "status",
-	Short: "Show unified OpenClaw status (tunnel, port-forward, service health)",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg := openclawConfig()
-		_ = ensureKubeAPIReachableWithTunnel()
-
-		// 1. SSH Tunnel status
-		tun := tunnelConfig()
-		var tunnelRunning bool
-		if tun.Host != "" {
-			tunMgr := tunnel.New(tun.User, tun.Host, tun.LocalPort, tun.RemoteHost, tun.RemotePort)
-			tunnelRunning = tunMgr.IsRunning()
-			fmt.Printf("tunnel:       %s", boolStatus(tunnelRunning))
-			if tunnelRunning {
-				fmt.Printf(" (localhost:%s -> %s:%s via %s@%s)", tun.LocalPort, tun.RemoteHost, tun.RemotePort, tun.User, tun.Host)
-			}
-			fmt.Println()
-		} else {
-			fmt.Printf("tunnel:       unconfigured (set TUNNEL_HOST to enable)\n")
-		}
-
-		// 2. Kubernetes API reachability
-		apiReachable := probeKubeAPI()
-		fmt.Printf("kube-api:     %s\n", boolStatus(apiReachable))
-
-		// 3. Port-forward status
-		mgr := pfManager(cfg, "")
-		pfStatus := mgr.Status()
-		fmt.Printf("port-forward: %s", pfStatus.State)
-		if pfStatus.PID > 0 {
-			fmt.Printf(" (pid %d)", pfStatus.PID)
-		}
-		fmt.Println()
-
-		// 4. OpenClaw service resolution
-		resolver := openclaw.New(cfg, nil)
-		svc, svcErr := resolver.ResolveService()
-		if svcErr != nil {
-			fmt.Printf("service:      error (%v)\n", svcErr)
-		} else {
-			fmt.Printf("service:      %s\n", svc)
-		}
-
-		_, podErr := resolver.ResolvePod()
-		if podErr != nil {
-			fmt.Printf("pod:          not found\n")
-		} else {
-			fmt.Printf("pod:          found\n")
+		cfg, pod, err := resolveTargetPod(ctx, pod)
+		if err != nil {
+			return err
 		}
 
-		// Overall health: API reachable (directly or via tunnel) + pf running + svc + pod resolved
-		apiOrTunnel := apiReachable || tunnelRunning
-		healthy := apiOrTunnel && pfStatus.State == portforward.StateRunning && svcErr == nil && podErr == nil
-		fmt.Printf("healthy:      %s\n", boolStatus(healthy))
-
-		if !healthy {
-			return fmt.Errorf("OpenClaw is not fully healthy")
-		}
-		return nil
+		logArgs := append([]string{"-n", cfg.Namespace, "logs", pod}, args...)
+		return runKubectl(ctx, logArgs...)
 	},
 }
 
@@ -2516,6 +3284,7 @@ func init() {
 	portForwardCmd.PersistentFlags().StringVarP(&pfNamespace, "namespace", "n", "", "Kubernetes namespace (default: openclaw)")
 	portForwardCmd.PersistentFlags().StringVar(&pfLocalPort, "local-port", "", "Local port (default: 18789)")
 	portForwardCmd.PersistentFlags().StringVar(&pfRemotePort, "remote-port", "", "Remote port (default: 18789)")
+	portForwardStatusCmd.Flags().BoolVar(&pfStatusJSON, "json", false, "Print the JSON health document")
 
 	// Tunnel flags (global; used by port-forward start and status)
 	rootCmd.PersistentFlags().StringVar(&tunHost, "tunnel-host", "", "SSH tunnel host (default: $TUNNEL_HOST or $MGMT_HOST)")
@@ -2523,6 +3292,10 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&tunLocalPort, "tunnel-local-port", "", "SSH tunnel local port (default: $TUNNEL_LOCAL_PORT or 6443)")
 	rootCmd.PersistentFlags().StringVar(&tunRemoteHost, "tunnel-remote-host", "", "SSH tunnel remote host (default: $TUNNEL_REMOTE_HOST or 127.0.0.1)")
 	rootCmd.PersistentFlags().StringVar(&tunRemotePort, "tunnel-remote-port", "", "SSH tunnel remote port (default: $TUNNEL_REMOTE_PORT or 6443)")
+	rootCmd.PersistentFlags().StringVar(&tunBindAddr, "tunnel-bind-address", "", "SSH tunnel local bind address, e.g. ::1 (default: $TUNNEL_BIND_ADDRESS or ssh's localhost)")
+	rootCmd.PersistentFlags().IntVar(&maxParallelFlag, "max-parallel", 0, "Maximum concurrent kubectl exec/cp calls across all workers, 0 for no limit (default: $OPENCLAW_MAX_PARALLEL or 8)")
+	// Consumed by main before cobra parses args; registered so it shows in --help
+	rootCmd.PersistentFlags().Bool("timings", false, "Print a per-phase duration breakdown (kubectl, helm, resolver) on exit")
 
 	portForwardCmd.AddCommand(portForwardStartCmd)
 	portForwardCmd.AddCommand(portForwardStopCmd)
@@ -2534,6 +3307,7 @@ func init() {
 	configCmd.PersistentFlags().StringVar(&configWorkspaceDir, "workspace-dir", "", "Local config workspace root (default: scripts/recipes/openclaw/config)")
 	configCmd.PersistentFlags().StringVar(&configBackupPath, "backup-path", "", "Directory or file path for config backups (default: <workspace-dir>/backup, use 'off' to disable on deploy)")
 	configDeployCmd.Flags().StringVar(&configDeployFile, "file", "", "Local OpenClaw config JSON file to deploy (default: scripts/recipes/openclaw/openclaw.json)")
+	configDeployCmd.Flags().BoolVarP(&configDeployYes, "yes", "y", false, "Skip the confirmation prompt (same as CONFIRM=true)")
 	configCmd.AddCommand(configBackupCmd)
 	configCmd.AddCommand(configPullCmd)
 	configCmd.AddCommand(configDeployCmd)
@@ -2541,9 +3315,14 @@ func init() {
 	approvalsCmd.PersistentFlags().StringVar(&approvalsWorkspaceDir, "workspace-dir", "", "Local approvals workspace root (default: scripts/recipes/openclaw/approvals)")
 	approvalsCmd.PersistentFlags().StringVar(&approvalsBackupPath, "backup-path", "", "Directory or file path for approvals backups (default: <workspace-dir>/backup, use 'off' to disable on deploy)")
 	approvalsDeployCmd.Flags().StringVar(&approvalsDeployFile, "file", "", "Local approvals JSON file to deploy (default: <workspace-dir>/approvals.json)")
+	approvalsDeployCmd.Flags().BoolVarP(&approvalsDeployYes, "yes", "y", false, "Skip the confirmation prompt (same as CONFIRM=true)")
 	approvalsCmd.AddCommand(approvalsBackupCmd)
 	approvalsCmd.AddCommand(approvalsPullCmd)
+	approvalsReviewCmd.Flags().StringVar(&approvalsDeployFile, "file", "", "Local approvals baseline JSON (default: <workspace-dir>/approvals.json)")
+	approvalsReviewCmd.Flags().BoolVar(&approvalsReviewList, "list", false, "Print pending entries without prompting")
+	approvalsReviewCmd.Flags().BoolVar(&approvalsReviewDryRun, "dry-run", false, "Review and summarize decisions without writing anything")
 	approvalsCmd.AddCommand(approvalsDeployCmd)
+	approvalsCmd.AddCommand(approvalsReviewCmd)
 	rootCmd.AddCommand(approvalsCmd)
 	cronCmd.PersistentFlags().StringVar(&cronWorkspaceDir, "workspace-dir", "", "Local cron workspace root (default: scripts/recipes/openclaw/cron)")
 	cronCmd.PersistentFlags().StringVar(&cronBackupPath, "backup-path", "", "Directory or file path for cron jobs backups (default: <workspace-dir>/backup, use 'off' to disable pre-sync backup in deploy)")
@@ -2564,10 +3343,17 @@ func init() {
 	skillsPullCmd.Flags().BoolVar(&skillsPullAll, "all", false, "Pull all runtime skills into local workspace")
 	skillsPullCmd.Flags().StringSliceVar(&skillsExclude, "exclude", []string{"hormuz-ais-watch"}, "Skill names to exclude when using --all (repeatable)")
 	skillsDeployCmd.Flags().StringVar(&skillsSourceDir, "source-dir", "", "Local skill directory to deploy (default: <workspace-dir>/<skill>)")
+	skillsDeployCmd.Flags().BoolVar(&skillsDeployAll, "all", false, "Deploy all skills in the local workspace")
+	skillsDeployCmd.Flags().StringSliceVar(&skillsDeployExclude, "exclude", nil, "Skill names to exclude when using --all (repeatable)")
+	skillsListCmd.Flags().BoolVar(&skillsListLocal, "local", false, "List local workspace skills with manifest validation status")
+	for _, c := range []*cobra.Command{skillsDeployCmd, skillsRemoveCmd} {
+		c.Flags().BoolVar(&skillsReload, "reload", true, "Restart OpenClaw afterwards so skills are reloaded")
+	}
 	skillsCmd.AddCommand(skillsListCmd)
 	skillsCmd.AddCommand(skillsBackupCmd)
 	skillsCmd.AddCommand(skillsPullCmd)
 	skillsCmd.AddCommand(skillsDeployCmd)
+	skillsCmd.AddCommand(skillsRemoveCmd)
 	rootCmd.AddCommand(skillsCmd)
 	secretsSyncCmd.Flags().StringVar(&secretsEnvFile, "env-file", ".env", "Local env file with secret values (takes precedence over process env)")
 	secretsSyncCmd.Flags().StringVar(&secretsName, "name", "openclaw-credentials", "Kubernetes Secret name to patch/create")
@@ -2576,13 +3362,18 @@ func init() {
 	secretsCmd.AddCommand(secretsSyncCmd)
 	rootCmd.AddCommand(secretsCmd)
 	agentsCmd.PersistentFlags().StringVar(&agentsWorkspaceDir, "workspace-dir", "", "Local agent-workspace root (default: scripts/recipes/openclaw/agent-workspace)")
+	agentsCmd.PersistentFlags().IntVar(&agentsConcurrency, "concurrency", defaultAgentConcurrency, "Number of agents to process in parallel (capped by --max-parallel)")
 	agentsCmd.AddCommand(agentsBackupCmd)
 	agentsCmd.AddCommand(agentsDeployCmd)
 	rootCmd.AddCommand(agentsCmd)
 	upgradeCmd.Flags().StringVar(&upgradeVersion, "version", "", "Target chart version (default: latest stable)")
 	upgradeCmd.Flags().BoolVar(&upgradeDryRun, "dry-run", false, "Preview upgrade without applying")
-	upgradeCmd.Flags().BoolVar(&upgradeSkipPinUpdate, "skip-pin-update", false, "Skip updating CHART_VERSION_OPENCLAW in recipes.conf")
+	upgradeCmd.Flags().BoolVar(&upgradeSkipPinUpdate, "skip-pin-update", false, "Skip updating the chart version pins in recipes.conf")
 	upgradeCmd.Flags().BoolVar(&upgradeForce, "force", false, "Force upgrade even if chart version matches")
+	upgradeCmd.Flags().StringVar(&upgradeManifest, "manifest", releasesManifestRel, "Release manifest listing the Helm releases to upgrade")
+	upgradeCmd.Flags().StringSliceVar(&upgradeReleases, "release", nil, "Upgrade only these releases of the manifest (default: all)")
+	upgradeCmd.Flags().StringVar(&helmBackupPath, "helm-backup-path", "", "Directory for release backups before the upgrade (default: "+defaultHelmBackupRel+", 'off' disables)")
+	upgradeCmd.Flags().StringVar(&upgradeChannel, "channel", "", "Release channel: stable or beta (default: CHART_CHANNEL_<NAME> in recipes.conf, then stable)")
 	rootCmd.AddCommand(upgradeCmd)
 	rootCmd.AddCommand(logsCmd)
 	rootCmd.AddCommand(statusCmd)
@@ -2616,6 +3407,14 @@ type tunnelParams struct {
 	LocalPort  string
 	RemoteHost string
 	RemotePort string
+	BindAddr   string
+}
+
+// manager returns the tunnel manager for these parameters
+func (p tunnelParams) manager() *tunnel.Manager {
+	mgr := tunnel.New(p.User, p.Host, p.LocalPort, p.RemoteHost, p.RemotePort)
+	mgr.BindAddress = p.BindAddr
+	return mgr
 }
 
 // tunnelConfig builds tunnel connection parameters from flags and environment.
@@ -2674,16 +3473,27 @@ func tunnelConfig() tunnelParams {
 		p.RemotePort = "6443"
 	}
 
+	// Bind address (empty: ssh binds localhost)
+	p.BindAddr = tunBindAddr
+	if p.BindAddr == "" {
+		p.BindAddr = os.Getenv("TUNNEL_BIND_ADDRESS")
+	}
+
 	return p
 }
 
 // pfManager creates a port-forward Manager from the openclaw config.
-// If target is empty, cfg.FallbackSvc is used.
+// If target is empty, cfg.FallbackSvc is used. PORT_FORWARD_ADDRESS (e.g.
+// ::1) overrides kubectl's default localhost binding.
 func pfManager(cfg openclaw.Config, target string) *portforward.Manager {
 	if strings.TrimSpace(target) == "" {
 		target = cfg.FallbackSvc
 	}
-	return portforward.New(cfg.Namespace, target, cfg.LocalPort, cfg.RemotePort)
+	var opts []portforward.Option
+	if address := strings.TrimSpace(os.Getenv("PORT_FORWARD_ADDRESS")); address != "" {
+		opts = append(opts, portforward.WithAddress(address))
+	}
+	return portforward.New(cfg.Namespace, target, cfg.LocalPort, cfg.RemotePort, opts...)
 }
 
 // boolStatus returns "ok" or "not ok" for boolean health values
@@ -2694,8 +3504,61 @@ func boolStatus(ok bool) string {
 	return "not ok"
 }
 
+// isPassthroughCommand reports whether name is a command that hands its
+// arguments to a shell or the OpenClaw CLI, so a --timings after them is not
+// netcup-claw's
+func isPassthroughCommand(name string) bool {
+	switch name {
+	case runCmd.Name(), openclawCmd.Name(), logsCmd.Name():
+		return true
+	}
+	return false
+}
+
+// setupTelemetry enables tracing, exported when OTEL_EXPORTER_OTLP_ENDPOINT
+// is set, and the local --timings report. It returns args without --timings.
+func setupTelemetry(args []string) ([]string, bool) {
+	telemetry.Init("netcup-claw", version)
+	args, timings := telemetry.StripTimingsFlag(args, isPassthroughCommand)
+	if timings {
+		telemetry.EnableTimings("netcup-claw", version)
+	}
+	return args, timings
+}
+
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	args, timings := setupTelemetry(os.Args[1:])
+	rootCmd.SetArgs(args)
+	// Ctrl-C and SIGTERM cancel the command's context: child processes are
+	// stopped and cleanups run before exiting. A second signal exits at once.
+	ctx, stop := interrupt.Context(context.Background())
+	defer stop()
+	ctx, span := telemetry.Start(ctx, "netcup-claw")
+	if c, _, err := rootCmd.Find(args); err == nil {
+		span.SetName(c.CommandPath())
+	}
+	start := time.Now()
+	handled, err := runPlugin(ctx, args)
+	if !handled {
+		err = finishCommandHooks(ctx, rootCmd.ExecuteContext(ctx))
+	}
+	recordUsage(args, handled, start, err)
+	err = span.End(err)
+	if timings {
+		telemetry.WriteTimings(os.Stderr)
+	}
+	_ = telemetry.Shutdown(context.Background())
+
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "interrupted: %v\n", err)
+			os.Exit(interrupt.ExitCode)
+		}
+		// A failing plugin has reported its own error; keep its exit code
+		var exitErr *exec.ExitError
+		if handled && errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -7,6 +7,8 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/telemetry"
 )
 
 func TestBuildShellRunKubectlArgs(t *testing.T) {
@@ -261,6 +263,8 @@ func TestChartVersionFromChart(t *testing.T) {
 		{"openclaw-1.3.21", "1.3.21"},
 		{"myrelease-0.1.0", "0.1.0"},
 		{"noversion", "noversion"},
+		{"openclaw-1.5.0-beta.1", "1.5.0-beta.1"},
+		{"my-2-chart-1.0.0", "1.0.0"},
 	}
 	for _, tc := range tests {
 		got := chartVersionFromChart(tc.chart)
@@ -270,6 +274,14 @@ func TestChartVersionFromChart(t *testing.T) {
 	}
 }
 
+func TestParseChartMetadata(t *testing.T) {
+	data := []byte("apiVersion: v2\nappVersion: \"2026.2.17\"\ndescription: OpenClaw\nname: openclaw\nversion: 1.3.21\n")
+	version, appVersion := parseChartMetadata(data)
+	if version != "1.3.21" || appVersion != "2026.2.17" {
+		t.Errorf("parseChartMetadata() = %q, %q", version, appVersion)
+	}
+}
+
 func TestUpdateRecipesConfPinAt(t *testing.T) {
 	tmpDir := t.TempDir()
 	confPath := filepath.Join(tmpDir, "recipes.conf")
@@ -507,3 +519,80 @@ func executeTunnelBootstrap(
 
 	return nil
 }
+
+func TestParseOpenClawJSONFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantArgs []string
+		wantOpts openclawJSONOptions
+	}{
+		{name: "passthrough", args: []string{"status"}, wantArgs: []string{"status"}},
+		{name: "json", args: []string{"cron", "list", "--json"}, wantArgs: []string{"cron", "list", "--json"}, wantOpts: openclawJSONOptions{JSON: true}},
+		{name: "pretty implies json", args: []string{"--pretty", "status"}, wantArgs: []string{"status", "--json"}, wantOpts: openclawJSONOptions{JSON: true, Pretty: true}},
+		{name: "query", args: []string{"status", "--query", ".gateway"}, wantArgs: []string{"status", "--json"}, wantOpts: openclawJSONOptions{JSON: true, Query: ".gateway"}},
+		{name: "query equals", args: []string{"status", "--query=.a[0]"}, wantArgs: []string{"status", "--json"}, wantOpts: openclawJSONOptions{JSON: true, Query: ".a[0]"}},
+		{name: "after separator", args: []string{"message", "send", "--", "--pretty"}, wantArgs: []string{"message", "send", "--pretty"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			args, opts, err := parseOpenClawJSONFlags(tc.args)
+			if err != nil {
+				t.Fatalf("parseOpenClawJSONFlags() error = %v", err)
+			}
+			if !reflect.DeepEqual(args, tc.wantArgs) || opts != tc.wantOpts {
+				t.Fatalf("parseOpenClawJSONFlags(%v) = %v, %+v; want %v, %+v", tc.args, args, opts, tc.wantArgs, tc.wantOpts)
+			}
+		})
+	}
+
+	if _, _, err := parseOpenClawJSONFlags([]string{"status", "--query"}); err == nil {
+		t.Fatal("parseOpenClawJSONFlags(--query without expression) error = nil")
+	}
+}
+
+func TestEmitOpenClawJSON(t *testing.T) {
+	doc := []byte(`{"gateway": {"status": "ok"}, "jobs": [{"id": 12345678901234567890}, {"id": 2}]}`)
+	tests := []struct {
+		name string
+		opts openclawJSONOptions
+		want string
+	}{
+		{name: "compact", opts: openclawJSONOptions{JSON: true}, want: `{"gateway":{"status":"ok"},"jobs":[{"id":12345678901234567890},{"id":2}]}` + "\n"},
+		{name: "pretty query", opts: openclawJSONOptions{JSON: true, Pretty: true, Query: ".gateway"}, want: "{\n  \"status\": \"ok\"\n}\n"},
+		{name: "iterate", opts: openclawJSONOptions{JSON: true, Query: ".jobs[].id"}, want: "12345678901234567890\n2\n"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out strings.Builder
+			if err := emitOpenClawJSON(&out, doc, tc.opts); err != nil {
+				t.Fatalf("emitOpenClawJSON() error = %v", err)
+			}
+			if out.String() != tc.want {
+				t.Fatalf("emitOpenClawJSON() = %q, want %q", out.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestStripTimingsFlagKeepsPassthroughArgs(t *testing.T) {
+	tests := []struct {
+		args  []string
+		want  string
+		found bool
+	}{
+		{[]string{"--timings", "run", "mytool"}, "run mytool", true},
+		{[]string{"run", "mytool", "--timings"}, "run mytool --timings", false},
+		{[]string{"openclaw", "status", "--timings"}, "openclaw status --timings", false},
+		{[]string{"logs", "--timings", "--follow"}, "logs --follow", true},
+		{[]string{"status", "--timings"}, "status", true},
+	}
+	for _, tt := range tests {
+		args, found := telemetry.StripTimingsFlag(tt.args, isPassthroughCommand)
+		if strings.Join(args, " ") != tt.want || found != tt.found {
+			t.Errorf("StripTimingsFlag(%v) = %v, %v; want %q, %v", tt.args, args, found, tt.want, tt.found)
+		}
+	}
+}
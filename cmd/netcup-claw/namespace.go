@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nsSnapshotOutput      string
+	nsSnapshotNoSecrets   bool
+	nsSnapshotEncrypt     bool
+	nsSnapshotConcurrency int
+	nsPassphraseFile      string
+	nsRestoreSkipSecrets  bool
+	nsRestoreSkipPVCs     bool
+	nsRestoreHelm         bool
+	nsRestoreDryRun       bool
+	nsRestoreYes          bool
+)
+
+// defaultSnapshotConcurrency is the default number of resource lists a
+// snapshot fetches in parallel
+const defaultSnapshotConcurrency = 2
+
+// snapshotPassphraseEnv holds the passphrase for encrypted snapshot members
+// when --passphrase-file is not given.
+const snapshotPassphraseEnv = "NETCUP_CLAW_SNAPSHOT_PASSPHRASE"
+
+var namespaceCmd = &cobra.Command{
+	Use:   "namespace",
+	Short: "Snapshot or restore the OpenClaw namespace",
+	Long: `Snapshot or restore the Kubernetes resources of the OpenClaw namespace.
+
+Sub-commands:
+  snapshot  - Export ConfigMaps, Secrets, PVC claims, and Helm values into a dated archive
+  restore   - Re-apply a snapshot archive to the namespace`,
+}
+
+var namespaceSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Export ConfigMaps, Secrets, PVC claims, and Helm values into a dated archive",
+	Long: `Export the OpenClaw namespace into <output>/openclaw-<namespace>-<timestamp>.tar.gz.
+
+The archive contains:
+  manifest.json     - namespace, timestamp, Helm chart, and object names
+  configmaps.json   - ConfigMaps (kube-root-ca.crt excluded)
+  secrets.json      - Secrets (service account tokens and Helm release records excluded)
+  pvcs.json         - PersistentVolumeClaim metadata and spec (no volume data)
+  helm-values.yaml  - user-supplied values of the openclaw Helm release
+
+With --encrypt-secrets, secrets.json and helm-values.yaml are stored
+AES-256-GCM encrypted (.enc) with a key derived from the passphrase in
+--passphrase-file or $NETCUP_CLAW_SNAPSHOT_PASSPHRASE. Without it the archive
+holds plaintext secrets and is written with mode 0600.
+
+The resource lists are fetched --concurrency at a time; use 1 over a slow
+tunnel.
+
+Examples:
+  netcup-claw namespace snapshot
+  netcup-claw namespace snapshot --encrypt-secrets --passphrase-file ~/.config/netcup-claw/passphrase
+  netcup-claw namespace snapshot --no-secrets --output /tmp/snapshots
+  netcup-claw namespace snapshot --concurrency 1`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := openclawConfig()
+		passphrase := ""
+		if nsSnapshotEncrypt && !nsSnapshotNoSecrets {
+			var err error
+			if passphrase, err = snapshotPassphrase(); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now().UTC()
+		manifest := snapshotManifest{Version: snapshotFormatVersion, Namespace: cfg.Namespace, CreatedAt: now}
+		members := map[string][]byte{}
+
+		resources := []string{"configmaps", "persistentvolumeclaims"}
+		if !nsSnapshotNoSecrets {
+			resources = append(resources, "secrets")
+		}
+		payloads, err := fetchSnapshotLists(ctx, cfg.Namespace, resources, nsSnapshotConcurrency)
+		if err != nil {
+			return err
+		}
+
+		if members[snapshotConfigMapsFile], manifest.ConfigMaps, err = buildSnapshotList("ConfigMap", payloads["configmaps"]); err != nil {
+			return err
+		}
+
+		if !nsSnapshotNoSecrets {
+			list, names, err := buildSnapshotList("Secret", payloads["secrets"])
+			if err != nil {
+				return err
+			}
+			manifest.Secrets = names
+			if passphrase != "" {
+				if members[snapshotSecretsEncFile], err = encryptSnapshotMember(passphrase, list); err != nil {
+					return fmt.Errorf("failed to encrypt secrets: %w", err)
+				}
+				manifest.SecretsEncrypted = true
+			} else {
+				members[snapshotSecretsFile] = list
+			}
+		}
+
+		if members[snapshotPVCsFile], manifest.PVCs, err = buildSnapshotList("PersistentVolumeClaim", payloads["persistentvolumeclaims"]); err != nil {
+			return err
+		}
+
+		if rel, err := helmCurrentRelease(ctx, cfg.Namespace); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: skipping Helm values: %v\n", err)
+		} else {
+			values, err := helmOutput(ctx, "get", "values", rel.Name, "-n", cfg.Namespace, "-o", "yaml")
+			if err != nil {
+				return fmt.Errorf("helm get values failed: %w", err)
+			}
+			manifest.HelmRelease = rel.Name
+			manifest.HelmChart = rel.Chart
+			if passphrase != "" {
+				if members[snapshotHelmValuesEnc], err = encryptSnapshotMember(passphrase, values); err != nil {
+					return fmt.Errorf("failed to encrypt Helm values: %w", err)
+				}
+			} else {
+				members[snapshotHelmValuesFile] = values
+			}
+		}
+
+		rawManifest, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode manifest: %w", err)
+		}
+		members[snapshotManifestFile] = append(rawManifest, '\n')
+
+		outputDir := strings.TrimSpace(nsSnapshotOutput)
+		if outputDir == "" {
+			outputDir = "scripts/recipes/openclaw/snapshots"
+		}
+		if err := os.MkdirAll(outputDir, 0o700); err != nil {
+			return fmt.Errorf("failed to create snapshot directory %s: %w", outputDir, err)
+		}
+		archivePath := filepath.Join(outputDir, fmt.Sprintf("openclaw-%s-%s.tar.gz", cfg.Namespace, now.Format("20060102-150405")))
+		f, err := os.OpenFile(archivePath, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", archivePath, err)
+		}
+		if err := writeSnapshotArchive(f, members, now); err != nil {
+			_ = f.Close()
+			_ = os.Remove(archivePath)
+			return fmt.Errorf("failed to write %s: %w", archivePath, err)
+		}
+		if err := f.Close(); err != nil {
+			_ = os.Remove(archivePath)
+			return fmt.Errorf("failed to close %s: %w", archivePath, err)
+		}
+
+		secretsNote := fmt.Sprintf("%d secrets", len(manifest.Secrets))
+		switch {
+		case nsSnapshotNoSecrets:
+			secretsNote = "secrets skipped"
+		case manifest.SecretsEncrypted:
+			secretsNote += " (encrypted)"
+		}
+		fmt.Printf("snapshot complete: %s (%d configmaps, %s, %d pvcs)\n", archivePath, len(manifest.ConfigMaps), secretsNote, len(manifest.PVCs))
+		return nil
+	},
+}
+
+var namespaceRestoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Re-apply a namespace snapshot archive",
+	Long: `Re-apply a snapshot created by 'namespace snapshot' to the OpenClaw namespace
+($OPENCLAW_NAMESPACE selects a different target namespace).
+
+ConfigMaps and Secrets are applied server-side, overwriting fields they own.
+PVCs are only created when missing; existing claims and their data are left
+alone. Helm values are re-applied only with --helm, by running helm upgrade
+with the snapshot's chart version and values.
+
+A restore asks for confirmation unless --yes or CONFIRM=true is given;
+--dry-run does not.
+
+Examples:
+  netcup-claw namespace restore scripts/recipes/openclaw/snapshots/openclaw-openclaw-20260101-120000.tar.gz --dry-run
+  netcup-claw namespace restore snapshot.tar.gz --passphrase-file ~/.config/netcup-claw/passphrase
+  netcup-claw namespace restore snapshot.tar.gz --skip-secrets --helm --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := openclawConfig()
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open snapshot %s: %w", args[0], err)
+		}
+		members, manifest, err := readSnapshotArchive(f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read snapshot %s: %w", args[0], err)
+		}
+		fmt.Printf("snapshot of namespace %s taken %s\n", manifest.Namespace, manifest.CreatedAt.Format(time.RFC3339))
+		if manifest.Namespace != cfg.Namespace {
+			fmt.Printf("restoring into namespace %s\n", cfg.Namespace)
+		}
+		if !nsRestoreDryRun {
+			if err := confirm.New(nsRestoreYes).Confirm(fmt.Sprintf("restore snapshot %s into namespace %s", args[0], cfg.Namespace)); err != nil {
+				return err
+			}
+		}
+
+		passphrase := ""
+		if (manifest.SecretsEncrypted && !nsRestoreSkipSecrets) || (nsRestoreHelm && members[snapshotHelmValuesEnc] != nil) {
+			if passphrase, err = snapshotPassphrase(); err != nil {
+				return err
+			}
+		}
+
+		if err := restoreSnapshotList(ctx, cfg.Namespace, "configmaps", members[snapshotConfigMapsFile], manifest.ConfigMaps); err != nil {
+			return err
+		}
+
+		if nsRestoreSkipSecrets {
+			fmt.Println("secrets: skipped")
+		} else {
+			secrets := members[snapshotSecretsFile]
+			if enc, ok := members[snapshotSecretsEncFile]; ok {
+				if secrets, err = decryptSnapshotMember(passphrase, enc); err != nil {
+					return err
+				}
+			}
+			if err := restoreSnapshotList(ctx, cfg.Namespace, "secrets", secrets, manifest.Secrets); err != nil {
+				return err
+			}
+		}
+
+		if nsRestoreSkipPVCs {
+			fmt.Println("pvcs: skipped")
+		} else if err := restoreSnapshotPVCs(ctx, cfg.Namespace, members[snapshotPVCsFile]); err != nil {
+			return err
+		}
+
+		if nsRestoreHelm {
+			values := members[snapshotHelmValuesFile]
+			if enc, ok := members[snapshotHelmValuesEnc]; ok {
+				if values, err = decryptSnapshotMember(passphrase, enc); err != nil {
+					return err
+				}
+			}
+			if values == nil || manifest.HelmChart == "" {
+				return fmt.Errorf("snapshot has no Helm values to restore")
+			}
+			if err := restoreSnapshotHelmValues(ctx, cfg.Namespace, manifest, values); err != nil {
+				return err
+			}
+		} else if manifest.HelmChart != "" {
+			fmt.Printf("helm: %s values not applied (use --helm)\n", manifest.HelmChart)
+		}
+
+		if nsRestoreDryRun {
+			fmt.Println("[dry-run] no changes made")
+			return nil
+		}
+		fmt.Printf("restore complete: %s\n", args[0])
+		return nil
+	},
+}
+
+// snapshotPassphrase reads the passphrase from --passphrase-file, falling
+// back to $NETCUP_CLAW_SNAPSHOT_PASSPHRASE.
+// fetchSnapshotLists returns the JSON lists of resources in namespace, keyed
+// by resource, fetching up to concurrency of them at a time
+func fetchSnapshotLists(ctx context.Context, namespace string, resources []string, concurrency int) (map[string][]byte, error) {
+	payloads := make([][]byte, len(resources))
+	errs := runBounded(len(resources), boundedConcurrency(concurrency), func(i int) error {
+		out, err := runKubectlOutput(ctx, "-n", namespace, "get", resources[i], "-o", "json")
+		if err != nil {
+			return fmt.Errorf("failed to list %s: %w", resources[i], err)
+		}
+		payloads[i] = out
+		return nil
+	})
+	lists := make(map[string][]byte, len(resources))
+	for i, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+		lists[resources[i]] = payloads[i]
+	}
+	return lists, nil
+}
+
+func snapshotPassphrase() (string, error) {
+	if path := strings.TrimSpace(nsPassphraseFile); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		if passphrase := strings.TrimRight(string(data), "\r\n"); passphrase != "" {
+			return passphrase, nil
+		}
+		return "", fmt.Errorf("passphrase file %s is empty", path)
+	}
+	if passphrase := os.Getenv(snapshotPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	return "", fmt.Errorf("a passphrase is required: use --passphrase-file or set %s", snapshotPassphraseEnv)
+}
+
+// applyKubectlStdin runs kubectl with payload on stdin
+func applyKubectlStdin(ctx context.Context, payload []byte, args ...string) error {
+	return kubectlRunner.Run(ctx, kubectl.Streams{
+		Stdin:  bytes.NewReader(payload),
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	}, args...)
+}
+
+func restoreSnapshotList(ctx context.Context, namespace, label string, list []byte, names []string) error {
+	if len(names) == 0 || list == nil {
+		fmt.Printf("%s: none in snapshot\n", label)
+		return nil
+	}
+	if nsRestoreDryRun {
+		fmt.Printf("[dry-run] would apply %d %s: %s\n", len(names), label, strings.Join(names, ", "))
+		return nil
+	}
+	if err := applyKubectlStdin(ctx, list, "-n", namespace, "apply", "--server-side", "--force-conflicts", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to apply %s: %w", label, err)
+	}
+	return nil
+}
+
+// restoreSnapshotPVCs creates the claims that do not exist in the namespace
+func restoreSnapshotPVCs(ctx context.Context, namespace string, list []byte) error {
+	if list == nil {
+		fmt.Println("pvcs: none in snapshot")
+		return nil
+	}
+	out, err := runKubectlOutput(ctx, "-n", namespace, "get", "persistentvolumeclaims", "-o", "jsonpath={.items[*].metadata.name}")
+	if err != nil {
+		return fmt.Errorf("failed to list persistentvolumeclaims: %w", err)
+	}
+	existing := map[string]bool{}
+	for _, name := range strings.Fields(string(out)) {
+		existing[name] = true
+	}
+	missing, names, err := filterSnapshotList(list, existing)
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		fmt.Println("pvcs: all present")
+		return nil
+	}
+	if nsRestoreDryRun {
+		fmt.Printf("[dry-run] would create %d pvcs: %s\n", len(names), strings.Join(names, ", "))
+		return nil
+	}
+	if err := applyKubectlStdin(ctx, missing, "-n", namespace, "create", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to create persistentvolumeclaims: %w", err)
+	}
+	return nil
+}
+
+// restoreSnapshotHelmValues upgrades the release to the snapshot's chart
+// version with the snapshot's values.
+func restoreSnapshotHelmValues(ctx context.Context, namespace string, manifest snapshotManifest, values []byte) error {
+	release := manifest.HelmRelease
+	if release == "" {
+		release = helmReleaseName
+	}
+	version := chartVersionFromChart(manifest.HelmChart)
+	helmArgs := []string{"upgrade", release, openclawChartRef(), "-n", namespace, "--version", version, "-f", "-", "--wait"}
+	if nsRestoreDryRun {
+		if preview := helmBackupPreview(namespace, release, "restore"); preview != "" {
+			fmt.Printf("[dry-run] %s\n", preview)
+		}
+		fmt.Printf("[dry-run] would run: helm %s\n", strings.Join(helmArgs, " "))
+		return nil
+	}
+	if err := backupBeforeHelm(ctx, namespace, release, "restore"); err != nil {
+		return err
+	}
+	if err := helmRepoEnsure(ctx); err != nil {
+		return err
+	}
+	c := interrupt.Command(ctx, "helm", helmArgs...)
+	c.Stdin = bytes.NewReader(values)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	if err := helmRun(ctx, c); err != nil {
+		return fmt.Errorf("helm upgrade failed: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	namespaceSnapshotCmd.Flags().StringVarP(&nsSnapshotOutput, "output", "o", "", "Directory for snapshot archives (default: scripts/recipes/openclaw/snapshots)")
+	namespaceSnapshotCmd.Flags().BoolVar(&nsSnapshotNoSecrets, "no-secrets", false, "Do not include Secrets in the snapshot")
+	namespaceSnapshotCmd.Flags().BoolVar(&nsSnapshotEncrypt, "encrypt-secrets", false, "Encrypt Secrets and Helm values with a passphrase")
+	namespaceSnapshotCmd.Flags().IntVar(&nsSnapshotConcurrency, "concurrency", defaultSnapshotConcurrency, "Number of resource lists to fetch in parallel (capped by --max-parallel)")
+	namespaceRestoreCmd.Flags().BoolVar(&nsRestoreSkipSecrets, "skip-secrets", false, "Do not restore Secrets")
+	namespaceRestoreCmd.Flags().BoolVar(&nsRestoreSkipPVCs, "skip-pvcs", false, "Do not create missing PVCs")
+	namespaceRestoreCmd.Flags().BoolVar(&nsRestoreHelm, "helm", false, "Re-apply the snapshot's Helm values with helm upgrade")
+	namespaceRestoreCmd.Flags().StringVar(&helmBackupPath, "helm-backup-path", "", "Directory for release backups before --helm (default: "+defaultHelmBackupRel+", 'off' disables)")
+	namespaceRestoreCmd.Flags().BoolVar(&nsRestoreDryRun, "dry-run", false, "Show what would be restored without changing the cluster")
+	namespaceRestoreCmd.Flags().BoolVarP(&nsRestoreYes, "yes", "y", false, "Skip the confirmation prompt (same as CONFIRM=true)")
+	namespaceCmd.PersistentFlags().StringVar(&nsPassphraseFile, "passphrase-file", "", "File holding the snapshot passphrase (default: $"+snapshotPassphraseEnv+")")
+	namespaceCmd.AddCommand(namespaceSnapshotCmd)
+	namespaceCmd.AddCommand(namespaceRestoreCmd)
+	rootCmd.AddCommand(namespaceCmd)
+}
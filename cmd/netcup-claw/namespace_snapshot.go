@@ -0,0 +1,251 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/encfile"
+)
+
+// Namespace snapshot archive members
+const (
+	snapshotManifestFile   = "manifest.json"
+	snapshotConfigMapsFile = "configmaps.json"
+	snapshotSecretsFile    = "secrets.json"
+	snapshotSecretsEncFile = "secrets.json.enc"
+	snapshotPVCsFile       = "pvcs.json"
+	snapshotHelmValuesFile = "helm-values.yaml"
+	snapshotHelmValuesEnc  = "helm-values.yaml.enc"
+
+	snapshotFormatVersion = 1
+)
+
+// snapshotEncMagic prefixes encrypted members (see internal/encfile)
+const snapshotEncMagic = "netcup-claw-enc-v1\n"
+
+// snapshotManifest describes a namespace snapshot archive
+type snapshotManifest struct {
+	Version          int       `json:"version"`
+	Namespace        string    `json:"namespace"`
+	CreatedAt        time.Time `json:"createdAt"`
+	HelmRelease      string    `json:"helmRelease,omitempty"`
+	HelmChart        string    `json:"helmChart,omitempty"`
+	ConfigMaps       []string  `json:"configMaps"`
+	Secrets          []string  `json:"secrets"`
+	SecretsEncrypted bool      `json:"secretsEncrypted"`
+	PVCs             []string  `json:"pvcs"`
+}
+
+// snapshotSkipped reports objects that are owned by the cluster or by Helm and
+// must not be restored: the root CA bundle, service account tokens, and Helm
+// release records (restoring those would corrupt the release history).
+func snapshotSkipped(kind string, obj map[string]any) bool {
+	meta, _ := obj["metadata"].(map[string]any)
+	name, _ := meta["name"].(string)
+	switch kind {
+	case "ConfigMap":
+		return name == "kube-root-ca.crt"
+	case "Secret":
+		typ, _ := obj["type"].(string)
+		return typ == "kubernetes.io/service-account-token" || typ == "helm.sh/release.v1"
+	}
+	return false
+}
+
+// cleanSnapshotObject strips server-populated fields so the object can be
+// re-applied into any namespace. PVC volume bindings are dropped so restored
+// claims are provisioned fresh.
+func cleanSnapshotObject(kind string, obj map[string]any) {
+	delete(obj, "status")
+	if meta, ok := obj["metadata"].(map[string]any); ok {
+		for _, key := range []string{"namespace", "uid", "resourceVersion", "creationTimestamp", "generation", "managedFields", "selfLink", "ownerReferences", "finalizers"} {
+			delete(meta, key)
+		}
+		if annotations, ok := meta["annotations"].(map[string]any); ok {
+			delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+			for key := range annotations {
+				if kind == "PersistentVolumeClaim" && (strings.HasPrefix(key, "pv.kubernetes.io/") || strings.HasPrefix(key, "volume.")) {
+					delete(annotations, key)
+				}
+			}
+			if len(annotations) == 0 {
+				delete(meta, "annotations")
+			}
+		}
+	}
+	if kind == "PersistentVolumeClaim" {
+		if spec, ok := obj["spec"].(map[string]any); ok {
+			delete(spec, "volumeName")
+		}
+	}
+}
+
+// buildSnapshotList filters and cleans the items of a `kubectl get -o json`
+// list and returns a re-appliable v1 List plus the sorted object names.
+func buildSnapshotList(kind string, payload []byte) ([]byte, []string, error) {
+	var list struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.Unmarshal(payload, &list); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s list: %w", kind, err)
+	}
+
+	items := make([]map[string]any, 0, len(list.Items))
+	var names []string
+	for _, obj := range list.Items {
+		if snapshotSkipped(kind, obj) {
+			continue
+		}
+		cleanSnapshotObject(kind, obj)
+		obj["kind"] = kind
+		obj["apiVersion"] = "v1"
+		meta, _ := obj["metadata"].(map[string]any)
+		name, _ := meta["name"].(string)
+		names = append(names, name)
+		items = append(items, obj)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		ni, _ := items[i]["metadata"].(map[string]any)["name"].(string)
+		nj, _ := items[j]["metadata"].(map[string]any)["name"].(string)
+		return ni < nj
+	})
+	sort.Strings(names)
+
+	out, err := json.MarshalIndent(map[string]any{"apiVersion": "v1", "kind": "List", "items": items}, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode %s list: %w", kind, err)
+	}
+	return append(out, '\n'), names, nil
+}
+
+// filterSnapshotList drops list items whose name is in skip
+func filterSnapshotList(payload []byte, skip map[string]bool) ([]byte, []string, error) {
+	var list map[string]any
+	if err := json.Unmarshal(payload, &list); err != nil {
+		return nil, nil, fmt.Errorf("invalid snapshot list: %w", err)
+	}
+	items, _ := list["items"].([]any)
+	kept := make([]any, 0, len(items))
+	var names []string
+	for _, item := range items {
+		obj, _ := item.(map[string]any)
+		meta, _ := obj["metadata"].(map[string]any)
+		name, _ := meta["name"].(string)
+		if skip[name] {
+			continue
+		}
+		kept = append(kept, item)
+		names = append(names, name)
+	}
+	list["items"] = kept
+	out, err := json.Marshal(list)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode snapshot list: %w", err)
+	}
+	return out, names, nil
+}
+
+// encryptSnapshotMember encrypts data with a key derived from passphrase
+func encryptSnapshotMember(passphrase string, data []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("an encryption passphrase is required")
+	}
+	return encfile.Seal(snapshotEncMagic, passphrase, data)
+}
+
+// decryptSnapshotMember reverses encryptSnapshotMember
+func decryptSnapshotMember(passphrase string, data []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("secrets are encrypted; a passphrase is required")
+	}
+	plain, err := encfile.Open(snapshotEncMagic, passphrase, data)
+	switch {
+	case errors.Is(err, encfile.ErrNotSealed):
+		return nil, errors.New("not an encrypted snapshot member")
+	case errors.Is(err, encfile.ErrTruncated):
+		return nil, errors.New("encrypted snapshot member is truncated")
+	case errors.Is(err, encfile.ErrDecrypt):
+		return nil, errors.New("failed to decrypt secrets (wrong passphrase?)")
+	}
+	return plain, err
+}
+
+// writeSnapshotArchive writes members as a gzipped tar stream in name order
+func writeSnapshotArchive(w io.Writer, members map[string][]byte, modTime time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(members[name])), ModTime: modTime, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(members[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// readSnapshotArchive reads the known members of a snapshot archive. Unknown
+// or nested entries are rejected rather than silently ignored.
+func readSnapshotArchive(r io.Reader) (map[string][]byte, snapshotManifest, error) {
+	var manifest snapshotManifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, manifest, fmt.Errorf("invalid gzip stream: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	known := map[string]bool{
+		snapshotManifestFile: true, snapshotConfigMapsFile: true, snapshotSecretsFile: true,
+		snapshotSecretsEncFile: true, snapshotPVCsFile: true, snapshotHelmValuesFile: true, snapshotHelmValuesEnc: true,
+	}
+	members := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, manifest, fmt.Errorf("invalid tar stream: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !known[name] {
+			return nil, manifest, fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, manifest, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		members[name] = data
+	}
+
+	raw, ok := members[snapshotManifestFile]
+	if !ok {
+		return nil, manifest, fmt.Errorf("archive has no %s; not a namespace snapshot", snapshotManifestFile)
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, manifest, fmt.Errorf("invalid %s: %w", snapshotManifestFile, err)
+	}
+	if manifest.Version != snapshotFormatVersion {
+		return nil, manifest, fmt.Errorf("unsupported snapshot version %d", manifest.Version)
+	}
+	return members, manifest, nil
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSnapshotMemberEncryption(t *testing.T) {
+	plain := []byte(`{"kind":"List","items":[]}`)
+	enc, err := encryptSnapshotMember("correct horse", plain)
+	if err != nil {
+		t.Fatalf("encryptSnapshotMember() error: %v", err)
+	}
+	if bytes.Contains(enc, plain) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+	got, err := decryptSnapshotMember("correct horse", enc)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("decryptSnapshotMember() = %q, %v", got, err)
+	}
+	if _, err := decryptSnapshotMember("wrong", enc); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("decrypt with wrong passphrase error = %v", err)
+	}
+	if _, err := encryptSnapshotMember("", plain); err == nil {
+		t.Error("encryptSnapshotMember() expected error without passphrase")
+	}
+}
+
+func TestBuildSnapshotList(t *testing.T) {
+	secrets := `{"items":[
+	  {"metadata":{"name":"openclaw-env","namespace":"openclaw","uid":"u1","resourceVersion":"42","managedFields":[{}],
+	    "annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{}"}},"type":"Opaque","data":{"K":"dg=="}},
+	  {"metadata":{"name":"sh.helm.release.v1.openclaw.v3"},"type":"helm.sh/release.v1"},
+	  {"metadata":{"name":"default-token"},"type":"kubernetes.io/service-account-token"},
+	  {"metadata":{"name":"api-keys","annotations":{"team":"ops"}},"type":"Opaque"}]}`
+	list, names, err := buildSnapshotList("Secret", []byte(secrets))
+	if err != nil {
+		t.Fatalf("buildSnapshotList() error: %v", err)
+	}
+	if strings.Join(names, ",") != "api-keys,openclaw-env" {
+		t.Errorf("names = %v", names)
+	}
+	var decoded struct {
+		Kind  string           `json:"kind"`
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.Unmarshal(list, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Kind != "List" || len(decoded.Items) != 2 {
+		t.Fatalf("list = %s", list)
+	}
+	meta := decoded.Items[1]["metadata"].(map[string]any)
+	for _, key := range []string{"namespace", "uid", "resourceVersion", "managedFields", "annotations"} {
+		if _, ok := meta[key]; ok {
+			t.Errorf("metadata.%s was not stripped", key)
+		}
+	}
+	if decoded.Items[1]["kind"] != "Secret" || decoded.Items[1]["apiVersion"] != "v1" {
+		t.Errorf("item kind/apiVersion = %v/%v", decoded.Items[1]["kind"], decoded.Items[1]["apiVersion"])
+	}
+	if decoded.Items[0]["metadata"].(map[string]any)["annotations"] == nil {
+		t.Error("user annotations must be kept")
+	}
+}
+
+func TestBuildSnapshotListPVC(t *testing.T) {
+	pvcs := `{"items":[{"metadata":{"name":"data","annotations":{"pv.kubernetes.io/bind-completed":"yes","volume.kubernetes.io/storage-provisioner":"local"}},
+	  "spec":{"accessModes":["ReadWriteOnce"],"volumeName":"pvc-123","resources":{"requests":{"storage":"5Gi"}}},"status":{"phase":"Bound"}}]}`
+	list, _, err := buildSnapshotList("PersistentVolumeClaim", []byte(pvcs))
+	if err != nil {
+		t.Fatalf("buildSnapshotList() error: %v", err)
+	}
+	for _, stale := range []string{"volumeName", "pv.kubernetes.io", "status", "Bound"} {
+		if bytes.Contains(list, []byte(stale)) {
+			t.Errorf("PVC list still contains %q: %s", stale, list)
+		}
+	}
+	if !bytes.Contains(list, []byte("5Gi")) {
+		t.Errorf("PVC list lost its spec: %s", list)
+	}
+
+	filtered, names, err := filterSnapshotList(list, map[string]bool{"data": true})
+	if err != nil || len(names) != 0 || bytes.Contains(filtered, []byte("5Gi")) {
+		t.Errorf("filterSnapshotList() = %s, %v, %v", filtered, names, err)
+	}
+}
+
+func TestSnapshotArchiveRoundTrip(t *testing.T) {
+	manifest := snapshotManifest{Version: snapshotFormatVersion, Namespace: "openclaw", ConfigMaps: []string{"a"}}
+	raw, _ := json.Marshal(manifest)
+	members := map[string][]byte{
+		snapshotManifestFile:   raw,
+		snapshotConfigMapsFile: []byte(`{"kind":"List"}`),
+		snapshotHelmValuesFile: []byte("image:\n  tag: x\n"),
+	}
+	var buf bytes.Buffer
+	if err := writeSnapshotArchive(&buf, members, time.Unix(0, 0)); err != nil {
+		t.Fatalf("writeSnapshotArchive() error: %v", err)
+	}
+	got, gotManifest, err := readSnapshotArchive(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("readSnapshotArchive() error: %v", err)
+	}
+	if gotManifest.Namespace != "openclaw" || len(got) != 3 || string(got[snapshotHelmValuesFile]) != "image:\n  tag: x\n" {
+		t.Errorf("round trip = %+v, %v", gotManifest, got)
+	}
+
+	buf.Reset()
+	members["../evil"] = []byte("x")
+	if err := writeSnapshotArchive(&buf, members, time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readSnapshotArchive(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("readSnapshotArchive() expected error for unexpected entry")
+	}
+
+	buf.Reset()
+	delete(members, "../evil")
+	delete(members, snapshotManifestFile)
+	if err := writeSnapshotArchive(&buf, members, time.Unix(0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readSnapshotArchive(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("readSnapshotArchive() expected error without manifest")
+	}
+}
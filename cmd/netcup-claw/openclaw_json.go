@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/openclawapi"
+	"github.com/mfittko/netcup-kube/internal/output"
+)
+
+// openclawJSONOptions are the netcup-claw flags of the openclaw passthrough
+type openclawJSONOptions struct {
+	JSON   bool
+	Pretty bool
+	Query  string
+}
+
+// parseOpenClawJSONFlags removes --json, --pretty and --query <expr> from the
+// arguments of the openclaw passthrough. --pretty and --query imply --json,
+// which is passed on to the OpenClaw CLI. Arguments after "--" are passed
+// through unchanged.
+func parseOpenClawJSONFlags(args []string) ([]string, openclawJSONOptions, error) {
+	var opts openclawJSONOptions
+	out := make([]string, 0, len(args)+1)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			out = append(out, args[i+1:]...)
+			i = len(args)
+		case arg == "--json":
+			opts.JSON = true
+		case arg == "--pretty":
+			opts.Pretty = true
+		case arg == "--query":
+			if i+1 >= len(args) {
+				return nil, opts, fmt.Errorf("--query requires an expression, e.g. --query .gateway.status")
+			}
+			opts.Query = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--query="):
+			opts.Query = strings.TrimPrefix(arg, "--query=")
+		default:
+			out = append(out, arg)
+		}
+	}
+	if opts.Pretty || opts.Query != "" {
+		opts.JSON = true
+	}
+	if opts.JSON {
+		out = append(out, "--json")
+	}
+	return out, opts, nil
+}
+
+// runOpenClawJSON runs an OpenClaw CLI command with --json, validates its
+// output and prints it, filtered by --query and indented with --pretty
+func runOpenClawJSON(ctx context.Context, api *openclawapi.Client, args []string, opts openclawJSONOptions) error {
+	doc, err := api.RunJSON(ctx, args...)
+	if err != nil {
+		return err
+	}
+	return emitOpenClawJSON(os.Stdout, doc, opts)
+}
+
+func emitOpenClawJSON(w io.Writer, doc json.RawMessage, opts openclawJSONOptions) error {
+	if opts.Query == "" {
+		var buf bytes.Buffer
+		var err error
+		if opts.Pretty {
+			err = json.Indent(&buf, doc, "", "  ")
+		} else {
+			err = json.Compact(&buf, doc)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid JSON from openclaw: %w", err)
+		}
+		buf.WriteByte('\n')
+		_, err = w.Write(buf.Bytes())
+		return err
+	}
+
+	// Keep numbers as written so large IDs survive the round trip
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON from openclaw: %w", err)
+	}
+	results, err := output.Query(value, opts.Query)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if opts.Pretty {
+		encoder.SetIndent("", "  ")
+	}
+	for _, result := range results {
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/pins"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pinsFile   string
+	pinsKind   string
+	pinsJSON   bool
+	pinsCreate bool
+)
+
+var pinsCmd = &cobra.Command{
+	Use:   "pins",
+	Short: "List, read and set the version pins of recipes.conf",
+	Long: `Manage the version pins in recipes.conf: chart versions (CHART_VERSION_*),
+release channels (CHART_CHANNEL_*), image tags (IMAGE_VERSION_*), the k3s
+version (K3S_VERSION) and other release versions (*_VERSION).
+
+Edits only rewrite the assignment line, so comments and the layout of the
+file are kept; the file is locked like during 'netcup-claw upgrade'.
+
+Sub-commands:
+  list    - List the pins (--kind chart|channel|image|k3s|release)
+  get     - Print the value of a pin
+  set     - Set one or more pins (--create adds missing ones)
+  export  - Print all pins as a JSON object for automation
+
+Examples:
+  netcup-claw pins list
+  netcup-claw pins list --kind chart --json
+  netcup-claw pins get CHART_VERSION_OPENCLAW
+  netcup-claw pins set CHART_VERSION_OPENCLAW=1.4.5 IMAGE_VERSION_REDISINSIGHT=2.64.0
+  netcup-claw pins set K3S_VERSION=v1.31.4+k3s1 --create
+  netcup-claw pins export | jq -r .CHART_VERSION_OPENCLAW`,
+	SilenceUsage: true,
+}
+
+var pinsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the version pins",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if pinsKind != "" && !slices.Contains(pins.Kinds(), pinsKind) {
+			return fmt.Errorf("invalid --kind %q (use %s)", pinsKind, strings.Join(pins.Kinds(), ", "))
+		}
+		f, err := pins.Load(pinsFile)
+		if err != nil {
+			return err
+		}
+		list := []pins.Pin{}
+		for _, p := range f.List() {
+			if pinsKind == "" || p.Kind == pinsKind {
+				list = append(list, p)
+			}
+		}
+		if pinsJSON {
+			return output.WriteJSON(os.Stdout, list)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "KEY\tVALUE\tKIND")
+		for _, p := range list {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", p.Key, p.Value, p.Kind)
+		}
+		return w.Flush()
+	},
+}
+
+var pinsGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a version pin",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := pins.Load(pinsFile)
+		if err != nil {
+			return err
+		}
+		p, ok := f.Get(args[0])
+		if !ok {
+			return fmt.Errorf("%s is not pinned in %s", args[0], pinsFile)
+		}
+		if pinsJSON {
+			return output.WriteJSON(os.Stdout, p)
+		}
+		fmt.Println(p.Value)
+		return nil
+	},
+}
+
+var pinsSetCmd = &cobra.Command{
+	Use:   "set <KEY=VALUE>...",
+	Short: "Set version pins",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		type change struct{ key, value string }
+		changes := make([]change, 0, len(args))
+		for _, arg := range args {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				return fmt.Errorf("invalid argument %q (use KEY=VALUE)", arg)
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if err := pins.Validate(key, value); err != nil {
+				return err
+			}
+			changes = append(changes, change{key, value})
+		}
+
+		var messages []string
+		err := pins.Update(pinsFile, func(f *pins.File) error {
+			for _, c := range changes {
+				old, exists := f.Get(c.key)
+				switch {
+				case exists && old.Value == c.value:
+					messages = append(messages, fmt.Sprintf("%s=%s unchanged", c.key, c.value))
+				case exists:
+					if err := f.Replace(c.key, c.value); err != nil {
+						return err
+					}
+					messages = append(messages, fmt.Sprintf("updated %s=%s (was %s)", c.key, c.value, old.Value))
+				case !pinsCreate:
+					return fmt.Errorf("%s is not pinned in %s; use --create to add it", c.key, pinsFile)
+				default:
+					if _, err := f.Set(c.key, c.value); err != nil {
+						return err
+					}
+					messages = append(messages, fmt.Sprintf("added %s=%s", c.key, c.value))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, m := range messages {
+			fmt.Printf("%s in %s\n", m, pinsFile)
+		}
+		return nil
+	},
+}
+
+var pinsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Print all version pins as a JSON object",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := pins.Load(pinsFile)
+		if err != nil {
+			return err
+		}
+		return output.WriteJSON(os.Stdout, f.Values())
+	},
+}
+
+func init() {
+	pinsCmd.PersistentFlags().StringVar(&pinsFile, "file", recipesConfRel, "Pin file to read and edit")
+	pinsListCmd.Flags().StringVar(&pinsKind, "kind", "", "Only list pins of this kind: "+strings.Join(pins.Kinds(), ", "))
+	pinsListCmd.Flags().BoolVar(&pinsJSON, "json", false, "Print the pins as JSON")
+	pinsGetCmd.Flags().BoolVar(&pinsJSON, "json", false, "Print the pin as JSON")
+	pinsSetCmd.Flags().BoolVar(&pinsCreate, "create", false, "Add pins that are not in the file yet")
+	pinsCmd.AddCommand(pinsListCmd)
+	pinsCmd.AddCommand(pinsGetCmd)
+	pinsCmd.AddCommand(pinsSetCmd)
+	pinsCmd.AddCommand(pinsExportCmd)
+	rootCmd.AddCommand(pinsCmd)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/plugin"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
+	"github.com/spf13/cobra"
+)
+
+const pluginPrefix = "netcup-claw"
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "List third-party plugins found on PATH",
+	Long: `netcup-claw runs executables named netcup-claw-<name> found on PATH as
+subcommands, like kubectl plugins: "netcup-claw foo bar" runs netcup-claw-foo-bar
+if present, otherwise netcup-claw-foo with "bar" as its first argument.
+Built-in commands always win.
+
+Plugins inherit the environment (OPENCLAW_*, KUBECONFIG, ...) with the
+settings of the current context applied and the tunnel settings resolved
+from the global --tunnel-* flags exported as TUNNEL_HOST, TUNNEL_USER,
+TUNNEL_LOCAL_PORT, TUNNEL_REMOTE_HOST and TUNNEL_REMOTE_PORT.
+NETCUP_CLAW_BIN is the path of netcup-claw, so plugins can call back into it.
+
+Sub-commands:
+  list  - Show discovered plugins
+
+Examples:
+  netcup-claw plugin list
+  netcup-claw --tunnel-host ops.example.com usage-report --since 7d`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show plugins discovered on PATH",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plugins := plugin.List(pluginPrefix, os.Getenv("PATH"))
+		if len(plugins) == 0 {
+			fmt.Printf("no %s-* plugins found on PATH\n", pluginPrefix)
+			return nil
+		}
+		for _, p := range plugins {
+			fmt.Printf("%-20s %s\n", p.Name, p.Path)
+			if p.ShadowedBy != "" {
+				fmt.Printf("  warning: shadowed by %s\n", p.ShadowedBy)
+			}
+			if first := strings.Fields(p.Name)[0]; isBuiltinCommand(first) {
+				fmt.Printf("  warning: overshadowed by built-in command %q\n", first)
+			}
+		}
+		return nil
+	},
+}
+
+// isBuiltinCommand reports whether name (or an alias) is a subcommand of rootCmd
+func isBuiltinCommand(name string) bool {
+	switch name {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPlugin runs a plugin if args name one that is not a built-in command.
+// handled is false when cobra should process args as usual.
+func runPlugin(ctx context.Context, args []string) (handled bool, err error) {
+	globals, rest := plugin.SplitArgs(args, func(name string) bool {
+		f := rootCmd.PersistentFlags().Lookup(name)
+		return f != nil && f.Value.Type() != "bool"
+	})
+	if len(rest) == 0 || isBuiltinCommand(rest[0]) {
+		return false, nil
+	}
+	path, pluginArgs, ok := plugin.Find(pluginPrefix, rest, exec.LookPath)
+	if !ok {
+		return false, nil
+	}
+	if err := rootCmd.PersistentFlags().Parse(globals); err != nil {
+		return true, err
+	}
+	if err := applyContext(); err != nil {
+		return true, err
+	}
+	if err := applyLocalCluster(); err != nil {
+		return true, err
+	}
+
+	tp := tunnelConfig()
+	if localCluster.Kind != "" {
+		// No tunnel: the plugin and nested netcup-claw calls use the local context
+		tp.Host = ""
+	}
+	env := append(os.Environ(),
+		"TUNNEL_HOST="+tp.Host,
+		"TUNNEL_USER="+tp.User,
+		"TUNNEL_LOCAL_PORT="+tp.LocalPort,
+		"TUNNEL_REMOTE_HOST="+tp.RemoteHost,
+		"TUNNEL_REMOTE_PORT="+tp.RemotePort,
+	)
+	if localCluster.Kind != "" {
+		env = append(env, localClusterEnv+"=true")
+	}
+	if self, err := os.Executable(); err == nil {
+		env = append(env, "NETCUP_CLAW_BIN="+self)
+	}
+
+	ctx, span := telemetry.Start(ctx, "plugin "+rest[0])
+	if traceParent := span.TraceParent(); traceParent != "" {
+		env = append(env, "TRACEPARENT="+traceParent)
+	}
+	return true, span.End(plugin.Run(ctx, path, pluginArgs, env))
+}
+
+func init() {
+	pluginCmd.AddCommand(pluginListCmd)
+	rootCmd.AddCommand(pluginCmd)
+}
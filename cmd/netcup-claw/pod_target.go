@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/openclaw"
+)
+
+const podFlag = "--pod"
+
+// podTarget is the pod and container picked with --pod and --container
+type podTarget struct {
+	Pod       string
+	Container string
+}
+
+// flagValue returns the value of the "--name value" or "--name=value" flag at
+// args[i] and the index of its last argument
+func flagValue(args []string, i int) (string, int, error) {
+	name, value, hasValue := strings.Cut(args[i], "=")
+	if !hasValue {
+		if i+1 >= len(args) {
+			return "", i, fmt.Errorf("%s requires a value", name)
+		}
+		i++
+		value = args[i]
+	}
+	if value == "" {
+		return "", i, fmt.Errorf("%s requires a value", name)
+	}
+	return value, i, nil
+}
+
+// parsePodFlags consumes leading --pod and --container flags. Everything from
+// the first other argument on (including "--") is returned unchanged, so the
+// flags of the command run in the pod are never touched.
+func parsePodFlags(args []string) ([]string, podTarget, error) {
+	var target podTarget
+	for i := 0; i < len(args); i++ {
+		name, _, _ := strings.Cut(args[i], "=")
+		if name != podFlag && name != "--container" {
+			return args[i:], target, nil
+		}
+		value, last, err := flagValue(args, i)
+		if err != nil {
+			return nil, target, err
+		}
+		if name == podFlag {
+			target.Pod = value
+		} else {
+			target.Container = value
+		}
+		i = last
+	}
+	return nil, target, nil
+}
+
+// stripPodFlag removes --pod <name> / --pod=<name> from kubectl logs args.
+// Arguments after "--" are left alone.
+func stripPodFlag(args []string) ([]string, string, error) {
+	out := make([]string, 0, len(args))
+	pod := ""
+	for i := 0; i < len(args); i++ {
+		name, _, _ := strings.Cut(args[i], "=")
+		switch {
+		case args[i] == "--":
+			return append(out, args[i:]...), pod, nil
+		case name == podFlag:
+			value, last, err := flagValue(args, i)
+			if err != nil {
+				return nil, "", err
+			}
+			pod = value
+			i = last
+		default:
+			out = append(out, args[i])
+		}
+	}
+	return out, pod, nil
+}
+
+// resolveTargetPod returns pod if set (e.g. to pick one of two pods during a
+// rollout), else the resolved OpenClaw pod
+func resolveTargetPod(ctx context.Context, pod string) (openclaw.Config, string, error) {
+	if pod == "" {
+		return resolveOpenClawPod(ctx)
+	}
+	cfg := openclawConfig()
+	if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+		return cfg, "", err
+	}
+	return cfg, pod, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePodFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    []string
+		target  podTarget
+		wantErr bool
+	}{
+		{args: []string{"status", "--pod", "x"}, want: []string{"status", "--pod", "x"}},
+		{args: []string{"--pod", "openclaw-b", "status"}, want: []string{"status"}, target: podTarget{Pod: "openclaw-b"}},
+		{args: []string{"--pod=openclaw-b", "--container=main", "--", "x"}, want: []string{"--", "x"}, target: podTarget{Pod: "openclaw-b", Container: "main"}},
+		{args: []string{"--pod"}, wantErr: true},
+		{args: []string{"--container=", "status"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, target, err := parsePodFlags(tt.args)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePodFlags(%q) succeeded, want error", tt.args)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) || target != tt.target {
+			t.Errorf("parsePodFlags(%q) = %q, %+v, %v; want %q, %+v", tt.args, got, target, err, tt.want, tt.target)
+		}
+	}
+}
+
+func TestStripPodFlag(t *testing.T) {
+	got, pod, err := stripPodFlag([]string{"--previous", "--pod", "openclaw-a", "-c", "main", "--", "--pod=x"})
+	want := []string{"--previous", "-c", "main", "--", "--pod=x"}
+	if err != nil || pod != "openclaw-a" || !reflect.DeepEqual(got, want) {
+		t.Errorf("stripPodFlag() = %q, %q, %v; want %q, openclaw-a", got, pod, err, want)
+	}
+	if _, _, err := stripPodFlag([]string{"--pod="}); err == nil {
+		t.Error("stripPodFlag(--pod=) succeeded, want error")
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/localproxy"
+	"github.com/mfittko/netcup-kube/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+var (
+	proxyListen  string
+	proxyRoutes  []string
+	proxyTLSMode string
+	proxyCertDir string
+)
+
+const (
+	defaultProxyListen      = "127.0.0.1:8443"
+	defaultGrafanaLocalPort = "3000"
+)
+
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Local TLS reverse proxy for forwarded services",
+	Long: `Serve local port-forwards behind one TLS listener with hostname-based routing.
+
+Sub-commands:
+  start  - Run the proxy in the foreground (Ctrl-C to stop)`,
+}
+
+var proxyStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Run the local TLS reverse proxy (foreground)",
+	Long: `Terminate TLS locally and reverse-proxy to local port-forwards by hostname.
+
+Default routes:
+  openclaw.localhost -> 127.0.0.1:$OPENCLAW_LOCAL_PORT (default 18789)
+  grafana.localhost  -> 127.0.0.1:$GRAFANA_LOCAL_PORT (default 3000)
+
+Additional routes come from --route (repeatable) and OPENCLAW_PROXY_ROUTES
+(comma-separated), both in the form name=port or host=port.
+
+TLS modes:
+  self-signed  generate and cache a self-signed certificate (default)
+  mkcert       issue a locally-trusted certificate via mkcert
+
+Examples:
+  netcup-claw port-forward start && netcup-claw proxy start
+  netcup-claw proxy start --route prometheus=9090 --tls mkcert
+  netcup-claw proxy start --listen 127.0.0.1:443`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		routes, err := proxyRouteTable()
+		if err != nil {
+			return err
+		}
+
+		certDir := proxyCertDir
+		if certDir == "" {
+			certDir = defaultProxyCertDir()
+		}
+		hosts := localproxy.Hosts(routes)
+		var certFile, keyFile string
+		switch proxyTLSMode {
+		case "self-signed":
+			certFile, keyFile, err = localproxy.EnsureSelfSignedCert(certDir, hosts, time.Now())
+		case "mkcert":
+			certFile, keyFile, err = localproxy.MkcertCert(certDir, hosts)
+		default:
+			return fmt.Errorf("invalid --tls %q (valid: self-signed, mkcert)", proxyTLSMode)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to prepare TLS certificate: %w", err)
+		}
+
+		listener, err := net.Listen("tcp", proxyListen)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", proxyListen, err)
+		}
+
+		_, port, _ := net.SplitHostPort(listener.Addr().String())
+		fmt.Printf("Proxy listening on https://%s (TLS: %s, cert: %s)\n", listener.Addr(), proxyTLSMode, certFile)
+		for _, r := range routes {
+			state := "up"
+			if !localPortOpen(r.Port) {
+				state = "down (start the port-forward)"
+			}
+			fmt.Printf("  https://%s:%s -> 127.0.0.1:%s [%s]\n", r.Host, port, r.Port, state)
+		}
+		if proxyTLSMode == "self-signed" {
+			fmt.Println("Browsers will warn about the self-signed certificate; use --tls mkcert for a trusted one.")
+		}
+
+		srv := &http.Server{
+			Handler:           localproxy.NewHandler(routes),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- srv.ServeTLS(listener, certFile, keyFile) }()
+
+		select {
+		case err := <-errCh:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return fmt.Errorf("proxy failed: %w", err)
+		case <-cmd.Context().Done():
+			fmt.Println("\nShutting down proxy...")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return srv.Shutdown(shutdownCtx)
+		}
+	},
+}
+
+// proxyRouteTable builds the route table from defaults, OPENCLAW_PROXY_ROUTES,
+// and --route flags (in increasing precedence).
+func proxyRouteTable() ([]localproxy.Route, error) {
+	grafanaPort := strings.TrimSpace(os.Getenv("GRAFANA_LOCAL_PORT"))
+	if grafanaPort == "" {
+		grafanaPort = defaultGrafanaLocalPort
+	}
+	defaults := []localproxy.Route{
+		{Host: "openclaw." + localproxy.DefaultDomain, Port: openclawConfig().LocalPort},
+		{Host: "grafana." + localproxy.DefaultDomain, Port: grafanaPort},
+	}
+
+	fromEnv, err := localproxy.ParseRoutes(os.Getenv("OPENCLAW_PROXY_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OPENCLAW_PROXY_ROUTES: %w", err)
+	}
+	var fromFlags []localproxy.Route
+	for _, spec := range proxyRoutes {
+		route, err := localproxy.ParseRoute(spec)
+		if err != nil {
+			return nil, err
+		}
+		fromFlags = append(fromFlags, route)
+	}
+	return localproxy.MergeRoutes(defaults, fromEnv, fromFlags), nil
+}
+
+func defaultProxyCertDir() string {
+	dir := filepath.Join(paths.ConfigDir("netcup-claw"), "proxy")
+	if legacy, err := os.UserConfigDir(); err == nil {
+		return paths.Migrate(filepath.Join(legacy, "netcup-claw", "proxy"), dir)
+	}
+	return dir
+}
+
+func localPortOpen(port string) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", port), 300*time.Millisecond)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+func init() {
+	proxyStartCmd.Flags().StringVar(&proxyListen, "listen", defaultProxyListen, "Address to listen on")
+	proxyStartCmd.Flags().StringArrayVar(&proxyRoutes, "route", nil, "Additional route name=port or host=port (repeatable)")
+	proxyStartCmd.Flags().StringVar(&proxyTLSMode, "tls", "self-signed", "TLS certificate mode: self-signed or mkcert")
+	proxyStartCmd.Flags().StringVar(&proxyCertDir, "cert-dir", "", "Directory for the proxy certificate (default: <user config dir>/netcup-claw/proxy)")
+	proxyCmd.AddCommand(proxyStartCmd)
+	rootCmd.AddCommand(proxyCmd)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// jsonQuery is the global --query expression
+var jsonQuery string
+
+// applyJSONQuery installs --query for the JSON output of cmd; it implies --json
+func applyJSONQuery(cmd *cobra.Command) error {
+	if jsonQuery == "" {
+		return nil
+	}
+	flag := cmd.Flags().Lookup("json")
+	if flag == nil || flag.Value.Type() != "bool" {
+		return fmt.Errorf("--query needs a command with JSON output (%s has no --json)", cmd.CommandPath())
+	}
+	if err := flag.Value.Set("true"); err != nil {
+		return err
+	}
+	return output.SetQuery(jsonQuery)
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&jsonQuery, "query", "", `Filter JSON output with an expression in a subset of jq (paths, |, select, comparisons, length, keys; no object construction, "," or map), e.g. '.components[] | select(.state != "ok")' (implies --json)`)
+}
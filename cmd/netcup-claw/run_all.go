@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+)
+
+// runOptions are the flags of "run" given before the shell command
+type runOptions struct {
+	AllPods   bool
+	Selector  string
+	Pod       string
+	Container string
+	// Concurrency bounds the pods running the command at once with
+	// --all-pods; 0 means all of them (still capped by --max-parallel)
+	Concurrency int
+	// MaxParallel is --max-parallel, which cobra leaves to "run" because
+	// run parses its own flags; only applied when HasMaxParallel is set
+	MaxParallel    int
+	HasMaxParallel bool
+}
+
+// parseRunFlags consumes the leading --all-pods, --selector, --pod,
+// --container, --concurrency and --max-parallel flags of "run"; everything
+// from the first other argument (or after "--") is the shell command
+func parseRunFlags(args []string) ([]string, runOptions, error) {
+	var opts runOptions
+	rest := []string(nil)
+	for i := 0; i < len(args); i++ {
+		name, _, _ := strings.Cut(args[i], "=")
+		if args[i] == "--" {
+			rest = args[i+1:]
+			break
+		}
+		if args[i] == "--all-pods" {
+			opts.AllPods = true
+			continue
+		}
+		if name != "--selector" && name != podFlag && name != "--container" && name != "--concurrency" && name != "--max-parallel" {
+			rest = args[i:]
+			break
+		}
+		value, last, err := flagValue(args, i)
+		if err != nil {
+			return nil, opts, err
+		}
+		switch name {
+		case "--selector":
+			opts.Selector = value
+		case podFlag:
+			opts.Pod = value
+		case "--concurrency":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, opts, fmt.Errorf("--concurrency must be a positive number, got %q", value)
+			}
+			opts.Concurrency = n
+		case "--max-parallel":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, opts, fmt.Errorf("--max-parallel must be 0 (no limit) or greater, got %q", value)
+			}
+			opts.MaxParallel, opts.HasMaxParallel = n, true
+		default:
+			opts.Container = value
+		}
+		i = last
+	}
+	if opts.Pod != "" && (opts.AllPods || opts.Selector != "") {
+		return nil, opts, fmt.Errorf("--pod cannot be combined with --all-pods or --selector")
+	}
+	if opts.Concurrency > 0 && !opts.AllPods {
+		return nil, opts, fmt.Errorf("--concurrency requires --all-pods")
+	}
+	return rest, opts, nil
+}
+
+// podRunResult is the outcome of the shell command in one pod
+type podRunResult struct {
+	Pod      string
+	ExitCode int
+	Err      error
+}
+
+// listRunningPods returns the running pods matching selector
+func listRunningPods(ctx context.Context, namespace, selector string) ([]string, error) {
+	out, err := runKubectlOutput(ctx, "-n", namespace, "get", "pod",
+		"-l", selector,
+		"--field-selector=status.phase=Running",
+		"-o", `jsonpath={range .items[*]}{.metadata.name}{"\n"}{end}`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods with label %s in namespace %s: %w", selector, namespace, err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// runInAllPods runs the shell command in up to concurrency pods at a time (0:
+// all at once). Each output line is prefixed with "[pod] "; a summary of the
+// exit codes follows.
+func runInAllPods(ctx context.Context, namespace, container string, pods, args []string, concurrency int, stdout, stderr io.Writer) error {
+	if concurrency < 1 {
+		concurrency = len(pods)
+	}
+	var mu sync.Mutex
+	results := make([]podRunResult, len(pods))
+	runBounded(len(pods), boundedConcurrency(concurrency), func(i int) error {
+		pod := pods[i]
+		prefix := "[" + pod + "] "
+		out := &prefixWriter{w: stdout, mu: &mu, prefix: prefix}
+		errOut := &prefixWriter{w: stderr, mu: &mu, prefix: prefix}
+		execArgs := podShellArgs(namespace, pod, container, args)
+		err := kubectlRunner.Run(ctx, kubectl.Streams{Stdout: out, Stderr: errOut}, execArgs...)
+		out.Flush()
+		errOut.Flush()
+		results[i] = podRunResult{Pod: pod, Err: err}
+		if err != nil {
+			results[i].ExitCode = -1
+			var kerr *kubectl.Error
+			if errors.As(err, &kerr) {
+				results[i].ExitCode = kerr.ExitCode
+			}
+		}
+		return err
+	})
+
+	_, _ = fmt.Fprintln(stdout)
+	printPodRunSummary(stdout, results)
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("command failed in %d of %d pods", failed, len(results))
+	}
+	return nil
+}
+
+func printPodRunSummary(w io.Writer, results []podRunResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "POD\tEXIT")
+	for _, r := range results {
+		exit := fmt.Sprintf("%d", r.ExitCode)
+		if r.ExitCode < 0 {
+			exit = "error"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\n", r.Pod, exit)
+	}
+	_ = tw.Flush()
+}
+
+// podShellArgs returns the kubectl args running the shell command in
+// container of pod; an empty container leaves the choice to kubectl
+func podShellArgs(namespace, pod, container string, args []string) []string {
+	execArgs := buildShellRunKubectlArgs(namespace, pod, args)
+	if container == "" {
+		return withoutExecContainer(execArgs)
+	}
+	return withExecContainer(execArgs, container)
+}
+
+// withExecContainer replaces the -c value of kubectl exec args
+func withExecContainer(args []string, container string) []string {
+	updated := append([]string(nil), args...)
+	for i := 0; i+1 < len(updated); i++ {
+		if updated[i] == "-c" {
+			updated[i+1] = container
+			break
+		}
+	}
+	return updated
+}
+
+// withoutExecContainer drops -c from kubectl exec args, so kubectl picks the
+// default container of the pod
+func withoutExecContainer(args []string) []string {
+	updated := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--" {
+			return append(updated, args[i:]...)
+		}
+		if args[i] == "-c" && i+1 < len(args) {
+			i++
+			continue
+		}
+		updated = append(updated, args[i])
+	}
+	return updated
+}
+
+// prefixWriter prefixes each complete line with prefix. Writers sharing mu
+// never interleave within a line.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		p.writeLine(p.buf[:i+1])
+		p.buf = p.buf[i+1:]
+	}
+}
+
+// Flush writes a final line that did not end in a newline
+func (p *prefixWriter) Flush() {
+	if len(p.buf) > 0 {
+		p.writeLine(append(p.buf, '\n'))
+		p.buf = nil
+	}
+}
+
+func (p *prefixWriter) writeLine(line []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, _ = io.WriteString(p.w, p.prefix)
+	_, _ = p.w.Write(line)
+}
+
+// runShellCommand implements "run": the main pod by default, or every running
+// pod matching the selector with --all-pods
+func runShellCommand(ctx context.Context, args []string) error {
+	args, opts, err := parseRunFlags(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("missing shell command")
+	}
+	if opts.HasMaxParallel {
+		setMaxParallel(opts.MaxParallel)
+	}
+	cfg := openclawConfig()
+	container := opts.Container
+	if opts.Selector == "" || opts.Selector == cfg.LabelSelector {
+		if container == "" {
+			container = openclawMainContainer
+		}
+	}
+	if opts.Selector != "" {
+		cfg.LabelSelector = opts.Selector
+	}
+
+	if !opts.AllPods {
+		var pod string
+		if opts.Selector == "" {
+			cfg, pod, err = resolveTargetPod(ctx, opts.Pod)
+		} else if err = ensureKubeAPIReachableWithTunnel(ctx); err == nil {
+			pod, err = newOpenClawResolver(cfg).ResolvePod(ctx)
+		}
+		if err != nil {
+			return err
+		}
+		return runKubectl(ctx, podShellArgs(cfg.Namespace, pod, container, args)...)
+	}
+
+	if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+		return err
+	}
+	pods, err := listRunningPods(ctx, cfg.Namespace, cfg.LabelSelector)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no running pod found with label %s in namespace %s", cfg.LabelSelector, cfg.Namespace)
+	}
+	return runInAllPods(ctx, cfg.Namespace, container, pods, args, opts.Concurrency, os.Stdout, os.Stderr)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/mfittko/netcup-kube/internal/testkit"
+)
+
+func TestParseRunFlags(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    []string
+		opts    runOptions
+		wantErr bool
+	}{
+		{args: []string{"ls", "-la"}, want: []string{"ls", "-la"}},
+		{args: []string{"--all-pods", "--selector", "app=openclaw", "df", "-h"}, want: []string{"df", "-h"}, opts: runOptions{AllPods: true, Selector: "app=openclaw"}},
+		{args: []string{"--selector=app=x", "--container=sidecar", "env"}, want: []string{"env"}, opts: runOptions{Selector: "app=x", Container: "sidecar"}},
+		{args: []string{"--", "--all-pods"}, want: []string{"--all-pods"}},
+		{args: []string{"echo", "--all-pods"}, want: []string{"echo", "--all-pods"}},
+		{args: []string{"--pod", "openclaw-b", "ls"}, want: []string{"ls"}, opts: runOptions{Pod: "openclaw-b"}},
+		{args: []string{"--pod", "openclaw-b", "--all-pods", "ls"}, wantErr: true},
+		{args: []string{"--all-pods", "--concurrency=2", "uptime"}, want: []string{"uptime"}, opts: runOptions{AllPods: true, Concurrency: 2}},
+		{args: []string{"--concurrency", "2", "uptime"}, wantErr: true},
+		{args: []string{"--all-pods", "--concurrency", "0", "uptime"}, wantErr: true},
+		{args: []string{"--max-parallel", "2", "--all-pods", "uptime"}, want: []string{"uptime"}, opts: runOptions{AllPods: true, MaxParallel: 2, HasMaxParallel: true}},
+		{args: []string{"--all-pods", "--max-parallel=0", "uptime"}, want: []string{"uptime"}, opts: runOptions{AllPods: true, HasMaxParallel: true}},
+		{args: []string{"--max-parallel", "-1", "uptime"}, wantErr: true},
+		{args: []string{"--selector"}, wantErr: true},
+		{args: []string{"--container="}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, opts, err := parseRunFlags(tt.args)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRunFlags(%q) succeeded, want error", tt.args)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) || opts != tt.opts {
+			t.Errorf("parseRunFlags(%q) = %q, %+v, %v; want %q, %+v", tt.args, got, opts, err, tt.want, tt.opts)
+		}
+	}
+}
+
+func TestPodShellArgs(t *testing.T) {
+	got := podShellArgs("openclaw", "pod-a", "", []string{"ls"})
+	want := []string{"-n", "openclaw", "exec", "pod-a", "--", "sh", "-lc", "ls"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("podShellArgs() without container = %q, want %q", got, want)
+	}
+	got = podShellArgs("openclaw", "pod-a", "sidecar", []string{"echo", "-c"})
+	want = []string{"-n", "openclaw", "exec", "-c", "sidecar", "pod-a", "--", "sh", "-lc", "echo -c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("podShellArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestRunInAllPods(t *testing.T) {
+	saved := kubectlRunner
+	t.Cleanup(func() { kubectlRunner = saved })
+	// The fake kubectl prints two lines and fails in pod-b
+	kubectlRunner = kubectl.New(
+		kubectl.WithRetries(0),
+		kubectl.WithCommandFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			pod := args[3]
+			script := "echo hello; printf partial"
+			if pod == "pod-b" {
+				script = "echo oops >&2; exit 3"
+			}
+			return exec.CommandContext(ctx, "sh", "-c", script)
+		}),
+	)
+
+	var stdout, stderr bytes.Buffer
+	err := runInAllPods(context.Background(), "openclaw", "", []string{"pod-a", "pod-b"}, []string{"true"}, 1, &stdout, &stderr)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 pods") {
+		t.Errorf("runInAllPods() error = %v", err)
+	}
+	for _, want := range []string{"[pod-a] hello\n", "[pod-a] partial\n", "pod-a  0\n", "pod-b  3\n"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout.String())
+		}
+	}
+	if stderr.String() != "[pod-b] oops\n" {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
+
+func TestRunMaxParallelStaysOutOfCommand(t *testing.T) {
+	t.Cleanup(func() { setMaxParallel(defaultMaxParallel) })
+	for _, args := range [][]string{
+		{"--max-parallel", "2", "run", "--all-pods", "echo", "hi"},
+		{"run", "--all-pods", "--max-parallel", "2", "echo", "hi"},
+	} {
+		kit := testkit.New(t)
+		kit.On("kubectl", []string{"--request-timeout=3s", "get", "--raw=/livez"}, "ok")
+		kit.On("kubectl", []string{"-n", "openclaw", "get", "pod", "-l", testkit.Any, "--field-selector=status.phase=Running", "-o", testkit.Any}, "openclaw-a\n")
+		kit.On("kubectl", []string{"-n", "openclaw", "exec", "-c", "main", "openclaw-a", "--", "sh", "-lc", "echo hi"}, "hi\n")
+
+		if err := runClaw(t, t.TempDir(), args...); err != nil {
+			t.Fatalf("netcup-claw %q: %v", args, err)
+		}
+		kit.AssertCalled("kubectl", "-n", "openclaw", "exec", "-c", "main", "openclaw-a", "--", "sh", "-lc", "echo hi")
+		if maxParallel != 2 {
+			t.Errorf("netcup-claw %q: max parallel = %d, want 2", args, maxParallel)
+		}
+	}
+}
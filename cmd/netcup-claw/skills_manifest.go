@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	skillManifestFile = "SKILL.md"
+
+	maxSkillNameLen        = 64
+	maxSkillDescriptionLen = 1024
+	// maxSkillDirBytes bounds what a deploy copies into the pod/PVC
+	maxSkillDirBytes = 50 * 1024 * 1024
+)
+
+var skillNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// skillManifest holds the top-level frontmatter fields of SKILL.md
+type skillManifest struct {
+	Name        string
+	Description string
+}
+
+// validateSkillName rejects names that are not a single safe path segment
+func validateSkillName(name string) error {
+	if len(name) > maxSkillNameLen || !skillNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid skill name %q (use lowercase letters, digits, and '-', max %d chars)", name, maxSkillNameLen)
+	}
+	return nil
+}
+
+// parseSkillManifest reads the YAML frontmatter block at the top of SKILL.md.
+// Only top-level "key: value" lines are interpreted; nested blocks (e.g.
+// metadata) are ignored.
+func parseSkillManifest(content string) (skillManifest, error) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return skillManifest{}, fmt.Errorf("%s must start with a '---' frontmatter block", skillManifestFile)
+	}
+
+	var m skillManifest
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "---" {
+			return m, nil
+		}
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		switch strings.TrimSpace(key) {
+		case "name":
+			m.Name = value
+		case "description":
+			m.Description = value
+		}
+	}
+	return skillManifest{}, fmt.Errorf("%s frontmatter is not terminated by '---'", skillManifestFile)
+}
+
+// validateSkillDir checks a local skill directory before it is deployed: the
+// directory name is a valid skill name, SKILL.md declares a matching name and
+// a description, and the tree has no symlinks and stays within the size limit.
+func validateSkillDir(dir string) (skillManifest, error) {
+	skill := filepath.Base(filepath.Clean(dir))
+	if err := validateSkillName(skill); err != nil {
+		return skillManifest{}, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, skillManifestFile))
+	if err != nil {
+		return skillManifest{}, fmt.Errorf("skill %s: missing %s: %w", skill, skillManifestFile, err)
+	}
+	m, err := parseSkillManifest(string(data))
+	if err != nil {
+		return skillManifest{}, fmt.Errorf("skill %s: %w", skill, err)
+	}
+	switch {
+	case m.Name == "":
+		return m, fmt.Errorf("skill %s: %s frontmatter is missing 'name'", skill, skillManifestFile)
+	case m.Name != skill:
+		return m, fmt.Errorf("skill %s: %s name %q must match the directory name", skill, skillManifestFile, m.Name)
+	case m.Description == "":
+		return m, fmt.Errorf("skill %s: %s frontmatter is missing 'description'", skill, skillManifestFile)
+	case len(m.Description) > maxSkillDescriptionLen:
+		return m, fmt.Errorf("skill %s: description is longer than %d characters", skill, maxSkillDescriptionLen)
+	}
+
+	var total int64
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			rel, _ := filepath.Rel(dir, path)
+			return fmt.Errorf("symlinks are not supported: %s", rel)
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return m, fmt.Errorf("skill %s: %w", skill, err)
+	}
+	if total > maxSkillDirBytes {
+		return m, fmt.Errorf("skill %s: %d bytes exceeds the %d MiB limit", skill, total, maxSkillDirBytes/(1024*1024))
+	}
+	return m, nil
+}
+
+// listLocalSkillDirs returns the skill directories in the local workspace,
+// skipping the backup directory and hidden entries.
+func listLocalSkillDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read skills workspace %s: %w", root, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || name == "backup" || strings.HasPrefix(name, ".") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSkill(t *testing.T, root, name, manifest string) string {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if manifest != "" {
+		if err := os.WriteFile(filepath.Join(dir, skillManifestFile), []byte(manifest), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParseSkillManifest(t *testing.T) {
+	m, err := parseSkillManifest("---\nname: market-watch\ndescription: \"Watch markets: FX and rates\"\nmetadata:\n  openclaw:\n    name: nested\n---\n# Market watch\n")
+	if err != nil {
+		t.Fatalf("parseSkillManifest() error: %v", err)
+	}
+	if m.Name != "market-watch" || m.Description != "Watch markets: FX and rates" {
+		t.Errorf("parseSkillManifest() = %+v", m)
+	}
+
+	if _, err := parseSkillManifest("# no frontmatter\n"); err == nil {
+		t.Error("expected error without frontmatter")
+	}
+	if _, err := parseSkillManifest("---\nname: x\n"); err == nil {
+		t.Error("expected error for unterminated frontmatter")
+	}
+}
+
+func TestValidateSkillDir(t *testing.T) {
+	root := t.TempDir()
+	tests := []struct {
+		name     string
+		dir      string
+		manifest string
+		wantErr  string
+	}{
+		{"valid", "market-watch", "---\nname: market-watch\ndescription: Watch markets\n---\n", ""},
+		{"missing manifest", "no-manifest", "", "missing SKILL.md"},
+		{"name mismatch", "renamed", "---\nname: other\ndescription: x\n---\n", "must match the directory name"},
+		{"missing description", "no-desc", "---\nname: no-desc\n---\n", "missing 'description'"},
+		{"invalid dir name", "Bad_Name", "---\nname: Bad_Name\ndescription: x\n---\n", "invalid skill name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeSkill(t, root, tt.dir, tt.manifest)
+			_, err := validateSkillDir(dir)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("validateSkillDir() error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateSkillDir() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSkillDirRejectsSymlinks(t *testing.T) {
+	dir := writeSkill(t, t.TempDir(), "linked", "---\nname: linked\ndescription: x\n---\n")
+	if err := os.Symlink("/etc/passwd", filepath.Join(dir, "passwd")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	if _, err := validateSkillDir(dir); err == nil || !strings.Contains(err.Error(), "symlinks are not supported") {
+		t.Errorf("validateSkillDir() error = %v, want symlink error", err)
+	}
+}
+
+func TestValidateSkillName(t *testing.T) {
+	for _, name := range []string{"hormuz-ais-watch", "a1"} {
+		if err := validateSkillName(name); err != nil {
+			t.Errorf("validateSkillName(%q) error: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "..", "a/b", "-x", "UPPER", strings.Repeat("a", 65)} {
+		if err := validateSkillName(name); err == nil {
+			t.Errorf("validateSkillName(%q) expected error", name)
+		}
+	}
+}
+
+func TestListLocalSkillDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"b-skill", "a-skill", "backup", ".git"} {
+		writeSkill(t, root, name, "")
+	}
+	if err := os.WriteFile(filepath.Join(root, "README.md"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := listLocalSkillDirs(root)
+	if err != nil {
+		t.Fatalf("listLocalSkillDirs() error: %v", err)
+	}
+	if strings.Join(got, ",") != "a-skill,b-skill" {
+		t.Errorf("listLocalSkillDirs() = %v", got)
+	}
+}
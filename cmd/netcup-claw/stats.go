@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsJSON  bool
+	statsSince time.Duration
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show local usage statistics of netcup-claw (opt-in)",
+	Long: `Show which netcup-claw commands run most often, how long they take and how
+often they fail, to find the workflows worth improving.
+
+Recording is off until "netcup-claw stats enable". Each run then appends the
+command name (no arguments), its duration and whether it succeeded to
+~/.local/state/netcup-claw/usage.jsonl. Nothing is ever sent anywhere.
+
+Sub-commands:
+  enable   - Start recording
+  disable  - Stop recording and delete the statistics
+  reset    - Delete the statistics but keep recording
+
+Examples:
+  netcup-claw stats enable
+  netcup-claw stats
+  netcup-claw stats --since 168h --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := usage.Path("netcup-claw")
+		var since time.Time
+		if statsSince > 0 {
+			since = time.Now().Add(-statsSince)
+		}
+		entries, err := usage.Load(path, since)
+		if err != nil {
+			return err
+		}
+		stats := usage.Summarize(entries)
+		if statsJSON {
+			return output.WriteJSON(os.Stdout, stats)
+		}
+		if !usage.Enabled(path) {
+			fmt.Println("Usage statistics are off; turn them on with: netcup-claw stats enable")
+			return nil
+		}
+		if len(stats) == 0 {
+			fmt.Println("No commands recorded yet.")
+			return nil
+		}
+		return usage.PrintSummary(os.Stdout, stats)
+	},
+}
+
+var statsEnableCmd = &cobra.Command{
+	Use:   "enable",
+	Short: "Record usage statistics locally",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := usage.Path("netcup-claw")
+		if err := usage.Enable(path); err != nil {
+			return err
+		}
+		fmt.Printf("Recording usage statistics to %s\n", path)
+		return nil
+	},
+}
+
+var statsDisableCmd = &cobra.Command{
+	Use:   "disable",
+	Short: "Stop recording and delete the usage statistics",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := usage.Disable(usage.Path("netcup-claw")); err != nil {
+			return err
+		}
+		fmt.Println("Usage statistics are off and deleted")
+		return nil
+	},
+}
+
+var statsResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Delete the recorded usage statistics",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return usage.Reset(usage.Path("netcup-claw"))
+	},
+}
+
+// recordUsage adds the run of args to the usage statistics, if enabled.
+// Failures to record are ignored; statistics must never break a command.
+func recordUsage(args []string, plugin bool, start time.Time, err error) {
+	command := "plugin"
+	if !plugin {
+		c, _, findErr := rootCmd.Find(args)
+		if findErr != nil {
+			return
+		}
+		command = strings.TrimPrefix(c.CommandPath(), rootCmd.Name()+" ")
+	}
+	_ = usage.Record(usage.Path("netcup-claw"), usage.Entry{
+		Time:       start.UTC(),
+		Command:    command,
+		DurationMS: time.Since(start).Milliseconds(),
+		Success:    err == nil,
+	})
+}
+
+func init() {
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print the statistics as JSON")
+	statsCmd.Flags().DurationVar(&statsSince, "since", 0, "Only count runs in this period, e.g. 168h (default: all)")
+	statsCmd.AddCommand(statsEnableCmd, statsDisableCmd, statsResetCmd)
+	rootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/openclaw"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/portforward"
+	"github.com/mfittko/netcup-kube/internal/waitfor"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusWatch    bool
+	statusInterval time.Duration
+	statusJSON     bool
+)
+
+// errHealthSkipped marks a check that does not apply (e.g. a database that
+// is not installed); it is shown as skipped and does not fail the status
+var errHealthSkipped = errors.New("skipped")
+
+// healthCheck is one node of the status dependency tree. A node is only run
+// when its parent is ok.
+type healthCheck struct {
+	Name   string
+	Parent string
+	// Run returns a short detail on success, or the failure cause
+	Run func(ctx context.Context) (string, error)
+}
+
+// healthResult is the outcome of one healthCheck
+type healthResult struct {
+	Name    string
+	Parent  string
+	State   output.HealthState
+	Detail  string
+	Latency time.Duration
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show OpenClaw health as a dependency tree (tunnel, kube API, workload, service, databases)",
+	Long: `Check every layer OpenClaw depends on and print the result as a tree:
+
+  tunnel -> kube-api -> namespace -> deployment -> pod -> service -> port-forward -> http
+                                                     pod -> postgres, redis
+
+Each node shows its latency and, when it fails, the cause. Nodes below a
+failed node are skipped. Database nodes are TCP checks from inside the
+OpenClaw pod and are skipped when the release is not installed.
+
+With --watch the tree is refreshed every --interval until interrupted.
+
+--json prints the health document shared with the other status commands
+({"source","state","checked_at","components":[{"component","state",
+"message","latency_ms","depends_on"}]}); with --watch one document per line.
+
+Examples:
+  netcup-claw status
+  netcup-claw status --watch
+  netcup-claw status -w --interval 10s
+  netcup-claw status --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := openclawConfig()
+		_ = ensureKubeAPIReachableWithTunnel(ctx)
+		checks := openclawHealthChecks(cfg)
+
+		if !statusWatch {
+			results := runHealthChecks(ctx, checks)
+			if statusJSON {
+				if err := statusHealthDocument(results).WriteJSON(os.Stdout); err != nil {
+					return err
+				}
+			} else {
+				renderHealthTree(os.Stdout, results)
+			}
+			if !healthy(results) {
+				return fmt.Errorf("OpenClaw is not fully healthy")
+			}
+			return nil
+		}
+
+		if statusInterval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+		clear := hasTerminalStdio() && !statusJSON
+		for {
+			results := runHealthChecks(ctx, checks)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if statusJSON {
+				if err := output.WriteJSONLine(os.Stdout, statusHealthDocument(results)); err != nil {
+					return err
+				}
+			} else {
+				if clear {
+					fmt.Print("\033[H\033[2J")
+				}
+				fmt.Printf("%s (every %s, Ctrl-C to stop)\n\n", time.Now().Format("15:04:05"), statusInterval)
+				renderHealthTree(os.Stdout, results)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(statusInterval):
+			}
+		}
+	},
+}
+
+// openclawHealthChecks returns the dependency tree of the OpenClaw workload
+func openclawHealthChecks(cfg openclaw.Config) []healthCheck {
+	resolver := newOpenClawResolver(cfg)
+	tun := tunnelConfig()
+	apiProbe := kubeProbe()
+	return []healthCheck{
+		{Name: "tunnel", Run: func(ctx context.Context) (string, error) {
+			if localCluster.Kind != "" {
+				return fmt.Sprintf("not used (local %s cluster %s)", localCluster.Kind, localCluster.Context), nil
+			}
+			if strings.TrimSpace(tun.Host) == "" {
+				return "not configured (direct kube API)", nil
+			}
+			if !tun.manager().IsRunning(ctx) {
+				return "", fmt.Errorf("ssh tunnel to %s@%s is not running", tun.User, tun.Host)
+			}
+			return fmt.Sprintf("localhost:%s -> %s:%s via %s@%s", tun.LocalPort, tun.RemoteHost, tun.RemotePort, tun.User, tun.Host), nil
+		}},
+		{Name: "kube-api", Parent: "tunnel", Run: func(ctx context.Context) (string, error) {
+			detail, err := apiProbe.Probe(ctx)
+			if err != nil {
+				return "", fmt.Errorf("%s", firstLine(err.Error(), err))
+			}
+			return detail, nil
+		}},
+		{Name: "namespace", Parent: "kube-api", Run: func(ctx context.Context) (string, error) {
+			if _, err := kubectlRunner.Output(ctx, "get", "namespace", cfg.Namespace, "-o", "name"); err != nil {
+				return "", err
+			}
+			return cfg.Namespace, nil
+		}},
+		{Name: "deployment", Parent: "namespace", Run: conditionCheck(waitfor.DeploymentReady(kubectlRunner, cfg.Namespace, deployedConfigDeploymentName()))},
+		{Name: "pod", Parent: "deployment", Run: func(ctx context.Context) (string, error) {
+			pod, err := resolver.ResolvePod(ctx)
+			if err != nil {
+				return "", err
+			}
+			ready, detail, err := waitfor.PodsReady(kubectlRunner, cfg.Namespace, cfg.LabelSelector)(ctx)
+			if err != nil {
+				return "", err
+			}
+			if !ready {
+				return "", errors.New(detail)
+			}
+			return pod + ", " + detail, nil
+		}},
+		{Name: "service", Parent: "pod", Run: func(ctx context.Context) (string, error) {
+			return resolver.ResolveService(ctx)
+		}},
+		{Name: "port-forward", Parent: "service", Run: func(ctx context.Context) (string, error) {
+			st := pfManager(cfg, "").Status()
+			if st.State != portforward.StateRunning {
+				return "", fmt.Errorf("state %s (start it with: netcup-claw port-forward start)", st.State)
+			}
+			return fmt.Sprintf("localhost:%s (pid %d)", cfg.LocalPort, st.PID), nil
+		}},
+		{Name: "http", Parent: "port-forward", Run: conditionCheck(waitfor.HTTPHealthy(&http.Client{Timeout: 5 * time.Second}, "http://localhost:"+cfg.LocalPort+"/"))},
+		{Name: "postgres", Parent: "pod", Run: dbConnectivityCheck(cfg, resolver, postgresTarget(false))},
+		{Name: "redis", Parent: "pod", Run: dbConnectivityCheck(cfg, resolver, redisTarget())},
+	}
+}
+
+// conditionCheck evaluates a waitfor condition once
+func conditionCheck(cond waitfor.Condition) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		ready, detail, err := cond(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !ready {
+			return "", errors.New(detail)
+		}
+		return detail, nil
+	}
+}
+
+// dbConnectivityCheck opens a TCP connection from the OpenClaw pod to a
+// platform database service
+func dbConnectivityCheck(cfg openclaw.Config, resolver *openclaw.Resolver, target dbTarget) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		namespace := dbNamespaceOrDefault()
+		svc := strings.TrimPrefix(target.FallbackSvc, "svc/")
+		if _, err := kubectlRunner.Output(ctx, "-n", namespace, "get", "service", svc, "-o", "name"); err != nil {
+			if strings.Contains(err.Error(), "NotFound") {
+				return "", fmt.Errorf("%w: service %s/%s not installed", errHealthSkipped, namespace, svc)
+			}
+			return "", err
+		}
+		pod, err := resolver.ResolvePod(ctx)
+		if err != nil {
+			return "", err
+		}
+		host := svc + "." + namespace + ".svc"
+		if _, err := kubectlRunner.Output(ctx, "-n", cfg.Namespace, "exec", "-c", openclawMainContainer, pod, "--", "node", "-e", podTCPScript(host, target.RemotePort)); err != nil {
+			return "", fmt.Errorf("tcp %s:%s from %s: %w", host, target.RemotePort, pod, err)
+		}
+		return fmt.Sprintf("tcp %s:%s", host, target.RemotePort), nil
+	}
+}
+
+// runHealthChecks runs checks in order; a check whose parent is not ok is
+// skipped
+func runHealthChecks(ctx context.Context, checks []healthCheck) []healthResult {
+	states := map[string]output.HealthState{}
+	results := make([]healthResult, 0, len(checks))
+	for _, c := range checks {
+		r := healthResult{Name: c.Name, Parent: c.Parent}
+		if c.Parent != "" && states[c.Parent] != output.HealthOK {
+			r.State = output.HealthSkip
+			r.Detail = "blocked by " + c.Parent
+		} else {
+			start := time.Now()
+			detail, err := c.Run(ctx)
+			r.Latency = time.Since(start)
+			switch {
+			case errors.Is(err, errHealthSkipped):
+				r.State, r.Detail = output.HealthSkip, strings.TrimPrefix(err.Error(), errHealthSkipped.Error()+": ")
+			case err != nil:
+				r.State, r.Detail = output.HealthFail, err.Error()
+			default:
+				r.State, r.Detail = output.HealthOK, detail
+			}
+		}
+		states[c.Name] = r.State
+		results = append(results, r)
+	}
+	return results
+}
+
+// healthy reports whether no check failed
+func healthy(results []healthResult) bool {
+	for _, r := range results {
+		if r.State == output.HealthFail {
+			return false
+		}
+	}
+	return true
+}
+
+// renderHealthTree prints results as an indented tree in check order
+func renderHealthTree(w io.Writer, results []healthResult) {
+	children := map[string][]healthResult{}
+	for _, r := range results {
+		children[r.Parent] = append(children[r.Parent], r)
+	}
+	var walk func(parent, prefix string)
+	walk = func(parent, prefix string) {
+		nodes := children[parent]
+		for i, r := range nodes {
+			branch, next := "", ""
+			if parent != "" {
+				branch, next = "├─ ", "│  "
+				if i == len(nodes)-1 {
+					branch, next = "└─ ", "   "
+				}
+			}
+			latency := "-"
+			if r.State != output.HealthSkip || r.Latency > 0 {
+				latency = fmt.Sprintf("%dms", r.Latency.Milliseconds())
+			}
+			label := prefix + branch + r.Name
+			fmt.Fprintf(w, "%s%s %-4s %7s  %s\n", label, strings.Repeat(" ", max(1, 32-len([]rune(label)))), r.State, latency, r.Detail)
+			walk(r.Name, prefix+next)
+		}
+	}
+	walk("", "")
+	fmt.Fprintf(w, "\nhealthy: %s\n", boolStatus(healthy(results)))
+}
+
+// statusHealthDocument converts results to the shared health schema
+func statusHealthDocument(results []healthResult) output.HealthDocument {
+	components := make([]output.HealthComponent, 0, len(results))
+	for _, r := range results {
+		components = append(components, output.HealthComponent{
+			Component: r.Name,
+			State:     r.State,
+			Message:   r.Detail,
+			LatencyMS: r.Latency.Milliseconds(),
+			DependsOn: r.Parent,
+		})
+	}
+	return output.NewHealthDocument("netcup-claw status", components, time.Now())
+}
+
+// printPortForwardHealth prints port-forward status as a health document and
+// fails when the port-forward is not running
+func printPortForwardHealth(cfg openclaw.Config, st portforward.Status) error {
+	pf := output.HealthComponent{
+		Component: "port-forward",
+		State:     output.HealthOK,
+		Message:   fmt.Sprintf("%s (namespace %s, pid %d)", st.State, cfg.Namespace, st.PID),
+	}
+	pf.Details = portForwardDetails(st)
+	local := output.HealthComponent{Component: "local-port", State: output.HealthSkip, DependsOn: "port-forward", Message: "blocked by port-forward"}
+	if st.State != portforward.StateRunning {
+		pf.State = output.HealthFail
+		pf.Message = fmt.Sprintf("%s (namespace %s)", st.State, cfg.Namespace)
+	} else {
+		start := time.Now()
+		err := portforward.ReadinessCheck(cfg.LocalPort, time.Second)
+		local.LatencyMS = time.Since(start).Milliseconds()
+		if err != nil {
+			local.State, local.Message = output.HealthFail, err.Error()
+		} else {
+			local.State, local.Message = output.HealthOK, "localhost:"+cfg.LocalPort+" accepts connections"
+		}
+	}
+	doc := output.NewHealthDocument("netcup-claw port-forward status", []output.HealthComponent{pf, local}, time.Now())
+	if err := doc.WriteJSON(os.Stdout); err != nil {
+		return err
+	}
+	if !doc.Healthy() {
+		return fmt.Errorf("port-forward is not healthy (state: %s)", st.State)
+	}
+	return nil
+}
+
+// portForwardDetails returns the recorded facts of a port-forward for the
+// JSON health document
+func portForwardDetails(st portforward.Status) map[string]any {
+	details := map[string]any{"local_port": st.LocalPort, "restarts": st.Restarts}
+	if st.PID > 0 {
+		details["pid"] = st.PID
+	}
+	for key, value := range map[string]string{"namespace": st.Namespace, "target": st.Target, "remote_port": st.RemotePort, "log_file": st.LogFile} {
+		if value != "" {
+			details[key] = value
+		}
+	}
+	if !st.StartedAt.IsZero() {
+		details["started_at"] = st.StartedAt.UTC().Format(time.RFC3339)
+	}
+	return details
+}
+
+// firstLine returns the first non-empty line of out, or err's message
+func firstLine(out string, err error) string {
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return err.Error()
+}
+
+func init() {
+	statusCmd.Flags().BoolVarP(&statusWatch, "watch", "w", false, "Refresh the tree until interrupted")
+	statusCmd.Flags().DurationVar(&statusInterval, "interval", 3*time.Second, "Refresh interval for --watch")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print the JSON health document instead of the tree")
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+)
+
+func okCheck(name, parent, detail string) healthCheck {
+	return healthCheck{Name: name, Parent: parent, Run: func(context.Context) (string, error) { return detail, nil }}
+}
+
+func TestRunHealthChecksSkipsBelowFailure(t *testing.T) {
+	ran := map[string]bool{}
+	track := func(c healthCheck) healthCheck {
+		run := c.Run
+		c.Run = func(ctx context.Context) (string, error) {
+			ran[c.Name] = true
+			return run(ctx)
+		}
+		return c
+	}
+	checks := []healthCheck{
+		track(okCheck("kube-api", "", "/livez ok")),
+		track(healthCheck{Name: "pod", Parent: "kube-api", Run: func(context.Context) (string, error) {
+			return "", errors.New("0 of 1 pods ready")
+		}}),
+		track(okCheck("service", "pod", "svc/openclaw")),
+		track(healthCheck{Name: "redis", Parent: "kube-api", Run: func(context.Context) (string, error) {
+			return "", fmt.Errorf("%w: service platform/redis-master not installed", errHealthSkipped)
+		}}),
+	}
+	results := runHealthChecks(context.Background(), checks)
+
+	want := []struct {
+		state  output.HealthState
+		detail string
+	}{
+		{output.HealthOK, "/livez ok"},
+		{output.HealthFail, "0 of 1 pods ready"},
+		{output.HealthSkip, "blocked by pod"},
+		{output.HealthSkip, "service platform/redis-master not installed"},
+	}
+	for i, w := range want {
+		if results[i].State != w.state || results[i].Detail != w.detail {
+			t.Errorf("%s = %s %q, want %s %q", results[i].Name, results[i].State, results[i].Detail, w.state, w.detail)
+		}
+	}
+	if ran["service"] {
+		t.Error("service check ran although its parent failed")
+	}
+	if healthy(results) {
+		t.Error("expected unhealthy result")
+	}
+	if !healthy(results[:1]) {
+		t.Error("expected healthy result without failures")
+	}
+}
+
+func TestRenderHealthTree(t *testing.T) {
+	results := runHealthChecks(context.Background(), []healthCheck{
+		okCheck("tunnel", "", "t"),
+		okCheck("kube-api", "tunnel", "k"),
+		okCheck("pod", "kube-api", "p"),
+		okCheck("service", "pod", "s"),
+		okCheck("postgres", "pod", "db"),
+	})
+	var buf bytes.Buffer
+	renderHealthTree(&buf, results)
+	lines := strings.Split(buf.String(), "\n")
+	prefixes := []string{"tunnel ", "└─ kube-api ", "   └─ pod ", "      ├─ service ", "      └─ postgres "}
+	for i, p := range prefixes {
+		if !strings.HasPrefix(lines[i], p) || !strings.Contains(lines[i], " ok ") {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], p)
+		}
+	}
+	if !strings.Contains(buf.String(), "healthy: ok") {
+		t.Errorf("missing overall health:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// Timeline sources
+const (
+	timelineHelm         = "helm"
+	timelineRollout      = "rollout"
+	timelineEvent        = "event"
+	timelineConfigDeploy = "config-deploy"
+	timelineBackup       = "backup"
+)
+
+var (
+	timelineSince time.Duration
+	timelineLimit int
+	timelineJSON  bool
+)
+
+// timelineEntry is one change shown by `netcup-claw timeline`
+type timelineEntry struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Object  string    `json:"object"`
+	Summary string    `json:"summary"`
+}
+
+var timelineCmd = &cobra.Command{
+	Use:   "timeline",
+	Short: "Show what changed recently in the OpenClaw namespace, oldest first",
+	Long: `Merge the recent changes of the OpenClaw namespace into one chronological
+view to answer "what changed recently?" during incident triage:
+
+  helm           revisions of the Helm releases (helm history)
+  rollout        Deployment revisions (ReplicaSets) with their images
+  event          Deployment events and Warning events of the namespace
+  config-deploy  config deploys recorded in $XDG_STATE_HOME/netcup-claw/config-deploys.jsonl
+  backup         config, approvals, cron, skills and Helm release backups of the workspace
+
+When the cluster cannot be reached, the local sources are still shown.
+
+Examples:
+  netcup-claw timeline
+  netcup-claw timeline --since 72h --limit 30
+  netcup-claw timeline --since 0 --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := openclawConfig()
+		var entries []timelineEntry
+		warn := func(source string, err error) {
+			fmt.Fprintf(os.Stderr, "warning: %s skipped: %v\n", source, err)
+		}
+
+		if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+			warn("cluster sources", err)
+		} else {
+			helmEntries, err := helmTimeline(ctx, cfg.Namespace)
+			if err != nil {
+				warn("helm history", err)
+			}
+			entries = append(entries, helmEntries...)
+
+			payload, err := runKubectlOutput(ctx, "-n", cfg.Namespace, "get", "replicasets", "-o", "json")
+			if err == nil {
+				var rollouts []timelineEntry
+				rollouts, err = parseRolloutTimeline(payload)
+				entries = append(entries, rollouts...)
+			}
+			if err != nil {
+				warn("rollout history", err)
+			}
+
+			payload, err = runKubectlOutput(ctx, "-n", cfg.Namespace, "get", "events", "-o", "json")
+			if err == nil {
+				var records []eventRecord
+				records, err = parseEventList(payload)
+				entries = append(entries, eventTimeline(records)...)
+			}
+			if err != nil {
+				warn("events", err)
+			}
+		}
+
+		deploys, err := loadConfigDeployEntries(configDeployAuditPath())
+		if err != nil {
+			warn("config deploys", err)
+		}
+		entries = append(entries, configDeployTimeline(deploys)...)
+		entries = append(entries, backupTimeline(timelineBackupDirs())...)
+
+		var since time.Time
+		if timelineSince > 0 {
+			since = time.Now().Add(-timelineSince)
+		}
+		entries = mergeTimeline(entries, since, timelineLimit)
+		if timelineJSON {
+			return output.WriteJSON(os.Stdout, entries)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No changes found.")
+			return nil
+		}
+		renderTimeline(os.Stdout, entries)
+		return nil
+	},
+}
+
+// helmTimeline returns the revisions of every Helm release in namespace
+func helmTimeline(ctx context.Context, namespace string) ([]timelineEntry, error) {
+	out, err := helmOutput(ctx, "list", "-n", namespace, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("helm list failed: %w", err)
+	}
+	var releases []helmRelease
+	if err := json.Unmarshal(out, &releases); err != nil {
+		return nil, fmt.Errorf("failed to parse helm list output: %w", err)
+	}
+	var entries []timelineEntry
+	for _, r := range releases {
+		revisions, err := helmHistory(ctx, namespace, r.Name, 20)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, helmHistoryTimeline(r.Name, revisions)...)
+	}
+	return entries, nil
+}
+
+// helmHistoryTimeline converts the revisions of release into entries
+func helmHistoryTimeline(release string, revisions []helmRevision) []timelineEntry {
+	entries := make([]timelineEntry, 0, len(revisions))
+	for _, h := range revisions {
+		updated, err := time.Parse(time.RFC3339Nano, h.Updated)
+		if err != nil {
+			continue
+		}
+		summary := fmt.Sprintf("revision %d %s: %s", h.Revision, h.Status, h.Chart)
+		if h.AppVersion != "" {
+			summary += " (app " + h.AppVersion + ")"
+		}
+		if h.Description != "" {
+			summary += ", " + h.Description
+		}
+		entries = append(entries, timelineEntry{Time: updated, Source: timelineHelm, Object: "release/" + release, Summary: summary})
+	}
+	return entries
+}
+
+// parseRolloutTimeline converts the ReplicaSets of a kubectl list into one
+// entry per Deployment revision
+func parseRolloutTimeline(payload []byte) ([]timelineEntry, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				CreationTimestamp string            `json:"creationTimestamp"`
+				Annotations       map[string]string `json:"annotations"`
+				OwnerReferences   []struct {
+					Kind string `json:"kind"`
+					Name string `json:"name"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Spec struct {
+				Template struct {
+					Spec struct {
+						Containers []struct {
+							Name  string `json:"name"`
+							Image string `json:"image"`
+						} `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(payload, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse replicasets: %w", err)
+	}
+	var entries []timelineEntry
+	for _, rs := range list.Items {
+		deployment := ""
+		for _, owner := range rs.Metadata.OwnerReferences {
+			if owner.Kind == "Deployment" {
+				deployment = owner.Name
+			}
+		}
+		created, err := time.Parse(time.RFC3339, rs.Metadata.CreationTimestamp)
+		if deployment == "" || err != nil {
+			continue
+		}
+		images := make([]string, 0, len(rs.Spec.Template.Spec.Containers))
+		for _, c := range rs.Spec.Template.Spec.Containers {
+			images = append(images, c.Name+"="+c.Image)
+		}
+		summary := "revision " + rs.Metadata.Annotations["deployment.kubernetes.io/revision"] + ": " + strings.Join(images, ", ")
+		entries = append(entries, timelineEntry{Time: created, Source: timelineRollout, Object: "deployment/" + deployment, Summary: summary})
+	}
+	return entries, nil
+}
+
+// eventTimeline keeps the Deployment events (rollout progress) and the
+// Warning events
+func eventTimeline(records []eventRecord) []timelineEntry {
+	var entries []timelineEntry
+	for _, r := range records {
+		if r.Type != "Warning" && !strings.HasPrefix(r.Object, "Deployment/") {
+			continue
+		}
+		ts, err := time.Parse(time.RFC3339, r.Time)
+		if err != nil {
+			continue
+		}
+		summary := r.Type + " " + r.Reason + ": " + r.Message
+		if r.Count > 1 {
+			summary += fmt.Sprintf(" (x%d)", r.Count)
+		}
+		entries = append(entries, timelineEntry{Time: ts, Source: timelineEvent, Object: strings.ToLower(r.Object), Summary: summary})
+	}
+	return entries
+}
+
+// configDeployTimeline converts the config deploy log into entries
+func configDeployTimeline(deploys []configDeployEntry) []timelineEntry {
+	entries := make([]timelineEntry, 0, len(deploys))
+	for _, d := range deploys {
+		summary := fmt.Sprintf("deployed %s (sha256 %.12s)", d.File, d.SHA256)
+		if !d.Success {
+			summary = fmt.Sprintf("deploy of %s failed: %s", d.File, d.Error)
+		}
+		if d.Backup != "" {
+			summary += ", previous config in " + d.Backup
+		}
+		entries = append(entries, timelineEntry{Time: d.Time, Source: timelineConfigDeploy, Object: "namespace/" + d.Namespace, Summary: summary})
+	}
+	return entries
+}
+
+// timelineBackupDirs returns the backup directories of the workspace, keyed
+// by what they back up; the Helm backups hold one directory per release
+func timelineBackupDirs() map[string]string {
+	helmRoot := strings.TrimSpace(helmBackupPath)
+	if helmRoot == "" {
+		helmRoot = defaultHelmBackupRel
+	}
+	dirs := map[string]string{
+		"config":    filepath.Join(localConfigWorkspaceDir(), "backup"),
+		"approvals": filepath.Join(localApprovalsWorkspaceDir(), "backup"),
+		"cron":      filepath.Join(localCronWorkspaceDir(), "backup"),
+		"skills":    filepath.Join(localSkillsWorkspaceDir(), "backup"),
+	}
+	if helmRoot != "off" {
+		dirs["helm"] = helmRoot
+	}
+	return dirs
+}
+
+// backupStamp matches the names writeSnapshotBackup and the skill backups
+// give their files and directories, e.g. openclaw-config-20260301-120000.json
+var backupStamp = regexp.MustCompile(`^(.+)-([0-9]{8}-[0-9]{6})(\.json)?$`)
+
+// backupTimeline lists the timestamped backups below dirs; missing
+// directories are skipped
+func backupTimeline(dirs map[string]string) []timelineEntry {
+	var entries []timelineEntry
+	for kind, dir := range dirs {
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || path == dir {
+				return nil
+			}
+			m := backupStamp.FindStringSubmatch(d.Name())
+			if m == nil {
+				return nil
+			}
+			ts, err := time.Parse("20060102-150405", m[2])
+			if err != nil {
+				return nil
+			}
+			object := kind + "/" + m[1]
+			if rel, err := filepath.Rel(dir, filepath.Dir(path)); err == nil && rel != "." {
+				object = kind + "/" + filepath.ToSlash(rel) + "/" + m[1]
+			}
+			entries = append(entries, timelineEntry{Time: ts, Source: timelineBackup, Object: object, Summary: path})
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		})
+	}
+	return entries
+}
+
+// mergeTimeline sorts entries oldest first, drops those before since and
+// keeps the limit most recent ones (all when limit is 0)
+func mergeTimeline(entries []timelineEntry, since time.Time, limit int) []timelineEntry {
+	kept := make([]timelineEntry, 0, len(entries))
+	for _, e := range entries {
+		if !e.Time.Before(since) {
+			kept = append(kept, e)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		if !kept[i].Time.Equal(kept[j].Time) {
+			return kept[i].Time.Before(kept[j].Time)
+		}
+		return kept[i].Source < kept[j].Source
+	})
+	if limit > 0 && len(kept) > limit {
+		kept = kept[len(kept)-limit:]
+	}
+	return kept
+}
+
+func renderTimeline(w io.Writer, entries []timelineEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "TIME\tSOURCE\tOBJECT\tSUMMARY")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.Source, e.Object, e.Summary)
+	}
+	_ = tw.Flush()
+}
+
+func init() {
+	timelineCmd.Flags().DurationVar(&timelineSince, "since", 24*time.Hour, "Only show changes newer than this (0 for all)")
+	timelineCmd.Flags().IntVar(&timelineLimit, "limit", 0, "Only show the most recent changes (0 for all)")
+	timelineCmd.Flags().BoolVar(&timelineJSON, "json", false, "Print the timeline as a JSON array")
+	rootCmd.AddCommand(timelineCmd)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHelmHistoryTimeline(t *testing.T) {
+	payload := []byte(`[
+  {"revision":4,"updated":"2026-03-01T10:00:00.123456789+01:00","status":"superseded","chart":"openclaw-1.3.17","app_version":"2026.2.1","description":"Upgrade complete"},
+  {"revision":5,"updated":"2026-03-02T09:30:00Z","status":"deployed","chart":"openclaw-1.3.18","app_version":"","description":""}
+]`)
+	revisions, err := parseHelmRevisions("openclaw", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := helmHistoryTimeline("openclaw", revisions)
+	if len(entries) != 2 {
+		t.Fatalf("helmHistoryTimeline = %+v", entries)
+	}
+	if want := time.Date(2026, 3, 1, 9, 0, 0, 123456789, time.UTC); !entries[0].Time.Equal(want) {
+		t.Errorf("time = %v, want %v", entries[0].Time, want)
+	}
+	if e := entries[0]; e.Source != timelineHelm || e.Object != "release/openclaw" || e.Summary != "revision 4 superseded: openclaw-1.3.17 (app 2026.2.1), Upgrade complete" {
+		t.Errorf("entry = %+v", e)
+	}
+	if entries[1].Summary != "revision 5 deployed: openclaw-1.3.18" {
+		t.Errorf("summary = %q", entries[1].Summary)
+	}
+	if _, err := parseHelmRevisions("openclaw", []byte("Error: release: not found")); err == nil {
+		t.Error("expected an error for non-JSON output")
+	}
+}
+
+func TestParseRolloutTimeline(t *testing.T) {
+	payload := []byte(`{"items":[
+  {"metadata":{"creationTimestamp":"2026-03-02T09:31:00Z","annotations":{"deployment.kubernetes.io/revision":"7"},
+    "ownerReferences":[{"kind":"Deployment","name":"openclaw"}]},
+   "spec":{"template":{"spec":{"containers":[{"name":"main","image":"ghcr.io/openclaw/openclaw:2026.3.1"},{"name":"chromium","image":"chromium:1"}]}}}},
+  {"metadata":{"creationTimestamp":"2026-03-02T09:31:00Z"},"spec":{}}
+]}`)
+	entries, err := parseRolloutTimeline(payload)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("parseRolloutTimeline = %+v, %v; want only the owned ReplicaSet", entries, err)
+	}
+	want := "revision 7: main=ghcr.io/openclaw/openclaw:2026.3.1, chromium=chromium:1"
+	if e := entries[0]; e.Object != "deployment/openclaw" || e.Summary != want {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestEventTimeline(t *testing.T) {
+	entries := eventTimeline([]eventRecord{
+		{Time: "2026-03-02T09:31:00Z", Type: "Normal", Reason: "ScalingReplicaSet", Object: "Deployment/openclaw", Message: "Scaled up replica set openclaw-7 to 1", Count: 1},
+		{Time: "2026-03-02T09:32:00Z", Type: "Normal", Reason: "Pulled", Object: "Pod/openclaw-7-abc", Message: "image pulled", Count: 1},
+		{Time: "2026-03-02T09:33:00Z", Type: "Warning", Reason: "BackOff", Object: "Pod/openclaw-7-abc", Message: "Back-off restarting", Count: 4},
+	})
+	if len(entries) != 2 {
+		t.Fatalf("entries = %+v, want the Deployment and the Warning event", entries)
+	}
+	if entries[1].Object != "pod/openclaw-7-abc" || entries[1].Summary != "Warning BackOff: Back-off restarting (x4)" {
+		t.Errorf("entry = %+v", entries[1])
+	}
+}
+
+func TestConfigDeployTimeline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", configDeployAuditFile)
+	ok := newConfigDeployEntry("openclaw", "openclaw.json", []byte(`{}`))
+	ok.Success, ok.Backup = true, "backup/openclaw-config-20260302-093000.json"
+	failed := newConfigDeployEntry("openclaw", "/work/openclaw.json", []byte(`{}`))
+	failed.Error = "failed to apply configmap: forbidden"
+	for _, e := range []configDeployEntry{ok, failed} {
+		if err := appendConfigDeployEntry(path, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deploys, err := loadConfigDeployEntries(path)
+	if err != nil || len(deploys) != 2 {
+		t.Fatalf("loadConfigDeployEntries = %+v, %v", deploys, err)
+	}
+	if !filepath.IsAbs(deploys[0].File) {
+		t.Errorf("file = %q, want an absolute path", deploys[0].File)
+	}
+	entries := configDeployTimeline(deploys)
+	if !strings.HasPrefix(entries[0].Summary, "deployed ") || !strings.Contains(entries[0].Summary, "(sha256 44136fa355b3), previous config in backup/") {
+		t.Errorf("summary = %q", entries[0].Summary)
+	}
+	if entries[1].Summary != "deploy of /work/openclaw.json failed: failed to apply configmap: forbidden" {
+		t.Errorf("summary = %q", entries[1].Summary)
+	}
+	if missing, err := loadConfigDeployEntries(filepath.Join(t.TempDir(), "none.jsonl")); err != nil || missing != nil {
+		t.Errorf("missing log = %v, %v", missing, err)
+	}
+}
+
+func TestBackupTimeline(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"config/openclaw-config-20260302-093000.json",
+		"config/notes.txt",
+		"helm/openclaw/openclaw/pre-upgrade-20260302-092900.json",
+		"skills/weather-20260301-080000/weather/SKILL.md",
+	}
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := backupTimeline(map[string]string{
+		"config": filepath.Join(root, "config"),
+		"helm":   filepath.Join(root, "helm"),
+		"skills": filepath.Join(root, "skills"),
+		"cron":   filepath.Join(root, "missing"),
+	})
+	entries = mergeTimeline(entries, time.Time{}, 0)
+	var objects []string
+	for _, e := range entries {
+		objects = append(objects, e.Object)
+	}
+	want := "skills/weather helm/openclaw/openclaw/pre-upgrade config/openclaw-config"
+	if got := strings.Join(objects, " "); got != want {
+		t.Fatalf("objects = %q, want %q", got, want)
+	}
+	if !entries[2].Time.Equal(time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("time = %v", entries[2].Time)
+	}
+}
+
+func TestMergeTimeline(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2026, 3, 2, h, 0, 0, 0, time.UTC) }
+	entries := []timelineEntry{
+		{Time: at(12), Source: timelineRollout},
+		{Time: at(8), Source: timelineHelm},
+		{Time: at(12), Source: timelineHelm},
+		{Time: at(10), Source: timelineBackup},
+	}
+	got := mergeTimeline(entries, at(9), 2)
+	if len(got) != 2 || got[0].Source != timelineHelm || got[1].Source != timelineRollout || !got[0].Time.Equal(at(12)) {
+		t.Fatalf("mergeTimeline = %+v", got)
+	}
+	if got := mergeTimeline(entries, time.Time{}, 0); len(got) != 4 || got[0].Source != timelineHelm {
+		t.Fatalf("mergeTimeline without bounds = %+v", got)
+	}
+}
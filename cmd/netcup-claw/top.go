@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var topJSON bool
+
+var topCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show CPU/memory, PVC usage, restarts, and OOM events of the OpenClaw pod",
+	Long: `Show resource usage of the OpenClaw workload pod.
+
+CPU and memory come from metrics-server (kubectl top). Without metrics-server
+the main container's cgroup counters are read via kubectl exec, sampling CPU
+over one second. PVC usage is measured with df inside the main container.
+Restart counts, last terminations, and OOM kills come from the pod status and
+namespace events.
+
+Examples:
+  netcup-claw top
+  netcup-claw top --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg, pod, err := resolveOpenClawPod(ctx)
+		if err != nil {
+			return err
+		}
+
+		podJSON, err := runKubectlOutput(ctx, "-n", cfg.Namespace, "get", "pod", pod, "-o", "json")
+		if err != nil {
+			return fmt.Errorf("failed to read pod %s: %w", pod, err)
+		}
+		report, err := buildTopReport(cfg.Namespace, pod, podJSON)
+		if err != nil {
+			return err
+		}
+
+		metrics, metricsErr := runKubectlOutput(ctx, "-n", cfg.Namespace, "top", "pod", pod, "--containers", "--no-headers")
+		if metricsErr != nil || !applyTopMetrics(&report, metrics) {
+			usage, err := runKubectlOutput(ctx, buildShellRunKubectlArgs(cfg.Namespace, pod, []string{topCgroupScript})...)
+			if err == nil {
+				err = applyCgroupUsage(&report, usage)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "warning: CPU/memory usage unavailable (metrics-server: %v; cgroup: %v)\n", metricsErr, err)
+			}
+		}
+
+		if len(report.Volumes) > 0 {
+			dfArgs := []string{"df", "-Pk"}
+			for _, v := range report.Volumes {
+				dfArgs = append(dfArgs, shellQuote(v.MountPath))
+			}
+			if out, err := runKubectlOutput(ctx, buildShellRunKubectlArgs(cfg.Namespace, pod, dfArgs)...); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: PVC usage unavailable: %v\n", err)
+			} else {
+				applyVolumeUsage(&report, out)
+			}
+		}
+
+		if events, err := runKubectlOutput(ctx, "-n", cfg.Namespace, "get", "events", "-o", "json"); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: events unavailable: %v\n", err)
+		} else if err := applyOOMEvents(&report, events); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", err)
+		}
+
+		if topJSON {
+			return output.WriteJSON(os.Stdout, report)
+		}
+		printTopReport(os.Stdout, report)
+		return nil
+	},
+}
+
+func init() {
+	topCmd.Flags().BoolVar(&topJSON, "json", false, "Print the report as JSON")
+	rootCmd.AddCommand(topCmd)
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/storage"
+)
+
+// Usage sources reported by `netcup-claw top`
+const (
+	topSourceMetrics = "metrics-server"
+	topSourceCgroup  = "cgroup"
+)
+
+// topReport is the resource usage snapshot printed by `netcup-claw top`
+type topReport struct {
+	Namespace  string         `json:"namespace"`
+	Pod        string         `json:"pod"`
+	Node       string         `json:"node,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	Containers []topContainer `json:"containers"`
+	Volumes    []topVolume    `json:"volumes"`
+	OOMEvents  []topOOMEvent  `json:"oomEvents"`
+}
+
+type topContainer struct {
+	Name            string `json:"name"`
+	CPU             string `json:"cpu,omitempty"`
+	Memory          string `json:"memory,omitempty"`
+	CPULimit        string `json:"cpuLimit,omitempty"`
+	MemoryLimit     string `json:"memoryLimit,omitempty"`
+	Restarts        int    `json:"restarts"`
+	LastTermination string `json:"lastTermination,omitempty"`
+}
+
+type topVolume struct {
+	Claim     string `json:"claim"`
+	MountPath string `json:"mountPath"`
+	Used      int64  `json:"usedBytes"`
+	Size      int64  `json:"sizeBytes"`
+}
+
+type topOOMEvent struct {
+	Time      string `json:"time"`
+	Container string `json:"container,omitempty"`
+	Reason    string `json:"reason"`
+	Message   string `json:"message,omitempty"`
+}
+
+type topPod struct {
+	Spec struct {
+		NodeName   string `json:"nodeName"`
+		Containers []struct {
+			Name      string `json:"name"`
+			Resources struct {
+				Limits map[string]string `json:"limits"`
+			} `json:"resources"`
+			VolumeMounts []struct {
+				Name      string `json:"name"`
+				MountPath string `json:"mountPath"`
+			} `json:"volumeMounts"`
+		} `json:"containers"`
+		Volumes []struct {
+			Name                  string `json:"name"`
+			PersistentVolumeClaim *struct {
+				ClaimName string `json:"claimName"`
+			} `json:"persistentVolumeClaim"`
+		} `json:"volumes"`
+	} `json:"spec"`
+	Status struct {
+		ContainerStatuses []struct {
+			Name         string `json:"name"`
+			RestartCount int    `json:"restartCount"`
+			LastState    struct {
+				Terminated *struct {
+					Reason     string `json:"reason"`
+					ExitCode   int    `json:"exitCode"`
+					FinishedAt string `json:"finishedAt"`
+				} `json:"terminated"`
+			} `json:"lastState"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// buildTopReport fills containers, restarts, last terminations, and PVC mounts
+// of the main container from `kubectl get pod -o json`. OOM kills recorded in
+// container lastState are reported as OOM events.
+func buildTopReport(namespace, pod string, payload []byte) (topReport, error) {
+	report := topReport{Namespace: namespace, Pod: pod, Containers: []topContainer{}, Volumes: []topVolume{}, OOMEvents: []topOOMEvent{}}
+	var p topPod
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return report, fmt.Errorf("failed to parse pod %s: %w", pod, err)
+	}
+	report.Node = p.Spec.NodeName
+
+	for _, c := range p.Spec.Containers {
+		report.Containers = append(report.Containers, topContainer{
+			Name:        c.Name,
+			CPULimit:    c.Resources.Limits["cpu"],
+			MemoryLimit: c.Resources.Limits["memory"],
+		})
+	}
+	for _, s := range p.Status.ContainerStatuses {
+		for i := range report.Containers {
+			if report.Containers[i].Name != s.Name {
+				continue
+			}
+			report.Containers[i].Restarts = s.RestartCount
+			if t := s.LastState.Terminated; t != nil {
+				report.Containers[i].LastTermination = fmt.Sprintf("%s (exit %d) %s", t.Reason, t.ExitCode, t.FinishedAt)
+				if t.Reason == "OOMKilled" {
+					report.OOMEvents = append(report.OOMEvents, topOOMEvent{Time: t.FinishedAt, Container: s.Name, Reason: t.Reason})
+				}
+			}
+		}
+	}
+
+	// PVC mounts of the main container, in mount order
+	claims := map[string]string{}
+	for _, v := range p.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			claims[v.Name] = v.PersistentVolumeClaim.ClaimName
+		}
+	}
+	for _, c := range p.Spec.Containers {
+		if c.Name != openclawMainContainer {
+			continue
+		}
+		for _, m := range c.VolumeMounts {
+			if claim, ok := claims[m.Name]; ok {
+				report.Volumes = append(report.Volumes, topVolume{Claim: claim, MountPath: m.MountPath})
+			}
+		}
+	}
+	return report, nil
+}
+
+// applyTopMetrics sets CPU and memory from `kubectl top pod --containers --no-headers`
+func applyTopMetrics(report *topReport, out []byte) bool {
+	found := false
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			continue
+		}
+		for i := range report.Containers {
+			if report.Containers[i].Name == fields[1] {
+				report.Containers[i].CPU = fields[2]
+				report.Containers[i].Memory = fields[3]
+				found = true
+			}
+		}
+	}
+	if found {
+		report.Source = topSourceMetrics
+	}
+	return found
+}
+
+// topCgroupScript samples cgroup v2 (or v1) CPU time one second apart and
+// reads current memory usage inside the container.
+const topCgroupScript = `cpu() {
+  if [ -r /sys/fs/cgroup/cpu.stat ]; then awk '/^usage_usec/ {printf "%.0f\n", $2 * 1000}' /sys/fs/cgroup/cpu.stat
+  elif [ -r /sys/fs/cgroup/cpuacct/cpuacct.usage ]; then cat /sys/fs/cgroup/cpuacct/cpuacct.usage
+  fi
+}
+a=$(cpu); sleep 1; b=$(cpu)
+echo "cpu_ns_start $a"
+echo "cpu_ns_end $b"
+if [ -r /sys/fs/cgroup/memory.current ]; then echo "memory $(cat /sys/fs/cgroup/memory.current)"
+elif [ -r /sys/fs/cgroup/memory/memory.usage_in_bytes ]; then echo "memory $(cat /sys/fs/cgroup/memory/memory.usage_in_bytes)"
+fi`
+
+// applyCgroupUsage sets CPU (millicores over the one second sample) and
+// memory of the main container from topCgroupScript output.
+func applyCgroupUsage(report *topReport, out []byte) error {
+	values := map[string]float64{}
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			values[key] = n
+		}
+	}
+	memory, ok := values["memory"]
+	if !ok {
+		return fmt.Errorf("cgroup memory usage not readable in container %s", openclawMainContainer)
+	}
+	for i := range report.Containers {
+		if report.Containers[i].Name != openclawMainContainer {
+			continue
+		}
+		report.Containers[i].Memory = storage.FormatBytes(int64(memory))
+		start, okStart := values["cpu_ns_start"]
+		end, okEnd := values["cpu_ns_end"]
+		if okStart && okEnd && end >= start {
+			report.Containers[i].CPU = fmt.Sprintf("%dm", int64((end-start)/1e6))
+		}
+		report.Source = topSourceCgroup
+		return nil
+	}
+	return fmt.Errorf("container %s not found in pod %s", openclawMainContainer, report.Pod)
+}
+
+// applyVolumeUsage fills PVC usage from `df -Pk <mount paths...>`, whose data
+// lines follow the argument order.
+func applyVolumeUsage(report *topReport, out []byte) {
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "Filesystem") {
+		lines = lines[1:]
+	}
+	for i, line := range lines {
+		fields := strings.Fields(line)
+		if i >= len(report.Volumes) || len(fields) < 6 {
+			break
+		}
+		size, errSize := strconv.ParseInt(fields[1], 10, 64)
+		used, errUsed := strconv.ParseInt(fields[2], 10, 64)
+		if errSize != nil || errUsed != nil {
+			continue
+		}
+		report.Volumes[i].Size = size * 1024
+		report.Volumes[i].Used = used * 1024
+	}
+}
+
+type topEventList struct {
+	Items []struct {
+		Reason         string `json:"reason"`
+		Message        string `json:"message"`
+		LastTimestamp  string `json:"lastTimestamp"`
+		EventTime      string `json:"eventTime"`
+		InvolvedObject struct {
+			Name      string `json:"name"`
+			FieldPath string `json:"fieldPath"`
+		} `json:"involvedObject"`
+	} `json:"items"`
+}
+
+// applyOOMEvents adds namespace events about the pod that mention OOM
+// (OOMKilling from the kubelet, OOMKilled back-offs), sorted newest first.
+func applyOOMEvents(report *topReport, payload []byte) error {
+	var events topEventList
+	if err := json.Unmarshal(payload, &events); err != nil {
+		return fmt.Errorf("failed to parse events: %w", err)
+	}
+	for _, e := range events.Items {
+		if e.InvolvedObject.Name != report.Pod && !strings.Contains(e.Message, report.Pod) {
+			continue
+		}
+		if !strings.Contains(strings.ToUpper(e.Reason+" "+e.Message), "OOM") {
+			continue
+		}
+		ts := e.LastTimestamp
+		if ts == "" {
+			ts = e.EventTime
+		}
+		report.OOMEvents = append(report.OOMEvents, topOOMEvent{
+			Time:      ts,
+			Container: strings.TrimSuffix(strings.TrimPrefix(e.InvolvedObject.FieldPath, "spec.containers{"), "}"),
+			Reason:    e.Reason,
+			Message:   e.Message,
+		})
+	}
+	sort.SliceStable(report.OOMEvents, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339, report.OOMEvents[i].Time)
+		tj, _ := time.Parse(time.RFC3339, report.OOMEvents[j].Time)
+		return ti.After(tj)
+	})
+	return nil
+}
+
+func printTopReport(out io.Writer, report topReport) {
+	fmt.Fprintf(out, "pod:    %s/%s", report.Namespace, report.Pod)
+	if report.Node != "" {
+		fmt.Fprintf(out, " on %s", report.Node)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "source: %s\n\n", orNone(report.Source, "unavailable"))
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTAINER\tCPU\tCPU LIMIT\tMEMORY\tMEMORY LIMIT\tRESTARTS\tLAST TERMINATION")
+	for _, c := range report.Containers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", c.Name, orNone(c.CPU, "-"), orNone(c.CPULimit, "-"),
+			orNone(c.Memory, "-"), orNone(c.MemoryLimit, "-"), c.Restarts, orNone(c.LastTermination, "-"))
+	}
+	_ = w.Flush()
+
+	if len(report.Volumes) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PVC\tMOUNT\tUSED\tSIZE\tUSE%")
+		for _, v := range report.Volumes {
+			used, size, pct := "-", "-", "-"
+			if v.Size > 0 {
+				used, size = storage.FormatBytes(v.Used), storage.FormatBytes(v.Size)
+				pct = fmt.Sprintf("%d%%", v.Used*100/v.Size)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.Claim, v.MountPath, used, size, pct)
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Fprintln(out)
+	if len(report.OOMEvents) == 0 {
+		fmt.Fprintln(out, "oom events: none")
+		return
+	}
+	fmt.Fprintln(out, "oom events:")
+	for _, e := range report.OOMEvents {
+		line := fmt.Sprintf("  %s  %s", orNone(e.Time, "-"), e.Reason)
+		if e.Container != "" {
+			line += " (" + e.Container + ")"
+		}
+		if e.Message != "" {
+			line += ": " + e.Message
+		}
+		fmt.Fprintln(out, line)
+	}
+}
+
+func orNone(value, fallback string) string {
+	if strings.TrimSpace(value) == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const testTopPod = `{
+  "spec": {
+    "nodeName": "mgmt",
+    "containers": [
+      {"name": "main", "resources": {"limits": {"cpu": "2", "memory": "2Gi"}},
+       "volumeMounts": [{"name": "data", "mountPath": "/home/node/.openclaw"}, {"name": "tmp", "mountPath": "/tmp"}]},
+      {"name": "ebpf-monitor", "volumeMounts": [{"name": "data", "mountPath": "/data"}]}
+    ],
+    "volumes": [{"name": "data", "persistentVolumeClaim": {"claimName": "openclaw-data"}}, {"name": "tmp", "emptyDir": {}}]
+  },
+  "status": {
+    "containerStatuses": [
+      {"name": "main", "restartCount": 3,
+       "lastState": {"terminated": {"reason": "OOMKilled", "exitCode": 137, "finishedAt": "2026-10-01T10:00:00Z"}}},
+      {"name": "ebpf-monitor", "restartCount": 0, "lastState": {}}
+    ]
+  }
+}`
+
+func TestBuildTopReport(t *testing.T) {
+	report, err := buildTopReport("openclaw", "openclaw-0", []byte(testTopPod))
+	if err != nil {
+		t.Fatalf("buildTopReport() error: %v", err)
+	}
+	if report.Node != "mgmt" || len(report.Containers) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	main := report.Containers[0]
+	if main.Restarts != 3 || main.MemoryLimit != "2Gi" || !strings.HasPrefix(main.LastTermination, "OOMKilled (exit 137)") {
+		t.Errorf("main container = %+v", main)
+	}
+	if len(report.Volumes) != 1 || report.Volumes[0].Claim != "openclaw-data" || report.Volumes[0].MountPath != "/home/node/.openclaw" {
+		t.Errorf("volumes = %+v", report.Volumes)
+	}
+	if len(report.OOMEvents) != 1 || report.OOMEvents[0].Container != "main" {
+		t.Errorf("oom events = %+v", report.OOMEvents)
+	}
+
+	if _, err := buildTopReport("openclaw", "openclaw-0", []byte("not json")); err == nil {
+		t.Error("buildTopReport() expected error for invalid JSON")
+	}
+}
+
+func TestTopUsageSources(t *testing.T) {
+	report, _ := buildTopReport("openclaw", "openclaw-0", []byte(testTopPod))
+	if applyTopMetrics(&report, []byte("error: Metrics API not available\n")) {
+		t.Error("applyTopMetrics() should not match an error message")
+	}
+	if !applyTopMetrics(&report, []byte("openclaw-0   main           250m   812Mi\nopenclaw-0   ebpf-monitor   5m     40Mi\n")) {
+		t.Fatal("applyTopMetrics() found no containers")
+	}
+	if c := report.Containers[0]; c.CPU != "250m" || c.Memory != "812Mi" || report.Source != topSourceMetrics {
+		t.Errorf("metrics = %+v, source %s", c, report.Source)
+	}
+
+	report, _ = buildTopReport("openclaw", "openclaw-0", []byte(testTopPod))
+	if err := applyCgroupUsage(&report, []byte("cpu_ns_start 1000000000\ncpu_ns_end 1350000000\nmemory 1073741824\n")); err != nil {
+		t.Fatalf("applyCgroupUsage() error: %v", err)
+	}
+	if c := report.Containers[0]; c.CPU != "350m" || c.Memory != "1.0Gi" || report.Source != topSourceCgroup {
+		t.Errorf("cgroup = %+v, source %s", c, report.Source)
+	}
+	if err := applyCgroupUsage(&report, []byte("cpu_ns_start \ncpu_ns_end \n")); err == nil {
+		t.Error("applyCgroupUsage() expected error without memory")
+	}
+
+	applyVolumeUsage(&report, []byte("Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/sda1 10485760 2621440 7864320 25% /home/node/.openclaw\n"))
+	if v := report.Volumes[0]; v.Size != 10<<30 || v.Used != 10<<28 {
+		t.Errorf("volume = %+v", v)
+	}
+}
+
+func TestApplyOOMEventsAndPrint(t *testing.T) {
+	report, _ := buildTopReport("openclaw", "openclaw-0", []byte(testTopPod))
+	events := `{"items":[
+	  {"reason":"BackOff","message":"Back-off restarting failed container","lastTimestamp":"2026-10-02T00:00:00Z","involvedObject":{"name":"openclaw-0"}},
+	  {"reason":"OOMKilling","message":"Memory cgroup out of memory: Killed process 42 (node)","lastTimestamp":"2026-10-03T00:00:00Z","involvedObject":{"name":"openclaw-0","fieldPath":"spec.containers{main}"}},
+	  {"reason":"OOMKilling","message":"Killed process 7","lastTimestamp":"2026-10-04T00:00:00Z","involvedObject":{"name":"other-pod"}}]}`
+	if err := applyOOMEvents(&report, []byte(events)); err != nil {
+		t.Fatalf("applyOOMEvents() error: %v", err)
+	}
+	if len(report.OOMEvents) != 2 || report.OOMEvents[0].Reason != "OOMKilling" || report.OOMEvents[0].Container != "main" {
+		t.Errorf("oom events = %+v", report.OOMEvents)
+	}
+
+	var out bytes.Buffer
+	printTopReport(&out, report)
+	for _, want := range []string{"openclaw/openclaw-0 on mgmt", "source: unavailable", "OOMKilled (exit 137)", "openclaw-data", "OOMKilling (main)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/registry"
+)
+
+// openclawImageTagKey is the value of the OpenClaw chart holding the tag of
+// the main container image; a digest pin is stored there as <tag>@<digest>
+const openclawImageTagKey = "app-template.controllers.main.containers.main.image.tag"
+
+var upgradePinDigest bool
+
+// imagePin is the outcome of resolving the OpenClaw image for an upgrade
+type imagePin struct {
+	// Image is the pinned image reference, empty when nothing is pinned
+	Image string
+	// HelmArgs set (or drop) the pin in the release values
+	HelmArgs []string
+	// Dropped is the earlier pin a plain upgrade removes
+	Dropped string
+}
+
+// digestPinned reports whether an image tag value carries a digest pin
+func digestPinned(tag string) bool {
+	return strings.Contains(tag, "@sha256:")
+}
+
+// planImagePin resolves the image tag the target chart version deploys to
+// its digest at the registry and returns the helm args pinning it. Without
+// --pin-digest, an earlier pin (seen as a digest in runningImage) is dropped
+// so the release follows the chart's tag again.
+func planImagePin(ctx context.Context, namespace, release, chartRef, version, runningImage string) (imagePin, error) {
+	if !upgradePinDigest && !strings.Contains(runningImage, "@sha256:") {
+		return imagePin{}, nil
+	}
+	out, err := helmOutput(ctx, "get", "values", release, "-n", namespace, "-o", "json")
+	if err != nil {
+		return imagePin{}, fmt.Errorf("failed to read values of release %s: %w", release, err)
+	}
+	var values map[string]any
+	if err := json.Unmarshal(out, &values); err != nil {
+		return imagePin{}, fmt.Errorf("failed to parse values of release %s: %w", release, err)
+	}
+	var pin imagePin
+	if tag, ok := valueAt(values, openclawImageTagKey).(string); ok && digestPinned(tag) {
+		deleteValueAt(values, openclawImageTagKey)
+		pin.Dropped = tag
+	}
+	if !upgradePinDigest {
+		if pin.Dropped != "" {
+			pin.HelmArgs = []string{"--set", openclawImageTagKey + "=null"}
+		}
+		return pin, nil
+	}
+
+	// Render without the earlier pin to see the tag of the target chart
+	payload, err := json.Marshal(values)
+	if err != nil {
+		return imagePin{}, err
+	}
+	deploy, err := renderReleaseDeployment(ctx, namespace, release, chartRef, version, payload)
+	if err != nil {
+		return imagePin{}, err
+	}
+	image, pullSecrets := renderedContainerImage(deploy, openclawMainContainer)
+	if image == "" {
+		return imagePin{}, fmt.Errorf("chart %s %s renders no %s container", chartRef, version, openclawMainContainer)
+	}
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return imagePin{}, err
+	}
+	if ref.Digest != "" {
+		// The chart pins a digest itself
+		pin.Image = image
+		return pin, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	var creds *registry.Credentials
+	for _, name := range pullSecrets {
+		if dockerConfig, res := readPullSecret(ctx, namespace, name); res.Status == "" {
+			if found, ok := dockerConfig.Lookup(ref.Registry); ok {
+				creds = &found
+				break
+			}
+		}
+	}
+	client := &registry.Client{HTTP: &http.Client{Timeout: 15 * time.Second}}
+	digest, err := client.Resolve(ctx, ref, creds)
+	if err != nil {
+		return imagePin{}, fmt.Errorf("failed to resolve %s to a digest: %w", image, err)
+	}
+	pin.Image = image + "@" + digest
+	pin.HelmArgs = []string{"--set-string", openclawImageTagKey + "=" + ref.Tag + "@" + digest}
+	return pin, nil
+}
+
+// renderedContainerImage returns the image of the named container of a
+// rendered Deployment and the names of its imagePullSecrets
+func renderedContainerImage(deploy map[string]any, container string) (string, []string) {
+	spec, _ := valueAt(deploy, "spec.template.spec").(map[string]any)
+	var secrets []string
+	refs, _ := spec["imagePullSecrets"].([]any)
+	for _, r := range refs {
+		if ref, ok := r.(map[string]any); ok {
+			secrets = append(secrets, fmt.Sprint(ref["name"]))
+		}
+	}
+	containers, _ := spec["containers"].([]any)
+	for _, c := range containers {
+		if c, ok := c.(map[string]any); ok && c["name"] == container {
+			image, _ := c["image"].(string)
+			return image, secrets
+		}
+	}
+	return "", secrets
+}
+
+// valueAt returns the value at a dotted key of nested maps, or nil
+func valueAt(values map[string]any, key string) any {
+	var cur any = values
+	for _, part := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// deleteValueAt removes the value at a dotted key of nested maps
+func deleteValueAt(values map[string]any, key string) {
+	parent, last := values, key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		parent, _ = valueAt(values, key[:i]).(map[string]any)
+		last = key[i+1:]
+	}
+	delete(parent, last)
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradePinDigest, "pin-digest", false, "Pin the OpenClaw image to the digest its chart tag resolves to at the registry")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValueAt(t *testing.T) {
+	var values map[string]any
+	if err := json.Unmarshal([]byte(`{"app-template":{"controllers":{"main":{"containers":{"main":{"image":{"tag":"2026.3.1@sha256:aaa","pullPolicy":"IfNotPresent"}}}}}},"persistence":{"size":"10Gi"}}`), &values); err != nil {
+		t.Fatal(err)
+	}
+	tag, _ := valueAt(values, openclawImageTagKey).(string)
+	if !digestPinned(tag) {
+		t.Fatalf("valueAt() = %q, want a digest pin", tag)
+	}
+	if valueAt(values, "persistence.size.missing") != nil || valueAt(values, "nope") != nil {
+		t.Error("valueAt() of a missing key is not nil")
+	}
+
+	deleteValueAt(values, openclawImageTagKey)
+	deleteValueAt(values, "persistence")
+	image, _ := valueAt(values, "app-template.controllers.main.containers.main.image").(map[string]any)
+	if _, ok := image["tag"]; ok || image["pullPolicy"] != "IfNotPresent" || values["persistence"] != nil {
+		t.Errorf("deleteValueAt() left %v", values)
+	}
+}
+
+func TestRenderedContainerImage(t *testing.T) {
+	var deploy map[string]any
+	if err := json.Unmarshal([]byte(`{"kind":"Deployment","spec":{"template":{"spec":{
+		"imagePullSecrets":[{"name":"ghcr"}],
+		"containers":[{"name":"chromium","image":"chromium:1"},{"name":"main","image":"ghcr.io/openclaw/openclaw:2026.3.1"}]}}}}`), &deploy); err != nil {
+		t.Fatal(err)
+	}
+	image, secrets := renderedContainerImage(deploy, openclawMainContainer)
+	if image != "ghcr.io/openclaw/openclaw:2026.3.1" || len(secrets) != 1 || secrets[0] != "ghcr" {
+		t.Errorf("renderedContainerImage() = %q, %v", image, secrets)
+	}
+	if image, _ := renderedContainerImage(deploy, "sidecar"); image != "" {
+		t.Errorf("renderedContainerImage(sidecar) = %q", image)
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	for image, want := range map[string]string{
+		"ghcr.io/openclaw/openclaw:2026.2.17":            "2026.2.17",
+		"ghcr.io/openclaw/openclaw:2026.2.17@sha256:abc": "2026.2.17",
+		"localhost:5000/openclaw":                        "latest",
+		"":                                               "",
+	} {
+		if got := imageTag(image); got != want {
+			t.Errorf("imageTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// hostnameTopologyKey is the node-level topology key of pod anti-affinity
+const hostnameTopologyKey = "kubernetes.io/hostname"
+
+var upgradeAllowDowntime bool
+
+// rolloutWorkload is the part of a Deployment or StatefulSet that decides
+// whether a rolling update keeps a ready pod
+type rolloutWorkload struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int `json:"replicas"`
+		// Deployment
+		Strategy struct {
+			Type          string `json:"type"`
+			RollingUpdate struct {
+				MaxSurge       json.RawMessage `json:"maxSurge"`
+				MaxUnavailable json.RawMessage `json:"maxUnavailable"`
+			} `json:"rollingUpdate"`
+		} `json:"strategy"`
+		// StatefulSet
+		UpdateStrategy struct {
+			Type string `json:"type"`
+		} `json:"updateStrategy"`
+		Template struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec struct {
+				Affinity struct {
+					PodAntiAffinity struct {
+						Required []struct {
+							TopologyKey string `json:"topologyKey"`
+						} `json:"requiredDuringSchedulingIgnoredDuringExecution"`
+					} `json:"podAntiAffinity"`
+				} `json:"affinity"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+}
+
+func (w rolloutWorkload) name() string {
+	return strings.ToLower(w.Kind) + "/" + w.Metadata.Name
+}
+
+func (w rolloutWorkload) replicas() int {
+	if w.Spec.Replicas == nil {
+		return 1
+	}
+	return *w.Spec.Replicas
+}
+
+// rolloutPDB is a PodDisruptionBudget of the namespace
+type rolloutPDB struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		MinAvailable   json.RawMessage `json:"minAvailable"`
+		MaxUnavailable json.RawMessage `json:"maxUnavailable"`
+		Selector       *struct {
+			MatchLabels      map[string]string `json:"matchLabels"`
+			MatchExpressions []json.RawMessage `json:"matchExpressions"`
+		} `json:"selector"`
+	} `json:"spec"`
+}
+
+// selects reports whether the PDB's selector matches the pod labels; label
+// expressions are not evaluated, so such PDBs never match
+func (p rolloutPDB) selects(labels map[string]string) bool {
+	sel := p.Spec.Selector
+	if sel == nil || len(sel.MatchExpressions) > 0 {
+		return false
+	}
+	for k, v := range sel.MatchLabels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// checkUpgradePreflight reports rollout risks of the release's workloads: a
+// rollout that can leave no ready pod, or stalls on a single node. It fails
+// on a risk unless --allow-downtime; a dry run only reports. PDBs that block
+// node drains are only warned about. When the
+// workloads cannot be read the check is skipped with a warning.
+func checkUpgradePreflight(ctx context.Context, namespace, release string) error {
+	risks, warnings, err := upgradePreflight(ctx, namespace, release)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: upgrade preflight skipped: %v\n", err)
+		return nil
+	}
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	if len(risks) == 0 {
+		return nil
+	}
+	for _, r := range risks {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", r)
+	}
+	switch {
+	case upgradeDryRun:
+		fmt.Println("dry-run: the upgrade would stop here unless --allow-downtime")
+		return nil
+	case upgradeAllowDowntime:
+		fmt.Fprintln(os.Stderr, "warning: continuing despite the rollout risks (--allow-downtime)")
+		return nil
+	}
+	return fmt.Errorf("the rollout of %s may leave no ready pod; rerun with --allow-downtime to upgrade anyway", release)
+}
+
+// upgradePreflight reads the release's Deployments and StatefulSets, the
+// namespace's PodDisruptionBudgets and the node count, and returns the
+// rollout risks and warnings
+func upgradePreflight(ctx context.Context, namespace, release string) (risks, warnings []string, err error) {
+	out, err := runKubectlOutput(ctx, "-n", namespace, "get", "deployments,statefulsets", "-l", "app.kubernetes.io/instance="+release, "-o", "json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read workloads of %s: %w", release, err)
+	}
+	var workloads struct {
+		Items []rolloutWorkload `json:"items"`
+	}
+	if err := json.Unmarshal(out, &workloads); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse workloads of %s: %w", release, err)
+	}
+
+	out, err = runKubectlOutput(ctx, "-n", namespace, "get", "poddisruptionbudgets", "-o", "json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read PodDisruptionBudgets: %w", err)
+	}
+	var pdbs struct {
+		Items []rolloutPDB `json:"items"`
+	}
+	if err := json.Unmarshal(out, &pdbs); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse PodDisruptionBudgets: %w", err)
+	}
+
+	out, err = runKubectlOutput(ctx, "get", "nodes", "-o", "name")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read nodes: %w", err)
+	}
+	nodes := len(strings.Fields(string(out)))
+	risks, warnings = rolloutRisks(workloads.Items, pdbs.Items, nodes)
+	return risks, warnings, nil
+}
+
+// rolloutRisks returns why rolling out the workloads may leave no ready pod
+// or stall, given the number of nodes, and warnings about PDBs of the
+// namespace that allow no disruption of them
+func rolloutRisks(workloads []rolloutWorkload, pdbs []rolloutPDB, nodes int) (risks, warnings []string) {
+	for _, w := range workloads {
+		replicas := w.replicas()
+		if replicas == 0 {
+			continue
+		}
+		switch w.Kind {
+		case "Deployment":
+			if w.Spec.Strategy.Type == "Recreate" {
+				risks = append(risks, fmt.Sprintf("%s uses the Recreate strategy: all %d pod(s) stop before the new ones start", w.name(), replicas))
+				break
+			}
+			ru := w.Spec.Strategy.RollingUpdate
+			surge := intOrPercent(ru.MaxSurge, replicas, true, "25%")
+			unavailable := intOrPercent(ru.MaxUnavailable, replicas, false, "25%")
+			if surge == 0 && unavailable == 0 {
+				// The API server rejects this; Kubernetes would use maxUnavailable 1
+				unavailable = 1
+			}
+			if unavailable >= replicas {
+				risks = append(risks, fmt.Sprintf("%s has %d replica(s) and maxUnavailable %d: the rollout may stop every pod before a new one is ready (set maxUnavailable to 0 or add replicas)", w.name(), replicas, unavailable))
+			}
+			if nodes == 1 && surge > 0 && hasHostnameAntiAffinity(w) {
+				risks = append(risks, fmt.Sprintf("%s requires pod anti-affinity on %s: the surge pod cannot be scheduled on the single node and the rollout stalls", w.name(), hostnameTopologyKey))
+			}
+		case "StatefulSet":
+			if w.Spec.UpdateStrategy.Type != "OnDelete" && replicas == 1 {
+				risks = append(risks, fmt.Sprintf("%s has 1 replica: the rolling update replaces the pod in place, leaving no ready pod until it restarts", w.name()))
+			}
+		}
+
+		for _, p := range pdbs {
+			if !p.selects(w.Spec.Template.Metadata.Labels) {
+				continue
+			}
+			if allowed := pdbDisruptionsAllowed(p, replicas); allowed <= 0 {
+				warnings = append(warnings, fmt.Sprintf("PodDisruptionBudget %s allows no disruption of %s (%d replica(s)): rollouts ignore it, but node drains such as k3s upgrades block on it", p.Metadata.Name, w.name(), replicas))
+			}
+		}
+	}
+	return risks, warnings
+}
+
+// hasHostnameAntiAffinity reports whether the workload's pods must not share
+// a node
+func hasHostnameAntiAffinity(w rolloutWorkload) bool {
+	for _, term := range w.Spec.Template.Spec.Affinity.PodAntiAffinity.Required {
+		if term.TopologyKey == hostnameTopologyKey {
+			return true
+		}
+	}
+	return false
+}
+
+// pdbDisruptionsAllowed returns how many of replicas healthy pods the PDB lets
+// be evicted
+func pdbDisruptionsAllowed(p rolloutPDB, replicas int) int {
+	if len(p.Spec.MaxUnavailable) > 0 {
+		return intOrPercent(p.Spec.MaxUnavailable, replicas, true, "0")
+	}
+	if len(p.Spec.MinAvailable) > 0 {
+		return replicas - intOrPercent(p.Spec.MinAvailable, replicas, true, "0")
+	}
+	return replicas
+}
+
+// intOrPercent resolves a Kubernetes IntOrString against total, rounding
+// percentages up or down; def is used when raw is empty or invalid
+func intOrPercent(raw json.RawMessage, total int, roundUp bool, def string) int {
+	value := def
+	if len(raw) > 0 && string(raw) != "null" {
+		var n int
+		if json.Unmarshal(raw, &n) == nil {
+			return n
+		}
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			value = s
+		}
+	}
+	if pct, ok := strings.CutSuffix(value, "%"); ok {
+		n, err := strconv.Atoi(pct)
+		if err != nil {
+			return 0
+		}
+		v := float64(n) * float64(total) / 100
+		if roundUp {
+			return int(math.Ceil(v))
+		}
+		return int(math.Floor(v))
+	}
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradeAllowDowntime, "allow-downtime", false, "Upgrade even when the preflight finds the rollout may leave no ready pod")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRolloutRisks(t *testing.T) {
+	var workloads []rolloutWorkload
+	if err := json.Unmarshal([]byte(`[
+	  {"kind":"Deployment","metadata":{"name":"openclaw"},"spec":{"replicas":1,"strategy":{"type":"Recreate"}}},
+	  {"kind":"Deployment","metadata":{"name":"openclaw-browser"},"spec":{"replicas":1,
+	    "strategy":{"type":"RollingUpdate","rollingUpdate":{"maxSurge":0,"maxUnavailable":1}}}},
+	  {"kind":"Deployment","metadata":{"name":"openclaw-gateway"},"spec":{"replicas":2,
+	    "strategy":{"type":"RollingUpdate","rollingUpdate":{"maxSurge":"25%","maxUnavailable":"25%"}},
+	    "template":{"metadata":{"labels":{"app":"gateway"}},"spec":{"affinity":{"podAntiAffinity":{
+	      "requiredDuringSchedulingIgnoredDuringExecution":[{"topologyKey":"kubernetes.io/hostname"}]}}}}}},
+	  {"kind":"Deployment","metadata":{"name":"openclaw-worker"},"spec":{"replicas":1,
+	    "strategy":{"type":"RollingUpdate","rollingUpdate":{"maxSurge":"25%","maxUnavailable":"25%"}},
+	    "template":{"metadata":{"labels":{"app":"worker"}}}}},
+	  {"kind":"StatefulSet","metadata":{"name":"openclaw-db"},"spec":{"replicas":1,"updateStrategy":{"type":"RollingUpdate"}}},
+	  {"kind":"StatefulSet","metadata":{"name":"openclaw-cache"},"spec":{"replicas":0}}
+	]`), &workloads); err != nil {
+		t.Fatal(err)
+	}
+	var pdbs []rolloutPDB
+	if err := json.Unmarshal([]byte(`[
+	  {"metadata":{"name":"worker"},"spec":{"minAvailable":1,"selector":{"matchLabels":{"app":"worker"}}}},
+	  {"metadata":{"name":"gateway"},"spec":{"maxUnavailable":"50%","selector":{"matchLabels":{"app":"gateway"}}}}
+	]`), &pdbs); err != nil {
+		t.Fatal(err)
+	}
+
+	risks, warnings := rolloutRisks(workloads, pdbs, 1)
+	got := strings.Join(risks, "\n")
+	for _, want := range []string{
+		"deployment/openclaw uses the Recreate strategy",
+		"deployment/openclaw-browser has 1 replica(s) and maxUnavailable 1",
+		"deployment/openclaw-gateway requires pod anti-affinity on kubernetes.io/hostname",
+		"statefulset/openclaw-db has 1 replica",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("risks lack %q:\n%s", want, got)
+		}
+	}
+	if len(risks) != 4 {
+		t.Errorf("risks = %q", risks)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "PodDisruptionBudget worker allows no disruption of deployment/openclaw-worker") {
+		t.Errorf("warnings = %q", warnings)
+	}
+
+	// Anti-affinity only stalls the rollout on a single node
+	if risks, _ := rolloutRisks(workloads[2:3], nil, 3); len(risks) != 0 {
+		t.Errorf("risks on three nodes = %q", risks)
+	}
+}
+
+func TestIntOrPercent(t *testing.T) {
+	for _, tc := range []struct {
+		raw     string
+		total   int
+		roundUp bool
+		want    int
+	}{
+		{"", 1, true, 1},
+		{"", 1, false, 0},
+		{"null", 4, false, 1},
+		{"2", 1, false, 2},
+		{`"50%"`, 3, true, 2},
+		{`"50%"`, 3, false, 1},
+		{`"x%"`, 3, false, 0},
+	} {
+		if got := intOrPercent(json.RawMessage(tc.raw), tc.total, tc.roundUp, "25%"); got != tc.want {
+			t.Errorf("intOrPercent(%s, %d, %v) = %d, want %d", tc.raw, tc.total, tc.roundUp, got, tc.want)
+		}
+	}
+}
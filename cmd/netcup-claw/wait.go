@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/openclaw"
+	"github.com/mfittko/netcup-kube/internal/waitfor"
+	"github.com/spf13/cobra"
+)
+
+// defaultRolloutTimeout bounds the rollout wait after restarts and upgrades
+const defaultRolloutTimeout = 3 * time.Minute
+
+var (
+	waitFor      string
+	waitTimeout  time.Duration
+	waitName     string
+	waitSelector string
+	waitURL      string
+	waitQuiet    bool
+)
+
+var waitCmd = &cobra.Command{
+	Use:   "wait",
+	Short: "Wait until a deployment, pods, or an HTTP endpoint is ready",
+	Long: `Block until a readiness condition holds, polling with exponential backoff
+(1s doubling up to 15s), and fail when --timeout expires.
+
+Conditions:
+  deployment-ready  The deployment (--name, default openclaw) is fully rolled out
+  pod-ready         Every pod matching --selector (default: the OpenClaw selector) is Ready
+  http-healthy      GET --url (default: http://localhost:<local port>/) answers 2xx/3xx
+
+Examples:
+  netcup-claw wait --for deployment-ready
+  netcup-claw wait --for pod-ready --selector app.kubernetes.io/name=litellm --timeout 2m
+  netcup-claw wait --for http-healthy --url http://localhost:18789/health`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cfg := openclawConfig()
+		var cond waitfor.Condition
+		var what string
+		switch waitFor {
+		case "deployment-ready":
+			name := waitName
+			if name == "" {
+				name = deployedConfigDeploymentName()
+			}
+			if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+				return err
+			}
+			cond = waitfor.DeploymentReady(kubectlRunner, cfg.Namespace, name)
+			what = "deployment/" + name
+		case "pod-ready":
+			selector := waitSelector
+			if selector == "" {
+				selector = cfg.LabelSelector
+			}
+			if err := ensureKubeAPIReachableWithTunnel(ctx); err != nil {
+				return err
+			}
+			cond = waitfor.PodsReady(kubectlRunner, cfg.Namespace, selector)
+			what = "pods " + selector
+		case "http-healthy":
+			url := waitURL
+			if url == "" {
+				url = "http://localhost:" + cfg.LocalPort + "/"
+			}
+			cond = waitfor.HTTPHealthy(&http.Client{Timeout: 10 * time.Second}, url)
+			what = url
+		default:
+			return fmt.Errorf("unknown --for %q (expected deployment-ready, pod-ready, or http-healthy)", waitFor)
+		}
+
+		var progress func(string)
+		if !waitQuiet {
+			progress = func(detail string) { fmt.Fprintf(os.Stderr, "waiting: %s\n", detail) }
+		}
+		if err := waitfor.Poll(cmd.Context(), cond, waitTimeout, waitfor.DefaultBackoff, progress); err != nil {
+			return fmt.Errorf("%s not ready: %w", what, err)
+		}
+		fmt.Printf("%s ready\n", what)
+		return nil
+	},
+}
+
+// waitForOpenClawRollout waits until the OpenClaw deployment is fully rolled out
+func waitForOpenClawRollout(ctx context.Context, cfg openclaw.Config) error {
+	name := deployedConfigDeploymentName()
+	progress := func(detail string) { fmt.Printf("waiting for deployment/%s: %s\n", name, detail) }
+	return waitfor.Poll(ctx, waitfor.DeploymentReady(kubectlRunner, cfg.Namespace, name),
+		defaultRolloutTimeout, waitfor.DefaultBackoff, progress)
+}
+
+func init() {
+	waitCmd.Flags().StringVar(&waitFor, "for", "", "Condition: deployment-ready, pod-ready, or http-healthy (required)")
+	waitCmd.Flags().DurationVar(&waitTimeout, "timeout", 5*time.Minute, "Give up after this long")
+	waitCmd.Flags().StringVar(&waitName, "name", "", "Deployment name for deployment-ready (default: openclaw)")
+	waitCmd.Flags().StringVar(&waitSelector, "selector", "", "Label selector for pod-ready (default: OpenClaw selector)")
+	waitCmd.Flags().StringVar(&waitURL, "url", "", "URL for http-healthy (default: the port-forward)")
+	waitCmd.Flags().BoolVarP(&waitQuiet, "quiet", "q", false, "Do not print progress")
+	_ = waitCmd.MarkFlagRequired("for")
+	rootCmd.AddCommand(waitCmd)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// defaultAgentConcurrency is the default number of agents processed in parallel.
+const defaultAgentConcurrency = 4
+
+// boundedConcurrency caps a per-command concurrency at --max-parallel, since
+// workers beyond it would only wait for a kubectl slot
+func boundedConcurrency(n int) int {
+	if maxParallel > 0 && n > maxParallel {
+		return maxParallel
+	}
+	return n
+}
+
+// runBounded calls fn for each index in [0, n) using at most limit concurrent
+// workers. The returned slice holds the error for each index (nil on success),
+// so one failing item never prevents the others from running.
+func runBounded(n, limit int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	if n == 0 {
+		return errs
+	}
+	if limit < 1 {
+		limit = 1
+	}
+	if limit > n {
+		limit = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < limit; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				errs[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return errs
+}
+
+// aggregateItemErrors combines per-item errors into a single error naming each
+// failed item, or returns nil if every item succeeded.
+func aggregateItemErrors(kind string, names []string, errs []error) error {
+	var msgs []string
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		msgs = append(msgs, fmt.Sprintf("%s: %v", names[i], err))
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d %s failed: %s", len(msgs), len(errs), kind, strings.Join(msgs, " | "))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/openclawapi"
+)
+
+func TestRunBounded_RespectsLimit(t *testing.T) {
+	var active, peak int32
+	var mu sync.Mutex
+	seen := map[int]bool{}
+
+	errs := runBounded(10, 3, func(i int) error {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+
+		mu.Lock()
+		seen[i] = true
+		mu.Unlock()
+		return nil
+	})
+
+	if len(errs) != 10 {
+		t.Fatalf("len(errs) = %d, want 10", len(errs))
+	}
+	if peak > 3 {
+		t.Fatalf("peak concurrency = %d, want <= 3", peak)
+	}
+	if len(seen) != 10 {
+		t.Fatalf("processed %d items, want 10", len(seen))
+	}
+}
+
+func TestRunBounded_ContinuesAfterFailure(t *testing.T) {
+	var calls int32
+	errs := runBounded(5, 2, func(i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 1 {
+			return fmt.Errorf("boom")
+		}
+		return nil
+	})
+
+	if calls != 5 {
+		t.Fatalf("calls = %d, want 5", calls)
+	}
+	for i, err := range errs {
+		if (i == 1) != (err != nil) {
+			t.Fatalf("errs[%d] = %v", i, err)
+		}
+	}
+}
+
+func TestRunBounded_ZeroItemsAndLimit(t *testing.T) {
+	if errs := runBounded(0, 4, func(int) error { return nil }); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+	errs := runBounded(2, 0, func(int) error { return nil })
+	if len(errs) != 2 || errs[0] != nil || errs[1] != nil {
+		t.Fatalf("unexpected errs with limit 0: %v", errs)
+	}
+}
+
+func TestAggregateItemErrors(t *testing.T) {
+	if err := aggregateItemErrors("agents", []string{"a", "b"}, []error{nil, nil}); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+
+	err := aggregateItemErrors("agents", []string{"a", "b", "c"}, []error{fmt.Errorf("x"), nil, fmt.Errorf("y")})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	msg := err.Error()
+	for _, want := range []string{"2 of 3 agents failed", "a: x", "c: y"} {
+		if !strings.Contains(msg, want) {
+			t.Fatalf("error %q missing %q", msg, want)
+		}
+	}
+}
+
+func TestFilterWorkspaceAgents(t *testing.T) {
+	got := filterWorkspaceAgents([]openclawapi.Agent{
+		{ID: "main", Workspace: "/home/node/.openclaw/workspace"},
+		{ID: "", Workspace: "/x"},
+		{ID: "ops", Workspace: "  "},
+		{ID: "research", Workspace: "/home/node/.openclaw/workspace-research"},
+	})
+	ids := agentIDs(got)
+	if strings.Join(ids, ",") != "main,research" {
+		t.Fatalf("filterWorkspaceAgents ids = %v", ids)
+	}
+}
+
+func TestBoundedConcurrency(t *testing.T) {
+	saved := maxParallel
+	t.Cleanup(func() { maxParallel = saved })
+
+	maxParallel = 3
+	if got := boundedConcurrency(8); got != 3 {
+		t.Errorf("boundedConcurrency(8) = %d, want 3", got)
+	}
+	if got := boundedConcurrency(2); got != 2 {
+		t.Errorf("boundedConcurrency(2) = %d, want 2", got)
+	}
+	maxParallel = 0
+	if got := boundedConcurrency(8); got != 8 {
+		t.Errorf("boundedConcurrency(8) without a limit = %d, want 8", got)
+	}
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+)
+
+// wizard asks the questions of "bootstrap --wizard" and collects the answers
+// as env file assignments
+type wizard struct {
+	reader *bufio.Reader
+	out    io.Writer
+	// current returns the value a key has before the wizard, used as default
+	current func(key string) string
+	answers []string
+	values  map[string]string
+}
+
+var errWizardInput = errors.New("input ended before the wizard was complete")
+
+// runBootstrapWizard interviews the operator about the settings bootstrap
+// prompts for and returns them as KEY, value pairs in question order
+func runBootstrapWizard(reader *bufio.Reader, out io.Writer, current func(key string) string) ([]string, error) {
+	w := &wizard{reader: reader, out: out, current: current, values: map[string]string{}}
+	_, _ = fmt.Fprintln(out, "netcup-kube bootstrap wizard. Press Enter to accept the [default].")
+
+	_, _ = fmt.Fprintln(out, "\nEdge TLS")
+	edge, err := w.choice("EDGE_PROXY", "Reverse proxy for TLS on this host", "caddy", "caddy", "none")
+	if err != nil {
+		return nil, err
+	}
+	if edge == "caddy" {
+		mode, err := w.choice("CADDY_CERT_MODE", "Certificate mode", "dns01_wildcard", "dns01_wildcard", "http01")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.ask("BASE_DOMAIN", "Base domain (e.g. example.com)", mode == "dns01_wildcard"); err != nil {
+			return nil, err
+		}
+		if mode == "http01" {
+			if _, err := w.ask("CADDY_HTTP01_HOSTS", "HTTP-01 host names (space-separated)", true); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := w.ask("ACME_EMAIL", "ACME email (recommended)", false); err != nil {
+			return nil, err
+		}
+	}
+
+	_, _ = fmt.Fprintln(out, "\nKubernetes Dashboard")
+	dash, err := w.yesNo("DASH_ENABLE", "Install the Kubernetes Dashboard?", false)
+	if err != nil {
+		return nil, err
+	}
+	if dash && edge == "caddy" && w.values["BASE_DOMAIN"] != "" {
+		if _, err := w.askDefault("DASH_HOST", "Dashboard host name", "kube."+w.values["BASE_DOMAIN"], false); err != nil {
+			return nil, err
+		}
+	}
+
+	_, _ = fmt.Fprintln(out, "\nPrivate network (vLAN)")
+	vlan, err := w.yesNo("ENABLE_VLAN_NAT", "NAT egress for vLAN-only nodes through this node?", false)
+	if err != nil {
+		return nil, err
+	}
+	if vlan {
+		if _, err := w.ask("PRIVATE_IFACE", "Private interface (e.g. eth1)", false); err != nil {
+			return nil, err
+		}
+		if _, err := w.ask("PRIVATE_CIDR", "Private vLAN CIDR (e.g. 10.10.0.0/24)", true); err != nil {
+			return nil, err
+		}
+	}
+
+	_, _ = fmt.Fprintln(out, "\nFirewall")
+	ufw, err := w.yesNo("ENABLE_UFW", "Enable the UFW firewall with safe defaults (recommended)?", true)
+	if err != nil {
+		return nil, err
+	}
+	if ufw {
+		if _, err := w.askDefault("ADMIN_SRC_CIDR", "Admin source CIDRs allowed to reach the k3s API (empty keeps 6443 closed)", sshClientCIDR(), false); err != nil {
+			return nil, err
+		}
+	}
+	return w.answers, nil
+}
+
+// ask asks for a free-form value of key, defaulting to its current value
+func (w *wizard) ask(key, prompt string, required bool) (string, error) {
+	return w.askDefault(key, prompt, "", required)
+}
+
+// askDefault asks for a value of key until it passes validation. The current
+// value of key wins over def as the default.
+func (w *wizard) askDefault(key, prompt, def string, required bool) (string, error) {
+	if cur := w.current(key); cur != "" {
+		def = cur
+	}
+	for {
+		answer, err := w.readLine(prompt, def)
+		if err != nil {
+			return "", err
+		}
+		if answer == "" && required {
+			_, _ = fmt.Fprintf(w.out, "  %s is required\n", key)
+			continue
+		}
+		if err := config.ValidateValue(key, answer); err != nil {
+			_, _ = fmt.Fprintf(w.out, "  %v\n", err)
+			continue
+		}
+		w.set(key, answer)
+		return answer, nil
+	}
+}
+
+// choice asks for one of options
+func (w *wizard) choice(key, prompt, def string, options ...string) (string, error) {
+	if cur := w.current(key); cur != "" {
+		def = cur
+	}
+	for {
+		answer, err := w.readLine(fmt.Sprintf("%s (%s)", prompt, strings.Join(options, "/")), def)
+		if err != nil {
+			return "", err
+		}
+		for _, option := range options {
+			if answer == option {
+				w.set(key, answer)
+				return answer, nil
+			}
+		}
+		_, _ = fmt.Fprintf(w.out, "  choose one of: %s\n", strings.Join(options, ", "))
+	}
+}
+
+// yesNo asks a yes/no question and records the answer as true or false
+func (w *wizard) yesNo(key, prompt string, def bool) (bool, error) {
+	if cur := w.current(key); cur != "" {
+		def = isTruthy(cur)
+	}
+	defText := "n"
+	if def {
+		defText = "y"
+	}
+	for {
+		answer, err := w.readLine(prompt+" (y/n)", defText)
+		if err != nil {
+			return false, err
+		}
+		switch strings.ToLower(answer) {
+		case "y", "yes", "true", "1", "on":
+			w.set(key, "true")
+			return true, nil
+		case "n", "no", "false", "0", "off":
+			w.set(key, "false")
+			return false, nil
+		}
+		_, _ = fmt.Fprintln(w.out, "  answer y or n")
+	}
+}
+
+func (w *wizard) readLine(prompt, def string) (string, error) {
+	if def != "" {
+		_, _ = fmt.Fprintf(w.out, "%s [%s]: ", prompt, def)
+	} else {
+		_, _ = fmt.Fprintf(w.out, "%s: ", prompt)
+	}
+	line, err := w.reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", errWizardInput
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+func (w *wizard) set(key, value string) {
+	w.answers = append(w.answers, key, value)
+	w.values[key] = value
+}
+
+// sshClientCIDR returns the address of the SSH client as a host CIDR, the
+// default admin source of scripts/main.sh
+func sshClientCIDR() string {
+	conn := os.Getenv("SSH_CONNECTION")
+	if conn == "" {
+		return ""
+	}
+	src := strings.TrimPrefix(strings.Fields(conn)[0], "::ffff:")
+	if strings.Contains(src, ":") {
+		return src + "/128"
+	}
+	return src + "/32"
+}
+
+// bootstrapPlan describes what bootstrap will do with the given settings
+func bootstrapPlan(value func(key string) string) []string {
+	channel := value("CHANNEL")
+	if v := value("K3S_VERSION"); v != "" {
+		channel = v
+	} else if channel == "" {
+		channel = "stable"
+	}
+	plan := []string{fmt.Sprintf("Install k3s server (%s) with Traefik on NodePorts", channel)}
+
+	if value("EDGE_PROXY") == "caddy" {
+		if value("CADDY_CERT_MODE") == "http01" {
+			plan = append(plan, fmt.Sprintf("Configure Caddy with HTTP-01 certificates for %s", value("CADDY_HTTP01_HOSTS")))
+		} else {
+			plan = append(plan, fmt.Sprintf("Configure Caddy with a DNS-01 wildcard certificate for *.%s", value("BASE_DOMAIN")))
+		}
+	} else {
+		plan = append(plan, "Skip the edge proxy (no host TLS)")
+	}
+	if isTruthy(value("DASH_ENABLE")) {
+		if host := value("DASH_HOST"); host != "" {
+			plan = append(plan, "Install the Kubernetes Dashboard at https://"+host)
+		} else {
+			plan = append(plan, "Install the Kubernetes Dashboard")
+		}
+	}
+	if isTruthy(value("ENABLE_VLAN_NAT")) {
+		plan = append(plan, fmt.Sprintf("NAT egress for the vLAN %s", value("PRIVATE_CIDR")))
+	}
+	if isTruthy(value("ENABLE_UFW")) {
+		if cidr := value("ADMIN_SRC_CIDR"); cidr != "" {
+			plan = append(plan, "Enable UFW; allow the k3s API (6443) from "+cidr)
+		} else {
+			plan = append(plan, "Enable UFW; keep the k3s API (6443) closed publicly")
+		}
+	}
+	return plan
+}
+
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "yes", "y", "on":
+		return true
+	}
+	return false
+}
+
+// bootstrapWithWizard runs the wizard, saves the answers to the env file (not
+// in dry-run mode), shows the plan and asks whether to run bootstrap now. It
+// returns false when the operator declines.
+func bootstrapWithWizard() (bool, error) {
+	if !isInteractive() {
+		return false, fmt.Errorf("--wizard needs a terminal; set the values in the env file instead (netcup-kube config edit)")
+	}
+	path, err := configFilePath()
+	if err != nil {
+		return false, err
+	}
+	current := func(key string) string { return cfg.Env[key] }
+	reader := bufio.NewReader(os.Stdin)
+	answers, err := runBootstrapWizard(reader, os.Stdout, current)
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i+1 < len(answers); i += 2 {
+		cfg.SetFlag(answers[i], answers[i+1])
+	}
+
+	fmt.Println()
+	if isDryRun() {
+		fmt.Printf("Dry run: not writing %s\n", path)
+	} else if err := setConfigValues(path, ".netcup-kube.env.wizard-*", answers...); err != nil {
+		return false, err
+	}
+
+	fmt.Println("\nPlan:")
+	for _, step := range bootstrapPlan(current) {
+		fmt.Printf("  - %s\n", step)
+	}
+	fmt.Println()
+	if !confirmDefaultYes(reader, os.Stdout, "Run bootstrap now? [Y/n] ") {
+		fmt.Println("Not bootstrapping; run it later with: sudo netcup-kube bootstrap")
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunBootstrapWizard(t *testing.T) {
+	t.Setenv("SSH_CONNECTION", "203.0.113.7 52000 198.51.100.1 22")
+	current := map[string]string{"ACME_EMAIL": "ops@example.com"}
+	input := strings.Join([]string{
+		"",              // EDGE_PROXY: caddy
+		"",              // CADDY_CERT_MODE: dns01_wildcard
+		"",              // BASE_DOMAIN is required
+		"not a domain!", // rejected by validation
+		"example.com",
+		"",        // ACME_EMAIL keeps the current value
+		"y",       // DASH_ENABLE
+		"",        // DASH_HOST: kube.example.com
+		"maybe",   // rejected
+		"yes",     // ENABLE_VLAN_NAT
+		"eth1",    // PRIVATE_IFACE
+		"10.10.0", // rejected
+		"10.10.0.0/24",
+		"", // ENABLE_UFW: yes
+		"", // ADMIN_SRC_CIDR: the SSH client
+	}, "\n") + "\n"
+
+	var out bytes.Buffer
+	answers, err := runBootstrapWizard(bufio.NewReader(strings.NewReader(input)), &out, func(key string) string { return current[key] })
+	if err != nil {
+		t.Fatalf("runBootstrapWizard() error = %v\n%s", err, out.String())
+	}
+	want := []string{
+		"EDGE_PROXY", "caddy",
+		"CADDY_CERT_MODE", "dns01_wildcard",
+		"BASE_DOMAIN", "example.com",
+		"ACME_EMAIL", "ops@example.com",
+		"DASH_ENABLE", "true",
+		"DASH_HOST", "kube.example.com",
+		"ENABLE_VLAN_NAT", "true",
+		"PRIVATE_IFACE", "eth1",
+		"PRIVATE_CIDR", "10.10.0.0/24",
+		"ENABLE_UFW", "true",
+		"ADMIN_SRC_CIDR", "203.0.113.7/32",
+	}
+	if !reflect.DeepEqual(answers, want) {
+		t.Fatalf("answers = %v\nwant %v", answers, want)
+	}
+	for _, msg := range []string{"BASE_DOMAIN is required", "answer y or n"} {
+		if !strings.Contains(out.String(), msg) {
+			t.Errorf("output does not contain %q:\n%s", msg, out.String())
+		}
+	}
+
+	values := map[string]string{}
+	for i := 0; i < len(answers); i += 2 {
+		values[answers[i]] = answers[i+1]
+	}
+	plan := strings.Join(bootstrapPlan(func(key string) string { return values[key] }), "\n")
+	for _, step := range []string{"k3s server (stable)", "*.example.com", "https://kube.example.com", "vLAN 10.10.0.0/24", "from 203.0.113.7/32"} {
+		if !strings.Contains(plan, step) {
+			t.Errorf("plan does not mention %q:\n%s", step, plan)
+		}
+	}
+
+	if _, err := runBootstrapWizard(bufio.NewReader(strings.NewReader("none\n")), &out, func(string) string { return "" }); err != errWizardInput {
+		t.Errorf("runBootstrapWizard(short input) error = %v, want errWizardInput", err)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/airgap"
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/k3s"
+	"github.com/spf13/cobra"
+)
+
+var (
+	bundleArch       string
+	bundleK3sVersion string
+	bundleCharts     []string
+	bundleNoCharts   bool
+)
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Prepare offline bundles for air-gapped servers",
+	Long: `Download everything bootstrap and the recipes fetch from the internet into a
+directory that can be copied to a server without internet access.
+
+A bundle holds the k3s installer, binary and airgap images, the Helm CLI, and
+the Helm charts pinned in scripts/recipes/recipes.conf. Container images of
+the recipe workloads are not included: mirror them into a registry the
+cluster can reach.
+
+Sub-commands:
+  create  - Download a bundle into a directory
+  use     - Verify a bundle and configure bootstrap and install to use it`,
+	SilenceUsage: true,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create <dir>",
+	Short: "Download a bundle into a directory",
+	Long: `Download the k3s installer, the k3s binary and airgap images, the Helm CLI,
+and the recipe charts into <dir>, verifying the release checksums, and write
+<dir>/manifest.json with the checksum of every file.
+
+The k3s version defaults to K3S_VERSION from the env file, then CHANNEL
+(resolved to its current release); --k3s-version overrides it. Charts are
+pulled with the helm CLI at the versions pinned in recipes.conf.
+
+Examples:
+  netcup-kube bundle create ./bundle
+  netcup-kube bundle create ./bundle --k3s-version v1.31.4+k3s1 --arch arm64
+  netcup-kube bundle create ./bundle --charts redis,postgresql`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := airgap.ValidateArch(bundleArch); err != nil {
+			return err
+		}
+		charts, err := bundleSelectCharts(bundleCharts, bundleNoCharts)
+		if err != nil {
+			return err
+		}
+		scriptsDir, err := findScriptsDir()
+		if err != nil {
+			return err
+		}
+		versions, err := config.LoadEnvFileToMap(filepath.Join(scriptsDir, "recipes", "recipes.conf"))
+		if err != nil {
+			return fmt.Errorf("failed to read chart versions: %w", err)
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		target := k3sPlanTarget("", bundleK3sVersion, cfg.Env)
+		if err := target.Validate(); err != nil {
+			return err
+		}
+		version := target.Version
+		if version == "" {
+			if version, err = k3s.ResolveChannel(ctx, &http.Client{Timeout: 30 * time.Second}, k3sChannelServer, target.Channel); err != nil {
+				return err
+			}
+			fmt.Printf("Channel %s resolves to %s\n", target.Channel, version)
+		}
+
+		opts := airgap.Options{
+			Dir:           args[0],
+			K3sVersion:    version,
+			Arch:          bundleArch,
+			ChartVersions: versions,
+			Charts:        charts,
+			Out:           os.Stdout,
+		}
+		if isDryRun() {
+			for _, d := range opts.Downloads() {
+				fmt.Printf("[dry-run] download %s -> %s\n", d.URL, filepath.Join(args[0], d.Path))
+			}
+			for _, c := range charts {
+				if v := opts.ChartVersion(c); v != "" {
+					fmt.Printf("[dry-run] helm pull %s --repo %s --version %s\n", c.Name, c.Repo, v)
+				}
+			}
+			return nil
+		}
+
+		m, err := airgap.Create(ctx, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nBundle for k3s %s (%s) with %d chart(s) written to %s\n", m.K3sVersion, m.Arch, len(m.Charts), args[0])
+		fmt.Printf("Copy it to the server and run: netcup-kube bundle use <dir>\n")
+		return nil
+	},
+}
+
+var bundleUseCmd = &cobra.Command{
+	Use:   "use <dir>",
+	Short: "Verify a bundle and configure bootstrap and install to use it",
+	Long: `Check every file of the bundle in <dir> against its manifest, then set
+AIRGAP_BUNDLE (the absolute bundle path) and K3S_VERSION (the bundled release)
+in the env file.
+
+With AIRGAP_BUNDLE set, bootstrap and join install k3s from the bundle
+(INSTALL_K3S_SKIP_DOWNLOAD, images preloaded from the airgap tarball) and the
+Helm CLI from its archive; install and the dashboard use the bundled charts
+instead of their repositories. Clear it with 'config set AIRGAP_BUNDLE='.
+
+Examples:
+  netcup-kube bundle use /opt/netcup-kube/bundle
+  netcup-kube bundle use ./bundle --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		m, err := airgap.Verify(dir)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Verified %d file(s): k3s %s (%s), Helm %s, %d chart(s)\n", len(m.Files), m.K3sVersion, m.Arch, orDash(m.HelmVersion), len(m.Charts))
+
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		if isDryRun() {
+			fmt.Printf("[dry-run] set AIRGAP_BUNDLE=%s K3S_VERSION=%s in %s\n", dir, m.K3sVersion, path)
+			return nil
+		}
+		return setConfigValues(path, ".netcup-kube-bundle-*.env", "AIRGAP_BUNDLE", dir, "K3S_VERSION", m.K3sVersion)
+	},
+}
+
+// bundleSelectCharts filters the bundled charts by name
+func bundleSelectCharts(names []string, none bool) ([]airgap.Chart, error) {
+	if none {
+		return nil, nil
+	}
+	if len(names) == 0 {
+		return airgap.Charts, nil
+	}
+	known := map[string]airgap.Chart{}
+	available := make([]string, 0, len(airgap.Charts))
+	for _, c := range airgap.Charts {
+		known[c.Name] = c
+		available = append(available, c.Name)
+	}
+	var charts []airgap.Chart
+	for _, name := range names {
+		c, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown chart %q (available: %s)", name, strings.Join(available, ", "))
+		}
+		charts = append(charts, c)
+	}
+	return charts, nil
+}
+
+// setConfigValues sets key/value pairs in the env file at path and prints the
+// resulting change
+func setConfigValues(path, stagePattern string, pairs ...string) error {
+	unlock, err := lockEnvFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	original, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	updated := string(original)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		updated = config.SetEnvValue(updated, pairs[i], pairs[i+1])
+	}
+	if updated == string(original) {
+		fmt.Printf("%s is up to date\n", path)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	stage, err := os.CreateTemp(filepath.Dir(path), stagePattern)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	stagePath := stage.Name()
+	_ = stage.Close()
+	defer func() { _ = os.Remove(stagePath) }()
+	if err := writeFileAtomic(path, stagePath, []byte(updated)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated %s\n", path)
+	printConfigDiff(os.Stdout, config.DiffEnv(string(original), updated))
+	return nil
+}
+
+func init() {
+	bundleCreateCmd.Flags().StringVar(&bundleK3sVersion, "k3s-version", "", "k3s version to bundle (default: K3S_VERSION, then CHANNEL from the env file)")
+	bundleCreateCmd.Flags().StringVar(&bundleArch, "arch", "amd64", "Server architecture: amd64 or arm64")
+	bundleCreateCmd.Flags().StringSliceVar(&bundleCharts, "charts", nil, "Charts to bundle (default: all recipe charts)")
+	bundleCreateCmd.Flags().BoolVar(&bundleNoCharts, "no-charts", false, "Bundle k3s and Helm only")
+
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleUseCmd)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBundleSelectCharts(t *testing.T) {
+	charts, err := bundleSelectCharts([]string{"redis", "postgresql"}, false)
+	if err != nil || len(charts) != 2 || charts[0].VersionKey != "CHART_VERSION_REDIS" {
+		t.Errorf("bundleSelectCharts() = %+v, %v", charts, err)
+	}
+	if charts, _ := bundleSelectCharts(nil, true); charts != nil {
+		t.Errorf("--no-charts selected %+v", charts)
+	}
+	if _, err := bundleSelectCharts([]string{"nginx"}, false); err == nil || !strings.Contains(err.Error(), "sealed-secrets") {
+		t.Errorf("bundleSelectCharts() error = %v", err)
+	}
+}
+
+func TestSetConfigValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "netcup-kube.env")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("# cluster\nK3S_VERSION=v1.30.0+k3s1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := setConfigValues(path, ".test-*.env", "AIRGAP_BUNDLE", "/opt/bundle", "K3S_VERSION", "v1.31.4+k3s1"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# cluster\nK3S_VERSION=v1.31.4+k3s1\nAIRGAP_BUNDLE=/opt/bundle\n"; string(data) != want {
+		t.Errorf("env file = %q, want %q", data, want)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/k3s"
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/spf13/cobra"
+)
+
+var (
+	certsInventory    string
+	certsYes          bool
+	certsReadyTimeout time.Duration
+	certsMinValidity  time.Duration
+	certsRestart      []string
+)
+
+var certsCmd = &cobra.Command{
+	Use:   "certs",
+	Short: "Manage the k3s certificates of the cluster",
+	Long: `Manage the certificates k3s issues for its servers, agents and the admin
+kubeconfig. They are valid for one year; k3s only renews them on a restart
+within 90 days of expiry, so clusters that were not restarted for a year stop
+answering with expired certificates.
+
+Sub-commands:
+  rotate  - Rotate the certificates of servers and agents and refresh the kubeconfig`,
+	SilenceUsage: true,
+}
+
+var certsRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Rotate the k3s certificates of servers and agents",
+	Long: `Rotate the k3s certificates of the cluster:
+
+  1. On each server over SSH: stop k3s, run 'k3s certificate rotate', start
+     k3s and wait for its API
+  2. Refresh the cached kubeconfig (config/k3s.yaml, or its encrypted .enc)
+     from /etc/rancher/k3s/k3s.yaml, which k3s rewrites with the new client
+     certificate; the previous cache is restored when the fetch fails
+  3. Wait for the servers to be Ready, then restart k3s-agent on each worker
+     (agents renew their certificates on start) and wait for it
+  4. Restart the dependent workloads given with --restart (kubectl rollout
+     restart), e.g. controllers holding connections with the old certificates
+  5. Verify that the kubeconfig client certificate and the API serving
+     certificate are valid for at least --min-validity
+
+The API is unavailable while k3s restarts. The cluster kubeconfig is not
+used before the rotation, so it also works when the certificates already
+expired. A KUBECONFIG pointing elsewhere than the netcup-kube cache is not
+replaced; copy /etc/rancher/k3s/k3s.yaml into it after the rotation.
+
+Nodes come from --inventory; without it the single node at MGMT_HOST is
+rotated. --dry-run prints the plan only.
+
+Examples:
+  netcup-kube certs rotate --dry-run
+  netcup-kube certs rotate --yes
+  netcup-kube certs rotate --inventory config/inventory.yaml --restart kube-system/metrics-server`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		restarts, err := parseRestartTargets(certsRestart)
+		if err != nil {
+			return err
+		}
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		nodes, err := k3sUpgradeNodes(certsInventory)
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		steps := k3s.CertRotationPlan(nodes)
+		fmt.Println("Planned sequence:")
+		for i, step := range steps {
+			fmt.Printf("  %d. %s\n", i+1, step.Describe(""))
+		}
+		for _, target := range restarts {
+			fmt.Printf("  -  kubectl rollout restart deployment/%s -n %s\n", target[1], target[0])
+		}
+		fmt.Printf("  -  verify the certificates are valid for at least %s\n", certsMinValidity)
+		if isDryRun() {
+			return nil
+		}
+
+		if err := confirm.New(certsYes).Confirm(fmt.Sprintf("rotate the k3s certificates of %d node(s); the API is unavailable while k3s restarts", len(nodes))); err != nil {
+			return err
+		}
+
+		kube := kubectl.New()
+		upgrader := &k3s.Upgrader{
+			Kube:         kube,
+			RunScript:    runK3sScript(ctx, path),
+			Out:          os.Stdout,
+			ReadyTimeout: certsReadyTimeout,
+			PollInterval: k3sPollInterval,
+			RefreshKubeconfig: func(ctx context.Context) error {
+				if err := refreshKubeconfigCache(ctx, path); err != nil {
+					return err
+				}
+				_, err := clusterKubectl(ctx, path)
+				return err
+			},
+		}
+		if err := upgrader.Execute(ctx, steps, ""); err != nil {
+			return err
+		}
+
+		for _, target := range restarts {
+			if err := restartDeployment(ctx, kube, target[0], target[1]); err != nil {
+				return err
+			}
+		}
+
+		return verifyClusterCerts(ctx, os.Getenv("KUBECONFIG"), certsMinValidity)
+	},
+}
+
+// parseRestartTargets splits --restart values into namespace and deployment
+func parseRestartTargets(values []string) ([][2]string, error) {
+	targets := make([][2]string, 0, len(values))
+	for _, v := range values {
+		ns, name, ok := strings.Cut(strings.TrimSpace(v), "/")
+		if !ok || ns == "" || name == "" || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("invalid --restart %q (expected <namespace>/<deployment>)", v)
+		}
+		targets = append(targets, [2]string{ns, name})
+	}
+	return targets, nil
+}
+
+// refreshKubeconfigCache replaces the cached kubeconfig with a fresh copy from
+// the server. The previous cache is moved aside and restored when the fetch
+// fails, so a failed refresh leaves the old (expired) credentials in place.
+func refreshKubeconfigCache(ctx context.Context, envPath string) error {
+	root, err := workspaceRoot()
+	if err != nil {
+		return err
+	}
+	local := filepath.Join(root, "config", "k3s.yaml")
+	switch kubeconfig := os.Getenv("KUBECONFIG"); {
+	case kubeconfig == serverKubeconfigPath:
+		return nil
+	case kubeconfig != "" && kubeconfig != local:
+		fmt.Fprintf(os.Stderr, "warning: KUBECONFIG=%s is not the netcup-kube cache; replace it with %s from the server\n", kubeconfig, serverKubeconfigPath)
+		return nil
+	case kubeconfig == "":
+		if _, err := os.Stat(serverKubeconfigPath); err == nil {
+			return nil
+		}
+	}
+
+	var moved []string
+	restore := func() {
+		for _, p := range moved {
+			_ = os.Rename(p+".pre-rotate", p)
+		}
+	}
+	for _, p := range []string{local, local + ".enc"} {
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		if err := os.Rename(p, p+".pre-rotate"); err != nil {
+			restore()
+			return fmt.Errorf("failed to move aside %s: %w", p, err)
+		}
+		moved = append(moved, p)
+	}
+	if _, err := cachedKubeconfig(ctx, envPath, local); err != nil {
+		restore()
+		return fmt.Errorf("failed to refresh the kubeconfig cache: %w", err)
+	}
+	for _, p := range moved {
+		_ = os.Remove(p + ".pre-rotate")
+	}
+	return nil
+}
+
+// restartDeployment restarts a dependent deployment and waits for its
+// rollout; deployments that do not exist are skipped
+func restartDeployment(ctx context.Context, kube k3s.Kubectl, namespace, name string) error {
+	fmt.Printf("Restarting deployment/%s in %s\n", name, namespace)
+	if _, err := kube.Output(ctx, "-n", namespace, "rollout", "restart", "deployment/"+name); err != nil {
+		if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "not found") {
+			fmt.Fprintf(os.Stderr, "warning: deployment/%s not found in %s; skipped\n", name, namespace)
+			return nil
+		}
+		return fmt.Errorf("failed to restart deployment/%s in %s: %w", name, namespace, err)
+	}
+	if _, err := kube.Output(ctx, "-n", namespace, "rollout", "status", "deployment/"+name, "--timeout=5m"); err != nil {
+		return fmt.Errorf("deployment/%s in %s did not roll out: %w", name, namespace, err)
+	}
+	return nil
+}
+
+// verifyClusterCerts prints the expiry of the kubeconfig client and API
+// serving certificates and fails when one expires within min
+func verifyClusterCerts(ctx context.Context, kubeconfig string, min time.Duration) error {
+	certs, err := k3s.ClusterCerts(ctx, kubeconfig, 10*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to verify the certificates: %w", err)
+	}
+	fmt.Println("Certificates:")
+	for _, c := range certs {
+		fmt.Printf("  %-18s %-28s valid until %s\n", c.Name, c.Subject, c.NotAfter.UTC().Format("2006-01-02"))
+	}
+	return k3s.CheckValidity(certs, time.Now(), min)
+}
+
+func init() {
+	certsRotateCmd.Flags().StringVar(&certsInventory, "inventory", "", "Cluster inventory file (YAML); default: the single node at MGMT_HOST")
+	certsRotateCmd.Flags().BoolVarP(&certsYes, "yes", "y", false, "Rotate without asking for confirmation")
+	certsRotateCmd.Flags().DurationVar(&certsReadyTimeout, "ready-timeout", 10*time.Minute, "Timeout for a node to become Ready after the rotation")
+	certsRotateCmd.Flags().DurationVar(&certsMinValidity, "min-validity", 300*24*time.Hour, "Minimum remaining validity of the rotated certificates")
+	certsRotateCmd.Flags().StringArrayVar(&certsRestart, "restart", nil, "Deployment to restart after the rotation, as <namespace>/<deployment> (repeatable)")
+	certsCmd.AddCommand(certsRotateCmd)
+}
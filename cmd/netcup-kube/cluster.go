@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/clusters"
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+const clusterFlag = "--cluster"
+
+var (
+	// selectedCluster is the --cluster (or NETCUP_KUBE_CLUSTER) of this run
+	selectedCluster   string
+	clusterListOutput string
+)
+
+var clusterCmd = &cobra.Command{
+	Use:   "cluster",
+	Short: "Show the cluster registry used by --cluster",
+	Long: `Operators with several Netcup servers describe them in a cluster registry
+(config/clusters.yaml, else ~/.config/netcup-kube/clusters.yaml, or the file
+in NETCUP_KUBE_CLUSTERS) and pick one per run with the global --cluster <name> flag or NETCUP_KUBE_CLUSTER.
+
+Each cluster sets MGMT_HOST (host), MGMT_USER (user), KUBECONFIG
+(kubeconfig, default config/k3s.<name>.yaml) and any vars on top of its
+env_file (default: config/netcup-kube.env). Flags still win. See
+config/clusters.example.yaml.
+
+Sub-commands:
+  list  - Show the registered clusters
+
+Examples:
+  netcup-kube cluster list
+  netcup-kube --cluster staging ssh tunnel start
+  NETCUP_KUBE_CLUSTER=prod netcup-kube install redis`,
+	SilenceUsage: true,
+}
+
+var clusterListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show the registered clusters",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(clusterListOutput)
+		if err != nil {
+			return err
+		}
+		reg, err := clusters.Load(clusters.Path())
+		if err != nil {
+			return err
+		}
+
+		if format == output.FormatJSON {
+			type entry struct {
+				Name       string            `json:"name"`
+				Selected   bool              `json:"selected"`
+				Host       string            `json:"host,omitempty"`
+				User       string            `json:"user,omitempty"`
+				Kubeconfig string            `json:"kubeconfig"`
+				EnvFile    string            `json:"env_file,omitempty"`
+				Vars       map[string]string `json:"vars"`
+			}
+			entries := make([]entry, 0, len(reg.Clusters))
+			for _, c := range reg.Clusters {
+				entries = append(entries, entry{
+					Name:       c.Name,
+					Selected:   c.Name == selectedCluster,
+					Host:       c.Host,
+					User:       c.User,
+					Kubeconfig: c.KubeconfigPath(),
+					EnvFile:    c.EnvFilePath(),
+					Vars:       c.Vars,
+				})
+			}
+			return output.WriteJSON(os.Stdout, entries)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "SELECTED\tNAME\tHOST\tUSER\tKUBECONFIG\tENV FILE")
+		for _, c := range reg.Clusters {
+			selected := ""
+			if c.Name == selectedCluster {
+				selected = "*"
+			}
+			envFile := c.EnvFilePath()
+			if envFile == "" {
+				envFile = defaultEnvFile()
+			}
+			if envFile == "" {
+				envFile = "-"
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", selected, c.Name, c.Host, c.User, c.KubeconfigPath(), envFile)
+		}
+		return w.Flush()
+	},
+}
+
+// stripClusterFlag removes --cluster <name> / --cluster=<name> from args
+// before cobra sees them, so commands with DisableFlagParsing do not pass it
+// on to their scripts. Arguments after "--" are left alone.
+func stripClusterFlag(args []string) ([]string, string, error) {
+	out := make([]string, 0, len(args))
+	name := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(out, args[i:]...), name, nil
+		case arg == clusterFlag:
+			if i+1 >= len(args) || strings.HasPrefix(args[i+1], "-") {
+				return nil, "", fmt.Errorf("%s requires a value", clusterFlag)
+			}
+			name = args[i+1]
+			i++
+		case strings.HasPrefix(arg, clusterFlag+"="):
+			name = strings.TrimPrefix(arg, clusterFlag+"=")
+			if name == "" {
+				return nil, "", fmt.Errorf("%s requires a value", clusterFlag)
+			}
+		default:
+			out = append(out, arg)
+		}
+	}
+	return out, name, nil
+}
+
+// selectCluster applies the registry entry name (default: NETCUP_KUBE_CLUSTER):
+// its env file becomes the default --env-file, and its settings override the
+// env file and are exported for kubectl, scripts and plugins
+func selectCluster(name string) error {
+	if name == "" {
+		name = strings.TrimSpace(os.Getenv("NETCUP_KUBE_CLUSTER"))
+	}
+	if name == "" {
+		return nil
+	}
+	reg, err := clusters.Load(clusters.Path())
+	if err != nil {
+		return err
+	}
+	c, err := reg.Cluster(name)
+	if err != nil {
+		return err
+	}
+
+	if path := c.EnvFilePath(); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("env file of cluster %q: %w", name, err)
+		}
+		envFile = path
+		sshEnvFile = path
+		remoteConfigPath = path
+	}
+	env := c.Env()
+	config.SetEnvFileOverrides(env)
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			return fmt.Errorf("failed to set %s for cluster %q: %w", k, name, err)
+		}
+	}
+	selectedCluster = name
+	return nil
+}
+
+func init() {
+	// Consumed by main before cobra parses args; registered so it shows in --help
+	rootCmd.PersistentFlags().String("cluster", "", "Cluster from the registry (config/clusters.yaml) to run against (default: $NETCUP_KUBE_CLUSTER)")
+	clusterListCmd.Flags().StringVarP(&clusterListOutput, "output", "o", "text", "Output format: text or json")
+	clusterCmd.AddCommand(clusterListCmd)
+	rootCmd.AddCommand(clusterCmd)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/clusters"
+	"github.com/mfittko/netcup-kube/internal/config"
+)
+
+func TestStripClusterFlag(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantArgs []string
+		wantName string
+		wantErr  bool
+	}{
+		{[]string{"ssh", "tunnel", "start"}, []string{"ssh", "tunnel", "start"}, "", false},
+		{[]string{"--cluster", "prod", "install", "redis"}, []string{"install", "redis"}, "prod", false},
+		{[]string{"dns", "--cluster=staging", "--type", "edge-http"}, []string{"dns", "--type", "edge-http"}, "staging", false},
+		{[]string{"remote", "run", "--", "--cluster", "x"}, []string{"remote", "run", "--", "--cluster", "x"}, "", false},
+		{[]string{"--cluster"}, nil, "", true},
+		{[]string{"--cluster", "--dry-run"}, nil, "", true},
+	}
+	for _, tt := range tests {
+		args, name, err := stripClusterFlag(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("stripClusterFlag(%v) error = %v", tt.args, err)
+			continue
+		}
+		if !tt.wantErr && (!reflect.DeepEqual(args, tt.wantArgs) || name != tt.wantName) {
+			t.Errorf("stripClusterFlag(%v) = %v, %q; want %v, %q", tt.args, args, name, tt.wantArgs, tt.wantName)
+		}
+	}
+}
+
+func TestSelectCluster(t *testing.T) {
+	dir := t.TempDir()
+	stagingEnv := filepath.Join(dir, "staging.env")
+	if err := os.WriteFile(stagingEnv, []byte("MGMT_HOST=198.51.100.1\nBASE_DOMAIN=staging.example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	registry := filepath.Join(dir, "clusters.yaml")
+	content := "clusters:\n  - name: staging\n    host: 203.0.113.20\n    env_file: " + stagingEnv + "\n"
+	if err := os.WriteFile(registry, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NETCUP_KUBE_CLUSTERS", registry)
+	t.Setenv("NETCUP_KUBE_CLUSTER", "staging")
+	t.Setenv("MGMT_HOST", "")
+	t.Setenv("KUBECONFIG", "")
+	prevEnvFile, prevSSH, prevRemote := envFile, sshEnvFile, remoteConfigPath
+	defer func() {
+		envFile, sshEnvFile, remoteConfigPath, selectedCluster = prevEnvFile, prevSSH, prevRemote, ""
+		config.SetEnvFileOverrides(nil)
+	}()
+
+	if err := selectCluster(""); err != nil {
+		t.Fatalf("selectCluster() error = %v", err)
+	}
+	if envFile != stagingEnv || sshEnvFile != stagingEnv || remoteConfigPath != stagingEnv {
+		t.Errorf("env file = %q/%q/%q, want %q", envFile, sshEnvFile, remoteConfigPath, stagingEnv)
+	}
+	if got := os.Getenv("KUBECONFIG"); got != filepath.Join("config", "k3s.staging.yaml") {
+		t.Errorf("KUBECONFIG = %q", got)
+	}
+
+	env, err := config.LoadEnvFileToMap(stagingEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env["MGMT_HOST"] != "203.0.113.20" || env["BASE_DOMAIN"] != "staging.example.com" {
+		t.Errorf("env file seen through the cluster = %v", env)
+	}
+
+	if err := selectCluster("prod"); err == nil {
+		t.Error("selectCluster(prod) succeeded for an unknown cluster")
+	}
+}
+
+func TestRegisterCluster(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clusters.yaml")
+	original := "# Production first\nclusters:\n  - name: prod\n    host: 203.0.113.10\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	staging := clusters.Cluster{Name: "staging", Host: "203.0.113.20", Vars: map[string]string{"SSH_IDENTITY_FILE": "~/.ssh/netcup"}}
+	if err := registerCluster(path, staging); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	want := original + "\n  - name: staging\n    host: 203.0.113.20\n    vars:\n      SSH_IDENTITY_FILE: ~/.ssh/netcup\n"
+	if string(data) != want {
+		t.Errorf("after adding:\n%s\nwant:\n%s", data, want)
+	}
+
+	staging.Host = "203.0.113.21"
+	if err := registerCluster(path, staging); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := clusters.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reg.Cluster("staging"); len(reg.Clusters) != 2 || got.Host != "203.0.113.21" {
+		t.Errorf("after updating: %+v", reg.Clusters)
+	}
+}
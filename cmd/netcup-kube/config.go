@@ -0,0 +1,462 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/filelock"
+	"github.com/mfittko/netcup-kube/internal/paths"
+	"github.com/spf13/cobra"
+)
+
+const exampleConfigFile = "config/netcup-kube.env.example"
+
+var (
+	configProfile     string
+	configRaw         bool
+	configShowSecrets bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and edit the netcup-kube env file",
+	Long: `View and edit the netcup-kube env file (default: config/netcup-kube.env,
+the file given with --env-file, or config/netcup-kube.<profile>.env with --profile).
+
+Sub-commands:
+  edit  - Open the env file in $EDITOR and validate it before saving
+  get   - Print one or more values (or all assignments)
+  set   - Set KEY=VALUE pairs, preserving comments and ordering
+  vars  - List the recognized environment variables and their values`,
+	SilenceUsage: true,
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit the env file in $EDITOR with validation",
+	Long: `Open the env file in $VISUAL or $EDITOR (default: vi) on a temporary copy.
+
+On save, the result is checked for syntax errors and validated with the same
+rules as "netcup-kube validate". Invalid changes are never written: the errors
+and a diff of the changed keys are shown, and on a terminal the editor can be
+reopened to fix them.
+
+If the env file does not exist yet, it is seeded from
+config/netcup-kube.env.example when available.
+
+Examples:
+  netcup-kube config edit
+  EDITOR="code --wait" netcup-kube config edit
+  netcup-kube config edit --env-file config/staging.env`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		original, err := readConfigForEdit(path)
+		if err != nil {
+			return err
+		}
+		return editConfigFile(path, original, launchEditor, os.Stdin, os.Stdout, isInteractive())
+	},
+}
+
+// editorFunc opens path in an editor and returns when editing is done
+type editorFunc func(path string) error
+
+// editConfigFile runs the edit/validate loop on a temporary copy of the env
+// file and only replaces path when the edited content is valid.
+func editConfigFile(path, original string, edit editorFunc, in io.Reader, out io.Writer, interactive bool) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, ".netcup-kube-edit-*.env")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+	if _, err := tmp.WriteString(original); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		if err := edit(tmpPath); err != nil {
+			return fmt.Errorf("editor failed: %w (%s was not modified)", err, path)
+		}
+		data, err := os.ReadFile(tmpPath)
+		if err != nil {
+			return fmt.Errorf("failed to read edited file: %w", err)
+		}
+		edited := string(data)
+		if edited == original {
+			_, _ = fmt.Fprintln(out, "No changes.")
+			return nil
+		}
+
+		diff := config.DiffEnv(original, edited)
+		if err := config.ValidateEnvContent(edited); err != nil {
+			_, _ = fmt.Fprintln(out, "Changes:")
+			printConfigDiff(out, diff)
+			_, _ = fmt.Fprintf(out, "\n%v\n", err)
+			if interactive && confirmDefaultYes(reader, out, "Re-open the editor to fix? [Y/n] ") {
+				continue
+			}
+			return fmt.Errorf("invalid configuration; %s was not modified", path)
+		}
+
+		if err := saveEditedConfig(path, original, tmpPath, data); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(out, "Saved %s\n", path)
+		printConfigDiff(out, diff)
+		return nil
+	}
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get [KEY...]",
+	Short: "Print values from the env file",
+	Long: `Print values from the env file.
+
+With keys, prints one value per line (variable references like ${MGMT_HOST}
+are expanded unless --raw is given) and fails if a key is not set. Without
+keys, prints all assignments in file order with secret-like values masked
+unless --show-secrets is given.
+
+Examples:
+  netcup-kube config get BASE_DOMAIN
+  netcup-kube config get --profile staging MGMT_HOST MGMT_USER
+  netcup-kube config get --raw TUNNEL_HOST`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return printConfigValues(os.Stdout, path, string(data), args)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY=VALUE [KEY=VALUE...]",
+	Short: "Set values in the env file",
+	Long: `Set one or more values in the env file, preserving comments and ordering.
+Existing assignments are updated in place; new keys are appended.
+
+Values of known keys are type-checked (CIDRs, ports, IPs, hostnames, URLs,
+booleans, enums) and the resulting file is validated like "netcup-kube validate"
+before it is written.
+
+Examples:
+  netcup-kube config set BASE_DOMAIN=example.com EDGE_PROXY=caddy
+  netcup-kube config set --profile staging MGMT_HOST=staging.example.com`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		unlock, err := lockEnvFile(path)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+		original, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		updated, err := applyConfigAssignments(string(original), args)
+		if err != nil {
+			return err
+		}
+		if updated == string(original) {
+			fmt.Println("No changes.")
+			return nil
+		}
+		if err := config.ValidateEnvContent(updated); err != nil {
+			return fmt.Errorf("%w\n%s was not modified", err, path)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		stage, err := os.CreateTemp(filepath.Dir(path), ".netcup-kube-set-*.env")
+		if err != nil {
+			return fmt.Errorf("failed to create temporary file: %w", err)
+		}
+		stagePath := stage.Name()
+		_ = stage.Close()
+		defer func() { _ = os.Remove(stagePath) }()
+		if err := writeFileAtomic(path, stagePath, []byte(updated)); err != nil {
+			return err
+		}
+
+		fmt.Printf("Updated %s\n", path)
+		printConfigDiff(os.Stdout, config.DiffEnv(string(original), updated))
+		return nil
+	},
+}
+
+// applyConfigAssignments validates KEY=VALUE arguments and applies them to
+// env file content.
+func applyConfigAssignments(content string, assignments []string) (string, error) {
+	for _, arg := range assignments {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return "", fmt.Errorf("invalid argument %q (expected KEY=VALUE)", arg)
+		}
+		key = strings.TrimSpace(key)
+		if err := config.ValidateValue(key, value); err != nil {
+			return "", err
+		}
+		content = config.SetEnvValue(content, key, value)
+	}
+	return content, nil
+}
+
+// printConfigValues implements `config get`
+func printConfigValues(out io.Writer, path, content string, keys []string) error {
+	expanded := config.New()
+	if !configRaw {
+		if err := expanded.LoadEnvString(content); err != nil {
+			return err
+		}
+	}
+	value := func(key string) string {
+		if configRaw {
+			v, _ := config.GetEnvValue(content, key)
+			return v
+		}
+		return expanded.Env[key]
+	}
+
+	if len(keys) == 0 {
+		for _, key := range config.EnvKeys(content) {
+			v := value(key)
+			if !configShowSecrets && v != "" && config.IsSensitiveKey(key) {
+				v = "********"
+			}
+			_, _ = fmt.Fprintf(out, "%s=%s\n", key, v)
+		}
+		return nil
+	}
+
+	for _, key := range keys {
+		if _, ok := config.GetEnvValue(content, key); !ok {
+			return fmt.Errorf("%s is not set in %s", key, path)
+		}
+		_, _ = fmt.Fprintln(out, value(key))
+	}
+	return nil
+}
+
+// configFilePath returns the env file managed by the config commands:
+// the --profile file, the --env-file, or the default.
+func configFilePath() (string, error) {
+	if configProfile != "" {
+		// envFile defaults to the existing default env file
+		if envFile != "" && envFile != defaultEnvFile() {
+			return "", fmt.Errorf("--profile and --env-file cannot be combined")
+		}
+		return profileConfigPath(configProfile)
+	}
+	if envFile != "" {
+		return envFile, nil
+	}
+	if path := defaultEnvFile(); path != "" {
+		return path, nil
+	}
+	return filepath.Join(envFileDir(), "netcup-kube.env"), nil
+}
+
+// envFileDir returns the directory holding the env files: config/ of the
+// working directory or of the checkout, else the config directory of
+// netcup-kube ($XDG_CONFIG_HOME/netcup-kube, ~/.config/netcup-kube)
+func envFileDir() string {
+	checkoutConfig := ""
+	if projectRoot, err := findProjectRoot(); err == nil {
+		checkoutConfig = filepath.Join(projectRoot, "config")
+	}
+	if dir := paths.Find("config", checkoutConfig); dir != "" {
+		return dir
+	}
+	return paths.ConfigDir("netcup-kube")
+}
+
+// defaultEnvFile returns the env file used without --env-file: netcup-kube.env
+// in envFileDir, else in the config directory of netcup-kube, or "" when
+// neither exists
+func defaultEnvFile() string {
+	return paths.Find(
+		filepath.Join(envFileDir(), "netcup-kube.env"),
+		filepath.Join(paths.ConfigDir("netcup-kube"), "netcup-kube.env"),
+	)
+}
+
+// profileConfigPath maps a profile name to netcup-kube.<profile>.env in envFileDir
+func profileConfigPath(profile string) (string, error) {
+	for _, r := range profile {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", fmt.Errorf("invalid profile name %q (use letters, digits, '-' or '_')", profile)
+		}
+	}
+	if profile == "" {
+		return "", fmt.Errorf("profile name must not be empty")
+	}
+	return filepath.Join(envFileDir(), "netcup-kube."+profile+".env"), nil
+}
+
+// readConfigForEdit returns the current env file, seeding new files from the
+// example config when it exists.
+func readConfigForEdit(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if filepath.Base(path) != "netcup-kube.env" {
+		return "", nil
+	}
+	example := paths.Find(path+".example", exampleConfigFile)
+	if data, err := os.ReadFile(example); err == nil {
+		fmt.Printf("%s does not exist; starting from %s\n", path, example)
+		return string(data), nil
+	}
+	return "", nil
+}
+
+// saveEditedConfig replaces path with the edited data unless another
+// invocation changed the file while the editor was open; the editor itself
+// runs unlocked so it never blocks cron jobs. On a conflict the edits are kept
+// in <path>.edited.
+func saveEditedConfig(path, original, tmpPath string, data []byte) error {
+	unlock, err := lockEnvFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err == nil && string(current) != original {
+		kept := path + ".edited"
+		if werr := os.WriteFile(kept, data, 0o600); werr != nil {
+			return fmt.Errorf("%s was changed by another invocation while it was being edited; it was not modified", path)
+		}
+		return fmt.Errorf("%s was changed by another invocation while it was being edited; it was not modified (your edits are in %s)", path, kept)
+	}
+	return writeFileAtomic(path, tmpPath, data)
+}
+
+// lockEnvFile serializes changes to the env file at path with other
+// invocations; call the returned function when done
+func lockEnvFile(path string) (func(), error) {
+	l, err := filelock.Acquire(path, filelock.DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = l.Release() }, nil
+}
+
+// writeFileAtomic replaces path with data, keeping the existing file mode
+// (0600 for new files). The content is staged next to path and renamed.
+func writeFileAtomic(path, stagePath string, data []byte) error {
+	mode := os.FileMode(0o600)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(stagePath, data, mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", stagePath, err)
+	}
+	if err := os.Chmod(stagePath, mode); err != nil {
+		return fmt.Errorf("failed to set permissions on %s: %w", stagePath, err)
+	}
+	if err := os.Rename(stagePath, path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return nil
+}
+
+func printConfigDiff(out io.Writer, diff []string) {
+	if len(diff) == 0 {
+		_, _ = fmt.Fprintln(out, "  (comments or formatting only)")
+		return
+	}
+	for _, line := range diff {
+		_, _ = fmt.Fprintf(out, "  %s\n", line)
+	}
+}
+
+// launchEditor opens path in $VISUAL, $EDITOR, or vi. The editor value may
+// include arguments (e.g. "code --wait").
+func launchEditor(path string) error {
+	editor := strings.TrimSpace(os.Getenv("VISUAL"))
+	if editor == "" {
+		editor = strings.TrimSpace(os.Getenv("EDITOR"))
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func confirmDefaultYes(reader *bufio.Reader, out io.Writer, prompt string) bool {
+	_, _ = fmt.Fprint(out, prompt)
+	answer, err := reader.ReadString('\n')
+	if err != nil && answer == "" {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return true
+	}
+	return false
+}
+
+func isInteractive() bool {
+	in, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	out, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return (in.Mode()&os.ModeCharDevice) != 0 && (out.Mode()&os.ModeCharDevice) != 0
+}
+
+func init() {
+	configCmd.PersistentFlags().StringVar(&configProfile, "profile", "", "Use config/netcup-kube.<profile>.env")
+	configGetCmd.Flags().BoolVar(&configRaw, "raw", false, "Print values without expanding ${VAR} references")
+	configGetCmd.Flags().BoolVar(&configShowSecrets, "show-secrets", false, "Show secret-like values when listing all assignments")
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+)
+
+// scriptedEditor returns an editor that writes each content in turn
+func scriptedEditor(t *testing.T, contents ...string) editorFunc {
+	t.Helper()
+	calls := 0
+	return func(path string) error {
+		if calls >= len(contents) {
+			t.Fatalf("editor called %d times, only %d scripted", calls+1, len(contents))
+		}
+		err := os.WriteFile(path, []byte(contents[calls]), 0o600)
+		calls++
+		return err
+	}
+}
+
+func TestEditConfigFileSavesValidChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netcup-kube.env")
+	original := "BASE_DOMAIN=example.com\n"
+	if err := os.WriteFile(path, []byte(original), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	edit := scriptedEditor(t, "BASE_DOMAIN=example.org\n")
+	if err := editConfigFile(path, original, edit, strings.NewReader(""), &out, false); err != nil {
+		t.Fatalf("editConfigFile() error: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "BASE_DOMAIN=example.org\n" {
+		t.Errorf("file = %q", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+	if !strings.Contains(out.String(), "~ BASE_DOMAIN: example.com -> example.org") {
+		t.Errorf("output = %q", out.String())
+	}
+	assertNoTempFiles(t, filepath.Dir(path))
+}
+
+func TestEditConfigFileRejectsInvalidChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netcup-kube.env")
+	original := "NODE_IP=10.0.0.1\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	edit := scriptedEditor(t, "NODE_IP=bogus\n")
+	err := editConfigFile(path, original, edit, strings.NewReader(""), &out, false)
+	if err == nil || !strings.Contains(err.Error(), "was not modified") {
+		t.Fatalf("expected rejection, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != original {
+		t.Errorf("file changed to %q", data)
+	}
+	if !strings.Contains(out.String(), "~ NODE_IP: 10.0.0.1 -> bogus") || !strings.Contains(out.String(), "invalid IP address") {
+		t.Errorf("output = %q", out.String())
+	}
+	assertNoTempFiles(t, filepath.Dir(path))
+}
+
+func TestEditConfigFileKeepsConcurrentChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netcup-kube.env")
+	original := "BASE_DOMAIN=example.com\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another invocation (e.g. config set from cron) writes while the editor is open
+	edit := func(tmpPath string) error {
+		if err := os.WriteFile(path, []byte("BASE_DOMAIN=example.com\nK3S_VERSION=v1.31.4+k3s1\n"), 0o600); err != nil {
+			return err
+		}
+		return os.WriteFile(tmpPath, []byte("BASE_DOMAIN=example.org\n"), 0o600)
+	}
+	var out bytes.Buffer
+	err := editConfigFile(path, original, edit, strings.NewReader(""), &out, false)
+	if err == nil || !strings.Contains(err.Error(), "changed by another invocation") {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "K3S_VERSION") {
+		t.Errorf("concurrent change was overwritten: %q", data)
+	}
+	if data, _ := os.ReadFile(path + ".edited"); string(data) != "BASE_DOMAIN=example.org\n" {
+		t.Errorf("edits not kept: %q", data)
+	}
+}
+
+func TestEditConfigFileReopensEditorWhenInteractive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netcup-kube.env")
+	original := "NODE_IP=10.0.0.1\n"
+	if err := os.WriteFile(path, []byte(original), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	edit := scriptedEditor(t, "NODE_IP=bogus\n", "NODE_IP=10.0.0.2\n")
+	if err := editConfigFile(path, original, edit, strings.NewReader("\n"), &out, true); err != nil {
+		t.Fatalf("editConfigFile() error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "NODE_IP=10.0.0.2\n" {
+		t.Errorf("file = %q", data)
+	}
+}
+
+func TestEditConfigFileNoChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netcup-kube.env")
+	var out bytes.Buffer
+	edit := scriptedEditor(t, "")
+	if err := editConfigFile(path, "", edit, strings.NewReader(""), &out, false); err != nil {
+		t.Fatalf("editConfigFile() error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be created, stat err = %v", err)
+	}
+	if !strings.Contains(out.String(), "No changes") {
+		t.Errorf("output = %q", out.String())
+	}
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	matches, _ := filepath.Glob(filepath.Join(dir, ".netcup-kube-edit-*"))
+	if len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestApplyConfigAssignments(t *testing.T) {
+	content := "# Management node\nMGMT_HOST=old.example.com\n\n# Edge\nEDGE_PROXY=none\n"
+	got, err := applyConfigAssignments(content, []string{"EDGE_PROXY=caddy", "BASE_DOMAIN=example.com"})
+	if err != nil {
+		t.Fatalf("applyConfigAssignments() error: %v", err)
+	}
+	want := "# Management node\nMGMT_HOST=old.example.com\n\n# Edge\nEDGE_PROXY=caddy\nBASE_DOMAIN=example.com\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	for _, bad := range []string{"NOEQUALS", "EDGE_PROXY=nginx", "TRAEFIK_NODEPORT_HTTP=0", "1KEY=x"} {
+		if _, err := applyConfigAssignments(content, []string{bad}); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestPrintConfigValues(t *testing.T) {
+	prevRaw, prevSecrets := configRaw, configShowSecrets
+	defer func() { configRaw, configShowSecrets = prevRaw, prevSecrets }()
+	configRaw, configShowSecrets = false, false
+
+	content := "MGMT_HOST=mgmt.example.com\nTUNNEL_HOST=${MGMT_HOST}\nTOKEN=secret\n"
+
+	var out bytes.Buffer
+	if err := printConfigValues(&out, "test.env", content, []string{"TUNNEL_HOST"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "mgmt.example.com\n" {
+		t.Errorf("expanded value = %q", out.String())
+	}
+
+	configRaw = true
+	out.Reset()
+	_ = printConfigValues(&out, "test.env", content, []string{"TUNNEL_HOST"})
+	if out.String() != "${MGMT_HOST}\n" {
+		t.Errorf("raw value = %q", out.String())
+	}
+
+	out.Reset()
+	_ = printConfigValues(&out, "test.env", content, nil)
+	if !strings.Contains(out.String(), "TOKEN=********\n") || !strings.HasPrefix(out.String(), "MGMT_HOST=") {
+		t.Errorf("listing = %q", out.String())
+	}
+
+	if err := printConfigValues(&out, "test.env", content, []string{"MISSING"}); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
+
+func TestConfigFilePathProfile(t *testing.T) {
+	prevProfile, prevEnvFile := configProfile, envFile
+	defer func() { configProfile, envFile = prevProfile, prevEnvFile }()
+
+	// Outside a checkout env files live in the config directory of netcup-kube
+	configHome := t.TempDir()
+	t.Setenv("NETCUP_KUBE_CONFIG_HOME", configHome)
+
+	configProfile, envFile = "staging", ""
+	if got, err := configFilePath(); err != nil || got != filepath.Join(configHome, "netcup-kube.staging.env") {
+		t.Errorf("configFilePath() = %q, %v", got, err)
+	}
+
+	envFile = "other.env"
+	if _, err := configFilePath(); err == nil {
+		t.Error("expected error when combining --profile and --env-file")
+	}
+
+	configProfile, envFile = "../etc", ""
+	if _, err := configFilePath(); err == nil {
+		t.Error("expected error for invalid profile name")
+	}
+}
+
+func TestDefaultEnvFileUserConfigDir(t *testing.T) {
+	configHome := t.TempDir()
+	t.Setenv("NETCUP_KUBE_CONFIG_HOME", configHome)
+
+	if got := defaultEnvFile(); got != "" {
+		t.Errorf("defaultEnvFile() = %q without any env file, want empty", got)
+	}
+	userEnvFile := filepath.Join(configHome, "netcup-kube.env")
+	if err := os.WriteFile(userEnvFile, []byte("MGMT_HOST=example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := defaultEnvFile(); got != userEnvFile {
+		t.Errorf("defaultEnvFile() = %q, want %q", got, userEnvFile)
+	}
+}
+
+func TestConfigVars(t *testing.T) {
+	c := config.New()
+	if err := c.LoadEnvString("BASE_DOMAIN=example.com\nNETCUP_DNS_API_PASSWORD=hunter2\n"); err != nil {
+		t.Fatal(err)
+	}
+	rows := map[string]configVar{}
+	for _, row := range configVars(c, false) {
+		rows[row.Name] = row
+	}
+
+	if got := rows["BASE_DOMAIN"]; got.Value != "example.com" || got.Source != "file" || got.Type != "hostname" {
+		t.Errorf("BASE_DOMAIN = %+v", got)
+	}
+	if got := rows["CHANNEL"]; got.Value != "stable" || got.Source != "default" {
+		t.Errorf("CHANNEL = %+v, want the default", got)
+	}
+	if got := rows["ACME_EMAIL"]; got.Value != "" || got.Source != "unset" {
+		t.Errorf("ACME_EMAIL = %+v, want unset", got)
+	}
+	if got := rows["NETCUP_DNS_API_PASSWORD"].Value; got != "********" {
+		t.Errorf("NETCUP_DNS_API_PASSWORD = %q, want it masked", got)
+	}
+	if got := rows["EDGE_PROXY"].Allowed; len(got) != 2 {
+		t.Errorf("EDGE_PROXY allowed = %v", got)
+	}
+
+	var out bytes.Buffer
+	if err := printConfigVars(&out, configVars(c, true)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "hunter2") {
+		t.Errorf("--show-secrets output does not contain the secret:\n%s", out.String())
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	configVarsOutput      string
+	configVarsShowSecrets bool
+)
+
+var configVarsCmd = &cobra.Command{
+	Use:   "vars",
+	Short: "List the recognized environment variables and their values",
+	Long: `List every environment variable netcup-kube and its scripts recognize, with
+its default, its effective value for this invocation and where that value
+comes from:
+
+  flag     a command-line flag (e.g. --dry-run)
+  cluster  the cluster selected with --cluster
+  file     the env file (--env-file, --profile or the default)
+  env      the environment of netcup-kube
+  default  not set; the default applies
+  unset    not set and without a default
+
+Names like HOOK_<STAGE>_<COMMAND> stand for a family of variables. Secret-like
+values are masked unless --show-secrets is given.
+
+Examples:
+  netcup-kube config vars
+  netcup-kube config vars --profile staging -o json
+  netcup-kube config vars --query '.[] | select(.source == "file") | .name'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(configVarsOutput)
+		if err != nil {
+			return err
+		}
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		if path != envFile {
+			envFile = path
+			if err := loadConfig(); err != nil {
+				return err
+			}
+		}
+
+		vars := configVars(cfg, configVarsShowSecrets)
+		if format == output.FormatJSON {
+			return output.WriteJSON(os.Stdout, vars)
+		}
+		return printConfigVars(os.Stdout, vars)
+	},
+}
+
+// configVar is one row of "config vars"
+type configVar struct {
+	Name        string   `json:"name"`
+	Group       string   `json:"group"`
+	Type        string   `json:"type"`
+	Allowed     []string `json:"allowed,omitempty"`
+	Default     string   `json:"default"`
+	Value       string   `json:"value"`
+	Source      string   `json:"source"`
+	Description string   `json:"description"`
+}
+
+// configVars returns the variables of the schema with their effective values in c
+func configVars(c *config.Config, showSecrets bool) []configVar {
+	vars := config.Vars()
+	rows := make([]configVar, 0, len(vars))
+	for _, v := range vars {
+		row := configVar{
+			Name:        v.Name,
+			Group:       v.Group,
+			Type:        v.Type(),
+			Allowed:     v.Allowed(),
+			Default:     v.Default,
+			Description: v.Description,
+		}
+		switch source := c.Source(v.Name); {
+		case source != "":
+			row.Value = c.Env[v.Name]
+			row.Source = string(source)
+		case v.Default != "":
+			row.Value = v.Default
+			row.Source = "default"
+		default:
+			row.Source = "unset"
+		}
+		if !showSecrets && row.Value != "" && config.IsSensitiveKey(v.Name) {
+			row.Value = "********"
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func printConfigVars(out io.Writer, vars []configVar) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tVALUE\tSOURCE\tDEFAULT\tDESCRIPTION")
+	for _, v := range vars {
+		value := v.Value
+		switch {
+		case v.Source == "unset":
+			value = "-"
+		case value == "":
+			value = `""`
+		}
+		def := v.Default
+		if def == "" {
+			def = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", v.Name, value, v.Source, def, v.Description)
+	}
+	return w.Flush()
+}
+
+func init() {
+	configVarsCmd.Flags().StringVarP(&configVarsOutput, "output", "o", "text", "Output format: text or json")
+	configVarsCmd.Flags().BoolVar(&configVarsShowSecrets, "show-secrets", false, "Show secret-like values")
+	configCmd.AddCommand(configVarsCmd)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/dashboard"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/portforward"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dashNamespace      string
+	dashServiceAccount string
+	dashDuration       time.Duration
+	dashLongLived      bool
+	dashRotate         bool
+	dashLocalPort      string
+	dashNoBrowser      bool
+)
+
+var dashboardCmd = &cobra.Command{
+	Use:   "dashboard",
+	Short: "Manage Kubernetes Dashboard tokens and access",
+	Long: `Manage access to the Kubernetes Dashboard installed by bootstrap
+(DASH_ENABLE=true) or 'netcup-kube install dashboard'.
+
+Sub-commands:
+  token  - Print a login token for the admin ServiceAccount (created if missing)
+  url    - Print the dashboard URL (ingress host, Caddy host, or port-forward)
+  open   - Print a token and open the dashboard in the browser`,
+	SilenceUsage: true,
+}
+
+var dashboardTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Print a login token for the admin ServiceAccount",
+	Long: `Print a dashboard login token on stdout.
+
+The ServiceAccount (default admin-user) and a cluster-admin ClusterRoleBinding
+of the same name are created when missing. By default the token is short-lived
+(TokenRequest API, --duration). --long-lived reads the token from the
+<account>-token Secret instead, creating it if needed; --rotate recreates that
+Secret, which invalidates the previous long-lived token.
+
+Examples:
+  netcup-kube dashboard token
+  netcup-kube dashboard token --duration 8h
+  netcup-kube dashboard token --long-lived --rotate`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, kube, err := dashboardSetup(cmd)
+		if err != nil {
+			return err
+		}
+		token, err := dashboardToken(ctx, kube)
+		if err != nil {
+			return err
+		}
+		fmt.Println(token)
+		return nil
+	},
+}
+
+var dashboardURLCmd = &cobra.Command{
+	Use:   "url",
+	Short: "Print the dashboard URL",
+	Long: `Print the URL the dashboard is reachable at: the host of the dashboard
+ingress, then DASH_HOST (Caddy edge from bootstrap), and otherwise
+https://localhost:<port>/ through a port-forward ('dashboard open' starts it).
+
+Examples:
+  netcup-kube dashboard url`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, kube, err := dashboardSetup(cmd)
+		if err != nil {
+			return err
+		}
+		access, err := dashboard.ResolveAccess(ctx, kube, dashNamespace, cfg.Env["DASH_HOST"], dashLocalPort)
+		if err != nil {
+			return err
+		}
+		fmt.Println(access.URL)
+		if access.Via == "port-forward" {
+			fmt.Fprintf(os.Stderr, "No dashboard ingress or DASH_HOST; forward the proxy first:\n  kubectl %s\n", strings.Join(dashboard.PortForwardArgs(dashNamespace, dashLocalPort), " "))
+		}
+		return nil
+	},
+}
+
+var dashboardOpenCmd = &cobra.Command{
+	Use:   "open",
+	Short: "Print a token and open the dashboard in the browser",
+	Long: `Create a login token, print it, and open the dashboard URL in the browser.
+
+Without an ingress or DASH_HOST the Kong proxy is port-forwarded to
+localhost:<port> until interrupted (Ctrl-C). Paste the printed token into the
+login screen.
+
+Examples:
+  netcup-kube dashboard open
+  netcup-kube dashboard open --port 9443 --no-browser`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, kube, err := dashboardSetup(cmd)
+		if err != nil {
+			return err
+		}
+		access, err := dashboard.ResolveAccess(ctx, kube, dashNamespace, cfg.Env["DASH_HOST"], dashLocalPort)
+		if err != nil {
+			return err
+		}
+		token, err := dashboardToken(ctx, kube)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Token:\n%s\n\n", token)
+
+		if access.Via != "port-forward" {
+			fmt.Printf("URL: %s\n", access.URL)
+			return openDashboardURL(access.URL)
+		}
+
+		forward := interrupt.Command(ctx, "kubectl", dashboard.PortForwardArgs(dashNamespace, dashLocalPort)...)
+		forward.Stderr = os.Stderr
+		if err := forward.Start(); err != nil {
+			return fmt.Errorf("failed to start port-forward: %w", err)
+		}
+		if err := portforward.WaitReady(ctx, dashLocalPort, 15*time.Second); err != nil {
+			_ = forward.Process.Kill()
+			_ = forward.Wait()
+			return err
+		}
+		fmt.Printf("URL: %s (port-forward running, Ctrl-C to stop)\n", access.URL)
+		if err := openDashboardURL(access.URL); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		if err := forward.Wait(); err != nil && ctx.Err() == nil {
+			return fmt.Errorf("port-forward exited: %w", err)
+		}
+		return nil
+	},
+}
+
+func dashboardSetup(cmd *cobra.Command) (context.Context, dashboard.Kubectl, error) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	path, err := configFilePath()
+	if err != nil {
+		return nil, nil, err
+	}
+	kube, err := clusterKubectl(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, kube, nil
+}
+
+// dashboardToken ensures the ServiceAccount exists and returns a token for it
+func dashboardToken(ctx context.Context, kube dashboard.Kubectl) (string, error) {
+	if dashRotate && !dashLongLived {
+		return "", fmt.Errorf("--rotate applies to --long-lived tokens (short-lived tokens expire after --duration)")
+	}
+	created, err := dashboard.EnsureServiceAccount(ctx, kube, dashNamespace, dashServiceAccount)
+	if err != nil {
+		return "", err
+	}
+	for _, obj := range created {
+		fmt.Fprintf(os.Stderr, "Created %s\n", obj)
+	}
+	if dashLongLived {
+		if dashRotate {
+			fmt.Fprintf(os.Stderr, "Rotating secret %s/%s\n", dashNamespace, dashboard.TokenSecretName(dashServiceAccount))
+		}
+		return dashboard.LongLivedToken(ctx, kube, dashNamespace, dashServiceAccount, dashRotate, 30*time.Second)
+	}
+	return dashboard.CreateToken(ctx, kube, dashNamespace, dashServiceAccount, dashDuration)
+}
+
+// openDashboardURL opens url in the default browser unless --no-browser is set
+func openDashboardURL(url string) error {
+	if dashNoBrowser {
+		return nil
+	}
+	return openBrowser(url)
+}
+
+func init() {
+	dashboardCmd.PersistentFlags().StringVarP(&dashNamespace, "namespace", "n", dashboard.DefaultNamespace, "Dashboard namespace")
+	dashboardCmd.PersistentFlags().StringVar(&dashServiceAccount, "service-account", dashboard.DefaultServiceAccount, "ServiceAccount to issue tokens for (bound to cluster-admin)")
+	dashboardCmd.PersistentFlags().StringVar(&dashLocalPort, "port", dashboard.DefaultLocalPort, "Local port for port-forward access")
+	for _, c := range []*cobra.Command{dashboardTokenCmd, dashboardOpenCmd} {
+		c.Flags().DurationVar(&dashDuration, "duration", 24*time.Hour, "Lifetime of short-lived tokens")
+		c.Flags().BoolVar(&dashLongLived, "long-lived", false, "Use the non-expiring token Secret instead of a short-lived token")
+		c.Flags().BoolVar(&dashRotate, "rotate", false, "Recreate the long-lived token Secret, invalidating the old token")
+	}
+	dashboardOpenCmd.Flags().BoolVar(&dashNoBrowser, "no-browser", false, "Only print the token and URL")
+	dashboardCmd.AddCommand(dashboardTokenCmd)
+	dashboardCmd.AddCommand(dashboardURLCmd)
+	dashboardCmd.AddCommand(dashboardOpenCmd)
+}
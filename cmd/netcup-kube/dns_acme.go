@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/dnscheck"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+const caddyfilePath = "/etc/caddy/Caddyfile"
+
+var (
+	debugACMEHost    string
+	debugACMEExpect  []string
+	debugACMESince   time.Duration
+	debugACMETimeout time.Duration
+	debugACMENoLogs  bool
+	debugACMEOutput  string
+)
+
+var dnsDebugACMECmd = &cobra.Command{
+	Use:   "debug-acme",
+	Short: "Explain why Caddy fails to obtain a certificate for a host",
+	Long: `Troubleshoot certificate issuance for one hostname:
+
+  1. DNS: does the host resolve to the node on public resolvers?
+  2. Reachability: are ports 80 and 443 reachable from this machine, and
+     which certificate is served on 443?
+  3. Caddy log: ACME errors for the host from "journalctl -u caddy", read
+     locally on the node or over SSH from MGMT_HOST
+
+The findings are summarized as likely causes (wrong DNS, closed firewall,
+rate limit, netcup DNS API credentials, ...). Run it from outside the
+server's network: probes from the node itself may succeed through hairpin
+NAT although the CA cannot connect. Exits non-zero unless a valid
+certificate is served and nothing looks wrong.
+
+Examples:
+  netcup-kube dns debug-acme --host kube.example.com
+  netcup-kube dns debug-acme --host kube.example.com --since 24h -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(debugACMEOutput)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		host := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(debugACMEHost)), ".")
+		if host == "" {
+			host = strings.ToLower(cfg.Env["BASE_DOMAIN"])
+		}
+		if host == "" {
+			return fmt.Errorf("--host is required (or set BASE_DOMAIN)")
+		}
+		expected, err := dnsCheckExpected(ctx, cfg.Env, debugACMEExpect)
+		if err != nil {
+			return err
+		}
+
+		r := &dnscheck.ACMEReport{Host: host, CertMode: cfg.Env["CADDY_CERT_MODE"]}
+		if r.CertMode == "" {
+			r.CertMode = "dns01_wildcard"
+		}
+		dns := dnscheck.Check(ctx, []dnscheck.Host{{Name: host, Query: host}}, expected, dnscheck.DefaultResolvers, dnscheck.NewLookup(debugACMETimeout))
+		r.DNS = dns.Hosts[0]
+		r.HTTP = dnscheck.ProbeHTTP(ctx, host, debugACMETimeout)
+		r.HTTPS, r.Certificate = dnscheck.ProbeTLS(ctx, host, debugACMETimeout)
+		if !debugACMENoLogs {
+			logs, source, err := caddyJournal(debugACMESince)
+			r.LogSource = source
+			if err != nil {
+				r.LogError = err.Error()
+			} else {
+				r.Events = dnscheck.ParseCaddyLog(logs, host)
+			}
+		}
+		dnscheck.Diagnose(r)
+
+		if format == output.FormatJSON {
+			if err := output.WriteJSON(os.Stdout, r); err != nil {
+				return err
+			}
+		} else {
+			printACMEReport(os.Stdout, r)
+		}
+		if !r.OK() {
+			return fmt.Errorf("certificate issuance for %s needs attention", host)
+		}
+		return nil
+	},
+}
+
+// caddyJournal reads Caddy's journal of the last since: locally when this is
+// the edge node, otherwise over SSH from MGMT_HOST
+func caddyJournal(since time.Duration) ([]byte, string, error) {
+	args := []string{"-u", "caddy", "--since", fmt.Sprintf("-%dmin", int(since.Minutes())), "--no-pager", "-o", "cat"}
+	if _, err := os.Stat(caddyfilePath); err == nil {
+		out, err := exec.Command("journalctl", args...).Output()
+		if err != nil {
+			return nil, "local journalctl", fmt.Errorf("failed to read the Caddy journal: %w", err)
+		}
+		return out, "local journalctl", nil
+	}
+
+	rc := remote.NewConfig()
+	if err := rc.LoadConfigFromEnv(envFile); err != nil {
+		return nil, "", fmt.Errorf("failed to load config: %w", err)
+	}
+	if rc.Host == "" {
+		return nil, "", fmt.Errorf("no host configured (set MGMT_HOST)")
+	}
+	source := fmt.Sprintf("ssh %s@%s", rc.User, rc.Host)
+	out, err := remote.NewSSHClient(rc.Host, rc.User).OutputCommand("sudo -n journalctl "+strings.Join(args, " "), nil)
+	if err != nil {
+		return nil, source, fmt.Errorf("failed to read the Caddy journal: %w", err)
+	}
+	return out, source, nil
+}
+
+func printACMEReport(out io.Writer, r *dnscheck.ACMEReport) {
+	_, _ = fmt.Fprintf(out, "Host: %s (cert mode %s)\n\n", r.Host, r.CertMode)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CHECK\tRESULT")
+	_, _ = fmt.Fprintf(w, "dns\t%s\n", r.DNS.Status)
+	for _, p := range []dnscheck.PortProbe{r.HTTP, r.HTTPS} {
+		if p.Reachable {
+			_, _ = fmt.Fprintf(w, "port %d\treachable, %s\n", p.Port, p.Detail)
+		} else {
+			_, _ = fmt.Fprintf(w, "port %d\tunreachable: %s\n", p.Port, p.Error)
+		}
+	}
+	if c := r.Certificate; c != nil {
+		state := "valid"
+		if !c.Valid {
+			state = "invalid: " + c.Error
+		}
+		_, _ = fmt.Fprintf(w, "certificate\t%s, issuer %s, expires %s\n", state, c.Issuer, c.NotAfter)
+	}
+	_ = w.Flush()
+
+	switch {
+	case r.LogError != "":
+		_, _ = fmt.Fprintf(out, "\nCaddy log (%s): %s\n", r.LogSource, r.LogError)
+	case r.LogSource != "":
+		_, _ = fmt.Fprintf(out, "\nCaddy log (%s): %d ACME entries for %s\n", r.LogSource, len(r.Events), r.Host)
+		for _, e := range r.Events {
+			line := fmt.Sprintf("  %s %-5s %s", e.Time, e.Level, e.Message)
+			if e.Error != "" {
+				line += ": " + e.Error
+			}
+			_, _ = fmt.Fprintln(out, line)
+		}
+	}
+
+	_, _ = fmt.Fprintln(out)
+	if len(r.Causes) == 0 {
+		if r.OK() {
+			_, _ = fmt.Fprintln(out, "No problems found; a valid certificate is served.")
+		} else {
+			_, _ = fmt.Fprintln(out, "No obvious cause found; inspect the full log with: journalctl -u caddy")
+		}
+		return
+	}
+	_, _ = fmt.Fprintln(out, "Likely causes:")
+	for i, c := range r.Causes {
+		_, _ = fmt.Fprintf(out, "  %d. %s\n", i+1, c)
+	}
+}
+
+func init() {
+	dnsDebugACMECmd.Flags().StringVar(&debugACMEHost, "host", "", "Hostname to troubleshoot (default: BASE_DOMAIN)")
+	dnsDebugACMECmd.Flags().StringArrayVar(&debugACMEExpect, "expect", nil, "Expected address (repeatable; default: NODE_EXTERNAL_IP and NODE_EXTERNAL_IP_V6, else MGMT_HOST)")
+	dnsDebugACMECmd.Flags().DurationVar(&debugACMESince, "since", 6*time.Hour, "How far back to read the Caddy log")
+	dnsDebugACMECmd.Flags().DurationVar(&debugACMETimeout, "timeout", 5*time.Second, "Timeout per DNS query and connection")
+	dnsDebugACMECmd.Flags().BoolVar(&debugACMENoLogs, "no-logs", false, "Skip reading the Caddy log")
+	dnsDebugACMECmd.Flags().StringVarP(&debugACMEOutput, "output", "o", "text", "Output format: text or json")
+	dnsCmd.AddCommand(dnsDebugACMECmd)
+}
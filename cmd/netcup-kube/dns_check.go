@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/dnscheck"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dnsCheckHosts     []string
+	dnsCheckExpect    []string
+	dnsCheckResolvers []string
+	dnsCheckTimeout   time.Duration
+	dnsCheckOutput    string
+)
+
+var dnsCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that the edge hostnames resolve to the node on public resolvers",
+	Long: `Resolve BASE_DOMAIN, its wildcard (DNS-01 mode), CADDY_HTTP01_HOSTS,
+DASH_HOST, OPENCLAW_HOST, and any --host on several public resolvers and
+verify that they point at the node.
+
+The expected addresses are --expect, else NODE_EXTERNAL_IP and
+NODE_EXTERNAL_IP_V6, else MGMT_HOST (resolved if it is a hostname). A and
+AAAA records are checked alike: an AAAA record pointing elsewhere breaks
+issuance because Let's Encrypt prefers IPv6. Per host the status is:
+
+  resolved     every resolver returns exactly the expected addresses
+  propagating  some resolvers do, others still return old or no records
+  wrong        a record exists but points elsewhere
+  incomplete   a record of an expected family (usually AAAA) is missing
+  missing      no resolver knows the record
+
+Run it before "netcup-kube dns" so certificate issuance does not fail (and
+burn ACME rate limits) on records that have not propagated yet. Exits
+non-zero unless every host is resolved.
+
+Examples:
+  netcup-kube dns check
+  netcup-kube dns check --host api.example.com --expect 203.0.113.10
+  netcup-kube dns check --resolvers 1.1.1.1,208.67.222.222 -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(dnsCheckOutput)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		hosts := dnscheck.Hosts(cfg.Env, dnsCheckHosts)
+		if len(hosts) == 0 {
+			return fmt.Errorf("no hostnames to check (set BASE_DOMAIN or pass --host)")
+		}
+		expected, err := dnsCheckExpected(ctx, cfg.Env, dnsCheckExpect)
+		if err != nil {
+			return err
+		}
+		resolvers := dnsCheckResolvers
+		if len(resolvers) == 0 {
+			resolvers = dnscheck.DefaultResolvers
+		}
+
+		result := dnscheck.Check(ctx, hosts, expected, resolvers, dnscheck.NewLookup(dnsCheckTimeout))
+		if format == output.FormatJSON {
+			if err := output.WriteJSON(os.Stdout, result); err != nil {
+				return err
+			}
+		} else {
+			printDNSCheck(os.Stdout, result)
+		}
+		if !result.OK() {
+			return fmt.Errorf("%d of %d hostnames do not resolve to %s on all resolvers", dnsCheckFailures(result), len(result.Hosts), strings.Join(expected, ", "))
+		}
+		return nil
+	},
+}
+
+// dnsCheckExpected returns the addresses the hostnames must resolve to:
+// the --expect flags, else NODE_EXTERNAL_IP and NODE_EXTERNAL_IP_V6, else
+// MGMT_HOST
+func dnsCheckExpected(ctx context.Context, env map[string]string, flags []string) ([]string, error) {
+	candidates := flags
+	if len(candidates) == 0 {
+		for _, key := range []string{"NODE_EXTERNAL_IP", "NODE_EXTERNAL_IP_V6"} {
+			if v := strings.TrimSpace(env[key]); v != "" {
+				candidates = append(candidates, v)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		if v := strings.TrimSpace(env["MGMT_HOST"]); v != "" {
+			candidates = []string{v}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no expected address (pass --expect or set NODE_EXTERNAL_IP or MGMT_HOST)")
+	}
+
+	var expected []string
+	for _, c := range candidates {
+		if ip := net.ParseIP(c); ip != nil {
+			expected = append(expected, ip.String())
+			continue
+		}
+		addrs, err := net.DefaultResolver.LookupHost(ctx, c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve expected address %s: %w", c, err)
+		}
+		expected = append(expected, addrs...)
+	}
+	return expected, nil
+}
+
+func dnsCheckFailures(result dnscheck.Result) int {
+	n := 0
+	for _, h := range result.Hosts {
+		if h.Status != dnscheck.HostResolved {
+			n++
+		}
+	}
+	return n
+}
+
+func printDNSCheck(out io.Writer, result dnscheck.Result) {
+	_, _ = fmt.Fprintf(out, "Expected: %s\n\n", strings.Join(result.Expected, ", "))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "HOST\tSTATUS\t%s\n", strings.Join(result.Resolvers, "\t"))
+	for _, h := range result.Hosts {
+		cells := []string{h.Name, h.Status}
+		for _, a := range h.Answers {
+			cells = append(cells, dnsCheckCell(a))
+		}
+		_, _ = fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	_ = w.Flush()
+}
+
+// dnsCheckCell shows what a resolver returned when it is not the expected
+// address, so stale records are visible at a glance
+func dnsCheckCell(a dnscheck.Answer) string {
+	switch a.Status {
+	case dnscheck.StatusWrong:
+		return "wrong (" + strings.Join(a.Addresses, ",") + ")"
+	case dnscheck.StatusError:
+		return "error"
+	}
+	return a.Status
+}
+
+func init() {
+	dnsCheckCmd.Flags().StringArrayVar(&dnsCheckHosts, "host", nil, "Additional hostname to check (repeatable; *.domain checks a wildcard)")
+	dnsCheckCmd.Flags().StringArrayVar(&dnsCheckExpect, "expect", nil, "Expected address (repeatable; default: NODE_EXTERNAL_IP and NODE_EXTERNAL_IP_V6, else MGMT_HOST)")
+	dnsCheckCmd.Flags().StringSliceVar(&dnsCheckResolvers, "resolvers", nil, "Comma-separated resolver IPs (default: "+strings.Join(dnscheck.DefaultResolvers, ",")+")")
+	dnsCheckCmd.Flags().DurationVar(&dnsCheckTimeout, "timeout", 5*time.Second, "Timeout per DNS query")
+	dnsCheckCmd.Flags().StringVarP(&dnsCheckOutput, "output", "o", "text", "Output format: text or json")
+	dnsCmd.AddCommand(dnsCheckCmd)
+}
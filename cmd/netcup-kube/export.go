@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/mfittko/netcup-kube/internal/export"
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportGitDir         string
+	exportIncludeSecrets bool
+	exportArgoCDRepo     string
+	exportArgoCDRevision string
+	exportOutput         string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export --git-dir <path>",
+	Short: "Export recipes, Traefik, Caddy, quotas and config as files for Git/Argo CD",
+	Long: `Render the state netcup-kube manages into a directory to commit to Git:
+
+  releases/<namespace>/<release>/  Helm release metadata and user-supplied values
+  manifests/traefik/               Traefik HelmChartConfig
+  manifests/quotas/                netcup-kube ResourceQuotas and LimitRanges
+  caddy/                           Caddyfile of the edge node and its site addresses
+  config/netcup-kube.env           the env file
+  argocd/                          Argo CD Applications (with --argocd-repo)
+
+Secret-like values (passwords, tokens, API keys) in Helm values and the env
+file are replaced by <redacted> unless --include-secrets is given. Re-running
+the export refreshes the files and removes those of releases that are gone;
+other files in the directory are left alone. Sections that cannot be read
+(e.g. no helm binary, Caddy host unreachable) are listed as warnings.
+
+With --argocd-repo, an Application per release installs its chart from the
+local Helm repository it was found in, with values from the exported
+values.yaml, and one Application syncs manifests/.
+
+Examples:
+  netcup-kube export --git-dir ../cluster-state
+  netcup-kube export --git-dir ../cluster-state --argocd-repo https://github.com/acme/cluster-state.git
+  netcup-kube export --git-dir ../cluster-state -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(exportOutput)
+		if err != nil {
+			return err
+		}
+		if exportGitDir == "" {
+			return fmt.Errorf("--git-dir is required")
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		kube, err := clusterKubectl(ctx, path)
+		if err != nil {
+			return err
+		}
+
+		src := export.Sources{Kubectl: kube, Caddyfile: readCaddyfile}
+		if _, err := exec.LookPath("helm"); err == nil {
+			// clusterKubectl exported KUBECONFIG, which helm reads as well
+			src.Helm = kubectl.New(kubectl.WithBinary("helm"))
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			src.EnvFile = string(data)
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		summary, err := export.Export(ctx, src, export.Options{
+			IncludeSecrets: exportIncludeSecrets,
+			ArgoCDRepo:     exportArgoCDRepo,
+			ArgoCDRevision: exportArgoCDRevision,
+		}, exportGitDir)
+		if err != nil {
+			return err
+		}
+		if format == output.FormatJSON {
+			return output.WriteJSON(os.Stdout, summary)
+		}
+		fmt.Printf("Exported %d Helm release(s) and %d file(s) to %s\n", len(summary.Releases), len(summary.Files), exportGitDir)
+		if summary.Redacted > 0 {
+			fmt.Printf("Redacted %d secret-like Helm value(s); use --include-secrets to keep them\n", summary.Redacted)
+		}
+		for _, w := range summary.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		return nil
+	},
+}
+
+// readCaddyfile reads the Caddyfile locally when this is the edge node,
+// otherwise over SSH from MGMT_HOST
+func readCaddyfile() ([]byte, error) {
+	if data, err := os.ReadFile(caddyfilePath); err == nil {
+		return data, nil
+	}
+	rc := remote.NewConfig()
+	if err := rc.LoadConfigFromEnv(envFile); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if rc.Host == "" {
+		return nil, fmt.Errorf("no host configured (set MGMT_HOST)")
+	}
+	out, err := remote.NewSSHClient(rc.Host, rc.User).OutputCommand("sudo -n cat "+caddyfilePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ssh %s@%s: %w", rc.User, rc.Host, err)
+	}
+	return out, nil
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportGitDir, "git-dir", "", "Directory to write the export to (e.g. a Git checkout)")
+	exportCmd.Flags().BoolVar(&exportIncludeSecrets, "include-secrets", false, "Keep secret-like values instead of redacting them")
+	exportCmd.Flags().StringVar(&exportArgoCDRepo, "argocd-repo", "", "Git URL of the export; writes Argo CD Applications")
+	exportCmd.Flags().StringVar(&exportArgoCDRevision, "argocd-revision", "HEAD", "Git revision the Argo CD Applications track")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "text", "Output format: text or json")
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/guardrail"
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/remote"
+)
+
+// heavyRecipes pull large images or provision sizable volumes, so installing
+// them is guarded by the resource preflight.
+var heavyRecipes = map[string]bool{
+	"argo-cd":               true,
+	"kube-prometheus-stack": true,
+	"openclaw":              true,
+	"postgres":              true,
+	"zeroclaw":              true,
+}
+
+// checkResourceGuardrails probes a node and compares its headroom against the
+// PREFLIGHT_* thresholds. Violations fail the operation in enforce mode and
+// are printed as warnings in warn mode or during a dry run. A failing probe
+// only warns: the guardrail must not block operations on hosts it cannot measure.
+func checkResourceGuardrails(target string, probe func() (guardrail.Usage, error)) error {
+	thresholds, err := guardrail.ThresholdsFromEnv(cfg.Env)
+	if err != nil {
+		return err
+	}
+	if thresholds.Mode == guardrail.ModeOff {
+		return nil
+	}
+
+	usage, err := probe()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: resource preflight for %s skipped: %v\n", target, err)
+		return nil
+	}
+	fmt.Printf("[preflight] %s: %s\n", target, usage.Summary())
+
+	violations := guardrail.Check(usage, thresholds)
+	if len(violations) == 0 {
+		return nil
+	}
+	if thresholds.Mode == guardrail.ModeEnforce && !isDryRun() {
+		return fmt.Errorf("resource preflight failed for %s:\n  - %s\nFree up space or memory, lower the thresholds, or set PREFLIGHT_MODE=warn to continue anyway",
+			target, strings.Join(violations, "\n  - "))
+	}
+	for _, v := range violations {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", target, v)
+	}
+	return nil
+}
+
+// localResourceProbe measures the machine netcup-kube runs on (bootstrap, join)
+func localResourceProbe() (guardrail.Usage, error) {
+	out, err := exec.Command("sh", "-c", guardrail.ProbeScript).Output()
+	if err != nil {
+		return guardrail.Usage{}, fmt.Errorf("failed to probe local resources: %w", err)
+	}
+	return guardrail.ParseProbe(out)
+}
+
+// nodeResourceProbe measures an inventory node over SSH, falling back to
+// MGMT_HOST/MGMT_USER from the env file.
+func nodeResourceProbe(envPath string, node inventory.Node) func() (guardrail.Usage, error) {
+	return func() (guardrail.Usage, error) {
+		rc := remote.NewConfig()
+		applyInventoryNode(rc, node)
+		if err := rc.LoadConfigFromEnv(envPath); err != nil {
+			return guardrail.Usage{}, fmt.Errorf("failed to load config: %w", err)
+		}
+		if rc.Host == "" {
+			return guardrail.Usage{}, fmt.Errorf("no host configured (set MGMT_HOST)")
+		}
+		return remote.ProbeResources(remote.NewSSHClient(rc.Host, rc.User))
+	}
+}
+
+// checkRecipeGuardrails runs the resource preflight before installing a heavy
+// recipe: locally on the server, otherwise over SSH against MGMT_HOST.
+func checkRecipeGuardrails(recipe string, recipeArgs []string, envFile string) error {
+	if !heavyRecipes[recipe] {
+		return nil
+	}
+	for _, arg := range recipeArgs {
+		if arg == "--uninstall" {
+			return nil
+		}
+	}
+	if _, err := os.Stat(serverKubeconfigPath); err == nil {
+		return checkResourceGuardrails("local node", localResourceProbe)
+	}
+	return checkResourceGuardrails("management node", nodeResourceProbe(envFile, inventory.Node{}))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/guardrail"
+)
+
+func TestCheckResourceGuardrails(t *testing.T) {
+	prev := cfg
+	defer func() { cfg = prev }()
+
+	low := func() (guardrail.Usage, error) {
+		return guardrail.Usage{Path: "/var/lib", DiskFree: 1 << 30, MemAvailable: 4 << 30}, nil
+	}
+
+	cfg = config.New()
+	err := checkResourceGuardrails("mgmt", low)
+	if err == nil || !strings.Contains(err.Error(), "PREFLIGHT_MIN_DISK_FREE") {
+		t.Errorf("enforce: error = %v", err)
+	}
+
+	cfg.SetFlag("DRY_RUN", "true")
+	if err := checkResourceGuardrails("mgmt", low); err != nil {
+		t.Errorf("dry-run: error = %v, want warning only", err)
+	}
+
+	cfg = config.New()
+	cfg.SetFlag("PREFLIGHT_MODE", "warn")
+	if err := checkResourceGuardrails("mgmt", low); err != nil {
+		t.Errorf("warn: error = %v", err)
+	}
+
+	cfg = config.New()
+	cfg.SetFlag("PREFLIGHT_MODE", "off")
+	called := false
+	if err := checkResourceGuardrails("mgmt", func() (guardrail.Usage, error) { called = true; return low() }); err != nil || called {
+		t.Errorf("off: error = %v, probed = %v", err, called)
+	}
+
+	cfg = config.New()
+	failing := func() (guardrail.Usage, error) { return guardrail.Usage{}, errors.New("ssh: connection refused") }
+	if err := checkResourceGuardrails("mgmt", failing); err != nil {
+		t.Errorf("probe failure: error = %v, want warning only", err)
+	}
+
+	if err := checkRecipeGuardrails("redis", nil, ""); err != nil {
+		t.Errorf("light recipe: error = %v", err)
+	}
+	if err := checkRecipeGuardrails("openclaw", []string{"--uninstall"}, ""); err != nil {
+		t.Errorf("uninstall: error = %v", err)
+	}
+}
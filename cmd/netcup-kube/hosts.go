@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/dnscheck"
+	"github.com/mfittko/netcup-kube/internal/hostsfile"
+	"github.com/mfittko/netcup-kube/internal/validation"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hostsFile      string
+	hostsIP        string
+	hostsLocalhost bool
+	hostsYes       bool
+	hostsAll       bool
+	hostsExtra     []string
+	hostsIngress   bool
+)
+
+var hostsCmd = &cobra.Command{
+	Use:   "hosts",
+	Short: "Manage /etc/hosts entries for the cluster's hostnames on this machine",
+	Long: `Manage local /etc/hosts entries for the hostnames of the recipes, e.g.
+to reach them before DNS points at the node or through local port-forwards.
+
+Entries live in a block between marker comments:
+
+  ` + hostsfile.BeginMarker + `
+  203.0.113.10	claw.example.com
+  ` + hostsfile.EndMarker + `
+
+Lines outside the block are never changed. Writes are guarded: the file is
+rewritten in place only if it did not change since it was read, a copy is
+kept as <file>` + hostsfile.BackupSuffix + `, and when the file is not writable
+(as /etc/hosts for regular users) the write goes through sudo after
+confirmation. --dry-run prints the resulting block only.
+
+Entries point at --ip, at 127.0.0.1 with --localhost (for port-forwards), or
+by default at the node: NODE_EXTERNAL_IP, else MGMT_HOST (resolved).
+
+Sub-commands:
+  add     - Add or update entries for hostnames
+  remove  - Remove entries of hostnames (--all clears the block)
+  sync    - Replace the block with the recipe hostnames of the config`,
+	SilenceUsage: true,
+}
+
+var hostsAddCmd = &cobra.Command{
+	Use:   "add <hostname>...",
+	Short: "Add or update managed entries for hostnames",
+	Long: `Add entries for the given hostnames to the managed block, replacing
+entries of the same hostnames.
+
+Examples:
+  netcup-kube hosts add claw.example.com
+  netcup-kube hosts add grafana.example.com --ip 10.10.0.1
+  netcup-kube hosts add dash.example.com --localhost --dry-run`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ip, err := hostsAddress(cmd.Context())
+		if err != nil {
+			return err
+		}
+		entries, err := hostsEntries(args, ip)
+		if err != nil {
+			return err
+		}
+		return updateHostsFile(func(f *hostsfile.File) {
+			f.Add(entries)
+			for _, e := range entries {
+				fmt.Printf("%s -> %s\n", e.Host, e.IP)
+			}
+		})
+	},
+}
+
+var hostsRemoveCmd = &cobra.Command{
+	Use:   "remove [hostname...]",
+	Short: "Remove managed entries of hostnames",
+	Long: `Remove the entries of the given hostnames from the managed block, or
+all of them with --all (the block is removed when it gets empty).
+
+Examples:
+  netcup-kube hosts remove claw.example.com
+  netcup-kube hosts remove --all`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if hostsAll == (len(args) > 0) {
+			return fmt.Errorf("pass hostnames or --all")
+		}
+		return updateHostsFile(func(f *hostsfile.File) {
+			if hostsAll {
+				fmt.Printf("removing %d managed entries\n", len(f.Entries))
+				f.Set(nil)
+				return
+			}
+			removed := f.Remove(args)
+			for _, host := range removed {
+				fmt.Printf("removed %s\n", host)
+			}
+			if len(removed) < len(args) {
+				fmt.Fprintf(os.Stderr, "warning: %d hostname(s) had no managed entry\n", len(args)-len(removed))
+			}
+		})
+	},
+}
+
+var hostsSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Replace the managed entries with the recipe hostnames",
+	Long: `Replace the managed block with entries for BASE_DOMAIN,
+CADDY_HTTP01_HOSTS, DASH_HOST, OPENCLAW_HOST and any --host, plus the hosts
+of the cluster's Ingress routes with --ingress. Wildcard hostnames cannot be
+listed in a hosts file and are skipped. Entries of hostnames no longer
+configured are removed.
+
+Examples:
+  netcup-kube hosts sync
+  netcup-kube hosts sync --ingress --dry-run
+  netcup-kube hosts sync --localhost --host api.example.com`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		var names []string
+		for _, h := range dnscheck.Hosts(cfg.Env, hostsExtra) {
+			names = append(names, h.Name)
+		}
+		if hostsIngress {
+			routes, err := ingressRoutes(ctx)
+			if err != nil {
+				return err
+			}
+			for _, r := range routes {
+				names = append(names, r.Host)
+			}
+		}
+		names = hostsNames(names)
+		if len(names) == 0 {
+			return fmt.Errorf("no hostnames to sync (set BASE_DOMAIN or pass --host)")
+		}
+		ip, err := hostsAddress(ctx)
+		if err != nil {
+			return err
+		}
+		entries, err := hostsEntries(names, ip)
+		if err != nil {
+			return err
+		}
+		return updateHostsFile(func(f *hostsfile.File) {
+			f.Set(entries)
+		})
+	},
+}
+
+// hostsNames drops wildcard and duplicate hostnames, which a hosts file
+// cannot resolve
+func hostsNames(names []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(name), "."))
+		if name == "" || strings.Contains(name, "*") || seen[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+	}
+	return out
+}
+
+func hostsEntries(names []string, ip string) ([]hostsfile.Entry, error) {
+	var entries []hostsfile.Entry
+	for _, name := range names {
+		if err := validation.Hostname("hostname", name); err != nil {
+			return nil, err
+		}
+		entries = append(entries, hostsfile.Entry{IP: ip, Host: name})
+	}
+	return entries, nil
+}
+
+// hostsAddress returns the address entries point at: --ip, 127.0.0.1 with
+// --localhost, else the node's (IPv4 preferred)
+func hostsAddress(ctx context.Context) (string, error) {
+	if hostsLocalhost && hostsIP != "" {
+		return "", fmt.Errorf("--ip and --localhost are mutually exclusive")
+	}
+	if hostsLocalhost {
+		return "127.0.0.1", nil
+	}
+	if hostsIP != "" {
+		ip := net.ParseIP(hostsIP)
+		if ip == nil {
+			return "", fmt.Errorf("invalid --ip %q", hostsIP)
+		}
+		return ip.String(), nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	addrs, err := dnsCheckExpected(ctx, cfg.Env, nil)
+	if err != nil {
+		return "", fmt.Errorf("no node address (pass --ip or --localhost, or set NODE_EXTERNAL_IP or MGMT_HOST)")
+	}
+	for _, a := range addrs {
+		if ip := net.ParseIP(a); ip != nil && ip.To4() != nil {
+			return a, nil
+		}
+	}
+	return addrs[0], nil
+}
+
+// updateHostsFile applies change to the managed block of --file and writes
+// the result unless nothing changed or --dry-run
+func updateHostsFile(change func(f *hostsfile.File)) error {
+	original, err := os.ReadFile(hostsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", hostsFile, err)
+	}
+	f, err := hostsfile.Parse(string(original))
+	if err != nil {
+		return fmt.Errorf("%s: %w", hostsFile, err)
+	}
+	change(f)
+	content := f.String()
+	if content == string(original) {
+		fmt.Printf("%s is up to date\n", hostsFile)
+		return nil
+	}
+	printHostsEntries(os.Stdout, f.Entries)
+	if isDryRun() {
+		fmt.Printf("dry-run: would write %d managed entries to %s\n", len(f.Entries), hostsFile)
+		return nil
+	}
+
+	sudo := func(stdin []byte, args ...string) error {
+		if err := confirm.New(hostsYes).Confirm(fmt.Sprintf("write %s with sudo", hostsFile)); err != nil {
+			return err
+		}
+		hostsYes = true
+		return hostsfile.ExecSudo(stdin, args...)
+	}
+	if err := hostsfile.Write(hostsFile, original, []byte(content), sudo); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d managed entries to %s (previous version: %s%s)\n", len(f.Entries), hostsFile, hostsFile, hostsfile.BackupSuffix)
+	return nil
+}
+
+func printHostsEntries(out io.Writer, entries []hostsfile.Entry) {
+	if len(entries) == 0 {
+		_, _ = fmt.Fprintln(out, "No managed entries.")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "HOST\tADDRESS")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", e.Host, e.IP)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	hostsCmd.PersistentFlags().StringVar(&hostsFile, "file", hostsfile.DefaultPath, "Hosts file to manage")
+	hostsCmd.PersistentFlags().BoolVarP(&hostsYes, "yes", "y", false, "Write with sudo without asking for confirmation")
+	for _, c := range []*cobra.Command{hostsAddCmd, hostsSyncCmd} {
+		c.Flags().StringVar(&hostsIP, "ip", "", "Address the hostnames point at (default: NODE_EXTERNAL_IP, else MGMT_HOST)")
+		c.Flags().BoolVar(&hostsLocalhost, "localhost", false, "Point the hostnames at 127.0.0.1, for local port-forwards")
+	}
+	hostsRemoveCmd.Flags().BoolVar(&hostsAll, "all", false, "Remove every managed entry")
+	hostsSyncCmd.Flags().StringArrayVar(&hostsExtra, "host", nil, "Additional hostname (repeatable)")
+	hostsSyncCmd.Flags().BoolVar(&hostsIngress, "ingress", false, "Also add the hosts of the cluster's Ingress routes")
+	hostsCmd.AddCommand(hostsAddCmd)
+	hostsCmd.AddCommand(hostsRemoveCmd)
+	hostsCmd.AddCommand(hostsSyncCmd)
+}
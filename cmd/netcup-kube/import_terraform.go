@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/clusters"
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/terraform"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tfImportMaps      []string
+	tfImportName      string
+	tfImportNoCluster bool
+)
+
+var importTerraformCmd = &cobra.Command{
+	Use:   "import-terraform <state-or-outputs-file>",
+	Short: "Populate the env file and cluster registry from Terraform/OpenTofu outputs",
+	Long: `Read the outputs of a Terraform or OpenTofu state file (terraform.tfstate),
+or the JSON of 'terraform output -json' ('-' reads stdin), and write them to
+the env file and the cluster registry, so bootstrap can follow provisioning.
+
+Outputs are matched by name unless --map says otherwise:
+
+  MGMT_HOST          mgmt_host, management_host, server_hostname, hostname, fqdn
+  MGMT_IP            mgmt_ip, management_ip, server_ip, server_ipv4, ipv4_address, public_ip, ip
+  MGMT_USER          mgmt_user, ssh_user, admin_user
+  NODE_IP            node_ip, private_ip, server_private_ip
+  BASE_DOMAIN        base_domain, dns_zone, zone, domain
+  SSH_IDENTITY_FILE  ssh_private_key_path, ssh_key_path, private_key_path, ssh_key_file
+  cluster name       cluster_name, name
+
+A list output contributes its first element. Values are validated like
+'config set'. The cluster (name from --name or the cluster_name output) is
+added to or updated in the registry with its host, user, env file and
+SSH_IDENTITY_FILE; use --no-cluster to only update the env file. With
+--dry-run the settings are printed and nothing is written.
+
+Examples:
+  netcup-kube import-terraform infra/terraform.tfstate --name prod
+  tofu output -json | netcup-kube import-terraform - --map edge_ip=MGMT_IP --map zone_name=BASE_DOMAIN
+  netcup-kube import-terraform outputs.json --no-cluster --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := readTerraformInput(args[0], cmd.InOrStdin())
+		if err != nil {
+			return err
+		}
+		outputs, err := terraform.Parse(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		res, err := terraform.Map(outputs, tfImportMaps)
+		if err != nil {
+			return err
+		}
+
+		var pairs []string
+		for _, a := range res.Assignments {
+			if a.Var == terraform.ClusterName || a.Var == "SSH_IDENTITY_FILE" {
+				continue
+			}
+			if err := config.ValidateValue(a.Var, a.Value); err != nil {
+				return fmt.Errorf("output %s: %w", a.Output, err)
+			}
+			pairs = append(pairs, a.Var, a.Value)
+		}
+		if len(res.Assignments) == 0 {
+			return fmt.Errorf("no known outputs in %s (outputs: %s); use --map OUTPUT=VAR", args[0], orDash(strings.Join(res.Unmapped, ", ")))
+		}
+
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		cluster, clusterNote := terraformCluster(res, path)
+
+		for _, a := range res.Assignments {
+			fmt.Printf("%s=%s (from %s)\n", a.Var, a.Value, a.Output)
+		}
+		for _, w := range res.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+		}
+		if len(res.Unmapped) > 0 {
+			fmt.Printf("Not imported: %s\n", strings.Join(res.Unmapped, ", "))
+		}
+		if isDryRun() {
+			fmt.Println("Dry run: nothing was written.")
+			return nil
+		}
+
+		if len(pairs) > 0 {
+			if err := setConfigValues(path, ".netcup-kube-terraform-*.env", pairs...); err != nil {
+				return err
+			}
+		}
+		if cluster == nil {
+			fmt.Println(clusterNote)
+			return nil
+		}
+		return registerCluster(clusters.Path(), *cluster)
+	},
+}
+
+func readTerraformInput(name string, stdin io.Reader) ([]byte, error) {
+	if name == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return data, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return data, nil
+}
+
+// terraformCluster returns the registry entry for the imported settings, or
+// nil and the reason none is written
+func terraformCluster(res terraform.Result, envPath string) (*clusters.Cluster, string) {
+	identity, hasIdentity := res.Value("SSH_IDENTITY_FILE")
+	if tfImportNoCluster {
+		if hasIdentity {
+			return nil, fmt.Sprintf("Cluster registry not updated (--no-cluster); export SSH_IDENTITY_FILE=%s to use the imported key.", identity)
+		}
+		return nil, "Cluster registry not updated (--no-cluster)."
+	}
+	name := tfImportName
+	if name == "" {
+		name, _ = res.Value(terraform.ClusterName)
+	}
+	if name == "" {
+		return nil, "Cluster registry not updated: no cluster name (use --name or a cluster_name output)."
+	}
+
+	c := clusters.Cluster{Name: name, Vars: map[string]string{}}
+	// The default env file needs no env_file entry
+	if envPath != defaultEnvFile() && envPath != filepath.Join(envFileDir(), "netcup-kube.env") {
+		c.EnvFile = envPath
+	}
+	if c.Host, _ = res.Value("MGMT_HOST"); c.Host == "" {
+		c.Host, _ = res.Value("MGMT_IP")
+	}
+	c.User, _ = res.Value("MGMT_USER")
+	if hasIdentity {
+		c.Vars["SSH_IDENTITY_FILE"] = identity
+	}
+	return &c, ""
+}
+
+// registerCluster adds c to the registry at path, appending to an existing
+// file so its comments are kept, or rewrites the file when c replaces an
+// entry of the same name
+func registerCluster(path string, c clusters.Cluster) error {
+	original, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read cluster registry: %w", err)
+	}
+	reg := &clusters.Registry{}
+	if len(original) > 0 {
+		if reg, err = clusters.Parse(original); err != nil {
+			return fmt.Errorf("invalid cluster registry %s: %w", path, err)
+		}
+	}
+	if existing, err := reg.Cluster(c.Name); err == nil && existing.Render() == c.Render() {
+		fmt.Printf("Cluster %q in %s is up to date\n", c.Name, path)
+		return nil
+	}
+
+	added := reg.Set(c)
+	updated := reg.Marshal()
+	if added && len(reg.Clusters) > 1 {
+		updated = append([]byte(strings.TrimRight(string(original), "\n")+"\n\n"), c.Render()...)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, updated, 0o644); err != nil {
+		return fmt.Errorf("failed to write cluster registry: %w", err)
+	}
+	if added {
+		fmt.Printf("Added cluster %q to %s (use: netcup-kube --cluster %s ...)\n", c.Name, path, c.Name)
+	} else {
+		fmt.Printf("Updated cluster %q in %s\n", c.Name, path)
+	}
+	return nil
+}
+
+func init() {
+	importTerraformCmd.Flags().StringArrayVar(&tfImportMaps, "map", nil, "Map an output to a setting as OUTPUT=VAR (repeatable; CLUSTER_NAME names the registry entry)")
+	importTerraformCmd.Flags().StringVar(&tfImportName, "name", "", "Cluster registry entry to add or update (default: the cluster_name output)")
+	importTerraformCmd.Flags().BoolVar(&tfImportNoCluster, "no-cluster", false, "Only update the env file, not the cluster registry")
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/ingress"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ingressNamespace string
+	ingressHosts     []string
+	ingressOutput    string
+	ingressEdge      string
+	ingressTimeout   time.Duration
+	ingressInsecure  bool
+)
+
+var ingressCmd = &cobra.Command{
+	Use:   "ingress",
+	Short: "List Ingress routes and test them through the public edge",
+	Long: `Inspect the Ingress resources of the cluster.
+
+Sub-commands:
+  list  - List every host and path with its class, backend and TLS secret
+  test  - Request each route over HTTPS through Caddy and Traefik
+
+Examples:
+  netcup-kube ingress list
+  netcup-kube ingress list -n openclaw -o json
+  netcup-kube ingress test
+  netcup-kube ingress test --host claw.example.com --edge 203.0.113.10`,
+}
+
+var ingressListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the hosts, paths and backends of every Ingress",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(ingressOutput)
+		if err != nil {
+			return err
+		}
+		routes, err := ingressRoutes(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if format == output.FormatJSON {
+			return output.WriteJSON(os.Stdout, routes)
+		}
+		printIngressRoutes(os.Stdout, routes)
+		return nil
+	},
+}
+
+var ingressTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Request every Ingress route over HTTPS through the public edge",
+	Long: `Request https://<host><path> of every Ingress route the way a browser
+does: Caddy terminates TLS on the node and proxies to the Traefik NodePort,
+which routes to the backend Service. Redirects are reported, not followed.
+
+Per route the status code, latency, TLS version and the certificate's issuer
+and expiry are shown. Failures name the hop that most likely answered:
+
+  Caddy 502 with an empty body  Traefik is unreachable from Caddy
+  404 page not found            no Traefik router matched the host/path
+  503 no available server       the Service has no ready endpoints
+  502/504 from Traefik          the backend pods refuse or time out
+
+401 and 403 count as routed. Wildcard hosts and regex paths are skipped.
+--edge connects to the given address instead of resolving each host, to
+test the edge before DNS points at it. Exits non-zero when a route fails.
+
+Examples:
+  netcup-kube ingress test
+  netcup-kube ingress test -n monitoring
+  netcup-kube ingress test --host claw.example.com --edge 203.0.113.10 -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(ingressOutput)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		routes, err := ingressRoutes(ctx)
+		if err != nil {
+			return err
+		}
+		if len(routes) == 0 {
+			return fmt.Errorf("no Ingress routes to test")
+		}
+		results := ingress.Test(ctx, routes, ingress.Options{
+			Edge:     ingressEdge,
+			Timeout:  ingressTimeout,
+			Insecure: ingressInsecure,
+		})
+		if format == output.FormatJSON {
+			if err := output.WriteJSON(os.Stdout, results); err != nil {
+				return err
+			}
+		} else {
+			printIngressTest(os.Stdout, results)
+		}
+		if n := ingress.Failures(results); n > 0 {
+			return fmt.Errorf("%d of %d Ingress routes failed", n, len(results))
+		}
+		return nil
+	},
+}
+
+// ingressRoutes lists the cluster's Ingress routes, filtered by --namespace
+// and --host
+func ingressRoutes(ctx context.Context) ([]ingress.Route, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	path, err := configFilePath()
+	if err != nil {
+		return nil, err
+	}
+	kube, err := clusterKubectl(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	routes, err := ingress.List(ctx, kube)
+	if err != nil {
+		return nil, err
+	}
+	return filterIngressRoutes(routes, ingressNamespace, ingressHosts), nil
+}
+
+func filterIngressRoutes(routes []ingress.Route, namespace string, hosts []string) []ingress.Route {
+	filtered := []ingress.Route{}
+	for _, r := range routes {
+		if namespace != "" && r.Namespace != namespace {
+			continue
+		}
+		if len(hosts) > 0 && !slices.Contains(hosts, r.Host) {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+func printIngressRoutes(out io.Writer, routes []ingress.Route) {
+	if len(routes) == 0 {
+		_, _ = fmt.Fprintln(out, "No Ingresses.")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "HOST\tPATH\tINGRESS\tCLASS\tBACKEND\tTLS")
+	for _, r := range routes {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s/%s\t%s\t%s\t%s\n", r.Host, r.Path, r.Namespace, r.Ingress, orDash(r.Class), r.Backend, ingressTLSColumn(r.TLSSecret))
+	}
+	_ = w.Flush()
+}
+
+// ingressTLSColumn shows where TLS terminates: Caddy on the edge unless the
+// Ingress names a secret
+func ingressTLSColumn(secret string) string {
+	if secret == "" {
+		return "edge"
+	}
+	return "secret/" + secret
+}
+
+func printIngressTest(out io.Writer, results []ingress.Result) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "URL\tSTATUS\tTIME\tTLS\tCERTIFICATE\tRESULT")
+	for _, r := range results {
+		url := r.URL
+		if url == "" {
+			url = r.Host + r.Path
+		}
+		status, latency := "-", "-"
+		if r.Status != 0 {
+			status, latency = strconv.Itoa(r.Status), strconv.FormatInt(r.LatencyMS, 10)+"ms"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", url, status, latency, orDash(r.TLSVersion), ingressCertificate(r), r.Outcome)
+	}
+	_ = w.Flush()
+	for _, r := range results {
+		if r.Outcome == ingress.OutcomeOK {
+			continue
+		}
+		detail := r.Hint
+		if r.Error != "" {
+			detail = r.Error + "; " + r.Hint
+		}
+		if r.Location != "" {
+			detail += " (redirects to " + r.Location + ")"
+		}
+		_, _ = fmt.Fprintf(out, "%s%s (%s/%s -> %s): %s\n", r.Host, r.Path, r.Namespace, r.Ingress, r.Backend, detail)
+	}
+}
+
+// ingressCertificate summarizes the served certificate: issuer and expiry
+// date, or why it is invalid
+func ingressCertificate(r ingress.Result) string {
+	c := r.Certificate
+	if c == nil {
+		return "-"
+	}
+	expiry := c.NotAfter
+	if t, err := time.Parse(time.RFC3339, c.NotAfter); err == nil {
+		expiry = t.Format("2006-01-02")
+	}
+	summary := c.Issuer + ", until " + expiry
+	if !c.Valid {
+		summary = "invalid (" + summary + ")"
+	}
+	return summary
+}
+
+func init() {
+	ingressCmd.PersistentFlags().StringVarP(&ingressNamespace, "namespace", "n", "", "Only include Ingresses of this namespace")
+	ingressCmd.PersistentFlags().StringArrayVar(&ingressHosts, "host", nil, "Only include this hostname (repeatable)")
+	ingressCmd.PersistentFlags().StringVarP(&ingressOutput, "output", "o", "text", "Output format: text or json")
+	ingressTestCmd.Flags().StringVar(&ingressEdge, "edge", "", "Connect to this address (host[:port], default port 443) instead of resolving each host")
+	ingressTestCmd.Flags().DurationVar(&ingressTimeout, "timeout", 10*time.Second, "Timeout per request")
+	ingressTestCmd.Flags().BoolVar(&ingressInsecure, "insecure", false, "Do not fail routes on invalid certificates")
+	ingressCmd.AddCommand(ingressListCmd)
+	ingressCmd.AddCommand(ingressTestCmd)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/dnscheck"
+	"github.com/mfittko/netcup-kube/internal/ingress"
+)
+
+func TestPrintIngressTest(t *testing.T) {
+	route := ingress.Route{Namespace: "openclaw", Ingress: "openclaw", Host: "claw.example.com", Path: "/", Backend: "openclaw:18789"}
+	down := route
+	down.Path = "/api"
+	results := []ingress.Result{
+		{Route: route, URL: "https://claw.example.com/", Status: 200, LatencyMS: 42, TLSVersion: "TLS 1.3", Outcome: ingress.OutcomeOK,
+			Certificate: &dnscheck.Certificate{Issuer: "R11", NotAfter: "2026-12-01T10:00:00Z", Valid: true}},
+		{Route: down, URL: "https://claw.example.com/api", Status: 503, LatencyMS: 7, Outcome: ingress.OutcomeFail, Hint: "the Service has no ready endpoints"},
+	}
+	var out bytes.Buffer
+	printIngressTest(&out, results)
+	text := out.String()
+	for _, want := range []string{
+		"https://claw.example.com/     200     42ms  TLS 1.3  R11, until 2026-12-01  ok",
+		"claw.example.com/api (openclaw/openclaw -> openclaw:18789): the Service has no ready endpoints",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output lacks %q:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "claw.example.com/ (") {
+		t.Errorf("ok route listed with a hint:\n%s", text)
+	}
+}
+
+func TestFilterIngressRoutes(t *testing.T) {
+	routes := []ingress.Route{
+		{Namespace: "openclaw", Host: "claw.example.com"},
+		{Namespace: "monitoring", Host: "grafana.example.com"},
+	}
+	if got := filterIngressRoutes(routes, "monitoring", nil); len(got) != 1 || got[0].Host != "grafana.example.com" {
+		t.Errorf("namespace filter = %+v", got)
+	}
+	if got := filterIngressRoutes(routes, "", []string{"claw.example.com"}); len(got) != 1 || got[0].Namespace != "openclaw" {
+		t.Errorf("host filter = %+v", got)
+	}
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/executor"
+	"github.com/mfittko/netcup-kube/internal/helmmirror"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/kubediag"
+	"github.com/mfittko/netcup-kube/internal/probe"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
 	"github.com/mfittko/netcup-kube/internal/tunnel"
 	"github.com/spf13/cobra"
 )
@@ -39,17 +48,31 @@ Available recipes:
   llm-proxy                Install llm-proxy (Helm chart; Secret-backed config)
   openclaw                 Install OpenClaw with kernel-level network monitoring
   zeroclaw                 Install ZeroClaw AI agent (TOML config, Anthropic provider)
+  system-upgrade-controller Install system-upgrade-controller (in-cluster k3s upgrades)
 
 Examples:
   netcup-kube install argo-cd --help
   netcup-kube install argo-cd --host cd.example.com
-  netcup-kube install redis --namespace platform --storage 20Gi`,
+  netcup-kube install redis --namespace platform --storage 20Gi
+  netcup-kube install redis --uninstall --yes
+  netcup-kube install redis --render-dir ./rendered
+
+With --render-dir <dir>, the recipe's Helm releases (command plus values files)
+and applied manifests are written to <dir>/<recipe> for review or a GitOps
+commit instead of changing the cluster; cluster reads still happen.
+
+Uninstalling (--uninstall) asks for confirmation; non-interactive runs need
+--yes or CONFIRM=true.`,
 	DisableFlagParsing: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Need at least the recipe name
 		if len(args) < 1 {
 			return cmd.Help()
 		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
 
 		// Check for help flag on the install command itself (not the recipe)
 		if args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
@@ -57,16 +80,24 @@ Examples:
 		}
 
 		recipe := args[0]
-		recipeArgs := args[1:]
+		recipeArgs, assumeYes := stripYesFlag(args[1:])
+		recipeArgs, renderTo, err := stripRenderDirFlag(recipeArgs)
+		if err != nil {
+			return err
+		}
 
-		// Find project root
-		projectRoot, err := findProjectRoot()
+		// Recipes come from the checkout, or from the binary when standalone
+		scriptsDir, err := findScriptsDir()
 		if err != nil {
-			return fmt.Errorf("could not find project root: %w", err)
+			return err
+		}
+		projectRoot, err := workspaceRoot()
+		if err != nil {
+			return err
 		}
 
 		// Check if recipe exists
-		recipesDir := filepath.Join(projectRoot, "scripts", "recipes")
+		recipesDir := filepath.Join(scriptsDir, "recipes")
 		recipeScript := filepath.Join(recipesDir, recipe, "install.sh")
 
 		if _, err := os.Stat(recipeScript); err != nil {
@@ -98,57 +129,81 @@ Examples:
 		// Parse --host flag for automatic domain management
 		hostArg, adminHostArg := parseRecipeHostArgs(recipeArgs)
 
+		// Render mode writes the recipe's manifests and Helm releases to
+		// <render-dir>/<recipe> instead of changing the cluster
+		if isHelpRequest {
+			renderTo = ""
+		}
+		if renderTo != "" {
+			if hasArg(recipeArgs, "--uninstall") {
+				return fmt.Errorf("--render-dir cannot be combined with --uninstall")
+			}
+			if renderTo, err = renderDir(filepath.Join(renderTo, recipe)); err != nil {
+				return err
+			}
+		}
+
 		// Ensure kubeconfig is available (unless just showing help)
 		kubeconfig := os.Getenv("KUBECONFIG")
 		if !isHelpRequest {
-			// If KUBECONFIG isn't set, default to the repo's ./config/k3s.yaml when running locally.
-			// When running on the server, prefer the node-local kubeconfig.
-			if kubeconfig == "" {
-				if _, err := os.Stat(serverKubeconfigPath); err == nil {
-					kubeconfig = serverKubeconfigPath
-				} else {
-					kubeconfig = localKubeconfig
-				}
+			if kubeconfig, err = resolveKubeconfig(ctx, envFile, localKubeconfig, projectRoot); err != nil {
+				return err
 			}
-
-			// If we are using a local kubeconfig path and it's missing, fetch it via scp.
-			// This also covers the case where the user set KUBECONFIG explicitly to a local path.
-			if kubeconfig != serverKubeconfigPath {
-				if _, err := os.Stat(kubeconfig); err != nil {
-					fmt.Printf("Kubeconfig %s not found. Fetching from remote...\n", kubeconfig)
-					if err := fetchKubeconfig(envFile, kubeconfig, filepath.Dir(kubeconfig)); err != nil {
-						return err
-					}
-					fmt.Printf("Kubeconfig saved to %s\n", kubeconfig)
+			if renderTo == "" {
+				if err := checkRecipeGuardrails(recipe, recipeArgs, envFile); err != nil {
+					return err
 				}
 			}
 		}
 
-		// Check if tunnel is needed and running (when not using the server's kubeconfig path, and not just showing help)
-		if !isHelpRequest && kubeconfig != serverKubeconfigPath {
-			if err := ensureTunnelRunning(envFile, projectRoot); err != nil {
+		// Confirm uninstalls here; the recipe then runs pre-confirmed
+		uninstall := !isHelpRequest && hasArg(recipeArgs, "--uninstall")
+		if uninstall {
+			if err := confirm.New(assumeYes).Confirm("uninstall recipe " + recipe); err != nil {
 				return err
 			}
 		}
 
 		// Run the recipe
-		recipeCmd := exec.Command(recipeScript, recipeArgs...)
+		recipeCmd := interrupt.Command(ctx, recipeScript, recipeArgs...)
+		recipeCmd.Env = os.Environ()
 		if kubeconfig != "" {
-			recipeCmd.Env = append(os.Environ(), fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
-		} else {
-			recipeCmd.Env = os.Environ()
+			recipeCmd.Env = append(recipeCmd.Env, fmt.Sprintf("KUBECONFIG=%s", kubeconfig))
+		}
+		if uninstall {
+			recipeCmd.Env = append(recipeCmd.Env, confirm.EnvVar+"=true")
+		}
+		if renderTo != "" {
+			recipeCmd.Env = append(recipeCmd.Env, "RENDER_DIR="+renderTo)
 		}
+		// Recipes read only the process environment; the air-gap bundle
+		// configured by 'bundle use' lives in the env file
+		if bundle := cfg.Env["AIRGAP_BUNDLE"]; bundle != "" && os.Getenv("AIRGAP_BUNDLE") == "" {
+			recipeCmd.Env = append(recipeCmd.Env, "AIRGAP_BUNDLE="+bundle)
+		}
+		// Likewise the chart mirror, with its keyring: password resolved
+		recipeCmd.Env = append(recipeCmd.Env, helmMirrorEnv(cfg.Env)...)
 		recipeCmd.Stdin = os.Stdin
 		recipeCmd.Stdout = os.Stdout
 		recipeCmd.Stderr = os.Stderr
 
-		if err := recipeCmd.Run(); err != nil {
+		_, span := telemetry.Start(ctx, "recipe "+recipe)
+		if err := span.End(recipeCmd.Run()); err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return fmt.Errorf("recipe %s interrupted: %w", recipe, ctxErr)
+			}
 			if exitErr, ok := err.(*exec.ExitError); ok {
-				os.Exit(exitErr.ExitCode())
+				// Exit via main so telemetry is flushed
+				return executor.ExitCodeError{Code: exitErr.ExitCode()}
 			}
 			return fmt.Errorf("recipe execution failed: %w", err)
 		}
 
+		if renderTo != "" {
+			fmt.Printf("\nRendered %s to %s (nothing was applied)\n", recipe, renderTo)
+			return nil
+		}
+
 		// If recipe succeeded and --host/--admin-host were specified, auto-add domain(s) to Caddy
 		domainsToAdd := uniqueNonEmptyStrings([]string{hostArg, adminHostArg})
 		if len(domainsToAdd) > 0 {
@@ -182,7 +237,7 @@ Examples:
 						fmt.Printf("\nAdding %s to Caddy edge-http domains...\n", domain)
 
 						// Run the domain add command
-						dnsCmd := exec.Command(remoteBin, buildRemoteDNSAddDomainsArgs(tmpEnvPath, domain)...)
+						dnsCmd := interrupt.Command(ctx, remoteBin, buildRemoteDNSAddDomainsArgs(tmpEnvPath, domain)...)
 						dnsCmd.Stdout = os.Stdout
 						dnsCmd.Stderr = os.Stderr
 
@@ -201,6 +256,81 @@ Examples:
 	},
 }
 
+// stripYesFlag removes --yes/-y from recipe arguments; recipe scripts take
+// CONFIRM=true instead
+func stripYesFlag(recipeArgs []string) ([]string, bool) {
+	rest := make([]string, 0, len(recipeArgs))
+	yes := false
+	for _, arg := range recipeArgs {
+		if arg == "--yes" || arg == "-y" {
+			yes = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, yes
+}
+
+// stripRenderDirFlag removes --render-dir <dir> / --render-dir=<dir> from
+// recipe arguments and returns the directory
+func stripRenderDirFlag(recipeArgs []string) ([]string, string, error) {
+	rest := make([]string, 0, len(recipeArgs))
+	dir := ""
+	for i := 0; i < len(recipeArgs); i++ {
+		arg := recipeArgs[i]
+		switch {
+		case arg == "--render-dir":
+			if i+1 >= len(recipeArgs) || strings.HasPrefix(recipeArgs[i+1], "-") {
+				return nil, "", fmt.Errorf("--render-dir requires a value")
+			}
+			dir = recipeArgs[i+1]
+			i++
+		case strings.HasPrefix(arg, "--render-dir="):
+			dir = strings.TrimPrefix(arg, "--render-dir=")
+			if dir == "" {
+				return nil, "", fmt.Errorf("--render-dir requires a value")
+			}
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, dir, nil
+}
+
+// renderDir returns dir as an absolute path and creates it
+// helmMirrorEnv returns the HELM_MIRROR_* settings of env as KEY=value
+// entries, sorted by key
+func helmMirrorEnv(env map[string]string) []string {
+	var entries []string
+	for k, v := range env {
+		if helmmirror.IsKey(k) {
+			entries = append(entries, k+"="+v)
+		}
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+func renderDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid render directory %s: %w", dir, err)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create render directory %s: %w", abs, err)
+	}
+	return abs, nil
+}
+
+func hasArg(args []string, want string) bool {
+	for _, arg := range args {
+		if arg == want {
+			return true
+		}
+	}
+	return false
+}
+
 func buildRemoteDNSAddDomainsArgs(envFile string, domain string) []string {
 	return []string{"remote", "run", "--no-tty", "--env-file", envFile, "--", "dns", "--type", "edge-http", "--add-domains", domain}
 }
@@ -239,7 +369,56 @@ func uniqueNonEmptyStrings(values []string) []string {
 	return unique
 }
 
-func fetchKubeconfig(envFile, localKubeconfig, configDir string) error {
+// resolveKubeconfig returns a kubeconfig for reaching the cluster API.
+// $KUBECONFIG wins; otherwise the node-local kubeconfig is used on the server
+// and localKubeconfig elsewhere (fetched via scp when missing, and kept
+// encrypted at rest when a passphrase is configured). Off the server
+// a reachable WireGuard link is preferred; otherwise the SSH tunnel is started.
+func resolveKubeconfig(ctx context.Context, envFile, localKubeconfig, projectRoot string) (string, error) {
+	kubeconfig := os.Getenv("KUBECONFIG")
+	if kubeconfig == "" {
+		if _, err := os.Stat(serverKubeconfigPath); err == nil {
+			kubeconfig = serverKubeconfigPath
+		} else {
+			kubeconfig = localKubeconfig
+		}
+	}
+	if kubeconfig == serverKubeconfigPath {
+		return kubeconfig, nil
+	}
+
+	decrypted := false
+	if kubeconfig == localKubeconfig {
+		// The cache may be encrypted at rest (see cachedKubeconfig)
+		cached, err := cachedKubeconfig(ctx, envFile, localKubeconfig)
+		if err != nil {
+			return "", err
+		}
+		decrypted = cached != localKubeconfig
+		kubeconfig = cached
+	} else if _, err := os.Stat(kubeconfig); err != nil {
+		// The user set KUBECONFIG explicitly to a local path that does not exist yet
+		fmt.Printf("Kubeconfig %s not found. Fetching from remote...\n", kubeconfig)
+		if err := fetchKubeconfig(ctx, envFile, kubeconfig, filepath.Dir(kubeconfig)); err != nil {
+			return "", err
+		}
+		fmt.Printf("Kubeconfig saved to %s\n", kubeconfig)
+	}
+
+	if wgKubeconfig, ok := wireguardKubeconfig(envFile, kubeconfig); ok {
+		if decrypted {
+			// A copy of the decrypted cache, so removed with it
+			trackPlaintextKubeconfig(wgKubeconfig)
+		}
+		return wgKubeconfig, nil
+	}
+	if err := ensureTunnelRunning(ctx, envFile, kubeconfig); err != nil {
+		return "", err
+	}
+	return kubeconfig, nil
+}
+
+func fetchKubeconfig(ctx context.Context, envFile, localKubeconfig, configDir string) error {
 	// Check if env file exists
 	if _, err := os.Stat(envFile); err != nil {
 		return fmt.Errorf("ERROR: %s not found. Please create it from the example", envFile)
@@ -272,7 +451,7 @@ func fetchKubeconfig(envFile, localKubeconfig, configDir string) error {
 	// Fetch kubeconfig via scp
 	fmt.Printf("Fetching kubeconfig from %s@%s:/etc/rancher/k3s/k3s.yaml\n", remoteUser, remoteHost)
 
-	scpCmd := exec.Command("scp",
+	scpCmd := interrupt.Command(ctx, "scp",
 		fmt.Sprintf("%s@%s:/etc/rancher/k3s/k3s.yaml", remoteUser, remoteHost),
 		localKubeconfig)
 	scpCmd.Stdout = os.Stdout
@@ -285,7 +464,10 @@ func fetchKubeconfig(envFile, localKubeconfig, configDir string) error {
 	return nil
 }
 
-func ensureTunnelRunning(envFile, projectRoot string) error {
+// ensureTunnelRunning starts the SSH tunnel to MGMT_HOST unless it runs. A
+// newly started tunnel is probed with kubeconfig; when the API does not answer
+// the error carries a diagnosis.
+func ensureTunnelRunning(ctx context.Context, envFile, kubeconfig string) error {
 	// Load env file to get tunnel settings
 	env, err := config.LoadEnvFileToMap(envFile)
 	if err != nil {
@@ -315,7 +497,7 @@ func ensureTunnelRunning(envFile, projectRoot string) error {
 	mgr := tunnel.New(remoteUser, remoteHost, tunnelPort, "127.0.0.1", "6443")
 
 	// Check if tunnel is running
-	if mgr.IsRunning() {
+	if mgr.IsRunning(ctx) {
 		fmt.Printf("Using tunnel: localhost:%s -> %s:6443\n", tunnelPort, remoteHost)
 		return nil
 	}
@@ -323,7 +505,7 @@ func ensureTunnelRunning(envFile, projectRoot string) error {
 	// Tunnel not running, start it
 	fmt.Println("SSH tunnel not running. Starting tunnel...")
 
-	if err := mgr.Start(); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		troubleshooting := fmt.Sprintf("\n\nTroubleshooting:\n  - Verify MGMT_HOST=%s and MGMT_USER=%s are correct\n  - Check SSH access: ssh %s@%s\n  - Start tunnel manually: netcup-kube ssh tunnel start",
 			remoteHost, remoteUser, remoteUser, remoteHost)
 		return fmt.Errorf("failed to start SSH tunnel: %w%s", err, troubleshooting)
@@ -333,12 +515,48 @@ func ensureTunnelRunning(envFile, projectRoot string) error {
 	time.Sleep(1 * time.Second)
 
 	// Verify tunnel is now running
-	if !mgr.IsRunning() {
+	if !mgr.IsRunning(ctx) {
 		troubleshooting := fmt.Sprintf("\n\nTroubleshooting:\n  - Check SSH access: ssh %s@%s\n  - View tunnel status: netcup-kube ssh tunnel status\n  - Start tunnel manually: netcup-kube ssh tunnel start",
 			remoteUser, remoteHost)
 		return fmt.Errorf("tunnel failed to start (verify SSH access and known_hosts)%s", troubleshooting)
 	}
 
 	fmt.Printf("Using tunnel: localhost:%s -> %s:6443\n", tunnelPort, remoteHost)
-	return nil
+	return waitForKubeAPI(ctx, kubeconfig, mgr)
+}
+
+// kubeAPIProbeTimeout bounds waiting for the API behind a new tunnel
+const kubeAPIProbeTimeout = 10 * time.Second
+
+// waitForKubeAPI probes the API through a new tunnel until it answers. The
+// kubectl probe is skipped without kubectl; the recipe reports its own errors.
+func waitForKubeAPI(ctx context.Context, kubeconfig string, mgr *tunnel.Manager) error {
+	cfg, err := probe.ConfigFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; using the kubectl probe\n", err)
+		cfg = probe.Config{}
+	}
+	cfg.Kubeconfig = kubeconfig
+	strategy, err := probe.New(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := exec.LookPath("kubectl"); err != nil && strategy.Name() == probe.StrategyKubectl {
+		return nil
+	}
+	var probeErr error
+	for deadline := time.Now().Add(kubeAPIProbeTimeout); ; time.Sleep(500 * time.Millisecond) {
+		if _, probeErr = strategy.Probe(ctx); probeErr == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+	}
+	return kubediag.Unreachable(ctx, "kube API unreachable through the SSH tunnel", kubediag.Options{
+		Kubeconfig:   kubeconfig,
+		TunnelSocket: mgr.GetControlSocket(),
+		TunnelStart:  "netcup-kube ssh tunnel start",
+		Probe:        probeErr,
+	})
 }
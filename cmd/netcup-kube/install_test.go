@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -44,7 +45,7 @@ MGMT_USER=testuser
 		t.Fatal(err)
 	}
 
-	if err := fetchKubeconfig(envFile, localKubeconfig, tmpDir); err != nil {
+	if err := fetchKubeconfig(context.Background(), envFile, localKubeconfig, tmpDir); err != nil {
 		t.Fatalf("fetchKubeconfig() error: %v", err)
 	}
 	data, err := os.ReadFile(localKubeconfig)
@@ -78,7 +79,7 @@ func TestEnsureTunnelRunning_NoEnvFile(t *testing.T) {
 	envFile := filepath.Join(tmpDir, "nonexistent.env")
 
 	// Should not error if env file doesn't exist (uses defaults)
-	err := ensureTunnelRunning(envFile, tmpDir)
+	err := ensureTunnelRunning(context.Background(), envFile, filepath.Join(tmpDir, "k3s.yaml"))
 	if err != nil {
 		t.Logf("ensureTunnelRunning() returned: %v (expected when env values missing)", err)
 	}
@@ -213,3 +214,49 @@ func TestUniqueNonEmptyStrings(t *testing.T) {
 		}
 	}
 }
+
+func TestStripYesFlag(t *testing.T) {
+	rest, yes := stripYesFlag([]string{"--uninstall", "--yes", "--namespace", "data"})
+	if !yes {
+		t.Fatal("expected --yes to be detected")
+	}
+	if strings.Join(rest, " ") != "--uninstall --namespace data" {
+		t.Fatalf("unexpected remaining args: %#v", rest)
+	}
+
+	rest, yes = stripYesFlag([]string{"--host", "cd.example.com"})
+	if yes || len(rest) != 2 {
+		t.Fatalf("expected args unchanged without --yes, got %#v (yes=%v)", rest, yes)
+	}
+}
+
+func TestStripRenderDirFlag(t *testing.T) {
+	rest, dir, err := stripRenderDirFlag([]string{"--host", "cd.example.com", "--render-dir", "out"})
+	if err != nil || dir != "out" || strings.Join(rest, " ") != "--host cd.example.com" {
+		t.Fatalf("got rest=%#v dir=%q err=%v", rest, dir, err)
+	}
+
+	_, dir, err = stripRenderDirFlag([]string{"--render-dir=gitops/rendered"})
+	if err != nil || dir != "gitops/rendered" {
+		t.Fatalf("got dir=%q err=%v", dir, err)
+	}
+
+	for _, args := range [][]string{{"--render-dir"}, {"--render-dir", "--host"}, {"--render-dir="}} {
+		if _, _, err := stripRenderDirFlag(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestHelmMirrorEnv(t *testing.T) {
+	got := helmMirrorEnv(map[string]string{
+		"HELM_MIRROR_URL":          "oci://registry.example.com/charts",
+		"HELM_MIRROR_PASSWORD":     "secret",
+		"HELM_MIRROR_REPO_BITNAMI": "https://mirror.example.com/bitnami",
+		"CHANNEL":                  "stable",
+	})
+	want := "HELM_MIRROR_PASSWORD=secret,HELM_MIRROR_REPO_BITNAMI=https://mirror.example.com/bitnami,HELM_MIRROR_URL=oci://registry.example.com/charts"
+	if strings.Join(got, ",") != want {
+		t.Errorf("helmMirrorEnv() = %v", got)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/remote"
+)
+
+var (
+	remoteInventory string
+	remoteNode      string
+	joinInventory   string
+	joinNode        string
+)
+
+// applyInventoryNode points a remote config at an inventory node. An explicit
+// --host/--user still wins over the inventory.
+func applyInventoryNode(cfg *remote.Config, node inventory.Node) {
+	if cfg.Host == "" {
+		cfg.Host = node.Host
+	}
+	if node.User != "" && !cfg.UserExplicit {
+		cfg.User = node.User
+		cfg.UserExplicit = true
+	}
+}
+
+// selectInventoryNode returns the named node, or the primary server if name is empty.
+func selectInventoryNode(inv *inventory.Inventory, name string) (inventory.Node, error) {
+	if strings.TrimSpace(name) == "" {
+		return inv.Primary(), nil
+	}
+	return inv.Node(strings.TrimSpace(name))
+}
+
+// inventoryTargets returns the nodes a remote command should act on: the
+// --node selection when set, otherwise every node when all is true, otherwise
+// the primary server.
+func inventoryTargets(inv *inventory.Inventory, name string, all bool) ([]inventory.Node, error) {
+	if strings.TrimSpace(name) == "" && all {
+		return inv.Nodes(), nil
+	}
+	node, err := selectInventoryNode(inv, name)
+	if err != nil {
+		return nil, err
+	}
+	return []inventory.Node{node}, nil
+}
+
+// resolveJoinNode picks the inventory node for `join`: --node if given,
+// otherwise the worker whose name matches this machine's hostname.
+func resolveJoinNode(inv *inventory.Inventory, name, hostname string) (inventory.Node, error) {
+	var (
+		node inventory.Node
+		err  error
+	)
+	if strings.TrimSpace(name) != "" {
+		node, err = inv.Node(strings.TrimSpace(name))
+		if err != nil {
+			return inventory.Node{}, err
+		}
+	} else {
+		found := false
+		for _, candidate := range inv.Workers {
+			if candidate.Name == hostname || candidate.Host == hostname {
+				node, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			return inventory.Node{}, fmt.Errorf("no worker in inventory matches hostname %q; pass --node", hostname)
+		}
+	}
+	if node.Role != inventory.RoleWorker {
+		return inventory.Node{}, fmt.Errorf("node %q is a %s node; join only applies to workers", node.Name, node.Role)
+	}
+	return node, nil
+}
+
+// applyJoinInventory loads the inventory and applies the cluster and node vars
+// to the script environment. SERVER_URL defaults to the primary server.
+func applyJoinInventory(c *config.Config, path, name string) error {
+	inv, err := inventory.Load(path)
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	node, err := resolveJoinNode(inv, name, hostname)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range inv.Env(node) {
+		c.SetFlag(key, value)
+	}
+	if strings.TrimSpace(c.Env["SERVER_URL"]) == "" {
+		c.SetFlag("SERVER_URL", inv.ServerURL())
+	}
+	fmt.Fprintf(os.Stderr, "Using inventory node %s (server %s)\n", node.Name, c.Env["SERVER_URL"])
+	return nil
+}
+
+// sshTarget is a node reached over SSH
+type sshTarget struct {
+	name   string
+	client remote.Client
+}
+
+// sshTargets returns the nodes to act on: the single MGMT_HOST target without
+// an inventory, otherwise the node selection, every node with all, or the
+// primary server
+func sshTargets(inventoryPath, nodeName string, all bool) ([]sshTarget, error) {
+	nodes := []inventory.Node{{}}
+	if inventoryPath != "" {
+		inv, err := inventory.Load(inventoryPath)
+		if err != nil {
+			return nil, err
+		}
+		if nodes, err = inventoryTargets(inv, nodeName, all); err != nil {
+			return nil, err
+		}
+	} else if nodeName != "" || all {
+		return nil, fmt.Errorf("--node and --all require --inventory")
+	}
+
+	targets := make([]sshTarget, 0, len(nodes))
+	for _, node := range nodes {
+		rc := remote.NewConfig()
+		applyInventoryNode(rc, node)
+		if err := rc.LoadConfigFromEnv(envFile); err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		if rc.Host == "" {
+			return nil, fmt.Errorf("no host configured (set MGMT_HOST or use --inventory)")
+		}
+		name := node.Name
+		if name == "" {
+			name = rc.Host
+		}
+		targets = append(targets, sshTarget{name: name, client: remote.NewSSHClient(rc.Host, rc.User)})
+	}
+	return targets, nil
+}
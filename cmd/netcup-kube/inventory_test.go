@@ -0,0 +1,134 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/remote"
+)
+
+const testInventory = `vars:
+  BASE_DOMAIN: example.com
+servers:
+  - name: mgmt
+    host: 203.0.113.10
+    vars:
+      NODE_IP: 10.10.0.1
+workers:
+  - name: worker-1
+    host: 203.0.113.11
+    user: ops
+    vars:
+      NODE_IP: 10.10.0.2
+`
+
+func mustParseInventory(t *testing.T) *inventory.Inventory {
+	t.Helper()
+	inv, err := inventory.Parse([]byte(testInventory))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	return inv
+}
+
+func TestApplyInventoryNode(t *testing.T) {
+	inv := mustParseInventory(t)
+	node, _ := inv.Node("worker-1")
+
+	cfg := remote.NewConfig()
+	applyInventoryNode(cfg, node)
+	if cfg.Host != "203.0.113.11" || cfg.User != "ops" || !cfg.UserExplicit {
+		t.Errorf("applyInventoryNode() = %+v", cfg)
+	}
+
+	// Explicit --host/--user win
+	cfg = remote.NewConfig()
+	cfg.Host = "override"
+	cfg.User = "admin"
+	cfg.UserExplicit = true
+	applyInventoryNode(cfg, node)
+	if cfg.Host != "override" || cfg.User != "admin" {
+		t.Errorf("applyInventoryNode() overrode explicit values: %+v", cfg)
+	}
+}
+
+func TestInventoryTargets(t *testing.T) {
+	inv := mustParseInventory(t)
+
+	tests := []struct {
+		name string
+		node string
+		all  bool
+		want []string
+	}{
+		{"default primary", "", false, []string{"mgmt"}},
+		{"all nodes", "", true, []string{"mgmt", "worker-1"}},
+		{"explicit node wins over all", "worker-1", true, []string{"worker-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := inventoryTargets(inv, tt.node, tt.all)
+			if err != nil {
+				t.Fatalf("inventoryTargets() error: %v", err)
+			}
+			var got []string
+			for _, n := range nodes {
+				got = append(got, n.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("inventoryTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := inventoryTargets(inv, "nope", false); err == nil {
+		t.Error("inventoryTargets(nope) expected error")
+	}
+}
+
+func TestResolveJoinNode(t *testing.T) {
+	inv := mustParseInventory(t)
+
+	if node, err := resolveJoinNode(inv, "", "worker-1"); err != nil || node.Name != "worker-1" {
+		t.Errorf("resolveJoinNode(hostname) = %+v, %v", node, err)
+	}
+	if _, err := resolveJoinNode(inv, "", "unknown-host"); err == nil || !strings.Contains(err.Error(), "--node") {
+		t.Errorf("resolveJoinNode(unknown) error = %v", err)
+	}
+	if _, err := resolveJoinNode(inv, "mgmt", ""); err == nil || !strings.Contains(err.Error(), "server node") {
+		t.Errorf("resolveJoinNode(mgmt) error = %v", err)
+	}
+}
+
+func TestApplyJoinInventory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.yaml")
+	if err := os.WriteFile(path, []byte(testInventory), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := config.New()
+	c.SetFlag("NODE_IP", "from-env")
+	if err := applyJoinInventory(c, path, "worker-1"); err != nil {
+		t.Fatalf("applyJoinInventory() error: %v", err)
+	}
+	if c.Env["NODE_IP"] != "10.10.0.2" || c.Env["BASE_DOMAIN"] != "example.com" {
+		t.Errorf("env = %v", c.Env)
+	}
+	if c.Env["SERVER_URL"] != "https://10.10.0.1:6443" {
+		t.Errorf("SERVER_URL = %q", c.Env["SERVER_URL"])
+	}
+
+	// An explicit SERVER_URL is kept
+	c = config.New()
+	c.SetFlag("SERVER_URL", "https://lb.example.com:6443")
+	if err := applyJoinInventory(c, path, "worker-1"); err != nil {
+		t.Fatalf("applyJoinInventory() error: %v", err)
+	}
+	if c.Env["SERVER_URL"] != "https://lb.example.com:6443" {
+		t.Errorf("SERVER_URL = %q", c.Env["SERVER_URL"])
+	}
+}
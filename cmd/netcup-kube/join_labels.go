@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/validation"
+)
+
+var (
+	joinLabels      string
+	joinTaints      string
+	joinNodeProfile string
+)
+
+// applyNodeProfile sets NODE_LABELS and NODE_TAINTS of a joining node: those
+// of the node profile (--node-profile, else NODE_PROFILE) plus --labels and
+// --taints (else NODE_LABELS and NODE_TAINTS). Explicit labels and taints
+// replace profile entries with the same key (and effect).
+func applyNodeProfile(c *config.Config, profile, labels, taints string) error {
+	if labels == "" {
+		labels = c.Env["NODE_LABELS"]
+	}
+	if taints == "" {
+		taints = c.Env["NODE_TAINTS"]
+	}
+	if profile == "" {
+		profile = strings.TrimSpace(c.Env["NODE_PROFILE"])
+	}
+	if profile != "" {
+		prefix, err := nodeProfilePrefix(profile)
+		if err != nil {
+			return err
+		}
+		profileLabels, hasLabels := c.Env[prefix+"_LABELS"]
+		profileTaints, hasTaints := c.Env[prefix+"_TAINTS"]
+		if !hasLabels && !hasTaints {
+			return fmt.Errorf("unknown node profile %q: set %s_LABELS and/or %s_TAINTS in the env file", profile, prefix, prefix)
+		}
+		labels = mergeNodeList(profileLabels, labels, labelKey)
+		taints = mergeNodeList(profileTaints, taints, taintKey)
+	}
+
+	if err := validation.NodeLabels("NODE_LABELS", labels); err != nil {
+		return err
+	}
+	if err := validation.NodeTaints("NODE_TAINTS", taints); err != nil {
+		return err
+	}
+	if labels != c.Env["NODE_LABELS"] {
+		c.SetFlag("NODE_LABELS", labels)
+	}
+	if taints != c.Env["NODE_TAINTS"] {
+		c.SetFlag("NODE_TAINTS", taints)
+	}
+	return nil
+}
+
+// nodeProfilePrefix maps a profile name to its variable prefix, e.g. "gpu-a"
+// to NODE_PROFILE_GPU_A
+func nodeProfilePrefix(profile string) (string, error) {
+	for _, r := range profile {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", fmt.Errorf("invalid node profile name %q (use letters, digits, '-' or '_')", profile)
+		}
+	}
+	return "NODE_PROFILE_" + strings.ToUpper(strings.ReplaceAll(profile, "-", "_")), nil
+}
+
+// mergeNodeList appends the comma-separated entries of override to those of
+// base; an override entry replaces a base entry with the same key
+func mergeNodeList(base, override string, key func(string) string) string {
+	var merged []string
+	index := map[string]int{}
+	for _, list := range []string{base, override} {
+		for _, entry := range strings.Split(list, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			if i, ok := index[key(entry)]; ok {
+				merged[i] = entry
+				continue
+			}
+			index[key(entry)] = len(merged)
+			merged = append(merged, entry)
+		}
+	}
+	return strings.Join(merged, ",")
+}
+
+// labelKey returns the key of a key=value label
+func labelKey(label string) string {
+	key, _, _ := strings.Cut(label, "=")
+	return key
+}
+
+// taintKey identifies a key[=value]:Effect taint by its key and effect
+func taintKey(taint string) string {
+	rest, effect, _ := strings.Cut(taint, ":")
+	key, _, _ := strings.Cut(rest, "=")
+	return key + ":" + effect
+}
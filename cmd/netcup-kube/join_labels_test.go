@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+)
+
+func TestApplyNodeProfile(t *testing.T) {
+	env := map[string]string{
+		"NODE_PROFILE_BATCH_LABELS": "role=worker,class=batch",
+		"NODE_PROFILE_BATCH_TAINTS": "dedicated=batch:NoSchedule",
+		"NODE_PROFILE_GPU_A_LABELS": "gpu=a",
+	}
+	tests := []struct {
+		name, profile, labels, taints string
+		env                           map[string]string
+		wantLabels, wantTaints        string
+		wantErr                       bool
+	}{
+		{name: "flags only", labels: "role=worker,zone=vlan", taints: "dedicated=batch:NoSchedule", wantLabels: "role=worker,zone=vlan", wantTaints: "dedicated=batch:NoSchedule"},
+		{name: "profile", profile: "batch", wantLabels: "role=worker,class=batch", wantTaints: "dedicated=batch:NoSchedule"},
+		{name: "flags override profile", profile: "batch", labels: "class=spot,zone=vlan", taints: "dedicated=ml:NoSchedule,spot:NoExecute",
+			wantLabels: "role=worker,class=spot,zone=vlan", wantTaints: "dedicated=ml:NoSchedule,spot:NoExecute"},
+		{name: "profile from env", env: map[string]string{"NODE_PROFILE": "gpu-a", "NODE_LABELS": "zone=vlan"}, wantLabels: "gpu=a,zone=vlan"},
+		{name: "unknown profile", profile: "nope", wantErr: true},
+		{name: "invalid profile name", profile: "a.b", wantErr: true},
+		{name: "invalid label", labels: "node-role.kubernetes.io/worker=true", wantErr: true},
+		{name: "invalid taint", taints: "dedicated=batch", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config.Config{Env: map[string]string{}}
+			for k, v := range env {
+				c.Env[k] = v
+			}
+			for k, v := range tt.env {
+				c.Env[k] = v
+			}
+			err := applyNodeProfile(c, tt.profile, tt.labels, tt.taints)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("applyNodeProfile() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyNodeProfile() error: %v", err)
+			}
+			if c.Env["NODE_LABELS"] != tt.wantLabels || c.Env["NODE_TAINTS"] != tt.wantTaints {
+				t.Errorf("NODE_LABELS=%q NODE_TAINTS=%q, want %q and %q", c.Env["NODE_LABELS"], c.Env["NODE_TAINTS"], tt.wantLabels, tt.wantTaints)
+			}
+		})
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/k3s"
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	k3sChannel      string
+	k3sVersion      string
+	k3sInventory    string
+	k3sSkipDrain    bool
+	k3sDrainTimeout time.Duration
+	k3sReadyTimeout time.Duration
+
+	k3sPlanNamespace   string
+	k3sPlanConcurrency int
+	k3sPlanDrain       bool
+	k3sPlanOutput      string
+
+	k3sChannelServer = k3s.DefaultChannelServer
+	k3sPollInterval  = 5 * time.Second
+)
+
+var k3sCmd = &cobra.Command{
+	Use:   "k3s",
+	Short: "Manage the k3s version of the cluster",
+	Long: `Manage the k3s version running on the cluster nodes.
+
+Sub-commands:
+  upgrade  - Upgrade servers and workers in order to a channel or version
+  plan     - Generate system-upgrade-controller Plans for the pinned version`,
+	SilenceUsage: true,
+}
+
+var k3sUpgradeCmd = &cobra.Command{
+	Use:   "upgrade (--channel <name> | --version <vX.Y.Z+k3sN>)",
+	Short: "Upgrade k3s on servers and workers in order",
+	Long: `Upgrade k3s node by node: servers first, then workers. Each node is
+cordoned and drained, upgraded over SSH by re-running the k3s installer with
+the pinned version, and uncordoned once it reports Ready at the new version.
+Nodes already at the target version are skipped. Single-node clusters are
+upgraded in place without draining.
+
+A channel (stable, latest, v1.31, ...) is resolved to its current version
+first, so every node gets the same release. On success K3S_VERSION (and
+CHANNEL, when given) is recorded in the env file, pinning later bootstrap and
+join runs to the upgraded version.
+
+Nodes come from --inventory; without it the single node at MGMT_HOST is
+upgraded. Inventory node names must match the Kubernetes node names.
+
+Examples:
+  netcup-kube k3s upgrade --channel stable --dry-run
+  netcup-kube k3s upgrade --version v1.31.4+k3s1
+  netcup-kube k3s upgrade --channel v1.31 --inventory config/inventory.yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := k3s.Target{Channel: k3sChannel, Version: k3sVersion}
+		if err := target.Validate(); err != nil {
+			return err
+		}
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		nodes, err := k3sUpgradeNodes(k3sInventory)
+		if err != nil {
+			return err
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		version := target.Version
+		if version == "" {
+			if version, err = k3s.ResolveChannel(ctx, &http.Client{Timeout: 30 * time.Second}, k3sChannelServer, target.Channel); err != nil {
+				return err
+			}
+			fmt.Printf("Channel %s resolves to %s\n", target.Channel, version)
+		}
+
+		kube, err := clusterKubectl(ctx, path)
+		if err != nil {
+			return err
+		}
+
+		current, err := k3s.NodeVersions(ctx, kube)
+		if err != nil {
+			return err
+		}
+		if nodes, err = k3sNodeNames(nodes, current); err != nil {
+			return err
+		}
+		if err := k3s.CheckNodes(nodes, current); err != nil {
+			return err
+		}
+
+		steps := k3s.Plan(nodes, current, version, !k3sSkipDrain)
+		printK3sPlan(nodes, current, steps, version)
+
+		for _, step := range steps {
+			if step.Kind != k3s.StepUpgrade {
+				continue
+			}
+			if err := checkResourceGuardrails(step.Node.Name, nodeResourceProbe(path, step.Node)); err != nil {
+				return err
+			}
+		}
+
+		if isDryRun() {
+			fmt.Printf("[dry-run] set %s in %s\n", k3sPinAssignments(target, version), path)
+			return nil
+		}
+
+		upgrader := &k3s.Upgrader{
+			Kube:         kube,
+			RunScript:    runK3sScript(ctx, path),
+			Out:          os.Stdout,
+			DrainTimeout: k3sDrainTimeout,
+			ReadyTimeout: k3sReadyTimeout,
+			PollInterval: k3sPollInterval,
+		}
+		if err := upgrader.Execute(ctx, steps, version); err != nil {
+			return err
+		}
+		return pinK3sVersion(path, target, version)
+	},
+}
+
+var k3sPlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Generate system-upgrade-controller Plans for the pinned version",
+	Long: `Generate server and agent upgrade Plans for Rancher's system-upgrade-controller
+(install it with 'netcup-kube install system-upgrade-controller').
+
+This is the GitOps alternative to 'k3s upgrade': commit the Plans to the repo
+your GitOps tool syncs (or kubectl apply them) and the controller upgrades
+servers first, then agents, inside the cluster.
+
+The target defaults to K3S_VERSION from the env file (as pinned by
+'k3s upgrade'), then CHANNEL; --version/--channel override it.
+
+Examples:
+  netcup-kube k3s plan | kubectl apply -f -
+  netcup-kube k3s plan --version v1.31.4+k3s1 --output gitops/k3s-upgrade.yaml
+  netcup-kube k3s plan --channel stable --drain --concurrency 2`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := k3sPlanTarget(k3sChannel, k3sVersion, cfg.Env)
+		plans, err := k3s.RenderUpgradePlans(k3s.PlanOptions{
+			Target:      target,
+			Namespace:   k3sPlanNamespace,
+			Concurrency: k3sPlanConcurrency,
+			Drain:       k3sPlanDrain,
+		})
+		if err != nil {
+			return err
+		}
+		if k3sPlanOutput == "" || k3sPlanOutput == "-" {
+			fmt.Print(plans)
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(k3sPlanOutput), 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(k3sPlanOutput), err)
+		}
+		if err := os.WriteFile(k3sPlanOutput, []byte(plans), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", k3sPlanOutput, err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote upgrade Plans for %s to %s\n", target, k3sPlanOutput)
+		return nil
+	},
+}
+
+// k3sPlanTarget picks the Plan target: flags first, then the pinned
+// K3S_VERSION, then CHANNEL, then the default channel.
+func k3sPlanTarget(channel, version string, env map[string]string) k3s.Target {
+	switch {
+	case channel != "" || version != "":
+		return k3s.Target{Channel: channel, Version: version}
+	case env["K3S_VERSION"] != "":
+		return k3s.Target{Version: env["K3S_VERSION"]}
+	case env["CHANNEL"] != "":
+		return k3s.Target{Channel: env["CHANNEL"]}
+	}
+	return k3s.Target{Channel: k3s.DefaultChannel}
+}
+
+// clusterKubectl points KUBECONFIG at the cluster (starting the SSH tunnel
+// when needed) and returns a kubectl runner for it.
+func clusterKubectl(ctx context.Context, envPath string) (*kubectl.Runner, error) {
+	root, err := workspaceRoot()
+	if err != nil {
+		return nil, err
+	}
+	kubeconfig, err := resolveKubeconfig(ctx, envPath, filepath.Join(root, "config", "k3s.yaml"), root)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Setenv("KUBECONFIG", kubeconfig); err != nil {
+		return nil, err
+	}
+	return kubectl.New(), nil
+}
+
+// k3sUpgradeNodes returns the nodes to upgrade: every inventory node, or a
+// single server at MGMT_HOST whose node name is looked up later.
+func k3sUpgradeNodes(inventoryPath string) ([]inventory.Node, error) {
+	if inventoryPath == "" {
+		host := cfg.Env["MGMT_HOST"]
+		if host == "" {
+			host = cfg.Env["MGMT_IP"]
+		}
+		if host == "" {
+			return nil, fmt.Errorf("no MGMT_HOST/MGMT_IP configured; set it or pass --inventory")
+		}
+		return []inventory.Node{{Host: host, Role: inventory.RoleServer}}, nil
+	}
+	inv, err := inventory.Load(inventoryPath)
+	if err != nil {
+		return nil, err
+	}
+	return inv.Nodes(), nil
+}
+
+// k3sNodeNames fills in the node name for the single-host (no inventory)
+// case, which is only unambiguous on a single-node cluster.
+func k3sNodeNames(nodes []inventory.Node, current map[string]string) ([]inventory.Node, error) {
+	if len(nodes) != 1 || nodes[0].Name != "" {
+		return nodes, nil
+	}
+	if len(current) != 1 {
+		return nil, fmt.Errorf("the cluster has %d nodes; pass --inventory to upgrade a multi-node cluster", len(current))
+	}
+	for name := range current {
+		nodes[0].Name = name
+	}
+	return nodes, nil
+}
+
+func printK3sPlan(nodes []inventory.Node, current map[string]string, steps []k3s.Step, version string) {
+	fmt.Printf("Target version: %s\n", version)
+	for _, node := range nodes {
+		state := "upgrade"
+		if current[node.Name] == version {
+			state = "up to date"
+		}
+		fmt.Printf("  %-20s %-7s %-24s %s\n", node.Name, node.Role, orDash(current[node.Name]), state)
+	}
+	if len(steps) == 0 {
+		fmt.Println("All nodes are already at the target version.")
+		return
+	}
+	fmt.Println("Planned sequence:")
+	for i, step := range steps {
+		fmt.Printf("  %d. %s\n", i+1, step.Describe(version))
+	}
+}
+
+// runK3sScript runs node scripts over SSH as the node's inventory user,
+// falling back to MGMT_USER from the env file. Canceling ctx stops a running
+// script.
+func runK3sScript(ctx context.Context, envPath string) k3s.ScriptRunner {
+	return func(node inventory.Node, script string, args []string) error {
+		rc := remote.NewConfig()
+		applyInventoryNode(rc, node)
+		if err := rc.LoadConfigFromEnv(envPath); err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		return sshClient(ctx, rc.Host, rc.User).ExecuteScript(script, args)
+	}
+}
+
+func k3sPinAssignments(target k3s.Target, version string) string {
+	assignments := "K3S_VERSION=" + version
+	if target.Channel != "" {
+		assignments += " CHANNEL=" + target.Channel
+	}
+	return assignments
+}
+
+// pinK3sVersion records the upgraded version in the env file
+func pinK3sVersion(path string, target k3s.Target, version string) error {
+	unlock, err := lockEnvFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	original, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	updated := config.SetEnvValue(string(original), "K3S_VERSION", version)
+	if target.Channel != "" {
+		updated = config.SetEnvValue(updated, "CHANNEL", target.Channel)
+	}
+	if updated == string(original) {
+		fmt.Printf("k3s %s already pinned in %s\n", version, path)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	stage, err := os.CreateTemp(filepath.Dir(path), ".netcup-kube-k3s-*.env")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	stagePath := stage.Name()
+	_ = stage.Close()
+	defer func() { _ = os.Remove(stagePath) }()
+	if err := writeFileAtomic(path, stagePath, []byte(updated)); err != nil {
+		return err
+	}
+
+	fmt.Printf("Pinned k3s %s in %s\n", version, path)
+	printConfigDiff(os.Stdout, config.DiffEnv(string(original), updated))
+	return nil
+}
+
+func init() {
+	k3sUpgradeCmd.Flags().StringVar(&k3sChannel, "channel", "", "Release channel to upgrade to (e.g. stable, latest, v1.31)")
+	k3sUpgradeCmd.Flags().StringVar(&k3sVersion, "version", "", "Exact k3s version to upgrade to (e.g. v1.31.4+k3s1)")
+	k3sUpgradeCmd.Flags().StringVar(&k3sInventory, "inventory", "", "Cluster inventory file (YAML); default: the single node at MGMT_HOST")
+	k3sUpgradeCmd.Flags().BoolVar(&k3sSkipDrain, "skip-drain", false, "Do not cordon/drain nodes before upgrading them")
+	k3sUpgradeCmd.Flags().DurationVar(&k3sDrainTimeout, "drain-timeout", 5*time.Minute, "Timeout for draining a node")
+	k3sUpgradeCmd.Flags().DurationVar(&k3sReadyTimeout, "ready-timeout", 10*time.Minute, "Timeout for a node to become Ready after upgrading")
+	k3sCmd.AddCommand(k3sUpgradeCmd)
+
+	k3sPlanCmd.Flags().StringVar(&k3sChannel, "channel", "", "Release channel the controller follows (default: pinned K3S_VERSION, then CHANNEL)")
+	k3sPlanCmd.Flags().StringVar(&k3sVersion, "version", "", "Exact k3s version to upgrade to (default: pinned K3S_VERSION)")
+	k3sPlanCmd.Flags().StringVar(&k3sPlanNamespace, "namespace", k3s.DefaultPlanNamespace, "Namespace watched by system-upgrade-controller")
+	k3sPlanCmd.Flags().IntVar(&k3sPlanConcurrency, "concurrency", 1, "Number of agents upgraded at the same time")
+	k3sPlanCmd.Flags().BoolVar(&k3sPlanDrain, "drain", false, "Drain agents before upgrading (default: cordon only)")
+	k3sPlanCmd.Flags().StringVarP(&k3sPlanOutput, "output", "o", "", "Write the Plans to a file instead of stdout")
+	k3sCmd.AddCommand(k3sPlanCmd)
+}
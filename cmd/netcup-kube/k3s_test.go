@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/k3s"
+)
+
+func TestPinK3sVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netcup-kube.env")
+	original := "# k3s\nCHANNEL=stable\nBASE_DOMAIN=example.com\n"
+	if err := os.WriteFile(path, []byte(original), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pinK3sVersion(path, k3s.Target{Channel: "v1.31"}, "v1.31.4+k3s1"); err != nil {
+		t.Fatalf("pinK3sVersion() error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	want := "# k3s\nCHANNEL=v1.31\nBASE_DOMAIN=example.com\nK3S_VERSION=v1.31.4+k3s1\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, want 0640", info.Mode().Perm())
+	}
+	assertNoTempFiles(t, filepath.Dir(path))
+}
+
+func TestK3sNodeNames(t *testing.T) {
+	single := []inventory.Node{{Host: "203.0.113.10", Role: inventory.RoleServer}}
+	nodes, err := k3sNodeNames(single, map[string]string{"mgmt": "v1.30.0+k3s1"})
+	if err != nil {
+		t.Fatalf("k3sNodeNames() error: %v", err)
+	}
+	if nodes[0].Name != "mgmt" {
+		t.Errorf("name = %q, want mgmt", nodes[0].Name)
+	}
+
+	single = []inventory.Node{{Host: "203.0.113.10", Role: inventory.RoleServer}}
+	if _, err := k3sNodeNames(single, map[string]string{"mgmt": "", "worker-1": ""}); err == nil {
+		t.Error("k3sNodeNames() expected error for a multi-node cluster without inventory")
+	}
+}
+
+func TestK3sPlanTarget(t *testing.T) {
+	env := map[string]string{"K3S_VERSION": "v1.31.4+k3s1", "CHANNEL": "stable"}
+	tests := []struct {
+		name             string
+		channel, version string
+		env              map[string]string
+		want             k3s.Target
+	}{
+		{"flag wins", "latest", "", env, k3s.Target{Channel: "latest"}},
+		{"pinned version", "", "", env, k3s.Target{Version: "v1.31.4+k3s1"}},
+		{"channel", "", "", map[string]string{"CHANNEL": "v1.30"}, k3s.Target{Channel: "v1.30"}},
+		{"default", "", "", map[string]string{}, k3s.Target{Channel: k3s.DefaultChannel}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := k3sPlanTarget(tt.channel, tt.version, tt.env); got != tt.want {
+				t.Errorf("k3sPlanTarget() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRestartTargets(t *testing.T) {
+	got, err := parseRestartTargets([]string{"kube-system/metrics-server", " traefik/traefik "})
+	if err != nil {
+		t.Fatalf("parseRestartTargets() error: %v", err)
+	}
+	if len(got) != 2 || got[0] != [2]string{"kube-system", "metrics-server"} || got[1] != [2]string{"traefik", "traefik"} {
+		t.Errorf("parseRestartTargets() = %v", got)
+	}
+	for _, bad := range []string{"metrics-server", "/x", "ns/", "a/b/c"} {
+		if _, err := parseRestartTargets([]string{bad}); err == nil {
+			t.Errorf("parseRestartTargets(%q) expected error", bad)
+		}
+	}
+}
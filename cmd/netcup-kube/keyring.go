@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/keyring"
+	"github.com/spf13/cobra"
+)
+
+var keyringCmd = &cobra.Command{
+	Use:   "keyring",
+	Short: "Store secrets in the OS keychain for keyring:<name> config references",
+	Long: `Store secrets such as ROOT_PASS, Netcup API tokens, and webhook URLs in the
+operating system's credential store instead of plaintext env values: the macOS
+Keychain, libsecret (secret-tool) on Linux, or the Windows Credential Manager.
+
+Reference a stored secret from the env file or the environment as
+keyring:<name>; netcup-kube and netcup-claw replace it with the secret when
+the value is used. References that cannot be resolved are reported as a
+warning and left unset.
+
+Sub-commands:
+  set     - Store a secret (read from stdin, or prompted without echo)
+  get     - Print a stored secret
+  delete  - Remove a stored secret
+
+Examples:
+  netcup-kube keyring set root-pass
+  echo "$TOKEN" | netcup-kube keyring set netcup-scp-refresh-token
+  netcup-kube config set ROOT_PASS=keyring:root-pass
+  NETCUP_SCP_REFRESH_TOKEN=keyring:netcup-scp-refresh-token netcup-kube node list-vps`,
+	SilenceUsage: true,
+}
+
+var keyringSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Store a secret under name",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := keyring.ValidateName(args[0]); err != nil {
+			return err
+		}
+		secret, err := readSecret(os.Stdin, fmt.Sprintf("Secret for %s: ", args[0]))
+		if err != nil {
+			return err
+		}
+		if err := keyring.New().Set(args[0], secret); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %s; reference it as %s%s\n", args[0], keyring.RefPrefix, args[0])
+		return nil
+	},
+}
+
+var keyringGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print the secret stored under name",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		secret, err := keyring.New().Get(args[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		fmt.Println(secret)
+		return nil
+	},
+}
+
+var keyringDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Remove the secret stored under name",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := keyring.New().Delete(args[0]); err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		fmt.Printf("Deleted %s\n", args[0])
+		return nil
+	},
+}
+
+// readSecret reads one line from in. On a terminal it prompts on stderr and
+// turns off echo while the secret is typed.
+func readSecret(in *os.File, prompt string) (string, error) {
+	info, err := in.Stat()
+	terminal := err == nil && info.Mode()&os.ModeCharDevice != 0
+	if terminal {
+		fmt.Fprint(os.Stderr, prompt)
+		if runtime.GOOS != "windows" && setEcho(in, false) == nil {
+			defer func() {
+				_ = setEcho(in, true)
+				fmt.Fprintln(os.Stderr)
+			}()
+		}
+	}
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	secret := strings.TrimRight(line, "\r\n")
+	if secret == "" {
+		return "", errors.New("no secret given")
+	}
+	return secret, nil
+}
+
+func setEcho(tty *os.File, on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	stty := exec.Command("stty", mode)
+	stty.Stdin = tty
+	return stty.Run()
+}
+
+func init() {
+	keyringCmd.AddCommand(keyringSetCmd)
+	keyringCmd.AddCommand(keyringGetCmd)
+	keyringCmd.AddCommand(keyringDeleteCmd)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/encfile"
+	"github.com/mfittko/netcup-kube/internal/paths"
+)
+
+const (
+	// kubeconfigEncMagic prefixes the encrypted kubeconfig cache
+	kubeconfigEncMagic = "netcup-kube-kubeconfig-v1\n"
+
+	// kubeconfigPassphraseEnv holds the passphrase for the encrypted cache
+	kubeconfigPassphraseEnv = "KUBECONFIG_PASSPHRASE"
+	// kubeconfigPassphraseFileEnv names a file holding the passphrase; it may
+	// also be set in the env file
+	kubeconfigPassphraseFileEnv = "KUBECONFIG_PASSPHRASE_FILE"
+)
+
+// plaintextKubeconfigs are the decrypted kubeconfigs this process wrote to
+// the runtime directory. main removes them once the command returned, also
+// after Ctrl-C, so the plaintext does not outlive the command.
+var plaintextKubeconfigs struct {
+	sync.Mutex
+	paths []string
+}
+
+// trackPlaintextKubeconfig schedules path for removal when the command ends
+func trackPlaintextKubeconfig(path string) {
+	plaintextKubeconfigs.Lock()
+	defer plaintextKubeconfigs.Unlock()
+	plaintextKubeconfigs.paths = append(plaintextKubeconfigs.paths, path)
+}
+
+// removePlaintextKubeconfigs deletes the decrypted kubeconfigs written by
+// this process
+func removePlaintextKubeconfigs() {
+	plaintextKubeconfigs.Lock()
+	defer plaintextKubeconfigs.Unlock()
+	for _, path := range plaintextKubeconfigs.paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove decrypted kubeconfig: %v\n", err)
+		}
+	}
+	plaintextKubeconfigs.paths = nil
+}
+
+// kubeconfigPassphrase returns the passphrase for the kubeconfig cache, or ""
+// when encryption is not configured
+func kubeconfigPassphrase(envFile string) (string, error) {
+	if passphrase := os.Getenv(kubeconfigPassphraseEnv); passphrase != "" {
+		return passphrase, nil
+	}
+	path := strings.TrimSpace(os.Getenv(kubeconfigPassphraseFileEnv))
+	if path == "" {
+		if env, err := config.LoadEnvFileToMap(envFile); err == nil {
+			path = strings.TrimSpace(env[kubeconfigPassphraseFileEnv])
+		}
+	}
+	if path == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[2:])
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read kubeconfig passphrase file: %w", err)
+	}
+	passphrase := strings.TrimRight(string(data), "\r\n")
+	if passphrase == "" {
+		return "", fmt.Errorf("kubeconfig passphrase file %s is empty", path)
+	}
+	return passphrase, nil
+}
+
+// cachedKubeconfig returns a usable path for the kubeconfig cached at
+// localKubeconfig, fetching it from the server when missing.
+//
+// Without a passphrase the cache is the plaintext file as before. With one,
+// the cache is stored as <localKubeconfig>.enc; a plaintext cache left over
+// from earlier runs is encrypted and removed, and each call decrypts the
+// cache into a file in the user's private runtime directory that is removed
+// when the command ends (see removePlaintextKubeconfigs).
+func cachedKubeconfig(ctx context.Context, envFile, localKubeconfig string) (string, error) {
+	passphrase, err := kubeconfigPassphrase(envFile)
+	if err != nil {
+		return "", err
+	}
+	encrypted := localKubeconfig + ".enc"
+
+	if passphrase == "" {
+		if _, err := os.Stat(localKubeconfig); err != nil {
+			if _, err := os.Stat(encrypted); err == nil {
+				return "", fmt.Errorf("kubeconfig cache %s is encrypted; set %s or %s", encrypted, kubeconfigPassphraseEnv, kubeconfigPassphraseFileEnv)
+			}
+			fmt.Printf("Kubeconfig %s not found. Fetching from remote...\n", localKubeconfig)
+			if err := fetchKubeconfig(ctx, envFile, localKubeconfig, filepath.Dir(localKubeconfig)); err != nil {
+				return "", err
+			}
+			fmt.Printf("Kubeconfig saved to %s\n", localKubeconfig)
+		}
+		return localKubeconfig, nil
+	}
+
+	decrypted, err := decryptedKubeconfigPath(encrypted)
+	if err != nil {
+		return "", err
+	}
+	trackPlaintextKubeconfig(decrypted)
+	if plain, err := os.ReadFile(localKubeconfig); err == nil {
+		if err := writeEncryptedKubeconfig(encrypted, passphrase, plain); err != nil {
+			return "", err
+		}
+		if err := os.Remove(localKubeconfig); err != nil {
+			return "", fmt.Errorf("failed to remove plaintext kubeconfig: %w", err)
+		}
+		fmt.Printf("Encrypted kubeconfig cache to %s\n", encrypted)
+	} else if _, err := os.Stat(encrypted); err != nil {
+		// Fetch straight into the private runtime directory so the plaintext
+		// never lands in the project directory
+		fmt.Printf("Kubeconfig %s not found. Fetching from remote...\n", encrypted)
+		if err := fetchKubeconfig(ctx, envFile, decrypted, filepath.Dir(decrypted)); err != nil {
+			return "", err
+		}
+		plain, err := os.ReadFile(decrypted)
+		if err != nil {
+			return "", fmt.Errorf("failed to read fetched kubeconfig: %w", err)
+		}
+		if err := writeEncryptedKubeconfig(encrypted, passphrase, plain); err != nil {
+			return "", err
+		}
+		fmt.Printf("Kubeconfig saved to %s\n", encrypted)
+	}
+
+	sealed, err := os.ReadFile(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to read kubeconfig cache: %w", err)
+	}
+	plain, err := encfile.Open(kubeconfigEncMagic, passphrase, sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to open kubeconfig cache %s: %w", encrypted, err)
+	}
+	if err := os.WriteFile(decrypted, plain, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write decrypted kubeconfig: %w", err)
+	}
+	if err := os.Chmod(decrypted, 0o600); err != nil {
+		return "", err
+	}
+	return decrypted, nil
+}
+
+// writeEncryptedKubeconfig seals plain into path, replacing it atomically
+func writeEncryptedKubeconfig(path, passphrase string, plain []byte) error {
+	sealed, err := encfile.Seal(kubeconfigEncMagic, passphrase, plain)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt kubeconfig: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0o600); err != nil {
+		return fmt.Errorf("failed to write encrypted kubeconfig: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write encrypted kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// decryptedKubeconfigPath returns where the cache at encrypted is decrypted
+// to: the private runtime directory of netcup-kube ($XDG_RUNTIME_DIR/netcup-kube,
+// usually a per-user tmpfs). The file name is keyed by the cache path and the
+// process, so neither several checkouts nor concurrent commands share it.
+func decryptedKubeconfigPath(encrypted string) (string, error) {
+	dir := paths.RuntimeDir("netcup-kube")
+	if err := paths.EnsurePrivateDir(dir); err != nil {
+		return "", fmt.Errorf("refusing to decrypt kubeconfig: %w", err)
+	}
+	abs, err := filepath.Abs(encrypted)
+	if err != nil {
+		abs = encrypted
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(dir, fmt.Sprintf("k3s-%s-%d.yaml", hex.EncodeToString(sum[:4]), os.Getpid())), nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCachedKubeconfigEncryptsPlaintextCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(tmpDir, "run"))
+	t.Setenv(kubeconfigPassphraseEnv, "")
+	t.Setenv(kubeconfigPassphraseFileEnv, "")
+
+	passphraseFile := filepath.Join(tmpDir, "passphrase")
+	if err := os.WriteFile(passphraseFile, []byte("correct horse\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(tmpDir, "netcup-kube.env")
+	if err := os.WriteFile(envFile, []byte(kubeconfigPassphraseFileEnv+"="+passphraseFile+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	plain := []byte("apiVersion: v1\nkind: Config\nusers: []\n")
+	local := filepath.Join(tmpDir, "config", "k3s.yaml")
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(local, plain, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := cachedKubeconfig(context.Background(), envFile, local)
+	if err != nil {
+		t.Fatalf("cachedKubeconfig() error: %v", err)
+	}
+	if _, err := os.Stat(local); !os.IsNotExist(err) {
+		t.Errorf("plaintext cache still exists: %v", err)
+	}
+	sealed, err := os.ReadFile(local + ".enc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(sealed), "kind: Config") {
+		t.Error("encrypted cache contains the plaintext")
+	}
+	if !strings.HasPrefix(path, filepath.Join(tmpDir, "run", "netcup-kube")) {
+		t.Errorf("decrypted kubeconfig %s is not in the runtime dir", path)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil || string(got) != string(plain) {
+		t.Fatalf("decrypted kubeconfig = %q, %v", got, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("decrypted kubeconfig mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// The plaintext is removed when the command ends
+	removePlaintextKubeconfigs()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("decrypted kubeconfig still exists after the command: %v", err)
+	}
+
+	// Without the passphrase the encrypted cache is reported, not re-fetched
+	if err := os.WriteFile(envFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cachedKubeconfig(context.Background(), envFile, local); err == nil || !strings.Contains(err.Error(), "is encrypted") {
+		t.Errorf("cachedKubeconfig() without passphrase error = %v", err)
+	}
+
+	t.Setenv(kubeconfigPassphraseEnv, "wrong")
+	if _, err := cachedKubeconfig(context.Background(), envFile, local); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("cachedKubeconfig() with wrong passphrase error = %v", err)
+	}
+}
+
+func TestCachedKubeconfigPlaintextWithoutPassphrase(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv(kubeconfigPassphraseEnv, "")
+	t.Setenv(kubeconfigPassphraseFileEnv, "")
+	local := filepath.Join(tmpDir, "k3s.yaml")
+	if err := os.WriteFile(local, []byte("kind: Config\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path, err := cachedKubeconfig(context.Background(), filepath.Join(tmpDir, "missing.env"), local)
+	if err != nil || path != local {
+		t.Fatalf("cachedKubeconfig() = %q, %v; want %q", path, err, local)
+	}
+}
@@ -1,15 +1,19 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/mfittko/netcup-kube/internal/config"
 	"github.com/mfittko/netcup-kube/internal/executor"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/keyring"
 	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
 	"github.com/mfittko/netcup-kube/internal/validation"
 	"github.com/spf13/cobra"
 )
@@ -24,6 +28,9 @@ var (
 	envFile          string
 	dryRun           bool
 	dryRunWriteFiles bool
+
+	bootstrapRenderDir string
+	bootstrapWizard    bool
 )
 
 // parseGlobalFlagsFromArgs manually parses global flags from args for commands with DisableFlagParsing.
@@ -86,39 +93,15 @@ and manage worker node joins.`,
 			}
 		}
 
-		// Initialize config
-		cfg = config.New()
-
-		// Load configuration in correct precedence order (lowest to highest priority):
-		// 1. environment variables (lowest priority)
-		// 2. env-file
-		// 3. command-line flags (highest priority)
-
-		// Load from environment first
-		cfg.LoadFromEnvironment()
-
-		// Load from env file (if specified or default exists) - this can override env vars
-		if envFile == "" {
-			// Try default location
-			homeEnvFile := filepath.Join("config", "netcup-kube.env")
-			if _, err := os.Stat(homeEnvFile); err == nil {
-				envFile = homeEnvFile
-			}
-		}
+		telemetry.FromContext(cmd.Context()).SetName(cmd.CommandPath())
 
-		if envFile != "" {
-			if err := cfg.LoadEnvFile(envFile); err != nil {
-				return fmt.Errorf("failed to load env file: %w", err)
-			}
-		}
-
-		// Apply dry-run flags last (these override everything)
-		if dryRun {
-			cfg.SetFlag("DRY_RUN", "true")
+		if err := applyJSONQuery(cmd); err != nil {
+			return err
 		}
-		if dryRunWriteFiles {
-			cfg.SetFlag("DRY_RUN_WRITE_FILES", "true")
+		if err := loadConfig(); err != nil {
+			return err
 		}
+		telemetry.FromContext(cmd.Context()).SetAttributes(telemetry.Bool("netcup_kube.dry_run", isDryRun()))
 
 		// Initialize executor
 		var err error
@@ -127,14 +110,59 @@ and manage worker node joins.`,
 			return fmt.Errorf("failed to initialize executor: %w", err)
 		}
 
-		return nil
+		return startCommandHooks(cmd.Context(), cmd, args)
 	},
 }
 
+// loadConfig builds cfg from the environment, the env file and the global flags
+func loadConfig() error {
+	// Initialize config
+	cfg = config.New()
+
+	// Load configuration in correct precedence order (lowest to highest priority):
+	// 1. environment variables (lowest priority)
+	// 2. env-file
+	// 3. settings of the --cluster (layered over the env file)
+	// 4. command-line flags (highest priority)
+
+	// Load from environment first
+	cfg.LoadFromEnvironment()
+
+	// Load from env file (if specified or default exists) - this can override env vars
+	if envFile == "" {
+		envFile = defaultEnvFile()
+	}
+
+	if envFile != "" {
+		if err := cfg.LoadEnvFile(envFile); err != nil {
+			return fmt.Errorf("failed to load env file: %w", err)
+		}
+	} else {
+		// Without an env file the --cluster settings still override the environment
+		cfg.ApplyEnvFileOverrides()
+	}
+
+	// Replace keyring:<name> references with the stored secrets
+	for _, err := range cfg.ResolveSecrets(keyring.IsRef, keyring.Resolve) {
+		fmt.Fprintf(os.Stderr, "Warning: %v; leaving it unset\n", err)
+	}
+
+	// Apply dry-run flags last (these override everything)
+	if dryRun {
+		cfg.SetFlag("DRY_RUN", "true")
+	}
+	if dryRunWriteFiles {
+		cfg.SetFlag("DRY_RUN_WRITE_FILES", "true")
+	}
+	return nil
+}
+
 func init() {
-	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", "Path to environment file (default: config/netcup-kube.env if exists)")
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", "Path to environment file (default: config/netcup-kube.env or ~/.config/netcup-kube/netcup-kube.env if exists)")
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Enable dry-run mode (no actual changes)")
 	rootCmd.PersistentFlags().BoolVar(&dryRunWriteFiles, "dry-run-write-files", false, "Dry-run but write config files")
+	// Consumed by main before cobra parses args; registered so it shows in --help
+	rootCmd.PersistentFlags().Bool("timings", false, "Print a per-phase duration breakdown (scripts, SSH, kubectl) on exit")
 
 	// Add subcommands
 	rootCmd.AddCommand(bootstrapCmd)
@@ -145,6 +173,27 @@ func init() {
 	rootCmd.AddCommand(remoteCmd)
 	rootCmd.AddCommand(installCmd)
 	rootCmd.AddCommand(sshCmd)
+	rootCmd.AddCommand(nodeCmd)
+	rootCmd.AddCommand(networkCmd)
+	rootCmd.AddCommand(wireguardCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(k3sCmd)
+	rootCmd.AddCommand(certsCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(storageCmd)
+	rootCmd.AddCommand(dashboardCmd)
+	rootCmd.AddCommand(monitoringCmd)
+	rootCmd.AddCommand(keyringCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(quotaCmd)
+	rootCmd.AddCommand(bundleCmd)
+	rootCmd.AddCommand(watchdogCmd)
+	rootCmd.AddCommand(serviceCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importTerraformCmd)
+	rootCmd.AddCommand(ingressCmd)
+	rootCmd.AddCommand(hostsCmd)
+	rootCmd.AddCommand(openCmd)
 }
 
 var bootstrapCmd = &cobra.Command{
@@ -155,15 +204,45 @@ var bootstrapCmd = &cobra.Command{
 This command installs k3s in server mode, configures Traefik to use NodePort,
 and optionally sets up Caddy for edge TLS and the Kubernetes Dashboard.
 
+With --wizard, the operator is interviewed about the domain, edge proxy,
+dashboard, vLAN NAT and firewall first. Answers are validated as they are
+given and saved to the env file, and the resulting plan is shown before
+bootstrap runs.
+
+With --render-dir, nothing is installed or applied (implies --dry-run); the
+generated files (k3s config, Traefik HelmChartConfig, Caddyfile, dashboard
+manifests and Helm release) are written below the directory, mirroring their
+target paths, for review or a GitOps commit.
+
 Examples:
   sudo netcup-kube bootstrap
   sudo netcup-kube bootstrap --dry-run
+  sudo netcup-kube bootstrap --wizard
+  sudo netcup-kube bootstrap --render-dir ./rendered
   sudo BASE_DOMAIN=example.com netcup-kube bootstrap`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Set MODE to bootstrap (though it's already the default)
 		cfg.SetFlag("MODE", "bootstrap")
+		if bootstrapWizard {
+			proceed, err := bootstrapWithWizard()
+			if err != nil || !proceed {
+				return err
+			}
+		}
+		if bootstrapRenderDir != "" {
+			dir, err := renderDir(bootstrapRenderDir)
+			if err != nil {
+				return err
+			}
+			cfg.SetFlag("RENDER_DIR", dir)
+			cfg.SetFlag("DRY_RUN", "true")
+			return scriptExecutor.ExecuteContext(cmd.Context(), "bootstrap", args, cfg.ToEnvSlice())
+		}
+		if err := checkResourceGuardrails("local node", localResourceProbe); err != nil {
+			return err
+		}
 
-		return scriptExecutor.Execute("bootstrap", args, cfg.ToEnvSlice())
+		return scriptExecutor.ExecuteContext(cmd.Context(), "bootstrap", args, cfg.ToEnvSlice())
 	},
 }
 
@@ -175,13 +254,36 @@ var joinCmd = &cobra.Command{
 Requires SERVER_URL and TOKEN (or TOKEN_FILE) to be set via environment
 variables or flags.
 
+--labels and --taints (NODE_LABELS, NODE_TAINTS) are applied when the node
+registers; join waits for the node to appear and warns about any that are
+missing. A node profile (--node-profile or NODE_PROFILE) adds the labels and
+taints of NODE_PROFILE_<NAME>_LABELS / _TAINTS from the env file, so worker
+classes stay consistent; --labels and --taints override entries with the same
+key.
+
 Examples:
   sudo SERVER_URL=https://x.x.x.x:6443 TOKEN=xxx netcup-kube join
-  sudo netcup-kube join --dry-run`,
+  sudo netcup-kube join --dry-run
+  sudo TOKEN=xxx netcup-kube join --inventory config/inventory.yaml --node worker-1
+  sudo netcup-kube join --labels role=worker,zone=vlan --taints dedicated=batch:NoSchedule
+  sudo netcup-kube join --node-profile batch`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if joinInventory != "" {
+			if err := applyJoinInventory(cfg, joinInventory, joinNode); err != nil {
+				return err
+			}
+		} else if joinNode != "" {
+			return fmt.Errorf("--node requires --inventory")
+		}
+		if err := applyNodeProfile(cfg, joinNodeProfile, joinLabels, joinTaints); err != nil {
+			return err
+		}
 		cfg.SetFlag("MODE", "join")
+		if err := checkResourceGuardrails("local node", localResourceProbe); err != nil {
+			return err
+		}
 
-		return scriptExecutor.Execute("join", args, cfg.ToEnvSlice())
+		return scriptExecutor.ExecuteContext(cmd.Context(), "join", args, cfg.ToEnvSlice())
 	},
 }
 
@@ -196,7 +298,14 @@ certificate for *.BASE_DOMAIN.
 
 HTTP-01 mode obtains certificates for specific hostnames.
 
+Sub-commands:
+  check        Verify the hostnames resolve to the node on public resolvers
+  debug-acme   Explain why Caddy fails to obtain a certificate for a host
+
 Examples:
+  # Check DNS propagation before requesting certificates
+  netcup-kube dns check
+
   # DNS-01 wildcard (default)
   sudo BASE_DOMAIN=example.com netcup-kube dns
   
@@ -211,13 +320,13 @@ Examples:
 		for _, arg := range args {
 			if arg == "-h" || arg == "--help" || arg == "help" {
 				// Pass through to the script to show its help
-				return scriptExecutor.Execute("dns", args, cfg.ToEnvSlice())
+				return scriptExecutor.ExecuteContext(cmd.Context(), "dns", args, cfg.ToEnvSlice())
 			}
 		}
 
 		// Filter out global flags from args
 		_, _, _, filteredArgs := parseGlobalFlagsFromArgs(args)
-		return scriptExecutor.Execute("dns", filteredArgs, cfg.ToEnvSlice())
+		return scriptExecutor.ExecuteContext(cmd.Context(), "dns", filteredArgs, cfg.ToEnvSlice())
 	},
 }
 
@@ -229,25 +338,33 @@ var pairCmd = &cobra.Command{
 Optionally opens UFW firewall on port 6443 for a specific source IP/CIDR.
 
 This command reads the join token from /var/lib/rancher/k3s/server/node-token
-and displays a ready-to-use join command for worker nodes.
+(or the agent token of the last 'token rotate') and displays a ready-to-use
+join command for worker nodes.
+
+To hand the join to a teammate without a plaintext token in chat, --qr
+renders it as a terminal QR code and --encrypt-to <recipient> prints it as an
+armored age blob for an age1... or SSH public key (both together put the
+blob in the QR code). qrencode and age are installed on demand.
 
 Examples:
   sudo netcup-kube pair
   sudo netcup-kube pair --allow-from 159.195.64.217
-  sudo netcup-kube pair --server-url https://152.53.136.34:6443`,
+  sudo netcup-kube pair --server-url https://152.53.136.34:6443
+  sudo netcup-kube pair --qr
+  sudo netcup-kube pair --encrypt-to "$(cat teammate.pub)"`,
 	DisableFlagParsing: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Check if help was requested
 		for _, arg := range args {
 			if arg == "-h" || arg == "--help" || arg == "help" {
 				// Pass through to the script to show its help
-				return scriptExecutor.Execute("pair", args, cfg.ToEnvSlice())
+				return scriptExecutor.ExecuteContext(cmd.Context(), "pair", args, cfg.ToEnvSlice())
 			}
 		}
 
 		// Filter out global flags from args
 		_, _, _, filteredArgs := parseGlobalFlagsFromArgs(args)
-		return scriptExecutor.Execute("pair", filteredArgs, cfg.ToEnvSlice())
+		return scriptExecutor.ExecuteContext(cmd.Context(), "pair", filteredArgs, cfg.ToEnvSlice())
 	},
 }
 
@@ -317,10 +434,58 @@ Examples:
 func init() {
 	// Add output flag to validate command only
 	validateCmd.Flags().StringP("output", "o", "text", "Output format: text or json")
+
+	bootstrapCmd.Flags().BoolVar(&bootstrapWizard, "wizard", false, "Interview for the main settings, save them to the env file and show the plan first")
+	bootstrapCmd.Flags().StringVar(&bootstrapRenderDir, "render-dir", "", "Write generated configs and manifests to this directory instead of applying them")
+
+	joinCmd.Flags().StringVar(&joinInventory, "inventory", "", "Cluster inventory file (YAML); applies node vars and derives SERVER_URL")
+	joinCmd.Flags().StringVar(&joinNode, "node", "", "Inventory node name for this host (default: match hostname)")
+	joinCmd.Flags().StringVar(&joinLabels, "labels", "", "Node labels as key=value,key2=value2 (default: $NODE_LABELS)")
+	joinCmd.Flags().StringVar(&joinTaints, "taints", "", "Node taints as key=value:Effect,... (default: $NODE_TAINTS)")
+	joinCmd.Flags().StringVar(&joinNodeProfile, "node-profile", "", "Node profile from NODE_PROFILE_<NAME>_LABELS/_TAINTS (default: $NODE_PROFILE)")
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// Opt-in tracing: spans are exported when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	telemetry.Init("netcup-kube", version)
+	args, timings := telemetry.StripTimingsFlag(os.Args[1:], nil)
+	if timings {
+		telemetry.EnableTimings("netcup-kube", version)
+	}
+	args, cluster, err := stripClusterFlag(args)
+	if err == nil {
+		err = selectCluster(cluster)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	rootCmd.SetArgs(args)
+	// Ctrl-C and SIGTERM cancel the command's context: child processes are
+	// stopped and cleanups run before exiting. A second signal exits at once.
+	ctx, stop := interrupt.Context(context.Background())
+	defer stop()
+	ctx, span := telemetry.Start(ctx, "netcup-kube")
+	start := time.Now()
+	handled, err := runPlugin(ctx, args)
+	if !handled {
+		err = finishCommandHooks(ctx, rootCmd.ExecuteContext(ctx))
+	}
+	removePlaintextKubeconfigs()
+	recordUsage(args, handled, start, err)
+	err = span.End(err)
+	if timings {
+		telemetry.WriteTimings(os.Stderr)
+	}
+	if shutdownErr := telemetry.Shutdown(context.Background()); shutdownErr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", shutdownErr)
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "interrupted: %v\n", err)
+			os.Exit(interrupt.ExitCode)
+		}
 		var exitErr executor.ExitCodeError
 		if errors.As(err, &exitErr) {
 			os.Exit(exitErr.Code)
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/monitoring"
+	"github.com/mfittko/netcup-kube/internal/portforward"
+	"github.com/spf13/cobra"
+)
+
+var (
+	monNamespace string
+	monRelease   string
+	monLocalPort string
+	monNoBrowser bool
+)
+
+var monitoringCmd = &cobra.Command{
+	Use:   "monitoring",
+	Short: "Access Grafana, Prometheus, and Alertmanager",
+	Long: `Reach the web UIs of the kube-prometheus-stack recipe
+('netcup-kube install kube-prometheus-stack').
+
+Sub-commands:
+  open          - Print Grafana credentials and open a UI in the browser
+  creds         - Print the Grafana admin username and password
+  port-forward  - Start, stop, or inspect background port-forwards
+  alerts        - List and add Alertmanager routes and receivers`,
+	SilenceUsage: true,
+}
+
+var monitoringOpenCmd = &cobra.Command{
+	Use:   "open [grafana|prometheus|alertmanager]",
+	Short: "Print Grafana credentials and open a UI in the browser",
+	Long: `Open a monitoring UI (default: grafana) in the browser.
+
+Grafana is opened through its ingress host when the recipe was installed with
+--host; otherwise, and for Prometheus and Alertmanager, a background
+port-forward is started (stop it with 'monitoring port-forward stop').
+Opening Grafana also prints the admin credentials.
+
+Examples:
+  netcup-kube monitoring open
+  netcup-kube monitoring open prometheus
+  netcup-kube monitoring open grafana --port 3300 --no-browser`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := "grafana"
+		if len(args) == 1 {
+			name = args[0]
+		}
+		component, err := monitoring.LookupComponent(name)
+		if err != nil {
+			return err
+		}
+		ctx, kube, err := monitoringSetup(cmd)
+		if err != nil {
+			return err
+		}
+		services, err := monitoring.ResolveServices(ctx, kube, monNamespace, monRelease)
+		if err != nil {
+			return err
+		}
+		svc, ok := services[component.Name]
+		if !ok {
+			return fmt.Errorf("no %s service found in namespace %s; is kube-prometheus-stack installed?", component.Name, monNamespace)
+		}
+
+		url := ""
+		if component.Name == "grafana" {
+			creds, err := monitoring.GrafanaCredentials(ctx, kube, monNamespace, svc.Name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Username: %s\nPassword: %s\n\n", creds.User, creds.Password)
+			host, err := monitoring.GrafanaHost(ctx, kube, monNamespace)
+			if err != nil {
+				return err
+			}
+			if host != "" {
+				url = "https://" + host + "/"
+			}
+		}
+		if url == "" {
+			localPort := component.LocalPort
+			if monLocalPort != "" {
+				localPort = monLocalPort
+			}
+			if err := startMonitoringForward(ctx, svc, localPort); err != nil {
+				return err
+			}
+			url = monitoring.LocalURL(localPort)
+		}
+
+		fmt.Printf("URL: %s\n", url)
+		if monNoBrowser {
+			return nil
+		}
+		return openBrowser(url)
+	},
+}
+
+var monitoringCredsCmd = &cobra.Command{
+	Use:   "creds",
+	Short: "Print the Grafana admin username and password",
+	Long: `Print the Grafana admin login stored in the chart's Secret.
+
+Examples:
+  netcup-kube monitoring creds`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, kube, err := monitoringSetup(cmd)
+		if err != nil {
+			return err
+		}
+		services, err := monitoring.ResolveServices(ctx, kube, monNamespace, monRelease)
+		if err != nil {
+			return err
+		}
+		svc, ok := services["grafana"]
+		if !ok {
+			return fmt.Errorf("no grafana service found in namespace %s; is kube-prometheus-stack installed?", monNamespace)
+		}
+		creds, err := monitoring.GrafanaCredentials(ctx, kube, monNamespace, svc.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Username: %s\nPassword: %s\n", creds.User, creds.Password)
+		return nil
+	},
+}
+
+var monitoringPortForwardCmd = &cobra.Command{
+	Use:   "port-forward",
+	Short: "Manage background port-forwards to the monitoring UIs",
+	Long: `Start, stop, or inspect background kubectl port-forwards to the monitoring
+UIs. Default local ports: grafana 3000, prometheus 9090, alertmanager 9093.`,
+	SilenceUsage: true,
+}
+
+var monitoringPortForwardStartCmd = &cobra.Command{
+	Use:   "start [component...]",
+	Short: "Start port-forwards (default: all components)",
+	Long: `Start background port-forwards. Already running forwards are left as they are.
+
+Examples:
+  netcup-kube monitoring port-forward start
+  netcup-kube monitoring port-forward start grafana --port 3300`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		components, err := monitoringComponents(args)
+		if err != nil {
+			return err
+		}
+		ctx, kube, err := monitoringSetup(cmd)
+		if err != nil {
+			return err
+		}
+		services, err := monitoring.ResolveServices(ctx, kube, monNamespace, monRelease)
+		if err != nil {
+			return err
+		}
+		for _, c := range components {
+			svc, ok := services[c.Name]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Warning: no %s service found in namespace %s; skipping\n", c.Name, monNamespace)
+				continue
+			}
+			if err := startMonitoringForward(ctx, svc, c.LocalPort); err != nil {
+				return err
+			}
+			fmt.Printf("%-12s %s -> %s\n", c.Name, monitoring.LocalURL(c.LocalPort), svc.Target())
+		}
+		return nil
+	},
+}
+
+var monitoringPortForwardStopCmd = &cobra.Command{
+	Use:   "stop [component...]",
+	Short: "Stop port-forwards (default: all components)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		components, err := monitoringComponents(args)
+		if err != nil {
+			return err
+		}
+		for _, c := range components {
+			if err := portforward.New(monNamespace, "", c.LocalPort, "").Stop(); err != nil {
+				return fmt.Errorf("failed to stop %s port-forward: %w", c.Name, err)
+			}
+			fmt.Printf("%s port-forward stopped (port: %s)\n", c.Name, c.LocalPort)
+		}
+		return nil
+	},
+}
+
+var monitoringPortForwardStatusCmd = &cobra.Command{
+	Use:   "status [component...]",
+	Short: "Show port-forward status (default: all components)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		components, err := monitoringComponents(args)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COMPONENT\tSTATE\tURL\tPID")
+		for _, c := range components {
+			st := portforward.New(monNamespace, "", c.LocalPort, "").Status()
+			pid := "-"
+			if st.PID > 0 {
+				pid = fmt.Sprintf("%d", st.PID)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, st.State, monitoring.LocalURL(c.LocalPort), pid)
+		}
+		return w.Flush()
+	},
+}
+
+func monitoringSetup(cmd *cobra.Command) (context.Context, monitoring.Kubectl, error) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	path, err := configFilePath()
+	if err != nil {
+		return nil, nil, err
+	}
+	kube, err := clusterKubectl(ctx, path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, kube, nil
+}
+
+// monitoringComponents resolves component arguments (all when none are given)
+// and applies --port, which only makes sense for a single component.
+func monitoringComponents(args []string) ([]monitoring.Component, error) {
+	if len(args) == 0 {
+		if monLocalPort != "" {
+			return nil, fmt.Errorf("--port requires a single component")
+		}
+		return monitoring.Components, nil
+	}
+	if monLocalPort != "" && len(args) > 1 {
+		return nil, fmt.Errorf("--port requires a single component")
+	}
+	components := make([]monitoring.Component, 0, len(args))
+	for _, name := range args {
+		c, err := monitoring.LookupComponent(name)
+		if err != nil {
+			return nil, err
+		}
+		if monLocalPort != "" {
+			c.LocalPort = monLocalPort
+		}
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+// startMonitoringForward starts (or reuses) the background forward for svc and
+// waits until the local port accepts connections. A forward started here is
+// stopped again when ctx is canceled while waiting.
+func startMonitoringForward(ctx context.Context, svc monitoring.Service, localPort string) error {
+	var opts []portforward.Option
+	if address := cfg.Env["PORT_FORWARD_ADDRESS"]; address != "" {
+		opts = append(opts, portforward.WithAddress(address))
+	}
+	mgr := portforward.New(monNamespace, svc.Target(), localPort, svc.Port, opts...)
+	alreadyRunning := mgr.Status().State == portforward.StateRunning
+	if err := mgr.Start(); err != nil {
+		return fmt.Errorf("failed to start %s port-forward: %w", svc.Component.Name, err)
+	}
+	if err := portforward.WaitReady(ctx, localPort, 10*time.Second); err != nil {
+		if ctx.Err() != nil && !alreadyRunning {
+			_ = mgr.Stop()
+		}
+		return err
+	}
+	return nil
+}
+
+func init() {
+	monitoringCmd.PersistentFlags().StringVarP(&monNamespace, "namespace", "n", monitoring.DefaultNamespace, "Monitoring namespace")
+	monitoringCmd.PersistentFlags().StringVar(&monRelease, "release", monitoring.DefaultRelease, "kube-prometheus-stack Helm release name")
+	for _, c := range []*cobra.Command{monitoringOpenCmd, monitoringPortForwardStartCmd, monitoringPortForwardStopCmd, monitoringPortForwardStatusCmd} {
+		c.Flags().StringVar(&monLocalPort, "port", "", "Local port (overrides the component default; single component only)")
+	}
+	monitoringOpenCmd.Flags().BoolVar(&monNoBrowser, "no-browser", false, "Only print the credentials and URL")
+
+	monitoringPortForwardCmd.AddCommand(monitoringPortForwardStartCmd)
+	monitoringPortForwardCmd.AddCommand(monitoringPortForwardStopCmd)
+	monitoringPortForwardCmd.AddCommand(monitoringPortForwardStatusCmd)
+	monitoringCmd.AddCommand(monitoringOpenCmd)
+	monitoringCmd.AddCommand(monitoringCredsCmd)
+	monitoringCmd.AddCommand(monitoringPortForwardCmd)
+}
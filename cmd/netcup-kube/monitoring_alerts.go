@@ -0,0 +1,276 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/keyring"
+	"github.com/mfittko/netcup-kube/internal/monitoring"
+	"github.com/spf13/cobra"
+)
+
+var (
+	alertsReceiver     string
+	alertsWebhookURL   string
+	alertsSlackURL     string
+	alertsSlackURLFile string
+	alertsSlackChannel string
+	alertsEmailTo      string
+	alertsEmailFrom    string
+	alertsSmarthost    string
+	alertsAuthUser     string
+	alertsAuthPassword string
+	alertsAuthPassFile string
+	alertsMatchers     []string
+	alertsGroupBy      []string
+	alertsContinue     bool
+	alertsNoResolved   bool
+	alertsReplace      bool
+	alertsNoReload     bool
+	alertsReloadWait   time.Duration
+)
+
+var monitoringAlertsCmd = &cobra.Command{
+	Use:   "alerts",
+	Short: "Manage Alertmanager routes and receivers",
+	Long: `Manage the Alertmanager configuration stored in the
+alertmanager-<release>-alertmanager Secret.
+
+The chart renders that Secret from its alertmanager.config value, so a later
+'netcup-kube install kube-prometheus-stack' overwrites changes made here.`,
+	SilenceUsage: true,
+}
+
+var monitoringAlertsRouteCmd = &cobra.Command{
+	Use:          "route",
+	Short:        "List or add alert routes",
+	SilenceUsage: true,
+}
+
+var monitoringAlertsRouteListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List child routes and receivers",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, kube, err := monitoringSetup(cmd)
+		if err != nil {
+			return err
+		}
+		data, err := monitoring.ReadAlertmanagerConfig(ctx, kube, monNamespace, monitoring.AlertmanagerSecretName(monRelease))
+		if err != nil {
+			return err
+		}
+		config, err := monitoring.ParseAlertmanagerConfig(data)
+		if err != nil {
+			return err
+		}
+		routes, err := config.Routes()
+		if err != nil {
+			return err
+		}
+		receivers, err := config.Receivers()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ROUTE\tRECEIVER\tMATCHERS\tCONTINUE")
+		for i, r := range routes {
+			matchers := strings.Join(r.Matchers, ", ")
+			if matchers == "" {
+				matchers = "(all)"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%t\n", i+1, r.Receiver, matchers, r.Continue)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		fmt.Println()
+
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "RECEIVER\tNOTIFIERS")
+		for _, r := range receivers {
+			notifiers := strings.Join(r.Kinds(), ", ")
+			if notifiers == "" {
+				notifiers = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\n", r.Name, notifiers)
+		}
+		return w.Flush()
+	},
+}
+
+var monitoringAlertsRouteAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a receiver and a route to it",
+	Long: `Add a webhook, Slack, or email receiver and a child route that sends
+matching alerts to it, then reload Alertmanager.
+
+Matchers use Alertmanager syntax (label=value, label!=value, label=~regex,
+label!~regex); without matchers the route receives every alert that reaches it.
+
+The Slack webhook URL and the SMTP password are secrets and are not taken on
+the command line: pass a keyring:<name> reference (see 'netcup-kube keyring
+set'), or a file with --slack-api-url-file / --auth-password-file ("-" reads
+stdin). With the global --dry-run the resulting configuration is printed
+instead of written.
+
+Examples:
+  netcup-kube monitoring alerts route add --receiver ops --webhook-url https://hooks.example.com/am --matcher 'severity="critical"'
+  netcup-kube monitoring alerts route add --receiver slack --slack-api-url keyring:slack-webhook --slack-channel '#alerts'
+  pass show slack/webhook | netcup-kube monitoring alerts route add --receiver slack --slack-api-url-file - --slack-channel '#alerts'
+  netcup-kube --dry-run monitoring alerts route add --receiver mail --email-to ops@example.com --email-from am@example.com --smarthost smtp.example.com:587`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		receiver, route, err := alertsReceiverAndRoute()
+		if err != nil {
+			return err
+		}
+
+		ctx, kube, err := monitoringSetup(cmd)
+		if err != nil {
+			return err
+		}
+		secret := monitoring.AlertmanagerSecretName(monRelease)
+		data, err := monitoring.ReadAlertmanagerConfig(ctx, kube, monNamespace, secret)
+		if err != nil {
+			return err
+		}
+		config, err := monitoring.ParseAlertmanagerConfig(data)
+		if err != nil {
+			return err
+		}
+		if err := config.AddRoute(route, receiver, alertsReplace); err != nil {
+			return err
+		}
+		updated, err := config.Marshal()
+		if err != nil {
+			return err
+		}
+
+		if isDryRun() {
+			fmt.Printf("[dry-run] Would write %s to secret %s/%s:\n\n%s", monitoring.AlertmanagerConfigKey, monNamespace, secret, updated)
+			return nil
+		}
+		if err := monitoring.WriteAlertmanagerConfig(ctx, kube, monNamespace, secret, updated); err != nil {
+			return err
+		}
+		fmt.Printf("Added receiver %s and its route to secret %s/%s\n", receiver.Name, monNamespace, secret)
+
+		if alertsNoReload {
+			return nil
+		}
+		fmt.Println("Reloading Alertmanager...")
+		if err := monitoring.ReloadAlertmanager(ctx, kube, monNamespace, monRelease, receiver, alertsReloadWait, 5*time.Second); err != nil {
+			return fmt.Errorf("the configuration was written, but Alertmanager still runs the previous one: %w", err)
+		}
+		fmt.Println("Alertmanager reloaded.")
+		return nil
+	},
+}
+
+// alertsReceiverAndRoute builds the receiver and route from the add flags;
+// validation happens when they are added to the configuration.
+func alertsReceiverAndRoute() (monitoring.Receiver, monitoring.Route, error) {
+	slackURL, err := alertsSecret("slack-api-url", alertsSlackURL, alertsSlackURLFile)
+	if err != nil {
+		return monitoring.Receiver{}, monitoring.Route{}, err
+	}
+	authPassword, err := alertsSecret("auth-password", alertsAuthPassword, alertsAuthPassFile)
+	if err != nil {
+		return monitoring.Receiver{}, monitoring.Route{}, err
+	}
+
+	receiver := monitoring.Receiver{Name: alertsReceiver}
+	if alertsWebhookURL != "" {
+		receiver.WebhookConfigs = append(receiver.WebhookConfigs, monitoring.WebhookConfig{
+			URL:          alertsWebhookURL,
+			SendResolved: !alertsNoResolved,
+		})
+	}
+	if slackURL != "" {
+		receiver.SlackConfigs = append(receiver.SlackConfigs, monitoring.SlackConfig{
+			APIURL:       slackURL,
+			Channel:      alertsSlackChannel,
+			SendResolved: !alertsNoResolved,
+		})
+	}
+	if alertsEmailTo != "" {
+		receiver.EmailConfigs = append(receiver.EmailConfigs, monitoring.EmailConfig{
+			To:           alertsEmailTo,
+			From:         alertsEmailFrom,
+			Smarthost:    alertsSmarthost,
+			AuthUsername: alertsAuthUser,
+			AuthPassword: authPassword,
+			SendResolved: !alertsNoResolved,
+		})
+	}
+	route := monitoring.Route{
+		Receiver: alertsReceiver,
+		Matchers: alertsMatchers,
+		GroupBy:  alertsGroupBy,
+		Continue: alertsContinue,
+	}
+	return receiver, route, nil
+}
+
+// alertsSecret returns the secret of flag name, given as a keyring:<name>
+// reference or read from file ("-" reads stdin). Plain values are refused:
+// they would show up in the process list and the shell history.
+func alertsSecret(name, value, file string) (string, error) {
+	switch {
+	case value != "" && file != "":
+		return "", fmt.Errorf("--%s and --%s-file are mutually exclusive", name, name)
+	case value != "":
+		if !keyring.IsRef(value) {
+			return "", fmt.Errorf("--%s takes a keyring:<name> reference, not the secret itself (or use --%s-file)", name, name)
+		}
+		return keyring.Resolve(value)
+	case file == "-":
+		return readSecret(os.Stdin, fmt.Sprintf("%s: ", name))
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read --%s-file: %w", name, err)
+		}
+		secret := strings.TrimRight(string(data), "\r\n")
+		if secret == "" {
+			return "", fmt.Errorf("--%s-file %s is empty", name, file)
+		}
+		return secret, nil
+	}
+	return "", nil
+}
+
+func init() {
+	f := monitoringAlertsRouteAddCmd.Flags()
+	f.StringVar(&alertsReceiver, "receiver", "", "Receiver name (required)")
+	f.StringVar(&alertsWebhookURL, "webhook-url", "", "Send alerts to this webhook URL")
+	f.StringVar(&alertsSlackURL, "slack-api-url", "", "Send alerts to the Slack incoming webhook URL stored under this keyring:<name> reference")
+	f.StringVar(&alertsSlackURLFile, "slack-api-url-file", "", "Send alerts to the Slack incoming webhook URL read from this file (- for stdin)")
+	f.StringVar(&alertsSlackChannel, "slack-channel", "", "Slack channel (#name or @user)")
+	f.StringVar(&alertsEmailTo, "email-to", "", "Send alerts to this email address")
+	f.StringVar(&alertsEmailFrom, "email-from", "", "Sender address (default: global smtp_from)")
+	f.StringVar(&alertsSmarthost, "smarthost", "", "SMTP server host:port (default: global smtp_smarthost)")
+	f.StringVar(&alertsAuthUser, "auth-username", "", "SMTP username")
+	f.StringVar(&alertsAuthPassword, "auth-password", "", "SMTP password as a keyring:<name> reference")
+	f.StringVar(&alertsAuthPassFile, "auth-password-file", "", "File holding the SMTP password (- for stdin)")
+	f.StringArrayVar(&alertsMatchers, "matcher", nil, "Alert matcher, e.g. severity=\"critical\" (repeatable)")
+	f.StringSliceVar(&alertsGroupBy, "group-by", nil, "Labels to group alerts by on this route")
+	f.BoolVar(&alertsContinue, "continue", false, "Keep matching sibling routes after this one")
+	f.BoolVar(&alertsNoResolved, "no-resolved", false, "Do not notify when alerts resolve")
+	f.BoolVar(&alertsReplace, "replace", false, "Replace an existing receiver of the same name and its routes")
+	f.BoolVar(&alertsNoReload, "no-reload", false, "Do not reload Alertmanager after writing")
+	f.DurationVar(&alertsReloadWait, "reload-timeout", 2*time.Minute, "How long to wait for Alertmanager to load the change")
+	_ = monitoringAlertsRouteAddCmd.MarkFlagRequired("receiver")
+	monitoringAlertsRouteAddCmd.MarkFlagsMutuallyExclusive("webhook-url", "slack-api-url", "slack-api-url-file", "email-to")
+	monitoringAlertsRouteAddCmd.MarkFlagsOneRequired("webhook-url", "slack-api-url", "slack-api-url-file", "email-to")
+
+	monitoringAlertsRouteCmd.AddCommand(monitoringAlertsRouteListCmd)
+	monitoringAlertsRouteCmd.AddCommand(monitoringAlertsRouteAddCmd)
+	monitoringAlertsCmd.AddCommand(monitoringAlertsRouteCmd)
+	monitoringCmd.AddCommand(monitoringAlertsCmd)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMonitoringComponents(t *testing.T) {
+	defer func() { monLocalPort = "" }()
+
+	all, err := monitoringComponents(nil)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("monitoringComponents(nil) = %v, %v", all, err)
+	}
+
+	monLocalPort = "3300"
+	if _, err := monitoringComponents(nil); err == nil {
+		t.Error("expected error for --port without a component")
+	}
+	if _, err := monitoringComponents([]string{"grafana", "prometheus"}); err == nil {
+		t.Error("expected error for --port with several components")
+	}
+	one, err := monitoringComponents([]string{"grafana"})
+	if err != nil || len(one) != 1 || one[0].LocalPort != "3300" {
+		t.Errorf("monitoringComponents(grafana) = %v, %v", one, err)
+	}
+
+	monLocalPort = ""
+	if _, err := monitoringComponents([]string{"loki"}); err == nil {
+		t.Error("expected error for unknown component")
+	}
+}
+
+func TestAlertsSecret(t *testing.T) {
+	if _, err := alertsSecret("slack-api-url", "https://hooks.slack.com/services/T/B/X", ""); err == nil || !strings.Contains(err.Error(), "keyring:<name>") {
+		t.Errorf("alertsSecret() with a plain secret error = %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "webhook")
+	if err := os.WriteFile(file, []byte("https://hooks.slack.com/services/T/B/X\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := alertsSecret("slack-api-url", "", file); err != nil || got != "https://hooks.slack.com/services/T/B/X" {
+		t.Errorf("alertsSecret() from file = %q, %v", got, err)
+	}
+	if _, err := alertsSecret("auth-password", "keyring:smtp", file); err == nil {
+		t.Error("alertsSecret() with a value and a file expected error")
+	}
+	if err := os.WriteFile(file, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := alertsSecret("auth-password", "", file); err == nil {
+		t.Error("alertsSecret() with an empty file expected error")
+	}
+	if got, err := alertsSecret("auth-password", "", ""); err != nil || got != "" {
+		t.Errorf("alertsSecret() unset = %q, %v", got, err)
+	}
+}
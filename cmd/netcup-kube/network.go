@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/mfittko/netcup-kube/internal/executor"
+	"github.com/mfittko/netcup-kube/internal/network"
+	"github.com/spf13/cobra"
+)
+
+var networkCmd = &cobra.Command{
+	Use:   "network",
+	Short: "Manage the Netcup vLAN interface and NAT declaratively",
+	Long: `Manage the Netcup vLAN interface, private addressing, and NAT rules on a node.
+
+The desired state is read from the same configuration as bootstrap:
+  PRIVATE_IFACE    vLAN interface (e.g. eth1)
+  VLAN_ADDRESS     interface address in CIDR form (default: NODE_IP + PRIVATE_CIDR prefix)
+  VLAN_MTU         optional MTU for the vLAN interface
+  NETWORK_BACKEND  netplan | networkd | auto (default: auto)
+  ENABLE_VLAN_NAT  enable egress NAT for vLAN-only nodes (requires PRIVATE_CIDR, PUBLIC_IFACE)
+
+Sub-commands:
+  plan   - Show the rendered configuration and any drift
+  apply  - Converge the host to the desired state
+  check  - Report drift and exit non-zero if any is found`,
+	SilenceUsage: true,
+}
+
+var networkPlanCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the rendered vLAN configuration and any drift",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, err := network.SpecFromEnv(cfg.Env)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("# %s (%s)\n%s\n", spec.ConfigPath(), spec.Backend, spec.RenderConfig())
+		if spec.NAT {
+			fmt.Printf("# %s\n%s\n", network.NATHelperPath, spec.RenderNATHelper())
+		}
+		printDrift(network.Detect(spec, network.LocalHost{Context: cmd.Context()}))
+		return nil
+	},
+}
+
+var networkApplyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Converge the vLAN interface and NAT rules to the desired state",
+	Long: `Write the vLAN interface config (netplan or systemd-networkd), reload it,
+and install NAT rules plus the vlan-nat.service boot unit when ENABLE_VLAN_NAT=true.
+Steps already in the desired state are skipped.
+
+Examples:
+  sudo PRIVATE_IFACE=eth1 NODE_IP=10.10.0.1 PRIVATE_CIDR=10.10.0.0/24 netcup-kube network apply
+  sudo netcup-kube network apply --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, err := network.SpecFromEnv(cfg.Env)
+		if err != nil {
+			return err
+		}
+
+		dry := isDryRun()
+		local := network.LocalHost{Context: cmd.Context()}
+		var host network.Host = local
+		if dry {
+			host = network.DryRunHost{Host: host, Out: os.Stdout}
+		} else if os.Geteuid() != 0 {
+			return fmt.Errorf("network apply must run as root (use sudo)")
+		}
+
+		if err := network.Apply(spec, host, os.Stdout); err != nil {
+			return err
+		}
+		if dry {
+			return nil
+		}
+
+		if drift := network.Detect(spec, local); len(drift) > 0 {
+			printDrift(drift)
+			return fmt.Errorf("network state still differs after apply")
+		}
+		fmt.Println("network: in sync")
+		return nil
+	},
+}
+
+var networkCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report drift from the desired vLAN state (exit 1 on drift)",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		spec, err := network.SpecFromEnv(cfg.Env)
+		if err != nil {
+			return err
+		}
+		drift := network.Detect(spec, network.LocalHost{Context: cmd.Context()})
+		printDrift(drift)
+		if len(drift) > 0 {
+			return executor.ExitCodeError{Code: 1}
+		}
+		return nil
+	},
+}
+
+func printDrift(drift []network.Drift) {
+	if len(drift) == 0 {
+		fmt.Println("network: in sync")
+		return
+	}
+	fmt.Printf("network: %d drift item(s)\n", len(drift))
+	for _, d := range drift {
+		fmt.Printf("  - %s\n", d)
+	}
+}
+
+func init() {
+	networkCmd.AddCommand(networkPlanCmd)
+	networkCmd.AddCommand(networkApplyCmd)
+	networkCmd.AddCommand(networkCheckCmd)
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/keyring"
+	"github.com/mfittko/netcup-kube/internal/netcupscp"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nodeUser         string
+	nodePubKey       string
+	nodeRepo         string
+	nodeServerURL    string
+	nodeTokenFile    string
+	nodeInventory    string
+	nodeJoinEnvFile  string
+	nodeYes          bool
+	nodeNoJoin       bool
+	nodeWaitTimeout  time.Duration
+	nodeSSHWait      time.Duration
+	nodePollInterval = 5 * time.Second
+)
+
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Short: "Manage worker vServers via the Netcup SCP API and show node usage",
+	Long: `Manage worker vServers through the Netcup Server Control Panel (SCP) API,
+and show the resource usage of the nodes over SSH.
+
+Authentication uses an SCP refresh (offline) token from NETCUP_SCP_REFRESH_TOKEN,
+or a short-lived NETCUP_SCP_ACCESS_TOKEN. NETCUP_SCP_API_URL overrides the API base URL.
+
+New vServers cannot be ordered through the SCP API; order them in the customer
+control panel first, then address them by name (or nickname).
+
+Sub-commands:
+  list-vps       - List vServers in the SCP account
+  start-vps      - Power on a vServer and wait until it is running
+  stop-vps       - Power off a vServer and wait until it is shut off
+  provision-vps  - Start a vServer, provision it, and join it to the cluster
+  top            - Show CPU, memory, disk and network usage of the nodes over SSH`,
+	SilenceUsage: true,
+}
+
+var nodeListVPSCmd = &cobra.Command{
+	Use:   "list-vps",
+	Short: "List vServers in the SCP account",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newSCPClient()
+		if err != nil {
+			return err
+		}
+		servers, err := client.ListServers(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tNAME\tNICKNAME\tHOSTNAME")
+		for _, s := range servers {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.ID, s.Name, s.Nickname, s.Hostname)
+		}
+		return w.Flush()
+	},
+}
+
+var nodeStartVPSCmd = &cobra.Command{
+	Use:   "start-vps <server>",
+	Short: "Power on a vServer and wait until it is running",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newSCPClient()
+		if err != nil {
+			return err
+		}
+		detail, err := ensureVPSState(cmd.Context(), client, args[0], netcupscp.StateOn, netcupscp.LiveStateRunning)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is running (%s)\n", detail.DisplayName(), detail.PrimaryIPv4())
+		return nil
+	},
+}
+
+var nodeStopVPSCmd = &cobra.Command{
+	Use:   "stop-vps <server>",
+	Short: "Power off a vServer and wait until it is shut off",
+	Long: `Power off a vServer and wait until it is shut off. Asks for confirmation
+unless --yes (or CONFIRM=true); with the global --dry-run nothing is changed.
+
+Examples:
+  netcup-kube node stop-vps worker-2
+  netcup-kube node stop-vps worker-2 --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newSCPClient()
+		if err != nil {
+			return err
+		}
+		if isDryRun() {
+			fmt.Printf("[dry-run] Would power off %s\n", args[0])
+			return nil
+		}
+		if err := confirm.New(nodeYes).Confirm("power off vServer " + args[0]); err != nil {
+			return err
+		}
+		detail, err := ensureVPSState(cmd.Context(), client, args[0], netcupscp.StateOff, netcupscp.LiveStateShutoff)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is shut off\n", detail.DisplayName())
+		return nil
+	},
+}
+
+var nodeProvisionVPSCmd = &cobra.Command{
+	Use:   "provision-vps <server>",
+	Short: "Start a vServer, provision it, and join it to the cluster",
+	Long: `Start a vServer via the SCP API, wait for SSH, provision it (sudo user +
+repo), upload the netcup-kube binary, and run 'netcup-kube join' on it.
+
+SERVER_URL comes from --server-url, $SERVER_URL, or the first server in
+--inventory. The join token is read from --token-file or $TOKEN and uploaded
+in a temporary env file (never passed on the command line).
+
+Examples:
+  TOKEN=xxx netcup-kube node provision-vps v2202501234567890 --server-url https://10.10.0.1:6443
+  netcup-kube node provision-vps worker-2 --inventory config/inventory.yaml --token-file ./node-token
+  netcup-kube node provision-vps worker-2 --no-join`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serverURL, token, err := resolveJoinCredentials()
+		if err != nil {
+			return err
+		}
+
+		client, err := newSCPClient()
+		if err != nil {
+			return err
+		}
+		detail, err := ensureVPSState(cmd.Context(), client, args[0], netcupscp.StateOn, netcupscp.LiveStateRunning)
+		if err != nil {
+			return err
+		}
+		host := detail.PrimaryIPv4()
+		if host == "" {
+			return fmt.Errorf("server %s has no IPv4 address", detail.DisplayName())
+		}
+		fmt.Printf("%s is running at %s\n", detail.DisplayName(), host)
+
+		if err := waitForSSH(host, nodeSSHWait); err != nil {
+			return err
+		}
+
+		cfg := remote.NewConfig()
+		cfg.Host = host
+		if nodeUser != "" {
+			cfg.User = nodeUser
+			cfg.UserExplicit = true
+		}
+		if nodePubKey != "" {
+			cfg.PubKeyPath = nodePubKey
+		}
+		if nodeRepo != "" {
+			cfg.RepoURL = nodeRepo
+		}
+
+		if err := remote.Provision(cfg); err != nil {
+			return fmt.Errorf("provisioning %s failed: %w", host, err)
+		}
+
+		if nodeNoJoin {
+			fmt.Printf("Provisioned %s; skipping join (--no-join)\n", host)
+			return nil
+		}
+
+		projectRoot, err := findProjectRoot()
+		if err != nil {
+			return fmt.Errorf("could not find project root: %w", err)
+		}
+		if err := remote.RemoteBuildAndUpload(sshClient(cmd.Context(), cfg.Host, cfg.User), cfg, projectRoot, remote.GitOptions{}); err != nil {
+			return err
+		}
+
+		envFile, cleanup, err := writeJoinEnvFile(nodeJoinEnvFile, serverURL, token)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		return remote.Run(cfg, remote.RunOptions{
+			Context:  cmd.Context(),
+			ForceTTY: false,
+			EnvFile:  envFile,
+			Args:     []string{"join"},
+		})
+	},
+}
+
+// newSCPClient builds an SCP client from NETCUP_SCP_* environment variables
+func newSCPClient() (*netcupscp.Client, error) {
+	refresh, err := keyring.Resolve(strings.TrimSpace(os.Getenv("NETCUP_SCP_REFRESH_TOKEN")))
+	if err != nil {
+		return nil, err
+	}
+	access, err := keyring.Resolve(strings.TrimSpace(os.Getenv("NETCUP_SCP_ACCESS_TOKEN")))
+	if err != nil {
+		return nil, err
+	}
+	if refresh == "" && access == "" {
+		return nil, fmt.Errorf("missing SCP credentials: set NETCUP_SCP_REFRESH_TOKEN (or NETCUP_SCP_ACCESS_TOKEN)")
+	}
+
+	var opts []netcupscp.Option
+	if access != "" {
+		opts = append(opts, netcupscp.WithAccessToken(access))
+	}
+	if base := strings.TrimSpace(os.Getenv("NETCUP_SCP_API_URL")); base != "" {
+		opts = append(opts, netcupscp.WithBaseURL(base))
+	}
+	return netcupscp.New(refresh, opts...), nil
+}
+
+// ensureVPSState switches a server to the requested power state (if needed)
+// and waits until its live state matches.
+func ensureVPSState(ctx context.Context, client *netcupscp.Client, name, powerState, liveState string) (*netcupscp.ServerDetail, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	server, err := client.FindServer(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	detail, err := client.GetServer(ctx, server.ID)
+	if err != nil {
+		return nil, err
+	}
+	if detail.State() == liveState {
+		return detail, nil
+	}
+
+	fmt.Printf("Setting %s to %s...\n", server.DisplayName(), powerState)
+	if _, err := client.SetState(ctx, server.ID, powerState); err != nil {
+		return nil, fmt.Errorf("failed to set %s to %s: %w", server.DisplayName(), powerState, err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, nodeWaitTimeout)
+	defer cancel()
+	return client.WaitForState(waitCtx, server.ID, liveState, nodePollInterval)
+}
+
+// resolveJoinCredentials returns the SERVER_URL and token for the join step
+func resolveJoinCredentials() (string, string, error) {
+	if nodeNoJoin {
+		return "", "", nil
+	}
+
+	serverURL := strings.TrimSpace(nodeServerURL)
+	if serverURL == "" {
+		serverURL = strings.TrimSpace(os.Getenv("SERVER_URL"))
+	}
+	if serverURL == "" && nodeInventory != "" {
+		inv, err := inventory.Load(nodeInventory)
+		if err != nil {
+			return "", "", err
+		}
+		serverURL = inv.ServerURL()
+	}
+	if serverURL == "" {
+		return "", "", fmt.Errorf("SERVER_URL is required for join: pass --server-url, set SERVER_URL, or use --inventory")
+	}
+
+	token := strings.TrimSpace(os.Getenv("TOKEN"))
+	if nodeTokenFile != "" {
+		data, err := os.ReadFile(nodeTokenFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		return "", "", fmt.Errorf("join token is required: pass --token-file or set TOKEN (from 'netcup-kube pair' on the management node)")
+	}
+	return serverURL, token, nil
+}
+
+// writeJoinEnvFile writes a private env file combining an optional base env
+// file with SERVER_URL and TOKEN, and returns a cleanup func.
+func writeJoinEnvFile(base, serverURL, token string) (string, func(), error) {
+	f, err := os.CreateTemp("", "netcup-kube-join-*.env")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("failed to create join env file: %w", err)
+	}
+	cleanup := func() { _ = os.Remove(f.Name()) }
+
+	var b strings.Builder
+	if base != "" {
+		data, err := os.ReadFile(base)
+		if err != nil {
+			_ = f.Close()
+			cleanup()
+			return "", func() {}, fmt.Errorf("failed to read --join-env-file: %w", err)
+		}
+		b.Write(data)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			b.WriteByte('\n')
+		}
+	}
+	fmt.Fprintf(&b, "SERVER_URL=%s\nTOKEN=%s\n", shellSingleQuote(serverURL), shellSingleQuote(token))
+
+	if err := f.Chmod(0o600); err != nil {
+		_ = f.Close()
+		cleanup()
+		return "", func() {}, fmt.Errorf("failed to secure join env file: %w", err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		_ = f.Close()
+		cleanup()
+		return "", func() {}, fmt.Errorf("failed to write join env file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("failed to write join env file: %w", err)
+	}
+	return f.Name(), cleanup, nil
+}
+
+func shellSingleQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+func sshPortOpen(host string) bool {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, "22"), 3*time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// waitForSSH waits until the host accepts TCP connections on port 22
+func waitForSSH(host string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if sshPortOpen(host) {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for SSH on %s", host)
+		}
+		time.Sleep(nodePollInterval)
+	}
+}
+
+func init() {
+	nodeCmd.PersistentFlags().DurationVar(&nodeWaitTimeout, "wait-timeout", 5*time.Minute, "How long to wait for power state changes")
+	nodeProvisionVPSCmd.Flags().StringVar(&nodeUser, "user", "", "Remote sudo user to create (default: cubeadmin)")
+	nodeProvisionVPSCmd.Flags().StringVar(&nodePubKey, "pubkey", "", "Path to SSH public key")
+	nodeProvisionVPSCmd.Flags().StringVar(&nodeRepo, "repo", "", "Repository URL (default: upstream netcup-kube)")
+	nodeProvisionVPSCmd.Flags().StringVar(&nodeServerURL, "server-url", "", "k3s server URL to join (default: $SERVER_URL or first inventory server)")
+	nodeProvisionVPSCmd.Flags().StringVar(&nodeTokenFile, "token-file", "", "Local file containing the join token (default: $TOKEN)")
+	nodeProvisionVPSCmd.Flags().StringVar(&nodeInventory, "inventory", "", "Cluster inventory file used to derive SERVER_URL")
+	// Not --env-file, which is the global CLI config file
+	nodeProvisionVPSCmd.Flags().StringVar(&nodeJoinEnvFile, "join-env-file", "", "Extra env file to source on the node before join")
+	nodeProvisionVPSCmd.Flags().BoolVar(&nodeNoJoin, "no-join", false, "Only start and provision the vServer; skip join")
+	nodeProvisionVPSCmd.Flags().DurationVar(&nodeSSHWait, "ssh-timeout", 3*time.Minute, "How long to wait for SSH after the vServer starts")
+	nodeStopVPSCmd.Flags().BoolVarP(&nodeYes, "yes", "y", false, "Power off without asking for confirmation")
+	nodeCmd.AddCommand(nodeListVPSCmd)
+	nodeCmd.AddCommand(nodeStartVPSCmd)
+	nodeCmd.AddCommand(nodeStopVPSCmd)
+	nodeCmd.AddCommand(nodeProvisionVPSCmd)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/remote"
+)
+
+func TestWriteJoinEnvFile(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "base.env")
+	if err := os.WriteFile(base, []byte("BASE_DOMAIN=example.com"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	path, cleanup, err := writeJoinEnvFile(base, "https://10.10.0.1:6443", "K10abc'def")
+	if err != nil {
+		t.Fatalf("writeJoinEnvFile() error: %v", err)
+	}
+	defer cleanup()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	data, _ := os.ReadFile(path)
+	want := "BASE_DOMAIN=example.com\nSERVER_URL='https://10.10.0.1:6443'\nTOKEN='K10abc'\"'\"'def'\n"
+	if string(data) != want {
+		t.Errorf("env file = %q, want %q", data, want)
+	}
+
+	cleanup()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("cleanup did not remove %s", path)
+	}
+}
+
+func TestResolveJoinCredentials(t *testing.T) {
+	defer func() {
+		nodeServerURL, nodeTokenFile, nodeInventory, nodeNoJoin = "", "", "", false
+	}()
+
+	t.Setenv("SERVER_URL", "")
+	t.Setenv("TOKEN", "")
+
+	nodeNoJoin = true
+	if _, _, err := resolveJoinCredentials(); err != nil {
+		t.Fatalf("--no-join should not require credentials: %v", err)
+	}
+	nodeNoJoin = false
+
+	if _, _, err := resolveJoinCredentials(); err == nil || !strings.Contains(err.Error(), "SERVER_URL") {
+		t.Fatalf("expected SERVER_URL error, got %v", err)
+	}
+
+	invPath := filepath.Join(t.TempDir(), "inventory.yaml")
+	if err := os.WriteFile(invPath, []byte(testInventory), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	nodeInventory = invPath
+	if _, _, err := resolveJoinCredentials(); err == nil || !strings.Contains(err.Error(), "token") {
+		t.Fatalf("expected token error, got %v", err)
+	}
+
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	nodeTokenFile = tokenPath
+	serverURL, token, err := resolveJoinCredentials()
+	if err != nil {
+		t.Fatalf("resolveJoinCredentials() error: %v", err)
+	}
+	if serverURL != "https://10.10.0.1:6443" || token != "secret" {
+		t.Errorf("got %q %q", serverURL, token)
+	}
+
+	nodeServerURL = "https://explicit:6443"
+	if serverURL, _, _ := resolveJoinCredentials(); serverURL != "https://explicit:6443" {
+		t.Errorf("--server-url not preferred: %q", serverURL)
+	}
+}
+
+func TestPrintNodeTop(t *testing.T) {
+	results := []nodeTopResult{
+		{Node: "server-1", NodeTop: &remote.NodeTop{
+			CPUs: 4, CPU: 20, Steal: 2.5, Load: [3]float64{0.52, 0.4, 0.31},
+			MemTotal: 8 << 30, MemAvailable: 6 << 30,
+			Disks:      []remote.NodeDisk{{Mount: "/", Size: 100 << 30, Used: 40 << 30}},
+			Interfaces: []remote.NodeInterface{{Name: "eth0", RxBytes: 2048, TxBytes: 512}, {Name: "lo", RxBytes: 1 << 20}},
+			Cgroups:    []remote.NodeCgroup{{Name: "k3s", CPUMilli: 250, Memory: 700 << 20}},
+		}},
+		{Node: "worker-1", Error: "failed to read node stats: exit status 255"},
+	}
+	var buf bytes.Buffer
+	if err := printNodeTop(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"server-1  20.0% of 4  2.5%   0.52  2.0Gi / 8.0Gi (25%)  / 40%  2.0Ki/s / 512B/s  250m 700.0Mi  -",
+		"worker-1  error       -      -     -",
+		"worker-1: failed to read node stats: exit status 255",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, buf.String())
+		}
+	}
+	if n := countNodeTopErrors(results); n != 1 {
+		t.Errorf("countNodeTopErrors() = %d", n)
+	}
+}
+
+func TestNodeStopVPSNeedsConfirmation(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "unexpected request", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	t.Setenv("NETCUP_SCP_ACCESS_TOKEN", "token")
+	t.Setenv("NETCUP_SCP_API_URL", srv.URL)
+	t.Setenv("CONFIRM", "")
+
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = config.New()
+	cfg.SetFlag("DRY_RUN", "true")
+	if err := nodeStopVPSCmd.RunE(nodeStopVPSCmd, []string{"worker-2"}); err != nil {
+		t.Fatalf("stop-vps --dry-run error: %v", err)
+	}
+
+	cfg = config.New()
+	if err := nodeStopVPSCmd.RunE(nodeStopVPSCmd, []string{"worker-2"}); err == nil {
+		t.Fatal("stop-vps without --yes succeeded non-interactively")
+	}
+	if requests != 0 {
+		t.Errorf("SCP API called %d times without confirmation", requests)
+	}
+}
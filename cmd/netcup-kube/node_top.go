@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/mfittko/netcup-kube/internal/report"
+	"github.com/mfittko/netcup-kube/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	nodeTopInventory string
+	nodeTopNode      string
+	nodeTopInterval  time.Duration
+	nodeTopOutput    string
+)
+
+var nodeTopCmd = &cobra.Command{
+	Use:   "top",
+	Short: "Show CPU, memory, disk and network usage of the nodes over SSH",
+	Long: `Show a quick view of node resource usage read from /proc and cgroup stats
+over SSH, so it works right after bootstrap, before metrics-server is up.
+
+CPU, network and the k3s/pod cgroup CPU are sampled over --interval; steal
+time shows contention on the vServer host. Memory excludes reclaimable
+caches, disks are / and /var/lib/rancher when it is a separate mount, and
+network sums the physical interfaces (pod veths, CNI and VPN devices are
+left out; -o json lists every interface).
+
+Without --inventory the node at MGMT_HOST is read; with it every node, or the
+one named by --node. Nodes are sampled in parallel.
+
+Examples:
+  netcup-kube node top
+  netcup-kube node top --inventory config/inventory.yaml
+  netcup-kube node top --inventory config/inventory.yaml --node worker-1 --interval 5s -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(nodeTopOutput)
+		if err != nil {
+			return err
+		}
+		if nodeTopInterval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+		targets, err := sshTargets(nodeTopInventory, nodeTopNode, true)
+		if err != nil {
+			return err
+		}
+
+		results := make([]nodeTopResult, len(targets))
+		var wg sync.WaitGroup
+		for i, t := range targets {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				results[i].Node = t.name
+				top, err := remote.GetNodeTop(t.client, nodeTopInterval)
+				if err != nil {
+					results[i].Error = err.Error()
+					return
+				}
+				results[i].NodeTop = &top
+			}()
+		}
+		wg.Wait()
+
+		if format == output.FormatJSON {
+			if err := output.WriteJSON(os.Stdout, results); err != nil {
+				return err
+			}
+		} else if err := printNodeTop(os.Stdout, results); err != nil {
+			return err
+		}
+		if n := countNodeTopErrors(results); n > 0 {
+			return fmt.Errorf("failed to read stats of %d node(s)", n)
+		}
+		return nil
+	},
+}
+
+// nodeTopResult is the usage of one node, or why it could not be read
+type nodeTopResult struct {
+	Node string `json:"node"`
+	*remote.NodeTop
+	Error string `json:"error,omitempty"`
+}
+
+func countNodeTopErrors(results []nodeTopResult) int {
+	n := 0
+	for _, r := range results {
+		if r.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func printNodeTop(out io.Writer, results []nodeTopResult) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NODE\tCPU\tSTEAL\tLOAD\tMEMORY\tDISK\tNET RX/TX\tK3S\tPODS")
+	for _, r := range results {
+		if r.NodeTop == nil {
+			_, _ = fmt.Fprintf(w, "%s\terror\t-\t-\t-\t-\t-\t-\t-\n", r.Node)
+			continue
+		}
+		t := r.NodeTop
+		rx, tx := t.Network()
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%.1f%%\t%.2f\t%s\t%s\t%s\t%s\t%s\n",
+			r.Node,
+			fmt.Sprintf("%.1f%% of %d", t.CPU, t.CPUs),
+			t.Steal,
+			t.Load[0],
+			storage.FormatBytes(t.MemUsed())+" / "+storage.FormatBytes(t.MemTotal)+" ("+report.Percent(t.MemUsed(), t.MemTotal)+")",
+			nodeTopDisks(t.Disks),
+			storage.FormatBytes(rx)+"/s / "+storage.FormatBytes(tx)+"/s",
+			nodeTopCgroup(t.Cgroups, "k3s"),
+			nodeTopCgroup(t.Cgroups, "pods"),
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			_, _ = fmt.Fprintf(out, "%s: %s\n", r.Node, r.Error)
+		}
+	}
+	return nil
+}
+
+func nodeTopDisks(disks []remote.NodeDisk) string {
+	if len(disks) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(disks))
+	for _, d := range disks {
+		parts = append(parts, d.Mount+" "+report.Percent(d.Used, d.Size))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func nodeTopCgroup(cgroups []remote.NodeCgroup, name string) string {
+	for _, c := range cgroups {
+		if c.Name == name {
+			return report.FormatCPU(c.CPUMilli) + " " + storage.FormatBytes(c.Memory)
+		}
+	}
+	return "-"
+}
+
+func init() {
+	nodeTopCmd.Flags().StringVar(&nodeTopInventory, "inventory", "", "Cluster inventory file (YAML); default: the single node at MGMT_HOST")
+	nodeTopCmd.Flags().StringVar(&nodeTopNode, "node", "", "Inventory node to read (default: every node)")
+	nodeTopCmd.Flags().DurationVar(&nodeTopInterval, "interval", time.Second, "Sampling interval for CPU and network rates (whole seconds)")
+	nodeTopCmd.Flags().StringVarP(&nodeTopOutput, "output", "o", "text", "Output format: text or json")
+	nodeCmd.AddCommand(nodeTopCmd)
+}
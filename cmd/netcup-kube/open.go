@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/dashboard"
+	"github.com/mfittko/netcup-kube/internal/ingress"
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/mfittko/netcup-kube/internal/monitoring"
+	"github.com/mfittko/netcup-kube/internal/portforward"
+	"github.com/mfittko/netcup-kube/internal/webui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	openLocalPort   string
+	openNoBrowser   bool
+	openNoClipboard bool
+	openShowSecret  bool
+)
+
+var openCmd = &cobra.Command{
+	Use:   "open <recipe|namespace/service[:port]>",
+	Short: "Open a recipe's web UI in the browser, with its login on the clipboard",
+	Long: `Open the web UI of a recipe (dashboard, grafana, prometheus, alertmanager,
+argo-cd, redisinsight, openclaw) or of any Service in the browser.
+
+The URL is the host of an Ingress routing to the Service, then the Caddy
+host of the config (DASH_HOST, OPENCLAW_HOST), and otherwise a port-forward
+to localhost that keeps running in the background (its PID is printed; a
+running forward on the same port is reused). PORT_FORWARD_ADDRESS sets the
+local bind address.
+
+The login is fetched when the UI needs one and copied to the clipboard
+(pbcopy, wl-copy, xclip, xsel or clip.exe); only the username is printed
+unless --show-secret or no clipboard tool is available:
+
+  dashboard  a short-lived token of the admin-user ServiceAccount
+  grafana    the admin password of the chart's Secret
+  argo-cd    the initial admin password (argocd-initial-admin-secret)
+  openclaw   the gateway token (OPENCLAW_GATEWAY_TOKEN of openclaw-credentials)
+
+Examples:
+  netcup-kube open grafana
+  netcup-kube open argo-cd --port 8081
+  netcup-kube open platform/redisinsight --no-browser
+  netcup-kube open dashboard --show-secret`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, err := webui.Lookup(args[0], cfg.Env)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		kube, err := clusterKubectl(ctx, path)
+		if err != nil {
+			return err
+		}
+		if target, err = resolveOpenTarget(ctx, kube, target); err != nil {
+			return err
+		}
+
+		user, secret, err := openLogin(ctx, kube, target)
+		if err != nil {
+			return err
+		}
+		url, err := openURL(ctx, kube, target)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("URL: %s\n", url)
+		if user != "" {
+			fmt.Printf("Username: %s\n", user)
+		}
+		if secret != "" {
+			copied := false
+			if !openNoClipboard {
+				if err := copyToClipboard(secret); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				} else {
+					copied = true
+					fmt.Println("Login copied to the clipboard")
+				}
+			}
+			if !copied || openShowSecret {
+				fmt.Printf("Login: %s\n", secret)
+			}
+		}
+		if openNoBrowser {
+			return nil
+		}
+		return openBrowser(url)
+	},
+}
+
+// resolveOpenTarget fills in what the catalog cannot know: the monitoring
+// Services as named by the installed release, and the port of Services
+// given without one
+func resolveOpenTarget(ctx context.Context, kube *kubectl.Runner, t webui.Target) (webui.Target, error) {
+	if _, err := monitoring.LookupComponent(t.Name); err == nil {
+		services, err := monitoring.ResolveServices(ctx, kube, t.Namespace, monitoring.DefaultRelease)
+		if err != nil {
+			return t, err
+		}
+		svc, ok := services[t.Name]
+		if !ok {
+			return t, fmt.Errorf("no %s service found in namespace %s; is kube-prometheus-stack installed?", t.Name, t.Namespace)
+		}
+		t.Service, t.Port = svc.Name, svc.Port
+		if t.SecretName != "" {
+			// The Grafana chart names its Secret after the Service
+			t.SecretName = svc.Name
+		}
+	}
+	if t.Port == "" {
+		port, err := webui.ServicePort(ctx, kube, t)
+		if err != nil {
+			return t, err
+		}
+		t.Port = port
+	}
+	if t.LocalPort == "" {
+		t.LocalPort = webui.DefaultLocalPort(t.Port)
+	}
+	if t.LocalScheme == "" {
+		t.LocalScheme = "http"
+		if t.Port == "443" {
+			t.LocalScheme = "https"
+		}
+	}
+	if openLocalPort != "" {
+		t.LocalPort = openLocalPort
+	}
+	return t, nil
+}
+
+// openLogin fetches the user and password (or token) of the UI, if any
+func openLogin(ctx context.Context, kube *kubectl.Runner, t webui.Target) (user, secret string, err error) {
+	if t.DashboardToken {
+		if _, err := dashboard.EnsureServiceAccount(ctx, kube, t.Namespace, dashboard.DefaultServiceAccount); err != nil {
+			return "", "", err
+		}
+		token, err := dashboard.CreateToken(ctx, kube, t.Namespace, dashboard.DefaultServiceAccount, 24*time.Hour)
+		return "", token, err
+	}
+	if t.SecretName == "" {
+		return "", "", nil
+	}
+	user = t.User
+	if t.UserKey != "" {
+		if user, err = webui.SecretValue(ctx, kube, t.Namespace, t.SecretName, t.UserKey); err != nil {
+			return "", "", err
+		}
+	}
+	if secret, err = webui.SecretValue(ctx, kube, t.Namespace, t.SecretName, t.SecretKey); err != nil {
+		return "", "", err
+	}
+	return user, secret, nil
+}
+
+// openURL returns the public URL of the UI, or starts a background
+// port-forward and returns its local URL. A forward started here is stopped
+// again when ctx is canceled before it is ready.
+func openURL(ctx context.Context, kube *kubectl.Runner, t webui.Target) (string, error) {
+	routes, err := ingress.List(ctx, kube)
+	if err != nil {
+		return "", err
+	}
+	if host := webui.PublicHost(routes, t); host != "" {
+		return "https://" + host + "/", nil
+	}
+	if host := cfg.Env[t.HostEnv]; t.HostEnv != "" && host != "" {
+		return "https://" + host + "/", nil
+	}
+
+	var opts []portforward.Option
+	if address := cfg.Env["PORT_FORWARD_ADDRESS"]; address != "" {
+		opts = append(opts, portforward.WithAddress(address))
+	}
+	mgr := portforward.New(t.Namespace, "svc/"+t.Service, t.LocalPort, t.Port, opts...)
+	alreadyRunning := mgr.Status().State == portforward.StateRunning
+	if err := mgr.Start(); err != nil {
+		return "", fmt.Errorf("failed to start %s port-forward: %w", t.Name, err)
+	}
+	if err := portforward.WaitReady(ctx, t.LocalPort, 10*time.Second); err != nil {
+		if ctx.Err() != nil && !alreadyRunning {
+			_ = mgr.Stop()
+		}
+		return "", err
+	}
+	fmt.Printf("Port-forward: localhost:%s -> %s/svc/%s:%s (background, PID %d)\n", t.LocalPort, t.Namespace, t.Service, t.Port, mgr.Status().PID)
+	return t.LocalScheme + "://localhost:" + t.LocalPort + "/", nil
+}
+
+func init() {
+	openCmd.Flags().StringVar(&openLocalPort, "port", "", "Local port for port-forward access (default: per UI)")
+	openCmd.Flags().BoolVar(&openNoBrowser, "no-browser", false, "Only print the URL and login")
+	openCmd.Flags().BoolVar(&openNoClipboard, "no-clipboard", false, "Print the login instead of copying it to the clipboard")
+	openCmd.Flags().BoolVar(&openShowSecret, "show-secret", false, "Also print the password or token when it was copied")
+}
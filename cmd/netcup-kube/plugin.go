@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/executor"
+	"github.com/mfittko/netcup-kube/internal/plugin"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
+	"github.com/spf13/cobra"
+)
+
+const pluginPrefix = "netcup-kube"
+
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "List third-party plugins found on PATH",
+	Long: `netcup-kube runs executables named netcup-kube-<name> found on PATH as
+subcommands, like kubectl plugins: "netcup-kube foo bar" runs netcup-kube-foo-bar
+if present, otherwise netcup-kube-foo with "bar" as its first argument.
+Built-in commands always win.
+
+Plugins receive the loaded configuration as environment: the process
+environment plus config/netcup-kube.env (or --env-file), with global flags
+applied (--dry-run sets DRY_RUN=true, --dry-run-write-files sets
+DRY_RUN_WRITE_FILES=true). NETCUP_KUBE_BIN is the path of netcup-kube and
+NETCUP_KUBE_ENV_FILE the env file that was loaded, if any.
+
+Sub-commands:
+  list  - Show discovered plugins
+
+Examples:
+  netcup-kube plugin list
+  netcup-kube --env-file config/prod.env backup-etcd --to s3://bucket`,
+	SilenceUsage: true,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show plugins discovered on PATH",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plugins := plugin.List(pluginPrefix, os.Getenv("PATH"))
+		if len(plugins) == 0 {
+			fmt.Printf("No %s-* plugins found on PATH\n", pluginPrefix)
+			return nil
+		}
+		printPlugins(rootCmd, plugins)
+		return nil
+	},
+}
+
+// printPlugins prints plugins with warnings for unreachable ones
+func printPlugins(root *cobra.Command, plugins []plugin.Plugin) {
+	for _, p := range plugins {
+		fmt.Printf("%-20s %s\n", p.Name, p.Path)
+		if p.ShadowedBy != "" {
+			fmt.Printf("  warning: shadowed by %s\n", p.ShadowedBy)
+		}
+		if isBuiltinCommand(root, strings.Fields(p.Name)[0]) {
+			fmt.Printf("  warning: overshadowed by built-in command %q\n", strings.Fields(p.Name)[0])
+		}
+	}
+}
+
+// isBuiltinCommand reports whether name (or an alias) is a subcommand of root
+func isBuiltinCommand(root *cobra.Command, name string) bool {
+	switch name {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	for _, c := range root.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPlugin runs a plugin if args name one that is not a built-in command.
+// handled is false when cobra should process args as usual.
+func runPlugin(ctx context.Context, args []string) (handled bool, err error) {
+	globals, rest := plugin.SplitArgs(args, func(name string) bool {
+		f := rootCmd.PersistentFlags().Lookup(name)
+		return f != nil && f.Value.Type() != "bool"
+	})
+	if len(rest) == 0 || isBuiltinCommand(rootCmd, rest[0]) {
+		return false, nil
+	}
+	path, pluginArgs, ok := plugin.Find(pluginPrefix, rest, exec.LookPath)
+	if !ok {
+		return false, nil
+	}
+
+	if err := rootCmd.PersistentFlags().Parse(globals); err != nil {
+		return true, err
+	}
+	if err := loadConfig(); err != nil {
+		return true, err
+	}
+	env := cfg.ToEnvSlice()
+	if self, err := os.Executable(); err == nil {
+		env = append(env, "NETCUP_KUBE_BIN="+self)
+	}
+	if envFile != "" {
+		env = append(env, "NETCUP_KUBE_ENV_FILE="+envFile)
+	}
+
+	ctx, span := telemetry.Start(ctx, "plugin "+rest[0])
+	if traceParent := span.TraceParent(); traceParent != "" {
+		env = append(env, "TRACEPARENT="+traceParent)
+	}
+	err = span.End(plugin.Run(ctx, path, pluginArgs, env))
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return true, executor.ExitCodeError{Code: exitErr.ExitCode()}
+	}
+	if err != nil {
+		return true, fmt.Errorf("plugin %s failed: %w", path, err)
+	}
+	return true, nil
+}
+
+func init() {
+	pluginCmd.AddCommand(pluginListCmd)
+	rootCmd.AddCommand(pluginCmd)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// jsonQuery is the global --query expression
+var jsonQuery string
+
+// applyJSONQuery installs --query for the JSON output of cmd. It implies
+// -o json, so it only works for commands whose --output selects text or json
+// (not those where --output names a file).
+func applyJSONQuery(cmd *cobra.Command) error {
+	if jsonQuery == "" {
+		return nil
+	}
+	flag := cmd.Flags().Lookup("output")
+	if flag == nil || flag.DefValue != string(output.FormatText) {
+		return fmt.Errorf("--query needs a command with JSON output (%s has no -o json)", cmd.CommandPath())
+	}
+	if err := flag.Value.Set(string(output.FormatJSON)); err != nil {
+		return err
+	}
+	return output.SetQuery(jsonQuery)
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&jsonQuery, "query", "", "Filter JSON output with an expression in a subset of jq (paths, |, select, comparisons, length, keys; no object construction, \",\" or map), e.g. '.errors[] | .field' (implies -o json)")
+}
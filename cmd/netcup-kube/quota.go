@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+	"github.com/mfittko/netcup-kube/internal/quota"
+	"github.com/spf13/cobra"
+)
+
+var (
+	quotaNamespace string
+	quotaPreset    string
+)
+
+var quotaCmd = &cobra.Command{
+	Use:   "quota",
+	Short: "Cap the resources a namespace can consume",
+	Long: `Apply ResourceQuota and LimitRange presets to namespaces, typically those
+created by recipes, so that a runaway workload cannot starve the rest of a
+single node.
+
+Sub-commands:
+  apply  - Apply a quota preset to a namespace`,
+	SilenceUsage: true,
+}
+
+var quotaApplyCmd = &cobra.Command{
+	Use:   "apply --namespace <namespace> [--preset small|medium]",
+	Short: "Apply a quota preset to a namespace",
+	Long: `Create or update the ResourceQuota ` + quota.QuotaName + ` and the LimitRange
+` + quota.LimitRangeName + ` in a namespace.
+
+Presets:
+` + quotaPresetHelp() + `
+
+The LimitRange gives containers without requests or limits a default, so that
+existing recipes keep scheduling under the quota; pods that exceed the quota
+are rejected at creation. Re-running with another preset replaces the budget.
+With --dry-run the manifests are printed instead of applied.
+
+Examples:
+  netcup-kube quota apply --namespace openclaw
+  netcup-kube quota apply -n platform --preset medium
+  netcup-kube quota apply -n openclaw --preset medium --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if quotaNamespace == "" {
+			return fmt.Errorf("--namespace is required")
+		}
+		preset, err := quota.Lookup(quotaPreset)
+		if err != nil {
+			return err
+		}
+		manifest := quota.Render(quotaNamespace, preset)
+		if isDryRun() {
+			fmt.Printf("[dry-run] kubectl apply -f - (preset %s, namespace %s)\n", preset.Name, quotaNamespace)
+			fmt.Print(manifest)
+			return nil
+		}
+
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		kube, err := clusterKubectl(ctx, path)
+		if err != nil {
+			return err
+		}
+		err = kube.Run(ctx, kubectl.Streams{Stdin: strings.NewReader(manifest), Stdout: os.Stdout, Stderr: os.Stderr}, "apply", "-f", "-")
+		if err != nil {
+			return fmt.Errorf("failed to apply quota preset %s to namespace %s: %w", preset.Name, quotaNamespace, err)
+		}
+		return nil
+	},
+}
+
+func quotaPresetHelp() string {
+	var b strings.Builder
+	for _, name := range quota.PresetNames() {
+		fmt.Fprintf(&b, "  %-7s - %s\n", name, quota.Presets[name].Description)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func init() {
+	quotaApplyCmd.Flags().StringVarP(&quotaNamespace, "namespace", "n", "", "Namespace to apply the quota to (required)")
+	quotaApplyCmd.Flags().StringVar(&quotaPreset, "preset", "small", "Quota preset: "+strings.Join(quota.PresetNames(), " or "))
+
+	quotaCmd.AddCommand(quotaApplyCmd)
+}
@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"text/tabwriter"
+	"time"
 
+	"github.com/mfittko/netcup-kube/internal/inventory"
 	"github.com/mfittko/netcup-kube/internal/remote"
 	"github.com/spf13/cobra"
 )
@@ -14,12 +20,17 @@ var (
 	remotePubKey     string
 	remoteRepo       string
 	remoteConfigPath string
+	remoteCompress   string
+	remoteRateLimit  string
 )
 
 var remoteCmd = &cobra.Command{
-	Use:          "remote",
-	Short:        "Execute commands on remote hosts",
-	Long:         `Remote execution engine for netcup-kube, providing safer, more reliable remote operations.`,
+	Use:   "remote",
+	Short: "Execute commands on remote hosts",
+	Long: `Remote execution engine for netcup-kube, providing safer, more reliable remote operations.
+
+File transfers (binaries, env files) can be compressed with --compress and
+throttled with --rate-limit for metered or slow links.`,
 	SilenceUsage: true,
 }
 
@@ -37,13 +48,23 @@ This command:
 Examples:
   netcup-kube remote provision
   netcup-kube remote --host root.example.com --user ops provision
-  ROOT_PASS=xxx netcup-kube remote --host 203.0.113.10 provision`,
+  ROOT_PASS=xxx netcup-kube remote --host 203.0.113.10 provision
+  netcup-kube remote --inventory config/inventory.yaml provision
+  netcup-kube remote --inventory config/inventory.yaml --node worker-1 provision`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		cfg, err := loadRemoteConfig(cmd)
+		cfgs, err := loadRemoteConfigs(cmd, true)
 		if err != nil {
 			return err
 		}
-		return remote.Provision(cfg)
+		for _, cfg := range cfgs {
+			if err := remote.Provision(cfg); err != nil {
+				if len(cfgs) > 1 {
+					return fmt.Errorf("provisioning %s failed: %w", cfg.Host, err)
+				}
+				return err
+			}
+		}
+		return nil
 	},
 }
 
@@ -52,17 +73,25 @@ var remoteGitCmd = &cobra.Command{
 	Short: "Remote git control for the repo (checkout/pull branch/ref)",
 	Long: `Manage git state of the remote repository.
 
+The sync refuses to run when the remote repo has uncommitted changes to
+tracked files; --force discards them. Submodules are initialized and updated
+when the repo has a .gitmodules file.
+
 Examples:
   netcup-kube remote git --branch main --pull
   netcup-kube remote git --ref v1.0.0
-  netcup-kube remote git --branch develop`,
+  netcup-kube remote git --branch develop
+  netcup-kube remote git --branch main --depth 1 --force`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := loadRemoteConfig(cmd)
 		if err != nil {
 			return err
 		}
 
-		client := remote.NewSSHClient(cfg.Host, cfg.User)
+		client, err := remote.WrapClient(sshClient(cmd.Context(), cfg.Host, cfg.User))
+		if err != nil {
+			return err
+		}
 
 		// Ensure user access and repo exists
 		if err := client.TestConnection(); err != nil {
@@ -74,6 +103,8 @@ Examples:
 			Ref:       gitRef,
 			Pull:      gitPull,
 			PullIsSet: cmd.Flags().Changed("pull") || cmd.Flags().Changed("no-pull"),
+			Force:     gitForce,
+			Depth:     gitDepth,
 		}
 
 		// Default to pull for standalone git command
@@ -89,23 +120,34 @@ Examples:
 var remoteBuildCmd = &cobra.Command{
 	Use:   "build",
 	Short: "Build the Go CLI for the remote host (cross-compile locally and upload)",
-	Long: `Build netcup-kube for the remote host architecture and upload.
+	Long: `Build netcup-kube and netcup-claw for the remote host architecture and upload.
 
 This command:
 - Detects the remote host architecture (amd64/arm64)
-- Builds the Go CLI locally with cross-compilation
-- Uploads the binary to the remote host
+- Builds the Go CLIs locally with cross-compilation
+- Uploads the binaries and a version manifest (manifest.json) to the remote bin directory
+
+Builds of a clean git checkout are cached per commit and architecture under
+the user cache dir, and the upload is skipped when the remote binary already
+matches. Use --no-cache to force a rebuild and upload.
+
+Without a local Go toolchain, --build-remote builds from the remote repo
+checkout on the host instead (installing Go into ~/.local/go if needed).
 
 Examples:
   netcup-kube remote build
-  netcup-kube remote build --branch main --pull`,
+  netcup-kube remote build --branch main --pull
+  netcup-kube remote build --build-remote`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := loadRemoteConfig(cmd)
 		if err != nil {
 			return err
 		}
 
-		client := remote.NewSSHClient(cfg.Host, cfg.User)
+		client, err := remote.WrapClient(sshClient(cmd.Context(), cfg.Host, cfg.User))
+		if err != nil {
+			return err
+		}
 
 		// Ensure user access and repo exists
 		if err := client.TestConnection(); err != nil {
@@ -119,10 +161,14 @@ Examples:
 		}
 
 		opts := remote.GitOptions{
-			Branch:    gitBranch,
-			Ref:       gitRef,
-			Pull:      gitPull,
-			PullIsSet: cmd.Flags().Changed("pull") || cmd.Flags().Changed("no-pull"),
+			Branch:      gitBranch,
+			Ref:         gitRef,
+			Pull:        gitPull,
+			PullIsSet:   cmd.Flags().Changed("pull") || cmd.Flags().Changed("no-pull"),
+			Force:       gitForce,
+			Depth:       gitDepth,
+			BuildRemote: buildRemote,
+			NoCache:     buildNoCache,
 		}
 
 		return remote.RemoteBuildAndUpload(client, cfg, projectRoot, opts)
@@ -130,16 +176,85 @@ Examples:
 }
 
 var (
-	gitBranch  string
-	gitRef     string
-	gitPull    bool
-	runNoTTY   bool
-	runEnvFile string
-	runBranch  string
-	runRef     string
-	runPull    bool
+	gitBranch      string
+	gitRef         string
+	gitPull        bool
+	gitForce       bool
+	gitDepth       int
+	buildRemote    bool
+	buildNoCache   bool
+	runNoTTY       bool
+	runEnvFile     string
+	runBranch      string
+	runRef         string
+	runPull        bool
+	runResilient   bool
+	runHeartbeat   = defaultRemoteHeartbeat
+	runIdleTimeout = defaultRemoteIdleTimeout
+	runAbortOnIdle bool
+	attachSession  string
+	attachMosh     bool
 )
 
+var remoteBinCmd = &cobra.Command{
+	Use:   "bin",
+	Short: "Inspect binaries uploaded to the remote host",
+}
+
+var remoteBinListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List binaries and versions installed on the remote host",
+	Long: `List the binaries in the remote bin directory with the version recorded in
+the manifest written by 'remote build'.
+
+States:
+  ok         checksum matches the manifest
+  modified   checksum differs from the manifest (replaced outside 'remote build')
+  missing    listed in the manifest but not installed
+  unmanaged  installed but not listed in the manifest
+
+Examples:
+  netcup-kube remote bin list`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadRemoteConfig(cmd)
+		if err != nil {
+			return err
+		}
+
+		client, err := remote.WrapClient(sshClient(cmd.Context(), cfg.Host, cfg.User))
+		if err != nil {
+			return err
+		}
+		if err := client.TestConnection(); err != nil {
+			return fmt.Errorf("SSH connection failed. Run 'netcup-kube remote provision' first")
+		}
+
+		bins, err := remote.ListRemoteBinaries(client, cfg)
+		if err != nil {
+			return err
+		}
+		if len(bins) == 0 {
+			fmt.Printf("No binaries installed in %s@%s:%s (run 'netcup-kube remote build')\n", cfg.User, cfg.Host, cfg.GetRemoteBinDir())
+			return nil
+		}
+		return printRemoteBinaries(os.Stdout, bins)
+	},
+}
+
+func printRemoteBinaries(out io.Writer, bins []remote.BinStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tVERSION\tARCH\tSHA256\tSTATE")
+	for _, b := range bins {
+		sum := b.SHA256
+		if len(sum) > 12 {
+			sum = sum[:12]
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", b.Name, b.Version, b.GOARCH, sum, b.State)
+	}
+	return w.Flush()
+}
+
 var remoteSmokeCmd = &cobra.Command{
 	Use:   "smoke",
 	Short: "Run a safe DRY_RUN smoke test on the remote management node",
@@ -166,10 +281,14 @@ Examples:
 		}
 
 		opts := remote.GitOptions{
-			Branch:    gitBranch,
-			Ref:       gitRef,
-			Pull:      gitPull,
-			PullIsSet: cmd.Flags().Changed("pull") || cmd.Flags().Changed("no-pull"),
+			Branch:      gitBranch,
+			Ref:         gitRef,
+			Pull:        gitPull,
+			PullIsSet:   cmd.Flags().Changed("pull") || cmd.Flags().Changed("no-pull"),
+			Force:       gitForce,
+			Depth:       gitDepth,
+			BuildRemote: buildRemote,
+			NoCache:     buildNoCache,
 		}
 
 		return remote.Smoke(cfg, opts, projectRoot)
@@ -187,8 +306,23 @@ This command:
 - Runs the netcup-kube command with sudo
 - Forces a TTY by default for interactive prompts
 
+While the command runs, a heartbeat is printed after each --heartbeat of
+silence, and a warning (or abort, with --abort-on-idle) after --idle-timeout
+without output, which usually means a hung apt or a prompt waiting for input.
+
+With --resilient the command runs inside a tmux session on the host, so a
+dropped connection does not kill it; rejoin with 'netcup-kube remote attach'.
+Combined with --no-tty the session is started detached and waited for. The
+exit status of the command is passed on; when its session is still running,
+the run attaches to it (or fails with --no-tty) instead of starting again.
+
+Each run is recorded with its arguments, env file hash, git ref and commit,
+and result; list them with 'netcup-kube remote history' and re-run one
+exactly with 'netcup-kube remote replay <id>'.
+
 Examples:
   netcup-kube remote run bootstrap
+  netcup-kube remote run --resilient bootstrap
   netcup-kube remote run pair
   netcup-kube remote run --env-file ./config/netcup-kube.env bootstrap
   netcup-kube remote run --branch main --pull bootstrap
@@ -206,6 +340,7 @@ Examples:
 		}
 
 		opts := remote.RunOptions{
+			Context:  cmd.Context(),
 			ForceTTY: !runNoTTY,
 			EnvFile:  runEnvFile,
 			Git: remote.GitOptions{
@@ -214,7 +349,9 @@ Examples:
 				Pull:      runPull,
 				PullIsSet: pullIsSet,
 			},
-			Args: args,
+			Args:      args,
+			Resilient: runResilient,
+			Idle:      remoteIdleOptions(),
 		}
 
 		// If no args (or user asked for run help), show help for this subcommand.
@@ -222,7 +359,7 @@ Examples:
 			return cmd.Help()
 		}
 
-		return remote.Run(cfg, opts)
+		return runRemoteRecorded(cfg, opts, 0)
 	},
 }
 
@@ -238,7 +375,8 @@ This command:
 - Runs the netcup-kube install command on the remote host
 - Forces a TTY by default for interactive prompts
 
-Note: Remote flags (--env-file, --branch, --pull, --no-tty) must come BEFORE the recipe name.
+Note: Remote flags (--env-file, --branch, --pull, --no-tty, --resilient, --idle-timeout,
+      --abort-on-idle) must come BEFORE the recipe name.
       Recipe flags (--namespace, --storage, etc.) come AFTER the recipe name.
 
 Examples:
@@ -260,7 +398,7 @@ Examples:
 				return cmd.Help()
 			}
 			// Remote flags that take values
-			if arg == "--env-file" || arg == "--branch" || arg == "--ref" {
+			if arg == "--env-file" || arg == "--branch" || arg == "--ref" || arg == "--idle-timeout" {
 				if i+1 >= len(args) {
 					return fmt.Errorf("flag %s requires a value", arg)
 				}
@@ -276,6 +414,12 @@ Examples:
 					runBranch = val
 				case "--ref":
 					runRef = val
+				case "--idle-timeout":
+					d, err := time.ParseDuration(val)
+					if err != nil {
+						return fmt.Errorf("invalid --idle-timeout %q: %w", val, err)
+					}
+					runIdleTimeout = d
 				}
 				i += 2
 				continue
@@ -296,6 +440,16 @@ Examples:
 				i++
 				continue
 			}
+			if arg == "--resilient" {
+				runResilient = true
+				i++
+				continue
+			}
+			if arg == "--abort-on-idle" {
+				runAbortOnIdle = true
+				i++
+				continue
+			}
 			// Everything else is recipe name + args
 			recipeArgs = args[i:]
 			break
@@ -320,6 +474,7 @@ Examples:
 		installArgs = append(installArgs, recipeArgs...)
 
 		opts := remote.RunOptions{
+			Context:  cmd.Context(),
 			ForceTTY: !runNoTTY,
 			EnvFile:  runEnvFile,
 			Git: remote.GitOptions{
@@ -328,22 +483,128 @@ Examples:
 				Pull:      runPull,
 				PullIsSet: pullIsSet,
 			},
-			Args: installArgs,
+			Args:      installArgs,
+			Resilient: runResilient,
+			Idle:      remoteIdleOptions(),
 		}
 
-		return remote.Run(cfg, opts)
+		return runRemoteRecorded(cfg, opts, 0)
+	},
+}
+
+const (
+	defaultRemoteHeartbeat   = time.Minute
+	defaultRemoteIdleTimeout = 15 * time.Minute
+)
+
+func remoteIdleOptions() remote.IdleOptions {
+	return remote.IdleOptions{Heartbeat: runHeartbeat, Timeout: runIdleTimeout, Abort: runAbortOnIdle}
+}
+
+var remoteAttachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Rejoin a remote run started with --resilient",
+	Long: `Attach to the tmux session of a 'remote run --resilient' command.
+
+Without --session, attaches to the only running netcup-kube session. When
+the command has finished, attach fails if it did. Use
+--mosh on roaming or lossy links (requires mosh locally and on the host).
+
+Examples:
+  netcup-kube remote attach
+  netcup-kube remote attach --session netcup-kube-bootstrap
+  netcup-kube remote attach --mosh`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadRemoteConfig(cmd)
+		if err != nil {
+			return err
+		}
+		return remote.Attach(cfg, remote.AttachOptions{Session: attachSession, Mosh: attachMosh})
 	},
 }
 
 func loadRemoteConfig(cmd *cobra.Command) (*remote.Config, error) {
-	cfg := buildRemoteConfig(cmd)
-	if err := cfg.LoadConfigFromEnv(cfg.ConfigPath); err != nil {
-		return nil, fmt.Errorf("failed to load config: %w", err)
+	cfgs, err := loadRemoteConfigs(cmd, false)
+	if err != nil {
+		return nil, err
 	}
-	if cfg.Host == "" {
-		return nil, fmt.Errorf("no host provided and no MGMT_HOST/MGMT_IP found in config")
+	return cfgs[0], nil
+}
+
+// sshClient returns an SSH client whose ssh and scp processes are stopped
+// when ctx is canceled
+func sshClient(ctx context.Context, host, user string) *remote.SSHClient {
+	c := remote.NewSSHClient(host, user)
+	c.Context = ctx
+	return c
+}
+
+// applyTransferFlags validates --compress and --rate-limit and exports them as
+// REMOTE_COMPRESS and REMOTE_RATE_LIMIT, which every SSH client reads
+func applyTransferFlags(cmd *cobra.Command) error {
+	if cmd.Flags().Changed("compress") {
+		if _, err := remote.ParseCompression(remoteCompress); err != nil {
+			return fmt.Errorf("--compress: %w", err)
+		}
+		if err := os.Setenv(remote.CompressEnv, remoteCompress); err != nil {
+			return err
+		}
 	}
-	return cfg, nil
+	if cmd.Flags().Changed("rate-limit") {
+		if _, err := remote.ParseRate(remoteRateLimit); err != nil {
+			return fmt.Errorf("--rate-limit: %w", err)
+		}
+		if err := os.Setenv(remote.RateLimitEnv, remoteRateLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRemoteConfigs returns one config per target host. Without --inventory
+// this is the single --host/MGMT_HOST target. With --inventory it is the
+// --node selection, or every node when all is true, or the primary server.
+func loadRemoteConfigs(cmd *cobra.Command, all bool) ([]*remote.Config, error) {
+	if err := applyTransferFlags(cmd); err != nil {
+		return nil, err
+	}
+	if remoteInventory == "" {
+		if remoteNode != "" {
+			return nil, fmt.Errorf("--node requires --inventory")
+		}
+		cfg := buildRemoteConfig(cmd)
+		if err := cfg.LoadConfigFromEnv(cfg.ConfigPath); err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		if cfg.Host == "" {
+			return nil, fmt.Errorf("no host provided and no MGMT_HOST/MGMT_IP found in config")
+		}
+		return []*remote.Config{cfg}, nil
+	}
+
+	inv, err := inventory.Load(remoteInventory)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := inventoryTargets(inv, remoteNode, all)
+	if err != nil {
+		return nil, err
+	}
+	if remoteHost != "" && len(nodes) > 1 {
+		return nil, fmt.Errorf("--host cannot be combined with --inventory across multiple nodes; use --node")
+	}
+
+	cfgs := make([]*remote.Config, 0, len(nodes))
+	for _, node := range nodes {
+		cfg := buildRemoteConfig(cmd)
+		applyInventoryNode(cfg, node)
+		if err := cfg.LoadConfigFromEnv(cfg.ConfigPath); err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, nil
 }
 
 func buildRemoteConfig(cmd *cobra.Command) *remote.Config {
@@ -366,6 +627,9 @@ func buildRemoteConfig(cmd *cobra.Command) *remote.Config {
 	}
 
 	// Use default config path if not specified
+	if remoteConfigPath == "" {
+		remoteConfigPath = defaultEnvFile()
+	}
 	if remoteConfigPath == "" {
 		remoteConfigPath = filepath.Join("config", "netcup-kube.env")
 	}
@@ -380,7 +644,11 @@ func init() {
 	remoteCmd.PersistentFlags().StringVar(&remoteUser, "user", "cubeadmin", "Remote sudo user")
 	remoteCmd.PersistentFlags().StringVar(&remotePubKey, "pubkey", "", "Path to SSH public key")
 	remoteCmd.PersistentFlags().StringVar(&remoteRepo, "repo", "https://github.com/mfittko/netcup-kube.git", "Repository URL")
-	remoteCmd.PersistentFlags().StringVar(&remoteConfigPath, "config", "", "Path to config file (default: config/netcup-kube.env)")
+	remoteCmd.PersistentFlags().StringVar(&remoteConfigPath, "config", "", "Path to config file (default: config/netcup-kube.env or ~/.config/netcup-kube/netcup-kube.env)")
+	remoteCmd.PersistentFlags().StringVar(&remoteInventory, "inventory", "", "Cluster inventory file (YAML) describing server/worker nodes")
+	remoteCmd.PersistentFlags().StringVar(&remoteNode, "node", "", "Inventory node to target (default: primary server; provision: all nodes)")
+	remoteCmd.PersistentFlags().StringVar(&remoteCompress, "compress", "", "Compress file transfers: none, gzip or zstd (default: $REMOTE_COMPRESS)")
+	remoteCmd.PersistentFlags().StringVar(&remoteRateLimit, "rate-limit", "", "Limit file transfers to bytes per second, e.g. 500K or 2M (default: $REMOTE_RATE_LIMIT)")
 
 	// Add git flags to commands that need them
 	for _, cmd := range []*cobra.Command{remoteGitCmd, remoteBuildCmd, remoteSmokeCmd} {
@@ -388,15 +656,24 @@ func init() {
 		cmd.Flags().StringVar(&gitRef, "ref", "", "Git ref (commit/tag)")
 		cmd.Flags().BoolVar(&gitPull, "pull", false, "Pull latest changes")
 		cmd.Flags().Bool("no-pull", false, "Do not pull changes")
+		cmd.Flags().BoolVar(&gitForce, "force", false, "Discard uncommitted changes in the remote repo before checkout")
+		cmd.Flags().IntVar(&gitDepth, "depth", 0, "Shallow fetch depth for slow links (0 = full history)")
+	}
+	for _, cmd := range []*cobra.Command{remoteBuildCmd, remoteSmokeCmd} {
+		cmd.Flags().BoolVar(&buildRemote, "build-remote", false, "Build on the remote host when no local Go toolchain is found")
+		cmd.Flags().BoolVar(&buildNoCache, "no-cache", false, "Rebuild and upload even if a cached or identical binary exists")
 	}
 
 	// Add subcommands
 	remoteCmd.AddCommand(remoteProvisionCmd)
 	remoteCmd.AddCommand(remoteGitCmd)
 	remoteCmd.AddCommand(remoteBuildCmd)
+	remoteBinCmd.AddCommand(remoteBinListCmd)
+	remoteCmd.AddCommand(remoteBinCmd)
 	remoteCmd.AddCommand(remoteSmokeCmd)
 	remoteCmd.AddCommand(remoteRunCmd)
 	remoteCmd.AddCommand(remoteInstallCmd)
+	remoteCmd.AddCommand(remoteAttachCmd)
 
 	// remote run flags (netcup-kube args should go after `--` if they start with `-`)
 	remoteRunCmd.Flags().BoolVar(&runNoTTY, "no-tty", false, "Disable forced TTY (default: forces a TTY for prompts)")
@@ -405,6 +682,14 @@ func init() {
 	remoteRunCmd.Flags().StringVar(&runRef, "ref", "", "Git ref (commit/tag)")
 	remoteRunCmd.Flags().BoolVar(&runPull, "pull", false, "Pull latest changes (ff-only)")
 	remoteRunCmd.Flags().Bool("no-pull", false, "Do not pull changes")
+	remoteRunCmd.Flags().BoolVar(&runResilient, "resilient", false, "Run inside a tmux session on the host that survives a dropped connection")
+	remoteRunCmd.Flags().DurationVar(&runHeartbeat, "heartbeat", defaultRemoteHeartbeat, "Print a heartbeat after this much silence (0 disables)")
+	remoteRunCmd.Flags().DurationVar(&runIdleTimeout, "idle-timeout", defaultRemoteIdleTimeout, "Warn when no output has been received for this long (0 disables)")
+	remoteRunCmd.Flags().BoolVar(&runAbortOnIdle, "abort-on-idle", false, "Abort instead of warning when --idle-timeout is reached")
+
+	// remote attach flags
+	remoteAttachCmd.Flags().StringVar(&attachSession, "session", "", "tmux session to attach to (default: the only running one)")
+	remoteAttachCmd.Flags().BoolVar(&attachMosh, "mosh", false, "Attach via mosh instead of ssh")
 
 	// remote install flags
 	remoteInstallCmd.Flags().BoolVar(&runNoTTY, "no-tty", false, "Disable forced TTY (default: forces a TTY for prompts)")
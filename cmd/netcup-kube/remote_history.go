@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyOutput      string
+	historyLimit       int
+	replayYes          bool
+	replayAllowEnvDiff bool
+)
+
+var remoteHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List previous 'remote run' and 'remote install' invocations",
+	Long: `List the recorded 'remote run' and 'remote install' invocations, newest
+last: host, arguments, env file, the git ref and commit the remote repo was at,
+and the result. Env file values are not recorded, only their SHA-256.
+
+Examples:
+  netcup-kube remote history
+  netcup-kube remote history --limit 5 -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(historyOutput)
+		if err != nil {
+			return err
+		}
+		entries, err := remote.LoadHistory(remote.HistoryPath())
+		if err != nil {
+			return fmt.Errorf("failed to read the remote history: %w", err)
+		}
+		if historyLimit > 0 && len(entries) > historyLimit {
+			entries = entries[len(entries)-historyLimit:]
+		}
+		if format == output.FormatJSON {
+			if entries == nil {
+				entries = []remote.HistoryEntry{}
+			}
+			return output.WriteJSON(os.Stdout, entries)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No remote runs recorded yet.")
+			return nil
+		}
+		return printRemoteHistory(os.Stdout, entries)
+	},
+}
+
+var remoteReplayCmd = &cobra.Command{
+	Use:   "replay <id>",
+	Short: "Re-run a recorded remote operation with the same arguments and commit",
+	Long: `Re-run a 'remote run' or 'remote install' from the history on the same
+host and user, with the same arguments, env file and TTY mode, and the remote
+repo checked out at the recorded commit (or the recorded branch/ref when the
+commit is unknown).
+
+The replay refuses to run when the env file changed since the recorded run,
+unless --allow-env-change is given. It asks for confirmation unless --yes or
+CONFIRM=true is set. The replay is itself recorded, referring to the original.
+
+Examples:
+  netcup-kube remote history
+  netcup-kube remote replay 12
+  netcup-kube remote replay 12 --yes`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid history id %q", args[0])
+		}
+		entries, err := remote.LoadHistory(remote.HistoryPath())
+		if err != nil {
+			return fmt.Errorf("failed to read the remote history: %w", err)
+		}
+		entry, err := remote.FindHistory(entries, id)
+		if err != nil {
+			return err
+		}
+
+		if entry.EnvFile != "" {
+			sum, err := remote.FileSHA256(entry.EnvFile)
+			if err != nil {
+				return fmt.Errorf("env file of run #%d is not readable: %w", id, err)
+			}
+			if sum != entry.EnvSHA256 {
+				if !replayAllowEnvDiff {
+					return fmt.Errorf("env file %s changed since run #%d; pass --allow-env-change to replay with the current content", entry.EnvFile, id)
+				}
+				fmt.Fprintf(os.Stderr, "Warning: env file %s changed since run #%d\n", entry.EnvFile, id)
+			}
+		}
+
+		cfg, err := loadRemoteConfig(cmd)
+		if err != nil {
+			return err
+		}
+		cfg.Host, cfg.User = entry.Host, entry.User
+
+		fmt.Printf("Replaying #%d on %s@%s at %s: netcup-kube %s\n", id, entry.User, entry.Host, orDash(entry.GitRef()), strings.Join(entry.Args, " "))
+		if err := confirm.New(replayYes).Confirm(fmt.Sprintf("replay run #%d on %s", id, entry.Host)); err != nil {
+			return err
+		}
+		opts := entry.ReplayOptions()
+		opts.Idle = remoteIdleOptions()
+		opts.Context = cmd.Context()
+		return runRemoteRecorded(cfg, opts, id)
+	},
+}
+
+// runRemoteRecorded runs a netcup-kube command on the remote host and appends
+// it to the history. A failure to record only prints a warning.
+func runRemoteRecorded(cfg *remote.Config, opts remote.RunOptions, replayOf int) error {
+	entry := remote.HistoryEntry{
+		Time:      time.Now().UTC(),
+		Host:      cfg.Host,
+		User:      cfg.User,
+		Args:      opts.Args,
+		Branch:    opts.Git.Branch,
+		Ref:       opts.Git.Ref,
+		Pull:      opts.Git.Pull,
+		NoTTY:     !opts.ForceTTY,
+		Resilient: opts.Resilient,
+		ReplayOf:  replayOf,
+	}
+	if opts.EnvFile != "" {
+		if abs, err := filepath.Abs(opts.EnvFile); err == nil {
+			entry.EnvFile = abs
+		}
+		entry.EnvSHA256, _ = remote.FileSHA256(opts.EnvFile)
+	}
+
+	start := time.Now()
+	err := remote.Run(cfg, opts)
+	entry.DurationMS = time.Since(start).Milliseconds()
+	entry.Success = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	// The commit the command ran against; unknown when the host was unreachable
+	entry.Commit, _ = remote.RemoteCommit(remote.NewSSHClient(cfg.Host, cfg.User), cfg)
+
+	recorded, herr := remote.AppendHistory(remote.HistoryPath(), entry)
+	if herr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to record the remote run: %v\n", herr)
+	} else {
+		fmt.Fprintf(os.Stderr, "[local] Recorded as #%d (replay: netcup-kube remote replay %d)\n", recorded.ID, recorded.ID)
+	}
+	return err
+}
+
+func printRemoteHistory(out io.Writer, entries []remote.HistoryEntry) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTIME\tHOST\tCOMMAND\tGIT\tENV FILE\tDURATION\tRESULT")
+	for _, e := range entries {
+		result := e.Result()
+		if e.ReplayOf > 0 {
+			result += fmt.Sprintf(" (replay of #%d)", e.ReplayOf)
+		}
+		duration := (time.Duration(e.DurationMS) * time.Millisecond).Round(time.Second)
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s@%s\t%s\t%s\t%s\t%s\t%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04"),
+			e.User, e.Host, strings.Join(e.Args, " "), orDash(e.GitRef()), orDash(e.EnvFile), duration, result)
+	}
+	return w.Flush()
+}
+
+func init() {
+	remoteHistoryCmd.Flags().StringVarP(&historyOutput, "output", "o", "text", "Output format: text or json")
+	remoteHistoryCmd.Flags().IntVar(&historyLimit, "limit", 20, "Show only the last N runs (0: all)")
+	remoteReplayCmd.Flags().BoolVarP(&replayYes, "yes", "y", false, "Replay without asking for confirmation")
+	remoteReplayCmd.Flags().BoolVar(&replayAllowEnvDiff, "allow-env-change", false, "Replay even if the env file changed since the recorded run")
+	remoteReplayCmd.Flags().DurationVar(&runHeartbeat, "heartbeat", defaultRemoteHeartbeat, "Print a heartbeat after this much silence (0 disables)")
+	remoteReplayCmd.Flags().DurationVar(&runIdleTimeout, "idle-timeout", defaultRemoteIdleTimeout, "Warn after this long without output (0 disables)")
+	remoteReplayCmd.Flags().BoolVar(&runAbortOnIdle, "abort-on-idle", false, "Abort instead of warning when --idle-timeout is reached")
+
+	remoteCmd.AddCommand(remoteHistoryCmd)
+	remoteCmd.AddCommand(remoteReplayCmd)
+}
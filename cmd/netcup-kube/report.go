@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/report"
+	"github.com/mfittko/netcup-kube/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var reportOutput string
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize what consumes the cluster's nodes",
+	Long: `Summarize resource consumption for operators of small single-node clusters:
+
+  - CPU and memory requests and limits of the running pods per namespace,
+    as a share of the nodes' allocatable capacity
+  - containers without a memory limit, which can grow until the node runs
+    out of memory
+  - PVC sizes per claim and namespace
+  - NodePort/LoadBalancer ports opened on the nodes and Ingress hosts
+
+Namespaces are sorted by memory requests, usually the first resource to run
+out. Limits above 100% mean the node is overcommitted should every workload
+reach its limit.
+
+Examples:
+  netcup-kube report
+  netcup-kube report -o json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(reportOutput)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		kube, err := clusterKubectl(ctx, path)
+		if err != nil {
+			return err
+		}
+		r, err := report.Collect(ctx, kube)
+		if err != nil {
+			return err
+		}
+		if format == output.FormatJSON {
+			return output.WriteJSON(os.Stdout, r)
+		}
+		printReport(os.Stdout, r)
+		return nil
+	},
+}
+
+func printReport(out io.Writer, r *report.Report) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NODE\tCPU\tMEMORY")
+	for _, n := range r.Nodes {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", n.Name, report.FormatCPU(n.Allocatable.CPUMilli), storage.FormatBytes(n.Allocatable.MemoryBytes))
+	}
+	_ = w.Flush()
+
+	_, _ = fmt.Fprintln(out)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAMESPACE\tPODS\tCPU REQ\tCPU LIM\tMEM REQ\tMEM LIM\tNO MEM LIMIT\tPVCS")
+	rows := append(append([]report.Namespace{}, r.Namespaces...), r.Total)
+	for _, ns := range rows {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			ns.Name, ns.Pods,
+			reportCPU(ns.Requests.CPUMilli, r.Allocatable.CPUMilli),
+			reportCPU(ns.Limits.CPUMilli, r.Allocatable.CPUMilli),
+			reportMemory(ns.Requests.MemoryBytes, r.Allocatable.MemoryBytes),
+			reportMemory(ns.Limits.MemoryBytes, r.Allocatable.MemoryBytes),
+			ns.Unlimited, reportPVCs(ns))
+	}
+	_ = w.Flush()
+
+	_, _ = fmt.Fprintln(out)
+	if len(r.Volumes) == 0 {
+		_, _ = fmt.Fprintln(out, "No PersistentVolumeClaims.")
+	} else {
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "PVC\tCLASS\tREQUESTED")
+		for _, v := range r.Volumes {
+			_, _ = fmt.Fprintf(w, "%s/%s\t%s\t%s\n", v.Namespace, v.Claim, orDash(v.StorageClass), orDash(v.Requested))
+		}
+		_ = w.Flush()
+	}
+
+	_, _ = fmt.Fprintln(out)
+	if len(r.NodePorts) == 0 {
+		_, _ = fmt.Fprintln(out, "No NodePorts.")
+	} else {
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "NODEPORT\tSERVICE\tTYPE\tPORT")
+		for _, p := range r.NodePorts {
+			_, _ = fmt.Fprintf(w, "%d/%s\t%s/%s\t%s\t%d\n", p.NodePort, p.Protocol, p.Namespace, p.Service, p.Type, p.Port)
+		}
+		_ = w.Flush()
+	}
+
+	_, _ = fmt.Fprintln(out)
+	if len(r.Ingresses) == 0 {
+		_, _ = fmt.Fprintln(out, "No Ingresses.")
+		return
+	}
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "INGRESS\tCLASS\tHOSTS")
+	for _, i := range r.Ingresses {
+		_, _ = fmt.Fprintf(w, "%s/%s\t%s\t%s\n", i.Namespace, i.Name, orDash(i.Class), strings.Join(i.Hosts, ","))
+	}
+	_ = w.Flush()
+}
+
+func reportCPU(milli, allocatable int64) string {
+	if milli == 0 {
+		return "-"
+	}
+	return report.FormatCPU(milli) + " (" + report.Percent(milli, allocatable) + ")"
+}
+
+func reportMemory(bytes, allocatable int64) string {
+	if bytes == 0 {
+		return "-"
+	}
+	return storage.FormatBytes(bytes) + " (" + report.Percent(bytes, allocatable) + ")"
+}
+
+func reportPVCs(ns report.Namespace) string {
+	if ns.PVCs == 0 {
+		return "-"
+	}
+	return strconv.Itoa(ns.PVCs) + " / " + storage.FormatBytes(ns.PVCBytes)
+}
+
+func init() {
+	reportCmd.Flags().StringVarP(&reportOutput, "output", "o", "text", "Output format: text or json")
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/report"
+)
+
+func TestPrintReport(t *testing.T) {
+	r := &report.Report{
+		Nodes:       []report.Node{{Name: "v2202", Allocatable: report.Resources{CPUMilli: 4000, MemoryBytes: 8 << 30}}},
+		Allocatable: report.Resources{CPUMilli: 4000, MemoryBytes: 8 << 30},
+		Namespaces: []report.Namespace{{
+			Name: "openclaw", Pods: 1, Unlimited: 1, PVCs: 1, PVCBytes: 5 << 30,
+			Requests: report.Resources{CPUMilli: 500, MemoryBytes: 2 << 30},
+		}},
+		Total:     report.Namespace{Name: "total", Pods: 1, Requests: report.Resources{CPUMilli: 500, MemoryBytes: 2 << 30}},
+		NodePorts: []report.NodePort{{Namespace: "kube-system", Service: "traefik", Type: "NodePort", Port: 443, NodePort: 30443, Protocol: "TCP"}},
+	}
+	var out bytes.Buffer
+	printReport(&out, r)
+	text := out.String()
+	for _, want := range []string{
+		"v2202  4    8.0Gi",
+		"500m (13%)",
+		"2.0Gi (25%)  -",
+		"1 / 5.0Gi",
+		"30443/TCP  kube-system/traefik",
+		"No PersistentVolumeClaims.",
+		"No Ingresses.",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("report lacks %q:\n%s", want, text)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serviceInventory string
+	serviceNode      string
+	serviceAll       bool
+	serviceOutput    string
+	serviceLines     int
+	serviceSince     string
+	serviceFollow    bool
+	serviceYes       bool
+)
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Inspect and restart k3s, Caddy and UFW on a node over SSH",
+	Long: `Manage the systemd services of a node without an SSH shell: k3s (k3s-agent
+on workers), caddy and ufw. Commands run over SSH against MGMT_HOST, or an
+inventory node with --inventory and --node; --all targets every node.
+
+Sub-commands:
+  status   - Show the systemd state of the services
+  restart  - Restart a service and show its state afterwards
+  logs     - Print (or follow) the journal of a service
+
+Examples:
+  netcup-kube service status
+  netcup-kube service status caddy -o json
+  netcup-kube service --inventory config/inventory.yaml --all status k3s k3s-agent
+  netcup-kube service restart caddy --yes
+  netcup-kube service logs k3s --since "1 hour ago"
+  netcup-kube service --inventory config/inventory.yaml --node worker-1 logs k3s-agent -f`,
+	SilenceUsage: true,
+}
+
+var serviceStatusCmd = &cobra.Command{
+	Use:   "status [service...]",
+	Short: "Show the systemd state of node services (default: all installed)",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(serviceOutput)
+		if err != nil {
+			return err
+		}
+		for _, name := range args {
+			if _, err := remote.ServiceUnit(name); err != nil {
+				return err
+			}
+		}
+		targets, err := serviceTargets(serviceAll)
+		if err != nil {
+			return err
+		}
+
+		var results []nodeServiceStatus
+		for _, t := range targets {
+			names, explicit := args, len(args) > 0
+			if !explicit {
+				names = remote.NodeServices
+			}
+			for _, name := range names {
+				status, err := remote.GetServiceStatus(t.client, name)
+				if err != nil {
+					return fmt.Errorf("%s: %w", t.name, err)
+				}
+				if status.Installed || explicit {
+					results = append(results, nodeServiceStatus{Node: t.name, ServiceStatus: status})
+				}
+			}
+		}
+		if format == output.FormatJSON {
+			return output.WriteJSON(os.Stdout, results)
+		}
+		return printServiceStatus(os.Stdout, results)
+	},
+}
+
+var serviceRestartCmd = &cobra.Command{
+	Use:   "restart <service>",
+	Short: "Restart a node service and show its state afterwards",
+	Long: `Restart a node service and show its state afterwards.
+
+A restart asks for confirmation unless --yes or CONFIRM=true is given;
+--dry-run only prints the planned restarts.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(serviceOutput)
+		if err != nil {
+			return err
+		}
+		if _, err := remote.ServiceUnit(args[0]); err != nil {
+			return err
+		}
+		targets, err := serviceTargets(serviceAll)
+		if err != nil {
+			return err
+		}
+		if isDryRun() {
+			for _, t := range targets {
+				fmt.Printf("[dry-run] Would restart %s on %s\n", args[0], t.name)
+			}
+			return nil
+		}
+		if err := confirm.New(serviceYes).Confirm(fmt.Sprintf("restart %s on %d node(s)", args[0], len(targets))); err != nil {
+			return err
+		}
+
+		var results []nodeServiceStatus
+		for _, t := range targets {
+			if format == output.FormatText {
+				fmt.Printf("Restarting %s on %s\n", args[0], t.name)
+			}
+			status, err := remote.RestartService(t.client, args[0])
+			if err != nil {
+				return fmt.Errorf("%s: %w", t.name, err)
+			}
+			results = append(results, nodeServiceStatus{Node: t.name, ServiceStatus: status})
+		}
+		if format == output.FormatJSON {
+			if err := output.WriteJSON(os.Stdout, results); err != nil {
+				return err
+			}
+		} else if err := printServiceStatus(os.Stdout, results); err != nil {
+			return err
+		}
+		for _, r := range results {
+			if !r.Active() {
+				return fmt.Errorf("%s is %s on %s after the restart", r.Unit, r.ActiveState, r.Node)
+			}
+		}
+		return nil
+	},
+}
+
+var serviceLogsCmd = &cobra.Command{
+	Use:   "logs <service>",
+	Short: "Print the journal of a node service",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := output.ParseFormat(serviceOutput)
+		if err != nil {
+			return err
+		}
+		if serviceAll {
+			return fmt.Errorf("logs reads a single node; use --node instead of --all")
+		}
+		if _, err := remote.ServiceUnit(args[0]); err != nil {
+			return err
+		}
+		targets, err := serviceTargets(false)
+		if err != nil {
+			return err
+		}
+		return remote.ServiceLogs(targets[0].client, args[0], remote.ServiceLogOptions{
+			Lines:  serviceLines,
+			Since:  serviceSince,
+			Follow: serviceFollow,
+			JSON:   format == output.FormatJSON,
+		})
+	},
+}
+
+// nodeServiceStatus is the state of a service on one node
+type nodeServiceStatus struct {
+	Node string `json:"node"`
+	remote.ServiceStatus
+}
+
+// serviceTargets returns the nodes service commands act on
+func serviceTargets(all bool) ([]sshTarget, error) {
+	return sshTargets(serviceInventory, serviceNode, all)
+}
+
+func printServiceStatus(out io.Writer, results []nodeServiceStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NODE\tSERVICE\tSTATE\tENABLED\tRESTARTS\tSINCE")
+	for _, r := range results {
+		state := r.ActiveState
+		if !r.Installed {
+			state = "not installed"
+		} else if r.SubState != "" {
+			state += " (" + r.SubState + ")"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", r.Node, r.Name, state, orDash(r.Enabled), r.Restarts, orDash(r.Since))
+	}
+	return w.Flush()
+}
+
+func init() {
+	serviceCmd.PersistentFlags().StringVar(&serviceInventory, "inventory", "", "Cluster inventory file (YAML); default: the single node at MGMT_HOST")
+	serviceCmd.PersistentFlags().StringVar(&serviceNode, "node", "", "Inventory node to target (default: primary server)")
+	serviceCmd.PersistentFlags().BoolVar(&serviceAll, "all", false, "Target every inventory node (status, restart)")
+	serviceCmd.PersistentFlags().StringVarP(&serviceOutput, "output", "o", "text", "Output format: text or json")
+	serviceRestartCmd.Flags().BoolVarP(&serviceYes, "yes", "y", false, "Restart without asking for confirmation")
+	serviceLogsCmd.Flags().IntVarP(&serviceLines, "lines", "n", 100, "Number of journal lines to show (0: all)")
+	serviceLogsCmd.Flags().StringVar(&serviceSince, "since", "", `Only show entries since this time (journalctl syntax, e.g. "1 hour ago")`)
+	serviceLogsCmd.Flags().BoolVarP(&serviceFollow, "follow", "f", false, "Follow the journal")
+
+	serviceCmd.AddCommand(serviceStatusCmd)
+	serviceCmd.AddCommand(serviceRestartCmd)
+	serviceCmd.AddCommand(serviceLogsCmd)
+}
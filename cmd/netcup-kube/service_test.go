@@ -0,0 +1,36 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/confirm"
+)
+
+func TestServiceRestartNeedsConfirmation(t *testing.T) {
+	t.Setenv("CONFIRM", "")
+	invPath := filepath.Join(t.TempDir(), "inventory.yaml")
+	if err := os.WriteFile(invPath, []byte(testInventory), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	serviceInventory, serviceAll = invPath, true
+	t.Cleanup(func() { serviceInventory, serviceAll = "", false })
+
+	oldCfg := cfg
+	t.Cleanup(func() { cfg = oldCfg })
+	cfg = config.New()
+	cfg.SetFlag("DRY_RUN", "true")
+	if err := serviceRestartCmd.RunE(serviceRestartCmd, []string{"caddy"}); err != nil {
+		t.Fatalf("service restart --dry-run error: %v", err)
+	}
+
+	cfg = config.New()
+	err := serviceRestartCmd.RunE(serviceRestartCmd, []string{"caddy"})
+	var notConfirmed *confirm.NotConfirmedError
+	if !errors.As(err, &notConfirmed) {
+		t.Fatalf("service restart without --yes: err = %v, want NotConfirmedError", err)
+	}
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/executor"
+	"github.com/mfittko/netcup-kube/internal/output"
+	"github.com/mfittko/netcup-kube/internal/paths"
 	"github.com/mfittko/netcup-kube/internal/tunnel"
 	"github.com/spf13/cobra"
 )
@@ -19,6 +24,8 @@ var (
 	sshLocalPort  string
 	sshRemoteHost string
 	sshRemotePort string
+	sshBindAddr   string
+	sshOutput     string
 )
 
 var sshCmd = &cobra.Command{
@@ -42,7 +49,9 @@ Examples:
   netcup-kube ssh tunnel start
   netcup-kube ssh tunnel stop
   netcup-kube ssh tunnel status
-  netcup-kube ssh tunnel start --local-port 6443`,
+  netcup-kube ssh tunnel status --output json
+  netcup-kube ssh tunnel start --local-port 6443
+  netcup-kube ssh tunnel start --bind-address ::1 --remote-host ::1`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Load environment and apply defaults
 		if err := loadSSHDefaults(); err != nil {
@@ -75,6 +84,7 @@ Examples:
   netcup-kube ssh tunnel start
   netcup-kube ssh tunnel stop
   netcup-kube ssh tunnel status
+  netcup-kube ssh tunnel status --output json
   netcup-kube ssh tunnel start --local-port 6443`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Load environment and apply defaults
@@ -105,6 +115,9 @@ Examples:
 				sshRemotePort = "6443"
 			}
 		}
+		if sshBindAddr == "" {
+			sshBindAddr = os.Getenv("TUNNEL_BIND_ADDRESS")
+		}
 
 		// Determine action
 		action := "start" // default
@@ -118,10 +131,17 @@ Examples:
 		// Execute tunnel action
 		switch action {
 		case "start":
-			return sshTunnelStart()
+			return sshTunnelStart(cmd.Context())
 		case "stop":
-			return sshTunnelStop()
+			return sshTunnelStop(cmd.Context())
 		case "status":
+			format, err := output.ParseFormat(sshOutput)
+			if err != nil {
+				return err
+			}
+			if format == output.FormatJSON {
+				return sshTunnelStatusJSON()
+			}
 			return sshTunnelStatus()
 		default:
 			return fmt.Errorf("unknown tunnel action: %s", action)
@@ -137,11 +157,7 @@ func loadSSHEnv() error {
 	envPath := sshEnvFile
 	if envPath == "" {
 		// Try default locations
-		if _, err := os.Stat("config/netcup-kube.env"); err == nil {
-			envPath = "config/netcup-kube.env"
-		} else if _, err := os.Stat(".env"); err == nil {
-			envPath = ".env"
-		}
+		envPath = paths.Find(defaultEnvFile(), ".env")
 	}
 
 	if envPath == "" {
@@ -186,11 +202,18 @@ func openSSHShell() error {
 	return sshCmd.Run()
 }
 
-func sshTunnelStart() error {
+// sshTunnelManager returns the tunnel described by the ssh tunnel flags
+func sshTunnelManager() *tunnel.Manager {
 	mgr := tunnel.New(sshUser, sshHost, sshLocalPort, sshRemoteHost, sshRemotePort)
+	mgr.BindAddress = sshBindAddr
+	return mgr
+}
+
+func sshTunnelStart(ctx context.Context) error {
+	mgr := sshTunnelManager()
 
 	// Check if already running
-	if mgr.IsRunning() {
+	if mgr.IsRunning(ctx) {
 		fmt.Printf("Tunnel already running on localhost:%s -> %s:%s via %s@%s\n",
 			sshLocalPort, sshRemoteHost, sshRemotePort, sshUser, sshHost)
 		return nil
@@ -200,7 +223,7 @@ func sshTunnelStart() error {
 	fmt.Printf("Starting tunnel on localhost:%s -> %s:%s via %s@%s\n",
 		sshLocalPort, sshRemoteHost, sshRemotePort, sshUser, sshHost)
 
-	if err := mgr.Start(); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		if strings.Contains(err.Error(), "already in use") {
 			return fmt.Errorf("ERROR: localhost:%s is already in use. Stop the existing process or choose a different --local-port", sshLocalPort)
 		}
@@ -213,17 +236,17 @@ func sshTunnelStart() error {
 	return nil
 }
 
-func sshTunnelStop() error {
-	mgr := tunnel.New(sshUser, sshHost, sshLocalPort, sshRemoteHost, sshRemotePort)
+func sshTunnelStop(ctx context.Context) error {
+	mgr := sshTunnelManager()
 
 	// Check if running
-	if !mgr.IsRunning() {
+	if !mgr.IsRunning(ctx) {
 		fmt.Printf("No tunnel running for localhost:%s via %s@%s.\n", sshLocalPort, sshUser, sshHost)
 		return nil
 	}
 
 	// Stop the tunnel
-	if err := mgr.Stop(); err != nil {
+	if err := mgr.Stop(ctx); err != nil {
 		return fmt.Errorf("failed to stop tunnel: %w", err)
 	}
 
@@ -232,7 +255,7 @@ func sshTunnelStop() error {
 }
 
 func sshTunnelStatus() error {
-	mgr := tunnel.New(sshUser, sshHost, sshLocalPort, sshRemoteHost, sshRemotePort)
+	mgr := sshTunnelManager()
 	ctlSocket := mgr.GetControlSocket()
 
 	// Check status
@@ -269,6 +292,47 @@ func sshTunnelStatus() error {
 	return fmt.Errorf("tunnel not running")
 }
 
+// sshTunnelStatusJSON prints the tunnel state as a health document and exits
+// with code 1 when the tunnel is not running
+func sshTunnelStatusJSON() error {
+	mgr := sshTunnelManager()
+	start := time.Now()
+	out, err := exec.Command("ssh", "-S", mgr.GetControlSocket(), "-O", "check", fmt.Sprintf("%s@%s", sshUser, sshHost)).CombinedOutput()
+	control := strings.TrimSpace(string(out))
+	tun := output.HealthComponent{
+		Component: "tunnel",
+		State:     output.HealthOK,
+		Message:   fmt.Sprintf("localhost:%s -> %s:%s via %s@%s", sshLocalPort, sshRemoteHost, sshRemotePort, sshUser, sshHost),
+		LatencyMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil && !strings.Contains(control, "Master running") {
+		tun.State = output.HealthFail
+		if control == "" {
+			control = err.Error()
+		}
+		tun.Message = "not running: " + control
+	}
+	listen := output.HealthComponent{Component: "local-port", State: output.HealthOK, DependsOn: "tunnel"}
+	if tunnel.PortInUse(sshLocalPort) {
+		listen.Message = "localhost:" + sshLocalPort + " is listening"
+	} else {
+		listen.State = output.HealthFail
+		listen.Message = "nothing is listening on localhost:" + sshLocalPort
+	}
+	if tun.State == output.HealthFail && listen.State == output.HealthOK {
+		listen.Message += " (not the tunnel)"
+	}
+
+	doc := output.NewHealthDocument("netcup-kube ssh tunnel status", []output.HealthComponent{tun, listen}, time.Now())
+	if err := doc.WriteJSON(os.Stdout); err != nil {
+		return err
+	}
+	if tun.State == output.HealthFail {
+		return executor.ExitCodeError{Code: 1}
+	}
+	return nil
+}
+
 func showPortListeners(port string) {
 	// Try lsof first (macOS)
 	if _, err := exec.LookPath("lsof"); err == nil {
@@ -302,6 +366,8 @@ func init() {
 	sshTunnelCmd.Flags().StringVar(&sshLocalPort, "local-port", "", "Local port to bind")
 	sshTunnelCmd.Flags().StringVar(&sshRemoteHost, "remote-host", "", "Remote host to forward to")
 	sshTunnelCmd.Flags().StringVar(&sshRemotePort, "remote-port", "", "Remote port to forward to")
+	sshTunnelCmd.Flags().StringVar(&sshBindAddr, "bind-address", "", "Local address to bind, e.g. 127.0.0.1 or ::1 (default: $TUNNEL_BIND_ADDRESS or ssh's localhost)")
+	sshTunnelCmd.Flags().StringVarP(&sshOutput, "output", "o", "text", "Output format for status: text or json")
 
 	// Add tunnel as a subcommand of ssh
 	sshCmd.AddCommand(sshTunnelCmd)
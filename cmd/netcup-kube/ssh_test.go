@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -195,7 +196,7 @@ func TestSSHTunnelStart_Validation(t *testing.T) {
 	sshRemoteHost = "127.0.0.1"
 	sshRemotePort = "6443"
 
-	err := sshTunnelStart()
+	err := sshTunnelStart(context.Background())
 	// Error expected since we can't actually connect
 	if err == nil {
 		t.Log("sshTunnelStart() completed (connection failure expected in test)")
@@ -218,7 +219,7 @@ func TestSSHTunnelStop_NoTunnel(t *testing.T) {
 	sshUser = "testuser"
 	sshLocalPort = "6443"
 
-	err := sshTunnelStop()
+	err := sshTunnelStop(context.Background())
 	// Should not error when stopping non-existent tunnel
 	if err != nil {
 		t.Errorf("sshTunnelStop() should not error for non-existent tunnel, got: %v", err)
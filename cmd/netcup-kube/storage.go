@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/k3s"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/mfittko/netcup-kube/internal/storage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	storageNamespace string
+	storageInventory string
+	storageNoUsage   bool
+	storageSize      string
+	storageAll       bool
+	storageTarget    string
+	storageDownload  string
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Inspect, resize, and snapshot PersistentVolumeClaims",
+	Long: `Manage PersistentVolumeClaims backed by the k3s local-path-provisioner,
+whose volumes are directories under ` + storage.DefaultLocalPathRoot + ` on a node.
+
+Sub-commands:
+  list      - Show PVCs with their node, directory, and disk usage
+  expand    - Grow a PVC where the storage class allows expansion
+  snapshot  - Archive PV directories into the backup directory on the node`,
+	SilenceUsage: true,
+}
+
+var storageListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Show PVCs with their node, directory, and disk usage",
+	Long: `List PersistentVolumeClaims with their storage class, requested size, and
+the disk space their volume directory actually uses on the node (measured
+with du over SSH; local-path does not enforce the requested size).
+
+Nodes come from --inventory; without it the single node at MGMT_HOST is used.
+
+Examples:
+  netcup-kube storage list
+  netcup-kube storage list -n openclaw
+  netcup-kube storage list --no-usage`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, path, kube, err := storageSetup(cmd)
+		if err != nil {
+			return err
+		}
+		volumes, err := storage.ListVolumes(ctx, kube, storageNamespace)
+		if err != nil {
+			return err
+		}
+		if len(volumes) == 0 {
+			fmt.Println("No PersistentVolumeClaims found.")
+			return nil
+		}
+
+		usage := map[string]int64{}
+		if !storageNoUsage {
+			resolve, err := storageNodeResolver(ctx, kube)
+			if err != nil {
+				return err
+			}
+			usage = storageDiskUsage(path, volumes, resolve)
+		}
+		printStorageVolumes(volumes, usage, !storageNoUsage)
+		return nil
+	},
+}
+
+var storageExpandCmd = &cobra.Command{
+	Use:   "expand <namespace>/<pvc> --size <quantity>",
+	Short: "Grow a PVC where the storage class allows expansion",
+	Long: `Raise the storage request of a PVC. The storage class must set
+allowVolumeExpansion; local-path does not (its volumes are only bounded by the
+node's free disk, see 'storage list'), so this applies to classes such as
+Longhorn or CSI drivers installed later. Volumes can only grow.
+
+Examples:
+  netcup-kube storage expand openclaw/openclaw-data --size 20Gi
+  netcup-kube storage expand platform/data-postgres-postgresql-0 --size 50Gi --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if storageSize == "" {
+			return fmt.Errorf("--size is required")
+		}
+		ctx, _, kube, err := storageSetup(cmd)
+		if err != nil {
+			return err
+		}
+		volumes, err := storage.ListVolumes(ctx, kube, "")
+		if err != nil {
+			return err
+		}
+		v, err := storage.FindVolume(volumes, args[0])
+		if err != nil {
+			return err
+		}
+		if isDryRun() {
+			fmt.Printf("[dry-run] resize %s (%s) from %s to %s\n", v.ID(), orDash(v.StorageClass), orDash(v.Requested), storageSize)
+			return nil
+		}
+		if err := storage.Expand(ctx, kube, v, storageSize); err != nil {
+			return err
+		}
+		fmt.Printf("Requested %s for %s (was %s); the volume grows once the driver resizes it.\n", storageSize, v.ID(), orDash(v.Requested))
+		return nil
+	},
+}
+
+var storageSnapshotCmd = &cobra.Command{
+	Use:   "snapshot [<namespace>/<pvc>...] [--all]",
+	Short: "Archive PV directories into the backup directory on the node",
+	Long: `Archive the volume directory of each PVC as
+<target>/<namespace>_<pvc>-<timestamp>.tar.gz on the node that holds it.
+
+The target defaults to STORAGE_BACKUP_DIR from the env file, then
+` + storage.DefaultBackupDir + `. Volumes are archived while in use; scale the
+workload down first when it needs a crash-consistent copy (e.g. databases).
+--download also copies the archives to a local directory, compressed and
+rate-limited per REMOTE_COMPRESS and REMOTE_RATE_LIMIT.
+
+Examples:
+  netcup-kube storage snapshot openclaw/openclaw-data
+  netcup-kube storage snapshot --all -n openclaw
+  netcup-kube storage snapshot --all --target /mnt/backup/pv --dry-run
+  REMOTE_RATE_LIMIT=2M netcup-kube storage snapshot --all --download ./backups`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if storageAll == (len(args) > 0) {
+			return fmt.Errorf("pass one or more <namespace>/<pvc> or --all")
+		}
+		ctx, path, kube, err := storageSetup(cmd)
+		if err != nil {
+			return err
+		}
+		volumes, err := storage.ListVolumes(ctx, kube, storageNamespace)
+		if err != nil {
+			return err
+		}
+		selected, err := selectStorageVolumes(volumes, args)
+		if err != nil {
+			return err
+		}
+		if len(selected) == 0 {
+			fmt.Println("No PersistentVolumeClaims to snapshot.")
+			return nil
+		}
+		resolve, err := storageNodeResolver(ctx, kube)
+		if err != nil {
+			return err
+		}
+
+		target := storageBackupDir(storageTarget, cfg.Env)
+		stamp := time.Now().UTC().Format("20060102-150405")
+		byNode, order, err := groupStorageVolumes(selected, resolve)
+		if err != nil {
+			return err
+		}
+		for _, name := range order {
+			node := byNode[name].node
+			scriptArgs := []string{target, stamp}
+			for _, v := range byNode[name].volumes {
+				dir, err := storage.NodePath(v)
+				if err != nil {
+					return err
+				}
+				scriptArgs = append(scriptArgs, dir, v.SnapshotName())
+			}
+			if isDryRun() {
+				for i := 2; i < len(scriptArgs); i += 2 {
+					fmt.Printf("[dry-run] %s: tar %s -> %s/%s-%s.tar.gz\n", name, scriptArgs[i], target, scriptArgs[i+1], stamp)
+					if storageDownload != "" {
+						fmt.Printf("[dry-run] %s: download %s/%s-%s.tar.gz -> %s\n", name, target, scriptArgs[i+1], stamp, storageDownload)
+					}
+				}
+				continue
+			}
+			fmt.Printf("Snapshotting %d volume(s) on %s (%s)...\n", len(byNode[name].volumes), name, node.Host)
+			if err := runK3sScript(ctx, path)(node, storage.SnapshotScript, scriptArgs); err != nil {
+				return fmt.Errorf("snapshot on %s failed: %w", name, err)
+			}
+			if storageDownload == "" {
+				continue
+			}
+			if err := os.MkdirAll(storageDownload, 0o700); err != nil {
+				return fmt.Errorf("failed to create download directory: %w", err)
+			}
+			for i := 3; i < len(scriptArgs); i += 2 {
+				archive := scriptArgs[i] + "-" + stamp + ".tar.gz"
+				fmt.Printf("Downloading %s from %s...\n", archive, name)
+				if err := downloadFromNode(path, node, target+"/"+archive, filepath.Join(storageDownload, archive)); err != nil {
+					return fmt.Errorf("download of %s from %s failed: %w", archive, name, err)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// downloadFromNode copies a root-owned file from an inventory node, with the
+// transfer options of REMOTE_COMPRESS and REMOTE_RATE_LIMIT
+func downloadFromNode(envPath string, node inventory.Node, remotePath, localPath string) error {
+	rc := remote.NewConfig()
+	applyInventoryNode(rc, node)
+	if err := rc.LoadConfigFromEnv(envPath); err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	return remote.NewSSHClient(rc.Host, rc.User).DownloadSudo(remotePath, localPath)
+}
+
+func storageSetup(cmd *cobra.Command) (context.Context, string, storage.Kubectl, error) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	path, err := configFilePath()
+	if err != nil {
+		return nil, "", nil, err
+	}
+	kube, err := clusterKubectl(ctx, path)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return ctx, path, kube, nil
+}
+
+// storageNodeResolver maps Kubernetes node names to SSH targets: inventory
+// nodes by name, or the single node at MGMT_HOST on a single-node cluster.
+func storageNodeResolver(ctx context.Context, kube storage.Kubectl) (func(name string) (inventory.Node, error), error) {
+	if storageInventory != "" {
+		inv, err := inventory.Load(storageInventory)
+		if err != nil {
+			return nil, err
+		}
+		return inv.Node, nil
+	}
+	nodes, err := k3sUpgradeNodes("")
+	if err != nil {
+		return nil, err
+	}
+	current, err := k3s.NodeVersions(ctx, kube)
+	if err != nil {
+		return nil, err
+	}
+	if nodes, err = k3sNodeNames(nodes, current); err != nil {
+		return nil, err
+	}
+	single := nodes[0]
+	return func(name string) (inventory.Node, error) {
+		if name != single.Name {
+			return inventory.Node{}, fmt.Errorf("node %q is not %s; pass --inventory", name, single.Name)
+		}
+		return single, nil
+	}, nil
+}
+
+type storageNodeGroup struct {
+	node    inventory.Node
+	volumes []storage.Volume
+}
+
+// groupStorageVolumes groups node-local volumes by node, in first-seen order
+func groupStorageVolumes(volumes []storage.Volume, resolve func(string) (inventory.Node, error)) (map[string]*storageNodeGroup, []string, error) {
+	groups := map[string]*storageNodeGroup{}
+	var order []string
+	for _, v := range volumes {
+		if v.Node == "" || v.Path == "" {
+			return nil, nil, fmt.Errorf("PVC %s is not a node-local volume (storage class %s)", v.ID(), orDash(v.StorageClass))
+		}
+		g, ok := groups[v.Node]
+		if !ok {
+			node, err := resolve(v.Node)
+			if err != nil {
+				return nil, nil, err
+			}
+			g = &storageNodeGroup{node: node}
+			groups[v.Node] = g
+			order = append(order, v.Node)
+		}
+		g.volumes = append(g.volumes, v)
+	}
+	return groups, order, nil
+}
+
+// selectStorageVolumes returns the named PVCs, or every node-local PVC when
+// none are named.
+func selectStorageVolumes(volumes []storage.Volume, ids []string) ([]storage.Volume, error) {
+	if len(ids) == 0 {
+		var local []storage.Volume
+		for _, v := range volumes {
+			if v.Node == "" || v.Path == "" {
+				fmt.Printf("Skipping %s: not a node-local volume\n", v.ID())
+				continue
+			}
+			local = append(local, v)
+		}
+		return local, nil
+	}
+	selected := make([]storage.Volume, 0, len(ids))
+	for _, id := range ids {
+		v, err := storage.FindVolume(volumes, id)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, v)
+	}
+	return selected, nil
+}
+
+// storageDiskUsage measures volume directories per node. Nodes that cannot be
+// reached are reported as a warning and their volumes show no usage.
+func storageDiskUsage(envPath string, volumes []storage.Volume, resolve func(string) (inventory.Node, error)) map[string]int64 {
+	usage := map[string]int64{}
+	paths := map[string][]string{}
+	var order []string
+	for _, v := range volumes {
+		dir, err := storage.NodePath(v)
+		if err != nil || v.Node == "" {
+			continue
+		}
+		if _, ok := paths[v.Node]; !ok {
+			order = append(order, v.Node)
+		}
+		paths[v.Node] = append(paths[v.Node], dir)
+	}
+	for _, name := range order {
+		node, err := resolve(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: no disk usage for node %s: %v\n", name, err)
+			continue
+		}
+		rc := remote.NewConfig()
+		applyInventoryNode(rc, node)
+		if err := rc.LoadConfigFromEnv(envPath); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: no disk usage for node %s: %v\n", name, err)
+			continue
+		}
+		// du exits non-zero when a directory is missing; keep the sizes it found
+		out, err := remote.NewSSHClient(rc.Host, rc.User).OutputCommand("sudo du -sb "+strings.Join(paths[name], " ")+" 2>/dev/null || true", nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: no disk usage for node %s: %v\n", name, err)
+			continue
+		}
+		for dir, n := range storage.ParseDiskUsage(out) {
+			usage[dir] = n
+		}
+	}
+	return usage
+}
+
+func printStorageVolumes(volumes []storage.Volume, usage map[string]int64, withUsage bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PVC\tCLASS\tSTATUS\tREQUESTED\tUSED\tNODE\tPATH")
+	for _, v := range volumes {
+		used := "-"
+		if n, ok := usage[v.Path]; ok {
+			used = storage.FormatBytes(n)
+		} else if withUsage && v.Path != "" {
+			used = "?"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", v.ID(), orDash(v.StorageClass), orDash(v.Phase), orDash(v.Requested), used, orDash(v.Node), orDash(v.Path))
+	}
+	_ = w.Flush()
+}
+
+// storageBackupDir picks the snapshot target: flag, STORAGE_BACKUP_DIR, default
+func storageBackupDir(flag string, env map[string]string) string {
+	if flag != "" {
+		return flag
+	}
+	if dir := env["STORAGE_BACKUP_DIR"]; dir != "" {
+		return dir
+	}
+	return storage.DefaultBackupDir
+}
+
+func init() {
+	storageCmd.PersistentFlags().StringVar(&storageInventory, "inventory", "", "Cluster inventory file (YAML); default: the single node at MGMT_HOST")
+	storageListCmd.Flags().StringVarP(&storageNamespace, "namespace", "n", "", "Only list PVCs in this namespace")
+	storageListCmd.Flags().BoolVar(&storageNoUsage, "no-usage", false, "Skip measuring disk usage on the nodes")
+	storageExpandCmd.Flags().StringVar(&storageSize, "size", "", "New storage request (e.g. 20Gi)")
+	storageSnapshotCmd.Flags().StringVarP(&storageNamespace, "namespace", "n", "", "Limit --all to PVCs in this namespace")
+	storageSnapshotCmd.Flags().BoolVar(&storageAll, "all", false, "Snapshot every node-local PVC")
+	storageSnapshotCmd.Flags().StringVar(&storageTarget, "target", "", "Backup directory on the node (default: STORAGE_BACKUP_DIR or "+storage.DefaultBackupDir+")")
+	storageSnapshotCmd.Flags().StringVar(&storageDownload, "download", "", "Also copy the archives to this local directory")
+	storageCmd.AddCommand(storageListCmd)
+	storageCmd.AddCommand(storageExpandCmd)
+	storageCmd.AddCommand(storageSnapshotCmd)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/storage"
+)
+
+func TestStorageBackupDir(t *testing.T) {
+	if got := storageBackupDir("/mnt/pv", map[string]string{"STORAGE_BACKUP_DIR": "/srv"}); got != "/mnt/pv" {
+		t.Errorf("flag: got %s", got)
+	}
+	if got := storageBackupDir("", map[string]string{"STORAGE_BACKUP_DIR": "/srv"}); got != "/srv" {
+		t.Errorf("env: got %s", got)
+	}
+	if got := storageBackupDir("", map[string]string{}); got != storage.DefaultBackupDir {
+		t.Errorf("default: got %s", got)
+	}
+}
+
+func TestSelectAndGroupStorageVolumes(t *testing.T) {
+	volumes := []storage.Volume{
+		{Namespace: "apps", Claim: "cache"},
+		{Namespace: "openclaw", Claim: "data", Node: "mgmt", Path: "/var/lib/rancher/k3s/storage/pvc-1"},
+		{Namespace: "platform", Claim: "pg", Node: "worker-1", Path: "/var/lib/rancher/k3s/storage/pvc-2"},
+		{Namespace: "platform", Claim: "redis", Node: "mgmt", Path: "/var/lib/rancher/k3s/storage/pvc-3"},
+	}
+
+	all, err := selectStorageVolumes(volumes, nil)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("selectStorageVolumes(all) = %v, %v; want 3 node-local volumes", all, err)
+	}
+	named, err := selectStorageVolumes(volumes, []string{"platform/pg"})
+	if err != nil || len(named) != 1 || named[0].Claim != "pg" {
+		t.Errorf("selectStorageVolumes(named) = %v, %v", named, err)
+	}
+
+	resolve := func(name string) (inventory.Node, error) {
+		return inventory.Node{Name: name, Host: name + ".example.com"}, nil
+	}
+	groups, order, err := groupStorageVolumes(all, resolve)
+	if err != nil {
+		t.Fatalf("groupStorageVolumes() error: %v", err)
+	}
+	if strings.Join(order, ",") != "mgmt,worker-1" || len(groups["mgmt"].volumes) != 2 || groups["worker-1"].node.Host != "worker-1.example.com" {
+		t.Errorf("groups = %v, order = %v", groups, order)
+	}
+
+	if _, _, err := groupStorageVolumes(volumes[:1], resolve); err == nil {
+		t.Error("groupStorageVolumes() expected error for a volume that is not node-local")
+	}
+	failing := func(name string) (inventory.Node, error) {
+		return inventory.Node{}, fmt.Errorf("unknown node %s", name)
+	}
+	if _, _, err := groupStorageVolumes(all, failing); err == nil {
+		t.Error("groupStorageVolumes() expected resolver error")
+	}
+}
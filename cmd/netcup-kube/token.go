@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/confirm"
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/k3s"
+	"github.com/mfittko/netcup-kube/internal/remote"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tokenInventory    string
+	tokenGrace        time.Duration
+	tokenYes          bool
+	tokenReadyTimeout time.Duration
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage the join token of the cluster's agents",
+	Long: `Manage the token workers use to join the cluster, as printed by 'pair'.
+
+Sub-commands:
+  rotate  - Issue a new agent join token, switch the workers to it and expire the old one`,
+	SilenceUsage: true,
+}
+
+var tokenRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Rotate the agent join token",
+	Long: `Rotate the token agents join (and re-join) the cluster with:
+
+  1. Issue a new agent join token on the first server (k3s token create;
+     a bootstrap token, k3s v1.26 or newer)
+  2. Store it in ` + k3s.AgentTokenFile + ` on every
+     server, so 'pair' prints the new token from now on
+  3. On each worker, one at a time: set the token in ` + k3s.ConfigFile + `,
+     restart k3s-agent and wait for the node to be Ready; the previous
+     config is restored when the agent does not start
+  4. Expire the agent tokens issued by earlier rotations: deleted at once,
+     or valid for --grace more so joins from older 'pair' outputs in flight
+     still succeed (staged rotation)
+
+The server token (node-token) that 'pair' printed before the first rotation
+is also the servers' join secret and is not rotated here; see
+'k3s token rotate' on the server for it.
+
+Nodes come from --inventory; without it only the single server at MGMT_HOST
+is updated. --dry-run prints the plan only.
+
+Examples:
+  netcup-kube token rotate --dry-run --inventory config/inventory.yaml
+  netcup-kube token rotate --inventory config/inventory.yaml --grace 24h
+  netcup-kube token rotate --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath()
+		if err != nil {
+			return err
+		}
+		nodes, err := k3sUpgradeNodes(tokenInventory)
+		if err != nil {
+			return err
+		}
+		ctx := cmd.Context()
+		if ctx == nil {
+			ctx = context.Background()
+		}
+
+		kube, err := clusterKubectl(ctx, path)
+		if err != nil {
+			return err
+		}
+		current, err := k3s.NodeVersions(ctx, kube)
+		if err != nil {
+			return err
+		}
+		if nodes, err = k3sNodeNames(nodes, current); err != nil {
+			return err
+		}
+		if err := k3s.CheckNodes(nodes, current); err != nil {
+			return err
+		}
+		tokens, err := k3s.AgentTokens(ctx, kube)
+		if err != nil {
+			return err
+		}
+		// Tokens already expiring within the grace window are left alone
+		var old []k3s.BootstrapToken
+		for _, t := range tokens {
+			if t.Expiration == nil || t.Expiration.After(time.Now().Add(tokenGrace)) {
+				old = append(old, t)
+			}
+		}
+
+		steps := k3s.TokenRotationPlan(nodes)
+		server := nodes[0]
+		for _, node := range nodes {
+			if node.Role != inventory.RoleWorker {
+				server = node
+				break
+			}
+		}
+		fmt.Println("Planned sequence:")
+		fmt.Printf("  1. issue a new agent join token on %s\n", server.Host)
+		for i, step := range steps {
+			fmt.Printf("  %d. %s\n", i+2, step.Describe(""))
+		}
+		for _, t := range old {
+			fmt.Printf("  -  %s\n", describeTokenExpiry(t.ID, tokenGrace))
+		}
+		if isDryRun() {
+			return nil
+		}
+
+		if err := confirm.New(tokenYes).Confirm(fmt.Sprintf("rotate the agent join token and restart k3s-agent on %d worker(s)", countSteps(steps, k3s.StepSwitchToken))); err != nil {
+			return err
+		}
+
+		out, err := tokenServerOutput(path, server, k3s.CreateAgentTokenCommand)
+		if err != nil {
+			return fmt.Errorf("failed to create the agent token on %s: %w", server.Host, err)
+		}
+		token, id, err := k3s.ParseAgentToken(out)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Issued agent token %s\n", id)
+
+		upgrader := &k3s.Upgrader{
+			Kube:         kube,
+			RunScript:    runK3sScript(ctx, path),
+			Out:          os.Stdout,
+			ReadyTimeout: tokenReadyTimeout,
+			PollInterval: k3sPollInterval,
+			Token:        token,
+		}
+		if err := upgrader.Execute(ctx, steps, ""); err != nil {
+			return fmt.Errorf("%w; the earlier agent tokens stay valid, re-run 'token rotate' once the node is fixed", err)
+		}
+
+		now := time.Now()
+		for _, t := range old {
+			if err := k3s.ExpireToken(ctx, kube, t.ID, now, tokenGrace); err != nil {
+				return err
+			}
+			if tokenGrace > 0 {
+				fmt.Printf("Earlier agent token %s expires at %s\n", t.ID, now.Add(tokenGrace).Format(time.RFC3339))
+			} else {
+				fmt.Printf("Deleted earlier agent token %s\n", t.ID)
+			}
+		}
+		fmt.Printf("Agent token %s is active; 'pair' prints it from now on\n", id)
+		return nil
+	},
+}
+
+// describeTokenExpiry tells what happens to an earlier agent token
+func describeTokenExpiry(id string, grace time.Duration) string {
+	if grace <= 0 {
+		return fmt.Sprintf("delete earlier agent token %s", id)
+	}
+	return fmt.Sprintf("expire earlier agent token %s in %s", id, grace)
+}
+
+func countSteps(steps []k3s.Step, kind k3s.StepKind) int {
+	n := 0
+	for _, step := range steps {
+		if step.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// tokenServerOutput runs command on a server over SSH and returns its stdout
+func tokenServerOutput(envPath string, node inventory.Node, command string) ([]byte, error) {
+	rc := remote.NewConfig()
+	applyInventoryNode(rc, node)
+	if err := rc.LoadConfigFromEnv(envPath); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	return remote.NewSSHClient(rc.Host, rc.User).OutputCommand(command, nil)
+}
+
+func init() {
+	tokenRotateCmd.Flags().StringVar(&tokenInventory, "inventory", "", "Cluster inventory file (YAML); default: the single node at MGMT_HOST")
+	tokenRotateCmd.Flags().DurationVar(&tokenGrace, "grace", 0, "Keep earlier agent tokens valid this long (0 deletes them at once)")
+	tokenRotateCmd.Flags().BoolVarP(&tokenYes, "yes", "y", false, "Rotate without asking for confirmation")
+	tokenRotateCmd.Flags().DurationVar(&tokenReadyTimeout, "ready-timeout", 5*time.Minute, "Timeout for a worker to become Ready after switching its token")
+	tokenCmd.AddCommand(tokenRotateCmd)
+}
@@ -3,7 +3,12 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/bundle"
 )
 
 // findProjectRoot locates the netcup-kube project root directory.
@@ -41,3 +46,69 @@ func findProjectRoot() (string, error) {
 
 	return "", fmt.Errorf("could not locate project root: scripts/main.sh not found in current directory or expected locations")
 }
+
+// findScriptsDir returns the directory holding main.sh and the recipes: the
+// checkout's scripts/ when running from one, otherwise the scripts embedded
+// in the binary, extracted to the user cache directory.
+func findScriptsDir() (string, error) {
+	if projectRoot, err := findProjectRoot(); err == nil {
+		return filepath.Join(projectRoot, "scripts"), nil
+	}
+	dir, err := bundle.Scripts()
+	if err != nil {
+		return "", fmt.Errorf("failed to extract embedded scripts: %w", err)
+	}
+	return dir, nil
+}
+
+// workspaceRoot returns the directory config/ is resolved against: the
+// checkout when running from one, otherwise the working directory (where the
+// default config/netcup-kube.env is looked up as well).
+func workspaceRoot() (string, error) {
+	if projectRoot, err := findProjectRoot(); err == nil {
+		return projectRoot, nil
+	}
+	return os.Getwd()
+}
+
+// openBrowser opens url in the default browser without waiting for it
+func openBrowser(url string) error {
+	var opener *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		opener = exec.Command("open", url)
+	case "windows":
+		opener = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		opener = exec.Command("xdg-open", url)
+	}
+	if err := opener.Start(); err != nil {
+		return fmt.Errorf("could not open a browser (%v); open %s manually", err, url)
+	}
+	return nil
+}
+
+// clipboardCommands are tried in order to copy to the clipboard
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// copyToClipboard copies text with the first clipboard tool found
+func copyToClipboard(text string) error {
+	for _, c := range clipboardCommands {
+		if _, err := exec.LookPath(c[0]); err != nil {
+			continue
+		}
+		copier := exec.Command(c[0], c[1:]...)
+		copier.Stdin = strings.NewReader(text)
+		if err := copier.Run(); err != nil {
+			return fmt.Errorf("%s failed: %w", c[0], err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no clipboard tool found (pbcopy, wl-copy, xclip, xsel, clip.exe)")
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/mfittko/netcup-kube/internal/network"
+	"github.com/mfittko/netcup-kube/internal/watchdog"
+	"github.com/spf13/cobra"
+)
+
+var watchdogCmd = &cobra.Command{
+	Use:   "watchdog",
+	Short: "Restart unhealthy k3s/Caddy services on the node and send alerts",
+	Long: `Manage a systemd timer on the node that checks k3s and Caddy, restarts a
+service that is down (or, for k3s, whose API is not ready), and posts a JSON
+alert to WATCHDOG_WEBHOOK after repeated failures and again on recovery.
+
+Settings (env file or environment):
+  WATCHDOG_SERVICES      services to watch (default: installed ones of k3s, k3s-agent, caddy)
+  WATCHDOG_INTERVAL      time between checks (default: 2m)
+  WATCHDOG_MAX_FAILURES  failed checks in a row before an alert (default: 3)
+  WATCHDOG_WEBHOOK       alert URL (default: journal only)
+
+Sub-commands:
+  install    - Install or update the watchdog script and timer
+  uninstall  - Remove the watchdog
+  status     - Show the timer and the failure counters
+
+Examples:
+  sudo WATCHDOG_WEBHOOK=https://ntfy.sh/my-cluster netcup-kube watchdog install
+  sudo netcup-kube watchdog install --dry-run
+  netcup-kube watchdog status`,
+	SilenceUsage: true,
+}
+
+var watchdogInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install or update the watchdog script and timer",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host, err := watchdogHost(cmd.Context(), "watchdog install")
+		if err != nil {
+			return err
+		}
+		spec, err := watchdog.SpecFromEnv(cfg.Env, network.LocalHost{Context: cmd.Context()})
+		if err != nil {
+			return err
+		}
+		if err := watchdog.Apply(spec, host, os.Stdout); err != nil {
+			return err
+		}
+		target := "the journal"
+		if spec.Webhook != "" {
+			target = spec.Webhook
+		}
+		fmt.Printf("watchdog: checking %s every %s; alerts after %d failed checks go to %s\n",
+			strings.Join(spec.Services, ", "), spec.Interval, spec.MaxFailures, target)
+		return nil
+	},
+}
+
+var watchdogUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop the watchdog timer and remove its files",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host, err := watchdogHost(cmd.Context(), "watchdog uninstall")
+		if err != nil {
+			return err
+		}
+		return watchdog.Uninstall(host, os.Stdout)
+	},
+}
+
+var watchdogStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the watchdog timer and the failure counters",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host := network.LocalHost{Context: cmd.Context()}
+		if _, err := os.Stat(watchdog.TimerPath); err != nil {
+			fmt.Println("watchdog: not installed (sudo netcup-kube watchdog install)")
+			return nil
+		}
+		state := "inactive"
+		if out, err := host.Output("systemctl", "is-active", watchdog.TimerName); err == nil {
+			state = strings.TrimSpace(string(out))
+		}
+		fmt.Printf("watchdog: %s is %s\n", watchdog.TimerName, state)
+
+		failures, err := watchdog.Failures(watchdog.StateDir)
+		if err != nil {
+			return fmt.Errorf("failed to read the failure counters: %w", err)
+		}
+		spec, err := watchdog.SpecFromEnv(cfg.Env, host)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "SERVICE\tSTATE\tFAILED CHECKS")
+		for _, svc := range spec.Services {
+			svcState := "inactive"
+			if out, err := host.Output("systemctl", "is-active", svc+".service"); err == nil {
+				svcState = strings.TrimSpace(string(out))
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\n", svc, svcState, failures[svc])
+		}
+		return tw.Flush()
+	},
+}
+
+// watchdogHost returns the host to install on: a dry-run host printing the
+// changes, or the local host when running as root
+func watchdogHost(ctx context.Context, command string) (network.Host, error) {
+	var host network.Host = network.LocalHost{Context: ctx}
+	if isDryRun() {
+		return network.DryRunHost{Host: host, Out: os.Stdout}, nil
+	}
+	if os.Geteuid() != 0 {
+		return nil, fmt.Errorf("%s must run as root (use sudo)", command)
+	}
+	return host, nil
+}
+
+func init() {
+	watchdogCmd.AddCommand(watchdogInstallCmd)
+	watchdogCmd.AddCommand(watchdogUninstallCmd)
+	watchdogCmd.AddCommand(watchdogStatusCmd)
+}
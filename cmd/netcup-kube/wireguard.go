@@ -0,0 +1,433 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/wireguard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	wgEndpoint string
+	wgOutput   string
+
+	wgConfigDir    = "/etc/wireguard"
+	wgProbeTimeout = 2 * time.Second
+)
+
+var wireguardCmd = &cobra.Command{
+	Use:   "wireguard",
+	Short: "Manage a WireGuard link to the management node",
+	Long: `Manage a WireGuard interface between operator machines and the management node.
+
+With WireGuard up, the kube API is reachable at the node's WireGuard address
+without a per-session SSH tunnel. Commands that need the API (e.g. install)
+probe WG_SERVER_IP:6443 first and fall back to the SSH tunnel when it is
+unreachable.
+
+Configuration:
+  WG_IFACE      interface name (default: wg-netcup)
+  WG_ADDRESS    management node address and subnet (default: 10.99.0.1/24)
+  WG_PORT       UDP listen port (default: 51820)
+  WG_ENDPOINT   public endpoint for peers (default: MGMT_HOST:WG_PORT)
+  WG_SERVER_IP  on operator machines: the node's WireGuard IP (e.g. 10.99.0.1)
+
+Sub-commands:
+  init      - Create the WireGuard interface on the management node
+  peer add  - Add a peer (laptop or worker) and print its config
+  status    - Show peers, handshakes, and kube API reachability`,
+	SilenceUsage: true,
+}
+
+var wireguardInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the WireGuard interface on the management node",
+	Long: `Create (or update) the WireGuard interface on the management node and enable
+wg-quick@<iface>. An existing private key is kept, so init is safe to re-run.
+
+Add the WireGuard address to TLS_SANS_EXTRA before bootstrap (or re-run it) so
+the k3s API certificate is valid for it.
+
+Examples:
+  sudo netcup-kube wireguard init
+  sudo WG_ADDRESS=10.99.0.1/24 WG_PORT=51820 netcup-kube wireguard init --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		iface, address, port, err := wireguardSettings()
+		if err != nil {
+			return err
+		}
+		path := wireguardConfigPath(iface)
+
+		wgCfg := &wireguard.Config{}
+		if data, err := os.ReadFile(path); err == nil {
+			if wgCfg, err = wireguard.Parse(string(data)); err != nil {
+				return fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+		}
+		if wgCfg.Interface.PrivateKey == "" {
+			priv, _, err := wireguard.GenerateKey()
+			if err != nil {
+				return err
+			}
+			wgCfg.Interface.PrivateKey = priv
+		}
+		wgCfg.Interface.Address = address
+		wgCfg.Interface.ListenPort = port
+
+		pub, err := wireguard.PublicKey(wgCfg.Interface.PrivateKey)
+		if err != nil {
+			return err
+		}
+
+		if isDryRun() {
+			fmt.Printf("[dry-run] write %s (0600)\n", path)
+			fmt.Printf("[dry-run] systemctl enable wg-quick@%s && systemctl restart wg-quick@%s\n", iface, iface)
+			if ufwEnabled() {
+				fmt.Printf("[dry-run] ufw allow %d/udp\n", port)
+			}
+			return nil
+		}
+		if os.Geteuid() != 0 {
+			return fmt.Errorf("wireguard init must run as root (use sudo)")
+		}
+		if _, err := exec.LookPath("wg-quick"); err != nil {
+			return fmt.Errorf("wg-quick not found; install wireguard-tools first (apt-get install wireguard-tools)")
+		}
+
+		if err := writeWireguardConfig(path, wgCfg.Render()); err != nil {
+			return err
+		}
+		unit := "wg-quick@" + iface
+		if err := runQuiet("systemctl", "enable", unit); err != nil {
+			return fmt.Errorf("failed to enable %s: %w", unit, err)
+		}
+		if err := runQuiet("systemctl", "restart", unit); err != nil {
+			return fmt.Errorf("failed to start %s: %w", unit, err)
+		}
+		if ufwEnabled() {
+			if err := runQuiet("ufw", "allow", fmt.Sprintf("%d/udp", port)); err != nil {
+				return fmt.Errorf("failed to open UDP port %d in UFW: %w", port, err)
+			}
+		}
+
+		fmt.Printf("WireGuard interface %s is up (%s, port %d/udp)\n", iface, address, port)
+		fmt.Printf("Public key: %s\n", pub)
+		fmt.Printf("\nNext: ensure TLS_SANS_EXTRA includes %s, then add peers with:\n", wgCfg.ServerIP())
+		fmt.Println("  sudo netcup-kube wireguard peer add <name>")
+		return nil
+	},
+}
+
+var wireguardPeerCmd = &cobra.Command{
+	Use:   "peer",
+	Short: "Manage WireGuard peers",
+}
+
+var wireguardPeerAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a WireGuard peer and print its client config",
+	Long: `Add a peer to the management node's WireGuard interface and print a wg-quick
+config for it. The peer gets the next free address in the WireGuard subnet and
+is activated without restarting the interface.
+
+The client config contains the peer's private key; use --output to write it to
+a file (mode 0600) instead of stdout.
+
+Examples:
+  sudo netcup-kube wireguard peer add laptop --output laptop.conf
+  sudo netcup-kube wireguard peer add worker-1 --endpoint 203.0.113.10:51820`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.TrimSpace(args[0])
+		if name == "" {
+			return fmt.Errorf("peer name must not be empty")
+		}
+
+		iface, _, port, err := wireguardSettings()
+		if err != nil {
+			return err
+		}
+		path := wireguardConfigPath(iface)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s (run `netcup-kube wireguard init` first): %w", path, err)
+		}
+		serverCfg, err := wireguard.Parse(string(data))
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		if serverCfg.FindPeer(name) != nil {
+			return fmt.Errorf("peer %q already exists in %s", name, path)
+		}
+
+		endpoint, err := wireguardEndpoint(port)
+		if err != nil {
+			return err
+		}
+		serverPub, err := wireguard.PublicKey(serverCfg.Interface.PrivateKey)
+		if err != nil {
+			return err
+		}
+		_, subnet, err := net.ParseCIDR(serverCfg.Interface.Address)
+		if err != nil {
+			return fmt.Errorf("invalid interface address %q in %s", serverCfg.Interface.Address, path)
+		}
+		peerAddr, err := serverCfg.NextPeerAddress()
+		if err != nil {
+			return err
+		}
+		peerPriv, peerPub, err := wireguard.GenerateKey()
+		if err != nil {
+			return err
+		}
+
+		serverCfg.Peers = append(serverCfg.Peers, wireguard.Peer{Name: name, PublicKey: peerPub, AllowedIPs: peerAddr})
+		clientCfg := &wireguard.Config{
+			Interface: wireguard.Interface{PrivateKey: peerPriv, Address: peerAddr},
+			Peers: []wireguard.Peer{{
+				Name:                "management",
+				PublicKey:           serverPub,
+				AllowedIPs:          subnet.String(),
+				Endpoint:            endpoint,
+				PersistentKeepalive: wireguard.DefaultKeepalive,
+			}},
+		}
+
+		if isDryRun() {
+			fmt.Printf("[dry-run] add peer %s (%s) to %s\n", name, peerAddr, path)
+			fmt.Printf("[dry-run] wg set %s peer %s allowed-ips %s\n", iface, peerPub, peerAddr)
+			return nil
+		}
+		if os.Geteuid() != 0 {
+			return fmt.Errorf("wireguard peer add must run as root (use sudo)")
+		}
+
+		if err := writeWireguardConfig(path, serverCfg.Render()); err != nil {
+			return err
+		}
+		if err := runQuiet("wg", "set", iface, "peer", peerPub, "allowed-ips", peerAddr); err != nil {
+			return fmt.Errorf("peer saved to %s but could not be activated (is %s up?): %w", path, iface, err)
+		}
+
+		if wgOutput != "" {
+			if err := os.WriteFile(wgOutput, []byte(clientCfg.Render()), 0o600); err != nil {
+				return fmt.Errorf("failed to write %s: %w", wgOutput, err)
+			}
+			fmt.Printf("Peer %s added (%s); client config written to %s\n", name, peerAddr, wgOutput)
+		} else {
+			fmt.Printf("# Peer %s added (%s). Client config:\n\n%s\n", name, peerAddr, clientCfg.Render())
+		}
+		fmt.Printf("On the peer: sudo wg-quick up <config>, then set WG_SERVER_IP=%s in config/netcup-kube.env\n", serverCfg.ServerIP())
+		return nil
+	},
+}
+
+var wireguardStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show WireGuard peers and kube API reachability",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		iface, _, _, err := wireguardSettings()
+		if err != nil {
+			return err
+		}
+
+		names := map[string]string{}
+		var localCfg *wireguard.Config
+		if data, err := os.ReadFile(wireguardConfigPath(iface)); err == nil {
+			if localCfg, err = wireguard.Parse(string(data)); err == nil {
+				for _, p := range localCfg.Peers {
+					names[p.PublicKey] = p.Name
+				}
+			}
+		}
+
+		out, err := exec.Command("wg", "show", iface, "dump").Output()
+		if err != nil {
+			fmt.Printf("Interface %s: down or not accessible (wg show requires root)\n", iface)
+		} else {
+			fmt.Printf("Interface %s: up\n", iface)
+			printWireguardPeers(wireguard.ParseDump(string(out)), names, time.Now())
+		}
+
+		serverIP := strings.TrimSpace(cfg.Env["WG_SERVER_IP"])
+		if serverIP == "" && localCfg != nil && localCfg.Interface.ListenPort > 0 {
+			serverIP = localCfg.ServerIP()
+		}
+		if serverIP == "" {
+			fmt.Println("kube API via WireGuard: WG_SERVER_IP not set")
+			return nil
+		}
+		addr := net.JoinHostPort(serverIP, wireguard.APIPort)
+		if wireguard.Reachable(addr, wgProbeTimeout) {
+			fmt.Printf("kube API via WireGuard (%s): reachable\n", addr)
+		} else {
+			fmt.Printf("kube API via WireGuard (%s): unreachable (SSH tunnel fallback will be used)\n", addr)
+		}
+		return nil
+	},
+}
+
+func printWireguardPeers(peers []wireguard.PeerStatus, names map[string]string, now time.Time) {
+	if len(peers) == 0 {
+		fmt.Println("  no peers")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "  NAME\tALLOWED IPS\tENDPOINT\tLAST HANDSHAKE\tRX\tTX")
+	for _, p := range peers {
+		name := names[p.PublicKey]
+		if name == "" {
+			name = p.PublicKey[:min(8, len(p.PublicKey))] + "..."
+		}
+		handshake := "never"
+		if !p.LastHandshake.IsZero() {
+			handshake = now.Sub(p.LastHandshake).Round(time.Second).String() + " ago"
+		}
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%d\t%d\n", name, p.AllowedIPs, orDash(p.Endpoint), handshake, p.RxBytes, p.TxBytes)
+	}
+	_ = w.Flush()
+}
+
+// wireguardSettings returns the interface name, address, and port from config
+func wireguardSettings() (string, string, int, error) {
+	iface := strings.TrimSpace(cfg.Env["WG_IFACE"])
+	if iface == "" {
+		iface = wireguard.DefaultInterface
+	}
+	address := strings.TrimSpace(cfg.Env["WG_ADDRESS"])
+	if address == "" {
+		address = wireguard.DefaultAddress
+	}
+	if ip, _, err := net.ParseCIDR(address); err != nil || ip.To4() == nil {
+		return "", "", 0, fmt.Errorf("WG_ADDRESS must be an IPv4 CIDR (e.g. %s), got %q", wireguard.DefaultAddress, address)
+	}
+	port := wireguard.DefaultPort
+	if raw := strings.TrimSpace(cfg.Env["WG_PORT"]); raw != "" {
+		p, err := strconv.Atoi(raw)
+		if err != nil || p < 1 || p > 65535 {
+			return "", "", 0, fmt.Errorf("WG_PORT must be a valid port, got %q", raw)
+		}
+		port = p
+	}
+	return iface, address, port, nil
+}
+
+// wireguardEndpoint returns the endpoint peers use to reach the management node
+func wireguardEndpoint(port int) (string, error) {
+	if endpoint := strings.TrimSpace(wgEndpoint); endpoint != "" {
+		return endpoint, nil
+	}
+	if endpoint := strings.TrimSpace(cfg.Env["WG_ENDPOINT"]); endpoint != "" {
+		return endpoint, nil
+	}
+	for _, key := range []string{"MGMT_HOST", "NODE_EXTERNAL_IP", "MGMT_IP"} {
+		if host := strings.TrimSpace(cfg.Env[key]); host != "" {
+			return net.JoinHostPort(host, strconv.Itoa(port)), nil
+		}
+	}
+	return "", fmt.Errorf("no endpoint for peers: use --endpoint or set WG_ENDPOINT (or MGMT_HOST)")
+}
+
+func wireguardConfigPath(iface string) string {
+	return filepath.Join(wgConfigDir, iface+".conf")
+}
+
+func writeWireguardConfig(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Chmod(path, 0o600)
+}
+
+// wireguardKubeconfig returns a kubeconfig that reaches the API over WireGuard
+// when WG_SERVER_IP is configured and reachable. It returns false when the
+// caller should fall back to the SSH tunnel.
+func wireguardKubeconfig(envFile, kubeconfig string) (string, bool) {
+	env, err := config.LoadEnvFileToMap(envFile)
+	if err != nil {
+		env = make(map[string]string)
+	}
+	serverIP := strings.TrimSpace(env["WG_SERVER_IP"])
+	if serverIP == "" {
+		serverIP = strings.TrimSpace(os.Getenv("WG_SERVER_IP"))
+	}
+	if serverIP == "" {
+		return "", false
+	}
+
+	addr := net.JoinHostPort(serverIP, wireguard.APIPort)
+	wgKubeconfig, err := wireguard.Kubeconfig(kubeconfig, addr, wgProbeTimeout)
+	if errors.Is(err, wireguard.ErrUnreachable) {
+		fmt.Printf("WireGuard API %s unreachable; falling back to SSH tunnel\n", addr)
+		return "", false
+	} else if err != nil {
+		fmt.Printf("Warning: %v; falling back to SSH tunnel\n", err)
+		return "", false
+	}
+
+	fmt.Printf("Using WireGuard: %s\n", addr)
+	return wgKubeconfig, true
+}
+
+func isDryRun() bool {
+	return strings.EqualFold(cfg.Env["DRY_RUN"], "true")
+}
+
+// ufwEnabled reports whether UFW should be updated: ENABLE_UFW=true, or
+// ENABLE_UFW unset and UFW already active on the host.
+func ufwEnabled() bool {
+	if _, err := exec.LookPath("ufw"); err != nil {
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(cfg.Env["ENABLE_UFW"])) {
+	case "true":
+		return true
+	case "":
+		out, err := exec.Command("ufw", "status").Output()
+		return err == nil && strings.Contains(string(out), "Status: active")
+	}
+	return false
+}
+
+func runQuiet(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func init() {
+	wireguardPeerAddCmd.Flags().StringVar(&wgEndpoint, "endpoint", "", "Endpoint peers connect to (default: WG_ENDPOINT or MGMT_HOST:WG_PORT)")
+	wireguardPeerAddCmd.Flags().StringVar(&wgOutput, "output", "", "Write the client config to this file instead of stdout")
+
+	wireguardPeerCmd.AddCommand(wireguardPeerAddCmd)
+	wireguardCmd.AddCommand(wireguardInitCmd)
+	wireguardCmd.AddCommand(wireguardPeerCmd)
+	wireguardCmd.AddCommand(wireguardStatusCmd)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+)
+
+func TestWireguardSettings(t *testing.T) {
+	prev := cfg
+	defer func() { cfg = prev }()
+
+	cfg = config.New()
+	iface, address, port, err := wireguardSettings()
+	if err != nil {
+		t.Fatalf("wireguardSettings() error: %v", err)
+	}
+	if iface != "wg-netcup" || address != "10.99.0.1/24" || port != 51820 {
+		t.Errorf("defaults = %s, %s, %d", iface, address, port)
+	}
+
+	cfg.SetFlag("WG_PORT", "70000")
+	if _, _, _, err := wireguardSettings(); err == nil {
+		t.Error("expected error for invalid WG_PORT")
+	}
+
+	cfg = config.New()
+	cfg.SetFlag("WG_ADDRESS", "fd00::1/64")
+	if _, _, _, err := wireguardSettings(); err == nil {
+		t.Error("expected error for IPv6 WG_ADDRESS")
+	}
+}
+
+func TestWireguardEndpoint(t *testing.T) {
+	prev := cfg
+	prevFlag := wgEndpoint
+	defer func() { cfg, wgEndpoint = prev, prevFlag }()
+
+	cfg = config.New()
+	wgEndpoint = ""
+	if _, err := wireguardEndpoint(51820); err == nil {
+		t.Error("expected error without any endpoint source")
+	}
+
+	cfg.SetFlag("MGMT_HOST", "mgmt.example.com")
+	if got, _ := wireguardEndpoint(51820); got != "mgmt.example.com:51820" {
+		t.Errorf("endpoint = %q", got)
+	}
+
+	cfg.SetFlag("WG_ENDPOINT", "203.0.113.10:51999")
+	if got, _ := wireguardEndpoint(51820); got != "203.0.113.10:51999" {
+		t.Errorf("endpoint = %q", got)
+	}
+
+	wgEndpoint = "override:1"
+	if got, _ := wireguardEndpoint(51820); got != "override:1" {
+		t.Errorf("endpoint = %q", got)
+	}
+}
@@ -0,0 +1,406 @@
+// Package airgap creates and verifies offline bundles: the k3s installer,
+// binary and airgap images, the Helm CLI, and the Helm charts pinned by the
+// recipes, so that bootstrap and install work without internet access on
+// the server.
+package airgap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ManifestFile lists the bundle's contents with their checksums
+	ManifestFile = "manifest.json"
+
+	// DefaultInstallerURL serves the k3s install script
+	DefaultInstallerURL = "https://get.k3s.io"
+
+	// DefaultReleaseURL serves the k3s release assets by version
+	DefaultReleaseURL = "https://github.com/k3s-io/k3s/releases/download"
+
+	// DefaultHelmURL serves the Helm CLI release archives
+	DefaultHelmURL = "https://get.helm.sh"
+
+	// HelmVersion is the Helm CLI bootstrap installs (see scripts/modules/dashboard.sh)
+	HelmVersion = "v3.19.4"
+)
+
+// Chart is a Helm chart a recipe installs; its version is pinned in
+// scripts/recipes/recipes.conf under VersionKey
+type Chart struct {
+	Name       string
+	Repo       string
+	VersionKey string
+}
+
+// Charts are the charts the recipes and bootstrap install
+var Charts = []Chart{
+	{Name: "kube-prometheus-stack", Repo: "https://prometheus-community.github.io/helm-charts", VersionKey: "CHART_VERSION_KUBE_PROMETHEUS_STACK"},
+	{Name: "kubernetes-dashboard", Repo: "https://kubernetes.github.io/dashboard/", VersionKey: "CHART_VERSION_KUBERNETES_DASHBOARD"},
+	{Name: "metoro-exporter", Repo: "https://metoro-io.github.io/metoro-helm-charts/", VersionKey: "CHART_VERSION_METORO_EXPORTER"},
+	{Name: "mysql", Repo: "https://charts.bitnami.com/bitnami", VersionKey: "CHART_VERSION_MYSQL"},
+	{Name: "openclaw", Repo: "https://serhanekicii.github.io/openclaw-helm", VersionKey: "CHART_VERSION_OPENCLAW"},
+	{Name: "postgresql", Repo: "https://charts.bitnami.com/bitnami", VersionKey: "CHART_VERSION_POSTGRESQL"},
+	{Name: "redis", Repo: "https://charts.bitnami.com/bitnami", VersionKey: "CHART_VERSION_REDIS"},
+	{Name: "sealed-secrets", Repo: "https://bitnami-labs.github.io/sealed-secrets", VersionKey: "CHART_VERSION_SEALED_SECRETS"},
+}
+
+// File is a bundled file, relative to the bundle directory
+type File struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// BundledChart is a chart archive in the bundle
+type BundledChart struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Repo    string `json:"repo"`
+	Path    string `json:"path"`
+}
+
+// Manifest describes a bundle
+type Manifest struct {
+	Created     time.Time      `json:"created"`
+	K3sVersion  string         `json:"k3sVersion"`
+	Arch        string         `json:"arch"`
+	HelmVersion string         `json:"helmVersion"`
+	Charts      []BundledChart `json:"charts"`
+	Files       []File         `json:"files"`
+}
+
+// Download is a release asset fetched into the bundle
+type Download struct {
+	URL  string
+	Path string
+	// Executable marks the installer and the k3s binary
+	Executable bool
+	// SumsURL is a checksum file listing the asset as SumsName
+	SumsURL  string
+	SumsName string
+}
+
+// ChartPuller fetches chart at version into dir as <name>-<version>.tgz
+type ChartPuller func(ctx context.Context, chart Chart, version, dir string) error
+
+// Options configure Create
+type Options struct {
+	Dir        string
+	K3sVersion string
+	Arch       string
+	// ChartVersions maps recipes.conf keys to chart versions
+	ChartVersions map[string]string
+	Charts        []Chart
+
+	Client       *http.Client
+	InstallerURL string
+	ReleaseURL   string
+	HelmURL      string
+	PullChart    ChartPuller
+	Out          io.Writer
+}
+
+// ValidateArch checks that arch is a k3s release architecture
+func ValidateArch(arch string) error {
+	if arch != "amd64" && arch != "arm64" {
+		return fmt.Errorf("unsupported architecture %q (use amd64 or arm64)", arch)
+	}
+	return nil
+}
+
+// Downloads returns the release assets Create fetches
+func (o Options) Downloads() []Download {
+	installer := orDefault(o.InstallerURL, DefaultInstallerURL)
+	release := strings.TrimRight(orDefault(o.ReleaseURL, DefaultReleaseURL), "/") + "/" + strings.ReplaceAll(o.K3sVersion, "+", "%2B")
+	helm := strings.TrimRight(orDefault(o.HelmURL, DefaultHelmURL), "/")
+
+	binary := "k3s"
+	if o.Arch != "amd64" {
+		binary = "k3s-" + o.Arch
+	}
+	images := "k3s-airgap-images-" + o.Arch + ".tar.zst"
+	sums := release + "/sha256sum-" + o.Arch + ".txt"
+	helmArchive := "helm-" + HelmVersion + "-linux-" + o.Arch + ".tar.gz"
+	return []Download{
+		{URL: installer, Path: "k3s/install.sh", Executable: true},
+		{URL: release + "/" + binary, Path: "k3s/k3s", Executable: true, SumsURL: sums, SumsName: binary},
+		{URL: release + "/" + images, Path: "k3s/" + images, SumsURL: sums, SumsName: images},
+		{URL: helm + "/" + helmArchive, Path: "helm/" + helmArchive, SumsURL: helm + "/" + helmArchive + ".sha256sum", SumsName: helmArchive},
+	}
+}
+
+// ChartVersion returns the pinned version of chart, or "" when recipes.conf
+// does not pin it to an exact version
+func (o Options) ChartVersion(chart Chart) string {
+	version := o.ChartVersions[chart.VersionKey]
+	if version == "latest" {
+		return ""
+	}
+	return version
+}
+
+// Create downloads the bundle into o.Dir and writes its manifest
+func Create(ctx context.Context, o Options) (*Manifest, error) {
+	if err := ValidateArch(o.Arch); err != nil {
+		return nil, err
+	}
+	if o.K3sVersion == "" {
+		return nil, fmt.Errorf("a k3s version is required")
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	if o.PullChart == nil {
+		o.PullChart = HelmPull
+	}
+	if o.Out == nil {
+		o.Out = io.Discard
+	}
+	for _, sub := range []string{"k3s", "helm", "charts"} {
+		if err := os.MkdirAll(filepath.Join(o.Dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Join(o.Dir, sub), err)
+		}
+	}
+
+	sums := map[string]map[string]string{}
+	for _, d := range o.Downloads() {
+		_, _ = fmt.Fprintf(o.Out, "Downloading %s\n", d.URL)
+		mode := os.FileMode(0o644)
+		if d.Executable {
+			mode = 0o755
+		}
+		digest, err := download(ctx, o.Client, d.URL, filepath.Join(o.Dir, filepath.FromSlash(d.Path)), mode)
+		if err != nil {
+			return nil, err
+		}
+		if d.SumsURL == "" {
+			continue
+		}
+		if sums[d.SumsURL] == nil {
+			data, err := fetch(ctx, o.Client, d.SumsURL)
+			if err != nil {
+				return nil, err
+			}
+			sums[d.SumsURL] = parseSums(data)
+		}
+		if want := sums[d.SumsURL][d.SumsName]; want != digest {
+			return nil, fmt.Errorf("checksum mismatch for %s: got %s, %s lists %q", d.URL, digest, d.SumsURL, want)
+		}
+	}
+
+	m := &Manifest{Created: time.Now().UTC(), K3sVersion: o.K3sVersion, Arch: o.Arch, HelmVersion: HelmVersion}
+	for _, chart := range o.Charts {
+		version := o.ChartVersion(chart)
+		if version == "" {
+			_, _ = fmt.Fprintf(o.Out, "Warning: skipping chart %s: %s is not pinned to a version\n", chart.Name, chart.VersionKey)
+			continue
+		}
+		_, _ = fmt.Fprintf(o.Out, "Pulling chart %s %s from %s\n", chart.Name, version, chart.Repo)
+		if err := o.PullChart(ctx, chart, version, filepath.Join(o.Dir, "charts")); err != nil {
+			return nil, fmt.Errorf("failed to pull chart %s %s: %w", chart.Name, version, err)
+		}
+		path := "charts/" + chart.Name + "-" + version + ".tgz"
+		if _, err := os.Stat(filepath.Join(o.Dir, filepath.FromSlash(path))); err != nil {
+			return nil, fmt.Errorf("chart %s %s was not saved as %s: %w", chart.Name, version, path, err)
+		}
+		m.Charts = append(m.Charts, BundledChart{Name: chart.Name, Version: version, Repo: chart.Repo, Path: path})
+	}
+
+	files, err := listFiles(o.Dir)
+	if err != nil {
+		return nil, err
+	}
+	m.Files = files
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(o.Dir, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", ManifestFile, err)
+	}
+	return m, nil
+}
+
+// Verify reads the manifest of the bundle in dir and checks every listed
+// file against its checksum
+func Verify(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Join(dir, ManifestFile), err)
+	}
+	listed := map[string]bool{}
+	for _, f := range m.Files {
+		listed[f.Path] = true
+		digest, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		if err != nil {
+			return nil, err
+		}
+		if digest != f.SHA256 {
+			return nil, fmt.Errorf("bundle file %s is corrupt: checksum %s, manifest lists %s", f.Path, digest, f.SHA256)
+		}
+	}
+	for _, required := range []string{"k3s/install.sh", "k3s/k3s"} {
+		if !listed[required] {
+			return nil, fmt.Errorf("bundle %s has no %s", dir, required)
+		}
+	}
+	return &m, nil
+}
+
+// HelmPull fetches a chart with the helm CLI
+func HelmPull(ctx context.Context, chart Chart, version, dir string) error {
+	if _, err := exec.LookPath("helm"); err != nil {
+		return fmt.Errorf("helm is required to bundle charts (or use --no-charts): %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "helm", "pull", chart.Name, "--repo", chart.Repo, "--version", version, "--destination", dir)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// download writes url to dest (staged next to it) and returns its SHA-256
+func download(ctx context.Context, client *http.Client, url, dest string, mode os.FileMode) (string, error) {
+	resp, err := get(ctx, client, url)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	stage, err := os.CreateTemp(filepath.Dir(dest), ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer func() { _ = os.Remove(stage.Name()) }()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(stage, h), resp.Body); err != nil {
+		_ = stage.Close()
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if err := stage.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(stage.Name(), mode); err != nil {
+		return "", err
+	}
+	if err := os.Rename(stage.Name(), dest); err != nil {
+		return "", fmt.Errorf("failed to save %s: %w", dest, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func fetch(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	resp, err := get(ctx, client, url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return data, nil
+}
+
+func get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// parseSums reads "<sha256>  <name>" lines as written by sha256sum
+func parseSums(data []byte) map[string]string {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+		}
+	}
+	return sums
+}
+
+func listFiles(dir string) ([]File, error) {
+	var files []File
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ManifestFile || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		digest, err := fileSHA256(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, File{Path: rel, SHA256: digest, Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bundle files: %w", err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read bundle file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func orDefault(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,230 @@
+package airgap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sum(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+func TestCreateAndVerify(t *testing.T) {
+	assets := map[string]string{
+		"/install.sh":                       "#!/bin/sh\n",
+		"/release/v1.31.4%2Bk3s1/k3s-arm64": "k3s binary",
+		"/release/v1.31.4%2Bk3s1/k3s-airgap-images-arm64.tar.zst":     "images",
+		"/helm/helm-" + HelmVersion + "-linux-arm64.tar.gz":           "helm",
+		"/helm/helm-" + HelmVersion + "-linux-arm64.tar.gz.sha256sum": sum("helm") + "  helm-" + HelmVersion + "-linux-arm64.tar.gz\n",
+	}
+	assets["/release/v1.31.4%2Bk3s1/sha256sum-arm64.txt"] = fmt.Sprintf("%s  k3s-arm64\n%s  k3s-airgap-images-arm64.tar.zst\n", sum("k3s binary"), sum("images"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := assets[r.URL.EscapedPath()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	var pulled []string
+	opts := Options{
+		Dir:           dir,
+		K3sVersion:    "v1.31.4+k3s1",
+		Arch:          "arm64",
+		ChartVersions: map[string]string{"CHART_VERSION_REDIS": "24.1.0", "CHART_VERSION_OPENCLAW": "latest"},
+		Charts:        []Chart{{Name: "redis", VersionKey: "CHART_VERSION_REDIS"}, {Name: "openclaw", VersionKey: "CHART_VERSION_OPENCLAW"}},
+		InstallerURL:  srv.URL + "/install.sh",
+		ReleaseURL:    srv.URL + "/release",
+		HelmURL:       srv.URL + "/helm",
+		PullChart: func(_ context.Context, chart Chart, version, into string) error {
+			pulled = append(pulled, chart.Name)
+			return os.WriteFile(filepath.Join(into, chart.Name+"-"+version+".tgz"), []byte("chart"), 0o644)
+		},
+	}
+	m, err := Create(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// openclaw follows "latest" and cannot be pinned in a bundle
+	if strings.Join(pulled, ",") != "redis" || len(m.Charts) != 1 || m.Charts[0].Path != "charts/redis-24.1.0.tgz" {
+		t.Errorf("charts = %+v (pulled %v)", m.Charts, pulled)
+	}
+	if len(m.Files) != 5 {
+		t.Errorf("files = %+v", m.Files)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "k3s", "k3s")); err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("k3s binary = %v, %v", info, err)
+	}
+
+	got, err := Verify(dir)
+	if err != nil || got.K3sVersion != "v1.31.4+k3s1" || got.Arch != "arm64" {
+		t.Fatalf("Verify() = %+v, %v", got, err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "charts", "redis-24.1.0.tgz"), []byte("tampered"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(dir); err == nil || !strings.Contains(err.Error(), "charts/redis-24.1.0.tgz is corrupt") {
+		t.Errorf("Verify() after tampering error = %v", err)
+	}
+}
+
+func TestCreateRejectsChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".txt") {
+			_, _ = fmt.Fprintf(w, "%s  k3s\n", sum("something else"))
+			return
+		}
+		_, _ = w.Write([]byte("payload"))
+	}))
+	defer srv.Close()
+
+	_, err := Create(context.Background(), Options{
+		Dir: t.TempDir(), K3sVersion: "v1.31.4+k3s1", Arch: "amd64",
+		InstallerURL: srv.URL, ReleaseURL: srv.URL, HelmURL: srv.URL,
+	})
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Create() error = %v", err)
+	}
+	if err := ValidateArch("riscv64"); err == nil {
+		t.Error("ValidateArch() expected error")
+	}
+}
+
+func TestDownloadsDefaults(t *testing.T) {
+	downloads := Options{K3sVersion: "v1.31.4+k3s1", Arch: "amd64"}.Downloads()
+	want := []string{
+		DefaultInstallerURL,
+		DefaultReleaseURL + "/v1.31.4%2Bk3s1/k3s",
+		DefaultReleaseURL + "/v1.31.4%2Bk3s1/k3s-airgap-images-amd64.tar.zst",
+		DefaultHelmURL + "/helm-" + HelmVersion + "-linux-amd64.tar.gz",
+	}
+	for i, d := range downloads {
+		if d.URL != want[i] {
+			t.Errorf("download %d = %s, want %s", i, d.URL, want[i])
+		}
+	}
+	if downloads[1].SumsName != "k3s" {
+		t.Errorf("k3s sums name = %q", downloads[1].SumsName)
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".txt"):
+			_, _ = fmt.Fprintf(w, "%s  k3s\n%s  k3s-airgap-images-amd64.tar.zst\n", sum("payload"), sum("payload"))
+		case strings.HasSuffix(r.URL.Path, ".sha256sum"):
+			_, _ = fmt.Fprintf(w, "%s  helm-%s-linux-amd64.tar.gz\n", sum("payload"), HelmVersion)
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			http.NotFound(w, r)
+		default:
+			_, _ = w.Write([]byte("payload"))
+		}
+	}))
+	defer srv.Close()
+	base := func() Options {
+		return Options{
+			Dir: t.TempDir(), K3sVersion: "v1.31.4+k3s1", Arch: "amd64",
+			InstallerURL: srv.URL, ReleaseURL: srv.URL, HelmURL: srv.URL, Client: srv.Client(),
+			ChartVersions: map[string]string{"CHART_VERSION_REDIS": "24.1.0"},
+			Charts:        []Chart{{Name: "redis", VersionKey: "CHART_VERSION_REDIS"}},
+		}
+	}
+
+	o := base()
+	o.K3sVersion = ""
+	if _, err := Create(context.Background(), o); err == nil || !strings.Contains(err.Error(), "k3s version is required") {
+		t.Errorf("Create() without a version error = %v", err)
+	}
+	o = base()
+	o.Arch = "s390x"
+	if _, err := Create(context.Background(), o); err == nil {
+		t.Error("Create() with an unsupported architecture expected error")
+	}
+	o = base()
+	o.InstallerURL = srv.URL + "/missing"
+	if _, err := Create(context.Background(), o); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Create() with a missing asset error = %v", err)
+	}
+	o = base()
+	o.PullChart = func(context.Context, Chart, string, string) error { return fmt.Errorf("repo unreachable") }
+	if _, err := Create(context.Background(), o); err == nil || !strings.Contains(err.Error(), "failed to pull chart redis 24.1.0") {
+		t.Errorf("Create() with a failing pull error = %v", err)
+	}
+	o = base()
+	o.PullChart = func(context.Context, Chart, string, string) error { return nil }
+	if _, err := Create(context.Background(), o); err == nil || !strings.Contains(err.Error(), "was not saved") {
+		t.Errorf("Create() with a chart not saved error = %v", err)
+	}
+	o = base()
+	o.Dir = filepath.Join(o.Dir, ManifestFile)
+	if err := os.WriteFile(o.Dir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Create(context.Background(), o); err == nil {
+		t.Error("Create() into a file expected error")
+	}
+}
+
+func TestVerifyErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Verify(dir); err == nil || !strings.Contains(err.Error(), "failed to read bundle manifest") {
+		t.Errorf("Verify() without a manifest error = %v", err)
+	}
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, ManifestFile), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("{")
+	if _, err := Verify(dir); err == nil || !strings.Contains(err.Error(), "failed to parse") {
+		t.Errorf("Verify() of invalid JSON error = %v", err)
+	}
+	write(`{"files":[{"path":"k3s/install.sh","sha256":"00"}]}`)
+	if _, err := Verify(dir); err == nil || !strings.Contains(err.Error(), "failed to read bundle file") {
+		t.Errorf("Verify() of a missing file error = %v", err)
+	}
+	write(`{"files":[]}`)
+	if _, err := Verify(dir); err == nil || !strings.Contains(err.Error(), "has no k3s/install.sh") {
+		t.Errorf("Verify() of an incomplete bundle error = %v", err)
+	}
+}
+
+func TestHelmPull(t *testing.T) {
+	bin := t.TempDir()
+	t.Setenv("PATH", bin)
+	chart := Chart{Name: "redis", Repo: "https://charts.example.com"}
+	if err := HelmPull(context.Background(), chart, "1.0.0", t.TempDir()); err == nil || !strings.Contains(err.Error(), "helm is required") {
+		t.Errorf("HelmPull() without helm error = %v", err)
+	}
+
+	script := "#!/bin/sh\n[ \"$2\" = redis ] || { echo \"unknown chart $2\" >&2; exit 1; }\necho \"$@\" > \"$8/args\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "helm"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dest := t.TempDir()
+	if err := HelmPull(context.Background(), chart, "1.0.0", dest); err != nil {
+		t.Fatalf("HelmPull() error: %v", err)
+	}
+	args, _ := os.ReadFile(filepath.Join(dest, "args"))
+	if got := strings.TrimSpace(string(args)); got != "pull redis --repo https://charts.example.com --version 1.0.0 --destination "+dest {
+		t.Errorf("helm args = %q", got)
+	}
+	chart.Name = "mysql"
+	if err := HelmPull(context.Background(), chart, "1.0.0", dest); err == nil || !strings.Contains(err.Error(), "unknown chart mysql") {
+		t.Errorf("HelmPull() failure error = %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Package bundle extracts the scripts embedded in the binary to a cache
+// directory, so that a standalone netcup-kube runs without a checkout.
+package bundle
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
+	"github.com/mfittko/netcup-kube/scripts"
+)
+
+// CacheDirEnv overrides the directory the embedded scripts are extracted to
+const CacheDirEnv = "NETCUP_KUBE_CACHE_DIR"
+
+// CacheDir returns $NETCUP_KUBE_CACHE_DIR, or scripts in the cache directory
+// of netcup-kube ($XDG_CACHE_HOME/netcup-kube, ~/.cache/netcup-kube)
+func CacheDir() string {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(paths.CacheDir("netcup-kube"), "scripts")
+}
+
+// Scripts extracts the scripts embedded in the binary to CacheDir and
+// returns the directory holding main.sh
+func Scripts() (string, error) {
+	return Extract(scripts.FS, CacheDir())
+}
+
+// Extract writes the files of fsys to dir/<digest>, where digest covers
+// their paths and contents, and returns that directory. A directory left by
+// an earlier run with the same content is reused; files are written to a
+// temporary directory that is renamed into place, so concurrent runs never
+// see a partial tree. Shell scripts are made executable.
+func Extract(fsys fs.FS, dir string) (string, error) {
+	digest, err := Digest(fsys)
+	if err != nil {
+		return "", err
+	}
+	target := filepath.Join(dir, digest[:16])
+	if _, err := os.Stat(target); err == nil {
+		return target, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	tmp, err := os.MkdirTemp(dir, ".extract-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory in %s: %w", dir, err)
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(tmp, filepath.FromSlash(name))
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0o755)
+		}
+		return extractFile(fsys, name, dest)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to extract scripts to %s: %w", tmp, err)
+	}
+	// The mode of the temporary directory is 0700
+	if err := os.Chmod(tmp, 0o755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		// Another process extracted the same content first
+		if _, statErr := os.Stat(target); statErr == nil {
+			return target, nil
+		}
+		return "", fmt.Errorf("failed to move scripts to %s: %w", target, err)
+	}
+	return target, nil
+}
+
+func extractFile(fsys fs.FS, name, dest string) error {
+	mode := os.FileMode(0o644)
+	if strings.HasSuffix(name, ".sh") {
+		mode = 0o755
+	}
+	src, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// Digest returns the hex SHA-256 over the paths and contents of the files in
+// fsys, in walk order
+func Digest(fsys fs.FS) (string, error) {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(h, "%s\x00%d\x00", path.Clean(name), len(data))
+		_, _ = h.Write(data)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read embedded scripts: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,130 @@
+package bundle
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/mfittko/netcup-kube/scripts"
+)
+
+func TestExtract(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.sh":                      {Data: []byte("#!/bin/bash\n")},
+		"recipes/redis/install.sh":     {Data: []byte("#!/bin/bash\n")},
+		"recipes/redis/values.yaml":    {Data: []byte("replicas: 1\n")},
+		"recipes/zeroclaw/chart/a.yml": {Data: []byte("a: b\n")},
+	}
+	cache := t.TempDir()
+
+	dir, err := Extract(fsys, cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "recipes", "redis", "install.sh"))
+	if err != nil || info.Mode().Perm() != 0o755 {
+		t.Fatalf("install.sh = %v, %v", info, err)
+	}
+	info, err = os.Stat(filepath.Join(dir, "recipes", "redis", "values.yaml"))
+	if err != nil || info.Mode().Perm() != 0o644 {
+		t.Fatalf("values.yaml = %v, %v", info, err)
+	}
+
+	// Unchanged content reuses the directory; changed content gets a new one
+	again, err := Extract(fsys, cache)
+	if err != nil || again != dir {
+		t.Errorf("Extract() again = %q, %v; want %q", again, err, dir)
+	}
+	fsys["main.sh"] = &fstest.MapFile{Data: []byte("#!/bin/bash\necho v2\n")}
+	changed, err := Extract(fsys, cache)
+	if err != nil || changed == dir {
+		t.Errorf("Extract() after change = %q, %v", changed, err)
+	}
+	entries, _ := os.ReadDir(cache)
+	if len(entries) != 2 {
+		t.Errorf("cache holds %d entries, want 2 (no temporary leftovers)", len(entries))
+	}
+}
+
+// TestEmbeddedRecipes checks that every recipe of the checkout has its
+// install script embedded
+func TestEmbeddedRecipes(t *testing.T) {
+	entries, err := os.ReadDir(filepath.Join("..", "..", "scripts", "recipes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"main.sh", "lib/common.sh", "recipes/lib.sh", "recipes/recipes.conf"} {
+		if _, err := fs.Stat(scripts.FS, name); err != nil {
+			t.Error(err)
+		}
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if _, err := fs.Stat(scripts.FS, "recipes/"+e.Name()+"/install.sh"); err != nil {
+			t.Errorf("recipe %s: %v", e.Name(), err)
+		}
+	}
+	if _, err := fs.Stat(scripts.FS, "recipes/openclaw/snapshots"); err == nil {
+		t.Error("openclaw snapshots must not be embedded")
+	}
+}
+
+func TestCacheDir(t *testing.T) {
+	t.Setenv(CacheDirEnv, "/opt/netcup-kube")
+	if dir := CacheDir(); dir != "/opt/netcup-kube" {
+		t.Errorf("CacheDir() = %q", dir)
+	}
+
+	t.Setenv(CacheDirEnv, "")
+	t.Setenv("NETCUP_KUBE_CACHE_HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "/var/cache/user")
+	if dir := CacheDir(); dir != filepath.Join("/var/cache/user", "netcup-kube", "scripts") {
+		t.Errorf("CacheDir() = %q", dir)
+	}
+}
+
+func TestScripts(t *testing.T) {
+	t.Setenv(CacheDirEnv, t.TempDir())
+	dir, err := Scripts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "main.sh")); err != nil || info.Mode().Perm() != 0o755 {
+		t.Errorf("main.sh = %v, %v", info, err)
+	}
+}
+
+// brokenFS lists a file it cannot open
+type brokenFS struct{ fstest.MapFS }
+
+func (f brokenFS) Open(name string) (fs.File, error) {
+	if name == "main.sh" {
+		return nil, fs.ErrPermission
+	}
+	return f.MapFS.Open(name)
+}
+
+func TestExtractErrors(t *testing.T) {
+	fsys := fstest.MapFS{"main.sh": {Data: []byte("#!/bin/bash\n")}}
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Extract(fsys, filepath.Join(file, "cache")); err == nil {
+		t.Error("Extract() below a file expected error")
+	}
+
+	if _, err := Extract(brokenFS{fsys}, t.TempDir()); err == nil {
+		t.Error("Extract() of an unreadable file expected error")
+	}
+	if err := extractFile(brokenFS{fsys}, "main.sh", filepath.Join(t.TempDir(), "main.sh")); err == nil {
+		t.Error("extractFile() of an unreadable file expected error")
+	}
+	if err := extractFile(fsys, "main.sh", filepath.Join(file, "main.sh")); err == nil {
+		t.Error("extractFile() below a file expected error")
+	}
+}
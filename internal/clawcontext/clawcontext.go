@@ -0,0 +1,164 @@
+// Package clawcontext stores named netcup-claw contexts: the tunnel host,
+// namespace, ports and kubeconfig of one OpenClaw install, so operators can
+// switch between installs the way kubectl switches contexts.
+package clawcontext
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
+)
+
+// Context holds the settings of one OpenClaw install. Empty fields leave the
+// matching environment variable untouched.
+type Context struct {
+	TunnelHost        string `json:"tunnel_host,omitempty"`
+	TunnelUser        string `json:"tunnel_user,omitempty"`
+	TunnelLocalPort   string `json:"tunnel_local_port,omitempty"`
+	TunnelRemoteHost  string `json:"tunnel_remote_host,omitempty"`
+	TunnelRemotePort  string `json:"tunnel_remote_port,omitempty"`
+	TunnelBindAddress string `json:"tunnel_bind_address,omitempty"`
+	Namespace         string `json:"namespace,omitempty"`
+	LocalPort         string `json:"local_port,omitempty"`
+	RemotePort        string `json:"remote_port,omitempty"`
+	Kubeconfig        string `json:"kubeconfig,omitempty"`
+}
+
+// Env returns the environment variables a context sets, keyed by name
+func (c Context) Env() map[string]string {
+	env := map[string]string{}
+	for key, value := range map[string]string{
+		"TUNNEL_HOST":          c.TunnelHost,
+		"TUNNEL_USER":          c.TunnelUser,
+		"TUNNEL_LOCAL_PORT":    c.TunnelLocalPort,
+		"TUNNEL_REMOTE_HOST":   c.TunnelRemoteHost,
+		"TUNNEL_REMOTE_PORT":   c.TunnelRemotePort,
+		"TUNNEL_BIND_ADDRESS":  c.TunnelBindAddress,
+		"OPENCLAW_NAMESPACE":   c.Namespace,
+		"OPENCLAW_LOCAL_PORT":  c.LocalPort,
+		"OPENCLAW_REMOTE_PORT": c.RemotePort,
+		"KUBECONFIG":           c.Kubeconfig,
+	} {
+		if value != "" {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// Merge returns c with every non-empty field of update applied
+func (c Context) Merge(update Context) Context {
+	for _, f := range []struct{ dst, src *string }{
+		{&c.TunnelHost, &update.TunnelHost},
+		{&c.TunnelUser, &update.TunnelUser},
+		{&c.TunnelLocalPort, &update.TunnelLocalPort},
+		{&c.TunnelRemoteHost, &update.TunnelRemoteHost},
+		{&c.TunnelRemotePort, &update.TunnelRemotePort},
+		{&c.TunnelBindAddress, &update.TunnelBindAddress},
+		{&c.Namespace, &update.Namespace},
+		{&c.LocalPort, &update.LocalPort},
+		{&c.RemotePort, &update.RemotePort},
+		{&c.Kubeconfig, &update.Kubeconfig},
+	} {
+		if *f.src != "" {
+			*f.dst = *f.src
+		}
+	}
+	return c
+}
+
+// File is the on-disk set of contexts and the one in use
+type File struct {
+	Current  string             `json:"current,omitempty"`
+	Contexts map[string]Context `json:"contexts"`
+}
+
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateName rejects names that would be awkward in a shell or file name
+func ValidateName(name string) error {
+	if !nameRe.MatchString(name) {
+		return fmt.Errorf("invalid context name %q (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// DefaultPath is $NETCUP_CLAW_CONTEXTS, else contexts.json in the config
+// directory of netcup-claw (~/.config/netcup-claw)
+func DefaultPath() string {
+	if path := os.Getenv("NETCUP_CLAW_CONTEXTS"); path != "" {
+		return path
+	}
+	path := filepath.Join(paths.ConfigDir("netcup-claw"), "contexts.json")
+	if legacy, err := os.UserConfigDir(); err == nil {
+		return paths.Migrate(filepath.Join(legacy, "netcup-claw", "contexts.json"), path)
+	}
+	return path
+}
+
+// Load reads the contexts at path; a missing file is an empty set
+func Load(path string) (*File, error) {
+	f := &File{Contexts: map[string]Context{}}
+	payload, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contexts %s: %w", path, err)
+	}
+	if err := json.Unmarshal(payload, f); err != nil {
+		return nil, fmt.Errorf("invalid contexts file %s: %w", path, err)
+	}
+	if f.Contexts == nil {
+		f.Contexts = map[string]Context{}
+	}
+	return f, nil
+}
+
+// Save writes the contexts to path atomically, readable only by the owner
+func (f *File) Save(path string) error {
+	payload, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(payload, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write contexts %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write contexts %s: %w", path, err)
+	}
+	return nil
+}
+
+// Names returns the context names in sorted order
+func (f *File) Names() []string {
+	names := make([]string, 0, len(f.Contexts))
+	for name := range f.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the named context or an error listing the known ones
+func (f *File) Lookup(name string) (Context, error) {
+	c, ok := f.Contexts[name]
+	if !ok {
+		if len(f.Contexts) == 0 {
+			return Context{}, fmt.Errorf("context %q not found (no contexts defined)", name)
+		}
+		return Context{}, fmt.Errorf("context %q not found (known: %v)", name, f.Names())
+	}
+	return c, nil
+}
@@ -0,0 +1,139 @@
+package clawcontext
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "netcup-claw", "contexts.json")
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load(missing) error = %v", err)
+	}
+	if len(f.Contexts) != 0 || f.Current != "" {
+		t.Fatalf("Load(missing) = %+v, want empty", f)
+	}
+
+	f.Contexts["prod"] = Context{TunnelHost: "ops.example.com", Namespace: "openclaw"}
+	f.Current = "prod"
+	if err := f.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("mode = %o, want 600", perm)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, f) {
+		t.Errorf("Load() = %+v, want %+v", got, f)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid contexts file") {
+		t.Errorf("Load(corrupt) error = %v", err)
+	}
+}
+
+func TestMergeAndEnv(t *testing.T) {
+	c := Context{TunnelHost: "old.example.com", Namespace: "openclaw", LocalPort: "18789"}
+	c = c.Merge(Context{TunnelHost: "ops.example.com", Kubeconfig: "/home/ops/.kube/prod.yaml"})
+	want := map[string]string{
+		"TUNNEL_HOST":         "ops.example.com",
+		"OPENCLAW_NAMESPACE":  "openclaw",
+		"OPENCLAW_LOCAL_PORT": "18789",
+		"KUBECONFIG":          "/home/ops/.kube/prod.yaml",
+	}
+	if got := c.Env(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Env() = %v, want %v", got, want)
+	}
+}
+
+func TestLookupAndValidateName(t *testing.T) {
+	f := &File{Contexts: map[string]Context{"staging": {}, "prod": {}}}
+	if _, err := f.Lookup("prod"); err != nil {
+		t.Errorf("Lookup(prod) error = %v", err)
+	}
+	if _, err := f.Lookup("dev"); err == nil || !strings.Contains(err.Error(), "[prod staging]") {
+		t.Errorf("Lookup(dev) error = %v", err)
+	}
+
+	for name, valid := range map[string]bool{"prod": true, "eu-1.staging": true, "": false, "-x": false, "a b": false, "../x": false} {
+		if err := ValidateName(name); (err == nil) != valid {
+			t.Errorf("ValidateName(%q) error = %v, want valid=%v", name, err, valid)
+		}
+	}
+}
+
+func TestDefaultPath(t *testing.T) {
+	t.Setenv("NETCUP_CLAW_CONTEXTS", "/etc/netcup-claw/contexts.json")
+	if got := DefaultPath(); got != "/etc/netcup-claw/contexts.json" {
+		t.Errorf("DefaultPath() = %q", got)
+	}
+	t.Setenv("NETCUP_CLAW_CONTEXTS", "")
+	t.Setenv("XDG_CONFIG_HOME", "/home/ops/.config")
+	if got := DefaultPath(); got != filepath.Join("/home/ops/.config", "netcup-claw", "contexts.json") {
+		t.Errorf("DefaultPath() = %q", got)
+	}
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "")
+	want := filepath.Join(os.TempDir(), fmt.Sprintf("netcup-claw-%d", os.Getuid()), "config", "contexts.json")
+	if got := DefaultPath(); got != want {
+		t.Errorf("DefaultPath() without a home directory = %q", got)
+	}
+}
+
+func TestLoadSaveErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(dir); err == nil || !strings.Contains(err.Error(), "failed to read contexts") {
+		t.Errorf("Load(directory) error = %v", err)
+	}
+	path := filepath.Join(dir, "contexts.json")
+	if err := os.WriteFile(path, []byte(`{"current":"prod"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path)
+	if err != nil || f.Contexts == nil {
+		t.Errorf("Load() without contexts = %+v, %v", f, err)
+	}
+
+	if err := f.Save(filepath.Join(path, "contexts.json")); err == nil {
+		t.Error("Save() below a file expected error")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "taken.json.tmp"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(filepath.Join(dir, "taken.json")); err == nil {
+		t.Error("Save() with an unwritable temporary file expected error")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "dir.json"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dir.json", "keep"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(filepath.Join(dir, "dir.json")); err == nil {
+		t.Error("Save() over a directory expected error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dir.json.tmp")); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	empty := &File{}
+	if _, err := empty.Lookup("prod"); err == nil || !strings.Contains(err.Error(), "no contexts defined") {
+		t.Errorf("Lookup() without contexts error = %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+// Package clusters loads the cluster registry that lets one netcup-kube
+// checkout manage several Netcup servers. Each entry names a cluster and the
+// settings that differ between them; `netcup-kube --cluster <name>` layers
+// those settings over the env file.
+//
+// Example:
+//
+//	clusters:
+//	  - name: prod
+//	    host: 203.0.113.10
+//	    user: ops
+//	    vars:
+//	      BASE_DOMAIN: example.com
+//	  - name: staging
+//	    host: 203.0.113.20
+//	    env_file: config/netcup-kube.staging.env
+//	    kubeconfig: ~/.kube/staging.yaml
+package clusters
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
+	"github.com/mfittko/netcup-kube/internal/yamlsubset"
+)
+
+// DefaultPath is the registry of a checkout; outside one, clusters.yaml in
+// the config directory of netcup-kube (~/.config/netcup-kube) is used
+const DefaultPath = "config/clusters.yaml"
+
+// Cluster describes one server and the settings to use for it
+type Cluster struct {
+	Name string
+	// Host and User become MGMT_HOST and MGMT_USER
+	Host string
+	User string
+	// Kubeconfig becomes KUBECONFIG; it defaults to config/k3s.<name>.yaml so
+	// clusters never share the fetched kubeconfig cache
+	Kubeconfig string
+	// EnvFile replaces config/netcup-kube.env as the base env file
+	EnvFile string
+	Vars    map[string]string
+}
+
+// Registry is the list of known clusters in file order
+type Registry struct {
+	Clusters []Cluster
+}
+
+// Path returns $NETCUP_KUBE_CLUSTERS, else DefaultPath when it exists, else
+// clusters.yaml in the config directory of netcup-kube
+func Path() string {
+	if path := strings.TrimSpace(os.Getenv("NETCUP_KUBE_CLUSTERS")); path != "" {
+		return path
+	}
+	userPath := filepath.Join(paths.ConfigDir("netcup-kube"), "clusters.yaml")
+	if path := paths.Find(DefaultPath, userPath); path != "" {
+		return path
+	}
+	return DefaultPath
+}
+
+// Load reads and parses a registry file
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cluster registry: %w", err)
+	}
+	reg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster registry %s: %w", path, err)
+	}
+	return reg, nil
+}
+
+// Parse parses and validates registry YAML
+func Parse(data []byte) (*Registry, error) {
+	raw, err := yamlsubset.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("top level must be a mapping")
+	}
+
+	reg := &Registry{}
+	for key, value := range root {
+		if key != "clusters" {
+			return nil, fmt.Errorf("unknown top-level key %q (expected clusters)", key)
+		}
+		clusters, err := decodeClusters(value)
+		if err != nil {
+			return nil, err
+		}
+		reg.Clusters = clusters
+	}
+
+	seen := map[string]bool{}
+	for _, c := range reg.Clusters {
+		if seen[c.Name] {
+			return nil, fmt.Errorf("duplicate cluster name %q", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return reg, nil
+}
+
+// Cluster returns the cluster with the given name
+func (r *Registry) Cluster(name string) (Cluster, error) {
+	for _, c := range r.Clusters {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	if len(r.Clusters) == 0 {
+		return Cluster{}, fmt.Errorf("cluster %q not found (the registry is empty)", name)
+	}
+	return Cluster{}, fmt.Errorf("cluster %q not found (available: %s)", name, strings.Join(r.Names(), ", "))
+}
+
+// Names returns the cluster names in file order
+func (r *Registry) Names() []string {
+	names := make([]string, len(r.Clusters))
+	for i, c := range r.Clusters {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Env returns the variables the cluster sets: its vars, then MGMT_HOST,
+// MGMT_USER and KUBECONFIG from the dedicated keys
+func (c Cluster) Env() map[string]string {
+	env := make(map[string]string, len(c.Vars)+3)
+	for k, v := range c.Vars {
+		env[k] = v
+	}
+	if c.Host != "" {
+		env["MGMT_HOST"] = c.Host
+	}
+	if c.User != "" {
+		env["MGMT_USER"] = c.User
+	}
+	env["KUBECONFIG"] = c.KubeconfigPath()
+	return env
+}
+
+// KubeconfigPath returns the kubeconfig of the cluster with ~ expanded
+func (c Cluster) KubeconfigPath() string {
+	if c.Kubeconfig == "" {
+		return filepath.Join("config", "k3s."+c.Name+".yaml")
+	}
+	return expandHome(c.Kubeconfig)
+}
+
+// EnvFilePath returns the base env file of the cluster with ~ expanded, or ""
+func (c Cluster) EnvFilePath() string {
+	return expandHome(c.EnvFile)
+}
+
+// Set replaces the cluster with the same name, or appends c. It reports
+// whether c was added.
+func (r *Registry) Set(c Cluster) bool {
+	for i := range r.Clusters {
+		if r.Clusters[i].Name == c.Name {
+			r.Clusters[i] = c
+			return false
+		}
+	}
+	r.Clusters = append(r.Clusters, c)
+	return true
+}
+
+// Marshal renders the registry as YAML. Comments of a loaded file are not
+// preserved.
+func (r *Registry) Marshal() []byte {
+	var b strings.Builder
+	b.WriteString("clusters:\n")
+	for i, c := range r.Clusters {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(c.Render())
+	}
+	return []byte(b.String())
+}
+
+// Render returns the cluster as an entry of the clusters list
+func (c Cluster) Render() string {
+	var b strings.Builder
+	field := func(key, value string) {
+		if value == "" {
+			return
+		}
+		prefix := "    "
+		if b.Len() == 0 {
+			prefix = "  - "
+		}
+		b.WriteString(prefix + key + ": " + scalar(value) + "\n")
+	}
+	field("name", c.Name)
+	field("host", c.Host)
+	field("user", c.User)
+	field("env_file", c.EnvFile)
+	field("kubeconfig", c.Kubeconfig)
+	if len(c.Vars) > 0 {
+		b.WriteString("    vars:\n")
+		keys := make([]string, 0, len(c.Vars))
+		for key := range c.Vars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			b.WriteString("      " + key + ": " + scalar(c.Vars[key]) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// scalar quotes s when the plain form would not read back as the same string
+func scalar(s string) string {
+	out, err := yamlsubset.Marshal(s)
+	if err != nil {
+		return s
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+func decodeClusters(value any) ([]Cluster, error) {
+	if s, ok := value.(string); ok && s == "" {
+		return nil, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("clusters must be a list")
+	}
+
+	clusters := make([]Cluster, 0, len(items))
+	for i, item := range items {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("clusters[%d] must be a mapping", i)
+		}
+		c := Cluster{Vars: map[string]string{}}
+		// Iterate in sorted order so error messages are deterministic
+		keys := make([]string, 0, len(entry))
+		for key := range entry {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := fmt.Sprintf("clusters[%d].%s", i, key)
+			if key == "vars" {
+				vars, err := decodeVars(field, entry[key])
+				if err != nil {
+					return nil, err
+				}
+				c.Vars = vars
+				continue
+			}
+			s, ok := entry[key].(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a string", field)
+			}
+			s = strings.TrimSpace(s)
+			switch key {
+			case "name":
+				c.Name = s
+			case "host":
+				c.Host = s
+			case "user":
+				c.User = s
+			case "kubeconfig":
+				c.Kubeconfig = s
+			case "env_file":
+				c.EnvFile = s
+			default:
+				return nil, fmt.Errorf("unknown key %s (expected name, host, user, kubeconfig, env_file, vars)", field)
+			}
+		}
+		if c.Name == "" {
+			return nil, fmt.Errorf("clusters[%d] is missing a name", i)
+		}
+		if !validName(c.Name) {
+			return nil, fmt.Errorf("invalid cluster name %q (use letters, digits, '-' or '_')", c.Name)
+		}
+		clusters = append(clusters, c)
+	}
+	return clusters, nil
+}
+
+func decodeVars(field string, value any) (map[string]string, error) {
+	if s, ok := value.(string); ok && s == "" {
+		return map[string]string{}, nil
+	}
+	entries, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping of KEY: value", field)
+	}
+	vars := make(map[string]string, len(entries))
+	for key, raw := range entries {
+		if !validVarName(key) {
+			return nil, fmt.Errorf("%s: invalid variable name %q", field, key)
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a scalar", field, key)
+		}
+		vars[key] = s
+	}
+	return vars, nil
+}
+
+// validName keeps cluster names usable in file names (config/k3s.<name>.yaml)
+func validName(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// validVarName reports whether key is a valid shell environment variable name
+func validVarName(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package clusters
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleRegistry = `clusters:
+  - name: prod
+    host: 203.0.113.10
+    user: ops
+    vars:
+      BASE_DOMAIN: example.com
+  - name: staging
+    host: 203.0.113.20
+    env_file: config/netcup-kube.staging.env
+    kubeconfig: /home/ops/.kube/staging.yaml
+`
+
+func TestParse(t *testing.T) {
+	reg, err := Parse([]byte(sampleRegistry))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := reg.Names(); !reflect.DeepEqual(got, []string{"prod", "staging"}) {
+		t.Errorf("Names() = %v", got)
+	}
+
+	prod, err := reg.Cluster("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"BASE_DOMAIN": "example.com",
+		"MGMT_HOST":   "203.0.113.10",
+		"MGMT_USER":   "ops",
+		"KUBECONFIG":  "config/k3s.prod.yaml",
+	}
+	if got := prod.Env(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Env() = %v, want %v", got, want)
+	}
+
+	staging, _ := reg.Cluster("staging")
+	if staging.EnvFilePath() != "config/netcup-kube.staging.env" || staging.KubeconfigPath() != "/home/ops/.kube/staging.yaml" {
+		t.Errorf("staging = %+v", staging)
+	}
+	if _, err := reg.Cluster("dev"); err == nil || !strings.Contains(err.Error(), "available: prod, staging") {
+		t.Errorf("Cluster(dev) error = %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"unknown top-level", "servers: []\n", "unknown top-level key"},
+		{"missing name", "clusters:\n  - host: a\n", "missing a name"},
+		{"bad name", "clusters:\n  - name: ../prod\n", "invalid cluster name"},
+		{"duplicate", "clusters:\n  - name: a\n  - name: a\n", "duplicate cluster name"},
+		{"unknown key", "clusters:\n  - name: a\n    port: 22\n", "unknown key clusters[0].port"},
+		{"bad var", "clusters:\n  - name: a\n    vars:\n      1X: y\n", "invalid variable name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathAndLoad(t *testing.T) {
+	t.Setenv("NETCUP_KUBE_CLUSTERS", "")
+	if got := Path(); got != DefaultPath {
+		t.Errorf("Path() = %q", got)
+	}
+	path := filepath.Join(t.TempDir(), "clusters.yaml")
+	t.Setenv("NETCUP_KUBE_CLUSTERS", " "+path+" ")
+	if got := Path(); got != path {
+		t.Errorf("Path() = %q", got)
+	}
+
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "failed to read cluster registry") {
+		t.Errorf("Load(missing) error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte("servers: []\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid cluster registry "+path) {
+		t.Errorf("Load(invalid) error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte("clusters:\n  - name: prod\n    kubeconfig: ~/.kube/prod.yaml\n    vars:\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	reg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	home, _ := os.UserHomeDir()
+	if got := reg.Clusters[0].KubeconfigPath(); got != filepath.Join(home, ".kube/prod.yaml") {
+		t.Errorf("KubeconfigPath() = %q", got)
+	}
+	if len(reg.Clusters[0].Vars) != 0 {
+		t.Errorf("Vars = %v", reg.Clusters[0].Vars)
+	}
+}
+
+func TestParseShapeErrors(t *testing.T) {
+	tests := []struct {
+		yaml string
+		want string
+	}{
+		{"- a\n", "top level must be a mapping"},
+		{"clusters: prod\n", "clusters must be a list"},
+		{"clusters:\n  - prod\n", "clusters[0] must be a mapping"},
+		{"clusters:\n  - name:\n      - a\n", "clusters[0].name must be a string"},
+		{"clusters:\n  - name: a\n    vars: x\n", "must be a mapping of KEY: value"},
+		{"clusters:\n  - name: a\n    vars:\n      A:\n        - b\n", "clusters[0].vars.A must be a scalar"},
+		{"clusters:\n  - name: a\n    vars:\n      A-B: c\n", "invalid variable name"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.yaml, err, tt.want)
+		}
+	}
+
+	reg, err := Parse([]byte("clusters:\n"))
+	if err != nil || len(reg.Clusters) != 0 {
+		t.Fatalf("Parse(empty) = %+v, %v", reg, err)
+	}
+	if _, err := reg.Cluster("prod"); err == nil || !strings.Contains(err.Error(), "registry is empty") {
+		t.Errorf("Cluster() of an empty registry error = %v", err)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	reg := &Registry{}
+	if !reg.Set(Cluster{Name: "prod", Host: "203.0.113.10", User: "ops", Vars: map[string]string{"BASE_DOMAIN": "example.com", "SSH_IDENTITY_FILE": "~/.ssh/netcup"}}) {
+		t.Fatal("Set() did not add prod")
+	}
+	reg.Set(Cluster{Name: "staging", Host: "203.0.113.20", EnvFile: "config/netcup-kube.staging.env", Vars: map[string]string{"PORT": "0022"}})
+	if reg.Set(Cluster{Name: "prod", Host: "203.0.113.11"}) {
+		t.Error("Set() added a second prod")
+	}
+
+	data := reg.Marshal()
+	if !strings.Contains(string(data), "  - name: staging\n    host: 203.0.113.20\n    env_file: config/netcup-kube.staging.env\n    vars:\n      PORT: \"0022\"\n") {
+		t.Errorf("Marshal() =\n%s", data)
+	}
+	parsed, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Clusters) != 2 || parsed.Clusters[0].Host != "203.0.113.11" || parsed.Clusters[1].Vars["PORT"] != "0022" {
+		t.Errorf("Parse(Marshal()) = %+v", parsed.Clusters)
+	}
+}
@@ -3,7 +3,11 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/mfittko/netcup-kube/internal/validation"
@@ -51,6 +55,7 @@ func isValidEnvKey(key string) bool {
 //
 // Note: This function does NOT perform variable expansion like ${VAR}.
 // For variable expansion support, use Config.LoadEnvFile() instead.
+// Env file overrides (see SetEnvFileOverrides) are applied on top.
 func LoadEnvFileToMap(path string) (map[string]string, error) {
 	result := make(map[string]string)
 
@@ -93,37 +98,111 @@ func LoadEnvFileToMap(path string) (map[string]string, error) {
 
 		result[key] = value
 	}
+	for k, v := range envFileOverrides {
+		result[k] = v
+	}
 
 	return result, scanner.Err()
 }
 
+// Source is where the value of a configuration key came from
+type Source string
+
+const (
+	// SourceEnv is the environment of the process
+	SourceEnv Source = "env"
+	// SourceFile is the env file
+	SourceFile Source = "file"
+	// SourceCluster is the cluster selected with --cluster
+	SourceCluster Source = "cluster"
+	// SourceFlag is a command-line flag
+	SourceFlag Source = "flag"
+)
+
 // Config holds the configuration for netcup-kube commands
 type Config struct {
 	// Environment variables to pass to scripts
 	Env map[string]string
+
+	sources map[string]Source
 }
 
 // New creates a new Config instance
 func New() *Config {
 	return &Config{
-		Env: make(map[string]string),
+		Env:     make(map[string]string),
+		sources: make(map[string]Source),
+	}
+}
+
+// Source returns where the value of key came from, or "" if it is not set
+func (c *Config) Source(key string) Source {
+	if _, ok := c.Env[key]; !ok {
+		return ""
+	}
+	return c.sources[key]
+}
+
+func (c *Config) set(key, value string, source Source) {
+	c.Env[key] = value
+	if c.sources == nil {
+		c.sources = make(map[string]Source)
 	}
+	c.sources[key] = source
 }
 
 // LoadEnvFile loads environment variables from a file
 // Returns nil if the file doesn't exist (not an error)
 // NOTE: Values from env files are considered trusted. Ensure env files come from trusted sources only.
+//
+// Env file overrides (see SetEnvFileOverrides) are applied on top, also when
+// the file does not exist.
 func (c *Config) LoadEnvFile(path string) error {
 	file, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
+			c.ApplyEnvFileOverrides()
 			return nil // File doesn't exist, not an error
 		}
 		return fmt.Errorf("failed to open env file: %w", err)
 	}
 	defer func() { _ = file.Close() }()
 
-	scanner := bufio.NewScanner(file)
+	if err := c.loadEnv(file); err != nil {
+		return err
+	}
+	c.ApplyEnvFileOverrides()
+	return nil
+}
+
+// envFileOverrides are layered over every env file loaded by this process
+var envFileOverrides map[string]string
+
+// SetEnvFileOverrides makes every env file loaded afterwards report these
+// values, as if they were appended to it. netcup-kube uses it for the
+// settings of the cluster selected with --cluster, so code that reads the
+// env file directly (remote targets, the SSH tunnel) sees them too.
+func SetEnvFileOverrides(env map[string]string) {
+	envFileOverrides = make(map[string]string, len(env))
+	for k, v := range env {
+		envFileOverrides[k] = v
+	}
+}
+
+// ApplyEnvFileOverrides sets the env file overrides on c
+func (c *Config) ApplyEnvFileOverrides() {
+	for k, v := range envFileOverrides {
+		c.set(k, v, SourceCluster)
+	}
+}
+
+// LoadEnvString loads environment variables from env file content
+func (c *Config) LoadEnvString(content string) error {
+	return c.loadEnv(strings.NewReader(content))
+}
+
+func (c *Config) loadEnv(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
@@ -145,7 +224,7 @@ func (c *Config) LoadEnvFile(path string) error {
 		value = c.expandVars(value)
 
 		// Set value, overriding any existing values (env-file has higher priority than process env)
-		c.Env[key] = value
+		c.set(key, value, SourceFile)
 	}
 
 	return scanner.Err()
@@ -166,7 +245,7 @@ func (c *Config) LoadFromEnvironment() {
 
 		// Only set if not already set; allows later config sources to override
 		if _, exists := c.Env[key]; !exists {
-			c.Env[key] = value
+			c.set(key, value, SourceEnv)
 		}
 	}
 }
@@ -174,13 +253,39 @@ func (c *Config) LoadFromEnvironment() {
 // SetFromFlags sets configuration values from command-line flags
 func (c *Config) SetFromFlags(key, value string) {
 	if value != "" {
-		c.Env[key] = value
+		c.set(key, value, SourceFlag)
 	}
 }
 
 // SetFlag sets a configuration flag (overrides anything else)
 func (c *Config) SetFlag(key, value string) {
-	c.Env[key] = value
+	c.set(key, value, SourceFlag)
+}
+
+// ResolveSecrets replaces "keyring:<name>" values with the secrets returned
+// by resolve (see internal/keyring). A value that cannot be resolved is
+// removed, so scripts never receive the reference itself, and its error is
+// returned.
+func (c *Config) ResolveSecrets(isRef func(string) bool, resolve func(string) (string, error)) []error {
+	keys := make([]string, 0, len(c.Env))
+	for key, value := range c.Env {
+		if isRef(value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		secret, err := resolve(c.Env[key])
+		if err != nil {
+			delete(c.Env, key)
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		c.Env[key] = secret
+	}
+	return errs
 }
 
 // expandVars performs simple variable expansion for ${VAR} syntax.
@@ -273,7 +378,13 @@ func (c *Config) Validate() error {
 	if err := validation.CIDR("PRIVATE_CIDR", c.Env["PRIVATE_CIDR"]); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validation.CIDR("ADMIN_SRC_CIDR", c.Env["ADMIN_SRC_CIDR"]); err != nil {
+	if err := validation.CIDRList("ADMIN_SRC_CIDR", c.Env["ADMIN_SRC_CIDR"]); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validation.IPv6CIDR("SERVICE_CIDR_V6", c.Env["SERVICE_CIDR_V6"]); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validation.IPv6CIDR("CLUSTER_CIDR_V6", c.Env["CLUSTER_CIDR_V6"]); err != nil {
 		errs = append(errs, err)
 	}
 
@@ -292,6 +403,12 @@ func (c *Config) Validate() error {
 	if err := validation.IP("NODE_EXTERNAL_IP", c.Env["NODE_EXTERNAL_IP"]); err != nil {
 		errs = append(errs, err)
 	}
+	if err := validation.IPv6("NODE_IP_V6", c.Env["NODE_IP_V6"]); err != nil {
+		errs = append(errs, err)
+	}
+	if err := validation.IPv6("NODE_EXTERNAL_IP_V6", c.Env["NODE_EXTERNAL_IP_V6"]); err != nil {
+		errs = append(errs, err)
+	}
 
 	// Validate hostnames/domains
 	if err := validation.Hostname("BASE_DOMAIN", c.Env["BASE_DOMAIN"]); err != nil {
@@ -323,6 +440,19 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Traefik only gets an IPv6 NodePort on a dual-stack cluster, so an IPv6
+	// edge upstream (http://[::1]:30080) cannot work without it
+	if u, err := url.Parse(c.Env["EDGE_UPSTREAM"]); err == nil && c.Env["DUAL_STACK"] != "true" {
+		if ip := net.ParseIP(u.Hostname()); ip != nil && ip.To4() == nil {
+			errs = append(errs, &validation.Error{
+				Field:       "EDGE_UPSTREAM",
+				Value:       c.Env["EDGE_UPSTREAM"],
+				Message:     "IPv6 upstream requires a dual-stack cluster",
+				Remediation: "Set DUAL_STACK=true or use an IPv4 upstream (e.g., http://127.0.0.1:30080)",
+			})
+		}
+	}
+
 	// Mode-specific validation
 	if mode == "join" {
 		// Join mode requires SERVER_URL and either TOKEN or TOKEN_FILE
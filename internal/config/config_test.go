@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -153,6 +154,39 @@ func TestLoadEnvFile_Precedence(t *testing.T) {
 	}
 }
 
+func TestEnvFileOverrides(t *testing.T) {
+	tmpFile := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(tmpFile, []byte("MGMT_HOST=from_file\nBASE_DOMAIN=example.com"), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	SetEnvFileOverrides(map[string]string{"MGMT_HOST": "203.0.113.20"})
+	defer SetEnvFileOverrides(nil)
+
+	cfg := New()
+	if err := cfg.LoadEnvFile(tmpFile); err != nil {
+		t.Fatalf("LoadEnvFile() error = %v", err)
+	}
+	if cfg.Env["MGMT_HOST"] != "203.0.113.20" || cfg.Env["BASE_DOMAIN"] != "example.com" {
+		t.Errorf("LoadEnvFile() with overrides = %v", cfg.Env)
+	}
+
+	missing := New()
+	if err := missing.LoadEnvFile(filepath.Join(t.TempDir(), "missing.env")); err != nil {
+		t.Fatalf("LoadEnvFile(missing) error = %v", err)
+	}
+	if missing.Env["MGMT_HOST"] != "203.0.113.20" {
+		t.Errorf("overrides not applied without env file: %v", missing.Env)
+	}
+
+	env, err := LoadEnvFileToMap(tmpFile)
+	if err != nil {
+		t.Fatalf("LoadEnvFileToMap() error = %v", err)
+	}
+	if env["MGMT_HOST"] != "203.0.113.20" {
+		t.Errorf("LoadEnvFileToMap() with overrides = %v", env)
+	}
+}
+
 func TestLoadFromEnvironment(t *testing.T) {
 	// Set test environment variables
 	if err := os.Setenv("TEST_VAR_1", "value1"); err != nil {
@@ -751,6 +785,39 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "valid dual-stack config",
+			env: map[string]string{
+				"DUAL_STACK":      "true",
+				"CLUSTER_CIDR_V6": "fd00:42::/56",
+				"SERVICE_CIDR_V6": "fd00:43::/112",
+				"NODE_IP_V6":      "2001:db8::10",
+				"ADMIN_SRC_CIDR":  "203.0.113.7/32,2001:db8:1::/64",
+				"EDGE_UPSTREAM":   "http://[::1]:30080",
+			},
+			wantErr: false,
+		},
+		{
+			name: "IPv4 CLUSTER_CIDR_V6",
+			env: map[string]string{
+				"CLUSTER_CIDR_V6": "10.42.0.0/16",
+			},
+			wantErr: true,
+		},
+		{
+			name: "IPv4 NODE_IP_V6",
+			env: map[string]string{
+				"NODE_IP_V6": "203.0.113.10",
+			},
+			wantErr: true,
+		},
+		{
+			name: "IPv6 EDGE_UPSTREAM without DUAL_STACK",
+			env: map[string]string{
+				"EDGE_UPSTREAM": "http://[::1]:30080",
+			},
+			wantErr: true,
+		},
 		{
 			name:    "empty config",
 			env:     map[string]string{},
@@ -769,3 +836,30 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveSecrets(t *testing.T) {
+	cfg := New()
+	cfg.Env = map[string]string{
+		"BASE_DOMAIN":             "example.com",
+		"ROOT_PASS":               "keyring:root-pass",
+		"NETCUP_SCP_ACCESS_TOKEN": "keyring:missing",
+	}
+	isRef := func(v string) bool { return strings.HasPrefix(v, "keyring:") }
+	resolve := func(v string) (string, error) {
+		if v == "keyring:root-pass" {
+			return "s3cret", nil
+		}
+		return "", fmt.Errorf("secret not found")
+	}
+
+	errs := cfg.ResolveSecrets(isRef, resolve)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "NETCUP_SCP_ACCESS_TOKEN") {
+		t.Fatalf("ResolveSecrets() errors = %v", errs)
+	}
+	if cfg.Env["ROOT_PASS"] != "s3cret" || cfg.Env["BASE_DOMAIN"] != "example.com" {
+		t.Errorf("unexpected env after resolving: %v", cfg.Env)
+	}
+	if _, ok := cfg.Env["NETCUP_SCP_ACCESS_TOKEN"]; ok {
+		t.Error("unresolved reference should be removed")
+	}
+}
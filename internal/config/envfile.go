@@ -0,0 +1,292 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/validation"
+)
+
+// sensitiveKeyMarkers identify keys whose values are masked in diffs
+var sensitiveKeyMarkers = []string{"TOKEN", "SECRET", "PASSWORD", "PASSWD", "API_KEY", "PRIVATE_KEY"}
+
+// CheckEnvSyntax reports lines that are neither comments, blank, nor valid
+// KEY=value assignments.
+func CheckEnvSyntax(content string) validation.Errors {
+	var errs validation.Errors
+	for i, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		switch {
+		case !ok:
+			errs = append(errs, &validation.Error{
+				Field:       fmt.Sprintf("line %d", i+1),
+				Value:       line,
+				Message:     fmt.Sprintf("expected KEY=value, got %q", line),
+				Remediation: "Use KEY=value assignments; start comments with #",
+			})
+		case !isValidEnvKey(key):
+			errs = append(errs, &validation.Error{
+				Field:       fmt.Sprintf("line %d", i+1),
+				Value:       key,
+				Message:     fmt.Sprintf("invalid variable name: %q", key),
+				Remediation: "Variable names must start with a letter or underscore and contain only letters, digits, or underscores",
+			})
+		}
+	}
+	return errs
+}
+
+// ValidateEnvContent checks env file content for syntax errors and runs the
+// same semantic validation as `netcup-kube validate` on its values. The
+// content is validated in isolation from the process environment.
+func ValidateEnvContent(content string) error {
+	errs := CheckEnvSyntax(content)
+
+	c := New()
+	if err := c.LoadEnvString(content); err != nil {
+		return err
+	}
+	if err := c.Validate(); err != nil {
+		if verrs, ok := err.(validation.Errors); ok {
+			errs = append(errs, verrs...)
+		} else {
+			errs = append(errs, err)
+		}
+	}
+
+	if errs.HasErrors() {
+		return errs
+	}
+	return nil
+}
+
+// DiffEnv returns a key-level diff between two env file contents:
+// "+ KEY=value" for added, "- KEY=value" for removed, and
+// "~ KEY: old -> new" for changed keys. Values of sensitive keys are masked.
+func DiffEnv(before, after string) []string {
+	old := envAssignments(before)
+	cur := envAssignments(after)
+
+	keys := make([]string, 0, len(old)+len(cur))
+	seen := make(map[string]bool)
+	for k := range old {
+		keys = append(keys, k)
+		seen[k] = true
+	}
+	for k := range cur {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var diff []string
+	for _, k := range keys {
+		oldVal, inOld := old[k]
+		newVal, inNew := cur[k]
+		switch {
+		case !inOld:
+			diff = append(diff, fmt.Sprintf("+ %s=%s", k, displayValue(k, newVal)))
+		case !inNew:
+			diff = append(diff, fmt.Sprintf("- %s=%s", k, displayValue(k, oldVal)))
+		case oldVal != newVal:
+			diff = append(diff, fmt.Sprintf("~ %s: %s -> %s", k, displayValue(k, oldVal), displayValue(k, newVal)))
+		}
+	}
+	return diff
+}
+
+// envAssignments returns the raw (unexpanded) assignments of env file content
+func envAssignments(content string) map[string]string {
+	values := make(map[string]string)
+	for _, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// IsSensitiveKey reports whether values of key should not be displayed
+func IsSensitiveKey(key string) bool {
+	upper := strings.ToUpper(key)
+	for _, marker := range sensitiveKeyMarkers {
+		if strings.Contains(upper, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactEnv replaces the values of sensitive keys in env file content with
+// placeholder, keeping comments, ordering and keyring: references
+func RedactEnv(content, placeholder string) string {
+	lines := strings.Split(content, "\n")
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.Trim(strings.TrimSpace(value), `"'`)
+		if !ok || value == "" || strings.HasPrefix(value, "keyring:") || !IsSensitiveKey(strings.TrimPrefix(key, "export ")) {
+			continue
+		}
+		lines[i] = key + "=" + placeholder
+	}
+	return strings.Join(lines, "\n")
+}
+
+func displayValue(key, value string) string {
+	if value == "" {
+		return `""`
+	}
+	if IsSensitiveKey(key) {
+		return "********"
+	}
+	return value
+}
+
+// valueKind describes the expected format of a known configuration key
+type valueKind int
+
+const (
+	kindString valueKind = iota
+	kindCIDR
+	kindCIDRList
+	kindIPv6CIDR
+	kindPort
+	kindIP
+	kindIPv6
+	kindHostname
+	kindURL
+	kindBool
+	kindEnum
+	kindNodeLabels
+	kindNodeTaints
+)
+
+type keySpec struct {
+	kind    valueKind
+	allowed []string
+}
+
+var boolValues = []string{"true", "false", "1", "0", "yes", "no", "y", "n", "on", "off"}
+
+// ValidateValue checks a single value against the expected format of a known
+// key. Values containing variable references (${VAR}) are not checked, since
+// they are only resolved when the file is loaded.
+func ValidateValue(key, value string) error {
+	if !isValidEnvKey(key) {
+		return &validation.Error{
+			Field:       key,
+			Value:       key,
+			Message:     fmt.Sprintf("invalid variable name: %q", key),
+			Remediation: "Variable names must start with a letter or underscore and contain only letters, digits, or underscores",
+		}
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return &validation.Error{Field: key, Value: value, Message: "value must not contain newlines"}
+	}
+	spec, ok := knownKeys[key]
+	if !ok || value == "" || strings.Contains(value, "${") {
+		return nil
+	}
+	switch spec.kind {
+	case kindCIDR:
+		return validation.CIDR(key, value)
+	case kindCIDRList:
+		return validation.CIDRList(key, value)
+	case kindIPv6CIDR:
+		return validation.IPv6CIDR(key, value)
+	case kindPort:
+		return validation.Port(key, value)
+	case kindIP:
+		return validation.IP(key, value)
+	case kindIPv6:
+		return validation.IPv6(key, value)
+	case kindHostname:
+		return validation.Hostname(key, value)
+	case kindURL:
+		return validation.URL(key, value)
+	case kindBool:
+		return validation.OneOf(key, strings.ToLower(value), boolValues)
+	case kindEnum:
+		return validation.OneOf(key, value, spec.allowed)
+	case kindNodeLabels:
+		return validation.NodeLabels(key, value)
+	case kindNodeTaints:
+		return validation.NodeTaints(key, value)
+	}
+	return nil
+}
+
+// GetEnvValue returns the raw (unexpanded) value of key in env file content.
+// When a key is assigned more than once, the last assignment wins, matching
+// how the file is loaded.
+func GetEnvValue(content, key string) (string, bool) {
+	value, ok := envAssignments(content)[key]
+	return value, ok
+}
+
+// SetEnvValue sets key to value in env file content, preserving comments and
+// ordering. The last active assignment of key is replaced in place; earlier
+// duplicates are left untouched. New keys are appended at the end.
+func SetEnvValue(content, key, value string) string {
+	lines := strings.Split(content, "\n")
+	last := -1
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if k, _, ok := strings.Cut(line, "="); ok && strings.TrimSpace(k) == key {
+			last = i
+		}
+	}
+
+	assignment := key + "=" + value
+	if last >= 0 {
+		raw := lines[last]
+		indent := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+		lines[last] = indent + assignment
+		return strings.Join(lines, "\n")
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + assignment + "\n"
+}
+
+// EnvKeys returns the keys assigned in env file content, in file order
+func EnvKeys(content string) []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
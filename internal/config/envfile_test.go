@@ -0,0 +1,155 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckEnvSyntax(t *testing.T) {
+	content := "# comment\n\nBASE_DOMAIN=example.com\nnot an assignment\n1BAD=x\n"
+	errs := CheckEnvSyntax(content)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(errs), errs)
+	}
+	if !strings.Contains(errs[0].Error(), "line 4") || !strings.Contains(errs[1].Error(), "line 5") {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestValidateEnvContent(t *testing.T) {
+	if err := ValidateEnvContent("BASE_DOMAIN=example.com\nNODE_IP=10.0.0.1\n"); err != nil {
+		t.Errorf("expected valid content, got %v", err)
+	}
+
+	err := ValidateEnvContent("NODE_IP=not-an-ip\nbroken line\n")
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"NODE_IP", "line 2"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
+
+func TestValidateEnvContentExample(t *testing.T) {
+	// Variable references resolve against earlier assignments in the content
+	content := "MGMT_HOST=mgmt.example.com\nTUNNEL_HOST=${MGMT_HOST}\nSERVER_URL=https://${MGMT_HOST}:6443\n"
+	if err := ValidateEnvContent(content); err != nil {
+		t.Errorf("expected valid content, got %v", err)
+	}
+}
+
+func TestDiffEnv(t *testing.T) {
+	before := "# header\nBASE_DOMAIN=example.com\nNODE_IP=10.0.0.1\nTOKEN=old\n"
+	after := "# header changed\nBASE_DOMAIN=example.org\nTOKEN=new\nEDGE_PROXY=caddy\n"
+	got := DiffEnv(before, after)
+	want := []string{
+		"~ BASE_DOMAIN: example.com -> example.org",
+		"+ EDGE_PROXY=caddy",
+		"- NODE_IP=10.0.0.1",
+		"~ TOKEN: ******** -> ********",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffEnv() = %v, want %v", got, want)
+	}
+
+	if diff := DiffEnv("A=1\n", "# only a comment\nA=1\n"); len(diff) != 0 {
+		t.Errorf("expected no key changes, got %v", diff)
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	content := "# token below\nBASE_DOMAIN=example.com\nTOKEN=K10abc\nHELM_MIRROR_PASSWORD=keyring:mirror\nGROQ_API_KEY=\"gsk\"\nCADDY_SECRET=\n"
+	want := "# token below\nBASE_DOMAIN=example.com\nTOKEN=<redacted>\nHELM_MIRROR_PASSWORD=keyring:mirror\nGROQ_API_KEY=<redacted>\nCADDY_SECRET=\n"
+	if got := RedactEnv(content, "<redacted>"); got != want {
+		t.Errorf("RedactEnv() = %q, want %q", got, want)
+	}
+}
+
+func TestSetEnvValue(t *testing.T) {
+	content := "# c\nA=1\n  B=2\nA=3\n# A=commented\n"
+	got := SetEnvValue(content, "A", "9")
+	if got != "# c\nA=1\n  B=2\nA=9\n# A=commented\n" {
+		t.Errorf("replace last assignment: %q", got)
+	}
+	if got := SetEnvValue(content, "B", "x"); got != "# c\nA=1\n  B=x\nA=3\n# A=commented\n" {
+		t.Errorf("preserve indent: %q", got)
+	}
+	if got := SetEnvValue("A=1", "C", "x"); got != "A=1\nC=x\n" {
+		t.Errorf("append: %q", got)
+	}
+	if v, ok := GetEnvValue(content, "A"); !ok || v != "3" {
+		t.Errorf("GetEnvValue = %q, %v", v, ok)
+	}
+	if keys := EnvKeys(content); !reflect.DeepEqual(keys, []string{"A", "B"}) {
+		t.Errorf("EnvKeys = %v", keys)
+	}
+}
+
+func TestValidateValue(t *testing.T) {
+	valid := map[string]string{
+		"PRIVATE_CIDR": "10.0.0.0/24",
+		"ENABLE_UFW":   "Yes",
+		"EDGE_PROXY":   "caddy",
+		"SERVER_URL":   "https://${MGMT_HOST}:6443",
+		"CUSTOM_KEY":   "anything goes",
+		"NODE_IP":      "",
+	}
+	for k, v := range valid {
+		if err := ValidateValue(k, v); err != nil {
+			t.Errorf("ValidateValue(%s, %q) = %v", k, v, err)
+		}
+	}
+	invalid := map[string]string{
+		"PRIVATE_CIDR": "10.0.0.0",
+		"ENABLE_UFW":   "maybe",
+		"WG_PORT":      "99999",
+		"CUSTOM":       "multi\nline",
+		"bad-key":      "x",
+	}
+	for k, v := range invalid {
+		if err := ValidateValue(k, v); err == nil {
+			t.Errorf("ValidateValue(%s, %q) expected error", k, v)
+		}
+	}
+}
+
+func TestVars(t *testing.T) {
+	seen := map[string]bool{}
+	for _, v := range Vars() {
+		if seen[v.Name] {
+			t.Errorf("duplicate variable %s", v.Name)
+		}
+		seen[v.Name] = true
+		if v.Group == "" || v.Description == "" {
+			t.Errorf("%s is missing a group or description", v.Name)
+		}
+		if v.Default != "" {
+			if err := ValidateValue(v.Name, v.Default); err != nil {
+				t.Errorf("default of %s is invalid: %v", v.Name, err)
+			}
+		}
+	}
+	if !seen["SERVICE_CIDR"] || knownKeys["SERVICE_CIDR"].kind != kindCIDR {
+		t.Errorf("SERVICE_CIDR is not typed from the schema")
+	}
+}
+
+func TestConfigSource(t *testing.T) {
+	t.Setenv("CHANNEL", "latest")
+	t.Setenv("MODE", "join")
+	c := New()
+	c.LoadFromEnvironment()
+	if err := c.LoadEnvString("MODE=bootstrap\nBASE_DOMAIN=example.com\n"); err != nil {
+		t.Fatal(err)
+	}
+	c.SetFlag("DRY_RUN", "true")
+
+	for key, want := range map[string]Source{"CHANNEL": SourceEnv, "MODE": SourceFile, "DRY_RUN": SourceFlag, "UNSET_KEY": ""} {
+		if got := c.Source(key); got != want {
+			t.Errorf("Source(%s) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package config
+
+// Var describes one environment variable recognized by netcup-kube and its
+// scripts
+type Var struct {
+	Name string
+	// Group is the topic of the variable, e.g. "k3s" or "edge"
+	Group string
+	// Default is the value used when the variable is unset; it is empty when
+	// there is none or it is computed (see Description)
+	Default     string
+	Description string
+	spec        keySpec
+}
+
+// Type returns the expected value format: string, cidr, cidr-list,
+// ipv6-cidr, port, ip, ipv6, hostname, url, bool, enum, node-labels or
+// node-taints
+func (v Var) Type() string {
+	return kindNames[v.spec.kind]
+}
+
+// Allowed returns the values of an enum variable
+func (v Var) Allowed() []string {
+	return append([]string(nil), v.spec.allowed...)
+}
+
+var kindNames = map[valueKind]string{
+	kindString:     "string",
+	kindCIDR:       "cidr",
+	kindCIDRList:   "cidr-list",
+	kindIPv6CIDR:   "ipv6-cidr",
+	kindPort:       "port",
+	kindIP:         "ip",
+	kindIPv6:       "ipv6",
+	kindHostname:   "hostname",
+	kindURL:        "url",
+	kindBool:       "bool",
+	kindEnum:       "enum",
+	kindNodeLabels: "node-labels",
+	kindNodeTaints: "node-taints",
+}
+
+// Vars returns the recognized variables grouped by topic. Names containing
+// <...> stand for a family of variables, e.g. HOOK_<STAGE>_<COMMAND>.
+func Vars() []Var {
+	return append([]Var(nil), schema...)
+}
+
+func enum(allowed ...string) keySpec {
+	return keySpec{kind: kindEnum, allowed: allowed}
+}
+
+// schema lists the variables documented in docs/cli-contract.md; it is also
+// the source of the type checks of ValidateValue
+var schema = []Var{
+	// Core
+	{Name: "MODE", Group: "core", Default: "bootstrap", Description: "Operation mode: bootstrap (server) or join (agent)", spec: enum("bootstrap", "join")},
+	{Name: "DRY_RUN", Group: "core", Default: "false", Description: "Log commands without executing them (--dry-run)", spec: keySpec{kind: kindBool}},
+	{Name: "DRY_RUN_WRITE_FILES", Group: "core", Default: "false", Description: "Write files in dry-run mode (--dry-run-write-files)"},
+	{Name: "RENDER_DIR", Group: "core", Description: "Write generated files below this directory instead of applying them (--render-dir)"},
+	{Name: "CONFIRM", Group: "core", Default: "false", Description: "Auto-confirm dangerous operations (required without a TTY)"},
+	{Name: "ALLOW_UNSUPPORTED_OS", Group: "core", Default: "false", Description: "Continue on untested releases of a known distribution family", spec: keySpec{kind: kindBool}},
+	{Name: "AIRGAP_BUNDLE", Group: "core", Description: "Offline bundle directory used instead of downloads (set by bundle use)"},
+	{Name: "HELM_MIRROR_URL", Group: "core", Description: "Chart mirror for all Helm repositories; {repo} is replaced by the repository name", spec: keySpec{kind: kindURL}},
+	{Name: "HELM_MIRROR_REPO_<NAME>", Group: "core", Description: "Mirror URL of a single repository; overrides HELM_MIRROR_URL"},
+	{Name: "HELM_MIRROR_USERNAME", Group: "core", Description: "Chart mirror user name"},
+	{Name: "HELM_MIRROR_PASSWORD", Group: "core", Description: "Chart mirror password (may be a keyring:<name> reference)"},
+	{Name: "NETCUP_KUBE_CACHE_DIR", Group: "core", Description: "Where a binary outside a checkout extracts its embedded scripts (default: ~/.cache/netcup-kube/scripts)"},
+
+	// Management host and operator access
+	{Name: "DEFAULT_USER", Group: "management", Description: "SSH user used when MGMT_USER or a worker user is empty"},
+	{Name: "MGMT_HOST", Group: "management", Description: "Host name of the management node"},
+	{Name: "MGMT_IP", Group: "management", Description: "IP address of the management node (used when MGMT_HOST is empty)", spec: keySpec{kind: kindIP}},
+	{Name: "MGMT_USER", Group: "management", Description: "SSH user on the management node (default: DEFAULT_USER)"},
+	{Name: "SSH_IDENTITY_FILE", Group: "management", Description: "Private SSH key for connections to the nodes, taken from the process environment (default: ~/.ssh/id_ed25519, then ~/.ssh/id_rsa)"},
+	{Name: "REMOTE_COMPRESS", Group: "management", Description: "Compression of remote file transfers, taken from the process environment: none, gzip or zstd (default: none)", spec: enum("none", "gzip", "zstd")},
+	{Name: "REMOTE_RATE_LIMIT", Group: "management", Description: "Bandwidth cap of remote file transfers in bytes per second (e.g. 500K, 2M), taken from the process environment (default: unlimited)"},
+	{Name: "TUNNEL_HOST", Group: "management", Description: "SSH tunnel host (default: MGMT_HOST)"},
+	{Name: "TUNNEL_USER", Group: "management", Default: "ops", Description: "SSH tunnel user (default: MGMT_USER)"},
+	{Name: "TUNNEL_LOCAL_PORT", Group: "management", Default: "6443", Description: "Local port of the SSH tunnel", spec: keySpec{kind: kindPort}},
+	{Name: "TUNNEL_REMOTE_HOST", Group: "management", Default: "127.0.0.1", Description: "Remote host the SSH tunnel forwards to"},
+	{Name: "TUNNEL_REMOTE_PORT", Group: "management", Default: "6443", Description: "Remote port the SSH tunnel forwards to", spec: keySpec{kind: kindPort}},
+	{Name: "TUNNEL_BIND_ADDRESS", Group: "management", Default: "127.0.0.1", Description: "Local address of the SSH tunnel; ::1 on IPv6-only hosts", spec: keySpec{kind: kindIP}},
+	{Name: "KUBE_PROBE", Group: "management", Default: "kubectl", Description: "How both CLIs check that the kube API answers, taken from the process environment: kubectl (get --raw=/livez), tcp, tls (handshake verified against the kubeconfig CA) or readyz (HTTP with the kubeconfig credentials)", spec: enum("kubectl", "tcp", "tls", "readyz")},
+	{Name: "KUBE_PROBE_TIMEOUT", Group: "management", Default: "3s", Description: "Timeout of a single kube API probe (Go duration), taken from the process environment"},
+	{Name: "KUBE_PROBE_TOKEN", Group: "management", Description: "Bearer token of the readyz probe instead of the kubeconfig credentials, taken from the process environment"},
+	{Name: "PORT_FORWARD_ADDRESS", Group: "management", Description: "Local address of netcup-claw and monitoring port-forwards (default: kubectl's)"},
+	{Name: "KUBECONFIG_PASSPHRASE_FILE", Group: "management", Description: "File holding the passphrase that encrypts the fetched kubeconfig cache"},
+
+	// k3s
+	{Name: "CHANNEL", Group: "k3s", Default: "stable", Description: "k3s release channel"},
+	{Name: "K3S_VERSION", Group: "k3s", Description: "Specific k3s version (overrides CHANNEL); set by k3s upgrade"},
+	{Name: "NODE_IP", Group: "k3s", Description: "Node IP to advertise (default: auto-detected)", spec: keySpec{kind: kindIP}},
+	{Name: "NODE_EXTERNAL_IP", Group: "k3s", Description: "External IP (default: NODE_IP without PRIVATE_IFACE)", spec: keySpec{kind: kindIP}},
+	{Name: "NODE_IP_V6", Group: "k3s", Description: "IPv6 node address (default: auto-detected with DUAL_STACK=true)", spec: keySpec{kind: kindIPv6}},
+	{Name: "NODE_EXTERNAL_IP_V6", Group: "k3s", Description: "External IPv6 address (default: NODE_IP_V6 without PRIVATE_IFACE)", spec: keySpec{kind: kindIPv6}},
+	{Name: "SERVER_URL", Group: "k3s", Description: "k3s server URL (required for MODE=join)", spec: keySpec{kind: kindURL}},
+	{Name: "TOKEN", Group: "k3s", Description: "Join token (required for MODE=join)"},
+	{Name: "TOKEN_FILE", Group: "k3s", Description: "File containing the join token"},
+	{Name: "FLANNEL_BACKEND", Group: "k3s", Default: "vxlan", Description: "Flannel backend type"},
+	{Name: "SERVICE_CIDR", Group: "k3s", Default: "10.43.0.0/16", Description: "Service CIDR", spec: keySpec{kind: kindCIDR}},
+	{Name: "CLUSTER_CIDR", Group: "k3s", Default: "10.42.0.0/16", Description: "Cluster (pod) CIDR", spec: keySpec{kind: kindCIDR}},
+	{Name: "DUAL_STACK", Group: "k3s", Default: "false", Description: "Bootstrap k3s dual-stack with IPv4 and IPv6 addresses", spec: keySpec{kind: kindBool}},
+	{Name: "SERVICE_CIDR_V6", Group: "k3s", Default: "fd00:43::/112", Description: "IPv6 service CIDR (with DUAL_STACK=true)", spec: keySpec{kind: kindIPv6CIDR}},
+	{Name: "CLUSTER_CIDR_V6", Group: "k3s", Default: "fd00:42::/56", Description: "IPv6 cluster (pod) CIDR (with DUAL_STACK=true)", spec: keySpec{kind: kindIPv6CIDR}},
+	{Name: "NODE_LABELS", Group: "k3s", Description: "Labels a joining node registers with, as key=value,key2=value2 (join --labels)", spec: keySpec{kind: kindNodeLabels}},
+	{Name: "NODE_TAINTS", Group: "k3s", Description: "Taints a joining node registers with, as key=value:Effect (join --taints)", spec: keySpec{kind: kindNodeTaints}},
+	{Name: "NODE_PROFILE", Group: "k3s", Description: "Node profile whose labels and taints a joining node gets (join --node-profile)"},
+	{Name: "NODE_PROFILE_<NAME>_LABELS", Group: "k3s", Description: "Labels of node profile <NAME>, e.g. NODE_PROFILE_BATCH_LABELS"},
+	{Name: "NODE_PROFILE_<NAME>_TAINTS", Group: "k3s", Description: "Taints of node profile <NAME>, e.g. NODE_PROFILE_BATCH_TAINTS"},
+	{Name: "TLS_SANS_EXTRA", Group: "k3s", Description: "Additional TLS SANs for the API server certificate"},
+	{Name: "KUBECONFIG_MODE", Group: "k3s", Description: "Kubeconfig file permissions (default: 0640 with sudo, 0600 as root)"},
+	{Name: "KUBECONFIG_GROUP", Group: "k3s", Description: "Kubeconfig file group (default: the sudo user's group)"},
+	{Name: "FORCE_REINSTALL", Group: "k3s", Default: "false", Description: "Reinstall k3s even if it is already installed"},
+	{Name: "INSTALLER_PATH", Group: "k3s", Default: "/tmp/install-k3s.sh", Description: "Download path of the k3s installer"},
+	{Name: "STORAGE_BACKUP_DIR", Group: "k3s", Default: "/var/backups/netcup-kube/storage", Description: "Node directory for storage snapshot archives"},
+
+	// Resource preflight
+	{Name: "PREFLIGHT_MODE", Group: "preflight", Default: "enforce", Description: "enforce refuses operations below thresholds, warn prints warnings, off skips the check"},
+	{Name: "PREFLIGHT_MIN_DISK_FREE", Group: "preflight", Default: "5Gi", Description: "Minimum free disk (Kubernetes quantity)"},
+	{Name: "PREFLIGHT_MIN_MEMORY", Group: "preflight", Default: "512Mi", Description: "Minimum available memory (Kubernetes quantity)"},
+	{Name: "PREFLIGHT_MIN_INODES_FREE_PCT", Group: "preflight", Default: "5", Description: "Minimum free inodes in percent"},
+
+	// Networking
+	{Name: "PRIVATE_IFACE", Group: "networking", Description: "Private interface, e.g. eth1 for the vLAN"},
+	{Name: "PRIVATE_CIDR", Group: "networking", Description: "Private vLAN CIDR for NAT (required with ENABLE_VLAN_NAT=true)", spec: keySpec{kind: kindCIDR}},
+	{Name: "ENABLE_VLAN_NAT", Group: "networking", Default: "false", Description: "Enable the NAT gateway for vLAN-only nodes", spec: keySpec{kind: kindBool}},
+	{Name: "PUBLIC_IFACE", Group: "networking", Description: "Public interface for NAT (default: auto-detected)"},
+	{Name: "VLAN_ADDRESS", Group: "networking", Description: "vLAN interface address (default: NODE_IP with the prefix of PRIVATE_CIDR)"},
+	{Name: "VLAN_MTU", Group: "networking", Description: "vLAN interface MTU (default: unmanaged)"},
+	{Name: "NETWORK_BACKEND", Group: "networking", Default: "auto", Description: "netplan, networkd, or auto (network command)", spec: enum("auto", "netplan", "networkd")},
+	{Name: "WG_IFACE", Group: "networking", Default: "wg-netcup", Description: "WireGuard interface"},
+	{Name: "WG_ADDRESS", Group: "networking", Default: "10.99.0.1/24", Description: "WireGuard address and subnet of the management node"},
+	{Name: "WG_PORT", Group: "networking", Default: "51820", Description: "WireGuard UDP listen port", spec: keySpec{kind: kindPort}},
+	{Name: "WG_ENDPOINT", Group: "networking", Description: "Endpoint written into peer configs (default: MGMT_HOST:WG_PORT)"},
+	{Name: "WG_SERVER_IP", Group: "networking", Description: "WireGuard IP of the management node; preferred over the SSH tunnel when reachable", spec: keySpec{kind: kindIP}},
+	{Name: "PERSIST_NAT_SERVICE", Group: "networking", Default: "true", Description: "Create a systemd unit for NAT persistence", spec: keySpec{kind: kindBool}},
+	{Name: "HTTP_PROXY", Group: "networking", Description: "HTTP proxy for k3s"},
+	{Name: "HTTPS_PROXY", Group: "networking", Description: "HTTPS proxy for k3s"},
+	{Name: "NO_PROXY_EXTRA", Group: "networking", Description: "Additional no-proxy entries"},
+
+	// UFW firewall
+	{Name: "ENABLE_UFW", Group: "firewall", Description: "Enable the UFW firewall (prompted on a TTY)", spec: keySpec{kind: kindBool}},
+	{Name: "ADMIN_SRC_CIDR", Group: "firewall", Description: "Comma-separated admin source CIDRs for the k3s API (default: the SSH client address)", spec: keySpec{kind: kindCIDRList}},
+
+	// Caddy edge proxy
+	{Name: "EDGE_PROXY", Group: "edge", Description: "Edge proxy type (default: caddy for bootstrap, none for join)", spec: enum("none", "caddy")},
+	{Name: "EDGE_UPSTREAM", Group: "edge", Default: "http://127.0.0.1:30080", Description: "Backend Caddy proxies to", spec: keySpec{kind: kindURL}},
+	{Name: "BASE_DOMAIN", Group: "edge", Description: "Base domain, e.g. example.com", spec: keySpec{kind: kindHostname}},
+	{Name: "ACME_EMAIL", Group: "edge", Description: "Email for ACME/Let's Encrypt"},
+	{Name: "CADDY_CERT_MODE", Group: "edge", Default: "dns01_wildcard", Description: "Caddy certificate mode", spec: enum("dns01_wildcard", "http01")},
+	{Name: "CADDY_HTTP01_HOSTS", Group: "edge", Description: "Space-separated host names for http01 mode"},
+	{Name: "NETCUP_CUSTOMER_NUMBER", Group: "edge", Description: "Netcup customer number (DNS API)"},
+	{Name: "NETCUP_DNS_API_KEY", Group: "edge", Description: "Netcup DNS API key"},
+	{Name: "NETCUP_DNS_API_PASSWORD", Group: "edge", Description: "Netcup DNS API password"},
+	{Name: "NETCUP_ENVFILE", Group: "edge", Default: "/etc/caddy/netcup.env", Description: "Path of the Netcup env file written for Caddy"},
+	{Name: "CADDY_HEALTH_TIMEOUT", Group: "edge", Default: "30", Description: "Seconds Caddy has to pass the health check after a config switch before it is reverted"},
+	{Name: "CADDY_HEALTH_URL", Group: "edge", Description: "URL probed after a config switch (default: the first site host on port 80 of the node)", spec: keySpec{kind: kindURL}},
+
+	// Kubernetes Dashboard
+	{Name: "DASH_ENABLE", Group: "dashboard", Description: "Install the Kubernetes Dashboard (prompted on a TTY)", spec: keySpec{kind: kindBool}},
+	{Name: "DASH_SUBDOMAIN", Group: "dashboard", Default: "kube", Description: "Dashboard subdomain"},
+	{Name: "DASH_HOST", Group: "dashboard", Description: "Dashboard host name (default: DASH_SUBDOMAIN.BASE_DOMAIN)", spec: keySpec{kind: kindHostname}},
+	{Name: "DASH_BASICAUTH", Group: "dashboard", Description: "Enable Caddy basic auth for the Dashboard (default: auto)"},
+	{Name: "DASH_AUTH_USER", Group: "dashboard", Default: "admin", Description: "Basic auth user name"},
+	{Name: "DASH_AUTH_PASS", Group: "dashboard", Description: "Basic auth password (prompted if needed)"},
+	{Name: "DASH_AUTH_HASH", Group: "dashboard", Description: "Pre-hashed basic auth password"},
+	{Name: "DASH_AUTH_FILE", Group: "dashboard", Default: "/etc/caddy/dashboard.basicauth", Description: "Basic auth file path"},
+	{Name: "DASH_AUTH_REGEN", Group: "dashboard", Default: "false", Description: "Regenerate the basic auth hash"},
+
+	// Traefik
+	{Name: "TRAEFIK_NODEPORT_HTTP", Group: "traefik", Default: "30080", Description: "Traefik HTTP NodePort", spec: keySpec{kind: kindPort}},
+	{Name: "TRAEFIK_NODEPORT_HTTPS", Group: "traefik", Default: "30443", Description: "Traefik HTTPS NodePort", spec: keySpec{kind: kindPort}},
+
+	// Tracing
+	{Name: "OTEL_EXPORTER_OTLP_ENDPOINT", Group: "tracing", Description: "OTLP/HTTP base URL, e.g. http://localhost:4318"},
+	{Name: "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", Group: "tracing", Description: "Full traces URL; overrides the base endpoint"},
+	{Name: "OTEL_EXPORTER_OTLP_HEADERS", Group: "tracing", Description: "Extra request headers as key=value,key2=value2"},
+	{Name: "OTEL_SDK_DISABLED", Group: "tracing", Description: "Disable tracing even if an endpoint is set"},
+
+	// Command hooks
+	{Name: "HOOK_<STAGE>_<COMMAND>", Group: "hooks", Description: "Shell command run around a command, e.g. HOOK_PRE_BOOTSTRAP"},
+	{Name: "HOOK_<STAGE>_<COMMAND>_ON_FAILURE", Group: "hooks", Description: "Failure policy of that command's hooks"},
+	{Name: "HOOKS_ON_FAILURE", Group: "hooks", Description: "abort, warn or ignore (default: abort for pre, warn for post hooks)"},
+	{Name: "HOOKS_TIMEOUT", Group: "hooks", Default: "5m", Description: "Maximum run time of a single hook"},
+	{Name: "HOOKS_DIR", Group: "hooks", Description: "Root of the per-CLI hook directories (default: config/hooks.d)"},
+
+	// Node watchdog
+	{Name: "WATCHDOG_SERVICES", Group: "watchdog", Description: "systemd services to watch (default: the installed ones of k3s, k3s-agent, caddy)"},
+	{Name: "WATCHDOG_INTERVAL", Group: "watchdog", Default: "2m", Description: "Time between two health checks (at least 30s)"},
+	{Name: "WATCHDOG_MAX_FAILURES", Group: "watchdog", Default: "3", Description: "Failed checks in a row before an alert is sent"},
+	{Name: "WATCHDOG_WEBHOOK", Group: "watchdog", Description: "URL receiving JSON alerts of failing and recovered services", spec: keySpec{kind: kindURL}},
+}
+
+// knownKeys maps configuration keys to their expected value format. Keys not
+// listed are accepted as free-form strings.
+var knownKeys = func() map[string]keySpec {
+	keys := make(map[string]keySpec, len(schema))
+	for _, v := range schema {
+		if v.spec.kind != kindString {
+			keys[v.Name] = v.spec
+		}
+	}
+	return keys
+}()
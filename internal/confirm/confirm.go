@@ -0,0 +1,88 @@
+// Package confirm asks before destructive operations, following the CONFIRM
+// convention of the shell scripts: interactive runs type "yes" at a prompt,
+// non-interactive runs must opt in with CONFIRM=true (or --yes) and otherwise
+// fail with instructions.
+package confirm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// EnvVar is the environment variable that pre-confirms non-interactive runs
+const EnvVar = "CONFIRM"
+
+// ErrAborted is returned when the prompt is answered with anything but "yes"
+var ErrAborted = errors.New("aborted")
+
+// NotConfirmedError is returned for non-interactive runs without CONFIRM=true
+type NotConfirmedError struct {
+	Action string
+}
+
+func (e *NotConfirmedError) Error() string {
+	return fmt.Sprintf("refusing to %s without confirmation in a non-interactive run; re-run with --yes or set %s=true", e.Action, EnvVar)
+}
+
+// Prompter asks for confirmation
+type Prompter struct {
+	In          io.Reader
+	Out         io.Writer
+	Interactive bool
+	// AssumeYes skips the prompt (--yes)
+	AssumeYes bool
+	Getenv    func(string) string
+}
+
+// New returns a Prompter on stdin/stderr that prompts when both stdin and
+// stdout are terminals, like is_tty in scripts/lib/common.sh.
+func New(assumeYes bool) *Prompter {
+	return &Prompter{
+		In:          os.Stdin,
+		Out:         os.Stderr,
+		Interactive: isTerminal(os.Stdin) && isTerminal(os.Stdout),
+		AssumeYes:   assumeYes,
+		Getenv:      os.Getenv,
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Preconfirmed reports whether --yes or CONFIRM=true already approved the run
+func (p *Prompter) Preconfirmed() bool {
+	if p.AssumeYes {
+		return true
+	}
+	getenv := p.Getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	return strings.EqualFold(strings.TrimSpace(getenv(EnvVar)), "true")
+}
+
+// Confirm returns nil when action may proceed. action completes the sentence
+// "refusing to ...", e.g. "deploy openclaw.json to namespace openclaw".
+func (p *Prompter) Confirm(action string) error {
+	if p.Preconfirmed() {
+		return nil
+	}
+	if !p.Interactive {
+		return &NotConfirmedError{Action: action}
+	}
+	fmt.Fprintf(p.Out, "About to %s. Type 'yes' to continue: ", action)
+	answer, err := bufio.NewReader(p.In).ReadString('\n')
+	if err != nil && answer == "" {
+		return ErrAborted
+	}
+	if strings.TrimSpace(answer) != "yes" {
+		return ErrAborted
+	}
+	return nil
+}
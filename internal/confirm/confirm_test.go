@@ -0,0 +1,58 @@
+package confirm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestConfirmPreconfirmed(t *testing.T) {
+	cases := map[string]*Prompter{
+		"assume yes":   {AssumeYes: true, Getenv: env(nil)},
+		"CONFIRM=true": {Getenv: env(map[string]string{"CONFIRM": "true"})},
+		"interactive":  {Interactive: true, AssumeYes: true, In: strings.NewReader("no\n"), Getenv: env(nil)},
+	}
+	for name, p := range cases {
+		if err := p.Confirm("delete things"); err != nil {
+			t.Errorf("%s: Confirm() = %v, want nil", name, err)
+		}
+	}
+}
+
+func TestConfirmNonInteractive(t *testing.T) {
+	p := &Prompter{Getenv: env(map[string]string{"CONFIRM": "false"})}
+	err := p.Confirm("delete things")
+	var notConfirmed *NotConfirmedError
+	if !errors.As(err, &notConfirmed) {
+		t.Fatalf("Confirm() = %v, want NotConfirmedError", err)
+	}
+	for _, want := range []string{"delete things", "--yes", "CONFIRM=true"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestConfirmInteractive(t *testing.T) {
+	for answer, wantErr := range map[string]error{
+		"yes\n": nil,
+		"yes":   nil,
+		"y\n":   ErrAborted,
+		"\n":    ErrAborted,
+		"":      ErrAborted,
+	} {
+		var out bytes.Buffer
+		p := &Prompter{In: strings.NewReader(answer), Out: &out, Interactive: true, Getenv: env(nil)}
+		if err := p.Confirm("delete things"); !errors.Is(err, wantErr) {
+			t.Errorf("answer %q: Confirm() = %v, want %v", answer, err, wantErr)
+		}
+		if !strings.Contains(out.String(), "About to delete things") {
+			t.Errorf("answer %q: prompt = %q", answer, out.String())
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package dashboard manages access to the Kubernetes Dashboard installed by
+// the dashboard recipe: the admin ServiceAccount, its login tokens, and the
+// URL the dashboard is reachable at.
+package dashboard
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+)
+
+const (
+	// DefaultNamespace is where the dashboard recipe installs the Helm release
+	DefaultNamespace = "kubernetes-dashboard"
+
+	// DefaultServiceAccount is the admin account suggested by the recipe
+	DefaultServiceAccount = "admin-user"
+
+	// ProxyService is the Kong proxy Service fronting the dashboard (HTTPS on 443)
+	ProxyService = "kubernetes-dashboard-kong-proxy"
+
+	// IngressName is the Traefik ingress the recipe creates for --host
+	IngressName = "kubernetes-dashboard"
+
+	// DefaultLocalPort is the local port used for port-forward access
+	DefaultLocalPort = "8443"
+
+	clusterRole = "cluster-admin"
+)
+
+// Kubectl runs kubectl against the cluster
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+	Run(ctx context.Context, streams kubectl.Streams, args ...string) error
+}
+
+// isNotFound reports whether a kubectl error is a NotFound API error
+func isNotFound(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "not found"))
+}
+
+// EnsureServiceAccount creates the ServiceAccount and its cluster-admin
+// ClusterRoleBinding (named after the account) when missing. It returns the
+// objects it created, e.g. "serviceaccount/admin-user".
+func EnsureServiceAccount(ctx context.Context, kube Kubectl, namespace, account string) ([]string, error) {
+	var created []string
+	if _, err := kube.Output(ctx, "-n", namespace, "get", "serviceaccount", account, "-o", "name"); err != nil {
+		if !isNotFound(err) {
+			return nil, fmt.Errorf("failed to read serviceaccount %s/%s: %w", namespace, account, err)
+		}
+		if _, err := kube.Output(ctx, "-n", namespace, "create", "serviceaccount", account); err != nil {
+			return nil, fmt.Errorf("failed to create serviceaccount %s/%s: %w", namespace, account, err)
+		}
+		created = append(created, "serviceaccount/"+account)
+	}
+
+	if _, err := kube.Output(ctx, "get", "clusterrolebinding", account, "-o", "name"); err != nil {
+		if !isNotFound(err) {
+			return created, fmt.Errorf("failed to read clusterrolebinding %s: %w", account, err)
+		}
+		if _, err := kube.Output(ctx, "create", "clusterrolebinding", account,
+			"--clusterrole="+clusterRole, "--serviceaccount="+namespace+":"+account); err != nil {
+			return created, fmt.Errorf("failed to create clusterrolebinding %s: %w", account, err)
+		}
+		created = append(created, "clusterrolebinding/"+account)
+	}
+	return created, nil
+}
+
+// CreateToken requests a short-lived token for the account (TokenRequest API)
+func CreateToken(ctx context.Context, kube Kubectl, namespace, account string, duration time.Duration) (string, error) {
+	out, err := kube.Output(ctx, "-n", namespace, "create", "token", account, "--duration="+duration.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to create token for %s/%s: %w", namespace, account, err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", fmt.Errorf("kubectl returned an empty token for %s/%s", namespace, account)
+	}
+	return token, nil
+}
+
+// TokenSecretName is the Secret holding the account's long-lived token
+func TokenSecretName(account string) string {
+	return account + "-token"
+}
+
+// TokenSecretManifest returns a service-account-token Secret for the account;
+// the token controller fills in data.token.
+func TokenSecretManifest(namespace, account string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Secret
+type: kubernetes.io/service-account-token
+metadata:
+  name: %s
+  namespace: %s
+  annotations:
+    kubernetes.io/service-account.name: %s
+`, TokenSecretName(account), namespace, account)
+}
+
+// LongLivedToken returns the token stored in the account's token Secret,
+// creating the Secret when missing. With rotate, the Secret is deleted and
+// recreated first, which invalidates the previous token.
+func LongLivedToken(ctx context.Context, kube Kubectl, namespace, account string, rotate bool, wait time.Duration) (string, error) {
+	name := TokenSecretName(account)
+	if rotate {
+		if _, err := kube.Output(ctx, "-n", namespace, "delete", "secret", name, "--ignore-not-found", "--wait=true"); err != nil {
+			return "", fmt.Errorf("failed to delete secret %s/%s: %w", namespace, name, err)
+		}
+	}
+	if _, err := kube.Output(ctx, "-n", namespace, "get", "secret", name, "-o", "name"); err != nil {
+		if !isNotFound(err) {
+			return "", fmt.Errorf("failed to read secret %s/%s: %w", namespace, name, err)
+		}
+		if err := kube.Run(ctx, kubectl.Streams{Stdin: strings.NewReader(TokenSecretManifest(namespace, account))}, "create", "-f", "-"); err != nil {
+			return "", fmt.Errorf("failed to create secret %s/%s: %w", namespace, name, err)
+		}
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		out, err := kube.Output(ctx, "-n", namespace, "get", "secret", name, "-o", "jsonpath={.data.token}")
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret %s/%s: %w", namespace, name, err)
+		}
+		if encoded := strings.TrimSpace(string(out)); encoded != "" {
+			token, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return "", fmt.Errorf("secret %s/%s holds an invalid token: %w", namespace, name, err)
+			}
+			return string(token), nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("token controller did not populate secret %s/%s within %s", namespace, name, wait)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+// Access describes how the dashboard is reached
+type Access struct {
+	URL string
+	// Via is "ingress", "caddy", or "port-forward"
+	Via string
+}
+
+// ResolveAccess prefers the recipe's ingress host, then the Caddy host from
+// bootstrap (DASH_HOST), and otherwise a port-forward to the Kong proxy on
+// localPort.
+func ResolveAccess(ctx context.Context, kube Kubectl, namespace, dashHost, localPort string) (Access, error) {
+	out, err := kube.Output(ctx, "-n", namespace, "get", "ingress", IngressName, "-o", "jsonpath={.spec.rules[0].host}")
+	if err != nil && !isNotFound(err) {
+		return Access{}, fmt.Errorf("failed to read ingress %s/%s: %w", namespace, IngressName, err)
+	}
+	if host := strings.TrimSpace(string(out)); err == nil && host != "" {
+		return Access{URL: "https://" + host + "/", Via: "ingress"}, nil
+	}
+	if host := strings.TrimSpace(dashHost); host != "" {
+		return Access{URL: "https://" + host + "/", Via: "caddy"}, nil
+	}
+	return Access{URL: "https://localhost:" + localPort + "/", Via: "port-forward"}, nil
+}
+
+// PortForwardArgs returns the kubectl arguments forwarding localPort to the proxy
+func PortForwardArgs(namespace, localPort string) []string {
+	return []string{"-n", namespace, "port-forward", "svc/" + ProxyService, localPort + ":443"}
+}
@@ -0,0 +1,167 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/kubectl"
+)
+
+type fakeKube struct {
+	outputs map[string]string
+	errs    map[string]error
+	calls   []string
+	stdin   string
+}
+
+func (k *fakeKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	call := strings.Join(args, " ")
+	k.calls = append(k.calls, call)
+	for prefix, err := range k.errs {
+		if strings.HasPrefix(call, prefix) {
+			return nil, err
+		}
+	}
+	for prefix, out := range k.outputs {
+		if strings.HasPrefix(call, prefix) {
+			return []byte(out), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected kubectl %s", call)
+}
+
+func (k *fakeKube) Run(_ context.Context, streams kubectl.Streams, args ...string) error {
+	k.calls = append(k.calls, strings.Join(args, " "))
+	if streams.Stdin != nil {
+		b, _ := io.ReadAll(streams.Stdin)
+		k.stdin = string(b)
+	}
+	return nil
+}
+
+var errNotFound = fmt.Errorf(`Error from server (NotFound): not found`)
+
+func TestEnsureServiceAccountCreatesMissing(t *testing.T) {
+	kube := &fakeKube{
+		outputs: map[string]string{
+			"-n kubernetes-dashboard create serviceaccount admin-user": "",
+			"create clusterrolebinding admin-user":                     "",
+		},
+		errs: map[string]error{
+			"-n kubernetes-dashboard get serviceaccount": errNotFound,
+			"get clusterrolebinding":                     errNotFound,
+		},
+	}
+	created, err := EnsureServiceAccount(context.Background(), kube, DefaultNamespace, DefaultServiceAccount)
+	if err != nil {
+		t.Fatalf("EnsureServiceAccount() error: %v", err)
+	}
+	if strings.Join(created, ",") != "serviceaccount/admin-user,clusterrolebinding/admin-user" {
+		t.Errorf("created = %v", created)
+	}
+	if last := kube.calls[len(kube.calls)-1]; !strings.Contains(last, "--clusterrole=cluster-admin") ||
+		!strings.Contains(last, "--serviceaccount=kubernetes-dashboard:admin-user") {
+		t.Errorf("binding call = %s", last)
+	}
+}
+
+func TestEnsureServiceAccountExisting(t *testing.T) {
+	kube := &fakeKube{outputs: map[string]string{
+		"-n kubernetes-dashboard get serviceaccount": "serviceaccount/admin-user",
+		"get clusterrolebinding":                     "clusterrolebinding.rbac.authorization.k8s.io/admin-user",
+	}}
+	created, err := EnsureServiceAccount(context.Background(), kube, DefaultNamespace, DefaultServiceAccount)
+	if err != nil {
+		t.Fatalf("EnsureServiceAccount() error: %v", err)
+	}
+	if len(created) != 0 || len(kube.calls) != 2 {
+		t.Errorf("created = %v, calls = %v", created, kube.calls)
+	}
+
+	kube = &fakeKube{errs: map[string]error{"-n": fmt.Errorf("connection refused")}}
+	if _, err := EnsureServiceAccount(context.Background(), kube, DefaultNamespace, DefaultServiceAccount); err == nil {
+		t.Error("EnsureServiceAccount() expected error when the API is unreachable")
+	}
+}
+
+func TestCreateToken(t *testing.T) {
+	kube := &fakeKube{outputs: map[string]string{"-n kubernetes-dashboard create token admin-user": "abc.def\n"}}
+	token, err := CreateToken(context.Background(), kube, DefaultNamespace, DefaultServiceAccount, 8*time.Hour)
+	if err != nil {
+		t.Fatalf("CreateToken() error: %v", err)
+	}
+	if token != "abc.def" {
+		t.Errorf("token = %q", token)
+	}
+	if !strings.HasSuffix(kube.calls[0], "--duration=8h0m0s") {
+		t.Errorf("call = %s", kube.calls[0])
+	}
+
+	kube = &fakeKube{outputs: map[string]string{"-n": "  \n"}}
+	if _, err := CreateToken(context.Background(), kube, DefaultNamespace, DefaultServiceAccount, time.Hour); err == nil {
+		t.Error("CreateToken() expected error for empty token")
+	}
+}
+
+func TestLongLivedTokenRotate(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte("long.token"))
+	kube := &fakeKube{
+		outputs: map[string]string{
+			"-n kubernetes-dashboard delete secret admin-user-token":                        "",
+			"-n kubernetes-dashboard get secret admin-user-token -o jsonpath={.data.token}": encoded,
+		},
+		errs: map[string]error{"-n kubernetes-dashboard get secret admin-user-token -o name": errNotFound},
+	}
+	token, err := LongLivedToken(context.Background(), kube, DefaultNamespace, DefaultServiceAccount, true, time.Second)
+	if err != nil {
+		t.Fatalf("LongLivedToken() error: %v", err)
+	}
+	if token != "long.token" {
+		t.Errorf("token = %q", token)
+	}
+	if !strings.HasPrefix(kube.calls[0], "-n kubernetes-dashboard delete secret admin-user-token") {
+		t.Errorf("first call = %s, want delete", kube.calls[0])
+	}
+	if !strings.Contains(kube.stdin, "kubernetes.io/service-account.name: admin-user") ||
+		!strings.Contains(kube.stdin, "type: kubernetes.io/service-account-token") {
+		t.Errorf("manifest = %s", kube.stdin)
+	}
+}
+
+func TestResolveAccess(t *testing.T) {
+	ctx := context.Background()
+
+	kube := &fakeKube{outputs: map[string]string{"-n kubernetes-dashboard get ingress": "kube.example.com"}}
+	access, err := ResolveAccess(ctx, kube, DefaultNamespace, "dash.example.com", DefaultLocalPort)
+	if err != nil || access.URL != "https://kube.example.com/" || access.Via != "ingress" {
+		t.Errorf("ingress access = %+v, %v", access, err)
+	}
+
+	kube = &fakeKube{errs: map[string]error{"-n kubernetes-dashboard get ingress": errNotFound}}
+	access, err = ResolveAccess(ctx, kube, DefaultNamespace, "dash.example.com", DefaultLocalPort)
+	if err != nil || access.URL != "https://dash.example.com/" || access.Via != "caddy" {
+		t.Errorf("caddy access = %+v, %v", access, err)
+	}
+
+	access, err = ResolveAccess(ctx, kube, DefaultNamespace, "", "9443")
+	if err != nil || access.URL != "https://localhost:9443/" || access.Via != "port-forward" {
+		t.Errorf("port-forward access = %+v, %v", access, err)
+	}
+
+	kube = &fakeKube{errs: map[string]error{"-n": fmt.Errorf("connection refused")}}
+	if _, err := ResolveAccess(ctx, kube, DefaultNamespace, "", DefaultLocalPort); err == nil {
+		t.Error("ResolveAccess() expected error when the API is unreachable")
+	}
+}
+
+func TestPortForwardArgs(t *testing.T) {
+	got := strings.Join(PortForwardArgs(DefaultNamespace, "9443"), " ")
+	if got != "-n kubernetes-dashboard port-forward svc/kubernetes-dashboard-kong-proxy 9443:443" {
+		t.Errorf("PortForwardArgs() = %s", got)
+	}
+}
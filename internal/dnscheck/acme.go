@@ -0,0 +1,309 @@
+package dnscheck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChallengePath is requested on port 80 to see who answers HTTP-01
+// challenges; Caddy replies 404 for unknown tokens
+const ChallengePath = "/.well-known/acme-challenge/netcup-kube-debug"
+
+// PortProbe is the outcome of connecting to a host port from this machine
+type PortProbe struct {
+	Port      int    `json:"port"`
+	Reachable bool   `json:"reachable"`
+	Detail    string `json:"detail,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Certificate describes the certificate served on port 443
+type Certificate struct {
+	Subject  string   `json:"subject"`
+	Issuer   string   `json:"issuer"`
+	DNSNames []string `json:"dns_names"`
+	NotAfter string   `json:"not_after"`
+	Valid    bool     `json:"valid"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// LogEvent is an ACME-related entry of the Caddy log
+type LogEvent struct {
+	Time       string `json:"time"`
+	Level      string `json:"level"`
+	Logger     string `json:"logger"`
+	Message    string `json:"message"`
+	Identifier string `json:"identifier,omitempty"`
+	Challenge  string `json:"challenge,omitempty"`
+	Problem    string `json:"problem,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ACMEReport collects everything `netcup-kube dns debug-acme` looked at
+type ACMEReport struct {
+	Host        string       `json:"host"`
+	CertMode    string       `json:"cert_mode"`
+	DNS         HostResult   `json:"dns"`
+	HTTP        PortProbe    `json:"http"`
+	HTTPS       PortProbe    `json:"https"`
+	Certificate *Certificate `json:"certificate,omitempty"`
+	LogSource   string       `json:"log_source,omitempty"`
+	LogError    string       `json:"log_error,omitempty"`
+	Events      []LogEvent   `json:"events"`
+	Causes      []string     `json:"causes"`
+}
+
+// OK reports whether a valid certificate is served and nothing looks wrong
+func (r *ACMEReport) OK() bool {
+	return len(r.Causes) == 0 && r.Certificate != nil && r.Certificate.Valid
+}
+
+// ProbeHTTP requests ChallengePath on port 80 without following redirects
+func ProbeHTTP(ctx context.Context, host string, timeout time.Duration) PortProbe {
+	p := PortProbe{Port: 80}
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+host+ChallengePath, nil)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		p.Error = err.Error()
+		return p
+	}
+	defer func() { _ = resp.Body.Close() }()
+	p.Reachable = true
+	p.Detail = resp.Status
+	if server := resp.Header.Get("Server"); server != "" {
+		p.Detail += " (server: " + server + ")"
+	}
+	return p
+}
+
+// ProbeTLS connects to port 443 and returns the certificate served for host
+func ProbeTLS(ctx context.Context, host string, timeout time.Duration) (PortProbe, *Certificate) {
+	p := PortProbe{Port: 443}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: timeout},
+		// Verified below so an invalid certificate is reported, not a dial error
+		Config: &tls.Config{ServerName: host, InsecureSkipVerify: true},
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, "443"))
+	if err != nil {
+		p.Error = err.Error()
+		return p, nil
+	}
+	defer func() { _ = conn.Close() }()
+	p.Reachable = true
+
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		p.Detail = "no certificate presented"
+		return p, nil
+	}
+	p.Detail = "TLS " + tls.VersionName(state.Version)
+	return p, DescribeCertificate(state, host)
+}
+
+// DescribeCertificate returns the leaf certificate of a TLS connection,
+// verified for host against the system roots, or nil when none was presented
+func DescribeCertificate(state tls.ConnectionState, host string) *Certificate {
+	if len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	leaf := state.PeerCertificates[0]
+	cert := &Certificate{
+		Subject:  leaf.Subject.CommonName,
+		Issuer:   leaf.Issuer.CommonName,
+		DNSNames: leaf.DNSNames,
+		NotAfter: leaf.NotAfter.UTC().Format(time.RFC3339),
+	}
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates}); err != nil {
+		cert.Error = err.Error()
+	} else {
+		cert.Valid = true
+	}
+	return cert
+}
+
+// ParseCaddyLog returns the ACME and certificate entries of Caddy's JSON log
+// that concern host (or the wildcard covering it); other lines are ignored
+func ParseCaddyLog(data []byte, host string) []LogEvent {
+	wildcard := ""
+	if _, parent, ok := strings.Cut(host, "."); ok {
+		wildcard = "*." + parent
+	}
+	var events []LogEvent
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry struct {
+			Level      string          `json:"level"`
+			TS         json.Number     `json:"ts"`
+			Logger     string          `json:"logger"`
+			Msg        string          `json:"msg"`
+			Identifier string          `json:"identifier"`
+			Challenge  string          `json:"challenge_type"`
+			Error      string          `json:"error"`
+			Problem    json.RawMessage `json:"problem"`
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 || line[0] != '{' || json.Unmarshal(line, &entry) != nil {
+			continue
+		}
+		if !strings.HasPrefix(entry.Logger, "tls") && !strings.HasPrefix(entry.Logger, "http.acme") {
+			continue
+		}
+		if entry.Identifier != host && entry.Identifier != wildcard && !strings.Contains(entry.Error, host) {
+			continue
+		}
+		event := LogEvent{
+			Time:       formatTS(entry.TS),
+			Level:      entry.Level,
+			Logger:     entry.Logger,
+			Message:    entry.Msg,
+			Identifier: entry.Identifier,
+			Challenge:  entry.Challenge,
+			Error:      entry.Error,
+		}
+		var problem struct {
+			Type   string `json:"type"`
+			Detail string `json:"detail"`
+		}
+		if json.Unmarshal(entry.Problem, &problem) == nil && problem.Type != "" {
+			event.Problem = problem.Type
+			if event.Error == "" {
+				event.Error = problem.Detail
+			}
+		}
+		if event.Problem == "" {
+			if i := strings.Index(event.Error, acmeErrorPrefix); i >= 0 {
+				event.Problem, _, _ = strings.Cut(event.Error[i:], " ")
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func formatTS(ts json.Number) string {
+	f, err := strconv.ParseFloat(string(ts), 64)
+	if err != nil {
+		return string(ts)
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)).UTC().Format(time.RFC3339)
+}
+
+const acmeErrorPrefix = "urn:ietf:params:acme:error:"
+
+// problemCauses explains the ACME problem types Let's Encrypt returns most
+var problemCauses = map[string]string{
+	"rateLimited":        "Let's Encrypt rate limit reached; wait for the retry time in the error before trying again",
+	"connection":         "the CA could not connect to the host to validate the challenge (firewall or a record pointing elsewhere)",
+	"unauthorized":       "the CA got a wrong challenge response; another server or proxy answers for this host",
+	"dns":                "the CA could not resolve the host or its _acme-challenge TXT record",
+	"caa":                "a CAA record forbids Let's Encrypt from issuing certificates for this domain",
+	"rejectedIdentifier": "the CA refuses to issue for this hostname",
+	"tls":                "the CA hit a TLS error while validating the challenge",
+}
+
+// Diagnose fills r.Causes with the likely reasons issuance fails, most
+// fundamental first: DNS, then reachability, then what Caddy logged
+func Diagnose(r *ACMEReport) {
+	var causes []string
+	add := func(format string, args ...any) {
+		cause := fmt.Sprintf(format, args...)
+		for _, c := range causes {
+			if c == cause {
+				return
+			}
+		}
+		causes = append(causes, cause)
+	}
+
+	switch r.DNS.Status {
+	case HostWrong:
+		add("DNS for %s resolves to %s, not only the node; fix the A/AAAA records (Let's Encrypt prefers the AAAA record)", r.Host, strings.Join(answeredAddresses(r.DNS), ", "))
+	case HostIncomplete:
+		add("%s lacks a record for one of the node's address families (usually AAAA); add it or drop the address from the expectation", r.Host)
+	case HostMissing:
+		add("%s has no DNS record; add an A record for the node", r.Host)
+	case HostPropagating:
+		add("DNS for %s has not propagated to every resolver yet; the CA may still see the old record", r.Host)
+	}
+
+	if !r.HTTP.Reachable && r.CertMode == "http01" {
+		add("port 80 is not reachable from here (%s); HTTP-01 challenges need it open in UFW and the netcup firewall", r.HTTP.Error)
+	}
+	if !r.HTTPS.Reachable {
+		add("port 443 is not reachable from here (%s); open it in UFW and the netcup firewall", r.HTTPS.Error)
+	}
+
+	issued := false
+	for _, e := range r.Events {
+		if strings.Contains(e.Message, "certificate obtained successfully") {
+			issued = true
+			continue
+		}
+		if e.Level != "error" && e.Level != "warn" {
+			continue
+		}
+		if cause, ok := problemCauses[strings.TrimPrefix(e.Problem, acmeErrorPrefix)]; ok {
+			add("%s", cause)
+			continue
+		}
+		lower := strings.ToLower(e.Error)
+		switch {
+		case strings.Contains(lower, "waiting for record to fully propagate"), strings.Contains(lower, "no txt record"):
+			add("the DNS-01 TXT record did not propagate in time; check that %s uses the netcup nameservers", r.Host)
+		case strings.Contains(lower, "netcup") && (strings.Contains(lower, "login") || strings.Contains(lower, "auth") || strings.Contains(lower, "api key")):
+			add("the netcup DNS API rejected the request; check NETCUP_CUSTOMER_NUMBER, NETCUP_DNS_API_KEY and NETCUP_DNS_API_PASSWORD")
+		case strings.Contains(lower, "timeout"), strings.Contains(lower, "connection refused"):
+			add("Caddy could not reach the ACME CA or DNS API from the node (outbound network)")
+		}
+	}
+
+	if r.Certificate != nil && !r.Certificate.Valid && len(causes) == 0 {
+		if issued {
+			add("Caddy obtained a certificate but serves an invalid one (%s); restart Caddy", r.Certificate.Error)
+		} else {
+			add("the served certificate is not valid for %s (%s); Caddy has not obtained one yet", r.Host, r.Certificate.Error)
+		}
+	}
+	r.Causes = causes
+}
+
+func answeredAddresses(h HostResult) []string {
+	seen := map[string]bool{}
+	var addrs []string
+	for _, a := range h.Answers {
+		for _, addr := range a.Addresses {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	return addrs
+}
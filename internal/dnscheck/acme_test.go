@@ -0,0 +1,158 @@
+package dnscheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const caddyLog = `{"level":"info","ts":1760000000.5,"logger":"tls.obtain","msg":"acquiring lock","identifier":"kube.example.com"}
+{"level":"error","ts":1760000010,"logger":"tls.issuance.acme.acme_client","msg":"challenge failed","identifier":"kube.example.com","challenge_type":"http-01","problem":{"type":"urn:ietf:params:acme:error:connection","detail":"203.0.113.10: Timeout during connect (likely firewall problem)"}}
+{"level":"error","ts":1760000020,"logger":"tls.obtain","msg":"could not get certificate from issuer","identifier":"kube.example.com","error":"HTTP 429 urn:ietf:params:acme:error:rateLimited - too many failed authorizations recently"}
+{"level":"error","ts":1760000030,"logger":"tls.obtain","msg":"could not get certificate from issuer","identifier":"other.example.com","error":"boom"}
+{"level":"info","ts":1760000040,"logger":"http.log.access","msg":"handled request","identifier":"kube.example.com"}
+Oct 10 12:00:00 node caddy[42]: not json
+`
+
+func TestParseCaddyLog(t *testing.T) {
+	events := ParseCaddyLog([]byte(caddyLog), "kube.example.com")
+	if len(events) != 3 {
+		t.Fatalf("ParseCaddyLog() = %d events, want 3: %+v", len(events), events)
+	}
+	if events[0].Time != "2025-10-09T08:53:20Z" {
+		t.Errorf("time = %s", events[0].Time)
+	}
+	if e := events[1]; e.Problem != "urn:ietf:params:acme:error:connection" || !strings.Contains(e.Error, "firewall") || e.Challenge != "http-01" {
+		t.Errorf("challenge event = %+v", e)
+	}
+	if e := events[2]; e.Problem != "urn:ietf:params:acme:error:rateLimited" {
+		t.Errorf("rate limit event = %+v", e)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	r := &ACMEReport{
+		Host:     "kube.example.com",
+		CertMode: "http01",
+		DNS: HostResult{Status: HostWrong, Answers: []Answer{
+			{Resolver: "1.1.1.1", Status: StatusWrong, Addresses: []string{"198.51.100.7"}},
+		}},
+		HTTP:   PortProbe{Port: 80, Error: "i/o timeout"},
+		HTTPS:  PortProbe{Port: 443, Reachable: true},
+		Events: ParseCaddyLog([]byte(caddyLog), "kube.example.com"),
+	}
+	Diagnose(r)
+	want := []string{"resolves to 198.51.100.7", "port 80 is not reachable", "could not connect", "rate limit"}
+	if len(r.Causes) != len(want) {
+		t.Fatalf("Causes = %q", r.Causes)
+	}
+	for i, w := range want {
+		if !strings.Contains(r.Causes[i], w) {
+			t.Errorf("Causes[%d] = %q, want %q", i, r.Causes[i], w)
+		}
+	}
+	if r.OK() {
+		t.Error("OK() = true")
+	}
+
+	healthy := &ACMEReport{
+		Host:        "kube.example.com",
+		DNS:         HostResult{Status: HostResolved},
+		HTTP:        PortProbe{Port: 80, Reachable: true},
+		HTTPS:       PortProbe{Port: 443, Reachable: true},
+		Certificate: &Certificate{Valid: true},
+	}
+	Diagnose(healthy)
+	if !healthy.OK() {
+		t.Errorf("healthy report: Causes = %q", healthy.Causes)
+	}
+
+	pending := &ACMEReport{
+		Host:        "kube.example.com",
+		DNS:         HostResult{Status: HostResolved},
+		HTTP:        PortProbe{Port: 80, Reachable: true},
+		HTTPS:       PortProbe{Port: 443, Reachable: true},
+		Certificate: &Certificate{Error: "x509: certificate signed by unknown authority"},
+	}
+	Diagnose(pending)
+	if len(pending.Causes) != 1 || !strings.Contains(pending.Causes[0], "has not obtained one yet") {
+		t.Errorf("pending report: Causes = %q", pending.Causes)
+	}
+}
+
+func TestProbeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ChallengePath {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.Header().Set("Server", "Caddy")
+		http.Redirect(w, r, "https://kube.example.com/", http.StatusPermanentRedirect)
+	}))
+	defer srv.Close()
+
+	host := strings.TrimPrefix(srv.URL, "http://")
+	if p := ProbeHTTP(context.Background(), host, time.Second); !p.Reachable || p.Detail != "308 Permanent Redirect (server: Caddy)" {
+		t.Errorf("ProbeHTTP() = %+v", p)
+	}
+	srv.Close()
+	if p := ProbeHTTP(context.Background(), host, time.Second); p.Reachable || p.Error == "" {
+		t.Errorf("ProbeHTTP() of a closed port = %+v", p)
+	}
+	if p := ProbeHTTP(context.Background(), "bad host", time.Second); p.Error == "" {
+		t.Errorf("ProbeHTTP() of an invalid host = %+v", p)
+	}
+}
+
+func TestProbeTLSUnreachable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p, cert := ProbeTLS(ctx, "127.0.0.1", time.Second)
+	if p.Port != 443 || p.Reachable || p.Error == "" || cert != nil {
+		t.Errorf("ProbeTLS() = %+v, %+v", p, cert)
+	}
+}
+
+func TestDiagnoseCauses(t *testing.T) {
+	event := func(level, msg, err string) LogEvent {
+		return LogEvent{Level: level, Message: msg, Error: err}
+	}
+	tests := []struct {
+		name   string
+		report ACMEReport
+		want   []string
+	}{
+		{"missing record", ACMEReport{DNS: HostResult{Status: HostMissing}, HTTP: PortProbe{Reachable: true}, HTTPS: PortProbe{Error: "connection refused"}},
+			[]string{"has no DNS record", "port 443 is not reachable from here (connection refused)"}},
+		{"propagating", ACMEReport{DNS: HostResult{Status: HostPropagating}, HTTPS: PortProbe{Reachable: true}, Events: []LogEvent{
+			event("error", "cleaning up", "waiting for record to fully propagate"),
+			event("warn", "presenting", "netcup: login failed"),
+			event("error", "obtain", "dial tcp: i/o timeout"),
+			event("info", "obtain", "timeout"),
+			event("error", "obtain", "something else"),
+		}}, []string{"has not propagated", "DNS-01 TXT record", "netcup DNS API rejected", "could not reach the ACME CA"}},
+		{"issued but invalid", ACMEReport{DNS: HostResult{Status: HostResolved}, HTTPS: PortProbe{Reachable: true},
+			Events:      []LogEvent{event("info", "certificate obtained successfully", "")},
+			Certificate: &Certificate{Error: "x509: certificate has expired"}},
+			[]string{"serves an invalid one (x509: certificate has expired)"}},
+	}
+	for _, tt := range tests {
+		r := tt.report
+		r.Host = "kube.example.com"
+		Diagnose(&r)
+		if len(r.Causes) != len(tt.want) {
+			t.Errorf("%s: Causes = %q", tt.name, r.Causes)
+			continue
+		}
+		for i, w := range tt.want {
+			if !strings.Contains(r.Causes[i], w) {
+				t.Errorf("%s: Causes[%d] = %q, want %q", tt.name, i, r.Causes[i], w)
+			}
+		}
+	}
+	if got := formatTS("yesterday"); got != "yesterday" {
+		t.Errorf("formatTS() = %q", got)
+	}
+}
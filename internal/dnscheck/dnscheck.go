@@ -0,0 +1,242 @@
+// Package dnscheck verifies that the hostnames served by the edge resolve to
+// the node's public address on several public resolvers, so missing or not
+// yet propagated records are caught before Caddy requests certificates.
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultResolvers are queried when no resolvers are given: Cloudflare,
+// Google, and Quad9
+var DefaultResolvers = []string{"1.1.1.1", "8.8.8.8", "9.9.9.9"}
+
+// wildcardProbe is the label queried to check a *.<domain> record
+const wildcardProbe = "netcup-kube-dns-check"
+
+// Answer statuses of a single resolver
+const (
+	StatusOK      = "ok"
+	StatusWrong   = "wrong"
+	StatusMissing = "missing"
+	StatusError   = "error"
+	// StatusIncomplete means only some expected families are answered,
+	// e.g. the A record is right but the AAAA record is missing
+	StatusIncomplete = "incomplete"
+)
+
+// Host statuses summarizing all resolvers
+const (
+	HostResolved    = "resolved"
+	HostPropagating = "propagating"
+	HostWrong       = "wrong"
+	HostIncomplete  = "incomplete"
+	HostMissing     = "missing"
+	HostError       = "error"
+)
+
+// Host is a hostname to check; Query differs from Name for wildcards
+type Host struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// Answer is what one resolver returned for a host
+type Answer struct {
+	Resolver  string   `json:"resolver"`
+	Status    string   `json:"status"`
+	Addresses []string `json:"addresses,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// HostResult is the outcome for one host across all resolvers
+type HostResult struct {
+	Host
+	Status  string   `json:"status"`
+	Answers []Answer `json:"answers"`
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Expected  []string     `json:"expected"`
+	Resolvers []string     `json:"resolvers"`
+	Hosts     []HostResult `json:"hosts"`
+}
+
+// OK reports whether every host resolves to the expected addresses on every
+// resolver
+func (r Result) OK() bool {
+	for _, h := range r.Hosts {
+		if h.Status != HostResolved {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupFunc resolves host on the DNS server at resolver
+type LookupFunc func(ctx context.Context, resolver, host string) ([]string, error)
+
+// NewLookup returns a LookupFunc querying resolver on port 53 directly,
+// bypassing the system resolver and its cache
+func NewLookup(timeout time.Duration) LookupFunc {
+	return func(ctx context.Context, resolver, host string) ([]string, error) {
+		r := &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				d := net.Dialer{Timeout: timeout}
+				return d.DialContext(ctx, network, net.JoinHostPort(resolver, "53"))
+			},
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return r.LookupHost(ctx, host)
+	}
+}
+
+// Hosts returns the hostnames the edge serves according to env: BASE_DOMAIN
+// and its wildcard (unless CADDY_CERT_MODE=http01), CADDY_HTTP01_HOSTS,
+// DASH_HOST, and OPENCLAW_HOST, followed by extra, without duplicates
+func Hosts(env map[string]string, extra []string) []Host {
+	var hosts []Host
+	seen := map[string]bool{}
+	add := func(name, query string) {
+		name = normalize(name)
+		query = normalize(query)
+		if name == "" || seen[name] || strings.Contains(name, "${") {
+			return
+		}
+		seen[name] = true
+		hosts = append(hosts, Host{Name: name, Query: query})
+	}
+	addHost := func(name string) {
+		if rest, ok := strings.CutPrefix(normalize(name), "*."); ok {
+			add(name, wildcardProbe+"."+rest)
+			return
+		}
+		add(name, name)
+	}
+
+	if base := normalize(env["BASE_DOMAIN"]); base != "" {
+		addHost(base)
+		if env["CADDY_CERT_MODE"] != "http01" {
+			addHost("*." + base)
+		}
+	}
+	for _, name := range strings.FieldsFunc(env["CADDY_HTTP01_HOSTS"], isHostSeparator) {
+		addHost(name)
+	}
+	addHost(env["DASH_HOST"])
+	addHost(env["OPENCLAW_HOST"])
+	for _, name := range extra {
+		addHost(name)
+	}
+	return hosts
+}
+
+func normalize(name string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), ".")
+}
+
+func isHostSeparator(r rune) bool {
+	return r == ' ' || r == ',' || r == '|' || r == '\t'
+}
+
+// Check resolves every host on every resolver concurrently and compares the
+// answers with the expected addresses
+func Check(ctx context.Context, hosts []Host, expected, resolvers []string, lookup LookupFunc) Result {
+	result := Result{Expected: expected, Resolvers: resolvers, Hosts: make([]HostResult, len(hosts))}
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		result.Hosts[i] = HostResult{Host: h, Answers: make([]Answer, len(resolvers))}
+		for j, resolver := range resolvers {
+			wg.Add(1)
+			go func(answer *Answer, resolver, query string) {
+				defer wg.Done()
+				addrs, err := lookup(ctx, resolver, query)
+				*answer = classify(resolver, addrs, err, expected)
+			}(&result.Hosts[i].Answers[j], resolver, h.Query)
+		}
+	}
+	wg.Wait()
+	for i := range result.Hosts {
+		result.Hosts[i].Status = summarize(result.Hosts[i].Answers)
+	}
+	return result
+}
+
+// classify rates one answer: ok when every returned address is expected and
+// every expected family (A, AAAA) is answered. A stray AAAA record counts as
+// wrong: Let's Encrypt validates over IPv6 whenever one exists.
+func classify(resolver string, addrs []string, err error, expected []string) Answer {
+	a := Answer{Resolver: resolver, Addresses: addrs}
+	sort.Strings(a.Addresses)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			a.Status = StatusMissing
+			return a
+		}
+		a.Status = StatusError
+		a.Error = err.Error()
+		return a
+	}
+	if len(addrs) == 0 {
+		a.Status = StatusMissing
+		return a
+	}
+
+	want := map[string]bool{}
+	families := map[bool]bool{} // keyed by "is IPv4"
+	for _, e := range expected {
+		if ip := net.ParseIP(e); ip != nil {
+			want[ip.String()] = true
+			families[ip.To4() != nil] = true
+		}
+	}
+	answered := map[bool]bool{}
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			continue
+		}
+		if !want[ip.String()] {
+			a.Status = StatusWrong
+			return a
+		}
+		answered[ip.To4() != nil] = true
+	}
+	a.Status = StatusOK
+	for family := range families {
+		if !answered[family] {
+			a.Status = StatusIncomplete
+		}
+	}
+	return a
+}
+
+func summarize(answers []Answer) string {
+	counts := map[string]int{}
+	for _, a := range answers {
+		counts[a.Status]++
+	}
+	switch {
+	case counts[StatusOK] == len(answers):
+		return HostResolved
+	case counts[StatusOK] > 0:
+		return HostPropagating
+	case counts[StatusWrong] > 0:
+		return HostWrong
+	case counts[StatusIncomplete] > 0:
+		return HostIncomplete
+	case counts[StatusMissing] > 0:
+		return HostMissing
+	}
+	return HostError
+}
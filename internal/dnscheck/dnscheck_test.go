@@ -0,0 +1,115 @@
+package dnscheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHosts(t *testing.T) {
+	env := map[string]string{
+		"BASE_DOMAIN":        "Example.com.",
+		"CADDY_HTTP01_HOSTS": "kube.example.com, demo.example.com",
+		"DASH_HOST":          "kube.example.com",
+		"OPENCLAW_HOST":      "${UNSET_HOST}",
+	}
+	got := Hosts(env, []string{"*.apps.example.com", "example.com"})
+	want := []Host{
+		{Name: "example.com", Query: "example.com"},
+		{Name: "*.example.com", Query: "netcup-kube-dns-check.example.com"},
+		{Name: "kube.example.com", Query: "kube.example.com"},
+		{Name: "demo.example.com", Query: "demo.example.com"},
+		{Name: "*.apps.example.com", Query: "netcup-kube-dns-check.apps.example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Hosts() = %+v, want %+v", got, want)
+	}
+
+	env["CADDY_CERT_MODE"] = "http01"
+	if hosts := Hosts(env, nil); len(hosts) != 3 || hosts[1].Name != "kube.example.com" {
+		t.Errorf("Hosts(http01) = %+v, want no wildcard", hosts)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	answers := map[string]map[string][]string{
+		"1.1.1.1": {
+			"example.com":      {"203.0.113.10"},
+			"kube.example.com": {"203.0.113.10"},
+			"old.example.com":  {"198.51.100.7"},
+		},
+		"8.8.8.8": {
+			"example.com":      {"203.0.113.10"},
+			"kube.example.com": {"198.51.100.7"},
+			"old.example.com":  {"2001:db8::bad", "203.0.113.10"},
+		},
+	}
+	lookup := func(_ context.Context, resolver, host string) ([]string, error) {
+		if host == "broken.example.com" {
+			return nil, errors.New("i/o timeout")
+		}
+		if addrs, ok := answers[resolver][host]; ok {
+			return addrs, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	hosts := []Host{
+		{Name: "example.com", Query: "example.com"},
+		{Name: "kube.example.com", Query: "kube.example.com"},
+		{Name: "old.example.com", Query: "old.example.com"},
+		{Name: "new.example.com", Query: "new.example.com"},
+		{Name: "broken.example.com", Query: "broken.example.com"},
+	}
+	result := Check(context.Background(), hosts, []string{"203.0.113.10"}, []string{"1.1.1.1", "8.8.8.8"}, lookup)
+
+	want := []string{HostResolved, HostPropagating, HostWrong, HostMissing, HostError}
+	for i, h := range result.Hosts {
+		if h.Status != want[i] {
+			t.Errorf("%s: status = %s, want %s (%+v)", h.Name, h.Status, want[i], h.Answers)
+		}
+	}
+	if a := result.Hosts[1].Answers[1]; a.Status != StatusWrong || a.Resolver != "8.8.8.8" {
+		t.Errorf("kube.example.com on 8.8.8.8 = %+v", a)
+	}
+	if result.OK() {
+		t.Error("OK() = true, want false")
+	}
+	result.Hosts = result.Hosts[:1]
+	if !result.OK() {
+		t.Error("OK() = false for resolved hosts only")
+	}
+}
+
+func TestNewLookup(t *testing.T) {
+	lookup := NewLookup(200 * time.Millisecond)
+	// localhost is answered from the hosts file without a query
+	if addrs, err := lookup(context.Background(), "127.0.0.1", "localhost"); err != nil || len(addrs) == 0 {
+		t.Errorf("lookup(localhost) = %v, %v", addrs, err)
+	}
+	if addrs, err := lookup(context.Background(), "127.0.0.1", "netcup-kube-dns-check.invalid"); err == nil {
+		t.Errorf("lookup() without a resolver = %v, want error", addrs)
+	}
+}
+
+func TestClassifyDualStack(t *testing.T) {
+	expected := []string{"203.0.113.10", "2001:db8::10"}
+	tests := []struct {
+		addrs []string
+		want  string
+	}{
+		{[]string{"2001:db8:0:0::10", "203.0.113.10"}, StatusOK},
+		{[]string{"203.0.113.10"}, StatusIncomplete},
+		{[]string{"203.0.113.10", "2001:db8::99"}, StatusWrong},
+	}
+	for _, tt := range tests {
+		if got := classify("1.1.1.1", tt.addrs, nil, expected); got.Status != tt.want {
+			t.Errorf("classify(%v) = %s, want %s", tt.addrs, got.Status, tt.want)
+		}
+	}
+	if got := classify("1.1.1.1", []string{"203.0.113.10", "2001:db8::10"}, nil, expected[:1]); got.Status != StatusWrong {
+		t.Errorf("unexpected AAAA record = %s, want %s", got.Status, StatusWrong)
+	}
+}
@@ -0,0 +1,123 @@
+// Package encfile encrypts small files at rest with a passphrase: the key is
+// derived by PBKDF2-HMAC-SHA256 and the payload sealed with AES-256-GCM.
+//
+// Sealed data is laid out as magic, salt, nonce, and ciphertext. The magic
+// identifies the format (and the caller) and is authenticated as additional
+// data, so payloads of one caller cannot be opened as another's.
+package encfile
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// Iterations is the PBKDF2 iteration count used for sealed payloads
+	Iterations = 600000
+
+	// SaltLen is the length of the random per-payload salt
+	SaltLen = 16
+)
+
+var (
+	// ErrNoPassphrase is returned when the passphrase is empty
+	ErrNoPassphrase = errors.New("a passphrase is required")
+	// ErrNotSealed is returned when data does not start with the expected magic
+	ErrNotSealed = errors.New("not an encrypted payload")
+	// ErrTruncated is returned when sealed data is too short to hold salt and nonce
+	ErrTruncated = errors.New("encrypted payload is truncated")
+	// ErrDecrypt is returned when authentication fails, usually because the
+	// passphrase is wrong
+	ErrDecrypt = errors.New("failed to decrypt (wrong passphrase?)")
+)
+
+// PBKDF2SHA256 derives a key as specified in RFC 8018 section 5.2
+func PBKDF2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		_ = binary.Write(prf, binary.BigEndian, block)
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(PBKDF2SHA256([]byte(passphrase), salt, Iterations, 32))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsSealed reports whether data starts with magic
+func IsSealed(magic string, data []byte) bool {
+	return bytes.HasPrefix(data, []byte(magic))
+}
+
+// Seal encrypts data with a key derived from passphrase
+func Seal(magic, passphrase string, data []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+	salt := make([]byte, SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte(magic), salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, data, []byte(magic)), nil
+}
+
+// Open reverses Seal
+func Open(magic, passphrase string, data []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrNoPassphrase
+	}
+	if !IsSealed(magic, data) {
+		return nil, ErrNotSealed
+	}
+	data = data[len(magic):]
+	if len(data) < SaltLen {
+		return nil, ErrTruncated
+	}
+	aead, err := newAEAD(passphrase, data[:SaltLen])
+	if err != nil {
+		return nil, err
+	}
+	data = data[SaltLen:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrTruncated
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(magic))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
@@ -0,0 +1,46 @@
+package encfile
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	// RFC 7914 section 11 test vector
+	got := hex.EncodeToString(PBKDF2SHA256([]byte("passwd"), []byte("salt"), 1, 64))
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != want {
+		t.Errorf("PBKDF2SHA256() = %s, want %s", got, want)
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	const magic = "test-enc-v1\n"
+	plain := []byte("apiVersion: v1\nkind: Config\n")
+	sealed, err := Seal(magic, "correct horse", plain)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+	if bytes.Contains(sealed, plain) || !IsSealed(magic, sealed) {
+		t.Fatal("sealed data is not encrypted or lacks the magic")
+	}
+	got, err := Open(magic, "correct horse", sealed)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("Open() = %q, %v", got, err)
+	}
+
+	if _, err := Open(magic, "wrong", sealed); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Open() with wrong passphrase error = %v", err)
+	}
+	if _, err := Open("other-enc-v1\n", "correct horse", sealed); !errors.Is(err, ErrNotSealed) {
+		t.Errorf("Open() with other magic error = %v", err)
+	}
+	if _, err := Open(magic, "correct horse", sealed[:len(magic)+4]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Open() of truncated data error = %v", err)
+	}
+	if _, err := Seal(magic, "", plain); !errors.Is(err, ErrNoPassphrase) {
+		t.Errorf("Seal() without passphrase error = %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package executor
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+
+	"github.com/mfittko/netcup-kube/internal/bundle"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
 )
 
 // ExitCodeError represents a non-zero exit status from the delegated script.
@@ -18,6 +23,10 @@ func (e ExitCodeError) Error() string {
 	return fmt.Sprintf("script exited with code %d", e.Code)
 }
 
+// embeddedScripts extracts the scripts embedded in the binary and returns
+// their directory
+var embeddedScripts = bundle.Scripts
+
 // Executor handles execution of the shell scripts
 type Executor struct {
 	projectRoot string
@@ -61,16 +70,31 @@ func New() (*Executor, error) {
 
 // Execute runs a command by delegating to scripts/main.sh
 func (e *Executor) Execute(command string, args []string, env []string) error {
-	// Validate that the script exists and is accessible
+	return e.ExecuteContext(context.Background(), command, args, env)
+}
+
+// ExecuteContext is Execute bound to ctx: when ctx is canceled (e.g. on
+// Ctrl-C) the script gets SIGTERM, so its traps can remove temp files and
+// undo partial changes, and is killed after interrupt.GracePeriod.
+func (e *Executor) ExecuteContext(ctx context.Context, command string, args []string, env []string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// Validate that the script exists and is accessible; outside a checkout
+	// the scripts embedded in the binary are used
 	if _, err := os.Stat(e.scriptPath); err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("script not found: %s (ensure you're running from the project directory or the binary is in the correct location)", e.scriptPath)
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("cannot access script %s: %w", e.scriptPath, err)
 		}
-		return fmt.Errorf("cannot access script %s: %w", e.scriptPath, err)
+		dir, extractErr := embeddedScripts()
+		if extractErr != nil {
+			return fmt.Errorf("script not found: %s, and the embedded scripts could not be extracted: %w", e.scriptPath, extractErr)
+		}
+		e.scriptPath = filepath.Join(dir, "main.sh")
 	}
 
 	// Build the command
-	cmd := exec.Command("bash", e.scriptPath, command)
+	cmd := interrupt.Command(ctx, "bash", e.scriptPath, command)
 
 	// Add any additional arguments
 	if len(args) > 0 {
@@ -81,13 +105,22 @@ func (e *Executor) Execute(command string, args []string, env []string) error {
 	// (cfg already includes all necessary system variables via LoadFromEnvironment)
 	cmd.Env = env
 
+	// Hand the trace context to the script so nested tools can join the trace
+	_, span := telemetry.Start(ctx, "script "+command, telemetry.Int("script.args", len(args)))
+	if traceParent := span.TraceParent(); traceParent != "" {
+		cmd.Env = append(cmd.Env, "TRACEPARENT="+traceParent)
+	}
+
 	// Connect stdio
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
 	// Run the command
-	if err := cmd.Run(); err != nil {
+	if err := span.End(cmd.Run()); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s interrupted: %w", command, ctx.Err())
+		}
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
 			// Preserve the exit code from the script
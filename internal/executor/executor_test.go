@@ -1,11 +1,13 @@
 package executor
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func evalSymlinksOrOriginal(path string) string {
@@ -148,6 +150,10 @@ func TestNew_FromBinDirectory(t *testing.T) {
 }
 
 func TestExecute_ScriptNotFound(t *testing.T) {
+	orig := embeddedScripts
+	embeddedScripts = func() (string, error) { return "", errors.New("no cache directory") }
+	defer func() { embeddedScripts = orig }()
+
 	exec := &Executor{
 		projectRoot: "/nonexistent",
 		scriptPath:  "/nonexistent/scripts/main.sh",
@@ -162,6 +168,27 @@ func TestExecute_ScriptNotFound(t *testing.T) {
 	}
 }
 
+func TestExecute_EmbeddedFallback(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.sh"), []byte("#!/bin/bash\n[ \"$1\" = fallback ]\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	orig := embeddedScripts
+	embeddedScripts = func() (string, error) { return dir, nil }
+	defer func() { embeddedScripts = orig }()
+
+	exec := &Executor{
+		projectRoot: "/nonexistent",
+		scriptPath:  "/nonexistent/scripts/main.sh",
+	}
+	if err := exec.Execute("fallback", nil, os.Environ()); err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if exec.scriptPath != filepath.Join(dir, "main.sh") {
+		t.Errorf("scriptPath = %q", exec.scriptPath)
+	}
+}
+
 func TestExecute_ScriptExists(t *testing.T) {
 	// Verify that the Execute method checks if script exists
 	tmpDir := t.TempDir()
@@ -323,6 +350,39 @@ exit 42
 	}
 }
 
+func TestExecuteContext_CanceledRunsTraps(t *testing.T) {
+	tmpDir := t.TempDir()
+	marker := filepath.Join(tmpDir, "cleaned-up")
+	scriptPath := filepath.Join(tmpDir, "main.sh")
+	scriptContent := `#!/bin/bash
+sleep 5 &
+trap 'kill $!; touch "$MARKER"; exit 143' TERM
+wait $!
+`
+	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0755); err != nil {
+		t.Fatalf("Failed to create script: %v", err)
+	}
+
+	exec := &Executor{
+		projectRoot: tmpDir,
+		scriptPath:  scriptPath,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := exec.ExecuteContext(ctx, "test", nil, []string{"MARKER=" + marker})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ExecuteContext() error = %v, want context deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("ExecuteContext() returned after %s, want prompt termination", elapsed)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("script trap did not run: %v", err)
+	}
+}
+
 func TestExitCodeError_Error(t *testing.T) {
 	err := ExitCodeError{Code: 42}
 	if got := err.Error(); got != "script exited with code 42" {
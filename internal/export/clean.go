@@ -0,0 +1,159 @@
+package export
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+)
+
+// chartVersionStart matches the "-<digit>" that starts the version in a Helm
+// chart reference such as "kube-prometheus-stack-58.1.0"
+var chartVersionStart = regexp.MustCompile(`-v?[0-9]+\.`)
+
+// SplitChart splits a helm list chart reference into chart name and version
+func SplitChart(chart string) (string, string) {
+	loc := chartVersionStart.FindStringIndex(chart)
+	if loc == nil {
+		return chart, ""
+	}
+	return chart[:loc[0]], chart[loc[0]+1:]
+}
+
+// serverFields are metadata fields set by the API server, not by the owner
+var serverFields = []string{"uid", "resourceVersion", "generation", "creationTimestamp", "managedFields", "selfLink", "ownerReferences"}
+
+// CleanObject drops status and server-set metadata from a Kubernetes object,
+// leaving what is needed to apply it again
+func CleanObject(obj map[string]any) map[string]any {
+	delete(obj, "status")
+	meta, ok := obj["metadata"].(map[string]any)
+	if !ok {
+		return obj
+	}
+	for _, field := range serverFields {
+		delete(meta, field)
+	}
+	if annotations, ok := meta["annotations"].(map[string]any); ok {
+		delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+		if len(annotations) == 0 {
+			delete(meta, "annotations")
+		}
+	}
+	return obj
+}
+
+// CaddyHosts returns the site addresses of a Caddyfile: the top-level block
+// labels, without the global options block and snippets
+func CaddyHosts(caddyfile []byte) []string {
+	seen := map[string]bool{}
+	var hosts []string
+	for _, line := range strings.Split(string(caddyfile), "\n") {
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' || line[0] == '(' || line[0] == '{' {
+			continue
+		}
+		label, ok := strings.CutSuffix(strings.TrimSpace(line), "{")
+		if !ok {
+			continue
+		}
+		for _, host := range strings.FieldsFunc(label, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
+
+// redactValues replaces non-empty scalar values under secret-like keys such
+// as password or apiKey and returns how many were replaced
+func redactValues(v any) int {
+	n := 0
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			switch value.(type) {
+			case map[string]any, []any:
+				n += redactValues(value)
+				continue
+			}
+			if value == nil || value == "" || !sensitiveValueKey(key) {
+				continue
+			}
+			if _, isBool := value.(bool); isBool {
+				continue
+			}
+			v[key] = Redacted
+			n++
+		}
+	case []any:
+		for _, item := range v {
+			n += redactValues(item)
+		}
+	}
+	return n
+}
+
+// sensitiveValueKey reports whether a Helm value key holds a secret. Keys
+// naming an existing Secret or a key within it are references, not secrets.
+func sensitiveValueKey(key string) bool {
+	lower := strings.ToLower(key)
+	if strings.HasPrefix(lower, "existing") || strings.HasSuffix(lower, "secretname") || strings.HasSuffix(lower, "secretref") {
+		return false
+	}
+	return config.IsSensitiveKey(upperSnake(key))
+}
+
+// upperSnake converts a camelCase Helm value key to UPPER_SNAKE, so
+// config.IsSensitiveKey matches apiKey like API_KEY
+func upperSnake(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) && i > 0 {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+func renderReadme(s *Summary, releases []Release, opts Options) string {
+	var b strings.Builder
+	b.WriteString("# netcup-kube export\n\n")
+	b.WriteString("Generated by `netcup-kube export`; re-run it to refresh. Files listed in\n")
+	b.WriteString("`" + IndexFile + "` are replaced or removed by the next export.\n\n")
+	b.WriteString("| Path | Content |\n|------|---------|\n")
+	b.WriteString("| `releases/<namespace>/<release>/` | Helm release (`release.yaml`) and its user-supplied values (`values.yaml`) |\n")
+	b.WriteString("| `manifests/traefik/` | Traefik HelmChartConfig (k3s) |\n")
+	b.WriteString("| `manifests/quotas/` | ResourceQuota and LimitRange per namespace (`netcup-kube quota`) |\n")
+	b.WriteString("| `caddy/` | Caddyfile of the edge node and its site addresses |\n")
+	b.WriteString("| `config/netcup-kube.env` | netcup-kube env file |\n")
+	if opts.ArgoCDRepo != "" {
+		b.WriteString("| `argocd/` | Argo CD Applications for the releases and `manifests/` |\n")
+	}
+
+	if len(releases) > 0 {
+		b.WriteString("\n## Releases\n\n| Namespace | Release | Chart | Version |\n|-----------|---------|-------|---------|\n")
+		for _, r := range releases {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", r.Namespace, r.Name, r.ChartName, r.ChartVersion)
+		}
+	}
+	if !opts.IncludeSecrets {
+		b.WriteString("\nSecret-like values are replaced by `" + Redacted + "`. Provide them through a\n")
+		b.WriteString("secret manager (e.g. Sealed Secrets) before syncing; `--include-secrets`\n")
+		b.WriteString("exports them as they are.\n")
+	}
+	if len(s.Warnings) > 0 {
+		warnings := append([]string(nil), s.Warnings...)
+		sort.Strings(warnings)
+		b.WriteString("\n## Not exported\n\n")
+		for _, w := range warnings {
+			b.WriteString("- " + w + "\n")
+		}
+	}
+	return b.String()
+}
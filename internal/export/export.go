@@ -0,0 +1,413 @@
+// Package export renders the state netcup-kube manages — Helm releases of
+// recipes and their values, the Traefik HelmChartConfig, Caddy sites,
+// namespace quotas and the env file — into a directory tree that can be
+// committed to Git or synced by Argo CD.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/config"
+	"github.com/mfittko/netcup-kube/internal/yamlsubset"
+)
+
+const (
+	// IndexFile lists the files written by the last export, so files of
+	// releases that no longer exist are removed by the next one
+	IndexFile = ".netcup-kube-export"
+
+	// Redacted replaces secret values in exported files
+	Redacted = "<redacted>"
+
+	managedBySelector = "app.kubernetes.io/managed-by=netcup-kube"
+)
+
+// Runner runs kubectl or helm and returns stdout
+type Runner interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Sources are where the exported state is read from
+type Sources struct {
+	Kubectl Runner
+	// Helm lists releases; nil skips them
+	Helm Runner
+	// EnvFile is the content of the netcup-kube env file; empty skips it
+	EnvFile string
+	// Caddyfile reads the Caddy configuration of the edge node; nil skips it
+	Caddyfile func() ([]byte, error)
+}
+
+// Options tune the export
+type Options struct {
+	// IncludeSecrets keeps secret-like values instead of redacting them
+	IncludeSecrets bool
+	// ArgoCDRepo is the Git URL the export is pushed to; when set, Argo CD
+	// Applications are written for the releases and plain manifests
+	ArgoCDRepo string
+	// ArgoCDRevision is the Git revision the Applications track
+	ArgoCDRevision string
+}
+
+// Summary lists what an export wrote and what it could not read
+type Summary struct {
+	Files    []string `json:"files"`
+	Releases []string `json:"releases"`
+	Redacted int      `json:"redacted_values"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (s *Summary) warn(format string, args ...any) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+}
+
+// exporter collects the files of one export in memory before writing them
+type exporter struct {
+	ctx     context.Context
+	src     Sources
+	opts    Options
+	files   map[string][]byte
+	summary *Summary
+}
+
+// Export writes the managed state below dir. Sections that cannot be read
+// are reported as warnings; files of a previous export that are not written
+// again are removed. Files not written by an export are left alone.
+func Export(ctx context.Context, src Sources, opts Options, dir string) (*Summary, error) {
+	e := &exporter{ctx: ctx, src: src, opts: opts, files: map[string][]byte{}, summary: &Summary{}}
+	if opts.ArgoCDRevision == "" {
+		e.opts.ArgoCDRevision = "HEAD"
+	}
+
+	releases := e.releases()
+	e.traefik()
+	e.caddy()
+	e.quotas()
+	e.envFile()
+	if e.opts.ArgoCDRepo != "" {
+		e.argoCD(releases)
+	}
+	e.files["README.md"] = []byte(renderReadme(e.summary, releases, e.opts))
+
+	if err := writeFiles(dir, e.files); err != nil {
+		return nil, err
+	}
+	for name := range e.files {
+		e.summary.Files = append(e.summary.Files, name)
+	}
+	sort.Strings(e.summary.Files)
+	return e.summary, nil
+}
+
+// Release is a Helm release as listed by helm list -o json
+type Release struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Revision   string `json:"revision"`
+	Status     string `json:"status"`
+	Chart      string `json:"chart"`
+	AppVersion string `json:"app_version"`
+	// ChartName, ChartVersion and RepoURL are derived from Chart and the
+	// local Helm repositories
+	ChartName    string `json:"-"`
+	ChartVersion string `json:"-"`
+	RepoURL      string `json:"-"`
+}
+
+// Dir is the directory of the release in the export
+func (r Release) Dir() string {
+	return filepath.ToSlash(filepath.Join("releases", r.Namespace, r.Name))
+}
+
+func (e *exporter) releases() []Release {
+	if e.src.Helm == nil {
+		e.summary.warn("helm not found; Helm releases were not exported")
+		return nil
+	}
+	out, err := e.src.Helm.Output(e.ctx, "list", "--all-namespaces", "--output", "json")
+	if err != nil {
+		e.summary.warn("failed to list Helm releases: %v", err)
+		return nil
+	}
+	var releases []Release
+	if err := json.Unmarshal(out, &releases); err != nil {
+		e.summary.warn("failed to parse the Helm release list: %v", err)
+		return nil
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].Dir() < releases[j].Dir() })
+
+	for i := range releases {
+		r := &releases[i]
+		r.ChartName, r.ChartVersion = SplitChart(r.Chart)
+		e.summary.Releases = append(e.summary.Releases, r.Namespace+"/"+r.Name)
+
+		release := map[string]any{
+			"name":       r.Name,
+			"namespace":  r.Namespace,
+			"chart":      r.ChartName,
+			"version":    r.ChartVersion,
+			"appVersion": r.AppVersion,
+			"revision":   r.Revision,
+			"status":     r.Status,
+		}
+		values, err := e.src.Helm.Output(e.ctx, "get", "values", r.Name, "--namespace", r.Namespace, "--output", "json")
+		if err != nil {
+			e.summary.warn("failed to read the values of %s/%s: %v", r.Namespace, r.Name, err)
+		} else if data, err := e.valuesYAML(values); err != nil {
+			e.summary.warn("failed to convert the values of %s/%s: %v", r.Namespace, r.Name, err)
+		} else {
+			e.files[r.Dir()+"/values.yaml"] = data
+		}
+		if e.opts.ArgoCDRepo != "" {
+			if r.RepoURL, err = e.chartRepo(r.ChartName, r.ChartVersion); err != nil {
+				e.summary.warn("no Argo CD Application for %s/%s: %v", r.Namespace, r.Name, err)
+			} else {
+				release["repoURL"] = r.RepoURL
+			}
+		}
+		e.marshal(r.Dir()+"/release.yaml", release)
+	}
+	return releases
+}
+
+// valuesYAML converts helm get values JSON to YAML, redacting secrets
+func (e *exporter) valuesYAML(raw []byte) ([]byte, error) {
+	var values any
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = map[string]any{}
+	}
+	if !e.opts.IncludeSecrets {
+		e.summary.Redacted += redactValues(values)
+	}
+	return yamlsubset.Marshal(values)
+}
+
+// chartRepo returns the URL of the local Helm repository that serves chart
+// at version
+func (e *exporter) chartRepo(chart, version string) (string, error) {
+	out, err := e.src.Helm.Output(e.ctx, "search", "repo", "--regexp", `\v[^/]+/`+chart+`\v`, "--version", version, "--output", "json")
+	if err != nil {
+		return "", fmt.Errorf("helm search failed: %w", err)
+	}
+	var hits []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(out, &hits); err != nil || len(hits) == 0 {
+		return "", fmt.Errorf("chart %s %s is not in a local Helm repository (helm repo add)", chart, version)
+	}
+	repoName, _, _ := strings.Cut(hits[0].Name, "/")
+
+	out, err = e.src.Helm.Output(e.ctx, "repo", "list", "--output", "json")
+	if err != nil {
+		return "", fmt.Errorf("helm repo list failed: %w", err)
+	}
+	var repos []struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if err := json.Unmarshal(out, &repos); err != nil {
+		return "", fmt.Errorf("failed to parse the Helm repository list: %w", err)
+	}
+	for _, repo := range repos {
+		if repo.Name == repoName {
+			return repo.URL, nil
+		}
+	}
+	return "", fmt.Errorf("helm repository %s not found", repoName)
+}
+
+func (e *exporter) traefik() {
+	out, err := e.src.Kubectl.Output(e.ctx, "get", "helmchartconfig", "traefik", "--namespace", "kube-system", "--output", "json", "--ignore-not-found")
+	if err != nil {
+		e.summary.warn("failed to read the Traefik HelmChartConfig: %v", err)
+		return
+	}
+	if len(strings.TrimSpace(string(out))) == 0 {
+		return
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(out, &obj); err != nil {
+		e.summary.warn("failed to parse the Traefik HelmChartConfig: %v", err)
+		return
+	}
+	e.marshal("manifests/traefik/helmchartconfig.yaml", CleanObject(obj))
+}
+
+func (e *exporter) quotas() {
+	out, err := e.src.Kubectl.Output(e.ctx, "get", "resourcequota,limitrange", "--all-namespaces", "--selector", managedBySelector, "--output", "json")
+	if err != nil {
+		e.summary.warn("failed to read the namespace quotas: %v", err)
+		return
+	}
+	var list struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		e.summary.warn("failed to parse the namespace quotas: %v", err)
+		return
+	}
+	byNamespace := map[string][]map[string]any{}
+	for _, item := range list.Items {
+		meta, _ := item["metadata"].(map[string]any)
+		ns, _ := meta["namespace"].(string)
+		byNamespace[ns] = append(byNamespace[ns], CleanObject(item))
+	}
+	for ns, objs := range byNamespace {
+		var docs []string
+		for _, obj := range objs {
+			data, err := yamlsubset.Marshal(obj)
+			if err != nil {
+				e.summary.warn("failed to convert a quota of namespace %s: %v", ns, err)
+				continue
+			}
+			docs = append(docs, string(data))
+		}
+		e.files["manifests/quotas/"+ns+".yaml"] = []byte(strings.Join(docs, "---\n"))
+	}
+}
+
+func (e *exporter) caddy() {
+	if e.src.Caddyfile == nil {
+		return
+	}
+	data, err := e.src.Caddyfile()
+	if err != nil {
+		e.summary.warn("failed to read the Caddyfile: %v", err)
+		return
+	}
+	e.files["caddy/Caddyfile"] = data
+	if hosts := CaddyHosts(data); len(hosts) > 0 {
+		e.files["caddy/hosts.txt"] = []byte(strings.Join(hosts, "\n") + "\n")
+	}
+}
+
+func (e *exporter) envFile() {
+	if e.src.EnvFile == "" {
+		return
+	}
+	content := e.src.EnvFile
+	if !e.opts.IncludeSecrets {
+		content = config.RedactEnv(content, Redacted)
+	}
+	e.files["config/netcup-kube.env"] = []byte(content)
+}
+
+// argoCD writes an Application per release whose chart repository is known
+// (values from the export repository) and one for the plain manifests
+func (e *exporter) argoCD(releases []Release) {
+	for _, r := range releases {
+		if r.RepoURL == "" {
+			continue
+		}
+		app := argoApplication(r.Namespace+"-"+r.Name, r.Namespace)
+		spec := app["spec"].(map[string]any)
+		spec["sources"] = []any{
+			map[string]any{
+				"repoURL":        r.RepoURL,
+				"chart":          r.ChartName,
+				"targetRevision": r.ChartVersion,
+				"helm": map[string]any{
+					"releaseName": r.Name,
+					"valueFiles":  []any{"$values/" + r.Dir() + "/values.yaml"},
+				},
+			},
+			map[string]any{"repoURL": e.opts.ArgoCDRepo, "targetRevision": e.opts.ArgoCDRevision, "ref": "values"},
+		}
+		e.marshal("argocd/"+r.Namespace+"-"+r.Name+".yaml", app)
+	}
+
+	app := argoApplication("netcup-kube-manifests", "default")
+	app["spec"].(map[string]any)["source"] = map[string]any{
+		"repoURL":        e.opts.ArgoCDRepo,
+		"targetRevision": e.opts.ArgoCDRevision,
+		"path":           "manifests",
+		"directory":      map[string]any{"recurse": true},
+	}
+	e.marshal("argocd/netcup-kube-manifests.yaml", app)
+}
+
+func argoApplication(name, namespace string) map[string]any {
+	return map[string]any{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": "argocd",
+			"labels":    map[string]any{"app.kubernetes.io/managed-by": "netcup-kube"},
+		},
+		"spec": map[string]any{
+			"project":     "default",
+			"destination": map[string]any{"server": "https://kubernetes.default.svc", "namespace": namespace},
+		},
+	}
+}
+
+func (e *exporter) marshal(name string, v any) {
+	data, err := yamlsubset.Marshal(v)
+	if err != nil {
+		e.summary.warn("failed to convert %s: %v", name, err)
+		return
+	}
+	e.files[name] = data
+}
+
+// writeFiles writes files below dir and removes the files of the previous
+// export that were not written again
+func writeFiles(dir string, files map[string][]byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	indexPath := filepath.Join(dir, IndexFile)
+	previous, err := os.ReadFile(indexPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", indexPath, err)
+	}
+	for _, name := range strings.Split(string(previous), "\n") {
+		name = strings.TrimSpace(name)
+		if name == "" || files[name] != nil || !filepath.IsLocal(name) {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+		removeEmptyParents(dir, filepath.Dir(path))
+	}
+
+	names := make([]string, 0, len(files))
+	for name, data := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if err := os.WriteFile(indexPath, []byte(strings.Join(names, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", indexPath, err)
+	}
+	return nil
+}
+
+// removeEmptyParents removes empty directories from sub up to (not including) root
+func removeEmptyParents(root, sub string) {
+	for sub != root && strings.HasPrefix(sub, root) {
+		if os.Remove(sub) != nil {
+			return
+		}
+		sub = filepath.Dir(sub)
+	}
+}
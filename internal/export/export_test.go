@@ -0,0 +1,267 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRunner returns canned output keyed by the space-joined arguments
+type fakeRunner map[string]string
+
+func (f fakeRunner) Output(_ context.Context, args ...string) ([]byte, error) {
+	out, ok := f[strings.Join(args, " ")]
+	if !ok {
+		return nil, errors.New("unexpected command: " + strings.Join(args, " "))
+	}
+	return []byte(out), nil
+}
+
+func TestSplitChart(t *testing.T) {
+	for chart, want := range map[string][2]string{
+		"kube-prometheus-stack-58.1.0": {"kube-prometheus-stack", "58.1.0"},
+		"redis-v1.2.3":                 {"redis", "v1.2.3"},
+		"argo-cd-7.3.11-rc.1":          {"argo-cd", "7.3.11-rc.1"},
+		"local":                        {"local", ""},
+	} {
+		name, version := SplitChart(chart)
+		if name != want[0] || version != want[1] {
+			t.Errorf("SplitChart(%q) = %q, %q; want %q, %q", chart, name, version, want[0], want[1])
+		}
+	}
+}
+
+func TestCaddyHosts(t *testing.T) {
+	caddyfile := `{
+	email ops@example.com
+}
+
+(tls_common) {
+	tls internal
+}
+
+# comment {
+kube.example.com, *.apps.example.com {
+	reverse_proxy 127.0.0.1:8080
+}
+
+kube.example.com {
+}
+`
+	got := strings.Join(CaddyHosts([]byte(caddyfile)), ",")
+	if got != "kube.example.com,*.apps.example.com" {
+		t.Errorf("CaddyHosts() = %q", got)
+	}
+}
+
+func TestRedactValues(t *testing.T) {
+	values := map[string]any{
+		"auth": map[string]any{
+			"password":       "hunter2",
+			"existingSecret": "redis-auth",
+			"enabled":        true,
+		},
+		"apiKey":          "abc",
+		"tokenSecretName": "bot-token",
+		"env":             []any{map[string]any{"name": "X", "secretKey": "s3"}},
+		"replicas":        float64(2),
+		"emptyPassword":   "",
+	}
+	if n := redactValues(values); n != 3 {
+		t.Errorf("redactValues() = %d, want 3", n)
+	}
+	auth := values["auth"].(map[string]any)
+	if auth["password"] != Redacted || auth["existingSecret"] != "redis-auth" || auth["enabled"] != true {
+		t.Errorf("auth = %v", auth)
+	}
+	if values["apiKey"] != Redacted || values["tokenSecretName"] != "bot-token" {
+		t.Errorf("values = %v", values)
+	}
+	if env := values["env"].([]any)[0].(map[string]any); env["secretKey"] != Redacted || env["name"] != "X" {
+		t.Errorf("env = %v", env)
+	}
+}
+
+func TestCleanObject(t *testing.T) {
+	obj := CleanObject(map[string]any{
+		"kind":   "ResourceQuota",
+		"status": map[string]any{"used": map[string]any{}},
+		"metadata": map[string]any{
+			"name":            "netcup-kube-quota",
+			"uid":             "123",
+			"resourceVersion": "9",
+			"annotations":     map[string]any{"kubectl.kubernetes.io/last-applied-configuration": "{}"},
+		},
+	})
+	meta := obj["metadata"].(map[string]any)
+	if _, ok := obj["status"]; ok || len(meta) != 1 || meta["name"] != "netcup-kube-quota" {
+		t.Errorf("CleanObject() = %v", obj)
+	}
+}
+
+func testSources() Sources {
+	return Sources{
+		Kubectl: fakeRunner{
+			"get helmchartconfig traefik --namespace kube-system --output json --ignore-not-found": `{"apiVersion":"helm.cattle.io/v1","kind":"HelmChartConfig","metadata":{"name":"traefik","namespace":"kube-system","uid":"x"},"spec":{"valuesContent":"ports: {}"}}`,
+			"get resourcequota,limitrange --all-namespaces --selector " + managedBySelector + " --output json": `{"items":[
+				{"kind":"ResourceQuota","metadata":{"name":"netcup-kube-quota","namespace":"apps"},"spec":{"hard":{"pods":"10"}}},
+				{"kind":"LimitRange","metadata":{"name":"netcup-kube-limits","namespace":"apps"},"spec":{}}]}`,
+		},
+		Helm: fakeRunner{
+			"list --all-namespaces --output json":                                 `[{"name":"redis","namespace":"data","revision":"3","status":"deployed","chart":"redis-19.0.1","app_version":"7.2"}]`,
+			"get values redis --namespace data --output json":                     `{"auth":{"password":"hunter2"},"replica":{"replicaCount":1}}`,
+			`search repo --regexp \v[^/]+/redis\v --version 19.0.1 --output json`: `[{"name":"bitnami/redis","version":"19.0.1"}]`,
+			"repo list --output json":                                             `[{"name":"bitnami","url":"https://charts.bitnami.com/bitnami"}]`,
+		},
+		EnvFile:   "MGMT_HOST=kube.example.com\nDNS_TOKEN=\"secret\"\n",
+		Caddyfile: func() ([]byte, error) { return []byte("kube.example.com {\n}\n"), nil },
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	summary, err := Export(context.Background(), testSources(), Options{ArgoCDRepo: "https://git.example.com/state.git"}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Warnings) != 0 || summary.Redacted != 1 || strings.Join(summary.Releases, ",") != "data/redis" {
+		t.Errorf("summary = %+v", summary)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	for name, want := range map[string]string{
+		"releases/data/redis/values.yaml":        "password: " + Redacted,
+		"releases/data/redis/release.yaml":       "repoURL: https://charts.bitnami.com/bitnami",
+		"manifests/traefik/helmchartconfig.yaml": "valuesContent: ",
+		"manifests/quotas/apps.yaml":             "---\n",
+		"caddy/hosts.txt":                        "kube.example.com\n",
+		"config/netcup-kube.env":                 "DNS_TOKEN=" + Redacted,
+		"argocd/data-redis.yaml":                 "$values/releases/data/redis/values.yaml",
+		"argocd/netcup-kube-manifests.yaml":      "path: manifests",
+		"README.md":                              "| data | redis | redis | 19.0.1 |",
+	} {
+		if got := read(name); !strings.Contains(got, want) {
+			t.Errorf("%s lacks %q:\n%s", name, want, got)
+		}
+	}
+	if strings.Contains(read("manifests/traefik/helmchartconfig.yaml"), "uid") {
+		t.Error("server-set metadata was exported")
+	}
+
+	// A second export without the release and Argo CD removes their files
+	// but keeps files the export did not write
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := testSources()
+	src.Helm = fakeRunner{"list --all-namespaces --output json": `[]`}
+	if _, err := Export(context.Background(), src, Options{}, dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, gone := range []string{"releases", "argocd"} {
+		if _, err := os.Stat(filepath.Join(dir, gone)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s still exists: %v", gone, err)
+		}
+	}
+	if read("notes.md") != "mine" {
+		t.Error("notes.md was changed")
+	}
+}
+
+func TestExportWarnsAboutMissingSources(t *testing.T) {
+	src := testSources()
+	src.Helm = nil
+	src.Caddyfile = func() ([]byte, error) { return nil, errors.New("no host configured") }
+	summary, err := Export(context.Background(), src, Options{IncludeSecrets: true}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := strings.Join(summary.Warnings, "\n")
+	if !strings.Contains(warnings, "helm not found") || !strings.Contains(warnings, "no host configured") {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestExportWarnsAboutBrokenSources(t *testing.T) {
+	src := testSources()
+	src.Kubectl = fakeRunner{
+		"get helmchartconfig traefik --namespace kube-system --output json --ignore-not-found":             "{",
+		"get resourcequota,limitrange --all-namespaces --selector " + managedBySelector + " --output json": "[",
+	}
+	src.Helm = fakeRunner{
+		"list --all-namespaces --output json":                                 `[{"name":"redis","namespace":"data","chart":"redis-19.0.1"}]`,
+		"get values redis --namespace data --output json":                     `null`,
+		`search repo --regexp \v[^/]+/redis\v --version 19.0.1 --output json`: `[{"name":"bitnami/redis"}]`,
+		"repo list --output json":                                             `[{"name":"other","url":"https://charts.example.com"}]`,
+	}
+	summary, err := Export(context.Background(), src, Options{ArgoCDRepo: "https://git.example.com/state.git"}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings := strings.Join(summary.Warnings, "\n")
+	for _, want := range []string{"failed to parse the Traefik HelmChartConfig", "failed to parse the namespace quotas", "helm repository bitnami not found"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings lack %q:\n%s", want, warnings)
+		}
+	}
+
+	src.Kubectl = fakeRunner{}
+	summary, err = Export(context.Background(), src, Options{}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	warnings = strings.Join(summary.Warnings, "\n")
+	for _, want := range []string{"failed to read the Traefik HelmChartConfig", "failed to read the namespace quotas"} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("warnings lack %q:\n%s", want, warnings)
+		}
+	}
+}
+
+func TestExportSkipsMissingTraefikConfig(t *testing.T) {
+	src := testSources()
+	src.Kubectl.(fakeRunner)["get helmchartconfig traefik --namespace kube-system --output json --ignore-not-found"] = ""
+	dir := t.TempDir()
+	if _, err := Export(context.Background(), src, Options{}, dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "manifests", "traefik")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("traefik manifests written without a HelmChartConfig: %v", err)
+	}
+}
+
+func TestWriteFilesErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFiles(filepath.Join(file, "export"), map[string][]byte{"a": nil}); err == nil {
+		t.Error("writeFiles() below a file expected error")
+	}
+
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, IndexFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFiles(dir, map[string][]byte{"a": nil}); err == nil {
+		t.Error("writeFiles() with an unreadable index expected error")
+	}
+
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "caddy"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFiles(dir, map[string][]byte{"caddy/Caddyfile": nil}); err == nil {
+		t.Error("writeFiles() over a file expected error")
+	}
+}
@@ -0,0 +1,78 @@
+// Package filelock serializes concurrent netcup-kube and netcup-claw
+// invocations (e.g. a cron job and a human) that modify the same state file.
+// Locks are advisory: they only exclude other holders of the same lock.
+//
+// The lock for a path is kept in the hidden file .<name>.lock next to it, so
+// it survives files being replaced by an atomic rename.
+package filelock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultTimeout is how long Acquire waits for another invocation by default
+const DefaultTimeout = 30 * time.Second
+
+// pollInterval is how often a held lock is retried
+const pollInterval = 100 * time.Millisecond
+
+// errLocked is returned by tryLock when another holder has the lock
+var errLocked = errors.New("locked")
+
+// Lock is a held lock; Release it when done
+type Lock struct {
+	f *os.File
+}
+
+// Path returns the lock file guarding path
+func Path(path string) string {
+	return filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+}
+
+// Acquire takes the exclusive lock guarding path, waiting up to timeout for
+// another invocation to release it. The directory of path is created if needed.
+func Acquire(path string, timeout time.Duration) (*Lock, error) {
+	lockPath := Path(path)
+	if err := os.MkdirAll(filepath.Dir(lockPath), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(lockPath), err)
+	}
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := tryLock(f)
+		if err == nil {
+			return &Lock{f: f}, nil
+		}
+		if !errors.Is(err, errLocked) {
+			_ = f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, fmt.Errorf("%s is locked by another invocation (waited %s; lock file %s)", path, timeout, lockPath)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release gives up the lock. The lock file is left in place: removing it
+// would let a waiter lock a file that a new invocation no longer sees.
+func (l *Lock) Release() error {
+	if l == nil || l.f == nil {
+		return nil
+	}
+	err := unlock(l.f)
+	if cerr := l.f.Close(); err == nil {
+		err = cerr
+	}
+	l.f = nil
+	return err
+}
@@ -0,0 +1,43 @@
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcquireExcludesOtherHolders(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "netcup-kube.env")
+
+	first, err := Acquire(path, time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), ".netcup-kube.env.lock")); err != nil {
+		t.Errorf("lock file not created: %v", err)
+	}
+
+	if _, err := Acquire(path, 200*time.Millisecond); err == nil || !strings.Contains(err.Error(), "locked by another invocation") {
+		t.Fatalf("second Acquire() error = %v, want locked", err)
+	}
+
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		_ = first.Release()
+		close(released)
+	}()
+	second, err := Acquire(path, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	<-released
+	if err := second.Release(); err != nil {
+		t.Errorf("Release() error = %v", err)
+	}
+	if err := second.Release(); err != nil {
+		t.Errorf("second Release() error = %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package filelock
+
+import "os"
+
+// Without flock(2) locking is a no-op; concurrent invocations are not
+// serialized on these platforms.
+
+func tryLock(*os.File) error { return nil }
+
+func unlock(*os.File) error { return nil }
@@ -0,0 +1,174 @@
+// Package guardrail checks node headroom (free disk, available memory, free
+// inodes) before heavy operations such as bootstrap, k3s upgrades, and large
+// recipe installs.
+package guardrail
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/storage"
+)
+
+// Modes for PREFLIGHT_MODE
+const (
+	ModeEnforce = "enforce"
+	ModeWarn    = "warn"
+	ModeOff     = "off"
+)
+
+// Defaults used when the corresponding env variable is unset
+const (
+	DefaultMinDiskFree      = "5Gi"
+	DefaultMinMemory        = "512Mi"
+	DefaultMinInodesFreePct = 5
+)
+
+// ProbeScript prints node headroom as "key value" lines. Disk and inodes are
+// measured on the filesystem holding /var/lib/rancher (k3s data, images, and
+// local-path volumes), falling back to /var/lib and /.
+const ProbeScript = `p=/var/lib/rancher; [ -d "$p" ] || p=/var/lib; [ -d "$p" ] || p=/
+echo "path $p"
+df -Pk "$p" | awk 'NR==2 {printf "disk_free %.0f\n", $4 * 1024}'
+df -Pi "$p" | awk 'NR==2 {print "inodes_total", $2; print "inodes_free", $4}'
+awk '/^MemAvailable:/ {printf "mem_available %.0f\n", $2 * 1024}' /proc/meminfo`
+
+// Usage is the measured headroom of a node
+type Usage struct {
+	Path         string
+	DiskFree     int64
+	MemAvailable int64
+	InodesTotal  int64
+	InodesFree   int64
+}
+
+// ParseProbe parses the output of ProbeScript
+func ParseProbe(out []byte) (Usage, error) {
+	var u Usage
+	seen := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if key == "path" {
+			u.Path = value
+			continue
+		}
+		// Tolerate exponent notation from awk implementations that ignore %.0f
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return u, fmt.Errorf("invalid %s value %q", key, value)
+		}
+		n := int64(f)
+		switch key {
+		case "disk_free":
+			u.DiskFree = n
+		case "mem_available":
+			u.MemAvailable = n
+		case "inodes_total":
+			u.InodesTotal = n
+		case "inodes_free":
+			u.InodesFree = n
+		default:
+			continue
+		}
+		seen[key] = true
+	}
+	for _, key := range []string{"disk_free", "mem_available"} {
+		if !seen[key] {
+			return u, fmt.Errorf("resource probe did not report %s", key)
+		}
+	}
+	return u, nil
+}
+
+// Thresholds are the minimum headroom required before an operation
+type Thresholds struct {
+	Mode             string
+	MinDiskFree      int64
+	MinMemory        int64
+	MinInodesFreePct int
+}
+
+// ThresholdsFromEnv reads PREFLIGHT_MODE, PREFLIGHT_MIN_DISK_FREE,
+// PREFLIGHT_MIN_MEMORY (Kubernetes quantities such as 5Gi), and
+// PREFLIGHT_MIN_INODES_FREE_PCT, falling back to the defaults.
+func ThresholdsFromEnv(env map[string]string) (Thresholds, error) {
+	t := Thresholds{Mode: ModeEnforce, MinInodesFreePct: DefaultMinInodesFreePct}
+
+	switch mode := strings.ToLower(strings.TrimSpace(env["PREFLIGHT_MODE"])); mode {
+	case "":
+	case ModeEnforce, ModeWarn, ModeOff:
+		t.Mode = mode
+	default:
+		return t, fmt.Errorf("invalid PREFLIGHT_MODE %q (use enforce, warn, or off)", env["PREFLIGHT_MODE"])
+	}
+
+	var err error
+	if t.MinDiskFree, err = envQuantity(env, "PREFLIGHT_MIN_DISK_FREE", DefaultMinDiskFree); err != nil {
+		return t, err
+	}
+	if t.MinMemory, err = envQuantity(env, "PREFLIGHT_MIN_MEMORY", DefaultMinMemory); err != nil {
+		return t, err
+	}
+	if raw := strings.TrimSpace(env["PREFLIGHT_MIN_INODES_FREE_PCT"]); raw != "" {
+		pct, err := strconv.Atoi(raw)
+		if err != nil || pct < 0 || pct > 100 {
+			return t, fmt.Errorf("invalid PREFLIGHT_MIN_INODES_FREE_PCT %q (use 0-100)", raw)
+		}
+		t.MinInodesFreePct = pct
+	}
+	return t, nil
+}
+
+func envQuantity(env map[string]string, key, fallback string) (int64, error) {
+	raw := strings.TrimSpace(env[key])
+	if raw == "" {
+		raw = fallback
+	}
+	n, err := storage.ParseQuantity(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// Check returns one message per threshold the usage falls below
+func Check(u Usage, t Thresholds) []string {
+	var violations []string
+	if u.DiskFree < t.MinDiskFree {
+		violations = append(violations, fmt.Sprintf("free disk on %s is %s (minimum %s, PREFLIGHT_MIN_DISK_FREE)",
+			orRoot(u.Path), storage.FormatBytes(u.DiskFree), storage.FormatBytes(t.MinDiskFree)))
+	}
+	if u.MemAvailable < t.MinMemory {
+		violations = append(violations, fmt.Sprintf("available memory is %s (minimum %s, PREFLIGHT_MIN_MEMORY)",
+			storage.FormatBytes(u.MemAvailable), storage.FormatBytes(t.MinMemory)))
+	}
+	// Filesystems without fixed inode tables (btrfs, xfs on some setups) report 0
+	if u.InodesTotal > 0 {
+		pct := int(u.InodesFree * 100 / u.InodesTotal)
+		if pct < t.MinInodesFreePct {
+			violations = append(violations, fmt.Sprintf("free inodes on %s are %d%% (minimum %d%%, PREFLIGHT_MIN_INODES_FREE_PCT)",
+				orRoot(u.Path), pct, t.MinInodesFreePct))
+		}
+	}
+	return violations
+}
+
+// Summary renders the usage on one line
+func (u Usage) Summary() string {
+	s := fmt.Sprintf("disk free %s, memory available %s", storage.FormatBytes(u.DiskFree), storage.FormatBytes(u.MemAvailable))
+	if u.InodesTotal > 0 {
+		s += fmt.Sprintf(", inodes free %d%%", u.InodesFree*100/u.InodesTotal)
+	}
+	return s
+}
+
+func orRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,81 @@
+package guardrail
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProbe(t *testing.T) {
+	out := "path /var/lib/rancher\ndisk_free 1.07374e+10\ninodes_total 655360\ninodes_free 600000\nmem_available 2147483648\n"
+	u, err := ParseProbe([]byte(out))
+	if err != nil {
+		t.Fatalf("ParseProbe() error: %v", err)
+	}
+	if u.Path != "/var/lib/rancher" || u.DiskFree != 10737400000 || u.MemAvailable != 2<<30 || u.InodesTotal != 655360 || u.InodesFree != 600000 {
+		t.Errorf("ParseProbe() = %+v", u)
+	}
+
+	if _, err := ParseProbe([]byte("path /\ndisk_free 100\n")); err == nil || !strings.Contains(err.Error(), "mem_available") {
+		t.Errorf("ParseProbe(missing memory) error = %v", err)
+	}
+	if _, err := ParseProbe([]byte("disk_free lots\nmem_available 1\n")); err == nil {
+		t.Error("ParseProbe() expected error for a non-numeric value")
+	}
+}
+
+func TestThresholdsFromEnv(t *testing.T) {
+	th, err := ThresholdsFromEnv(map[string]string{})
+	if err != nil {
+		t.Fatalf("ThresholdsFromEnv(defaults) error: %v", err)
+	}
+	if th.Mode != ModeEnforce || th.MinDiskFree != 5<<30 || th.MinMemory != 512<<20 || th.MinInodesFreePct != DefaultMinInodesFreePct {
+		t.Errorf("defaults = %+v", th)
+	}
+
+	th, err = ThresholdsFromEnv(map[string]string{
+		"PREFLIGHT_MODE":                "Warn",
+		"PREFLIGHT_MIN_DISK_FREE":       "20Gi",
+		"PREFLIGHT_MIN_MEMORY":          "1Gi",
+		"PREFLIGHT_MIN_INODES_FREE_PCT": "10",
+	})
+	if err != nil {
+		t.Fatalf("ThresholdsFromEnv() error: %v", err)
+	}
+	if th.Mode != ModeWarn || th.MinDiskFree != 20<<30 || th.MinMemory != 1<<30 || th.MinInodesFreePct != 10 {
+		t.Errorf("thresholds = %+v", th)
+	}
+
+	for _, env := range []map[string]string{
+		{"PREFLIGHT_MODE": "strict"},
+		{"PREFLIGHT_MIN_DISK_FREE": "5GB"},
+		{"PREFLIGHT_MIN_MEMORY": "-1"},
+		{"PREFLIGHT_MIN_INODES_FREE_PCT": "150"},
+	} {
+		if _, err := ThresholdsFromEnv(env); err == nil {
+			t.Errorf("ThresholdsFromEnv(%v) expected error", env)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	th := Thresholds{Mode: ModeEnforce, MinDiskFree: 5 << 30, MinMemory: 512 << 20, MinInodesFreePct: 5}
+
+	ok := Usage{Path: "/var/lib", DiskFree: 10 << 30, MemAvailable: 1 << 30, InodesTotal: 1000, InodesFree: 500}
+	if v := Check(ok, th); len(v) != 0 {
+		t.Errorf("Check(ok) = %v", v)
+	}
+
+	low := Usage{Path: "/var/lib", DiskFree: 1 << 30, MemAvailable: 100 << 20, InodesTotal: 1000, InodesFree: 10}
+	v := Check(low, th)
+	if len(v) != 3 {
+		t.Fatalf("Check(low) = %v, want 3 violations", v)
+	}
+	if !strings.Contains(v[0], "1.0Gi") || !strings.Contains(v[1], "PREFLIGHT_MIN_MEMORY") || !strings.Contains(v[2], "1%") {
+		t.Errorf("Check(low) = %v", v)
+	}
+
+	noInodes := Usage{DiskFree: 10 << 30, MemAvailable: 1 << 30}
+	if v := Check(noInodes, th); len(v) != 0 {
+		t.Errorf("Check(no inode table) = %v", v)
+	}
+}
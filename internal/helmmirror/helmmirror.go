@@ -0,0 +1,108 @@
+// Package helmmirror points the upstream Helm chart repositories at a mirror
+// or pull-through cache (Artifactory, Nexus, an OCI registry proxy).
+//
+// The mirror is configured with env keys shared by netcup-claw and the recipe
+// scripts (scripts/lib/common.sh implements the same mapping):
+//
+//	HELM_MIRROR_URL          base URL; "{repo}" is replaced by the repository
+//	                         name, otherwise "/<repo>" is appended
+//	HELM_MIRROR_REPO_<NAME>  URL for a single repository (name upper-cased,
+//	                         '-' as '_'), e.g. HELM_MIRROR_REPO_BITNAMI
+//	HELM_MIRROR_USERNAME     credentials for the mirror
+//	HELM_MIRROR_PASSWORD
+//
+// URLs starting with oci:// are OCI registries: charts are referenced as
+// <url>/<chart> instead of through a repository added with `helm repo add`.
+package helmmirror
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Config keys.
+const (
+	URLKey        = "HELM_MIRROR_URL"
+	UsernameKey   = "HELM_MIRROR_USERNAME"
+	PasswordKey   = "HELM_MIRROR_PASSWORD"
+	RepoKeyPrefix = "HELM_MIRROR_REPO_"
+)
+
+// Mirror maps upstream repository names to mirror URLs.
+type Mirror struct {
+	URL      string
+	Repos    map[string]string // RepoKey suffix -> URL
+	Username string
+	Password string
+}
+
+// FromEnv builds the mirror configuration from env values.
+func FromEnv(env map[string]string) Mirror {
+	m := Mirror{
+		URL:      strings.TrimSpace(env[URLKey]),
+		Username: env[UsernameKey],
+		Password: env[PasswordKey],
+	}
+	for k, v := range env {
+		if suffix, ok := strings.CutPrefix(k, RepoKeyPrefix); ok && suffix != "" && strings.TrimSpace(v) != "" {
+			if m.Repos == nil {
+				m.Repos = map[string]string{}
+			}
+			m.Repos[suffix] = strings.TrimSpace(v)
+		}
+	}
+	return m
+}
+
+// IsKey reports whether key configures the mirror.
+func IsKey(key string) bool {
+	return key == URLKey || key == UsernameKey || key == PasswordKey || strings.HasPrefix(key, RepoKeyPrefix)
+}
+
+// RepoKey returns the HELM_MIRROR_REPO_<NAME> key overriding repo.
+func RepoKey(repo string) string {
+	return RepoKeyPrefix + strings.ToUpper(strings.ReplaceAll(repo, "-", "_"))
+}
+
+// Enabled reports whether any repository is mirrored.
+func (m Mirror) Enabled() bool {
+	return m.URL != "" || len(m.Repos) > 0
+}
+
+// RepoURL returns the mirror URL of repo, or "" when it is not mirrored.
+func (m Mirror) RepoURL(repo string) string {
+	if u := m.Repos[strings.TrimPrefix(RepoKey(repo), RepoKeyPrefix)]; u != "" {
+		return u
+	}
+	if m.URL == "" {
+		return ""
+	}
+	if strings.Contains(m.URL, "{repo}") {
+		return strings.ReplaceAll(m.URL, "{repo}", repo)
+	}
+	return strings.TrimSuffix(m.URL, "/") + "/" + repo
+}
+
+// IsOCI reports whether u is an OCI registry reference.
+func IsOCI(u string) bool {
+	return strings.HasPrefix(u, "oci://")
+}
+
+// Registry returns the host of an OCI reference, as used by `helm registry login`.
+func Registry(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// ChartRef returns the reference to install chart from repo with: <url>/<chart>
+// for an OCI mirror, otherwise <repo>/<chart> (the repository is added under
+// its upstream name, pointing at the mirror when one is configured).
+func (m Mirror) ChartRef(repo, chart string) string {
+	if u := m.RepoURL(repo); IsOCI(u) {
+		return strings.TrimSuffix(u, "/") + "/" + chart
+	}
+	return repo + "/" + chart
+}
@@ -0,0 +1,48 @@
+package helmmirror
+
+import "testing"
+
+func TestRepoURL(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		repo string
+		want string
+	}{
+		{"unset", nil, "bitnami", ""},
+		{"appended", map[string]string{URLKey: "https://artifactory.example.com/api/helm/"}, "bitnami", "https://artifactory.example.com/api/helm/bitnami"},
+		{"placeholder", map[string]string{URLKey: "https://nexus.example.com/repository/{repo}-proxy"}, "prometheus-community", "https://nexus.example.com/repository/prometheus-community-proxy"},
+		{"oci", map[string]string{URLKey: "oci://registry.example.com/charts"}, "openclaw", "oci://registry.example.com/charts/openclaw"},
+		{"override", map[string]string{URLKey: "https://mirror.example.com", "HELM_MIRROR_REPO_SEALED_SECRETS": "https://other.example.com/ss"}, "sealed-secrets", "https://other.example.com/ss"},
+		{"override only", map[string]string{"HELM_MIRROR_REPO_BITNAMI": "oci://registry.example.com/bitnamicharts"}, "redis-stack", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromEnv(tt.env).RepoURL(tt.repo); got != tt.want {
+				t.Errorf("RepoURL(%q) = %q, want %q", tt.repo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChartRef(t *testing.T) {
+	m := FromEnv(map[string]string{
+		URLKey:                      "https://mirror.example.com/helm",
+		"HELM_MIRROR_REPO_OPENCLAW": "oci://registry.example.com/charts/",
+	})
+	if got := m.ChartRef("openclaw", "openclaw"); got != "oci://registry.example.com/charts/openclaw" {
+		t.Errorf("ChartRef(openclaw) = %q", got)
+	}
+	if got := m.ChartRef("bitnami", "redis"); got != "bitnami/redis" {
+		t.Errorf("ChartRef(bitnami) = %q", got)
+	}
+	if got := Registry("oci://registry.example.com:5000/charts"); got != "registry.example.com:5000" {
+		t.Errorf("Registry() = %q", got)
+	}
+	if !IsKey("HELM_MIRROR_REPO_BITNAMI") || IsKey("HELM_REPO") {
+		t.Error("IsKey() mismatch")
+	}
+	if (Mirror{}).Enabled() || !m.Enabled() {
+		t.Error("Enabled() mismatch")
+	}
+}
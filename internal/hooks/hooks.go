@@ -0,0 +1,293 @@
+// Package hooks runs user-defined scripts before and after CLI commands, such
+// as an inventory check before `bootstrap` or a notification after
+// `k3s upgrade`.
+//
+// Hooks for a command come from two places, config keys first:
+//
+//   - HOOK_<STAGE>_<COMMAND>=<shell command>, e.g. HOOK_POST_K3S_UPGRADE
+//   - executables in <dir>/<stage>-<command>[.<suffix>], e.g.
+//     config/hooks.d/netcup-kube/post-k3s-upgrade.sh, run in name order
+//
+// A failing hook follows its failure policy: abort fails the command, warn
+// reports the failure and continues, ignore continues silently.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
+)
+
+// Stage is when a hook runs relative to its command
+type Stage string
+
+const (
+	Pre  Stage = "pre"
+	Post Stage = "post"
+)
+
+// Policy decides what a failing hook does to its command
+type Policy string
+
+const (
+	Abort  Policy = "abort"
+	Warn   Policy = "warn"
+	Ignore Policy = "ignore"
+)
+
+// Dir returns the hooks.d directory of cli: <root>/<cli> when root (HOOKS_DIR)
+// is set, else config/hooks.d/<cli> when it exists, else hooks.d in the config
+// directory of cli (~/.config/<cli>/hooks.d)
+func Dir(root, cli string) string {
+	if root != "" {
+		return filepath.Join(root, cli)
+	}
+	if dir := paths.Find(filepath.Join("config", "hooks.d", cli)); dir != "" {
+		return dir
+	}
+	return filepath.Join(paths.ConfigDir(cli), "hooks.d")
+}
+
+// DefaultTimeout bounds a single hook unless HOOKS_TIMEOUT is set
+const DefaultTimeout = 5 * time.Minute
+
+// Hook is one script to run
+type Hook struct {
+	// Name is the config key or file name
+	Name string
+	// Path is set for hooks.d executables
+	Path string
+	// Command is set for config-key hooks and runs via sh -c
+	Command string
+	Policy  Policy
+}
+
+// Runner finds and runs the hooks of one CLI
+type Runner struct {
+	// CLI is the binary name, exported to hooks as NETCUP_HOOK_CLI
+	CLI string
+	// Dir holds <stage>-<command> executables; empty disables them
+	Dir string
+	// Lookup reads configuration (HOOK_*, HOOKS_ON_FAILURE, HOOKS_TIMEOUT)
+	Lookup func(key string) string
+	// Env is the base environment of hook processes
+	Env    []string
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Invocation describes the command a hook runs for
+type Invocation struct {
+	// Command is the command path without the CLI name, e.g. "k3s upgrade"
+	Command string
+	Args    []string
+	// Err, ExitCode and Duration are the command result, set for post hooks
+	Err      error
+	ExitCode int
+	Duration time.Duration
+}
+
+// Key returns the config key of a stage and command, e.g. HOOK_POST_K3S_UPGRADE
+func Key(stage Stage, command string) string {
+	return "HOOK_" + strings.ToUpper(string(stage)) + "_" + keyPart(command)
+}
+
+func keyPart(command string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(command) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
+
+// fileBase returns the hooks.d file name prefix, e.g. post-k3s-upgrade
+func fileBase(stage Stage, command string) string {
+	return string(stage) + "-" + strings.Join(strings.Fields(command), "-")
+}
+
+// Hooks returns the hooks for a stage and command in run order
+func (r *Runner) Hooks(stage Stage, command string) ([]Hook, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, nil
+	}
+	key := Key(stage, command)
+	policy, err := r.policy(stage, key)
+	if err != nil {
+		return nil, err
+	}
+	var hooks []Hook
+	if cmd := strings.TrimSpace(r.lookup(key)); cmd != "" {
+		hooks = append(hooks, Hook{Name: key, Command: cmd, Policy: policy})
+	}
+
+	if r.Dir == "" {
+		return hooks, nil
+	}
+	entries, err := os.ReadDir(r.Dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return hooks, nil
+		}
+		return nil, fmt.Errorf("failed to read hooks directory %s: %w", r.Dir, err)
+	}
+	base := fileBase(stage, command)
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || (name != base && !strings.HasPrefix(name, base+".")) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(r.Dir, name)
+		info, err := os.Stat(path)
+		if err != nil || info.Mode().Perm()&0o111 == 0 {
+			fmt.Fprintf(r.stderr(), "Warning: hook %s is not executable; skipping\n", path)
+			continue
+		}
+		hooks = append(hooks, Hook{Name: name, Path: path, Policy: policy})
+	}
+	return hooks, nil
+}
+
+// policy returns <key>_ON_FAILURE, else HOOKS_ON_FAILURE, else abort for pre
+// hooks and warn for post hooks
+func (r *Runner) policy(stage Stage, key string) (Policy, error) {
+	value := strings.TrimSpace(r.lookup(key + "_ON_FAILURE"))
+	if value == "" {
+		value = strings.TrimSpace(r.lookup("HOOKS_ON_FAILURE"))
+	}
+	switch p := Policy(strings.ToLower(value)); p {
+	case "":
+		if stage == Pre {
+			return Abort, nil
+		}
+		return Warn, nil
+	case Abort, Warn, Ignore:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid hook failure policy %q for %s (expected abort, warn, or ignore)", value, key)
+	}
+}
+
+// Run runs the hooks of a stage in order. It returns an error for the first
+// failing hook whose policy is abort; later hooks of the stage are skipped.
+func (r *Runner) Run(ctx context.Context, stage Stage, inv Invocation) error {
+	hooks, err := r.Hooks(stage, inv.Command)
+	if err != nil {
+		return err
+	}
+	if len(hooks) == 0 {
+		return nil
+	}
+	timeout, err := r.timeout()
+	if err != nil {
+		return err
+	}
+	env := append(append([]string{}, r.Env...), r.metadata(stage, inv)...)
+	for _, h := range hooks {
+		err := r.runHook(ctx, h, env, timeout)
+		if err == nil {
+			continue
+		}
+		switch h.Policy {
+		case Abort:
+			return fmt.Errorf("%s hook %s failed: %w", stage, h.Name, err)
+		case Warn:
+			fmt.Fprintf(r.stderr(), "Warning: %s hook %s failed: %v\n", stage, h.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) runHook(ctx context.Context, h Hook, env []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var cmd *exec.Cmd
+	if h.Path != "" {
+		cmd = exec.CommandContext(ctx, h.Path)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", h.Command)
+	}
+	cmd.Env = env
+	cmd.Stdout = r.Stdout
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	cmd.Stderr = r.stderr()
+	// Do not wait on children of a killed hook that still hold its output
+	cmd.WaitDelay = 2 * time.Second
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
+
+// metadata is the NETCUP_HOOK_* environment describing the invocation
+func (r *Runner) metadata(stage Stage, inv Invocation) []string {
+	env := []string{
+		"NETCUP_HOOK_CLI=" + r.CLI,
+		"NETCUP_HOOK_STAGE=" + string(stage),
+		"NETCUP_HOOK_COMMAND=" + inv.Command,
+		"NETCUP_HOOK_ARGS=" + strings.Join(inv.Args, " "),
+	}
+	if stage == Post {
+		status, exitCode := "success", inv.ExitCode
+		if inv.Err != nil {
+			status = "failure"
+			if exitCode == 0 {
+				exitCode = 1
+			}
+			env = append(env, "NETCUP_HOOK_ERROR="+inv.Err.Error())
+		}
+		env = append(env,
+			"NETCUP_HOOK_STATUS="+status,
+			"NETCUP_HOOK_EXIT_CODE="+strconv.Itoa(exitCode),
+			"NETCUP_HOOK_DURATION_SECONDS="+strconv.FormatFloat(inv.Duration.Seconds(), 'f', 1, 64),
+		)
+	}
+	return env
+}
+
+func (r *Runner) timeout() (time.Duration, error) {
+	value := strings.TrimSpace(r.lookup("HOOKS_TIMEOUT"))
+	if value == "" {
+		return DefaultTimeout, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid HOOKS_TIMEOUT %q (expected a duration such as 2m)", value)
+	}
+	return d, nil
+}
+
+func (r *Runner) lookup(key string) string {
+	if r.Lookup == nil {
+		return ""
+	}
+	return r.Lookup(key)
+}
+
+func (r *Runner) stderr() io.Writer {
+	if r.Stderr == nil {
+		return os.Stderr
+	}
+	return r.Stderr
+}
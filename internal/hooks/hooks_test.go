@@ -0,0 +1,130 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeHook(t *testing.T, dir, name, body string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+body+"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got := Key(Post, "k3s upgrade"); got != "HOOK_POST_K3S_UPGRADE" {
+		t.Errorf("Key = %q", got)
+	}
+	if got := Key(Pre, "port-forward start"); got != "HOOK_PRE_PORT_FORWARD_START" {
+		t.Errorf("Key = %q", got)
+	}
+}
+
+func TestHooksOrderAndMatching(t *testing.T) {
+	dir := t.TempDir()
+	writeHook(t, dir, "post-k3s-upgrade.20-notify.sh", "true", 0o755)
+	writeHook(t, dir, "post-k3s-upgrade.10-check", "true", 0o755)
+	writeHook(t, dir, "post-k3s", "true", 0o755)
+	writeHook(t, dir, "post-k3s-upgrade-extra", "true", 0o755)
+	writeHook(t, dir, "post-k3s-upgrade.disabled", "true", 0o644)
+
+	var stderr bytes.Buffer
+	r := &Runner{Dir: dir, Stderr: &stderr, Lookup: func(key string) string {
+		return map[string]string{"HOOK_POST_K3S_UPGRADE": "echo hi"}[key]
+	}}
+	hooks, err := r.Hooks(Post, "k3s upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, h := range hooks {
+		names = append(names, h.Name)
+		if h.Policy != Warn {
+			t.Errorf("%s: policy = %s, want warn", h.Name, h.Policy)
+		}
+	}
+	want := "HOOK_POST_K3S_UPGRADE post-k3s-upgrade.10-check post-k3s-upgrade.20-notify.sh"
+	if strings.Join(names, " ") != want {
+		t.Errorf("hooks = %v, want %s", names, want)
+	}
+	if !strings.Contains(stderr.String(), "not executable") {
+		t.Errorf("expected a warning for the non-executable hook, got %q", stderr.String())
+	}
+}
+
+func TestRunPolicies(t *testing.T) {
+	lookup := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+	ctx := context.Background()
+	inv := Invocation{Command: "bootstrap"}
+
+	r := &Runner{Lookup: lookup(map[string]string{"HOOK_PRE_BOOTSTRAP": "exit 3"}), Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	if err := r.Run(ctx, Pre, inv); err == nil || !strings.Contains(err.Error(), "pre hook HOOK_PRE_BOOTSTRAP failed") {
+		t.Errorf("pre default policy: err = %v, want abort", err)
+	}
+
+	var stderr bytes.Buffer
+	r = &Runner{Lookup: lookup(map[string]string{"HOOK_POST_BOOTSTRAP": "exit 3"}), Stdout: &bytes.Buffer{}, Stderr: &stderr}
+	if err := r.Run(ctx, Post, inv); err != nil {
+		t.Errorf("post default policy: err = %v, want warn", err)
+	}
+	if !strings.Contains(stderr.String(), "Warning: post hook HOOK_POST_BOOTSTRAP failed") {
+		t.Errorf("missing warning: %q", stderr.String())
+	}
+
+	stderr.Reset()
+	r = &Runner{Lookup: lookup(map[string]string{"HOOK_PRE_BOOTSTRAP": "exit 3", "HOOKS_ON_FAILURE": "ignore"}), Stdout: &bytes.Buffer{}, Stderr: &stderr}
+	if err := r.Run(ctx, Pre, inv); err != nil || stderr.Len() != 0 {
+		t.Errorf("ignore policy: err = %v, stderr = %q", err, stderr.String())
+	}
+
+	r = &Runner{Lookup: lookup(map[string]string{"HOOK_PRE_BOOTSTRAP": "true", "HOOK_PRE_BOOTSTRAP_ON_FAILURE": "retry"})}
+	if err := r.Run(ctx, Pre, inv); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := &Runner{Lookup: func(key string) string {
+		return map[string]string{"HOOK_PRE_BOOTSTRAP": "sleep 5", "HOOKS_TIMEOUT": "100ms"}[key]
+	}, Stdout: &bytes.Buffer{}, Stderr: &bytes.Buffer{}}
+	start := time.Now()
+	err := r.Run(context.Background(), Pre, Invocation{Command: "bootstrap"})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("err = %v, want timeout", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Error("hook was not stopped at the timeout")
+	}
+}
+
+func TestRunInjectsMetadata(t *testing.T) {
+	var stdout bytes.Buffer
+	r := &Runner{
+		CLI:    "netcup-kube",
+		Env:    []string{"PATH=" + os.Getenv("PATH"), "BASE_DOMAIN=example.com"},
+		Stdout: &stdout,
+		Lookup: func(key string) string {
+			if key == "HOOK_POST_K3S_UPGRADE" {
+				return `echo "$NETCUP_HOOK_CLI|$NETCUP_HOOK_STAGE|$NETCUP_HOOK_COMMAND|$NETCUP_HOOK_ARGS|$NETCUP_HOOK_STATUS|$NETCUP_HOOK_EXIT_CODE|$NETCUP_HOOK_ERROR|$BASE_DOMAIN"`
+			}
+			return ""
+		},
+	}
+	inv := Invocation{Command: "k3s upgrade", Args: []string{"--channel", "stable"}, Err: errors.New("boom"), ExitCode: 4, Duration: time.Second}
+	if err := r.Run(context.Background(), Post, inv); err != nil {
+		t.Fatal(err)
+	}
+	want := "netcup-kube|post|k3s upgrade|--channel stable|failure|4|boom|example.com\n"
+	if stdout.String() != want {
+		t.Errorf("hook output = %q, want %q", stdout.String(), want)
+	}
+}
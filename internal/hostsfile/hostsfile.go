@@ -0,0 +1,201 @@
+// Package hostsfile manages the block of entries netcup-kube owns in a hosts
+// file (/etc/hosts). The block is delimited by marker comments; lines outside
+// it are never changed.
+package hostsfile
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultPath is the hosts file of the machine
+	DefaultPath = "/etc/hosts"
+
+	// BeginMarker and EndMarker delimit the managed block
+	BeginMarker = "# BEGIN netcup-kube managed hosts (do not edit by hand)"
+	EndMarker   = "# END netcup-kube managed hosts"
+
+	// BackupSuffix names the copy of the hosts file kept before a write
+	BackupSuffix = ".netcup-kube.bak"
+)
+
+// Entry maps a hostname to an address
+type Entry struct {
+	IP   string `json:"ip"`
+	Host string `json:"host"`
+}
+
+// File is a parsed hosts file: the lines around the managed block and the
+// block's entries
+type File struct {
+	before  []string
+	after   []string
+	Entries []Entry
+}
+
+// Parse splits content into the managed block and the lines around it. A
+// file without a block gets one appended on String.
+func Parse(content string) (*File, error) {
+	f := &File{}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case BeginMarker:
+			if begin >= 0 {
+				return nil, fmt.Errorf("hosts file has more than one %q line", BeginMarker)
+			}
+			begin = i
+		case EndMarker:
+			if begin < 0 || end >= 0 {
+				return nil, fmt.Errorf("hosts file has a misplaced %q line", EndMarker)
+			}
+			end = i
+		}
+	}
+	if begin >= 0 && end < 0 {
+		return nil, fmt.Errorf("hosts file has %q without %q", BeginMarker, EndMarker)
+	}
+	if begin < 0 {
+		f.before = lines
+		return f, nil
+	}
+	f.before, f.after = lines[:begin], lines[end+1:]
+	for _, line := range lines[begin+1 : end] {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, host := range fields[1:] {
+			if strings.HasPrefix(host, "#") {
+				break
+			}
+			f.Entries = append(f.Entries, Entry{IP: fields[0], Host: host})
+		}
+	}
+	return f, nil
+}
+
+// Set replaces the managed entries, one per host (the last one wins)
+func (f *File) Set(entries []Entry) {
+	byHost := map[string]Entry{}
+	for _, e := range entries {
+		e.Host = strings.ToLower(e.Host)
+		byHost[e.Host] = e
+	}
+	f.Entries = f.Entries[:0]
+	for _, e := range byHost {
+		f.Entries = append(f.Entries, e)
+	}
+	sort.Slice(f.Entries, func(i, j int) bool { return f.Entries[i].Host < f.Entries[j].Host })
+}
+
+// Add adds entries to the managed block, replacing those of the same hosts
+func (f *File) Add(entries []Entry) {
+	f.Set(append(append([]Entry{}, f.Entries...), entries...))
+}
+
+// Remove drops the entries of hosts from the managed block and returns the
+// hosts that were present
+func (f *File) Remove(hosts []string) []string {
+	drop := map[string]bool{}
+	for _, h := range hosts {
+		drop[strings.ToLower(h)] = true
+	}
+	var kept []Entry
+	var removed []string
+	for _, e := range f.Entries {
+		if drop[e.Host] {
+			removed = append(removed, e.Host)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	f.Entries = kept
+	return removed
+}
+
+// String renders the file; the managed block stays where it was (appended
+// when new) and is left out when it has no entries
+func (f *File) String() string {
+	lines := append([]string{}, f.before...)
+	if len(f.Entries) > 0 {
+		lines = append(lines, BeginMarker)
+		for _, e := range f.Entries {
+			lines = append(lines, e.IP+"\t"+e.Host)
+		}
+		lines = append(lines, EndMarker)
+	}
+	lines = append(lines, f.after...)
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Sudo runs a command as root with stdin, e.g. via the sudo binary
+type Sudo func(stdin []byte, args ...string) error
+
+// ExecSudo runs args through sudo, prompting for a password on the terminal
+func ExecSudo(stdin []byte, args ...string) error {
+	cmd := exec.Command("sudo", append([]string{"--"}, args...)...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// Write replaces the hosts file at path with content, guarded: it refuses
+// when the file changed since original was read, keeps a copy of it as
+// path+BackupSuffix, and writes in place (the file may be a bind mount).
+// When the current user may not write path, the copy and the write go
+// through sudo.
+func Write(path string, original, content []byte, sudo Sudo) error {
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !bytes.Equal(current, original) {
+		return fmt.Errorf("%s changed since it was read; re-run the command", path)
+	}
+	if _, err := Parse(string(content)); err != nil {
+		return fmt.Errorf("refusing to write %s: %w", path, err)
+	}
+
+	backup := path + BackupSuffix
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if errors.Is(err, os.ErrPermission) {
+		if sudo == nil {
+			return fmt.Errorf("no permission to write %s", path)
+		}
+		if err := sudo(nil, "cp", "-p", path, backup); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		if err := sudo(content, "sh", "-c", `cat > "$1"`, "sh", path); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := os.WriteFile(backup, original, 0o644); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := f.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,169 @@
+package hostsfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const system = "127.0.0.1\tlocalhost\n::1\tlocalhost ip6-localhost\n"
+
+func TestParseAndRender(t *testing.T) {
+	f, err := Parse(system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Add([]Entry{{IP: "203.0.113.10", Host: "Claw.example.com"}, {IP: "203.0.113.10", Host: "dash.example.com"}})
+	want := system + BeginMarker + "\n203.0.113.10\tclaw.example.com\n203.0.113.10\tdash.example.com\n" + EndMarker + "\n"
+	if got := f.String(); got != want {
+		t.Fatalf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	// The block keeps its place and lines after it survive
+	content := strings.Replace(want, EndMarker+"\n", EndMarker+"\n10.0.0.5\tnas\n", 1)
+	f, err = Parse(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Entries) != 2 {
+		t.Fatalf("Entries = %+v", f.Entries)
+	}
+	f.Add([]Entry{{IP: "127.0.0.1", Host: "claw.example.com"}})
+	if removed := f.Remove([]string{"dash.example.com", "other.example.com"}); len(removed) != 1 {
+		t.Errorf("Remove() = %v", removed)
+	}
+	want = system + BeginMarker + "\n127.0.0.1\tclaw.example.com\n" + EndMarker + "\n10.0.0.5\tnas\n"
+	if got := f.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+
+	f.Set(nil)
+	if got := f.String(); got != system+"10.0.0.5\tnas\n" {
+		t.Errorf("String() of an empty block =\n%s", got)
+	}
+}
+
+func TestParseRejectsBrokenBlock(t *testing.T) {
+	for _, content := range []string{
+		system + BeginMarker + "\n1.2.3.4 a\n",
+		system + EndMarker + "\n",
+		BeginMarker + "\n" + EndMarker + "\n" + BeginMarker + "\n" + EndMarker + "\n",
+	} {
+		if _, err := Parse(content); err == nil {
+			t.Errorf("Parse(%q) expected error", content)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(system), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	updated := system + BeginMarker + "\n1.2.3.4\ta.example.com\n" + EndMarker + "\n"
+	noSudo := func([]byte, ...string) error {
+		t.Error("sudo used for a writable file")
+		return nil
+	}
+
+	if err := Write(path, []byte("stale"), []byte(updated), noSudo); err == nil || !strings.Contains(err.Error(), "changed since") {
+		t.Errorf("Write() with a stale original error = %v", err)
+	}
+	if err := Write(path, []byte(system), []byte(updated), noSudo); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != updated {
+		t.Errorf("hosts = %q", got)
+	}
+	if got, _ := os.ReadFile(path + BackupSuffix); string(got) != system {
+		t.Errorf("backup = %q", got)
+	}
+}
+
+func TestParseSkipsComments(t *testing.T) {
+	f, err := Parse(BeginMarker + "\n# pinned by hand\n\n203.0.113.10\tclaw.example.com dash.example.com # edge\n" + EndMarker + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{{IP: "203.0.113.10", Host: "claw.example.com"}, {IP: "203.0.113.10", Host: "dash.example.com"}}
+	if !reflect.DeepEqual(f.Entries, want) {
+		t.Errorf("Entries = %+v", f.Entries)
+	}
+	f.Set(nil)
+	if got := f.String(); got != "" {
+		t.Errorf("String() of an empty file = %q", got)
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	dir := t.TempDir()
+	valid := []byte(BeginMarker + "\n1.2.3.4\ta.example.com\n" + EndMarker + "\n")
+
+	if err := Write(dir, nil, valid, nil); err == nil || !strings.Contains(err.Error(), "failed to read") {
+		t.Errorf("Write(directory) error = %v", err)
+	}
+	if err := Write(filepath.Join(dir, "missing", "hosts"), nil, valid, nil); err == nil || !strings.Contains(err.Error(), "failed to open") {
+		t.Errorf("Write() in a missing directory error = %v", err)
+	}
+	path := filepath.Join(dir, "hosts")
+	if err := Write(path, nil, []byte(BeginMarker+"\n"), nil); err == nil || !strings.Contains(err.Error(), "refusing to write") {
+		t.Errorf("Write(broken block) error = %v", err)
+	}
+	if err := os.Mkdir(path+BackupSuffix, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(path, nil, valid, nil); err == nil || !strings.Contains(err.Error(), "failed to back up") {
+		t.Errorf("Write() with an unwritable backup error = %v", err)
+	}
+
+	// A missing hosts file is created
+	path = filepath.Join(dir, "new-hosts")
+	if err := Write(path, nil, valid, nil); err != nil {
+		t.Fatalf("Write(new file) error: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != string(valid) {
+		t.Errorf("hosts = %q", got)
+	}
+}
+
+func TestWriteThroughSudo(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root may write any file")
+	}
+	path := filepath.Join(t.TempDir(), "hosts")
+	if err := os.WriteFile(path, []byte(system), 0o444); err != nil {
+		t.Fatal(err)
+	}
+	updated := []byte(system + BeginMarker + "\n1.2.3.4\ta.example.com\n" + EndMarker + "\n")
+
+	if err := Write(path, []byte(system), updated, nil); err == nil || !strings.Contains(err.Error(), "no permission") {
+		t.Errorf("Write() without sudo error = %v", err)
+	}
+
+	var calls []string
+	var written []byte
+	sudo := func(stdin []byte, args ...string) error {
+		calls = append(calls, strings.Join(args, " "))
+		if args[0] == "sh" {
+			written = stdin
+		}
+		return nil
+	}
+	if err := Write(path, []byte(system), updated, sudo); err != nil {
+		t.Fatalf("Write() through sudo error: %v", err)
+	}
+	if want := []string{"cp -p " + path + " " + path + BackupSuffix, `sh -c cat > "$1" sh ` + path}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("sudo calls = %q, want %q", calls, want)
+	}
+	if string(written) != string(updated) {
+		t.Errorf("written = %q", written)
+	}
+
+	failing := func([]byte, ...string) error { return errors.New("sudo: a password is required") }
+	if err := Write(path, []byte(system), updated, failing); err == nil || !strings.Contains(err.Error(), "failed to back up") {
+		t.Errorf("Write() with failing sudo error = %v", err)
+	}
+}
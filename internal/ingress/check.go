@@ -0,0 +1,166 @@
+package ingress
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/dnscheck"
+)
+
+// Outcomes of a route test
+const (
+	OutcomeOK      = "ok"
+	OutcomeWarn    = "warn"
+	OutcomeFail    = "fail"
+	OutcomeSkipped = "skipped"
+)
+
+// Options configure Test
+type Options struct {
+	// Edge is the address (host or host:port, port 443 by default) to connect
+	// to instead of resolving each hostname, like curl --resolve; it tests
+	// the edge before DNS points at it
+	Edge    string
+	Timeout time.Duration
+	// Insecure accepts invalid certificates instead of failing the route
+	Insecure bool
+}
+
+// Result is the outcome of requesting one route through the edge
+type Result struct {
+	Route
+	URL         string                `json:"url,omitempty"`
+	Status      int                   `json:"status,omitempty"`
+	Location    string                `json:"location,omitempty"`
+	LatencyMS   int64                 `json:"latencyMs,omitempty"`
+	TLSVersion  string                `json:"tlsVersion,omitempty"`
+	Certificate *dnscheck.Certificate `json:"certificate,omitempty"`
+	Outcome     string                `json:"outcome"`
+	Hint        string                `json:"hint,omitempty"`
+	Error       string                `json:"error,omitempty"`
+}
+
+// Test requests every route in parallel and returns the results in route
+// order
+func Test(ctx context.Context, routes []Route, opts Options) []Result {
+	results := make([]Result, len(routes))
+	var wg sync.WaitGroup
+	for i, r := range routes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = Probe(ctx, r, opts)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// Probe requests https://host/path of a route without following redirects
+func Probe(ctx context.Context, r Route, opts Options) Result {
+	res := Result{Route: r}
+	if !r.Testable() {
+		res.Outcome = OutcomeSkipped
+		res.Hint = "wildcard host or pattern path; request a concrete URL instead"
+		return res
+	}
+	res.URL = "https://" + r.Host + r.Path
+
+	dialer := &net.Dialer{Timeout: opts.Timeout}
+	transport := &http.Transport{
+		// Verified below so an invalid certificate is reported, not an error
+		TLSClientConfig:   &tls.Config{ServerName: r.Host, InsecureSkipVerify: true},
+		DisableKeepAlives: true,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if opts.Edge != "" {
+				addr = edgeAddress(opts.Edge)
+			}
+			return dialer.DialContext(ctx, network, addr)
+		},
+	}
+	client := &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, res.URL, nil)
+	if err != nil {
+		res.Outcome, res.Error = OutcomeFail, err.Error()
+		return res
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.Outcome, res.Error = OutcomeFail, err.Error()
+		res.Hint = "the edge is unreachable: check DNS, the firewall and that Caddy runs (netcup-kube dns debug-acme)"
+		return res
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	res.LatencyMS = time.Since(start).Milliseconds()
+	res.Status = resp.StatusCode
+	res.Location = resp.Header.Get("Location")
+	if resp.TLS != nil {
+		res.TLSVersion = tls.VersionName(resp.TLS.Version)
+		res.Certificate = dnscheck.DescribeCertificate(*resp.TLS, r.Host)
+	}
+	res.Outcome, res.Hint = classify(resp.StatusCode, strings.TrimSpace(string(body)))
+	if c := res.Certificate; c != nil && !c.Valid && !opts.Insecure {
+		res.Outcome = OutcomeFail
+		res.Hint = "invalid certificate: " + c.Error
+	}
+	return res
+}
+
+// edgeAddress adds the HTTPS port to an edge address without one
+func edgeAddress(edge string) string {
+	if _, _, err := net.SplitHostPort(edge); err == nil {
+		return edge
+	}
+	return net.JoinHostPort(strings.Trim(edge, "[]"), "443")
+}
+
+// classify maps a response to an outcome and, for failures, the hop of the
+// Caddy → Traefik → Service chain that most likely answered. Caddy answers
+// 502 with an empty body when Traefik is unreachable; Traefik's own error
+// pages are short plain text.
+func classify(status int, body string) (outcome, hint string) {
+	switch {
+	case status == http.StatusBadGateway && body == "":
+		return OutcomeFail, "Caddy cannot reach Traefik: check EDGE_UPSTREAM and the Traefik NodePort"
+	case status == http.StatusNotFound && body == "404 page not found":
+		return OutcomeFail, "no Traefik router matched: check the ingress class and host"
+	case status == http.StatusServiceUnavailable && (body == "no available server" || body == "Service Unavailable"):
+		return OutcomeFail, "the Service has no ready endpoints"
+	case status == http.StatusBadGateway || status == http.StatusGatewayTimeout:
+		return OutcomeFail, "Traefik cannot reach the backend pods"
+	case status >= 500:
+		return OutcomeFail, fmt.Sprintf("the backend answered %d", status)
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		// Routed; the app or a middleware requires authentication
+		return OutcomeOK, ""
+	case status >= 400:
+		return OutcomeWarn, fmt.Sprintf("the backend answered %d for this path", status)
+	}
+	return OutcomeOK, ""
+}
+
+// Failures counts the failed results
+func Failures(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Outcome == OutcomeFail {
+			n++
+		}
+	}
+	return n
+}
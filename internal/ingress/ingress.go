@@ -0,0 +1,174 @@
+// Package ingress lists the routes of a cluster's Ingress resources and tests
+// them end to end through the public edge: Caddy terminates TLS on the node
+// and proxies to the Traefik NodePort, which routes to the backend Service.
+package ingress
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kubectl runs kubectl against the cluster
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Route is one host and path of an Ingress and the backend it routes to
+type Route struct {
+	Namespace string `json:"namespace"`
+	Ingress   string `json:"ingress"`
+	Class     string `json:"class,omitempty"`
+	// Host is "*" for rules without a host and the default backend
+	Host     string `json:"host"`
+	Path     string `json:"path"`
+	PathType string `json:"pathType,omitempty"`
+	// Backend is service:port, or kind/name for resource backends
+	Backend string `json:"backend"`
+	// TLSSecret is the secret of a matching spec.tls entry; empty when the
+	// edge (Caddy) terminates TLS
+	TLSSecret string `json:"tlsSecret,omitempty"`
+}
+
+type backend struct {
+	Service *struct {
+		Name string `json:"name"`
+		Port struct {
+			Name   string `json:"name"`
+			Number int    `json:"number"`
+		} `json:"port"`
+	} `json:"service"`
+	Resource *struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"resource"`
+}
+
+func (b backend) String() string {
+	switch {
+	case b.Service != nil:
+		port := b.Service.Port.Name
+		if b.Service.Port.Number != 0 {
+			port = strconv.Itoa(b.Service.Port.Number)
+		}
+		return b.Service.Name + ":" + port
+	case b.Resource != nil:
+		return strings.ToLower(b.Resource.Kind) + "/" + b.Resource.Name
+	}
+	return "-"
+}
+
+type ingressList struct {
+	Items []struct {
+		Metadata struct {
+			Name        string            `json:"name"`
+			Namespace   string            `json:"namespace"`
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+		Spec struct {
+			IngressClassName string   `json:"ingressClassName"`
+			DefaultBackend   *backend `json:"defaultBackend"`
+			TLS              []struct {
+				Hosts      []string `json:"hosts"`
+				SecretName string   `json:"secretName"`
+			} `json:"tls"`
+			Rules []struct {
+				Host string `json:"host"`
+				HTTP *struct {
+					Paths []struct {
+						Path     string  `json:"path"`
+						PathType string  `json:"pathType"`
+						Backend  backend `json:"backend"`
+					} `json:"paths"`
+				} `json:"http"`
+			} `json:"rules"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// List returns the routes of every Ingress in the cluster
+func List(ctx context.Context, kube Kubectl) ([]Route, error) {
+	out, err := kube.Output(ctx, "get", "ingresses", "--all-namespaces", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	return Parse(out)
+}
+
+// Parse returns the routes of `kubectl get ingresses -o json` output, sorted
+// by host, path and Ingress
+func Parse(data []byte) ([]Route, error) {
+	var list ingressList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse ingresses: %w", err)
+	}
+	routes := []Route{}
+	for _, i := range list.Items {
+		class := i.Spec.IngressClassName
+		if class == "" {
+			// Set by charts that predate ingressClassName
+			class = i.Metadata.Annotations["kubernetes.io/ingress.class"]
+		}
+		base := Route{Namespace: i.Metadata.Namespace, Ingress: i.Metadata.Name, Class: class}
+		tlsSecret := func(host string) string {
+			for _, t := range i.Spec.TLS {
+				for _, h := range t.Hosts {
+					if h == host || (strings.HasPrefix(h, "*.") && wildcardMatches(h, host)) {
+						return t.SecretName
+					}
+				}
+			}
+			return ""
+		}
+		if b := i.Spec.DefaultBackend; b != nil {
+			r := base
+			r.Host, r.Path, r.Backend = "*", "/", b.String()
+			routes = append(routes, r)
+		}
+		for _, rule := range i.Spec.Rules {
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+			if rule.HTTP == nil {
+				continue
+			}
+			for _, p := range rule.HTTP.Paths {
+				r := base
+				r.Host, r.Path, r.PathType, r.Backend = host, p.Path, p.PathType, p.Backend.String()
+				if r.Path == "" {
+					r.Path = "/"
+				}
+				r.TLSSecret = tlsSecret(rule.Host)
+				routes = append(routes, r)
+			}
+		}
+	}
+	sort.SliceStable(routes, func(a, b int) bool {
+		ra, rb := routes[a], routes[b]
+		if ra.Host != rb.Host {
+			return ra.Host < rb.Host
+		}
+		if ra.Path != rb.Path {
+			return ra.Path < rb.Path
+		}
+		return ra.Namespace+"/"+ra.Ingress < rb.Namespace+"/"+rb.Ingress
+	})
+	return routes, nil
+}
+
+// wildcardMatches reports whether a *.domain pattern covers host (one label)
+func wildcardMatches(pattern, host string) bool {
+	_, parent, ok := strings.Cut(host, ".")
+	return ok && pattern == "*."+parent
+}
+
+// Testable reports whether the route can be requested: a concrete host and a
+// literal path
+func (r Route) Testable() bool {
+	return r.Host != "*" && !strings.HasPrefix(r.Host, "*.") && strings.HasPrefix(r.Path, "/") &&
+		!strings.ContainsAny(r.Path, "()[]{}*?+^$|\\")
+}
@@ -0,0 +1,194 @@
+package ingress
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	data := `{"items":[
+	  {"metadata":{"name":"openclaw","namespace":"openclaw"},"spec":{
+	    "ingressClassName":"traefik",
+	    "tls":[{"hosts":["*.example.com"],"secretName":"wildcard-tls"}],
+	    "rules":[{"host":"claw.example.com","http":{"paths":[
+	      {"path":"/api","pathType":"Prefix","backend":{"service":{"name":"openclaw","port":{"number":18789}}}},
+	      {"pathType":"ImplementationSpecific","backend":{"service":{"name":"openclaw-ui","port":{"name":"http"}}}}]}}]}},
+	  {"metadata":{"name":"legacy","namespace":"default","annotations":{"kubernetes.io/ingress.class":"traefik"}},"spec":{
+	    "defaultBackend":{"resource":{"apiGroup":"k8s.example.com","kind":"StorageBucket","name":"static"}}}}]}`
+	routes, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Route{
+		{Namespace: "default", Ingress: "legacy", Class: "traefik", Host: "*", Path: "/", Backend: "storagebucket/static"},
+		{Namespace: "openclaw", Ingress: "openclaw", Class: "traefik", Host: "claw.example.com", Path: "/", PathType: "ImplementationSpecific", Backend: "openclaw-ui:http", TLSSecret: "wildcard-tls"},
+		{Namespace: "openclaw", Ingress: "openclaw", Class: "traefik", Host: "claw.example.com", Path: "/api", PathType: "Prefix", Backend: "openclaw:18789", TLSSecret: "wildcard-tls"},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("routes = %+v", routes)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Errorf("route %d = %+v, want %+v", i, routes[i], want[i])
+		}
+	}
+	if routes[0].Testable() || !routes[2].Testable() {
+		t.Errorf("testable: wildcard %v, /api %v", routes[0].Testable(), routes[2].Testable())
+	}
+}
+
+func TestProbe(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "claw.example.com" {
+			t.Errorf("host = %q", r.Host)
+		}
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/login":
+			http.Redirect(w, r, "/auth", http.StatusFound)
+		case "/missing":
+			http.Error(w, "404 page not found", http.StatusNotFound)
+		case "/down":
+			http.Error(w, "no available server", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	edge := strings.TrimPrefix(srv.URL, "https://")
+	route := func(path string) Route { return Route{Host: "claw.example.com", Path: path} }
+
+	tests := []struct {
+		path     string
+		insecure bool
+		outcome  string
+		hint     string
+	}{
+		{"/", true, OutcomeOK, ""},
+		{"/login", true, OutcomeOK, ""},
+		{"/missing", true, OutcomeFail, "no Traefik router"},
+		{"/down", true, OutcomeFail, "no ready endpoints"},
+		// The test server's certificate is not trusted
+		{"/", false, OutcomeFail, "invalid certificate"},
+	}
+	for _, tt := range tests {
+		res := Probe(context.Background(), route(tt.path), Options{Edge: edge, Timeout: 5 * time.Second, Insecure: tt.insecure})
+		if res.Outcome != tt.outcome || !strings.Contains(res.Hint, tt.hint) {
+			t.Errorf("%s (insecure %v): outcome %q hint %q error %q", tt.path, tt.insecure, res.Outcome, res.Hint, res.Error)
+		}
+		if res.TLSVersion == "" || res.Certificate == nil {
+			t.Errorf("%s: no TLS details: %+v", tt.path, res)
+		}
+	}
+
+	if res := Probe(context.Background(), route("/login"), Options{Edge: edge, Timeout: 5 * time.Second, Insecure: true}); res.Location != "/auth" || res.Status != http.StatusFound {
+		t.Errorf("redirect = %d %q", res.Status, res.Location)
+	}
+	if res := Probe(context.Background(), Route{Host: "*.example.com", Path: "/"}, Options{}); res.Outcome != OutcomeSkipped {
+		t.Errorf("wildcard outcome = %q", res.Outcome)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		outcome string
+	}{
+		{http.StatusBadGateway, "", OutcomeFail},
+		{http.StatusBadGateway, "Bad Gateway", OutcomeFail},
+		{http.StatusUnauthorized, "", OutcomeOK},
+		{http.StatusNotFound, "Cannot GET /", OutcomeWarn},
+		{http.StatusMovedPermanently, "", OutcomeOK},
+	}
+	for _, tt := range tests {
+		if outcome, _ := classify(tt.status, tt.body); outcome != tt.outcome {
+			t.Errorf("classify(%d, %q) = %q, want %q", tt.status, tt.body, outcome, tt.outcome)
+		}
+	}
+}
+
+// fakeKubectl returns out for `get ingresses`
+type fakeKubectl struct {
+	out []byte
+	err error
+}
+
+func (f fakeKubectl) Output(_ context.Context, args ...string) ([]byte, error) {
+	if strings.Join(args, " ") != "get ingresses --all-namespaces -o json" {
+		return nil, errors.New("unexpected command: " + strings.Join(args, " "))
+	}
+	return f.out, f.err
+}
+
+func TestList(t *testing.T) {
+	routes, err := List(context.Background(), fakeKubectl{out: []byte(`{"items":[{"metadata":{"name":"web","namespace":"apps"},"spec":{"rules":[
+	  {"host":"web.example.com","http":{"paths":[{"backend":{"service":{"name":"web","port":{"number":80}}}}]}},
+	  {"host":"bare.example.com"}]}}]}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Host != "web.example.com" || routes[0].Path != "/" || routes[0].Backend != "web:80" {
+		t.Errorf("List() = %+v", routes)
+	}
+
+	if _, err := List(context.Background(), fakeKubectl{err: errors.New("forbidden")}); err == nil || !strings.Contains(err.Error(), "failed to list ingresses") {
+		t.Errorf("List() error = %v", err)
+	}
+	if _, err := List(context.Background(), fakeKubectl{out: []byte("{")}); err == nil || !strings.Contains(err.Error(), "failed to parse ingresses") {
+		t.Errorf("List(invalid) error = %v", err)
+	}
+	if got := (backend{}).String(); got != "-" {
+		t.Errorf("backend{}.String() = %q", got)
+	}
+}
+
+func TestTestRoutes(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+	edge := strings.TrimPrefix(srv.URL, "https://")
+
+	routes := []Route{
+		{Host: "web.example.com", Path: "/"},
+		{Host: "web.example.com", Path: "/broken"},
+		{Host: "*", Path: "/"},
+	}
+	results := Test(context.Background(), routes, Options{Edge: edge, Timeout: 5 * time.Second, Insecure: true})
+	if len(results) != 3 || results[0].Outcome != OutcomeOK || results[1].Outcome != OutcomeFail || results[2].Outcome != OutcomeSkipped {
+		t.Fatalf("Test() = %+v", results)
+	}
+	if n := Failures(results); n != 1 {
+		t.Errorf("Failures() = %d, want 1", n)
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	res := Probe(context.Background(), Route{Host: "web.example.com", Path: "/"}, Options{Edge: "127.0.0.1:1", Timeout: time.Second})
+	if res.Outcome != OutcomeFail || res.Error == "" || !strings.Contains(res.Hint, "unreachable") {
+		t.Errorf("Probe(unreachable) = %+v", res)
+	}
+	if res := Probe(context.Background(), Route{Host: "web example", Path: "/"}, Options{Timeout: time.Second}); res.Outcome != OutcomeFail || res.Error == "" {
+		t.Errorf("Probe(invalid host) = %+v", res)
+	}
+}
+
+func TestEdgeAddress(t *testing.T) {
+	for edge, want := range map[string]string{
+		"203.0.113.10":      "203.0.113.10:443",
+		"203.0.113.10:8443": "203.0.113.10:8443",
+		"[2001:db8::1]":     "[2001:db8::1]:443",
+		"edge.example.com":  "edge.example.com:443",
+	} {
+		if got := edgeAddress(edge); got != want {
+			t.Errorf("edgeAddress(%q) = %q, want %q", edge, got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package interrupt ties command execution to Ctrl-C: a context canceled by
+// SIGINT/SIGTERM, child processes that are stopped gracefully when it is,
+// and a context for cleanups that must still run afterwards.
+package interrupt
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// ExitCode is the exit status of an interrupted command (128+SIGINT), as
+	// shells report it
+	ExitCode = 130
+
+	// GracePeriod is how long a canceled child process gets to clean up after
+	// SIGTERM before it is killed
+	GracePeriod = 10 * time.Second
+)
+
+// Context returns a context canceled on the first SIGINT or SIGTERM. The
+// signal handler is removed then, so a second Ctrl-C terminates the process
+// immediately should a cleanup hang. stop releases the handler early.
+func Context(parent context.Context) (ctx context.Context, stop context.CancelFunc) {
+	ctx, stop = signal.NotifyContext(parent, os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	return ctx, stop
+}
+
+// Command is exec.CommandContext with a graceful cancel: the process gets
+// SIGTERM when ctx is done, so it can remove temp files and undo partial
+// changes, and is killed if it is still running after GracePeriod.
+func Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = GracePeriod
+	return cmd
+}
+
+// CleanupContext returns a context for undoing partial changes after ctx was
+// canceled: it keeps the values of ctx (trace spans) but not its
+// cancellation, and is bounded by timeout.
+func CleanupContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithTimeout(context.WithoutCancel(ctx), timeout)
+}
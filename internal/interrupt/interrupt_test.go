@@ -0,0 +1,78 @@
+package interrupt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCommandTerminatesGracefully(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "cleaned-up")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	cmd := Command(ctx, "sh", "-c", `sleep 5 & trap 'kill $!; touch "$0"; exit 143' TERM; wait $!`, marker)
+	start := time.Now()
+	err := cmd.Run()
+	if err == nil || ctx.Err() == nil {
+		t.Fatalf("Run() error = %v, want the canceled command to fail", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Run() returned after %s, want prompt termination", elapsed)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("the TERM trap did not run: %v", err)
+	}
+}
+
+func TestCleanupContext(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ctx, stop := CleanupContext(parent, time.Minute)
+	defer stop()
+	if ctx.Err() != nil {
+		t.Fatalf("CleanupContext() of a canceled context is done: %v", ctx.Err())
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		t.Error("CleanupContext() has no deadline")
+	}
+
+	ctx, stop = CleanupContext(parent, time.Millisecond)
+	defer stop()
+	<-ctx.Done()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("CleanupContext() error = %v, want deadline exceeded", ctx.Err())
+	}
+}
+
+func TestContextCanceledBySignal(t *testing.T) {
+	ctx, stop := Context(context.Background())
+	defer stop()
+	if err := syscall.Kill(os.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Context() was not canceled by SIGTERM")
+	}
+
+	ctx, stop = Context(context.Background())
+	stop()
+	if ctx.Err() == nil {
+		t.Error("Context() is not done after stop")
+	}
+}
+
+func TestCleanupContextWithoutParent(t *testing.T) {
+	ctx, stop := CleanupContext(nil, time.Minute)
+	defer stop()
+	if ctx.Err() != nil {
+		t.Errorf("CleanupContext(nil) error = %v", ctx.Err())
+	}
+}
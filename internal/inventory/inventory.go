@@ -0,0 +1,288 @@
+// Package inventory loads cluster inventory files describing the server and
+// worker nodes of a multi-node netcup-kube cluster.
+//
+// Example:
+//
+//	vars:
+//	  BASE_DOMAIN: example.com
+//	  PRIVATE_IFACE: eth1
+//	servers:
+//	  - name: mgmt
+//	    host: 203.0.113.10
+//	    vars:
+//	      NODE_IP: 10.10.0.1
+//	workers:
+//	  - name: worker-1
+//	    host: 203.0.113.11
+//	    user: ops
+//	    vars:
+//	      NODE_IP: 10.10.0.2
+package inventory
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/yamlsubset"
+)
+
+const (
+	// RoleServer marks a k3s server (control-plane) node
+	RoleServer = "server"
+
+	// RoleWorker marks a k3s agent (worker) node
+	RoleWorker = "worker"
+
+	// DefaultAPIPort is the k3s API server port used to build SERVER_URL
+	DefaultAPIPort = "6443"
+)
+
+// Node describes a single host in the inventory
+type Node struct {
+	Name string
+	Host string
+	// User is the SSH sudo user; empty means "use the caller's default"
+	User string
+	Role string
+	Vars map[string]string
+}
+
+// Inventory describes all nodes of a cluster plus cluster-wide vars
+type Inventory struct {
+	Vars    map[string]string
+	Servers []Node
+	Workers []Node
+}
+
+// Load reads and parses an inventory file
+func Load(path string) (*Inventory, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inventory: %w", err)
+	}
+	inv, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inventory %s: %w", path, err)
+	}
+	return inv, nil
+}
+
+// Parse parses and validates inventory YAML
+func Parse(data []byte) (*Inventory, error) {
+	raw, err := yamlsubset.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("top level must be a mapping")
+	}
+
+	inv := &Inventory{Vars: map[string]string{}}
+	for key, value := range root {
+		switch key {
+		case "vars":
+			vars, err := decodeVars("vars", value)
+			if err != nil {
+				return nil, err
+			}
+			inv.Vars = vars
+		case "servers":
+			nodes, err := decodeNodes("servers", RoleServer, value)
+			if err != nil {
+				return nil, err
+			}
+			inv.Servers = nodes
+		case "workers":
+			nodes, err := decodeNodes("workers", RoleWorker, value)
+			if err != nil {
+				return nil, err
+			}
+			inv.Workers = nodes
+		default:
+			return nil, fmt.Errorf("unknown top-level key %q (expected vars, servers, workers)", key)
+		}
+	}
+
+	if err := inv.Validate(); err != nil {
+		return nil, err
+	}
+	return inv, nil
+}
+
+// Validate checks that the inventory is usable
+func (inv *Inventory) Validate() error {
+	if len(inv.Servers) == 0 {
+		return fmt.Errorf("at least one server node is required")
+	}
+	seen := map[string]bool{}
+	for _, node := range inv.Nodes() {
+		if node.Name == "" {
+			return fmt.Errorf("%s node with host %q is missing a name", node.Role, node.Host)
+		}
+		if node.Host == "" {
+			return fmt.Errorf("node %q is missing a host", node.Name)
+		}
+		if seen[node.Name] {
+			return fmt.Errorf("duplicate node name %q", node.Name)
+		}
+		seen[node.Name] = true
+	}
+	return nil
+}
+
+// Nodes returns all nodes, servers first, in file order
+func (inv *Inventory) Nodes() []Node {
+	nodes := make([]Node, 0, len(inv.Servers)+len(inv.Workers))
+	nodes = append(nodes, inv.Servers...)
+	return append(nodes, inv.Workers...)
+}
+
+// Node returns the node with the given name
+func (inv *Inventory) Node(name string) (Node, error) {
+	for _, node := range inv.Nodes() {
+		if node.Name == name {
+			return node, nil
+		}
+	}
+	return Node{}, fmt.Errorf("node %q not found in inventory (available: %s)", name, strings.Join(inv.NodeNames(), ", "))
+}
+
+// NodeNames returns the names of all nodes in file order
+func (inv *Inventory) NodeNames() []string {
+	nodes := inv.Nodes()
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Name
+	}
+	return names
+}
+
+// Primary returns the first server node, which acts as the management node
+func (inv *Inventory) Primary() Node {
+	return inv.Servers[0]
+}
+
+// Env returns the effective environment for a node: cluster-wide vars
+// overridden by the node's own vars.
+func (inv *Inventory) Env(node Node) map[string]string {
+	env := make(map[string]string, len(inv.Vars)+len(node.Vars))
+	for k, v := range inv.Vars {
+		env[k] = v
+	}
+	for k, v := range node.Vars {
+		env[k] = v
+	}
+	return env
+}
+
+// ServerURL returns the k3s API URL workers should join, using the primary
+// server's NODE_IP (typically its vLAN address) and falling back to its host.
+func (inv *Inventory) ServerURL() string {
+	primary := inv.Primary()
+	addr := primary.Host
+	if ip := strings.TrimSpace(inv.Env(primary)["NODE_IP"]); ip != "" {
+		addr = ip
+	}
+	if strings.Contains(addr, ":") && !strings.HasPrefix(addr, "[") {
+		addr = "[" + addr + "]"
+	}
+	return fmt.Sprintf("https://%s:%s", addr, DefaultAPIPort)
+}
+
+func decodeNodes(field, role string, value any) ([]Node, error) {
+	if s, ok := value.(string); ok && s == "" {
+		return nil, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of nodes", field)
+	}
+
+	nodes := make([]Node, 0, len(items))
+	for i, item := range items {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] must be a mapping", field, i)
+		}
+		node := Node{Role: role, Vars: map[string]string{}}
+		// Iterate in sorted order so error messages are deterministic
+		keys := make([]string, 0, len(entry))
+		for key := range entry {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldPath := fmt.Sprintf("%s[%d].%s", field, i, key)
+			switch key {
+			case "name", "host", "user":
+				s, ok := entry[key].(string)
+				if !ok {
+					return nil, fmt.Errorf("%s must be a string", fieldPath)
+				}
+				s = strings.TrimSpace(s)
+				switch key {
+				case "name":
+					node.Name = s
+				case "host":
+					node.Host = s
+				case "user":
+					node.User = s
+				}
+			case "vars":
+				vars, err := decodeVars(fieldPath, entry[key])
+				if err != nil {
+					return nil, err
+				}
+				node.Vars = vars
+			default:
+				return nil, fmt.Errorf("unknown key %s (expected name, host, user, vars)", fieldPath)
+			}
+		}
+		if node.Name == "" {
+			node.Name = node.Host
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func decodeVars(field string, value any) (map[string]string, error) {
+	if s, ok := value.(string); ok && s == "" {
+		return map[string]string{}, nil
+	}
+	entries, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a mapping of KEY: value", field)
+	}
+	vars := make(map[string]string, len(entries))
+	for key, raw := range entries {
+		if !isValidVarName(key) {
+			return nil, fmt.Errorf("%s: invalid variable name %q", field, key)
+		}
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s.%s must be a scalar", field, key)
+		}
+		vars[key] = s
+	}
+	return vars, nil
+}
+
+// isValidVarName reports whether key is a valid shell environment variable name
+func isValidVarName(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case r == '_', r >= 'A' && r <= 'Z', r >= 'a' && r <= 'z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
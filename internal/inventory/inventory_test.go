@@ -0,0 +1,120 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sampleInventory = `vars:
+  BASE_DOMAIN: example.com
+  PRIVATE_IFACE: eth1
+servers:
+  - name: mgmt
+    host: 203.0.113.10
+    vars:
+      NODE_IP: 10.10.0.1
+workers:
+  - name: worker-1
+    host: 203.0.113.11
+    user: ops
+    vars:
+      NODE_IP: 10.10.0.2
+      PRIVATE_IFACE: eth2
+  - host: 203.0.113.12
+`
+
+func TestParse(t *testing.T) {
+	inv, err := Parse([]byte(sampleInventory))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	if got := inv.NodeNames(); !reflect.DeepEqual(got, []string{"mgmt", "worker-1", "203.0.113.12"}) {
+		t.Errorf("NodeNames() = %v", got)
+	}
+	if inv.Primary().Name != "mgmt" || inv.Primary().Role != RoleServer {
+		t.Errorf("Primary() = %+v", inv.Primary())
+	}
+
+	w1, err := inv.Node("worker-1")
+	if err != nil {
+		t.Fatalf("Node(worker-1) error: %v", err)
+	}
+	if w1.User != "ops" || w1.Role != RoleWorker {
+		t.Errorf("worker-1 = %+v", w1)
+	}
+
+	env := inv.Env(w1)
+	want := map[string]string{"BASE_DOMAIN": "example.com", "PRIVATE_IFACE": "eth2", "NODE_IP": "10.10.0.2"}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("Env(worker-1) = %v, want %v", env, want)
+	}
+
+	if _, err := inv.Node("missing"); err == nil || !strings.Contains(err.Error(), "available: mgmt, worker-1") {
+		t.Errorf("Node(missing) error = %v", err)
+	}
+}
+
+func TestServerURL(t *testing.T) {
+	inv, err := Parse([]byte(sampleInventory))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := inv.ServerURL(); got != "https://10.10.0.1:6443" {
+		t.Errorf("ServerURL() = %q", got)
+	}
+
+	inv, err = Parse([]byte("servers:\n  - name: a\n    host: 2001:db8::1\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := inv.ServerURL(); got != "https://[2001:db8::1]:6443" {
+		t.Errorf("ServerURL() IPv6 = %q", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"no servers", "workers:\n  - host: a\n", "at least one server"},
+		{"missing host", "servers:\n  - name: a\n", "missing a host"},
+		{"duplicate name", "servers:\n  - name: a\n    host: x\nworkers:\n  - name: a\n    host: y\n", "duplicate node name"},
+		{"unknown top key", "nodes: []\nservers:\n  - host: x\n", "unknown top-level key"},
+		{"unknown node key", "servers:\n  - host: x\n    port: 22\n", "unknown key servers[0].port"},
+		{"bad var name", "vars:\n  BAD-NAME: x\nservers:\n  - host: x\n", "invalid variable name"},
+		{"servers not list", "servers: x\n", "must be a list"},
+		{"nested var", "vars:\n  A:\n    B: c\nservers:\n  - host: x\n", "must be a scalar"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inventory.yaml")
+	if err := os.WriteFile(path, []byte(sampleInventory), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	inv, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(inv.Workers) != 2 {
+		t.Errorf("Workers = %d, want 2", len(inv.Workers))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load(missing) expected error")
+	}
+}
@@ -0,0 +1,131 @@
+package k3s
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/probe"
+)
+
+// rotateCertsScript rotates the certificates of a k3s server: k3s must be
+// stopped while `k3s certificate rotate` runs, and regenerates the rotated
+// certificates (including the admin kubeconfig) when it starts again.
+const rotateCertsScript = `set -euo pipefail
+sudo systemctl stop k3s
+sudo k3s certificate rotate
+sudo systemctl start k3s
+
+for _ in $(seq 1 60); do
+  if sudo k3s kubectl get --raw=/readyz >/dev/null 2>&1; then
+    exit 0
+  fi
+  sleep 2
+done
+echo "k3s API did not become ready after the certificate rotation" >&2
+exit 1
+`
+
+// restartAgentScript restarts the k3s agent, which renews its client
+// certificates from the server on start
+const restartAgentScript = `set -euo pipefail
+sudo systemctl restart k3s-agent
+`
+
+// CertRotationPlan returns the certificate rotation sequence for nodes:
+// every server is rotated first, then the cached kubeconfig is refreshed,
+// the servers are awaited Ready and each worker's agent is restarted.
+// Nodes without a name (the single MGMT_HOST case) wait for all nodes.
+func CertRotationPlan(nodes []inventory.Node) []Step {
+	var servers, workers []inventory.Node
+	for _, node := range nodes {
+		if node.Role == inventory.RoleWorker {
+			workers = append(workers, node)
+		} else {
+			servers = append(servers, node)
+		}
+	}
+
+	var steps []Step
+	for _, node := range servers {
+		steps = append(steps, Step{StepRotateCerts, node})
+	}
+	steps = append(steps, Step{Kind: StepRefreshKubeconfig})
+	for _, node := range servers {
+		steps = append(steps, Step{StepWait, node})
+	}
+	for _, node := range workers {
+		steps = append(steps, Step{StepRestartAgent, node}, Step{StepWait, node})
+	}
+	return steps
+}
+
+// CertExpiry is the validity of one certificate of the cluster
+type CertExpiry struct {
+	Name     string    `json:"name"`
+	Subject  string    `json:"subject"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// ClusterCerts returns the client certificate of the kubeconfig and the
+// serving certificate the API server presents, read from a TLS handshake
+func ClusterCerts(ctx context.Context, kubeconfig string, timeout time.Duration) ([]CertExpiry, error) {
+	target, err := probe.LoadKubeconfig(kubeconfig, "")
+	if err != nil {
+		return nil, err
+	}
+	var certs []CertExpiry
+	if len(target.ClientCert) > 0 {
+		block, _ := pem.Decode(target.ClientCert)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM client certificate in %s", kubeconfig)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate in %s: %w", kubeconfig, err)
+		}
+		certs = append(certs, CertExpiry{Name: "kubeconfig client", Subject: cert.Subject.CommonName, NotAfter: cert.NotAfter})
+	}
+
+	addr, err := target.Addr()
+	if err != nil {
+		return certs, err
+	}
+	config, err := target.TLSConfig()
+	if err != nil {
+		return certs, err
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: timeout}, Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return certs, fmt.Errorf("TLS handshake with %s failed: %w", addr, err)
+	}
+	state := conn.(*tls.Conn).ConnectionState()
+	_ = conn.Close()
+	if len(state.PeerCertificates) == 0 {
+		return certs, fmt.Errorf("%s presented no certificate", addr)
+	}
+	serving := state.PeerCertificates[0]
+	certs = append(certs, CertExpiry{Name: "API server", Subject: serving.Subject.CommonName, NotAfter: serving.NotAfter})
+	return certs, nil
+}
+
+// CheckValidity fails when a certificate expires within min of now
+func CheckValidity(certs []CertExpiry, now time.Time, min time.Duration) error {
+	var short []string
+	for _, c := range certs {
+		if c.NotAfter.Before(now.Add(min)) {
+			short = append(short, fmt.Sprintf("%s (%s) expires %s", c.Name, c.Subject, c.NotAfter.UTC().Format("2006-01-02")))
+		}
+	}
+	if len(short) > 0 {
+		return fmt.Errorf("certificates valid for less than %s: %s", min, strings.Join(short, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package k3s
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+)
+
+func TestCertRotationPlan(t *testing.T) {
+	got := describe(CertRotationPlan(testNodes()))
+	want := []string{
+		"rotate-certs mgmt", "refresh-kubeconfig ", "wait mgmt",
+		"restart-agent worker-1", "wait worker-1", "restart-agent worker-2", "wait worker-2",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("CertRotationPlan() = %v, want %v", got, want)
+	}
+
+	single := CertRotationPlan([]inventory.Node{{Host: "10.0.0.1", Role: inventory.RoleServer}})
+	if d := single[2].Describe(""); d != "wait for all nodes to be Ready" {
+		t.Errorf("Describe() = %q", d)
+	}
+}
+
+func TestExecuteCertRotation(t *testing.T) {
+	kube := &fakeKube{outputs: map[string][]string{
+		"get nodes": {"mgmt\tFalse\n", "mgmt\tTrue\n"},
+	}}
+	var calls []string
+	u := &Upgrader{
+		Kube: kube,
+		RunScript: func(node inventory.Node, script string, args []string) error {
+			calls = append(calls, "script "+node.Host)
+			return nil
+		},
+		RefreshKubeconfig: func(context.Context) error {
+			calls = append(calls, "refresh")
+			return nil
+		},
+		Out:          io.Discard,
+		ReadyTimeout: time.Second,
+		PollInterval: time.Millisecond,
+	}
+	steps := CertRotationPlan([]inventory.Node{{Host: "10.0.0.1", Role: inventory.RoleServer}})
+	if err := u.Execute(context.Background(), steps, ""); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if got := strings.Join(calls, ","); got != "script 10.0.0.1,refresh" {
+		t.Errorf("calls = %s", got)
+	}
+	if len(kube.calls) != 2 {
+		t.Errorf("kubectl calls = %v, want two polls", kube.calls)
+	}
+}
+
+func TestCheckValidity(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	certs := []CertExpiry{
+		{Name: "kubeconfig client", Subject: "system:admin", NotAfter: now.AddDate(1, 0, 0)},
+		{Name: "API server", Subject: "k3s", NotAfter: now.AddDate(0, 0, 20)},
+	}
+	if err := CheckValidity(certs[:1], now, 300*24*time.Hour); err != nil {
+		t.Errorf("CheckValidity() error: %v", err)
+	}
+	err := CheckValidity(certs, now, 300*24*time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "API server (k3s) expires 2026-03-21") {
+		t.Errorf("CheckValidity() error = %v", err)
+	}
+}
+
+func TestClusterCerts(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	// The handshake is closed without a request
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	kubeconfig := filepath.Join(t.TempDir(), "k3s.yaml")
+	data := `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: ` + base64.StdEncoding.EncodeToString(ca) + `
+    server: ` + srv.URL + `
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+kind: Config
+users:
+- name: default
+  user:
+    token: secret
+`
+	if err := os.WriteFile(kubeconfig, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	certs, err := ClusterCerts(context.Background(), kubeconfig, time.Second)
+	if err != nil {
+		t.Fatalf("ClusterCerts() error: %v", err)
+	}
+	if len(certs) != 1 || certs[0].Name != "API server" || !certs[0].NotAfter.Equal(srv.Certificate().NotAfter) {
+		t.Errorf("ClusterCerts() = %+v", certs)
+	}
+}
@@ -0,0 +1,110 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultPlanNamespace is where system-upgrade-controller watches for Plans
+	DefaultPlanNamespace = "system-upgrade"
+
+	// UpgradeImage is the k3s upgrade image run by system-upgrade-controller
+	UpgradeImage = "rancher/k3s-upgrade"
+
+	serverPlanName = "k3s-server"
+	agentPlanName  = "k3s-agent"
+)
+
+// PlanOptions configures the generated system-upgrade-controller Plans
+type PlanOptions struct {
+	Target    Target
+	Namespace string
+	// ChannelServer resolves Target.Channel; the controller polls it
+	ChannelServer string
+	// Concurrency is the number of agents upgraded at once (servers: always 1)
+	Concurrency int
+	// Drain drains agents before upgrading them instead of only cordoning
+	Drain bool
+}
+
+// RenderUpgradePlans renders a server Plan and an agent Plan for
+// system-upgrade-controller. Agents wait for the server Plan to finish via
+// the k3s-upgrade "prepare" step, matching the ordering of `k3s upgrade`.
+func RenderUpgradePlans(opts PlanOptions) (string, error) {
+	if err := opts.Target.Validate(); err != nil {
+		return "", err
+	}
+	if opts.Namespace == "" {
+		opts.Namespace = DefaultPlanNamespace
+	}
+	if opts.ChannelServer == "" {
+		opts.ChannelServer = DefaultChannelServer
+	}
+	if opts.Concurrency < 1 {
+		return "", fmt.Errorf("concurrency must be at least 1 (got %d)", opts.Concurrency)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by netcup-kube k3s plan; apply with kubectl or commit to your GitOps repo.\n")
+	writePlanHeader(&b, serverPlanName, opts.Namespace)
+	b.WriteString("  concurrency: 1\n")
+	b.WriteString("  cordon: true\n")
+	b.WriteString("  nodeSelector:\n")
+	b.WriteString("    matchExpressions:\n")
+	b.WriteString("      - key: node-role.kubernetes.io/control-plane\n")
+	b.WriteString("        operator: In\n")
+	b.WriteString("        values:\n")
+	b.WriteString("          - \"true\"\n")
+	b.WriteString("  serviceAccountName: system-upgrade\n")
+	b.WriteString("  upgrade:\n")
+	fmt.Fprintf(&b, "    image: %s\n", UpgradeImage)
+	writePlanTarget(&b, opts)
+
+	b.WriteString("---\n")
+	writePlanHeader(&b, agentPlanName, opts.Namespace)
+	fmt.Fprintf(&b, "  concurrency: %d\n", opts.Concurrency)
+	if opts.Drain {
+		b.WriteString("  drain:\n")
+		b.WriteString("    force: true\n")
+		b.WriteString("    ignoreDaemonSets: true\n")
+		b.WriteString("    deleteEmptydirData: true\n")
+		b.WriteString("    skipWaitForDeleteTimeout: 60\n")
+	} else {
+		b.WriteString("  cordon: true\n")
+	}
+	b.WriteString("  nodeSelector:\n")
+	b.WriteString("    matchExpressions:\n")
+	b.WriteString("      - key: node-role.kubernetes.io/control-plane\n")
+	b.WriteString("        operator: DoesNotExist\n")
+	b.WriteString("  prepare:\n")
+	fmt.Fprintf(&b, "    image: %s\n", UpgradeImage)
+	b.WriteString("    args:\n")
+	b.WriteString("      - prepare\n")
+	fmt.Fprintf(&b, "      - %s\n", serverPlanName)
+	b.WriteString("  serviceAccountName: system-upgrade\n")
+	b.WriteString("  upgrade:\n")
+	fmt.Fprintf(&b, "    image: %s\n", UpgradeImage)
+	writePlanTarget(&b, opts)
+	return b.String(), nil
+}
+
+func writePlanHeader(b *strings.Builder, name, namespace string) {
+	b.WriteString("apiVersion: upgrade.cattle.io/v1\n")
+	b.WriteString("kind: Plan\n")
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(b, "  name: %s\n", name)
+	fmt.Fprintf(b, "  namespace: %s\n", namespace)
+	b.WriteString("  labels:\n")
+	b.WriteString("    app.kubernetes.io/managed-by: netcup-kube\n")
+	b.WriteString("spec:\n")
+}
+
+func writePlanTarget(b *strings.Builder, opts PlanOptions) {
+	if opts.Target.Version != "" {
+		// The controller maps "+" to "-" when deriving the image tag
+		fmt.Fprintf(b, "  version: %s\n", opts.Target.Version)
+		return
+	}
+	fmt.Fprintf(b, "  channel: %s/%s\n", strings.TrimRight(opts.ChannelServer, "/"), opts.Target.Channel)
+}
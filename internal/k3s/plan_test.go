@@ -0,0 +1,55 @@
+package k3s
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderUpgradePlansVersion(t *testing.T) {
+	out, err := RenderUpgradePlans(PlanOptions{Target: Target{Version: "v1.31.4+k3s1"}, Concurrency: 2})
+	if err != nil {
+		t.Fatalf("RenderUpgradePlans() error: %v", err)
+	}
+	docs := strings.Split(out, "---\n")
+	if len(docs) != 2 {
+		t.Fatalf("got %d documents, want 2:\n%s", len(docs), out)
+	}
+	server, agent := docs[0], docs[1]
+	for _, want := range []string{"name: k3s-server", "namespace: system-upgrade", "concurrency: 1", "operator: In", "version: v1.31.4+k3s1"} {
+		if !strings.Contains(server, want) {
+			t.Errorf("server plan missing %q:\n%s", want, server)
+		}
+	}
+	for _, want := range []string{"name: k3s-agent", "concurrency: 2", "cordon: true", "operator: DoesNotExist", "- k3s-server", "version: v1.31.4+k3s1"} {
+		if !strings.Contains(agent, want) {
+			t.Errorf("agent plan missing %q:\n%s", want, agent)
+		}
+	}
+	if strings.Contains(out, "drain:") || strings.Contains(out, "channel:") {
+		t.Errorf("unexpected drain/channel:\n%s", out)
+	}
+}
+
+func TestRenderUpgradePlansChannelDrain(t *testing.T) {
+	out, err := RenderUpgradePlans(PlanOptions{Target: Target{Channel: "stable"}, Namespace: "upgrades", Concurrency: 1, Drain: true})
+	if err != nil {
+		t.Fatalf("RenderUpgradePlans() error: %v", err)
+	}
+	for _, want := range []string{"namespace: upgrades", "channel: " + DefaultChannelServer + "/stable", "drain:\n    force: true"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("plans missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "version:") {
+		t.Errorf("unexpected version:\n%s", out)
+	}
+}
+
+func TestRenderUpgradePlansInvalid(t *testing.T) {
+	if _, err := RenderUpgradePlans(PlanOptions{Target: Target{Version: "latest"}, Concurrency: 1}); err == nil {
+		t.Error("expected error for invalid version")
+	}
+	if _, err := RenderUpgradePlans(PlanOptions{Target: Target{Channel: "stable"}}); err == nil {
+		t.Error("expected error for zero concurrency")
+	}
+}
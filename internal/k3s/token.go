@@ -0,0 +1,162 @@
+package k3s
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+)
+
+const (
+	// AgentTokenDescription marks the bootstrap tokens netcup-kube issues
+	// as agent join tokens
+	AgentTokenDescription = "netcup-kube agent join token"
+
+	// AgentTokenFile holds the current agent join token on each server;
+	// `pair` prints it instead of the server token when it exists
+	AgentTokenFile = "/var/lib/rancher/k3s/server/netcup-kube-agent-token"
+
+	// bootstrapTokenType is the Secret type of Kubernetes bootstrap tokens
+	bootstrapTokenType = "bootstrap.kubernetes.io/token"
+)
+
+// agentTokenPattern matches the tokens `k3s token create` prints: the CA
+// hash of the cluster and a bootstrap token <id>.<secret>
+var agentTokenPattern = regexp.MustCompile(`^K10[0-9a-f]{64}::([a-z0-9]{6})\.[a-z0-9]{16}$`)
+
+// CreateAgentTokenCommand issues a non-expiring agent join token on a
+// server; agents present their token on every start, so it must outlive them
+const CreateAgentTokenCommand = `sudo -n k3s token create --ttl 0 --description '` + AgentTokenDescription + `'`
+
+// ParseAgentToken returns the token and its id from the output of
+// CreateAgentTokenCommand
+func ParseAgentToken(out []byte) (token, id string, err error) {
+	token = strings.TrimSpace(string(out))
+	m := agentTokenPattern.FindStringSubmatch(token)
+	if m == nil {
+		return "", "", fmt.Errorf("unexpected output of k3s token create (k3s v1.26 or newer is required)")
+	}
+	return token, m[1], nil
+}
+
+// BootstrapToken is a bootstrap token of the cluster, without its secret
+type BootstrapToken struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description"`
+	Expiration  *time.Time `json:"expiration,omitempty"`
+}
+
+// AgentTokens returns the agent join tokens issued by netcup-kube, oldest
+// id first
+func AgentTokens(ctx context.Context, kube Kubectl) ([]BootstrapToken, error) {
+	out, err := kube.Output(ctx, "-n", "kube-system", "get", "secrets",
+		"--field-selector", "type="+bootstrapTokenType, "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bootstrap tokens: %w", err)
+	}
+	return parseAgentTokens(out)
+}
+
+func parseAgentTokens(payload []byte) ([]BootstrapToken, error) {
+	var list struct {
+		Items []struct {
+			Data map[string]string `json:"data"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(payload, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse bootstrap tokens: %w", err)
+	}
+	var tokens []BootstrapToken
+	for _, item := range list.Items {
+		field := func(key string) string {
+			value, _ := base64.StdEncoding.DecodeString(item.Data[key])
+			return string(value)
+		}
+		t := BootstrapToken{ID: field("token-id"), Description: field("description")}
+		if t.ID == "" || t.Description != AgentTokenDescription {
+			continue
+		}
+		if exp, err := time.Parse(time.RFC3339, field("expiration")); err == nil {
+			t.Expiration = &exp
+		}
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens, nil
+}
+
+// ExpireToken deletes the bootstrap token id, or lets it expire at the end
+// of grace; the API server rejects expired tokens
+func ExpireToken(ctx context.Context, kube Kubectl, id string, now time.Time, grace time.Duration) error {
+	secret := "bootstrap-token-" + id
+	if grace <= 0 {
+		if _, err := kube.Output(ctx, "-n", "kube-system", "delete", "secret", secret); err != nil {
+			return fmt.Errorf("failed to delete token %s: %w", id, err)
+		}
+		return nil
+	}
+	patch := fmt.Sprintf(`{"stringData":{"expiration":%q}}`, now.Add(grace).UTC().Format(time.RFC3339))
+	if _, err := kube.Output(ctx, "-n", "kube-system", "patch", "secret", secret, "--type", "merge", "-p", patch); err != nil {
+		return fmt.Errorf("failed to expire token %s: %w", id, err)
+	}
+	return nil
+}
+
+// TokenRotationPlan returns the agent token rotation sequence: the new token
+// is stored on every server first, then each worker is switched to it and
+// awaited Ready, one at a time.
+func TokenRotationPlan(nodes []inventory.Node) []Step {
+	var steps []Step
+	for _, node := range nodes {
+		if node.Role != inventory.RoleWorker {
+			steps = append(steps, Step{StepStoreToken, node})
+		}
+	}
+	for _, node := range nodes {
+		if node.Role == inventory.RoleWorker {
+			steps = append(steps, Step{StepSwitchToken, node}, Step{StepWait, node})
+		}
+	}
+	return steps
+}
+
+// storeTokenScript writes ${token} to AgentTokenFile, readable by root only
+const storeTokenScript = `set -euo pipefail
+sudo install -m 600 /dev/null "` + AgentTokenFile + `"
+printf '%s\n' "${token}" | sudo tee "` + AgentTokenFile + `" >/dev/null
+`
+
+// switchTokenScript sets the token of the agent config and restarts the
+// agent, which presents it to the server on start; the previous config is
+// restored when the agent does not come back
+const switchTokenScript = `set -euo pipefail
+config="` + ConfigFile + `"
+sudo cp -p "${config}" "${config}.pre-token-rotate"
+if sudo grep -q '^token:' "${config}"; then
+  sudo sed -i "s|^token:.*|token: \"${token}\"|" "${config}"
+else
+  printf 'token: "%s"\n' "${token}" | sudo tee -a "${config}" >/dev/null
+fi
+if ! sudo systemctl restart k3s-agent; then
+  echo "k3s-agent did not start with the new token; restoring ${config}" >&2
+  sudo mv "${config}.pre-token-rotate" "${config}"
+  sudo systemctl restart k3s-agent || true
+  exit 1
+fi
+sudo rm -f "${config}.pre-token-rotate"
+`
+
+// withToken prefixes script with the token assignment, so the token is
+// passed on stdin rather than on the ssh command line
+func withToken(script, token string) (string, error) {
+	if !agentTokenPattern.MatchString(token) {
+		return "", fmt.Errorf("no valid agent token to install")
+	}
+	return "token='" + token + "'\n" + script, nil
+}
@@ -0,0 +1,104 @@
+package k3s
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+)
+
+const testAgentToken = "K10" + "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef" + "::abc123.0123456789abcdef"
+
+type recordingKube struct {
+	calls []string
+	out   []byte
+}
+
+func (k *recordingKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	k.calls = append(k.calls, strings.Join(args, " "))
+	return k.out, nil
+}
+
+func TestParseAgentToken(t *testing.T) {
+	token, id, err := ParseAgentToken([]byte(testAgentToken + "\n"))
+	if err != nil || token != testAgentToken || id != "abc123" {
+		t.Errorf("ParseAgentToken() = %q, %q, %v", token, id, err)
+	}
+	if _, _, err := ParseAgentToken([]byte("Incorrect Usage: flag provided but not defined: -ttl\n")); err == nil {
+		t.Error("ParseAgentToken() expected error for unexpected output")
+	}
+}
+
+func TestAgentTokens(t *testing.T) {
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	kube := &recordingKube{out: []byte(`{"items":[
+		{"data":{"token-id":"` + b64("zzz999") + `","description":"` + b64(AgentTokenDescription) + `"}},
+		{"data":{"token-id":"abc123","description":"` + b64("k3s bootstrap") + `"}},
+		{"data":{"token-id":"` + b64("aaa111") + `","description":"` + b64(AgentTokenDescription) + `","expiration":"` + b64("2026-03-02T00:00:00Z") + `"}}
+	]}`)}
+	tokens, err := AgentTokens(context.Background(), kube)
+	if err != nil {
+		t.Fatalf("AgentTokens() error: %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].ID != "aaa111" || tokens[0].Expiration == nil || tokens[1].ID != "zzz999" || tokens[1].Expiration != nil {
+		t.Errorf("AgentTokens() = %+v", tokens)
+	}
+}
+
+func TestExpireToken(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	kube := &recordingKube{}
+	if err := ExpireToken(context.Background(), kube, "abc123", now, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExpireToken(context.Background(), kube, "abc123", now, 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"-n kube-system delete secret bootstrap-token-abc123",
+		`-n kube-system patch secret bootstrap-token-abc123 --type merge -p {"stringData":{"expiration":"2026-03-02T12:00:00Z"}}`,
+	}
+	if strings.Join(kube.calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("calls = %v, want %v", kube.calls, want)
+	}
+}
+
+func TestTokenRotationPlan(t *testing.T) {
+	got := describe(TokenRotationPlan(testNodes()))
+	want := []string{"store-token mgmt", "switch-token worker-1", "wait worker-1", "switch-token worker-2", "wait worker-2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("TokenRotationPlan() = %v, want %v", got, want)
+	}
+}
+
+func TestExecuteTokenRotation(t *testing.T) {
+	var scripts []string
+	u := &Upgrader{
+		RunScript: func(node inventory.Node, script string, args []string) error {
+			if len(args) != 0 {
+				t.Errorf("token scripts must not take arguments, got %v", args)
+			}
+			scripts = append(scripts, node.Name+" "+strings.SplitN(script, "\n", 2)[0])
+			return nil
+		},
+		Out:   io.Discard,
+		Token: testAgentToken,
+	}
+	steps := TokenRotationPlan(testNodes())[:2]
+	if err := u.Execute(context.Background(), steps, ""); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	want := "mgmt token='" + testAgentToken + "',worker-1 token='" + testAgentToken + "'"
+	if got := strings.Join(scripts, ","); got != want {
+		t.Errorf("scripts = %s", got)
+	}
+
+	u.Token = "K10abc::server:secret'; rm -rf /"
+	if err := u.Execute(context.Background(), steps, ""); err == nil || !strings.Contains(err.Error(), "no valid agent token") {
+		t.Errorf("Execute() error = %v, want invalid token", err)
+	}
+}
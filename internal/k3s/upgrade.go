@@ -0,0 +1,393 @@
+// Package k3s plans and drives rolling k3s upgrades and certificate and
+// join token rotations: servers first, then workers, one node at a time,
+// with optional cordon/drain around each node.
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
+)
+
+const (
+	// DefaultChannel is the release channel used when neither a channel nor a
+	// version is requested
+	DefaultChannel = "stable"
+
+	// DefaultChannelServer resolves release channels to versions
+	DefaultChannelServer = "https://update.k3s.io/v1-release/channels"
+
+	// ConfigFile is the k3s config written by bootstrap/join
+	ConfigFile = "/etc/rancher/k3s/config.yaml"
+)
+
+var (
+	versionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+(-rc\d+)?(\+k3s\d+)?$`)
+	channelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*$`)
+)
+
+// Target is the requested upgrade target: a release channel or a version
+type Target struct {
+	Channel string
+	Version string
+}
+
+// Validate checks that exactly one of Channel and Version is set and well-formed
+func (t Target) Validate() error {
+	switch {
+	case t.Channel != "" && t.Version != "":
+		return fmt.Errorf("--channel and --version are mutually exclusive")
+	case t.Version != "":
+		if !versionPattern.MatchString(t.Version) {
+			return fmt.Errorf("invalid k3s version %q (expected e.g. v1.31.4+k3s1)", t.Version)
+		}
+	case t.Channel != "":
+		if !channelPattern.MatchString(t.Channel) {
+			return fmt.Errorf("invalid k3s channel %q (expected e.g. stable, latest, v1.31)", t.Channel)
+		}
+	default:
+		return fmt.Errorf("either --channel or --version is required")
+	}
+	return nil
+}
+
+func (t Target) String() string {
+	if t.Version != "" {
+		return t.Version
+	}
+	return "channel " + t.Channel
+}
+
+// ResolveChannel returns the version a release channel currently points to.
+// The channel server answers with a redirect to the GitHub release tag.
+func ResolveChannel(ctx context.Context, client *http.Client, server, channel string) (string, error) {
+	noRedirect := *client
+	noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	url := strings.TrimRight(server, "/") + "/" + channel
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := noRedirect.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve k3s channel %q: %w", channel, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	location := resp.Header.Get("Location")
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+		return "", fmt.Errorf("failed to resolve k3s channel %q: unexpected response %s", channel, resp.Status)
+	}
+	version := path.Base(location)
+	if !versionPattern.MatchString(version) {
+		return "", fmt.Errorf("failed to resolve k3s channel %q: unexpected release %q", channel, location)
+	}
+	return version, nil
+}
+
+// StepKind identifies a single action of the upgrade sequence
+type StepKind string
+
+const (
+	StepCordon   StepKind = "cordon"
+	StepDrain    StepKind = "drain"
+	StepUpgrade  StepKind = "upgrade"
+	StepWait     StepKind = "wait"
+	StepUncordon StepKind = "uncordon"
+
+	StepRotateCerts       StepKind = "rotate-certs"
+	StepRefreshKubeconfig StepKind = "refresh-kubeconfig"
+	StepRestartAgent      StepKind = "restart-agent"
+
+	StepStoreToken  StepKind = "store-token"
+	StepSwitchToken StepKind = "switch-token"
+)
+
+// Step is one action on one node
+type Step struct {
+	Kind StepKind
+	Node inventory.Node
+}
+
+// Describe returns a human-readable description of the step
+func (s Step) Describe(version string) string {
+	switch s.Kind {
+	case StepCordon:
+		return fmt.Sprintf("kubectl cordon %s", s.Node.Name)
+	case StepDrain:
+		return fmt.Sprintf("kubectl drain %s --ignore-daemonsets --delete-emptydir-data", s.Node.Name)
+	case StepUpgrade:
+		return fmt.Sprintf("install k3s %s (%s) on %s", version, ExecMode(s.Node), s.Node.Host)
+	case StepWait:
+		switch {
+		case s.Node.Name == "":
+			return "wait for all nodes to be Ready"
+		case version == "":
+			return fmt.Sprintf("wait for %s to be Ready", s.Node.Name)
+		}
+		return fmt.Sprintf("wait for %s to be Ready at %s", s.Node.Name, version)
+	case StepUncordon:
+		return fmt.Sprintf("kubectl uncordon %s", s.Node.Name)
+	case StepRotateCerts:
+		return fmt.Sprintf("rotate k3s certificates on %s (stop k3s, k3s certificate rotate, start k3s)", s.Node.Host)
+	case StepRefreshKubeconfig:
+		return "refresh the cached kubeconfig from the server"
+	case StepRestartAgent:
+		return fmt.Sprintf("restart k3s-agent on %s", s.Node.Host)
+	case StepStoreToken:
+		return fmt.Sprintf("store the new agent token in %s on %s", AgentTokenFile, s.Node.Host)
+	case StepSwitchToken:
+		return fmt.Sprintf("set the new agent token in %s and restart k3s-agent on %s", ConfigFile, s.Node.Host)
+	}
+	return string(s.Kind)
+}
+
+// target names the node of the step in errors and traces
+func (s Step) target() string {
+	if s.Node.Name != "" {
+		return s.Node.Name
+	}
+	return s.Node.Host
+}
+
+// ExecMode returns the INSTALL_K3S_EXEC value for a node
+func ExecMode(node inventory.Node) string {
+	if node.Role == inventory.RoleWorker {
+		return "agent"
+	}
+	return "server"
+}
+
+// Plan returns the upgrade sequence for nodes: servers before workers, each
+// node completed (and Ready) before the next starts. Nodes already at version
+// are skipped. Cordon/drain is only planned when drain is set and the cluster
+// has another node to take the workloads.
+func Plan(nodes []inventory.Node, current map[string]string, version string, drain bool) []Step {
+	ordered := make([]inventory.Node, 0, len(nodes))
+	for _, role := range []string{inventory.RoleServer, inventory.RoleWorker} {
+		for _, node := range nodes {
+			if node.Role == role {
+				ordered = append(ordered, node)
+			}
+		}
+	}
+	drain = drain && len(nodes) > 1
+
+	var steps []Step
+	for _, node := range ordered {
+		if current[node.Name] == version {
+			continue
+		}
+		if drain {
+			steps = append(steps, Step{StepCordon, node}, Step{StepDrain, node})
+		}
+		steps = append(steps, Step{StepUpgrade, node}, Step{StepWait, node})
+		if drain {
+			steps = append(steps, Step{StepUncordon, node})
+		}
+	}
+	return steps
+}
+
+// upgradeScript downloads the k3s installer and re-runs it with the pinned
+// version, keeping the node's role and config file (like bootstrap/join).
+const upgradeScript = `set -euo pipefail
+version="$1"
+exec_mode="$2"
+config_file="$3"
+
+installer="$(mktemp)"
+trap 'rm -f "${installer}"' EXIT
+curl --fail --silent --show-error --location --proto '=https' --tlsv1.2 https://get.k3s.io -o "${installer}"
+sudo env INSTALL_K3S_VERSION="${version}" INSTALL_K3S_EXEC="${exec_mode}" K3S_CONFIG_FILE="${config_file}" sh "${installer}"
+`
+
+// Kubectl runs kubectl against the cluster being upgraded
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// ScriptRunner runs a bash script with args on a node
+type ScriptRunner func(node inventory.Node, script string, args []string) error
+
+// Upgrader executes an upgrade, certificate rotation or token rotation plan
+type Upgrader struct {
+	Kube      Kubectl
+	RunScript ScriptRunner
+	Out       io.Writer
+	// RefreshKubeconfig replaces the cached kubeconfig after a certificate
+	// rotation and points Kube at it
+	RefreshKubeconfig func(ctx context.Context) error
+	// Token is the agent join token installed by a token rotation
+	Token string
+
+	DrainTimeout time.Duration
+	ReadyTimeout time.Duration
+	PollInterval time.Duration
+}
+
+// Execute runs the steps in order and stops at the first failure. A node
+// that fails to upgrade stays cordoned so it does not receive new workloads.
+func (u *Upgrader) Execute(ctx context.Context, steps []Step, version string) error {
+	for i, step := range steps {
+		_, _ = fmt.Fprintf(u.Out, "[%d/%d] %s\n", i+1, len(steps), step.Describe(version))
+		stepCtx, span := telemetry.Start(ctx, "k3s "+string(step.Kind), telemetry.String("k3s.node", step.target()), telemetry.String("k3s.version", version))
+		if err := span.End(u.run(stepCtx, step, version)); err != nil {
+			return fmt.Errorf("%s: %w", strings.TrimSpace(string(step.Kind)+" "+step.target()), err)
+		}
+	}
+	return nil
+}
+
+func (u *Upgrader) run(ctx context.Context, step Step, version string) error {
+	switch step.Kind {
+	case StepCordon:
+		_, err := u.Kube.Output(ctx, "cordon", step.Node.Name)
+		return err
+	case StepDrain:
+		_, err := u.Kube.Output(ctx, "drain", step.Node.Name,
+			"--ignore-daemonsets", "--delete-emptydir-data",
+			fmt.Sprintf("--timeout=%s", u.DrainTimeout))
+		return err
+	case StepUpgrade:
+		return u.RunScript(step.Node, upgradeScript, []string{version, ExecMode(step.Node), ConfigFile})
+	case StepWait:
+		return u.waitReady(ctx, step.Node.Name, version)
+	case StepUncordon:
+		_, err := u.Kube.Output(ctx, "uncordon", step.Node.Name)
+		return err
+	case StepRotateCerts:
+		return u.RunScript(step.Node, rotateCertsScript, nil)
+	case StepRefreshKubeconfig:
+		if u.RefreshKubeconfig == nil {
+			return nil
+		}
+		return u.RefreshKubeconfig(ctx)
+	case StepRestartAgent:
+		return u.RunScript(step.Node, restartAgentScript, nil)
+	case StepStoreToken, StepSwitchToken:
+		script := storeTokenScript
+		if step.Kind == StepSwitchToken {
+			script = switchTokenScript
+		}
+		script, err := withToken(script, u.Token)
+		if err != nil {
+			return err
+		}
+		return u.RunScript(step.Node, script, nil)
+	}
+	return fmt.Errorf("unknown step %q", step.Kind)
+}
+
+// waitReady polls the node until it reports Ready with the target kubelet
+// version; an empty version accepts any, an empty name waits for every node.
+// API errors are retried, since the server restarts during upgrade.
+func (u *Upgrader) waitReady(ctx context.Context, name, version string) error {
+	if name == "" {
+		return u.waitAllReady(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, u.ReadyTimeout)
+	defer cancel()
+
+	last := "no response"
+	for {
+		out, err := u.Kube.Output(ctx, "get", "node", name, "-o",
+			`jsonpath={.status.nodeInfo.kubeletVersion}{"\t"}{.status.conditions[?(@.type=="Ready")].status}`)
+		if err == nil {
+			got, ready, _ := strings.Cut(strings.TrimSpace(string(out)), "\t")
+			if (version == "" || got == version) && ready == "True" {
+				return nil
+			}
+			last = fmt.Sprintf("version %s, Ready=%s", orUnknown(got), orUnknown(ready))
+		}
+		select {
+		case <-ctx.Done():
+			if version == "" {
+				return fmt.Errorf("node did not become Ready within %s (last: %s)", u.ReadyTimeout, last)
+			}
+			return fmt.Errorf("node did not become Ready at %s within %s (last: %s)", version, u.ReadyTimeout, last)
+		case <-time.After(u.PollInterval):
+		}
+	}
+}
+
+// waitAllReady polls until every node of the cluster reports Ready
+func (u *Upgrader) waitAllReady(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, u.ReadyTimeout)
+	defer cancel()
+
+	last := "no response"
+	for {
+		out, err := u.Kube.Output(ctx, "get", "nodes", "-o",
+			`jsonpath={range .items[*]}{.metadata.name}{"\t"}{.status.conditions[?(@.type=="Ready")].status}{"\n"}{end}`)
+		if err == nil {
+			var notReady []string
+			for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+				name, ready, _ := strings.Cut(strings.TrimSpace(line), "\t")
+				if name != "" && ready != "True" {
+					notReady = append(notReady, name)
+				}
+			}
+			if len(notReady) == 0 && strings.TrimSpace(string(out)) != "" {
+				return nil
+			}
+			last = "not Ready: " + orUnknown(strings.Join(notReady, ", "))
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("nodes did not become Ready within %s (last: %s)", u.ReadyTimeout, last)
+		case <-time.After(u.PollInterval):
+		}
+	}
+}
+
+// NodeVersions returns the kubelet version of every cluster node by name
+func NodeVersions(ctx context.Context, kube Kubectl) (map[string]string, error) {
+	out, err := kube.Output(ctx, "get", "nodes", "-o",
+		`jsonpath={range .items[*]}{.metadata.name}{"\t"}{.status.nodeInfo.kubeletVersion}{"\n"}{end}`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	versions := map[string]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		name, version, ok := strings.Cut(strings.TrimSpace(line), "\t")
+		if ok && name != "" {
+			versions[name] = version
+		}
+	}
+	return versions, nil
+}
+
+// CheckNodes verifies that every planned node is registered in the cluster,
+// so a mismatch between inventory names and node names fails before any
+// node is touched.
+func CheckNodes(nodes []inventory.Node, current map[string]string) error {
+	var missing []string
+	for _, node := range nodes {
+		if _, ok := current[node.Name]; !ok {
+			missing = append(missing, node.Name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("nodes not found in the cluster: %s (inventory names must match kubectl node names)", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,198 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/inventory"
+)
+
+func testNodes() []inventory.Node {
+	return []inventory.Node{
+		{Name: "worker-1", Host: "10.0.0.2", Role: inventory.RoleWorker},
+		{Name: "mgmt", Host: "10.0.0.1", Role: inventory.RoleServer},
+		{Name: "worker-2", Host: "10.0.0.3", Role: inventory.RoleWorker},
+	}
+}
+
+func describe(steps []Step) []string {
+	out := make([]string, len(steps))
+	for i, s := range steps {
+		out[i] = string(s.Kind) + " " + s.Node.Name
+	}
+	return out
+}
+
+func TestTargetValidate(t *testing.T) {
+	tests := []struct {
+		target  Target
+		wantErr bool
+	}{
+		{Target{Channel: "stable"}, false},
+		{Target{Channel: "v1.31"}, false},
+		{Target{Version: "v1.31.4+k3s1"}, false},
+		{Target{Version: "v1.32.0-rc1+k3s1"}, false},
+		{Target{}, true},
+		{Target{Channel: "stable", Version: "v1.31.4+k3s1"}, true},
+		{Target{Version: "1.31.4"}, true},
+		{Target{Channel: "Stable; rm"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.target.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPlanOrdersServersFirstAndDrains(t *testing.T) {
+	current := map[string]string{"mgmt": "v1.30.0+k3s1", "worker-1": "v1.30.0+k3s1", "worker-2": "v1.31.4+k3s1"}
+	got := describe(Plan(testNodes(), current, "v1.31.4+k3s1", true))
+	want := []string{
+		"cordon mgmt", "drain mgmt", "upgrade mgmt", "wait mgmt", "uncordon mgmt",
+		"cordon worker-1", "drain worker-1", "upgrade worker-1", "wait worker-1", "uncordon worker-1",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Plan() = %v, want %v", got, want)
+	}
+}
+
+func TestPlanSingleNodeSkipsDrain(t *testing.T) {
+	nodes := []inventory.Node{{Name: "mgmt", Role: inventory.RoleServer}}
+	got := describe(Plan(nodes, map[string]string{"mgmt": "v1.30.0+k3s1"}, "v1.31.4+k3s1", true))
+	want := []string{"upgrade mgmt", "wait mgmt"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Plan() = %v, want %v", got, want)
+	}
+}
+
+func TestPlanUpToDate(t *testing.T) {
+	current := map[string]string{"mgmt": "v1.31.4+k3s1", "worker-1": "v1.31.4+k3s1", "worker-2": "v1.31.4+k3s1"}
+	if steps := Plan(testNodes(), current, "v1.31.4+k3s1", true); len(steps) != 0 {
+		t.Errorf("Plan() = %v, want no steps", describe(steps))
+	}
+}
+
+func TestResolveChannel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stable" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "https://github.com/k3s-io/k3s/releases/tag/v1.31.4+k3s1", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	got, err := ResolveChannel(context.Background(), srv.Client(), srv.URL, "stable")
+	if err != nil {
+		t.Fatalf("ResolveChannel() error: %v", err)
+	}
+	if got != "v1.31.4+k3s1" {
+		t.Errorf("ResolveChannel() = %q", got)
+	}
+	if _, err := ResolveChannel(context.Background(), srv.Client(), srv.URL, "nope"); err == nil {
+		t.Error("ResolveChannel(nope) expected error")
+	}
+}
+
+type fakeKube struct {
+	calls   []string
+	outputs map[string][]string
+	errs    map[string]error
+}
+
+func (k *fakeKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	key := args[0]
+	if key == "get" {
+		key = "get " + args[1]
+		if args[1] == "node" {
+			key = "get " + args[2]
+		}
+	}
+	k.calls = append(k.calls, strings.Join(args[:2], " "))
+	if err := k.errs[key]; err != nil {
+		return nil, err
+	}
+	if queue := k.outputs[key]; len(queue) > 0 {
+		k.outputs[key] = queue[1:]
+		return []byte(queue[0]), nil
+	}
+	return nil, nil
+}
+
+func TestExecute(t *testing.T) {
+	kube := &fakeKube{outputs: map[string][]string{
+		"get mgmt": {"v1.30.0+k3s1\tTrue", "v1.31.4+k3s1\tFalse", "v1.31.4+k3s1\tTrue"},
+	}}
+	var scripts []string
+	u := &Upgrader{
+		Kube: kube,
+		RunScript: func(node inventory.Node, script string, args []string) error {
+			scripts = append(scripts, node.Name+" "+strings.Join(args, " "))
+			return nil
+		},
+		Out:          io.Discard,
+		ReadyTimeout: time.Second,
+		PollInterval: time.Millisecond,
+	}
+	nodes := []inventory.Node{{Name: "mgmt", Role: inventory.RoleServer}, {Name: "worker-1", Role: inventory.RoleWorker}}
+	steps := Plan(nodes, nil, "v1.31.4+k3s1", true)[:5]
+	if err := u.Execute(context.Background(), steps, "v1.31.4+k3s1"); err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+
+	wantCalls := "cordon mgmt,drain mgmt,get node,get node,get node,uncordon mgmt"
+	if got := strings.Join(kube.calls, ","); got != wantCalls {
+		t.Errorf("kubectl calls = %s, want %s", got, wantCalls)
+	}
+	if len(scripts) != 1 || scripts[0] != "mgmt v1.31.4+k3s1 server "+ConfigFile {
+		t.Errorf("scripts = %v", scripts)
+	}
+}
+
+func TestExecuteStopsOnFailure(t *testing.T) {
+	kube := &fakeKube{errs: map[string]error{"drain": fmt.Errorf("pdb violation")}}
+	u := &Upgrader{
+		Kube: kube,
+		RunScript: func(inventory.Node, string, []string) error {
+			t.Error("upgrade must not run after a failed drain")
+			return nil
+		},
+		Out: io.Discard,
+	}
+	steps := Plan(testNodes(), nil, "v1.31.4+k3s1", true)
+	err := u.Execute(context.Background(), steps, "v1.31.4+k3s1")
+	if err == nil || !strings.Contains(err.Error(), "drain mgmt") {
+		t.Errorf("Execute() error = %v, want drain mgmt failure", err)
+	}
+}
+
+func TestWaitReadyTimeout(t *testing.T) {
+	kube := &fakeKube{outputs: map[string][]string{}}
+	u := &Upgrader{Kube: kube, ReadyTimeout: 20 * time.Millisecond, PollInterval: time.Millisecond}
+	err := u.waitReady(context.Background(), "mgmt", "v1.31.4+k3s1")
+	if err == nil || !strings.Contains(err.Error(), "did not become Ready") {
+		t.Errorf("waitReady() error = %v", err)
+	}
+}
+
+func TestNodeVersionsAndCheckNodes(t *testing.T) {
+	kube := &fakeKube{outputs: map[string][]string{
+		"get nodes": {"mgmt\tv1.30.0+k3s1\nworker-1\tv1.30.0+k3s1\n"},
+	}}
+	current, err := NodeVersions(context.Background(), kube)
+	if err != nil {
+		t.Fatalf("NodeVersions() error: %v", err)
+	}
+	if len(current) != 2 || current["worker-1"] != "v1.30.0+k3s1" {
+		t.Errorf("NodeVersions() = %v", current)
+	}
+	if err := CheckNodes(testNodes(), current); err == nil || !strings.Contains(err.Error(), "worker-2") {
+		t.Errorf("CheckNodes() error = %v, want worker-2 missing", err)
+	}
+}
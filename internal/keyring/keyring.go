@@ -0,0 +1,286 @@
+// Package keyring stores secrets in the operating system's credential store
+// so config values can reference them as "keyring:<name>" instead of holding
+// plaintext: the macOS Keychain (security), libsecret on Linux (secret-tool),
+// and the Windows Credential Manager (advapi32 via PowerShell).
+//
+// Secrets are stored under the service "netcup-kube" with the reference name
+// as the account, so they can also be managed with the native tools.
+package keyring
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// Service is the service (or target prefix) secrets are stored under
+	Service = "netcup-kube"
+
+	// RefPrefix marks a config value as a keyring reference
+	RefPrefix = "keyring:"
+)
+
+// ErrNotFound is returned when no secret is stored under a name
+var ErrNotFound = errors.New("secret not found in keyring")
+
+// command is one invocation of a credential store tool
+type command struct {
+	Name  string
+	Args  []string
+	Stdin string
+	Env   []string
+}
+
+// runner runs a command and returns its stdout and exit code
+type runner func(c command) (stdout []byte, exitCode int, err error)
+
+// Keyring accesses the credential store of an operating system
+type Keyring struct {
+	// GOOS selects the backend
+	GOOS string
+	run  runner
+}
+
+// New returns a Keyring for the running operating system
+func New() *Keyring {
+	return &Keyring{GOOS: runtime.GOOS, run: execRunner}
+}
+
+func execRunner(c command) ([]byte, int, error) {
+	cmd := exec.Command(c.Name, c.Args...)
+	cmd.Stdin = strings.NewReader(c.Stdin)
+	if len(c.Env) > 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return out, exitErr.ExitCode(), err
+	}
+	return out, 0, err
+}
+
+// IsRef reports whether value is a keyring reference
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, RefPrefix)
+}
+
+// ValidateName checks that name is usable as a keyring entry. Names are
+// limited to letters, digits, '.', '_' and '-' so they pass through every
+// backend's command line unquoted.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("keyring name is empty")
+	}
+	for _, c := range name {
+		ok := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '.' || c == '_' || c == '-'
+		if !ok {
+			return fmt.Errorf("invalid keyring name %q: use letters, digits, '.', '_' and '-'", name)
+		}
+	}
+	return nil
+}
+
+// Resolve returns value unchanged unless it is a "keyring:<name>" reference,
+// in which case the stored secret is returned
+func (k *Keyring) Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	name := strings.TrimSpace(strings.TrimPrefix(value, RefPrefix))
+	secret, err := k.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s%s: %w", RefPrefix, name, err)
+	}
+	return secret, nil
+}
+
+// Resolve resolves value with the keyring of the running operating system
+func Resolve(value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	return New().Resolve(value)
+}
+
+// Get returns the secret stored under name
+func (k *Keyring) Get(name string) (string, error) {
+	if err := ValidateName(name); err != nil {
+		return "", err
+	}
+	var c command
+	notFound := -1
+	switch k.GOOS {
+	case "darwin":
+		c = command{Name: "security", Args: []string{"find-generic-password", "-s", Service, "-a", name, "-w"}}
+		notFound = 44
+	case "windows":
+		c = windowsCommand(windowsGetScript, name, "")
+		notFound = 2
+	default:
+		// secret-tool exits 1 without output when nothing matches
+		c = command{Name: "secret-tool", Args: []string{"lookup", "service", Service, "account", name}}
+		notFound = 1
+	}
+	out, code, err := k.run(c)
+	if err != nil {
+		if code == notFound && len(out) == 0 {
+			return "", ErrNotFound
+		}
+		return "", k.toolError(c.Name, err)
+	}
+	secret := strings.TrimRight(string(out), "\r\n")
+	if k.GOOS != "windows" && secret == "" {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+// Set stores secret under name, replacing any existing entry. The secret is
+// passed on stdin or through the environment, never on a command line.
+func (k *Keyring) Set(name, secret string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if secret == "" {
+		return errors.New("refusing to store an empty secret")
+	}
+	var c command
+	switch k.GOOS {
+	case "darwin":
+		// security -i reads commands from stdin; -X takes the password as hex
+		c = command{
+			Name:  "security",
+			Args:  []string{"-i"},
+			Stdin: fmt.Sprintf("add-generic-password -U -s %s -a %s -X %s\n", Service, name, hex.EncodeToString([]byte(secret))),
+		}
+	case "windows":
+		c = windowsCommand(windowsSetScript, name, secret)
+	default:
+		c = command{
+			Name:  "secret-tool",
+			Args:  []string{"store", "--label", Service + ": " + name, "service", Service, "account", name},
+			Stdin: secret,
+		}
+	}
+	if _, _, err := k.run(c); err != nil {
+		return k.toolError(c.Name, err)
+	}
+	return nil
+}
+
+// Delete removes the secret stored under name
+func (k *Keyring) Delete(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	var c command
+	notFound := -1
+	switch k.GOOS {
+	case "darwin":
+		c = command{Name: "security", Args: []string{"delete-generic-password", "-s", Service, "-a", name}}
+		notFound = 44
+	case "windows":
+		c = windowsCommand(windowsDeleteScript, name, "")
+		notFound = 2
+	default:
+		c = command{Name: "secret-tool", Args: []string{"clear", "service", Service, "account", name}}
+	}
+	if _, code, err := k.run(c); err != nil {
+		if code == notFound {
+			return ErrNotFound
+		}
+		return k.toolError(c.Name, err)
+	}
+	return nil
+}
+
+func (k *Keyring) toolError(tool string, err error) error {
+	var notFound *exec.Error
+	if errors.As(err, &notFound) {
+		hint := ""
+		if tool == "secret-tool" {
+			hint = " (install libsecret-tools and run a Secret Service such as gnome-keyring)"
+		}
+		return fmt.Errorf("keyring is not available on this system: %s not found%s", tool, hint)
+	}
+	return fmt.Errorf("%s failed: %w", tool, err)
+}
+
+// windowsCredDefs declares the advapi32 credential functions for PowerShell
+const windowsCredDefs = `$ErrorActionPreference = 'Stop'
+Add-Type -Namespace NetcupKube -Name Cred -MemberDefinition @'
+[StructLayout(LayoutKind.Sequential, CharSet = CharSet.Unicode)]
+public struct CREDENTIAL {
+  public int Flags; public int Type; public string TargetName; public string Comment;
+  public System.Runtime.InteropServices.ComTypes.FILETIME LastWritten;
+  public int CredentialBlobSize; public IntPtr CredentialBlob; public int Persist;
+  public int AttributeCount; public IntPtr Attributes; public string TargetAlias; public string UserName;
+}
+[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+public static extern bool CredReadW(string target, int type, int flags, out IntPtr cred);
+[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+public static extern bool CredWriteW(ref CREDENTIAL cred, int flags);
+[DllImport("advapi32.dll", CharSet = CharSet.Unicode, SetLastError = true)]
+public static extern bool CredDeleteW(string target, int type, int flags);
+[DllImport("advapi32.dll")]
+public static extern void CredFree(IntPtr cred);
+'@
+$target = $env:NETCUP_KEYRING_TARGET
+`
+
+// Generic credentials (type 1); exit code 2 means not found
+const (
+	windowsGetScript = `$p = [IntPtr]::Zero
+if (-not [NetcupKube.Cred]::CredReadW($target, 1, 0, [ref]$p)) { exit 2 }
+$c = [Runtime.InteropServices.Marshal]::PtrToStructure($p, [type][NetcupKube.Cred+CREDENTIAL])
+[Console]::Out.Write([Runtime.InteropServices.Marshal]::PtrToStringUni($c.CredentialBlob, $c.CredentialBlobSize / 2))
+[NetcupKube.Cred]::CredFree($p)
+`
+	windowsSetScript = `$b = [Text.Encoding]::Unicode.GetBytes($env:NETCUP_KEYRING_SECRET)
+$c = New-Object NetcupKube.Cred+CREDENTIAL
+$c.Type = 1; $c.Persist = 2; $c.TargetName = $target; $c.UserName = $env:NETCUP_KEYRING_ACCOUNT
+$c.CredentialBlobSize = $b.Length
+$c.CredentialBlob = [Runtime.InteropServices.Marshal]::AllocHGlobal($b.Length)
+[Runtime.InteropServices.Marshal]::Copy($b, 0, $c.CredentialBlob, $b.Length)
+$ok = [NetcupKube.Cred]::CredWriteW([ref]$c, 0)
+[Runtime.InteropServices.Marshal]::FreeHGlobal($c.CredentialBlob)
+if (-not $ok) { exit 1 }
+`
+	windowsDeleteScript = `if (-not [NetcupKube.Cred]::CredDeleteW($target, 1, 0)) { exit 2 }
+`
+)
+
+// windowsCommand runs script with the credential definitions through
+// PowerShell; the target, account and secret are passed in the environment
+// so the secret never appears on the command line
+func windowsCommand(script, name, secret string) command {
+	env := []string{"NETCUP_KEYRING_TARGET=" + Service + ":" + name, "NETCUP_KEYRING_ACCOUNT=" + name}
+	if secret != "" {
+		env = append(env, "NETCUP_KEYRING_SECRET="+secret)
+	}
+	// -EncodedCommand takes base64 UTF-16LE and, unlike -Command -, runs
+	// multi-line scripts as a whole
+	var encoded []byte
+	for _, u := range utf16.Encode([]rune(windowsCredDefs + script)) {
+		encoded = append(encoded, byte(u), byte(u>>8))
+	}
+	return command{
+		Name: "powershell.exe",
+		Args: []string{"-NoProfile", "-NonInteractive", "-EncodedCommand", base64.StdEncoding.EncodeToString(encoded)},
+		Env:  env,
+	}
+}
@@ -0,0 +1,224 @@
+package keyring
+
+import (
+	"encoding/base64"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+type fakeRunner struct {
+	calls []command
+	out   string
+	code  int
+	err   error
+}
+
+func (f *fakeRunner) run(c command) ([]byte, int, error) {
+	f.calls = append(f.calls, c)
+	return []byte(f.out), f.code, f.err
+}
+
+func newFake(goos string, f *fakeRunner) *Keyring {
+	return &Keyring{GOOS: goos, run: f.run}
+}
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		goos string
+		tool string
+	}{
+		{"darwin", "security find-generic-password -s netcup-kube -a root-pass -w"},
+		{"linux", "secret-tool lookup service netcup-kube account root-pass"},
+	}
+	for _, tt := range tests {
+		f := &fakeRunner{out: "s3cret\n"}
+		got, err := newFake(tt.goos, f).Get("root-pass")
+		if err != nil || got != "s3cret" {
+			t.Fatalf("%s: Get() = %q, %v", tt.goos, got, err)
+		}
+		if call := f.calls[0].Name + " " + strings.Join(f.calls[0].Args, " "); call != tt.tool {
+			t.Errorf("%s: ran %q, want %q", tt.goos, call, tt.tool)
+		}
+	}
+}
+
+func TestGetNotFound(t *testing.T) {
+	for goos, code := range map[string]int{"darwin": 44, "linux": 1, "windows": 2} {
+		f := &fakeRunner{code: code, err: errors.New("exit status")}
+		if _, err := newFake(goos, f).Get("missing"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: Get() error = %v, want ErrNotFound", goos, err)
+		}
+	}
+}
+
+func TestSetKeepsSecretOffCommandLine(t *testing.T) {
+	for _, goos := range []string{"darwin", "linux", "windows"} {
+		f := &fakeRunner{}
+		if err := newFake(goos, f).Set("netcup-api-key", "s3cret"); err != nil {
+			t.Fatalf("%s: Set() error: %v", goos, err)
+		}
+		c := f.calls[0]
+		if strings.Contains(strings.Join(c.Args, " "), "s3cret") {
+			t.Errorf("%s: secret on the command line: %v", goos, c.Args)
+		}
+		switch goos {
+		case "darwin":
+			if !strings.Contains(c.Stdin, "-X 733363726574") {
+				t.Errorf("darwin: stdin %q lacks hex password", c.Stdin)
+			}
+		case "linux":
+			if c.Stdin != "s3cret" {
+				t.Errorf("linux: stdin = %q", c.Stdin)
+			}
+		case "windows":
+			if !containsString(c.Env, "NETCUP_KEYRING_SECRET=s3cret") || !containsString(c.Env, "NETCUP_KEYRING_TARGET=netcup-kube:netcup-api-key") {
+				t.Errorf("windows: env = %v", c.Env)
+			}
+			script := decodePowerShell(t, c.Args[len(c.Args)-1])
+			if !strings.Contains(script, "CredWriteW") {
+				t.Errorf("windows: script does not write a credential:\n%s", script)
+			}
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	f := &fakeRunner{out: "token"}
+	k := newFake("linux", f)
+	if got, err := k.Resolve("plain-value"); err != nil || got != "plain-value" || len(f.calls) != 0 {
+		t.Fatalf("Resolve(plain) = %q, %v (calls %d)", got, err, len(f.calls))
+	}
+	if got, err := k.Resolve("keyring:scp-token"); err != nil || got != "token" {
+		t.Fatalf("Resolve(ref) = %q, %v", got, err)
+	}
+	if _, err := k.Resolve("keyring:bad name"); err == nil {
+		t.Error("Resolve() expected error for invalid name")
+	}
+}
+
+func TestToolMissing(t *testing.T) {
+	f := &fakeRunner{err: &exec.Error{Name: "secret-tool", Err: exec.ErrNotFound}}
+	err := newFake("linux", f).Set("x", "y")
+	if err == nil || !strings.Contains(err.Error(), "keyring is not available") {
+		t.Errorf("Set() error = %v", err)
+	}
+}
+
+func containsString(values []string, want string) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
+
+func decodePowerShell(t *testing.T, encoded string) string {
+	t.Helper()
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		units[i] = uint16(raw[2*i]) | uint16(raw[2*i+1])<<8
+	}
+	return string(utf16.Decode(units))
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		goos string
+		tool string
+		code int
+	}{
+		{"darwin", "security delete-generic-password -s netcup-kube -a old-token", 44},
+		{"linux", "secret-tool clear service netcup-kube account old-token", 1},
+		{"windows", "powershell.exe", 2},
+	}
+	for _, tt := range tests {
+		f := &fakeRunner{}
+		if err := newFake(tt.goos, f).Delete("old-token"); err != nil {
+			t.Fatalf("%s: Delete() error: %v", tt.goos, err)
+		}
+		if call := strings.Join(append([]string{f.calls[0].Name}, f.calls[0].Args...), " "); !strings.HasPrefix(call, tt.tool) {
+			t.Errorf("%s: ran %q", tt.goos, call)
+		}
+		if tt.goos == "windows" && !strings.Contains(decodePowerShell(t, f.calls[0].Args[3]), "CredDeleteW") {
+			t.Error("windows: script does not delete a credential")
+		}
+
+		f = &fakeRunner{code: tt.code, err: errors.New("exit status")}
+		err := newFake(tt.goos, f).Delete("old-token")
+		// secret-tool clear does not report missing entries
+		if tt.goos != "linux" && !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: Delete() error = %v, want ErrNotFound", tt.goos, err)
+		}
+		if tt.goos == "linux" && (err == nil || !strings.Contains(err.Error(), "secret-tool failed")) {
+			t.Errorf("%s: Delete() error = %v", tt.goos, err)
+		}
+	}
+	if err := newFake("linux", &fakeRunner{}).Delete(""); err == nil {
+		t.Error("Delete() expected error for an empty name")
+	}
+}
+
+func TestGetErrors(t *testing.T) {
+	f := &fakeRunner{code: 1, out: "partial", err: errors.New("exit status 1")}
+	if _, err := newFake("linux", f).Get("name"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() with output and a failure = %v", err)
+	}
+	if _, err := newFake("darwin", &fakeRunner{out: "\n"}).Get("name"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of an empty secret = %v, want ErrNotFound", err)
+	}
+	got, err := newFake("windows", &fakeRunner{out: ""}).Get("name")
+	if err != nil || got != "" {
+		t.Errorf("windows: Get() = %q, %v", got, err)
+	}
+	if _, err := newFake("linux", &fakeRunner{}).Get("a/b"); err == nil {
+		t.Error("Get() expected error for an invalid name")
+	}
+}
+
+func TestSetRejectsInvalidInput(t *testing.T) {
+	k := newFake("linux", &fakeRunner{})
+	if err := k.Set("bad name", "x"); err == nil {
+		t.Error("Set() expected error for an invalid name")
+	}
+	if err := k.Set("name", ""); err == nil {
+		t.Error("Set() expected error for an empty secret")
+	}
+	if err := newFake("darwin", &fakeRunner{err: &exec.Error{Name: "security", Err: exec.ErrNotFound}}).Set("x", "y"); err == nil || strings.Contains(err.Error(), "libsecret") {
+		t.Errorf("darwin: Set() error = %v", err)
+	}
+}
+
+func TestExecRunner(t *testing.T) {
+	out, code, err := execRunner(command{Name: "sh", Args: []string{"-c", `printf '%s-%s' "$(cat)" "$KEYRING_TEST"`}, Stdin: "in", Env: []string{"KEYRING_TEST=env"}})
+	if err != nil || code != 0 || string(out) != "in-env" {
+		t.Errorf("execRunner() = %q, %d, %v", out, code, err)
+	}
+	_, code, err = execRunner(command{Name: "sh", Args: []string{"-c", "echo locked >&2; exit 44"}})
+	if code != 44 || err == nil || !strings.Contains(err.Error(), "locked") {
+		t.Errorf("execRunner() failure = %d, %v", code, err)
+	}
+	if _, _, err := execRunner(command{Name: "netcup-kube-no-such-tool"}); err == nil {
+		t.Error("execRunner() expected error for a missing tool")
+	}
+}
+
+func TestPackageResolve(t *testing.T) {
+	if got, err := Resolve("plain"); err != nil || got != "plain" {
+		t.Errorf("Resolve(plain) = %q, %v", got, err)
+	}
+	if k := New(); k.GOOS == "" || k.run == nil {
+		t.Errorf("New() = %+v", k)
+	}
+	// An invalid name fails before any credential store is touched
+	if _, err := Resolve("keyring:bad name"); err == nil {
+		t.Error("Resolve() expected error for an invalid name")
+	}
+}
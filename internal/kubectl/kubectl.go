@@ -0,0 +1,390 @@
+// Package kubectl centralizes kubectl execution with per-call timeouts,
+// retries on transient transport errors, and structured stderr capture.
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
+)
+
+const (
+	// DefaultBinary is the kubectl executable looked up in PATH
+	DefaultBinary = "kubectl"
+
+	// DefaultRetries is the number of retries after a transient failure
+	DefaultRetries = 2
+
+	// DefaultBackoff is the delay before the first retry; it doubles per attempt
+	DefaultBackoff = 500 * time.Millisecond
+
+	// waitDelay bounds how long to wait for I/O after the process is killed
+	waitDelay = 2 * time.Second
+
+	// maxStderrCapture bounds the stderr kept for error reporting
+	maxStderrCapture = 64 * 1024
+)
+
+// transientPatterns are stderr fragments that indicate a transport-level
+// failure (typically a dropped SSH tunnel) rather than a kubectl/API error.
+var transientPatterns = []string{
+	"tls handshake timeout",
+	"tls: handshake",
+	"connection refused",
+	"connection reset by peer",
+	"broken pipe",
+	"unexpected eof",
+	": eof",
+	"i/o timeout",
+	"use of closed network connection",
+	"http2: client connection lost",
+	"http2: server sent goaway",
+	"the server is currently unable to handle the request",
+	"net/http: request canceled while waiting for connection",
+	"unable to connect to the server",
+}
+
+// IsTransient reports whether kubectl stderr output matches a known transient
+// transport error.
+func IsTransient(stderr string) bool {
+	lower := strings.ToLower(stderr)
+	for _, pattern := range transientPatterns {
+		if strings.Contains(lower, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Error describes a failed kubectl invocation
+type Error struct {
+	Args      []string
+	ExitCode  int
+	Stderr    string
+	Attempts  int
+	Transient bool
+	TimedOut  bool
+	Err       error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("kubectl error: %v", e.Err)
+	if e.TimedOut {
+		msg = fmt.Sprintf("kubectl error: timed out: %v", e.Err)
+	}
+	if e.Stderr != "" {
+		msg += fmt.Sprintf(" (stderr: %s)", e.Stderr)
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// StderrLines returns the captured stderr split into non-empty lines
+func (e *Error) StderrLines() []string {
+	var lines []string
+	for _, line := range strings.Split(e.Stderr, "\n") {
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			lines = append(lines, trimmed)
+		}
+	}
+	return lines
+}
+
+// AsError returns the *Error wrapped in err, if any
+func AsError(err error) (*Error, bool) {
+	var kerr *Error
+	if errors.As(err, &kerr) {
+		return kerr, true
+	}
+	return nil, false
+}
+
+// CommandFunc builds the command for a single kubectl attempt
+type CommandFunc func(ctx context.Context, name string, args ...string) *exec.Cmd
+
+// Streams holds the stdio attached to a streaming kubectl invocation
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Runner executes kubectl commands
+type Runner struct {
+	binary  string
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+	recover func(ctx context.Context) error
+	onFail  func(err *Error)
+	command CommandFunc
+	sleep   func(time.Duration)
+	// slots bounds concurrent exec and cp calls; nil means unbounded
+	slots chan struct{}
+}
+
+// Option is a functional option for Runner
+type Option func(*Runner)
+
+// WithBinary overrides the kubectl executable
+func WithBinary(binary string) Option {
+	return func(r *Runner) {
+		r.binary = binary
+	}
+}
+
+// WithTimeout bounds each Output attempt. Streaming Run calls (logs -f,
+// interactive exec) are bounded only by their context. Zero disables the
+// timeout (the default).
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Runner) {
+		r.timeout = timeout
+	}
+}
+
+// WithRetries sets how many times a transient failure is retried
+func WithRetries(retries int) Option {
+	return func(r *Runner) {
+		if retries < 0 {
+			retries = 0
+		}
+		r.retries = retries
+	}
+}
+
+// WithBackoff sets the delay before the first retry
+func WithBackoff(backoff time.Duration) Option {
+	return func(r *Runner) {
+		r.backoff = backoff
+	}
+}
+
+// WithRecover sets a hook run before each retry (e.g. re-establishing an SSH
+// tunnel) with the context of the call. If it returns an error, the failure
+// is returned without retrying.
+func WithRecover(fn func(ctx context.Context) error) Option {
+	return func(r *Runner) {
+		r.recover = fn
+	}
+}
+
+// WithOnFailure sets a hook called after every failed attempt
+func WithOnFailure(fn func(err *Error)) Option {
+	return func(r *Runner) {
+		r.onFail = fn
+	}
+}
+
+// WithCommandFunc overrides how commands are built (for testing)
+func WithCommandFunc(fn CommandFunc) Option {
+	return func(r *Runner) {
+		r.command = fn
+	}
+}
+
+// WithSleep overrides the backoff sleep function (for testing)
+func WithSleep(fn func(time.Duration)) Option {
+	return func(r *Runner) {
+		r.sleep = fn
+	}
+}
+
+// WithMaxParallel bounds how many exec and cp calls run at the same time,
+// across all goroutines sharing the Runner. Zero means unbounded.
+func WithMaxParallel(n int) Option {
+	return func(r *Runner) {
+		r.SetMaxParallel(n)
+	}
+}
+
+// SetMaxParallel changes the WithMaxParallel bound. It must be called before
+// the Runner is used concurrently.
+func (r *Runner) SetMaxParallel(n int) {
+	r.slots = nil
+	if n > 0 {
+		r.slots = make(chan struct{}, n)
+	}
+}
+
+// limited reports whether calls with args count against the parallel bound:
+// exec and cp move data through the API server, so bulk operations fan out
+// over them
+func limited(args []string) bool {
+	switch verb(args) {
+	case "exec", "cp":
+		return true
+	}
+	return false
+}
+
+// New creates a Runner with the given options
+func New(opts ...Option) *Runner {
+	r := &Runner{
+		binary:  DefaultBinary,
+		retries: DefaultRetries,
+		backoff: DefaultBackoff,
+		command: interrupt.Command,
+		sleep:   time.Sleep,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Output runs kubectl and returns stdout. Stderr is captured into the
+// returned *Error on failure.
+func (r *Runner) Output(ctx context.Context, args ...string) ([]byte, error) {
+	var out []byte
+	err := r.do(ctx, args, r.timeout, func(cmd *exec.Cmd, stderr *cappedBuffer) error {
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+		out = stdout.Bytes()
+		return nil
+	})
+	return out, err
+}
+
+// Run runs kubectl with the given streams attached. Stderr is forwarded to
+// streams.Stderr and also captured into the returned *Error on failure.
+func (r *Runner) Run(ctx context.Context, streams Streams, args ...string) error {
+	return r.do(ctx, args, 0, func(cmd *exec.Cmd, stderr *cappedBuffer) error {
+		cmd.Stdin = streams.Stdin
+		cmd.Stdout = streams.Stdout
+		if streams.Stderr != nil {
+			cmd.Stderr = io.MultiWriter(streams.Stderr, stderr)
+		} else {
+			cmd.Stderr = stderr
+		}
+		return cmd.Run()
+	})
+}
+
+func (r *Runner) do(ctx context.Context, args []string, timeout time.Duration, run func(cmd *exec.Cmd, stderr *cappedBuffer) error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// Only the verb is recorded: arguments can carry secret values
+	ctx, span := telemetry.Start(ctx, "kubectl "+verb(args))
+
+	if r.slots != nil && limited(args) {
+		select {
+		case r.slots <- struct{}{}:
+			defer func() { <-r.slots }()
+		case <-ctx.Done():
+			return span.End(ctx.Err())
+		}
+	}
+
+	backoff := r.backoff
+	for attempt := 1; ; attempt++ {
+		kerr := r.attempt(ctx, args, attempt, timeout, run)
+		if kerr == nil {
+			span.SetAttributes(telemetry.Int("kubectl.attempts", attempt))
+			return span.End(nil)
+		}
+		if r.onFail != nil {
+			r.onFail(kerr)
+		}
+		if !kerr.Transient || attempt > r.retries || ctx.Err() != nil {
+			span.SetAttributes(telemetry.Int("kubectl.attempts", attempt))
+			return span.End(kerr)
+		}
+		if r.recover != nil {
+			if err := r.recover(ctx); err != nil {
+				span.SetAttributes(telemetry.Int("kubectl.attempts", attempt))
+				return span.End(kerr)
+			}
+		}
+		if backoff > 0 {
+			r.sleep(backoff)
+			backoff *= 2
+		}
+	}
+}
+
+// verb returns the first positional kubectl argument, skipping global flags
+// and the values of -n/--namespace, --context, and --kubeconfig.
+func verb(args []string) string {
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-n" || arg == "--namespace" || arg == "--context" || arg == "--kubeconfig":
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			return arg
+		}
+	}
+	return ""
+}
+
+func (r *Runner) attempt(ctx context.Context, args []string, attempt int, timeout time.Duration, run func(cmd *exec.Cmd, stderr *cappedBuffer) error) *Error {
+	attemptCtx := ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stderr := &cappedBuffer{limit: maxStderrCapture}
+	cmd := r.command(attemptCtx, r.binary, args...)
+	// Don't block on pipes held open by orphaned children after a kill
+	cmd.WaitDelay = waitDelay
+	err := run(cmd, stderr)
+	if err == nil {
+		return nil
+	}
+
+	kerr := &Error{
+		Args:     append([]string(nil), args...),
+		ExitCode: -1,
+		Stderr:   strings.TrimSpace(stderr.String()),
+		Attempts: attempt,
+		Err:      err,
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		kerr.ExitCode = exitErr.ExitCode()
+	}
+	if errors.Is(attemptCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		// The per-attempt timeout fired; treat like a stalled tunnel
+		kerr.TimedOut = true
+		kerr.Transient = true
+	} else {
+		kerr.Transient = IsTransient(kerr.Stderr)
+	}
+	return kerr
+}
+
+// cappedBuffer keeps at most limit bytes, dropping the oldest output
+type cappedBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.limit; over > 0 {
+		b.buf = b.buf[over:]
+	}
+	return len(p), nil
+}
+
+func (b *cappedBuffer) String() string {
+	return string(b.buf)
+}
@@ -0,0 +1,258 @@
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedCommand returns a CommandFunc that runs the given shell scripts in
+// order, one per attempt, and records the kubectl args it was called with.
+func scriptedCommand(t *testing.T, scripts []string, calls *[][]string) CommandFunc {
+	t.Helper()
+	return func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		i := len(*calls)
+		*calls = append(*calls, args)
+		if i >= len(scripts) {
+			t.Fatalf("unexpected attempt %d", i+1)
+		}
+		return exec.CommandContext(ctx, "sh", "-c", scripts[i])
+	}
+}
+
+func noSleep(time.Duration) {}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   bool
+	}{
+		{"Unable to connect to the server: net/http: TLS handshake timeout", true},
+		{"dial tcp 127.0.0.1:6443: connect: connection refused", true},
+		{"error: unexpected EOF", true},
+		{"Get \"https://127.0.0.1:6443/api\": EOF", true},
+		{"Error from server (NotFound): pods \"x\" not found", false},
+		{"error: unknown flag: --bogus", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.stderr); got != tt.want {
+			t.Errorf("IsTransient(%q) = %v, want %v", tt.stderr, got, tt.want)
+		}
+	}
+}
+
+func TestOutput_Success(t *testing.T) {
+	var calls [][]string
+	r := New(WithCommandFunc(scriptedCommand(t, []string{"echo pod-a"}, &calls)))
+
+	out, err := r.Output(context.Background(), "get", "pods")
+	if err != nil {
+		t.Fatalf("Output() error: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "pod-a" {
+		t.Errorf("Output() = %q, want pod-a", out)
+	}
+	if len(calls) != 1 || strings.Join(calls[0], " ") != "get pods" {
+		t.Errorf("calls = %v", calls)
+	}
+}
+
+func TestOutput_RetriesTransient(t *testing.T) {
+	var calls [][]string
+	recovered := 0
+	failures := 0
+	r := New(
+		WithCommandFunc(scriptedCommand(t, []string{
+			"echo 'net/http: TLS handshake timeout' >&2; exit 1",
+			"echo ok",
+		}, &calls)),
+		WithRecover(func(context.Context) error { recovered++; return nil }),
+		WithOnFailure(func(*Error) { failures++ }),
+		WithSleep(noSleep),
+	)
+
+	out, err := r.Output(context.Background(), "get", "ns")
+	if err != nil {
+		t.Fatalf("Output() error: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "ok" {
+		t.Errorf("Output() = %q, want ok", out)
+	}
+	if len(calls) != 2 || recovered != 1 || failures != 1 {
+		t.Errorf("calls=%d recovered=%d failures=%d, want 2/1/1", len(calls), recovered, failures)
+	}
+}
+
+func TestOutput_NoRetryOnPermanentError(t *testing.T) {
+	var calls [][]string
+	r := New(
+		WithCommandFunc(scriptedCommand(t, []string{
+			"echo 'Error from server (NotFound): pods \"x\" not found' >&2; exit 1",
+		}, &calls)),
+		WithSleep(noSleep),
+	)
+
+	_, err := r.Output(context.Background(), "get", "pod", "x")
+	kerr, ok := AsError(err)
+	if !ok {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if kerr.Transient || kerr.ExitCode != 1 || kerr.Attempts != 1 {
+		t.Errorf("unexpected error fields: %+v", kerr)
+	}
+	if !strings.Contains(kerr.Stderr, "NotFound") {
+		t.Errorf("Stderr = %q, want NotFound", kerr.Stderr)
+	}
+	if !strings.Contains(err.Error(), "kubectl error:") || !strings.Contains(err.Error(), "stderr:") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if len(calls) != 1 {
+		t.Errorf("calls = %d, want 1", len(calls))
+	}
+}
+
+func TestOutput_RetriesExhausted(t *testing.T) {
+	var calls [][]string
+	var slept []time.Duration
+	script := "echo 'connection refused' >&2; exit 1"
+	r := New(
+		WithCommandFunc(scriptedCommand(t, []string{script, script, script}, &calls)),
+		WithRetries(2),
+		WithBackoff(10*time.Millisecond),
+		WithSleep(func(d time.Duration) { slept = append(slept, d) }),
+	)
+
+	_, err := r.Output(context.Background(), "version")
+	kerr, ok := AsError(err)
+	if !ok || !kerr.Transient || kerr.Attempts != 3 {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if len(slept) != 2 || slept[0] != 10*time.Millisecond || slept[1] != 20*time.Millisecond {
+		t.Errorf("backoff = %v, want [10ms 20ms]", slept)
+	}
+}
+
+func TestOutput_RecoverFailureStopsRetry(t *testing.T) {
+	var calls [][]string
+	r := New(
+		WithCommandFunc(scriptedCommand(t, []string{"echo 'unexpected EOF' >&2; exit 1"}, &calls)),
+		WithRecover(func(context.Context) error { return errors.New("no tunnel host") }),
+		WithSleep(noSleep),
+	)
+
+	if _, err := r.Output(context.Background(), "get", "pods"); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(calls) != 1 {
+		t.Errorf("calls = %d, want 1", len(calls))
+	}
+}
+
+func TestOutput_TimeoutIsTransient(t *testing.T) {
+	var calls [][]string
+	r := New(
+		WithCommandFunc(scriptedCommand(t, []string{"exec sleep 5"}, &calls)),
+		WithTimeout(50*time.Millisecond),
+		WithRetries(0),
+	)
+
+	_, err := r.Output(context.Background(), "get", "pods")
+	kerr, ok := AsError(err)
+	if !ok || !kerr.TimedOut || !kerr.Transient {
+		t.Fatalf("expected timed out error, got %#v", err)
+	}
+	if !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Error() = %q", err.Error())
+	}
+}
+
+func TestRun_StreamsAndCapturesStderr(t *testing.T) {
+	var calls [][]string
+	r := New(WithCommandFunc(scriptedCommand(t, []string{"echo out; echo 'denied' >&2; exit 3"}, &calls)))
+
+	var stdout, stderr bytes.Buffer
+	err := r.Run(context.Background(), Streams{Stdout: &stdout, Stderr: &stderr}, "exec", "pod")
+	kerr, ok := AsError(err)
+	if !ok || kerr.ExitCode != 3 {
+		t.Fatalf("unexpected error: %#v", err)
+	}
+	if strings.TrimSpace(stdout.String()) != "out" || strings.TrimSpace(stderr.String()) != "denied" {
+		t.Errorf("stdout=%q stderr=%q", stdout.String(), stderr.String())
+	}
+	if lines := kerr.StderrLines(); len(lines) != 1 || lines[0] != "denied" {
+		t.Errorf("StderrLines() = %v", lines)
+	}
+}
+
+func TestCappedBuffer(t *testing.T) {
+	b := &cappedBuffer{limit: 4}
+	_, _ = b.Write([]byte("abc"))
+	_, _ = b.Write([]byte("def"))
+	if b.String() != "cdef" {
+		t.Errorf("cappedBuffer = %q, want cdef", b.String())
+	}
+}
+
+func TestVerb(t *testing.T) {
+	tests := map[string][]string{
+		"get":  {"get", "pods"},
+		"exec": {"-n", "openclaw", "exec", "-c", "main", "pod"},
+		"top":  {"--context=prod", "--namespace", "openclaw", "top", "pod"},
+		"":     {"--request-timeout=3s"},
+	}
+	for want, args := range tests {
+		if got := verb(args); got != want {
+			t.Errorf("verb(%v) = %q, want %q", args, got, want)
+		}
+	}
+}
+
+func TestMaxParallel(t *testing.T) {
+	dir := t.TempDir()
+	// Each call registers itself in dir and prints how many calls are running
+	script := `touch "$0/$$"; ls "$0" | wc -l; sleep 0.1; rm "$0/$$"`
+	r := New(
+		WithMaxParallel(2),
+		WithCommandFunc(func(ctx context.Context, name string, args ...string) *exec.Cmd {
+			return exec.CommandContext(ctx, "sh", "-c", script, dir)
+		}),
+	)
+
+	outs := make(chan string, 6)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := r.Output(context.Background(), "-n", "openclaw", "exec", "pod", "--", "true")
+			if err != nil {
+				t.Errorf("Output() error: %v", err)
+			}
+			outs <- strings.TrimSpace(string(out))
+		}()
+	}
+	wg.Wait()
+	close(outs)
+	for out := range outs {
+		if out != "1" && out != "2" {
+			t.Errorf("%s exec calls ran at once, want at most 2", out)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.SetMaxParallel(1)
+	r.slots <- struct{}{}
+	if _, err := r.Output(ctx, "cp", "a", "b"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Output() waiting for a slot with a canceled context error = %v", err)
+	}
+	if !limited([]string{"cp", "a", "b"}) || limited([]string{"get", "pods", "--watch"}) {
+		t.Error("limited() should cover exec and cp only")
+	}
+}
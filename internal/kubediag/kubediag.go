@@ -0,0 +1,273 @@
+// Package kubediag explains why the Kubernetes API cannot be reached, step by
+// step: the kubeconfig and its credentials, the SSH tunnel, the TCP
+// connection and the TLS handshake to the API server.
+package kubediag
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/probe"
+)
+
+// DefaultTimeout bounds the TCP connect and TLS handshake of a diagnosis
+const DefaultTimeout = probe.DefaultTimeout
+
+// Step names, in the order they are checked
+const (
+	StepKubeconfig  = "kubeconfig"
+	StepCredentials = "credentials"
+	StepTunnel      = "tunnel"
+	StepTCP         = "tcp"
+	StepTLS         = "tls"
+)
+
+// Step status
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Step is one check of a diagnosis
+type Step struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+}
+
+// Report is the outcome of Diagnose
+type Report struct {
+	Kubeconfig string `json:"kubeconfig"`
+	Context    string `json:"context,omitempty"`
+	Server     string `json:"server,omitempty"`
+	Steps      []Step `json:"steps"`
+	// Probe is the error output of the failed API probe, if any
+	Probe  string   `json:"probe,omitempty"`
+	Causes []string `json:"causes"`
+}
+
+// Options configure Diagnose
+type Options struct {
+	// Kubeconfig is the kubeconfig file; empty uses the first entry of
+	// KUBECONFIG, then ~/.kube/config
+	Kubeconfig string
+	// Context overrides the current-context of the kubeconfig
+	Context string
+	// TunnelSocket is the control socket of the SSH tunnel the API is
+	// expected behind; empty skips the tunnel check
+	TunnelSocket string
+	// TunnelStart is the command starting the tunnel, used in hints
+	TunnelStart string
+	// Probe is the error of the API probe that failed
+	Probe error
+	// Timeout bounds each network step (default DefaultTimeout)
+	Timeout time.Duration
+	// Now returns the current time for certificate expiry (default time.Now)
+	Now func() time.Time
+}
+
+// Step returns the step named name, or nil when it was not checked
+func (r *Report) Step(name string) *Step {
+	for i := range r.Steps {
+		if r.Steps[i].Name == name {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+func (r *Report) add(name, status, format string, args ...any) {
+	r.Steps = append(r.Steps, Step{Name: name, Status: status, Detail: fmt.Sprintf(format, args...)})
+}
+
+func (r *Report) cause(format string, args ...any) {
+	r.Causes = append(r.Causes, fmt.Sprintf(format, args...))
+}
+
+// Format renders the steps and likely causes as indented text
+func (r *Report) Format() string {
+	var b strings.Builder
+	b.WriteString("Diagnosis:\n")
+	for _, s := range r.Steps {
+		fmt.Fprintf(&b, "  %-12s %-8s %s\n", s.Name, s.Status, s.Detail)
+	}
+	if r.Probe != "" {
+		fmt.Fprintf(&b, "  %-12s %-8s %s\n", "probe", StatusFailed, r.Probe)
+	}
+	if len(r.Causes) > 0 {
+		b.WriteString("Likely cause:\n")
+		for _, c := range r.Causes {
+			fmt.Fprintf(&b, "  - %s\n", c)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// Error is an unreachable API with its diagnosis
+type Error struct {
+	Message string
+	Report  *Report
+}
+
+func (e *Error) Error() string {
+	return e.Message + "\n\n" + e.Report.Format()
+}
+
+// Unreachable diagnoses the API and returns message with the diagnosis
+func Unreachable(ctx context.Context, message string, opts Options) error {
+	return &Error{Message: message, Report: Diagnose(ctx, opts)}
+}
+
+// Diagnose checks each step between this machine and the Kubernetes API and
+// lists the likely causes of a failure, most fundamental first. Steps that
+// depend on a failed one are skipped.
+func Diagnose(ctx context.Context, opts Options) *Report {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	r := &Report{Kubeconfig: probe.KubeconfigPath(opts.Kubeconfig)}
+	if opts.Probe != nil {
+		r.Probe = strings.TrimSpace(opts.Probe.Error())
+	}
+
+	target, err := probe.LoadKubeconfig(r.Kubeconfig, opts.Context)
+	if err != nil {
+		r.add(StepKubeconfig, StatusFailed, "%v", err)
+		if errors.Is(err, os.ErrNotExist) {
+			r.cause("no kubeconfig at %s; fetch it from the server (/etc/rancher/k3s/k3s.yaml) or set KUBECONFIG", r.Kubeconfig)
+		} else {
+			r.cause("the kubeconfig %s is unusable: %v", r.Kubeconfig, err)
+		}
+		return r
+	}
+	r.Context, r.Server = target.Context, target.Server
+	r.add(StepKubeconfig, StatusOK, "%s (context %s, server %s)", r.Kubeconfig, target.Context, target.Server)
+
+	checkCredentials(r, target, opts.Now())
+
+	addr, err := target.Addr()
+	if err != nil {
+		r.add(StepTCP, StatusFailed, "%v", err)
+		r.cause("the kubeconfig server URL %q is invalid", target.Server)
+		return r
+	}
+
+	tunnelDown := false
+	if opts.TunnelSocket != "" {
+		if _, err := os.Stat(opts.TunnelSocket); err != nil {
+			tunnelDown = true
+			r.add(StepTunnel, StatusFailed, "no control socket at %s", opts.TunnelSocket)
+		} else {
+			r.add(StepTunnel, StatusOK, "control socket %s", opts.TunnelSocket)
+		}
+	}
+
+	detail, err := probe.TCP{Addr: addr, Timeout: opts.Timeout}.Probe(ctx)
+	if err != nil {
+		r.add(StepTCP, StatusFailed, "%s: %v", addr, err)
+		r.add(StepTLS, StatusSkipped, "no TCP connection")
+		switch {
+		case tunnelDown:
+			r.cause("the SSH tunnel is not running%s", startHint(opts.TunnelStart))
+		case opts.TunnelSocket != "":
+			r.cause("the SSH tunnel runs but nothing answers on %s; k3s may be down on the server (systemctl status k3s) or the tunnel forwards to the wrong port", addr)
+		default:
+			r.cause("%s is not reachable from here; check that k3s runs on the server and that the firewall allows the port", addr)
+		}
+		return r
+	}
+	r.add(StepTCP, StatusOK, "%s", detail)
+	if tunnelDown {
+		r.cause("something other than the SSH tunnel listens on %s", addr)
+	}
+
+	checkTLS(ctx, r, target, addr, opts)
+	if len(r.Causes) == 0 && r.Probe != "" {
+		if strings.Contains(strings.ToLower(r.Probe), "unauthorized") || strings.Contains(strings.ToLower(r.Probe), "forbidden") {
+			r.cause("the API server rejected the credentials; fetch a fresh kubeconfig from the server")
+		} else {
+			r.cause("the API server is reachable but the probe failed: %s", r.Probe)
+		}
+	}
+	return r
+}
+
+func startHint(command string) string {
+	if command == "" {
+		return ""
+	}
+	return "; start it with: " + command
+}
+
+func checkCredentials(r *Report, target probe.Target, now time.Time) {
+	if target.ClientCert == nil {
+		if target.Auth == "" {
+			r.add(StepCredentials, StatusFailed, "context %s has no user credentials", target.Context)
+			r.cause("the kubeconfig user of context %s has no credentials", target.Context)
+			return
+		}
+		r.add(StepCredentials, StatusOK, "%s (not checked)", target.Auth)
+		return
+	}
+	cert, err := parseCertificate(target.ClientCert)
+	if err != nil {
+		r.add(StepCredentials, StatusFailed, "client certificate: %v", err)
+		r.cause("the client certificate in the kubeconfig is unreadable: %v", err)
+		return
+	}
+	if now.After(cert.NotAfter) {
+		r.add(StepCredentials, StatusFailed, "client certificate %s expired %s", cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+		r.cause("the client certificate expired on %s; fetch a fresh kubeconfig from the server (k3s rotates certificates on restart)", cert.NotAfter.UTC().Format("2006-01-02"))
+		return
+	}
+	r.add(StepCredentials, StatusOK, "client certificate %s valid until %s", cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+}
+
+func checkTLS(ctx context.Context, r *Report, target probe.Target, addr string, opts Options) {
+	// Only the server is verified here; the client certificate was checked
+	// on its own and the API may still reject it
+	target.ClientCert, target.ClientKey = nil, nil
+	config, err := target.TLSConfig()
+	if err != nil {
+		r.add(StepTLS, StatusFailed, "%v", err)
+		r.cause("the CA in the kubeconfig is unreadable; fetch a fresh kubeconfig from the server")
+		return
+	}
+	detail, err := probe.TLS{Addr: addr, Config: config, Timeout: opts.Timeout}.Probe(ctx)
+	if err != nil {
+		r.add(StepTLS, StatusFailed, "%v", err)
+		var unknownAuthority x509.UnknownAuthorityError
+		var hostname x509.HostnameError
+		var invalid x509.CertificateInvalidError
+		switch {
+		case errors.As(err, &unknownAuthority):
+			r.cause("the API server certificate is not signed by the kubeconfig CA; the cluster was likely reinstalled, fetch a fresh kubeconfig")
+		case errors.As(err, &hostname):
+			r.cause("the API server certificate is not valid for %s; add it to the k3s tls-san list or connect through the tunnel", config.ServerName)
+		case errors.As(err, &invalid):
+			r.cause("the API server certificate is invalid (%v); check the clock on both machines", invalid)
+		default:
+			r.cause("the TLS handshake with %s failed; the port may not be the Kubernetes API or the tunnel forwards elsewhere", addr)
+		}
+		return
+	}
+	r.add(StepTLS, StatusOK, "%s", detail)
+}
+
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
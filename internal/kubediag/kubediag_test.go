@@ -0,0 +1,306 @@
+package kubediag
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/probe"
+)
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// clientCert returns a PEM client certificate valid until notAfter
+func clientCert(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "system:admin"},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// writeKubeconfig writes a k3s-style kubeconfig for server
+func writeKubeconfig(t *testing.T, server string, ca, cert []byte) string {
+	t.Helper()
+	b64 := base64.StdEncoding.EncodeToString
+	data := `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: ` + b64(ca) + `
+    server: ` + server + `
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+kind: Config
+preferences: {}
+users:
+- name: default
+  user:
+    client-certificate-data: ` + b64(cert) + `
+    client-key-data: c2VjcmV0
+`
+	path := filepath.Join(t.TempDir(), "k3s.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func apiServer(t *testing.T) (*httptest.Server, []byte) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	// The probes hang up after the handshake
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	return srv, ca
+}
+
+// closedAddr returns a local address nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	_ = l.Close()
+	return addr
+}
+
+func statuses(r *Report) map[string]string {
+	got := map[string]string{}
+	for _, s := range r.Steps {
+		got[s.Name] = s.Status
+	}
+	return got
+}
+
+func diagnose(opts Options) *Report {
+	opts.Now = func() time.Time { return now }
+	opts.Timeout = time.Second
+	return Diagnose(context.Background(), opts)
+}
+
+func TestDiagnose_MissingKubeconfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "k3s.yaml")
+	r := diagnose(Options{Kubeconfig: path})
+	if s := r.Step(StepKubeconfig); s == nil || s.Status != StatusFailed || len(r.Steps) != 1 {
+		t.Fatalf("steps = %+v", r.Steps)
+	}
+	if len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "no kubeconfig at "+path) {
+		t.Errorf("causes = %v", r.Causes)
+	}
+}
+
+func TestDiagnose_KubeconfigFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "first.yaml")
+	t.Setenv("KUBECONFIG", path+string(os.PathListSeparator)+"/other.yaml")
+	if r := diagnose(Options{}); r.Kubeconfig != path {
+		t.Errorf("Kubeconfig = %q, want the first KUBECONFIG entry", r.Kubeconfig)
+	}
+}
+
+func TestDiagnose_TunnelDown(t *testing.T) {
+	_, ca := apiServer(t)
+	kubeconfig := writeKubeconfig(t, "https://"+closedAddr(t), ca, clientCert(t, now.AddDate(1, 0, 0)))
+	r := diagnose(Options{
+		Kubeconfig:   kubeconfig,
+		TunnelSocket: filepath.Join(t.TempDir(), "tunnel.ctl"),
+		TunnelStart:  "netcup-kube ssh tunnel start",
+		Probe:        errors.New("The connection to the server 127.0.0.1:6443 was refused"),
+	})
+	want := map[string]string{StepKubeconfig: StatusOK, StepCredentials: StatusOK, StepTunnel: StatusFailed, StepTCP: StatusFailed, StepTLS: StatusSkipped}
+	for name, status := range want {
+		if got := statuses(r)[name]; got != status {
+			t.Errorf("%s = %q, want %q (%+v)", name, got, status, r.Steps)
+		}
+	}
+	if len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "start it with: netcup-kube ssh tunnel start") {
+		t.Errorf("causes = %v", r.Causes)
+	}
+}
+
+func TestDiagnose_TunnelUpButNothingListens(t *testing.T) {
+	_, ca := apiServer(t)
+	socket := filepath.Join(t.TempDir(), "tunnel.ctl")
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	kubeconfig := writeKubeconfig(t, "https://"+closedAddr(t), ca, clientCert(t, now.AddDate(1, 0, 0)))
+	r := diagnose(Options{Kubeconfig: kubeconfig, TunnelSocket: socket})
+	if statuses(r)[StepTunnel] != StatusOK || len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "systemctl status k3s") {
+		t.Errorf("steps = %+v, causes = %v", r.Steps, r.Causes)
+	}
+}
+
+func TestDiagnose_ExpiredClientCertificate(t *testing.T) {
+	srv, ca := apiServer(t)
+	kubeconfig := writeKubeconfig(t, srv.URL, ca, clientCert(t, now.AddDate(0, 0, -3)))
+	r := diagnose(Options{Kubeconfig: kubeconfig, Probe: errors.New("error: You must be logged in to the server (Unauthorized)")})
+	if statuses(r)[StepCredentials] != StatusFailed || statuses(r)[StepTLS] != StatusOK {
+		t.Fatalf("steps = %+v", r.Steps)
+	}
+	if len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "expired on 2026-02-26") {
+		t.Errorf("causes = %v", r.Causes)
+	}
+}
+
+func TestDiagnose_RejectedCredentials(t *testing.T) {
+	srv, ca := apiServer(t)
+	kubeconfig := writeKubeconfig(t, srv.URL, ca, clientCert(t, now.AddDate(1, 0, 0)))
+	r := diagnose(Options{Kubeconfig: kubeconfig, Probe: errors.New("error: You must be logged in to the server (Unauthorized)")})
+	for _, s := range r.Steps {
+		if s.Status != StatusOK {
+			t.Fatalf("step %s = %s: %s", s.Name, s.Status, s.Detail)
+		}
+	}
+	if len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "rejected the credentials") {
+		t.Errorf("causes = %v", r.Causes)
+	}
+}
+
+func TestDiagnose_UnknownServerCertificate(t *testing.T) {
+	srv, _ := apiServer(t)
+	otherCA := clientCert(t, now.AddDate(1, 0, 0))
+	kubeconfig := writeKubeconfig(t, srv.URL, otherCA, clientCert(t, now.AddDate(1, 0, 0)))
+	r := diagnose(Options{Kubeconfig: kubeconfig})
+	if statuses(r)[StepTLS] != StatusFailed || len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "not signed by the kubeconfig CA") {
+		t.Errorf("steps = %+v, causes = %v", r.Steps, r.Causes)
+	}
+}
+
+func TestUnreachableError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "k3s.yaml")
+	err := Unreachable(context.Background(), "kube API still unreachable after tunnel recovery", Options{Kubeconfig: path, Probe: errors.New("refused")})
+	var diag *Error
+	if !errors.As(err, &diag) {
+		t.Fatalf("err = %T", err)
+	}
+	msg := err.Error()
+	for _, want := range []string{"kube API still unreachable after tunnel recovery\n\nDiagnosis:", "kubeconfig   failed", "probe        failed   refused", "Likely cause:\n  - no kubeconfig"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error lacks %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestCheckCredentials(t *testing.T) {
+	tests := []struct {
+		target probe.Target
+		status string
+		cause  string
+	}{
+		{probe.Target{Context: "x"}, StatusFailed, "has no credentials"},
+		{probe.Target{Context: "x", Auth: "bearer token"}, StatusOK, ""},
+		{probe.Target{Context: "x", ClientCert: []byte("not pem")}, StatusFailed, "unreadable: no PEM certificate"},
+		{probe.Target{Context: "x", ClientCert: clientCert(t, now.AddDate(0, 1, 0))}, StatusOK, ""},
+	}
+	for _, tt := range tests {
+		r := &Report{}
+		checkCredentials(r, tt.target, now)
+		if s := r.Step(StepCredentials); s == nil || s.Status != tt.status {
+			t.Errorf("%+v: steps = %+v", tt.target, r.Steps)
+		}
+		if (tt.cause == "") != (len(r.Causes) == 0) || tt.cause != "" && !strings.Contains(r.Causes[0], tt.cause) {
+			t.Errorf("%+v: causes = %v, want %q", tt.target, r.Causes, tt.cause)
+		}
+	}
+	if s := (&Report{}).Step(StepTLS); s != nil {
+		t.Errorf("Step() of an unchecked step = %+v", s)
+	}
+}
+
+func TestDiagnose_BrokenKubeconfig(t *testing.T) {
+	cert := clientCert(t, now.AddDate(1, 0, 0))
+	r := diagnose(Options{Kubeconfig: writeKubeconfig(t, "://bad", cert, cert)})
+	if statuses(r)[StepTCP] != StatusFailed || len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "server URL") {
+		t.Errorf("invalid server: steps = %+v, causes = %v", r.Steps, r.Causes)
+	}
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte("clusters: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r = diagnose(Options{Kubeconfig: path})
+	if statuses(r)[StepKubeconfig] != StatusFailed || len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "is unusable") {
+		t.Errorf("unparsable: steps = %+v, causes = %v", r.Steps, r.Causes)
+	}
+}
+
+func TestDiagnose_TLSFailures(t *testing.T) {
+	srv, _ := apiServer(t)
+	cert := clientCert(t, now.AddDate(1, 0, 0))
+	r := diagnose(Options{Kubeconfig: writeKubeconfig(t, srv.URL, []byte("not pem"), cert)})
+	if statuses(r)[StepTLS] != StatusFailed || len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "CA in the kubeconfig is unreadable") {
+		t.Errorf("bad CA: steps = %+v, causes = %v", r.Steps, r.Causes)
+	}
+
+	// A plain TCP listener fails the handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+			_ = conn.Close()
+		}
+	}()
+	_, ca := apiServer(t)
+	socket := filepath.Join(t.TempDir(), "tunnel.ctl")
+	r = diagnose(Options{Kubeconfig: writeKubeconfig(t, "https://"+l.Addr().String(), ca, cert), TunnelSocket: socket})
+	causes := strings.Join(r.Causes, "\n")
+	if statuses(r)[StepTLS] != StatusFailed || !strings.Contains(causes, "something other than the SSH tunnel") || !strings.Contains(causes, "TLS handshake") {
+		t.Errorf("not TLS: steps = %+v, causes = %v", r.Steps, r.Causes)
+	}
+}
+
+func TestDiagnose_ProbeFailsOnHealthyPath(t *testing.T) {
+	srv, ca := apiServer(t)
+	kubeconfig := writeKubeconfig(t, srv.URL, ca, clientCert(t, now.AddDate(1, 0, 0)))
+	r := diagnose(Options{Kubeconfig: kubeconfig, Probe: errors.New("the server is currently unable to handle the request")})
+	if len(r.Causes) != 1 || !strings.Contains(r.Causes[0], "reachable but the probe failed") {
+		t.Errorf("causes = %v", r.Causes)
+	}
+}
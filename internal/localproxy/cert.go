@@ -0,0 +1,134 @@
+package localproxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	certFileName = "proxy.crt"
+	keyFileName  = "proxy.key"
+
+	certValidity = 365 * 24 * time.Hour
+	// renewBefore regenerates certificates that are close to expiry
+	renewBefore = 7 * 24 * time.Hour
+)
+
+// CertPaths returns the certificate and key paths inside dir
+func CertPaths(dir string) (certFile, keyFile string) {
+	return filepath.Join(dir, certFileName), filepath.Join(dir, keyFileName)
+}
+
+// EnsureSelfSignedCert returns a certificate valid for hosts, reusing the one
+// in dir when it still covers all hosts and is not about to expire.
+func EnsureSelfSignedCert(dir string, hosts []string, now time.Time) (certFile, keyFile string, err error) {
+	certFile, keyFile = CertPaths(dir)
+	if certCovers(certFile, keyFile, hosts, now) {
+		return certFile, keyFile, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate serial: %w", err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "netcup-claw local proxy"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range certHosts(hosts) {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode key: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %w", keyFile, err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %w", certFile, err)
+	}
+	return certFile, keyFile, nil
+}
+
+// MkcertCert issues a locally-trusted certificate for hosts using mkcert.
+// mkcert must be installed and its CA installed (`mkcert -install`).
+func MkcertCert(dir string, hosts []string) (certFile, keyFile string, err error) {
+	if _, err := exec.LookPath("mkcert"); err != nil {
+		return "", "", fmt.Errorf("mkcert not found in PATH (install it or use --tls self-signed)")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	certFile, keyFile = CertPaths(dir)
+	args := append([]string{"-cert-file", certFile, "-key-file", keyFile}, certHosts(hosts)...)
+	if out, err := exec.Command("mkcert", args...).CombinedOutput(); err != nil {
+		return "", "", fmt.Errorf("mkcert failed: %w: %s", err, out)
+	}
+	return certFile, keyFile, nil
+}
+
+// certHosts adds the loopback names every proxy certificate should cover
+func certHosts(hosts []string) []string {
+	all := append([]string{"localhost", "127.0.0.1"}, hosts...)
+	slices.Sort(all)
+	return slices.Compact(all)
+}
+
+// certCovers reports whether the existing certificate is usable for hosts
+func certCovers(certFile, keyFile string, hosts []string, now time.Time) bool {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil || len(pair.Certificate) == 0 {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if now.Before(cert.NotBefore) || now.Add(renewBefore).After(cert.NotAfter) {
+		return false
+	}
+	for _, h := range certHosts(hosts) {
+		if cert.VerifyHostname(h) != nil {
+			return false
+		}
+	}
+	return true
+}
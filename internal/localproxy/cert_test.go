@@ -0,0 +1,46 @@
+package localproxy
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEnsureSelfSignedCertReusesAndRenews(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	certFile, keyFile, err := EnsureSelfSignedCert(dir, []string{"openclaw.localhost"}, now)
+	if err != nil {
+		t.Fatalf("EnsureSelfSignedCert() error: %v", err)
+	}
+	if !certCovers(certFile, keyFile, []string{"openclaw.localhost", "localhost"}, now) {
+		t.Fatal("generated certificate does not cover requested hosts")
+	}
+	first, _ := os.ReadFile(certFile)
+
+	// Same hosts: certificate is reused
+	if _, _, err := EnsureSelfSignedCert(dir, []string{"openclaw.localhost"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(certFile); string(again) != string(first) {
+		t.Error("expected certificate to be reused")
+	}
+
+	// New host: certificate is regenerated
+	if _, _, err := EnsureSelfSignedCert(dir, []string{"openclaw.localhost", "grafana.localhost"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(certFile); string(again) == string(first) {
+		t.Error("expected certificate to be regenerated for a new host")
+	}
+
+	// Near expiry: not considered usable
+	if certCovers(certFile, keyFile, []string{"grafana.localhost"}, now.Add(certValidity-time.Hour)) {
+		t.Error("expected certificate close to expiry to be renewed")
+	}
+
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, %v", info.Mode().Perm(), err)
+	}
+}
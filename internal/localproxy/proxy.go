@@ -0,0 +1,141 @@
+// Package localproxy serves local port-forwards behind a single TLS listener,
+// routing requests by hostname (e.g. openclaw.localhost, grafana.localhost).
+package localproxy
+
+import (
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultDomain is appended to route names without a domain
+const DefaultDomain = "localhost"
+
+// Route maps a hostname to a local port
+type Route struct {
+	Host string
+	Port string
+}
+
+// Target returns the upstream URL for the route
+func (r Route) Target() string {
+	return "http://" + net.JoinHostPort("127.0.0.1", r.Port)
+}
+
+// ParseRoute parses "name=port" or "host.example=port". Names without a dot
+// get the DefaultDomain suffix, so "grafana=3000" routes grafana.localhost.
+func ParseRoute(spec string) (Route, error) {
+	host, port, ok := strings.Cut(strings.TrimSpace(spec), "=")
+	host = strings.ToLower(strings.TrimSpace(host))
+	port = strings.TrimSpace(port)
+	if !ok || host == "" || port == "" {
+		return Route{}, fmt.Errorf("invalid route %q (expected host=port)", spec)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return Route{}, fmt.Errorf("invalid port in route %q", spec)
+	}
+	if !strings.Contains(host, ".") {
+		host += "." + DefaultDomain
+	}
+	return Route{Host: host, Port: port}, nil
+}
+
+// ParseRoutes parses a comma-separated list of routes
+func ParseRoutes(list string) ([]Route, error) {
+	var routes []Route
+	for _, spec := range strings.Split(list, ",") {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		route, err := ParseRoute(spec)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// MergeRoutes combines route lists; later routes override earlier ones for the
+// same host. The result is sorted by host.
+func MergeRoutes(lists ...[]Route) []Route {
+	byHost := map[string]Route{}
+	for _, list := range lists {
+		for _, r := range list {
+			byHost[r.Host] = r
+		}
+	}
+	merged := make([]Route, 0, len(byHost))
+	for _, r := range byHost {
+		merged = append(merged, r)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Host < merged[j].Host })
+	return merged
+}
+
+// Hosts returns the hostnames of the routes
+func Hosts(routes []Route) []string {
+	hosts := make([]string, 0, len(routes))
+	for _, r := range routes {
+		hosts = append(hosts, r.Host)
+	}
+	return hosts
+}
+
+// NewHandler returns a handler that reverse-proxies each request to the route
+// matching its Host header. WebSocket upgrades are passed through.
+func NewHandler(routes []Route) http.Handler {
+	proxies := make(map[string]*httputil.ReverseProxy, len(routes))
+	for _, r := range routes {
+		target, _ := url.Parse(r.Target())
+		route := r
+		proxy := &httputil.ReverseProxy{
+			Rewrite: func(pr *httputil.ProxyRequest) {
+				pr.SetURL(target)
+				pr.SetXForwarded()
+				// Keep the browser-facing host so apps build correct links
+				pr.Out.Host = pr.In.Host
+			},
+			ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+				http.Error(w, fmt.Sprintf("%s: upstream 127.0.0.1:%s is not reachable (is the port-forward running?): %v", route.Host, route.Port, err), http.StatusBadGateway)
+			},
+		}
+		proxies[r.Host] = proxy
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host := strings.ToLower(req.Host)
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if proxy, ok := proxies[host]; ok {
+			proxy.ServeHTTP(w, req)
+			return
+		}
+		writeIndex(w, routes, req.Host)
+	})
+}
+
+// writeIndex lists the configured routes for unknown hosts
+func writeIndex(w http.ResponseWriter, routes []Route, requested string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	port := ""
+	if _, p, err := net.SplitHostPort(requested); err == nil {
+		port = ":" + p
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>netcup-claw proxy</h1><p>No route for %s. Configured routes:</p><ul>", html.EscapeString(requested))
+	for _, r := range routes {
+		link := "https://" + r.Host + port + "/"
+		fmt.Fprintf(&b, `<li><a href="%s">%s</a> &rarr; 127.0.0.1:%s</li>`, html.EscapeString(link), html.EscapeString(r.Host), html.EscapeString(r.Port))
+	}
+	b.WriteString("</ul>")
+	_, _ = w.Write([]byte(b.String()))
+}
@@ -0,0 +1,88 @@
+package localproxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Route
+		wantErr bool
+	}{
+		{spec: "grafana=3000", want: Route{Host: "grafana.localhost", Port: "3000"}},
+		{spec: " Prom.Example.Test = 9090 ", want: Route{Host: "prom.example.test", Port: "9090"}},
+		{spec: "grafana", wantErr: true},
+		{spec: "=3000", wantErr: true},
+		{spec: "grafana=http", wantErr: true},
+		{spec: "grafana=70000", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRoute(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRoute(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRoute(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestMergeRoutesLaterWins(t *testing.T) {
+	extra, err := ParseRoutes("grafana=3001, prometheus=9090,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := MergeRoutes([]Route{{Host: "grafana.localhost", Port: "3000"}, {Host: "openclaw.localhost", Port: "18789"}}, extra)
+	want := []Route{
+		{Host: "grafana.localhost", Port: "3001"},
+		{Host: "openclaw.localhost", Port: "18789"},
+		{Host: "prometheus.localhost", Port: "9090"},
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("merged = %+v", merged)
+	}
+	for i := range want {
+		if merged[i] != want[i] {
+			t.Errorf("merged[%d] = %+v, want %+v", i, merged[i], want[i])
+		}
+	}
+}
+
+func TestHandlerRoutesByHost(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "host="+r.Host+" path="+r.URL.Path)
+	}))
+	defer backend.Close()
+	u, _ := url.Parse(backend.URL)
+
+	handler := NewHandler([]Route{
+		{Host: "openclaw.localhost", Port: u.Port()},
+		{Host: "grafana.localhost", Port: "1"},
+	})
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "https://openclaw.localhost:8443/ui", nil)
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Body.String() != "host=openclaw.localhost:8443 path=/ui" {
+		t.Errorf("routed response = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "https://grafana.localhost:8443/", nil))
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "port-forward") {
+		t.Errorf("down upstream response = %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "https://unknown.localhost:8443/", nil))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "https://openclaw.localhost:8443/") {
+		t.Errorf("index response = %d %q", rec.Code, rec.Body.String())
+	}
+}
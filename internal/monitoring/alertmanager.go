@@ -0,0 +1,433 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/yamlsubset"
+)
+
+// AlertmanagerConfigKey is the Secret key holding the Alertmanager configuration
+const AlertmanagerConfigKey = "alertmanager.yaml"
+
+// AlertmanagerSecretName is the config Secret the chart creates for the release
+func AlertmanagerSecretName(release string) string {
+	return "alertmanager-" + release + "-alertmanager"
+}
+
+// Receiver is an Alertmanager receiver with the notifier types the CLI manages
+type Receiver struct {
+	Name           string          `json:"name"`
+	WebhookConfigs []WebhookConfig `json:"webhook_configs,omitempty"`
+	SlackConfigs   []SlackConfig   `json:"slack_configs,omitempty"`
+	EmailConfigs   []EmailConfig   `json:"email_configs,omitempty"`
+}
+
+// WebhookConfig posts alerts as JSON to URL
+type WebhookConfig struct {
+	URL          string `json:"url"`
+	SendResolved bool   `json:"send_resolved"`
+}
+
+// SlackConfig posts alerts to a Slack incoming webhook
+type SlackConfig struct {
+	APIURL       string `json:"api_url"`
+	Channel      string `json:"channel,omitempty"`
+	SendResolved bool   `json:"send_resolved"`
+}
+
+// EmailConfig sends alerts by mail; empty From/Smarthost fall back to the
+// global smtp_from/smtp_smarthost settings.
+type EmailConfig struct {
+	To           string `json:"to"`
+	From         string `json:"from,omitempty"`
+	Smarthost    string `json:"smarthost,omitempty"`
+	AuthUsername string `json:"auth_username,omitempty"`
+	AuthPassword string `json:"auth_password,omitempty"`
+	SendResolved bool   `json:"send_resolved"`
+}
+
+// Kinds returns a short description of each notifier, e.g. "slack #alerts"
+func (r Receiver) Kinds() []string {
+	var kinds []string
+	for _, c := range r.WebhookConfigs {
+		kinds = append(kinds, "webhook "+urlHost(c.URL))
+	}
+	for _, c := range r.SlackConfigs {
+		kinds = append(kinds, strings.TrimSpace("slack "+c.Channel))
+	}
+	for _, c := range r.EmailConfigs {
+		kinds = append(kinds, "email "+c.To)
+	}
+	return kinds
+}
+
+// urlHost hides URL paths, which for Slack and many webhooks carry the secret
+func urlHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return "(invalid url)"
+	}
+	return u.Host
+}
+
+// Validate checks the receiver has a name and exactly one well-formed notifier
+func (r Receiver) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("receiver name is required")
+	}
+	if n := len(r.WebhookConfigs) + len(r.SlackConfigs) + len(r.EmailConfigs); n != 1 {
+		return fmt.Errorf("receiver %s: exactly one webhook, slack, or email notifier is required (got %d)", r.Name, n)
+	}
+	for _, c := range r.WebhookConfigs {
+		if err := validateURL(c.URL, "http", "https"); err != nil {
+			return fmt.Errorf("receiver %s: webhook url: %w", r.Name, err)
+		}
+	}
+	for _, c := range r.SlackConfigs {
+		if err := validateURL(c.APIURL, "https"); err != nil {
+			return fmt.Errorf("receiver %s: slack api url: %w", r.Name, err)
+		}
+		if c.Channel != "" && !strings.HasPrefix(c.Channel, "#") && !strings.HasPrefix(c.Channel, "@") {
+			return fmt.Errorf("receiver %s: slack channel %q must start with # or @", r.Name, c.Channel)
+		}
+	}
+	for _, c := range r.EmailConfigs {
+		if !strings.Contains(c.To, "@") {
+			return fmt.Errorf("receiver %s: invalid email recipient %q", r.Name, c.To)
+		}
+		if c.From != "" && !strings.Contains(c.From, "@") {
+			return fmt.Errorf("receiver %s: invalid email sender %q", r.Name, c.From)
+		}
+		if c.Smarthost != "" {
+			if _, _, err := net.SplitHostPort(c.Smarthost); err != nil {
+				return fmt.Errorf("receiver %s: smarthost %q must be host:port", r.Name, c.Smarthost)
+			}
+		}
+		if (c.AuthUsername == "") != (c.AuthPassword == "") {
+			return fmt.Errorf("receiver %s: SMTP auth needs both username and password", r.Name)
+		}
+	}
+	return nil
+}
+
+func validateURL(raw string, schemes ...string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q is not an absolute URL", raw)
+	}
+	for _, s := range schemes {
+		if u.Scheme == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q must use %s", raw, strings.Join(schemes, " or "))
+}
+
+// Route is a child route of the top-level Alertmanager route
+type Route struct {
+	Receiver string   `json:"receiver,omitempty"`
+	Matchers []string `json:"matchers,omitempty"`
+	GroupBy  []string `json:"group_by,omitempty"`
+	Continue bool     `json:"continue,omitempty"`
+}
+
+// matcherPattern accepts Alertmanager matchers such as severity="critical"
+// or namespace=~"apps|web"
+var matcherPattern = regexp.MustCompile(`^\s*[a-zA-Z_][a-zA-Z0-9_]*\s*(=~|!~|!=|=)\s*\S.*$`)
+
+// Validate checks the route targets a receiver and its matchers are well formed
+func (r Route) Validate() error {
+	if r.Receiver == "" {
+		return fmt.Errorf("route receiver is required")
+	}
+	for _, m := range r.Matchers {
+		if !matcherPattern.MatchString(m) {
+			return fmt.Errorf("invalid matcher %q (expected label=value, label!=value, label=~regex, or label!~regex)", m)
+		}
+	}
+	return nil
+}
+
+// AlertmanagerConfig is a parsed alertmanager.yaml. Sections the CLI does not
+// manage are kept as they are.
+type AlertmanagerConfig struct {
+	raw map[string]any
+}
+
+// ParseAlertmanagerConfig parses alertmanager.yaml
+func ParseAlertmanagerConfig(data []byte) (*AlertmanagerConfig, error) {
+	doc, err := yamlsubset.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", AlertmanagerConfigKey, err)
+	}
+	raw, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse %s: top level must be a mapping", AlertmanagerConfigKey)
+	}
+	return &AlertmanagerConfig{raw: raw}, nil
+}
+
+// Marshal renders the configuration as YAML
+func (c *AlertmanagerConfig) Marshal() ([]byte, error) {
+	return yamlsubset.Marshal(c.raw)
+}
+
+// Receivers returns the configured receivers
+func (c *AlertmanagerConfig) Receivers() ([]Receiver, error) {
+	var receivers []Receiver
+	if err := convert(c.raw["receivers"], &receivers); err != nil {
+		return nil, fmt.Errorf("invalid receivers: %w", err)
+	}
+	return receivers, nil
+}
+
+// Routes returns the child routes of the top-level route
+func (c *AlertmanagerConfig) Routes() ([]Route, error) {
+	route, _ := c.raw["route"].(map[string]any)
+	var routes []Route
+	if err := convert(route["routes"], &routes); err != nil {
+		return nil, fmt.Errorf("invalid routes: %w", err)
+	}
+	return routes, nil
+}
+
+// AddRoute adds receiver and appends route to the top-level route's children.
+// An existing receiver of the same name is an error unless replace is set, in
+// which case the receiver and the child routes to it are replaced.
+func (c *AlertmanagerConfig) AddRoute(route Route, receiver Receiver, replace bool) error {
+	if err := receiver.Validate(); err != nil {
+		return err
+	}
+	if route.Receiver != receiver.Name {
+		return fmt.Errorf("route receiver %q does not match receiver %q", route.Receiver, receiver.Name)
+	}
+	if err := route.Validate(); err != nil {
+		return err
+	}
+	global, _ := c.raw["global"].(map[string]any)
+	for _, e := range receiver.EmailConfigs {
+		if e.From == "" && global["smtp_from"] == nil {
+			return fmt.Errorf("receiver %s: email sender is required (no global smtp_from)", receiver.Name)
+		}
+		if e.Smarthost == "" && global["smtp_smarthost"] == nil {
+			return fmt.Errorf("receiver %s: smarthost is required (no global smtp_smarthost)", receiver.Name)
+		}
+	}
+
+	top, ok := c.raw["route"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("%s has no top-level route", AlertmanagerConfigKey)
+	}
+	receiverNode, err := toNode(receiver)
+	if err != nil {
+		return err
+	}
+	routeNode, err := toNode(route)
+	if err != nil {
+		return err
+	}
+
+	receivers, _ := c.raw["receivers"].([]any)
+	kept := make([]any, 0, len(receivers)+1)
+	for _, r := range receivers {
+		if m, ok := r.(map[string]any); ok && m["name"] == receiver.Name {
+			if !replace {
+				return fmt.Errorf("receiver %s already exists (use --replace to overwrite it)", receiver.Name)
+			}
+			continue
+		}
+		kept = append(kept, r)
+	}
+	c.raw["receivers"] = append(kept, receiverNode)
+
+	children, _ := top["routes"].([]any)
+	routes := make([]any, 0, len(children)+1)
+	for _, r := range children {
+		if m, ok := r.(map[string]any); ok && m["receiver"] == receiver.Name {
+			continue
+		}
+		routes = append(routes, r)
+	}
+	top["routes"] = append(routes, routeNode)
+	return nil
+}
+
+// toNode converts a typed value into the generic tree used by yamlsubset
+func toNode(v any) (any, error) {
+	var node any
+	if err := convert(v, &node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// convert copies between typed structs and generic trees through JSON
+func convert(from, to any) error {
+	if from == nil {
+		return nil
+	}
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+// ReadAlertmanagerConfig returns alertmanager.yaml from the config Secret
+func ReadAlertmanagerConfig(ctx context.Context, kube Kubectl, namespace, secret string) ([]byte, error) {
+	out, err := kube.Output(ctx, "-n", namespace, "get", "secret", secret, "-o", "jsonpath={.data.alertmanager\\.yaml}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret %s/%s: %w", namespace, secret, err)
+	}
+	encoded := strings.TrimSpace(string(out))
+	if encoded == "" {
+		return nil, fmt.Errorf("secret %s/%s has no %s key", namespace, secret, AlertmanagerConfigKey)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s/%s holds invalid data: %w", namespace, secret, err)
+	}
+	return data, nil
+}
+
+// WriteAlertmanagerConfig replaces alertmanager.yaml in the config Secret
+func WriteAlertmanagerConfig(ctx context.Context, kube Kubectl, namespace, secret string, data []byte) error {
+	patch, err := json.Marshal(map[string]any{
+		"data": map[string]string{AlertmanagerConfigKey: base64.StdEncoding.EncodeToString(data)},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := kube.Output(ctx, "-n", namespace, "patch", "secret", secret, "--type", "merge", "-p", string(patch)); err != nil {
+		return fmt.Errorf("failed to update secret %s/%s: %w", namespace, secret, err)
+	}
+	return nil
+}
+
+// ReloadAlertmanager asks every Alertmanager pod of the release to reload its
+// configuration until the loaded configuration holds receiver as written. The
+// operator copies the Secret into the pods asynchronously, so early reloads
+// may still see the previous configuration.
+func ReloadAlertmanager(ctx context.Context, kube Kubectl, namespace, release string, receiver Receiver, wait, interval time.Duration) error {
+	out, err := kube.Output(ctx, "-n", namespace, "get", "pods", "-l", "alertmanager="+release+"-alertmanager", "-o", "jsonpath={.items[*].metadata.name}")
+	if err != nil {
+		return fmt.Errorf("failed to list alertmanager pods: %w", err)
+	}
+	pods := strings.Fields(string(out))
+	if len(pods) == 0 {
+		return fmt.Errorf("no alertmanager pods found in %s", namespace)
+	}
+
+	deadline := time.Now().Add(wait)
+	for _, pod := range pods {
+		for {
+			exec := []string{"-n", namespace, "exec", pod, "-c", "alertmanager", "--", "wget", "-q", "-O", "-"}
+			if _, err := kube.Output(ctx, append(exec, "--post-data=", "http://127.0.0.1:9093/-/reload")...); err != nil {
+				return fmt.Errorf("failed to reload %s: %w", pod, err)
+			}
+			status, err := kube.Output(ctx, append(exec, "http://127.0.0.1:9093/api/v2/status")...)
+			if err != nil {
+				return fmt.Errorf("failed to read status of %s: %w", pod, err)
+			}
+			if loaded, ok := loadedReceiver(status, receiver.Name); ok && loaded.matches(receiver) {
+				break
+			}
+			if time.Now().After(deadline) {
+				return fmt.Errorf("%s has not loaded receiver %s after %s; the config reloader applies it once the Secret propagates", pod, receiver.Name, wait)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+	}
+	return nil
+}
+
+// receiverItem matches the first line of a receivers entry, e.g. "- name: ops"
+var receiverItem = regexp.MustCompile(`^(\s*)- name: ['"]?([^'"]*)['"]?\s*$`)
+
+// loadedReceiver returns the receiver called name from the configuration an
+// Alertmanager reports in /api/v2/status. Only its block is parsed, since the
+// rest of the loaded configuration (templates, inhibit rules) may fall
+// outside the YAML subset.
+func loadedReceiver(status []byte, name string) (Receiver, bool) {
+	var st struct {
+		Config struct {
+			Original string `json:"original"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(status, &st); err != nil {
+		return Receiver{}, false
+	}
+	lines := strings.Split(st.Config.Original, "\n")
+	for i, line := range lines {
+		m := receiverItem.FindStringSubmatch(line)
+		if m == nil || m[2] != name {
+			continue
+		}
+		block := []string{"receivers:", strings.TrimPrefix(line, m[1])}
+		for _, next := range lines[i+1:] {
+			if strings.TrimSpace(next) != "" && len(next)-len(strings.TrimLeft(next, " ")) <= len(m[1]) {
+				break
+			}
+			block = append(block, strings.TrimPrefix(next, m[1]))
+		}
+		config, err := ParseAlertmanagerConfig([]byte(strings.Join(block, "\n") + "\n"))
+		if err != nil {
+			return Receiver{}, false
+		}
+		receivers, err := config.Receivers()
+		if err != nil || len(receivers) != 1 {
+			return Receiver{}, false
+		}
+		return receivers[0], true
+	}
+	return Receiver{}, false
+}
+
+// loadedSecret is how Alertmanager reports secret settings such as URLs and
+// passwords
+const loadedSecret = "<secret>"
+
+// matches reports whether the loaded receiver r has the notifier of want.
+// Secrets are masked in the loaded configuration and match any value.
+func (r Receiver) matches(want Receiver) bool {
+	if r.Name != want.Name || len(r.WebhookConfigs) != len(want.WebhookConfigs) ||
+		len(r.SlackConfigs) != len(want.SlackConfigs) || len(r.EmailConfigs) != len(want.EmailConfigs) {
+		return false
+	}
+	same := func(got, want string) bool { return got == want || got == loadedSecret }
+	for i, w := range want.WebhookConfigs {
+		g := r.WebhookConfigs[i]
+		if !same(g.URL, w.URL) || g.SendResolved != w.SendResolved {
+			return false
+		}
+	}
+	for i, w := range want.SlackConfigs {
+		g := r.SlackConfigs[i]
+		if !same(g.APIURL, w.APIURL) || g.Channel != w.Channel || g.SendResolved != w.SendResolved {
+			return false
+		}
+	}
+	for i, w := range want.EmailConfigs {
+		g := r.EmailConfigs[i]
+		if g.To != w.To || w.From != "" && g.From != w.From || w.Smarthost != "" && g.Smarthost != w.Smarthost ||
+			g.AuthUsername != w.AuthUsername || !same(g.AuthPassword, w.AuthPassword) || g.SendResolved != w.SendResolved {
+			return false
+		}
+	}
+	return true
+}
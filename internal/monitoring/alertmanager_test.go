@@ -0,0 +1,206 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAlertmanagerConfig = `global:
+  resolve_timeout: 5m
+inhibit_rules:
+- equal:
+  - namespace
+  - alertname
+  source_matchers:
+  - severity = critical
+  target_matchers:
+  - severity =~ warning|info
+receivers:
+- name: "null"
+route:
+  group_by:
+  - namespace
+  receiver: "null"
+  routes:
+  - matchers:
+    - alertname = "Watchdog"
+    receiver: "null"
+templates:
+- /etc/alertmanager/config/*.tmpl
+`
+
+func webhookReceiver(name string) Receiver {
+	return Receiver{Name: name, WebhookConfigs: []WebhookConfig{{URL: "https://hooks.example.com/am", SendResolved: true}}}
+}
+
+func TestAddRoute(t *testing.T) {
+	config, err := ParseAlertmanagerConfig([]byte(testAlertmanagerConfig))
+	if err != nil {
+		t.Fatalf("ParseAlertmanagerConfig() error: %v", err)
+	}
+	route := Route{Receiver: "ops", Matchers: []string{`severity="critical"`}}
+	if err := config.AddRoute(route, webhookReceiver("ops"), false); err != nil {
+		t.Fatalf("AddRoute() error: %v", err)
+	}
+	if err := config.AddRoute(route, webhookReceiver("ops"), false); err == nil || !strings.Contains(err.Error(), "--replace") {
+		t.Errorf("AddRoute() duplicate error = %v", err)
+	}
+
+	out, err := config.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	reparsed, err := ParseAlertmanagerConfig(out)
+	if err != nil {
+		t.Fatalf("ParseAlertmanagerConfig(Marshal()) error: %v\n%s", err, out)
+	}
+	routes, err := reparsed.Routes()
+	if err != nil || len(routes) != 2 || routes[1].Receiver != "ops" || routes[1].Matchers[0] != `severity="critical"` {
+		t.Fatalf("routes = %+v, %v", routes, err)
+	}
+	receivers, err := reparsed.Receivers()
+	if err != nil || len(receivers) != 2 || receivers[0].Name != "null" {
+		t.Fatalf("receivers = %+v, %v", receivers, err)
+	}
+	if kinds := receivers[1].Kinds(); len(kinds) != 1 || kinds[0] != "webhook hooks.example.com" {
+		t.Errorf("kinds = %v", kinds)
+	}
+	for _, want := range []string{"- name: \"null\"\n", "send_resolved: true", "inhibit_rules:", "resolve_timeout: 5m"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	// Replacing swaps the receiver and its route instead of appending
+	replacement := Receiver{Name: "ops", SlackConfigs: []SlackConfig{{APIURL: "https://hooks.slack.com/services/x", Channel: "#ops"}}}
+	if err := reparsed.AddRoute(Route{Receiver: "ops"}, replacement, true); err != nil {
+		t.Fatalf("AddRoute(replace) error: %v", err)
+	}
+	routes, _ = reparsed.Routes()
+	receivers, _ = reparsed.Receivers()
+	if len(routes) != 2 || len(routes[1].Matchers) != 0 || len(receivers) != 2 || receivers[1].Kinds()[0] != "slack #ops" {
+		t.Errorf("after replace: routes = %+v, receivers = %+v", routes, receivers)
+	}
+}
+
+func TestAddRouteValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		route    Route
+		receiver Receiver
+		wantErr  string
+	}{
+		{"no notifier", Route{Receiver: "a"}, Receiver{Name: "a"}, "exactly one"},
+		{"relative webhook", Route{Receiver: "a"}, Receiver{Name: "a", WebhookConfigs: []WebhookConfig{{URL: "/hook"}}}, "absolute"},
+		{"http slack", Route{Receiver: "a"}, Receiver{Name: "a", SlackConfigs: []SlackConfig{{APIURL: "http://hooks.slack.com/x"}}}, "https"},
+		{"slack channel", Route{Receiver: "a"}, Receiver{Name: "a", SlackConfigs: []SlackConfig{{APIURL: "https://hooks.slack.com/x", Channel: "ops"}}}, "# or @"},
+		{"email recipient", Route{Receiver: "a"}, Receiver{Name: "a", EmailConfigs: []EmailConfig{{To: "ops", From: "a@b.c", Smarthost: "smtp:25"}}}, "recipient"},
+		{"email smarthost", Route{Receiver: "a"}, Receiver{Name: "a", EmailConfigs: []EmailConfig{{To: "ops@b.c", From: "a@b.c"}}}, "smarthost is required"},
+		{"email auth", Route{Receiver: "a"}, Receiver{Name: "a", EmailConfigs: []EmailConfig{{To: "ops@b.c", From: "a@b.c", Smarthost: "smtp:25", AuthUsername: "u"}}}, "both"},
+		{"matcher", Route{Receiver: "a", Matchers: []string{"severity"}}, webhookReceiver("a"), "invalid matcher"},
+		{"receiver mismatch", Route{Receiver: "b"}, webhookReceiver("a"), "does not match"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := ParseAlertmanagerConfig([]byte(testAlertmanagerConfig))
+			if err != nil {
+				t.Fatalf("ParseAlertmanagerConfig() error: %v", err)
+			}
+			err = config.AddRoute(tt.route, tt.receiver, false)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("AddRoute() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	config, _ := ParseAlertmanagerConfig([]byte("global:\n  smtp_from: am@example.com\n  smtp_smarthost: smtp.example.com:587\nroute:\n  receiver: \"null\"\n"))
+	mail := Receiver{Name: "mail", EmailConfigs: []EmailConfig{{To: "ops@example.com"}}}
+	if err := config.AddRoute(Route{Receiver: "mail"}, mail, false); err != nil {
+		t.Errorf("AddRoute() with global SMTP settings error: %v", err)
+	}
+
+	config, _ = ParseAlertmanagerConfig([]byte("receivers: []\n"))
+	if err := config.AddRoute(Route{Receiver: "a"}, webhookReceiver("a"), false); err == nil {
+		t.Error("AddRoute() expected error without a top-level route")
+	}
+}
+
+func TestReadWriteAlertmanagerConfig(t *testing.T) {
+	secret := AlertmanagerSecretName(DefaultRelease)
+	if secret != "alertmanager-kube-prometheus-stack-alertmanager" {
+		t.Errorf("AlertmanagerSecretName() = %s", secret)
+	}
+	kube := &fakeKube{outputs: map[string]string{
+		"-n monitoring get secret " + secret:   base64.StdEncoding.EncodeToString([]byte(testAlertmanagerConfig)),
+		"-n monitoring patch secret " + secret: "",
+	}}
+	data, err := ReadAlertmanagerConfig(context.Background(), kube, DefaultNamespace, secret)
+	if err != nil || string(data) != testAlertmanagerConfig {
+		t.Errorf("ReadAlertmanagerConfig() = %q, %v", data, err)
+	}
+	if err := WriteAlertmanagerConfig(context.Background(), kube, DefaultNamespace, secret, data); err != nil {
+		t.Errorf("WriteAlertmanagerConfig() error: %v", err)
+	}
+
+	kube = &fakeKube{outputs: map[string]string{"-n monitoring get secret": ""}}
+	if _, err := ReadAlertmanagerConfig(context.Background(), kube, DefaultNamespace, secret); err == nil {
+		t.Error("ReadAlertmanagerConfig() expected error for missing key")
+	}
+}
+
+func TestReloadAlertmanager(t *testing.T) {
+	statusKey := "-n monitoring exec alertmanager-kube-prometheus-stack-alertmanager-0 -c alertmanager -- wget -q -O - http"
+	status := func(original string) string {
+		data, _ := json.Marshal(map[string]any{"config": map[string]string{"original": original}})
+		return string(data)
+	}
+	kube := &fakeKube{outputs: map[string]string{
+		"-n monitoring get pods": "alertmanager-kube-prometheus-stack-alertmanager-0",
+		"-n monitoring exec alertmanager-kube-prometheus-stack-alertmanager-0 -c alertmanager -- wget -q -O - --post-data=": "",
+		statusKey: status("global:\n  resolve_timeout: 5m\nreceivers:\n- name: slack-critical\n  slack_configs:\n  - api_url: <secret>\n    channel: '#crit'\n    send_resolved: true\n- name: ops\n  webhook_configs:\n  - url: <secret>\n    send_resolved: true\n    http_config:\n      follow_redirects: true\ntemplates:\n- /etc/alertmanager/config/*.tmpl\n"),
+	}}
+	ops := Receiver{Name: "ops", WebhookConfigs: []WebhookConfig{{URL: "https://hooks.example.com/am", SendResolved: true}}}
+	if err := ReloadAlertmanager(context.Background(), kube, DefaultNamespace, DefaultRelease, ops, time.Second, time.Millisecond); err != nil {
+		t.Errorf("ReloadAlertmanager() error: %v", err)
+	}
+
+	// A receiver whose name contains the new one does not count
+	slack := Receiver{Name: "slack", SlackConfigs: []SlackConfig{{APIURL: "https://hooks.slack.com/x", Channel: "#alerts", SendResolved: true}}}
+	if err := ReloadAlertmanager(context.Background(), kube, DefaultNamespace, DefaultRelease, slack, 10*time.Millisecond, time.Millisecond); err == nil {
+		t.Error("ReloadAlertmanager() expected error when the receiver never loads")
+	}
+
+	// A replaced receiver counts once the loaded one has the new settings
+	critical := Receiver{Name: "slack-critical", SlackConfigs: []SlackConfig{{APIURL: "https://hooks.slack.com/x", Channel: "#pager", SendResolved: true}}}
+	if err := ReloadAlertmanager(context.Background(), kube, DefaultNamespace, DefaultRelease, critical, 10*time.Millisecond, time.Millisecond); err == nil {
+		t.Error("ReloadAlertmanager() expected error while the previous receiver is loaded")
+	}
+	critical.SlackConfigs[0].Channel = "#crit"
+	if err := ReloadAlertmanager(context.Background(), kube, DefaultNamespace, DefaultRelease, critical, time.Second, time.Millisecond); err != nil {
+		t.Errorf("ReloadAlertmanager() error: %v", err)
+	}
+
+	kube = &fakeKube{outputs: map[string]string{"-n monitoring get pods": ""}}
+	if err := ReloadAlertmanager(context.Background(), kube, DefaultNamespace, DefaultRelease, ops, time.Second, time.Millisecond); err == nil {
+		t.Error("ReloadAlertmanager() expected error without pods")
+	}
+}
+
+func TestReceiverMatches(t *testing.T) {
+	want := Receiver{Name: "mail", EmailConfigs: []EmailConfig{{To: "ops@example.com", AuthUsername: "am", AuthPassword: "pw", SendResolved: true}}}
+	loaded := Receiver{Name: "mail", EmailConfigs: []EmailConfig{{To: "ops@example.com", From: "am@example.com", Smarthost: "smtp.example.com:587", AuthUsername: "am", AuthPassword: loadedSecret, SendResolved: true}}}
+	if !loaded.matches(want) {
+		t.Error("matches() = false for a loaded receiver with global defaults and a masked password")
+	}
+	loaded.EmailConfigs[0].To = "dev@example.com"
+	if loaded.matches(want) {
+		t.Error("matches() = true for a different recipient")
+	}
+	if (Receiver{Name: "mail"}).matches(want) {
+		t.Error("matches() = true without notifiers")
+	}
+}
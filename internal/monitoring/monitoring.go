@@ -0,0 +1,177 @@
+// Package monitoring locates the Grafana, Prometheus, and Alertmanager
+// services installed by the kube-prometheus-stack recipe and reads the Grafana
+// admin credentials.
+package monitoring
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultNamespace is where the kube-prometheus-stack recipe installs the Helm release
+	DefaultNamespace = "monitoring"
+
+	// DefaultRelease is the Helm release name used by the recipe
+	DefaultRelease = "kube-prometheus-stack"
+
+	// GrafanaIngress is the Traefik ingress the recipe creates for --host
+	GrafanaIngress = "grafana"
+)
+
+// Kubectl runs kubectl against the cluster
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Component is one of the web UIs shipped by kube-prometheus-stack
+type Component struct {
+	Name string
+	// LocalPort is the default local port for port-forward access
+	LocalPort string
+}
+
+// Components lists the UIs in the order they are shown and forwarded
+var Components = []Component{
+	{Name: "grafana", LocalPort: "3000"},
+	{Name: "prometheus", LocalPort: "9090"},
+	{Name: "alertmanager", LocalPort: "9093"},
+}
+
+// LookupComponent returns the component with the given name
+func LookupComponent(name string) (Component, error) {
+	for _, c := range Components {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	names := make([]string, 0, len(Components))
+	for _, c := range Components {
+		names = append(names, c.Name)
+	}
+	return Component{}, fmt.Errorf("unknown component %q (expected one of: %s)", name, strings.Join(names, ", "))
+}
+
+// Service is a component resolved to its Service in the cluster
+type Service struct {
+	Component Component
+	Name      string
+	Port      string
+}
+
+// Target returns the kubectl port-forward target for the service
+func (s Service) Target() string {
+	return "svc/" + s.Name
+}
+
+type serviceList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			ClusterIP string `json:"clusterIP"`
+			Ports     []struct {
+				Port int `json:"port"`
+			} `json:"ports"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// ResolveServices finds the Service for each component in the namespace. The
+// release's own "<release>-<component>" Service wins; otherwise any
+// non-headless Service whose name ends in "-<component>" is used, which covers
+// releases installed under a different name. Components without a Service are
+// omitted from the result.
+func ResolveServices(ctx context.Context, kube Kubectl, namespace, release string) (map[string]Service, error) {
+	out, err := kube.Output(ctx, "-n", namespace, "get", "services", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services in %s: %w", namespace, err)
+	}
+	var list serviceList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse services: %w", err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool { return list.Items[i].Metadata.Name < list.Items[j].Metadata.Name })
+
+	services := map[string]Service{}
+	for _, c := range Components {
+		exact := release + "-" + c.Name
+		for _, item := range list.Items {
+			name := item.Metadata.Name
+			if item.Spec.ClusterIP == "None" || len(item.Spec.Ports) == 0 {
+				continue
+			}
+			if name != exact && !strings.HasSuffix(name, "-"+c.Name) {
+				continue
+			}
+			if _, found := services[c.Name]; found && name != exact {
+				continue
+			}
+			services[c.Name] = Service{Component: c, Name: name, Port: strconv.Itoa(item.Spec.Ports[0].Port)}
+		}
+	}
+	return services, nil
+}
+
+// Credentials are the Grafana admin login
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// GrafanaCredentials reads the admin login from the Grafana Secret, which the
+// chart names after the Grafana Service.
+func GrafanaCredentials(ctx context.Context, kube Kubectl, namespace, secret string) (Credentials, error) {
+	out, err := kube.Output(ctx, "-n", namespace, "get", "secret", secret, "-o", "jsonpath={.data}")
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read secret %s/%s: %w", namespace, secret, err)
+	}
+	var data map[string]string
+	if err := json.Unmarshal(out, &data); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse secret %s/%s: %w", namespace, secret, err)
+	}
+	decode := func(key string) (string, error) {
+		value, ok := data[key]
+		if !ok {
+			return "", fmt.Errorf("secret %s/%s has no %s key", namespace, secret, key)
+		}
+		raw, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return "", fmt.Errorf("secret %s/%s holds an invalid %s: %w", namespace, secret, key, err)
+		}
+		return string(raw), nil
+	}
+	user, err := decode("admin-user")
+	if err != nil {
+		return Credentials{}, err
+	}
+	password, err := decode("admin-password")
+	if err != nil {
+		return Credentials{}, err
+	}
+	return Credentials{User: user, Password: password}, nil
+}
+
+// GrafanaHost returns the host of the recipe's Grafana ingress, or "" when
+// Grafana is not exposed through an ingress.
+func GrafanaHost(ctx context.Context, kube Kubectl, namespace string) (string, error) {
+	out, err := kube.Output(ctx, "-n", namespace, "get", "ingress", GrafanaIngress, "-o", "jsonpath={.spec.rules[0].host}")
+	if err != nil {
+		if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "not found") {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read ingress %s/%s: %w", namespace, GrafanaIngress, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// LocalURL is the address of a component forwarded to localPort
+func LocalURL(localPort string) string {
+	return "http://localhost:" + localPort + "/"
+}
@@ -0,0 +1,121 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeKube struct {
+	outputs map[string]string
+	errs    map[string]error
+}
+
+func (k *fakeKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	call := strings.Join(args, " ")
+	for prefix, err := range k.errs {
+		if strings.HasPrefix(call, prefix) {
+			return nil, err
+		}
+	}
+	for prefix, out := range k.outputs {
+		if strings.HasPrefix(call, prefix) {
+			return []byte(out), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected kubectl %s", call)
+}
+
+const testServices = `{"items":[
+  {"metadata":{"name":"alertmanager-operated"},"spec":{"clusterIP":"None","ports":[{"port":9093}]}},
+  {"metadata":{"name":"kube-prometheus-stack-alertmanager"},"spec":{"clusterIP":"10.43.0.10","ports":[{"port":9093},{"port":8080}]}},
+  {"metadata":{"name":"kube-prometheus-stack-grafana"},"spec":{"clusterIP":"10.43.0.11","ports":[{"port":80}]}},
+  {"metadata":{"name":"kube-prometheus-stack-prometheus"},"spec":{"clusterIP":"10.43.0.12","ports":[{"port":9090}]}},
+  {"metadata":{"name":"legacy-prometheus"},"spec":{"clusterIP":"10.43.0.13","ports":[{"port":9091}]}},
+  {"metadata":{"name":"prometheus-operated"},"spec":{"clusterIP":"None","ports":[{"port":9090}]}}]}`
+
+func TestResolveServices(t *testing.T) {
+	kube := &fakeKube{outputs: map[string]string{"-n monitoring get services": testServices}}
+	services, err := ResolveServices(context.Background(), kube, DefaultNamespace, DefaultRelease)
+	if err != nil {
+		t.Fatalf("ResolveServices() error: %v", err)
+	}
+	want := map[string]string{
+		"grafana":      "svc/kube-prometheus-stack-grafana:80",
+		"prometheus":   "svc/kube-prometheus-stack-prometheus:9090",
+		"alertmanager": "svc/kube-prometheus-stack-alertmanager:9093",
+	}
+	for name, target := range want {
+		svc, ok := services[name]
+		if !ok {
+			t.Errorf("%s not resolved", name)
+			continue
+		}
+		if got := svc.Target() + ":" + svc.Port; got != target {
+			t.Errorf("%s = %s, want %s", name, got, target)
+		}
+	}
+
+	// A release installed under another name falls back to the suffix match
+	services, err = ResolveServices(context.Background(), kube, DefaultNamespace, "other")
+	if err != nil {
+		t.Fatalf("ResolveServices() error: %v", err)
+	}
+	if svc := services["prometheus"]; svc.Name != "kube-prometheus-stack-prometheus" {
+		t.Errorf("fallback prometheus = %+v", svc)
+	}
+
+	kube = &fakeKube{outputs: map[string]string{"-n monitoring get services": `{"items":[]}`}}
+	services, err = ResolveServices(context.Background(), kube, DefaultNamespace, DefaultRelease)
+	if err != nil || len(services) != 0 {
+		t.Errorf("empty namespace = %v, %v", services, err)
+	}
+}
+
+func TestGrafanaCredentials(t *testing.T) {
+	enc := base64.StdEncoding.EncodeToString
+	kube := &fakeKube{outputs: map[string]string{
+		"-n monitoring get secret kube-prometheus-stack-grafana": fmt.Sprintf(`{"admin-password":%q,"admin-user":%q,"ldap-toml":""}`, enc([]byte("s3cret")), enc([]byte("admin"))),
+	}}
+	creds, err := GrafanaCredentials(context.Background(), kube, DefaultNamespace, "kube-prometheus-stack-grafana")
+	if err != nil {
+		t.Fatalf("GrafanaCredentials() error: %v", err)
+	}
+	if creds.User != "admin" || creds.Password != "s3cret" {
+		t.Errorf("creds = %+v", creds)
+	}
+
+	kube = &fakeKube{outputs: map[string]string{"-n monitoring get secret": fmt.Sprintf(`{"admin-user":%q}`, enc([]byte("admin")))}}
+	if _, err := GrafanaCredentials(context.Background(), kube, DefaultNamespace, "grafana"); err == nil || !strings.Contains(err.Error(), "admin-password") {
+		t.Errorf("missing password error = %v", err)
+	}
+}
+
+func TestGrafanaHost(t *testing.T) {
+	kube := &fakeKube{outputs: map[string]string{"-n monitoring get ingress grafana": "grafana.example.com\n"}}
+	if host, err := GrafanaHost(context.Background(), kube, DefaultNamespace); err != nil || host != "grafana.example.com" {
+		t.Errorf("GrafanaHost() = %q, %v", host, err)
+	}
+
+	kube = &fakeKube{errs: map[string]error{"-n monitoring get ingress": fmt.Errorf(`Error from server (NotFound): ingresses "grafana" not found`)}}
+	if host, err := GrafanaHost(context.Background(), kube, DefaultNamespace); err != nil || host != "" {
+		t.Errorf("GrafanaHost() without ingress = %q, %v", host, err)
+	}
+
+	kube = &fakeKube{errs: map[string]error{"-n": fmt.Errorf("connection refused")}}
+	if _, err := GrafanaHost(context.Background(), kube, DefaultNamespace); err == nil {
+		t.Error("GrafanaHost() expected error when the API is unreachable")
+	}
+}
+
+func TestLookupComponent(t *testing.T) {
+	c, err := LookupComponent("alertmanager")
+	if err != nil || c.LocalPort != "9093" {
+		t.Errorf("LookupComponent(alertmanager) = %+v, %v", c, err)
+	}
+	if _, err := LookupComponent("loki"); err == nil || !strings.Contains(err.Error(), "grafana, prometheus, alertmanager") {
+		t.Errorf("LookupComponent(loki) error = %v", err)
+	}
+}
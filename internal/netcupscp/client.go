@@ -0,0 +1,381 @@
+// Package netcupscp is a minimal client for the Netcup Server Control Panel
+// (SCP) REST API, covering the server lookups and power-state changes needed
+// to bring worker vServers up and down.
+//
+// Ordering new vServers is not exposed by the SCP API; servers must be ordered
+// in the customer control panel first and are then addressed by name.
+package netcupscp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the SCP REST API base URL
+	DefaultBaseURL = "https://www.servercontrolpanel.de/scp-core"
+
+	// DefaultTokenURL is the OpenID Connect token endpoint used to exchange a
+	// refresh (offline) token for an access token
+	DefaultTokenURL = "https://www.servercontrolpanel.de/realms/scp/protocol/openid-connect/token"
+
+	// DefaultClientID is the public OIDC client used by the SCP API
+	DefaultClientID = "scp"
+
+	// StateOn and StateOff are the power states accepted by SetState
+	StateOn  = "ON"
+	StateOff = "OFF"
+
+	// LiveStateRunning and LiveStateShutoff are the states reported by GetServer
+	LiveStateRunning = "RUNNING"
+	LiveStateShutoff = "SHUTOFF"
+
+	defaultHTTPTimeout = 30 * time.Second
+	tokenExpirySlack   = 30 * time.Second
+)
+
+// Server is a vServer as returned by the server list
+type Server struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Nickname string `json:"nickname"`
+	Hostname string `json:"hostname"`
+}
+
+// DisplayName returns the nickname if set, otherwise the server name
+func (s Server) DisplayName() string {
+	if strings.TrimSpace(s.Nickname) != "" {
+		return s.Nickname
+	}
+	return s.Name
+}
+
+// LiveInfo holds the runtime state of a server
+type LiveInfo struct {
+	State string `json:"state"`
+}
+
+// IPAddress is a single address assigned to a server
+type IPAddress struct {
+	IP string `json:"ip"`
+}
+
+// ServerDetail is the full server record including live state and addresses
+type ServerDetail struct {
+	Server
+	LiveInfo      *LiveInfo   `json:"serverLiveInfo"`
+	IPv4Addresses []IPAddress `json:"ipv4Addresses"`
+	IPv6Addresses []IPAddress `json:"ipv6Addresses"`
+}
+
+// State returns the live state, or "" if unknown
+func (d *ServerDetail) State() string {
+	if d.LiveInfo == nil {
+		return ""
+	}
+	return strings.ToUpper(d.LiveInfo.State)
+}
+
+// PrimaryIPv4 returns the first IPv4 address, or "" if none is assigned
+func (d *ServerDetail) PrimaryIPv4() string {
+	for _, addr := range d.IPv4Addresses {
+		if ip := strings.TrimSpace(addr.IP); ip != "" {
+			return ip
+		}
+	}
+	return ""
+}
+
+// Task is an asynchronous SCP task returned by state changes
+type Task struct {
+	UUID  string `json:"uuid"`
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// APIError is a non-2xx response from the SCP API
+type APIError struct {
+	StatusCode int
+	Method     string
+	Path       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("netcup SCP %s %s: status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("netcup SCP %s %s: status %d", e.Method, e.Path, e.StatusCode)
+}
+
+// Client talks to the SCP REST API
+type Client struct {
+	baseURL      string
+	tokenURL     string
+	clientID     string
+	refreshToken string
+	httpClient   *http.Client
+	now          func() time.Time
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// Option is a functional option for Client
+type Option func(*Client)
+
+// WithBaseURL overrides the API base URL
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(u, "/")
+	}
+}
+
+// WithTokenURL overrides the OIDC token endpoint
+func WithTokenURL(u string) Option {
+	return func(c *Client) {
+		c.tokenURL = u
+	}
+}
+
+// WithHTTPClient overrides the HTTP client
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithAccessToken uses a pre-issued access token instead of the refresh flow
+func WithAccessToken(token string) Option {
+	return func(c *Client) {
+		c.accessToken = token
+		c.expiresAt = time.Time{}
+	}
+}
+
+// WithClock sets the time source used for token expiry (for testing)
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		c.now = now
+	}
+}
+
+// New creates a client authenticating with an SCP refresh (offline) token
+func New(refreshToken string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:      DefaultBaseURL,
+		tokenURL:     DefaultTokenURL,
+		clientID:     DefaultClientID,
+		refreshToken: refreshToken,
+		httpClient:   &http.Client{Timeout: defaultHTTPTimeout},
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ListServers returns all vServers of the account
+func (c *Client) ListServers(ctx context.Context) ([]Server, error) {
+	var servers []Server
+	if err := c.do(ctx, http.MethodGet, "/api/v1/servers", nil, &servers); err != nil {
+		return nil, err
+	}
+	return servers, nil
+}
+
+// GetServer returns the full record of a server
+func (c *Client) GetServer(ctx context.Context, id int) (*ServerDetail, error) {
+	var detail ServerDetail
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v1/servers/%d", id), nil, &detail); err != nil {
+		return nil, err
+	}
+	return &detail, nil
+}
+
+// FindServer looks up a server by name, nickname, or hostname
+func (c *Client) FindServer(ctx context.Context, name string) (*Server, error) {
+	servers, err := c.ListServers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range servers {
+		s := servers[i]
+		if s.Name == name || s.Nickname == name || s.Hostname == name {
+			return &s, nil
+		}
+	}
+	return nil, fmt.Errorf("server %q not found in SCP account", name)
+}
+
+// SetState changes the power state of a server (StateOn / StateOff)
+func (c *Client) SetState(ctx context.Context, id int, state string) (*Task, error) {
+	var task Task
+	body := map[string]string{"state": state}
+	if err := c.do(ctx, http.MethodPatch, fmt.Sprintf("/api/v1/servers/%d", id), body, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// WaitForState polls the server until its live state matches want
+func (c *Client) WaitForState(ctx context.Context, id int, want string, interval time.Duration) (*ServerDetail, error) {
+	want = strings.ToUpper(want)
+	for {
+		detail, err := c.GetServer(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if detail.State() == want {
+			return detail, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timed out waiting for server %d to reach %s (last state: %s): %w", id, want, detail.State(), ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		if method == http.MethodPatch {
+			req.Header.Set("Content-Type", "application/merge-patch+json")
+		} else {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("netcup SCP %s %s: %w", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read SCP response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &APIError{StatusCode: resp.StatusCode, Method: method, Path: path, Message: errorMessage(data)}
+	}
+	if out == nil || len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode SCP response for %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// token returns a valid access token, refreshing it when expired
+func (c *Client) token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessToken != "" && (c.expiresAt.IsZero() || c.now().Before(c.expiresAt)) {
+		return c.accessToken, nil
+	}
+	if strings.TrimSpace(c.refreshToken) == "" {
+		return "", fmt.Errorf("no SCP credentials: set NETCUP_SCP_REFRESH_TOKEN (or NETCUP_SCP_ACCESS_TOKEN)")
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {c.clientID},
+		"refresh_token": {c.refreshToken},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh SCP access token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to refresh SCP access token: status %d: %s", resp.StatusCode, errorMessage(data))
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return "", fmt.Errorf("token response did not include an access_token")
+	}
+
+	c.accessToken = tok.AccessToken
+	ttl := time.Duration(tok.ExpiresIn) * time.Second
+	if ttl > tokenExpirySlack {
+		ttl -= tokenExpirySlack
+	}
+	c.expiresAt = c.now().Add(ttl)
+	return c.accessToken, nil
+}
+
+// errorMessage extracts a readable message from an error response body
+func errorMessage(data []byte) string {
+	var payload struct {
+		Message          string `json:"message"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(data, &payload); err == nil {
+		switch {
+		case payload.Message != "":
+			return payload.Message
+		case payload.ErrorDescription != "":
+			return payload.ErrorDescription
+		case payload.Error != "":
+			return payload.Error
+		}
+	}
+	msg := strings.TrimSpace(string(data))
+	if len(msg) > 512 {
+		msg = msg[:512]
+	}
+	return msg
+}
@@ -0,0 +1,287 @@
+package netcupscp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestListServers_RefreshesToken(t *testing.T) {
+	var tokenCalls int32
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			atomic.AddInt32(&tokenCalls, 1)
+			body, _ := io.ReadAll(r.Body)
+			form, _ := url.ParseQuery(string(body))
+			if form.Get("grant_type") != "refresh_token" || form.Get("refresh_token") != "refresh-1" || form.Get("client_id") != DefaultClientID {
+				t.Errorf("unexpected token form: %v", form)
+			}
+			_, _ = w.Write([]byte(`{"access_token":"access-1","expires_in":300}`))
+		case "/api/v1/servers":
+			if got := r.Header.Get("Authorization"); got != "Bearer access-1" {
+				t.Errorf("Authorization = %q", got)
+			}
+			_, _ = w.Write([]byte(`[{"id":1,"name":"v2201","nickname":"worker-1","hostname":"w1.example.com"}]`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	c := New("refresh-1", WithBaseURL(srv.URL), WithTokenURL(srv.URL+"/token"))
+	for i := 0; i < 2; i++ {
+		servers, err := c.ListServers(context.Background())
+		if err != nil {
+			t.Fatalf("ListServers() error: %v", err)
+		}
+		if len(servers) != 1 || servers[0].DisplayName() != "worker-1" {
+			t.Fatalf("ListServers() = %+v", servers)
+		}
+	}
+	if tokenCalls != 1 {
+		t.Errorf("token endpoint called %d times, want 1 (token should be reused)", tokenCalls)
+	}
+}
+
+func TestToken_RefreshesAfterExpiry(t *testing.T) {
+	var tokenCalls int32
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			atomic.AddInt32(&tokenCalls, 1)
+			_, _ = w.Write([]byte(`{"access_token":"tok","expires_in":60}`))
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	})
+
+	now := time.Unix(1000, 0)
+	c := New("r", WithBaseURL(srv.URL), WithTokenURL(srv.URL+"/token"), WithClock(func() time.Time { return now }))
+	if _, err := c.ListServers(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := c.ListServers(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if tokenCalls != 2 {
+		t.Errorf("token endpoint called %d times, want 2", tokenCalls)
+	}
+}
+
+func TestStaticAccessToken(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			t.Error("token endpoint should not be called with a static access token")
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer static" {
+			t.Errorf("Authorization = %q", got)
+		}
+		_, _ = w.Write([]byte(`[]`))
+	})
+
+	c := New("", WithBaseURL(srv.URL), WithTokenURL(srv.URL+"/token"), WithAccessToken("static"))
+	if _, err := c.ListServers(context.Background()); err != nil {
+		t.Fatalf("ListServers() error: %v", err)
+	}
+}
+
+func TestNoCredentials(t *testing.T) {
+	c := New("")
+	if _, err := c.ListServers(context.Background()); err == nil || !strings.Contains(err.Error(), "NETCUP_SCP_REFRESH_TOKEN") {
+		t.Fatalf("expected credentials error, got %v", err)
+	}
+}
+
+func TestGetServerAndSetState(t *testing.T) {
+	var patched map[string]string
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/servers/7":
+			_, _ = w.Write([]byte(`{"id":7,"name":"v7","serverLiveInfo":{"state":"running"},"ipv4Addresses":[{"ip":"203.0.113.7"}]}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/v1/servers/7":
+			if ct := r.Header.Get("Content-Type"); ct != "application/merge-patch+json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			_ = json.NewDecoder(r.Body).Decode(&patched)
+			_, _ = w.Write([]byte(`{"uuid":"task-1","state":"PENDING"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	c := New("", WithBaseURL(srv.URL), WithAccessToken("t"))
+	detail, err := c.GetServer(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("GetServer() error: %v", err)
+	}
+	if detail.State() != LiveStateRunning || detail.PrimaryIPv4() != "203.0.113.7" {
+		t.Errorf("GetServer() = %+v", detail)
+	}
+
+	task, err := c.SetState(context.Background(), 7, StateOff)
+	if err != nil {
+		t.Fatalf("SetState() error: %v", err)
+	}
+	if task.UUID != "task-1" || patched["state"] != StateOff {
+		t.Errorf("task=%+v patched=%v", task, patched)
+	}
+}
+
+func TestFindServer(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":1,"name":"v1"},{"id":2,"name":"v2","nickname":"worker-2"}]`))
+	})
+	c := New("", WithBaseURL(srv.URL), WithAccessToken("t"))
+
+	s, err := c.FindServer(context.Background(), "worker-2")
+	if err != nil || s.ID != 2 {
+		t.Fatalf("FindServer(worker-2) = %+v, %v", s, err)
+	}
+	if _, err := c.FindServer(context.Background(), "missing"); err == nil {
+		t.Fatal("FindServer(missing) expected error")
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"access denied"}`))
+	})
+	c := New("", WithBaseURL(srv.URL), WithAccessToken("t"))
+
+	_, err := c.ListServers(context.Background())
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden || apiErr.Message != "access denied" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+}
+
+func TestWaitForState(t *testing.T) {
+	var calls int32
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		state := "SHUTOFF"
+		if atomic.AddInt32(&calls, 1) >= 3 {
+			state = "RUNNING"
+		}
+		_, _ = w.Write([]byte(`{"id":1,"serverLiveInfo":{"state":"` + state + `"}}`))
+	})
+	c := New("", WithBaseURL(srv.URL), WithAccessToken("t"))
+
+	detail, err := c.WaitForState(context.Background(), 1, LiveStateRunning, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitForState() error: %v", err)
+	}
+	if detail.State() != LiveStateRunning || calls != 3 {
+		t.Errorf("state=%s calls=%d", detail.State(), calls)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitForState(ctx, 1, "PAUSED", 5*time.Millisecond); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+}
+
+func TestServerHelpers(t *testing.T) {
+	if got := (Server{Name: "v1", Nickname: " "}).DisplayName(); got != "v1" {
+		t.Errorf("DisplayName() = %q", got)
+	}
+	detail := &ServerDetail{IPv4Addresses: []IPAddress{{IP: " "}}}
+	if detail.State() != "" || detail.PrimaryIPv4() != "" {
+		t.Errorf("empty detail: state=%q ip=%q", detail.State(), detail.PrimaryIPv4())
+	}
+
+	err := &APIError{StatusCode: 500, Method: "GET", Path: "/api/v1/servers"}
+	if got := err.Error(); got != "netcup SCP GET /api/v1/servers: status 500" {
+		t.Errorf("Error() = %q", got)
+	}
+	err.Message = "boom"
+	if got := err.Error(); got != "netcup SCP GET /api/v1/servers: status 500: boom" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestTokenErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status int
+		body   string
+		want   string
+	}{
+		"rejected":       {http.StatusBadRequest, `{"error":"invalid_grant","error_description":"token expired"}`, "status 400: token expired"},
+		"error only":     {http.StatusUnauthorized, `{"error":"invalid_client"}`, "status 401: invalid_client"},
+		"invalid json":   {http.StatusOK, `not json`, "failed to decode token response"},
+		"missing access": {http.StatusOK, `{"expires_in":60}`, "did not include an access_token"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			})
+			c := New("r", WithBaseURL(srv.URL), WithTokenURL(srv.URL+"/token"), WithHTTPClient(srv.Client()))
+			if _, err := c.ListServers(context.Background()); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("ListServers() error = %v, want %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	long := strings.Repeat("x", 600)
+	srv := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/servers":
+			_, _ = w.Write([]byte(`{"not":"a list"}`))
+		case "/api/v1/servers/1":
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte(long))
+		default:
+			w.WriteHeader(http.StatusConflict)
+		}
+	})
+	c := New("", WithBaseURL(srv.URL+"/"), WithAccessToken("t"))
+
+	if _, err := c.ListServers(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to decode SCP response") {
+		t.Errorf("ListServers() error = %v", err)
+	}
+	if _, err := c.FindServer(context.Background(), "v1"); err == nil {
+		t.Error("FindServer() expected error")
+	}
+	_, err := c.GetServer(context.Background(), 1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Message) != 512 {
+		t.Errorf("GetServer() error = %v", err)
+	}
+	if _, err := c.SetState(context.Background(), 2, StateOn); err == nil || !strings.Contains(err.Error(), "status 409") {
+		t.Errorf("SetState() error = %v", err)
+	}
+	if _, err := c.WaitForState(context.Background(), 1, LiveStateRunning, time.Millisecond); err == nil {
+		t.Error("WaitForState() expected error")
+	}
+
+	srv.Close()
+	if _, err := c.ListServers(context.Background()); err == nil {
+		t.Error("ListServers() against a closed server expected error")
+	}
+	c = New("r", WithTokenURL(srv.URL+"/token"))
+	if _, err := c.ListServers(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to refresh") {
+		t.Errorf("ListServers() with an unreachable token endpoint error = %v", err)
+	}
+}
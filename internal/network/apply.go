@@ -0,0 +1,296 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+)
+
+// Host abstracts the side effects needed to inspect and change a node
+type Host interface {
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	// Check runs a read-only command and reports whether it succeeded
+	Check(name string, args ...string) error
+	// Run runs a command that changes host state
+	Run(name string, args ...string) error
+	Output(name string, args ...string) ([]byte, error)
+}
+
+// LocalHost operates on the local machine
+type LocalHost struct {
+	// Context stops the commands when canceled (Ctrl-C); nil means no bound
+	Context context.Context
+}
+
+// ReadFile reads a local file
+func (LocalHost) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// WriteFile writes a local file, creating parent directories
+func (LocalHost) WriteFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
+
+// Check runs a read-only command, discarding its output
+func (h LocalHost) Check(name string, args ...string) error {
+	return h.Run(name, args...)
+}
+
+// Run runs a command, discarding its output
+func (h LocalHost) Run(name string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := h.command(name, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %w (%s)", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Output runs a command and returns its stdout
+func (h LocalHost) Output(name string, args ...string) ([]byte, error) {
+	return h.command(name, args...).Output()
+}
+
+// command builds a process bound to h.Context
+func (h LocalHost) command(name string, args ...string) *exec.Cmd {
+	ctx := h.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return interrupt.Command(ctx, name, args...)
+}
+
+// DryRunHost reads from the wrapped host but only prints changes
+type DryRunHost struct {
+	Host Host
+	Out  io.Writer
+}
+
+// ReadFile reads from the wrapped host
+func (h DryRunHost) ReadFile(path string) ([]byte, error) {
+	return h.Host.ReadFile(path)
+}
+
+// WriteFile prints the file that would be written
+func (h DryRunHost) WriteFile(path string, data []byte, perm os.FileMode) error {
+	fmt.Fprintf(h.Out, "[dry-run] write %s (%04o):\n%s", path, perm, data)
+	return nil
+}
+
+// Check runs the read-only command on the wrapped host
+func (h DryRunHost) Check(name string, args ...string) error {
+	return h.Host.Check(name, args...)
+}
+
+// Run prints the command that would be run
+func (h DryRunHost) Run(name string, args ...string) error {
+	fmt.Fprintf(h.Out, "[dry-run] %s %s\n", name, strings.Join(args, " "))
+	return nil
+}
+
+// Output runs the command on the wrapped host
+func (h DryRunHost) Output(name string, args ...string) ([]byte, error) {
+	return h.Host.Output(name, args...)
+}
+
+// Drift is a single difference between desired and actual state
+type Drift struct {
+	Item string
+	Want string
+	Got  string
+}
+
+func (d Drift) String() string {
+	return fmt.Sprintf("%s: want %s, got %s", d.Item, d.Want, d.Got)
+}
+
+// Detect compares the desired spec with the host and returns all drift
+func Detect(spec Spec, host Host) []Drift {
+	var drift []Drift
+
+	path := spec.ConfigPath()
+	if got, err := host.ReadFile(path); err != nil {
+		drift = append(drift, Drift{Item: path, Want: "managed config", Got: "missing"})
+	} else if !sameContent(got, spec.RenderConfig()) {
+		drift = append(drift, Drift{Item: path, Want: "managed config", Got: "modified"})
+	}
+
+	if !interfaceHasAddress(host, spec.Iface, spec.Address) {
+		drift = append(drift, Drift{Item: "interface " + spec.Iface, Want: "address " + spec.Address, Got: describeAddresses(host, spec.Iface)})
+	}
+
+	if spec.MTU > 0 {
+		if got := readInterfaceMTU(host, spec.Iface); got != fmt.Sprint(spec.MTU) {
+			drift = append(drift, Drift{Item: "interface " + spec.Iface, Want: fmt.Sprintf("mtu %d", spec.MTU), Got: "mtu " + orUnknown(got)})
+		}
+	}
+
+	if !spec.NAT {
+		return drift
+	}
+
+	if got, err := host.ReadFile("/proc/sys/net/ipv4/ip_forward"); err != nil || strings.TrimSpace(string(got)) != "1" {
+		drift = append(drift, Drift{Item: "net.ipv4.ip_forward", Want: "1", Got: orUnknown(strings.TrimSpace(string(got)))})
+	}
+	for _, rule := range spec.NATRules() {
+		if err := host.Check("iptables", ruleArgs("-C", rule)...); err != nil {
+			drift = append(drift, Drift{Item: "iptables " + strings.Join(rule, " "), Want: "present", Got: "missing"})
+		}
+	}
+	if got, err := host.ReadFile(NATHelperPath); err != nil {
+		drift = append(drift, Drift{Item: NATHelperPath, Want: "NAT helper", Got: "missing"})
+	} else if !sameContent(got, spec.RenderNATHelper()) {
+		drift = append(drift, Drift{Item: NATHelperPath, Want: "NAT helper", Got: "modified"})
+	}
+	if err := host.Check("systemctl", "is-enabled", "--quiet", NATUnitName); err != nil {
+		drift = append(drift, Drift{Item: NATUnitName, Want: "enabled", Got: "not enabled"})
+	}
+	return drift
+}
+
+// Apply converges the host to the spec. Steps already in the desired state
+// are skipped, so Apply is safe to re-run.
+func Apply(spec Spec, host Host, out io.Writer) error {
+	path := spec.ConfigPath()
+	want := spec.RenderConfig()
+	if got, err := host.ReadFile(path); err != nil || !sameContent(got, want) {
+		fmt.Fprintf(out, "Writing %s\n", path)
+		if err := host.WriteFile(path, []byte(want), 0o600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		if err := reloadBackend(spec, host, out); err != nil {
+			return err
+		}
+	} else if !interfaceHasAddress(host, spec.Iface, spec.Address) {
+		// Config is in place but not active (e.g. manual `ip addr del`)
+		if err := reloadBackend(spec, host, out); err != nil {
+			return err
+		}
+	}
+
+	if !spec.NAT {
+		return nil
+	}
+
+	fmt.Fprintln(out, "Enabling net.ipv4.ip_forward")
+	if err := host.Run("sysctl", "-w", "net.ipv4.ip_forward=1"); err != nil {
+		return fmt.Errorf("failed to enable ip_forward: %w", err)
+	}
+	for _, rule := range spec.NATRules() {
+		if err := host.Check("iptables", ruleArgs("-C", rule)...); err == nil {
+			continue
+		}
+		fmt.Fprintf(out, "Adding iptables rule: %s\n", strings.Join(rule, " "))
+		if err := host.Run("iptables", ruleArgs("-A", rule)...); err != nil {
+			return fmt.Errorf("failed to add iptables rule: %w", err)
+		}
+	}
+
+	helper := spec.RenderNATHelper()
+	if got, err := host.ReadFile(NATHelperPath); err != nil || !sameContent(got, helper) {
+		fmt.Fprintf(out, "Writing %s\n", NATHelperPath)
+		if err := host.WriteFile(NATHelperPath, []byte(helper), 0o755); err != nil {
+			return fmt.Errorf("failed to write %s: %w", NATHelperPath, err)
+		}
+	}
+	unit := RenderNATUnit()
+	if got, err := host.ReadFile(NATUnitPath); err != nil || !sameContent(got, unit) {
+		fmt.Fprintf(out, "Writing %s\n", NATUnitPath)
+		if err := host.WriteFile(NATUnitPath, []byte(unit), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", NATUnitPath, err)
+		}
+		if err := host.Run("systemctl", "daemon-reload"); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
+	}
+	if err := host.Check("systemctl", "is-enabled", "--quiet", NATUnitName); err != nil {
+		fmt.Fprintf(out, "Enabling %s\n", NATUnitName)
+		if err := host.Run("systemctl", "enable", NATUnitName); err != nil {
+			return fmt.Errorf("failed to enable %s: %w", NATUnitName, err)
+		}
+	}
+	return nil
+}
+
+func reloadBackend(spec Spec, host Host, out io.Writer) error {
+	if spec.Backend == BackendNetplan {
+		fmt.Fprintln(out, "Running netplan apply")
+		if err := host.Run("netplan", "apply"); err != nil {
+			return fmt.Errorf("netplan apply failed: %w", err)
+		}
+		return nil
+	}
+	fmt.Fprintf(out, "Reloading systemd-networkd for %s\n", spec.Iface)
+	if err := host.Run("networkctl", "reload"); err != nil {
+		return fmt.Errorf("networkctl reload failed: %w", err)
+	}
+	if err := host.Run("networkctl", "reconfigure", spec.Iface); err != nil {
+		return fmt.Errorf("networkctl reconfigure failed: %w", err)
+	}
+	return nil
+}
+
+func interfaceHasAddress(host Host, iface, address string) bool {
+	out, err := host.Output("ip", "-4", "-o", "addr", "show", "dev", iface)
+	if err != nil {
+		return false
+	}
+	for _, field := range strings.Fields(string(out)) {
+		if field == address {
+			return true
+		}
+	}
+	return false
+}
+
+func describeAddresses(host Host, iface string) string {
+	out, err := host.Output("ip", "-4", "-o", "addr", "show", "dev", iface)
+	if err != nil {
+		return "interface not found"
+	}
+	var addrs []string
+	fields := strings.Fields(string(out))
+	for i, field := range fields {
+		if field == "inet" && i+1 < len(fields) {
+			addrs = append(addrs, fields[i+1])
+		}
+	}
+	if len(addrs) == 0 {
+		return "no IPv4 address"
+	}
+	return strings.Join(addrs, ", ")
+}
+
+func readInterfaceMTU(host Host, iface string) string {
+	out, err := host.ReadFile(filepath.Join("/sys/class/net", iface, "mtu"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
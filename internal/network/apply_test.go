@@ -0,0 +1,305 @@
+package network
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fakeHost records writes and commands; iptables rules added with -A become
+// visible to -C.
+type fakeHost struct {
+	files   map[string]string
+	addrOut string
+	enabled bool
+	rules   map[string]bool
+	runs    []string
+}
+
+func newFakeHost() *fakeHost {
+	return &fakeHost{files: map[string]string{}, rules: map[string]bool{}}
+}
+
+func (h *fakeHost) ReadFile(path string) ([]byte, error) {
+	data, ok := h.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(data), nil
+}
+
+func (h *fakeHost) WriteFile(path string, data []byte, perm os.FileMode) error {
+	h.files[path] = string(data)
+	return nil
+}
+
+func (h *fakeHost) Check(name string, args ...string) error {
+	switch name {
+	case "iptables":
+		if h.rules[ruleKey(args)] {
+			return nil
+		}
+	case "systemctl":
+		if h.enabled {
+			return nil
+		}
+	}
+	return errors.New("exit status 1")
+}
+
+func (h *fakeHost) Run(name string, args ...string) error {
+	h.runs = append(h.runs, name+" "+strings.Join(args, " "))
+	switch {
+	case name == "iptables":
+		h.rules[ruleKey(args)] = true
+	case name == "systemctl" && len(args) > 0 && args[0] == "enable":
+		h.enabled = true
+	case name == "sysctl":
+		h.files["/proc/sys/net/ipv4/ip_forward"] = "1\n"
+	case name == "netplan" || (name == "networkctl" && args[0] == "reconfigure"):
+		h.addrOut = "3: eth1    inet 10.10.0.1/24 brd 10.10.0.255 scope global eth1"
+	}
+	return nil
+}
+
+func (h *fakeHost) Output(name string, args ...string) ([]byte, error) {
+	if h.addrOut == "" {
+		return nil, errors.New("device not found")
+	}
+	return []byte(h.addrOut), nil
+}
+
+// ruleKey drops the -C/-A action so checks match previously added rules
+func ruleKey(args []string) string {
+	var kept []string
+	for _, a := range args {
+		if a != "-C" && a != "-A" {
+			kept = append(kept, a)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+func natSpec() Spec {
+	return Spec{
+		Iface:       "eth1",
+		Address:     "10.10.0.1/24",
+		Backend:     BackendNetplan,
+		NAT:         true,
+		PrivateCIDR: "10.10.0.0/24",
+		PublicIface: "eth0",
+	}
+}
+
+func TestApplyConvergesAndIsIdempotent(t *testing.T) {
+	spec := natSpec()
+	host := newFakeHost()
+
+	if drift := Detect(spec, host); len(drift) == 0 {
+		t.Fatal("expected drift on a fresh host")
+	}
+
+	var out bytes.Buffer
+	if err := Apply(spec, host, &out); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if drift := Detect(spec, host); len(drift) != 0 {
+		t.Fatalf("expected no drift after apply, got %v", drift)
+	}
+	if host.files[NATUnitPath] == "" || host.files[NATHelperPath] == "" {
+		t.Error("expected NAT helper and unit to be written")
+	}
+
+	host.runs = nil
+	if err := Apply(spec, host, &out); err != nil {
+		t.Fatalf("second Apply: %v", err)
+	}
+	for _, run := range host.runs {
+		if !strings.HasPrefix(run, "sysctl") {
+			t.Errorf("unexpected command on converged host: %s", run)
+		}
+	}
+}
+
+func TestDetectReportsModifiedConfig(t *testing.T) {
+	spec := natSpec()
+	spec.NAT = false
+	host := newFakeHost()
+	host.files[NetplanPath] = "network: {}\n"
+	host.addrOut = "3: eth1    inet 10.10.0.9/24 scope global eth1"
+
+	drift := Detect(spec, host)
+	if len(drift) != 2 {
+		t.Fatalf("expected 2 drift items, got %v", drift)
+	}
+	if drift[0].Got != "modified" {
+		t.Errorf("config drift = %v", drift[0])
+	}
+	if drift[1].Got != "10.10.0.9/24" {
+		t.Errorf("address drift = %v", drift[1])
+	}
+}
+
+func TestDryRunHostDoesNotWrite(t *testing.T) {
+	spec := natSpec()
+	inner := newFakeHost()
+	var out bytes.Buffer
+	host := DryRunHost{Host: inner, Out: &out}
+
+	if err := Apply(spec, host, &out); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if len(inner.files) != 0 || len(inner.runs) != 0 {
+		t.Errorf("dry-run changed host: files=%v runs=%v", inner.files, inner.runs)
+	}
+	if !strings.Contains(out.String(), "[dry-run] write "+NetplanPath) {
+		t.Errorf("expected dry-run output, got:\n%s", out.String())
+	}
+}
+
+// failingHost fails every command whose line starts with fail
+type failingHost struct {
+	*fakeHost
+	fail string
+}
+
+func (h failingHost) Run(name string, args ...string) error {
+	if strings.HasPrefix(name+" "+strings.Join(args, " "), h.fail) {
+		return errors.New("exit status 1")
+	}
+	return h.fakeHost.Run(name, args...)
+}
+
+func (h failingHost) WriteFile(path string, data []byte, perm os.FileMode) error {
+	if path == h.fail {
+		return os.ErrPermission
+	}
+	return h.fakeHost.WriteFile(path, data, perm)
+}
+
+func TestApplyNetworkdReloadsInterface(t *testing.T) {
+	spec := natSpec()
+	spec.Backend, spec.NAT, spec.MTU = BackendNetworkd, false, 1400
+	host := newFakeHost()
+
+	var out bytes.Buffer
+	if err := Apply(spec, host, &out); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	want := []string{"networkctl reload", "networkctl reconfigure eth1"}
+	if strings.Join(host.runs, ",") != strings.Join(want, ",") {
+		t.Errorf("runs = %v, want %v", host.runs, want)
+	}
+	if !strings.Contains(host.files[NetworkdPath], "MTUBytes=1400") {
+		t.Errorf("config = %q", host.files[NetworkdPath])
+	}
+
+	drift := Detect(spec, host)
+	if len(drift) != 1 || drift[0].String() != "interface eth1: want mtu 1400, got mtu unknown" {
+		t.Errorf("Detect() = %v", drift)
+	}
+
+	// A config in place whose address is gone is reloaded again
+	host.addrOut, host.runs = "3: eth1    mtu 1400", nil
+	if err := Apply(spec, host, &out); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if len(host.runs) != 2 {
+		t.Errorf("runs = %v, want a reload", host.runs)
+	}
+	if got := describeAddresses(host, "eth1"); got != "10.10.0.1/24" {
+		t.Errorf("describeAddresses() = %q", got)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	for _, fail := range []string{
+		NetplanPath, "netplan apply", "sysctl", "iptables", NATHelperPath, NATUnitPath,
+		"systemctl daemon-reload", "systemctl enable",
+	} {
+		host := failingHost{fakeHost: newFakeHost(), fail: fail}
+		if err := Apply(natSpec(), host, &bytes.Buffer{}); err == nil {
+			t.Errorf("Apply() with %q failing expected error", fail)
+		}
+	}
+	spec := natSpec()
+	spec.Backend = BackendNetworkd
+	for _, fail := range []string{"networkctl reload", "networkctl reconfigure"} {
+		host := failingHost{fakeHost: newFakeHost(), fail: fail}
+		if err := Apply(spec, host, &bytes.Buffer{}); err == nil {
+			t.Errorf("Apply() with %q failing expected error", fail)
+		}
+	}
+}
+
+func TestDetectOnBareHost(t *testing.T) {
+	host := newFakeHost()
+	host.addrOut = "3: eth1    mtu 1500"
+	drift := Detect(natSpec(), host)
+	got := make([]string, len(drift))
+	for i, d := range drift {
+		got[i] = d.Got
+	}
+	want := "missing,no IPv4 address,unknown,missing,missing,missing,missing,not enabled"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Detect() = %v", drift)
+	}
+
+	host.files[NATHelperPath] = "#!/bin/sh\n"
+	if drift := Detect(natSpec(), host); drift[len(drift)-2].Got != "modified" {
+		t.Errorf("Detect() helper drift = %v", drift[len(drift)-2])
+	}
+}
+
+func TestDryRunHostReadsThrough(t *testing.T) {
+	inner := newFakeHost()
+	inner.files["/etc/hosts"] = "127.0.0.1 localhost\n"
+	inner.rules["FORWARD -j ACCEPT"] = true
+	host := DryRunHost{Host: inner, Out: &bytes.Buffer{}}
+
+	if data, err := host.ReadFile("/etc/hosts"); err != nil || len(data) == 0 {
+		t.Errorf("ReadFile() = %q, %v", data, err)
+	}
+	if err := host.Check("iptables", "-C", "FORWARD", "-j", "ACCEPT"); err != nil {
+		t.Errorf("Check() error: %v", err)
+	}
+	if _, err := host.Output("ip", "addr"); err == nil {
+		t.Error("Output() expected the inner host's error")
+	}
+}
+
+func TestLocalHost(t *testing.T) {
+	var host LocalHost
+	path := t.TempDir() + "/etc/network/file"
+	if err := host.WriteFile(path, []byte("data"), 0o640); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if data, err := host.ReadFile(path); err != nil || string(data) != "data" {
+		t.Errorf("ReadFile() = %q, %v", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("mode = %v, %v", info.Mode(), err)
+	}
+
+	if err := host.Check("true"); err != nil {
+		t.Errorf("Check(true) error: %v", err)
+	}
+	if err := host.Run("sh", "-c", "echo denied >&2; exit 3"); err == nil || !strings.Contains(err.Error(), "(denied)") {
+		t.Errorf("Run() error = %v", err)
+	}
+	if err := host.Run("false"); err == nil || strings.Contains(err.Error(), "(") {
+		t.Errorf("Run(false) error = %v", err)
+	}
+	if out, err := host.Output("echo", "ok"); err != nil || string(out) != "ok\n" {
+		t.Errorf("Output() = %q, %v", out, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := (LocalHost{Context: ctx}).Run("sleep", "10"); err == nil {
+		t.Error("Run() with a canceled context succeeded")
+	}
+}
@@ -0,0 +1,243 @@
+// Package network renders and applies the Netcup vLAN interface and NAT
+// configuration of a node declaratively, and detects drift between the
+// desired state and the host.
+package network
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	// BackendNetplan writes a netplan file and runs `netplan apply`
+	BackendNetplan = "netplan"
+
+	// BackendNetworkd writes a systemd-networkd .network file
+	BackendNetworkd = "networkd"
+
+	// NetplanPath is the netplan file managed by netcup-kube
+	NetplanPath = "/etc/netplan/60-netcup-vlan.yaml"
+
+	// NetworkdPath is the systemd-networkd file managed by netcup-kube
+	NetworkdPath = "/etc/systemd/network/60-netcup-vlan.network"
+
+	// NATHelperPath and NATUnitPath match the files written by bootstrap
+	// (scripts/modules/nat.sh) so both paths manage the same state.
+	NATHelperPath = "/usr/local/sbin/vlan-nat-apply"
+	NATUnitPath   = "/etc/systemd/system/vlan-nat.service"
+	NATUnitName   = "vlan-nat.service"
+
+	managedHeader = "# Managed by netcup-kube network; manual edits are reported as drift."
+)
+
+// Spec is the desired vLAN network state of a node
+type Spec struct {
+	// Iface is the vLAN interface (PRIVATE_IFACE)
+	Iface string
+	// Address is the interface address in CIDR form (e.g. 10.10.0.1/24)
+	Address string
+	// MTU is optional; 0 leaves the MTU unmanaged
+	MTU int
+	// Backend is BackendNetplan or BackendNetworkd
+	Backend string
+
+	// NAT enables egress masquerading for vLAN-only nodes (ENABLE_VLAN_NAT)
+	NAT         bool
+	PrivateCIDR string
+	PublicIface string
+}
+
+// SpecFromEnv builds a Spec from netcup-kube configuration variables:
+// PRIVATE_IFACE, VLAN_ADDRESS (or NODE_IP + PRIVATE_CIDR prefix), VLAN_MTU,
+// NETWORK_BACKEND, ENABLE_VLAN_NAT, PRIVATE_CIDR, PUBLIC_IFACE.
+func SpecFromEnv(env map[string]string) (Spec, error) {
+	get := func(key string) string { return strings.TrimSpace(env[key]) }
+
+	spec := Spec{
+		Iface:       get("PRIVATE_IFACE"),
+		Address:     get("VLAN_ADDRESS"),
+		Backend:     get("NETWORK_BACKEND"),
+		NAT:         isTrue(get("ENABLE_VLAN_NAT")),
+		PrivateCIDR: get("PRIVATE_CIDR"),
+		PublicIface: get("PUBLIC_IFACE"),
+	}
+
+	if spec.Iface == "" {
+		return spec, fmt.Errorf("PRIVATE_IFACE is required (the Netcup vLAN interface, e.g. eth1)")
+	}
+
+	if spec.Address == "" {
+		nodeIP := get("NODE_IP")
+		if nodeIP == "" || spec.PrivateCIDR == "" {
+			return spec, fmt.Errorf("VLAN_ADDRESS (or NODE_IP together with PRIVATE_CIDR) is required")
+		}
+		_, ipnet, err := net.ParseCIDR(spec.PrivateCIDR)
+		if err != nil {
+			return spec, fmt.Errorf("PRIVATE_CIDR is invalid: %s", spec.PrivateCIDR)
+		}
+		ones, _ := ipnet.Mask.Size()
+		spec.Address = fmt.Sprintf("%s/%d", nodeIP, ones)
+	}
+	ip, _, err := net.ParseCIDR(spec.Address)
+	if err != nil || ip.To4() == nil {
+		return spec, fmt.Errorf("vLAN address must be an IPv4 CIDR (e.g. 10.10.0.1/24), got %q", spec.Address)
+	}
+
+	if raw := get("VLAN_MTU"); raw != "" {
+		mtu, err := strconv.Atoi(raw)
+		if err != nil || mtu < 576 || mtu > 9000 {
+			return spec, fmt.Errorf("VLAN_MTU must be between 576 and 9000, got %q", raw)
+		}
+		spec.MTU = mtu
+	}
+
+	switch spec.Backend {
+	case "", "auto":
+		spec.Backend = DetectBackend()
+	case BackendNetplan, BackendNetworkd:
+	default:
+		return spec, fmt.Errorf("NETWORK_BACKEND must be netplan, networkd, or auto, got %q", spec.Backend)
+	}
+
+	if spec.NAT {
+		if spec.PrivateCIDR == "" || spec.PublicIface == "" {
+			return spec, fmt.Errorf("ENABLE_VLAN_NAT=true requires PRIVATE_CIDR and PUBLIC_IFACE")
+		}
+		if _, _, err := net.ParseCIDR(spec.PrivateCIDR); err != nil {
+			return spec, fmt.Errorf("PRIVATE_CIDR is invalid: %s", spec.PrivateCIDR)
+		}
+	}
+	return spec, nil
+}
+
+// DetectBackend returns netplan when /etc/netplan exists, otherwise networkd
+func DetectBackend() string {
+	if info, err := os.Stat("/etc/netplan"); err == nil && info.IsDir() {
+		return BackendNetplan
+	}
+	return BackendNetworkd
+}
+
+func isTrue(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "true", "yes", "y", "on":
+		return true
+	}
+	return false
+}
+
+// ConfigPath returns the interface config file path for the spec's backend
+func (s Spec) ConfigPath() string {
+	if s.Backend == BackendNetplan {
+		return NetplanPath
+	}
+	return NetworkdPath
+}
+
+// RenderConfig renders the interface config file for the spec's backend
+func (s Spec) RenderConfig() string {
+	if s.Backend == BackendNetplan {
+		return s.renderNetplan()
+	}
+	return s.renderNetworkd()
+}
+
+func (s Spec) renderNetplan() string {
+	var b strings.Builder
+	b.WriteString(managedHeader + "\n")
+	b.WriteString("network:\n")
+	b.WriteString("  version: 2\n")
+	b.WriteString("  ethernets:\n")
+	fmt.Fprintf(&b, "    %s:\n", s.Iface)
+	b.WriteString("      dhcp4: false\n")
+	b.WriteString("      addresses:\n")
+	fmt.Fprintf(&b, "        - %s\n", s.Address)
+	if s.MTU > 0 {
+		fmt.Fprintf(&b, "      mtu: %d\n", s.MTU)
+	}
+	return b.String()
+}
+
+func (s Spec) renderNetworkd() string {
+	var b strings.Builder
+	b.WriteString(managedHeader + "\n")
+	b.WriteString("[Match]\n")
+	fmt.Fprintf(&b, "Name=%s\n", s.Iface)
+	b.WriteString("\n[Network]\n")
+	fmt.Fprintf(&b, "Address=%s\n", s.Address)
+	if s.MTU > 0 {
+		b.WriteString("\n[Link]\n")
+		fmt.Fprintf(&b, "MTUBytes=%d\n", s.MTU)
+	}
+	return b.String()
+}
+
+// NATRules returns the iptables rule specs (without -C/-A) for vLAN NAT
+func (s Spec) NATRules() [][]string {
+	if !s.NAT {
+		return nil
+	}
+	return [][]string{
+		{"-t", "nat", "POSTROUTING", "-s", s.PrivateCIDR, "-o", s.PublicIface, "-j", "MASQUERADE"},
+		{"FORWARD", "-s", s.PrivateCIDR, "-o", s.PublicIface, "-j", "ACCEPT"},
+		{"FORWARD", "-d", s.PrivateCIDR, "-m", "state", "--state", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+	}
+}
+
+// ruleArgs inserts the iptables action (-C/-A/-D) before the chain name
+func ruleArgs(action string, rule []string) []string {
+	args := make([]string, 0, len(rule)+1)
+	i := 0
+	if len(rule) >= 2 && rule[0] == "-t" {
+		args = append(args, rule[0], rule[1])
+		i = 2
+	}
+	args = append(args, action)
+	return append(args, rule[i:]...)
+}
+
+// RenderNATHelper renders the boot-time helper that re-applies NAT rules.
+// The output matches the helper written by bootstrap (scripts/modules/nat.sh).
+func (s Spec) RenderNATHelper() string {
+	cidr := shellQuote(s.PrivateCIDR)
+	iface := shellQuote(s.PublicIface)
+	lines := []string{
+		"#!/usr/bin/env bash",
+		"set -euo pipefail",
+		"sysctl -w net.ipv4.ip_forward=1 >/dev/null || true",
+		fmt.Sprintf("iptables -t nat -C POSTROUTING -s %[1]s -o %[2]s -j MASQUERADE 2>/dev/null || iptables -t nat -A POSTROUTING -s %[1]s -o %[2]s -j MASQUERADE", cidr, iface),
+		fmt.Sprintf("iptables -C FORWARD -s %[1]s -o %[2]s -j ACCEPT 2>/dev/null || iptables -A FORWARD -s %[1]s -o %[2]s -j ACCEPT", cidr, iface),
+		fmt.Sprintf("iptables -C FORWARD -d %[1]s -m state --state ESTABLISHED,RELATED -j ACCEPT 2>/dev/null || iptables -A FORWARD -d %[1]s -m state --state ESTABLISHED,RELATED -j ACCEPT", cidr),
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// RenderNATUnit renders the systemd unit that runs the NAT helper at boot
+func RenderNATUnit() string {
+	return `[Unit]
+Description=Apply vLAN NAT rules for vLAN-only nodes
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=` + NATHelperPath + `
+
+[Install]
+WantedBy=multi-user.target
+`
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// sameContent compares file contents ignoring trailing newlines, since the
+// bash writer (write_file) strips them.
+func sameContent(got []byte, want string) bool {
+	return strings.TrimRight(string(got), "\n") == strings.TrimRight(want, "\n")
+}
@@ -0,0 +1,134 @@
+package network
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSpecFromEnv(t *testing.T) {
+	t.Run("derives address from NODE_IP and PRIVATE_CIDR", func(t *testing.T) {
+		spec, err := SpecFromEnv(map[string]string{
+			"PRIVATE_IFACE":   "eth1",
+			"NODE_IP":         "10.10.0.1",
+			"PRIVATE_CIDR":    "10.10.0.0/24",
+			"NETWORK_BACKEND": "networkd",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if spec.Address != "10.10.0.1/24" {
+			t.Errorf("Address = %q, want 10.10.0.1/24", spec.Address)
+		}
+		if spec.Backend != BackendNetworkd {
+			t.Errorf("Backend = %q, want networkd", spec.Backend)
+		}
+	})
+
+	t.Run("explicit address and MTU", func(t *testing.T) {
+		spec, err := SpecFromEnv(map[string]string{
+			"PRIVATE_IFACE":   "eth1",
+			"VLAN_ADDRESS":    "192.168.5.2/16",
+			"VLAN_MTU":        "1400",
+			"NETWORK_BACKEND": "netplan",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if spec.Address != "192.168.5.2/16" || spec.MTU != 1400 {
+			t.Errorf("got %+v", spec)
+		}
+	})
+
+	errCases := map[string]map[string]string{
+		"missing iface":   {"VLAN_ADDRESS": "10.0.0.1/24"},
+		"missing address": {"PRIVATE_IFACE": "eth1"},
+		"ipv6 address":    {"PRIVATE_IFACE": "eth1", "VLAN_ADDRESS": "fd00::1/64"},
+		"bad mtu":         {"PRIVATE_IFACE": "eth1", "VLAN_ADDRESS": "10.0.0.1/24", "VLAN_MTU": "100"},
+		"bad backend":     {"PRIVATE_IFACE": "eth1", "VLAN_ADDRESS": "10.0.0.1/24", "NETWORK_BACKEND": "ifupdown"},
+		"nat without public iface": {
+			"PRIVATE_IFACE": "eth1", "VLAN_ADDRESS": "10.0.0.1/24", "NETWORK_BACKEND": "netplan",
+			"ENABLE_VLAN_NAT": "true", "PRIVATE_CIDR": "10.0.0.0/24",
+		},
+	}
+	for name, env := range errCases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := SpecFromEnv(env); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestRenderConfig(t *testing.T) {
+	spec := Spec{Iface: "eth1", Address: "10.10.0.1/24", MTU: 1400, Backend: BackendNetplan}
+	got := spec.RenderConfig()
+	for _, want := range []string{"    eth1:\n", "        - 10.10.0.1/24\n", "      mtu: 1400\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("netplan config missing %q:\n%s", want, got)
+		}
+	}
+	if spec.ConfigPath() != NetplanPath {
+		t.Errorf("ConfigPath = %q", spec.ConfigPath())
+	}
+
+	spec.Backend = BackendNetworkd
+	got = spec.RenderConfig()
+	for _, want := range []string{"Name=eth1\n", "Address=10.10.0.1/24\n", "MTUBytes=1400\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("networkd config missing %q:\n%s", want, got)
+		}
+	}
+	if spec.ConfigPath() != NetworkdPath {
+		t.Errorf("ConfigPath = %q", spec.ConfigPath())
+	}
+}
+
+func TestRenderNATHelperMatchesBootstrap(t *testing.T) {
+	spec := Spec{NAT: true, PrivateCIDR: "10.10.0.0/24", PublicIface: "eth0"}
+	got := spec.RenderNATHelper()
+	want := "iptables -t nat -C POSTROUTING -s '10.10.0.0/24' -o 'eth0' -j MASQUERADE 2>/dev/null || " +
+		"iptables -t nat -A POSTROUTING -s '10.10.0.0/24' -o 'eth0' -j MASQUERADE\n"
+	if !strings.HasPrefix(got, "#!/usr/bin/env bash\nset -euo pipefail\n") || !strings.Contains(got, want) {
+		t.Errorf("unexpected helper:\n%s", got)
+	}
+}
+
+func TestRuleArgs(t *testing.T) {
+	spec := Spec{NAT: true, PrivateCIDR: "10.10.0.0/24", PublicIface: "eth0"}
+	rules := spec.NATRules()
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(rules))
+	}
+	got := ruleArgs("-C", rules[0])
+	want := []string{"-t", "nat", "-C", "POSTROUTING", "-s", "10.10.0.0/24", "-o", "eth0", "-j", "MASQUERADE"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ruleArgs = %v, want %v", got, want)
+	}
+	if got := ruleArgs("-A", rules[1]); got[0] != "-A" || got[1] != "FORWARD" {
+		t.Errorf("ruleArgs = %v", got)
+	}
+}
+
+func TestSameContentIgnoresTrailingNewlines(t *testing.T) {
+	if !sameContent([]byte("a\nb"), "a\nb\n") {
+		t.Error("expected equal content")
+	}
+	if sameContent([]byte("a\nc\n"), "a\nb\n") {
+		t.Error("expected different content")
+	}
+}
+
+func TestDetectBackend(t *testing.T) {
+	want := BackendNetworkd
+	if info, err := os.Stat("/etc/netplan"); err == nil && info.IsDir() {
+		want = BackendNetplan
+	}
+	if got := DetectBackend(); got != want {
+		t.Errorf("DetectBackend() = %q, want %q", got, want)
+	}
+	if (Spec{}).NATRules() != nil {
+		t.Error("NATRules() without NAT should be nil")
+	}
+}
@@ -0,0 +1,161 @@
+package openclaw
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
+)
+
+// DefaultCacheTTL is the default lifetime of cached resolution results
+const DefaultCacheTTL = 30 * time.Second
+
+// cacheEntry is a single cached resolution result
+type cacheEntry struct {
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// resolveCache stores resolution results in memory and, optionally, in a
+// state file so that separate CLI invocations can share lookups.
+type resolveCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	path    string
+	now     func() time.Time
+	entries map[string]cacheEntry
+	loaded  bool
+}
+
+func newResolveCache(ttl time.Duration, path string, now func() time.Time) *resolveCache {
+	if now == nil {
+		now = time.Now
+	}
+	return &resolveCache{
+		ttl:     ttl,
+		path:    path,
+		now:     now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// get returns a cached value if present and not expired
+func (c *resolveCache) get(key string) (string, bool) {
+	if c == nil || c.ttl <= 0 {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !c.now().Before(entry.ExpiresAt) {
+		delete(c.entries, key)
+		return "", false
+	}
+	return entry.Value, true
+}
+
+// put stores a value with the configured TTL
+func (c *resolveCache) put(key, value string) {
+	if c == nil || c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	c.entries[key] = cacheEntry{Value: value, ExpiresAt: c.now().Add(c.ttl)}
+	c.saveLocked()
+}
+
+// remove drops a single key
+func (c *resolveCache) remove(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	if _, ok := c.entries[key]; !ok {
+		return
+	}
+	delete(c.entries, key)
+	c.saveLocked()
+}
+
+// clear drops all keys with the given prefix
+func (c *resolveCache) clear(prefix string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+	c.saveLocked()
+}
+
+// loadLocked reads the state file once. A missing or corrupt file yields an empty cache.
+func (c *resolveCache) loadLocked() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	if c.path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	var entries map[string]cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return
+	}
+	now := c.now()
+	for key, entry := range entries {
+		if now.Before(entry.ExpiresAt) {
+			c.entries[key] = entry
+		}
+	}
+}
+
+// saveLocked persists the cache to the state file (best-effort)
+func (c *resolveCache) saveLocked() {
+	if c.path == "" {
+		return
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(c.path, data, 0600)
+}
+
+// DefaultCacheFile returns the default resolver state file path for a
+// namespace, in the private runtime directory of netcup-claw. The cache is
+// rebuilt on a miss, so entries left by older versions in /tmp are not moved.
+func DefaultCacheFile(namespace string) string {
+	base := paths.RuntimeDir("netcup-claw")
+	if err := paths.EnsurePrivateDir(base); err != nil {
+		base = os.TempDir()
+	}
+	name := strings.NewReplacer("/", "_", ":", "_", " ", "_").Replace(namespace)
+	return filepath.Join(base, fmt.Sprintf("netcup-claw-resolve-%s.json", name))
+}
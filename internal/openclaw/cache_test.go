@@ -0,0 +1,151 @@
+package openclaw
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.t }
+
+func countingExec(out string, err error, calls *int) ExecFunc {
+	return func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		*calls++
+		return []byte(out), err
+	}
+}
+
+func TestResolvePod_CacheHit(t *testing.T) {
+	calls := 0
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	r := New(DefaultConfig(), countingExec("openclaw-pod-1", nil, &calls),
+		WithCacheTTL(30*time.Second), WithClock(clock.Now))
+
+	for i := 0; i < 3; i++ {
+		pod, err := r.ResolvePod(context.Background())
+		if err != nil {
+			t.Fatalf("ResolvePod() error: %v", err)
+		}
+		if pod != "openclaw-pod-1" {
+			t.Fatalf("ResolvePod() = %q, want %q", pod, "openclaw-pod-1")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("exec called %d times, want 1", calls)
+	}
+}
+
+func TestResolvePod_CacheExpires(t *testing.T) {
+	calls := 0
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	r := New(DefaultConfig(), countingExec("openclaw-pod-1", nil, &calls),
+		WithCacheTTL(30*time.Second), WithClock(clock.Now))
+
+	if _, err := r.ResolvePod(context.Background()); err != nil {
+		t.Fatalf("ResolvePod() error: %v", err)
+	}
+	clock.t = clock.t.Add(31 * time.Second)
+	if _, err := r.ResolvePod(context.Background()); err != nil {
+		t.Fatalf("ResolvePod() error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("exec called %d times, want 2", calls)
+	}
+}
+
+func TestResolvePod_NoCacheByDefault(t *testing.T) {
+	calls := 0
+	r := New(DefaultConfig(), countingExec("openclaw-pod-1", nil, &calls))
+	_, _ = r.ResolvePod(context.Background())
+	_, _ = r.ResolvePod(context.Background())
+	if calls != 2 {
+		t.Errorf("exec called %d times, want 2", calls)
+	}
+}
+
+func TestResolvePod_FailureNotCached(t *testing.T) {
+	calls := 0
+	r := New(DefaultConfig(), countingExec("", fmt.Errorf("kubectl error"), &calls),
+		WithCacheTTL(30*time.Second))
+
+	for i := 0; i < 2; i++ {
+		if _, err := r.ResolvePod(context.Background()); err == nil {
+			t.Fatal("ResolvePod() expected error, got nil")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("exec called %d times, want 2", calls)
+	}
+}
+
+func TestResolveService_ExecErrorNotCached(t *testing.T) {
+	calls := 0
+	r := New(DefaultConfig(), countingExec("", fmt.Errorf("kubectl error"), &calls),
+		WithCacheTTL(30*time.Second))
+
+	_, _ = r.ResolveService(context.Background())
+	_, _ = r.ResolveService(context.Background())
+	if calls != 2 {
+		t.Errorf("exec called %d times, want 2", calls)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	calls := 0
+	r := New(DefaultConfig(), countingExec("openclaw-svc", nil, &calls),
+		WithCacheTTL(30*time.Second))
+
+	_, _ = r.ResolveService(context.Background())
+	r.Invalidate()
+	_, _ = r.ResolveService(context.Background())
+	if calls != 2 {
+		t.Errorf("exec called %d times, want 2", calls)
+	}
+}
+
+func TestCacheFile_SharedAcrossResolvers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolve.json")
+	calls := 0
+	exec := countingExec("openclaw-pod-1", nil, &calls)
+
+	first := New(DefaultConfig(), exec, WithCacheTTL(time.Minute), WithCacheFile(path))
+	if _, err := first.ResolvePod(context.Background()); err != nil {
+		t.Fatalf("ResolvePod() error: %v", err)
+	}
+
+	second := New(DefaultConfig(), exec, WithCacheTTL(time.Minute), WithCacheFile(path))
+	pod, err := second.ResolvePod(context.Background())
+	if err != nil {
+		t.Fatalf("ResolvePod() error: %v", err)
+	}
+	if pod != "openclaw-pod-1" {
+		t.Errorf("ResolvePod() = %q, want %q", pod, "openclaw-pod-1")
+	}
+	if calls != 1 {
+		t.Errorf("exec called %d times, want 1", calls)
+	}
+
+	second.Invalidate()
+	third := New(DefaultConfig(), exec, WithCacheTTL(time.Minute), WithCacheFile(path))
+	_, _ = third.ResolvePod(context.Background())
+	if calls != 2 {
+		t.Errorf("exec called %d times after invalidate, want 2", calls)
+	}
+}
+
+func TestDefaultCacheFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv("NETCUP_CLAW_RUNTIME_DIR", "")
+	got := DefaultCacheFile("openclaw")
+	want := filepath.Join(dir, "netcup-claw", "netcup-claw-resolve-openclaw.json")
+	if got != want {
+		t.Errorf("DefaultCacheFile() = %q, want %q", got, want)
+	}
+}
@@ -1,9 +1,14 @@
 package openclaw
 
-import "os/exec"
+import (
+	"context"
 
-// defaultExec runs an external command and returns its combined output
-func defaultExec(name string, args ...string) ([]byte, error) {
-	cmd := exec.Command(name, args...)
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+)
+
+// defaultExec runs an external command and returns its combined output; the
+// command is stopped when ctx is canceled
+func defaultExec(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := interrupt.Command(ctx, name, args...)
 	return cmd.CombinedOutput()
 }
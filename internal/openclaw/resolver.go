@@ -1,8 +1,12 @@
 package openclaw
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/telemetry"
 )
 
 const (
@@ -43,31 +47,94 @@ func DefaultConfig() Config {
 }
 
 // ExecFunc is the function signature for running external commands
-type ExecFunc func(name string, args ...string) ([]byte, error)
+type ExecFunc func(ctx context.Context, name string, args ...string) ([]byte, error)
 
 // Resolver handles OpenClaw service and pod discovery
 type Resolver struct {
 	cfg      Config
 	execFunc ExecFunc
+
+	cacheTTL  time.Duration
+	cacheFile string
+	now       func() time.Time
+	cache     *resolveCache
+}
+
+// Option is a functional option for Resolver
+type Option func(*Resolver)
+
+// WithCacheTTL enables caching of resolution results for the given duration.
+// A zero or negative TTL disables caching (the default).
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(r *Resolver) {
+		r.cacheTTL = ttl
+	}
+}
+
+// WithCacheFile persists cached resolution results to the given state file,
+// so repeated CLI invocations can reuse them until they expire.
+func WithCacheFile(path string) Option {
+	return func(r *Resolver) {
+		r.cacheFile = path
+	}
+}
+
+// WithClock sets the time source used for cache expiry (for testing)
+func WithClock(now func() time.Time) Option {
+	return func(r *Resolver) {
+		r.now = now
+	}
 }
 
 // New creates a new Resolver with the given configuration and exec function.
 // If execFunc is nil, a default exec function using os/exec is used.
-func New(cfg Config, execFunc ExecFunc) *Resolver {
+func New(cfg Config, execFunc ExecFunc, opts ...Option) *Resolver {
 	if execFunc == nil {
 		execFunc = defaultExec
 	}
-	return &Resolver{
+	r := &Resolver{
 		cfg:      cfg,
 		execFunc: execFunc,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.cacheTTL > 0 {
+		r.cache = newResolveCache(r.cacheTTL, r.cacheFile, r.now)
+	}
+	return r
+}
+
+// cacheKey returns the cache key for a lookup kind under the current config
+func (r *Resolver) cacheKey(kind string) string {
+	return r.cachePrefix() + kind
+}
+
+// cachePrefix scopes cache keys to the namespace and label selector
+func (r *Resolver) cachePrefix() string {
+	return r.cfg.Namespace + "|" + r.cfg.LabelSelector + "|"
+}
+
+// Invalidate drops cached resolution results for this resolver's namespace and
+// selector. Callers should invalidate after an operation against a resolved
+// pod or service fails, since the target may have been rescheduled.
+func (r *Resolver) Invalidate() {
+	r.cache.clear(r.cachePrefix())
 }
 
 // ResolveService resolves the OpenClaw service target.
 // It first tries label-based discovery and falls back to the configured fallback service.
-func (r *Resolver) ResolveService() (string, error) {
+func (r *Resolver) ResolveService(ctx context.Context) (string, error) {
+	key := r.cacheKey("svc")
+	if cached, ok := r.cache.get(key); ok {
+		return cached, nil
+	}
+
+	ctx, span := telemetry.Start(ctx, "resolve service")
+	defer span.End(nil)
+
 	// Try label-based discovery
-	out, err := r.execFunc("kubectl",
+	out, err := r.execFunc(ctx, "kubectl",
 		"-n", r.cfg.Namespace,
 		"get", "svc",
 		"-l", r.cfg.LabelSelector,
@@ -76,33 +143,46 @@ func (r *Resolver) ResolveService() (string, error) {
 	if err == nil {
 		name := strings.TrimSpace(string(out))
 		if name != "" {
+			r.cache.put(key, "svc/"+name)
 			return "svc/" + name, nil
 		}
+		r.cache.put(key, r.cfg.FallbackSvc)
+		return r.cfg.FallbackSvc, nil
 	}
 
-	// Fallback to configured service
+	// Fallback to configured service; lookup failed, so do not cache
+	r.cache.remove(key)
 	return r.cfg.FallbackSvc, nil
 }
 
 // ResolvePod resolves the main OpenClaw pod name.
 // It uses label-based discovery and returns an error if no pod is found.
-func (r *Resolver) ResolvePod() (string, error) {
-	out, err := r.execFunc("kubectl",
+func (r *Resolver) ResolvePod(ctx context.Context) (string, error) {
+	key := r.cacheKey("pod")
+	if cached, ok := r.cache.get(key); ok {
+		return cached, nil
+	}
+
+	ctx, span := telemetry.Start(ctx, "resolve pod")
+	out, err := r.execFunc(ctx, "kubectl",
 		"-n", r.cfg.Namespace,
 		"get", "pod",
 		"-l", r.cfg.LabelSelector,
 		"-o", "jsonpath={.items[0].metadata.name}",
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed to list pods in namespace %s: %w", r.cfg.Namespace, err)
+		r.cache.remove(key)
+		return "", span.End(fmt.Errorf("failed to list pods in namespace %s: %w", r.cfg.Namespace, err))
 	}
 
 	name := strings.TrimSpace(string(out))
 	if name == "" {
-		return "", fmt.Errorf("no pod found with label %s in namespace %s", r.cfg.LabelSelector, r.cfg.Namespace)
+		r.cache.remove(key)
+		return "", span.End(fmt.Errorf("no pod found with label %s in namespace %s", r.cfg.LabelSelector, r.cfg.Namespace))
 	}
 
-	return name, nil
+	r.cache.put(key, name)
+	return name, span.End(nil)
 }
 
 // Config returns the resolver configuration
@@ -1,6 +1,7 @@
 package openclaw
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
@@ -28,7 +29,7 @@ func TestDefaultConfig(t *testing.T) {
 func TestNew(t *testing.T) {
 	cfg := DefaultConfig()
 	called := false
-	execFn := func(name string, args ...string) ([]byte, error) {
+	execFn := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		called = true
 		return nil, nil
 	}
@@ -58,12 +59,12 @@ func TestNew_NilExecFunc(t *testing.T) {
 
 func TestResolveService_LabelFound(t *testing.T) {
 	cfg := DefaultConfig()
-	execFn := func(name string, args ...string) ([]byte, error) {
+	execFn := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte("openclaw-svc"), nil
 	}
 
 	r := New(cfg, execFn)
-	svc, err := r.ResolveService()
+	svc, err := r.ResolveService(context.Background())
 
 	if err != nil {
 		t.Fatalf("ResolveService() unexpected error: %v", err)
@@ -75,12 +76,12 @@ func TestResolveService_LabelFound(t *testing.T) {
 
 func TestResolveService_LabelEmpty(t *testing.T) {
 	cfg := DefaultConfig()
-	execFn := func(name string, args ...string) ([]byte, error) {
+	execFn := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte("  "), nil
 	}
 
 	r := New(cfg, execFn)
-	svc, err := r.ResolveService()
+	svc, err := r.ResolveService(context.Background())
 
 	if err != nil {
 		t.Fatalf("ResolveService() unexpected error: %v", err)
@@ -92,12 +93,12 @@ func TestResolveService_LabelEmpty(t *testing.T) {
 
 func TestResolveService_ExecError_Fallback(t *testing.T) {
 	cfg := DefaultConfig()
-	execFn := func(name string, args ...string) ([]byte, error) {
+	execFn := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return nil, fmt.Errorf("kubectl not found")
 	}
 
 	r := New(cfg, execFn)
-	svc, err := r.ResolveService()
+	svc, err := r.ResolveService(context.Background())
 
 	if err != nil {
 		t.Fatalf("ResolveService() should not error on exec failure, got: %v", err)
@@ -109,12 +110,12 @@ func TestResolveService_ExecError_Fallback(t *testing.T) {
 
 func TestResolvePod_Found(t *testing.T) {
 	cfg := DefaultConfig()
-	execFn := func(name string, args ...string) ([]byte, error) {
+	execFn := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte("openclaw-pod-xyz"), nil
 	}
 
 	r := New(cfg, execFn)
-	pod, err := r.ResolvePod()
+	pod, err := r.ResolvePod(context.Background())
 
 	if err != nil {
 		t.Fatalf("ResolvePod() unexpected error: %v", err)
@@ -126,12 +127,12 @@ func TestResolvePod_Found(t *testing.T) {
 
 func TestResolvePod_ExecError(t *testing.T) {
 	cfg := DefaultConfig()
-	execFn := func(name string, args ...string) ([]byte, error) {
+	execFn := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return nil, fmt.Errorf("kubectl error")
 	}
 
 	r := New(cfg, execFn)
-	_, err := r.ResolvePod()
+	_, err := r.ResolvePod(context.Background())
 
 	if err == nil {
 		t.Fatal("ResolvePod() expected error on exec failure, got nil")
@@ -140,12 +141,12 @@ func TestResolvePod_ExecError(t *testing.T) {
 
 func TestResolvePod_Empty(t *testing.T) {
 	cfg := DefaultConfig()
-	execFn := func(name string, args ...string) ([]byte, error) {
+	execFn := func(ctx context.Context, name string, args ...string) ([]byte, error) {
 		return []byte(""), nil
 	}
 
 	r := New(cfg, execFn)
-	_, err := r.ResolvePod()
+	_, err := r.ResolvePod(context.Background())
 
 	if err == nil {
 		t.Fatal("ResolvePod() expected error for empty pod name, got nil")
@@ -177,7 +178,7 @@ func TestPortForwardTarget_CustomPort(t *testing.T) {
 
 func TestDefaultExec_Success(t *testing.T) {
 	// Use a built-in command to test the default exec function
-	out, err := defaultExec("echo", "hello")
+	out, err := defaultExec(context.Background(), "echo", "hello")
 	if err != nil {
 		t.Fatalf("defaultExec(echo, hello) error: %v", err)
 	}
@@ -188,8 +189,16 @@ func TestDefaultExec_Success(t *testing.T) {
 
 func TestDefaultExec_Error(t *testing.T) {
 	// Use a command that's guaranteed to fail
-	_, err := defaultExec("false")
+	_, err := defaultExec(context.Background(), "false")
 	if err == nil {
 		t.Fatal("defaultExec(false) expected error, got nil")
 	}
 }
+
+func TestDefaultExec_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := defaultExec(ctx, "sleep", "10"); err == nil {
+		t.Fatal("defaultExec() with a canceled context expected error, got nil")
+	}
+}
@@ -0,0 +1,262 @@
+// Package openclawapi is a typed client for the OpenClaw CLI (openclaw.mjs)
+// running inside the workload pod. Every call is a `kubectl exec` of
+// `node openclaw.mjs <command> --json` whose output is parsed into Go types.
+package openclawapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultContainer is the pod container that ships the OpenClaw CLI
+	DefaultContainer = "main"
+	// DefaultCLIPath is the OpenClaw CLI entry point inside the container
+	DefaultCLIPath = "/app/openclaw.mjs"
+	// DefaultRetries is the number of retries of a failed read-only call
+	DefaultRetries = 2
+	// DefaultBackoff is the wait before the first retry; it doubles per retry
+	DefaultBackoff = 2 * time.Second
+)
+
+// Kubectl runs kubectl and returns its stdout
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Client calls the OpenClaw CLI in one pod
+type Client struct {
+	Kube      Kubectl
+	Namespace string
+	Pod       string
+	Container string
+	CLIPath   string
+	// Retries applies to read-only calls; the kubectl runner retries
+	// transport failures on its own, this covers CLI failures such as a
+	// gateway that is still starting
+	Retries int
+	Backoff time.Duration
+	sleep   func(time.Duration)
+}
+
+// New returns a client with default container, CLI path and retry policy
+func New(kube Kubectl, namespace, pod string) *Client {
+	return &Client{
+		Kube:      kube,
+		Namespace: namespace,
+		Pod:       pod,
+		Container: DefaultContainer,
+		CLIPath:   DefaultCLIPath,
+		Retries:   DefaultRetries,
+		Backoff:   DefaultBackoff,
+	}
+}
+
+// Agent is one entry of `openclaw agents list --json`
+type Agent struct {
+	ID        string `json:"id"`
+	Workspace string `json:"workspace"`
+}
+
+// ApprovalsSnapshot is the output of `openclaw approvals get --json`
+type ApprovalsSnapshot struct {
+	// Raw is the CLI output as returned, suitable for backups
+	Raw []byte
+	// File is the approvals document, unwrapped from the CLI envelope
+	File json.RawMessage
+}
+
+// Args returns the kubectl arguments that run the OpenClaw CLI with args
+func (c *Client) Args(args ...string) []string {
+	execArgs := []string{
+		"-n", c.Namespace,
+		"exec",
+		"-c", c.Container,
+		c.Pod,
+		"--",
+		"node",
+		"--no-warnings",
+		c.CLIPath,
+	}
+	return append(execArgs, args...)
+}
+
+// AgentsList returns the configured agents and the raw CLI output
+func (c *Client) AgentsList(ctx context.Context) ([]Agent, []byte, error) {
+	var agents []Agent
+	raw, err := c.readJSON(ctx, &agents, "agents", "list", "--json")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list agents: %w", err)
+	}
+	return agents, raw, nil
+}
+
+// ApprovalsGet returns the runtime exec approvals
+func (c *Client) ApprovalsGet(ctx context.Context) (*ApprovalsSnapshot, error) {
+	var envelope json.RawMessage
+	raw, err := c.readJSON(ctx, &envelope, "approvals", "get", "--json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch approvals snapshot: %w", err)
+	}
+	file, err := NormalizeApprovals(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &ApprovalsSnapshot{Raw: raw, File: file}, nil
+}
+
+// ApprovalsSet replaces the runtime exec approvals with payload (an approvals
+// document) and returns the CLI output. It is not retried.
+func (c *Client) ApprovalsSet(ctx context.Context, payload []byte) ([]byte, error) {
+	tmpLocalFile, err := os.CreateTemp("", "netcup-claw-approvals-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary approvals file: %w", err)
+	}
+	tmpLocalPath := tmpLocalFile.Name()
+	defer func() {
+		_ = os.Remove(tmpLocalPath)
+	}()
+	if _, err := tmpLocalFile.Write(payload); err != nil {
+		_ = tmpLocalFile.Close()
+		return nil, fmt.Errorf("failed to write temporary approvals file: %w", err)
+	}
+	if err := tmpLocalFile.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close temporary approvals file: %w", err)
+	}
+
+	remoteTempPath := "/tmp/netcup-claw-approvals.json"
+	if _, err := c.Kube.Output(ctx, "-n", c.Namespace, "cp", tmpLocalPath, c.Pod+":"+remoteTempPath, "-c", c.Container); err != nil {
+		return nil, fmt.Errorf("failed to upload approvals file: %w", err)
+	}
+	out, err := c.Kube.Output(ctx, c.Args("approvals", "set", "--file", remoteTempPath, "--json")...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply approvals file: %w", err)
+	}
+	_, _ = c.Kube.Output(ctx, "-n", c.Namespace, "exec", "-c", c.Container, c.Pod, "--", "rm", "-f", remoteTempPath)
+	return out, nil
+}
+
+// Status returns `openclaw status --json`. Its schema belongs to OpenClaw, so
+// it is returned as a generic document.
+func (c *Client) Status(ctx context.Context) (map[string]any, error) {
+	status := map[string]any{}
+	if _, err := c.readJSON(ctx, &status, "status", "--json"); err != nil {
+		return nil, fmt.Errorf("failed to fetch openclaw status: %w", err)
+	}
+	return status, nil
+}
+
+// RunJSON runs an arbitrary CLI command that prints JSON (the caller passes
+// --json) and returns its output once validated. It is not retried since the
+// command may change state. Lines the CLI logs before the document are skipped.
+func (c *Client) RunJSON(ctx context.Context, args ...string) (json.RawMessage, error) {
+	out, err := c.Kube.Output(ctx, c.Args(args...)...)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ExtractJSON(out)
+	if err != nil {
+		name := "command"
+		if len(args) > 0 {
+			name = args[0]
+		}
+		return nil, fmt.Errorf("invalid JSON from openclaw %s: %w", name, err)
+	}
+	return doc, nil
+}
+
+// ExtractJSON returns the JSON document in out, starting at the first line
+// that opens an object or array when out has leading log lines
+func ExtractJSON(out []byte) (json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(out)
+	if json.Valid(trimmed) {
+		return json.RawMessage(trimmed), nil
+	}
+	for i := 0; i < len(trimmed); {
+		if trimmed[i] == '{' || trimmed[i] == '[' {
+			if candidate := bytes.TrimSpace(trimmed[i:]); json.Valid(candidate) {
+				return json.RawMessage(candidate), nil
+			}
+		}
+		next := bytes.IndexByte(trimmed[i:], '\n')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("empty output")
+	}
+	var v any
+	return nil, json.Unmarshal(trimmed, &v)
+}
+
+// readJSON runs a read-only CLI command, decodes its output into v and
+// returns the raw output. Failed runs and unparsable output are retried.
+func (c *Client) readJSON(ctx context.Context, v any, args ...string) ([]byte, error) {
+	backoff := c.Backoff
+	var lastErr error
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			c.wait(backoff)
+			backoff *= 2
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		out, err := c.Kube.Output(ctx, c.Args(args...)...)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := json.Unmarshal(out, v); err != nil {
+			lastErr = fmt.Errorf("invalid JSON from openclaw %s: %w", args[0], err)
+			continue
+		}
+		return out, nil
+	}
+	return nil, lastErr
+}
+
+func (c *Client) wait(d time.Duration) {
+	if c.sleep != nil {
+		c.sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// NormalizeApprovals returns the approvals document of payload as compact
+// JSON, unwrapping the {"file": ...} envelope of `approvals get --json`.
+func NormalizeApprovals(payload []byte) ([]byte, error) {
+	var asMap map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &asMap); err != nil {
+		return nil, fmt.Errorf("invalid approvals JSON: %w", err)
+	}
+
+	if rawFile, ok := asMap["file"]; ok && len(rawFile) > 0 {
+		var inner any
+		if err := json.Unmarshal(rawFile, &inner); err != nil {
+			return nil, fmt.Errorf("invalid approvals snapshot envelope (field 'file'): %w", err)
+		}
+		normalized, err := json.Marshal(inner)
+		if err != nil {
+			return nil, fmt.Errorf("failed to normalize approvals snapshot envelope: %w", err)
+		}
+		return normalized, nil
+	}
+
+	var direct any
+	if err := json.Unmarshal(payload, &direct); err != nil {
+		return nil, fmt.Errorf("invalid approvals JSON: %w", err)
+	}
+	normalized, err := json.Marshal(direct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to normalize approvals JSON: %w", err)
+	}
+	return normalized, nil
+}
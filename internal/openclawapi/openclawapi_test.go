@@ -0,0 +1,148 @@
+package openclawapi
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeKube struct {
+	outputs []string
+	errs    []error
+	calls   []string
+}
+
+func (k *fakeKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	i := len(k.calls)
+	k.calls = append(k.calls, strings.Join(args, " "))
+	var out string
+	var err error
+	if i < len(k.outputs) {
+		out = k.outputs[i]
+	}
+	if i < len(k.errs) {
+		err = k.errs[i]
+	}
+	return []byte(out), err
+}
+
+func newTestClient(kube *fakeKube) (*Client, *[]time.Duration) {
+	c := New(kube, "openclaw", "openclaw-abc")
+	var waits []time.Duration
+	c.sleep = func(d time.Duration) { waits = append(waits, d) }
+	return c, &waits
+}
+
+func TestArgs(t *testing.T) {
+	c := New(nil, "openclaw", "openclaw-abc")
+	got := c.Args("status", "--json")
+	want := []string{"-n", "openclaw", "exec", "-c", "main", "openclaw-abc", "--", "node", "--no-warnings", "/app/openclaw.mjs", "status", "--json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Args() = %v, want %v", got, want)
+	}
+}
+
+func TestAgentsList(t *testing.T) {
+	kube := &fakeKube{outputs: []string{`[{"id":"main","workspace":"/home/node/.openclaw/workspace"},{"id":"ops"}]`}}
+	c, _ := newTestClient(kube)
+	agents, raw, err := c.AgentsList(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Agent{{ID: "main", Workspace: "/home/node/.openclaw/workspace"}, {ID: "ops"}}
+	if !reflect.DeepEqual(agents, want) || len(raw) == 0 {
+		t.Errorf("AgentsList() = %+v, raw %q", agents, raw)
+	}
+	if !strings.HasSuffix(kube.calls[0], "/app/openclaw.mjs agents list --json") {
+		t.Errorf("unexpected call %q", kube.calls[0])
+	}
+}
+
+func TestReadRetriesFailuresAndInvalidJSON(t *testing.T) {
+	kube := &fakeKube{
+		outputs: []string{"", "gateway starting...", `{"file":{"agents":{}}}`},
+		errs:    []error{errors.New("command terminated with exit code 1"), nil, nil},
+	}
+	c, waits := newTestClient(kube)
+	snapshot, err := c.ApprovalsGet(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(snapshot.File) != `{"agents":{}}` || string(snapshot.Raw) != `{"file":{"agents":{}}}` {
+		t.Errorf("unexpected snapshot file=%s raw=%s", snapshot.File, snapshot.Raw)
+	}
+	if want := []time.Duration{DefaultBackoff, 2 * DefaultBackoff}; !reflect.DeepEqual(*waits, want) {
+		t.Errorf("waits = %v, want %v", *waits, want)
+	}
+}
+
+func TestReadGivesUpAfterRetries(t *testing.T) {
+	kube := &fakeKube{errs: []error{errors.New("a"), errors.New("b"), errors.New("c"), nil}}
+	c, _ := newTestClient(kube)
+	_, err := c.Status(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "c") {
+		t.Fatalf("expected last error, got %v", err)
+	}
+	if len(kube.calls) != DefaultRetries+1 {
+		t.Errorf("expected %d calls, got %d", DefaultRetries+1, len(kube.calls))
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	kube := &fakeKube{outputs: []string{"[plugins] loaded 3 plugins\n{\n  \"ok\": true\n}\n", "not json", ""}}
+	c, waits := newTestClient(kube)
+
+	doc, err := c.RunJSON(context.Background(), "channels", "list", "--json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc) != "{\n  \"ok\": true\n}" {
+		t.Errorf("RunJSON() = %q", doc)
+	}
+
+	if _, err := c.RunJSON(context.Background(), "channels", "list", "--json"); err == nil || !strings.Contains(err.Error(), "invalid JSON from openclaw channels") {
+		t.Errorf("RunJSON(not json) error = %v", err)
+	}
+	if _, err := c.RunJSON(context.Background(), "status", "--json"); err == nil || !strings.Contains(err.Error(), "empty output") {
+		t.Errorf("RunJSON(empty) error = %v", err)
+	}
+	if len(*waits) != 0 || len(kube.calls) != 3 {
+		t.Errorf("RunJSON must not retry: waits %v, calls %d", *waits, len(kube.calls))
+	}
+}
+
+func TestApprovalsSet(t *testing.T) {
+	kube := &fakeKube{outputs: []string{"", `{"ok":true}`, ""}}
+	c, _ := newTestClient(kube)
+	out, err := c.ApprovalsSet(context.Background(), []byte(`{"agents":{}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"ok":true}` || len(kube.calls) != 3 {
+		t.Fatalf("out=%q calls=%v", out, kube.calls)
+	}
+	if !strings.HasPrefix(kube.calls[0], "-n openclaw cp ") || !strings.HasSuffix(kube.calls[0], "openclaw-abc:/tmp/netcup-claw-approvals.json -c main") {
+		t.Errorf("unexpected upload call %q", kube.calls[0])
+	}
+	if !strings.HasSuffix(kube.calls[1], "approvals set --file /tmp/netcup-claw-approvals.json --json") {
+		t.Errorf("unexpected set call %q", kube.calls[1])
+	}
+}
+
+func TestNormalizeApprovals(t *testing.T) {
+	for _, in := range []string{`{"file": {"agents": {"main": {}}}}`, `{ "agents": {"main": {}} }`} {
+		got, err := NormalizeApprovals([]byte(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != `{"agents":{"main":{}}}` {
+			t.Errorf("NormalizeApprovals(%s) = %s", in, got)
+		}
+	}
+	if _, err := NormalizeApprovals([]byte(`[]`)); err == nil {
+		t.Error("expected error for non-object payload")
+	}
+}
@@ -0,0 +1,123 @@
+// Package osrelease parses /etc/os-release and decides whether a distribution
+// is supported for provisioning and bootstrap.
+package osrelease
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Family groups distributions by package manager
+type Family string
+
+const (
+	FamilyDebian  Family = "debian" // apt
+	FamilyRHEL    Family = "rhel"   // dnf
+	FamilyUnknown Family = "unknown"
+)
+
+// Info holds the os-release fields used for OS detection
+type Info struct {
+	ID         string
+	IDLike     []string
+	VersionID  string
+	PrettyName string
+}
+
+// minMajor is the oldest supported major release per distribution ID
+var minMajor = map[string]int{
+	"debian":    12,
+	"ubuntu":    22,
+	"rhel":      8,
+	"rocky":     8,
+	"almalinux": 8,
+	"centos":    8,
+	"ol":        8,
+}
+
+// SupportedSummary describes the supported distributions for error messages
+const SupportedSummary = "Debian 12+, Ubuntu 22.04+, RHEL/Rocky/AlmaLinux/CentOS Stream/Oracle Linux 8+"
+
+// Parse parses os-release content (KEY=value lines, optionally quoted)
+func Parse(content string) Info {
+	var info Info
+	for _, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch key {
+		case "ID":
+			info.ID = strings.ToLower(value)
+		case "ID_LIKE":
+			info.IDLike = strings.Fields(strings.ToLower(value))
+		case "VERSION_ID":
+			info.VersionID = value
+		case "PRETTY_NAME":
+			info.PrettyName = value
+		}
+	}
+	return info
+}
+
+// Name returns a human-readable name for the distribution
+func (i Info) Name() string {
+	if i.PrettyName != "" {
+		return i.PrettyName
+	}
+	if i.ID == "" {
+		return "unknown"
+	}
+	return strings.TrimSpace(i.ID + " " + i.VersionID)
+}
+
+// Family returns the distribution family, based on ID and ID_LIKE
+func (i Info) Family() Family {
+	for _, id := range append([]string{i.ID}, i.IDLike...) {
+		switch id {
+		case "debian", "ubuntu":
+			return FamilyDebian
+		case "rhel", "fedora", "centos":
+			return FamilyRHEL
+		}
+	}
+	return FamilyUnknown
+}
+
+// PackageManager returns the package manager command for the family
+func (i Info) PackageManager() string {
+	switch i.Family() {
+	case FamilyDebian:
+		return "apt-get"
+	case FamilyRHEL:
+		return "dnf"
+	}
+	return ""
+}
+
+// Check reports whether the distribution is supported. allowUnsupported
+// accepts untested releases of a known family, mirroring ALLOW_UNSUPPORTED_OS.
+func Check(i Info, allowUnsupported bool) error {
+	if min, ok := minMajor[i.ID]; ok && majorVersion(i.VersionID) >= min {
+		return nil
+	}
+	if allowUnsupported && i.Family() != FamilyUnknown {
+		return nil
+	}
+	return fmt.Errorf("unsupported OS: %s (supported: %s; set ALLOW_UNSUPPORTED_OS=true to try anyway)", i.Name(), SupportedSummary)
+}
+
+func majorVersion(version string) int {
+	major, _, _ := strings.Cut(version, ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return 0
+	}
+	return n
+}
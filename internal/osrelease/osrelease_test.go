@@ -0,0 +1,48 @@
+package osrelease
+
+import "testing"
+
+func TestParseAndCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		family    Family
+		supported bool
+		allowed   bool
+	}{
+		{"debian 13", "PRETTY_NAME=\"Debian GNU/Linux 13 (trixie)\"\nID=debian\nVERSION_ID=\"13\"\n", FamilyDebian, true, true},
+		{"debian 11", "ID=debian\nVERSION_ID=\"11\"\n", FamilyDebian, false, true},
+		{"ubuntu 24.04", "ID=ubuntu\nID_LIKE=debian\nVERSION_ID=\"24.04\"\n", FamilyDebian, true, true},
+		{"rocky 9", "ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.4\"\n", FamilyRHEL, true, true},
+		{"almalinux 8", "ID=\"almalinux\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"8.10\"\n", FamilyRHEL, true, true},
+		{"fedora", "ID=fedora\nVERSION_ID=40\n", FamilyRHEL, false, true},
+		{"arch", "ID=arch\n", FamilyUnknown, false, false},
+		{"empty", "", FamilyUnknown, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := Parse(tt.content)
+			if got := info.Family(); got != tt.family {
+				t.Errorf("Family() = %q, want %q", got, tt.family)
+			}
+			if err := Check(info, false); (err == nil) != tt.supported {
+				t.Errorf("Check(strict) error = %v, want supported=%v", err, tt.supported)
+			}
+			if err := Check(info, true); (err == nil) != tt.allowed {
+				t.Errorf("Check(allow) error = %v, want allowed=%v", err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestPackageManagerAndName(t *testing.T) {
+	if pm := Parse("ID=ubuntu\nID_LIKE=debian\n").PackageManager(); pm != "apt-get" {
+		t.Errorf("ubuntu package manager = %q", pm)
+	}
+	if pm := Parse("ID=rocky\nID_LIKE=\"rhel centos fedora\"\n").PackageManager(); pm != "dnf" {
+		t.Errorf("rocky package manager = %q", pm)
+	}
+	if name := Parse("ID=arch\n").Name(); name != "arch" {
+		t.Errorf("Name() = %q", name)
+	}
+}
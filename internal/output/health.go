@@ -0,0 +1,74 @@
+package output
+
+import (
+	"io"
+	"time"
+)
+
+// HealthState is the state of a component in a HealthDocument
+type HealthState string
+
+const (
+	// HealthOK means the component works
+	HealthOK HealthState = "ok"
+	// HealthFail means the component is broken; the document is unhealthy
+	HealthFail HealthState = "fail"
+	// HealthSkip means the component was not checked, e.g. because a
+	// dependency failed or it is not installed
+	HealthSkip HealthState = "skip"
+)
+
+// HealthComponent is one checked component of a HealthDocument
+type HealthComponent struct {
+	Component string      `json:"component"`
+	State     HealthState `json:"state"`
+	Message   string      `json:"message"`
+	LatencyMS int64       `json:"latency_ms"`
+	// DependsOn names the component this one was checked through, if any
+	DependsOn string `json:"depends_on,omitempty"`
+	// Details holds component-specific facts, e.g. the pid of a port-forward
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// HealthDocument is the machine-readable health schema shared by the status
+// commands of netcup-kube and netcup-claw, so dashboards can consume them
+// uniformly
+type HealthDocument struct {
+	// Source is the command that produced the document, e.g. "netcup-claw status"
+	Source     string            `json:"source"`
+	State      HealthState       `json:"state"`
+	CheckedAt  string            `json:"checked_at"`
+	Components []HealthComponent `json:"components"`
+}
+
+// NewHealthDocument returns a document whose overall state is fail if any
+// component failed and ok otherwise
+func NewHealthDocument(source string, components []HealthComponent, now time.Time) HealthDocument {
+	doc := HealthDocument{
+		Source:     source,
+		State:      HealthOK,
+		CheckedAt:  now.UTC().Format(time.RFC3339),
+		Components: components,
+	}
+	if doc.Components == nil {
+		doc.Components = []HealthComponent{}
+	}
+	for _, c := range components {
+		if c.State == HealthFail {
+			doc.State = HealthFail
+			break
+		}
+	}
+	return doc
+}
+
+// Healthy reports whether no component failed
+func (d HealthDocument) Healthy() bool {
+	return d.State != HealthFail
+}
+
+// WriteJSON writes the document as indented JSON, filtered by the query set
+// with SetQuery
+func (d HealthDocument) WriteJSON(w io.Writer) error {
+	return WriteJSON(w, d)
+}
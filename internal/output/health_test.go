@@ -0,0 +1,57 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewHealthDocument(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+	doc := NewHealthDocument("netcup-claw status", []HealthComponent{
+		{Component: "kube-api", State: HealthOK, LatencyMS: 41},
+		{Component: "redis", State: HealthSkip, DependsOn: "pod"},
+	}, now)
+	if doc.State != HealthOK || !doc.Healthy() || doc.CheckedAt != "2026-10-16T10:00:00Z" {
+		t.Errorf("unexpected document %+v", doc)
+	}
+
+	doc = NewHealthDocument("x", []HealthComponent{{Component: "pod", State: HealthFail}}, now)
+	if doc.State != HealthFail || doc.Healthy() {
+		t.Errorf("expected failed document, got %+v", doc)
+	}
+}
+
+func TestHealthDocumentWriteJSON(t *testing.T) {
+	doc := NewHealthDocument("netcup-kube ssh tunnel status", nil, time.Unix(0, 0))
+	var buf bytes.Buffer
+	if err := doc.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"source", "state", "checked_at", "components"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("missing %q in %s", key, buf.String())
+		}
+	}
+	if components, ok := decoded["components"].([]any); !ok || len(components) != 0 {
+		t.Errorf("expected empty components array, got %v", decoded["components"])
+	}
+
+	buf.Reset()
+	_ = NewHealthDocument("x", []HealthComponent{{Component: "tunnel", State: HealthOK, Message: "up", LatencyMS: 3}}, time.Unix(0, 0)).WriteJSON(&buf)
+	var typed HealthDocument
+	if err := json.Unmarshal(buf.Bytes(), &typed); err != nil {
+		t.Fatal(err)
+	}
+	if c := typed.Components[0]; c.Component != "tunnel" || c.LatencyMS != 3 || c.Message != "up" {
+		t.Errorf("round trip mismatch: %+v", c)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("depends_on")) {
+		t.Error("depends_on should be omitted when empty")
+	}
+}
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,6 +29,61 @@ func ParseFormat(s string) (Format, error) {
 	}
 }
 
+// query is the --query expression applied to all JSON output
+var query string
+
+// SetQuery sets the jq subset expression (see Query) that WriteJSON applies to
+// every JSON document; "" prints documents unchanged
+func SetQuery(expr string) error {
+	if expr != "" {
+		if _, err := parseQuery(expr); err != nil {
+			return err
+		}
+	}
+	query = expr
+	return nil
+}
+
+// WriteJSON writes v as indented JSON, or, with a query set, each value the
+// query selects from it
+func WriteJSON(w io.Writer, v any) error {
+	return writeJSON(w, v, "  ")
+}
+
+// WriteJSONLine is WriteJSON for line-oriented streams: every value is
+// written compactly on its own line
+func WriteJSONLine(w io.Writer, v any) error {
+	return writeJSON(w, v, "")
+}
+
+func writeJSON(w io.Writer, v any, indent string) error {
+	values := []any{v}
+	if query != "" {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		// Keep numbers as written so large IDs survive the round trip
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.UseNumber()
+		var doc any
+		if err := decoder.Decode(&doc); err != nil {
+			return err
+		}
+		if values, err = Query(doc, query); err != nil {
+			return err
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", indent)
+	for _, value := range values {
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Formatter handles outputting data in different formats
 type Formatter struct {
 	format Format
@@ -69,9 +125,7 @@ func (f *Formatter) Print(result *Result) error {
 
 // printJSON outputs the result as JSON
 func (f *Formatter) printJSON(result *Result) error {
-	encoder := json.NewEncoder(f.writer)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(result)
+	return WriteJSON(f.writer, result)
 }
 
 // printText outputs the result as human-readable text
@@ -121,9 +175,7 @@ type ValidationResult struct {
 func (f *Formatter) PrintValidation(result *ValidationResult) error {
 	switch f.format {
 	case FormatJSON:
-		encoder := json.NewEncoder(f.writer)
-		encoder.SetIndent("", "  ")
-		return encoder.Encode(result)
+		return WriteJSON(f.writer, result)
 	case FormatText:
 		if result.Valid {
 			_, err := fmt.Fprintln(f.writer, "Validation passed")
@@ -0,0 +1,757 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Query evaluates an expression in a subset of jq against a decoded JSON
+// document and returns the values it produces. The subset covers:
+//
+//	paths        .status.gateway  ."key.with.dots"  .["key"]  .agents[0]  .agents[-1]  .agents[]
+//	pipes        .components[] | .name
+//	filters      select(.state != "ok")  not
+//	comparisons  ==  !=  <  <=  >  >=  and  or
+//	literals     "text"  42  true  false  null
+//	builtins     length  keys
+//	grouping     (.a | .b)  [.agents[].id]
+//
+// Missing object keys yield null, as in jq. Other jq constructs, such as
+// object construction, "," or map, fail with ErrUnsupported.
+func Query(doc any, expr string) ([]any, error) {
+	q, err := parseQuery(expr)
+	if err != nil {
+		return nil, err
+	}
+	values, err := q.eval(doc)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", expr, err)
+	}
+	return values, nil
+}
+
+// ErrUnsupported marks valid jq that is outside the subset Query implements
+var ErrUnsupported = errors.New("not supported by --query, which implements a subset of jq")
+
+// unsupported reports a jq construct that Query does not implement
+func unsupported(construct string, pos int) error {
+	return fmt.Errorf("%s at offset %d: %w", construct, pos, ErrUnsupported)
+}
+
+// jqKeywords are jq keywords outside the subset, reported as such rather than
+// as unknown functions
+var jqKeywords = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "end": true, "as": true, "def": true,
+	"reduce": true, "foreach": true, "try": true, "catch": true, "label": true, "import": true, "include": true,
+}
+
+// queryNode is a parsed expression; eval returns the values it produces for
+// one input, like a jq filter
+type queryNode interface {
+	eval(v any) ([]any, error)
+}
+
+type identityNode struct{}
+
+func (identityNode) eval(v any) ([]any, error) { return []any{v}, nil }
+
+type literalNode struct{ value any }
+
+func (n literalNode) eval(any) ([]any, error) { return []any{n.value}, nil }
+
+// pathNode applies a key, an index or iteration to the values of from
+type pathNode struct {
+	from    queryNode
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+func (n pathNode) eval(v any) ([]any, error) {
+	inputs, err := n.from.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	var out []any
+	for _, in := range inputs {
+		selected, err := n.apply(in)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, selected...)
+	}
+	return out, nil
+}
+
+func (n pathNode) apply(v any) ([]any, error) {
+	switch {
+	case n.iterate:
+		switch t := v.(type) {
+		case []any:
+			return t, nil
+		case map[string]any:
+			out := make([]any, 0, len(t))
+			for _, k := range sortedKeys(t) {
+				out = append(out, t[k])
+			}
+			return out, nil
+		case nil:
+			return nil, fmt.Errorf("cannot iterate over null")
+		default:
+			return nil, fmt.Errorf("cannot iterate over %s", jsonType(v))
+		}
+	case n.isIndex:
+		switch t := v.(type) {
+		case []any:
+			i := n.index
+			if i < 0 {
+				i += len(t)
+			}
+			if i < 0 || i >= len(t) {
+				return []any{nil}, nil
+			}
+			return []any{t[i]}, nil
+		case nil:
+			return []any{nil}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s with a number", jsonType(v))
+		}
+	default:
+		switch t := v.(type) {
+		case map[string]any:
+			return []any{t[n.key]}, nil
+		case nil:
+			return []any{nil}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s with %q", jsonType(v), n.key)
+		}
+	}
+}
+
+// pipeNode feeds every value of left into right
+type pipeNode struct{ left, right queryNode }
+
+func (n pipeNode) eval(v any) ([]any, error) {
+	inputs, err := n.left.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	var out []any
+	for _, in := range inputs {
+		values, err := n.right.eval(in)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, values...)
+	}
+	return out, nil
+}
+
+// binaryNode is a comparison or a boolean operator
+type binaryNode struct {
+	op          string
+	left, right queryNode
+}
+
+func (n binaryNode) eval(v any) ([]any, error) {
+	lefts, err := n.left.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	var out []any
+	for _, l := range lefts {
+		// and/or only evaluate the right side when it decides the result
+		if n.op == "and" && !truthy(l) || n.op == "or" && truthy(l) {
+			out = append(out, n.op == "or")
+			continue
+		}
+		rights, err := n.right.eval(v)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rights {
+			switch n.op {
+			case "and", "or":
+				out = append(out, truthy(r))
+			case "==":
+				out = append(out, compareValues(l, r) == 0)
+			case "!=":
+				out = append(out, compareValues(l, r) != 0)
+			case "<":
+				out = append(out, compareValues(l, r) < 0)
+			case "<=":
+				out = append(out, compareValues(l, r) <= 0)
+			case ">":
+				out = append(out, compareValues(l, r) > 0)
+			case ">=":
+				out = append(out, compareValues(l, r) >= 0)
+			}
+		}
+	}
+	return out, nil
+}
+
+// selectNode passes its input through for every truthy value of cond
+type selectNode struct{ cond queryNode }
+
+func (n selectNode) eval(v any) ([]any, error) {
+	conds, err := n.cond.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	var out []any
+	for _, c := range conds {
+		if truthy(c) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+// collectNode gathers the values of inner into one array
+type collectNode struct{ inner queryNode }
+
+func (n collectNode) eval(v any) ([]any, error) {
+	if n.inner == nil {
+		return []any{[]any{}}, nil
+	}
+	values, err := n.inner.eval(v)
+	if err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = []any{}
+	}
+	return []any{values}, nil
+}
+
+// builtinNode is a function without arguments
+type builtinNode struct{ name string }
+
+func (n builtinNode) eval(v any) ([]any, error) {
+	switch n.name {
+	case "not":
+		return []any{!truthy(v)}, nil
+	case "length":
+		switch t := v.(type) {
+		case nil:
+			return []any{0}, nil
+		case string:
+			return []any{utf8.RuneCountInString(t)}, nil
+		case []any:
+			return []any{len(t)}, nil
+		case map[string]any:
+			return []any{len(t)}, nil
+		case bool:
+			return nil, fmt.Errorf("boolean has no length")
+		default:
+			f, _ := toFloat(v)
+			return []any{math.Abs(f)}, nil
+		}
+	case "keys":
+		switch t := v.(type) {
+		case map[string]any:
+			keys := sortedKeys(t)
+			out := make([]any, len(keys))
+			for i, k := range keys {
+				out[i] = k
+			}
+			return []any{out}, nil
+		case []any:
+			out := make([]any, len(t))
+			for i := range t {
+				out[i] = i
+			}
+			return []any{out}, nil
+		default:
+			return nil, fmt.Errorf("%s has no keys", jsonType(v))
+		}
+	}
+	return nil, fmt.Errorf("unknown function %s", n.name)
+}
+
+func truthy(v any) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return v != nil
+}
+
+// compareValues orders JSON values like jq: null < false < true < numbers <
+// strings < arrays < objects
+func compareValues(a, b any) int {
+	ra, rb := typeRank(a), typeRank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch ta := a.(type) {
+	case string:
+		return strings.Compare(ta, b.(string))
+	case []any:
+		tb := b.([]any)
+		for i := 0; i < len(ta) && i < len(tb); i++ {
+			if c := compareValues(ta[i], tb[i]); c != 0 {
+				return c
+			}
+		}
+		return len(ta) - len(tb)
+	case map[string]any:
+		tb := b.(map[string]any)
+		ka, kb := sortedKeys(ta), sortedKeys(tb)
+		for i := 0; i < len(ka) && i < len(kb); i++ {
+			if c := strings.Compare(ka[i], kb[i]); c != 0 {
+				return c
+			}
+		}
+		if len(ka) != len(kb) {
+			return len(ka) - len(kb)
+		}
+		for _, k := range ka {
+			if c := compareValues(ta[k], tb[k]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case nil, bool:
+		return 0
+	default:
+		fa, _ := toFloat(a)
+		fb, _ := toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	}
+}
+
+func typeRank(v any) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if t {
+			return 2
+		}
+		return 1
+	case string:
+		return 4
+	case []any:
+		return 5
+	case map[string]any:
+		return 6
+	default:
+		return 3
+	}
+}
+
+// toFloat converts the number types of decoded JSON (float64, json.Number)
+// and of builtins (int)
+func toFloat(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// queryToken is a lexical token; field tokens carry the key of .key / ."key"
+type queryToken struct {
+	kind string // "field", "dot", "ident", "string", "number", "op", "punct", "eof"
+	text string
+	pos  int
+}
+
+type queryParser struct {
+	expr   string
+	tokens []queryToken
+	pos    int
+}
+
+func parseQuery(expr string) (queryNode, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("invalid query %q: empty expression", expr)
+	}
+	tokens, err := lexQuery(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", expr, err)
+	}
+	p := &queryParser{expr: expr, tokens: tokens}
+	node, err := p.parsePipe()
+	if err != nil {
+		return nil, fmt.Errorf("invalid query %q: %w", expr, err)
+	}
+	if tok := p.peek(); tok.kind != "eof" {
+		return nil, fmt.Errorf("invalid query %q: unexpected %q at offset %d", expr, tok.text, tok.pos)
+	}
+	return node, nil
+}
+
+func lexQuery(expr string) ([]queryToken, error) {
+	var tokens []queryToken
+	pos := 0
+	for pos < len(expr) {
+		c := expr[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '.':
+			start := pos
+			pos++
+			switch {
+			case pos < len(expr) && expr[pos] == '.':
+				return nil, unsupported("recursive descent ..", start)
+			case pos < len(expr) && expr[pos] == '"':
+				key, n, err := parseQuoted(expr[pos:], pos)
+				if err != nil {
+					return nil, err
+				}
+				tokens = append(tokens, queryToken{kind: "field", text: key, pos: start})
+				pos += n
+			case pos < len(expr) && isQueryIdentChar(expr[pos]):
+				end := pos
+				for end < len(expr) && isQueryIdentChar(expr[end]) {
+					end++
+				}
+				tokens = append(tokens, queryToken{kind: "field", text: expr[pos:end], pos: start})
+				pos = end
+			default:
+				tokens = append(tokens, queryToken{kind: "dot", text: ".", pos: start})
+			}
+		case c == '"':
+			s, n, err := parseQuoted(expr[pos:], pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, queryToken{kind: "string", text: s, pos: pos})
+			pos += n
+		case c >= '0' && c <= '9' || c == '-' && pos+1 < len(expr) && expr[pos+1] >= '0' && expr[pos+1] <= '9':
+			end := pos + 1
+			for end < len(expr) && (expr[end] >= '0' && expr[end] <= '9' || expr[end] == '.' || expr[end] == 'e' || expr[end] == 'E') {
+				end++
+			}
+			tokens = append(tokens, queryToken{kind: "number", text: expr[pos:end], pos: pos})
+			pos = end
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			end := pos
+			for end < len(expr) && (expr[end] == '_' || expr[end] >= 'a' && expr[end] <= 'z' || expr[end] >= 'A' && expr[end] <= 'Z' || expr[end] >= '0' && expr[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, queryToken{kind: "ident", text: expr[pos:end], pos: pos})
+			pos = end
+		case strings.ContainsRune("=!<>", rune(c)):
+			op := string(c)
+			if pos+1 < len(expr) && expr[pos+1] == '=' {
+				op += "="
+			}
+			if op == "=" {
+				return nil, unsupported("assignment =", pos)
+			}
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected %q at offset %d", op, pos)
+			}
+			tokens = append(tokens, queryToken{kind: "op", text: op, pos: pos})
+			pos += len(op)
+		case strings.ContainsRune("[]()|", rune(c)):
+			tokens = append(tokens, queryToken{kind: "punct", text: string(c), pos: pos})
+			pos++
+		case c == '{':
+			return nil, unsupported("object construction {...}", pos)
+		case c == ',':
+			return nil, unsupported("the comma operator ,", pos)
+		case c == '/' && pos+1 < len(expr) && expr[pos+1] == '/':
+			return nil, unsupported("the alternative operator //", pos)
+		case strings.ContainsRune("+-*/%", rune(c)):
+			return nil, unsupported(fmt.Sprintf("arithmetic %c", c), pos)
+		case c == '?':
+			return nil, unsupported("the optional operator ?", pos)
+		case c == '$':
+			return nil, unsupported("variables ($name)", pos)
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", c, pos)
+		}
+	}
+	return append(tokens, queryToken{kind: "eof", pos: len(expr)}), nil
+}
+
+func (p *queryParser) peek() queryToken { return p.tokens[p.pos] }
+
+func (p *queryParser) next() queryToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != "eof" {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *queryParser) accept(kind, text string) bool {
+	if tok := p.peek(); tok.kind == kind && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) expect(text string) error {
+	if p.accept("punct", text) {
+		return nil
+	}
+	tok := p.peek()
+	if tok.kind == "eof" {
+		return fmt.Errorf("expected %q at end of expression", text)
+	}
+	return fmt.Errorf("expected %q at offset %d", text, tok.pos)
+}
+
+func (p *queryParser) parsePipe() (queryNode, error) {
+	left, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("punct", "|") {
+		right, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		left = pipeNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("ident", "or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("ident", "and") {
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseComparison() (queryNode, error) {
+	left, err := p.parsePostfix()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == "op" {
+		p.next()
+		right, err := p.parsePostfix()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: tok.text, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *queryParser) parsePostfix() (queryNode, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == "field":
+			p.next()
+			node = pathNode{from: node, key: tok.text}
+		case tok.kind == "dot" && p.tokens[p.pos+1].kind == "punct" && p.tokens[p.pos+1].text == "[":
+			// .a.[0] is the same as .a[0]
+			p.next()
+		case tok.kind == "punct" && tok.text == "[":
+			p.next()
+			step, err := p.parseBracket(node)
+			if err != nil {
+				return nil, err
+			}
+			node = step
+		default:
+			return node, nil
+		}
+	}
+}
+
+// parseBracket parses the inside of [...] after a path: nothing (iteration),
+// a number (index) or a string (key)
+func (p *queryParser) parseBracket(from queryNode) (queryNode, error) {
+	if p.accept("punct", "]") {
+		return pathNode{from: from, iterate: true}, nil
+	}
+	tok := p.next()
+	var step pathNode
+	switch tok.kind {
+	case "string":
+		step = pathNode{from: from, key: tok.text}
+	case "number":
+		i, err := strconv.Atoi(tok.text)
+		if err != nil {
+			return nil, fmt.Errorf("bad index %s", tok.text)
+		}
+		step = pathNode{from: from, index: i, isIndex: true}
+	case "eof":
+		return nil, fmt.Errorf("unterminated '['")
+	default:
+		return nil, fmt.Errorf("bad index %s at offset %d", tok.text, tok.pos)
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return step, nil
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case "field":
+		return pathNode{from: identityNode{}, key: tok.text}, nil
+	case "dot":
+		return identityNode{}, nil
+	case "string":
+		return literalNode{value: tok.text}, nil
+	case "number":
+		f, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %s", tok.text)
+		}
+		return literalNode{value: f}, nil
+	case "ident":
+		switch tok.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		case "not", "length", "keys":
+			return builtinNode{name: tok.text}, nil
+		case "select":
+			if err := p.expect("("); err != nil {
+				return nil, err
+			}
+			cond, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return selectNode{cond: cond}, nil
+		}
+		if jqKeywords[tok.text] {
+			return nil, unsupported("jq keyword "+tok.text, tok.pos)
+		}
+		return nil, fmt.Errorf("%w (functions: select, not, length, keys)", unsupported("function "+tok.text, tok.pos))
+	case "punct":
+		switch tok.text {
+		case "(":
+			inner, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return inner, nil
+		case "[":
+			if p.accept("punct", "]") {
+				return collectNode{}, nil
+			}
+			inner, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			return collectNode{inner: inner}, nil
+		}
+	case "eof":
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+}
+
+// parseQuoted parses a leading JSON string at offset pos of the expression
+// and returns it with its length
+func parseQuoted(s string, pos int) (string, int, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '(' {
+				return "", 0, unsupported(`string interpolation \(...)`, pos+i)
+			}
+			i++
+		case '"':
+			key, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, err
+			}
+			return key, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+func isQueryIdentChar(c byte) bool {
+	return c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func jsonType(v any) string {
+	switch v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	default:
+		return "number"
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,185 @@
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{
+		"gateway": {"status": "running", "port": 18789},
+		"agents": [{"id": "main"}, {"id": "ops"}],
+		"a.b": true
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []any
+	}{
+		{".", []any{doc}},
+		{".gateway.status", []any{"running"}},
+		{".gateway.port", []any{float64(18789)}},
+		{".agents[0].id", []any{"main"}},
+		{".agents[-1].id", []any{"ops"}},
+		{".agents[].id", []any{"main", "ops"}},
+		{".agents[5]", []any{nil}},
+		{`."a.b"`, []any{true}},
+		{`.["gateway"].status`, []any{"running"}},
+		{".missing.key", []any{nil}},
+		{".agents[] | .id", []any{"main", "ops"}},
+		{`.agents[] | select(.id != "main") | .id`, []any{"ops"}},
+		{".gateway.port >= 18789 and .gateway.status == \"running\"", []any{true}},
+		{`.gateway.status == "stopped" or (.agents | length) > 1`, []any{true}},
+		{".agents | length", []any{2}},
+		{".gateway | keys", []any{[]any{"port", "status"}}},
+		{"[.agents[].id]", []any{[]any{"main", "ops"}}},
+		{".missing | not", []any{true}},
+		{".agents[] | select(.id == \"none\")", nil},
+	}
+	for _, tt := range tests {
+		got, err := Query(doc, tt.expr)
+		if err != nil {
+			t.Errorf("Query(%s) error = %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%s) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "gateway", ".gateway.status.x", ".agents.id", ".agents[x]", ".agents[0", ".gateway..status", ".gateway |", "select(.a", ".a = 1", "map_ok"} {
+		if _, err := Query(doc, expr); err == nil {
+			t.Errorf("Query(%q) expected error", expr)
+		}
+	}
+}
+
+func TestWriteJSONQuery(t *testing.T) {
+	t.Cleanup(func() { _ = SetQuery("") })
+	doc := HealthDocument{State: HealthFail, Components: []HealthComponent{
+		{Component: "pod", State: HealthOK},
+		{Component: "gateway", State: HealthFail, Message: "connection refused"},
+	}}
+
+	if err := SetQuery(".components[] | select(.state != "); err == nil {
+		t.Fatal("SetQuery(invalid) error = nil")
+	}
+	if err := SetQuery(`.components[] | select(.state != "ok") | .component`); err != nil {
+		t.Fatalf("SetQuery() error = %v", err)
+	}
+	var out strings.Builder
+	if err := doc.WriteJSON(&out); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	if got, want := out.String(), "\"gateway\"\n"; got != want {
+		t.Errorf("WriteJSON() = %q, want %q", got, want)
+	}
+
+	out.Reset()
+	if err := SetQuery("[.components[].component]"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteJSONLine(&out, doc); err != nil {
+		t.Fatalf("WriteJSONLine() error = %v", err)
+	}
+	if got, want := out.String(), "[\"pod\",\"gateway\"]\n"; got != want {
+		t.Errorf("WriteJSONLine() = %q, want %q", got, want)
+	}
+}
+
+func TestQueryValues(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{
+		"name": "héllo",
+		"n": -3,
+		"list": [1, "a", null, true, false, [1, 2], {"k": 1}],
+		"obj": {"b": 2, "a": 1},
+		"flag": true
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []any
+	}{
+		{".obj[]", []any{float64(1), float64(2)}},
+		{".obj | keys", []any{[]any{"a", "b"}}},
+		{".list[5] | keys", []any{[]any{0, 1}}},
+		{".name | length", []any{5}},
+		{".n | length", []any{float64(3)}},
+		{".obj | length", []any{2}},
+		{".missing | length", []any{0}},
+		{".missing[0]", []any{nil}},
+		{"[]", []any{[]any{}}},
+		{"[.list[] | select(. == \"none\")]", []any{[]any{}}},
+		{"null < false and false < true and true < 0 and 0 < \"\"", []any{true}},
+		{`"a" < "b" and "b" <= "b" and "c" > "b"`, []any{true}},
+		{"[.list[0]] < .list[5]", []any{true}},
+		{".list[5] > [.list[3]]", []any{true}},
+		{".obj < .list[6]", []any{true}},
+		{".obj == .obj", []any{true}},
+		{".list[6] > .obj", []any{true}},
+		{"null == null and true == true", []any{true}},
+		{".n < 0 and .n <= -3 and .n != 1", []any{true}},
+		{"false or .flag", []any{true}},
+		{"[.list[] | not]", []any{[]any{false, false, true, false, true, false, false}}},
+		{"1.5 > 1", []any{true}},
+	}
+	for _, tt := range tests {
+		got, err := Query(doc, tt.expr)
+		if err != nil {
+			t.Errorf("Query(%s) error = %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Query(%s) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"{} == .obj", ".missing[]", ".name[]", ".name[0]", ".n.key", ".flag | length", ".name | keys", `."unterminated`, `."bad\q"`} {
+		if _, err := Query(doc, expr); err == nil {
+			t.Errorf("Query(%q) expected error", expr)
+		}
+	}
+}
+
+func TestQueryUnsupported(t *testing.T) {
+	tests := []struct {
+		expr      string
+		construct string
+	}{
+		{"{id: .id}", "object construction {...} at offset 0"},
+		{".a, .b", "the comma operator , at offset 2"},
+		{".agents | map(.id)", "function map at offset 10"},
+		{`"\(.id)"`, `string interpolation \(...) at offset 1`},
+		{".a // .b", "the alternative operator // at offset 3"},
+		{".a + 1", "arithmetic + at offset 3"},
+		{".a?", "the optional operator ? at offset 2"},
+		{". as $x | $x", "variables ($name) at offset 5"},
+		{"try .a", "jq keyword try at offset 0"},
+		{"if .a then 1 else 2 end", "jq keyword if at offset 0"},
+		{"..", "recursive descent .. at offset 0"},
+		{".a |= 1", "assignment = at offset 4"},
+	}
+	for _, tt := range tests {
+		_, err := Query(nil, tt.expr)
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Query(%q) error = %v, want ErrUnsupported", tt.expr, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.construct) {
+			t.Errorf("Query(%q) error = %q, want it to name %q", tt.expr, err, tt.construct)
+		}
+	}
+
+	if err := SetQuery("{a: .b}"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetQuery() error = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package paths
+
+import (
+	"os"
+	"syscall"
+)
+
+// owner returns the uid owning the file described by info
+func owner(info os.FileInfo) (uid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(st.Uid), true
+}
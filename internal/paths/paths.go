@@ -0,0 +1,162 @@
+// Package paths resolves where netcup-kube and netcup-claw keep their files,
+// following the XDG base directory specification:
+//
+//	config   $XDG_CONFIG_HOME/<app>  (~/.config/<app>)       env files, registries, certificates
+//	state    $XDG_STATE_HOME/<app>   (~/.local/state/<app>)  data that must survive reboots
+//	cache    $XDG_CACHE_HOME/<app>   (~/.cache/<app>)        data that can be rebuilt
+//	runtime  $XDG_RUNTIME_DIR/<app>  (/tmp/<app>-<uid>)      sockets, PID files, decrypted secrets
+//
+// Each directory can be overridden per application with <APP>_CONFIG_HOME,
+// <APP>_STATE_HOME, <APP>_CACHE_HOME and <APP>_RUNTIME_DIR, where <APP> is
+// the upper-cased application name with '-' as '_' (e.g. NETCUP_CLAW_STATE_HOME).
+package paths
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ConfigDir returns the configuration directory of app
+func ConfigDir(app string) string {
+	return dir(app, "CONFIG_HOME", "XDG_CONFIG_HOME", ".config", os.UserConfigDir)
+}
+
+// StateDir returns the state directory of app
+func StateDir(app string) string {
+	return dir(app, "STATE_HOME", "XDG_STATE_HOME", filepath.Join(".local", "state"), os.UserConfigDir)
+}
+
+// CacheDir returns the cache directory of app
+func CacheDir(app string) string {
+	return dir(app, "CACHE_HOME", "XDG_CACHE_HOME", ".cache", os.UserCacheDir)
+}
+
+// RuntimeDir returns the runtime directory of app. Without XDG_RUNTIME_DIR it
+// is a per-user directory below /tmp, which is kept short because SSH control
+// sockets live there. Use EnsurePrivateDir before writing to it.
+func RuntimeDir(app string) string {
+	if override := os.Getenv(envPrefix(app) + "RUNTIME_DIR"); override != "" {
+		return override
+	}
+	if base := os.Getenv("XDG_RUNTIME_DIR"); filepath.IsAbs(base) {
+		return filepath.Join(base, app)
+	}
+	tmp := "/tmp"
+	if runtime.GOOS == "windows" {
+		tmp = os.TempDir()
+	}
+	return filepath.Join(tmp, fmt.Sprintf("%s-%d", app, os.Getuid()))
+}
+
+// EnsurePrivateDir creates dir with mode 0700 and tightens an existing one
+// that other users can access. A directory owned by another user (e.g.
+// planted in a shared /tmp) is rejected.
+func EnsurePrivateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if uid, ok := owner(info); ok && uid != os.Geteuid() {
+		return fmt.Errorf("directory %s is owned by uid %d, not the current user", dir, uid)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		if err := os.Chmod(dir, 0o700); err != nil {
+			return fmt.Errorf("directory %s is accessible by other users: %w", dir, err)
+		}
+	}
+	return nil
+}
+
+// Find returns the first of candidates that exists, or ""
+func Find(candidates ...string) string {
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		if _, err := os.Stat(c); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+// Migrate moves a file or directory from its pre-XDG location legacy to
+// current, unless current already exists. It returns the path to use: current,
+// or legacy when it exists but could not be moved (e.g. a live socket on
+// another filesystem), so callers keep working with the old location.
+func Migrate(legacy, current string) string {
+	if legacy == "" || legacy == current {
+		return current
+	}
+	info, err := os.Lstat(legacy)
+	if err != nil {
+		return current
+	}
+	if _, err := os.Lstat(current); err == nil {
+		return current
+	}
+	if err := os.MkdirAll(filepath.Dir(current), 0o700); err != nil {
+		return legacy
+	}
+	if err := os.Rename(legacy, current); err == nil {
+		return current
+	}
+	// Renames fail across filesystems; regular files can still be copied
+	if !info.Mode().IsRegular() || copyFile(legacy, current, info.Mode().Perm()) != nil {
+		return legacy
+	}
+	_ = os.Remove(legacy)
+	return current
+}
+
+func dir(app, overrideSuffix, xdgVar, homeRel string, native func() (string, error)) string {
+	if override := os.Getenv(envPrefix(app) + overrideSuffix); override != "" {
+		return override
+	}
+	// The spec says relative values are invalid and must be ignored
+	if base := os.Getenv(xdgVar); filepath.IsAbs(base) {
+		return filepath.Join(base, app)
+	}
+	if runtime.GOOS == "windows" {
+		if base, err := native(); err == nil {
+			return filepath.Join(base, app)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, homeRel, app)
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("%s-%d", app, os.Getuid()), strings.TrimPrefix(homeRel, "."))
+}
+
+func envPrefix(app string) string {
+	return strings.ToUpper(strings.ReplaceAll(app, "-", "_")) + "_"
+}
+
+func copyFile(src, dst string, perm os.FileMode) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			_ = os.Remove(dst)
+		}
+	}()
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,211 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDirs(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, key := range []string{"XDG_CONFIG_HOME", "XDG_STATE_HOME", "XDG_CACHE_HOME", "NETCUP_CLAW_CONFIG_HOME", "NETCUP_CLAW_STATE_HOME", "NETCUP_CLAW_CACHE_HOME"} {
+		t.Setenv(key, "")
+	}
+
+	if got, want := ConfigDir("netcup-claw"), filepath.Join(home, ".config", "netcup-claw"); got != want {
+		t.Errorf("ConfigDir() = %q, want %q", got, want)
+	}
+	if got, want := StateDir("netcup-claw"), filepath.Join(home, ".local", "state", "netcup-claw"); got != want {
+		t.Errorf("StateDir() = %q, want %q", got, want)
+	}
+	if got, want := CacheDir("netcup-claw"), filepath.Join(home, ".cache", "netcup-claw"); got != want {
+		t.Errorf("CacheDir() = %q, want %q", got, want)
+	}
+
+	// Relative XDG values are invalid and ignored
+	t.Setenv("XDG_STATE_HOME", "state")
+	if got, want := StateDir("netcup-claw"), filepath.Join(home, ".local", "state", "netcup-claw"); got != want {
+		t.Errorf("StateDir(relative XDG_STATE_HOME) = %q, want %q", got, want)
+	}
+	t.Setenv("XDG_STATE_HOME", "/var/lib/state")
+	if got, want := StateDir("netcup-claw"), filepath.Join("/var/lib/state", "netcup-claw"); got != want {
+		t.Errorf("StateDir(XDG_STATE_HOME) = %q, want %q", got, want)
+	}
+	t.Setenv("NETCUP_CLAW_STATE_HOME", "/srv/claw")
+	if got := StateDir("netcup-claw"); got != "/srv/claw" {
+		t.Errorf("StateDir(NETCUP_CLAW_STATE_HOME) = %q, want /srv/claw", got)
+	}
+}
+
+func TestRuntimeDir(t *testing.T) {
+	t.Setenv("NETCUP_KUBE_RUNTIME_DIR", "")
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got, want := RuntimeDir("netcup-kube"), filepath.Join("/run/user/1000", "netcup-kube"); got != want {
+		t.Errorf("RuntimeDir() = %q, want %q", got, want)
+	}
+
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	if got := RuntimeDir("netcup-kube"); filepath.Dir(got) != "/tmp" || filepath.Base(got) == "netcup-kube" {
+		t.Errorf("RuntimeDir() without XDG_RUNTIME_DIR = %q, want a per-user directory in /tmp", got)
+	}
+
+	t.Setenv("NETCUP_KUBE_RUNTIME_DIR", "/run/netcup")
+	if got := RuntimeDir("netcup-kube"); got != "/run/netcup" {
+		t.Errorf("RuntimeDir(NETCUP_KUBE_RUNTIME_DIR) = %q, want /run/netcup", got)
+	}
+}
+
+func TestEnsurePrivateDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsurePrivateDir(dir); err != nil {
+		t.Fatalf("EnsurePrivateDir() error = %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o700 {
+		t.Errorf("mode = %o, want 700", perm)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "b.env")
+	if err := os.WriteFile(existing, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Find("", filepath.Join(dir, "a.env"), existing); got != existing {
+		t.Errorf("Find() = %q, want %q", got, existing)
+	}
+	if got := Find(filepath.Join(dir, "a.env")); got != "" {
+		t.Errorf("Find(missing) = %q, want empty", got)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.json")
+	current := filepath.Join(dir, "netcup-claw", "state.json")
+
+	if got := Migrate(legacy, current); got != current {
+		t.Errorf("Migrate(missing legacy) = %q, want %q", got, current)
+	}
+
+	if err := os.WriteFile(legacy, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Migrate(legacy, current); got != current {
+		t.Fatalf("Migrate() = %q, want %q", got, current)
+	}
+	if data, err := os.ReadFile(current); err != nil || string(data) != "old" {
+		t.Errorf("current = %q, %v; want the legacy content", data, err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy file still exists: %v", err)
+	}
+
+	// An existing current file is never replaced
+	if err := os.WriteFile(legacy, []byte("stale"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := Migrate(legacy, current); got != current {
+		t.Errorf("Migrate(existing current) = %q, want %q", got, current)
+	}
+	if data, _ := os.ReadFile(current); string(data) != "old" {
+		t.Errorf("current = %q, want it unchanged", data)
+	}
+}
+
+func TestDirsWithoutHome(t *testing.T) {
+	t.Setenv("HOME", "")
+	t.Setenv("XDG_CACHE_HOME", "")
+	t.Setenv("NETCUP_KUBE_CACHE_HOME", "")
+	got := CacheDir("netcup-kube")
+	if filepath.Dir(filepath.Dir(got)) != filepath.Clean(os.TempDir()) || filepath.Base(got) != "cache" {
+		t.Errorf("CacheDir() without HOME = %q, want a per-user directory in %s", got, os.TempDir())
+	}
+}
+
+func TestEnsurePrivateDirErrors(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsurePrivateDir(filepath.Join(file, "run")); err == nil {
+		t.Error("EnsurePrivateDir() below a file expected error")
+	}
+}
+
+func TestEnsurePrivateDirRejectsForeignOwner(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("creating a directory owned by another user needs root")
+	}
+	dir := filepath.Join(t.TempDir(), "run")
+	if err := os.Mkdir(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chown(dir, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsurePrivateDir(dir); err == nil || !strings.Contains(err.Error(), "owned by uid 65534") {
+		t.Errorf("EnsurePrivateDir() on a foreign directory error = %v", err)
+	}
+}
+
+func TestMigrateFallbacks(t *testing.T) {
+	dir := t.TempDir()
+	if got := Migrate("", "current"); got != "current" {
+		t.Errorf("Migrate(no legacy) = %q", got)
+	}
+	legacy := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(legacy, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// The new location cannot be created, so the legacy file stays in use
+	blocked := filepath.Join(legacy, "netcup-claw", "state.json")
+	if got := Migrate(legacy, blocked); got != legacy {
+		t.Errorf("Migrate(blocked) = %q, want %q", got, legacy)
+	}
+	// Neither a rename nor a copy can replace a directory
+	target := filepath.Join(dir, "netcup-claw") + string(filepath.Separator)
+	if got := Migrate(legacy, target); got != legacy {
+		t.Errorf("Migrate(directory) = %q, want %q", got, legacy)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("legacy file removed: %v", err)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.WriteFile(src, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dst := filepath.Join(dir, "dst")
+	if err := copyFile(src, dst, 0o640); err != nil {
+		t.Fatalf("copyFile() error: %v", err)
+	}
+	if info, err := os.Stat(dst); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("dst = %v, %v", info, err)
+	}
+	if err := copyFile(src, dst, 0o600); err == nil {
+		t.Error("copyFile() over an existing file expected error")
+	}
+	if err := copyFile(filepath.Join(dir, "missing"), filepath.Join(dir, "other"), 0o600); err == nil {
+		t.Error("copyFile() of a missing file expected error")
+	}
+	// A failed copy removes the partial destination
+	if err := copyFile(dir, filepath.Join(dir, "partial"), 0o600); err == nil {
+		t.Error("copyFile() of a directory expected error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "partial")); !os.IsNotExist(err) {
+		t.Errorf("partial copy left behind: %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+// Package pins reads and edits the version pins of recipes.conf: chart
+// versions (CHART_VERSION_*), release channels (CHART_CHANNEL_*), container
+// image tags (IMAGE_VERSION_*), the k3s version (K3S_VERSION) and other
+// release versions (*_VERSION, e.g. ARGOCD_VERSION).
+//
+// Edits rewrite only the assignment line of a key, so comments, blank lines,
+// the order of the file and trailing comments of the line are kept. New keys
+// are added after the last pin of the same kind.
+package pins
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/filelock"
+)
+
+// Kinds of pins
+const (
+	KindChart   = "chart"
+	KindChannel = "channel"
+	KindImage   = "image"
+	KindK3s     = "k3s"
+	KindRelease = "release"
+)
+
+var (
+	keyPattern   = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	valuePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+)
+
+// KindOf returns the kind of a pin key, and false for keys that are not pins
+// (namespaces, storage sizes and other settings)
+func KindOf(key string) (string, bool) {
+	switch {
+	case strings.HasPrefix(key, "CHART_VERSION_"):
+		return KindChart, true
+	case strings.HasPrefix(key, "CHART_CHANNEL_"):
+		return KindChannel, true
+	case strings.HasPrefix(key, "IMAGE_VERSION_"):
+		return KindImage, true
+	case key == "K3S_VERSION":
+		return KindK3s, true
+	case strings.HasSuffix(key, "_VERSION"):
+		return KindRelease, true
+	}
+	return "", false
+}
+
+// Pin is one pinned version
+type Pin struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Kind  string `json:"kind"`
+	// Line is the 1-based line of the assignment
+	Line int `json:"line"`
+}
+
+// File is a recipes.conf-style KEY=value file
+type File struct {
+	lines []string
+}
+
+// Parse reads file content
+func Parse(data []byte) *File {
+	content := strings.TrimSuffix(string(data), "\n")
+	if content == "" {
+		return &File{}
+	}
+	return &File{lines: strings.Split(content, "\n")}
+}
+
+// Load reads the file at path
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return Parse(data), nil
+}
+
+// Bytes renders the file, ending with a newline
+func (f *File) Bytes() []byte {
+	if len(f.lines) == 0 {
+		return nil
+	}
+	return []byte(strings.Join(f.lines, "\n") + "\n")
+}
+
+// assignment splits a line into key, value and the rest of the line after
+// the value (a trailing comment with its leading space)
+func assignment(line string) (key, value, rest string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return "", "", "", false
+	}
+	key, value, ok = strings.Cut(trimmed, "=")
+	key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+	if !ok || !keyPattern.MatchString(key) {
+		return "", "", "", false
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value, rest = value[:i], value[i:]
+	}
+	return key, strings.Trim(strings.TrimSpace(value), `"'`), rest, true
+}
+
+// List returns the pins in file order, the last assignment winning for
+// repeated keys
+func (f *File) List() []Pin {
+	var pins []Pin
+	index := map[string]int{}
+	for i, line := range f.lines {
+		key, value, _, ok := assignment(line)
+		if !ok {
+			continue
+		}
+		kind, isPin := KindOf(key)
+		if !isPin {
+			continue
+		}
+		p := Pin{Key: key, Value: value, Kind: kind, Line: i + 1}
+		if j, seen := index[key]; seen {
+			pins[j] = p
+			continue
+		}
+		index[key] = len(pins)
+		pins = append(pins, p)
+	}
+	return pins
+}
+
+// Get returns the pin of key
+func (f *File) Get(key string) (Pin, bool) {
+	for _, p := range f.List() {
+		if p.Key == key {
+			return p, true
+		}
+	}
+	return Pin{}, false
+}
+
+// Values returns the pins as a key to value map
+func (f *File) Values() map[string]string {
+	values := map[string]string{}
+	for _, p := range f.List() {
+		values[p.Key] = p.Value
+	}
+	return values
+}
+
+// Validate checks that key is a pin key and value a version-like value
+func Validate(key, value string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("invalid key %q (use upper case letters, digits and _)", key)
+	}
+	if _, ok := KindOf(key); !ok {
+		return fmt.Errorf("%s is not a version pin (CHART_VERSION_*, CHART_CHANNEL_*, IMAGE_VERSION_*, K3S_VERSION or *_VERSION)", key)
+	}
+	if !valuePattern.MatchString(value) {
+		return fmt.Errorf("invalid value %q for %s (a version or tag without spaces)", value, key)
+	}
+	return nil
+}
+
+// ErrNotFound is returned by Replace for keys that are not pinned
+var ErrNotFound = errors.New("pin not found")
+
+// Replace updates the value of an existing pin, keeping a trailing comment
+func (f *File) Replace(key, value string) error {
+	if err := Validate(key, value); err != nil {
+		return err
+	}
+	p, ok := f.Get(key)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	line := f.lines[p.Line-1]
+	_, _, rest, _ := assignment(line)
+	indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+	prefix := ""
+	if strings.HasPrefix(strings.TrimSpace(line), "export ") {
+		prefix = "export "
+	}
+	f.lines[p.Line-1] = indent + prefix + key + "=" + value + rest
+	return nil
+}
+
+// Set updates key, or adds it after the last pin of the same kind (at the
+// end of the file when there is none). It reports whether the key was added.
+func (f *File) Set(key, value string) (bool, error) {
+	err := f.Replace(key, value)
+	if !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	kind, _ := KindOf(key)
+	at := len(f.lines)
+	for _, p := range f.List() {
+		if p.Kind == kind {
+			at = p.Line
+		}
+	}
+	f.lines = append(f.lines[:at], append([]string{key + "=" + value}, f.lines[at:]...)...)
+	return true, nil
+}
+
+// Update edits the file at path under its lock; edit runs on the current
+// content and the file is only written when it returns no error
+func Update(path string, edit func(*File) error) error {
+	l, err := filelock.Acquire(path, filelock.DefaultTimeout)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	f, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := edit(f); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, f.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Kinds returns the pin kinds
+func Kinds() []string {
+	return []string{KindChart, KindChannel, KindImage, KindK3s, KindRelease}
+}
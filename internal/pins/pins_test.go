@@ -0,0 +1,107 @@
+package pins
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const conf = `# Helm Chart Versions
+CHART_VERSION_REDIS=24.1.0
+CHART_VERSION_OPENCLAW=1.4.4 # bumped by netcup-claw upgrade
+
+# Container Image Versions
+IMAGE_VERSION_REDISINSIGHT="2.62.0"
+
+ARGOCD_VERSION=v2.13.2
+NAMESPACE_PLATFORM=platform
+`
+
+func TestList(t *testing.T) {
+	f := Parse([]byte(conf))
+	var got []string
+	for _, p := range f.List() {
+		got = append(got, p.Key+"="+p.Value+":"+p.Kind)
+	}
+	want := "CHART_VERSION_REDIS=24.1.0:chart CHART_VERSION_OPENCLAW=1.4.4:chart IMAGE_VERSION_REDISINSIGHT=2.62.0:image ARGOCD_VERSION=v2.13.2:release"
+	if strings.Join(got, " ") != want {
+		t.Errorf("List() = %v", got)
+	}
+	if p, ok := f.Get("CHART_VERSION_OPENCLAW"); !ok || p.Line != 3 {
+		t.Errorf("Get() = %+v, %v", p, ok)
+	}
+	if _, ok := f.Get("NAMESPACE_PLATFORM"); ok {
+		t.Error("Get() returned a setting that is not a pin")
+	}
+}
+
+func TestSet(t *testing.T) {
+	f := Parse([]byte(conf))
+	if err := f.Replace("CHART_VERSION_OPENCLAW", "1.4.5"); err != nil {
+		t.Fatal(err)
+	}
+	if added, err := f.Set("CHART_VERSION_POSTGRESQL", "16.2.4"); err != nil || !added {
+		t.Fatalf("Set() = %v, %v", added, err)
+	}
+	if added, err := f.Set("K3S_VERSION", "v1.31.4+k3s1"); err != nil || !added {
+		t.Fatalf("Set() = %v, %v", added, err)
+	}
+	if added, err := f.Set("IMAGE_VERSION_REDISINSIGHT", "2.64.0"); err != nil || added {
+		t.Fatalf("Set() = %v, %v", added, err)
+	}
+	want := `# Helm Chart Versions
+CHART_VERSION_REDIS=24.1.0
+CHART_VERSION_OPENCLAW=1.4.5 # bumped by netcup-claw upgrade
+CHART_VERSION_POSTGRESQL=16.2.4
+
+# Container Image Versions
+IMAGE_VERSION_REDISINSIGHT=2.64.0
+
+ARGOCD_VERSION=v2.13.2
+NAMESPACE_PLATFORM=platform
+K3S_VERSION=v1.31.4+k3s1
+`
+	if got := string(f.Bytes()); got != want {
+		t.Errorf("Bytes() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct{ key, value string }{
+		{"NAMESPACE_PLATFORM", "platform"},
+		{"chart_version_redis", "1.0.0"},
+		{"CHART_VERSION_REDIS", ""},
+		{"CHART_VERSION_REDIS", "1.0 # x"},
+	} {
+		if err := Validate(tc.key, tc.value); err == nil {
+			t.Errorf("Validate(%q, %q) succeeded", tc.key, tc.value)
+		}
+	}
+	if err := Parse([]byte(conf)).Replace("CHART_VERSION_MYSQL", "12.3.5"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Replace() error = %v", err)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "recipes.conf")
+	if err := os.WriteFile(path, []byte(conf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Update(path, func(f *File) error { return f.Replace("CHART_VERSION_MYSQL", "1") }); err == nil {
+		t.Fatal("Update() succeeded for a missing pin")
+	}
+	if data, _ := os.ReadFile(path); string(data) != conf {
+		t.Error("a failed Update() changed the file")
+	}
+	if err := Update(path, func(f *File) error { return f.Replace("ARGOCD_VERSION", "v2.14.0") }); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Values()["ARGOCD_VERSION"] != "v2.14.0" {
+		t.Errorf("Values() = %v", f.Values())
+	}
+}
@@ -0,0 +1,120 @@
+// Package plugin discovers and runs third-party subcommands installed as
+// <cli>-<name> executables on PATH, the way kubectl plugins work:
+// `netcup-kube foo bar` runs `netcup-kube-foo-bar` if present, else
+// `netcup-kube-foo bar`.
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+)
+
+// Plugin is an executable found on PATH
+type Plugin struct {
+	// Name is the subcommand, with dashes of the file name shown as spaces
+	Name string
+	Path string
+	// ShadowedBy is the path of an earlier PATH entry with the same name
+	ShadowedBy string
+}
+
+// SplitArgs separates leading global flags from the first positional
+// argument onwards. takesValue reports whether a long or short flag name
+// (without dashes) consumes the following argument.
+func SplitArgs(args []string, takesValue func(name string) bool) (globals, rest []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			return args[:i], args[i:]
+		}
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		if takesValue(name) && i+1 < len(args) {
+			i++
+		}
+	}
+	return args, nil
+}
+
+// Find returns the executable for the longest run of leading positional args
+// that names a plugin (<prefix>-<arg0>-<arg1>...) and the args left for it.
+func Find(prefix string, args []string, lookPath func(string) (string, error)) (path string, pluginArgs []string, ok bool) {
+	n := 0
+	for n < len(args) && validName(args[n]) {
+		n++
+	}
+	for ; n > 0; n-- {
+		candidate := prefix + "-" + strings.Join(args[:n], "-")
+		if p, err := lookPath(candidate); err == nil {
+			return p, args[n:], true
+		}
+	}
+	return "", nil, false
+}
+
+// validName reports whether arg may be part of a plugin file name
+func validName(arg string) bool {
+	return arg != "" && !strings.HasPrefix(arg, "-") && !strings.ContainsAny(arg, `/\=`)
+}
+
+// List returns the plugins with the given prefix found in the directories of
+// pathEnv, sorted by name. Later duplicates are reported as shadowed.
+func List(prefix, pathEnv string) []Plugin {
+	var plugins []Plugin
+	first := map[string]string{}
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			file := entry.Name()
+			if !strings.HasPrefix(file, prefix+"-") || entry.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, file)
+			if !isExecutable(path) {
+				continue
+			}
+			p := Plugin{
+				Name: strings.ReplaceAll(strings.TrimPrefix(file, prefix+"-"), "-", " "),
+				Path: path,
+			}
+			if earlier, seen := first[file]; seen {
+				p.ShadowedBy = earlier
+			} else {
+				first[file] = path
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	sort.SliceStable(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// Run executes the plugin with the caller's stdio and env as its full
+// environment. A non-zero exit is returned as *exec.ExitError. When ctx is
+// canceled the plugin gets SIGTERM first, so it can clean up.
+func Run(ctx context.Context, path string, args, env []string) error {
+	cmd := interrupt.Command(ctx, path, args...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
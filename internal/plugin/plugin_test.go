@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	takesValue := func(name string) bool { return name == "env-file" }
+	globals, rest := SplitArgs([]string{"--dry-run", "--env-file", "x.env", "--tunnel=a", "foo", "--bar"}, takesValue)
+	if strings.Join(globals, " ") != "--dry-run --env-file x.env --tunnel=a" {
+		t.Errorf("globals = %v", globals)
+	}
+	if strings.Join(rest, " ") != "foo --bar" {
+		t.Errorf("rest = %v", rest)
+	}
+
+	if _, rest := SplitArgs([]string{"--help"}, takesValue); len(rest) != 0 {
+		t.Errorf("flags only: rest = %v", rest)
+	}
+}
+
+func TestFindPrefersLongestName(t *testing.T) {
+	installed := map[string]bool{"netcup-kube-foo": true, "netcup-kube-foo-bar": true}
+	lookPath := func(name string) (string, error) {
+		if installed[name] {
+			return "/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	path, args, ok := Find("netcup-kube", []string{"foo", "bar", "baz", "--x"}, lookPath)
+	if !ok || path != "/bin/netcup-kube-foo-bar" || strings.Join(args, " ") != "baz --x" {
+		t.Errorf("got %q %v %v", path, args, ok)
+	}
+
+	path, args, ok = Find("netcup-kube", []string{"foo", "--bar", "baz"}, lookPath)
+	if !ok || path != "/bin/netcup-kube-foo" || strings.Join(args, " ") != "--bar baz" {
+		t.Errorf("got %q %v %v", path, args, ok)
+	}
+
+	if _, _, ok := Find("netcup-kube", []string{"../foo"}, lookPath); ok {
+		t.Error("path-like names must not resolve")
+	}
+	if _, _, ok := Find("netcup-kube", []string{"nope"}, lookPath); ok {
+		t.Error("unknown plugin resolved")
+	}
+}
+
+func TestList(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	write := func(dir, name string, mode os.FileMode) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(first, "netcup-claw-usage-report", 0o755)
+	write(first, "netcup-claw-notes.txt", 0o644)
+	write(second, "netcup-claw-usage-report", 0o755)
+	write(second, "netcup-claw-backup", 0o755)
+	write(second, "other-tool", 0o755)
+
+	plugins := List("netcup-claw", first+string(os.PathListSeparator)+second)
+	if len(plugins) != 3 {
+		t.Fatalf("got %d plugins: %+v", len(plugins), plugins)
+	}
+	if plugins[0].Name != "backup" || plugins[1].Name != "usage report" {
+		t.Errorf("unexpected order: %+v", plugins)
+	}
+	if plugins[1].ShadowedBy != "" || plugins[2].ShadowedBy != filepath.Join(first, "netcup-claw-usage-report") {
+		t.Errorf("shadowing not reported: %+v", plugins)
+	}
+}
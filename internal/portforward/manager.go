@@ -1,13 +1,18 @@
 package portforward
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/mfittko/netcup-kube/internal/filelock"
+	"github.com/mfittko/netcup-kube/internal/paths"
 )
 
 // State represents the port-forward lifecycle state
@@ -26,18 +31,49 @@ const (
 
 // Status holds port-forward status information
 type Status struct {
-	State     State  `json:"state"`
-	PID       int    `json:"pid,omitempty"`
-	LocalPort string `json:"local_port"`
-	LogFile   string `json:"log_file,omitempty"`
+	State      State     `json:"state"`
+	PID        int       `json:"pid,omitempty"`
+	LocalPort  string    `json:"local_port"`
+	LogFile    string    `json:"log_file,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Target     string    `json:"target,omitempty"`
+	RemotePort string    `json:"remote_port,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	// Restarts counts starts that replaced a port-forward which had died
+	Restarts int `json:"restarts"`
 }
 
+// stateVersion is the schema version written to state files. Files without
+// a version predate the field but share the version 1 layout.
+const stateVersion = 1
+
 // stateFile is the on-disk representation of port-forward state
 type stateFile struct {
-	State     State  `json:"state"`
-	PID       int    `json:"pid,omitempty"`
-	LocalPort string `json:"local_port"`
-	LogFile   string `json:"log_file,omitempty"`
+	Version    int       `json:"version"`
+	State      State     `json:"state"`
+	PID        int       `json:"pid,omitempty"`
+	LocalPort  string    `json:"local_port"`
+	LogFile    string    `json:"log_file,omitempty"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Target     string    `json:"target,omitempty"`
+	RemotePort string    `json:"remote_port,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	Restarts   int       `json:"restarts,omitempty"`
+}
+
+// status converts the on-disk state to a Status
+func (st *stateFile) status() Status {
+	return Status{
+		State:      st.State,
+		PID:        st.PID,
+		LocalPort:  st.LocalPort,
+		LogFile:    st.LogFile,
+		Namespace:  st.Namespace,
+		Target:     st.Target,
+		RemotePort: st.RemotePort,
+		StartedAt:  st.StartedAt,
+		Restarts:   st.Restarts,
+	}
 }
 
 // Manager handles the lifecycle of a background kubectl port-forward process.
@@ -46,8 +82,11 @@ type Manager struct {
 	Target     string
 	LocalPort  string
 	RemotePort string
+	// Address is the local address kubectl listens on; empty means localhost
+	Address string
 
-	// stateDir is the directory for PID/log/state files. Defaults to /tmp.
+	// stateDir is the directory for PID/log/state files. Defaults to the
+	// runtime directory of netcup-claw.
 	stateDir string
 
 	// startFunc allows injection for testing
@@ -55,6 +94,9 @@ type Manager struct {
 
 	// processChecker allows injection for testing
 	processChecker ProcessChecker
+
+	// warnings receives recovery warnings; defaults to stderr
+	warnings io.Writer
 }
 
 // StartFunc launches the kubectl port-forward process and returns its PID.
@@ -74,6 +116,22 @@ func WithStateDir(dir string) Option {
 	}
 }
 
+// WithAddress makes kubectl listen on address, e.g. "::1" or
+// "127.0.0.1,::1", instead of localhost
+func WithAddress(address string) Option {
+	return func(m *Manager) {
+		m.Address = address
+		m.startFunc = kubectlStartFunc(address)
+	}
+}
+
+// WithWarnings sets where recovery warnings (e.g. a corrupt state file) go
+func WithWarnings(w io.Writer) Option {
+	return func(m *Manager) {
+		m.warnings = w
+	}
+}
+
 // WithStartFunc sets a custom start function (for testing)
 func WithStartFunc(fn StartFunc) Option {
 	return func(m *Manager) {
@@ -95,19 +153,29 @@ func New(namespace, target, localPort, remotePort string, opts ...Option) *Manag
 		Target:         target,
 		LocalPort:      localPort,
 		RemotePort:     remotePort,
-		stateDir:       defaultStateDir(),
 		startFunc:      defaultStartFunc,
 		processChecker: defaultProcessChecker,
+		warnings:       os.Stderr,
 	}
 	for _, opt := range opts {
 		opt(m)
 	}
+	if m.stateDir == "" {
+		m.stateDir = defaultStateDir()
+		m.migrateLegacyState()
+	}
 	return m
 }
 
 // Start starts the port-forward in the background. It is idempotent: if already
 // running, it returns immediately without starting a duplicate process.
 func (m *Manager) Start() error {
+	l, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
 	// Check if already running
 	st, _ := m.readState()
 	if st != nil && (st.State == StateRunning || st.State == StateStarting) {
@@ -116,54 +184,56 @@ func (m *Manager) Start() error {
 		}
 	}
 
-	if isPortListening(m.LocalPort) {
+	if isPortListening(m.LocalPort, m.probeHosts()...) {
 		return fmt.Errorf("local port %s is already in use; stop the existing forward or use a different local port", m.LocalPort)
 	}
 
+	// A start after the previous port-forward died (rather than was
+	// stopped) counts as a restart
+	restarts := 0
+	if st != nil && st.State != StateStopped {
+		restarts = st.Restarts + 1
+	}
+
 	// Transition to starting
-	logFile := m.logFilePath()
-	if err := m.writeState(&stateFile{
-		State:     StateStarting,
-		LocalPort: m.LocalPort,
-		LogFile:   logFile,
-	}); err != nil {
+	next := &stateFile{
+		State:      StateStarting,
+		LocalPort:  m.LocalPort,
+		LogFile:    m.logFilePath(),
+		Namespace:  m.Namespace,
+		Target:     m.Target,
+		RemotePort: m.RemotePort,
+		StartedAt:  time.Now().UTC(),
+		Restarts:   restarts,
+	}
+	logFile := next.LogFile
+	if err := m.writeState(next); err != nil {
 		return fmt.Errorf("failed to write state: %w", err)
 	}
 
 	// Launch background process
 	pid, err := m.startFunc(m.Namespace, m.Target, m.LocalPort, m.RemotePort, logFile)
 	if err != nil {
-		_ = m.writeState(&stateFile{State: StateFailed, LocalPort: m.LocalPort})
+		next.State = StateFailed
+		_ = m.writeState(next)
 		return fmt.Errorf("failed to start port-forward: %w", err)
 	}
 
 	// Transition to running
-	if err := m.writeState(&stateFile{
-		State:     StateRunning,
-		PID:       pid,
-		LocalPort: m.LocalPort,
-		LogFile:   logFile,
-	}); err != nil {
+	next.State, next.PID = StateRunning, pid
+	if err := m.writeState(next); err != nil {
 		if proc, findErr := os.FindProcess(pid); findErr == nil {
 			_ = proc.Kill()
 		}
-		_ = m.writeState(&stateFile{
-			State:     StateFailed,
-			PID:       pid,
-			LocalPort: m.LocalPort,
-			LogFile:   logFile,
-		})
+		next.State = StateFailed
+		_ = m.writeState(next)
 		return fmt.Errorf("failed to write state: %w", err)
 	}
 
 	time.Sleep(200 * time.Millisecond)
 	if !m.processChecker(pid) {
-		_ = m.writeState(&stateFile{
-			State:     StateFailed,
-			PID:       pid,
-			LocalPort: m.LocalPort,
-			LogFile:   logFile,
-		})
+		next.State = StateFailed
+		_ = m.writeState(next)
 		logTail := strings.TrimSpace(readLogTail(logFile, 2048))
 		if logTail != "" {
 			return fmt.Errorf("port-forward process exited immediately (pid %d); see log file %s for details: %s", pid, logFile, logTail)
@@ -177,6 +247,12 @@ func (m *Manager) Start() error {
 // Stop stops the running port-forward process. It is idempotent: if not running,
 // it returns immediately.
 func (m *Manager) Stop() error {
+	l, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
 	st, err := m.readState()
 	if err != nil || st == nil || st.State == StateStopped {
 		return nil // Already stopped, idempotent
@@ -194,7 +270,7 @@ func (m *Manager) Stop() error {
 
 	var writeErr error
 	for i := 0; i < 3; i++ {
-		if err := m.writeState(&stateFile{State: StateStopped, LocalPort: m.LocalPort}); err == nil {
+		if err := m.writeState(&stateFile{State: StateStopped, LocalPort: m.LocalPort, Namespace: m.Namespace}); err == nil {
 			writeErr = nil
 			break
 		} else {
@@ -217,34 +293,16 @@ func (m *Manager) Status() Status {
 	}
 
 	// Validate that the tracked process is still alive
-	if st.State == StateRunning && st.PID > 0 {
-		if !m.processChecker(st.PID) {
-			// Process died; update state
-			failed := &stateFile{
-				State:     StateFailed,
-				PID:       st.PID,
-				LocalPort: st.LocalPort,
-				LogFile:   st.LogFile,
-			}
-			if failed.LocalPort == "" {
-				failed.LocalPort = m.LocalPort
-			}
-			_ = m.writeState(failed)
-			return Status{
-				State:     StateFailed,
-				PID:       failed.PID,
-				LocalPort: failed.LocalPort,
-				LogFile:   failed.LogFile,
-			}
+	if st.State == StateRunning && st.PID > 0 && !m.processChecker(st.PID) {
+		// Process died; update state
+		st.State = StateFailed
+		if st.LocalPort == "" {
+			st.LocalPort = m.LocalPort
 		}
+		_ = m.writeState(st)
 	}
 
-	return Status{
-		State:     st.State,
-		PID:       st.PID,
-		LocalPort: st.LocalPort,
-		LogFile:   st.LogFile,
-	}
+	return st.status()
 }
 
 // stateFilePath returns the path to the state file
@@ -259,7 +317,20 @@ func (m *Manager) logFilePath() string {
 	return filepath.Join(m.stateDir, key)
 }
 
-// readState reads the state from disk. Returns nil without error if the file doesn't exist.
+// lock serializes Start and Stop of this port-forward across invocations, so
+// two of them never launch duplicate processes or interleave state writes
+func (m *Manager) lock() (*filelock.Lock, error) {
+	l, err := filelock.Acquire(m.stateFilePath(), filelock.DefaultTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock port-forward state: %w", err)
+	}
+	return l, nil
+}
+
+// readState reads the state from disk. Returns nil without error if the file
+// doesn't exist. A corrupt file or one of an unknown schema version is moved
+// aside to <file>.corrupt with a warning and treated as stopped, so a crash
+// mid-write or a downgrade never wedges the port-forward.
 func (m *Manager) readState() (*stateFile, error) {
 	path := m.stateFilePath()
 	data, err := os.ReadFile(path)
@@ -272,21 +343,57 @@ func (m *Manager) readState() (*stateFile, error) {
 
 	var st stateFile
 	if err := json.Unmarshal(data, &st); err != nil {
-		return nil, fmt.Errorf("failed to parse state file: %w", err)
+		m.discardState(path, fmt.Sprintf("is corrupt (%v)", err))
+		return nil, nil
+	}
+	if st.Version != 0 && st.Version != stateVersion {
+		m.discardState(path, fmt.Sprintf("has unsupported version %d (want %d)", st.Version, stateVersion))
+		return nil, nil
 	}
 
 	return &st, nil
 }
 
+// discardState moves an unusable state file aside for inspection
+func (m *Manager) discardState(path, reason string) {
+	corrupt := path + ".corrupt"
+	if err := os.Rename(path, corrupt); err != nil {
+		corrupt = path
+	}
+	if m.warnings != nil {
+		_, _ = fmt.Fprintf(m.warnings, "warning: port-forward state %s %s; treating it as stopped (kept as %s)\n", path, reason, corrupt)
+	}
+}
+
 // writeState writes the state to disk
 func (m *Manager) writeState(st *stateFile) error {
 	path := m.stateFilePath()
+	st.Version = stateVersion
 	data, err := json.Marshal(st)
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	return os.WriteFile(path, data, 0600)
+	// Write a temporary file and rename it, so readers and crashes never
+	// leave a partial state file behind
+	tmp, err := os.CreateTemp(m.stateDir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
 }
 
 // sanitize replaces characters that are unsafe in filenames
@@ -299,17 +406,51 @@ func sanitize(s string) string {
 	return replacer.Replace(s)
 }
 
-// defaultStateDir returns the default directory for state files
+// defaultStateDir returns the default directory for state files: the private
+// runtime directory of netcup-claw, or the legacy shared directory when it
+// cannot be created
 func defaultStateDir() string {
+	dir := paths.RuntimeDir("netcup-claw")
+	if err := paths.EnsurePrivateDir(dir); err != nil {
+		return legacyStateDir()
+	}
+	return dir
+}
+
+// legacyStateDir is where state files were kept before the runtime directory
+func legacyStateDir() string {
 	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
 		return dir
 	}
 	return "/tmp"
 }
 
+// migrateLegacyState moves the state and log file of a port-forward started by
+// an older version into the state directory, so it can still be stopped
+func (m *Manager) migrateLegacyState() {
+	legacy := legacyStateDir()
+	if legacy == m.stateDir {
+		return
+	}
+	oldLog := filepath.Join(legacy, filepath.Base(m.logFilePath()))
+	logMoved := paths.Migrate(oldLog, m.logFilePath()) == m.logFilePath()
+	paths.Migrate(filepath.Join(legacy, filepath.Base(m.stateFilePath())), m.stateFilePath())
+
+	if st, err := m.readState(); err == nil && st != nil && st.LogFile == oldLog && logMoved {
+		st.LogFile = m.logFilePath()
+		_ = m.writeState(st)
+	}
+}
+
 // ReadinessCheck probes the local port for readiness with a timeout.
 // Returns nil when the port is accepting connections within the deadline.
 func ReadinessCheck(localPort string, timeout time.Duration) error {
+	return WaitReady(context.Background(), localPort, timeout)
+}
+
+// WaitReady is ReadinessCheck that also returns ctx's error once ctx is
+// done, e.g. on Ctrl-C, so the caller can stop a forward it just started.
+func WaitReady(ctx context.Context, localPort string, timeout time.Duration) error {
 	deadline := time.Now().Add(timeout)
 	for {
 		remaining := time.Until(deadline)
@@ -325,20 +466,49 @@ func ReadinessCheck(localPort string, timeout time.Duration) error {
 		if remaining < sleepDuration {
 			sleepDuration = remaining
 		}
-		if sleepDuration > 0 {
-			time.Sleep(sleepDuration)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sleepDuration):
 		}
 	}
 	return fmt.Errorf("port-forward on :%s not ready after %s", localPort, timeout)
 }
 
-// isPortListening checks if the local port is accepting TCP connections
-func isPortListening(port string) bool {
+// probeHosts returns the addresses the forward listens on; nil means the
+// loopback addresses of both families
+func (m *Manager) probeHosts() []string {
+	var hosts []string
+	for _, a := range strings.Split(m.Address, ",") {
+		switch a = strings.Trim(strings.TrimSpace(a), "[]"); a {
+		case "", "localhost":
+		case "0.0.0.0":
+			hosts = append(hosts, "127.0.0.1")
+		case "::":
+			hosts = append(hosts, "::1")
+		default:
+			hosts = append(hosts, a)
+		}
+	}
+	return hosts
+}
+
+// isPortListening checks if the local port is accepting TCP connections on
+// any of hosts (default: 127.0.0.1 and ::1)
+func isPortListening(port string, hosts ...string) bool {
 	portNum, err := strconv.Atoi(port)
 	if err != nil || portNum <= 0 || portNum > 65535 {
 		return false
 	}
-	return tcpProbe("127.0.0.1", port)
+	if len(hosts) == 0 {
+		hosts = []string{"127.0.0.1", "::1"}
+	}
+	for _, host := range hosts {
+		if tcpProbe(host, port) {
+			return true
+		}
+	}
+	return false
 }
 
 func readLogTail(path string, maxBytes int) string {
@@ -1,6 +1,9 @@
 package portforward
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -339,6 +342,18 @@ func TestReadinessCheck_Timeout(t *testing.T) {
 	}
 }
 
+func TestWaitReady_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := WaitReady(ctx, "59876", 5*time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("WaitReady() error = %v, want context canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("WaitReady() returned after %s, want prompt return", elapsed)
+	}
+}
+
 func TestReadinessCheck_Success(t *testing.T) {
 	// Start a real TCP listener to simulate a ready port
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
@@ -357,6 +372,44 @@ func TestReadinessCheck_Success(t *testing.T) {
 	}
 }
 
+func TestReadinessCheck_IPv6Loopback(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("Cannot bind IPv6 listener: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("SplitHostPort error: %v", err)
+	}
+	if err := ReadinessCheck(port, 2*time.Second); err != nil {
+		t.Fatalf("ReadinessCheck() on ::1 unexpected error: %v", err)
+	}
+	if m := New("ns", "svc/x", port, port, WithAddress("127.0.0.1")); isPortListening(port, m.probeHosts()...) {
+		t.Error("port bound on ::1 reported listening on 127.0.0.1")
+	}
+}
+
+func TestWithAddress_ProbeHosts(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"", ""},
+		{"localhost", ""},
+		{"::1", "::1"},
+		{"127.0.0.1, [::1]", "127.0.0.1,::1"},
+		{"0.0.0.0,::", "127.0.0.1,::1"},
+	}
+	for _, tt := range tests {
+		m := New("ns", "svc/x", "8080", "80", WithAddress(tt.address))
+		if got := strings.Join(m.probeHosts(), ","); got != tt.want {
+			t.Errorf("probeHosts(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
 func TestIsPortListening_InvalidPort(t *testing.T) {
 	// Invalid port numbers should return false
 	if isPortListening("notaport") {
@@ -380,19 +433,38 @@ func TestDefaultStateDir(t *testing.T) {
 func TestDefaultStateDir_WithXDG(t *testing.T) {
 	tmpDir := t.TempDir()
 	t.Setenv("XDG_RUNTIME_DIR", tmpDir)
+	t.Setenv("NETCUP_CLAW_RUNTIME_DIR", "")
 
 	dir := defaultStateDir()
-	if dir != tmpDir {
-		t.Errorf("defaultStateDir() = %q with XDG_RUNTIME_DIR set, want %q", dir, tmpDir)
+	if want := filepath.Join(tmpDir, "netcup-claw"); dir != want {
+		t.Errorf("defaultStateDir() = %q with XDG_RUNTIME_DIR set, want %q", dir, want)
 	}
 }
 
-func TestDefaultStateDir_WithoutXDG(t *testing.T) {
-	t.Setenv("XDG_RUNTIME_DIR", "")
+func TestNew_MigratesLegacyState(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", tmpDir)
+	t.Setenv("NETCUP_CLAW_RUNTIME_DIR", "")
 
-	dir := defaultStateDir()
-	if dir != "/tmp" {
-		t.Errorf("defaultStateDir() = %q without XDG_RUNTIME_DIR, want /tmp", dir)
+	oldLog := filepath.Join(tmpDir, "netcup-claw-pf-openclaw-18789.log")
+	if err := os.WriteFile(oldLog, []byte("Forwarding from 127.0.0.1:18789\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	st, _ := json.Marshal(stateFile{State: StateRunning, PID: 4242, LocalPort: "18789", LogFile: oldLog})
+	if err := os.WriteFile(filepath.Join(tmpDir, "netcup-claw-pf-openclaw-18789.json"), st, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New("openclaw", "svc/openclaw", "18789", "18789", WithProcessChecker(func(int) bool { return true }))
+	status := m.Status()
+	if status.State != StateRunning || status.PID != 4242 {
+		t.Fatalf("Status() = %+v, want the migrated running state", status)
+	}
+	if want := filepath.Join(tmpDir, "netcup-claw", "netcup-claw-pf-openclaw-18789.log"); status.LogFile != want {
+		t.Errorf("LogFile = %q, want %q", status.LogFile, want)
+	}
+	if _, err := os.Stat(oldLog); !os.IsNotExist(err) {
+		t.Errorf("legacy log file still exists: %v", err)
 	}
 }
 
@@ -404,18 +476,124 @@ func TestDefaultStartFunc_BadLogFile(t *testing.T) {
 	}
 }
 
+func TestStart_RecordsTargetAndRestarts(t *testing.T) {
+	// Far above pid_max, so Stop never signals a real process
+	pid := 90000000
+	alive := map[int]bool{}
+	startFn := func(namespace, target, localPort, remotePort, logFile string) (int, error) {
+		pid++
+		alive[pid] = true
+		return pid, nil
+	}
+	m := newTestManager(t, startFn, func(p int) bool { return alive[p] })
+
+	before := time.Now().Add(-time.Second)
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	st := m.Status()
+	if st.Namespace != "openclaw" || st.Target != "svc/openclaw" || st.RemotePort != "18789" {
+		t.Errorf("Status() = %+v, want namespace, target and remote port recorded", st)
+	}
+	if st.StartedAt.Before(before) || st.StartedAt.After(time.Now()) {
+		t.Errorf("StartedAt = %v, want about now", st.StartedAt)
+	}
+	if st.Restarts != 0 {
+		t.Errorf("Restarts = %d after first start, want 0", st.Restarts)
+	}
+
+	// The process dies and is started again
+	alive[st.PID] = false
+	if status := m.Status(); status.State != StateFailed || status.Target != "svc/openclaw" {
+		t.Fatalf("Status() after death = %+v, want failed with target kept", status)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start() after death error: %v", err)
+	}
+	if st := m.Status(); st.Restarts != 1 {
+		t.Errorf("Restarts = %d after restart, want 1", st.Restarts)
+	}
+
+	// An explicit stop resets the count
+	if err := m.Stop(); err != nil {
+		t.Fatalf("Stop() error: %v", err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatalf("Start() after stop error: %v", err)
+	}
+	if st := m.Status(); st.Restarts != 0 {
+		t.Errorf("Restarts = %d after stop and start, want 0", st.Restarts)
+	}
+}
+
 func TestReadState_CorruptFile(t *testing.T) {
 	tmpDir := t.TempDir()
-	m := New("openclaw", "svc/openclaw", "18789", "18789", WithStateDir(tmpDir))
+	var warnings strings.Builder
+	m := New("openclaw", "svc/openclaw", "18789", "18789", WithStateDir(tmpDir), WithWarnings(&warnings))
 
-	// Write invalid JSON
-	if err := os.WriteFile(m.stateFilePath(), []byte("not json"), 0600); err != nil {
+	// A truncated write leaves invalid JSON behind
+	if err := os.WriteFile(m.stateFilePath(), []byte(`{"state":"runn`), 0600); err != nil {
 		t.Fatal(err)
 	}
 
-	_, err := m.readState()
-	if err == nil {
-		t.Error("readState() expected error for corrupt JSON, got nil")
+	st, err := m.readState()
+	if err != nil || st != nil {
+		t.Fatalf("readState() = %+v, %v; want corrupt state treated as stopped", st, err)
+	}
+	if !strings.Contains(warnings.String(), "is corrupt") {
+		t.Errorf("warnings = %q, want a corrupt state warning", warnings.String())
+	}
+	if _, err := os.Stat(m.stateFilePath() + ".corrupt"); err != nil {
+		t.Errorf("corrupt state not kept for inspection: %v", err)
+	}
+	if status := m.Status(); status.State != StateStopped {
+		t.Errorf("Status() = %+v, want stopped", status)
+	}
+}
+
+func TestReadState_UnsupportedVersion(t *testing.T) {
+	tmpDir := t.TempDir()
+	var warnings strings.Builder
+	m := New("openclaw", "svc/openclaw", "18789", "18789", WithStateDir(tmpDir), WithWarnings(&warnings))
+
+	if err := os.WriteFile(m.stateFilePath(), []byte(`{"version":99,"state":"running","pid":4242,"local_port":"18789"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := m.readState(); err != nil || st != nil {
+		t.Fatalf("readState() = %+v, %v; want unknown version treated as stopped", st, err)
+	}
+	if !strings.Contains(warnings.String(), "unsupported version 99") {
+		t.Errorf("warnings = %q", warnings.String())
+	}
+
+	// Unversioned files from before the version field are still read
+	if err := os.WriteFile(m.stateFilePath(), []byte(`{"state":"running","pid":4242,"local_port":"18789"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if st, err := m.readState(); err != nil || st == nil || st.PID != 4242 {
+		t.Errorf("readState(unversioned) = %+v, %v", st, err)
+	}
+}
+
+func TestWriteState_Atomic(t *testing.T) {
+	tmpDir := t.TempDir()
+	m := New("openclaw", "svc/openclaw", "18789", "18789", WithStateDir(tmpDir))
+
+	if err := m.writeState(&stateFile{State: StateRunning, PID: 4242, LocalPort: "18789"}); err != nil {
+		t.Fatalf("writeState() error = %v", err)
+	}
+	data, err := os.ReadFile(m.stateFilePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"version":1`) {
+		t.Errorf("state = %s, want a schema version", data)
+	}
+	entries, _ := os.ReadDir(tmpDir)
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".tmp") {
+			t.Errorf("temporary file left behind: %s", e.Name())
+		}
 	}
 }
 
@@ -11,9 +11,22 @@ import (
 
 const dialTimeout = 500 * time.Millisecond
 
-// defaultStartFunc starts kubectl port-forward as a detached background process
-// and returns its PID.
+// defaultStartFunc starts kubectl port-forward on kubectl's default address
+// (localhost, both 127.0.0.1 and ::1)
 func defaultStartFunc(namespace, target, localPort, remotePort, logFile string) (int, error) {
+	return kubectlStartFunc("")(namespace, target, localPort, remotePort, logFile)
+}
+
+// kubectlStartFunc returns a StartFunc that starts kubectl port-forward as a
+// detached background process listening on address (comma-separated, e.g.
+// "::1" or "127.0.0.1,::1"; empty for kubectl's default) and returns its PID.
+func kubectlStartFunc(address string) StartFunc {
+	return func(namespace, target, localPort, remotePort, logFile string) (int, error) {
+		return startKubectl(namespace, target, localPort, remotePort, logFile, address)
+	}
+}
+
+func startKubectl(namespace, target, localPort, remotePort, logFile, address string) (int, error) {
 	// Open (or create) the log file for stdout/stderr of the child process
 	lf, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
 	if err != nil {
@@ -22,12 +35,11 @@ func defaultStartFunc(namespace, target, localPort, remotePort, logFile string)
 	defer func() { _ = lf.Close() }()
 
 	portMapping := fmt.Sprintf("%s:%s", localPort, remotePort)
-	cmd := exec.Command("kubectl",
-		"-n", namespace,
-		"port-forward",
-		target,
-		portMapping,
-	)
+	args := []string{"-n", namespace, "port-forward"}
+	if address != "" {
+		args = append(args, "--address", address)
+	}
+	cmd := exec.Command("kubectl", append(args, target, portMapping)...)
 	cmd.Stdout = lf
 	cmd.Stderr = lf
 
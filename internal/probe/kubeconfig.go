@@ -0,0 +1,198 @@
+package probe
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/yamlsubset"
+)
+
+// Target is the API server and credentials of a kubeconfig context
+type Target struct {
+	Context  string
+	Server   string
+	CAData   []byte
+	Insecure bool
+	// ClientCert and ClientKey are PEM; empty unless the user has them
+	ClientCert []byte
+	ClientKey  []byte
+	Token      string
+	// Auth names credentials other than a client certificate, e.g. "bearer
+	// token"; empty when the user has none
+	Auth string
+}
+
+// KubeconfigPath resolves the kubeconfig kubectl would read first: path, the
+// first entry of KUBECONFIG, then ~/.kube/config
+func KubeconfigPath(path string) string {
+	if path != "" {
+		return path
+	}
+	for _, p := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if p != "" {
+			return p
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".kube", "config")
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// LoadKubeconfig reads the server, CA and credentials of a kubeconfig
+// context; an empty context is the current-context. A missing file is an
+// error wrapping os.ErrNotExist.
+func LoadKubeconfig(path, contextName string) (Target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Target{}, err
+	}
+	doc, err := yamlsubset.Parse(data)
+	if err != nil {
+		return Target{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	root, _ := doc.(map[string]any)
+	t := Target{Context: contextName}
+	if t.Context == "" {
+		t.Context, _ = root["current-context"].(string)
+	}
+	if t.Context == "" {
+		return t, fmt.Errorf("%s has no current-context", path)
+	}
+	ctx := namedEntry(root, "contexts", "context", t.Context)
+	if ctx == nil {
+		return t, fmt.Errorf("context %q not found in %s", t.Context, path)
+	}
+	clusterName, _ := ctx["cluster"].(string)
+	cluster := namedEntry(root, "clusters", "cluster", clusterName)
+	if cluster == nil {
+		return t, fmt.Errorf("cluster %q of context %s not found", clusterName, t.Context)
+	}
+	t.Server, _ = cluster["server"].(string)
+	if t.Server == "" {
+		return t, fmt.Errorf("cluster %q has no server", clusterName)
+	}
+	t.Insecure = cluster["insecure-skip-tls-verify"] == "true"
+	if t.CAData, err = inlineOrFile(cluster, "certificate-authority-data", "certificate-authority", path); err != nil {
+		return t, err
+	}
+
+	userName, _ := ctx["user"].(string)
+	user := namedEntry(root, "users", "user", userName)
+	if user == nil {
+		return t, nil
+	}
+	if t.ClientCert, err = inlineOrFile(user, "client-certificate-data", "client-certificate", path); err != nil {
+		return t, err
+	}
+	if t.ClientKey, err = inlineOrFile(user, "client-key-data", "client-key", path); err != nil {
+		return t, err
+	}
+	t.Token, _ = user["token"].(string)
+	if file, _ := user["tokenFile"].(string); t.Token == "" && file != "" {
+		data, err := os.ReadFile(resolveRelative(file, path))
+		if err != nil {
+			return t, fmt.Errorf("failed to read tokenFile: %w", err)
+		}
+		t.Token = strings.TrimSpace(string(data))
+	}
+	switch {
+	case t.Token != "":
+		t.Auth = "bearer token"
+	case user["exec"] != nil:
+		t.Auth = "exec credential plugin"
+	case user["auth-provider"] != nil:
+		t.Auth = "auth provider"
+	case user["username"] != nil:
+		t.Auth = "basic auth"
+	}
+	return t, nil
+}
+
+// Addr returns the host:port of the server, port 443 when the URL has none
+func (t Target) Addr() (string, error) {
+	u, err := url.Parse(t.Server)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid server URL %q", t.Server)
+	}
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), "443"), nil
+	}
+	return u.Host, nil
+}
+
+// TLSConfig verifies the server against the kubeconfig CA (or the system
+// roots without one) and presents the client certificate when there is one
+func (t Target) TLSConfig() (*tls.Config, error) {
+	u, err := url.Parse(t.Server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid server URL %q", t.Server)
+	}
+	config := &tls.Config{ServerName: u.Hostname(), InsecureSkipVerify: t.Insecure}
+	if len(t.CAData) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(t.CAData) {
+			return nil, ErrInvalidCA
+		}
+		config.RootCAs = pool
+	}
+	if len(t.ClientCert) > 0 && len(t.ClientKey) > 0 {
+		cert, err := tls.X509KeyPair(t.ClientCert, t.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// namedEntry returns the <field> mapping of the list item with the given name
+func namedEntry(root map[string]any, list, field, name string) map[string]any {
+	items, _ := root[list].([]any)
+	for _, item := range items {
+		m, _ := item.(map[string]any)
+		if m == nil || m["name"] != name {
+			continue
+		}
+		entry, _ := m[field].(map[string]any)
+		return entry
+	}
+	return nil
+}
+
+// inlineOrFile returns the base64 data field of m, or the content of the file
+// field resolved against the kubeconfig directory
+func inlineOrFile(m map[string]any, dataKey, fileKey, kubeconfig string) ([]byte, error) {
+	if raw, _ := m[dataKey].(string); raw != "" {
+		data, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", dataKey, err)
+		}
+		return data, nil
+	}
+	file, _ := m[fileKey].(string)
+	if file == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(resolveRelative(file, kubeconfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", fileKey, err)
+	}
+	return data, nil
+}
+
+// resolveRelative resolves a kubeconfig file reference against its directory
+func resolveRelative(file, kubeconfig string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(filepath.Dir(kubeconfig), file)
+}
@@ -0,0 +1,269 @@
+// Package probe checks whether the Kubernetes API answers. Strategies range
+// from kubectl (kubeconfig-aware, the default) over a raw TCP connect and a
+// verified TLS handshake to an HTTP GET of /readyz with the kubeconfig
+// credentials or a bearer token.
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Strategy names
+const (
+	StrategyKubectl = "kubectl"
+	StrategyTCP     = "tcp"
+	StrategyTLS     = "tls"
+	StrategyReadyz  = "readyz"
+)
+
+// Environment variables configuring the probe of both CLIs
+const (
+	StrategyEnv = "KUBE_PROBE"
+	TimeoutEnv  = "KUBE_PROBE_TIMEOUT"
+	TokenEnv    = "KUBE_PROBE_TOKEN"
+)
+
+// DefaultTimeout bounds a single probe
+const DefaultTimeout = 3 * time.Second
+
+// ErrInvalidCA is returned when the kubeconfig CA holds no PEM certificate
+var ErrInvalidCA = errors.New("certificate-authority-data holds no PEM certificate")
+
+// Strategy probes the API once, returning a short description of the answer
+type Strategy interface {
+	Name() string
+	Probe(ctx context.Context) (string, error)
+}
+
+// Config selects and configures a strategy
+type Config struct {
+	// Strategy is kubectl (default), tcp, tls or readyz
+	Strategy string
+	// Timeout bounds a probe (default DefaultTimeout)
+	Timeout time.Duration
+	// Kubeconfig and Context select the API server; empty uses what kubectl
+	// would
+	Kubeconfig string
+	Context    string
+	// Token authenticates the readyz strategy instead of the kubeconfig
+	// credentials
+	Token string
+}
+
+// ParseStrategy validates a strategy name; empty is kubectl
+func ParseStrategy(s string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); v {
+	case "":
+		return StrategyKubectl, nil
+	case StrategyKubectl, StrategyTCP, StrategyTLS, StrategyReadyz:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid probe strategy %q (expected kubectl, tcp, tls or readyz)", s)
+}
+
+// ConfigFromEnv reads KUBE_PROBE, KUBE_PROBE_TIMEOUT and KUBE_PROBE_TOKEN
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{Token: os.Getenv(TokenEnv)}
+	strategy, err := ParseStrategy(os.Getenv(StrategyEnv))
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", StrategyEnv, err)
+	}
+	cfg.Strategy = strategy
+	if raw := strings.TrimSpace(os.Getenv(TimeoutEnv)); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return cfg, fmt.Errorf("%s: invalid duration %q", TimeoutEnv, raw)
+		}
+		cfg.Timeout = timeout
+	}
+	return cfg, nil
+}
+
+// New returns the strategy of cfg. The tcp, tls and readyz strategies read
+// the kubeconfig on every probe, so it may appear after New.
+func New(cfg Config) (Strategy, error) {
+	strategy, err := ParseStrategy(cfg.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if strategy == StrategyKubectl {
+		return Kubectl{Kubeconfig: cfg.Kubeconfig, Context: cfg.Context, Timeout: cfg.Timeout}, nil
+	}
+	cfg.Strategy = strategy
+	return kubeconfigStrategy{cfg: cfg}, nil
+}
+
+// Kubectl runs kubectl get --raw=/livez, which handles every kind of
+// kubeconfig credentials
+type Kubectl struct {
+	Kubeconfig string
+	Context    string
+	Timeout    time.Duration
+}
+
+func (k Kubectl) Name() string { return StrategyKubectl }
+
+// Args returns the kubectl arguments of the probe
+func (k Kubectl) Args() []string {
+	var args []string
+	if k.Kubeconfig != "" {
+		args = append(args, "--kubeconfig", k.Kubeconfig)
+	}
+	if k.Context != "" {
+		args = append(args, "--context", k.Context)
+	}
+	timeout := k.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return append(args, "--request-timeout="+timeout.String(), "get", "--raw=/livez")
+}
+
+// Probe returns kubectl's error output as the error
+func (k Kubectl) Probe(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", k.Args()...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.New(msg)
+		}
+		return "", err
+	}
+	return "/livez " + strings.TrimSpace(string(out)), nil
+}
+
+// TCP connects to the API server port
+type TCP struct {
+	Addr    string
+	Timeout time.Duration
+}
+
+func (p TCP) Name() string { return StrategyTCP }
+
+// Probe returns the dial error without the repeated "dial tcp <addr>" prefix
+func (p TCP) Probe(ctx context.Context) (string, error) {
+	dialer := &net.Dialer{Timeout: p.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return "", unwrapOpError(err)
+	}
+	_ = conn.Close()
+	return p.Addr + " accepts connections", nil
+}
+
+// TLS completes a TLS handshake with the API server
+type TLS struct {
+	Addr    string
+	Config  *tls.Config
+	Timeout time.Duration
+}
+
+func (p TLS) Name() string { return StrategyTLS }
+
+// Probe returns x509 verification errors as such, for errors.As
+func (p TLS) Probe(ctx context.Context) (string, error) {
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: p.Timeout}, Config: p.Config}
+	conn, err := dialer.DialContext(ctx, "tcp", p.Addr)
+	if err != nil {
+		return "", unwrapOpError(err)
+	}
+	state := conn.(*tls.Conn).ConnectionState()
+	_ = conn.Close()
+	return tls.VersionName(state.Version) + ", server certificate verified", nil
+}
+
+// Readyz requests /readyz of the API server
+type Readyz struct {
+	Server string
+	Config *tls.Config
+	// Token is sent as bearer token when not empty
+	Token   string
+	Timeout time.Duration
+}
+
+func (p Readyz) Name() string { return StrategyReadyz }
+
+func (p Readyz) Probe(ctx context.Context) (string, error) {
+	client := &http.Client{
+		Timeout:   p.Timeout,
+		Transport: &http.Transport{TLSClientConfig: p.Config, Proxy: http.ProxyFromEnvironment},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(p.Server, "/")+"/readyz", nil)
+	if err != nil {
+		return "", err
+	}
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		msg := "/readyz: " + resp.Status
+		if line := strings.TrimSpace(strings.SplitN(string(body), "\n", 2)[0]); line != "" {
+			msg += ": " + line
+		}
+		return "", errors.New(msg)
+	}
+	return "/readyz " + strings.TrimSpace(string(body)), nil
+}
+
+// kubeconfigStrategy builds a tcp, tls or readyz probe from the kubeconfig
+type kubeconfigStrategy struct {
+	cfg Config
+}
+
+func (s kubeconfigStrategy) Name() string { return s.cfg.Strategy }
+
+func (s kubeconfigStrategy) Probe(ctx context.Context) (string, error) {
+	target, err := LoadKubeconfig(KubeconfigPath(s.cfg.Kubeconfig), s.cfg.Context)
+	if err != nil {
+		return "", err
+	}
+	addr, err := target.Addr()
+	if err != nil {
+		return "", err
+	}
+	if s.cfg.Strategy == StrategyTCP {
+		return TCP{Addr: addr, Timeout: s.cfg.Timeout}.Probe(ctx)
+	}
+	config, err := target.TLSConfig()
+	if err != nil {
+		return "", err
+	}
+	if s.cfg.Strategy == StrategyTLS {
+		return TLS{Addr: addr, Config: config, Timeout: s.cfg.Timeout}.Probe(ctx)
+	}
+	token := s.cfg.Token
+	if token == "" {
+		token = target.Token
+	}
+	return Readyz{Server: target.Server, Config: config, Token: token, Timeout: s.cfg.Timeout}.Probe(ctx)
+}
+
+// unwrapOpError drops the "dial tcp <addr>:" prefix of a net.OpError
+func unwrapOpError(err error) error {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Err != nil {
+		return opErr.Err
+	}
+	return err
+}
@@ -0,0 +1,396 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStrategy(t *testing.T) {
+	for in, want := range map[string]string{"": StrategyKubectl, "TCP": StrategyTCP, " tls ": StrategyTLS, "readyz": StrategyReadyz} {
+		if got, err := ParseStrategy(in); err != nil || got != want {
+			t.Errorf("ParseStrategy(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseStrategy("icmp"); err == nil {
+		t.Error("expected an error for icmp")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv(StrategyEnv, "readyz")
+	t.Setenv(TimeoutEnv, "750ms")
+	t.Setenv(TokenEnv, "s3cr3t")
+	cfg, err := ConfigFromEnv()
+	if err != nil || cfg != (Config{Strategy: StrategyReadyz, Timeout: 750 * time.Millisecond, Token: "s3cr3t"}) {
+		t.Fatalf("ConfigFromEnv = %+v, %v", cfg, err)
+	}
+
+	t.Setenv(TimeoutEnv, "soon")
+	if _, err := ConfigFromEnv(); err == nil || !strings.Contains(err.Error(), TimeoutEnv) {
+		t.Errorf("invalid timeout = %v", err)
+	}
+	t.Setenv(TimeoutEnv, "")
+	t.Setenv(StrategyEnv, "ping")
+	if _, err := ConfigFromEnv(); err == nil || !strings.Contains(err.Error(), StrategyEnv) {
+		t.Errorf("invalid strategy = %v", err)
+	}
+}
+
+func TestKubectlArgs(t *testing.T) {
+	if got, want := (Kubectl{}).Args(), []string{"--request-timeout=3s", "get", "--raw=/livez"}; !slices.Equal(got, want) {
+		t.Errorf("default Args = %v, want %v", got, want)
+	}
+	got := Kubectl{Kubeconfig: "/k3s.yaml", Context: "prod", Timeout: 10 * time.Second}.Args()
+	if want := []string{"--kubeconfig", "/k3s.yaml", "--context", "prod", "--request-timeout=10s", "get", "--raw=/livez"}; !slices.Equal(got, want) {
+		t.Errorf("Args = %v, want %v", got, want)
+	}
+}
+
+// fakeKubectl puts a kubectl script on PATH
+func fakeKubectl(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestKubectlProbe(t *testing.T) {
+	fakeKubectl(t, `echo ok`)
+	if detail, err := (Kubectl{}).Probe(context.Background()); err != nil || detail != "/livez ok" {
+		t.Fatalf("Probe = %q, %v", detail, err)
+	}
+
+	fakeKubectl(t, `echo "The connection to the server 127.0.0.1:6443 was refused" >&2; exit 1`)
+	if _, err := (Kubectl{}).Probe(context.Background()); err == nil || err.Error() != "The connection to the server 127.0.0.1:6443 was refused" {
+		t.Fatalf("Probe error = %v, want kubectl's stderr", err)
+	}
+}
+
+// apiServer serves /readyz, answering 401 without the bearer token "s3cr3t"
+func apiServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/readyz" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			http.Error(w, `{"kind":"Status","reason":"Unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		_, _ = io.WriteString(w, "ok")
+	}))
+	// The tcp and tls probes hang up after connecting
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	kubeconfig := `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: ` + base64.StdEncoding.EncodeToString(ca) + `
+    server: ` + srv.URL + `
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+users:
+- name: default
+  user:
+    token: s3cr3t
+`
+	path := filepath.Join(t.TempDir(), "k3s.yaml")
+	if err := os.WriteFile(path, []byte(kubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return srv, path
+}
+
+func TestKubeconfigStrategies(t *testing.T) {
+	_, kubeconfig := apiServer(t)
+	for strategy, want := range map[string]string{
+		StrategyTCP:    "accepts connections",
+		StrategyTLS:    "server certificate verified",
+		StrategyReadyz: "/readyz ok",
+	} {
+		s, err := New(Config{Strategy: strategy, Kubeconfig: kubeconfig})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Name() != strategy {
+			t.Errorf("Name = %q, want %q", s.Name(), strategy)
+		}
+		if detail, err := s.Probe(context.Background()); err != nil || !strings.Contains(detail, want) {
+			t.Errorf("%s probe = %q, %v; want %q", strategy, detail, err, want)
+		}
+	}
+}
+
+func TestReadyzToken(t *testing.T) {
+	_, kubeconfig := apiServer(t)
+	s, err := New(Config{Strategy: StrategyReadyz, Kubeconfig: kubeconfig, Token: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Probe(context.Background()); err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Fatalf("probe with a wrong token = %v, want 401", err)
+	}
+}
+
+func TestProbeFailures(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l.Addr().String()
+	_ = l.Close()
+
+	if _, err := (TCP{Addr: closed, Timeout: time.Second}).Probe(context.Background()); err == nil || strings.Contains(err.Error(), "dial tcp") {
+		t.Errorf("TCP probe of a closed port = %v, want the bare dial error", err)
+	}
+
+	s, err := New(Config{Strategy: StrategyTLS, Kubeconfig: filepath.Join(t.TempDir(), "missing.yaml")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Probe(context.Background()); err == nil || !os.IsNotExist(err) {
+		t.Errorf("probe without kubeconfig = %v", err)
+	}
+}
+
+func TestLoadKubeconfig(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "client.crt"), []byte("pem"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, doc, context string
+		wantAuth, wantErr  string
+		wantCert           bool
+	}{
+		{
+			name:     "token",
+			doc:      "clusters:\n- name: c\n  cluster:\n    server: https://10.0.0.1:6443\ncontexts:\n- name: x\n  context:\n    cluster: c\n    user: u\ncurrent-context: x\nusers:\n- name: u\n  user:\n    token: abc\n",
+			wantAuth: "bearer token",
+		},
+		{
+			name:     "certificate file relative to the kubeconfig",
+			doc:      "clusters:\n- name: c\n  cluster:\n    server: https://10.0.0.1:6443\ncontexts:\n- name: x\n  context:\n    cluster: c\n    user: u\ncurrent-context: x\nusers:\n- name: u\n  user:\n    client-certificate: client.crt\n",
+			wantCert: true,
+		},
+		{
+			name:     "context override",
+			doc:      "clusters:\n- name: c\n  cluster:\n    server: https://10.0.0.1:6443\ncontexts:\n- name: x\n  context:\n    cluster: c\n    user: u\ncurrent-context: other\nusers:\n- name: u\n  user:\n    exec:\n      command: aws\n",
+			context:  "x",
+			wantAuth: "exec credential plugin",
+		},
+		{name: "no current context", doc: "clusters: []\n", wantErr: "no current-context"},
+		{name: "unknown context", doc: "current-context: x\ncontexts: []\n", wantErr: `context "x" not found`},
+		{
+			name:    "no server",
+			doc:     "clusters:\n- name: c\n  cluster:\n    insecure-skip-tls-verify: true\ncontexts:\n- name: x\n  context:\n    cluster: c\ncurrent-context: x\n",
+			wantErr: "has no server",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config")
+			if err := os.WriteFile(path, []byte(tt.doc), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadKubeconfig(path, tt.context)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Server != "https://10.0.0.1:6443" || got.Auth != tt.wantAuth || (got.ClientCert != nil) != tt.wantCert {
+				t.Errorf("loadKubeconfig = %+v", got)
+			}
+		})
+	}
+}
+
+func TestKubeconfigPathDefault(t *testing.T) {
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("HOME", "/home/ops")
+	if got := KubeconfigPath(""); got != filepath.Join("/home/ops", ".kube", "config") {
+		t.Errorf("KubeconfigPath() = %q", got)
+	}
+	t.Setenv("HOME", "")
+	if got := KubeconfigPath(""); got != filepath.Join(".kube", "config") {
+		t.Errorf("KubeconfigPath() without a home = %q", got)
+	}
+	if got := KubeconfigPath("/etc/k3s.yaml"); got != "/etc/k3s.yaml" {
+		t.Errorf("KubeconfigPath(explicit) = %q", got)
+	}
+}
+
+func TestInlineOrFileErrors(t *testing.T) {
+	if _, err := inlineOrFile(map[string]any{"certificate-authority-data": "%%%"}, "certificate-authority-data", "certificate-authority", "/k"); err == nil || !strings.Contains(err.Error(), "invalid certificate-authority-data") {
+		t.Errorf("invalid base64 error = %v", err)
+	}
+	if _, err := inlineOrFile(map[string]any{"certificate-authority": "/missing/ca.crt"}, "certificate-authority-data", "certificate-authority", "/k"); err == nil || !strings.Contains(err.Error(), "failed to read certificate-authority") {
+		t.Errorf("missing file error = %v", err)
+	}
+}
+
+func TestStrategyNames(t *testing.T) {
+	for s, want := range map[Strategy]string{Kubectl{}: StrategyKubectl, TCP{}: StrategyTCP, TLS{}: StrategyTLS, Readyz{}: StrategyReadyz} {
+		if got := s.Name(); got != want {
+			t.Errorf("%T.Name() = %q, want %q", s, got, want)
+		}
+	}
+	if s, err := New(Config{}); err != nil || s.Name() != StrategyKubectl {
+		t.Errorf("New(default) = %v, %v", s, err)
+	}
+	if _, err := New(Config{Strategy: "icmp"}); err == nil {
+		t.Error("New(icmp) expected error")
+	}
+}
+
+func TestTargetAddrAndTLSConfig(t *testing.T) {
+	for server, want := range map[string]string{
+		"https://10.0.0.1:6443":     "10.0.0.1:6443",
+		"https://kube.example.com":  "kube.example.com:443",
+		"https://[2001:db8::1]":     "[2001:db8::1]:443",
+		"https://[2001:db8::1]:643": "[2001:db8::1]:643",
+	} {
+		if got, err := (Target{Server: server}).Addr(); err != nil || got != want {
+			t.Errorf("Addr(%s) = %q, %v; want %q", server, got, err, want)
+		}
+	}
+	for _, server := range []string{"", "10.0.0.1:6443", "://bad"} {
+		if _, err := (Target{Server: server}).Addr(); err == nil {
+			t.Errorf("Addr(%q) expected error", server)
+		}
+	}
+
+	if _, err := (Target{Server: "://bad"}).TLSConfig(); err == nil {
+		t.Error("TLSConfig(invalid URL) expected error")
+	}
+	if _, err := (Target{Server: "https://kube.example.com", CAData: []byte("not pem")}).TLSConfig(); err != ErrInvalidCA {
+		t.Errorf("TLSConfig(invalid CA) error = %v", err)
+	}
+	if _, err := (Target{Server: "https://kube.example.com", ClientCert: []byte("cert"), ClientKey: []byte("key")}).TLSConfig(); err == nil || !strings.Contains(err.Error(), "invalid client certificate") {
+		t.Errorf("TLSConfig(invalid client certificate) error = %v", err)
+	}
+	config, err := (Target{Server: "https://kube.example.com:6443", Insecure: true}).TLSConfig()
+	if err != nil || config.ServerName != "kube.example.com" || !config.InsecureSkipVerify || config.RootCAs != nil {
+		t.Errorf("TLSConfig() = %+v, %v", config, err)
+	}
+}
+
+func TestLoadKubeconfigCredentials(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	const cluster = "clusters:\n- name: c\n  cluster:\n    server: https://10.0.0.1:6443\ncontexts:\n- name: x\n  context:\n    cluster: c\n    user: u\ncurrent-context: x\n"
+	tests := []struct {
+		name, users         string
+		wantAuth, wantToken string
+		wantErr             string
+	}{
+		{name: "token file", users: "users:\n- name: u\n  user:\n    tokenFile: token\n", wantAuth: "bearer token", wantToken: "s3cr3t"},
+		{name: "missing token file", users: "users:\n- name: u\n  user:\n    tokenFile: missing\n", wantErr: "failed to read tokenFile"},
+		{name: "auth provider", users: "users:\n- name: u\n  user:\n    auth-provider:\n      name: oidc\n", wantAuth: "auth provider"},
+		{name: "basic auth", users: "users:\n- name: u\n  user:\n    username: admin\n", wantAuth: "basic auth"},
+		{name: "no user entry", users: "users: []\n"},
+		{name: "invalid client certificate", users: "users:\n- name: u\n  user:\n    client-certificate-data: '%%%'\n", wantErr: "invalid client-certificate-data"},
+		{name: "missing client key", users: "users:\n- name: u\n  user:\n    client-key: missing.key\n", wantErr: "failed to read client-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config")
+			if err := os.WriteFile(path, []byte(cluster+tt.users), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadKubeconfig(path, "")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got.Auth != tt.wantAuth || got.Token != tt.wantToken {
+				t.Errorf("LoadKubeconfig = %+v, %v", got, err)
+			}
+		})
+	}
+
+	for doc, want := range map[string]string{
+		"clusters: [\n": "failed to parse",
+		"contexts:\n- name: x\n  context:\n    cluster: c\ncurrent-context: x\n":                                                                                             "cluster \"c\" of context x not found",
+		"clusters:\n- name: c\n  cluster:\n    server: https://a\n    certificate-authority: ca.crt\ncontexts:\n- name: x\n  context:\n    cluster: c\ncurrent-context: x\n": "failed to read certificate-authority",
+	} {
+		path := filepath.Join(dir, "config")
+		if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadKubeconfig(path, ""); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadKubeconfig(%q) error = %v, want %q", doc, err, want)
+		}
+	}
+}
+
+func TestKubeconfigStrategyFailures(t *testing.T) {
+	dir := t.TempDir()
+	write := func(server, extra string) string {
+		path := filepath.Join(dir, "config")
+		doc := "clusters:\n- name: c\n  cluster:\n    server: " + server + "\n" + extra + "contexts:\n- name: x\n  context:\n    cluster: c\ncurrent-context: x\n"
+		if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	s, _ := New(Config{Strategy: StrategyTCP, Kubeconfig: write("10.0.0.1:6443", "")})
+	if _, err := s.Probe(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid server URL") {
+		t.Errorf("tcp probe of an invalid server = %v", err)
+	}
+	s, _ = New(Config{Strategy: StrategyTLS, Kubeconfig: write("https://127.0.0.1:1", "    certificate-authority-data: bm90IHBlbQ==\n")})
+	if _, err := s.Probe(context.Background()); err != ErrInvalidCA {
+		t.Errorf("tls probe with an invalid CA = %v", err)
+	}
+
+	srv, _ := apiServer(t)
+	if _, err := (TLS{Addr: strings.TrimPrefix(srv.URL, "https://"), Config: &tls.Config{ServerName: "kube.example.com"}, Timeout: time.Second}).Probe(context.Background()); err == nil {
+		t.Error("TLS probe of an untrusted server expected error")
+	}
+	readyz := Readyz{Server: srv.URL + "/missing", Config: &tls.Config{InsecureSkipVerify: true}, Timeout: time.Second}
+	if _, err := readyz.Probe(context.Background()); err == nil || !strings.Contains(err.Error(), "404 Not Found: 404 page not found") {
+		t.Errorf("readyz probe of a missing path = %v", err)
+	}
+	readyz.Server = "https://127.0.0.1:1"
+	if _, err := readyz.Probe(context.Background()); err == nil {
+		t.Error("readyz probe of a closed port expected error")
+	}
+	readyz.Server = "://bad"
+	if _, err := readyz.Probe(context.Background()); err == nil {
+		t.Error("readyz probe of an invalid URL expected error")
+	}
+}
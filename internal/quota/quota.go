@@ -0,0 +1,126 @@
+// Package quota renders ResourceQuota and LimitRange presets that cap what a
+// namespace can consume, so a runaway recipe workload cannot starve the other
+// workloads on a single node.
+package quota
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// QuotaName is the name of the ResourceQuota managed by netcup-kube
+	QuotaName = "netcup-kube-quota"
+
+	// LimitRangeName is the name of the LimitRange managed by netcup-kube
+	LimitRangeName = "netcup-kube-limits"
+
+	presetAnnotation = "netcup-kube/quota-preset"
+)
+
+// Resource is a named Kubernetes quantity, kept in manifest order
+type Resource struct {
+	Name  string
+	Value string
+}
+
+// Preset is a namespace budget
+type Preset struct {
+	Name        string
+	Description string
+	// Hard is the ResourceQuota; limits.* entries require every container
+	// to declare limits, which the LimitRange defaults provide
+	Hard []Resource
+	// DefaultRequest and Default are applied to containers that declare no
+	// requests or limits; Max caps a single container
+	DefaultRequest []Resource
+	Default        []Resource
+	Max            []Resource
+}
+
+// Presets are the built-in budgets by name
+var Presets = map[string]Preset{
+	"small": {
+		Name:        "small",
+		Description: "a single app: 1 CPU / 2Gi requested, 2 CPU / 4Gi limits, 20 pods, 20Gi of PVCs",
+		Hard: []Resource{
+			{"requests.cpu", "1"}, {"requests.memory", "2Gi"},
+			{"limits.cpu", "2"}, {"limits.memory", "4Gi"},
+			{"pods", "20"}, {"persistentvolumeclaims", "5"}, {"requests.storage", "20Gi"},
+		},
+		DefaultRequest: []Resource{{"cpu", "100m"}, {"memory", "128Mi"}},
+		Default:        []Resource{{"cpu", "500m"}, {"memory", "512Mi"}},
+		Max:            []Resource{{"cpu", "1"}, {"memory", "2Gi"}},
+	},
+	"medium": {
+		Name:        "medium",
+		Description: "a stack of services: 2 CPU / 4Gi requested, 4 CPU / 8Gi limits, 50 pods, 50Gi of PVCs",
+		Hard: []Resource{
+			{"requests.cpu", "2"}, {"requests.memory", "4Gi"},
+			{"limits.cpu", "4"}, {"limits.memory", "8Gi"},
+			{"pods", "50"}, {"persistentvolumeclaims", "10"}, {"requests.storage", "50Gi"},
+		},
+		DefaultRequest: []Resource{{"cpu", "250m"}, {"memory", "256Mi"}},
+		Default:        []Resource{{"cpu", "1"}, {"memory", "1Gi"}},
+		Max:            []Resource{{"cpu", "2"}, {"memory", "4Gi"}},
+	},
+}
+
+// PresetNames returns the preset names in sorted order
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the preset called name
+func Lookup(name string) (Preset, error) {
+	p, ok := Presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return p, nil
+}
+
+// Render returns the ResourceQuota and LimitRange of preset p for namespace
+func Render(namespace string, p Preset) string {
+	var b strings.Builder
+	writeHeader(&b, "ResourceQuota", QuotaName, namespace, p.Name)
+	b.WriteString("spec:\n")
+	b.WriteString("  hard:\n")
+	writeResources(&b, "    ", p.Hard)
+	b.WriteString("---\n")
+	writeHeader(&b, "LimitRange", LimitRangeName, namespace, p.Name)
+	b.WriteString("spec:\n")
+	b.WriteString("  limits:\n")
+	b.WriteString("    - type: Container\n")
+	b.WriteString("      defaultRequest:\n")
+	writeResources(&b, "        ", p.DefaultRequest)
+	b.WriteString("      default:\n")
+	writeResources(&b, "        ", p.Default)
+	b.WriteString("      max:\n")
+	writeResources(&b, "        ", p.Max)
+	return b.String()
+}
+
+func writeHeader(b *strings.Builder, kind, name, namespace, preset string) {
+	b.WriteString("apiVersion: v1\n")
+	fmt.Fprintf(b, "kind: %s\n", kind)
+	b.WriteString("metadata:\n")
+	fmt.Fprintf(b, "  name: %s\n", name)
+	fmt.Fprintf(b, "  namespace: %s\n", namespace)
+	b.WriteString("  labels:\n")
+	b.WriteString("    app.kubernetes.io/managed-by: netcup-kube\n")
+	b.WriteString("  annotations:\n")
+	fmt.Fprintf(b, "    %s: %s\n", presetAnnotation, preset)
+}
+
+func writeResources(b *strings.Builder, indent string, resources []Resource) {
+	for _, r := range resources {
+		fmt.Fprintf(b, "%s%s: %q\n", indent, r.Name, r.Value)
+	}
+}
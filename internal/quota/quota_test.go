@@ -0,0 +1,38 @@
+package quota
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	p, err := Lookup("small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := Render("openclaw", p)
+	for _, want := range []string{
+		"kind: ResourceQuota\nmetadata:\n  name: netcup-kube-quota\n  namespace: openclaw\n",
+		"    netcup-kube/quota-preset: small\n",
+		"  hard:\n    requests.cpu: \"1\"\n    requests.memory: \"2Gi\"\n",
+		"---\napiVersion: v1\nkind: LimitRange\n",
+		"    - type: Container\n      defaultRequest:\n        cpu: \"100m\"\n",
+		"      max:\n        cpu: \"1\"\n        memory: \"2Gi\"\n",
+	} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("manifest lacks %q:\n%s", want, manifest)
+		}
+	}
+}
+
+func TestPresetsAreConsistent(t *testing.T) {
+	for _, name := range PresetNames() {
+		p := Presets[name]
+		if p.Name != name || len(p.Hard) == 0 || len(p.Default) == 0 || len(p.DefaultRequest) == 0 || len(p.Max) == 0 {
+			t.Errorf("preset %s is incomplete: %+v", name, p)
+		}
+	}
+	if _, err := Lookup("huge"); err == nil || !strings.Contains(err.Error(), "medium, small") {
+		t.Errorf("Lookup() error = %v", err)
+	}
+}
@@ -0,0 +1,331 @@
+// Package registry resolves container image references against their
+// registry (Docker Registry HTTP API v2) and reads the credentials of
+// Kubernetes image pull secrets.
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DockerHub is the registry of image references without a registry host
+const DockerHub = "docker.io"
+
+// dockerHubEndpoint serves the registry API of DockerHub
+const dockerHubEndpoint = "registry-1.docker.io"
+
+// manifestAccept lists the manifest types a HEAD request accepts, indexes
+// first, so the digest is the one a node pulling the tag records
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+var (
+	// ErrNotFound is returned when the registry has no manifest for the tag
+	ErrNotFound = errors.New("manifest not found")
+	// ErrUnauthorized is returned when the registry rejects the credentials
+	// (or requires some)
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Reference is a parsed image reference
+type Reference struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+}
+
+// ParseReference parses an image reference the way container runtimes do:
+// no registry means DockerHub (with library/ for single-name images) and no
+// tag or digest means latest.
+func ParseReference(image string) (Reference, error) {
+	image = strings.TrimSpace(image)
+	if image == "" {
+		return Reference{}, fmt.Errorf("empty image reference")
+	}
+	var ref Reference
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, ref.Digest = name[:i], name[i+1:]
+		if !strings.HasPrefix(ref.Digest, "sha256:") {
+			return Reference{}, fmt.Errorf("invalid digest in image %q", image)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:i], name[i+1:]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	first, rest, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, ref.Repository = first, rest
+	} else {
+		ref.Registry, ref.Repository = DockerHub, name
+		if !found {
+			ref.Repository = "library/" + name
+		}
+	}
+	if ref.Repository == "" || strings.ToLower(ref.Repository) != ref.Repository {
+		return Reference{}, fmt.Errorf("invalid repository in image %q", image)
+	}
+	return ref, nil
+}
+
+// String returns the reference in its canonical form
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Credentials authenticate against a registry
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client resolves references against registries over HTTPS
+type Client struct {
+	HTTP *http.Client
+}
+
+// Resolve returns the digest the registry serves for the tag (or digest) of
+// ref. creds may be nil for anonymous access.
+func (c *Client) Resolve(ctx context.Context, ref Reference, creds *Credentials) (string, error) {
+	target := ref.Tag
+	if ref.Digest != "" {
+		target = ref.Digest
+	}
+	manifestURL := "https://" + endpoint(ref.Registry) + "/v2/" + ref.Repository + "/manifests/" + target
+
+	resp, err := c.head(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), ref, creds)
+		if err != nil {
+			return "", err
+		}
+		if resp, err = c.head(ctx, manifestURL, authorization); err != nil {
+			return "", err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		digest := resp.Header.Get("Docker-Content-Digest")
+		if digest == "" {
+			return "", fmt.Errorf("%s: the registry returned no Docker-Content-Digest", ref)
+		}
+		return digest, nil
+	case http.StatusNotFound:
+		return "", fmt.Errorf("%s: %w", ref, ErrNotFound)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "", fmt.Errorf("%s: %w", ref, ErrUnauthorized)
+	}
+	return "", fmt.Errorf("%s: unexpected response %s", ref, resp.Status)
+}
+
+// CheckCredentials authenticates creds for pulling ref without resolving a
+// manifest; registries without token auth are accepted as is
+func (c *Client) CheckCredentials(ctx context.Context, ref Reference, creds Credentials) error {
+	resp, err := c.head(ctx, "https://"+endpoint(ref.Registry)+"/v2/", "")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	authorization, err := c.authorize(ctx, challenge, ref, &creds)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(authorization, "Bearer ") {
+		return nil
+	}
+	// Basic auth is only checked by using it
+	if resp, err = c.head(ctx, "https://"+endpoint(ref.Registry)+"/v2/", authorization); err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+func (c *Client) head(ctx context.Context, rawURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", manifestAccept)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+// authorize answers a WWW-Authenticate challenge: a bearer token from the
+// realm (anonymous without creds), or basic auth
+func (c *Client) authorize(ctx context.Context, challenge string, ref Reference, creds *Credentials) (string, error) {
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if creds == nil {
+			return "", ErrUnauthorized
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds.Username+":"+creds.Password)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported registry authentication %q", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || realm.Scheme == "" {
+		return "", fmt.Errorf("invalid token realm %q", params["realm"])
+	}
+	query := realm.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", "repository:"+ref.Repository+":pull")
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if creds != nil {
+		req.SetBasicAuth(creds.Username, creds.Password)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", ErrUnauthorized
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request to %s failed: %s", realm.Host, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("failed to parse token from %s: %w", realm.Host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("no token from %s", realm.Host)
+	}
+	return "Bearer " + token.Token, nil
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+// parseChallenge splits `Bearer realm="...",service="..."`
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var pair string
+		rest = strings.TrimLeft(rest, ", ")
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				break
+			}
+			pair, rest = value[1:end+1], value[end+2:]
+		} else {
+			pair, rest, _ = strings.Cut(value, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = pair
+	}
+	return scheme, params
+}
+
+// endpoint maps a registry name to the host serving its API
+func endpoint(registry string) string {
+	if registry == DockerHub || registry == "index.docker.io" {
+		return dockerHubEndpoint
+	}
+	return registry
+}
+
+// DockerConfig is the content of a kubernetes.io/dockerconfigjson secret
+type DockerConfig struct {
+	Auths map[string]struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	} `json:"auths"`
+}
+
+// ParseDockerConfig parses the .dockerconfigjson of a pull secret
+func ParseDockerConfig(data []byte) (DockerConfig, error) {
+	var cfg DockerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid .dockerconfigjson: %w", err)
+	}
+	return cfg, nil
+}
+
+// Lookup returns the credentials for registry; keys may be host names or
+// URLs, and DockerHub is also known as https://index.docker.io/v1/
+func (d DockerConfig) Lookup(registry string) (Credentials, bool) {
+	for key, entry := range d.Auths {
+		host := key
+		if u, err := url.Parse(key); err == nil && u.Host != "" {
+			host = u.Host
+		}
+		if host != registry && !(registry == DockerHub && (host == "index.docker.io" || host == dockerHubEndpoint)) {
+			continue
+		}
+		creds := Credentials{Username: entry.Username, Password: entry.Password}
+		if entry.Auth != "" {
+			if decoded, err := base64.StdEncoding.DecodeString(entry.Auth); err == nil {
+				creds.Username, creds.Password, _ = strings.Cut(string(decoded), ":")
+			}
+		}
+		return creds, true
+	}
+	return Credentials{}, false
+}
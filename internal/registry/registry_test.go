@@ -0,0 +1,124 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	cases := []struct {
+		image string
+		want  Reference
+	}{
+		{"nginx", Reference{Registry: DockerHub, Repository: "library/nginx", Tag: "latest"}},
+		{"bitnami/redis:7.2", Reference{Registry: DockerHub, Repository: "bitnami/redis", Tag: "7.2"}},
+		{"ghcr.io/openclaw/openclaw:2026.3.1", Reference{Registry: "ghcr.io", Repository: "openclaw/openclaw", Tag: "2026.3.1"}},
+		{"localhost:5000/app@sha256:abc", Reference{Registry: "localhost:5000", Repository: "app", Digest: "sha256:abc"}},
+		{"ghcr.io/org/app:v1@sha256:abc", Reference{Registry: "ghcr.io", Repository: "org/app", Tag: "v1", Digest: "sha256:abc"}},
+	}
+	for _, c := range cases {
+		got, err := ParseReference(c.image)
+		if err != nil || got != c.want {
+			t.Errorf("ParseReference(%q) = %+v, %v, want %+v", c.image, got, err, c.want)
+		}
+	}
+	for _, image := range []string{"", "ghcr.io/Org/App:v1", "app@md5:abc"} {
+		if _, err := ParseReference(image); err == nil {
+			t.Errorf("ParseReference(%q) expected error", image)
+		}
+	}
+}
+
+func TestDockerConfigLookup(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("robot:s3cret:x"))
+	cfg, err := ParseDockerConfig([]byte(`{"auths":{
+		"https://index.docker.io/v1/":{"auth":"` + auth + `"},
+		"ghcr.io":{"username":"me","password":"pat"}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds, ok := cfg.Lookup(DockerHub); !ok || creds.Username != "robot" || creds.Password != "s3cret:x" {
+		t.Errorf("Lookup(docker.io) = %+v, %v", creds, ok)
+	}
+	if creds, ok := cfg.Lookup("ghcr.io"); !ok || creds.Username != "me" || creds.Password != "pat" {
+		t.Errorf("Lookup(ghcr.io) = %+v, %v", creds, ok)
+	}
+	if _, ok := cfg.Lookup("quay.io"); ok {
+		t.Error("Lookup(quay.io) expected no credentials")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.example/token",service="registry.example",scope="repository:a/b:pull"`)
+	if scheme != "Bearer" || params["realm"] != "https://auth.example/token" || params["service"] != "registry.example" || params["scope"] != "repository:a/b:pull" {
+		t.Errorf("parseChallenge() = %q, %v", scheme, params)
+	}
+}
+
+// newTokenRegistry serves org/app:v1 behind bearer token auth that accepts
+// user:pass only
+func newTokenRegistry(t *testing.T) *httptest.Server {
+	t.Helper()
+	var srv *httptest.Server
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:org/app:pull" {
+				t.Errorf("token scope = %q", r.URL.Query().Get("scope"))
+			}
+			_, _ = w.Write([]byte(`{"token":"t0k"}`))
+		case r.Header.Get("Authorization") != "Bearer t0k":
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/":
+		case r.URL.Path == "/v2/org/app/manifests/v1":
+			if !strings.Contains(r.Header.Get("Accept"), "image.index") {
+				t.Errorf("Accept = %q", r.Header.Get("Accept"))
+			}
+			w.Header().Set("Docker-Content-Digest", "sha256:1111")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestResolve(t *testing.T) {
+	srv := newTokenRegistry(t)
+	client := &Client{HTTP: srv.Client()}
+	host := strings.TrimPrefix(srv.URL, "https://")
+	creds := &Credentials{Username: "user", Password: "pass"}
+
+	ref, err := ParseReference(host + "/org/app:v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if digest, err := client.Resolve(context.Background(), ref, creds); err != nil || digest != "sha256:1111" {
+		t.Errorf("Resolve() = %q, %v", digest, err)
+	}
+	if _, err := client.Resolve(context.Background(), ref, nil); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Resolve() anonymous error = %v, want ErrUnauthorized", err)
+	}
+	ref.Tag = "v2"
+	if _, err := client.Resolve(context.Background(), ref, creds); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Resolve() missing tag error = %v, want ErrNotFound", err)
+	}
+
+	if err := client.CheckCredentials(context.Background(), ref, *creds); err != nil {
+		t.Errorf("CheckCredentials() error: %v", err)
+	}
+	if err := client.CheckCredentials(context.Background(), ref, Credentials{Username: "user", Password: "old"}); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("CheckCredentials() error = %v, want ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,269 @@
+// Package releases loads the release manifest of `netcup-claw upgrade`: the
+// Helm releases in the OpenClaw namespace, their charts, the recipes.conf
+// keys pinning their versions, and the releases each one depends on.
+//
+// Example:
+//
+//	releases:
+//	  - name: openclaw
+//	    repo: openclaw
+//	    url: https://serhanekicii.github.io/openclaw-helm
+//	    chart: openclaw
+//	    pin: CHART_VERSION_OPENCLAW
+//	  - name: openclaw-browser
+//	    repo: browserless
+//	    url: https://charts.example.com/browserless
+//	    chart: browserless-chrome
+//	    pin: CHART_VERSION_OPENCLAW_BROWSER
+//	    needs: [openclaw]
+package releases
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/yamlsubset"
+)
+
+// Release is a Helm release upgraded by netcup-claw
+type Release struct {
+	Name  string
+	Repo  string
+	URL   string
+	Chart string
+	// Pin is the recipes.conf key holding the chart version; empty means
+	// the version is not pinned
+	Pin   string
+	Needs []string
+}
+
+// ChartRef returns the <repo>/<chart> reference of the release's chart
+func (r Release) ChartRef() string {
+	return r.Repo + "/" + r.Chart
+}
+
+// Default is the manifest used when none exists: the OpenClaw release alone
+var Default = []Release{{
+	Name:  "openclaw",
+	Repo:  "openclaw",
+	URL:   "https://serhanekicii.github.io/openclaw-helm",
+	Chart: "openclaw",
+	Pin:   "CHART_VERSION_OPENCLAW",
+}}
+
+// Load reads the manifest at path; a missing file yields Default
+func Load(path string) ([]Release, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release manifest: %w", err)
+	}
+	rels, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release manifest %s: %w", path, err)
+	}
+	return rels, nil
+}
+
+// Parse parses and validates manifest YAML
+func Parse(data []byte) ([]Release, error) {
+	raw, err := yamlsubset.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	root, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("top level must be a mapping")
+	}
+	for key := range root {
+		if key != "releases" {
+			return nil, fmt.Errorf("unknown top-level key %q (expected releases)", key)
+		}
+	}
+	items, ok := root["releases"].([]any)
+	if !ok {
+		return nil, fmt.Errorf("releases must be a list")
+	}
+
+	rels := make([]Release, 0, len(items))
+	for i, item := range items {
+		entry, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("releases[%d] must be a mapping", i)
+		}
+		rel, err := decodeRelease(i, entry)
+		if err != nil {
+			return nil, err
+		}
+		rels = append(rels, rel)
+	}
+	if err := Validate(rels); err != nil {
+		return nil, err
+	}
+	return rels, nil
+}
+
+func decodeRelease(i int, entry map[string]any) (Release, error) {
+	var rel Release
+	// Iterate in sorted order so error messages are deterministic
+	keys := make([]string, 0, len(entry))
+	for key := range entry {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fieldPath := fmt.Sprintf("releases[%d].%s", i, key)
+		if key == "needs" {
+			needs, err := decodeNeeds(fieldPath, entry[key])
+			if err != nil {
+				return rel, err
+			}
+			rel.Needs = needs
+			continue
+		}
+		s, ok := entry[key].(string)
+		if !ok {
+			return rel, fmt.Errorf("%s must be a string", fieldPath)
+		}
+		s = strings.TrimSpace(s)
+		switch key {
+		case "name":
+			rel.Name = s
+		case "repo":
+			rel.Repo = s
+		case "url":
+			rel.URL = s
+		case "chart":
+			rel.Chart = s
+		case "pin":
+			rel.Pin = s
+		default:
+			return rel, fmt.Errorf("unknown key %s (expected name, repo, url, chart, pin, needs)", fieldPath)
+		}
+	}
+	if rel.Chart == "" {
+		rel.Chart = rel.Name
+	}
+	return rel, nil
+}
+
+func decodeNeeds(field string, value any) ([]string, error) {
+	if s, ok := value.(string); ok {
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		return []string{s}, nil
+	}
+	items, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a list of release names", field)
+	}
+	needs := make([]string, 0, len(items))
+	for j, item := range items {
+		s, ok := item.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("%s[%d] must be a release name", field, j)
+		}
+		needs = append(needs, strings.TrimSpace(s))
+	}
+	return needs, nil
+}
+
+// Validate checks that every release is complete, names are unique, and
+// dependencies refer to releases of the manifest
+func Validate(rels []Release) error {
+	if len(rels) == 0 {
+		return fmt.Errorf("at least one release is required")
+	}
+	seen := map[string]bool{}
+	for _, rel := range rels {
+		if rel.Name == "" {
+			return fmt.Errorf("release with chart %q is missing a name", rel.Chart)
+		}
+		if seen[rel.Name] {
+			return fmt.Errorf("duplicate release name %q", rel.Name)
+		}
+		seen[rel.Name] = true
+		if rel.Repo == "" || rel.URL == "" {
+			return fmt.Errorf("release %q needs a repo and url", rel.Name)
+		}
+	}
+	for _, rel := range rels {
+		for _, dep := range rel.Needs {
+			if !seen[dep] {
+				return fmt.Errorf("release %q needs unknown release %q", rel.Name, dep)
+			}
+		}
+	}
+	_, err := Order(rels)
+	return err
+}
+
+// Order returns the releases in dependency order: every release after the
+// releases it needs, otherwise in manifest order
+func Order(rels []Release) ([]Release, error) {
+	done := map[string]bool{}
+	ordered := make([]Release, 0, len(rels))
+	for len(ordered) < len(rels) {
+		progressed := false
+		for _, rel := range rels {
+			if done[rel.Name] || !ready(rel, done) {
+				continue
+			}
+			done[rel.Name] = true
+			ordered = append(ordered, rel)
+			progressed = true
+		}
+		if !progressed {
+			var cycle []string
+			for _, rel := range rels {
+				if !done[rel.Name] {
+					cycle = append(cycle, rel.Name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle between releases %s", strings.Join(cycle, ", "))
+		}
+	}
+	return ordered, nil
+}
+
+func ready(rel Release, done map[string]bool) bool {
+	for _, dep := range rel.Needs {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the named releases (all when names is empty), keeping the
+// order of rels
+func Select(rels []Release, names []string) ([]Release, error) {
+	if len(names) == 0 {
+		return rels, nil
+	}
+	want := map[string]bool{}
+	for _, name := range names {
+		want[name] = true
+	}
+	var selected []Release
+	available := make([]string, 0, len(rels))
+	for _, rel := range rels {
+		available = append(available, rel.Name)
+		if want[rel.Name] {
+			selected = append(selected, rel)
+			delete(want, rel.Name)
+		}
+	}
+	for _, name := range names {
+		if want[name] {
+			return nil, fmt.Errorf("release %q not found in manifest (available: %s)", name, strings.Join(available, ", "))
+		}
+	}
+	return selected, nil
+}
@@ -0,0 +1,132 @@
+package releases
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const manifest = `# upgraded by netcup-claw upgrade
+releases:
+  - name: openclaw-browser
+    repo: browserless
+    url: https://charts.example.com/browserless
+    chart: browserless-chrome
+    needs: [openclaw]
+  - name: openclaw
+    repo: openclaw
+    url: https://serhanekicii.github.io/openclaw-helm
+    pin: CHART_VERSION_OPENCLAW
+  - name: openclaw-proxy
+    repo: openclaw
+    url: https://serhanekicii.github.io/openclaw-helm
+    needs: openclaw-browser
+`
+
+func TestParseAndOrder(t *testing.T) {
+	rels, err := Parse([]byte(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rels[1].Chart != "openclaw" || rels[1].ChartRef() != "openclaw/openclaw" || rels[0].Pin != "" {
+		t.Errorf("releases = %+v", rels)
+	}
+
+	ordered, err := Order(rels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, rel := range ordered {
+		names = append(names, rel.Name)
+	}
+	if got := strings.Join(names, ","); got != "openclaw,openclaw-browser,openclaw-proxy" {
+		t.Errorf("Order() = %s", got)
+	}
+
+	selected, err := Select(ordered, []string{"openclaw-proxy", "openclaw"})
+	if err != nil || len(selected) != 2 || selected[0].Name != "openclaw" {
+		t.Errorf("Select() = %+v, %v", selected, err)
+	}
+	if _, err := Select(ordered, []string{"redis"}); err == nil || !strings.Contains(err.Error(), "available: openclaw,") {
+		t.Errorf("Select(redis) error = %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"empty", "releases: []\n", "at least one release"},
+		{"unknown key", "releases:\n  - name: a\n    repo: r\n    url: u\n    version: 1\n", "unknown key releases[0].version"},
+		{"missing repo", "releases:\n  - name: a\n", `release "a" needs a repo and url`},
+		{"unknown dependency", "releases:\n  - name: a\n    repo: r\n    url: u\n    needs: [b]\n", `needs unknown release "b"`},
+		{"cycle", "releases:\n  - name: a\n    repo: r\n    url: u\n    needs: [b]\n  - name: b\n    repo: r\n    url: u\n    needs: [a]\n", "dependency cycle between releases a, b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadDefault(t *testing.T) {
+	rels, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil || len(rels) != 1 || rels[0].Name != "openclaw" {
+		t.Fatalf("Load(missing) = %+v, %v", rels, err)
+	}
+
+	// The manifest shipped with the recipe must stay valid
+	data, err := os.ReadFile(filepath.Join("..", "..", "scripts", "recipes", "openclaw", "releases.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse(data); err != nil {
+		t.Errorf("releases.yaml: %v", err)
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.4.4", "1.4.4", 0},
+		{"v1.4.4", "1.4.4+build.7", 0},
+		{"1.4", "1.4.0", 0},
+		{"1.4.10", "1.4.9", 1},
+		{"1.5.0-beta.1", "1.4.9", 1},
+		{"1.5.0-beta.1", "1.5.0", -1},
+		{"1.5.0-beta.2", "1.5.0-beta.10", -1},
+		{"1.5.0-alpha", "1.5.0-beta", -1},
+		{"1.5.0-beta", "1.5.0-beta.1", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := CompareVersions(tt.b, tt.a); got != -tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
+
+func TestChannels(t *testing.T) {
+	if ChannelKey("CHART_VERSION_OPENCLAW") != "CHART_CHANNEL_OPENCLAW" || ChannelKey("") != "" {
+		t.Error("ChannelKey() mismatch")
+	}
+	if err := ValidateChannel("beta"); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateChannel("nightly"); err == nil {
+		t.Error("ValidateChannel(nightly) expected error")
+	}
+	if !IsPrerelease("1.5.0-rc.1+build") || IsPrerelease("1.5.0+build-7") {
+		t.Error("IsPrerelease() mismatch")
+	}
+}
@@ -0,0 +1,112 @@
+package releases
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Release channels of `netcup-claw upgrade`: stable only considers final
+// chart versions, beta includes pre-releases (helm search --devel)
+const (
+	ChannelStable = "stable"
+	ChannelBeta   = "beta"
+)
+
+// Channels lists the release channels from the most to the least conservative
+var Channels = []string{ChannelStable, ChannelBeta}
+
+// ValidateChannel checks that channel is a known release channel
+func ValidateChannel(channel string) error {
+	for _, c := range Channels {
+		if channel == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown channel %q (expected %s)", channel, strings.Join(Channels, " or "))
+}
+
+// ChannelKey returns the recipes.conf key tracking the channel of a release
+// pinned under pin (CHART_VERSION_X -> CHART_CHANNEL_X), or "" for releases
+// without a CHART_VERSION_ pin
+func ChannelKey(pin string) string {
+	if name, ok := strings.CutPrefix(pin, "CHART_VERSION_"); ok && name != "" {
+		return "CHART_CHANNEL_" + name
+	}
+	return ""
+}
+
+// IsPrerelease reports whether a semantic version has a pre-release part,
+// e.g. 1.5.0-beta.1
+func IsPrerelease(version string) bool {
+	core, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), "+")
+	return strings.Contains(core, "-")
+}
+
+// CompareVersions compares two semantic versions (an optional leading "v" and
+// build metadata are ignored) and returns -1, 0, or 1. A pre-release sorts
+// before its release; pre-release identifiers compare numerically when both
+// are numbers and lexically otherwise. Unparsable parts compare as strings.
+func CompareVersions(a, b string) int {
+	aCore, aPre := splitVersion(a)
+	bCore, bPre := splitVersion(b)
+	if c := compareIdentifiers(strings.Split(aCore, "."), strings.Split(bCore, "."), true); c != 0 {
+		return c
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	return compareIdentifiers(strings.Split(aPre, "."), strings.Split(bPre, "."), false)
+}
+
+func splitVersion(v string) (core, pre string) {
+	v, _, _ = strings.Cut(strings.TrimPrefix(strings.TrimSpace(v), "v"), "+")
+	core, pre, _ = strings.Cut(v, "-")
+	return core, pre
+}
+
+// compareIdentifiers compares dot-separated identifiers; with padZero missing
+// trailing identifiers count as 0 (1.2 == 1.2.0), otherwise the shorter list
+// sorts first
+func compareIdentifiers(a, b []string, padZero bool) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if !padZero && (i >= len(a) || i >= len(b)) {
+			if i >= len(a) {
+				return -1
+			}
+			return 1
+		}
+		x, y := "0", "0"
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		xn, xErr := strconv.Atoi(x)
+		yn, yErr := strconv.Atoi(y)
+		switch {
+		case xErr == nil && yErr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case xErr == nil:
+			return -1 // numeric identifiers sort before alphanumeric ones
+		case yErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RemoteBinaries lists the commands under cmd/ that are built and uploaded to
+// the remote bin directory. The first entry is the primary CLI.
+var RemoteBinaries = []string{"netcup-kube", "netcup-claw"}
+
+// BinManifestName is the manifest file written next to the remote binaries
+const BinManifestName = "manifest.json"
+
+// BinManifest records which binaries were uploaded and from which source version
+type BinManifest struct {
+	Version  string     `json:"version"`
+	GOARCH   string     `json:"goarch"`
+	BuiltAt  string     `json:"built_at"`
+	Binaries []BinEntry `json:"binaries"`
+}
+
+// BinEntry is a single binary in the manifest
+type BinEntry struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+}
+
+// BinStatus describes an installed binary as found on the remote host
+type BinStatus struct {
+	Name    string
+	Version string
+	GOARCH  string
+	SHA256  string
+	// State is "ok", "modified" (checksum differs from the manifest),
+	// "missing", or "unmanaged" (present but not in the manifest)
+	State string
+}
+
+// uploadBinManifest writes the manifest locally and uploads it to remoteBinDir
+func uploadBinManifest(client Client, tmpDir, remoteBinDir string, manifest BinManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	local := filepath.Join(tmpDir, BinManifestName)
+	if err := os.WriteFile(local, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := client.Upload(local, path.Join(remoteBinDir, BinManifestName)); err != nil {
+		return fmt.Errorf("manifest upload failed: %w", err)
+	}
+	return nil
+}
+
+// ListRemoteBinaries reports the binaries installed in the remote bin directory,
+// checking each against the uploaded manifest.
+func ListRemoteBinaries(client Client, cfg *Config) ([]BinStatus, error) {
+	binDir := cfg.GetRemoteBinDir()
+
+	var manifest BinManifest
+	if out, err := client.OutputCommand("cat", []string{path.Join(binDir, BinManifestName)}); err == nil {
+		if err := json.Unmarshal(out, &manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse remote manifest: %w", err)
+		}
+	}
+
+	// The glob is expanded by the remote shell. sha256sum prints "<sum>  <path>"
+	// for each file it can read and fails when there are none, so errors only
+	// mean an empty listing.
+	installed := map[string]string{}
+	out, _ := client.OutputCommand("sha256sum", []string{binDir + "/*"})
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		name := path.Base(fields[1])
+		if name == BinManifestName || strings.HasPrefix(name, ".") {
+			continue
+		}
+		installed[name] = fields[0]
+	}
+
+	var statuses []BinStatus
+	for _, entry := range manifest.Binaries {
+		st := BinStatus{Name: entry.Name, Version: manifest.Version, GOARCH: manifest.GOARCH, SHA256: entry.SHA256, State: "ok"}
+		sum, ok := installed[entry.Name]
+		switch {
+		case !ok:
+			st.State = "missing"
+		case sum != entry.SHA256:
+			st.State = "modified"
+			st.SHA256 = sum
+		}
+		delete(installed, entry.Name)
+		statuses = append(statuses, st)
+	}
+	var unmanaged []string
+	for name := range installed {
+		unmanaged = append(unmanaged, name)
+	}
+	sort.Strings(unmanaged)
+	for _, name := range unmanaged {
+		statuses = append(statuses, BinStatus{Name: name, Version: "-", GOARCH: "-", SHA256: installed[name], State: "unmanaged"})
+	}
+	return statuses, nil
+}
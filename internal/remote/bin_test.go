@@ -0,0 +1,68 @@
+package remote
+
+import (
+	"testing"
+)
+
+func TestListRemoteBinaries_States(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Host = "example.com"
+	cfg.User = "ops"
+	binDir := cfg.GetRemoteBinDir()
+
+	fc := &fakeClient{output: map[string][]byte{
+		"cat " + binDir + "/manifest.json": []byte(`{
+  "version": "v1.2.0-3-gabc123",
+  "goarch": "amd64",
+  "built_at": "2026-01-01T00:00:00Z",
+  "binaries": [
+    {"name": "netcup-kube", "sha256": "aaa"},
+    {"name": "netcup-claw", "sha256": "bbb"},
+    {"name": "netcup-extra", "sha256": "ccc"}
+  ]
+}`),
+		"sha256sum " + binDir + "/*": []byte("aaa  " + binDir + "/netcup-kube\n" +
+			"zzz  " + binDir + "/netcup-claw\n" +
+			"ddd  " + binDir + "/manifest.json\n" +
+			"eee  " + binDir + "/helper\n"),
+	}}
+
+	bins, err := ListRemoteBinaries(fc, cfg)
+	if err != nil {
+		t.Fatalf("ListRemoteBinaries error: %v", err)
+	}
+	want := map[string]string{
+		"netcup-kube":  "ok",
+		"netcup-claw":  "modified",
+		"netcup-extra": "missing",
+		"helper":       "unmanaged",
+	}
+	if len(bins) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(bins), len(want), bins)
+	}
+	for _, b := range bins {
+		if want[b.Name] != b.State {
+			t.Errorf("%s: state %q, want %q", b.Name, b.State, want[b.Name])
+		}
+	}
+	if bins[0].Version != "v1.2.0-3-gabc123" || bins[0].GOARCH != "amd64" {
+		t.Errorf("unexpected manifest fields: %+v", bins[0])
+	}
+}
+
+func TestListRemoteBinaries_NoManifest(t *testing.T) {
+	cfg := NewConfig()
+	cfg.User = "ops"
+	bins, err := ListRemoteBinaries(&fakeClient{}, cfg)
+	if err != nil {
+		t.Fatalf("ListRemoteBinaries error: %v", err)
+	}
+	if len(bins) != 0 {
+		t.Fatalf("expected no binaries, got %+v", bins)
+	}
+
+	fc := &fakeClient{output: map[string][]byte{"cat " + cfg.GetRemoteBinDir() + "/manifest.json": []byte("{")}}
+	if _, err := ListRemoteBinaries(fc, cfg); err == nil {
+		t.Fatalf("expected error for corrupt manifest")
+	}
+}
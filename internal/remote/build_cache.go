@@ -0,0 +1,99 @@
+package remote
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildCacheKey returns the cache key for a build of projectRoot for goarch,
+// or "" when the build cannot be cached (not a git checkout, or uncommitted
+// changes to tracked files).
+func buildCacheKey(projectRoot, goarch string) string {
+	commit, err := localGitHead(projectRoot)
+	if err != nil || commit == "" {
+		return ""
+	}
+	return commit + "-" + goarch
+}
+
+// cachedBinaryPath returns the cache location for a binary built under key
+func cachedBinaryPath(key, name string) (string, error) {
+	dir, err := buildCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, key, name), nil
+}
+
+// storeCachedBinary copies a freshly built binary into the cache. Failures are
+// reported but never fail the build.
+func storeCachedBinary(src, dst string) {
+	if err := copyFile(src, dst, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "[local] WARNING: failed to cache build: %v\n", err)
+	}
+}
+
+// copyFile copies src to dst via a temp file in the destination directory so a
+// partially written binary is never left at dst.
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 of a local file
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// remoteSHA256 returns the SHA-256 of a remote file, or "" when it does not
+// exist or cannot be hashed.
+func remoteSHA256(client Client, path string) string {
+	out, err := client.OutputCommand("sha256sum", []string{path})
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
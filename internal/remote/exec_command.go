@@ -4,6 +4,10 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
 )
 
 // Injection points for unit tests. Centralized here so dependencies like `execCommand`
@@ -14,8 +18,9 @@ var (
 	mkdirTemp   = os.MkdirTemp
 	removeAll   = os.RemoveAll
 
+	// localGoBuild cross-compiles ./cmd/<name> to out, where name is the base name of out
 	localGoBuild = func(projectRoot, out, goarch string) error {
-		cmd := execCommand("go", "build", "-o", out, "./cmd/netcup-kube")
+		cmd := execCommand("go", "build", "-o", out, "./cmd/"+filepath.Base(out))
 		cmd.Dir = projectRoot
 		cmd.Env = append(os.Environ(),
 			"CGO_ENABLED=0",
@@ -26,4 +31,34 @@ var (
 		cmd.Stderr = os.Stderr
 		return cmd.Run()
 	}
+
+	// localGitHead returns the HEAD commit of projectRoot, or "" when tracked
+	// files have uncommitted changes (the build would not match the commit).
+	localGitHead = func(projectRoot string) (string, error) {
+		out, err := execCommand("git", "-C", projectRoot, "rev-parse", "HEAD").Output()
+		if err != nil {
+			return "", err
+		}
+		status, err := execCommand("git", "-C", projectRoot, "status", "--porcelain", "--untracked-files=no").Output()
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(string(status)) != "" {
+			return "", nil
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	// localVersion describes the local checkout for the binary manifest
+	localVersion = func(projectRoot string) string {
+		out, err := execCommand("git", "-C", projectRoot, "describe", "--tags", "--always", "--dirty").Output()
+		if err != nil || strings.TrimSpace(string(out)) == "" {
+			return "unknown"
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	buildCacheDir = func() (string, error) {
+		return filepath.Join(paths.CacheDir("netcup-kube"), "build"), nil
+	}
 )
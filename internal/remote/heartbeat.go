@@ -0,0 +1,121 @@
+package remote
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// IdleOptions configures output monitoring for long-running remote commands
+type IdleOptions struct {
+	// Heartbeat prints a progress line after this much silence, repeating at
+	// the same interval (0 disables)
+	Heartbeat time.Duration
+	// Timeout warns once no output has been received for this long (0 disables)
+	Timeout time.Duration
+	// Abort terminates the command when Timeout is reached instead of warning
+	Abort bool
+}
+
+func (o IdleOptions) enabled() bool {
+	return o.Heartbeat > 0 || o.Timeout > 0
+}
+
+// idleTick is how often the idle watcher checks for output (overridden in tests)
+var idleTick = time.Second
+
+// activityWriter records the time of the last write before passing it on
+type activityWriter struct {
+	w    io.Writer
+	last *atomic.Int64
+}
+
+func (a activityWriter) Write(p []byte) (int, error) {
+	a.last.Store(time.Now().UnixNano())
+	return a.w.Write(p)
+}
+
+// runWithIdleWatch runs cmd, printing heartbeats and idle warnings to msgs
+// while its stdout/stderr stay silent. forceTTY selects the hint shown when
+// the idle timeout is reached.
+func runWithIdleWatch(cmd *exec.Cmd, opts IdleOptions, forceTTY bool, msgs io.Writer) error {
+	var last atomic.Int64
+	last.Store(time.Now().UnixNano())
+	stdout, stderr := cmd.Stdout, cmd.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	cmd.Stdout = activityWriter{w: stdout, last: &last}
+	cmd.Stderr = activityWriter{w: stderr, last: &last}
+	if cmd.WaitDelay == 0 {
+		// Don't hang on output pipes held open by orphaned children after a kill
+		cmd.WaitDelay = 2 * time.Second
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	ticker := time.NewTicker(idleTick)
+	defer ticker.Stop()
+
+	var (
+		seen      = last.Load()
+		nextBeat  = opts.Heartbeat
+		warned    bool
+		abortedAt time.Duration
+	)
+	for {
+		select {
+		case err := <-done:
+			if abortedAt > 0 {
+				return fmt.Errorf("aborted after %s without output", abortedAt)
+			}
+			return err
+		case <-ticker.C:
+			if abortedAt > 0 {
+				continue
+			}
+			if l := last.Load(); l != seen {
+				seen, nextBeat, warned = l, opts.Heartbeat, false
+			}
+			idle := time.Since(time.Unix(0, seen))
+
+			if opts.Timeout > 0 && idle >= opts.Timeout && !warned {
+				warned = true
+				_, _ = fmt.Fprintf(msgs, "\r\n[local] WARNING: no output for %s; the remote command may be hung (e.g. apt waiting for a lock) or waiting for input.\r\n", roundIdle(idle))
+				if forceTTY {
+					_, _ = fmt.Fprint(msgs, "[local] If a prompt is waiting, answer it here. For unattended runs, use --no-tty with a complete --env-file.\r\n")
+				} else {
+					_, _ = fmt.Fprint(msgs, "[local] Prompts cannot be answered with --no-tty; re-run without it, or set the missing values in --env-file.\r\n")
+				}
+				if opts.Abort {
+					_, _ = fmt.Fprint(msgs, "[local] Aborting (--abort-on-idle).\r\n")
+					abortedAt = roundIdle(idle)
+					_ = cmd.Process.Kill()
+				}
+				continue
+			}
+			if opts.Heartbeat > 0 && idle >= nextBeat {
+				_, _ = fmt.Fprintf(msgs, "\r\n[local] still running... no output for %s\r\n", roundIdle(idle))
+				nextBeat += opts.Heartbeat
+			}
+		}
+	}
+}
+
+// roundIdle rounds idle durations for display
+func roundIdle(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(time.Second)
+	}
+	return d.Round(time.Millisecond)
+}
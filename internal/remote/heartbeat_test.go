@@ -0,0 +1,72 @@
+package remote
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func withIdleTick(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := idleTick
+	idleTick = d
+	t.Cleanup(func() { idleTick = old })
+}
+
+func TestRunWithIdleWatch_AbortsWhenSilent(t *testing.T) {
+	withIdleTick(t, 5*time.Millisecond)
+
+	var out, msgs bytes.Buffer
+	cmd := exec.Command("sh", "-c", "exec sleep 5")
+	cmd.Stdout = &out
+	start := time.Now()
+	err := runWithIdleWatch(cmd, IdleOptions{Timeout: 50 * time.Millisecond, Abort: true}, false, &msgs)
+	if err == nil || !strings.Contains(err.Error(), "aborted") {
+		t.Fatalf("expected abort error, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("command was not killed promptly")
+	}
+	if !strings.Contains(msgs.String(), "--no-tty") {
+		t.Fatalf("expected --no-tty hint, got %q", msgs.String())
+	}
+}
+
+func TestRunWithIdleWatch_HeartbeatAndWarn(t *testing.T) {
+	withIdleTick(t, 5*time.Millisecond)
+
+	var out, msgs bytes.Buffer
+	cmd := exec.Command("sh", "-c", "echo start; sleep 0.3; echo end")
+	cmd.Stdout = &out
+	opts := IdleOptions{Heartbeat: 50 * time.Millisecond, Timeout: 150 * time.Millisecond}
+	if err := runWithIdleWatch(cmd, opts, true, &msgs); err != nil {
+		t.Fatalf("runWithIdleWatch error: %v", err)
+	}
+	if out.String() != "start\nend\n" {
+		t.Fatalf("command output not passed through: %q", out.String())
+	}
+	if !strings.Contains(msgs.String(), "still running") {
+		t.Fatalf("expected heartbeat, got %q", msgs.String())
+	}
+	if strings.Count(msgs.String(), "WARNING") != 1 {
+		t.Fatalf("expected exactly one idle warning, got %q", msgs.String())
+	}
+	if !strings.Contains(msgs.String(), "answer it here") {
+		t.Fatalf("expected TTY hint, got %q", msgs.String())
+	}
+}
+
+func TestRunWithIdleWatch_PassesExitError(t *testing.T) {
+	withIdleTick(t, 5*time.Millisecond)
+
+	var msgs bytes.Buffer
+	cmd := exec.Command("sh", "-c", "exit 3")
+	if err := runWithIdleWatch(cmd, IdleOptions{Heartbeat: time.Second}, false, &msgs); err == nil {
+		t.Fatalf("expected exit error")
+	}
+	if msgs.Len() != 0 {
+		t.Fatalf("expected no messages, got %q", msgs.String())
+	}
+}
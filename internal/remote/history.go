@@ -0,0 +1,174 @@
+package remote
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
+)
+
+// HistoryFileName is the remote run history in the netcup-kube state directory
+const HistoryFileName = "remote-history.jsonl"
+
+// HistoryEntry is one recorded `remote run` or `remote install`. The env file
+// is recorded by path and SHA-256 only; its values never enter the history.
+type HistoryEntry struct {
+	ID         int       `json:"id"`
+	Time       time.Time `json:"time"`
+	Host       string    `json:"host"`
+	User       string    `json:"user"`
+	Args       []string  `json:"args"`
+	EnvFile    string    `json:"env_file,omitempty"`
+	EnvSHA256  string    `json:"env_sha256,omitempty"`
+	Branch     string    `json:"branch,omitempty"`
+	Ref        string    `json:"ref,omitempty"`
+	Pull       bool      `json:"pull,omitempty"`
+	Commit     string    `json:"commit,omitempty"`
+	NoTTY      bool      `json:"no_tty,omitempty"`
+	Resilient  bool      `json:"resilient,omitempty"`
+	ReplayOf   int       `json:"replay_of,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Result returns "success" or "failure"
+func (e HistoryEntry) Result() string {
+	if e.Success {
+		return "success"
+	}
+	return "failure"
+}
+
+// GitRef describes the code the entry ran: the commit when known, otherwise
+// the requested ref or branch
+func (e HistoryEntry) GitRef() string {
+	switch {
+	case e.Commit != "" && e.Branch != "":
+		return e.Branch + "@" + shortCommit(e.Commit)
+	case e.Commit != "":
+		return shortCommit(e.Commit)
+	case e.Ref != "":
+		return e.Ref
+	}
+	return e.Branch
+}
+
+// HistoryPath returns the history file of netcup-kube
+func HistoryPath() string {
+	return filepath.Join(paths.StateDir("netcup-kube"), HistoryFileName)
+}
+
+// FileSHA256 returns the hex SHA-256 of a local file
+func FileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RemoteCommit returns the commit the remote repo is checked out at
+func RemoteCommit(client Client, cfg *Config) (string, error) {
+	out, err := client.OutputCommand("git", []string{"-C", cfg.GetRemoteRepoDir(), "rev-parse", "HEAD"})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the remote commit: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// AppendHistory assigns the next ID to e and appends it to path
+func AppendHistory(path string, e HistoryEntry) (HistoryEntry, error) {
+	entries, err := LoadHistory(path)
+	if err != nil {
+		return e, err
+	}
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+	if err := paths.EnsurePrivateDir(filepath.Dir(path)); err != nil {
+		return e, err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return e, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return e, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return e, f.Close()
+}
+
+// LoadHistory reads the history, oldest first, skipping lines that cannot be
+// parsed
+func LoadHistory(path string) ([]HistoryEntry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.ID == 0 {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// FindHistory returns the entry with id
+func FindHistory(entries []HistoryEntry, id int) (HistoryEntry, error) {
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return HistoryEntry{}, fmt.Errorf("no remote run #%d in the history (see: netcup-kube remote history)", id)
+}
+
+// ReplayOptions returns the run options that repeat e: the same arguments and
+// env file, pinned to the recorded commit when known, otherwise to the
+// recorded ref or branch
+func (e HistoryEntry) ReplayOptions() RunOptions {
+	git := GitOptions{Branch: e.Branch, Ref: e.Ref, Pull: e.Pull, PullIsSet: true}
+	if e.Commit != "" {
+		git = GitOptions{Ref: e.Commit, PullIsSet: true}
+	}
+	return RunOptions{
+		Git:       git,
+		ForceTTY:  !e.NoTTY,
+		EnvFile:   e.EnvFile,
+		Args:      append([]string(nil), e.Args...),
+		Resilient: e.Resilient,
+	}
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 12 {
+		return commit[:12]
+	}
+	return commit
+}
@@ -0,0 +1,138 @@
+package remote
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", HistoryFileName)
+	entries, err := LoadHistory(path)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("LoadHistory(missing) = %v, %v", entries, err)
+	}
+
+	first, err := AppendHistory(path, HistoryEntry{Time: time.Now(), Host: "mgmt", User: "ops", Args: []string{"bootstrap"}, Success: true})
+	if err != nil {
+		t.Fatalf("AppendHistory() error: %v", err)
+	}
+	second, err := AppendHistory(path, HistoryEntry{Time: time.Now(), Host: "mgmt", User: "ops", Args: []string{"dns", "--type", "edge-http"}, Branch: "main"})
+	if err != nil {
+		t.Fatalf("AppendHistory() error: %v", err)
+	}
+	if first.ID != 1 || second.ID != 2 {
+		t.Errorf("IDs = %d, %d; want 1, 2", first.ID, second.ID)
+	}
+
+	// A line cut off by a crash is skipped
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"id":3,"host":`)
+	_ = f.Close()
+
+	entries, err = LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	if len(entries) != 2 || !reflect.DeepEqual(entries[1].Args, second.Args) {
+		t.Fatalf("LoadHistory() = %+v", entries)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("history file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	if _, err := FindHistory(entries, 2); err != nil {
+		t.Errorf("FindHistory(2) error: %v", err)
+	}
+	if _, err := FindHistory(entries, 7); err == nil {
+		t.Error("FindHistory(7) expected error")
+	}
+}
+
+func TestHistoryReplayOptions(t *testing.T) {
+	e := HistoryEntry{Args: []string{"install", "redis"}, EnvFile: "/tmp/prod.env", Branch: "main", Pull: true, NoTTY: true}
+	opts := e.ReplayOptions()
+	if opts.Git.Branch != "main" || !opts.Git.Pull || opts.ForceTTY || opts.EnvFile != "/tmp/prod.env" {
+		t.Errorf("ReplayOptions() without commit = %+v", opts)
+	}
+	if e.GitRef() != "main" {
+		t.Errorf("GitRef() = %q, want main", e.GitRef())
+	}
+
+	e.Commit = "0123456789abcdef0123"
+	opts = e.ReplayOptions()
+	if opts.Git.Ref != e.Commit || opts.Git.Branch != "" || opts.Git.Pull {
+		t.Errorf("ReplayOptions() with commit = %+v, want the commit pinned", opts.Git)
+	}
+	if e.GitRef() != "main@0123456789ab" {
+		t.Errorf("GitRef() = %q", e.GitRef())
+	}
+
+	opts.Args[0] = "changed"
+	if e.Args[0] != "install" {
+		t.Error("ReplayOptions() shares the Args slice with the entry")
+	}
+}
+
+func TestRemoteCommit(t *testing.T) {
+	cfg := &Config{User: "ops"}
+	fc := &fakeClient{output: map[string][]byte{"git -C /home/ops/netcup-kube rev-parse HEAD": []byte("abc123\n")}}
+	commit, err := RemoteCommit(fc, cfg)
+	if err != nil || commit != "abc123" {
+		t.Errorf("RemoteCommit() = %q, %v", commit, err)
+	}
+}
+
+func TestHistoryHelpers(t *testing.T) {
+	if got := (HistoryEntry{Success: true}).Result(); got != "success" {
+		t.Errorf("Result() = %q", got)
+	}
+	if got := (HistoryEntry{}).Result(); got != "failure" {
+		t.Errorf("Result() = %q", got)
+	}
+	if got := (HistoryEntry{Commit: "abc123"}).GitRef(); got != "abc123" {
+		t.Errorf("GitRef() = %q", got)
+	}
+	if got := (HistoryEntry{Ref: "v1.2.0", Branch: "main"}).GitRef(); got != "v1.2.0" {
+		t.Errorf("GitRef() = %q", got)
+	}
+
+	t.Setenv("NETCUP_KUBE_STATE_HOME", "/var/lib/netcup-kube")
+	if got := HistoryPath(); got != filepath.Join("/var/lib/netcup-kube", HistoryFileName) {
+		t.Errorf("HistoryPath() = %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "prod.env")
+	if err := os.WriteFile(path, []byte("DOMAIN=example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if sum, err := FileSHA256(path); err != nil || len(sum) != 64 {
+		t.Errorf("FileSHA256() = %q, %v", sum, err)
+	}
+	if _, err := FileSHA256(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("FileSHA256(missing) expected error")
+	}
+
+	if _, err := RemoteCommit(&fakeClient{}, &Config{User: "ops"}); err == nil {
+		t.Error("RemoteCommit() without output expected error")
+	}
+}
+
+func TestAppendHistoryErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := AppendHistory(dir, HistoryEntry{}); err == nil {
+		t.Error("AppendHistory(directory) expected error")
+	}
+	blocker := filepath.Join(dir, "file")
+	if err := os.WriteFile(blocker, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AppendHistory(filepath.Join(blocker, HistoryFileName), HistoryEntry{}); err == nil {
+		t.Error("AppendHistory() below a file expected error")
+	}
+}
@@ -0,0 +1,233 @@
+package remote
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nodeTopScript samples /proc and the k3s and pod cgroups twice, $interval
+// seconds apart, then prints the point-in-time values. cgroup v2 is read first,
+// falling back to the v1 cpuacct and memory controllers.
+const nodeTopScript = `cg() {
+  n=$1; shift
+  for d in "$@"; do
+    if [ -f "/sys/fs/cgroup/$d/cpu.stat" ]; then
+      echo "cgroup $n $(awk '/^usage_usec/ {print $2}' "/sys/fs/cgroup/$d/cpu.stat") $(cat "/sys/fs/cgroup/$d/memory.current" 2>/dev/null || echo 0)"
+      return
+    elif [ -f "/sys/fs/cgroup/cpuacct/$d/cpuacct.usage" ]; then
+      echo "cgroup $n $(awk '{printf "%.0f", $1 / 1000}' "/sys/fs/cgroup/cpuacct/$d/cpuacct.usage") $(cat "/sys/fs/cgroup/memory/$d/memory.usage_in_bytes" 2>/dev/null || echo 0)"
+      return
+    fi
+  done
+}
+snap() {
+  echo "sample $1 $(cut -d' ' -f1 /proc/uptime)"
+  grep '^cpu ' /proc/stat
+  awk 'NR > 2 {sub(":", " "); print "net", $1, $2, $10}' /proc/net/dev
+  cg k3s system.slice/k3s.service system.slice/k3s-agent.service
+  cg pods kubepods kubepods.slice
+}
+snap 0
+sleep "$interval"
+snap 1
+echo "cpus $(nproc 2>/dev/null || grep -c '^processor' /proc/cpuinfo)"
+echo "load $(cut -d' ' -f1-3 /proc/loadavg)"
+awk '/^(MemTotal|MemAvailable|SwapTotal|SwapFree):/ {sub(":", "", $1); print "mem", $1, $2}' /proc/meminfo
+df -Pk / /var/lib/rancher 2>/dev/null | awk 'NR > 1 {print "disk", $6, $2, $3, $4}'`
+
+// virtualInterfaces are the prefixes of interfaces left out of the node's
+// network rates: loopback, pod veths, and the CNI, overlay and VPN devices
+var virtualInterfaces = []string{"lo", "veth", "cni", "flannel", "cali", "vxlan", "kube-", "docker", "wg", "tailscale"}
+
+// NodeTop is a quick view of a node's resource usage, read from /proc and
+// cgroup stats, so it needs neither metrics-server nor the kubelet
+type NodeTop struct {
+	CPUs int `json:"cpus"`
+	// CPU, IOWait and Steal are percentages of all CPUs over the sample
+	CPU          float64         `json:"cpu_percent"`
+	IOWait       float64         `json:"iowait_percent"`
+	Steal        float64         `json:"steal_percent"`
+	Load         [3]float64      `json:"load"`
+	MemTotal     int64           `json:"mem_total_bytes"`
+	MemAvailable int64           `json:"mem_available_bytes"`
+	SwapUsed     int64           `json:"swap_used_bytes"`
+	Disks        []NodeDisk      `json:"disks"`
+	Interfaces   []NodeInterface `json:"interfaces"`
+	Cgroups      []NodeCgroup    `json:"cgroups"`
+	Uptime       int64           `json:"uptime_seconds"`
+}
+
+// NodeDisk is the usage of a mounted filesystem
+type NodeDisk struct {
+	Mount string `json:"mount"`
+	Size  int64  `json:"size_bytes"`
+	Used  int64  `json:"used_bytes"`
+	Free  int64  `json:"free_bytes"`
+}
+
+// NodeInterface is the traffic of a network interface over the sample
+type NodeInterface struct {
+	Name    string `json:"name"`
+	RxBytes int64  `json:"rx_bytes_per_second"`
+	TxBytes int64  `json:"tx_bytes_per_second"`
+}
+
+// NodeCgroup is the usage of a cgroup: k3s (the k3s or k3s-agent service)
+// or pods (all Kubernetes pods)
+type NodeCgroup struct {
+	Name     string `json:"name"`
+	CPUMilli int64  `json:"cpu_millicores"`
+	Memory   int64  `json:"memory_bytes"`
+}
+
+// MemUsed is the memory in use, not counting reclaimable caches
+func (t NodeTop) MemUsed() int64 {
+	return t.MemTotal - t.MemAvailable
+}
+
+// Network sums the rates of the node's physical interfaces
+func (t NodeTop) Network() (rx, tx int64) {
+	for _, i := range t.Interfaces {
+		if slices.ContainsFunc(virtualInterfaces, func(p string) bool { return strings.HasPrefix(i.Name, p) }) {
+			continue
+		}
+		rx += i.RxBytes
+		tx += i.TxBytes
+	}
+	return rx, tx
+}
+
+// GetNodeTop samples the node's usage over interval (rounded up to whole
+// seconds)
+func GetNodeTop(client Client, interval time.Duration) (NodeTop, error) {
+	secs := int(math.Ceil(interval.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	out, err := client.OutputCommand("interval="+strconv.Itoa(secs)+"\n"+nodeTopScript, nil)
+	if err != nil {
+		return NodeTop{}, fmt.Errorf("failed to read node stats: %w", err)
+	}
+	return ParseNodeTop(out)
+}
+
+// nodeSample is one snapshot of the counters
+type nodeSample struct {
+	uptime float64
+	cpu    []int64
+	net    map[string][2]int64
+	cgroup map[string][2]int64
+}
+
+// ParseNodeTop parses the output of the node stats script
+func ParseNodeTop(out []byte) (NodeTop, error) {
+	var (
+		t       NodeTop
+		samples []*nodeSample
+		mem     = map[string]int64{}
+	)
+	for _, line := range strings.Split(string(out), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		var cur *nodeSample
+		if len(samples) > 0 {
+			cur = samples[len(samples)-1]
+		}
+		switch {
+		case f[0] == "sample" && len(f) == 3:
+			up, err := strconv.ParseFloat(f[2], 64)
+			if err != nil {
+				return t, fmt.Errorf("invalid uptime %q", f[2])
+			}
+			samples = append(samples, &nodeSample{uptime: up, net: map[string][2]int64{}, cgroup: map[string][2]int64{}})
+		case f[0] == "cpu" && cur != nil:
+			cur.cpu = parseInts(f[1:])
+		case f[0] == "net" && len(f) == 4 && cur != nil:
+			v := parseInts(f[2:])
+			cur.net[f[1]] = [2]int64{v[0], v[1]}
+		case f[0] == "cgroup" && len(f) == 4 && cur != nil:
+			v := parseInts(f[2:])
+			cur.cgroup[f[1]] = [2]int64{v[0], v[1]}
+		case f[0] == "cpus" && len(f) == 2:
+			t.CPUs, _ = strconv.Atoi(f[1])
+		case f[0] == "load" && len(f) == 4:
+			for i := range t.Load {
+				t.Load[i], _ = strconv.ParseFloat(f[i+1], 64)
+			}
+		case f[0] == "mem" && len(f) == 3:
+			kb, _ := strconv.ParseInt(f[2], 10, 64)
+			mem[f[1]] = kb * 1024
+		case f[0] == "disk" && len(f) == 5:
+			v := parseInts(f[2:])
+			if !slices.ContainsFunc(t.Disks, func(d NodeDisk) bool { return d.Mount == f[1] }) {
+				t.Disks = append(t.Disks, NodeDisk{Mount: f[1], Size: v[0] * 1024, Used: v[1] * 1024, Free: v[2] * 1024})
+			}
+		}
+	}
+	if len(samples) != 2 || len(samples[0].cpu) < 8 || len(samples[1].cpu) < 8 {
+		return t, fmt.Errorf("node stats script returned no CPU samples")
+	}
+	if mem["MemTotal"] == 0 {
+		return t, fmt.Errorf("node stats script did not report memory")
+	}
+	t.MemTotal, t.MemAvailable = mem["MemTotal"], mem["MemAvailable"]
+	t.SwapUsed = mem["SwapTotal"] - mem["SwapFree"]
+
+	before, after := samples[0], samples[1]
+	t.Uptime = int64(after.uptime)
+	elapsed := after.uptime - before.uptime
+	if elapsed <= 0 {
+		return t, fmt.Errorf("node stats samples are not in order")
+	}
+
+	// user nice system idle iowait irq softirq steal
+	delta := func(i int) int64 { return after.cpu[i] - before.cpu[i] }
+	var total int64
+	for i := 0; i < 8; i++ {
+		total += delta(i)
+	}
+	if total > 0 {
+		pct := func(n int64) float64 { return math.Round(float64(n)*1000/float64(total)) / 10 }
+		t.CPU = pct(total - delta(3) - delta(4))
+		t.IOWait = pct(delta(4))
+		t.Steal = pct(delta(7))
+	}
+
+	for name, v := range after.net {
+		prev, ok := before.net[name]
+		if !ok {
+			continue
+		}
+		t.Interfaces = append(t.Interfaces, NodeInterface{
+			Name:    name,
+			RxBytes: int64(float64(v[0]-prev[0]) / elapsed),
+			TxBytes: int64(float64(v[1]-prev[1]) / elapsed),
+		})
+	}
+	slices.SortFunc(t.Interfaces, func(a, b NodeInterface) int { return strings.Compare(a.Name, b.Name) })
+
+	for _, name := range []string{"k3s", "pods"} {
+		v, ok := after.cgroup[name]
+		prev, okBefore := before.cgroup[name]
+		if !ok || !okBefore {
+			continue
+		}
+		t.Cgroups = append(t.Cgroups, NodeCgroup{Name: name, CPUMilli: int64(float64(v[0]-prev[0]) / elapsed / 1000), Memory: v[1]})
+	}
+	return t, nil
+}
+
+// parseInts parses every field, treating malformed ones as zero
+func parseInts(fields []string) []int64 {
+	v := make([]int64, len(fields))
+	for i, f := range fields {
+		v[i], _ = strconv.ParseInt(f, 10, 64)
+	}
+	return v
+}
@@ -0,0 +1,69 @@
+package remote
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const nodeTopOutput = `sample 0 86400.00
+cpu  1000 0 500 8000 100 0 0 50 0 0
+net lo 5000 5000
+net eth0 100000 20000
+net veth1a2b 900000 900000
+cgroup k3s 1000000 734003200
+cgroup pods 5000000 1073741824
+sample 1 86402.00
+cpu  1250 0 600 9550 150 0 0 100 0 0
+net lo 9000 9000
+net eth0 300000 60000
+net veth1a2b 1900000 1900000
+cgroup k3s 1500000 735051776
+cgroup pods 6000000 1073741824
+cpus 4
+load 0.52 0.40 0.31
+mem MemTotal 8000000
+mem MemAvailable 6000000
+mem SwapTotal 0
+mem SwapFree 0
+disk / 100000000 40000000 60000000
+disk / 100000000 40000000 60000000
+`
+
+func TestGetNodeTop(t *testing.T) {
+	fc := &fakeClient{output: map[string][]byte{"interval=2\n" + nodeTopScript + " ": []byte(nodeTopOutput)}}
+	top, err := GetNodeTop(fc, 1500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("GetNodeTop() error: %v", err)
+	}
+	// 2000 jiffies in total: 400 busy, 50 iowait, 50 steal
+	if top.CPUs != 4 || top.CPU != 20 || top.IOWait != 2.5 || top.Steal != 2.5 || top.Load[0] != 0.52 {
+		t.Errorf("CPU = %+v", top)
+	}
+	if top.MemUsed() != 2000000*1024 || top.SwapUsed != 0 || top.Uptime != 86402 {
+		t.Errorf("memory = %d, swap = %d, uptime = %d", top.MemUsed(), top.SwapUsed, top.Uptime)
+	}
+	if len(top.Disks) != 1 || top.Disks[0].Used != 40000000*1024 {
+		t.Errorf("disks = %+v", top.Disks)
+	}
+	if rx, tx := top.Network(); rx != 100000 || tx != 20000 {
+		t.Errorf("Network() = %d, %d", rx, tx)
+	}
+	if len(top.Interfaces) != 3 || top.Interfaces[0].Name != "eth0" {
+		t.Errorf("interfaces = %+v", top.Interfaces)
+	}
+	want := []NodeCgroup{{Name: "k3s", CPUMilli: 250, Memory: 735051776}, {Name: "pods", CPUMilli: 500, Memory: 1073741824}}
+	if len(top.Cgroups) != 2 || top.Cgroups[0] != want[0] || top.Cgroups[1] != want[1] {
+		t.Errorf("cgroups = %+v", top.Cgroups)
+	}
+}
+
+func TestParseNodeTopIncomplete(t *testing.T) {
+	if _, err := ParseNodeTop([]byte("cpus 4\n")); err == nil || !strings.Contains(err.Error(), "no CPU samples") {
+		t.Errorf("ParseNodeTop() error = %v", err)
+	}
+	noMem := strings.Split(nodeTopOutput, "mem MemTotal")[0]
+	if _, err := ParseNodeTop([]byte(noMem)); err == nil {
+		t.Error("ParseNodeTop() accepted output without memory")
+	}
+}
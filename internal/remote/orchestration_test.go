@@ -90,14 +90,32 @@ func TestRemoteGitSync_Placeholders(t *testing.T) {
 		t.Fatalf("expected 1 script call, got %d", len(fc.scriptCalls))
 	}
 	args := fc.scriptCalls[0].args
-	if len(args) != 4 {
-		t.Fatalf("expected 4 args, got %d: %#v", len(args), args)
+	if len(args) != 6 {
+		t.Fatalf("expected 6 args, got %d: %#v", len(args), args)
 	}
-	if args[1] != "__NONE__" || args[2] != "__NONE__" || args[3] != "true" {
+	if args[1] != "__NONE__" || args[2] != "__NONE__" || args[3] != "true" || args[4] != "false" || args[5] != "0" {
 		t.Fatalf("unexpected placeholders: %#v", args)
 	}
 }
 
+func TestRemoteGitSync_ForceAndDepth(t *testing.T) {
+	fc := &fakeClient{}
+	if err := RemoteGitSync(fc, "/home/u/netcup-kube", GitOptions{Branch: "main", Force: true, Depth: 1}); err != nil {
+		t.Fatalf("RemoteGitSync error: %v", err)
+	}
+	args := fc.scriptCalls[0].args
+	if args[4] != "true" || args[5] != "1" {
+		t.Fatalf("unexpected force/depth args: %#v", args)
+	}
+
+	if err := RemoteGitSync(fc, "/home/u/netcup-kube", GitOptions{Depth: -1}); err == nil {
+		t.Fatalf("expected error for negative depth")
+	}
+	if len(fc.scriptCalls) != 1 {
+		t.Fatalf("expected no script call for invalid depth")
+	}
+}
+
 func TestRemoteDetectGoarch(t *testing.T) {
 	fc := &fakeClient{output: map[string][]byte{
 		"uname -m": []byte("x86_64\n"),
@@ -157,8 +175,15 @@ func TestRemoteBuildAndUpload_Success(t *testing.T) {
 	if err := RemoteBuildAndUpload(fc, cfg, tmp, GitOptions{}); err != nil {
 		t.Fatalf("RemoteBuildAndUpload error: %v", err)
 	}
-	if len(fc.uploads) != 1 {
-		t.Fatalf("expected 1 upload, got %d", len(fc.uploads))
+	// one upload per binary plus the manifest
+	if len(fc.uploads) != len(RemoteBinaries)+1 {
+		t.Fatalf("expected %d uploads, got %d", len(RemoteBinaries)+1, len(fc.uploads))
+	}
+	if got := fc.uploads[0].remote; got != cfg.GetRemoteBinPath() {
+		t.Fatalf("first upload = %q, want %q", got, cfg.GetRemoteBinPath())
+	}
+	if got := fc.uploads[len(fc.uploads)-1].remote; got != "/home/ops/netcup-kube/bin/manifest.json" {
+		t.Fatalf("last upload = %q, want manifest", got)
 	}
 	if len(fc.execCalls) == 0 {
 		t.Fatalf("expected exec calls for install/chmod")
@@ -211,6 +236,61 @@ func TestRemoteBuildAndUpload_NoGoToolchain(t *testing.T) {
 	}
 }
 
+func TestRemoteBuildAndUpload_BuildRemoteFallback(t *testing.T) {
+	oldLook := lookPath
+	oldBuild := localGoBuild
+	t.Cleanup(func() { lookPath = oldLook; localGoBuild = oldBuild })
+	lookPath = func(_ string) (string, error) { return "", exec.ErrNotFound }
+	localGoBuild = func(_ string, _ string, _ string) error {
+		t.Fatalf("local build must not run without a local toolchain")
+		return nil
+	}
+
+	tmp := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte("module x\n\ngo 1.24\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fc := &fakeClient{output: map[string][]byte{"uname -m": []byte("aarch64\n")}}
+	cfg := NewConfig()
+	cfg.Host = "example.com"
+	cfg.User = "ops"
+	if err := RemoteBuildAndUpload(fc, cfg, tmp, GitOptions{BuildRemote: true}); err != nil {
+		t.Fatalf("RemoteBuildAndUpload error: %v", err)
+	}
+	if len(fc.uploads) != 0 {
+		t.Fatalf("expected no uploads for a remote build, got %d", len(fc.uploads))
+	}
+	if len(fc.scriptCalls) != 1 {
+		t.Fatalf("expected 1 script call, got %d", len(fc.scriptCalls))
+	}
+	want := []string{"/home/ops/netcup-kube", "/home/ops/netcup-kube/bin", "1.24.0", "arm64", "netcup-kube", "netcup-claw"}
+	if got := fc.scriptCalls[0].args; strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("script args = %v, want %v", got, want)
+	}
+	if !strings.Contains(fc.scriptCalls[0].script, "build -o \"${out}.tmp\" \"./cmd/${name}\"") {
+		t.Fatalf("expected script to run go build")
+	}
+}
+
+func TestRemoteGoVersion(t *testing.T) {
+	tmp := t.TempDir()
+	if got := remoteGoVersion(tmp); got != defaultRemoteGoVersion {
+		t.Fatalf("missing go.mod: got %q", got)
+	}
+	for content, want := range map[string]string{
+		"module x\n\ngo 1.23\n":    "1.23.0",
+		"module x\n\ngo 1.24.2\n":  "1.24.2",
+		"module x\n\ngo 1.25rc1\n": defaultRemoteGoVersion,
+	} {
+		if err := os.WriteFile(filepath.Join(tmp, "go.mod"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := remoteGoVersion(tmp); got != want {
+			t.Fatalf("remoteGoVersion(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
 func TestRun_WrapperExecutes(t *testing.T) {
 	oldExec := execCommand
 	t.Cleanup(func() { execCommand = oldExec })
@@ -236,7 +316,14 @@ func TestRun_WrapperExecutes(t *testing.T) {
 func TestProvision_WrapperExecutes(t *testing.T) {
 	oldExec := execCommand
 	t.Cleanup(func() { execCommand = oldExec })
-	execCommand = func(_ string, _ ...string) *exec.Cmd { return exec.Command("true") }
+	execCommand = func(_ string, args ...string) *exec.Cmd {
+		for _, a := range args {
+			if a == "/etc/os-release" {
+				return exec.Command("printf", `ID=debian\nVERSION_ID="13"\n`)
+			}
+		}
+		return exec.Command("true")
+	}
 
 	tmp := t.TempDir()
 	pub := filepath.Join(tmp, "id.pub")
@@ -531,3 +618,73 @@ func TestSmokeWithClient_CoversSmokePaths(t *testing.T) {
 		t.Fatalf("expected remote run calls")
 	}
 }
+
+func TestRemoteBuildAndUpload_CachedBuildAndUpToDateRemote(t *testing.T) {
+	cacheDir := t.TempDir()
+	oldLook := lookPath
+	oldBuild := localGoBuild
+	oldHead := localGitHead
+	oldCache := buildCacheDir
+	t.Cleanup(func() {
+		lookPath = oldLook
+		localGoBuild = oldBuild
+		localGitHead = oldHead
+		buildCacheDir = oldCache
+	})
+	lookPath = func(_ string) (string, error) { return "/usr/bin/go", nil }
+	localGitHead = func(_ string) (string, error) { return "abc123", nil }
+	buildCacheDir = func() (string, error) { return cacheDir, nil }
+	builds := 0
+	localGoBuild = func(_ string, out string, _ string) error {
+		builds++
+		return os.WriteFile(out, []byte("bin"), 0755)
+	}
+
+	cfg := NewConfig()
+	cfg.Host = "example.com"
+	cfg.User = "ops"
+	fc := &fakeClient{output: map[string][]byte{"uname -m": []byte("x86_64\n")}}
+	if err := RemoteBuildAndUpload(fc, cfg, t.TempDir(), GitOptions{}); err != nil {
+		t.Fatalf("RemoteBuildAndUpload error: %v", err)
+	}
+	if builds != 2 || len(fc.uploads) != 3 {
+		t.Fatalf("first run: builds=%d uploads=%d, want 2/3", builds, len(fc.uploads))
+	}
+	cached := filepath.Join(cacheDir, "abc123-amd64", "netcup-kube")
+	if !fileExists(cached) {
+		t.Fatalf("expected cached binary at %s", cached)
+	}
+
+	// Second run reuses the cache and only uploads the manifest when checksums match
+	sum, err := fileSHA256(cached)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range RemoteBinaries {
+		remoteBin := cfg.GetRemoteBinDir() + "/" + name
+		fc.output["sha256sum "+remoteBin] = []byte(sum + "  " + remoteBin + "\n")
+	}
+	if err := RemoteBuildAndUpload(fc, cfg, t.TempDir(), GitOptions{}); err != nil {
+		t.Fatalf("RemoteBuildAndUpload error: %v", err)
+	}
+	if builds != 2 || len(fc.uploads) != 4 {
+		t.Fatalf("cached run: builds=%d uploads=%d, want 2/4", builds, len(fc.uploads))
+	}
+
+	// NoCache forces both
+	if err := RemoteBuildAndUpload(fc, cfg, t.TempDir(), GitOptions{NoCache: true}); err != nil {
+		t.Fatalf("RemoteBuildAndUpload error: %v", err)
+	}
+	if builds != 4 || len(fc.uploads) != 7 {
+		t.Fatalf("no-cache run: builds=%d uploads=%d, want 4/7", builds, len(fc.uploads))
+	}
+}
+
+func TestBuildCacheKey_DirtyTreeNotCached(t *testing.T) {
+	oldHead := localGitHead
+	t.Cleanup(func() { localGitHead = oldHead })
+	localGitHead = func(_ string) (string, error) { return "", nil }
+	if key := buildCacheKey(t.TempDir(), "amd64"); key != "" {
+		t.Fatalf("expected no cache key for dirty tree, got %q", key)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"os"
 	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/keyring"
+	"github.com/mfittko/netcup-kube/internal/osrelease"
 )
 
 // Provision prepares the remote host with a sudo user and clones the repository
@@ -39,6 +42,10 @@ func Provision(cfg *Config) error {
 		return err
 	}
 
+	if err := provisionPreflight(rootClient, allowUnsupportedOS()); err != nil {
+		return err
+	}
+
 	// Build and run the provisioning script
 	script := buildProvisionScript(cfg.User, pubKey, cfg.RepoURL, cfg.Host)
 
@@ -50,6 +57,28 @@ func Provision(cfg *Config) error {
 	return nil
 }
 
+// provisionPreflight checks the remote distribution before anything is installed
+func provisionPreflight(client Client, allowUnsupported bool) error {
+	out, err := client.OutputCommand("cat", []string{"/etc/os-release"})
+	if err != nil {
+		return fmt.Errorf("failed to detect remote OS (/etc/os-release): %w", err)
+	}
+	info := osrelease.Parse(string(out))
+	if err := osrelease.Check(info, allowUnsupported); err != nil {
+		return err
+	}
+	fmt.Printf("[remote] Detected OS: %s (%s)\n", info.Name(), info.PackageManager())
+	return nil
+}
+
+func allowUnsupportedOS() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("ALLOW_UNSUPPORTED_OS"))) {
+	case "1", "true", "yes", "y", "on":
+		return true
+	}
+	return false
+}
+
 // ensureRootAccess ensures we can SSH to root, copying keys if needed
 func ensureRootAccess(client Client, host string, pubKeyPath string) error {
 	// Test if we already have access
@@ -61,7 +90,10 @@ func ensureRootAccess(client Client, host string, pubKeyPath string) error {
 	// Check if sshpass is available for password auth
 	if _, err := lookPath("sshpass"); err == nil {
 		// Try to use sshpass
-		rootPass := os.Getenv("ROOT_PASS")
+		rootPass, err := keyring.Resolve(os.Getenv("ROOT_PASS"))
+		if err != nil {
+			return err
+		}
 		if rootPass == "" {
 			// No password provided; instruct user to set ROOT_PASS or use ssh-copy-id
 			return fmt.Errorf("ROOT_PASS environment variable is empty or not set. Either set ROOT_PASS or run:\n  ssh-copy-id -o StrictHostKeyChecking=no -i %s root@%s", pubKeyPath, host)
@@ -98,15 +130,28 @@ Then re-run the provision command`, pubKeyPath, host)
 // buildProvisionScript creates the provisioning script
 func buildProvisionScript(user, pubKey, repoURL, host string) string {
 	template := `set -euo pipefail
-export DEBIAN_FRONTEND=noninteractive
-apt-get update -y
-apt-get install -y --no-install-recommends sudo git curl ca-certificates
+if command -v apt-get >/dev/null 2>&1; then
+  export DEBIAN_FRONTEND=noninteractive
+  apt-get update -y
+  apt-get install -y --no-install-recommends sudo git curl ca-certificates
+  sudo_group=sudo
+elif command -v dnf >/dev/null 2>&1; then
+  dnf install -y --setopt=install_weak_deps=False sudo git curl ca-certificates
+  sudo_group=wheel
+else
+  echo "[remote] ERROR: no supported package manager found (apt-get or dnf)" >&2
+  exit 1
+fi
 
 # Create user if missing
 if ! id -u __NEW_USER__ >/dev/null 2>&1; then
-  adduser --disabled-password --gecos "" __NEW_USER__
+  if command -v adduser >/dev/null 2>&1 && adduser --help 2>&1 | grep -q -- --disabled-password; then
+    adduser --disabled-password --gecos "" __NEW_USER__
+  else
+    useradd -m -s /bin/bash __NEW_USER__
+  fi
 fi
-usermod -aG sudo __NEW_USER__
+usermod -aG "${sudo_group}" __NEW_USER__
 install -d -m 0700 -o __NEW_USER__ -g __NEW_USER__ /home/__NEW_USER__/.ssh
 
 # Append key once (treat pubkey literally, no regex interpretation)
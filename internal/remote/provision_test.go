@@ -31,7 +31,9 @@ func TestBuildProvisionScript(t *testing.T) {
 		"sudo",
 		"git",
 		"adduser",
-		"usermod -aG sudo",
+		"dnf install",
+		"useradd -m",
+		"usermod -aG \"${sudo_group}\"",
 		"git clone",
 	}
 
@@ -183,3 +185,22 @@ func TestProvision_MultilinePubKey(t *testing.T) {
 		t.Errorf("Expected 'multiple lines' error, got: %v", err)
 	}
 }
+
+func TestProvisionPreflight(t *testing.T) {
+	fc := &fakeClient{output: map[string][]byte{"cat /etc/os-release": []byte("ID=rocky\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.4\"\n")}}
+	if err := provisionPreflight(fc, false); err != nil {
+		t.Fatalf("expected Rocky 9 to be supported: %v", err)
+	}
+
+	fc.output["cat /etc/os-release"] = []byte("ID=debian\nVERSION_ID=\"11\"\n")
+	if err := provisionPreflight(fc, false); err == nil {
+		t.Fatalf("expected Debian 11 to be rejected")
+	}
+	if err := provisionPreflight(fc, true); err != nil {
+		t.Fatalf("expected Debian 11 to pass with ALLOW_UNSUPPORTED_OS: %v", err)
+	}
+
+	if err := provisionPreflight(&fakeClient{}, false); err == nil {
+		t.Fatalf("expected error when os-release cannot be read")
+	}
+}
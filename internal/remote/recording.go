@@ -0,0 +1,291 @@
+package remote
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Environment variables selecting the transport of WrapClient
+const (
+	// RecordEnv names a fixture file all remote interactions are recorded to
+	RecordEnv = "NETCUP_KUBE_REMOTE_RECORD"
+	// ReplayEnv names a fixture file remote interactions are replayed from,
+	// without connecting to the host
+	ReplayEnv = "NETCUP_KUBE_REMOTE_REPLAY"
+)
+
+// Interaction is one recorded call of the Client interface
+type Interaction struct {
+	// Method is the Client method: TestConnection, Execute, ExecuteScript,
+	// Upload, RunCommandString or OutputCommand
+	Method   string   `json:"method"`
+	Command  string   `json:"command,omitempty"`
+	Args     []string `json:"args,omitempty"`
+	ForceTTY bool     `json:"force_tty,omitempty"`
+	// ScriptSHA256 identifies the script of ExecuteScript, which is not
+	// stored
+	ScriptSHA256 string `json:"script_sha256,omitempty"`
+	// Local is informational; uploads are matched by their remote path since
+	// the local file is usually a temp file
+	Local  string `json:"local,omitempty"`
+	Remote string `json:"remote,omitempty"`
+	// Output is the stdout of OutputCommand; the other methods stream their
+	// output to the terminal, which is not recorded
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// String renders the call, without its result
+func (i Interaction) String() string {
+	parts := []string{i.Method}
+	if i.Command != "" {
+		parts = append(parts, i.Command)
+	}
+	parts = append(parts, i.Args...)
+	if i.ScriptSHA256 != "" {
+		parts = append(parts, "script:"+i.ScriptSHA256[:12])
+	}
+	if i.Remote != "" {
+		parts = append(parts, "-> "+i.Remote)
+	}
+	if i.ForceTTY {
+		parts = append(parts, "(tty)")
+	}
+	return strings.Join(parts, " ")
+}
+
+// sameCall reports whether i and other are the same call
+func (i Interaction) sameCall(other Interaction) bool {
+	return i.Method == other.Method && i.Command == other.Command && slices.Equal(i.Args, other.Args) &&
+		i.ForceTTY == other.ForceTTY && i.ScriptSHA256 == other.ScriptSHA256 && i.Remote == other.Remote
+}
+
+// err returns the recorded error, or nil
+func (i Interaction) err() error {
+	if i.Error == "" {
+		return nil
+	}
+	return errors.New(i.Error)
+}
+
+func scriptDigest(script string) string {
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// LoadInteractions reads a fixture file written by a RecordingClient
+func LoadInteractions(path string) ([]Interaction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read remote fixture: %w", err)
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("failed to parse remote fixture %s: %w", path, err)
+	}
+	return interactions, nil
+}
+
+// SaveInteractions writes interactions as a fixture file
+func SaveInteractions(path string, interactions []Interaction) error {
+	if interactions == nil {
+		interactions = []Interaction{}
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	// Recorded commands are shell, where && and > are common
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(interactions); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write remote fixture: %w", err)
+	}
+	return nil
+}
+
+// RecordingClient passes every call to another Client and records it
+type RecordingClient struct {
+	inner Client
+	// path is rewritten after every call so a recording survives an aborted
+	// run; empty keeps the recording in memory
+	path string
+
+	mu           sync.Mutex
+	interactions []Interaction
+}
+
+// NewRecordingClient records the calls of inner, saving them to path (if not
+// empty) after every call
+func NewRecordingClient(inner Client, path string) *RecordingClient {
+	return &RecordingClient{inner: inner, path: path}
+}
+
+// Interactions returns the calls recorded so far
+func (r *RecordingClient) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.interactions)
+}
+
+func (r *RecordingClient) record(i Interaction, err error) {
+	i.Error = errorText(err)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.interactions = append(r.interactions, i)
+	if r.path == "" {
+		return
+	}
+	if saveErr := SaveInteractions(r.path, r.interactions); saveErr != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v\n", saveErr)
+	}
+}
+
+func (r *RecordingClient) TestConnection() error {
+	err := r.inner.TestConnection()
+	r.record(Interaction{Method: "TestConnection"}, err)
+	return err
+}
+
+func (r *RecordingClient) Execute(command string, args []string, forceTTY bool) error {
+	err := r.inner.Execute(command, args, forceTTY)
+	r.record(Interaction{Method: "Execute", Command: command, Args: slices.Clone(args), ForceTTY: forceTTY}, err)
+	return err
+}
+
+func (r *RecordingClient) ExecuteScript(script string, args []string) error {
+	err := r.inner.ExecuteScript(script, args)
+	r.record(Interaction{Method: "ExecuteScript", ScriptSHA256: scriptDigest(script), Args: slices.Clone(args)}, err)
+	return err
+}
+
+func (r *RecordingClient) Upload(localPath, remotePath string) error {
+	err := r.inner.Upload(localPath, remotePath)
+	r.record(Interaction{Method: "Upload", Local: localPath, Remote: remotePath}, err)
+	return err
+}
+
+func (r *RecordingClient) RunCommandString(cmdString string, forceTTY bool) error {
+	err := r.inner.RunCommandString(cmdString, forceTTY)
+	r.record(Interaction{Method: "RunCommandString", Command: cmdString, ForceTTY: forceTTY}, err)
+	return err
+}
+
+func (r *RecordingClient) OutputCommand(command string, args []string) ([]byte, error) {
+	out, err := r.inner.OutputCommand(command, args)
+	r.record(Interaction{Method: "OutputCommand", Command: command, Args: slices.Clone(args), Output: string(out)}, err)
+	return out, err
+}
+
+// ReplayClient answers calls from recorded interactions, in order, without
+// connecting anywhere. A call that differs from the next recorded one fails
+// with an error naming both.
+type ReplayClient struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	next         int
+}
+
+// NewReplayClient replays interactions
+func NewReplayClient(interactions []Interaction) *ReplayClient {
+	return &ReplayClient{interactions: interactions}
+}
+
+// LoadReplayClient replays the fixture file at path
+func LoadReplayClient(path string) (*ReplayClient, error) {
+	interactions, err := LoadInteractions(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayClient(interactions), nil
+}
+
+// Done returns an error when recorded interactions were not replayed
+func (p *ReplayClient) Done() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.next == len(p.interactions) {
+		return nil
+	}
+	var pending []string
+	for _, i := range p.interactions[p.next:] {
+		pending = append(pending, "  "+i.String())
+	}
+	return fmt.Errorf("replay: %d recorded calls not made:\n%s", len(pending), strings.Join(pending, "\n"))
+}
+
+func (p *ReplayClient) replay(call Interaction) Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.next >= len(p.interactions) {
+		return Interaction{Error: fmt.Sprintf("replay: unexpected call %d: %s (recording has %d calls)", p.next+1, call, len(p.interactions))}
+	}
+	want := p.interactions[p.next]
+	if !want.sameCall(call) {
+		return Interaction{Error: fmt.Sprintf("replay: call %d is %s, recorded %s", p.next+1, call, want)}
+	}
+	p.next++
+	return want
+}
+
+func (p *ReplayClient) TestConnection() error {
+	return p.replay(Interaction{Method: "TestConnection"}).err()
+}
+
+func (p *ReplayClient) Execute(command string, args []string, forceTTY bool) error {
+	return p.replay(Interaction{Method: "Execute", Command: command, Args: args, ForceTTY: forceTTY}).err()
+}
+
+func (p *ReplayClient) ExecuteScript(script string, args []string) error {
+	return p.replay(Interaction{Method: "ExecuteScript", ScriptSHA256: scriptDigest(script), Args: args}).err()
+}
+
+func (p *ReplayClient) Upload(localPath, remotePath string) error {
+	return p.replay(Interaction{Method: "Upload", Local: localPath, Remote: remotePath}).err()
+}
+
+func (p *ReplayClient) RunCommandString(cmdString string, forceTTY bool) error {
+	return p.replay(Interaction{Method: "RunCommandString", Command: cmdString, ForceTTY: forceTTY}).err()
+}
+
+func (p *ReplayClient) OutputCommand(command string, args []string) ([]byte, error) {
+	i := p.replay(Interaction{Method: "OutputCommand", Command: command, Args: args})
+	var out []byte
+	if i.Output != "" {
+		out = []byte(i.Output)
+	}
+	return out, i.err()
+}
+
+// WrapClient returns the transport selected by the environment for client:
+// a ReplayClient with NETCUP_KUBE_REMOTE_REPLAY, client recorded with
+// NETCUP_KUBE_REMOTE_RECORD, else client itself
+func WrapClient(client Client) (Client, error) {
+	if path := strings.TrimSpace(os.Getenv(ReplayEnv)); path != "" {
+		replay, err := LoadReplayClient(path)
+		if err != nil {
+			return nil, err
+		}
+		return replay, nil
+	}
+	if path := strings.TrimSpace(os.Getenv(RecordEnv)); path != "" {
+		return NewRecordingClient(client, path), nil
+	}
+	return client, nil
+}
@@ -0,0 +1,170 @@
+package remote
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func replayFixture(t *testing.T, name string) *ReplayClient {
+	t.Helper()
+	replay, err := LoadReplayClient(filepath.Join("testdata", "replay", name))
+	if err != nil {
+		t.Fatalf("LoadReplayClient: %v", err)
+	}
+	return replay
+}
+
+func replayConfig() *Config {
+	cfg := NewConfig()
+	cfg.Host = "mgmt.example.com"
+	cfg.User = "ops"
+	return cfg
+}
+
+func TestRecordingClient_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	fc := &fakeClient{
+		output:       map[string][]byte{"uname -m": []byte("aarch64\n")},
+		execErrByKey: map[string]error{"test -d /srv": errors.New("exit status 1")},
+	}
+	rc := NewRecordingClient(fc, path)
+
+	if err := rc.TestConnection(); err != nil {
+		t.Fatalf("TestConnection: %v", err)
+	}
+	if err := rc.Execute("test", []string{"-d", "/srv"}, false); err == nil {
+		t.Fatal("expected the recorded Execute error")
+	}
+	if err := rc.ExecuteScript("echo hi", []string{"a"}); err != nil {
+		t.Fatalf("ExecuteScript: %v", err)
+	}
+	if err := rc.Upload("/tmp/x.env", "/tmp/remote.env"); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if err := rc.RunCommandString("sudo true", true); err != nil {
+		t.Fatalf("RunCommandString: %v", err)
+	}
+	if out, err := rc.OutputCommand("uname", []string{"-m"}); err != nil || string(out) != "aarch64\n" {
+		t.Fatalf("OutputCommand = %q, %v", out, err)
+	}
+	if len(fc.execCalls) != 1 || len(fc.scriptCalls) != 1 || len(fc.uploads) != 1 || len(fc.runCalls) != 1 {
+		t.Fatalf("calls were not passed through: %+v", fc)
+	}
+
+	saved, err := LoadInteractions(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, rc.Interactions()) || len(saved) != 6 {
+		t.Fatalf("saved %+v, recorded %+v", saved, rc.Interactions())
+	}
+	if saved[1].Error != "exit status 1" || saved[2].ScriptSHA256 != scriptDigest("echo hi") {
+		t.Errorf("unexpected recording: %+v", saved)
+	}
+
+	replay := NewReplayClient(saved)
+	if err := replay.TestConnection(); err != nil {
+		t.Fatalf("replay TestConnection: %v", err)
+	}
+	if err := replay.Execute("test", []string{"-d", "/srv"}, false); err == nil || err.Error() != "exit status 1" {
+		t.Fatalf("replay Execute = %v, want the recorded error", err)
+	}
+	if err := replay.ExecuteScript("echo hi", []string{"a"}); err != nil {
+		t.Fatalf("replay ExecuteScript: %v", err)
+	}
+	// Uploads match on the remote path; local files are usually temp files
+	if err := replay.Upload("/tmp/other.env", "/tmp/remote.env"); err != nil {
+		t.Fatalf("replay Upload: %v", err)
+	}
+	if err := replay.RunCommandString("sudo true", true); err != nil {
+		t.Fatalf("replay RunCommandString: %v", err)
+	}
+	if out, err := replay.OutputCommand("uname", []string{"-m"}); err != nil || string(out) != "aarch64\n" {
+		t.Fatalf("replay OutputCommand = %q, %v", out, err)
+	}
+	if err := replay.Done(); err != nil {
+		t.Fatalf("Done: %v", err)
+	}
+}
+
+func TestReplayClient_Mismatches(t *testing.T) {
+	replay := NewReplayClient([]Interaction{
+		{Method: "ExecuteScript", ScriptSHA256: scriptDigest("echo v1")},
+		{Method: "TestConnection"},
+	})
+	err := replay.ExecuteScript("echo v2", nil)
+	if err == nil || !strings.Contains(err.Error(), "call 1 is ExecuteScript") {
+		t.Fatalf("changed script = %v, want a mismatch", err)
+	}
+	if err := replay.ExecuteScript("echo v1", nil); err != nil {
+		t.Fatalf("matching call after a mismatch: %v", err)
+	}
+	if err := replay.Done(); err == nil || !strings.Contains(err.Error(), "TestConnection") {
+		t.Fatalf("Done = %v, want the pending TestConnection", err)
+	}
+	if err := replay.TestConnection(); err != nil {
+		t.Fatal(err)
+	}
+	if err := replay.Execute("id", nil, false); err == nil || !strings.Contains(err.Error(), "unexpected call 3") {
+		t.Fatalf("extra call = %v", err)
+	}
+}
+
+func TestRunWithClient_ReplaysResilientRun(t *testing.T) {
+	replay := replayFixture(t, "run-resilient.json")
+	if err := runWithClient(replay, replayConfig(), RunOptions{Resilient: true, Args: []string{"bootstrap"}}); err != nil {
+		t.Fatalf("runWithClient: %v", err)
+	}
+	if err := replay.Done(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The runner command is part of the recording, so changed arguments fail
+	replay = replayFixture(t, "run-resilient.json")
+	err := runWithClient(replay, replayConfig(), RunOptions{Resilient: true, Args: []string{"bootstrap", "--dry-run"}})
+	if err == nil || !strings.Contains(err.Error(), "replay: call 6") {
+		t.Fatalf("changed args = %v, want a replay mismatch", err)
+	}
+}
+
+func TestAttachWithClient_ReplaysSessionLookup(t *testing.T) {
+	replay := replayFixture(t, "attach.json")
+	if err := attachWithClient(replay, replayConfig(), AttachOptions{}); err != nil {
+		t.Fatalf("attachWithClient: %v", err)
+	}
+	if err := replay.Done(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWrapClient(t *testing.T) {
+	inner := &fakeClient{}
+
+	t.Setenv(RecordEnv, "")
+	t.Setenv(ReplayEnv, "")
+	if c, err := WrapClient(inner); err != nil || c != Client(inner) {
+		t.Fatalf("WrapClient without env = %T, %v", c, err)
+	}
+
+	t.Setenv(RecordEnv, filepath.Join(t.TempDir(), "rec.json"))
+	if c, err := WrapClient(inner); err != nil {
+		t.Fatal(err)
+	} else if _, ok := c.(*RecordingClient); !ok {
+		t.Fatalf("WrapClient with %s = %T", RecordEnv, c)
+	}
+
+	t.Setenv(ReplayEnv, filepath.Join("testdata", "replay", "attach.json"))
+	if c, err := WrapClient(inner); err != nil {
+		t.Fatal(err)
+	} else if _, ok := c.(*ReplayClient); !ok {
+		t.Fatalf("WrapClient with %s = %T", ReplayEnv, c)
+	}
+
+	t.Setenv(ReplayEnv, filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := WrapClient(inner); err == nil {
+		t.Fatal("expected an error for a missing replay fixture")
+	}
+}
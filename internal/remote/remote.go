@@ -1,10 +1,14 @@
 package remote
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mfittko/netcup-kube/internal/config"
 )
@@ -34,6 +38,14 @@ type GitOptions struct {
 	Ref       string
 	Pull      bool
 	PullIsSet bool
+	// Force discards uncommitted changes in the remote repo before checkout
+	Force bool
+	// Depth limits fetches to the given number of commits (0 = full history)
+	Depth int
+	// BuildRemote builds on the remote host when no local Go toolchain is found
+	BuildRemote bool
+	// NoCache forces a rebuild and upload even when a cached or identical binary exists
+	NoCache bool
 }
 
 // RunOptions holds options for the run command
@@ -42,6 +54,13 @@ type RunOptions struct {
 	ForceTTY bool
 	EnvFile  string
 	Args     []string
+	// Resilient runs the command inside a tmux session on the remote host so it
+	// survives a dropped connection (rejoin with `remote attach`)
+	Resilient bool
+	// Idle configures heartbeats and idle-timeout detection while the command runs
+	Idle IdleOptions
+	// Context stops the SSH session when canceled (Ctrl-C)
+	Context context.Context
 }
 
 // NewConfig creates a new remote config with defaults
@@ -54,14 +73,15 @@ func NewConfig() *Config {
 
 // LoadConfigFromEnv loads host configuration from environment file if available
 func (c *Config) LoadConfigFromEnv(configPath string) error {
-	if configPath == "" || !fileExists(configPath) {
-		return nil
-	}
-
-	// Use the shared env-file parser (supports ${VAR} expansion like MGMT_USER=${DEFAULT_USER})
+	// Use the shared env-file parser (supports ${VAR} expansion like MGMT_USER=${DEFAULT_USER});
+	// without a file only the env file overrides of a --cluster apply
 	loader := config.New()
-	if err := loader.LoadEnvFile(configPath); err != nil {
-		return err
+	if configPath != "" && fileExists(configPath) {
+		if err := loader.LoadEnvFile(configPath); err != nil {
+			return err
+		}
+	} else {
+		loader.ApplyEnvFileOverrides()
 	}
 	vars := loader.Env
 
@@ -124,6 +144,11 @@ func (c *Config) GetRemoteBinPath() string {
 	return fmt.Sprintf(remoteBinPath, c.User)
 }
 
+// GetRemoteBinDir returns the remote directory holding all uploaded binaries
+func (c *Config) GetRemoteBinDir() string {
+	return path.Dir(c.GetRemoteBinPath())
+}
+
 // fileExists checks if a file exists
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
@@ -139,8 +164,14 @@ func getHostname() string {
 	return name
 }
 
-// RemoteGitSync synchronizes the remote repository
+// RemoteGitSync synchronizes the remote repository. It refuses to touch a repo
+// with uncommitted changes to tracked files unless opts.Force is set, and
+// initializes submodules when the checkout has any.
 func RemoteGitSync(client Client, repoDir string, opts GitOptions) error {
+	if opts.Depth < 0 {
+		return fmt.Errorf("invalid --depth %d (must be >= 0)", opts.Depth)
+	}
+
 	branch := opts.Branch
 	ref := opts.Ref
 	pull := opts.Pull
@@ -161,21 +192,51 @@ func RemoteGitSync(client Client, repoDir string, opts GitOptions) error {
 		pullArg = "true"
 	}
 
+	forceArg := "false"
+	if opts.Force {
+		forceArg = "true"
+	}
+
 	script := `set -euo pipefail
 repo="${1:?repo dir required}"
 branch="${2:-__NONE__}"
 ref="${3:-__NONE__}"
 pull="${4:-true}"
+force="${5:-false}"
+depth="${6:-0}"
 
 [[ "${branch}" == "__NONE__" ]] && branch=""
 [[ "${ref}" == "__NONE__" ]] && ref=""
 
+depth_args=()
+if [[ "${depth}" != "0" ]]; then
+  depth_args=(--depth "${depth}")
+fi
+
 cd "${repo}"
-git fetch --all -p
+
+if [[ -n "$(git status --porcelain --untracked-files=no)" ]]; then
+  if [[ "${force}" != "true" ]]; then
+    echo "[remote] ERROR: uncommitted changes in ${repo}:" >&2
+    git status --short --untracked-files=no >&2
+    echo "[remote] Commit or stash them on the host, or re-run with --force to discard them." >&2
+    exit 1
+  fi
+  echo "[remote] --force: discarding uncommitted changes" >&2
+  git reset --hard -q HEAD
+fi
+
+git fetch --all -p "${depth_args[@]}"
 
 if [[ -n "${ref}" ]]; then
   echo "[remote] checkout ref: ${ref}"
-  git checkout --detach "${ref}"
+  if [[ "${depth}" != "0" ]] && ! git rev-parse -q --verify "${ref}^{commit}" >/dev/null; then
+    # Not reachable in the shallow history: fetch the ref itself
+    git fetch -p "${depth_args[@]}" origin "${ref}"
+    git checkout --detach FETCH_HEAD
+  else
+    git checkout --detach "${ref}"
+  fi
 elif [[ -n "${branch}" ]]; then
   echo "[remote] checkout branch: ${branch}"
   if git show-ref --verify --quiet "refs/heads/${branch}"; then
@@ -197,17 +258,26 @@ fi
 if [[ "${pull}" == "true" && -z "${ref}" ]]; then
   if [[ -n "${branch}" ]]; then
     echo "[remote] pull: origin ${branch} (ff-only)"
-    git pull --ff-only origin "${branch}"
+    git pull --ff-only "${depth_args[@]}" origin "${branch}"
   else
     echo "[remote] NOTE: --pull requested but no --branch/--ref provided; skipping pull." >&2
   fi
 fi
+
+if [[ -f .gitmodules ]]; then
+  echo "[remote] updating submodules"
+  git submodule sync --recursive -q
+  git submodule update --init --recursive "${depth_args[@]}"
+fi
 `
 
-	return client.ExecuteScript(script, []string{repoDir, branchArg, refArg, pullArg})
+	return client.ExecuteScript(script, []string{repoDir, branchArg, refArg, pullArg, forceArg, strconv.Itoa(opts.Depth)})
 }
 
-// RemoteBuildAndUpload builds the Go binary locally and uploads it to the remote host
+// RemoteBuildAndUpload builds the Go binaries listed in RemoteBinaries locally and
+// uploads them to the remote bin directory together with a version manifest.
+// Builds of a clean git checkout are cached per commit and GOARCH, and uploads are
+// skipped for binaries whose remote checksum already matches.
 func RemoteBuildAndUpload(client Client, cfg *Config, projectRoot string, opts GitOptions) error {
 	// Sync git if requested
 	// NOTE: A sync is still performed when Branch/Ref are set even if Pull=false,
@@ -220,7 +290,10 @@ func RemoteBuildAndUpload(client Client, cfg *Config, projectRoot string, opts G
 
 	// Check for local go toolchain
 	if _, err := lookPath("go"); err != nil {
-		return fmt.Errorf("missing local 'go' toolchain. Install Go 1.23+ and retry")
+		if opts.BuildRemote {
+			return remoteGoBuild(client, cfg, projectRoot)
+		}
+		return fmt.Errorf("missing local 'go' toolchain. Install Go 1.23+ and retry (or use --build-remote to build on the remote host)")
 	}
 
 	// Detect remote architecture
@@ -229,44 +302,92 @@ func RemoteBuildAndUpload(client Client, cfg *Config, projectRoot string, opts G
 		return err
 	}
 
-	// Build locally
 	tmpDir, err := mkdirTemp("", "netcup-kube")
 	if err != nil {
 		return fmt.Errorf("failed to create temp dir: %w", err)
 	}
 	defer func() { _ = removeAll(tmpDir) }()
 
-	out := filepath.Join(tmpDir, "netcup-kube")
-	fmt.Printf("[local] Building netcup-kube for linux/%s\n", goarch)
-
-	if err := localGoBuild(projectRoot, out, goarch); err != nil {
-		return fmt.Errorf("build failed: %w", err)
-	}
-
-	remoteBin := cfg.GetRemoteBinPath()
-	remoteBinDir := filepath.Dir(remoteBin)
-
-	fmt.Printf("[local] Uploading %s to %s@%s:%s\n", out, cfg.User, cfg.Host, remoteBin)
+	key := buildCacheKey(projectRoot, goarch)
+	remoteBinDir := cfg.GetRemoteBinDir()
 
 	// Create remote bin directory
 	if err := client.Execute("install", []string{"-d", "-m", "0755", remoteBinDir}, false); err != nil {
 		return fmt.Errorf("failed to create remote bin directory: %w", err)
 	}
 
-	// Upload the binary
-	if err := client.Upload(out, remoteBin); err != nil {
-		return fmt.Errorf("upload failed: %w", err)
+	manifest := BinManifest{
+		Version:  localVersion(projectRoot),
+		GOARCH:   goarch,
+		BuiltAt:  time.Now().UTC().Format(time.RFC3339),
+		Binaries: make([]BinEntry, 0, len(RemoteBinaries)),
 	}
 
-	// Make it executable
-	if err := client.Execute("chmod", []string{"+x", remoteBin}, false); err != nil {
-		return fmt.Errorf("chmod failed: %w", err)
+	for _, name := range RemoteBinaries {
+		out, err := buildBinary(projectRoot, tmpDir, name, goarch, key, opts.NoCache)
+		if err != nil {
+			return err
+		}
+		sum, err := fileSHA256(out)
+		if err != nil {
+			return fmt.Errorf("failed to checksum %s: %w", name, err)
+		}
+		manifest.Binaries = append(manifest.Binaries, BinEntry{Name: name, SHA256: sum})
+
+		remoteBin := path.Join(remoteBinDir, name)
+
+		// Skip the upload when the remote binary is already identical
+		if !opts.NoCache && sum == remoteSHA256(client, remoteBin) {
+			fmt.Printf("[local] Remote %s is up to date (sha256 %s): %s\n", name, sum[:12], remoteBin)
+			continue
+		}
+
+		fmt.Printf("[local] Uploading %s to %s@%s:%s\n", out, cfg.User, cfg.Host, remoteBin)
+
+		// Upload the binary
+		if err := client.Upload(out, remoteBin); err != nil {
+			return fmt.Errorf("upload failed: %w", err)
+		}
+
+		// Make it executable
+		if err := client.Execute("chmod", []string{"+x", remoteBin}, false); err != nil {
+			return fmt.Errorf("chmod failed: %w", err)
+		}
 	}
 
-	fmt.Printf("[local] Done. Remote CLI: %s\n", remoteBin)
+	if err := uploadBinManifest(client, tmpDir, remoteBinDir, manifest); err != nil {
+		return err
+	}
+
+	fmt.Printf("[local] Done. Remote CLI: %s\n", cfg.GetRemoteBinPath())
 	return nil
 }
 
+// buildBinary returns a local build of cmd/<name> for goarch, reusing the build
+// cache when key is set.
+func buildBinary(projectRoot, tmpDir, name, goarch, key string, noCache bool) (string, error) {
+	var cachePath string
+	if key != "" {
+		if p, err := cachedBinaryPath(key, name); err == nil {
+			cachePath = p
+			if !noCache && fileExists(p) {
+				fmt.Printf("[local] Using cached %s build for %s\n", name, key)
+				return p, nil
+			}
+		}
+	}
+
+	out := filepath.Join(tmpDir, name)
+	fmt.Printf("[local] Building %s for linux/%s\n", name, goarch)
+	if err := localGoBuild(projectRoot, out, goarch); err != nil {
+		return "", fmt.Errorf("build failed: %w", err)
+	}
+	if cachePath != "" {
+		storeCachedBinary(out, cachePath)
+	}
+	return out, nil
+}
+
 // remoteDetectGoarch detects the remote architecture
 func remoteDetectGoarch(client Client) (string, error) {
 	output, err := client.OutputCommand("uname", []string{"-m"})
@@ -0,0 +1,113 @@
+package remote
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultRemoteGoVersion is installed on the remote host when go.mod does not
+// pin a usable version
+const defaultRemoteGoVersion = "1.23.4"
+
+var goDirectiveRe = regexp.MustCompile(`(?m)^go\s+(\d+\.\d+(?:\.\d+)?)\s*$`)
+
+// remoteGoVersion returns the Go version to install on the remote host, derived
+// from the go directive in projectRoot/go.mod. A bare "1.N" becomes "1.N.0".
+func remoteGoVersion(projectRoot string) string {
+	data, err := os.ReadFile(filepath.Join(projectRoot, "go.mod"))
+	if err != nil {
+		return defaultRemoteGoVersion
+	}
+	m := goDirectiveRe.FindSubmatch(data)
+	if m == nil {
+		return defaultRemoteGoVersion
+	}
+	version := string(m[1])
+	if strings.Count(version, ".") == 1 {
+		version += ".0"
+	}
+	return version
+}
+
+// remoteGoBuild builds netcup-kube on the remote host from the remote repo
+// checkout, installing Go from the official tarball into ~/.local/go if no
+// suitable toolchain is present. The binaries and manifest end up at the same
+// paths and modes as an uploaded local build.
+func remoteGoBuild(client Client, cfg *Config, projectRoot string) error {
+	goarch, err := remoteDetectGoarch(client)
+	if err != nil {
+		return err
+	}
+
+	version := remoteGoVersion(projectRoot)
+	fmt.Printf("[local] No local Go toolchain; building %s on %s (linux/%s, go%s)\n",
+		strings.Join(RemoteBinaries, ", "), cfg.Host, goarch, version)
+
+	script := `set -euo pipefail
+repo="${1:?repo dir required}"
+bin_dir="${2:?bin dir required}"
+version="${3:?go version required}"
+goarch="${4:?goarch required}"
+shift 4
+
+go_bin=""
+if command -v go >/dev/null 2>&1; then
+  go_bin="$(command -v go)"
+elif [[ -x "${HOME}/.local/go/bin/go" ]]; then
+  go_bin="${HOME}/.local/go/bin/go"
+elif [[ -x /usr/local/go/bin/go ]]; then
+  go_bin=/usr/local/go/bin/go
+fi
+
+if [[ -n "${go_bin}" ]]; then
+  # GOTOOLCHAIN=auto lets an older toolchain fetch the version go.mod requires
+  export GOTOOLCHAIN=auto
+else
+  echo "[remote] installing go${version} (linux/${goarch}) into ${HOME}/.local/go"
+  tarball="go${version}.linux-${goarch}.tar.gz"
+  tmp="$(mktemp -d)"
+  trap 'rm -rf "${tmp}"' EXIT
+  curl -fsSL -o "${tmp}/${tarball}" "https://go.dev/dl/${tarball}"
+  rm -rf "${HOME}/.local/go"
+  mkdir -p "${HOME}/.local"
+  tar -C "${HOME}/.local" -xzf "${tmp}/${tarball}"
+  go_bin="${HOME}/.local/go/bin/go"
+fi
+
+cd "${repo}"
+install -d -m 0755 "${bin_dir}"
+entries=""
+for name in "$@"; do
+  out="${bin_dir}/${name}"
+  echo "[remote] building ${name} with $("${go_bin}" version)"
+  CGO_ENABLED=0 GOOS=linux GOARCH="${goarch}" "${go_bin}" build -o "${out}.tmp" "./cmd/${name}"
+  chmod +x "${out}.tmp"
+  mv -f "${out}.tmp" "${out}"
+  sum="$(sha256sum "${out}" | cut -d' ' -f1)"
+  entries="${entries:+${entries},}
+    {\"name\": \"${name}\", \"sha256\": \"${sum}\"}"
+done
+
+src_version="$(git describe --tags --always --dirty 2>/dev/null || echo unknown)"
+cat >"${bin_dir}/` + BinManifestName + `" <<MANIFEST
+{
+  "version": "${src_version}",
+  "goarch": "${goarch}",
+  "built_at": "$(date -u +%Y-%m-%dT%H:%M:%SZ)",
+  "binaries": [${entries}
+  ]
+}
+MANIFEST
+`
+
+	args := append([]string{cfg.GetRemoteRepoDir(), cfg.GetRemoteBinDir(), version, goarch}, RemoteBinaries...)
+	if err := client.ExecuteScript(script, args); err != nil {
+		return fmt.Errorf("remote build failed: %w", err)
+	}
+
+	fmt.Printf("[local] Done. Remote CLI: %s\n", cfg.GetRemoteBinPath())
+	return nil
+}
@@ -0,0 +1,157 @@
+package remote
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// resilientSessionPrefix prefixes tmux sessions started by `remote run --resilient`
+const resilientSessionPrefix = "netcup-kube-"
+
+// AttachOptions holds options for the attach command
+type AttachOptions struct {
+	// Session is the tmux session to attach to (default: the only running one)
+	Session string
+	// Mosh attaches via mosh instead of ssh, for roaming or lossy links
+	Mosh bool
+}
+
+// ResilientSessionName returns the tmux session name used for a remote run
+func ResilientSessionName(args []string) string {
+	name := "run"
+	if len(args) > 0 {
+		name = args[0]
+	}
+	var b strings.Builder
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return resilientSessionPrefix + b.String()
+}
+
+// resilientStatusFile returns where a resilient session stores the exit
+// status of its command, in the home directory of the remote user
+func resilientStatusFile(cfg *Config, session string) string {
+	return path.Join(path.Dir(cfg.GetRemoteRepoDir()), "."+session+".rc")
+}
+
+// resilientCommand wraps a remote command string so it runs inside a tmux
+// session that survives a dropped connection. The exit status is written to
+// statusFile, since the tmux client exits 0 whatever the command returned; a
+// detached session also signals the wait-for channel named after the session.
+// The session stays open after the command finishes so its result can still
+// be seen after re-attaching.
+func resilientCommand(session, statusFile, cmdString string, detached bool) string {
+	wrapped := "rm -f " + shellEscape(statusFile) + "; " + cmdString + `; rc=$?; echo "${rc}" > ` + shellEscape(statusFile) + "; "
+	if detached {
+		wrapped += "tmux wait-for -S " + shellEscape(session) + "; "
+	}
+	wrapped += `echo; echo "[remote] netcup-kube finished (exit ${rc}). Press Enter to close this session."; read -r _; exit ${rc}`
+	if detached {
+		return fmt.Sprintf("tmux new-session -d -s %s %s", shellEscape(session), shellEscape(wrapped))
+	}
+	return fmt.Sprintf("tmux new-session -s %s %s", shellEscape(session), shellEscape(wrapped))
+}
+
+// resilientSessionRunning reports whether the tmux session exists on the host
+func resilientSessionRunning(client Client, session string) bool {
+	return client.Execute("tmux", []string{"has-session", "-t", session}, false) == nil
+}
+
+// resilientResult reads back the exit status of a resilient session once the
+// tmux client returned. Without a status file the command is still running
+// (the client detached), which is not a failure.
+func resilientResult(client Client, cfg *Config, session string) error {
+	statusFile := resilientStatusFile(cfg, session)
+	out, err := client.OutputCommand("cat", []string{statusFile})
+	if err != nil {
+		fmt.Printf("[local] %s is still running; rejoin with: netcup-kube remote attach --session %s\n", session, session)
+		return nil
+	}
+	if err := client.Execute("rm", []string{"-f", statusFile}, false); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to remove %s: %v\n", statusFile, err)
+	}
+	if rc := strings.TrimSpace(string(out)); rc != "0" {
+		return fmt.Errorf("netcup-kube in session %s failed (exit %s)", session, rc)
+	}
+	return nil
+}
+
+// ensureRemoteTmux checks that tmux is available on the remote host
+func ensureRemoteTmux(client Client, cfg *Config) error {
+	if _, err := client.OutputCommand("tmux", []string{"-V"}); err != nil {
+		return fmt.Errorf(`tmux is required for --resilient but was not found on %s@%s
+Install it on the host:
+  sudo apt-get install -y tmux`, cfg.User, cfg.Host)
+	}
+	return nil
+}
+
+// remoteSessions lists the resilient tmux sessions running on the remote host
+func remoteSessions(client Client) []string {
+	// The format is quoted because the remote shell treats a leading # as a comment
+	out, err := client.OutputCommand("tmux", []string{"list-sessions", "-F", "'#{session_name}'"})
+	if err != nil {
+		return nil
+	}
+	var sessions []string
+	for _, line := range strings.Split(string(out), "\n") {
+		name := strings.TrimSpace(line)
+		if strings.HasPrefix(name, resilientSessionPrefix) {
+			sessions = append(sessions, name)
+		}
+	}
+	return sessions
+}
+
+// Attach rejoins a tmux session started by `remote run --resilient`
+func Attach(cfg *Config, opts AttachOptions) error {
+	client, err := WrapClient(NewSSHClient(cfg.Host, cfg.User))
+	if err != nil {
+		return err
+	}
+	return attachWithClient(client, cfg, opts)
+}
+
+func attachWithClient(client Client, cfg *Config, opts AttachOptions) error {
+	session := opts.Session
+	if session == "" {
+		sessions := remoteSessions(client)
+		switch len(sessions) {
+		case 0:
+			return fmt.Errorf("no resilient sessions running on %s@%s (start one with 'netcup-kube remote run --resilient ...')", cfg.User, cfg.Host)
+		case 1:
+			session = sessions[0]
+		default:
+			return fmt.Errorf("multiple sessions running on %s@%s, choose one with --session: %s",
+				cfg.User, cfg.Host, strings.Join(sessions, ", "))
+		}
+	}
+
+	fmt.Printf("[local] Attaching to %s on %s@%s (detach with Ctrl-b d)\n", session, cfg.User, cfg.Host)
+
+	if opts.Mosh {
+		if _, err := lookPath("mosh"); err != nil {
+			return fmt.Errorf("mosh not found in PATH (install mosh locally and on the host, or omit --mosh)")
+		}
+		cmd := execCommand("mosh", fmt.Sprintf("%s@%s", cfg.User, cfg.Host), "--", "tmux", "attach-session", "-t", session)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+		return resilientResult(client, cfg, session)
+	}
+
+	if err := client.RunCommandString("tmux attach-session -t "+shellEscape(session), true); err != nil {
+		return err
+	}
+	return resilientResult(client, cfg, session)
+}
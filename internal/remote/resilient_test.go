@@ -0,0 +1,142 @@
+package remote
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResilientSessionName(t *testing.T) {
+	tests := map[string][]string{
+		"netcup-kube-bootstrap": {"bootstrap"},
+		"netcup-kube-install":   {"install", "redis"},
+		"netcup-kube-run":       nil,
+		"netcup-kube-a-b":       {"a/b"},
+	}
+	for want, args := range tests {
+		if got := ResilientSessionName(args); got != want {
+			t.Errorf("ResilientSessionName(%v) = %q, want %q", args, got, want)
+		}
+	}
+}
+
+func TestRunWithClient_Resilient(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "x.env")
+	if err := os.WriteFile(envFile, []byte("A=B\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewConfig()
+	cfg.Host = "example.com"
+	cfg.User = "ops"
+	statusKey := "cat /home/ops/.netcup-kube-bootstrap.rc"
+	noSession := map[string]error{"tmux has-session -t netcup-kube-bootstrap": errors.New("exit status 1")}
+
+	fc := &fakeClient{
+		output:       map[string][]byte{"tmux -V": []byte("tmux 3.3a\n"), statusKey: []byte("0\n")},
+		execErrByKey: noSession,
+	}
+	opts := RunOptions{ForceTTY: true, EnvFile: envFile, Args: []string{"bootstrap"}, Resilient: true}
+	if err := runWithClient(fc, cfg, opts); err != nil {
+		t.Fatalf("runWithClient error: %v", err)
+	}
+	if len(fc.runCalls) != 1 {
+		t.Fatalf("expected 1 run call, got %d", len(fc.runCalls))
+	}
+	got := fc.runCalls[0].cmdString
+	if !strings.HasPrefix(got, "tmux new-session -s 'netcup-kube-bootstrap' ") || !strings.Contains(got, `> '\''/home/ops/.netcup-kube-bootstrap.rc'\''`) {
+		t.Fatalf("expected attached tmux session writing the status file, got %q", got)
+	}
+	// the env file must survive until the detached runner has sourced it
+	for _, c := range fc.execCalls {
+		if c.command == "sudo" && len(c.args) > 0 && c.args[0] == "rm" {
+			t.Fatalf("unexpected local cleanup of env file in resilient mode: %#v", c)
+		}
+	}
+	if last := fc.execCalls[len(fc.execCalls)-1]; last.command != "rm" || last.args[1] != "/home/ops/.netcup-kube-bootstrap.rc" {
+		t.Fatalf("status file not removed after reading it: %#v", last)
+	}
+
+	// --no-tty starts the session detached, waits for it and reports a failure
+	fc = &fakeClient{
+		output:       map[string][]byte{"tmux -V": []byte("tmux 3.3a\n"), statusKey: []byte("2\n")},
+		execErrByKey: noSession,
+	}
+	opts.ForceTTY = false
+	opts.EnvFile = ""
+	if err := runWithClient(fc, cfg, opts); err == nil || !strings.Contains(err.Error(), "exit 2") {
+		t.Fatalf("expected the remote exit status, got %v", err)
+	}
+	if got := fc.runCalls[0].cmdString; !strings.HasPrefix(got, "tmux new-session -d -s ") || !strings.Contains(got, "tmux wait-for -S") {
+		t.Fatalf("expected detached tmux session, got %q", got)
+	}
+	if len(fc.runCalls) != 2 || fc.runCalls[1].cmdString != "tmux wait-for 'netcup-kube-bootstrap'" {
+		t.Fatalf("expected to wait for the session, got %#v", fc.runCalls)
+	}
+
+	// without a status file the command is still running (detached client)
+	fc = &fakeClient{output: map[string][]byte{"tmux -V": []byte("tmux 3.3a\n")}, execErrByKey: noSession}
+	opts.ForceTTY = true
+	if err := runWithClient(fc, cfg, opts); err != nil {
+		t.Fatalf("detached client: %v", err)
+	}
+
+	// a running session is attached to, without uploading the env file
+	fc = &fakeClient{output: map[string][]byte{"tmux -V": []byte("tmux 3.3a\n")}}
+	opts.EnvFile = envFile
+	if err := runWithClient(fc, cfg, opts); err != nil {
+		t.Fatalf("runWithClient error: %v", err)
+	}
+	if len(fc.uploads) != 0 || len(fc.runCalls) != 1 || fc.runCalls[0].cmdString != "tmux attach-session -t 'netcup-kube-bootstrap'" {
+		t.Fatalf("expected an attach without upload, got uploads %#v, runs %#v", fc.uploads, fc.runCalls)
+	}
+	opts.ForceTTY = false
+	if err := runWithClient(fc, cfg, opts); err == nil || !strings.Contains(err.Error(), "already running") {
+		t.Fatalf("expected already running error, got %v", err)
+	}
+
+	// missing tmux
+	fc = &fakeClient{}
+	if err := runWithClient(fc, cfg, opts); err == nil || !strings.Contains(err.Error(), "tmux") {
+		t.Fatalf("expected tmux error, got %v", err)
+	}
+}
+
+func TestAttachWithClient(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Host = "example.com"
+	cfg.User = "ops"
+	listKey := "tmux list-sessions -F '#{session_name}'"
+
+	// no sessions
+	if err := attachWithClient(&fakeClient{}, cfg, AttachOptions{}); err == nil {
+		t.Fatalf("expected error without sessions")
+	}
+
+	// single session is picked automatically
+	fc := &fakeClient{output: map[string][]byte{listKey: []byte("main\nnetcup-kube-bootstrap\n")}}
+	if err := attachWithClient(fc, cfg, AttachOptions{}); err != nil {
+		t.Fatalf("attachWithClient error: %v", err)
+	}
+	if got := fc.runCalls[0].cmdString; got != "tmux attach-session -t 'netcup-kube-bootstrap'" || !fc.runCalls[0].forceTTY {
+		t.Fatalf("unexpected attach call: %#v", fc.runCalls[0])
+	}
+
+	// multiple sessions require --session
+	fc = &fakeClient{output: map[string][]byte{listKey: []byte("netcup-kube-bootstrap\nnetcup-kube-install\n")}}
+	if err := attachWithClient(fc, cfg, AttachOptions{}); err == nil || !strings.Contains(err.Error(), "--session") {
+		t.Fatalf("expected --session error, got %v", err)
+	}
+	if err := attachWithClient(fc, cfg, AttachOptions{Session: "netcup-kube-install"}); err != nil {
+		t.Fatalf("attachWithClient error: %v", err)
+	}
+
+	// mosh requires a local mosh binary
+	oldLook := lookPath
+	t.Cleanup(func() { lookPath = oldLook })
+	lookPath = func(_ string) (string, error) { return "", errors.New("not found") }
+	if err := attachWithClient(fc, cfg, AttachOptions{Session: "netcup-kube-install", Mosh: true}); err == nil {
+		t.Fatalf("expected error when mosh is missing")
+	}
+}
@@ -0,0 +1,17 @@
+package remote
+
+import (
+	"fmt"
+
+	"github.com/mfittko/netcup-kube/internal/guardrail"
+)
+
+// ProbeResources measures free disk, available memory, and free inodes on the
+// remote host.
+func ProbeResources(client Client) (guardrail.Usage, error) {
+	out, err := client.OutputCommand(guardrail.ProbeScript, nil)
+	if err != nil {
+		return guardrail.Usage{}, fmt.Errorf("failed to probe node resources: %w", err)
+	}
+	return guardrail.ParseProbe(out)
+}
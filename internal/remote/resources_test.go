@@ -0,0 +1,24 @@
+package remote
+
+import (
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/guardrail"
+)
+
+func TestProbeResources(t *testing.T) {
+	fc := &fakeClient{output: map[string][]byte{
+		guardrail.ProbeScript + " ": []byte("path /var/lib/rancher\ndisk_free 4096\nmem_available 8192\n"),
+	}}
+	u, err := ProbeResources(fc)
+	if err != nil {
+		t.Fatalf("ProbeResources() error: %v", err)
+	}
+	if u.DiskFree != 4096 || u.MemAvailable != 8192 {
+		t.Errorf("ProbeResources() = %+v", u)
+	}
+
+	if _, err := ProbeResources(&fakeClient{}); err == nil {
+		t.Error("ProbeResources() expected error when the probe fails")
+	}
+}
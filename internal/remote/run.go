@@ -9,7 +9,13 @@ import (
 // Run executes a netcup-kube command on the remote host
 func Run(cfg *Config, opts RunOptions) error {
 	// Create user SSH client
-	client := NewSSHClient(cfg.Host, cfg.User)
+	sshClient := NewSSHClient(cfg.Host, cfg.User)
+	sshClient.Idle = opts.Idle
+	sshClient.Context = opts.Context
+	client, err := WrapClient(sshClient)
+	if err != nil {
+		return err
+	}
 
 	return runWithClient(client, cfg, opts)
 }
@@ -61,6 +67,25 @@ Build/upload it first:
   netcup-kube remote build`, cfg.User, cfg.Host, remoteBin)
 	}
 
+	// A resilient run whose session is still running is rejoined instead:
+	// starting it again would drop the new command and leave its env file
+	// behind
+	var session string
+	if opts.Resilient {
+		if err := ensureRemoteTmux(client, cfg); err != nil {
+			return err
+		}
+		session = ResilientSessionName(opts.Args)
+		if resilientSessionRunning(client, session) {
+			if !opts.ForceTTY {
+				return fmt.Errorf("session %s is already running on %s@%s; rejoin with: netcup-kube remote attach --session %s",
+					session, cfg.User, cfg.Host, session)
+			}
+			fmt.Printf("[local] %s is already running; attaching instead of starting netcup-kube %s\n", session, joinArgs(opts.Args))
+			return attachWithClient(client, cfg, AttachOptions{Session: session})
+		}
+	}
+
 	// Upload env file if specified
 	remoteEnv := "__NONE__"
 	if opts.EnvFile != "" {
@@ -73,7 +98,11 @@ Build/upload it first:
 		if err := client.Upload(opts.EnvFile, remoteEnv); err != nil {
 			return fmt.Errorf("failed to upload env file: %w", err)
 		}
-		defer cleanupRemoteEnv(client, remoteEnv, opts.ForceTTY)
+		// A resilient run may outlive this process; the runner removes the
+		// env file itself once it has been sourced.
+		if !opts.Resilient {
+			defer cleanupRemoteEnv(client, remoteEnv, opts.ForceTTY)
+		}
 	}
 
 	// Build the remote runner script
@@ -87,6 +116,7 @@ if [[ "${env_file}" != "__NONE__" && -n "${env_file}" ]]; then
   # shellcheck disable=SC1090
   source "${env_file}"
   set +a
+  rm -f "${env_file}" || true
 fi
 
 exec "${bin}" "$@"
@@ -110,10 +140,24 @@ exec "${bin}" "$@"
 	// Build the full command string
 	cmdString := strings.Join(cmdParts, " ")
 
+	if opts.Resilient {
+		cmdString = resilientCommand(session, resilientStatusFile(cfg, session), cmdString, !opts.ForceTTY)
+		fmt.Printf("[local] Running in tmux session %q; if the connection drops, rejoin with: netcup-kube remote attach\n", session)
+	}
+
 	fmt.Printf("[local] Running on %s@%s: netcup-kube %s\n", cfg.User, cfg.Host,
 		joinArgs(opts.Args))
 
-	return client.RunCommandString(cmdString, opts.ForceTTY)
+	if err := client.RunCommandString(cmdString, opts.ForceTTY); err != nil || !opts.Resilient {
+		return err
+	}
+	if !opts.ForceTTY {
+		// The detached session returned at once; wait for its command
+		if err := client.RunCommandString("tmux wait-for "+shellEscape(session), false); err != nil {
+			return fmt.Errorf("lost %s while waiting for it (it keeps running; rejoin with: netcup-kube remote attach --session %s): %w", session, session, err)
+		}
+	}
+	return resilientResult(client, cfg, session)
 }
 
 // ensureUserAccess checks if we can SSH as the user
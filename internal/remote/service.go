@@ -0,0 +1,133 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NodeServices are the systemd services of a node that netcup-kube manages.
+// k3s-agent is the k3s service of a worker.
+var NodeServices = []string{"k3s", "k3s-agent", "caddy", "ufw"}
+
+// serviceProperties are the systemctl show properties read into ServiceStatus
+var serviceProperties = []string{"Id", "LoadState", "ActiveState", "SubState", "UnitFileState", "MainPID", "ActiveEnterTimestamp", "NRestarts"}
+
+// ServiceStatus is the systemd state of a node service
+type ServiceStatus struct {
+	Name        string `json:"name"`
+	Unit        string `json:"unit"`
+	Installed   bool   `json:"installed"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	Enabled     string `json:"enabled"`
+	MainPID     int    `json:"main_pid,omitempty"`
+	Since       string `json:"since,omitempty"`
+	Restarts    int    `json:"restarts"`
+}
+
+// Active reports whether the service is running (or, for oneshot services
+// like ufw, has run successfully)
+func (s ServiceStatus) Active() bool {
+	return s.ActiveState == "active"
+}
+
+// ServiceLogOptions selects the journal lines of ServiceLogs
+type ServiceLogOptions struct {
+	Lines  int
+	Since  string
+	Follow bool
+	// JSON prints journal entries as JSON lines (journalctl -o json)
+	JSON bool
+}
+
+// ServiceUnit validates a node service name and returns its systemd unit
+func ServiceUnit(name string) (string, error) {
+	name = strings.TrimSuffix(name, ".service")
+	for _, svc := range NodeServices {
+		if name == svc {
+			return svc + ".service", nil
+		}
+	}
+	return "", fmt.Errorf("unknown service %q (supported: %s)", name, strings.Join(NodeServices, ", "))
+}
+
+// GetServiceStatus reads the systemd state of a node service
+func GetServiceStatus(client Client, name string) (ServiceStatus, error) {
+	unit, err := ServiceUnit(name)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+	out, err := client.OutputCommand("systemctl", []string{"show", "--no-pager", "-p", strings.Join(serviceProperties, ","), unit})
+	if err != nil {
+		return ServiceStatus{}, fmt.Errorf("failed to read the status of %s: %w", unit, err)
+	}
+	status := ParseServiceStatus(out)
+	status.Name = strings.TrimSuffix(unit, ".service")
+	status.Unit = unit
+	return status, nil
+}
+
+// ParseServiceStatus parses the key=value output of systemctl show
+func ParseServiceStatus(out []byte) ServiceStatus {
+	props := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			props[key] = strings.TrimSpace(value)
+		}
+	}
+	status := ServiceStatus{
+		Unit:        props["Id"],
+		Installed:   props["LoadState"] == "loaded",
+		ActiveState: props["ActiveState"],
+		SubState:    props["SubState"],
+		Enabled:     props["UnitFileState"],
+		Since:       props["ActiveEnterTimestamp"],
+	}
+	status.MainPID, _ = strconv.Atoi(props["MainPID"])
+	status.Restarts, _ = strconv.Atoi(props["NRestarts"])
+	if status.ActiveState != "active" {
+		status.Since = ""
+	}
+	return status
+}
+
+// RestartService restarts a node service and returns its state afterwards
+func RestartService(client Client, name string) (ServiceStatus, error) {
+	unit, err := ServiceUnit(name)
+	if err != nil {
+		return ServiceStatus{}, err
+	}
+	if err := client.Execute("sudo", []string{"-n", "systemctl", "restart", unit}, false); err != nil {
+		return ServiceStatus{}, fmt.Errorf("failed to restart %s: %w", unit, err)
+	}
+	return GetServiceStatus(client, name)
+}
+
+// ServiceLogs streams the journal of a node service to stdout
+func ServiceLogs(client Client, name string, opts ServiceLogOptions) error {
+	unit, err := ServiceUnit(name)
+	if err != nil {
+		return err
+	}
+	args := []string{"-n", "journalctl", "-u", unit, "--no-pager"}
+	if opts.Lines > 0 {
+		args = append(args, "-n", strconv.Itoa(opts.Lines))
+	}
+	if opts.Since != "" {
+		args = append(args, "--since", opts.Since)
+	}
+	if opts.JSON {
+		args = append(args, "-o", "json")
+	}
+	if opts.Follow {
+		args = append(args, "-f")
+	}
+	if err := client.Execute("sudo", args, false); err != nil {
+		return fmt.Errorf("failed to read the journal of %s: %w", unit, err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package remote
+
+import (
+	"strings"
+	"testing"
+)
+
+const showK3s = `Id=k3s.service
+LoadState=loaded
+ActiveState=active
+SubState=running
+UnitFileState=enabled
+MainPID=812
+ActiveEnterTimestamp=Mon 2026-10-12 08:14:03 UTC
+NRestarts=2
+`
+
+func showKey(unit string) string {
+	return "systemctl show --no-pager -p " + strings.Join(serviceProperties, ",") + " " + unit
+}
+
+func TestGetServiceStatus(t *testing.T) {
+	fc := &fakeClient{output: map[string][]byte{showKey("k3s.service"): []byte(showK3s)}}
+	got, err := GetServiceStatus(fc, "k3s")
+	if err != nil {
+		t.Fatalf("GetServiceStatus() error: %v", err)
+	}
+	want := ServiceStatus{Name: "k3s", Unit: "k3s.service", Installed: true, ActiveState: "active", SubState: "running",
+		Enabled: "enabled", MainPID: 812, Since: "Mon 2026-10-12 08:14:03 UTC", Restarts: 2}
+	if got != want {
+		t.Errorf("GetServiceStatus() = %+v, want %+v", got, want)
+	}
+
+	if _, err := GetServiceStatus(fc, "sshd"); err == nil || !strings.Contains(err.Error(), "unknown service") {
+		t.Errorf("GetServiceStatus(sshd) error = %v, want unknown service", err)
+	}
+}
+
+func TestParseServiceStatusNotInstalled(t *testing.T) {
+	got := ParseServiceStatus([]byte("Id=caddy.service\nLoadState=not-found\nActiveState=inactive\nSubState=dead\nMainPID=0\nActiveEnterTimestamp=\nNRestarts=0\n"))
+	if got.Installed || got.Active() || got.MainPID != 0 || got.Since != "" {
+		t.Errorf("ParseServiceStatus() = %+v, want an inactive service that is not installed", got)
+	}
+}
+
+func TestRestartService(t *testing.T) {
+	fc := &fakeClient{output: map[string][]byte{showKey("caddy.service"): []byte(strings.ReplaceAll(showK3s, "k3s", "caddy"))}}
+	status, err := RestartService(fc, "caddy.service")
+	if err != nil {
+		t.Fatalf("RestartService() error: %v", err)
+	}
+	if !status.Active() {
+		t.Errorf("RestartService() status = %+v, want active", status)
+	}
+	if len(fc.execCalls) != 1 || strings.Join(fc.execCalls[0].args, " ") != "-n systemctl restart caddy.service" {
+		t.Errorf("exec calls = %+v", fc.execCalls)
+	}
+}
+
+func TestServiceLogs(t *testing.T) {
+	fc := &fakeClient{}
+	if err := ServiceLogs(fc, "k3s-agent", ServiceLogOptions{Lines: 50, Since: "1 hour ago", Follow: true, JSON: true}); err != nil {
+		t.Fatalf("ServiceLogs() error: %v", err)
+	}
+	want := "sudo -n journalctl -u k3s-agent.service --no-pager -n 50 --since 1 hour ago -o json -f"
+	if len(fc.execCalls) != 1 {
+		t.Fatalf("exec calls = %+v", fc.execCalls)
+	}
+	if got := fc.execCalls[0].command + " " + strings.Join(fc.execCalls[0].args, " "); got != want {
+		t.Errorf("ServiceLogs() ran %q, want %q", got, want)
+	}
+}
@@ -11,7 +11,10 @@ func Smoke(cfg *Config, opts GitOptions, projectRoot string) error {
 		return fmt.Errorf("missing host")
 	}
 
-	client := NewSSHClient(cfg.Host, cfg.User)
+	client, err := WrapClient(NewSSHClient(cfg.Host, cfg.User))
+	if err != nil {
+		return err
+	}
 	return smokeWithClient(client, cfg, opts, projectRoot)
 }
 
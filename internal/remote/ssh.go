@@ -1,11 +1,16 @@
 package remote
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/telemetry"
 )
 
 // SSHClient handles SSH operations to remote hosts
@@ -13,6 +18,13 @@ type SSHClient struct {
 	Host         string
 	User         string
 	IdentityFile string
+	// Idle enables heartbeats and idle detection for RunCommandString
+	Idle IdleOptions
+	// Transfer configures compression and rate limiting of Upload and Download
+	Transfer TransferOptions
+	// Context bounds the ssh and scp processes: when it is canceled (Ctrl-C)
+	// they get SIGTERM and are killed after a grace period. nil means no bound.
+	Context context.Context
 }
 
 // NewSSHClient creates a new SSH client.
@@ -20,9 +32,11 @@ type SSHClient struct {
 // NOTE: We pick a *private key* file here (no ".pub"), because `ssh -i` needs the private key.
 // Public keys are used separately for provisioning (authorized_keys), see `Config.GetPubKey()`.
 func NewSSHClient(host, user string) *SSHClient {
-	// Pick a public key (prefer ed25519)
+	// Pick a private key: SSH_IDENTITY_FILE (e.g. set by a --cluster entry),
+	// else the default keys (prefer ed25519)
 	identityFile := ""
 	for _, cand := range []string{
+		expandTilde(strings.TrimSpace(os.Getenv("SSH_IDENTITY_FILE"))),
 		filepath.Join(os.Getenv("HOME"), ".ssh", "id_ed25519"),
 		filepath.Join(os.Getenv("HOME"), ".ssh", "id_rsa"),
 	} {
@@ -32,10 +46,16 @@ func NewSSHClient(host, user string) *SSHClient {
 		}
 	}
 
+	transfer, err := TransferOptionsFromEnv()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v; transferring files uncompressed and unlimited\n", err)
+	}
+
 	return &SSHClient{
 		Host:         host,
 		User:         user,
 		IdentityFile: identityFile,
+		Transfer:     transfer,
 	}
 }
 
@@ -65,12 +85,13 @@ func (c *SSHClient) ExecuteWithEnv(command string, args []string, env map[string
 	remoteCmd := c.buildRemoteCommand(command, args, env)
 	sshArgs = append(sshArgs, remoteCmd)
 
-	cmd := execCommand("ssh", sshArgs...)
+	span := c.startSpan("ssh execute", telemetry.String("ssh.command", command))
+	cmd := c.command("ssh", sshArgs...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	return cmd.Run()
+	return span.End(cmd.Run())
 }
 
 // ExecuteScript runs a bash script on the remote host via stdin
@@ -88,16 +109,22 @@ func (c *SSHClient) ExecuteScript(script string, args []string) error {
 
 	sshArgs = append(sshArgs, args...)
 
-	cmd := execCommand("ssh", sshArgs...)
+	span := c.startSpan("ssh script", telemetry.Int("ssh.script_bytes", len(script)))
+	cmd := c.command("ssh", sshArgs...)
 	cmd.Stdin = strings.NewReader(script)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	return cmd.Run()
+	return span.End(cmd.Run())
 }
 
-// Upload copies a local file to the remote host using scp
+// Upload copies a local file to the remote host using scp, or compressed over
+// ssh per the client's TransferOptions
 func (c *SSHClient) Upload(localPath, remotePath string) error {
+	if c.Transfer.compressed() {
+		return c.uploadCompressed(localPath, remotePath)
+	}
+
 	scpArgs := []string{
 		"-o", "StrictHostKeyChecking=no",
 	}
@@ -105,15 +132,17 @@ func (c *SSHClient) Upload(localPath, remotePath string) error {
 	if c.IdentityFile != "" {
 		scpArgs = append(scpArgs, "-i", c.IdentityFile)
 	}
+	scpArgs = append(scpArgs, c.Transfer.scpLimitArgs()...)
 
 	target := fmt.Sprintf("%s@%s:%s", c.User, c.Host, remotePath)
 	scpArgs = append(scpArgs, localPath, target)
 
-	cmd := execCommand("scp", scpArgs...)
+	span := c.startSpan("scp upload", telemetry.String("scp.remote_path", remotePath))
+	cmd := c.command("scp", scpArgs...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 
-	return cmd.Run()
+	return span.End(cmd.Run())
 }
 
 // TestConnection tests if SSH connection works (batch mode)
@@ -130,11 +159,12 @@ func (c *SSHClient) TestConnection() error {
 	target := fmt.Sprintf("%s@%s", c.User, c.Host)
 	sshArgs = append(sshArgs, target, "true")
 
-	cmd := execCommand("ssh", sshArgs...)
+	span := c.startSpan("ssh test-connection")
+	cmd := c.command("ssh", sshArgs...)
 	cmd.Stdout = io.Discard
 	cmd.Stderr = io.Discard
 
-	return cmd.Run()
+	return span.End(cmd.Run())
 }
 
 // RunCommandString executes a raw remote shell command string via ssh.
@@ -154,11 +184,15 @@ func (c *SSHClient) RunCommandString(cmdString string, forceTTY bool) error {
 	target := fmt.Sprintf("%s@%s", c.User, c.Host)
 	sshArgs = append(sshArgs, target, cmdString)
 
-	cmd := execCommand("ssh", sshArgs...)
+	span := c.startSpan("ssh run", telemetry.Bool("ssh.tty", forceTTY))
+	cmd := c.command("ssh", sshArgs...)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	if c.Idle.enabled() {
+		return span.End(runWithIdleWatch(cmd, c.Idle, forceTTY, os.Stderr))
+	}
+	return span.End(cmd.Run())
 }
 
 // OutputCommand runs a remote command via ssh and returns stdout.
@@ -175,8 +209,33 @@ func (c *SSHClient) OutputCommand(command string, args []string) ([]byte, error)
 	sshArgs = append(sshArgs, target, command)
 	sshArgs = append(sshArgs, args...)
 
-	cmd := execCommand("ssh", sshArgs...)
-	return cmd.Output()
+	span := c.startSpan("ssh output")
+	cmd := c.command("ssh", sshArgs...)
+	out, err := cmd.Output()
+	return out, span.End(err)
+}
+
+// startSpan records an SSH/SCP call against this host. Remote command lines
+// are not recorded since they can carry environment values.
+func (c *SSHClient) startSpan(name string, attrs ...telemetry.Attribute) *telemetry.Span {
+	attrs = append([]telemetry.Attribute{telemetry.String("net.peer.name", c.Host), telemetry.String("ssh.user", c.User)}, attrs...)
+	ctx := c.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := telemetry.Start(ctx, name, attrs...)
+	return span
+}
+
+// command builds an ssh/scp process like execCommand, bound to c.Context
+func (c *SSHClient) command(name string, args ...string) *exec.Cmd {
+	cmd := execCommand(name, args...)
+	if c.Context == nil {
+		return cmd
+	}
+	bound := interrupt.Command(c.Context, name)
+	bound.Path, bound.Args, bound.Env, bound.Dir, bound.Err = cmd.Path, cmd.Args, cmd.Env, cmd.Dir, cmd.Err
+	return bound
 }
 
 // buildRemoteCommand constructs a properly escaped remote command
@@ -213,3 +272,11 @@ func shellEscape(s string) string {
 	escaped := strings.ReplaceAll(s, "'", "'\\''")
 	return fmt.Sprintf("'%s'", escaped)
 }
+
+// expandTilde expands a leading ~/ to the home directory
+func expandTilde(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(os.Getenv("HOME"), path[2:])
+	}
+	return path
+}
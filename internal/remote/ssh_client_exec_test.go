@@ -1,9 +1,11 @@
 package remote
 
 import (
+	"context"
 	"os/exec"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestSSHClient_Execute_BuildsSSHArgs(t *testing.T) {
@@ -117,3 +119,23 @@ func TestSSHClient_ExecuteScript_Upload_TestConnection(t *testing.T) {
 		t.Fatalf("expected args captured")
 	}
 }
+
+func TestSSHClient_ContextStopsCommand(t *testing.T) {
+	old := execCommand
+	defer func() { execCommand = old }()
+
+	execCommand = func(_ string, _ ...string) *exec.Cmd {
+		return exec.Command("sleep", "5")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	c := &SSHClient{Host: "example.com", User: "ops", Context: ctx}
+	start := time.Now()
+	if _, err := c.OutputCommand("true", nil); err == nil {
+		t.Fatal("expected error from a canceled command")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("command ran for %s after its context was canceled", elapsed)
+	}
+}
@@ -0,0 +1,330 @@
+package remote
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/telemetry"
+)
+
+// Environment variables configuring the file transfers of an SSHClient
+const (
+	CompressEnv  = "REMOTE_COMPRESS"
+	RateLimitEnv = "REMOTE_RATE_LIMIT"
+)
+
+// Transfer compression algorithms
+const (
+	CompressNone = "none"
+	CompressGzip = "gzip"
+	CompressZstd = "zstd"
+)
+
+// TransferOptions configure how an SSHClient copies files, for metered or
+// slow links
+type TransferOptions struct {
+	// Compression compresses the file in transit: gzip (needs nothing but
+	// gzip on the host) or zstd (needs zstd locally and on the host); empty
+	// or "none" copies it with scp
+	Compression string
+	// RateLimit caps the transfer rate in bytes per second; 0 is unlimited.
+	// With compression it limits the compressed stream.
+	RateLimit int64
+}
+
+func (o TransferOptions) compressed() bool {
+	return o.Compression != "" && o.Compression != CompressNone
+}
+
+// ParseCompression validates a compression algorithm
+func ParseCompression(s string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); v {
+	case "", CompressNone:
+		return "", nil
+	case CompressGzip, CompressZstd:
+		return v, nil
+	}
+	return "", fmt.Errorf("invalid compression %q (expected none, gzip or zstd)", s)
+}
+
+// ParseRate parses a transfer rate in bytes per second with an optional
+// binary suffix: 500K, 1.5M, 2G; empty or 0 is unlimited
+func ParseRate(s string) (int64, error) {
+	raw := strings.TrimSpace(s)
+	if raw == "" {
+		return 0, nil
+	}
+	num := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(raw), "/S"), "B")
+	unit := 1.0
+	switch {
+	case strings.HasSuffix(num, "K"):
+		unit = 1 << 10
+	case strings.HasSuffix(num, "M"):
+		unit = 1 << 20
+	case strings.HasSuffix(num, "G"):
+		unit = 1 << 30
+	}
+	if unit != 1 {
+		num = num[:len(num)-1]
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, fmt.Errorf("invalid rate %q (expected bytes per second like 500K or 2M)", s)
+	}
+	return int64(v * unit), nil
+}
+
+// TransferOptionsFromEnv reads REMOTE_COMPRESS and REMOTE_RATE_LIMIT
+func TransferOptionsFromEnv() (TransferOptions, error) {
+	compression, err := ParseCompression(os.Getenv(CompressEnv))
+	if err != nil {
+		return TransferOptions{}, fmt.Errorf("%s: %w", CompressEnv, err)
+	}
+	rate, err := ParseRate(os.Getenv(RateLimitEnv))
+	if err != nil {
+		return TransferOptions{}, fmt.Errorf("%s: %w", RateLimitEnv, err)
+	}
+	return TransferOptions{Compression: compression, RateLimit: rate}, nil
+}
+
+// scpLimitArgs returns the scp bandwidth limit, which scp takes in Kbit/s
+func (o TransferOptions) scpLimitArgs() []string {
+	if o.RateLimit <= 0 {
+		return nil
+	}
+	kbit := o.RateLimit * 8 / 1000
+	if kbit < 1 {
+		kbit = 1
+	}
+	return []string{"-l", strconv.FormatInt(kbit, 10)}
+}
+
+// sshArgs returns the ssh arguments running cmdString on the host
+func (c *SSHClient) sshArgs(cmdString string) []string {
+	args := []string{"-o", "StrictHostKeyChecking=no"}
+	if c.IdentityFile != "" {
+		args = append(args, "-i", c.IdentityFile)
+	}
+	return append(args, fmt.Sprintf("%s@%s", c.User, c.Host), cmdString)
+}
+
+// decompressCommand and compressCommand are the host side of compressed
+// transfers
+func decompressCommand(compression string) string {
+	if compression == CompressZstd {
+		return "zstd -q -d -c"
+	}
+	return "gzip -d -c"
+}
+
+func compressCommand(compression string) string {
+	if compression == CompressZstd {
+		return "zstd -q -c --"
+	}
+	return "gzip -c --"
+}
+
+// compressStream returns the compressed content of r. zstd runs the local
+// zstd binary, as the standard library has no encoder.
+func compressStream(compression string, r io.Reader) (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	if compression == CompressZstd {
+		if _, err := lookPath("zstd"); err != nil {
+			return nil, fmt.Errorf("zstd compression needs zstd installed locally: %w", err)
+		}
+		cmd := execCommand("zstd", "-q", "-c")
+		cmd.Stdin = r
+		cmd.Stdout = pw
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start zstd: %w", err)
+		}
+		go func() { pw.CloseWithError(cmd.Wait()) }()
+		return pr, nil
+	}
+	go func() {
+		gz := gzip.NewWriter(pw)
+		_, err := io.Copy(gz, r)
+		if closeErr := gz.Close(); err == nil {
+			err = closeErr
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
+// decompressStream writes the decompressed content of r to w
+func decompressStream(compression string, r io.Reader, w io.Writer) error {
+	if compression == CompressZstd {
+		if _, err := lookPath("zstd"); err != nil {
+			return fmt.Errorf("zstd compression needs zstd installed locally: %w", err)
+		}
+		cmd := execCommand("zstd", "-q", "-d", "-c")
+		cmd.Stdin = r
+		cmd.Stdout = w
+		cmd.Stderr = os.Stderr
+		return cmd.Run()
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, gz); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// uploadCompressed streams localPath compressed over ssh and writes it to
+// remotePath through a temp file, keeping the local file mode
+func (c *SSHClient) uploadCompressed(localPath, remotePath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	stream, err := compressStream(c.Transfer.Compression, f)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = stream.Close() }()
+
+	dest := shellEscape(remotePath)
+	script := fmt.Sprintf(`umask 077; tmp=$(mktemp %s) && { %s > "$tmp" && chmod %o "$tmp" && mv -f "$tmp" %s; } || { rc=$?; rm -f "$tmp"; exit $rc; }`,
+		shellEscape(remotePath+".XXXXXX"), decompressCommand(c.Transfer.Compression), info.Mode().Perm(), dest)
+
+	span := c.startSpan("ssh upload", telemetry.String("scp.remote_path", remotePath), telemetry.String("transfer.compression", c.Transfer.Compression))
+	cmd := c.command("ssh", c.sshArgs(script)...)
+	cmd.Stdin = newRateLimitedReader(stream, c.Transfer.RateLimit)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return span.End(cmd.Run())
+}
+
+// Download copies a file from the remote host to localPath, compressed and
+// rate-limited per the client's TransferOptions. The local file is replaced
+// only once the transfer completed.
+func (c *SSHClient) Download(remotePath, localPath string) error {
+	if c.Transfer.compressed() {
+		return c.downloadStream(remotePath, localPath, false)
+	}
+	args := []string{"-o", "StrictHostKeyChecking=no"}
+	if c.IdentityFile != "" {
+		args = append(args, "-i", c.IdentityFile)
+	}
+	args = append(args, c.Transfer.scpLimitArgs()...)
+	args = append(args, fmt.Sprintf("%s@%s:%s", c.User, c.Host, remotePath), localPath)
+
+	span := c.startSpan("scp download", telemetry.String("scp.remote_path", remotePath))
+	cmd := c.command("scp", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return span.End(cmd.Run())
+}
+
+// DownloadSudo is Download for files only root can read, such as backups:
+// the file is read with sudo on the host and streamed over ssh
+func (c *SSHClient) DownloadSudo(remotePath, localPath string) error {
+	return c.downloadStream(remotePath, localPath, true)
+}
+
+// downloadStream reads remotePath over ssh into a temp file next to
+// localPath, which is renamed into place once complete
+func (c *SSHClient) downloadStream(remotePath, localPath string, sudo bool) error {
+	read := "cat --"
+	if c.Transfer.compressed() {
+		read = compressCommand(c.Transfer.Compression)
+	}
+	if sudo {
+		read = "sudo -n " + read
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(localPath), "."+filepath.Base(localPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	span := c.startSpan("ssh download", telemetry.String("scp.remote_path", remotePath), telemetry.String("transfer.compression", c.Transfer.Compression))
+	cmd := c.command("ssh", c.sshArgs(read+" "+shellEscape(remotePath))...)
+	cmd.Stderr = os.Stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		_ = tmp.Close()
+		return span.End(err)
+	}
+	if err := cmd.Start(); err != nil {
+		_ = tmp.Close()
+		return span.End(err)
+	}
+	in := newRateLimitedReader(stdout, c.Transfer.RateLimit)
+	var decodeErr error
+	if c.Transfer.compressed() {
+		decodeErr = decompressStream(c.Transfer.Compression, in, tmp)
+	} else {
+		_, decodeErr = io.Copy(tmp, in)
+	}
+	if decodeErr != nil {
+		// Unblock ssh if decoding stopped early
+		_, _ = io.Copy(io.Discard, stdout)
+	}
+	waitErr := cmd.Wait()
+	closeErr := tmp.Close()
+	switch {
+	case waitErr != nil:
+		return span.End(fmt.Errorf("failed to read %s: %w", remotePath, waitErr))
+	case decodeErr != nil:
+		return span.End(fmt.Errorf("failed to receive %s: %w", remotePath, decodeErr))
+	case closeErr != nil:
+		return span.End(closeErr)
+	}
+	return span.End(os.Rename(tmp.Name(), localPath))
+}
+
+// rateLimitedReader delays reads so no more than rate bytes per second pass
+type rateLimitedReader struct {
+	r     io.Reader
+	rate  int64
+	read  int64
+	start time.Time
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// newRateLimitedReader limits r to rate bytes per second; r itself when rate
+// is 0
+func newRateLimitedReader(r io.Reader, rate int64) io.Reader {
+	if rate <= 0 {
+		return r
+	}
+	return &rateLimitedReader{r: r, rate: rate, now: time.Now, sleep: time.Sleep}
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	if l.start.IsZero() {
+		l.start = l.now()
+	}
+	// Read at most a tenth of a second's worth at once, so the rate stays
+	// smooth instead of bursting a whole buffer
+	if chunk := max(l.rate/10, 1); int64(len(p)) > chunk {
+		p = p[:chunk]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	due := l.start.Add(time.Duration(float64(l.read) / float64(l.rate) * float64(time.Second)))
+	if wait := due.Sub(l.now()); wait > 0 {
+		l.sleep(wait)
+	}
+	return n, err
+}
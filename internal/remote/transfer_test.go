@@ -0,0 +1,335 @@
+package remote
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"1000", 1000, false},
+		{"500K", 500 << 10, false},
+		{"500k", 500 << 10, false},
+		{"1.5M", 3 << 19, false},
+		{"2MB/s", 2 << 20, false},
+		{"1G", 1 << 30, false},
+		{"fast", 0, true},
+		{"-1K", 0, true},
+		{"K", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseCompression(t *testing.T) {
+	for in, want := range map[string]string{"": "", "none": "", "gzip": CompressGzip, " ZSTD ": CompressZstd} {
+		if got, err := ParseCompression(in); err != nil || got != want {
+			t.Errorf("ParseCompression(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseCompression("xz"); err == nil {
+		t.Error("expected an error for xz")
+	}
+}
+
+func TestTransferOptionsFromEnv(t *testing.T) {
+	t.Setenv(CompressEnv, "gzip")
+	t.Setenv(RateLimitEnv, "1M")
+	opts, err := TransferOptionsFromEnv()
+	if err != nil || opts != (TransferOptions{Compression: CompressGzip, RateLimit: 1 << 20}) {
+		t.Fatalf("TransferOptionsFromEnv = %+v, %v", opts, err)
+	}
+
+	t.Setenv(RateLimitEnv, "soon")
+	if _, err := TransferOptionsFromEnv(); err == nil || !strings.Contains(err.Error(), RateLimitEnv) {
+		t.Fatalf("invalid rate = %v, want an error naming %s", err, RateLimitEnv)
+	}
+}
+
+func TestScpLimitArgs(t *testing.T) {
+	for rate, want := range map[int64][]string{
+		0:       nil,
+		100:     {"-l", "1"},
+		500_000: {"-l", "4000"},
+	} {
+		if got := (TransferOptions{RateLimit: rate}).scpLimitArgs(); !slices.Equal(got, want) {
+			t.Errorf("scpLimitArgs(%d) = %v, want %v", rate, got, want)
+		}
+	}
+}
+
+func TestRateLimitedReader(t *testing.T) {
+	clock := time.Unix(0, 0)
+	var slept time.Duration
+	l := &rateLimitedReader{
+		r:     bytes.NewReader(make([]byte, 1000)),
+		rate:  100,
+		now:   func() time.Time { return clock },
+		sleep: func(d time.Duration) { slept += d; clock = clock.Add(d) },
+	}
+	buf := make([]byte, 512)
+	n, err := l.Read(buf)
+	if err != nil || n != 10 {
+		t.Fatalf("first read = %d, %v; want a 10 byte chunk", n, err)
+	}
+	if slept != 100*time.Millisecond {
+		t.Fatalf("slept %v after 10 bytes at 100 B/s, want 100ms", slept)
+	}
+	var out bytes.Buffer
+	if _, err := out.ReadFrom(l); err != nil {
+		t.Fatal(err)
+	}
+	if total := n + out.Len(); total != 1000 || slept != 10*time.Second {
+		t.Fatalf("read %d bytes in %v, want 1000 in 10s", total, slept)
+	}
+
+	if r := newRateLimitedReader(bytes.NewReader(nil), 0); r == nil {
+		t.Fatal("nil reader")
+	} else if _, ok := r.(*rateLimitedReader); ok {
+		t.Fatal("rate 0 should not limit")
+	}
+}
+
+// localSSH runs the remote command of an ssh invocation with the local
+// shell, without sudo
+func localSSH(t *testing.T) *[][]string {
+	t.Helper()
+	old := execCommand
+	t.Cleanup(func() { execCommand = old })
+	var calls [][]string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		calls = append(calls, append([]string{name}, args...))
+		if name != "ssh" {
+			return old(name, args...)
+		}
+		script := strings.ReplaceAll(args[len(args)-1], "sudo -n ", "")
+		return old("sh", "-c", script)
+	}
+	return &calls
+}
+
+func TestSSHClient_UploadCompressed(t *testing.T) {
+	calls := localSSH(t)
+	dir := t.TempDir()
+	local := filepath.Join(dir, "netcup-kube")
+	content := bytes.Repeat([]byte("netcup-kube binary\n"), 1000)
+	if err := os.WriteFile(local, content, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "remote bin")
+
+	c := &SSHClient{Host: "example.com", User: "ops", Transfer: TransferOptions{Compression: CompressGzip, RateLimit: 1 << 30}}
+	if err := c.Upload(local, dest); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	got, err := os.ReadFile(dest)
+	if err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("uploaded %d bytes (%v), want %d", len(got), err, len(content))
+	}
+	if info, _ := os.Stat(dest); info.Mode().Perm() != 0o755 {
+		t.Errorf("mode = %v, want the local 0755", info.Mode().Perm())
+	}
+	if len(*calls) != 1 || (*calls)[0][0] != "ssh" || !strings.Contains((*calls)[0][len((*calls)[0])-1], "gzip -d -c") {
+		t.Fatalf("calls = %v, want one ssh gzip stream", *calls)
+	}
+	if leftovers, _ := filepath.Glob(dest + ".*"); len(leftovers) != 0 {
+		t.Errorf("temp files left: %v", leftovers)
+	}
+}
+
+func TestSSHClient_UploadUncompressedPassesLimit(t *testing.T) {
+	old := execCommand
+	defer func() { execCommand = old }()
+	var gotName string
+	var gotArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gotName, gotArgs = name, args
+		return exec.Command("true")
+	}
+
+	c := &SSHClient{Host: "example.com", User: "ops", Transfer: TransferOptions{RateLimit: 1 << 20}}
+	if err := c.Upload("/tmp/netcup-kube", "/home/ops/netcup-kube"); err != nil {
+		t.Fatal(err)
+	}
+	if gotName != "scp" || !strings.Contains(strings.Join(gotArgs, " "), "-l 8388 ") {
+		t.Fatalf("got %s %v, want scp -l 8388", gotName, gotArgs)
+	}
+}
+
+func TestSSHClient_DownloadCompressed(t *testing.T) {
+	for _, compression := range []string{CompressGzip, CompressZstd} {
+		t.Run(compression, func(t *testing.T) {
+			if compression == CompressZstd {
+				if _, err := exec.LookPath("zstd"); err != nil {
+					t.Skip("zstd not installed")
+				}
+			}
+			calls := localSSH(t)
+			dir := t.TempDir()
+			src := filepath.Join(dir, "snapshot.tar.gz")
+			content := bytes.Repeat([]byte{0x1f, 0x8b, 0x00, 0xff}, 4096)
+			if err := os.WriteFile(src, content, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			dest := filepath.Join(dir, "local.tar.gz")
+
+			c := &SSHClient{Host: "example.com", User: "ops", Transfer: TransferOptions{Compression: compression}}
+			if err := c.DownloadSudo(src, dest); err != nil {
+				t.Fatalf("DownloadSudo: %v", err)
+			}
+			if got, err := os.ReadFile(dest); err != nil || !bytes.Equal(got, content) {
+				t.Fatalf("downloaded %d bytes (%v), want %d", len(got), err, len(content))
+			}
+			if last := (*calls)[0]; !strings.HasPrefix(last[len(last)-1], "sudo -n "+compressCommand(compression)) {
+				t.Errorf("remote command = %q", last[len(last)-1])
+			}
+		})
+	}
+}
+
+func TestSSHClient_DownloadFailureKeepsDestination(t *testing.T) {
+	localSSH(t)
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "backup.tar.gz")
+	if err := os.WriteFile(dest, []byte("previous"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &SSHClient{Host: "example.com", User: "ops", Transfer: TransferOptions{Compression: CompressGzip}}
+	if err := c.Download(filepath.Join(dir, "missing"), dest); err == nil {
+		t.Fatal("expected an error for a missing remote file")
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "previous" {
+		t.Errorf("destination changed to %q", got)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temp files left: %v", entries)
+	}
+}
+
+func TestSSHClient_DownloadUncompressed(t *testing.T) {
+	old := execCommand
+	defer func() { execCommand = old }()
+	var gotName string
+	var gotArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gotName, gotArgs = name, args
+		return exec.Command("true")
+	}
+
+	c := &SSHClient{Host: "example.com", User: "ops", IdentityFile: "/home/ops/.ssh/netcup", Transfer: TransferOptions{RateLimit: 1 << 20}}
+	if err := c.Download("/var/backups/etcd.db", "/tmp/etcd.db"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "-o StrictHostKeyChecking=no -i /home/ops/.ssh/netcup -l 8388 ops@example.com:/var/backups/etcd.db /tmp/etcd.db"; gotName != "scp" || strings.Join(gotArgs, " ") != want {
+		t.Fatalf("got %s %v, want scp %s", gotName, gotArgs, want)
+	}
+}
+
+func TestSSHClient_DownloadSudoPlain(t *testing.T) {
+	calls := localSSH(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "k3s.yaml")
+	if err := os.WriteFile(src, []byte("apiVersion: v1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "kubeconfig")
+
+	c := &SSHClient{Host: "example.com", User: "ops", IdentityFile: "/home/ops/.ssh/netcup"}
+	if err := c.DownloadSudo(src, dest); err != nil {
+		t.Fatalf("DownloadSudo: %v", err)
+	}
+	if got, _ := os.ReadFile(dest); string(got) != "apiVersion: v1\n" {
+		t.Errorf("downloaded %q", got)
+	}
+	if last := (*calls)[0]; !slices.Contains(last, "/home/ops/.ssh/netcup") || !strings.HasPrefix(last[len(last)-1], "sudo -n cat -- ") {
+		t.Errorf("ssh call = %v", last)
+	}
+	if err := c.DownloadSudo(src, filepath.Join(dir, "missing", "kubeconfig")); err == nil {
+		t.Error("DownloadSudo() into a missing directory expected error")
+	}
+}
+
+func TestSSHClient_DownloadCorruptStream(t *testing.T) {
+	old := execCommand
+	defer func() { execCommand = old }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return old("sh", "-c", "echo not-gzip")
+	}
+
+	dest := filepath.Join(t.TempDir(), "backup.tar.gz")
+	c := &SSHClient{Host: "example.com", User: "ops", Transfer: TransferOptions{Compression: CompressGzip}}
+	if err := c.Download("/var/backups/backup.tar.gz", dest); err == nil || !strings.Contains(err.Error(), "failed to receive") {
+		t.Fatalf("Download(corrupt) error = %v", err)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("destination written from a corrupt stream: %v", err)
+	}
+}
+
+func TestZstdNeedsLocalBinary(t *testing.T) {
+	old := lookPath
+	defer func() { lookPath = old }()
+	lookPath = func(string) (string, error) { return "", exec.ErrNotFound }
+
+	if _, err := compressStream(CompressZstd, bytes.NewReader(nil)); err == nil || !strings.Contains(err.Error(), "needs zstd installed") {
+		t.Errorf("compressStream(zstd) error = %v", err)
+	}
+	if err := decompressStream(CompressZstd, bytes.NewReader(nil), &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "needs zstd installed") {
+		t.Errorf("decompressStream(zstd) error = %v", err)
+	}
+
+	local := filepath.Join(t.TempDir(), "netcup-kube")
+	if err := os.WriteFile(local, []byte("binary"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := &SSHClient{Host: "example.com", User: "ops", Transfer: TransferOptions{Compression: CompressZstd}}
+	if err := c.Upload(local, "/home/ops/netcup-kube"); err == nil {
+		t.Error("Upload() with zstd missing expected error")
+	}
+	if err := c.Upload(filepath.Join(t.TempDir(), "missing"), "/home/ops/netcup-kube"); err == nil {
+		t.Error("Upload(missing) expected error")
+	}
+}
+
+func TestSSHClient_UploadZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	calls := localSSH(t)
+	dir := t.TempDir()
+	local := filepath.Join(dir, "netcup-kube")
+	content := bytes.Repeat([]byte("netcup-kube binary\n"), 1000)
+	if err := os.WriteFile(local, content, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(dir, "uploaded")
+
+	c := &SSHClient{Host: "example.com", User: "ops", IdentityFile: "/home/ops/.ssh/netcup", Transfer: TransferOptions{Compression: CompressZstd}}
+	if err := c.Upload(local, dest); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if got, err := os.ReadFile(dest); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("uploaded %d bytes (%v), want %d", len(got), err, len(content))
+	}
+	last := (*calls)[len(*calls)-1]
+	if !strings.Contains(last[len(last)-1], "zstd -q -d -c") {
+		t.Errorf("remote command = %q", last[len(last)-1])
+	}
+}
@@ -0,0 +1,360 @@
+// Package report summarizes what consumes a cluster's nodes: CPU and memory
+// requests and limits per namespace against the nodes' allocatable capacity,
+// PVC sizes, and the services and ingresses exposed on the nodes.
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/storage"
+)
+
+// Kubectl runs kubectl against the cluster
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Resources is an amount of CPU (in millicores) and memory (in bytes)
+type Resources struct {
+	CPUMilli    int64 `json:"cpuMillicores"`
+	MemoryBytes int64 `json:"memoryBytes"`
+}
+
+func (r *Resources) add(o Resources) {
+	r.CPUMilli += o.CPUMilli
+	r.MemoryBytes += o.MemoryBytes
+}
+
+// Node is a node and its allocatable capacity
+type Node struct {
+	Name        string    `json:"name"`
+	Allocatable Resources `json:"allocatable"`
+}
+
+// Namespace is the resource footprint of the running pods in a namespace
+type Namespace struct {
+	Name     string    `json:"name"`
+	Pods     int       `json:"pods"`
+	Requests Resources `json:"requests"`
+	Limits   Resources `json:"limits"`
+	// Unlimited counts containers without a memory limit, which can grow
+	// until the node runs out of memory
+	Unlimited int   `json:"containersWithoutMemoryLimit"`
+	PVCs      int   `json:"pvcs"`
+	PVCBytes  int64 `json:"pvcBytes"`
+}
+
+// Volume is a PVC with its requested size
+type Volume struct {
+	Namespace      string `json:"namespace"`
+	Claim          string `json:"claim"`
+	StorageClass   string `json:"storageClass"`
+	Requested      string `json:"requested"`
+	RequestedBytes int64  `json:"requestedBytes"`
+}
+
+// NodePort is a service port opened on every node
+type NodePort struct {
+	Namespace string `json:"namespace"`
+	Service   string `json:"service"`
+	Type      string `json:"type"`
+	Port      int    `json:"port"`
+	NodePort  int    `json:"nodePort"`
+	Protocol  string `json:"protocol"`
+}
+
+// Ingress is an Ingress and the hosts it routes
+type Ingress struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Class     string   `json:"class,omitempty"`
+	Hosts     []string `json:"hosts"`
+}
+
+// Report is the cluster resource report
+type Report struct {
+	Nodes       []Node      `json:"nodes"`
+	Allocatable Resources   `json:"allocatable"`
+	Namespaces  []Namespace `json:"namespaces"`
+	Total       Namespace   `json:"total"`
+	Volumes     []Volume    `json:"volumes"`
+	NodePorts   []NodePort  `json:"nodePorts"`
+	Ingresses   []Ingress   `json:"ingresses"`
+}
+
+type nodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Allocatable map[string]string `json:"allocatable"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type container struct {
+	Resources struct {
+		Requests map[string]string `json:"requests"`
+		Limits   map[string]string `json:"limits"`
+	} `json:"resources"`
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Containers     []container `json:"containers"`
+			InitContainers []container `json:"initContainers"`
+		} `json:"spec"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type serviceList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Type  string `json:"type"`
+			Ports []struct {
+				Port     int    `json:"port"`
+				NodePort int    `json:"nodePort"`
+				Protocol string `json:"protocol"`
+			} `json:"ports"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+type ingressList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			IngressClassName string `json:"ingressClassName"`
+			Rules            []struct {
+				Host string `json:"host"`
+			} `json:"rules"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// Collect gathers the report from the cluster
+func Collect(ctx context.Context, kube Kubectl) (*Report, error) {
+	var nodes nodeList
+	if err := getJSON(ctx, kube, &nodes, "nodes"); err != nil {
+		return nil, err
+	}
+	var pods podList
+	if err := getJSON(ctx, kube, &pods, "pods", "--all-namespaces"); err != nil {
+		return nil, err
+	}
+	var services serviceList
+	if err := getJSON(ctx, kube, &services, "services", "--all-namespaces"); err != nil {
+		return nil, err
+	}
+	var ingresses ingressList
+	if err := getJSON(ctx, kube, &ingresses, "ingresses", "--all-namespaces"); err != nil {
+		return nil, err
+	}
+	volumes, err := storage.ListVolumes(ctx, kube, "")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Report{}
+	for _, n := range nodes.Items {
+		node := Node{Name: n.Metadata.Name, Allocatable: parseResources(n.Status.Allocatable)}
+		r.Nodes = append(r.Nodes, node)
+		r.Allocatable.add(node.Allocatable)
+	}
+
+	byName := map[string]*Namespace{}
+	namespace := func(name string) *Namespace {
+		if byName[name] == nil {
+			byName[name] = &Namespace{Name: name}
+		}
+		return byName[name]
+	}
+	for _, p := range pods.Items {
+		// Finished pods no longer hold their requests
+		if p.Status.Phase == "Succeeded" || p.Status.Phase == "Failed" {
+			continue
+		}
+		ns := namespace(p.Metadata.Namespace)
+		requests, limits, unlimited := podResources(p.Spec.Containers, p.Spec.InitContainers)
+		ns.Pods++
+		ns.Requests.add(requests)
+		ns.Limits.add(limits)
+		ns.Unlimited += unlimited
+	}
+	for _, v := range volumes {
+		size, _ := storage.ParseQuantity(v.Requested)
+		r.Volumes = append(r.Volumes, Volume{
+			Namespace:      v.Namespace,
+			Claim:          v.Claim,
+			StorageClass:   v.StorageClass,
+			Requested:      v.Requested,
+			RequestedBytes: size,
+		})
+		ns := namespace(v.Namespace)
+		ns.PVCs++
+		ns.PVCBytes += size
+	}
+
+	r.Total.Name = "total"
+	for _, ns := range byName {
+		r.Namespaces = append(r.Namespaces, *ns)
+		r.Total.Pods += ns.Pods
+		r.Total.Requests.add(ns.Requests)
+		r.Total.Limits.add(ns.Limits)
+		r.Total.Unlimited += ns.Unlimited
+		r.Total.PVCs += ns.PVCs
+		r.Total.PVCBytes += ns.PVCBytes
+	}
+	// Memory is what runs out first on a single small node
+	sort.Slice(r.Namespaces, func(i, j int) bool {
+		a, b := r.Namespaces[i], r.Namespaces[j]
+		if a.Requests.MemoryBytes != b.Requests.MemoryBytes {
+			return a.Requests.MemoryBytes > b.Requests.MemoryBytes
+		}
+		return a.Name < b.Name
+	})
+
+	for _, s := range services.Items {
+		if s.Spec.Type != "NodePort" && s.Spec.Type != "LoadBalancer" {
+			continue
+		}
+		for _, p := range s.Spec.Ports {
+			if p.NodePort == 0 {
+				continue
+			}
+			r.NodePorts = append(r.NodePorts, NodePort{
+				Namespace: s.Metadata.Namespace,
+				Service:   s.Metadata.Name,
+				Type:      s.Spec.Type,
+				Port:      p.Port,
+				NodePort:  p.NodePort,
+				Protocol:  p.Protocol,
+			})
+		}
+	}
+	sort.Slice(r.NodePorts, func(i, j int) bool { return r.NodePorts[i].NodePort < r.NodePorts[j].NodePort })
+
+	for _, i := range ingresses.Items {
+		ing := Ingress{Namespace: i.Metadata.Namespace, Name: i.Metadata.Name, Class: i.Spec.IngressClassName, Hosts: []string{}}
+		for _, rule := range i.Spec.Rules {
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+			ing.Hosts = append(ing.Hosts, host)
+		}
+		r.Ingresses = append(r.Ingresses, ing)
+	}
+	sort.Slice(r.Ingresses, func(i, j int) bool {
+		return r.Ingresses[i].Namespace+"/"+r.Ingresses[i].Name < r.Ingresses[j].Namespace+"/"+r.Ingresses[j].Name
+	})
+	return r, nil
+}
+
+func getJSON(ctx context.Context, kube Kubectl, into any, args ...string) error {
+	out, err := kube.Output(ctx, append([]string{"get"}, append(args, "-o", "json")...)...)
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %w", args[0], err)
+	}
+	if err := json.Unmarshal(out, into); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// podResources returns the effective requests and limits of a pod: the sum
+// over its containers, or the largest init container when that is higher,
+// as the scheduler accounts them. unlimited counts containers without a
+// memory limit.
+func podResources(containers, initContainers []container) (requests, limits Resources, unlimited int) {
+	for _, c := range containers {
+		requests.add(parseResources(c.Resources.Requests))
+		limits.add(parseResources(c.Resources.Limits))
+		if c.Resources.Limits["memory"] == "" {
+			unlimited++
+		}
+	}
+	for _, c := range initContainers {
+		req := parseResources(c.Resources.Requests)
+		lim := parseResources(c.Resources.Limits)
+		requests.CPUMilli = max(requests.CPUMilli, req.CPUMilli)
+		requests.MemoryBytes = max(requests.MemoryBytes, req.MemoryBytes)
+		limits.CPUMilli = max(limits.CPUMilli, lim.CPUMilli)
+		limits.MemoryBytes = max(limits.MemoryBytes, lim.MemoryBytes)
+	}
+	return requests, limits, unlimited
+}
+
+// parseResources reads the cpu and memory entries of a requests, limits or
+// allocatable map; unparseable quantities count as zero
+func parseResources(values map[string]string) Resources {
+	cpu, _ := ParseCPU(values["cpu"])
+	memory, _ := storage.ParseQuantity(values["memory"])
+	return Resources{CPUMilli: cpu, MemoryBytes: memory}
+}
+
+var cpuPattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)(n|u|m)?$`)
+
+// ParseCPU converts a Kubernetes CPU quantity (e.g. 250m, 2, 0.5) to
+// millicores; "" is zero
+func ParseCPU(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	m := cpuPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid CPU quantity %q", s)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CPU quantity %q: %w", s, err)
+	}
+	switch m[2] {
+	case "n":
+		n /= 1e6
+	case "u":
+		n /= 1e3
+	case "m":
+	default:
+		n *= 1000
+	}
+	return int64(n + 0.5), nil
+}
+
+// FormatCPU renders millicores like kubectl: 250m, or whole cores
+func FormatCPU(milli int64) string {
+	if milli%1000 == 0 {
+		return strconv.FormatInt(milli/1000, 10)
+	}
+	return strconv.FormatInt(milli, 10) + "m"
+}
+
+// Percent renders part as a percentage of whole, or "-" when whole is zero
+func Percent(part, whole int64) string {
+	if whole <= 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d%%", (part*100+whole/2)/whole)
+}
@@ -0,0 +1,89 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeKube map[string]string
+
+func (f fakeKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	out, ok := f[args[1]]
+	if !ok {
+		return nil, fmt.Errorf("unexpected kubectl %s", strings.Join(args, " "))
+	}
+	return []byte(out), nil
+}
+
+func TestCollect(t *testing.T) {
+	kube := fakeKube{
+		"nodes": `{"items":[{"metadata":{"name":"v2202"},"status":{"allocatable":{"cpu":"4","memory":"8Gi"}}}]}`,
+		"pods": `{"items":[
+		  {"metadata":{"namespace":"openclaw"},"status":{"phase":"Running"},"spec":{
+		    "containers":[{"resources":{"requests":{"cpu":"250m","memory":"512Mi"},"limits":{"memory":"1Gi"}}},
+		                  {"resources":{"requests":{"cpu":"100m","memory":"64Mi"}}}],
+		    "initContainers":[{"resources":{"requests":{"cpu":"1","memory":"128Mi"}}}]}},
+		  {"metadata":{"namespace":"platform"},"status":{"phase":"Running"},"spec":{
+		    "containers":[{"resources":{"requests":{"cpu":"0.5","memory":"1Gi"},"limits":{"cpu":"1","memory":"2Gi"}}}]}},
+		  {"metadata":{"namespace":"platform"},"status":{"phase":"Succeeded"},"spec":{
+		    "containers":[{"resources":{"requests":{"cpu":"2","memory":"4Gi"}}}]}}]}`,
+		"services": `{"items":[
+		  {"metadata":{"name":"traefik","namespace":"kube-system"},"spec":{"type":"NodePort","ports":[
+		    {"port":443,"nodePort":30443,"protocol":"TCP"},{"port":80,"nodePort":30080,"protocol":"TCP"}]}},
+		  {"metadata":{"name":"postgres","namespace":"platform"},"spec":{"type":"ClusterIP","ports":[{"port":5432,"protocol":"TCP"}]}}]}`,
+		"ingresses": `{"items":[{"metadata":{"name":"openclaw","namespace":"openclaw"},
+		  "spec":{"ingressClassName":"traefik","rules":[{"host":"claw.example.com"},{}]}}]}`,
+		"persistentvolumeclaims": `{"items":[
+		  {"metadata":{"name":"data","namespace":"platform"},"spec":{"storageClassName":"local-path","resources":{"requests":{"storage":"10Gi"}}}}]}`,
+		"persistentvolumes": `{"items":[]}`,
+	}
+
+	r, err := Collect(context.Background(), kube)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Allocatable != (Resources{CPUMilli: 4000, MemoryBytes: 8 << 30}) {
+		t.Errorf("allocatable = %+v", r.Allocatable)
+	}
+	if len(r.Namespaces) != 2 || r.Namespaces[0].Name != "platform" {
+		t.Fatalf("namespaces = %+v", r.Namespaces)
+	}
+	platform, openclaw := r.Namespaces[0], r.Namespaces[1]
+	if platform.Pods != 1 || platform.Requests.CPUMilli != 500 || platform.PVCBytes != 10<<30 || platform.PVCs != 1 {
+		t.Errorf("platform = %+v", platform)
+	}
+	// The 1-core init container outweighs the 350m of the app containers
+	if openclaw.Requests != (Resources{CPUMilli: 1000, MemoryBytes: 576 << 20}) || openclaw.Unlimited != 1 {
+		t.Errorf("openclaw = %+v", openclaw)
+	}
+	if r.Total.Requests.MemoryBytes != (1<<30)+(576<<20) || r.Total.Pods != 2 {
+		t.Errorf("total = %+v", r.Total)
+	}
+	if len(r.NodePorts) != 2 || r.NodePorts[0].NodePort != 30080 {
+		t.Errorf("nodePorts = %+v", r.NodePorts)
+	}
+	if len(r.Ingresses) != 1 || strings.Join(r.Ingresses[0].Hosts, ",") != "claw.example.com,*" {
+		t.Errorf("ingresses = %+v", r.Ingresses)
+	}
+}
+
+func TestParseCPU(t *testing.T) {
+	tests := map[string]int64{"": 0, "250m": 250, "2": 2000, "0.5": 500, "1500000n": 2, "100u": 0}
+	for in, want := range tests {
+		got, err := ParseCPU(in)
+		if err != nil || got != want {
+			t.Errorf("ParseCPU(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseCPU("lots"); err == nil {
+		t.Error("ParseCPU() expected error")
+	}
+	if FormatCPU(2000) != "2" || FormatCPU(250) != "250m" {
+		t.Errorf("FormatCPU() = %s, %s", FormatCPU(2000), FormatCPU(250))
+	}
+	if Percent(1, 3) != "33%" || Percent(1, 0) != "-" {
+		t.Errorf("Percent() = %s, %s", Percent(1, 3), Percent(1, 0))
+	}
+}
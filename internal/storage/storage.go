@@ -0,0 +1,301 @@
+// Package storage inspects and manages PersistentVolumeClaims backed by the
+// k3s local-path-provisioner, whose volumes are plain directories on a node.
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultLocalPathRoot is where local-path-provisioner creates volume directories on k3s
+	DefaultLocalPathRoot = "/var/lib/rancher/k3s/storage"
+
+	// DefaultBackupDir is the node directory volume snapshots are written to
+	DefaultBackupDir = "/var/backups/netcup-kube/storage"
+
+	hostnameLabel = "kubernetes.io/hostname"
+)
+
+// Kubectl runs kubectl against the cluster
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Volume is a PVC joined with its bound PersistentVolume
+type Volume struct {
+	Namespace    string
+	Claim        string
+	Volume       string
+	StorageClass string
+	Phase        string
+	// Requested is the size in the PVC spec; Capacity is what was provisioned
+	Requested string
+	Capacity  string
+	// Node and Path locate the volume directory for node-local volumes
+	Node string
+	Path string
+}
+
+// ID returns the namespace/claim identifier used on the command line
+func (v Volume) ID() string {
+	return v.Namespace + "/" + v.Claim
+}
+
+// SnapshotName returns the archive base name for a snapshot of the volume
+func (v Volume) SnapshotName() string {
+	return v.Namespace + "_" + v.Claim
+}
+
+type claimList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			VolumeName       string `json:"volumeName"`
+			StorageClassName string `json:"storageClassName"`
+			Resources        struct {
+				Requests map[string]string `json:"requests"`
+			} `json:"resources"`
+		} `json:"spec"`
+		Status struct {
+			Phase    string            `json:"phase"`
+			Capacity map[string]string `json:"capacity"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+type volumeList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			HostPath *struct {
+				Path string `json:"path"`
+			} `json:"hostPath"`
+			Local *struct {
+				Path string `json:"path"`
+			} `json:"local"`
+			NodeAffinity *struct {
+				Required struct {
+					NodeSelectorTerms []struct {
+						MatchExpressions []struct {
+							Key    string   `json:"key"`
+							Values []string `json:"values"`
+						} `json:"matchExpressions"`
+					} `json:"nodeSelectorTerms"`
+				} `json:"required"`
+			} `json:"nodeAffinity"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// ListVolumes returns every PVC in the cluster (or one namespace when
+// namespace is non-empty), sorted by namespace and name.
+func ListVolumes(ctx context.Context, kube Kubectl, namespace string) ([]Volume, error) {
+	scope := []string{"--all-namespaces"}
+	if namespace != "" {
+		scope = []string{"-n", namespace}
+	}
+	out, err := kube.Output(ctx, append([]string{"get", "persistentvolumeclaims", "-o", "json"}, scope...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistentvolumeclaims: %w", err)
+	}
+	var claims claimList
+	if err := json.Unmarshal(out, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse persistentvolumeclaims: %w", err)
+	}
+
+	out, err = kube.Output(ctx, "get", "persistentvolumes", "-o", "json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persistentvolumes: %w", err)
+	}
+	var pvs volumeList
+	if err := json.Unmarshal(out, &pvs); err != nil {
+		return nil, fmt.Errorf("failed to parse persistentvolumes: %w", err)
+	}
+	type location struct{ node, path string }
+	locations := make(map[string]location, len(pvs.Items))
+	for _, pv := range pvs.Items {
+		var loc location
+		switch {
+		case pv.Spec.HostPath != nil:
+			loc.path = pv.Spec.HostPath.Path
+		case pv.Spec.Local != nil:
+			loc.path = pv.Spec.Local.Path
+		}
+		if pv.Spec.NodeAffinity != nil {
+			for _, term := range pv.Spec.NodeAffinity.Required.NodeSelectorTerms {
+				for _, expr := range term.MatchExpressions {
+					if expr.Key == hostnameLabel && len(expr.Values) > 0 {
+						loc.node = expr.Values[0]
+					}
+				}
+			}
+		}
+		locations[pv.Metadata.Name] = loc
+	}
+
+	volumes := make([]Volume, 0, len(claims.Items))
+	for _, c := range claims.Items {
+		loc := locations[c.Spec.VolumeName]
+		volumes = append(volumes, Volume{
+			Namespace:    c.Metadata.Namespace,
+			Claim:        c.Metadata.Name,
+			Volume:       c.Spec.VolumeName,
+			StorageClass: c.Spec.StorageClassName,
+			Phase:        c.Status.Phase,
+			Requested:    c.Spec.Resources.Requests["storage"],
+			Capacity:     c.Status.Capacity["storage"],
+			Node:         loc.node,
+			Path:         loc.path,
+		})
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].ID() < volumes[j].ID() })
+	return volumes, nil
+}
+
+// FindVolume returns the volume with the given namespace/claim identifier
+func FindVolume(volumes []Volume, id string) (Volume, error) {
+	if !strings.Contains(id, "/") {
+		return Volume{}, fmt.Errorf("invalid PVC %q (use <namespace>/<name>)", id)
+	}
+	for _, v := range volumes {
+		if v.ID() == id {
+			return v, nil
+		}
+	}
+	return Volume{}, fmt.Errorf("PVC %s not found", id)
+}
+
+// ExpansionSupported reports whether the storage class allows volume expansion
+func ExpansionSupported(ctx context.Context, kube Kubectl, class string) (bool, error) {
+	if class == "" {
+		return false, nil
+	}
+	out, err := kube.Output(ctx, "get", "storageclass", class, "-o", "jsonpath={.allowVolumeExpansion}")
+	if err != nil {
+		return false, fmt.Errorf("failed to read storage class %s: %w", class, err)
+	}
+	return strings.TrimSpace(string(out)) == "true", nil
+}
+
+// Expand raises the storage request of a PVC. Shrinking is rejected, as is a
+// storage class that does not allow expansion (local-path does not: its
+// volumes are directories limited only by the node's disk).
+func Expand(ctx context.Context, kube Kubectl, v Volume, size string) error {
+	want, err := ParseQuantity(size)
+	if err != nil {
+		return err
+	}
+	if current, err := ParseQuantity(v.Requested); err == nil && want <= current {
+		return fmt.Errorf("PVC %s already requests %s; volumes can only grow", v.ID(), v.Requested)
+	}
+	ok, err := ExpansionSupported(ctx, kube, v.StorageClass)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("storage class %q of PVC %s does not allow volume expansion", v.StorageClass, v.ID())
+	}
+	patch := fmt.Sprintf(`{"spec":{"resources":{"requests":{"storage":%q}}}}`, size)
+	if _, err := kube.Output(ctx, "-n", v.Namespace, "patch", "persistentvolumeclaim", v.Claim, "--type", "merge", "-p", patch); err != nil {
+		return fmt.Errorf("failed to resize PVC %s: %w", v.ID(), err)
+	}
+	return nil
+}
+
+var quantityPattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)(Ki|Mi|Gi|Ti|Pi|k|M|G|T|P)?$`)
+
+var quantityUnits = map[string]float64{
+	"": 1, "k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15,
+	"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50,
+}
+
+// ParseQuantity converts a Kubernetes storage quantity (e.g. 10Gi, 500M) to bytes
+func ParseQuantity(s string) (int64, error) {
+	m := quantityPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 10Gi or 500Mi)", s)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	return int64(n * quantityUnits[m[2]]), nil
+}
+
+// FormatBytes renders a byte count with binary units
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ci", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+var safePathPattern = regexp.MustCompile(`^/[A-Za-z0-9._/-]+$`)
+
+// NodePath validates a volume directory before it is used in a node command
+func NodePath(v Volume) (string, error) {
+	if v.Path == "" {
+		return "", fmt.Errorf("PVC %s has no node-local volume directory (bound: %q)", v.ID(), v.Volume)
+	}
+	if !safePathPattern.MatchString(v.Path) || strings.Contains(v.Path, "..") {
+		return "", fmt.Errorf("PVC %s has an unexpected volume path %q", v.ID(), v.Path)
+	}
+	return v.Path, nil
+}
+
+// ParseDiskUsage parses `du -sb` output into bytes per path
+func ParseDiskUsage(out []byte) map[string]int64 {
+	usage := map[string]int64{}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		n, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		usage[fields[1]] = n
+	}
+	return usage
+}
+
+// SnapshotScript archives volume directories on a node. Arguments: the backup
+// directory, a timestamp, then <path> <name> pairs. Each volume is written to
+// <backup dir>/<name>-<timestamp>.tar.gz.
+const SnapshotScript = `set -euo pipefail
+target="$1"
+stamp="$2"
+shift 2
+sudo mkdir -p "$target"
+sudo chmod 0700 "$target"
+while [ "$#" -ge 2 ]; do
+  path="$1"
+  name="$2"
+  shift 2
+  if ! sudo test -d "$path"; then
+    echo "volume directory not found: $path" >&2
+    exit 1
+  fi
+  out="$target/$name-$stamp.tar.gz"
+  sudo tar -czf "$out" -C "$(dirname "$path")" "$(basename "$path")"
+  echo "snapshot: $out ($(sudo du -h "$out" | cut -f1))"
+done
+`
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+type fakeKube struct {
+	outputs map[string]string
+	calls   []string
+}
+
+func (k *fakeKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	call := strings.Join(args, " ")
+	k.calls = append(k.calls, call)
+	for prefix, out := range k.outputs {
+		if strings.HasPrefix(call, prefix) {
+			return []byte(out), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected kubectl %s", call)
+}
+
+const testClaims = `{"items":[
+  {"metadata":{"name":"openclaw-data","namespace":"openclaw"},
+   "spec":{"volumeName":"pvc-1","storageClassName":"local-path","resources":{"requests":{"storage":"10Gi"}}},
+   "status":{"phase":"Bound","capacity":{"storage":"10Gi"}}},
+  {"metadata":{"name":"data-postgres-0","namespace":"platform"},
+   "spec":{"volumeName":"pvc-2","storageClassName":"local-path","resources":{"requests":{"storage":"8Gi"}}},
+   "status":{"phase":"Bound"}},
+  {"metadata":{"name":"cache","namespace":"apps"},
+   "spec":{"storageClassName":"longhorn","resources":{"requests":{"storage":"1Gi"}}},
+   "status":{"phase":"Pending"}}]}`
+
+const testVolumes = `{"items":[
+  {"metadata":{"name":"pvc-1"},"spec":{"hostPath":{"path":"/var/lib/rancher/k3s/storage/pvc-1_openclaw_openclaw-data"},
+   "nodeAffinity":{"required":{"nodeSelectorTerms":[{"matchExpressions":[{"key":"kubernetes.io/hostname","operator":"In","values":["mgmt"]}]}]}}}},
+  {"metadata":{"name":"pvc-2"},"spec":{"local":{"path":"/var/lib/rancher/k3s/storage/pvc-2_platform_data-postgres-0"},
+   "nodeAffinity":{"required":{"nodeSelectorTerms":[{"matchExpressions":[{"key":"kubernetes.io/hostname","operator":"In","values":["worker-1"]}]}]}}}}]}`
+
+func TestListVolumes(t *testing.T) {
+	kube := &fakeKube{outputs: map[string]string{
+		"get persistentvolumeclaims": testClaims,
+		"get persistentvolumes":      testVolumes,
+	}}
+	volumes, err := ListVolumes(context.Background(), kube, "")
+	if err != nil {
+		t.Fatalf("ListVolumes() error: %v", err)
+	}
+	var ids []string
+	for _, v := range volumes {
+		ids = append(ids, v.ID())
+	}
+	if strings.Join(ids, ",") != "apps/cache,openclaw/openclaw-data,platform/data-postgres-0" {
+		t.Fatalf("ids = %v", ids)
+	}
+	if v := volumes[1]; v.Node != "mgmt" || v.Path != "/var/lib/rancher/k3s/storage/pvc-1_openclaw_openclaw-data" || v.Requested != "10Gi" {
+		t.Errorf("openclaw volume = %+v", v)
+	}
+	if v := volumes[2]; v.Node != "worker-1" || !strings.HasSuffix(v.Path, "data-postgres-0") {
+		t.Errorf("local volume = %+v", v)
+	}
+	if v := volumes[0]; v.Node != "" || v.Path != "" {
+		t.Errorf("unbound volume = %+v", v)
+	}
+	if !strings.Contains(kube.calls[0], "--all-namespaces") {
+		t.Errorf("first call = %s, want --all-namespaces", kube.calls[0])
+	}
+
+	if _, err := FindVolume(volumes, "openclaw"); err == nil {
+		t.Error("FindVolume() expected error without namespace")
+	}
+	if _, err := FindVolume(volumes, "openclaw/missing"); err == nil {
+		t.Error("FindVolume() expected error for unknown PVC")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	v := Volume{Namespace: "openclaw", Claim: "data", StorageClass: "longhorn", Requested: "10Gi"}
+	kube := &fakeKube{outputs: map[string]string{
+		"get storageclass longhorn":   "true",
+		"get storageclass local-path": "",
+		"-n openclaw patch":           "",
+	}}
+	if err := Expand(context.Background(), kube, v, "20Gi"); err != nil {
+		t.Fatalf("Expand() error: %v", err)
+	}
+	if last := kube.calls[len(kube.calls)-1]; !strings.Contains(last, `{"spec":{"resources":{"requests":{"storage":"20Gi"}}}}`) {
+		t.Errorf("patch call = %s", last)
+	}
+
+	if err := Expand(context.Background(), kube, v, "5Gi"); err == nil || !strings.Contains(err.Error(), "only grow") {
+		t.Errorf("Expand(shrink) error = %v", err)
+	}
+	v.StorageClass = "local-path"
+	if err := Expand(context.Background(), kube, v, "20Gi"); err == nil || !strings.Contains(err.Error(), "does not allow") {
+		t.Errorf("Expand(local-path) error = %v", err)
+	}
+	if err := Expand(context.Background(), kube, v, "lots"); err == nil {
+		t.Error("Expand() expected error for invalid size")
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"10Gi", 10 << 30},
+		{"500Mi", 500 << 20},
+		{"1.5Gi", 3 << 29},
+		{"2G", 2e9},
+		{"1024", 1024},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuantity(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseQuantity(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "10GB", "-1Gi", "Gi"} {
+		if _, err := ParseQuantity(bad); err == nil {
+			t.Errorf("ParseQuantity(%q) expected error", bad)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	for n, want := range map[int64]string{512: "512B", 2048: "2.0Ki", 5 << 30: "5.0Gi", 1536 << 20: "1.5Gi"} {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestParseDiskUsageAndNodePath(t *testing.T) {
+	usage := ParseDiskUsage([]byte("4096\t/var/lib/rancher/k3s/storage/a\nbogus line\n123\t/var/lib/rancher/k3s/storage/b c\n"))
+	if usage["/var/lib/rancher/k3s/storage/a"] != 4096 || usage["/var/lib/rancher/k3s/storage/b c"] != 123 || len(usage) != 2 {
+		t.Errorf("ParseDiskUsage() = %v", usage)
+	}
+
+	if _, err := NodePath(Volume{Namespace: "a", Claim: "b", Path: "/var/lib/x;rm -rf /"}); err == nil {
+		t.Error("NodePath() expected error for unsafe path")
+	}
+	if _, err := NodePath(Volume{Namespace: "a", Claim: "b", Path: "/var/lib/../../etc"}); err == nil {
+		t.Error("NodePath() expected error for path traversal")
+	}
+	if _, err := NodePath(Volume{Namespace: "a", Claim: "b"}); err == nil {
+		t.Error("NodePath() expected error for a volume without a path")
+	}
+}
@@ -0,0 +1,379 @@
+// Package telemetry records opt-in OpenTelemetry traces of command execution
+// (script steps, SSH calls, kubectl invocations) and exports them as OTLP/HTTP
+// JSON when the command finishes. Tracing is enabled only when
+// OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set in
+// the process environment; otherwise every call is a no-op.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxSpans bounds the in-memory buffer; later spans are dropped
+const maxSpans = 10000
+
+// exportTimeout bounds the export on shutdown so a dead collector cannot hang the CLI
+const exportTimeout = 5 * time.Second
+
+// Attribute is a span attribute; values are strings, ints, or bools
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int returns an integer attribute
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: value} }
+
+// Bool returns a boolean attribute
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// Span is one timed operation. A nil *Span (tracing disabled) is valid and
+// all its methods are no-ops.
+type Span struct {
+	tracer   *tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    []Attribute
+	errMsg   string
+	failed   bool
+	ended    bool
+}
+
+type tracer struct {
+	mu       sync.Mutex
+	endpoint string
+	headers  map[string]string
+	service  string
+	version  string
+	root     *Span
+	spans    []*Span
+	dropped  int
+	client   *http.Client
+}
+
+var (
+	mu     sync.Mutex
+	active *tracer
+)
+
+type spanKey struct{}
+
+// Init enables tracing when an OTLP endpoint is configured in the process
+// environment and reports whether it did. OTEL_SDK_DISABLED=true and
+// OTEL_TRACES_EXPORTER=none turn tracing off.
+func Init(service, version string) bool {
+	endpoint := Endpoint(os.Getenv)
+	if endpoint == "" {
+		return false
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	active = &tracer{
+		endpoint: endpoint,
+		headers:  ParseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		service:  service,
+		version:  version,
+		client:   &http.Client{Timeout: exportTimeout},
+	}
+	return true
+}
+
+// Endpoint returns the OTLP/HTTP traces URL from the environment, or "" when
+// tracing is not configured or disabled.
+func Endpoint(getenv func(string) string) string {
+	if strings.EqualFold(strings.TrimSpace(getenv("OTEL_SDK_DISABLED")), "true") ||
+		strings.EqualFold(strings.TrimSpace(getenv("OTEL_TRACES_EXPORTER")), "none") {
+		return ""
+	}
+	if endpoint := strings.TrimSpace(getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := strings.TrimSpace(getenv("OTEL_EXPORTER_OTLP_ENDPOINT")); endpoint != "" {
+		return strings.TrimRight(endpoint, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// ParseHeaders parses OTEL_EXPORTER_OTLP_HEADERS (key1=value1,key2=value2)
+func ParseHeaders(raw string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// Enabled reports whether spans are being recorded
+func Enabled() bool {
+	return current() != nil
+}
+
+func current() *tracer {
+	mu.Lock()
+	defer mu.Unlock()
+	return active
+}
+
+// Start begins a span. Its parent is the span in ctx or, when ctx carries
+// none, the first span of the process (the command span), so calls without a
+// context still nest under the running command.
+func Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, *Span) {
+	t := current()
+	if t == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s := &Span{tracer: t, name: name, start: time.Now(), attrs: attrs}
+	_, _ = rand.Read(s.spanID[:])
+
+	t.mu.Lock()
+	parent, _ := ctx.Value(spanKey{}).(*Span)
+	if parent == nil && t.root != nil && !t.root.ended {
+		parent = t.root
+	}
+	if parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(s.traceID[:])
+		if t.root == nil {
+			t.root = s
+		}
+	}
+	t.mu.Unlock()
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// FromContext returns the span carried by ctx, or nil
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SetName renames the span (e.g. once the command path is known)
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.name = name
+}
+
+// SetAttributes adds attributes to the span
+func (s *Span) SetAttributes(attrs ...Attribute) {
+	if s == nil {
+		return
+	}
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End finishes the span, marking it failed when err is non-nil, and returns
+// err unchanged so callers can write `return span.End(cmd.Run())`.
+func (s *Span) End(err error) error {
+	if s == nil {
+		return err
+	}
+	t := s.tracer
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s.ended {
+		return err
+	}
+	s.ended = true
+	s.end = time.Now()
+	if err != nil {
+		s.failed = true
+		s.errMsg = err.Error()
+	}
+	if len(t.spans) < maxSpans {
+		t.spans = append(t.spans, s)
+	} else {
+		t.dropped++
+	}
+	return err
+}
+
+// TraceParent returns the W3C traceparent header for the span, for handing
+// the trace context to child processes via TRACEPARENT.
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(s.traceID[:]), hex.EncodeToString(s.spanID[:]))
+}
+
+// Shutdown ends any unfinished command span and exports the recorded spans.
+// Export failures are returned but should only be reported as warnings.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	t := active
+	active = nil
+	mu.Unlock()
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	if t.root != nil && !t.root.ended {
+		t.root.ended = true
+		t.root.end = time.Now()
+		t.spans = append(t.spans, t.root)
+	}
+	payload, err := json.Marshal(t.payload())
+	spans := len(t.spans)
+	dropped := t.dropped
+	t.mu.Unlock()
+	// --timings alone records spans without exporting them
+	if err != nil || spans == 0 || t.endpoint == "" {
+		return err
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to export traces: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export traces to %s: %w", t.endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("failed to export traces to %s: %s", t.endpoint, resp.Status)
+	}
+	if dropped > 0 {
+		return fmt.Errorf("exported %d spans; dropped %d over the %d span limit", spans, dropped, maxSpans)
+	}
+	return nil
+}
+
+// OTLP JSON encoding (opentelemetry-proto, JSON mapping)
+
+type otlpValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+	BoolValue   *bool   `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+const (
+	spanKindInternal = 1
+	statusOK         = 1
+	statusError      = 2
+)
+
+func otlpAttributes(attrs []Attribute) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		var v otlpValue
+		switch value := a.Value.(type) {
+		case string:
+			v.StringValue = &value
+		case int:
+			s := strconv.Itoa(value)
+			v.IntValue = &s
+		case bool:
+			v.BoolValue = &value
+		default:
+			s := fmt.Sprint(value)
+			v.StringValue = &s
+		}
+		out = append(out, otlpKeyValue{Key: a.Key, Value: v})
+	}
+	return out
+}
+
+// payload builds the ExportTraceServiceRequest; callers hold t.mu
+func (t *tracer) payload() map[string]any {
+	spans := make([]otlpSpan, 0, len(t.spans))
+	for _, s := range t.spans {
+		o := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        otlpAttributes(s.attrs),
+			Status:            otlpStatus{Code: statusOK},
+		}
+		if s.parentID != [8]byte{} {
+			o.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.failed {
+			o.Status = otlpStatus{Code: statusError, Message: s.errMsg}
+		}
+		spans = append(spans, o)
+	}
+	resource := otlpAttributes([]Attribute{String("service.name", t.service), String("service.version", t.version)})
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": resource},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": t.service, "version": t.version},
+				"spans": spans,
+			}},
+		}},
+	}
+}
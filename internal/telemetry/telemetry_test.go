@@ -0,0 +1,156 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestEndpoint(t *testing.T) {
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, ""},
+		{map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318/"}, "http://localhost:4318/v1/traces"},
+		{map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://tempo:4318/v1/traces"}, "http://tempo:4318/v1/traces"},
+		{map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_SDK_DISABLED": "true"}, ""},
+		{map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://a:4318", "OTEL_TRACES_EXPORTER": "none"}, ""},
+	}
+	for _, tt := range tests {
+		if got := Endpoint(env(tt.env)); got != tt.want {
+			t.Errorf("Endpoint(%v) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+
+	headers := ParseHeaders("Authorization=Basic abc, X-Scope-OrgID=tenant1,bogus")
+	if len(headers) != 2 || headers["Authorization"] != "Basic abc" || headers["X-Scope-OrgID"] != "tenant1" {
+		t.Errorf("ParseHeaders() = %v", headers)
+	}
+}
+
+func TestDisabledIsNoop(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	if Init("netcup-kube", "test") || Enabled() {
+		t.Fatal("tracing enabled without an endpoint")
+	}
+	ctx, span := Start(context.Background(), "noop")
+	if span != nil || FromContext(ctx) != nil {
+		t.Fatal("Start() returned a span while disabled")
+	}
+	span.SetName("x")
+	span.SetAttributes(String("k", "v"))
+	want := errors.New("boom")
+	if err := span.End(want); err != want {
+		t.Errorf("End() = %v, want the error passed in", err)
+	}
+	if span.TraceParent() != "" {
+		t.Error("TraceParent() should be empty while disabled")
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error: %v", err)
+	}
+}
+
+type exported struct {
+	ResourceSpans []struct {
+		ScopeSpans []struct {
+			Spans []otlpSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+func TestExport(t *testing.T) {
+	var body []byte
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		auth = r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer t0ken")
+
+	if !Init("netcup-kube", "test") {
+		t.Fatal("Init() did not enable tracing")
+	}
+	ctx, root := Start(context.Background(), "netcup-kube")
+	root.SetName("netcup-kube k3s upgrade")
+
+	stepCtx, step := Start(ctx, "k3s drain", String("k3s.node", "mgmt"))
+	_, kubectl := Start(stepCtx, "kubectl drain")
+	_ = kubectl.End(errors.New("timed out"))
+	_ = step.End(nil)
+
+	// spans started without a context nest under the command span
+	_, ssh := Start(context.Background(), "ssh script")
+	if !regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`).MatchString(ssh.TraceParent()) {
+		t.Errorf("TraceParent() = %s", ssh.TraceParent())
+	}
+	_ = ssh.End(nil)
+
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error: %v", err)
+	}
+	if Enabled() {
+		t.Error("tracing still enabled after Shutdown()")
+	}
+	if auth != "Bearer t0ken" {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	var payload exported
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v\n%s", err, body)
+	}
+	spans := map[string]otlpSpan{}
+	for _, s := range payload.ResourceSpans[0].ScopeSpans[0].Spans {
+		spans[s.Name] = s
+	}
+	if len(spans) != 4 {
+		t.Fatalf("spans = %v", spans)
+	}
+	rootSpan := spans["netcup-kube k3s upgrade"]
+	if rootSpan.ParentSpanID != "" || len(rootSpan.TraceID) != 32 {
+		t.Errorf("root span = %+v", rootSpan)
+	}
+	if spans["k3s drain"].ParentSpanID != rootSpan.SpanID || spans["ssh script"].ParentSpanID != rootSpan.SpanID {
+		t.Errorf("children not parented to the command span: %+v", spans)
+	}
+	failed := spans["kubectl drain"]
+	if failed.ParentSpanID != spans["k3s drain"].SpanID || failed.Status.Code != statusError || failed.Status.Message != "timed out" {
+		t.Errorf("kubectl span = %+v", failed)
+	}
+	for name, s := range spans {
+		if s.TraceID != rootSpan.TraceID {
+			t.Errorf("span %s has trace %s, want %s", name, s.TraceID, rootSpan.TraceID)
+		}
+	}
+}
+
+func TestExportFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", srv.URL)
+
+	Init("netcup-kube", "test")
+	_, span := Start(context.Background(), "netcup-kube")
+	_ = span.End(nil)
+	if err := Shutdown(context.Background()); err == nil {
+		t.Error("Shutdown() expected error for a 503 response")
+	}
+}
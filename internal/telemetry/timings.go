@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// TimingsFlag is the global flag that prints a phase breakdown on exit
+const TimingsFlag = "--timings"
+
+// Phase aggregates the finished spans sharing a name
+type Phase struct {
+	Name  string
+	Calls int
+	Total time.Duration
+	Max   time.Duration
+}
+
+// EnableTimings records spans for a --timings report. Tracing stays local
+// unless Init found an OTLP endpoint.
+func EnableTimings(service, version string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if active == nil {
+		active = &tracer{service: service, version: version}
+	}
+}
+
+// StripTimingsFlag removes --timings from command-line args (up to a "--"
+// separator) so commands that pass their arguments through to scripts never
+// see it, and reports whether it was present. Commands for which passthrough
+// reports true hand their arguments to another program, so stripping also
+// stops at the first non-flag argument after them: in "run mytool --timings"
+// the flag belongs to mytool. passthrough may be nil.
+func StripTimingsFlag(args []string, passthrough func(name string) bool) ([]string, bool) {
+	out := make([]string, 0, len(args))
+	found := false
+	inPassthrough := false
+	for i, arg := range args {
+		if arg == "--" || inPassthrough && !strings.HasPrefix(arg, "-") {
+			out = append(out, args[i:]...)
+			break
+		}
+		if arg == TimingsFlag {
+			found = true
+			continue
+		}
+		if passthrough != nil && !strings.HasPrefix(arg, "-") && passthrough(arg) {
+			inPassthrough = true
+		}
+		out = append(out, arg)
+	}
+	return out, found
+}
+
+// Timings returns finished spans grouped by name, longest total first, and
+// the duration of the command span. Nested phases overlap their parents
+// (e.g. "k3s drain" contains "kubectl drain").
+func Timings() ([]Phase, time.Duration) {
+	t := current()
+	if t == nil {
+		return nil, 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var total time.Duration
+	byName := map[string]*Phase{}
+	for _, s := range t.spans {
+		d := s.end.Sub(s.start)
+		if s == t.root {
+			total = d
+			continue
+		}
+		p := byName[s.name]
+		if p == nil {
+			p = &Phase{Name: s.name}
+			byName[s.name] = p
+		}
+		p.Calls++
+		p.Total += d
+		if d > p.Max {
+			p.Max = d
+		}
+	}
+	phases := make([]Phase, 0, len(byName))
+	for _, p := range byName {
+		phases = append(phases, *p)
+	}
+	sort.Slice(phases, func(i, j int) bool {
+		if phases[i].Total != phases[j].Total {
+			return phases[i].Total > phases[j].Total
+		}
+		return phases[i].Name < phases[j].Name
+	})
+	return phases, total
+}
+
+// WriteTimings prints the phase breakdown
+func WriteTimings(w io.Writer) {
+	phases, total := Timings()
+	fmt.Fprintf(w, "\ntimings (total %s):\n", round(total))
+	if len(phases) == 0 {
+		fmt.Fprintln(w, "  no phases recorded")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  PHASE\tCALLS\tTOTAL\tMAX\tSHARE")
+	for _, p := range phases {
+		share := "-"
+		if total > 0 {
+			share = fmt.Sprintf("%.0f%%", float64(p.Total)*100/float64(total))
+		}
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\t%s\n", p.Name, p.Calls, round(p.Total), round(p.Max), share)
+	}
+	_ = tw.Flush()
+}
+
+func round(d time.Duration) time.Duration {
+	if d >= time.Second {
+		return d.Round(10 * time.Millisecond)
+	}
+	return d.Round(100 * time.Microsecond)
+}
@@ -0,0 +1,82 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStripTimingsFlag(t *testing.T) {
+	args, found := StripTimingsFlag([]string{"install", "--timings", "redis", "--", "--timings"}, nil)
+	if !found || strings.Join(args, " ") != "install redis -- --timings" {
+		t.Errorf("StripTimingsFlag() = %v, %v", args, found)
+	}
+	if _, found := StripTimingsFlag([]string{"remote", "run", "--", "--timings"}, nil); found {
+		t.Error("StripTimingsFlag() should ignore --timings after --")
+	}
+}
+
+func TestStripTimingsFlagPassthrough(t *testing.T) {
+	passthrough := func(name string) bool { return name == "run" || name == "logs" }
+	tests := []struct {
+		args  []string
+		want  string
+		found bool
+	}{
+		{[]string{"--timings", "run", "mytool"}, "run mytool", true},
+		{[]string{"run", "--timings", "--all-pods", "mytool"}, "run --all-pods mytool", true},
+		{[]string{"run", "mytool", "--timings"}, "run mytool --timings", false},
+		{[]string{"logs", "--timings"}, "logs", true},
+		{[]string{"status", "--timings"}, "status", true},
+	}
+	for _, tt := range tests {
+		args, found := StripTimingsFlag(tt.args, passthrough)
+		if strings.Join(args, " ") != tt.want || found != tt.found {
+			t.Errorf("StripTimingsFlag(%v) = %v, %v; want %q, %v", tt.args, args, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestTimings(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	EnableTimings("netcup-claw", "test")
+	defer func() { _ = Shutdown(context.Background()) }()
+
+	ctx, root := Start(context.Background(), "netcup-claw upgrade")
+	for _, d := range []time.Duration{3 * time.Millisecond, time.Millisecond} {
+		_, span := Start(ctx, "kubectl get")
+		time.Sleep(d)
+		_ = span.End(nil)
+	}
+	_, helm := Start(ctx, "helm upgrade")
+	time.Sleep(10 * time.Millisecond)
+	_ = helm.End(nil)
+	_ = root.End(nil)
+
+	phases, total := Timings()
+	if len(phases) != 2 || phases[0].Name != "helm upgrade" || phases[1].Name != "kubectl get" {
+		t.Fatalf("phases = %+v", phases)
+	}
+	if kubectl := phases[1]; kubectl.Calls != 2 || kubectl.Max < 3*time.Millisecond || kubectl.Total < 4*time.Millisecond {
+		t.Errorf("kubectl phase = %+v", kubectl)
+	}
+	if total < 14*time.Millisecond {
+		t.Errorf("total = %s", total)
+	}
+
+	var out bytes.Buffer
+	WriteTimings(&out)
+	for _, want := range []string{"timings (total", "PHASE", "helm upgrade", "kubectl get"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// --timings alone never exports
+	if err := Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() error: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Package terraform reads the outputs of a Terraform or OpenTofu state file,
+// or of `terraform output -json`, and maps them to netcup-kube settings.
+package terraform
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output is one root module output
+type Output struct {
+	Value     any  `json:"value"`
+	Sensitive bool `json:"sensitive"`
+}
+
+// ClusterName is the pseudo variable an output maps to when it names the
+// cluster registry entry
+const ClusterName = "CLUSTER_NAME"
+
+// DefaultMapping lists, per setting, the output names that are looked up when
+// no --map is given, in order of preference
+var DefaultMapping = []struct {
+	Var     string
+	Outputs []string
+}{
+	{"MGMT_HOST", []string{"mgmt_host", "management_host", "server_hostname", "hostname", "fqdn"}},
+	{"MGMT_IP", []string{"mgmt_ip", "management_ip", "server_ip", "server_ipv4", "ipv4_address", "public_ip", "ip"}},
+	{"MGMT_USER", []string{"mgmt_user", "ssh_user", "admin_user"}},
+	{"NODE_IP", []string{"node_ip", "private_ip", "server_private_ip"}},
+	{"BASE_DOMAIN", []string{"base_domain", "dns_zone", "zone", "domain"}},
+	{"SSH_IDENTITY_FILE", []string{"ssh_private_key_path", "ssh_key_path", "private_key_path", "ssh_key_file"}},
+	{ClusterName, []string{"cluster_name", "name"}},
+}
+
+// Parse reads the outputs from a state file (version 3 or 4) or from
+// `terraform output -json`
+func Parse(data []byte) (map[string]Output, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return nil, fmt.Errorf("not a Terraform state or outputs file: %w", err)
+	}
+
+	if _, ok := probe["terraform_version"]; ok {
+		var state struct {
+			Version int               `json:"version"`
+			Outputs map[string]Output `json:"outputs"`
+			Modules []struct {
+				Path    []string          `json:"path"`
+				Outputs map[string]Output `json:"outputs"`
+			} `json:"modules"`
+		}
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("invalid Terraform state: %w", err)
+		}
+		if state.Version < 4 {
+			// Version 3 keeps root outputs in the "root" module
+			for _, m := range state.Modules {
+				if len(m.Path) == 1 && m.Path[0] == "root" {
+					return nonNil(m.Outputs), nil
+				}
+			}
+		}
+		return nonNil(state.Outputs), nil
+	}
+
+	outputs := make(map[string]Output, len(probe))
+	for name, raw := range probe {
+		var o Output
+		if err := json.Unmarshal(raw, &o); err != nil {
+			return nil, fmt.Errorf("invalid output %q: %w", name, err)
+		}
+		outputs[name] = o
+	}
+	return outputs, nil
+}
+
+func nonNil(outputs map[string]Output) map[string]Output {
+	if outputs == nil {
+		return map[string]Output{}
+	}
+	return outputs
+}
+
+// Assignment is a setting taken from an output
+type Assignment struct {
+	Var    string `json:"var"`
+	Output string `json:"output"`
+	Value  string `json:"value"`
+}
+
+// Result is the outcome of Map
+type Result struct {
+	Assignments []Assignment `json:"assignments"`
+	// Unmapped lists outputs that no setting was taken from
+	Unmapped []string `json:"unmapped,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// Value returns the value assigned to name
+func (r Result) Value(name string) (string, bool) {
+	for _, a := range r.Assignments {
+		if a.Var == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Map picks settings from outputs: explicit maps ("output=VAR") first, then
+// DefaultMapping for settings they do not cover. A list output contributes its
+// first element.
+func Map(outputs map[string]Output, explicit []string) (Result, error) {
+	var res Result
+	used := map[string]bool{}
+	assigned := map[string]bool{}
+
+	take := func(name, variable string) bool {
+		o, ok := outputs[name]
+		if !ok {
+			return false
+		}
+		value, err := scalar(o.Value)
+		if err != nil {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("output %s: %v", name, err))
+			return false
+		}
+		if list, ok := o.Value.([]any); ok && len(list) > 1 {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("output %s has %d values; using the first for %s", name, len(list), variable))
+		}
+		res.Assignments = append(res.Assignments, Assignment{Var: variable, Output: name, Value: value})
+		used[name] = true
+		assigned[variable] = true
+		return true
+	}
+
+	for _, m := range explicit {
+		name, variable, ok := strings.Cut(m, "=")
+		name, variable = strings.TrimSpace(name), strings.TrimSpace(variable)
+		if !ok || name == "" || variable == "" {
+			return res, fmt.Errorf("invalid mapping %q (expected OUTPUT=VAR)", m)
+		}
+		if _, ok := outputs[name]; !ok {
+			return res, fmt.Errorf("output %q not found (available: %s)", name, strings.Join(sortedNames(outputs), ", "))
+		}
+		if assigned[variable] {
+			return res, fmt.Errorf("%s is mapped more than once", variable)
+		}
+		if !take(name, variable) {
+			return res, fmt.Errorf("output %q cannot be used for %s", name, variable)
+		}
+	}
+	for _, d := range DefaultMapping {
+		if assigned[d.Var] {
+			continue
+		}
+		for _, name := range d.Outputs {
+			if !used[name] && take(name, d.Var) {
+				break
+			}
+		}
+	}
+
+	for _, name := range sortedNames(outputs) {
+		if !used[name] {
+			res.Unmapped = append(res.Unmapped, name)
+		}
+	}
+	return res, nil
+}
+
+// scalar converts an output value to an env file value
+func scalar(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		if len(v) == 0 {
+			return "", fmt.Errorf("empty list")
+		}
+		if _, nested := v[0].([]any); nested {
+			return "", fmt.Errorf("nested lists are not supported")
+		}
+		return scalar(v[0])
+	case map[string]any:
+		return "", fmt.Errorf("objects are not supported")
+	case nil:
+		return "", fmt.Errorf("value is null")
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+func sortedNames(outputs map[string]Output) []string {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
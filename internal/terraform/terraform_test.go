@@ -0,0 +1,87 @@
+package terraform
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseState(t *testing.T) {
+	state := `{
+  "version": 4,
+  "terraform_version": "1.8.5",
+  "outputs": {
+    "server_ip": {"value": "203.0.113.10", "type": "string"},
+    "dns_zone": {"value": "example.com", "type": "string", "sensitive": false}
+  },
+  "resources": []
+}`
+	outputs, err := Parse([]byte(state))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 2 || outputs["server_ip"].Value != "203.0.113.10" {
+		t.Errorf("Parse() = %v", outputs)
+	}
+
+	v3 := `{"version": 3, "terraform_version": "0.11.14", "modules": [
+  {"path": ["root", "net"], "outputs": {"ip": {"value": "10.0.0.1"}}},
+  {"path": ["root"], "outputs": {"server_ip": {"value": "203.0.113.20"}}}]}`
+	if outputs, err = Parse([]byte(v3)); err != nil || outputs["server_ip"].Value != "203.0.113.20" || len(outputs) != 1 {
+		t.Errorf("Parse(v3) = %v, %v", outputs, err)
+	}
+
+	if _, err := Parse([]byte("ip = 1.2.3.4")); err == nil {
+		t.Error("Parse() accepted HCL")
+	}
+}
+
+func TestMap(t *testing.T) {
+	outputs, err := Parse([]byte(`{
+  "server_ips": {"value": ["203.0.113.10", "203.0.113.11"], "type": ["list", "string"]},
+  "zone_name": {"value": "example.com", "type": "string"},
+  "ssh_key_path": {"value": "~/.ssh/netcup", "type": "string"},
+  "cluster_name": {"value": "prod", "type": "string"},
+  "ssh_user": {"value": {"name": "ops"}, "type": ["object", {"name": "string"}]},
+  "project": {"value": "netcup", "type": "string"}
+}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Map(outputs, []string{"server_ips=MGMT_IP", "zone_name=BASE_DOMAIN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"MGMT_IP":           "203.0.113.10",
+		"BASE_DOMAIN":       "example.com",
+		"SSH_IDENTITY_FILE": "~/.ssh/netcup",
+		ClusterName:         "prod",
+	} {
+		if got, ok := res.Value(name); !ok || got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, ok := res.Value("MGMT_USER"); ok {
+		t.Error("an object output was mapped to MGMT_USER")
+	}
+	if strings.Join(res.Unmapped, ",") != "project,ssh_user" {
+		t.Errorf("Unmapped = %v", res.Unmapped)
+	}
+	warnings := strings.Join(res.Warnings, "\n")
+	if !strings.Contains(warnings, "server_ips has 2 values") || !strings.Contains(warnings, "ssh_user: objects are not supported") {
+		t.Errorf("Warnings = %q", warnings)
+	}
+}
+
+func TestMapErrors(t *testing.T) {
+	outputs := map[string]Output{"ip": {Value: "203.0.113.10"}, "fqdn": {Value: "kube.example.com"}}
+	for _, maps := range [][]string{
+		{"ip"},
+		{"missing=MGMT_IP"},
+		{"ip=MGMT_IP", "fqdn=MGMT_IP"},
+	} {
+		if _, err := Map(outputs, maps); err == nil {
+			t.Errorf("Map(%v) succeeded", maps)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+// Package testkit runs command-level tests without a cluster. It puts fake
+// kubectl and helm executables first on PATH that answer from recorded
+// responses (fixtures) and log every invocation, so a test can drive a whole
+// flow (upgrade, config deploy, agents backup) and assert on the calls.
+//
+// The fakes re-execute the test binary, so a package using the kit must hand
+// its TestMain to Main:
+//
+//	func TestMain(m *testing.M) { testkit.Main(m) }
+//
+// Fixtures are JSON arrays of Rule. With NETCUP_TESTKIT_RECORD=1 the fakes
+// pass every call through to the real tools instead and Record writes the
+// responses as a fixture file, to be trimmed and committed.
+package testkit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// RecordEnv makes the fakes call the real tools and record the responses
+	RecordEnv = "NETCUP_TESTKIT_RECORD"
+
+	dirEnv      = "NETCUP_TESTKIT_DIR"
+	toolEnv     = "NETCUP_TESTKIT_TOOL"
+	realPathEnv = "NETCUP_TESTKIT_REAL_PATH"
+
+	rulesFile    = "rules.json"
+	callsFile    = "calls.jsonl"
+	recordedFile = "recorded.json"
+
+	// Any matches one argument of a rule; AnyRest as the last element matches
+	// the remaining arguments, none included
+	Any     = "*"
+	AnyRest = "..."
+)
+
+// Rule is a recorded response of a fake tool
+type Rule struct {
+	Tool string   `json:"tool"`
+	Args []string `json:"args"`
+	// Stdout is written as is; StdoutFile (relative to the fixture file) is
+	// written instead when set, for large or binary output such as archives
+	Stdout     string `json:"stdout,omitempty"`
+	StdoutFile string `json:"stdout_file,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	Exit       int    `json:"exit,omitempty"`
+	// Times limits how often the rule answers, so repeated calls can get
+	// different responses from consecutive rules; 0 is unlimited
+	Times int `json:"times,omitempty"`
+	// ReadStdin records the standard input of the call (e.g. kubectl apply -f -)
+	ReadStdin bool `json:"read_stdin,omitempty"`
+}
+
+// Matches reports whether the rule answers tool with args
+func (r Rule) Matches(tool string, args []string) bool {
+	if r.Tool != tool {
+		return false
+	}
+	for i, want := range r.Args {
+		if want == AnyRest && i == len(r.Args)-1 {
+			return true
+		}
+		if i >= len(args) || (want != Any && want != args[i]) {
+			return false
+		}
+	}
+	return len(args) == len(r.Args)
+}
+
+// Call is one invocation of a fake tool
+type Call struct {
+	Tool string   `json:"tool"`
+	Args []string `json:"args"`
+	// Rule is the index of the answering rule, -1 when none matched
+	Rule  int    `json:"rule"`
+	Stdin string `json:"stdin,omitempty"`
+}
+
+// String renders the call as a command line
+func (c Call) String() string {
+	return strings.TrimSpace(c.Tool + " " + strings.Join(c.Args, " "))
+}
+
+// Kit is a set of fake tools on PATH for one test
+type Kit struct {
+	t     testing.TB
+	dir   string
+	mu    sync.Mutex
+	rules []Rule
+}
+
+// New installs fake executables for tools (default: kubectl and helm) in a
+// temporary directory put first on PATH for the rest of the test
+func New(t testing.TB, tools ...string) *Kit {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("testkit fakes are shell scripts")
+	}
+	if len(tools) == 0 {
+		tools = []string{"kubectl", "helm"}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("testkit: %v", err)
+	}
+	k := &Kit{t: t, dir: t.TempDir()}
+	bin := filepath.Join(k.dir, "bin")
+	if err := os.Mkdir(bin, 0o755); err != nil {
+		t.Fatalf("testkit: %v", err)
+	}
+	for _, tool := range tools {
+		script := fmt.Sprintf("#!/bin/sh\n%s=%s exec %s \"$@\"\n", toolEnv, shellQuote(tool), shellQuote(exe))
+		if err := os.WriteFile(filepath.Join(bin, tool), []byte(script), 0o755); err != nil {
+			t.Fatalf("testkit: %v", err)
+		}
+	}
+	k.writeRules()
+	t.Setenv(dirEnv, k.dir)
+	t.Setenv(realPathEnv, os.Getenv("PATH"))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return k
+}
+
+// Recording reports whether NETCUP_TESTKIT_RECORD asks for real responses
+func Recording() bool {
+	v := strings.TrimSpace(os.Getenv(RecordEnv))
+	return v != "" && v != "0" && v != "false"
+}
+
+// Add appends rules; earlier rules win when several match
+func (k *Kit) Add(rules ...Rule) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.rules = append(k.rules, rules...)
+	k.writeRules()
+}
+
+// On adds a rule answering tool with args with stdout
+func (k *Kit) On(tool string, args []string, stdout string) {
+	k.Add(Rule{Tool: tool, Args: args, Stdout: stdout})
+}
+
+// Load adds the rules of a fixture file; StdoutFile paths are relative to it
+func (k *Kit) Load(path string) {
+	k.t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		k.t.Fatalf("testkit: %v", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		k.t.Fatalf("testkit: failed to parse %s: %v", path, err)
+	}
+	for i, r := range rules {
+		if r.StdoutFile != "" && !filepath.IsAbs(r.StdoutFile) {
+			abs, err := filepath.Abs(filepath.Join(filepath.Dir(path), r.StdoutFile))
+			if err != nil {
+				k.t.Fatalf("testkit: %v", err)
+			}
+			rules[i].StdoutFile = abs
+		}
+	}
+	k.Add(rules...)
+}
+
+// Record writes the responses recorded by the fakes to path when recording;
+// call it deferred after the flow ran. Binary output (archives) does not
+// survive JSON and has to be moved to a stdout_file by hand.
+func (k *Kit) Record(path string) {
+	k.t.Helper()
+	if !Recording() {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(k.dir, recordedFile))
+	if err != nil {
+		k.t.Fatalf("testkit: nothing recorded: %v", err)
+	}
+	var rules []Rule
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		var r Rule
+		if err := json.Unmarshal(line, &r); err != nil {
+			k.t.Fatalf("testkit: %v", err)
+		}
+		rules = append(rules, r)
+	}
+	out, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		k.t.Fatalf("testkit: %v", err)
+	}
+	if err := os.WriteFile(path, append(out, '\n'), 0o644); err != nil {
+		k.t.Fatalf("testkit: %v", err)
+	}
+	k.t.Logf("testkit: recorded %d responses to %s", len(rules), path)
+}
+
+// Calls returns the invocations of the fakes in order
+func (k *Kit) Calls() []Call {
+	k.t.Helper()
+	calls, err := readCalls(k.dir)
+	if err != nil {
+		k.t.Fatalf("testkit: %v", err)
+	}
+	return calls
+}
+
+// CallsTo returns the invocations of tool
+func (k *Kit) CallsTo(tool string) []Call {
+	var calls []Call
+	for _, c := range k.Calls() {
+		if c.Tool == tool {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Called reports whether a call matching tool and the args pattern was made
+func (k *Kit) Called(tool string, args ...string) bool {
+	pattern := Rule{Tool: tool, Args: args}
+	for _, c := range k.Calls() {
+		if pattern.Matches(c.Tool, c.Args) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertCalled fails the test unless a call matching tool and args was made
+func (k *Kit) AssertCalled(tool string, args ...string) {
+	k.t.Helper()
+	if !k.Called(tool, args...) {
+		k.t.Errorf("testkit: no call matched %s %s; calls:\n%s", tool, strings.Join(args, " "), k.callLog())
+	}
+}
+
+// AssertNotCalled fails the test when a call matching tool and args was made
+func (k *Kit) AssertNotCalled(tool string, args ...string) {
+	k.t.Helper()
+	if k.Called(tool, args...) {
+		k.t.Errorf("testkit: unexpected call matching %s %s; calls:\n%s", tool, strings.Join(args, " "), k.callLog())
+	}
+}
+
+// AssertAllMatched fails the test when a call had no fixture
+func (k *Kit) AssertAllMatched() {
+	k.t.Helper()
+	var missing []string
+	for _, c := range k.Calls() {
+		if c.Rule < 0 {
+			missing = append(missing, "  "+c.String())
+		}
+	}
+	if len(missing) > 0 {
+		k.t.Errorf("testkit: calls without a fixture:\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+func (k *Kit) callLog() string {
+	var lines []string
+	for _, c := range k.Calls() {
+		lines = append(lines, "  "+c.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (k *Kit) writeRules() {
+	data, err := json.Marshal(k.rules)
+	if err == nil {
+		err = os.WriteFile(filepath.Join(k.dir, rulesFile), data, 0o644)
+	}
+	if err != nil {
+		k.t.Fatalf("testkit: %v", err)
+	}
+}
+
+// Main runs the tests, or acts as a fake tool when the test binary was
+// started by one of the fakes of New
+func Main(m *testing.M) {
+	if tool := os.Getenv(toolEnv); tool != "" {
+		os.Exit(fake(tool, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+// fake answers one invocation of tool and returns its exit code
+func fake(tool string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	dir := os.Getenv(dirEnv)
+	if Recording() {
+		return record(dir, tool, args, stdin, stdout, stderr)
+	}
+	var rules []Rule
+	data, err := os.ReadFile(filepath.Join(dir, rulesFile))
+	if err == nil {
+		err = json.Unmarshal(data, &rules)
+	}
+	calls, callsErr := readCalls(dir)
+	if err == nil {
+		err = callsErr
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(stderr, "testkit: %v\n", err)
+		return 127
+	}
+	used := map[int]int{}
+	for _, c := range calls {
+		used[c.Rule]++
+	}
+
+	call := Call{Tool: tool, Args: args, Rule: -1}
+	for i, r := range rules {
+		if r.Matches(tool, args) && (r.Times == 0 || used[i] < r.Times) {
+			call.Rule = i
+			break
+		}
+	}
+	if call.Rule < 0 {
+		_ = appendJSONLine(filepath.Join(dir, callsFile), call)
+		_, _ = fmt.Fprintf(stderr, "testkit: no fixture for %s\n", call.String())
+		return 1
+	}
+	r := rules[call.Rule]
+	if r.ReadStdin {
+		in, _ := io.ReadAll(stdin)
+		call.Stdin = string(in)
+	}
+	if err := appendJSONLine(filepath.Join(dir, callsFile), call); err != nil {
+		_, _ = fmt.Fprintf(stderr, "testkit: %v\n", err)
+		return 127
+	}
+	if r.StdoutFile != "" {
+		out, err := os.ReadFile(r.StdoutFile)
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "testkit: %v\n", err)
+			return 127
+		}
+		_, _ = stdout.Write(out)
+	} else {
+		_, _ = io.WriteString(stdout, r.Stdout)
+	}
+	_, _ = io.WriteString(stderr, r.Stderr)
+	return r.Exit
+}
+
+// record runs the real tool and appends its response as a rule
+func record(dir, tool string, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var out, errOut bytes.Buffer
+	cmd := exec.Command(tool, args...)
+	cmd.Env = append(os.Environ(), "PATH="+os.Getenv(realPathEnv), toolEnv+"=")
+	cmd.Stdin = stdin
+	cmd.Stdout = io.MultiWriter(stdout, &out)
+	cmd.Stderr = io.MultiWriter(stderr, &errOut)
+	if path, err := lookPath(tool, os.Getenv(realPathEnv)); err == nil {
+		cmd.Path = path
+	}
+	exit := 0
+	if err := cmd.Run(); err != nil {
+		exit = 1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exit = exitErr.ExitCode()
+		}
+	}
+	r := Rule{Tool: tool, Args: args, Stdout: out.String(), Stderr: errOut.String(), Exit: exit, Times: 1}
+	_ = appendJSONLine(filepath.Join(dir, recordedFile), r)
+	_ = appendJSONLine(filepath.Join(dir, callsFile), Call{Tool: tool, Args: args})
+	return exit
+}
+
+// lookPath finds tool in path, skipping the fakes
+func lookPath(tool, path string) (string, error) {
+	for _, dir := range filepath.SplitList(path) {
+		candidate := filepath.Join(dir, tool)
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return candidate, nil
+		}
+	}
+	return "", exec.ErrNotFound
+}
+
+func readCalls(dir string) ([]Call, error) {
+	f, err := os.Open(filepath.Join(dir, callsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var calls []Call
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var c Call
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", callsFile, err)
+		}
+		calls = append(calls, c)
+	}
+	return calls, scanner.Err()
+}
+
+// appendJSONLine appends v as one line; a single write with O_APPEND keeps
+// lines of concurrent fakes intact
+func appendJSONLine(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
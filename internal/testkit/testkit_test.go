@@ -0,0 +1,206 @@
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) { Main(m) }
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		rule Rule
+		args []string
+		want bool
+	}{
+		{Rule{Tool: "kubectl", Args: []string{"get", "pods"}}, []string{"get", "pods"}, true},
+		{Rule{Tool: "kubectl", Args: []string{"get", "pods"}}, []string{"get", "pods", "-A"}, false},
+		{Rule{Tool: "kubectl", Args: []string{"get", "pods"}}, []string{"get"}, false},
+		{Rule{Tool: "kubectl", Args: []string{"get", Any}}, []string{"get", "nodes"}, true},
+		{Rule{Tool: "kubectl", Args: []string{"get", Any}}, []string{"get"}, false},
+		{Rule{Tool: "kubectl", Args: []string{"get", AnyRest}}, []string{"get"}, true},
+		{Rule{Tool: "kubectl", Args: []string{"get", AnyRest}}, []string{"get", "pods", "-A"}, true},
+		{Rule{Tool: "kubectl", Args: []string{AnyRest, "x"}}, []string{"...", "x"}, true},
+		{Rule{Tool: "helm", Args: []string{"get", "pods"}}, []string{"get", "pods"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Matches("kubectl", tt.args); got != tt.want {
+			t.Errorf("%v.Matches(%v) = %v, want %v", tt.rule, tt.args, got, tt.want)
+		}
+	}
+}
+
+func run(t *testing.T, stdin string, name string, args ...string) (stdout, stderr string, exit int) {
+	t.Helper()
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var out, errOut strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatalf("%s: %v", name, err)
+		}
+		exit = exitErr.ExitCode()
+	}
+	return out.String(), errOut.String(), exit
+}
+
+func TestFakeAnswersFromRules(t *testing.T) {
+	kit := New(t)
+	kit.Add(
+		Rule{Tool: "kubectl", Args: []string{"get", "deployment", "openclaw"}, Stdout: "progressing", Times: 1},
+		Rule{Tool: "kubectl", Args: []string{"get", "deployment", "openclaw"}, Stdout: "ready"},
+		Rule{Tool: "kubectl", Args: []string{"apply", "-f", "-"}, Stdout: "applied\n", ReadStdin: true},
+		Rule{Tool: "helm", Args: []string{"upgrade", AnyRest}, Stderr: "Error: UPGRADE FAILED\n", Exit: 1},
+	)
+
+	for _, want := range []string{"progressing", "ready", "ready"} {
+		if out, _, exit := run(t, "", "kubectl", "get", "deployment", "openclaw"); out != want || exit != 0 {
+			t.Errorf("kubectl get deployment = %q (exit %d), want %q", out, exit, want)
+		}
+	}
+	if out, _, _ := run(t, "kind: ConfigMap\n", "kubectl", "apply", "-f", "-"); out != "applied\n" {
+		t.Errorf("kubectl apply = %q", out)
+	}
+	if _, errOut, exit := run(t, "", "helm", "upgrade", "openclaw", "openclaw/openclaw"); exit != 1 || errOut != "Error: UPGRADE FAILED\n" {
+		t.Errorf("helm upgrade = %q (exit %d)", errOut, exit)
+	}
+	if _, errOut, exit := run(t, "", "kubectl", "delete", "pod", "x"); exit != 1 || !strings.Contains(errOut, "no fixture for kubectl delete pod x") {
+		t.Errorf("unmatched call = %q (exit %d)", errOut, exit)
+	}
+
+	calls := kit.Calls()
+	if len(calls) != 6 {
+		t.Fatalf("got %d calls, want 6: %v", len(calls), calls)
+	}
+	if calls[0].Rule != 0 || calls[1].Rule != 1 || calls[5].Rule != -1 {
+		t.Errorf("unexpected answering rules: %v", calls)
+	}
+	if calls[3].Stdin != "kind: ConfigMap\n" {
+		t.Errorf("stdin = %q", calls[3].Stdin)
+	}
+	if got := kit.CallsTo("helm"); len(got) != 1 || got[0].String() != "helm upgrade openclaw openclaw/openclaw" {
+		t.Errorf("CallsTo(helm) = %v", got)
+	}
+	if !kit.Called("kubectl", "apply", AnyRest) || kit.Called("kubectl", "rollout", AnyRest) {
+		t.Error("Called does not match the call log")
+	}
+}
+
+func TestLoadResolvesStdoutFiles(t *testing.T) {
+	dir := t.TempDir()
+	archive := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe}
+	if err := os.WriteFile(filepath.Join(dir, "workspace.tar.gz"), archive, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixture := `[
+  {"tool": "kubectl", "args": ["exec", "openclaw-0", "--", "tar", "-czf", "-", "."], "stdout_file": "workspace.tar.gz"},
+  {"tool": "helm", "args": ["version", "--short"], "stdout": "v3.17.0\n"}
+]`
+	if err := os.WriteFile(filepath.Join(dir, "fixture.json"), []byte(fixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	kit := New(t)
+	kit.Load(filepath.Join(dir, "fixture.json"))
+
+	if out, _, _ := run(t, "", "kubectl", "exec", "openclaw-0", "--", "tar", "-czf", "-", "."); out != string(archive) {
+		t.Errorf("binary stdout = %x, want %x", out, archive)
+	}
+	if out, _, _ := run(t, "", "helm", "version", "--short"); out != "v3.17.0\n" {
+		t.Errorf("helm version = %q", out)
+	}
+}
+
+func TestOnlyListedToolsAreFaked(t *testing.T) {
+	New(t, "kubectl")
+	if path, err := exec.LookPath("helm"); err == nil && strings.HasPrefix(path, filepath.Dir(mustLookPath(t, "kubectl"))) {
+		t.Errorf("helm resolves to a fake: %s", path)
+	}
+}
+
+func mustLookPath(t *testing.T, name string) string {
+	t.Helper()
+	path, err := exec.LookPath(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRecordPassesThroughToRealTools(t *testing.T) {
+	t.Setenv(RecordEnv, "1")
+	kit := New(t, "echo")
+	if out, _, exit := run(t, "", "echo", "ready"); out != "ready\n" || exit != 0 {
+		t.Fatalf("echo = %q (exit %d)", out, exit)
+	}
+	path := filepath.Join(t.TempDir(), "recorded.json")
+	kit.Record(path)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		t.Fatal(err)
+	}
+	want := Rule{Tool: "echo", Args: []string{"ready"}, Stdout: "ready\n", Times: 1}
+	if len(rules) != 1 || !reflect.DeepEqual(rules[0], want) {
+		t.Errorf("recorded %+v, want %+v", rules, want)
+	}
+	kit.AssertCalled("echo", "ready")
+	kit.AssertAllMatched()
+}
+
+// errorRecorder keeps the errors reported through it instead of failing
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertions(t *testing.T) {
+	rec := &errorRecorder{TB: t}
+	kit := New(rec, "kubectl")
+	kit.On("kubectl", []string{"get", "nodes"}, "node-1\n")
+	if out, _, _ := run(t, "", "kubectl", "get", "nodes"); out != "node-1\n" {
+		t.Errorf("kubectl get nodes = %q", out)
+	}
+
+	kit.AssertCalled("kubectl", "get", "nodes")
+	kit.AssertNotCalled("kubectl", "delete", AnyRest)
+	kit.AssertAllMatched()
+	if len(rec.errors) != 0 {
+		t.Fatalf("assertions on a matching call log failed: %v", rec.errors)
+	}
+
+	_, _, _ = run(t, "", "kubectl", "delete", "pod", "x")
+	kit.AssertCalled("kubectl", "apply", AnyRest)
+	kit.AssertNotCalled("kubectl", "get", "nodes")
+	kit.AssertAllMatched()
+	if len(rec.errors) != 3 {
+		t.Fatalf("got %d errors, want 3: %v", len(rec.errors), rec.errors)
+	}
+	if !strings.Contains(rec.errors[0], "  kubectl get nodes\n  kubectl delete pod x") || !strings.Contains(rec.errors[2], "calls without a fixture:\n  kubectl delete pod x") {
+		t.Errorf("errors = %q", rec.errors)
+	}
+}
+
+func TestRecordingEnv(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "0": false, "false": false, "1": true, " true ": true} {
+		t.Setenv(RecordEnv, value)
+		if got := Recording(); got != want {
+			t.Errorf("Recording() with %q = %v, want %v", value, got, want)
+		}
+	}
+}
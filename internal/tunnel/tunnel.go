@@ -1,11 +1,16 @@
 package tunnel
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/filelock"
+	"github.com/mfittko/netcup-kube/internal/interrupt"
+	"github.com/mfittko/netcup-kube/internal/paths"
 )
 
 // Manager handles SSH tunnel operations
@@ -15,6 +20,9 @@ type Manager struct {
 	LocalPort  string
 	RemoteHost string
 	RemotePort string
+	// BindAddress is the local address to listen on, e.g. 127.0.0.1 or ::1;
+	// empty leaves it to ssh (localhost, both families where available)
+	BindAddress string
 }
 
 // New creates a new tunnel manager
@@ -28,34 +36,60 @@ func New(user, host, localPort, remoteHost, remotePort string) *Manager {
 	}
 }
 
-// GetControlSocket returns the path to the SSH ControlMaster socket for the tunnel
+// GetControlSocket returns the path to the SSH ControlMaster socket for the
+// tunnel, in the private runtime directory of netcup-kube. A socket left in the
+// pre-XDG location (directly in $XDG_RUNTIME_DIR or /tmp) is moved there, so a
+// running tunnel stays manageable across upgrades.
 func (m *Manager) GetControlSocket() string {
-	base := os.Getenv("XDG_RUNTIME_DIR")
-	if base == "" {
-		base = "/tmp"
-	}
-
 	key := fmt.Sprintf("%s@%s-%s", m.User, m.Host, m.LocalPort)
 	key = strings.ReplaceAll(key, "@", "_")
 	key = strings.ReplaceAll(key, ":", "_")
 	key = strings.ReplaceAll(key, "/", "_")
+	name := fmt.Sprintf("netcup-kube-tunnel-%s.ctl", key)
+
+	legacyBase := os.Getenv("XDG_RUNTIME_DIR")
+	if legacyBase == "" {
+		legacyBase = "/tmp"
+	}
+	legacy := filepath.Join(legacyBase, name)
 
-	return filepath.Join(base, fmt.Sprintf("netcup-kube-tunnel-%s.ctl", key))
+	dir := paths.RuntimeDir("netcup-kube")
+	if err := paths.EnsurePrivateDir(dir); err != nil {
+		return legacy
+	}
+	return paths.Migrate(legacy, filepath.Join(dir, name))
+}
+
+// lock serializes starting and stopping the tunnel across invocations, so
+// two of them never race for the control socket and local port
+func (m *Manager) lock() (*filelock.Lock, error) {
+	l, err := filelock.Acquire(m.GetControlSocket(), filelock.DefaultTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock tunnel: %w", err)
+	}
+	return l, nil
 }
 
 // IsRunning checks if the tunnel is currently running
-func (m *Manager) IsRunning() bool {
+func (m *Manager) IsRunning(ctx context.Context) bool {
 	ctlSocket := m.GetControlSocket()
-	checkCmd := exec.Command("ssh", "-S", ctlSocket, "-O", "check", fmt.Sprintf("%s@%s", m.User, m.Host))
+	checkCmd := interrupt.Command(ctx, "ssh", "-S", ctlSocket, "-O", "check", fmt.Sprintf("%s@%s", m.User, m.Host))
 	checkCmd.Stdout = nil
 	checkCmd.Stderr = nil
 	return checkCmd.Run() == nil
 }
 
-// Start starts the SSH tunnel
-func (m *Manager) Start() error {
+// Start starts the SSH tunnel; ssh is stopped if ctx is canceled before the
+// tunnel is up
+func (m *Manager) Start(ctx context.Context) error {
+	l, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
 	// Check if already running
-	if m.IsRunning() {
+	if m.IsRunning(ctx) {
 		return nil
 	}
 
@@ -66,10 +100,10 @@ func (m *Manager) Start() error {
 
 	// Start the tunnel
 	ctlSocket := m.GetControlSocket()
-	tunnelCmd := exec.Command("ssh",
+	tunnelCmd := interrupt.Command(ctx, "ssh",
 		"-M", "-S", ctlSocket,
 		"-fN",
-		"-L", fmt.Sprintf("%s:%s:%s", m.LocalPort, m.RemoteHost, m.RemotePort),
+		"-L", m.ForwardSpec(),
 		fmt.Sprintf("%s@%s", m.User, m.Host),
 		"-o", "ControlPersist=yes",
 		"-o", "ExitOnForwardFailure=yes",
@@ -84,14 +118,38 @@ func (m *Manager) Start() error {
 	return nil
 }
 
-// Stop stops the SSH tunnel
-func (m *Manager) Stop() error {
-	if !m.IsRunning() {
+// ForwardSpec returns the ssh -L argument, bracketing IPv6 addresses
+// ([::1]:6443:[::1]:6443)
+func (m *Manager) ForwardSpec() string {
+	spec := fmt.Sprintf("%s:%s:%s", m.LocalPort, bracketIPv6(m.RemoteHost), m.RemotePort)
+	if m.BindAddress != "" {
+		spec = bracketIPv6(m.BindAddress) + ":" + spec
+	}
+	return spec
+}
+
+func bracketIPv6(host string) string {
+	if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+		return "[" + host + "]"
+	}
+	return host
+}
+
+// Stop stops the SSH tunnel. Callers cleaning up after Ctrl-C pass a
+// context from interrupt.CleanupContext.
+func (m *Manager) Stop(ctx context.Context) error {
+	l, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = l.Release() }()
+
+	if !m.IsRunning(ctx) {
 		return nil
 	}
 
 	ctlSocket := m.GetControlSocket()
-	exitCmd := exec.Command("ssh", "-S", ctlSocket, "-O", "exit", fmt.Sprintf("%s@%s", m.User, m.Host))
+	exitCmd := interrupt.Command(ctx, "ssh", "-S", ctlSocket, "-O", "exit", fmt.Sprintf("%s@%s", m.User, m.Host))
 	exitCmd.Stdout = nil
 	exitCmd.Stderr = nil
 
@@ -99,8 +157,8 @@ func (m *Manager) Stop() error {
 }
 
 // Status returns information about the tunnel status
-func (m *Manager) Status() (running bool, listenPort string) {
-	running = m.IsRunning()
+func (m *Manager) Status(ctx context.Context) (running bool, listenPort string) {
+	running = m.IsRunning(ctx)
 	if running {
 		listenPort = m.LocalPort
 	}
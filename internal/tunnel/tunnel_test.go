@@ -1,6 +1,7 @@
 package tunnel
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -35,6 +36,24 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestForwardSpec(t *testing.T) {
+	tests := []struct {
+		bind, remoteHost, want string
+	}{
+		{"", "127.0.0.1", "6443:127.0.0.1:6443"},
+		{"127.0.0.1", "127.0.0.1", "127.0.0.1:6443:127.0.0.1:6443"},
+		{"::1", "::1", "[::1]:6443:[::1]:6443"},
+		{"[::1]", "localhost", "[::1]:6443:localhost:6443"},
+	}
+	for _, tt := range tests {
+		mgr := New("u", "h", "6443", tt.remoteHost, "6443")
+		mgr.BindAddress = tt.bind
+		if got := mgr.ForwardSpec(); got != tt.want {
+			t.Errorf("ForwardSpec(bind=%q, remote=%q) = %q, want %q", tt.bind, tt.remoteHost, got, tt.want)
+		}
+	}
+}
+
 func TestGetControlSocket(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -66,6 +85,10 @@ func TestGetControlSocket(t *testing.T) {
 		},
 	}
 
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("NETCUP_KUBE_RUNTIME_DIR", "")
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mgr := New(tt.user, tt.host, tt.localPort, "127.0.0.1", "6443")
@@ -82,27 +105,43 @@ func TestGetControlSocket(t *testing.T) {
 				t.Errorf("GetControlSocket() filename = %v, want %v", base, tt.wantBase)
 			}
 
-			// Verify it's in either XDG_RUNTIME_DIR or /tmp
-			dir := filepath.Dir(got)
-			xdgRuntime := os.Getenv("XDG_RUNTIME_DIR")
-			if xdgRuntime != "" {
-				if dir != xdgRuntime {
-					t.Errorf("GetControlSocket() dir = %v, want %v", dir, xdgRuntime)
-				}
-			} else {
-				if dir != "/tmp" {
-					t.Errorf("GetControlSocket() dir = %v, want /tmp", dir)
-				}
+			// Verify it's in the private runtime directory of netcup-kube
+			if dir, want := filepath.Dir(got), filepath.Join(runtimeDir, "netcup-kube"); dir != want {
+				t.Errorf("GetControlSocket() dir = %v, want %v", dir, want)
 			}
 		})
 	}
 }
 
+func TestGetControlSocketMigratesLegacySocket(t *testing.T) {
+	runtimeDir := t.TempDir()
+	t.Setenv("XDG_RUNTIME_DIR", runtimeDir)
+	t.Setenv("NETCUP_KUBE_RUNTIME_DIR", "")
+
+	legacy := filepath.Join(runtimeDir, "netcup-kube-tunnel-ops_example.com-6443.ctl")
+	if err := os.WriteFile(legacy, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got := New("ops", "example.com", "6443", "127.0.0.1", "6443").GetControlSocket()
+	if want := filepath.Join(runtimeDir, "netcup-kube", filepath.Base(legacy)); got != want {
+		t.Fatalf("GetControlSocket() = %v, want %v", got, want)
+	}
+	if _, err := os.Stat(got); err != nil {
+		t.Errorf("socket was not moved: %v", err)
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("legacy socket still exists: %v", err)
+	}
+}
+
 func TestIsRunning(t *testing.T) {
+	t.Setenv("NETCUP_KUBE_RUNTIME_DIR", t.TempDir())
+
 	// Test with a tunnel that definitely doesn't exist
 	mgr := New("nonexistent-user", "nonexistent-host.invalid", "99999", "127.0.0.1", "6443")
 
-	running := mgr.IsRunning()
+	running := mgr.IsRunning(context.Background())
 	// Should return false for a non-existent tunnel
 	if running {
 		t.Error("IsRunning() = true for non-existent tunnel, want false")
@@ -113,7 +152,7 @@ func TestStatus(t *testing.T) {
 	// Test with a tunnel that doesn't exist
 	mgr := New("testuser", "test.example.com", "6443", "127.0.0.1", "6443")
 
-	running, port := mgr.Status()
+	running, port := mgr.Status(context.Background())
 
 	// Should return false and empty port for non-running tunnel
 	if running {
@@ -133,7 +172,7 @@ func TestStart_PortInUse(t *testing.T) {
 	mgr := New("testuser", "nonexistent-host.invalid", "99999", "127.0.0.1", "6443")
 
 	// This should fail because the host doesn't exist
-	err := mgr.Start()
+	err := mgr.Start(context.Background())
 	// We expect an error because the host is invalid
 	if err == nil {
 		t.Log("Start() succeeded unexpectedly (may have failed at SSH step, which is expected)")
@@ -144,7 +183,7 @@ func TestStop_NotRunning(t *testing.T) {
 	// Test stopping a tunnel that isn't running
 	mgr := New("testuser", "test.example.com", "6443", "127.0.0.1", "6443")
 
-	err := mgr.Stop()
+	err := mgr.Stop(context.Background())
 	// Should return nil when tunnel isn't running
 	if err != nil {
 		t.Errorf("Stop() error = %v for non-running tunnel, want nil", err)
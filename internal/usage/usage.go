@@ -0,0 +1,192 @@
+// Package usage keeps opt-in usage statistics of netcup-kube and netcup-claw
+// for the operator's own review: which commands run, how long they take and
+// how often they fail. Entries are appended to usage.jsonl in the state
+// directory of the CLI and never leave the machine; arguments are not
+// recorded. Recording is enabled while that file exists.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/paths"
+)
+
+// FileName is the statistics file in the state directory of a CLI
+const FileName = "usage.jsonl"
+
+// Entry is one recorded command run
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	DurationMS int64     `json:"duration_ms"`
+	Success    bool      `json:"success"`
+}
+
+// Path returns the statistics file of app
+func Path(app string) string {
+	return filepath.Join(paths.StateDir(app), FileName)
+}
+
+// Enabled reports whether statistics are recorded to path
+func Enabled(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Enable creates the statistics file, which turns recording on
+func Enable(path string) error {
+	if err := paths.EnsurePrivateDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// Disable turns recording off and deletes the recorded statistics
+func Disable(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
+}
+
+// Reset deletes the recorded statistics but keeps recording on
+func Reset(path string) error {
+	if !Enabled(path) {
+		return nil
+	}
+	if err := os.Truncate(path, 0); err != nil {
+		return fmt.Errorf("failed to reset %s: %w", path, err)
+	}
+	return nil
+}
+
+// Record appends e to path if recording is enabled; otherwise it does nothing
+func Record(path string, e Entry) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	// One write per entry: appends of a short line do not interleave with
+	// concurrent invocations
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the entries recorded at or after since, skipping lines that
+// cannot be parsed (e.g. cut off by a crash)
+func Load(path string, since time.Time) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Command == "" {
+			continue
+		}
+		if e.Time.Before(since) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// CommandStats summarizes the runs of one command
+type CommandStats struct {
+	Command     string    `json:"command"`
+	Runs        int       `json:"runs"`
+	Failures    int       `json:"failures"`
+	FailureRate float64   `json:"failure_rate"`
+	AvgMS       int64     `json:"avg_ms"`
+	MaxMS       int64     `json:"max_ms"`
+	LastRun     time.Time `json:"last_run"`
+}
+
+// Summarize groups entries by command, most-used first
+func Summarize(entries []Entry) []CommandStats {
+	byCommand := map[string]*CommandStats{}
+	totals := map[string]int64{}
+	for _, e := range entries {
+		s := byCommand[e.Command]
+		if s == nil {
+			s = &CommandStats{Command: e.Command}
+			byCommand[e.Command] = s
+		}
+		s.Runs++
+		if !e.Success {
+			s.Failures++
+		}
+		totals[e.Command] += e.DurationMS
+		if e.DurationMS > s.MaxMS {
+			s.MaxMS = e.DurationMS
+		}
+		if e.Time.After(s.LastRun) {
+			s.LastRun = e.Time
+		}
+	}
+
+	stats := make([]CommandStats, 0, len(byCommand))
+	for _, s := range byCommand {
+		s.FailureRate = float64(s.Failures) / float64(s.Runs)
+		s.AvgMS = totals[s.Command] / int64(s.Runs)
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Runs != stats[j].Runs {
+			return stats[i].Runs > stats[j].Runs
+		}
+		return stats[i].Command < stats[j].Command
+	})
+	return stats
+}
+
+// PrintSummary writes stats as a table
+func PrintSummary(w io.Writer, stats []CommandStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "COMMAND\tRUNS\tFAILED\tAVG\tMAX\tLAST RUN")
+	for _, s := range stats {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d (%.0f%%)\t%s\t%s\t%s\n", s.Command, s.Runs, s.Failures, s.FailureRate*100,
+			formatMS(s.AvgMS), formatMS(s.MaxMS), s.LastRun.Local().Format("2006-01-02 15:04"))
+	}
+	return tw.Flush()
+}
+
+func formatMS(ms int64) string {
+	d := time.Duration(ms) * time.Millisecond
+	if d >= time.Second {
+		return d.Round(100 * time.Millisecond).String()
+	}
+	return d.String()
+}
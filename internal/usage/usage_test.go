@@ -0,0 +1,106 @@
+package usage
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordOptIn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", FileName)
+	e := Entry{Time: time.Now().UTC(), Command: "install redis", DurationMS: 1200, Success: true}
+
+	if err := Record(path, e); err != nil {
+		t.Fatalf("Record() without opt-in: %v", err)
+	}
+	if Enabled(path) {
+		t.Fatal("Record() created the statistics file without opt-in")
+	}
+
+	if err := Enable(path); err != nil {
+		t.Fatalf("Enable(): %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("file mode = %o, want 600", perm)
+	}
+	if err := Record(path, e); err != nil {
+		t.Fatalf("Record(): %v", err)
+	}
+	entries, err := Load(path, time.Time{})
+	if err != nil || len(entries) != 1 || entries[0].Command != "install redis" {
+		t.Fatalf("Load() = %+v, %v", entries, err)
+	}
+
+	if err := Reset(path); err != nil {
+		t.Fatalf("Reset(): %v", err)
+	}
+	if entries, _ := Load(path, time.Time{}); len(entries) != 0 || !Enabled(path) {
+		t.Errorf("after Reset() entries = %d, enabled = %v", len(entries), Enabled(path))
+	}
+
+	if err := Disable(path); err != nil {
+		t.Fatalf("Disable(): %v", err)
+	}
+	if Enabled(path) {
+		t.Error("Disable() kept the statistics file")
+	}
+	if err := Disable(path); err != nil {
+		t.Errorf("Disable() twice: %v", err)
+	}
+}
+
+func TestLoadSince(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	now := time.Now().UTC()
+	data := `{"time":"` + now.Add(-48*time.Hour).Format(time.RFC3339) + `","command":"old","duration_ms":1,"success":true}
+not json
+{"time":"` + now.Format(time.RFC3339) + `","command":"new","duration_ms":1,"success":true}
+{"time":"` + now.Format(time.RFC3339) + `","command":"cut`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Load(path, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Command != "new" {
+		t.Errorf("Load() = %+v, want only %q", entries, "new")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	stats := Summarize([]Entry{
+		{Time: t0, Command: "dns check", DurationMS: 100, Success: true},
+		{Time: t0, Command: "install redis", DurationMS: 1000, Success: false},
+		{Time: t0.Add(time.Hour), Command: "install redis", DurationMS: 3000, Success: true},
+		{Time: t0, Command: "bootstrap", DurationMS: 50, Success: true},
+	})
+	if len(stats) != 3 {
+		t.Fatalf("Summarize() = %+v", stats)
+	}
+	redis := stats[0]
+	if redis.Command != "install redis" || redis.Runs != 2 || redis.Failures != 1 || redis.FailureRate != 0.5 ||
+		redis.AvgMS != 2000 || redis.MaxMS != 3000 || !redis.LastRun.Equal(t0.Add(time.Hour)) {
+		t.Errorf("stats[0] = %+v", redis)
+	}
+	// Ties are ordered by name
+	if stats[1].Command != "bootstrap" || stats[2].Command != "dns check" {
+		t.Errorf("order = %s, %s", stats[1].Command, stats[2].Command)
+	}
+
+	var buf bytes.Buffer
+	if err := PrintSummary(&buf, stats); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "1 (50%)") || !strings.Contains(buf.String(), "2s") {
+		t.Errorf("PrintSummary() =\n%s", buf.String())
+	}
+}
@@ -54,6 +54,44 @@ func CIDR(field, value string) error {
 	return nil
 }
 
+// CIDRList validates a comma-separated list of CIDRs, e.g. one IPv4 and one
+// IPv6 range for dual-stack settings ("203.0.113.0/24,2001:db8::/64")
+func CIDRList(field, value string) error {
+	if value == "" {
+		return nil // Empty values are handled by Required()
+	}
+
+	for _, part := range strings.Split(value, ",") {
+		if _, _, err := net.ParseCIDR(strings.TrimSpace(part)); err != nil {
+			return &Error{
+				Field:       field,
+				Value:       value,
+				Message:     fmt.Sprintf("invalid CIDR notation: %q", strings.TrimSpace(part)),
+				Remediation: "Provide one or more comma-separated CIDRs (e.g., 203.0.113.0/24 or 203.0.113.0/24,2001:db8::/64)",
+			}
+		}
+	}
+	return nil
+}
+
+// IPv6CIDR validates an IPv6 CIDR (e.g., "fd00:42::/56")
+func IPv6CIDR(field, value string) error {
+	if value == "" {
+		return nil // Empty values are handled by Required()
+	}
+
+	ip, _, err := net.ParseCIDR(value)
+	if err != nil || ip.To4() != nil {
+		return &Error{
+			Field:       field,
+			Value:       value,
+			Message:     fmt.Sprintf("invalid IPv6 CIDR: %q", value),
+			Remediation: "Provide an IPv6 CIDR in the format: IP/prefix (e.g., fd00:42::/56)",
+		}
+	}
+	return nil
+}
+
 // Port validates a port number (1-65535)
 func Port(field, value string) error {
 	if value == "" {
@@ -89,6 +127,23 @@ func IP(field, value string) error {
 	return nil
 }
 
+// IPv6 validates an IPv6 address
+func IPv6(field, value string) error {
+	if value == "" {
+		return nil // Empty values are handled by Required()
+	}
+
+	if ip := net.ParseIP(value); ip == nil || ip.To4() != nil {
+		return &Error{
+			Field:       field,
+			Value:       value,
+			Message:     fmt.Sprintf("invalid IPv6 address: %q", value),
+			Remediation: "Provide a valid IPv6 address (e.g., 2001:db8::10)",
+		}
+	}
+	return nil
+}
+
 // Hostname validates a hostname or domain name
 func Hostname(field, value string) error {
 	if value == "" {
@@ -131,6 +186,127 @@ func URL(field, value string) error {
 	return nil
 }
 
+// labelNameRegex matches the name part of a Kubernetes label key and label
+// values: up to 63 alphanumerics, '-', '_' or '.', starting and ending with an
+// alphanumeric
+var labelNameRegex = regexp.MustCompile(`^[a-zA-Z0-9]([-a-zA-Z0-9_.]{0,61}[a-zA-Z0-9])?$`)
+
+// kubeletLabels are the kubernetes.io labels a kubelet may set on its own
+// node; the NodeRestriction admission plugin rejects all others
+var kubeletLabels = map[string]bool{
+	"kubernetes.io/hostname":                   true,
+	"kubernetes.io/arch":                       true,
+	"kubernetes.io/os":                         true,
+	"beta.kubernetes.io/arch":                  true,
+	"beta.kubernetes.io/os":                    true,
+	"beta.kubernetes.io/instance-type":         true,
+	"node.kubernetes.io/instance-type":         true,
+	"topology.kubernetes.io/region":            true,
+	"topology.kubernetes.io/zone":              true,
+	"failure-domain.beta.kubernetes.io/region": true,
+	"failure-domain.beta.kubernetes.io/zone":   true,
+}
+
+// labelKey checks a Kubernetes label or taint key ([prefix/]name)
+func labelKey(key string) string {
+	prefix, name, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix {
+		name, prefix = prefix, ""
+	}
+	if hasPrefix && (len(prefix) > 253 || !hostnameRegex.MatchString(prefix)) {
+		return fmt.Sprintf("invalid key prefix %q", prefix)
+	}
+	if !labelNameRegex.MatchString(name) {
+		return fmt.Sprintf("invalid key %q", key)
+	}
+	return ""
+}
+
+// nodeMaySetLabel reports whether a kubelet may register its node with the
+// label key
+func nodeMaySetLabel(key string) bool {
+	prefix, _, hasPrefix := strings.Cut(key, "/")
+	if !hasPrefix || kubeletLabels[key] {
+		return true
+	}
+	inDomain := func(domain string) bool { return prefix == domain || strings.HasSuffix(prefix, "."+domain) }
+	if inDomain("kubelet.kubernetes.io") || inDomain("node.kubernetes.io") {
+		return true
+	}
+	return !inDomain("kubernetes.io") && !inDomain("k8s.io")
+}
+
+// NodeLabels validates a comma-separated list of key=value node labels a
+// joining node can register with (e.g. "role=worker,zone=vlan")
+func NodeLabels(field, value string) error {
+	if value == "" {
+		return nil // Empty values are handled by Required()
+	}
+	for _, label := range strings.Split(value, ",") {
+		label = strings.TrimSpace(label)
+		key, val, ok := strings.Cut(label, "=")
+		msg := ""
+		switch {
+		case !ok:
+			msg = fmt.Sprintf("label %q is not key=value", label)
+		case labelKey(key) != "":
+			msg = labelKey(key)
+		case val != "" && !labelNameRegex.MatchString(val):
+			msg = fmt.Sprintf("invalid value of label %q", key)
+		}
+		if msg != "" {
+			return &Error{
+				Field:       field,
+				Value:       value,
+				Message:     msg,
+				Remediation: "Provide comma-separated key=value labels (e.g., role=worker,zone=vlan)",
+			}
+		}
+		if !nodeMaySetLabel(key) {
+			return &Error{
+				Field:       field,
+				Value:       value,
+				Message:     fmt.Sprintf("label %q cannot be set by the node itself", key),
+				Remediation: fmt.Sprintf("Use a label outside kubernetes.io/k8s.io, or set it from the server: kubectl label node <name> %s", label),
+			}
+		}
+	}
+	return nil
+}
+
+// NodeTaints validates a comma-separated list of node taints in the form
+// key[=value]:Effect (e.g. "dedicated=batch:NoSchedule")
+func NodeTaints(field, value string) error {
+	if value == "" {
+		return nil // Empty values are handled by Required()
+	}
+	for _, taint := range strings.Split(value, ",") {
+		taint = strings.TrimSpace(taint)
+		rest, effect, ok := strings.Cut(taint, ":")
+		key, val, _ := strings.Cut(rest, "=")
+		msg := ""
+		switch {
+		case !ok:
+			msg = fmt.Sprintf("taint %q has no effect", taint)
+		case effect != "NoSchedule" && effect != "PreferNoSchedule" && effect != "NoExecute":
+			msg = fmt.Sprintf("invalid effect %q of taint %q", effect, key)
+		case labelKey(key) != "":
+			msg = labelKey(key)
+		case val != "" && !labelNameRegex.MatchString(val):
+			msg = fmt.Sprintf("invalid value of taint %q", key)
+		}
+		if msg != "" {
+			return &Error{
+				Field:       field,
+				Value:       value,
+				Message:     msg,
+				Remediation: "Provide comma-separated key[=value]:Effect taints with effect NoSchedule, PreferNoSchedule or NoExecute (e.g., dedicated=batch:NoSchedule)",
+			}
+		}
+	}
+	return nil
+}
+
 // Required validates that a field is not empty
 func Required(field, value string) error {
 	if value == "" {
@@ -248,6 +248,35 @@ func TestIP(t *testing.T) {
 	}
 }
 
+func TestIPv6AndCIDRList(t *testing.T) {
+	tests := []struct {
+		name    string
+		fn      func(field, value string) error
+		value   string
+		wantErr bool
+	}{
+		{"IPv6 address", IPv6, "2001:db8::10", false},
+		{"IPv6 rejects IPv4", IPv6, "203.0.113.10", true},
+		{"IPv6 CIDR", IPv6CIDR, "fd00:42::/56", false},
+		{"IPv6 CIDR rejects IPv4", IPv6CIDR, "10.42.0.0/16", true},
+		{"CIDR list dual-stack", CIDRList, "203.0.113.0/24, 2001:db8::/64", false},
+		{"CIDR list single", CIDRList, "2001:db8::1/128", false},
+		{"CIDR list bad entry", CIDRList, "203.0.113.0/24,2001:db8::", true},
+		{"empty", CIDRList, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fn("FIELD", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if valErr, ok := err.(*Error); err != nil && (!ok || valErr.Remediation == "") {
+				t.Errorf("error %v is not an *Error with remediation", err)
+			}
+		})
+	}
+}
+
 func TestHostname(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -626,3 +655,48 @@ func TestError_Error(t *testing.T) {
 		}
 	})
 }
+
+func TestNodeLabels(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"", false},
+		{"role=worker,zone=vlan", false},
+		{"example.com/class=batch, empty=", false},
+		{"topology.kubernetes.io/zone=fsn1", false},
+		{"node.kubernetes.io/pool=a", false},
+		{"role", true},
+		{"-bad=x", true},
+		{"role=bad value", true},
+		{"bad_prefix!/x=y", true},
+		{"node-role.kubernetes.io/worker=true", true},
+		{"k8s.io/x=y", true},
+	}
+	for _, tt := range tests {
+		err := NodeLabels("NODE_LABELS", tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NodeLabels(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNodeTaints(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"", false},
+		{"dedicated=batch:NoSchedule", false},
+		{"gpu:NoExecute,spot=true:PreferNoSchedule", false},
+		{"dedicated=batch", true},
+		{"dedicated=batch:Never", true},
+		{"=batch:NoSchedule", true},
+	}
+	for _, tt := range tests {
+		err := NodeTaints("NODE_TAINTS", tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NodeTaints(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
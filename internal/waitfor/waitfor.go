@@ -0,0 +1,209 @@
+// Package waitfor polls readiness conditions (deployment rollouts, ready pods,
+// healthy HTTP endpoints) with exponential backoff until they hold or a
+// timeout expires.
+package waitfor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultInitialInterval is the delay after the first failed check
+	DefaultInitialInterval = time.Second
+
+	// DefaultMaxInterval caps the delay between checks
+	DefaultMaxInterval = 15 * time.Second
+)
+
+// Kubectl runs kubectl against the cluster
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Condition reports whether the awaited state holds. detail describes the
+// current state for progress and timeout messages. A returned error stops
+// polling immediately.
+type Condition func(ctx context.Context) (ready bool, detail string, err error)
+
+// Backoff controls the delay between checks
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// DefaultBackoff doubles from one second up to fifteen seconds
+var DefaultBackoff = Backoff{Initial: DefaultInitialInterval, Max: DefaultMaxInterval}
+
+// next returns the delay after delay, doubled and capped at Max
+func (b Backoff) next(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return b.Initial
+	}
+	delay *= 2
+	if b.Max > 0 && delay > b.Max {
+		delay = b.Max
+	}
+	return delay
+}
+
+// Poll checks cond until it is ready, it fails, or timeout expires. progress,
+// if set, is called with the detail of every check that is not ready yet.
+func Poll(ctx context.Context, cond Condition, timeout time.Duration, backoff Backoff, progress func(detail string)) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var delay time.Duration
+	detail := ""
+	for {
+		ready, d, err := cond(ctx)
+		if err != nil {
+			return err
+		}
+		if ready {
+			return nil
+		}
+		detail = d
+		if progress != nil {
+			progress(detail)
+		}
+
+		delay = backoff.next(delay)
+		select {
+		case <-ctx.Done():
+			if detail != "" {
+				return fmt.Errorf("timed out after %s: %s", timeout, detail)
+			}
+			return fmt.Errorf("timed out after %s", timeout)
+		case <-time.After(delay):
+		}
+	}
+}
+
+type deploymentStatus struct {
+	Metadata struct {
+		Generation int64 `json:"generation"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int32 `json:"replicas"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration int64 `json:"observedGeneration"`
+		Replicas           int32 `json:"replicas"`
+		UpdatedReplicas    int32 `json:"updatedReplicas"`
+		ReadyReplicas      int32 `json:"readyReplicas"`
+		AvailableReplicas  int32 `json:"availableReplicas"`
+	} `json:"status"`
+}
+
+// DeploymentReady holds once the deployment's latest generation is fully
+// rolled out: every replica updated and available and no old replicas left,
+// the same criteria as kubectl rollout status.
+func DeploymentReady(kube Kubectl, namespace, name string) Condition {
+	return func(ctx context.Context) (bool, string, error) {
+		out, err := kube.Output(ctx, "-n", namespace, "get", "deployment", name, "-o", "json")
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, "", nil
+			}
+			return false, "", fmt.Errorf("failed to read deployment %s/%s: %w", namespace, name, err)
+		}
+		var d deploymentStatus
+		if err := json.Unmarshal(out, &d); err != nil {
+			return false, "", fmt.Errorf("failed to parse deployment %s/%s: %w", namespace, name, err)
+		}
+		return deploymentRolledOut(d)
+	}
+}
+
+func deploymentRolledOut(d deploymentStatus) (bool, string, error) {
+	want := int32(1)
+	if d.Spec.Replicas != nil {
+		want = *d.Spec.Replicas
+	}
+	s := d.Status
+	switch {
+	case s.ObservedGeneration < d.Metadata.Generation:
+		return false, "waiting for the deployment spec update to be observed", nil
+	case s.UpdatedReplicas < want:
+		return false, fmt.Sprintf("%d of %d replicas updated", s.UpdatedReplicas, want), nil
+	case s.Replicas > s.UpdatedReplicas:
+		return false, fmt.Sprintf("%d old replicas pending termination", s.Replicas-s.UpdatedReplicas), nil
+	case s.AvailableReplicas < s.UpdatedReplicas:
+		return false, fmt.Sprintf("%d of %d updated replicas available", s.AvailableReplicas, s.UpdatedReplicas), nil
+	}
+	return true, fmt.Sprintf("%d of %d replicas ready", s.ReadyReplicas, want), nil
+}
+
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string  `json:"name"`
+			DeletionTimestamp *string `json:"deletionTimestamp"`
+		} `json:"metadata"`
+		Status struct {
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// PodsReady holds once at least one pod matches selector and every matching
+// pod that is not terminating has the Ready condition.
+func PodsReady(kube Kubectl, namespace, selector string) Condition {
+	return func(ctx context.Context) (bool, string, error) {
+		out, err := kube.Output(ctx, "-n", namespace, "get", "pods", "-l", selector, "-o", "json")
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, "", nil
+			}
+			return false, "", fmt.Errorf("failed to list pods %s in %s: %w", selector, namespace, err)
+		}
+		var pods podList
+		if err := json.Unmarshal(out, &pods); err != nil {
+			return false, "", fmt.Errorf("failed to parse pods: %w", err)
+		}
+		total, ready := 0, 0
+		for _, p := range pods.Items {
+			if p.Metadata.DeletionTimestamp != nil {
+				continue
+			}
+			total++
+			for _, c := range p.Status.Conditions {
+				if c.Type == "Ready" && c.Status == "True" {
+					ready++
+					break
+				}
+			}
+		}
+		detail := fmt.Sprintf("%d of %d pods matching %s ready", ready, total, selector)
+		if total == 0 {
+			return false, "no pods match " + selector, nil
+		}
+		return ready == total, detail, nil
+	}
+}
+
+// HTTPHealthy holds once a GET of url answers with a 2xx or 3xx status
+func HTTPHealthy(client *http.Client, url string) Condition {
+	return func(ctx context.Context) (bool, string, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return false, "", fmt.Errorf("invalid url %s: %w", url, err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, fmt.Sprintf("GET %s: %v", url, err), nil
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return false, fmt.Sprintf("GET %s: %s", url, resp.Status), nil
+		}
+		return true, fmt.Sprintf("GET %s: %s", url, resp.Status), nil
+	}
+}
@@ -0,0 +1,152 @@
+package waitfor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeKube struct {
+	outputs []string
+	calls   int
+}
+
+func (k *fakeKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	out := k.outputs[len(k.outputs)-1]
+	if k.calls < len(k.outputs) {
+		out = k.outputs[k.calls]
+	}
+	k.calls++
+	if strings.HasPrefix(out, "error:") {
+		return nil, fmt.Errorf("%s", out)
+	}
+	return []byte(out), nil
+}
+
+var fastBackoff = Backoff{Initial: time.Millisecond, Max: 2 * time.Millisecond}
+
+func TestBackoffNext(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 5 * time.Second}
+	var got []time.Duration
+	delay := time.Duration(0)
+	for i := 0; i < 5; i++ {
+		delay = b.next(delay)
+		got = append(got, delay)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("delays = %v, want %v", got, want)
+	}
+}
+
+func TestPoll(t *testing.T) {
+	checks := 0
+	var details []string
+	cond := func(context.Context) (bool, string, error) {
+		checks++
+		return checks == 3, fmt.Sprintf("check %d", checks), nil
+	}
+	if err := Poll(context.Background(), cond, time.Second, fastBackoff, func(d string) { details = append(details, d) }); err != nil {
+		t.Fatalf("Poll() error: %v", err)
+	}
+	if checks != 3 || len(details) != 2 {
+		t.Errorf("checks = %d, details = %v", checks, details)
+	}
+
+	never := func(context.Context) (bool, string, error) { return false, "still starting", nil }
+	err := Poll(context.Background(), never, 20*time.Millisecond, fastBackoff, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out after 20ms: still starting") {
+		t.Errorf("Poll() timeout error = %v", err)
+	}
+
+	failing := func(context.Context) (bool, string, error) { return false, "", fmt.Errorf("forbidden") }
+	if err := Poll(context.Background(), failing, time.Second, fastBackoff, nil); err == nil || err.Error() != "forbidden" {
+		t.Errorf("Poll() error = %v, want forbidden", err)
+	}
+}
+
+func deployment(generation, observed, replicas, updated, available, total int) string {
+	return fmt.Sprintf(`{"metadata":{"generation":%d},"spec":{"replicas":%d},
+"status":{"observedGeneration":%d,"replicas":%d,"updatedReplicas":%d,"readyReplicas":%d,"availableReplicas":%d}}`,
+		generation, replicas, observed, total, updated, available, available)
+}
+
+func TestDeploymentReady(t *testing.T) {
+	tests := []struct {
+		name   string
+		json   string
+		ready  bool
+		detail string
+	}{
+		{"not observed", deployment(3, 2, 1, 1, 1, 1), false, "observed"},
+		{"updating", deployment(3, 3, 2, 1, 1, 2), false, "1 of 2 replicas updated"},
+		{"old replicas", deployment(3, 3, 1, 1, 1, 2), false, "old replicas"},
+		{"unavailable", deployment(3, 3, 1, 1, 0, 1), false, "0 of 1 updated replicas available"},
+		{"rolled out", deployment(3, 3, 1, 1, 1, 1), true, "1 of 1 replicas ready"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cond := DeploymentReady(&fakeKube{outputs: []string{tt.json}}, "openclaw", "openclaw")
+			ready, detail, err := cond(context.Background())
+			if err != nil || ready != tt.ready || !strings.Contains(detail, tt.detail) {
+				t.Errorf("ready = %v, detail = %q, err = %v", ready, detail, err)
+			}
+		})
+	}
+
+	cond := DeploymentReady(&fakeKube{outputs: []string{"error: deployments.apps \"openclaw\" not found"}}, "openclaw", "openclaw")
+	if _, _, err := cond(context.Background()); err == nil {
+		t.Error("expected error for missing deployment")
+	}
+
+	kube := &fakeKube{outputs: []string{deployment(2, 1, 1, 0, 0, 1), deployment(2, 2, 1, 1, 0, 2), deployment(2, 2, 1, 1, 1, 1)}}
+	if err := Poll(context.Background(), DeploymentReady(kube, "openclaw", "openclaw"), time.Second, fastBackoff, nil); err != nil || kube.calls != 3 {
+		t.Errorf("Poll(DeploymentReady) = %v after %d calls", err, kube.calls)
+	}
+}
+
+func TestPodsReady(t *testing.T) {
+	pods := `{"items":[
+  {"metadata":{"name":"a"},"status":{"conditions":[{"type":"Ready","status":"True"}]}},
+  {"metadata":{"name":"b"},"status":{"conditions":[{"type":"Ready","status":"False"}]}},
+  {"metadata":{"name":"c","deletionTimestamp":"2026-10-16T10:00:00Z"},"status":{}}]}`
+	ready, detail, err := PodsReady(&fakeKube{outputs: []string{pods}}, "openclaw", "app=x")(context.Background())
+	if err != nil || ready || detail != "1 of 2 pods matching app=x ready" {
+		t.Errorf("ready = %v, detail = %q, err = %v", ready, detail, err)
+	}
+
+	ready, detail, err = PodsReady(&fakeKube{outputs: []string{`{"items":[]}`}}, "openclaw", "app=x")(context.Background())
+	if err != nil || ready || !strings.Contains(detail, "no pods") {
+		t.Errorf("empty: ready = %v, detail = %q, err = %v", ready, detail, err)
+	}
+
+	all := strings.Replace(pods, `"False"`, `"True"`, 1)
+	if ready, _, _ := PodsReady(&fakeKube{outputs: []string{all}}, "openclaw", "app=x")(context.Background()); !ready {
+		t.Error("expected ready when every live pod is Ready")
+	}
+}
+
+func TestHTTPHealthy(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	cond := HTTPHealthy(srv.Client(), srv.URL)
+	if ready, detail, err := cond(context.Background()); ready || err != nil || !strings.Contains(detail, "503") {
+		t.Errorf("503: ready = %v, detail = %q, err = %v", ready, detail, err)
+	}
+	status = http.StatusOK
+	if ready, _, err := cond(context.Background()); !ready || err != nil {
+		t.Errorf("200: ready = %v, err = %v", ready, err)
+	}
+
+	if ready, _, err := HTTPHealthy(srv.Client(), "http://127.0.0.1:1/")(context.Background()); ready || err != nil {
+		t.Errorf("refused: ready = %v, err = %v (want not ready without error)", ready, err)
+	}
+}
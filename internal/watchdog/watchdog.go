@@ -0,0 +1,311 @@
+// Package watchdog renders and installs a systemd timer on a node that checks
+// the health of k3s and Caddy, restarts a failed service, and posts an alert
+// webhook when a service keeps failing.
+package watchdog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mfittko/netcup-kube/internal/network"
+)
+
+const (
+	// ScriptPath is the health check run by the watchdog service
+	ScriptPath = "/usr/local/sbin/netcup-kube-watchdog"
+
+	// ServicePath and TimerPath are the systemd units of the watchdog
+	ServicePath = "/etc/systemd/system/netcup-kube-watchdog.service"
+	TimerPath   = "/etc/systemd/system/netcup-kube-watchdog.timer"
+	TimerName   = "netcup-kube-watchdog.timer"
+
+	// StateDir holds one <service>.failures counter per unhealthy service
+	StateDir = "/var/lib/netcup-kube/watchdog"
+
+	// DefaultInterval is the time between two health checks
+	DefaultInterval = 2 * time.Minute
+
+	// DefaultMaxFailures is the number of failed checks in a row after which
+	// an alert is sent
+	DefaultMaxFailures = 3
+
+	minInterval = 30 * time.Second
+)
+
+// candidateServices are watched by default when their unit is installed
+var candidateServices = []string{"k3s", "k3s-agent", "caddy"}
+
+var serviceNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.@-]*$`)
+
+// Spec is the desired watchdog configuration of a node
+type Spec struct {
+	// Services are the systemd services to watch, without ".service"
+	Services []string
+	// Interval is the time between two health checks
+	Interval time.Duration
+	// MaxFailures is the number of failed checks in a row that trigger an alert
+	MaxFailures int
+	// Webhook receives a JSON alert; empty only logs to the journal
+	Webhook string
+}
+
+// SpecFromEnv builds a Spec from WATCHDOG_SERVICES, WATCHDOG_INTERVAL,
+// WATCHDOG_MAX_FAILURES and WATCHDOG_WEBHOOK. Without WATCHDOG_SERVICES, the
+// installed services among k3s, k3s-agent and caddy are watched.
+func SpecFromEnv(env map[string]string, host network.Host) (Spec, error) {
+	get := func(key string) string { return strings.TrimSpace(env[key]) }
+	spec := Spec{
+		Services:    strings.Fields(strings.ReplaceAll(get("WATCHDOG_SERVICES"), ",", " ")),
+		Interval:    DefaultInterval,
+		MaxFailures: DefaultMaxFailures,
+		Webhook:     get("WATCHDOG_WEBHOOK"),
+	}
+
+	if raw := get("WATCHDOG_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval < minInterval {
+			return spec, fmt.Errorf("WATCHDOG_INTERVAL must be a duration of at least %s (e.g. 2m), got %q", minInterval, raw)
+		}
+		spec.Interval = interval
+	}
+	if raw := get("WATCHDOG_MAX_FAILURES"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return spec, fmt.Errorf("WATCHDOG_MAX_FAILURES must be a positive number, got %q", raw)
+		}
+		spec.MaxFailures = n
+	}
+	if spec.Webhook != "" {
+		if u, err := url.Parse(spec.Webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return spec, fmt.Errorf("WATCHDOG_WEBHOOK must be an http(s) URL, got %q", spec.Webhook)
+		}
+	}
+
+	for i, svc := range spec.Services {
+		svc = strings.TrimSuffix(svc, ".service")
+		if !serviceNameRegex.MatchString(svc) {
+			return spec, fmt.Errorf("invalid service name in WATCHDOG_SERVICES: %q", svc)
+		}
+		spec.Services[i] = svc
+	}
+	if len(spec.Services) == 0 {
+		for _, svc := range candidateServices {
+			if host.Check("systemctl", "cat", svc+".service") == nil {
+				spec.Services = append(spec.Services, svc)
+			}
+		}
+		if len(spec.Services) == 0 {
+			return spec, fmt.Errorf("none of %s is installed; set WATCHDOG_SERVICES", strings.Join(candidateServices, ", "))
+		}
+	}
+	return spec, nil
+}
+
+var scriptTemplate = template.Must(template.New("script").Funcs(template.FuncMap{"quote": shellQuote}).Parse(`#!/usr/bin/env bash
+# Generated by netcup-kube watchdog install; changes are overwritten.
+# Checks the watched services, restarts an unhealthy one and alerts once it
+# failed {{.MaxFailures}} checks in a row.
+set -uo pipefail
+
+STATE_DIR={{quote .StateDir}}
+MAX_FAILURES={{.MaxFailures}}
+WEBHOOK={{quote .Webhook}}
+HOST="$(hostname)"
+mkdir -p "${STATE_DIR}"
+
+healthy() {
+  systemctl is-active --quiet "$1.service" || return 1
+  case "$1" in
+    k3s) timeout 10 k3s kubectl get --raw=/readyz > /dev/null 2>&1 ;;
+  esac
+}
+
+alert() {
+  local svc="$1" state="$2" failures="$3" message="$4"
+  logger -t netcup-kube-watchdog "${message}"
+  [[ -n "${WEBHOOK}" ]] || return 0
+  curl -fsS -m 10 -H 'Content-Type: application/json' \
+    -d "{\"source\":\"netcup-kube-watchdog\",\"host\":\"${HOST}\",\"service\":\"${svc}\",\"state\":\"${state}\",\"failures\":${failures},\"message\":\"${message}\"}" \
+    "${WEBHOOK}" > /dev/null || logger -t netcup-kube-watchdog "failed to post alert to the webhook"
+}
+
+for svc in{{range .Services}} {{.}}{{end}}; do
+  file="${STATE_DIR}/${svc}.failures"
+  failures="$(cat "${file}" 2> /dev/null || echo 0)"
+  # Give a service that is still starting (e.g. after the last restart) time
+  [[ "$(systemctl show -p ActiveState --value "${svc}.service")" == "activating" ]] && continue
+  if healthy "${svc}"; then
+    if [[ "${failures}" -ge "${MAX_FAILURES}" ]]; then
+      alert "${svc}" recovered 0 "${svc} on ${HOST} recovered after ${failures} failed checks"
+    fi
+    rm -f "${file}"
+    continue
+  fi
+  failures=$((failures + 1))
+  echo "${failures}" > "${file}"
+  logger -t netcup-kube-watchdog "${svc} is unhealthy (${failures} failed checks in a row); restarting it"
+  systemctl restart "${svc}.service" || true
+  if [[ "${failures}" -eq "${MAX_FAILURES}" ]]; then
+    alert "${svc}" failing "${failures}" "${svc} on ${HOST} failed ${failures} health checks in a row; restarted after each"
+  fi
+done
+`))
+
+var serviceTemplate = template.Must(template.New("service").Funcs(template.FuncMap{
+	"join": func(s []string) string { return strings.Join(s, ", ") },
+}).Parse(`[Unit]
+Description=netcup-kube watchdog for {{join .Services}}
+After=network-online.target
+
+[Service]
+Type=oneshot
+ExecStart={{.ScriptPath}}
+`))
+
+var timerTemplate = template.Must(template.New("timer").Parse(`[Unit]
+Description=Run the netcup-kube watchdog every {{.Interval}}
+
+[Timer]
+OnBootSec=5min
+OnUnitActiveSec={{.Seconds}}s
+AccuracySec=5s
+
+[Install]
+WantedBy=timers.target
+`))
+
+// templateData is the input of the templates
+type templateData struct {
+	Spec
+	ScriptPath string
+	StateDir   string
+	Seconds    int
+}
+
+func (s Spec) render(t *template.Template) string {
+	var buf bytes.Buffer
+	data := templateData{Spec: s, ScriptPath: ScriptPath, StateDir: StateDir, Seconds: int(s.Interval.Seconds())}
+	if err := t.Execute(&buf, data); err != nil {
+		// The templates are fixed and the data is validated by SpecFromEnv
+		panic(fmt.Sprintf("watchdog: failed to render %s: %v", t.Name(), err))
+	}
+	return buf.String()
+}
+
+// RenderScript renders the health check script
+func (s Spec) RenderScript() string {
+	return s.render(scriptTemplate)
+}
+
+// RenderService renders the oneshot systemd service running the script
+func (s Spec) RenderService() string {
+	return s.render(serviceTemplate)
+}
+
+// RenderTimer renders the systemd timer starting the service every Interval
+func (s Spec) RenderTimer() string {
+	return s.render(timerTemplate)
+}
+
+// Apply writes the script and units that differ from the spec and enables
+// the timer. It is safe to re-run.
+func Apply(spec Spec, host network.Host, out io.Writer) error {
+	files := []struct {
+		path string
+		data string
+		perm os.FileMode
+	}{
+		{ScriptPath, spec.RenderScript(), 0o755},
+		{ServicePath, spec.RenderService(), 0o644},
+		{TimerPath, spec.RenderTimer(), 0o644},
+	}
+	changed := false
+	for _, f := range files {
+		if got, err := host.ReadFile(f.path); err == nil && string(got) == f.data {
+			continue
+		}
+		fmt.Fprintf(out, "Writing %s\n", f.path)
+		if err := host.WriteFile(f.path, []byte(f.data), f.perm); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.path, err)
+		}
+		changed = true
+	}
+	if changed {
+		if err := host.Run("systemctl", "daemon-reload"); err != nil {
+			return fmt.Errorf("failed to reload systemd: %w", err)
+		}
+	}
+	if changed || host.Check("systemctl", "is-active", "--quiet", TimerName) != nil {
+		fmt.Fprintf(out, "Enabling %s\n", TimerName)
+		if err := host.Run("systemctl", "enable", "--now", TimerName); err != nil {
+			return fmt.Errorf("failed to enable %s: %w", TimerName, err)
+		}
+		if changed {
+			// Pick up a new interval right away
+			if err := host.Run("systemctl", "restart", TimerName); err != nil {
+				return fmt.Errorf("failed to restart %s: %w", TimerName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Uninstall stops the timer and removes the script, units and failure
+// counters
+func Uninstall(host network.Host, out io.Writer) error {
+	if host.Check("systemctl", "cat", TimerName) == nil {
+		fmt.Fprintf(out, "Disabling %s\n", TimerName)
+		if err := host.Run("systemctl", "disable", "--now", TimerName); err != nil {
+			return fmt.Errorf("failed to disable %s: %w", TimerName, err)
+		}
+	}
+	fmt.Fprintln(out, "Removing the watchdog files")
+	if err := host.Run("rm", "-rf", ScriptPath, ServicePath, TimerPath, StateDir); err != nil {
+		return fmt.Errorf("failed to remove the watchdog files: %w", err)
+	}
+	if err := host.Run("systemctl", "daemon-reload"); err != nil {
+		return fmt.Errorf("failed to reload systemd: %w", err)
+	}
+	return nil
+}
+
+// Failures returns the failure counters in dir by service name; services
+// that passed their last check have no counter
+func Failures(dir string) (map[string]int, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	failures := map[string]int{}
+	for _, e := range entries {
+		svc, ok := strings.CutSuffix(e.Name(), ".failures")
+		if !ok || e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(string(data))); err == nil {
+			failures[svc] = n
+		}
+	}
+	return failures, nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
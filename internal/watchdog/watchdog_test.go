@@ -0,0 +1,171 @@
+package watchdog
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHost records writes and commands; checks pass for the installed units
+// and for the timer once it was enabled
+type fakeHost struct {
+	files     map[string]string
+	installed map[string]bool
+	runs      []string
+}
+
+func newFakeHost(installed ...string) *fakeHost {
+	h := &fakeHost{files: map[string]string{}, installed: map[string]bool{}}
+	for _, unit := range installed {
+		h.installed[unit] = true
+	}
+	return h
+}
+
+func (h *fakeHost) ReadFile(path string) ([]byte, error) {
+	data, ok := h.files[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(data), nil
+}
+
+func (h *fakeHost) WriteFile(path string, data []byte, perm os.FileMode) error {
+	h.files[path] = string(data)
+	return nil
+}
+
+func (h *fakeHost) Check(name string, args ...string) error {
+	if name == "systemctl" && h.installed[args[len(args)-1]] {
+		return nil
+	}
+	return errors.New("exit status 1")
+}
+
+func (h *fakeHost) Run(name string, args ...string) error {
+	h.runs = append(h.runs, name+" "+strings.Join(args, " "))
+	if name == "systemctl" && args[0] == "enable" {
+		h.installed[args[len(args)-1]] = true
+	}
+	return nil
+}
+
+func (h *fakeHost) Output(name string, args ...string) ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestSpecFromEnvDefaults(t *testing.T) {
+	spec, err := SpecFromEnv(map[string]string{}, newFakeHost("k3s.service", "caddy.service"))
+	if err != nil {
+		t.Fatalf("SpecFromEnv: %v", err)
+	}
+	if strings.Join(spec.Services, ",") != "k3s,caddy" {
+		t.Errorf("Services = %v, want the installed k3s and caddy", spec.Services)
+	}
+	if spec.Interval != DefaultInterval || spec.MaxFailures != DefaultMaxFailures || spec.Webhook != "" {
+		t.Errorf("spec = %+v, want defaults", spec)
+	}
+
+	if _, err := SpecFromEnv(map[string]string{}, newFakeHost()); err == nil {
+		t.Error("SpecFromEnv without any installed service: want error")
+	}
+}
+
+func TestSpecFromEnv(t *testing.T) {
+	spec, err := SpecFromEnv(map[string]string{
+		"WATCHDOG_SERVICES":     "k3s-agent.service, caddy",
+		"WATCHDOG_INTERVAL":     "45s",
+		"WATCHDOG_MAX_FAILURES": "5",
+		"WATCHDOG_WEBHOOK":      "https://ntfy.sh/cluster",
+	}, newFakeHost())
+	if err != nil {
+		t.Fatalf("SpecFromEnv: %v", err)
+	}
+	if strings.Join(spec.Services, ",") != "k3s-agent,caddy" || spec.Interval != 45*time.Second ||
+		spec.MaxFailures != 5 || spec.Webhook != "https://ntfy.sh/cluster" {
+		t.Errorf("spec = %+v", spec)
+	}
+
+	for key, value := range map[string]string{
+		"WATCHDOG_SERVICES":     "k3s;reboot",
+		"WATCHDOG_INTERVAL":     "5s",
+		"WATCHDOG_MAX_FAILURES": "0",
+		"WATCHDOG_WEBHOOK":      "ftp://example.com",
+	} {
+		env := map[string]string{"WATCHDOG_SERVICES": "k3s", key: value}
+		if _, err := SpecFromEnv(env, newFakeHost()); err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("%s=%q: err = %v, want an error naming the key", key, value, err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	spec := Spec{Services: []string{"k3s", "caddy"}, Interval: 2 * time.Minute, MaxFailures: 3, Webhook: "https://example.com/hook?a='b'"}
+
+	script := spec.RenderScript()
+	for _, want := range []string{
+		"MAX_FAILURES=3",
+		`WEBHOOK='https://example.com/hook?a='"'"'b'"'"''`,
+		"for svc in k3s caddy; do",
+		"k3s kubectl get --raw=/readyz",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script does not contain %q:\n%s", want, script)
+		}
+	}
+	if service := spec.RenderService(); !strings.Contains(service, "ExecStart="+ScriptPath) || !strings.Contains(service, "for k3s, caddy") {
+		t.Errorf("service:\n%s", service)
+	}
+	if timer := spec.RenderTimer(); !strings.Contains(timer, "OnUnitActiveSec=120s") {
+		t.Errorf("timer:\n%s", timer)
+	}
+}
+
+func TestApplyIsIdempotent(t *testing.T) {
+	spec := Spec{Services: []string{"k3s"}, Interval: time.Minute, MaxFailures: 3}
+	host := newFakeHost()
+	var out bytes.Buffer
+	if err := Apply(spec, host, &out); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	for _, path := range []string{ScriptPath, ServicePath, TimerPath} {
+		if host.files[path] == "" {
+			t.Errorf("%s was not written", path)
+		}
+	}
+	if got := strings.Join(host.runs, "\n"); !strings.Contains(got, "systemctl daemon-reload") || !strings.Contains(got, "systemctl enable --now "+TimerName) {
+		t.Errorf("runs = %v", host.runs)
+	}
+
+	host.runs = nil
+	if err := Apply(spec, host, &out); err != nil {
+		t.Fatalf("second Apply: %v", err)
+	}
+	if len(host.runs) != 0 {
+		t.Errorf("second Apply ran %v, want nothing", host.runs)
+	}
+}
+
+func TestFailures(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "k3s.failures"), []byte("2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "other"), []byte("1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	failures, err := Failures(dir)
+	if err != nil {
+		t.Fatalf("Failures: %v", err)
+	}
+	if len(failures) != 1 || failures["k3s"] != 2 {
+		t.Errorf("Failures = %v, want k3s: 2", failures)
+	}
+	if failures, err := Failures(filepath.Join(dir, "missing")); err != nil || len(failures) != 0 {
+		t.Errorf("Failures(missing) = %v, %v", failures, err)
+	}
+}
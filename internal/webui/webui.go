@@ -0,0 +1,153 @@
+// Package webui knows the web UIs the recipes install: the Service each runs
+// behind, how it is exposed (Ingress host, Caddy host from the config, or a
+// local port-forward), and which Secret holds its login.
+package webui
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mfittko/netcup-kube/internal/ingress"
+)
+
+// Kubectl runs kubectl against the cluster
+type Kubectl interface {
+	Output(ctx context.Context, args ...string) ([]byte, error)
+}
+
+// Target is a web UI in the cluster
+type Target struct {
+	Name    string
+	Aliases []string
+	// Namespace defaults to the value of NamespaceEnv in the config
+	Namespace    string
+	NamespaceEnv string
+	Service      string
+	Port         string
+	// LocalPort and LocalScheme are used for port-forward access
+	LocalPort   string
+	LocalScheme string
+	// HostEnv names the config key of a public hostname served by Caddy
+	HostEnv string
+
+	// Login: the user (fixed, or read from UserKey) and the password or
+	// token read from SecretKey of SecretName
+	User       string
+	UserKey    string
+	SecretName string
+	SecretKey  string
+	// DashboardToken logs in with a ServiceAccount token instead
+	DashboardToken bool
+}
+
+// Targets lists the UIs of the recipes
+var Targets = []Target{
+	{Name: "dashboard", Namespace: "kubernetes-dashboard", Service: "kubernetes-dashboard-kong-proxy", Port: "443",
+		LocalPort: "8443", LocalScheme: "https", HostEnv: "DASH_HOST", DashboardToken: true},
+	{Name: "grafana", Namespace: "monitoring", Service: "kube-prometheus-stack-grafana", Port: "80", LocalPort: "3000",
+		UserKey: "admin-user", SecretName: "kube-prometheus-stack-grafana", SecretKey: "admin-password"},
+	{Name: "prometheus", Namespace: "monitoring", Service: "kube-prometheus-stack-prometheus", Port: "9090", LocalPort: "9090"},
+	{Name: "alertmanager", Namespace: "monitoring", Service: "kube-prometheus-stack-alertmanager", Port: "9093", LocalPort: "9093"},
+	{Name: "argo-cd", Aliases: []string{"argocd"}, Namespace: "argocd", NamespaceEnv: "NAMESPACE_ARGOCD",
+		Service: "argocd-server", Port: "80", LocalPort: "8080",
+		User: "admin", SecretName: "argocd-initial-admin-secret", SecretKey: "password"},
+	{Name: "redisinsight", Namespace: "platform", NamespaceEnv: "NAMESPACE_PLATFORM", Service: "redisinsight", Port: "80", LocalPort: "5540"},
+	{Name: "openclaw", Namespace: "openclaw", NamespaceEnv: "NAMESPACE_OPENCLAW", Service: "openclaw", Port: "18789", LocalPort: "18789",
+		HostEnv: "OPENCLAW_HOST", SecretName: "openclaw-credentials", SecretKey: "OPENCLAW_GATEWAY_TOKEN"},
+}
+
+// Lookup returns the recipe UI named name, or a Target for a Service given
+// as <namespace>/<service>[:port]. env supplies namespace overrides.
+func Lookup(name string, env map[string]string) (Target, error) {
+	for _, t := range Targets {
+		if t.Name == name || slices.Contains(t.Aliases, name) {
+			if ns := strings.TrimSpace(env[t.NamespaceEnv]); t.NamespaceEnv != "" && ns != "" {
+				t.Namespace = ns
+			}
+			return t, nil
+		}
+	}
+	namespace, service, ok := strings.Cut(name, "/")
+	if !ok || namespace == "" || service == "" {
+		names := make([]string, 0, len(Targets))
+		for _, t := range Targets {
+			names = append(names, t.Name)
+		}
+		return Target{}, fmt.Errorf("unknown UI %q (expected one of: %s, or <namespace>/<service>[:port])", name, strings.Join(names, ", "))
+	}
+	t := Target{Name: name, Namespace: namespace, Service: service}
+	if svc, port, ok := strings.Cut(service, ":"); ok {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return Target{}, fmt.Errorf("invalid port in %q", name)
+		}
+		t.Service, t.Port = svc, port
+	}
+	return t, nil
+}
+
+// ServicePort returns the first port of the target's Service, for targets
+// given without one
+func ServicePort(ctx context.Context, kube Kubectl, t Target) (string, error) {
+	out, err := kube.Output(ctx, "-n", t.Namespace, "get", "service", t.Service, "-o", "jsonpath={.spec.ports[0].port}")
+	if err != nil {
+		return "", fmt.Errorf("failed to read service %s/%s: %w", t.Namespace, t.Service, err)
+	}
+	port := strings.TrimSpace(string(out))
+	if port == "" {
+		return "", fmt.Errorf("service %s/%s has no ports", t.Namespace, t.Service)
+	}
+	return port, nil
+}
+
+// DefaultLocalPort picks the local port of a forward to remote: the same
+// port, or 8000+port for privileged ports
+func DefaultLocalPort(remote string) string {
+	if n, err := strconv.Atoi(remote); err == nil && n < 1024 {
+		return strconv.Itoa(8000 + n)
+	}
+	return remote
+}
+
+// PublicHost returns the host of an Ingress route to the target's Service,
+// preferring the root path, or "" when it is not exposed through an Ingress
+func PublicHost(routes []ingress.Route, t Target) string {
+	var hosts []string
+	for _, r := range routes {
+		service, _, _ := strings.Cut(r.Backend, ":")
+		if r.Namespace != t.Namespace || service != t.Service || r.Host == "*" || strings.Contains(r.Host, "*") {
+			continue
+		}
+		if r.Path == "/" || r.Path == "" {
+			return r.Host
+		}
+		hosts = append(hosts, r.Host)
+	}
+	if len(hosts) == 0 {
+		return ""
+	}
+	sort.Strings(hosts)
+	return hosts[0]
+}
+
+// SecretValue returns the decoded value of key in a Secret
+func SecretValue(ctx context.Context, kube Kubectl, namespace, name, key string) (string, error) {
+	out, err := kube.Output(ctx, "-n", namespace, "get", "secret", name, "-o", "jsonpath={.data."+strings.ReplaceAll(key, ".", `\.`)+"}")
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s/%s: %w", namespace, name, err)
+	}
+	encoded := strings.TrimSpace(string(out))
+	if encoded == "" {
+		return "", fmt.Errorf("secret %s/%s has no %s key", namespace, name, key)
+	}
+	value, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("secret %s/%s holds an invalid %s: %w", namespace, name, key, err)
+	}
+	return string(value), nil
+}
@@ -0,0 +1,77 @@
+package webui
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mfittko/netcup-kube/internal/ingress"
+)
+
+type fakeKube struct {
+	args []string
+	out  string
+}
+
+func (k *fakeKube) Output(_ context.Context, args ...string) ([]byte, error) {
+	k.args = args
+	return []byte(k.out), nil
+}
+
+func TestLookup(t *testing.T) {
+	got, err := Lookup("argocd", map[string]string{"NAMESPACE_ARGOCD": "gitops"})
+	if err != nil || got.Name != "argo-cd" || got.Namespace != "gitops" || got.SecretName != "argocd-initial-admin-secret" {
+		t.Errorf("Lookup(argocd) = %+v, %v", got, err)
+	}
+	got, err = Lookup("platform/redis-commander:8081", nil)
+	if err != nil || got.Namespace != "platform" || got.Service != "redis-commander" || got.Port != "8081" {
+		t.Errorf("Lookup(service) = %+v, %v", got, err)
+	}
+	for _, name := range []string{"kibana", "platform/", "platform/svc:http"} {
+		if _, err := Lookup(name, nil); err == nil {
+			t.Errorf("Lookup(%q) expected error", name)
+		}
+	}
+}
+
+func TestPublicHost(t *testing.T) {
+	target, _ := Lookup("argo-cd", nil)
+	routes := []ingress.Route{
+		{Namespace: "argocd", Host: "*", Path: "/", Backend: "argocd-server:80"},
+		{Namespace: "argocd", Host: "cd.example.com", Path: "/api", Backend: "argocd-server:80"},
+		{Namespace: "other", Host: "wrong.example.com", Path: "/", Backend: "argocd-server:80"},
+	}
+	if got := PublicHost(routes, target); got != "cd.example.com" {
+		t.Errorf("PublicHost() = %q", got)
+	}
+	routes = append(routes, ingress.Route{Namespace: "argocd", Host: "argo.example.com", Path: "/", Backend: "argocd-server:http"})
+	if got := PublicHost(routes, target); got != "argo.example.com" {
+		t.Errorf("PublicHost() with a root route = %q", got)
+	}
+	if got := PublicHost(nil, target); got != "" {
+		t.Errorf("PublicHost(nil) = %q", got)
+	}
+}
+
+func TestDefaultLocalPort(t *testing.T) {
+	for remote, want := range map[string]string{"80": "8080", "443": "8443", "5540": "5540"} {
+		if got := DefaultLocalPort(remote); got != want {
+			t.Errorf("DefaultLocalPort(%s) = %s, want %s", remote, got, want)
+		}
+	}
+}
+
+func TestSecretValue(t *testing.T) {
+	kube := &fakeKube{out: "czNjcmV0"}
+	value, err := SecretValue(context.Background(), kube, "openclaw", "openclaw-credentials", "OPENCLAW_GATEWAY_TOKEN")
+	if err != nil || value != "s3cret" {
+		t.Errorf("SecretValue() = %q, %v", value, err)
+	}
+	if got := strings.Join(kube.args, " "); got != "-n openclaw get secret openclaw-credentials -o jsonpath={.data.OPENCLAW_GATEWAY_TOKEN}" {
+		t.Errorf("args = %s", got)
+	}
+	kube.out = ""
+	if _, err := SecretValue(context.Background(), kube, "argocd", "argocd-initial-admin-secret", "password"); err == nil {
+		t.Error("SecretValue() of a missing key expected error")
+	}
+}
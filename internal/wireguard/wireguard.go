@@ -0,0 +1,353 @@
+// Package wireguard generates keys and wg-quick configurations for a WireGuard
+// link between operator machines and the management node, and probes whether
+// the kube API is reachable over it.
+package wireguard
+
+import (
+	"bufio"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultInterface is the wg-quick interface managed by netcup-kube
+	DefaultInterface = "wg-netcup"
+
+	// DefaultAddress is the management node address inside the WireGuard subnet
+	DefaultAddress = "10.99.0.1/24"
+
+	// DefaultPort is the UDP listen port on the management node
+	DefaultPort = 51820
+
+	// DefaultKeepalive keeps NAT mappings open for peers behind NAT
+	DefaultKeepalive = 25
+
+	// APIPort is the k3s API port on the management node's WireGuard address
+	APIPort = "6443"
+
+	peerNamePrefix = "# netcup-kube peer:"
+)
+
+// GenerateKey returns a new base64-encoded private/public key pair
+func GenerateKey() (privateKey, publicKey string, err error) {
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate WireGuard key: %w", err)
+	}
+	raw := key.Bytes()
+	// Clamp like `wg genkey` so the stored key is in canonical form
+	raw[0] &= 248
+	raw[31] = (raw[31] & 127) | 64
+	privateKey = base64.StdEncoding.EncodeToString(raw)
+	publicKey, err = PublicKey(privateKey)
+	return privateKey, publicKey, err
+}
+
+// PublicKey derives the base64-encoded public key of a private key
+func PublicKey(privateKey string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil || len(raw) != 32 {
+		return "", fmt.Errorf("invalid WireGuard private key")
+	}
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid WireGuard private key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// Interface is the [Interface] section of a wg-quick config
+type Interface struct {
+	PrivateKey string
+	Address    string
+	ListenPort int
+	// Extra holds unmanaged lines (PostUp, DNS, ...) verbatim
+	Extra []string
+}
+
+// Peer is a [Peer] section of a wg-quick config
+type Peer struct {
+	// Name is stored as a comment so peers can be addressed by name
+	Name                string
+	PublicKey           string
+	AllowedIPs          string
+	Endpoint            string
+	PersistentKeepalive int
+	Extra               []string
+}
+
+// Config is a wg-quick configuration file
+type Config struct {
+	Interface Interface
+	Peers     []Peer
+}
+
+// Parse reads a wg-quick configuration
+func Parse(data string) (*Config, error) {
+	cfg := &Config{}
+	section := ""
+	var peer *Peer
+	pendingName := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, peerNamePrefix):
+			pendingName = strings.TrimSpace(strings.TrimPrefix(line, peerNamePrefix))
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		case strings.EqualFold(line, "[Interface]"):
+			section = "interface"
+			continue
+		case strings.EqualFold(line, "[Peer]"):
+			section = "peer"
+			cfg.Peers = append(cfg.Peers, Peer{Name: pendingName})
+			peer = &cfg.Peers[len(cfg.Peers)-1]
+			pendingName = ""
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+
+		switch section {
+		case "interface":
+			switch strings.ToLower(key) {
+			case "privatekey":
+				cfg.Interface.PrivateKey = value
+			case "address":
+				cfg.Interface.Address = value
+			case "listenport":
+				port, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid ListenPort %q", lineNo, value)
+				}
+				cfg.Interface.ListenPort = port
+			default:
+				cfg.Interface.Extra = append(cfg.Interface.Extra, line)
+			}
+		case "peer":
+			switch strings.ToLower(key) {
+			case "publickey":
+				peer.PublicKey = value
+			case "allowedips":
+				peer.AllowedIPs = value
+			case "endpoint":
+				peer.Endpoint = value
+			case "persistentkeepalive":
+				keepalive, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid PersistentKeepalive %q", lineNo, value)
+				}
+				peer.PersistentKeepalive = keepalive
+			default:
+				peer.Extra = append(peer.Extra, line)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: key outside of a section", lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Render writes the configuration in wg-quick format
+func (c *Config) Render() string {
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	if c.Interface.Address != "" {
+		fmt.Fprintf(&b, "Address = %s\n", c.Interface.Address)
+	}
+	if c.Interface.ListenPort > 0 {
+		fmt.Fprintf(&b, "ListenPort = %d\n", c.Interface.ListenPort)
+	}
+	fmt.Fprintf(&b, "PrivateKey = %s\n", c.Interface.PrivateKey)
+	for _, line := range c.Interface.Extra {
+		b.WriteString(line + "\n")
+	}
+
+	for _, p := range c.Peers {
+		b.WriteString("\n")
+		if p.Name != "" {
+			fmt.Fprintf(&b, "%s %s\n", peerNamePrefix, p.Name)
+		}
+		b.WriteString("[Peer]\n")
+		fmt.Fprintf(&b, "PublicKey = %s\n", p.PublicKey)
+		if p.AllowedIPs != "" {
+			fmt.Fprintf(&b, "AllowedIPs = %s\n", p.AllowedIPs)
+		}
+		if p.Endpoint != "" {
+			fmt.Fprintf(&b, "Endpoint = %s\n", p.Endpoint)
+		}
+		if p.PersistentKeepalive > 0 {
+			fmt.Fprintf(&b, "PersistentKeepalive = %d\n", p.PersistentKeepalive)
+		}
+		for _, line := range p.Extra {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// FindPeer returns the peer with the given name, or nil
+func (c *Config) FindPeer(name string) *Peer {
+	for i := range c.Peers {
+		if c.Peers[i].Name == name {
+			return &c.Peers[i]
+		}
+	}
+	return nil
+}
+
+// ServerIP returns the interface IP without its prefix length
+func (c *Config) ServerIP() string {
+	ip, _, err := net.ParseCIDR(strings.TrimSpace(strings.Split(c.Interface.Address, ",")[0]))
+	if err != nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// NextPeerAddress allocates the lowest free host address in the interface
+// subnet, skipping the server address and addresses used by existing peers.
+func (c *Config) NextPeerAddress() (string, error) {
+	serverIP, subnet, err := net.ParseCIDR(strings.TrimSpace(strings.Split(c.Interface.Address, ",")[0]))
+	if err != nil || serverIP.To4() == nil {
+		return "", fmt.Errorf("interface address must be an IPv4 CIDR, got %q", c.Interface.Address)
+	}
+
+	used := map[string]bool{serverIP.String(): true}
+	for _, p := range c.Peers {
+		for _, allowed := range strings.Split(p.AllowedIPs, ",") {
+			if ip, _, err := net.ParseCIDR(strings.TrimSpace(allowed)); err == nil {
+				used[ip.String()] = true
+			}
+		}
+	}
+
+	base := subnet.IP.To4()
+	ones, bits := subnet.Mask.Size()
+	size := uint32(1) << uint(bits-ones)
+	start := uint32(base[0])<<24 | uint32(base[1])<<16 | uint32(base[2])<<8 | uint32(base[3])
+	// Skip the network and broadcast addresses
+	for offset := uint32(1); offset+1 < size; offset++ {
+		n := start + offset
+		ip := net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)).String()
+		if !used[ip] {
+			return ip + "/32", nil
+		}
+	}
+	return "", fmt.Errorf("no free addresses left in %s", subnet)
+}
+
+// Reachable reports whether a TCP connection to addr succeeds within timeout.
+// It is used to decide whether the kube API can be reached over WireGuard
+// before falling back to an SSH tunnel.
+func Reachable(addr string, timeout time.Duration) bool {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+// PeerStatus is the runtime state of a peer as reported by `wg show <iface> dump`
+type PeerStatus struct {
+	PublicKey     string
+	Endpoint      string
+	AllowedIPs    string
+	LastHandshake time.Time
+	RxBytes       int64
+	TxBytes       int64
+}
+
+// ParseDump parses the peer lines of `wg show <iface> dump`. The first line
+// describes the interface itself and is skipped.
+func ParseDump(out string) []PeerStatus {
+	var peers []PeerStatus
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	for i, line := range lines {
+		if i == 0 {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		status := PeerStatus{
+			PublicKey:  fields[0],
+			Endpoint:   fields[2],
+			AllowedIPs: fields[3],
+		}
+		if ts, err := strconv.ParseInt(fields[4], 10, 64); err == nil && ts > 0 {
+			status.LastHandshake = time.Unix(ts, 0)
+		}
+		status.RxBytes, _ = strconv.ParseInt(fields[5], 10, 64)
+		status.TxBytes, _ = strconv.ParseInt(fields[6], 10, 64)
+		if status.Endpoint == "(none)" {
+			status.Endpoint = ""
+		}
+		peers = append(peers, status)
+	}
+	return peers
+}
+
+// RewriteKubeconfigServer replaces the cluster server URL(s) in a kubeconfig
+// so it points at the API over WireGuard instead of the tunnelled localhost.
+func RewriteKubeconfigServer(kubeconfig, server string) string {
+	lines := strings.Split(kubeconfig, "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "server:") {
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			lines[i] = indent + "server: " + server
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ErrUnreachable is returned by Kubeconfig when the API does not answer over
+// WireGuard, so the caller falls back to the SSH tunnel
+var ErrUnreachable = errors.New("kube API unreachable over WireGuard")
+
+// Kubeconfig writes a copy of kubeconfig whose server is https://addr, as
+// <name>-wireguard.yaml next to it (mode 0600), and returns its path. It
+// probes addr first and returns ErrUnreachable when it does not answer within
+// timeout. A kubeconfig that already is such a copy is rewritten in place.
+func Kubeconfig(kubeconfig, addr string, timeout time.Duration) (string, error) {
+	if !Reachable(addr, timeout) {
+		return "", fmt.Errorf("%s: %w", addr, ErrUnreachable)
+	}
+	data, err := os.ReadFile(kubeconfig)
+	if err != nil {
+		return "", err
+	}
+	path := kubeconfig
+	if stem := strings.TrimSuffix(kubeconfig, filepath.Ext(kubeconfig)); !strings.HasSuffix(stem, "-wireguard") {
+		path = stem + "-wireguard.yaml"
+	}
+	if err := os.WriteFile(path, []byte(RewriteKubeconfigServer(string(data), "https://"+addr)), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, os.Chmod(path, 0o600)
+}
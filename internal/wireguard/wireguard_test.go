@@ -0,0 +1,180 @@
+package wireguard
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateKey(t *testing.T) {
+	priv, pub, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %v", err)
+	}
+	if len(priv) != 44 || len(pub) != 44 {
+		t.Fatalf("unexpected key lengths: %d, %d", len(priv), len(pub))
+	}
+	derived, err := PublicKey(priv)
+	if err != nil || derived != pub {
+		t.Errorf("PublicKey() = %q, %v; want %q", derived, err, pub)
+	}
+	if _, err := PublicKey("not-a-key"); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
+
+func TestParseRenderRoundTrip(t *testing.T) {
+	input := `[Interface]
+Address = 10.99.0.1/24
+ListenPort = 51820
+PrivateKey = cHJpdmF0ZQ==
+PostUp = iptables -A FORWARD -i wg-netcup -j ACCEPT
+
+# netcup-kube peer: laptop
+[Peer]
+PublicKey = bGFwdG9w
+AllowedIPs = 10.99.0.2/32
+
+[Peer]
+PublicKey = d29ya2Vy
+AllowedIPs = 10.99.0.4/32
+PersistentKeepalive = 25
+`
+	cfg, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if cfg.Interface.ListenPort != 51820 || len(cfg.Interface.Extra) != 1 {
+		t.Errorf("interface = %+v", cfg.Interface)
+	}
+	if len(cfg.Peers) != 2 || cfg.Peers[0].Name != "laptop" || cfg.Peers[1].Name != "" {
+		t.Fatalf("peers = %+v", cfg.Peers)
+	}
+	if cfg.FindPeer("laptop") == nil || cfg.FindPeer("missing") != nil {
+		t.Error("FindPeer returned unexpected result")
+	}
+	if cfg.ServerIP() != "10.99.0.1" {
+		t.Errorf("ServerIP() = %q", cfg.ServerIP())
+	}
+
+	again, err := Parse(cfg.Render())
+	if err != nil {
+		t.Fatalf("Parse(Render()) error: %v", err)
+	}
+	if again.Render() != cfg.Render() {
+		t.Errorf("render is not stable:\n%s\n---\n%s", cfg.Render(), again.Render())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, input := range []string{
+		"PrivateKey = x\n",
+		"[Interface]\nListenPort = abc\n",
+		"[Interface]\nno-equals-sign\n",
+	} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) expected error", input)
+		}
+	}
+}
+
+func TestNextPeerAddress(t *testing.T) {
+	cfg := &Config{
+		Interface: Interface{Address: "10.99.0.1/24"},
+		Peers: []Peer{
+			{AllowedIPs: "10.99.0.2/32"},
+			{AllowedIPs: "10.99.0.4/32, 192.168.0.0/24"},
+		},
+	}
+	got, err := cfg.NextPeerAddress()
+	if err != nil || got != "10.99.0.3/32" {
+		t.Fatalf("NextPeerAddress() = %q, %v", got, err)
+	}
+
+	full := &Config{Interface: Interface{Address: "10.99.0.1/30"}, Peers: []Peer{{AllowedIPs: "10.99.0.2/32"}}}
+	if _, err := full.NextPeerAddress(); err == nil {
+		t.Error("expected error for exhausted subnet")
+	}
+}
+
+func TestParseDump(t *testing.T) {
+	out := "cHJpdg==\tcHVi\t51820\toff\n" +
+		"bGFwdG9w\t(none)\t198.51.100.7:40000\t10.99.0.2/32\t1700000000\t1024\t2048\t25\n" +
+		"d29ya2Vy\t(none)\t(none)\t10.99.0.3/32\t0\t0\t0\toff\n"
+	peers := ParseDump(out)
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(peers))
+	}
+	if peers[0].Endpoint != "198.51.100.7:40000" || peers[0].RxBytes != 1024 || peers[0].LastHandshake.Unix() != 1700000000 {
+		t.Errorf("peer 0 = %+v", peers[0])
+	}
+	if peers[1].Endpoint != "" || !peers[1].LastHandshake.IsZero() {
+		t.Errorf("peer 1 = %+v", peers[1])
+	}
+}
+
+func TestRewriteKubeconfigServer(t *testing.T) {
+	in := "clusters:\n- cluster:\n    certificate-authority-data: abc\n    server: https://127.0.0.1:6443\n  name: default\n"
+	got := RewriteKubeconfigServer(in, "https://10.99.0.1:6443")
+	if !strings.Contains(got, "    server: https://10.99.0.1:6443\n") || strings.Contains(got, "127.0.0.1") {
+		t.Errorf("unexpected kubeconfig:\n%s", got)
+	}
+}
+
+func TestReachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	if !Reachable(addr, time.Second) {
+		t.Error("expected listener to be reachable")
+	}
+	_ = ln.Close()
+	if Reachable(addr, 200*time.Millisecond) {
+		t.Error("expected closed port to be unreachable")
+	}
+}
+
+func TestKubeconfig(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	dir := t.TempDir()
+	kubeconfig := filepath.Join(dir, "k3s.yaml")
+	if err := os.WriteFile(kubeconfig, []byte("clusters:\n- cluster:\n    server: https://127.0.0.1:6443\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := Kubeconfig(kubeconfig, addr, time.Second)
+	if err != nil {
+		t.Fatalf("Kubeconfig() error: %v", err)
+	}
+	if path != filepath.Join(dir, "k3s-wireguard.yaml") {
+		t.Errorf("Kubeconfig() = %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "server: https://"+addr) {
+		t.Errorf("WireGuard kubeconfig = %q (%v)", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("WireGuard kubeconfig mode = %v (%v), want 0600", info.Mode().Perm(), err)
+	}
+	if again, err := Kubeconfig(path, addr, time.Second); err != nil || again != path {
+		t.Errorf("Kubeconfig(copy) = %s, %v; want it rewritten in place", again, err)
+	}
+	if _, err := Kubeconfig(filepath.Join(dir, "missing.yaml"), addr, time.Second); err == nil {
+		t.Error("Kubeconfig() expected error for a missing kubeconfig")
+	}
+
+	_ = ln.Close()
+	if _, err := Kubeconfig(kubeconfig, addr, 200*time.Millisecond); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Kubeconfig() error = %v, want ErrUnreachable", err)
+	}
+}
@@ -0,0 +1,517 @@
+// Package yamlsubset reads and writes the small YAML subset used by inventory
+// files and the Alertmanager configuration: block mappings, block sequences,
+// plain/quoted scalars, flow sequences of scalars ([a, b]), empty flow
+// collections ({} / []), and # comments. Anchors, tags, multi-document
+// streams, and block scalars (| / >) are not supported and are reported as
+// errors.
+package yamlsubset
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// numberLike matches plain scalars that YAML 1.1 parsers read as numbers
+var numberLike = regexp.MustCompile(`^[-+]?(0[xXoObB][0-9a-fA-F_]+|[0-9][0-9_]*(\.[0-9_]*)?([eE][-+]?[0-9]+)?|\.[0-9]+)$`)
+
+// yamlLine is a single significant (non-blank, non-comment) source line
+type yamlLine struct {
+	num     int
+	indent  int
+	content string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+	// typed resolves plain scalars to nil, bool, int64, and float64
+	typed bool
+}
+
+// Parse parses data into nested map[string]any / []any / string values.
+// Every scalar is a string; null and missing values are "".
+func Parse(data []byte) (any, error) {
+	return parse(data, false)
+}
+
+// Decode parses data like Parse, but resolves plain scalars the way YAML does:
+// null/~ and missing values to nil, true/false to bool, and numbers to int64
+// or float64. Quoted scalars stay strings, so Marshal(Decode(data)) keeps the
+// meaning of the document.
+func Decode(data []byte) (any, error) {
+	return parse(data, true)
+}
+
+func parse(data []byte, typed bool) (any, error) {
+	lines, err := splitYAMLLines(string(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return map[string]any{}, nil
+	}
+
+	p := &yamlParser{lines: lines, typed: typed}
+	value, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+	}
+	return value, nil
+}
+
+func splitYAMLLines(src string) ([]yamlLine, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		num := i + 1
+		leading := raw[:len(raw)-len(strings.TrimLeft(raw, " \t"))]
+		if strings.Contains(leading, "\t") && strings.TrimSpace(raw) != "" {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", num)
+		}
+		content := strings.TrimRight(stripYAMLComment(raw), " \t")
+		trimmed := strings.TrimLeft(content, " ")
+		if trimmed == "" {
+			continue
+		}
+		if trimmed == "---" || trimmed == "..." {
+			if len(lines) > 0 {
+				return nil, fmt.Errorf("line %d: multiple documents are not supported", num)
+			}
+			continue
+		}
+		lines = append(lines, yamlLine{
+			num:     num,
+			indent:  len(content) - len(trimmed),
+			content: trimmed,
+		})
+	}
+	return lines, nil
+}
+
+// stripYAMLComment removes a trailing comment that is outside of quotes.
+func stripYAMLComment(line string) string {
+	inSingle, inDouble := false, false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && inDouble:
+			i++
+		case c == '\'' && !inDouble:
+			inSingle = !inSingle
+		case c == '"' && !inSingle:
+			inDouble = !inDouble
+		case c == '#' && !inSingle && !inDouble:
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+func isSeqItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+func (p *yamlParser) parseBlock(indent int) (any, error) {
+	if isSeqItem(p.lines[p.pos].content) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (map[string]any, error) {
+	result := map[string]any{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		if isSeqItem(line.content) {
+			return nil, fmt.Errorf("line %d: unexpected sequence item in mapping", line.num)
+		}
+
+		key, rest, ok := splitYAMLKey(line.content)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.num)
+		}
+		if _, dup := result[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.num, key)
+		}
+		p.pos++
+
+		if rest != "" {
+			value, err := p.parseScalarOrFlow(rest, line.num)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+			continue
+		}
+
+		// Nested block: deeper indentation, or a sequence at the same indentation
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent || (next.indent == indent && isSeqItem(next.content)) {
+				value, err := p.parseBlock(next.indent)
+				if err != nil {
+					return nil, err
+				}
+				result[key] = value
+				continue
+			}
+		}
+		result[key] = p.empty()
+	}
+	return result, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) ([]any, error) {
+	var result []any
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent || (line.indent == indent && !isSeqItem(line.content)) {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+
+		item := strings.TrimLeft(strings.TrimPrefix(line.content, "-"), " ")
+		if item == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				value, err := p.parseBlock(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, value)
+				continue
+			}
+			result = append(result, p.empty())
+			continue
+		}
+
+		if _, _, ok := splitYAMLKey(item); ok && !isFlowOrQuoted(item) {
+			// "- key: value" starts a mapping whose keys align with "key"
+			itemIndent := line.indent + (len(line.content) - len(item))
+			p.lines[p.pos] = yamlLine{num: line.num, indent: itemIndent, content: item}
+			value, err := p.parseMapping(itemIndent)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+			continue
+		}
+
+		value, err := p.parseScalarOrFlow(item, line.num)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+		p.pos++
+	}
+	return result, nil
+}
+
+func isFlowOrQuoted(s string) bool {
+	return strings.HasPrefix(s, "[") || strings.HasPrefix(s, "{") || strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'")
+}
+
+// splitYAMLKey splits "key: value" (or "key:") into key and trimmed value.
+func splitYAMLKey(content string) (string, string, bool) {
+	if strings.HasPrefix(content, "\"") || strings.HasPrefix(content, "'") {
+		quote := content[0]
+		end := strings.IndexByte(content[1:], quote)
+		if end < 0 {
+			return "", "", false
+		}
+		end++
+		rest := content[end+1:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		key, err := unquoteYAML(content[:end+1])
+		if err != nil {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+
+	for i := 0; i < len(content); i++ {
+		if content[i] != ':' {
+			continue
+		}
+		if i == len(content)-1 || content[i+1] == ' ' {
+			key := strings.TrimSpace(content[:i])
+			if key == "" {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(content[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func (p *yamlParser) parseScalarOrFlow(s string, num int) (any, error) {
+	switch {
+	case s == "{}":
+		return map[string]any{}, nil
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return nil, fmt.Errorf("line %d: unterminated flow sequence", num)
+		}
+		inner := strings.TrimSpace(s[1 : len(s)-1])
+		items := []any{}
+		if inner == "" {
+			return items, nil
+		}
+		for _, part := range strings.Split(inner, ",") {
+			value, err := p.parseScalar(strings.TrimSpace(part), num)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case strings.HasPrefix(s, "{"):
+		return nil, fmt.Errorf("line %d: flow mappings are not supported", num)
+	case strings.HasPrefix(s, "|") || strings.HasPrefix(s, ">"):
+		return nil, fmt.Errorf("line %d: block scalars are not supported", num)
+	case strings.HasPrefix(s, "&") || strings.HasPrefix(s, "*") || strings.HasPrefix(s, "!"):
+		return nil, fmt.Errorf("line %d: anchors, aliases, and tags are not supported", num)
+	}
+	return p.parseScalar(s, num)
+}
+
+// empty is the value of a key or sequence item without a value
+func (p *yamlParser) empty() any {
+	if p.typed {
+		return nil
+	}
+	return ""
+}
+
+func (p *yamlParser) parseScalar(s string, num int) (any, error) {
+	if strings.HasPrefix(s, "\"") || strings.HasPrefix(s, "'") {
+		value, err := unquoteYAML(s)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", num, err)
+		}
+		return value, nil
+	}
+	if p.typed {
+		return resolvePlain(s), nil
+	}
+	if s == "~" || s == "null" {
+		return "", nil
+	}
+	return s, nil
+}
+
+// resolvePlain applies the YAML core schema to a plain scalar
+func resolvePlain(s string) any {
+	switch s {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if strings.ContainsAny(s, "0123456789") && !strings.ContainsAny(s, "_xXbBoO") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	}
+	return s
+}
+
+func unquoteYAML(s string) (string, error) {
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("unterminated quoted string %s", s)
+	}
+	if s[0] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	value, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid quoted string %s", s)
+	}
+	return value, nil
+}
+
+// Marshal renders v (nested map[string]any / []any / scalars, as returned by
+// Decode or encoding/json) as block-style YAML with sorted mapping keys.
+// Strings are quoted whenever the plain form would read back differently.
+func Marshal(v any) ([]byte, error) {
+	var b strings.Builder
+	if err := writeValue(&b, v, 0); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+func writeValue(b *strings.Builder, v any, indent int) error {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) == 0 {
+			b.WriteString("{}\n")
+			return nil
+		}
+		return writeMapping(b, v, indent)
+	case []any:
+		if len(v) == 0 {
+			b.WriteString("[]\n")
+			return nil
+		}
+		return writeSequence(b, v, indent)
+	}
+	s, err := formatScalar(v)
+	if err != nil {
+		return err
+	}
+	b.WriteString(s + "\n")
+	return nil
+}
+
+func writeMapping(b *strings.Builder, m map[string]any, indent int) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		// The first key of a sequence item follows "- " on the same line
+		if i > 0 || !strings.HasSuffix(b.String(), "- ") {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		b.WriteString(formatString(k) + ":")
+		if err := writeNested(b, m[k], indent, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeSequence(b *strings.Builder, items []any, indent int) error {
+	for i, item := range items {
+		if i > 0 || !strings.HasSuffix(b.String(), "- ") {
+			b.WriteString(strings.Repeat(" ", indent))
+		}
+		b.WriteString("-")
+		if err := writeNested(b, item, indent, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNested writes the value after "key:" or "-". Sequences under a key stay
+// at the key's indentation; mappings are indented below keys and continue on
+// the "- " line inside sequences.
+func writeNested(b *strings.Builder, v any, indent int, underKey bool) error {
+	switch v := v.(type) {
+	case map[string]any:
+		if len(v) > 0 {
+			if underKey {
+				b.WriteString("\n")
+				return writeMapping(b, v, indent+2)
+			}
+			b.WriteString(" ")
+			return writeMapping(b, v, indent+2)
+		}
+	case []any:
+		if len(v) > 0 {
+			b.WriteString("\n")
+			if underKey {
+				return writeSequence(b, v, indent)
+			}
+			return writeSequence(b, v, indent+2)
+		}
+	}
+	b.WriteString(" ")
+	return writeValue(b, v, indent)
+}
+
+func formatScalar(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "null", nil
+	case string:
+		return formatString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return ".inf", nil
+		case math.IsInf(v, -1):
+			return "-.inf", nil
+		case math.IsNaN(v):
+			return "", fmt.Errorf("cannot encode NaN")
+		case v == math.Trunc(v) && math.Abs(v) < 1e15:
+			return strconv.FormatInt(int64(v), 10), nil
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	}
+	return "", fmt.Errorf("cannot encode %T", v)
+}
+
+// formatString returns s plain when it reads back as the same string, and
+// double-quoted otherwise. YAML 1.1 booleans (yes/no/on/off) are quoted too,
+// for consumers such as Alertmanager that still use a YAML 1.1 parser.
+func formatString(s string) string {
+	if needsQuotes(s) {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func needsQuotes(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) {
+		return true
+	}
+	if _, ok := resolvePlain(s).(string); !ok {
+		return true
+	}
+	if numberLike.MatchString(s) {
+		return true
+	}
+	switch strings.ToLower(s) {
+	case "y", "n", "yes", "no", "on", "off":
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return true
+	}
+	for _, r := range s {
+		if r < ' ' || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
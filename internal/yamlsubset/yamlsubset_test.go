@@ -0,0 +1,210 @@
+package yamlsubset
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `# cluster
+vars:
+  BASE_DOMAIN: example.com   # trailing comment
+  QUOTED: "a # not a comment"
+  SINGLE: 'it''s'
+  EMPTY:
+servers:
+- name: mgmt
+  host: 203.0.113.10
+  tags: [a, "b c"]
+workers:
+  - name: w1
+    vars:
+      NODE_IP: 10.0.0.2
+  - plain
+  -
+    nested: yes
+`
+	got, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := map[string]any{
+		"vars": map[string]any{
+			"BASE_DOMAIN": "example.com",
+			"QUOTED":      "a # not a comment",
+			"SINGLE":      "it's",
+			"EMPTY":       "",
+		},
+		"servers": []any{
+			map[string]any{"name": "mgmt", "host": "203.0.113.10", "tags": []any{"a", "b c"}},
+		},
+		"workers": []any{
+			map[string]any{"name": "w1", "vars": map[string]any{"NODE_IP": "10.0.0.2"}},
+			"plain",
+			map[string]any{"nested": "yes"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %#v\nwant %#v", got, want)
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	got, err := Parse([]byte("# only comments\n\n---\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if !reflect.DeepEqual(got, map[string]any{}) {
+		t.Errorf("Parse() = %#v, want empty map", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{"tab indent", "a:\n\tb: c\n", "tabs"},
+		{"duplicate key", "a: 1\na: 2\n", "duplicate key"},
+		{"bad indent", "a: 1\n  b: 2\n", "unexpected indentation"},
+		{"no colon", "just text\n", "expected"},
+		{"block scalar", "a: |\n  text\n", "block scalars"},
+		{"flow mapping", "a: {b: c}\n", "flow mappings"},
+		{"anchor", "a: &x 1\n", "anchors"},
+		{"multi document", "a: 1\n---\nb: 2\n", "multiple documents"},
+		{"unterminated quote", "a: \"abc\n", "unterminated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.src))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+const alertmanagerConfig = `global:
+  resolve_timeout: 5m
+inhibit_rules:
+- equal:
+  - namespace
+  - alertname
+  source_matchers:
+  - severity = critical
+  target_matchers:
+  - severity =~ warning|info
+receivers:
+- name: "null"
+route:
+  group_by: [namespace]
+  group_wait: 30s
+  repeat_interval: 12h
+  routes:
+  - matchers:
+    - alertname = "Watchdog"
+    receiver: "null"
+    continue: false
+templates:
+- /etc/alertmanager/config/*.tmpl
+`
+
+func TestDecode(t *testing.T) {
+	got, err := Decode([]byte(alertmanagerConfig))
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	root := got.(map[string]any)
+	route := root["route"].(map[string]any)
+	child := route["routes"].([]any)[0].(map[string]any)
+	if child["receiver"] != "null" || child["continue"] != false {
+		t.Errorf("child route = %#v", child)
+	}
+	if m := child["matchers"].([]any)[0]; m != `alertname = "Watchdog"` {
+		t.Errorf("matcher = %#v", m)
+	}
+
+	got, err = Decode([]byte("a: 3\nb: 1.5\nc: ~\nd:\ne: '42'\nf: 30s\ng: [1, x]\n"))
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	want := map[string]any{"a": int64(3), "b": 1.5, "c": nil, "d": nil, "e": "42", "f": "30s", "g": []any{int64(1), "x"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode() = %#v\nwant %#v", got, want)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	decoded, err := Decode([]byte(alertmanagerConfig))
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	out, err := Marshal(decoded)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	again, err := Decode(out)
+	if err != nil {
+		t.Fatalf("Decode(Marshal()) error: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(decoded, again) {
+		t.Errorf("round trip changed the document:\n%s", out)
+	}
+	for _, want := range []string{"- name: \"null\"\n", "  routes:\n  - continue: false\n    matchers:\n", "group_by:\n  - namespace\n"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("Marshal() output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMarshalQuoting(t *testing.T) {
+	value := map[string]any{
+		"plain":   "http://example.com/hook",
+		"empty":   "",
+		"bool":    "true",
+		"yes":     "yes",
+		"number":  "0x1F",
+		"colon":   "a: b",
+		"comment": "a #b",
+		"dash":    "- x",
+		"newline": "line1\nline2",
+		"float":   float64(3),
+		"real":    2.5,
+		"nil":     nil,
+		"list":    []any{},
+		"map":     map[string]any{},
+		"nested":  []any{[]any{"a"}, map[string]any{"k": []any{"v"}}},
+	}
+	out, err := Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	want := `bool: "true"
+colon: "a: b"
+comment: "a #b"
+dash: "- x"
+empty: ""
+float: 3
+list: []
+map: {}
+nested:
+-
+  - a
+- k:
+  - v
+newline: "line1\nline2"
+nil: null
+number: "0x1F"
+plain: http://example.com/hook
+real: 2.5
+"yes": "yes"
+`
+	if string(out) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", out, want)
+	}
+	if _, err := Marshal(map[string]any{"x": struct{}{}}); err == nil {
+		t.Error("Marshal() expected error for unsupported type")
+	}
+}
@@ -0,0 +1,15 @@
+// Package scripts embeds the shell scripts, recipes and their templates so
+// that a standalone netcup-kube binary can run them without a checkout.
+package scripts
+
+import "embed"
+
+// FS holds main.sh with its libraries and modules, and the recipes. Recipe
+// files are listed by pattern rather than embedding recipes/ whole, so local
+// state kept below it (such as openclaw snapshots) never ends up in a binary.
+//
+//go:embed main.sh lib modules
+//go:embed recipes/README.md recipes/lib.sh recipes/recipes.conf
+//go:embed recipes/*/*.sh recipes/*/*.yaml recipes/*/*.json recipes/*/*.toml recipes/*/*.md
+//go:embed recipes/openclaw/agent-workspace recipes/zeroclaw/chart
+var FS embed.FS
@@ -84,6 +84,10 @@ write_file() {
   else
     content="$(cat)"
   fi
+  if render_enabled; then
+    printf '%s' "$content" | render_file "${path}" "${mode}"
+    return 0
+  fi
   if [[ "${DRY_RUN:-false}" == "true" ]]; then
     if [[ "${DRY_RUN_WRITE_FILES:-false}" != "true" ]]; then
       log "[DRY_RUN] would write ${path}"
@@ -105,6 +109,305 @@ write_file() {
   fi
 }
 
+# Render mode: with RENDER_DIR set, generated files, manifests and Helm
+# releases are written below it for review or a GitOps commit instead of being
+# applied.
+render_enabled() { [[ -n "${RENDER_DIR:-}" ]]; }
+
+# render_file PATH MODE: write stdin to ${RENDER_DIR}/PATH (absolute paths are
+# mirrored below RENDER_DIR)
+render_file() {
+  local path="${RENDER_DIR%/}/${1#/}"
+  local mode="${2:-}"
+  mkdir -p "$(dirname "${path}")"
+  cat > "${path}"
+  if [[ -n "${mode}" ]]; then
+    chmod "${mode}" "${path}" || true
+  fi
+  log "[RENDER] wrote ${path}"
+}
+
+# apply_manifest NAME: kubectl apply the manifest on stdin, or render it to
+# manifests/NAME.yaml
+apply_manifest() {
+  local name="$1"
+  if render_enabled; then
+    render_file "manifests/${name}.yaml" "0644"
+    return 0
+  fi
+  if [[ "${DRY_RUN:-false}" == "true" ]]; then
+    cat > /dev/null
+    log "[DRY_RUN] would apply ${name}"
+    return 0
+  fi
+  k apply -f -
+}
+
+# helm_upgrade_install ARGS...: `helm upgrade --install ARGS`, or render it
+helm_upgrade_install() {
+  if render_enabled; then
+    render_helm_release upgrade --install "$@"
+    return 0
+  fi
+  run helm upgrade --install "$@"
+}
+
+# render_helm_release HELM_ARGS...: record a `helm upgrade --install` (or
+# `helm install`) as helm/<release>/install.sh. --values and --set-file inputs
+# are copied next to it; credential-looking --set values are redacted.
+render_helm_release() {
+  local release="" arg value key n=0 skip=false
+  for arg in "$@"; do
+    if [[ "${skip}" == "true" ]]; then
+      skip=false
+      continue
+    fi
+    case "${arg}" in
+      upgrade | install) ;;
+      -f | --values | --set | --set-string | --set-file | --set-json | -n | --namespace | --version | --timeout | --repo | --kube-context | --post-renderer) skip=true ;;
+      -*) ;;
+      *)
+        release="${arg}"
+        break
+        ;;
+    esac
+  done
+  [[ -n "${release}" ]] || die "render: could not find the Helm release name"
+
+  local dir="${RENDER_DIR%/}/helm/${release}"
+  mkdir -p "${dir}"
+  local -a out=(helm)
+  while [[ $# -gt 0 ]]; do
+    arg="$1"
+    shift
+    value=""
+    case "${arg}" in
+      -f | --values | --set | --set-string | --set-file | --set-json)
+        value="${1:-}"
+        shift || true
+        ;;
+      --values=* | --set=* | --set-string=* | --set-file=* | --set-json=*)
+        value="${arg#*=}"
+        arg="${arg%%=*}"
+        ;;
+      *)
+        out+=("${arg}")
+        continue
+        ;;
+    esac
+    case "${arg}" in
+      -f | --values)
+        n=$((n + 1))
+        cp "${value}" "${dir}/values-${n}.yaml"
+        value="values-${n}.yaml"
+        ;;
+      --set-file)
+        key="${value%%=*}"
+        cp "${value#*=}" "${dir}/${key}.file"
+        value="${key}=${key}.file"
+        ;;
+      *)
+        key="${value%%=*}"
+        case "${key}" in
+          *[Pp]assword* | *[Tt]oken* | *[Ss]ecret* | *[Aa]pi[Kk]ey* | *[Aa]pi_[Kk]ey*) value="${key}=<redacted>" ;;
+        esac
+        ;;
+    esac
+    out+=("${arg}" "${value}")
+  done
+
+  {
+    printf '#!/usr/bin/env bash\n'
+    printf '# Rendered by netcup-kube; run from this directory to apply.\n'
+    printf 'set -euo pipefail\n'
+    printf 'helm'
+    printf ' %q' "${out[@]:1}"
+    printf '\n'
+  } | render_file "helm/${release}/install.sh" "0755"
+}
+
+# Air-gapped mode: AIRGAP_BUNDLE points at a bundle from `netcup-kube bundle
+# create` (set by `netcup-kube bundle use`). k3s and the Helm CLI are
+# installed from it, and Helm charts are taken from its charts/ directory
+# instead of their repositories.
+airgap_enabled() { [[ -n "${AIRGAP_BUNDLE:-}" ]]; }
+
+# airgap_chart REPO/CHART [VERSION]: print the bundled archive of a chart;
+# without VERSION the bundle must hold exactly one version of it
+airgap_chart() {
+  local name="${1##*/}" version="${2:-}" dir="${AIRGAP_BUNDLE%/}/charts" match="" candidate
+  if [[ -n "${version}" ]]; then
+    match="${dir}/${name}-${version#v}.tgz"
+    [[ -f "${match}" ]] || die "Chart ${1} ${version} is not in the air-gap bundle ${AIRGAP_BUNDLE} (re-create it with netcup-kube bundle create)"
+    echo "${match}"
+    return 0
+  fi
+  for candidate in "${dir}/${name}"-[0-9]*.tgz; do
+    [[ -f "${candidate}" ]] || continue
+    [[ -z "${match}" ]] || die "The air-gap bundle holds several versions of ${1}; pass --version"
+    match="${candidate}"
+  done
+  [[ -n "${match}" ]] || die "Chart ${1} is not in the air-gap bundle ${AIRGAP_BUNDLE}"
+  echo "${match}"
+}
+
+# Chart mirror: HELM_MIRROR_URL points the Helm repositories at a mirror or
+# pull-through cache (Artifactory, Nexus, an OCI registry proxy). "{repo}" in
+# the URL is replaced by the repository name, otherwise the name is appended;
+# HELM_MIRROR_REPO_<NAME> (upper-cased, '-' as '_') overrides one repository.
+# HELM_MIRROR_USERNAME/HELM_MIRROR_PASSWORD authenticate against the mirror.
+# With an oci:// mirror no repository is added: charts are installed from
+# <url>/<chart>. internal/helmmirror implements the same mapping.
+helm_mirror_enabled() {
+  [[ -n "${HELM_MIRROR_URL:-}" || -n "$(compgen -v HELM_MIRROR_REPO_ || true)" ]]
+}
+
+# helm_mirror_url REPO: print the mirror URL of a repository, if any
+helm_mirror_url() {
+  local repo="$1" key
+  key="HELM_MIRROR_REPO_$(printf '%s' "${repo}" | tr '[:lower:]-' '[:upper:]_')"
+  if [[ -n "${!key:-}" ]]; then
+    echo "${!key}"
+    return 0
+  fi
+  [[ -n "${HELM_MIRROR_URL:-}" ]] || return 0
+  case "${HELM_MIRROR_URL}" in
+    *"{repo}"*) echo "${HELM_MIRROR_URL//\{repo\}/${repo}}" ;;
+    *) echo "${HELM_MIRROR_URL%/}/${repo}" ;;
+  esac
+}
+
+# helm_mirror_auth HELM_ARGS...: run helm with the mirror credentials, the
+# password passed on stdin
+helm_mirror_auth() {
+  if [[ -z "${HELM_MIRROR_USERNAME:-}" ]]; then
+    helm "$@"
+    return
+  fi
+  printf '%s' "${HELM_MIRROR_PASSWORD:-}" | helm "$@" --username "${HELM_MIRROR_USERNAME}" --password-stdin
+}
+
+# helm_repo_add NAME URL [--force-update]: add a Helm repository if it does
+# not exist yet (--force-update: replace its URL), then update it. A mirrored repository is (re-)pointed at the
+# mirror; an OCI mirror only needs a registry login.
+helm_repo_add() {
+  local name="$1" url="$2" force_update="${3:-}" mirror
+  [[ -n "${name}" ]] || die "Helm repo name is required"
+  [[ -n "${url}" ]] || die "Helm repo URL is required"
+
+  mirror="$(helm_mirror_url "${name}")"
+  case "${mirror}" in
+    oci://*)
+      log "Using OCI chart mirror ${mirror} for Helm repository ${name}"
+      if [[ -n "${HELM_MIRROR_USERNAME:-}" ]]; then
+        mirror="${mirror#oci://}"
+        helm_mirror_auth registry login "${mirror%%/*}"
+      fi
+      return 0
+      ;;
+    ?*)
+      log "Using chart mirror ${mirror} for Helm repository ${name}"
+      helm_mirror_auth repo add "${name}" "${mirror}" --force-update
+      ;;
+    *)
+      log "Adding Helm repository: ${name}"
+      if [[ "${force_update}" == "--force-update" ]]; then
+        helm repo add "${name}" "${url}" --force-update
+      # Use word boundary to ensure exact repo name match
+      elif ! helm repo list 2> /dev/null | grep -q "^${name}[[:space:]]"; then
+        helm repo add "${name}" "${url}"
+      fi
+      ;;
+  esac
+  helm repo update "${name}"
+}
+
+# helm_chart_source REPO/CHART [VERSION]: print where to install a chart
+# from: the bundled archive in air-gapped mode, the chart in an OCI mirror,
+# or REPO/CHART itself
+helm_chart_source() {
+  if airgap_enabled; then
+    airgap_chart "$@"
+    return
+  fi
+  local mirror
+  mirror="$(helm_mirror_url "${1%%/*}")"
+  if [[ "${mirror}" == oci://* ]]; then
+    echo "${mirror%/}/${1##*/}"
+    return 0
+  fi
+  echo "$1"
+}
+
+# helm_latest_chart_version REPO/CHART [--devel]: print the latest version of
+# a chart; --devel includes pre-releases
+helm_latest_chart_version() {
+  local source
+  local -a devel=()
+  [[ "${2:-}" == "--devel" ]] && devel=(--devel)
+  source="$(helm_chart_source "$1")" || return 1
+  if [[ "${source}" == oci://* ]]; then
+    helm show chart "${source}" ${devel[@]+"${devel[@]}"} 2> /dev/null | awk '/^version:/ {print $2; exit}'
+    return 0
+  fi
+  helm search repo "$1" --versions ${devel[@]+"${devel[@]}"} 2> /dev/null | awk 'NR==2 {print $2}'
+}
+
+# helm_with_sources HELM_ARGS...: run helm with REPO/CHART references
+# replaced by helm_chart_source; in air-gapped mode repository commands are
+# skipped
+helm_with_sources() {
+  case "${1:-}" in
+    repo)
+      if airgap_enabled; then
+        log "[AIRGAP] skipping helm repo ${2:-}"
+        return 0
+      fi
+      command helm "$@"
+      return
+      ;;
+    upgrade | install | template | show | pull) ;;
+    *)
+      command helm "$@"
+      return
+      ;;
+  esac
+
+  local version="" prev="" arg chart skip=false
+  for arg in "$@"; do
+    [[ "${prev}" == "--version" ]] && version="${arg}"
+    [[ "${arg}" == --version=* ]] && version="${arg#*=}"
+    prev="${arg}"
+  done
+  local -a out=()
+  for arg in "$@"; do
+    if [[ "${skip}" == "true" ]]; then
+      skip=false
+      out+=("${arg}")
+      continue
+    fi
+    case "${arg}" in
+      -f | --values | --set | --set-string | --set-file | --set-json | -n | --namespace | --version | --timeout | --repo | --kube-context | --post-renderer) skip=true ;;
+      -*) ;;
+      */*)
+        if [[ ! -e "${arg}" && "${arg}" =~ ^[A-Za-z0-9_-]+/[A-Za-z0-9_-]+$ ]]; then
+          chart="$(helm_chart_source "${arg}" "${version}")" || exit 1
+          if [[ "${chart}" != "${arg}" ]]; then
+            log "Using chart ${chart} for ${arg}"
+            arg="${chart}"
+          fi
+        fi
+        ;;
+    esac
+    out+=("${arg}")
+  done
+  command helm "${out[@]}"
+}
+
+if airgap_enabled || helm_mirror_enabled; then
+  helm() { helm_with_sources "$@"; }
+fi
+
 # Prompts
 prompt() {
   local q="$1"
@@ -158,9 +461,97 @@ prompt_secret() {
 require_root() { [[ "${EUID:-$(id -u)}" -eq 0 ]] || die "Run as root (sudo -s)"; }
 need_cmd() { command -v "$1" > /dev/null 2>&1 || die "Missing command: $1"; }
 
+# OS detection & packages (apt on Debian/Ubuntu, dnf on RHEL-family)
+os_release_value() {
+  local file="${OS_RELEASE_FILE:-/etc/os-release}"
+  [[ -r "${file}" ]] || return 0
+  sed -n "s/^$1=//p" "${file}" | head -n1 | tr -d "\"'"
+}
+
+os_family() {
+  case " $(os_release_value ID) $(os_release_value ID_LIKE) " in
+    *" debian "* | *" ubuntu "*) echo "debian" ;;
+    *" rhel "* | *" fedora "* | *" centos "*) echo "rhel" ;;
+    *) echo "unknown" ;;
+  esac
+}
+
+# Validate the distribution before installing anything.
+# ALLOW_UNSUPPORTED_OS=true continues on untested releases of a known family.
+os_preflight() {
+  local id version major supported="false"
+  id="$(os_release_value ID)"
+  version="$(os_release_value VERSION_ID)"
+  major="${version%%.*}"
+  [[ "${major}" =~ ^[0-9]+$ ]] || major=0
+  case "${id}" in
+    debian) [[ "${major}" -ge 12 ]] && supported="true" ;;
+    ubuntu) [[ "${major}" -ge 22 ]] && supported="true" ;;
+    rhel | rocky | almalinux | centos | ol) [[ "${major}" -ge 8 ]] && supported="true" ;;
+  esac
+
+  local name
+  name="$(os_release_value PRETTY_NAME)"
+  name="${name:-${id:-unknown}${version:+ ${version}}}"
+  if [[ "${supported}" == "true" ]]; then
+    log "Detected OS: ${name} ($(os_family))"
+    return 0
+  fi
+  if [[ "$(bool_norm "${ALLOW_UNSUPPORTED_OS:-false}")" == "true" && "$(os_family)" != "unknown" ]]; then
+    log "WARNING: ${name} is not a tested distribution; continuing because ALLOW_UNSUPPORTED_OS=true"
+    return 0
+  fi
+  die "Unsupported OS: ${name}. Supported: Debian 12+, Ubuntu 22.04+, RHEL/Rocky/AlmaLinux/CentOS Stream/Oracle Linux 8+ (set ALLOW_UNSUPPORTED_OS=true to try anyway)"
+}
+
+# Map Debian package names to their RHEL-family equivalents (empty = not needed)
+pkg_name() {
+  if [[ "$(os_family)" != "rhel" ]]; then
+    echo "$1"
+    return
+  fi
+  case "$1" in
+    iproute2) echo "iproute" ;;
+    procps) echo "procps-ng" ;;
+    gnupg) echo "gnupg2" ;;
+    golang-go) echo "golang" ;;
+    # Not packaged on EL9, and images ship coreutils-single / curl-minimal
+    lsb-release | coreutils) echo "" ;;
+    curl) command -v curl > /dev/null 2>&1 || echo "curl" ;;
+    *) echo "$1" ;;
+  esac
+}
+
+# Install packages (Debian names) with the native package manager
+pkg_install() {
+  local p mapped pkgs=()
+  for p in "$@"; do
+    mapped="$(pkg_name "${p}")"
+    [[ -n "${mapped}" ]] && pkgs+=("${mapped}")
+  done
+  [[ "${#pkgs[@]}" -gt 0 ]] || return 0
+
+  case "$(os_family)" in
+    debian)
+      need_cmd apt-get
+      export DEBIAN_FRONTEND=noninteractive
+      run apt-get update -y
+      run apt-get install -y --no-install-recommends "${pkgs[@]}"
+      ;;
+    rhel)
+      need_cmd dnf
+      run dnf install -y --setopt=install_weak_deps=False "${pkgs[@]}"
+      ;;
+    *)
+      die "Unsupported OS: no known package manager (supported: apt on Debian/Ubuntu, dnf on RHEL-family)"
+      ;;
+  esac
+}
+
 # Networking helpers
 infer_default_iface() { ip -4 route show default 2> /dev/null | awk '{print $5; exit}'; }
 infer_ipv4_on_iface() { ip -4 -o addr show dev "$1" 2> /dev/null | awk '{print $4}' | cut -d/ -f1 | head -n1; }
+infer_ipv6_on_iface() { ip -6 -o addr show dev "$1" scope global 2> /dev/null | awk '{print $4}' | cut -d/ -f1 | head -n1; }
 
 infer_node_ip() {
   if [[ -n "${PRIVATE_IFACE:-}" ]]; then
@@ -174,14 +565,47 @@ infer_node_ip() {
   ip -4 route get 1.1.1.1 2> /dev/null | awk '/src/{print $7; exit}'
 }
 
-infer_admin_src_cidr() { [[ -n "${SSH_CONNECTION:-}" ]] && echo "${SSH_CONNECTION%% *}/32" || echo ""; }
+# Global IPv6 address of the node (private iface first, then the source of the
+# default IPv6 route); empty on IPv4-only hosts
+infer_node_ip6() {
+  if [[ -n "${PRIVATE_IFACE:-}" ]]; then
+    local ip
+    ip="$(infer_ipv6_on_iface "${PRIVATE_IFACE}")"
+    [[ -n "$ip" ]] && {
+      echo "$ip"
+      return
+    }
+  fi
+  ip -6 route get 2606:4700:4700::1111 2> /dev/null | awk '{for (i = 1; i < NF; i++) if ($i == "src") {print $(i + 1); exit}}'
+}
+
+infer_admin_src_cidr() {
+  [[ -n "${SSH_CONNECTION:-}" ]] || {
+    echo ""
+    return
+  }
+  local src="${SSH_CONNECTION%% *}"
+  src="${src#::ffff:}"
+  if [[ "$src" == *:* ]]; then
+    echo "${src}/128"
+  else
+    echo "${src}/32"
+  fi
+}
 
+# Accepts one or more comma-separated IPv4/IPv6 CIDRs
 validate_cidr_loose() {
-  local c="$1"
+  local c="$1" part
   [[ -z "$c" ]] && return 0
-  [[ "$c" =~ ^([0-9]{1,3}\.){3}[0-9]{1,3}/([0-9]|[1-2][0-9]|3[0-2])$ ]]
+  for part in ${c//,/ }; do
+    [[ "$part" =~ ^([0-9]{1,3}\.){3}[0-9]{1,3}/([0-9]|[1-2][0-9]|3[0-2])$ ]] && continue
+    [[ "$part" == *:* && "$part" =~ ^[0-9a-fA-F:.]+/([0-9]|[1-9][0-9]|1[01][0-9]|12[0-8])$ ]] && continue
+    return 1
+  done
 }
 
+dual_stack_enabled() { [[ "$(bool_norm "${DUAL_STACK:-false}")" == "true" ]]; }
+
 # Kubernetes helpers
 kcfg() { echo "/etc/rancher/k3s/k3s.yaml"; }
 kctl() { KUBECONFIG="$(kcfg)" kubectl "$@"; }
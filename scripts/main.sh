@@ -28,6 +28,10 @@ K3S_VERSION="${K3S_VERSION:-}"
 FLANNEL_BACKEND="${FLANNEL_BACKEND:-vxlan}"
 SERVICE_CIDR="${SERVICE_CIDR:-10.43.0.0/16}"
 CLUSTER_CIDR="${CLUSTER_CIDR:-10.42.0.0/16}"
+# Dual-stack (IPv4 + IPv6) cluster; the IPv6 ranges are added to the IPv4 ones
+DUAL_STACK="${DUAL_STACK:-false}"
+SERVICE_CIDR_V6="${SERVICE_CIDR_V6:-fd00:43::/112}"
+CLUSTER_CIDR_V6="${CLUSTER_CIDR_V6:-fd00:42::/56}"
 TLS_SANS_EXTRA="${TLS_SANS_EXTRA:-}"
 
 SERVER_URL="${SERVER_URL:-}"
@@ -61,6 +65,8 @@ PRIVATE_IFACE="${PRIVATE_IFACE:-}"
 PRIVATE_CIDR="${PRIVATE_CIDR:-}"
 NODE_IP="${NODE_IP:-}"
 NODE_EXTERNAL_IP="${NODE_EXTERNAL_IP:-}"
+NODE_IP_V6="${NODE_IP_V6:-}"
+NODE_EXTERNAL_IP_V6="${NODE_EXTERNAL_IP_V6:-}"
 
 ENABLE_VLAN_NAT="${ENABLE_VLAN_NAT:-false}"
 PUBLIC_IFACE="${PUBLIC_IFACE:-}"
@@ -108,6 +114,15 @@ resolve_inputs() {
     NODE_EXTERNAL_IP="${NODE_IP}"
   fi
 
+  DUAL_STACK="$(bool_norm "${DUAL_STACK}")"
+  if [[ "${DUAL_STACK}" == "true" ]]; then
+    [[ -n "${NODE_IP_V6}" ]] || NODE_IP_V6="$(infer_node_ip6)"
+    [[ -n "${NODE_IP_V6}" ]] || die "DUAL_STACK=true but no global IPv6 address found; set NODE_IP_V6"
+    if [[ -z "${PRIVATE_IFACE}" && -z "${NODE_EXTERNAL_IP_V6}" ]]; then
+      NODE_EXTERNAL_IP_V6="${NODE_IP_V6}"
+    fi
+  fi
+
   # Join nodes should not, by default, configure an edge proxy. If the user wants
   # to configure Caddy on a join node they can set EDGE_PROXY=caddy explicitly.
   if [[ -z "${EDGE_PROXY}" ]]; then
@@ -192,6 +207,8 @@ resolve_inputs() {
 cmd_bootstrap() {
   require_root
 
+  os_preflight
+
   log "Installing base packages"
   system_pkg_install
 
@@ -242,6 +259,8 @@ cmd_bootstrap() {
   k3s_post_install_checks
   if [[ "${MODE}" == "bootstrap" ]]; then
     traefik_wait_ready
+  else
+    k3s_verify_node_registration
   fi
 
   if [[ "${DASH_ENABLE}" == "true" ]]; then
@@ -262,6 +281,7 @@ cmd_bootstrap() {
   echo "k3s:"
   echo "  node-ip: ${NODE_IP}"
   [[ -n "${NODE_EXTERNAL_IP}" ]] && echo "  node-external-ip: ${NODE_EXTERNAL_IP}"
+  [[ "${DUAL_STACK}" == "true" ]] && echo "  dual-stack: ${NODE_IP_V6} (pods ${CLUSTER_CIDR_V6}, services ${SERVICE_CIDR_V6})"
   if [[ -n "${KUBECONFIG_GROUP:-}" ]]; then
     echo "  kubeconfig: $(kcfg) (mode ${KUBECONFIG_MODE}, group ${KUBECONFIG_GROUP})"
   else
@@ -329,7 +349,9 @@ Arguments:
            (kube.<BASE_DOMAIN> by default).
 
 Notes:
-  - This overwrites /etc/caddy/Caddyfile and restarts Caddy.
+  - This replaces /etc/caddy/Caddyfile: the new config is staged in the inactive
+    blue/green slot, validated, switched to and Caddy reloaded; it is reverted
+    when Caddy is unhealthy afterwards (CADDY_HEALTH_TIMEOUT, CADDY_HEALTH_URL).
   - Use CONFIRM=true for non-interactive runs.
 EOF
     return 0
@@ -406,7 +428,9 @@ Options:
   --format <human|csv>          Output format for --show (default: human)
 
 Notes:
-  - This overwrites /etc/caddy/Caddyfile and restarts Caddy.
+  - This replaces /etc/caddy/Caddyfile: the new config is staged in the inactive
+    blue/green slot, validated, switched to and Caddy reloaded; it is reverted
+    when Caddy is unhealthy afterwards (CADDY_HEALTH_TIMEOUT, CADDY_HEALTH_URL).
   - wildcard requires Netcup DNS API credentials (NETCUP_CUSTOMER_NUMBER / NETCUP_DNS_API_KEY / NETCUP_DNS_API_PASSWORD).
   - Use CONFIRM=true for non-interactive runs.
 EOF
@@ -645,6 +669,8 @@ cmd_pair() {
 
   local allow_from=""
   local server_url="${SERVER_URL:-}"
+  local qr="false"
+  local encrypt_to=""
   local self_bin
   self_bin="$(basename "$0")"
 
@@ -660,17 +686,32 @@ cmd_pair() {
         server_url="${1:-}"
         [[ -n "${server_url}" ]] || die "--server-url requires an argument"
         ;;
+      --qr)
+        qr="true"
+        ;;
+      --encrypt-to)
+        shift
+        encrypt_to="${1:-}"
+        [[ -n "${encrypt_to}" ]] || die "--encrypt-to requires an age recipient (age1... or an SSH public key)"
+        ;;
       -h | --help)
         cat << EOF
-Usage: $(basename "$0") pair [--server-url URL] [--allow-from IP/CIDR]
+Usage: $(basename "$0") pair [--server-url URL] [--allow-from IP/CIDR] [--qr] [--encrypt-to RECIPIENT]
 
 Print a copy/paste join command for a worker node (and optionally open UFW 6443 on the
 management node for the provided source IP/CIDR).
 
+--qr prints the join command as a terminal QR code instead of text. --encrypt-to
+prints it as an armored age blob that only RECIPIENT (an age1... public key or an
+SSH public key) can decrypt, safe to paste into chat. With both, the QR code
+holds the encrypted blob. qrencode/age are installed if missing.
+
 Examples:
   sudo $(basename "$0") pair
   sudo $(basename "$0") pair --server-url https://152.53.136.34:6443
   sudo $(basename "$0") pair --allow-from 159.195.64.217
+  sudo $(basename "$0") pair --qr
+  sudo $(basename "$0") pair --encrypt-to age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
 EOF
         return 0
         ;;
@@ -683,9 +724,12 @@ EOF
 
   [[ -n "${server_url}" ]] || server_url="https://$(infer_node_ip):6443"
 
-  local token
-  token="$(tr -d ' \n\r\t' < /var/lib/rancher/k3s/server/node-token)"
-  [[ -n "${token}" ]] || die "Could not read join token from /var/lib/rancher/k3s/server/node-token"
+  # Prefer the agent token of the last 'netcup-kube token rotate' over the
+  # server token, so rotated-out tokens are no longer handed out
+  local token token_file="/var/lib/rancher/k3s/server/node-token"
+  [[ -s /var/lib/rancher/k3s/server/netcup-kube-agent-token ]] && token_file="/var/lib/rancher/k3s/server/netcup-kube-agent-token"
+  token="$(tr -d ' \n\r\t' < "${token_file}")"
+  [[ -n "${token}" ]] || die "Could not read join token from ${token_file}"
 
   if [[ -n "${allow_from}" ]]; then
     confirm_dangerous_or_die "Open k3s API (6443/tcp) in UFW from ${allow_from}"
@@ -693,6 +737,11 @@ EOF
     run ufw reload
   fi
 
+  if [[ "${qr}" == "true" || -n "${encrypt_to}" ]]; then
+    pair_secure_output "${server_url}" "${token}" "${self_bin}" "${qr}" "${encrypt_to}"
+    return 0
+  fi
+
   cat << EOF
 Join pairing info
 -----------------
@@ -711,6 +760,49 @@ Notes:
 EOF
 }
 
+# Print the join command without the token in plain text: as a QR code
+# and/or encrypted to an age recipient
+pair_secure_output() {
+  local server_url="$1" token="$2" self_bin="$3" qr="$4" encrypt_to="$5"
+  local join_cmd payload
+  join_cmd="sudo env SERVER_URL=\"${server_url}\" TOKEN=\"${token}\" ENABLE_UFW=false EDGE_PROXY=none DASH_ENABLE=false ${self_bin} join"
+  payload="${join_cmd}"
+
+  if [[ -n "${encrypt_to}" ]]; then
+    command -v age > /dev/null 2>&1 || pkg_install age
+    need_cmd age
+    payload="$(printf '%s\n' "${join_cmd}" | age --armor --recipient "${encrypt_to}")" \
+      || die "age could not encrypt to ${encrypt_to} (expected an age1... key or an SSH public key)"
+  fi
+  if [[ "${qr}" == "true" ]]; then
+    command -v qrencode > /dev/null 2>&1 || pkg_install qrencode
+    need_cmd qrencode
+  fi
+
+  cat << EOF
+Join pairing info
+-----------------
+SERVER_URL=${server_url}
+
+EOF
+  if [[ "${qr}" == "true" ]]; then
+    printf '%s' "${payload}" | qrencode --type ANSIUTF8 --level M
+    echo
+  else
+    printf '%s\n\n' "${payload}"
+  fi
+
+  if [[ -n "${encrypt_to}" ]]; then
+    cat << EOF
+On the WORKER node, save the encrypted block as join.age and run:
+
+  age --decrypt --identity <your-age-or-ssh-key> join.age | sh
+EOF
+  else
+    echo "Scan the code and run the decoded command on the WORKER node."
+  fi
+}
+
 usage() {
   cat << EOF
 Usage: $(basename "$0") <command>
@@ -761,4 +853,5 @@ main() {
   esac
 }
 
-main "$@"
+# Sourcing only defines the commands (used by tests/integration)
+[[ "${BASH_SOURCE[0]}" != "$0" ]] || main "$@"
@@ -79,8 +79,7 @@ caddy_has_netcup_module() {
 
 caddy_build_with_netcup() {
   log "Building Caddy with Netcup DNS provider (xcaddy)"
-  run apt-get update -y
-  run apt-get install -y --no-install-recommends golang-go git
+  pkg_install golang-go git
   export GOPATH=/root/go
   export GOBIN=/root/go/bin
   run mkdir -p "${GOPATH}" "${GOBIN}"
@@ -120,7 +119,8 @@ WantedBy=multi-user.target
 EOF
   )"
   run systemctl daemon-reload
-  run systemctl enable --now caddy
+  # Started (or reloaded) by caddy_activate once a validated config is active
+  run systemctl enable caddy
 }
 
 caddy_load_or_create_dashboard_basicauth() {
@@ -177,6 +177,113 @@ caddy_load_or_create_dashboard_basicauth() {
   fi
 }
 
+# Blue/green Caddyfile: /etc/caddy/Caddyfile is a symlink to the active slot,
+# Caddyfile.blue or Caddyfile.green. A new config is written to the other
+# slot, formatted and validated there, and switched to by replacing the
+# symlink atomically; when Caddy is unhealthy after the reload, the symlink
+# is pointed back and Caddy reloaded again, so the live config is never
+# edited in place.
+CADDYFILE="/etc/caddy/Caddyfile"
+
+# caddy_active_slot prints the slot the Caddyfile points at (blue or green),
+# or nothing for a missing or plain Caddyfile (before the first staged switch)
+caddy_active_slot() {
+  local target
+  target="$(readlink "${CADDYFILE}" 2> /dev/null || true)"
+  case "${target##*/}" in
+    Caddyfile.blue) echo "blue" ;;
+    Caddyfile.green) echo "green" ;;
+  esac
+}
+
+# caddy_staged_file prints where the next config is written: the inactive
+# slot. Render mode keeps the plain Caddyfile path.
+caddy_staged_file() {
+  if render_enabled; then
+    echo "${CADDYFILE}"
+  elif [[ "$(caddy_active_slot)" == "blue" ]]; then
+    echo "${CADDYFILE}.green"
+  else
+    echo "${CADDYFILE}.blue"
+  fi
+}
+
+# caddy_point_to SLOT_FILE: atomically point the Caddyfile symlink at SLOT_FILE
+# (rename(2) over the old link or plain file)
+caddy_point_to() {
+  local slot
+  slot="$(basename "$1")"
+  run ln -sfn "${slot}" "${CADDYFILE}.switch"
+  run mv -Tf "${CADDYFILE}.switch" "${CADDYFILE}"
+}
+
+# caddy_healthy HOST: wait up to CADDY_HEALTH_TIMEOUT seconds (default 30)
+# until the service is active, the admin API answers and HOST is served on
+# port 80. Port 80 needs no certificate, so hosts still waiting for issuance
+# do not count as failures. CADDY_HEALTH_URL replaces the port 80 probe; any
+# status below 500 is healthy.
+caddy_healthy() {
+  local host="$1"
+  local deadline=$((SECONDS + ${CADDY_HEALTH_TIMEOUT:-30}))
+  local -a probe=(curl -s -o /dev/null --max-time 5 -w '%{http_code}')
+  if [[ -n "${CADDY_HEALTH_URL:-}" ]]; then
+    probe+=("${CADDY_HEALTH_URL}")
+  else
+    probe+=(--resolve "${host}:80:127.0.0.1" "http://${host}/")
+  fi
+  local code
+  while true; do
+    if systemctl is-active --quiet caddy && curl -fs -o /dev/null --max-time 5 http://localhost:2019/config/; then
+      code="$("${probe[@]}" 2> /dev/null || true)"
+      [[ "${code}" =~ ^[1-4][0-9][0-9]$ ]] && return 0
+    fi
+    ((SECONDS < deadline)) || return 1
+    sleep 2
+  done
+}
+
+# caddy_activate STAGED_FILE HOST: format and validate the staged config,
+# switch to it and reload Caddy (start it on first install). When the health
+# check fails, switch back to the previous slot and fail, keeping the staged
+# file for inspection.
+caddy_activate() {
+  local staged="$1" host="$2"
+  local active previous=""
+  active="$(caddy_active_slot)"
+
+  # Keep the generated Caddyfile formatted (removes 'Caddyfile input is not formatted' warnings).
+  run /usr/local/bin/caddy fmt --overwrite "${staged}"
+  run /usr/local/bin/caddy validate --adapter caddyfile --config "${staged}"
+
+  if [[ -n "${active}" ]]; then
+    previous="${CADDYFILE}.${active}"
+  elif [[ -f "${CADDYFILE}" && ! -L "${CADDYFILE}" ]]; then
+    # First staged switch: the plain Caddyfile becomes the other slot
+    previous="${CADDYFILE}.green"
+    [[ "${staged}" != "${previous}" ]] || previous="${CADDYFILE}.blue"
+    run cp -p "${CADDYFILE}" "${previous}"
+  fi
+
+  log "Switching Caddy to ${staged}"
+  caddy_point_to "${staged}"
+  local reloaded="true"
+  if systemctl is-active --quiet caddy; then
+    run systemctl reload caddy || reloaded="false"
+  else
+    run systemctl restart caddy || reloaded="false"
+  fi
+  if [[ "${reloaded}" == "true" ]] && caddy_healthy "${host}"; then
+    log "Caddy is healthy with the new config (previous: ${previous:-none})"
+    return 0
+  fi
+
+  [[ -n "${previous}" ]] || die "Caddy is unhealthy with the new config and there is no previous config to revert to; check: journalctl -u caddy"
+  log "WARN: Caddy is unhealthy with the new config; reverting to ${previous}"
+  caddy_point_to "${previous}"
+  run systemctl reload caddy || run systemctl restart caddy || true
+  die "New Caddy config failed the health check and was reverted; it is kept at ${staged} (journalctl -u caddy)"
+}
+
 caddy_write_caddyfile() {
   [[ "${EDGE_PROXY:-}" == "caddy" ]] || return 0
   [[ -n "${EDGE_UPSTREAM:-}" ]] || EDGE_UPSTREAM="http://127.0.0.1:${TRAEFIK_NODEPORT_HTTP}"
@@ -210,6 +317,11 @@ caddy_write_caddyfile() {
     [[ -n "${site_hosts}" ]] || die "No hosts provided for HTTP-01. Set CADDY_HTTP01_HOSTS."
   fi
 
+  # Probed by the health check after the switch
+  CADDY_HEALTH_HOST="${site_hosts%%,*}"
+  local caddyfile
+  caddyfile="$(caddy_staged_file)"
+
   local global_email_block=""
   if [[ -n "${ACME_EMAIL:-}" ]]; then
     global_email_block="{
@@ -226,7 +338,7 @@ caddy_write_caddyfile() {
     [[ -n "${CADDY_DNS_PROPAGATION_TIMEOUT:-}" ]] || CADDY_DNS_PROPAGATION_TIMEOUT="10m"
     [[ -n "${CADDY_DNS_PROPAGATION_DELAY:-}" ]] || CADDY_DNS_PROPAGATION_DELAY="5s"
 
-    write_file /etc/caddy/Caddyfile "0644" "$(
+    write_file "${caddyfile}" "0644" "$(
       cat << EOF
 ${global_email_block}
 
@@ -262,7 +374,7 @@ EOR
 EOF
     )"
   else
-    write_file /etc/caddy/Caddyfile "0644" "$(
+    write_file "${caddyfile}" "0644" "$(
       cat << EOF
 ${global_email_block}
 
@@ -306,8 +418,7 @@ caddy_setup() {
   else
     if ! command -v /usr/local/bin/caddy > /dev/null 2>&1; then
       log "Installing Caddy (http01 mode)"
-      run apt-get update -y
-      run apt-get install -y --no-install-recommends caddy || true
+      pkg_install caddy || true
       if command -v /usr/bin/caddy > /dev/null 2>&1; then
         run ln -sf /usr/bin/caddy /usr/local/bin/caddy
       fi
@@ -319,7 +430,7 @@ caddy_setup() {
   caddy_install_systemd_unit
 
   if [[ "${DRY_RUN:-false}" != "true" ]]; then
-    log "Validating and restarting Caddy"
+    log "Validating and activating the staged Caddy config"
     if [[ "${CADDY_CERT_MODE}" == "dns01_wildcard" && -n "${BASE_DOMAIN:-}" ]]; then
       dns_warn_if_netcup_not_authoritative "${BASE_DOMAIN}"
     fi
@@ -330,11 +441,8 @@ caddy_setup() {
       source "${NETCUP_ENVFILE}"
       set +a
     fi
-    # Keep the generated Caddyfile formatted (removes 'Caddyfile input is not formatted' warnings).
-    run /usr/local/bin/caddy fmt --overwrite /etc/caddy/Caddyfile
-    run /usr/local/bin/caddy validate --config /etc/caddy/Caddyfile
-    run systemctl restart caddy
+    caddy_activate "$(caddy_staged_file)" "${CADDY_HEALTH_HOST}"
   else
-    log "[DRY_RUN] Skipping caddy validate/restart"
+    log "[DRY_RUN] Skipping caddy validate/switch/reload"
   fi
 }
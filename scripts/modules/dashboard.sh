@@ -5,9 +5,18 @@ set -euo pipefail
 
 helm_install_cli() {
   command -v helm > /dev/null 2>&1 && return 0
+  if airgap_enabled; then
+    local archive
+    for archive in "${AIRGAP_BUNDLE%/}"/helm/helm-*-linux-*.tar.gz; do
+      [[ -f "${archive}" ]] || die "AIRGAP_BUNDLE=${AIRGAP_BUNDLE} has no Helm CLI archive"
+      log "Installing Helm CLI from the air-gap bundle"
+      run tar -C /tmp -xzf "${archive}"
+      run install -m 0755 /tmp/linux-*/helm /usr/local/bin/helm
+      return 0
+    done
+  fi
   log "Installing Helm CLI"
-  run apt-get update -y
-  run apt-get install -y --no-install-recommends tar ca-certificates curl
+  pkg_install tar ca-certificates curl
   local ver="v3.19.4"
   local tgz="helm-${ver}-linux-amd64.tar.gz"
   run curl -fsSL "https://get.helm.sh/${tgz}" -o "/tmp/${tgz}"
@@ -32,10 +41,9 @@ dashboard_install() {
   log "Installing/Upgrading Kubernetes Dashboard via Helm (ingress disabled; we create our own Traefik ingress)"
   # Ensure repo exists and URL is correct (don't swallow failures; otherwise `helm repo update` will error with "no repositories found")
   # Upstream chart repo URL: https://kubernetes.github.io/dashboard/
-  run helm repo add kubernetes-dashboard https://kubernetes.github.io/dashboard/ --force-update
-  run helm repo update kubernetes-dashboard
+  run helm_repo_add kubernetes-dashboard https://kubernetes.github.io/dashboard/ --force-update
 
-  run helm upgrade --install kubernetes-dashboard kubernetes-dashboard/kubernetes-dashboard \
+  helm_upgrade_install kubernetes-dashboard kubernetes-dashboard/kubernetes-dashboard \
     --namespace kubernetes-dashboard --create-namespace \
     --set ingress.enabled=false
 
@@ -48,7 +56,7 @@ dashboard_install() {
   fi
 
   log "Ensuring Traefik ServersTransport for dashboard (skip upstream TLS verification)"
-  kctl apply -f - << EOF
+  apply_manifest dashboard-servers-transport << EOF
 apiVersion: traefik.io/v1alpha1
 kind: ServersTransport
 metadata:
@@ -59,7 +67,7 @@ spec:
 EOF
 
   log "Creating/Updating dashboard Ingress (Traefik entrypoint: web)"
-  kctl apply -f - << EOF
+  apply_manifest dashboard-ingress << EOF
 apiVersion: networking.k8s.io/v1
 kind: Ingress
 metadata:
@@ -12,6 +12,10 @@ k3s_build_tls_sans_yaml() {
     echo "${fqdn}"
     echo "${node_ip}"
     [[ -n "${NODE_EXTERNAL_IP:-}" ]] && echo "${NODE_EXTERNAL_IP}"
+    if dual_stack_enabled; then
+      [[ -n "${NODE_IP_V6:-}" ]] && echo "${NODE_IP_V6}"
+      [[ -n "${NODE_EXTERNAL_IP_V6:-}" ]] && echo "${NODE_EXTERNAL_IP_V6}"
+    fi
     if [[ -n "${TLS_SANS_EXTRA:-}" ]]; then
       IFS=',' read -r -a extra <<< "${TLS_SANS_EXTRA}"
       for s in "${extra[@]}"; do
@@ -22,6 +26,19 @@ k3s_build_tls_sans_yaml() {
   } | awk '{print "- "$0}'
 }
 
+# k3s_yaml_list: render a comma-separated list as a YAML list under key
+# (nothing if the list is empty)
+k3s_yaml_list() {
+  local key="$1" item items
+  IFS=',' read -r -a items <<< "$2"
+  [[ "${#items[@]}" -gt 0 ]] || return 0
+  echo "${key}:"
+  for item in "${items[@]}"; do
+    item="$(echo "${item}" | xargs)"
+    [[ -n "${item}" ]] && echo "  - \"${item}\""
+  done
+}
+
 k3s_write_config() {
   local node_ip="$1"
   local flannel_iface_line=""
@@ -29,6 +46,17 @@ k3s_write_config() {
   local kubeconfig_group_line=""
   [[ -n "${KUBECONFIG_GROUP:-}" ]] && kubeconfig_group_line=$'write-kubeconfig-group: '"\"${KUBECONFIG_GROUP}\""
 
+  # Dual-stack: k3s takes comma-separated IPv4,IPv6 pairs
+  local node_ips="${node_ip}" external_ips="${NODE_EXTERNAL_IP:-}"
+  local cluster_cidrs="${CLUSTER_CIDR}" service_cidrs="${SERVICE_CIDR}" ipv6_masq_line=""
+  if dual_stack_enabled; then
+    node_ips="${node_ips},${NODE_IP_V6}"
+    [[ -n "${NODE_EXTERNAL_IP_V6:-}" ]] && external_ips="${external_ips:+${external_ips},}${NODE_EXTERNAL_IP_V6}"
+    cluster_cidrs="${cluster_cidrs},${CLUSTER_CIDR_V6}"
+    service_cidrs="${service_cidrs},${SERVICE_CIDR_V6}"
+    ipv6_masq_line='flannel-ipv6-masq: true'
+  fi
+
   local cfg
   case "${MODE}" in
     bootstrap)
@@ -38,11 +66,12 @@ k3s_write_config() {
         cat << EOF
 write-kubeconfig-mode: "${KUBECONFIG_MODE}"
 ${kubeconfig_group_line}
-node-ip: "${node_ip}"
+node-ip: "${node_ips}"
 ${flannel_iface_line}
 flannel-backend: "${FLANNEL_BACKEND}"
-cluster-cidr: "${CLUSTER_CIDR}"
-service-cidr: "${SERVICE_CIDR}"
+${ipv6_masq_line}
+cluster-cidr: "${cluster_cidrs}"
+service-cidr: "${service_cidrs}"
 etcd-expose-metrics: true
 etcd-snapshot-schedule-cron: "0 */6 * * *"
 etcd-snapshot-retention: 12
@@ -56,15 +85,17 @@ EOF
       # (e.g. etcd/tls-san/cluster-init), otherwise k3s-agent will fail to start with "flag provided but not defined".
       cfg="$(
         cat << EOF
-node-ip: "${node_ip}"
+node-ip: "${node_ips}"
 ${flannel_iface_line}
+$(k3s_yaml_list node-label "${NODE_LABELS:-}")
+$(k3s_yaml_list node-taint "${NODE_TAINTS:-}")
 EOF
       )"
       ;;
     *) die "Unknown MODE: ${MODE}" ;;
   esac
 
-  [[ -n "${NODE_EXTERNAL_IP:-}" ]] && cfg+=$'\n'"node-external-ip: \"${NODE_EXTERNAL_IP}\""$'\n'
+  [[ -n "${external_ips}" ]] && cfg+=$'\n'"node-external-ip: \"${external_ips}\""$'\n'
 
   case "${MODE}" in
     bootstrap) cfg+=$'\ncluster-init: true\n' ;;
@@ -85,6 +116,7 @@ EOF
 k3s_maybe_configure_proxy() {
   [[ -n "${HTTP_PROXY:-}${HTTPS_PROXY:-}" ]] || return 0
   local default_no_proxy=".svc,.cluster.local,localhost,127.0.0.1,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,${CLUSTER_CIDR},${SERVICE_CIDR}"
+  dual_stack_enabled && default_no_proxy="${default_no_proxy},::1,${CLUSTER_CIDR_V6},${SERVICE_CIDR_V6}"
   local no_proxy_combined="${default_no_proxy}"
   [[ -n "${NO_PROXY_EXTRA:-}" ]] && no_proxy_combined="${no_proxy_combined},${NO_PROXY_EXTRA}"
   local svc
@@ -124,20 +156,42 @@ k3s_maybe_skip_install() {
 }
 
 k3s_download_installer() {
+  if airgap_enabled; then
+    k3s_stage_airgap_bundle
+    return 0
+  fi
   log "Downloading k3s installer to ${INSTALLER_PATH}"
   run curl --fail --location --proto '=https' --tlsv1.2 https://get.k3s.io -o "${INSTALLER_PATH}"
   run chmod +x "${INSTALLER_PATH}"
 }
 
+# k3s_stage_airgap_bundle: put the bundled installer, binary and airgap
+# images where the installer (with INSTALL_K3S_SKIP_DOWNLOAD) and k3s expect them
+k3s_stage_airgap_bundle() {
+  local dir="${AIRGAP_BUNDLE%/}/k3s" images
+  [[ -f "${dir}/install.sh" && -f "${dir}/k3s" ]] || die "AIRGAP_BUNDLE=${AIRGAP_BUNDLE} has no k3s/install.sh and k3s/k3s (create it with netcup-kube bundle create)"
+  log "Installing k3s from the air-gap bundle ${AIRGAP_BUNDLE}"
+  run install -m 0755 "${dir}/install.sh" "${INSTALLER_PATH}"
+  run install -m 0755 "${dir}/k3s" /usr/local/bin/k3s
+  run mkdir -p /var/lib/rancher/k3s/agent/images
+  for images in "${dir}"/k3s-airgap-images-*; do
+    [[ -f "${images}" ]] || continue
+    run install -m 0644 "${images}" /var/lib/rancher/k3s/agent/images/
+  done
+}
+
 k3s_install() {
-  local exec_mode="server"
+  local exec_mode="server" skip_download="false"
   if [[ "${MODE}" == "join" ]]; then
     exec_mode="agent"
   fi
+  if airgap_enabled; then
+    skip_download="true"
+  fi
   if [[ -n "${K3S_VERSION:-}" ]]; then
-    run env INSTALL_K3S_VERSION="${K3S_VERSION}" INSTALL_K3S_EXEC="${exec_mode}" K3S_CONFIG_FILE="/etc/rancher/k3s/config.yaml" "${INSTALLER_PATH}"
+    run env INSTALL_K3S_SKIP_DOWNLOAD="${skip_download}" INSTALL_K3S_VERSION="${K3S_VERSION}" INSTALL_K3S_EXEC="${exec_mode}" K3S_CONFIG_FILE="/etc/rancher/k3s/config.yaml" "${INSTALLER_PATH}"
   else
-    run env INSTALL_K3S_CHANNEL="${CHANNEL}" INSTALL_K3S_EXEC="${exec_mode}" K3S_CONFIG_FILE="/etc/rancher/k3s/config.yaml" "${INSTALLER_PATH}"
+    run env INSTALL_K3S_SKIP_DOWNLOAD="${skip_download}" INSTALL_K3S_CHANNEL="${CHANNEL}" INSTALL_K3S_EXEC="${exec_mode}" K3S_CONFIG_FILE="/etc/rancher/k3s/config.yaml" "${INSTALLER_PATH}"
   fi
 }
 
@@ -175,8 +229,55 @@ k3s_post_install_checks() {
   fi
 }
 
+# k3s_verify_node_registration: wait until a joined node exists in the
+# cluster and check it carries NODE_LABELS and NODE_TAINTS. k3s applies them
+# only when the node registers for the first time, so a re-join with changed
+# values needs kubectl on the server.
+k3s_verify_node_registration() {
+  [[ -n "${NODE_LABELS:-}${NODE_TAINTS:-}" ]] || return 0
+  [[ "${DRY_RUN:-false}" == "true" ]] && return 0
+  local node have="" want item key rest
+  local kubeconfig="/var/lib/rancher/k3s/agent/kubelet.kubeconfig"
+  node="$(hostname | tr '[:upper:]' '[:lower:]')"
+  log "Waiting for node ${node} to register"
+  for _ in {1..60}; do
+    if have="$(k3s kubectl --kubeconfig "${kubeconfig}" get node "${node}" \
+      -o go-template='{{range $k, $v := .metadata.labels}}{{$k}}={{$v}}{{"\n"}}{{end}}{{range .spec.taints}}{{.key}}={{.value}}:{{.effect}}{{"\n"}}{{end}}' 2> /dev/null)"; then
+      break
+    fi
+    have=""
+    sleep 2
+  done
+  if [[ -z "${have}" ]]; then
+    log "WARNING: node ${node} did not register within 2 minutes; check it on the server: kubectl get node ${node} --show-labels"
+    return 0
+  fi
+
+  IFS=',' read -r -a want <<< "${NODE_LABELS:-}"
+  for item in "${want[@]}"; do
+    item="$(echo "${item}" | xargs)"
+    [[ -z "${item}" ]] || grep -qxF "${item}" <<< "${have}" \
+      || log "WARNING: node ${node} lacks label ${item} (it registered before); on the server run: kubectl label node ${node} ${item} --overwrite"
+  done
+  IFS=',' read -r -a want <<< "${NODE_TAINTS:-}"
+  for item in "${want[@]}"; do
+    item="$(echo "${item}" | xargs)"
+    [[ -n "${item}" ]] || continue
+    # Taints without a value are listed as key=:Effect
+    key="${item%%:*}"
+    rest="${item#*:}"
+    [[ "${key}" == *=* ]] || key="${key}="
+    grep -qxF "${key}:${rest}" <<< "${have}" \
+      || log "WARNING: node ${node} lacks taint ${item} (it registered before); on the server run: kubectl taint node ${node} ${item} --overwrite"
+  done
+  log "Node ${node} registered"
+}
+
 traefik_write_nodeport_manifest() {
   log "Writing Traefik NodePort HelmChartConfig manifest"
+  # On dual-stack clusters the NodePorts also answer on IPv6 (e.g. EDGE_UPSTREAM=http://[::1]:30080)
+  local ip_family_line=""
+  dual_stack_enabled && ip_family_line=$'\n      ipFamilyPolicy: PreferDualStack'
   run mkdir -p /var/lib/rancher/k3s/server/manifests
   write_file /var/lib/rancher/k3s/server/manifests/traefik-nodeport.yaml "0644" "$(
     cat << EOF
@@ -188,7 +289,7 @@ metadata:
 spec:
   valuesContent: |-
     service:
-      type: NodePort
+      type: NodePort${ip_family_line}
     ports:
       web:
         port: 80
@@ -4,10 +4,7 @@ set -euo pipefail
 # Requires: common.sh sourced
 
 system_pkg_install() {
-  need_cmd apt-get
-  export DEBIAN_FRONTEND=noninteractive
-  run apt-get update -y
-  run apt-get install -y --no-install-recommends \
+  pkg_install \
     ca-certificates curl iproute2 iptables kmod util-linux procps gnupg lsb-release sed tar coreutils jq nftables
 }
 
@@ -4,7 +4,11 @@ set -euo pipefail
 # Requires: common.sh sourced
 
 ufw_enable_safe_defaults() {
-  run apt-get install -y --no-install-recommends ufw
+  if [[ "$(os_family)" != "debian" ]]; then
+    log "WARNING: ENABLE_UFW is only supported on Debian/Ubuntu; skipping (on RHEL-family, k3s recommends disabling firewalld)"
+    return 0
+  fi
+  pkg_install ufw
   run ufw allow OpenSSH || true
   run ufw --force enable
 }
@@ -14,7 +18,10 @@ ufw_apply_rules() {
   ufw status 2> /dev/null | grep -qi "Status: active" || return 0
 
   if [[ -n "${ADMIN_SRC_CIDR:-}" ]]; then
-    run ufw allow from "${ADMIN_SRC_CIDR}" to any port 6443 proto tcp || true
+    local cidr
+    for cidr in ${ADMIN_SRC_CIDR//,/ }; do
+      run ufw allow from "${cidr}" to any port 6443 proto tcp || true
+    done
   fi
 
   if [[ "${EDGE_PROXY:-}" == "caddy" ]]; then
@@ -9,13 +9,15 @@ set -euo pipefail
 
 recipe_confirm_or_die() {
   local msg="$1"
+  # CONFIRM=true pre-approves; `netcup-kube install` sets it after confirming.
+  [[ "${CONFIRM:-false}" == "true" ]] && return 0
   if is_tty; then
     local ok
     ok="$(prompt "${msg} (type 'yes' to continue)" "no")"
     [[ "${ok}" == "yes" ]] || die "Aborted."
     return 0
   fi
-  [[ "${CONFIRM:-false}" == "true" ]] || die "Non-interactive run requires CONFIRM=true. Refusing: ${msg}"
+  die "Non-interactive run requires CONFIRM=true (or --yes). Refusing: ${msg}"
 }
 
 recipe_ensure_namespace() {
@@ -30,6 +32,9 @@ recipe_maybe_add_edge_http_domain() {
   [[ -n "${host}" ]] || return 0
 
   log "NOTE: Ensure ${host} is in your edge-http domains before accessing the UI."
+  if render_enabled; then
+    return 0
+  fi
   if [[ -f "/etc/caddy/Caddyfile" ]]; then
     # We are on the server; append domain using the dedicated subcommand.
     if command -v "${SCRIPTS_DIR}/main.sh" > /dev/null 2>&1; then
@@ -75,24 +80,9 @@ recipe_kdelete() {
 
 recipe_helm_repo_add() {
   # Add a Helm repository if it doesn't already exist, then update.
+  # Honors the chart mirror configuration (HELM_MIRROR_*, see lib/common.sh).
   # Usage: recipe_helm_repo_add <name> <url> [--force-update]
-  local repo_name="$1"
-  local repo_url="$2"
-  local force_update="${3:-}"
-
-  [[ -n "${repo_name}" ]] || die "Helm repo name is required"
-  [[ -n "${repo_url}" ]] || die "Helm repo URL is required"
-
-  log "Adding Helm repository: ${repo_name}"
-  # Use word boundary to ensure exact repo name match
-  if ! helm repo list 2> /dev/null | grep -q "^${repo_name}[[:space:]]"; then
-    if [[ "${force_update}" == "--force-update" ]]; then
-      helm repo add "${repo_name}" "${repo_url}" --force-update
-    else
-      helm repo add "${repo_name}" "${repo_url}"
-    fi
-  fi
-  helm repo update "${repo_name}"
+  helm_repo_add "$@"
 }
 
 recipe_check_kubeconfig() {
@@ -136,3 +126,75 @@ recipe_check_kubeconfig() {
     run scp "${remote_user}@${remote_host}:/etc/rancher/k3s/k3s.yaml" "${KUBECONFIG}"
   fi
 }
+
+# recipe_render_kubectl_apply KUBECTL_ARGS...: render the manifest an apply
+# would send (stdin, a local file or a URL) as manifests/NNN.yaml
+recipe_render_kubectl_apply() {
+  local src="" next=false arg n
+  for arg in "$@"; do
+    if [[ "${next}" == "true" ]]; then
+      src="${arg}"
+      break
+    fi
+    case "${arg}" in
+      -f | --filename) next=true ;;
+      --filename=*) src="${arg#*=}" && break ;;
+    esac
+  done
+  mkdir -p "${RENDER_DIR%/}/manifests"
+  n="$(find "${RENDER_DIR%/}/manifests" -maxdepth 1 -name '*.yaml' | wc -l | tr -d ' ')"
+  local name
+  name="$(printf '%03d' $((n + 1)))"
+  case "${src}" in
+    "" | -) render_file "manifests/${name}.yaml" "0644" ;;
+    http://* | https://*) curl -fsSL "${src}" | render_file "manifests/${name}.yaml" "0644" ;;
+    *) render_file "manifests/${name}.yaml" "0644" < "${src}" ;;
+  esac
+}
+
+# Render mode (`netcup-kube install <recipe> --render-dir <dir>`): Helm
+# releases and applied manifests are written below RENDER_DIR instead of
+# changing the cluster. Reads (get, create --dry-run, helm repo/template) still
+# run; other cluster mutations are skipped.
+if render_enabled; then
+  eval "recipe_k_passthrough() $(declare -f k | tail -n +2)"
+
+  k() {
+    local verb="" skip=false arg
+    for arg in "$@"; do
+      if [[ "${skip}" == "true" ]]; then
+        skip=false
+        continue
+      fi
+      case "${arg}" in
+        -n | --namespace | --context | --kubeconfig) skip=true ;;
+        -*) ;;
+        *)
+          verb="${arg}"
+          break
+          ;;
+      esac
+    done
+    case "${verb}" in
+      apply) recipe_render_kubectl_apply "$@" ;;
+      create)
+        case " $* " in
+          *" --dry-run"*) recipe_k_passthrough "$@" ;;
+          *) log "[RENDER] skipping kubectl ${verb}" ;;
+        esac
+        ;;
+      delete | patch | label | annotate | replace | scale | set | rollout | wait | exec | cp | edit | taint | cordon | drain)
+        log "[RENDER] skipping kubectl ${verb}"
+        ;;
+      *) recipe_k_passthrough "$@" ;;
+    esac
+  }
+
+  helm() {
+    case "${1:-}" in
+      upgrade | install) render_helm_release "$@" ;;
+      uninstall | delete | rollback) log "[RENDER] skipping helm ${1}" ;;
+      *) command helm "$@" ;;
+    esac
+  }
+fi
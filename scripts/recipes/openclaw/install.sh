@@ -671,7 +671,9 @@ log "NOTE: Using managed OpenClaw config from: ${EFFECTIVE_OPENCLAW_CONFIG_FILE}
 
 CHART_VERSION_TO_USE="${CHART_VERSION_OPENCLAW}"
 if [[ "${CHART_VERSION_TO_USE}" == "latest" ]]; then
-  latest_chart_version="$(helm search repo openclaw/openclaw --versions 2> /dev/null | awk 'NR==2 {print $2}' || true)"
+  devel_flag=""
+  [[ "${CHART_CHANNEL_OPENCLAW:-stable}" == "beta" ]] && devel_flag="--devel"
+  latest_chart_version="$(helm_latest_chart_version openclaw/openclaw ${devel_flag} || true)"
   if [[ -n "${latest_chart_version}" ]]; then
     CHART_VERSION_TO_USE="${latest_chart_version}"
     log "Using latest OpenClaw chart version (CHART_VERSION_OPENCLAW=latest): ${CHART_VERSION_TO_USE}"
@@ -0,0 +1,101 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+SCRIPTS_DIR="$(cd "${SCRIPT_DIR}/../.." && pwd)"
+# shellcheck disable=SC1091
+source "${SCRIPTS_DIR}/lib/common.sh"
+# shellcheck disable=SC1091
+source "${SCRIPTS_DIR}/recipes/lib.sh"
+
+usage() {
+  cat << 'EOF_USAGE'
+Install Rancher's system-upgrade-controller (in-cluster k3s upgrades via Plans).
+
+Usage:
+  netcup-kube install system-upgrade-controller [--apply-plans] [--uninstall]
+
+Options:
+  --apply-plans        Also apply the k3s upgrade Plans from 'netcup-kube k3s plan'
+                       (targets the pinned K3S_VERSION, then CHANNEL).
+  --uninstall          Uninstall the controller, its CRDs, and all Plans.
+  -h, --help           Show this help.
+
+Environment:
+  KUBECONFIG           Kubeconfig to use. If not set, defaults to /etc/rancher/k3s/k3s.yaml (on the node).
+
+Notes:
+  - Installs the pinned upstream release manifests (SYSTEM_UPGRADE_CONTROLLER_VERSION in recipes.conf)
+    into the 'system-upgrade' namespace.
+  - The controller does nothing until Plans exist. Generate them with:
+      netcup-kube k3s plan --output <gitops-repo>/k3s-upgrade.yaml
+    and commit them to your GitOps repo, or re-run this recipe with --apply-plans.
+  - This is the GitOps alternative to the SSH-driven 'netcup-kube k3s upgrade'; use one or the other.
+EOF_USAGE
+}
+
+APPLY_PLANS="false"
+UNINSTALL="false"
+RELEASE_URL="https://github.com/rancher/system-upgrade-controller/releases/download/${SYSTEM_UPGRADE_CONTROLLER_VERSION}"
+
+while [[ $# -gt 0 ]]; do
+  case "$1" in
+    --apply-plans)
+      APPLY_PLANS="true"
+      ;;
+    --uninstall)
+      UNINSTALL="true"
+      ;;
+    -h | --help | help)
+      usage
+      exit 0
+      ;;
+    *)
+      echo "Unknown argument: $1" >&2
+      usage
+      exit 1
+      ;;
+  esac
+  shift || true
+done
+
+recipe_check_kubeconfig
+
+if [[ "${UNINSTALL}" == "true" ]]; then
+  recipe_confirm_or_die "Uninstall system-upgrade-controller (including all upgrade Plans)"
+  log "Deleting upgrade Plans"
+  k delete plans.upgrade.cattle.io --all -n "${NAMESPACE_SYSTEM_UPGRADE}" --ignore-not-found=true || true
+  log "Deleting system-upgrade-controller ${SYSTEM_UPGRADE_CONTROLLER_VERSION}"
+  k delete --ignore-not-found=true -f "${RELEASE_URL}/system-upgrade-controller.yaml" || true
+  k delete --ignore-not-found=true -f "${RELEASE_URL}/crd.yaml" || true
+  exit 0
+fi
+
+log "Installing system-upgrade-controller ${SYSTEM_UPGRADE_CONTROLLER_VERSION}"
+k apply -f "${RELEASE_URL}/crd.yaml"
+k apply -f "${RELEASE_URL}/system-upgrade-controller.yaml"
+
+log "Waiting for system-upgrade-controller to become ready"
+k -n "${NAMESPACE_SYSTEM_UPGRADE}" rollout status deploy/system-upgrade-controller --timeout=5m
+
+if [[ "${APPLY_PLANS}" == "true" ]]; then
+  project_root="$(cd "${SCRIPTS_DIR}/.." && pwd)"
+  netcup_kube_bin="${project_root}/bin/netcup-kube"
+  [[ -x "${netcup_kube_bin}" ]] || die "${netcup_kube_bin} not found; build it first (make build) or apply Plans manually"
+  log "Applying k3s upgrade Plans"
+  "${netcup_kube_bin}" k3s plan --namespace "${NAMESPACE_SYSTEM_UPGRADE}" | k apply -f -
+fi
+
+log "system-upgrade-controller installed successfully!"
+cat << EOF_NEXT
+
+Next steps
+----------
+- Generate Plans for the pinned k3s version (GitOps):
+    netcup-kube k3s plan --output <gitops-repo>/k3s-upgrade.yaml
+- Or apply them directly:
+    netcup-kube k3s plan | kubectl apply -f -
+- Watch progress:
+    kubectl -n ${NAMESPACE_SYSTEM_UPGRADE} get plans,jobs
+    kubectl get nodes -o wide
+EOF_NEXT
@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// caddyActivateScript runs caddy_activate from scripts/modules/caddy.sh with
+// the Caddyfile below $DIR and caddy, systemctl and curl stubbed out. The
+// admin API probe of caddy_healthy fails unless $HEALTHY is true.
+const caddyActivateScript = `
+set -euo pipefail
+source scripts/lib/common.sh
+source scripts/modules/caddy.sh
+CADDYFILE="${DIR}/Caddyfile"
+CADDY_HEALTH_TIMEOUT=0
+
+/usr/local/bin/caddy() { :; }
+systemctl() { echo "systemctl $*" >> "${DIR}/calls"; }
+curl() {
+  [[ "${HEALTHY}" == "true" ]] || return 7
+  [[ " $* " != *" -w "* ]] || printf '200'
+}
+
+caddy_activate "$(caddy_staged_file)" kube.example.com
+`
+
+// runCaddyActivate stages a new config next to the active blue slot and
+// activates it
+func runCaddyActivate(t *testing.T, healthy bool) (dir string, output []byte, err error) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("caddy.sh needs GNU coreutils (mv -T)")
+	}
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+
+	dir = t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Caddyfile.blue"), []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("Caddyfile.blue", filepath.Join(dir, "Caddyfile")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Caddyfile.green"), []byte("new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("bash", "-c", caddyActivateScript)
+	cmd.Dir = filepath.Join("..", "..")
+	cmd.Env = append(os.Environ(), "DIR="+dir, "HEALTHY="+strconv.FormatBool(healthy), "DRY_RUN=false")
+	output, err = cmd.CombinedOutput()
+	return dir, output, err
+}
+
+// TestCaddyActivateRevertsUnhealthyConfig checks that a config failing the
+// health check is switched back to the previous slot and kept for inspection
+func TestCaddyActivateRevertsUnhealthyConfig(t *testing.T) {
+	dir, output, err := runCaddyActivate(t, false)
+	if err == nil {
+		t.Fatalf("caddy_activate succeeded with a failing health check. Output: %s", output)
+	}
+	if !strings.Contains(string(output), "failed the health check and was reverted") {
+		t.Errorf("Output should report the revert, got: %s", output)
+	}
+
+	target, err := os.Readlink(filepath.Join(dir, "Caddyfile"))
+	if err != nil || target != "Caddyfile.blue" {
+		t.Errorf("Caddyfile points to %q (%v), want the previous Caddyfile.blue", target, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "Caddyfile")); err != nil || string(data) != "old\n" {
+		t.Errorf("Active config = %q (%v), want the previous one", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Caddyfile.green")); err != nil {
+		t.Errorf("Rejected config was not kept: %v", err)
+	}
+	calls, _ := os.ReadFile(filepath.Join(dir, "calls"))
+	if n := strings.Count(string(calls), "systemctl reload caddy"); n != 2 {
+		t.Errorf("Caddy reloaded %d times, want 2 (new config, then the previous one). Calls:\n%s", n, calls)
+	}
+}
+
+// TestCaddyActivateKeepsHealthyConfig checks that a healthy config stays active
+func TestCaddyActivateKeepsHealthyConfig(t *testing.T) {
+	dir, output, err := runCaddyActivate(t, true)
+	if err != nil {
+		t.Fatalf("caddy_activate failed: %v. Output: %s", err, output)
+	}
+	target, err := os.Readlink(filepath.Join(dir, "Caddyfile"))
+	if err != nil || target != "Caddyfile.green" {
+		t.Errorf("Caddyfile points to %q (%v), want Caddyfile.green", target, err)
+	}
+}
@@ -0,0 +1,144 @@
+package integration
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	pairToken     = "K10secret::server:abc"
+	pairRecipient = "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"
+	pairArmor     = "-----BEGIN AGE ENCRYPTED FILE-----"
+)
+
+// pairStubs are age and qrencode stand-ins that record their arguments and
+// input below $DIR
+var pairStubs = map[string]string{
+	"age": `#!/usr/bin/env bash
+printf '%s\n' "$*" > "${DIR}/age.args"
+cat > "${DIR}/age.in"
+printf '%s\nYWdlLWJsb2I=\n-----END AGE ENCRYPTED FILE-----\n' "` + pairArmor + `"
+`,
+	"qrencode": `#!/usr/bin/env bash
+cat > "${DIR}/qr.in"
+echo "<qr code>"
+`,
+}
+
+// runPair sources scripts/main.sh and runs the given shell snippet with the
+// stubs first in PATH and package installs failing
+func runPair(t *testing.T, snippet string) (dir string, output []byte, err error) {
+	t.Helper()
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+
+	dir = t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, script := range pairStubs {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := `
+set -euo pipefail
+source scripts/main.sh
+require_root() { :; }
+pkg_install() { echo "unexpected install of $*" >&2; return 1; }
+` + snippet
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Dir = filepath.Join("..", "..")
+	cmd.Env = append(os.Environ(), "DIR="+dir, "PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	output, err = cmd.CombinedOutput()
+	return dir, output, err
+}
+
+// TestPairSecureOutput checks that --qr and --encrypt-to never print the join
+// token in plain text and hand the right payload to qrencode and age
+func TestPairSecureOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		qr         string
+		encryptTo  string
+		wantQRIn   string // input of qrencode; empty: not called
+		wantAge    bool
+		wantOutput string
+	}{
+		{
+			name:       "qr",
+			qr:         "true",
+			wantQRIn:   pairToken,
+			wantOutput: "<qr code>",
+		},
+		{
+			name:       "encrypt-to",
+			qr:         "false",
+			encryptTo:  pairRecipient,
+			wantAge:    true,
+			wantOutput: pairArmor,
+		},
+		{
+			name:       "qr with encrypt-to",
+			qr:         "true",
+			encryptTo:  pairRecipient,
+			wantQRIn:   pairArmor,
+			wantAge:    true,
+			wantOutput: "<qr code>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, output, err := runPair(t, `pair_secure_output https://10.0.0.1:6443 "`+pairToken+`" netcup-kube "`+tt.qr+`" "`+tt.encryptTo+`"`)
+			if err != nil {
+				t.Fatalf("pair_secure_output failed: %v. Output: %s", err, output)
+			}
+			if strings.Contains(string(output), pairToken) {
+				t.Errorf("Output contains the plaintext token: %s", output)
+			}
+			if !strings.Contains(string(output), tt.wantOutput) {
+				t.Errorf("Output should contain %q, got: %s", tt.wantOutput, output)
+			}
+
+			qrIn, qrErr := os.ReadFile(filepath.Join(dir, "qr.in"))
+			if tt.wantQRIn == "" && qrErr == nil {
+				t.Errorf("qrencode called with %q", qrIn)
+			} else if tt.wantQRIn != "" && !strings.Contains(string(qrIn), tt.wantQRIn) {
+				t.Errorf("qrencode input = %q, want it to contain %q", qrIn, tt.wantQRIn)
+			}
+
+			ageArgs, ageErr := os.ReadFile(filepath.Join(dir, "age.args"))
+			if !tt.wantAge {
+				if ageErr == nil {
+					t.Errorf("age called with %q", ageArgs)
+				}
+				return
+			}
+			if !strings.Contains(string(ageArgs), "--recipient "+pairRecipient) {
+				t.Errorf("age args = %q, want the recipient", ageArgs)
+			}
+			if ageIn, _ := os.ReadFile(filepath.Join(dir, "age.in")); !strings.Contains(string(ageIn), `TOKEN="`+pairToken+`"`) {
+				t.Errorf("age input = %q, want the join command", ageIn)
+			}
+		})
+	}
+}
+
+// TestPairEncryptToRequiresRecipient checks that a bare --encrypt-to is
+// rejected before the token is read
+func TestPairEncryptToRequiresRecipient(t *testing.T) {
+	_, output, err := runPair(t, "cmd_pair --qr --encrypt-to")
+	if err == nil {
+		t.Fatalf("pair --encrypt-to without a recipient succeeded. Output: %s", output)
+	}
+	if !strings.Contains(string(output), "--encrypt-to requires an age recipient") {
+		t.Errorf("Output should name the missing recipient, got: %s", output)
+	}
+}
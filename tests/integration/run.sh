@@ -2,9 +2,11 @@
 set -euo pipefail
 
 IMAGE="${IMAGE:-debian:13-slim}"
-FALLBACK_IMAGE="${FALLBACK_IMAGE:-debian:trixie-slim}"
+# Set FALLBACK_IMAGE= (empty) to disable the fallback for non-Debian images
+FALLBACK_IMAGE="${FALLBACK_IMAGE-debian:trixie-slim}"
 
 if ! docker pull "$IMAGE"; then
+  [[ -n "$FALLBACK_IMAGE" ]] || exit 1
   echo "Primary image $IMAGE not available, trying fallback $FALLBACK_IMAGE" >&2
   docker pull "$FALLBACK_IMAGE"
   IMAGE="$FALLBACK_IMAGE"
@@ -1,22 +1,28 @@
 #!/usr/bin/env bash
 set -euo pipefail
 
-export DEBIAN_FRONTEND=noninteractive
-
 # Install dependencies, but allow ca-certificates to fail due to Docker limitation
-apt-get update -y
-if ! apt-get install -y --no-install-recommends \
-  ca-certificates curl iproute2 iptables kmod util-linux procps gnupg lsb-release sed tar coreutils jq nftables; then
-  echo "Warning: Some packages may have failed to install (ca-certificates issue in Docker)" >&2
-  # Verify critical packages are available
-  for cmd in curl ip iptables jq; do
-    if ! command -v "$cmd" &> /dev/null; then
-      echo "Error: Critical command '$cmd' not found" >&2
-      exit 1
-    fi
-  done
+if command -v apt-get &> /dev/null; then
+  export DEBIAN_FRONTEND=noninteractive
+  apt-get update -y
+  apt-get install -y --no-install-recommends \
+    ca-certificates curl iproute2 iptables kmod util-linux procps gnupg lsb-release sed tar coreutils jq nftables ||
+    echo "Warning: Some packages may have failed to install (ca-certificates issue in Docker)" >&2
+else
+  # RHEL-family images ship curl-minimal/coreutils-single; keep those
+  dnf install -y --setopt=install_weak_deps=False \
+    ca-certificates iproute iptables-nft kmod util-linux procps-ng gnupg2 sed tar jq nftables ||
+    echo "Warning: Some packages may have failed to install" >&2
 fi
 
+# Verify critical packages are available
+for cmd in curl ip iptables jq; do
+  if ! command -v "$cmd" &> /dev/null; then
+    echo "Error: Critical command '$cmd' not found" >&2
+    exit 1
+  fi
+done
+
 cd /workspace
 
 export DRY_RUN=true
@@ -90,6 +96,11 @@ else
   exit 1
 fi
 
+echo "==== Testing OS preflight ===="
+# The container image must be detected as a supported distribution
+bash -c 'source scripts/lib/common.sh && os_preflight'
+echo "✓ OS preflight passed ($(bash -c 'source scripts/lib/common.sh && os_family'))"
+
 echo "==== Testing bootstrap path ===="
 # Bootstrap path
 ./bin/netcup-kube bootstrap